@@ -11,7 +11,7 @@ import (
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintln(os.Stderr, errors.Format(err))
-		os.Exit(1)
+		os.Exit(errors.ExitCode(err))
 	}
 }
 
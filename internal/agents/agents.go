@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Definition represents a parsed agent definition from a markdown file.
@@ -15,9 +16,17 @@ type Definition struct {
 	// Name is the agent name, derived from the filename (without .md extension)
 	Name string
 
-	// Content is the full markdown content of the agent definition
+	// Content is the markdown content of the agent definition, without its
+	// frontmatter
 	Content string
 
+	// Probe is a shell command run in the agent's worktree before the agent
+	// starts; if it fails the agent is not spawned (see RunProbe)
+	Probe string
+
+	// ProbeTimeout bounds the probe (0: DefaultProbeTimeout)
+	ProbeTimeout time.Duration
+
 	// SourcePath is the absolute path to the source file
 	SourcePath string
 
@@ -91,6 +100,21 @@ func (r *Reader) ReadAllDefinitions() ([]Definition, error) {
 	return MergeDefinitions(localDefs, repoDefs), nil
 }
 
+// FindDefinition returns the definition with the given name, preferring the
+// checked-in repo definition like ReadAllDefinitions does.
+func (r *Reader) FindDefinition(name string) (Definition, error) {
+	defs, err := r.ReadAllDefinitions()
+	if err != nil {
+		return Definition{}, err
+	}
+	for _, def := range defs {
+		if def.Name == name {
+			return def, nil
+		}
+	}
+	return Definition{}, fmt.Errorf("agent definition %q not found", name)
+}
+
 // MergeDefinitions merges local and repo definitions.
 // Repo definitions take precedence over local definitions on filename conflict.
 func MergeDefinitions(local, repo []Definition) []Definition {
@@ -167,17 +191,67 @@ func readDefinitionsFromDir(dir string, source DefinitionSource) ([]Definition,
 		// Extract name from filename (without .md extension)
 		name := strings.TrimSuffix(entry.Name(), ".md")
 
-		definitions = append(definitions, Definition{
+		def := Definition{
 			Name:       name,
-			Content:    string(content),
 			SourcePath: filePath,
 			Source:     source,
-		})
+		}
+		if err := def.parse(string(content)); err != nil {
+			return nil, fmt.Errorf("invalid agent definition %s: %w", filePath, err)
+		}
+		definitions = append(definitions, def)
 	}
 
 	return definitions, nil
 }
 
+// parse sets the definition's content and the settings in its frontmatter.
+// Frontmatter is optional: "key: value" lines between two "---" lines at the
+// very start of the file.
+//
+//	---
+//	probe: go build ./...
+//	probe-timeout: 10m
+//	---
+func (d *Definition) parse(content string) error {
+	d.Content = content
+	rest, ok := strings.CutPrefix(content, "---\n")
+	if !ok {
+		return nil
+	}
+	header, body, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		// An unterminated block is ordinary markdown (e.g. a horizontal rule)
+		return nil
+	}
+
+	for _, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("invalid frontmatter line %q: expected key: value", line)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "probe":
+			d.Probe = value
+		case "probe-timeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("invalid probe-timeout %q: must be a positive duration", value)
+			}
+			d.ProbeTimeout = timeout
+		default:
+			return fmt.Errorf("unknown frontmatter key %q", strings.TrimSpace(key))
+		}
+	}
+	d.Content = strings.TrimLeft(body, "\n")
+	return nil
+}
+
 // ParseTitle extracts the title from a markdown definition.
 // It looks for the first H1 heading (# Title) in the content.
 // Returns the name as-is if no H1 heading is found.
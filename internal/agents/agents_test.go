@@ -1,9 +1,13 @@
 package agents
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestReadLocalDefinitions(t *testing.T) {
@@ -268,6 +272,17 @@ func TestReadAllDefinitions(t *testing.T) {
 			t.Errorf("expected worker to be from repo, got %s", def.Source)
 		}
 	}
+
+	worker, err := reader.FindDefinition("worker")
+	if err != nil {
+		t.Fatalf("FindDefinition failed: %v", err)
+	}
+	if worker.Content != "repo worker" {
+		t.Errorf("expected the repo worker definition, got %q", worker.Content)
+	}
+	if _, err := reader.FindDefinition("missing"); err == nil {
+		t.Error("expected an error for a missing definition")
+	}
 }
 
 func TestParseTitle(t *testing.T) {
@@ -369,3 +384,57 @@ func TestEmptyRepoPath(t *testing.T) {
 		t.Fatalf("expected 0 definitions, got %d", len(defs))
 	}
 }
+
+func TestDefinitionFrontmatter(t *testing.T) {
+	dir := t.TempDir()
+	content := "---\nprobe: go build ./...\nprobe-timeout: 10m\n---\n\n# Builder\n\nBuilds things.\n"
+	if err := os.WriteFile(filepath.Join(dir, "builder.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := NewReader(dir, "").ReadLocalDefinitions()
+	if err != nil {
+		t.Fatalf("ReadLocalDefinitions failed: %v", err)
+	}
+	if len(defs) != 1 {
+		t.Fatalf("expected 1 definition, got %d", len(defs))
+	}
+	def := defs[0]
+	if def.Probe != "go build ./..." || def.ProbeTimeout != 10*time.Minute {
+		t.Errorf("probe = %q (%s), want go build ./... (10m)", def.Probe, def.ProbeTimeout)
+	}
+	if !strings.HasPrefix(def.Content, "# Builder") || def.ParseTitle() != "Builder" {
+		t.Errorf("frontmatter should be stripped from content, got %q", def.Content)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "builder.md"), []byte("---\nprobes: true\n---\n# Builder\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewReader(dir, "").ReadLocalDefinitions(); err == nil {
+		t.Error("unknown frontmatter keys should be rejected")
+	}
+}
+
+func TestRunProbe(t *testing.T) {
+	dir := t.TempDir()
+
+	ok := Definition{Name: "worker", Probe: "test -d ."}
+	if err := ok.RunProbe(context.Background(), dir); err != nil {
+		t.Errorf("passing probe failed: %v", err)
+	}
+
+	failing := Definition{Name: "worker", Probe: "echo 'main.go:3: undefined: x'; exit 2"}
+	err := failing.RunProbe(context.Background(), dir)
+	var probeErr *ProbeError
+	if !errors.As(err, &probeErr) {
+		t.Fatalf("failing probe returned %v, want a *ProbeError", err)
+	}
+	if probeErr.Output != "main.go:3: undefined: x" || !strings.Contains(err.Error(), "undefined: x") {
+		t.Errorf("probe error should carry the output, got %q", err)
+	}
+
+	slow := Definition{Name: "worker", Probe: "sleep 5", ProbeTimeout: 50 * time.Millisecond}
+	if err := slow.RunProbe(context.Background(), dir); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("slow probe returned %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,71 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultProbeTimeout bounds probes that don't set probe-timeout
+const DefaultProbeTimeout = 5 * time.Minute
+
+// maxProbeOutput is how much of a failed probe's output is kept; the end of
+// the output is where compilers and test runners report what went wrong
+const maxProbeOutput = 8 * 1024
+
+// ProbeError is returned when an agent's probe fails. Output holds the tail
+// of what the probe printed.
+type ProbeError struct {
+	Agent   string
+	Command string
+	Output  string
+	Err     error
+}
+
+func (e *ProbeError) Error() string {
+	msg := fmt.Sprintf("probe for %s failed (%s): %v", e.Agent, e.Command, e.Err)
+	if e.Output != "" {
+		msg += "\n" + e.Output
+	}
+	return msg
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// RunProbe runs the definition's probe in dir, the agent's worktree, and
+// returns a *ProbeError if it fails or times out. Definitions without a
+// probe pass.
+func (d *Definition) RunProbe(ctx context.Context, dir string) error {
+	if d.Probe == "" {
+		return nil
+	}
+	timeout := d.ProbeTimeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", d.Probe)
+	cmd.Dir = dir
+	// Children of the shell may outlive it and hold the output pipe open
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	out := strings.TrimSpace(string(output))
+	if len(out) > maxProbeOutput {
+		out = "...\n" + out[len(out)-maxProbeOutput:]
+	}
+	return &ProbeError{Agent: d.Name, Command: d.Probe, Output: out, Err: err}
+}
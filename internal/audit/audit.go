@@ -0,0 +1,191 @@
+// Package audit keeps an append-only log of every change to the daemon's
+// state: repositories added and removed, agents spawned, completed and
+// removed, tasks queued and assigned, messages delivered, and configuration
+// changes. Each entry names the command that made the change and who sent
+// it, so operators can review what agents and people did.
+//
+// Unlike the per-repository feed, which is trimmed and written for agents,
+// entries are never rewritten. When the log grows past MaxBytes it is
+// renamed to audit.jsonl.1, replacing the previous one, and a new log is
+// started.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Action identifies a kind of state change
+type Action string
+
+const (
+	ActionRepoAdded          Action = "repo_added"
+	ActionRepoRemoved        Action = "repo_removed"
+	ActionRepoConfigured     Action = "repo_configured"
+	ActionCurrentRepoSet     Action = "current_repo_set"
+	ActionCurrentRepoCleared Action = "current_repo_cleared"
+	ActionRepoLockTaken      Action = "repo_lock_taken"
+	ActionRefreshResumed     Action = "refresh_resumed"
+	ActionAgentSpawned       Action = "agent_spawned"
+	ActionAgentRemoved       Action = "agent_removed"
+	ActionAgentCompleted     Action = "agent_completed"
+	ActionAgentHandedOff     Action = "agent_handed_off"
+	ActionAgentRestarted     Action = "agent_restarted"
+	ActionAgentRecovered     Action = "agent_recovered"
+	ActionAgentTimedOut      Action = "agent_timed_out"
+	ActionBranchPulled       Action = "branch_pulled"
+	ActionScratchAdded       Action = "scratch_worktree_added"
+	ActionScratchRemoved     Action = "scratch_worktree_removed"
+	ActionWarmClaimed        Action = "warm_worktree_claimed"
+	ActionTaskQueued         Action = "task_queued"
+	ActionTaskCancelled      Action = "task_cancelled"
+	ActionTaskAssigned       Action = "task_assigned"
+	ActionMessageSent        Action = "message_sent"
+	ActionMessageDelivered   Action = "message_delivered"
+	ActionQuestionAsked      Action = "question_asked"
+	ActionAutoAnswerAdded    Action = "auto_answer_added"
+	ActionAutoAnswerRemoved  Action = "auto_answer_removed"
+	ActionMergeQueueEvent    Action = "merge_queue_event"
+	ActionConflictResolved   Action = "conflict_resolved"
+	ActionStateRepaired      Action = "state_repaired"
+	ActionStateRestored      Action = "state_restored"
+	ActionCleanup            Action = "cleanup"
+	ActionDaemonConfigured   Action = "daemon_configured"
+	ActionDaemonStopped      Action = "daemon_stopped"
+)
+
+// DaemonActor is the actor of changes the daemon makes on its own
+const DaemonActor = "daemon"
+
+// MaxBytes is the size at which the log is rotated
+const MaxBytes = 16 << 20
+
+// Entry is one state change
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action Action    `json:"action"`
+	Repo   string    `json:"repo,omitempty"`
+	Agent  string    `json:"agent,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+	// Command is the socket command that made the change, or the daemon
+	// task (e.g. "health_check") for changes the daemon made on its own
+	Command string `json:"command"`
+	// Origin is the CLI command that sent the request, e.g. "work"
+	Origin string `json:"origin,omitempty"`
+	// Actor is who asked: the caller's user or remote address, the agent
+	// that sent a message, or DaemonActor
+	Actor string `json:"actor"`
+}
+
+// Filter selects entries to List
+type Filter struct {
+	// Repo keeps one repository's entries (empty for all)
+	Repo string
+	// Since keeps entries at or after a time (zero for all)
+	Since time.Time
+	// Limit keeps the newest entries when > 0
+	Limit int
+}
+
+// Log appends entries to a JSON lines file and reads them back
+type Log struct {
+	path     string
+	maxBytes int64
+	mu       sync.Mutex
+}
+
+// NewLog creates an audit log stored at path
+func NewLog(path string) *Log {
+	return &Log{path: path, maxBytes: MaxBytes}
+}
+
+// Append adds an entry, stamping it with the current time if unset
+func (l *Log) Append(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(data)) > l.maxBytes {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries filter selects, oldest first, including those
+// in the rotated log
+func (l *Log) List(filter Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []Entry
+	for _, path := range []string{l.path + ".1", l.path} {
+		read, err := readEntries(path, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, read...)
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// readEntries reads the entries of one file that filter selects. Malformed
+// lines, such as one cut short by a crash, are skipped.
+func readEntries(path string, filter Filter) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Action == "" {
+			continue
+		}
+		if filter.Repo != "" && e.Repo != filter.Repo {
+			continue
+		}
+		if e.Time.Before(filter.Since) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
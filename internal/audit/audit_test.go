@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendAndList(t *testing.T) {
+	l := NewLog(filepath.Join(t.TempDir(), "audit", "audit.jsonl"))
+
+	if entries, err := l.List(Filter{}); err != nil || len(entries) != 0 {
+		t.Fatalf("List() on a missing log = %v, %v", entries, err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	l.Append(Entry{Time: old, Action: ActionRepoAdded, Repo: "repo", Command: "add_repo", Origin: "init", Actor: "ann (uid 1000)"})
+	l.Append(Entry{Action: ActionAgentSpawned, Repo: "repo", Agent: "fox", Command: "add_agent", Actor: "ann (uid 1000)"})
+	l.Append(Entry{Action: ActionMessageSent, Repo: "other", Agent: "supervisor", Command: "route_messages", Actor: "fox"})
+	l.Append(Entry{Action: ActionAgentRemoved, Repo: "repo", Agent: "fox", Command: "health_check", Actor: DaemonActor})
+
+	entries, err := l.List(Filter{})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(entries) != 4 || entries[0].Origin != "init" || entries[3].Actor != DaemonActor {
+		t.Errorf("List() = %+v", entries)
+	}
+	if entries[1].Time.IsZero() {
+		t.Error("Append() should stamp entries without a time")
+	}
+
+	if entries, _ := l.List(Filter{Repo: "repo"}); len(entries) != 3 {
+		t.Errorf("List(repo) returned %d entries, want 3", len(entries))
+	}
+	if entries, _ := l.List(Filter{Repo: "repo", Since: time.Now().Add(-time.Hour)}); len(entries) != 2 {
+		t.Errorf("List(repo, since 1h) returned %d entries, want 2", len(entries))
+	}
+	if entries, _ := l.List(Filter{Limit: 1}); len(entries) != 1 || entries[0].Action != ActionAgentRemoved {
+		t.Errorf("List(limit 1) = %+v, want the newest entry", entries)
+	}
+}
+
+func TestListSkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := NewLog(path)
+	l.Append(Entry{Action: ActionTaskQueued, Repo: "repo", Command: "add_task"})
+
+	// A crash can leave a partial last line
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"time":"2026-01-01T00:00:00Z","act`)
+	f.Close()
+
+	if entries, err := l.List(Filter{}); err != nil || len(entries) != 1 {
+		t.Errorf("List() = %+v, %v; want the one complete entry", entries, err)
+	}
+}
+
+func TestAppendRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := NewLog(path)
+	l.maxBytes = 300
+
+	for i := 0; i < 6; i++ {
+		if err := l.Append(Entry{Action: ActionAgentSpawned, Repo: "repo", Agent: "fox", Command: "add_agent", Actor: "ann"}); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("log wasn't rotated: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > l.maxBytes {
+		t.Errorf("log after rotation = %v, %v; want it under %d bytes", info, err, l.maxBytes)
+	}
+	// The rotated entries are still listed, oldest first
+	entries, err := l.List(Filter{})
+	if err != nil || len(entries) < 2 || entries[0].Time.After(entries[len(entries)-1].Time) {
+		t.Errorf("List() after rotation = %+v, %v", entries, err)
+	}
+}
@@ -0,0 +1,322 @@
+// Package cleanup finds and removes resources multiclaude left behind:
+// stale git worktree metadata, worktree directories git no longer knows
+// about, branches of agents that are gone, message directories of removed
+// agents, and tmux windows and sessions that no agent in state owns.
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/mux"
+)
+
+// KeepWindowOption is a tmux window option that exempts a window from
+// cleanup and the window reaper: `tmux set-option -w @multiclaude-keep on`
+const KeepWindowOption = "@multiclaude-keep"
+
+// Kind identifies what sort of resource an Item is
+type Kind string
+
+const (
+	// KindWorktreeRef is git worktree metadata whose directory is gone
+	KindWorktreeRef Kind = "worktree-ref"
+	// KindWorktreeDir is a directory under wts/ that is not a git worktree
+	KindWorktreeDir Kind = "worktree-dir"
+	// KindBranch is a work/ or workspace/ branch without a worktree
+	KindBranch Kind = "branch"
+	// KindMessages is the message directory of an agent not in state
+	KindMessages Kind = "messages"
+	// KindWindow is a tmux window in a repository's session no agent owns
+	KindWindow Kind = "window"
+	// KindSession is an mc-* tmux session for a repository not in state
+	KindSession Kind = "session"
+)
+
+// branchPrefixes are the branch namespaces multiclaude creates for agents
+var branchPrefixes = []string{"work/", "workspace/"}
+
+// Item is one orphaned resource that was (or would be) removed
+type Item struct {
+	Repo    string `json:"repo,omitempty"`
+	Kind    Kind   `json:"kind"`
+	Target  string `json:"target"`
+	Removed bool   `json:"removed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Options controls a cleanup run
+type Options struct {
+	// Repo limits cleanup to one repository; empty means every repository
+	// and also removes sessions of repositories that no longer exist
+	Repo string
+	// DryRun reports what would be removed without removing it
+	DryRun bool
+	// Skip reports repositories that must be left alone, such as ones
+	// another daemon is managing
+	Skip func(repo string) bool
+	// Namer recognizes the sessions multiclaude names; the zero Namer
+	// knows the default prefix
+	Namer config.Namer
+}
+
+// Cleaner removes orphaned resources
+type Cleaner struct {
+	paths *config.Paths
+	state *state.State
+	tmux  mux.Multiplexer
+}
+
+// New creates a cleaner. tmuxClient may be nil when tmux is unavailable, in
+// which case windows and sessions are left alone.
+func New(paths *config.Paths, st *state.State, tmuxClient mux.Multiplexer) *Cleaner {
+	return &Cleaner{
+		paths: paths,
+		state: st,
+		tmux:  tmuxClient,
+	}
+}
+
+// Run finds orphaned resources and, unless opts.DryRun is set, removes
+// them. Failures to remove an item are reported on the item rather than
+// stopping the run.
+func (c *Cleaner) Run(ctx context.Context, opts Options) ([]Item, error) {
+	repos, err := c.repoNames(opts.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, repoName := range repos {
+		if opts.Skip != nil && opts.Skip(repoName) {
+			continue
+		}
+		items = append(items, c.worktrees(repoName, opts.DryRun)...)
+		items = append(items, c.messageDirs(repoName, opts.DryRun)...)
+		items = append(items, c.windows(ctx, repoName, opts.DryRun)...)
+	}
+	if opts.Repo == "" {
+		items = append(items, c.sessions(ctx, opts.Namer, opts.DryRun)...)
+	}
+	return items, nil
+}
+
+// repoNames returns the repositories to clean: those in state plus any
+// with leftover worktree or message directories
+func (c *Cleaner) repoNames(only string) ([]string, error) {
+	names := make(map[string]bool)
+	for _, name := range c.state.ListRepos() {
+		names[name] = true
+	}
+	for _, dir := range []string{c.paths.WorktreesDir, c.paths.MessagesDir} {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				names[entry.Name()] = true
+			}
+		}
+	}
+
+	if only != "" {
+		if !names[only] {
+			return nil, fmt.Errorf("repository %q not found", only)
+		}
+		return []string{only}, nil
+	}
+
+	repos := make([]string, 0, len(names))
+	for name := range names {
+		repos = append(repos, name)
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// worktrees prunes stale worktree metadata, removes orphaned worktree
+// directories and deletes branches left behind by removed agents
+func (c *Cleaner) worktrees(repoName string, dryRun bool) []Item {
+	repoPath := c.paths.RepoDir(repoName)
+	wtRootDir := c.paths.WorktreeDir(repoName)
+
+	// Without the clone nothing under wts/ can be a worktree
+	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+		if _, err := os.Stat(wtRootDir); err != nil {
+			return nil
+		}
+		item := Item{Repo: repoName, Kind: KindWorktreeDir, Target: wtRootDir}
+		if !dryRun {
+			item.apply(os.RemoveAll(wtRootDir))
+		}
+		return []Item{item}
+	}
+
+	var items []Item
+	wt := worktree.NewManager(repoPath)
+
+	if prunable, err := wt.Prunable(); err == nil && len(prunable) > 0 {
+		var pruneErr error
+		if !dryRun {
+			pruneErr = wt.Prune()
+		}
+		for _, entry := range prunable {
+			item := Item{Repo: repoName, Kind: KindWorktreeRef, Target: entry}
+			if !dryRun {
+				item.apply(pruneErr)
+			}
+			items = append(items, item)
+		}
+	}
+
+	if dryRun {
+		orphaned, _ := worktree.FindOrphaned(wtRootDir, wt)
+		for _, path := range orphaned {
+			items = append(items, Item{Repo: repoName, Kind: KindWorktreeDir, Target: path})
+		}
+	} else if result, err := worktree.CleanupOrphanedWithDetails(wtRootDir, wt); err == nil {
+		for _, path := range result.Removed {
+			items = append(items, Item{Repo: repoName, Kind: KindWorktreeDir, Target: path, Removed: true})
+		}
+		for path, msg := range result.Errors {
+			items = append(items, Item{Repo: repoName, Kind: KindWorktreeDir, Target: path, Error: msg})
+		}
+	}
+
+	for _, prefix := range branchPrefixes {
+		branches, _ := wt.FindOrphanedBranches(prefix)
+		for _, branch := range branches {
+			item := Item{Repo: repoName, Kind: KindBranch, Target: branch}
+			if !dryRun {
+				item.apply(wt.DeleteBranch(branch))
+			}
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// messageDirs removes message directories of agents that are not in state
+func (c *Cleaner) messageDirs(repoName string, dryRun bool) []Item {
+	validAgents, _ := c.state.ListAgents(repoName)
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+
+	orphaned, err := msgMgr.FindOrphaned(repoName, validAgents)
+	if err != nil {
+		return nil
+	}
+
+	items := make([]Item, 0, len(orphaned))
+	for _, agentName := range orphaned {
+		item := Item{Repo: repoName, Kind: KindMessages, Target: agentName}
+		if !dryRun {
+			item.apply(msgMgr.RemoveAgent(repoName, agentName))
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// windows kills windows in the repository's session that no agent owns.
+// Windows listed in the window reaper's keep list or marked with
+// KeepWindowOption are left alone, and the last window is never killed
+// because that would end the session.
+func (c *Cleaner) windows(ctx context.Context, repoName string, dryRun bool) []Item {
+	if c.tmux == nil {
+		return nil
+	}
+	repo, exists := c.state.GetAllRepos()[repoName]
+	if !exists || repo.TmuxSession == "" {
+		return nil
+	}
+	windows, err := c.tmux.ListWindowInfo(ctx, repo.TmuxSession)
+	if err != nil {
+		return nil
+	}
+
+	keep := KeptWindows(repo)
+	open := len(windows)
+	var items []Item
+	for _, window := range windows {
+		if keep[window.Name] {
+			continue
+		}
+		if value, err := c.tmux.GetWindowOption(ctx, repo.TmuxSession, window.Name, KeepWindowOption); err == nil && value != "" && value != "off" {
+			continue
+		}
+		if open <= 1 {
+			break
+		}
+
+		item := Item{Repo: repoName, Kind: KindWindow, Target: repo.TmuxSession + ":" + window.Name}
+		if !dryRun {
+			item.apply(c.tmux.KillWindow(ctx, repo.TmuxSession, window.Name))
+		}
+		if item.Error == "" {
+			open--
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// sessions kills sessions named with one of namer's prefixes that belong
+// to no repository in state
+func (c *Cleaner) sessions(ctx context.Context, namer config.Namer, dryRun bool) []Item {
+	if c.tmux == nil {
+		return nil
+	}
+	sessions, err := c.tmux.ListSessions(ctx)
+	if err != nil {
+		return nil
+	}
+
+	valid := make(map[string]bool)
+	for name, repo := range c.state.GetAllRepos() {
+		valid[namer.Session(name)] = true
+		valid[repo.TmuxSession] = true
+	}
+
+	var items []Item
+	for _, session := range sessions {
+		if !namer.OwnsSession(session) || valid[session] {
+			continue
+		}
+		item := Item{Kind: KindSession, Target: session}
+		if !dryRun {
+			item.apply(c.tmux.KillSession(ctx, session))
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// KeptWindows returns the windows in a repository's session that must not
+// be killed: every agent's window plus the window reaper's keep list
+func KeptWindows(repo *state.Repository) map[string]bool {
+	keep := make(map[string]bool)
+	for _, agent := range repo.Agents {
+		keep[agent.TmuxWindow] = true
+	}
+	for _, name := range repo.WindowReaper.Keep {
+		keep[name] = true
+	}
+	return keep
+}
+
+// apply records the outcome of removing an item
+func (i *Item) apply(err error) {
+	if err != nil {
+		i.Error = err.Error()
+		return
+	}
+	i.Removed = true
+}
@@ -0,0 +1,169 @@
+package cleanup
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sort"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+// setupCleaner creates a repository "repo" with one live worker and a
+// scattering of leftovers, plus worktrees of a repository whose clone is gone
+func setupCleaner(t *testing.T) (*Cleaner, *config.Paths) {
+	t.Helper()
+
+	paths := config.NewTestPaths(t.TempDir())
+	repoPath := paths.RepoDir("repo")
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "--allow-empty", "-m", "Initial commit"},
+		{"branch", "work/gone"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	wt := worktree.NewManager(repoPath)
+	if err := wt.CreateNewBranch(paths.AgentWorktree("repo", "worker-1"), "work/worker-1", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	for _, dir := range []string{
+		paths.AgentWorktree("repo", "stray"),
+		paths.AgentWorktree("deleted", "worker-1"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+
+	msgMgr := messages.NewManager(paths.MessagesDir)
+	for _, agent := range []string{"worker-1", "ghost"} {
+		if _, err := msgMgr.Send("repo", "supervisor", agent, "hello"); err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+	}
+
+	st := state.New(paths.StateFile)
+	st.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+	st.AddAgent("repo", "worker-1", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker-1"})
+
+	return New(paths, st, nil), paths
+}
+
+func assertTargets(t *testing.T, items []Item, want []string) {
+	t.Helper()
+	got := targets(items)
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func targets(items []Item) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, string(item.Kind)+" "+item.Target)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestRun(t *testing.T) {
+	cleaner, paths := setupCleaner(t)
+	ctx := context.Background()
+
+	want := []string{
+		"branch work/gone",
+		"messages ghost",
+		"worktree-dir " + paths.WorktreeDir("deleted"),
+		"worktree-dir " + paths.AgentWorktree("repo", "stray"),
+	}
+
+	items, err := cleaner.Run(ctx, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	assertTargets(t, items, want)
+	for _, item := range items {
+		if item.Removed {
+			t.Errorf("dry run removed %s %s", item.Kind, item.Target)
+		}
+	}
+	if _, err := os.Stat(paths.AgentWorktree("repo", "stray")); err != nil {
+		t.Error("dry run must not remove anything")
+	}
+
+	items, err = cleaner.Run(ctx, Options{})
+	if err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+	assertTargets(t, items, want)
+	for _, item := range items {
+		if !item.Removed || item.Error != "" {
+			t.Errorf("%s %s: removed=%v error=%q", item.Kind, item.Target, item.Removed, item.Error)
+		}
+	}
+	if _, err := os.Stat(paths.AgentWorktree("repo", "worker-1")); err != nil {
+		t.Error("cleanup removed the live worker's worktree")
+	}
+
+	items, err = cleaner.Run(ctx, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("second dry run failed: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("second dry run found %v, want nothing", targets(items))
+	}
+}
+
+func TestRunSingleRepo(t *testing.T) {
+	cleaner, paths := setupCleaner(t)
+
+	items, err := cleaner.Run(context.Background(), Options{Repo: "deleted"})
+	if err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+	assertTargets(t, items, []string{"worktree-dir " + paths.WorktreeDir("deleted")})
+	if _, err := os.Stat(paths.AgentWorktree("repo", "stray")); err != nil {
+		t.Error("cleanup of one repository touched another")
+	}
+
+	if _, err := cleaner.Run(context.Background(), Options{Repo: "nope"}); err == nil {
+		t.Error("expected an error for an unknown repository")
+	}
+}
+
+func TestRunSkip(t *testing.T) {
+	cleaner, _ := setupCleaner(t)
+
+	items, err := cleaner.Run(context.Background(), Options{
+		DryRun: true,
+		Skip:   func(repo string) bool { return repo == "repo" },
+	})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	for _, item := range items {
+		if item.Repo == "repo" {
+			t.Errorf("skipped repository reported %s %s", item.Kind, item.Target)
+		}
+	}
+}
@@ -5,29 +5,54 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/dlorenc/multiclaude/internal/agents"
+	"github.com/dlorenc/multiclaude/internal/audit"
 	"github.com/dlorenc/multiclaude/internal/bugreport"
+	"github.com/dlorenc/multiclaude/internal/cleanup"
+	"github.com/dlorenc/multiclaude/internal/clock"
 	"github.com/dlorenc/multiclaude/internal/daemon"
 	"github.com/dlorenc/multiclaude/internal/errors"
+	"github.com/dlorenc/multiclaude/internal/fleet"
 	"github.com/dlorenc/multiclaude/internal/format"
 	"github.com/dlorenc/multiclaude/internal/hooks"
+	"github.com/dlorenc/multiclaude/internal/launch"
+	"github.com/dlorenc/multiclaude/internal/logstore"
+	"github.com/dlorenc/multiclaude/internal/loopdetect"
 	"github.com/dlorenc/multiclaude/internal/messages"
 	"github.com/dlorenc/multiclaude/internal/names"
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/internal/oneshot"
 	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/repair"
+	"github.com/dlorenc/multiclaude/internal/review"
+	"github.com/dlorenc/multiclaude/internal/scope"
+	"github.com/dlorenc/multiclaude/internal/snapshot"
 	"github.com/dlorenc/multiclaude/internal/socket"
 	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/telemetry"
 	"github.com/dlorenc/multiclaude/internal/templates"
+	"github.com/dlorenc/multiclaude/internal/timeline"
+	"github.com/dlorenc/multiclaude/internal/tracing"
+	"github.com/dlorenc/multiclaude/internal/tui"
 	"github.com/dlorenc/multiclaude/internal/worktree"
 	"github.com/dlorenc/multiclaude/pkg/claude"
 	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/events"
+	"github.com/dlorenc/multiclaude/pkg/mux"
 	"github.com/dlorenc/multiclaude/pkg/tmux"
 )
 
@@ -83,6 +108,9 @@ type CLI struct {
 	rootCmd       *Command
 	paths         *config.Paths
 	documentation string // Auto-generated CLI documentation for prompts
+
+	host   string      // --host as given: a host name, host:port, local, or auto
+	remote *fleet.Host // The daemon --host targets; nil for this machine's
 }
 
 // New creates a new CLI
@@ -146,10 +174,25 @@ func (c *CLI) loadState() (*state.State, error) {
 	return st, nil
 }
 
+// agentWorktreeManager returns a worktree manager for creating worktrees of
+// agents of the given type, which skip Git LFS content if the repository's
+// config says so. LFS download progress is printed.
+func (c *CLI) agentWorktreeManager(repoName string, agentType state.AgentType) *worktree.Manager {
+	fetchLFS := true
+	if st, err := c.loadState(); err == nil {
+		if repo, exists := st.GetAllRepos()[repoName]; exists {
+			fetchLFS = repo.LFSContentFor(agentType)
+		}
+	}
+	return worktree.NewManager(c.paths.RepoDir(repoName),
+		worktree.WithLFSContent(fetchLFS),
+		worktree.WithProgress(os.Stdout))
+}
+
 // sendDaemonRequest sends a request to the daemon and handles common error cases.
 // It returns the response if successful, or an error if communication fails or the daemon returns an error.
 func (c *CLI) sendDaemonRequest(command string, args map[string]interface{}) (*socket.Response, error) {
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: command,
 		Args:    args,
@@ -163,6 +206,78 @@ func (c *CLI) sendDaemonRequest(command string, args map[string]interface{}) (*s
 	return resp, nil
 }
 
+// daemonSender sends requests to a daemon
+type daemonSender interface {
+	Send(req socket.Request) (*socket.Response, error)
+}
+
+// remoteDaemonTimeout bounds a request to the daemon --host targets
+const remoteDaemonTimeout = 10 * time.Minute
+
+// remoteDaemon sends requests to another host's daemon
+type remoteDaemon struct {
+	host fleet.Host
+}
+
+// Send implements daemonSender
+func (r remoteDaemon) Send(req socket.Request) (*socket.Response, error) {
+	client := fleet.NewClient()
+	client.Timeout = remoteDaemonTimeout
+	return client.Send(context.Background(), r.host, req)
+}
+
+// daemonClient returns a client for the daemon commands talk to: this
+// machine's, or the one --host names
+func (c *CLI) daemonClient() daemonSender {
+	if c.remote != nil {
+		return remoteDaemon{host: *c.remote}
+	}
+	return socket.NewClient(c.paths.DaemonSock)
+}
+
+// extractHost removes the global --host flag from args and returns its
+// value
+func extractHost(args []string) ([]string, string, error) {
+	var rest []string
+	host := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if value, ok := strings.CutPrefix(arg, "--host="); ok {
+			host = value
+			continue
+		}
+		if arg == "--host" {
+			if i+1 >= len(args) || strings.HasPrefix(args[i+1], "-") {
+				return nil, "", errors.InvalidUsage("--host needs a host name, host:port, local, or auto")
+			}
+			host = args[i+1]
+			i++
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, host, nil
+}
+
+// resolveHost finds the daemon a --host value names: a host from the hosts
+// file, or the TCP socket at host:port (tcp://host:port for one without
+// TLS). Ad-hoc addresses use $MULTICLAUDE_TOKEN and, if set,
+// $MULTICLAUDE_CA_CERT.
+func (c *CLI) resolveHost(value string) (fleet.Host, error) {
+	hosts, err := fleet.LoadHosts(c.paths.HostsFile())
+	if err != nil {
+		return fleet.Host{}, errors.Wrap(errors.CategoryConfig, "failed to load hosts", err)
+	}
+	if host, ok := fleet.Find(hosts, value); ok {
+		return host, nil
+	}
+	addr, plaintext := strings.CutPrefix(value, "tcp://")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fleet.Host{}, errors.InvalidArgument("--host", value, "a host from 'multiclaude hosts list', host:port of a daemon's TCP socket, local, or auto")
+	}
+	return fleet.Host{Name: addr, Address: addr, CACert: os.Getenv("MULTICLAUDE_CA_CERT"), Plaintext: plaintext}, nil
+}
+
 // removeDirectoryIfExists removes a directory and prints status messages.
 // It prints a warning if removal fails, or a success message if it succeeds.
 // If the directory doesn't exist, it does nothing.
@@ -176,26 +291,26 @@ func removeDirectoryIfExists(path, description string) {
 	}
 }
 
-// tmuxSanitizer replaces problematic characters with hyphens for tmux session names.
-// tmux has issues with dots, colons, spaces, and forward slashes in session names.
-var tmuxSanitizer = strings.NewReplacer(
-	".", "-",
-	":", "-",
-	" ", "-",
-	"/", "-",
-)
+// namer returns the tmux session Namer the config file sets up, falling
+// back to the default prefix if the file can't be read
+func (c *CLI) namer() config.Namer {
+	file, err := config.LoadFile(c.paths.ConfigFile())
+	if err != nil {
+		return config.Namer{}
+	}
+	return file.Namer()
+}
 
-// sanitizeTmuxSessionName creates a tmux-safe session name from a repo name.
-// tmux has issues with certain characters like dots, so we replace them.
-func sanitizeTmuxSessionName(repoName string) string {
-	// Strip control characters (ASCII 0-31) for safety
-	sanitized := strings.Map(func(r rune) rune {
-		if r < 32 {
-			return -1 // drop the character
+// tmuxSession returns a repository's tmux session: the one state records,
+// which keeps an older name until the daemon migrates it, or else the name
+// the configured prefix gives it
+func (c *CLI) tmuxSession(repoName string) string {
+	if st, err := c.loadState(); err == nil {
+		if repo, exists := st.GetRepo(repoName); exists && repo.TmuxSession != "" {
+			return repo.TmuxSession
 		}
-		return r
-	}, repoName)
-	return fmt.Sprintf("mc-%s", tmuxSanitizer.Replace(sanitized))
+	}
+	return c.namer().Session(repoName)
 }
 
 // Execute executes the CLI with the given arguments
@@ -209,7 +324,90 @@ func (c *CLI) Execute(args []string) error {
 		return c.showVersion()
 	}
 
-	return c.executeCommand(c.rootCmd, args)
+	// --host points any command at another host's daemon
+	args, host, err := extractHost(args)
+	if err != nil {
+		return err
+	}
+	c.host = host
+	if host != "" && host != fleet.LocalHost && host != "auto" {
+		remote, err := c.resolveHost(host)
+		if err != nil {
+			return err
+		}
+		c.remote = &remote
+	}
+
+	// The daemon's audit log records which command sent each request
+	socket.Origin = c.commandPath(args)
+	defer func() { socket.Origin = "" }()
+
+	// The daemon traces its own requests
+	if path := c.commandPath(args); path != "daemon _run" {
+		shutdown := tracing.Init("multiclaude")
+		defer shutdown()
+		ctx, span := tracing.Start(context.Background(), path)
+		tracing.SetFallback(ctx)
+		if c.host != "" {
+			span.SetAttr("multiclaude.host", c.host)
+		}
+		defer func() { span.End(err) }()
+	}
+
+	recorder := telemetry.NewRecorder(c.paths.TelemetryDir())
+	if !recorder.Enabled() {
+		err = c.executeCommand(c.rootCmd, args)
+		return err
+	}
+
+	socket.RoundTripObserver = func(command string, elapsed time.Duration, err error) {
+		recorder.Record(telemetry.KindSocket, command, elapsed, err)
+	}
+	defer func() { socket.RoundTripObserver = nil }()
+
+	start := time.Now()
+	err = c.executeCommand(c.rootCmd, args)
+	recorder.Record(telemetry.KindCommand, c.commandPath(args), time.Since(start), err)
+	return err
+}
+
+// commandPath returns the command names at the start of args (e.g. "work
+// list"), leaving out positional arguments and flags so telemetry samples
+// stay anonymous
+func (c *CLI) commandPath(args []string) string {
+	cmd := c.rootCmd
+	var names []string
+	for _, arg := range args {
+		sub, ok := cmd.Subcommands[arg]
+		if !ok {
+			break
+		}
+		names = append(names, arg)
+		cmd = sub
+	}
+	if len(names) == 0 {
+		return "(unknown)"
+	}
+	return strings.Join(names, " ")
+}
+
+// recordStep records how long a slow step inside a command took, if
+// telemetry is enabled, and adds it to the command's trace
+func (c *CLI) recordStep(name string, start time.Time, err error) {
+	telemetry.NewRecorder(c.paths.TelemetryDir()).Record(telemetry.KindStep, name, time.Since(start), err)
+	tracing.Record(context.Background(), name, start, err)
+}
+
+// gitCommand returns a git command whose run is recorded as a span of the
+// command's trace
+func gitCommand(args ...string) *tracing.Cmd {
+	return tracing.Command(context.Background(), exec.Command("git", args...))
+}
+
+// tmuxCommand returns a tmux command whose run is recorded as a span of the
+// command's trace
+func tmuxCommand(args ...string) *tracing.Cmd {
+	return tracing.Command(context.Background(), exec.Command("tmux", args...))
 }
 
 // showVersion displays the version information
@@ -281,6 +479,7 @@ func (c *CLI) showHelp() error {
 
 	fmt.Println()
 	fmt.Println("Use 'multiclaude <command> --help' for more information about a command.")
+	fmt.Println("Add --host <name|host:port> to any command to use another host's daemon.")
 	return nil
 }
 
@@ -327,7 +526,7 @@ func (c *CLI) registerCommands() {
 	daemonCmd.Subcommands["start"] = &Command{
 		Name:        "start",
 		Description: "Start the daemon",
-		Usage:       "multiclaude daemon start",
+		Usage:       "multiclaude daemon start [--speedup <n>]",
 		Run:         c.startDaemon,
 	}
 
@@ -341,7 +540,7 @@ func (c *CLI) registerCommands() {
 	daemonCmd.Subcommands["status"] = &Command{
 		Name:        "status",
 		Description: "Show daemon status",
-		Usage:       "multiclaude daemon status",
+		Usage:       "multiclaude daemon status [--last-restore] [--json]",
 		Run:         c.daemonStatus,
 	}
 
@@ -352,6 +551,27 @@ func (c *CLI) registerCommands() {
 		Run:         c.daemonLogs,
 	}
 
+	daemonCmd.Subcommands["reload"] = &Command{
+		Name:        "reload",
+		Description: "Re-read the config file without restarting the daemon",
+		Usage:       "multiclaude daemon reload",
+		Run:         c.reloadDaemon,
+	}
+
+	daemonCmd.Subcommands["share"] = &Command{
+		Name:        "share",
+		Description: "Let a Unix group use this daemon",
+		Usage:       "multiclaude daemon share <group> | --off",
+		Run:         c.shareDaemon,
+	}
+
+	daemonCmd.Subcommands["relay"] = &Command{
+		Name:        "relay",
+		Description: "Forward one JSON request from stdin to the daemon (used by other hosts over SSH)",
+		Usage:       "multiclaude daemon relay",
+		Run:         c.relayDaemon,
+	}
+
 	daemonCmd.Subcommands["_run"] = &Command{
 		Name:        "_run",
 		Description: "Internal: run daemon in foreground (used by daemon start)",
@@ -372,17 +592,31 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["init"] = &Command{
 		Name:        "init",
 		Description: "Initialize a repository",
-		Usage:       "multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned]",
+		Usage:       "multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned] [--partial|--filter=<spec>] [--mirror[=<upstream-url>]]",
 		Run:         c.initRepo,
 	}
 
 	c.rootCmd.Subcommands["list"] = &Command{
 		Name:        "list",
 		Description: "List tracked repositories",
-		Usage:       "multiclaude list",
+		Usage:       "multiclaude list [--group <group>] [--host <name|host:port>]",
 		Run:         c.listRepos,
 	}
 
+	c.rootCmd.Subcommands["status"] = &Command{
+		Name:        "status",
+		Description: "Show a dashboard of the daemon, agents, and recent events",
+		Usage:       "multiclaude status [--group <group>] [--watch[=<interval>]] [--json] [--all-hosts]",
+		Run:         c.showStatus,
+	}
+
+	c.rootCmd.Subcommands["ui"] = &Command{
+		Name:        "ui",
+		Description: "Monitor and control agents in an interactive terminal UI",
+		Usage:       "multiclaude ui",
+		Run:         c.runUI,
+	}
+
 	// Repository commands (repo subcommand)
 	repoCmd := &Command{
 		Name:        "repo",
@@ -418,13 +652,27 @@ func (c *CLI) registerCommands() {
 		Run:         c.clearCurrentRepo,
 	}
 
+	repoCmd.Subcommands["resume-refresh"] = &Command{
+		Name:        "resume-refresh",
+		Description: "Resume worktree auto-refresh after main was force-pushed",
+		Usage:       "multiclaude repo resume-refresh [--repo <repo>]",
+		Run:         c.resumeRefresh,
+	}
+
+	repoCmd.Subcommands["lock"] = &Command{
+		Name:        "lock",
+		Description: "Show which daemon manages a repository, or take it over",
+		Usage:       "multiclaude repo lock [--repo <repo>] [--take-over]",
+		Run:         c.repoLock,
+	}
+
 	c.rootCmd.Subcommands["repo"] = repoCmd
 
 	// Worker commands
 	workCmd := &Command{
 		Name:        "work",
 		Description: "Manage worker agents",
-		Usage:       "multiclaude work [<task>] [--repo <repo>] [--branch <branch>] [--push-to <branch>]",
+		Usage:       "multiclaude work [<task>] [--repo <repo> | --group <group>] [--base <branch|tag|sha>] [--branch <branch>] [--push-to <branch>] [--git-token-file <path>] [--allowed-paths <a,b>] [--path <subdir>] [--label <a,b>] [--deadline <duration>] [--priority P0|P1|P2|P3] [--skip-probe] [--headless] [--host <name|host:port|auto>] [--queue]",
 		Subcommands: make(map[string]*Command),
 	}
 
@@ -433,10 +681,17 @@ func (c *CLI) registerCommands() {
 	workCmd.Subcommands["list"] = &Command{
 		Name:        "list",
 		Description: "List active workers",
-		Usage:       "multiclaude work list [--repo <repo>]",
+		Usage:       "multiclaude work list [--repo <repo>] [--status <status>] [--label <label>] [--sort <field>|-<field>] [--limit N] [--offset N]",
 		Run:         c.listWorkers,
 	}
 
+	workCmd.Subcommands["status"] = &Command{
+		Name:        "status",
+		Description: "Show each worker's git state and window liveness",
+		Usage:       "multiclaude work status [--repo <repo>] [--json]",
+		Run:         c.workerStatus,
+	}
+
 	workCmd.Subcommands["rm"] = &Command{
 		Name:        "rm",
 		Description: "Remove a worker",
@@ -444,6 +699,41 @@ func (c *CLI) registerCommands() {
 		Run:         c.removeWorker,
 	}
 
+	workCmd.Subcommands["handoff"] = &Command{
+		Name:        "handoff",
+		Description: "Hand a worker's worktree and branch to a new worker",
+		Usage:       "multiclaude work handoff <worker-name> <task> [--summary <text>] [--name <new-name>] [--repo <repo>]",
+		Run:         c.handoffWorker,
+	}
+
+	workCmd.Subcommands["pull"] = &Command{
+		Name:        "pull",
+		Description: "Rebase a worker's worktree onto commits pushed to its branch",
+		Usage:       "multiclaude work pull <worker-name> [--repo <repo>]",
+		Run:         c.pullWorkerBranch,
+	}
+
+	workCmd.Subcommands["resolve"] = &Command{
+		Name:        "resolve",
+		Description: "Choose how to handle a worker whose rebase onto main conflicted",
+		Usage:       "multiclaude work resolve <worker-name> assign|helper|skip [--repo <repo>]",
+		Run:         c.resolveWorkerConflict,
+	}
+
+	workCmd.Subcommands["recover"] = &Command{
+		Name:        "recover",
+		Description: "Abort a worker's interrupted rebase or merge and restore its refresh stash",
+		Usage:       "multiclaude work recover <worker-name> [--repo <repo>] [--older-than <duration>] [--force]",
+		Run:         c.recoverWorker,
+	}
+
+	workCmd.Subcommands["rerun"] = &Command{
+		Name:        "rerun",
+		Description: "Spawn a new worker identical to an earlier one",
+		Usage:       "multiclaude work rerun <worker-name> [--name <new-name>] [--repo <repo>] [--latest]",
+		Run:         c.rerunWorker,
+	}
+
 	c.rootCmd.Subcommands["work"] = workCmd
 
 	// Workspace commands
@@ -529,13 +819,107 @@ func (c *CLI) registerCommands() {
 		Run:         c.ackMessage,
 	}
 
+	agentCmd.Subcommands["reply"] = &Command{
+		Name:        "reply",
+		Description: "Answer a question broadcast to every agent",
+		Usage:       "multiclaude agent reply <broadcast-id> <answer>",
+		Run:         c.replyToBroadcast,
+	}
+
+	agentCmd.Subcommands["ask"] = &Command{
+		Name:        "ask",
+		Description: "Ask a human a question; the reply is typed into this window",
+		Usage:       "multiclaude agent ask <question>",
+		Run:         c.askQuestion,
+	}
+
+	agentCmd.Subcommands["heartbeat"] = &Command{
+		Name:        "heartbeat",
+		Description: "Tell the daemon this agent is still working",
+		Usage:       "multiclaude agent heartbeat",
+		Run:         c.agentHeartbeat,
+	}
+
 	agentCmd.Subcommands["complete"] = &Command{
 		Name:        "complete",
 		Description: "Signal worker completion",
-		Usage:       "multiclaude agent complete [--summary <text>] [--failure <reason>]",
+		Usage:       "multiclaude agent complete [--summary <text>] [--failure <reason>] [--squash [--title <subject>]] [--push] [--pr] [--cleanup]",
 		Run:         c.completeWorker,
 	}
 
+	agentCmd.Subcommands["check-branch"] = &Command{
+		Name:        "check-branch",
+		Description: "Check this branch against the repository's branch guard",
+		Usage:       "multiclaude agent check-branch",
+		Run:         c.checkBranchGuard,
+	}
+
+	agentCmd.Subcommands["check-review"] = &Command{
+		Name:        "check-review",
+		Description: "Check a review comment against the PR's review checklists",
+		Usage:       "multiclaude agent check-review <comment-file>|-",
+		Run:         c.checkReviewChecklist,
+	}
+
+	agentCmd.Subcommands["pull"] = &Command{
+		Name:        "pull",
+		Description: "Rebase this worktree onto commits someone else pushed to its branch",
+		Usage:       "multiclaude agent pull",
+		Run:         c.pullOwnBranch,
+	}
+
+	scratchCmd := &Command{
+		Name:        "scratch",
+		Description: "Manage extra worktrees for spikes and bisects, removed with this agent",
+		Usage:       "multiclaude agent scratch [add|rm|list]",
+		Subcommands: make(map[string]*Command),
+	}
+	scratchCmd.Run = c.listScratchWorktrees
+
+	scratchCmd.Subcommands["add"] = &Command{
+		Name:        "add",
+		Description: "Create a scratch worktree detached at a revision of this worktree",
+		Usage:       "multiclaude agent scratch add <name> [--at <rev>] [--branch]",
+		Run:         c.addScratchWorktree,
+	}
+
+	scratchCmd.Subcommands["rm"] = &Command{
+		Name:        "rm",
+		Description: "Remove a scratch worktree",
+		Usage:       "multiclaude agent scratch rm <name>",
+		Run:         c.removeScratchWorktree,
+	}
+
+	scratchCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List this agent's scratch worktrees",
+		Usage:       "multiclaude agent scratch list",
+		Run:         c.listScratchWorktrees,
+	}
+
+	agentCmd.Subcommands["scratch"] = scratchCmd
+
+	agentCmd.Subcommands["feed"] = &Command{
+		Name:        "feed",
+		Description: "Show recent daemon actions in this repository (spawns, refreshes, cleanups, merges)",
+		Usage:       "multiclaude agent feed [--since <1h|2d>] [--limit <n>] [--repo <repo>] [--json]",
+		Run:         c.showFeed,
+	}
+
+	agentCmd.Subcommands["handoff"] = &Command{
+		Name:        "handoff",
+		Description: "Hand this worktree and a summary to a new worker",
+		Usage:       "multiclaude agent handoff <task for the new worker> --summary <progress so far> [--name <new-name>]",
+		Run:         c.handoffSelf,
+	}
+
+	agentCmd.Subcommands["queue-event"] = &Command{
+		Name:        "queue-event",
+		Description: "Record a merge queue event for a PR (used by the merge-queue agent)",
+		Usage:       "multiclaude agent queue-event <enqueued|ci_started|ci_finished|merged|failed|closed> [--pr <number>] [--branch <branch>] [--repo <repo>]",
+		Run:         c.recordQueueEvent,
+	}
+
 	agentCmd.Subcommands["restart"] = &Command{
 		Name:        "restart",
 		Description: "Restart a crashed or exited agent",
@@ -546,25 +930,60 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["agent"] = agentCmd
 
 	// Attach command
+	c.rootCmd.Subcommands["path"] = &Command{
+		Name:        "path",
+		Description: "Print the worktree path of an agent or workspace",
+		Usage:       "multiclaude path [<agent-name>] [--repo <repo>] [--shell]",
+		Run:         c.agentPath,
+	}
+
+	c.rootCmd.Subcommands["shell-init"] = &Command{
+		Name:        "shell-init",
+		Description: "Print shell functions for jumping into agent worktrees (mcd)",
+		Usage:       "multiclaude shell-init [bash|zsh|fish]",
+		Run:         c.shellInit,
+	}
+
+	c.rootCmd.Subcommands["respond"] = &Command{
+		Name:        "respond",
+		Description: "Reply to an agent that is waiting for input",
+		Usage:       "multiclaude respond [--agent <name>|<#>] [--repo <repo>] [--response-id <id>] <reply>",
+		Run:         c.respondToAgent,
+	}
+
+	c.rootCmd.Subcommands["broadcast"] = &Command{
+		Name:        "broadcast",
+		Description: "Ask every active agent a question and summarize their replies",
+		Usage:       "multiclaude broadcast [--repo <repo>] [--timeout <5m>] [--json] <question>",
+		Run:         c.broadcastQuestion,
+	}
+
 	c.rootCmd.Subcommands["attach"] = &Command{
 		Name:        "attach",
 		Description: "Attach to an agent",
-		Usage:       "multiclaude attach <agent-name> [--read-only]",
+		Usage:       "multiclaude attach [<agent-name>] [--read-only] [--control]",
 		Run:         c.attachAgent,
 	}
 
+	c.rootCmd.Subcommands["overview"] = &Command{
+		Name:        "overview",
+		Description: "Open a tiled window watching the supervisor, merge queue, and newest workers",
+		Usage:       "multiclaude overview [<repo>] [--workers <4>] [--no-attach]",
+		Run:         c.showOverview,
+	}
+
 	// Maintenance commands
 	c.rootCmd.Subcommands["cleanup"] = &Command{
 		Name:        "cleanup",
 		Description: "Clean up orphaned resources",
-		Usage:       "multiclaude cleanup [--dry-run] [--verbose] [--merged]",
+		Usage:       "multiclaude cleanup [--dry-run] [--repo <repo>] [--verbose] [--merged]",
 		Run:         c.cleanup,
 	}
 
 	c.rootCmd.Subcommands["repair"] = &Command{
 		Name:        "repair",
-		Description: "Repair state after crash",
-		Usage:       "multiclaude repair [--verbose]",
+		Description: "Repair state after crash, optionally restoring a backup of the state file first",
+		Usage:       "multiclaude repair [--from-backup [<n>]] [--dry-run] [--yes]",
 		Run:         c.repair,
 	}
 
@@ -588,7 +1007,7 @@ func (c *CLI) registerCommands() {
 	c.rootCmd.Subcommands["review"] = &Command{
 		Name:        "review",
 		Description: "Spawn a review agent for a PR",
-		Usage:       "multiclaude review <pr-url>",
+		Usage:       "multiclaude review <pr-url> [--repo <repo>] [--read-only]",
 		Run:         c.reviewPR,
 	}
 
@@ -596,7 +1015,7 @@ func (c *CLI) registerCommands() {
 	logsCmd := &Command{
 		Name:        "logs",
 		Description: "View and manage agent output logs",
-		Usage:       "multiclaude logs [<agent-name>] [-f|--follow]",
+		Usage:       "multiclaude logs [<agent-name>] [-f|--follow] [--grep <pattern>]",
 		Subcommands: make(map[string]*Command),
 	}
 
@@ -623,16 +1042,256 @@ func (c *CLI) registerCommands() {
 		Run:         c.cleanLogs,
 	}
 
+	logsCmd.Subcommands["export"] = &Command{
+		Name:        "export",
+		Description: "Write an agent's full log, archived segments included",
+		Usage:       "multiclaude logs export <agent-name> [--repo <repo>] [--output <file>]",
+		Run:         c.exportLogs,
+	}
+
+	logsCmd.Subcommands["storage"] = &Command{
+		Name:        "storage",
+		Description: "Show or set where rotated logs are kept (local, s3, gcs)",
+		Usage:       "multiclaude logs storage [--backend=local|s3|gcs] [--bucket=<name>] [--prefix=<path>] [--region=<region>] [--endpoint=<url>] [--retention=<duration>|off]",
+		Run:         c.logStorage,
+	}
+
 	c.rootCmd.Subcommands["logs"] = logsCmd
 
 	// Config command
-	c.rootCmd.Subcommands["config"] = &Command{
+	configCmd := &Command{
 		Name:        "config",
 		Description: "View or modify repository configuration",
-		Usage:       "multiclaude config [repo] [--mq-enabled=true|false] [--mq-track=all|author|assigned]",
+		Usage:       "multiclaude config [repo] [--mq-enabled=true|false] [--mq-track=all|author|assigned] [--mq-auto-merge=true|false] [--mq-label=<label>] [--mq-interval=<duration>] [--mq-required-checks=a,b] [--mq-merge-method=squash|merge|rebase] [--groups=a,b] [--base=<ref>] [--guard-paths=a,b] [--guard-max-file-mb=N] [--guard-block-binaries=true|false] [--commit-style=none|conventional|regex] [--commit-pattern=<regex>] [--auto-answer=true|false] [--tmux-alerts=true|false] [--conflict-assist=true|false] [--push-remote=<remote>] [--lfs-skip=<agent types>] [--warm-pool=N] [--warm-bootstrap=<cmd>] [--reaper=dry-run|enforce|off] [--reaper-grace=<duration>] [--reaper-keep=a,b] [--auto-recover=true|false] [--recover-after=<duration>] [--stuck-after=<duration>] [--stuck-rules=<match>=<duration>,...] [--stuck-quiet=<regex>=<duration>,...] [--stuck-nudge=true|false] [--restart-max=N] [--queue-workers=N] [--max-workers=N] [--allow-spawn=user,@group] [--allow-remove=...] [--allow-merge=...] [--allow-admin=...]",
 		Run:         c.configRepo,
+		Subcommands: make(map[string]*Command),
+	}
+	configCmd.Subcommands["set"] = &Command{
+		Name:        "set",
+		Description: "Change one repository setting, e.g. max-workers",
+		Usage:       "multiclaude config set <setting> <value> [--repo <repo>]",
+		Run:         c.setRepoConfig,
+	}
+	configCmd.Subcommands["validate"] = &Command{
+		Name:        "validate",
+		Description: "Check the config file (~/.multiclaude/config.yaml)",
+		Usage:       "multiclaude config validate [--file <path>]",
+		Run:         c.validateConfigFile,
+	}
+	c.rootCmd.Subcommands["config"] = configCmd
+
+	// Metrics commands
+	metricsCmd := &Command{
+		Name:        "metrics",
+		Description: "Export agent throughput and merge queue metrics",
+		Subcommands: make(map[string]*Command),
+	}
+
+	metricsCmd.Subcommands["export"] = &Command{
+		Name:        "export",
+		Description: "Append a per-repo metrics snapshot to the metrics CSV/JSON files",
+		Usage:       "multiclaude metrics export [--date YYYY-MM-DD]",
+		Run:         c.exportMetrics,
+	}
+
+	metricsCmd.Subcommands["queue"] = &Command{
+		Name:        "queue",
+		Description: "Show merge queue depth, time-in-queue, outcomes, and CI wait",
+		Usage:       "multiclaude metrics queue [--repo <repo>] [--prometheus]",
+		Run:         c.showQueueMetrics,
+	}
+
+	c.rootCmd.Subcommands["metrics"] = metricsCmd
+
+	// Merge queue commands
+	mergeQueueCmd := &Command{
+		Name:        "mergequeue",
+		Description: "Inspect the merge queue",
+		Subcommands: make(map[string]*Command),
+	}
+
+	mergeQueueCmd.Subcommands["simulate"] = &Command{
+		Name:        "simulate",
+		Description: "Show the order queued PRs would merge in, failing required checks, and branches that need a rebase, without merging anything",
+		Usage:       "multiclaude mergequeue simulate [--repo <repo>] [--json]",
+		Run:         c.simulateMergeQueue,
+	}
+
+	c.rootCmd.Subcommands["mergequeue"] = mergeQueueCmd
+
+	c.rootCmd.Subcommands["timeline"] = &Command{
+		Name:        "timeline",
+		Description: "Export a Gantt-style timeline of agent tasks, waiting periods, refreshes, and conflicts",
+		Usage:       "multiclaude timeline [--repo <repo>] [--since <7d|RFC 3339>] [--until <RFC 3339>] [--format markdown|mermaid|json] [--output <file>]",
+		Run:         c.exportTimeline,
+	}
+
+	c.rootCmd.Subcommands["audit"] = &Command{
+		Name:        "audit",
+		Description: "Show the log of state changes: repos added, agents spawned and removed, tasks assigned, messages sent",
+		Usage:       "multiclaude audit [--repo <repo>] [--since <1h|2d>] [--limit <n>] [--json]",
+		Run:         c.showAudit,
+	}
+
+	c.rootCmd.Subcommands["run"] = &Command{
+		Name:        "run",
+		Description: "Run one agent on one task to completion without the daemon or tmux (e.g. in CI)",
+		Usage:       "multiclaude run [--repo <path|repo>] --task <task> [--headless] [--timeout <30m>] [--base <branch>] [--branch <name>] [--diff <file>] [--pr] [--keep-worktree]",
+		Run:         c.runOneShot,
+	}
+
+	taskCmd := &Command{
+		Name:        "task",
+		Description: "Queue tasks for the daemon to hand to workers as slots free up",
+		Subcommands: make(map[string]*Command),
+	}
+
+	taskCmd.Subcommands["add"] = &Command{
+		Name:        "add",
+		Description: "Queue a task",
+		Usage:       "multiclaude task add <description> [--priority urgent|high|normal|low|P0-P3] [--repo <repo>]",
+		Run:         c.addTask,
+	}
+
+	taskCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List running, queued, and finished tasks",
+		Usage:       "multiclaude task list [--repo <repo>]",
+		Run:         c.listTasks,
+	}
+
+	taskCmd.Subcommands["cancel"] = &Command{
+		Name:        "cancel",
+		Description: "Remove a task that hasn't been dispatched yet",
+		Usage:       "multiclaude task cancel <task-id> [--repo <repo>]",
+		Run:         c.cancelTask,
+	}
+
+	taskCmd.Run = c.listTasks
+
+	c.rootCmd.Subcommands["task"] = taskCmd
+
+	hostsCmd := &Command{
+		Name:        "hosts",
+		Description: "Manage the other hosts whose daemons form a fleet with this one",
+		Subcommands: make(map[string]*Command),
+	}
+
+	hostsCmd.Subcommands["add"] = &Command{
+		Name:        "add",
+		Description: "Add a host, reached with ssh <ssh-target> or on its daemon's TCP socket",
+		Usage:       "multiclaude hosts add <name> <ssh-target> | <name> --address <host:port> [--token-file <path>] [--ca-cert <path>] [--plaintext]",
+		Run:         c.addHost,
+	}
+
+	hostsCmd.Subcommands["remove"] = &Command{
+		Name:        "remove",
+		Description: "Remove a host",
+		Usage:       "multiclaude hosts remove <name>",
+		Run:         c.removeHost,
+	}
+
+	hostsCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List hosts and whether their daemons are reachable",
+		Usage:       "multiclaude hosts list",
+		Run:         c.listHosts,
+	}
+
+	hostsCmd.Run = c.listHosts
+
+	c.rootCmd.Subcommands["hosts"] = hostsCmd
+
+	// Events commands
+	eventsCmd := &Command{
+		Name:        "events",
+		Description: "Inspect daemon notification events",
+		Subcommands: make(map[string]*Command),
 	}
 
+	eventsCmd.Subcommands["schema"] = &Command{
+		Name:        "schema",
+		Description: "Print the JSON Schema for notification event payloads",
+		Usage:       "multiclaude events schema",
+		Run:         c.showEventSchema,
+	}
+
+	eventsCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List stored notification events, oldest first",
+		Usage:       "multiclaude events list [--since <2h|RFC 3339>] [--until <30m|RFC 3339>] [--repo <repo>] [--type <event type>] [--limit <n>] [--json]",
+		Run:         c.listEvents,
+	}
+
+	c.rootCmd.Subcommands["events"] = eventsCmd
+
+	// Telemetry commands
+	telemetryCmd := &Command{
+		Name:        "telemetry",
+		Description: "Opt-in local timings of commands and daemon roundtrips",
+		Subcommands: make(map[string]*Command),
+	}
+
+	telemetryCmd.Subcommands["enable"] = &Command{
+		Name:        "enable",
+		Description: "Start recording command latencies and failures locally",
+		Usage:       "multiclaude telemetry enable",
+		Run:         c.enableTelemetry,
+	}
+
+	telemetryCmd.Subcommands["disable"] = &Command{
+		Name:        "disable",
+		Description: "Stop recording (existing samples are kept)",
+		Usage:       "multiclaude telemetry disable",
+		Run:         c.disableTelemetry,
+	}
+
+	telemetryCmd.Subcommands["report"] = &Command{
+		Name:        "report",
+		Description: "Show latency percentiles and failure rates",
+		Usage:       "multiclaude telemetry report [--since <7d|24h|30m>] [--json]",
+		Run:         c.telemetryReport,
+	}
+
+	telemetryCmd.Subcommands["clear"] = &Command{
+		Name:        "clear",
+		Description: "Delete recorded samples",
+		Usage:       "multiclaude telemetry clear",
+		Run:         c.clearTelemetry,
+	}
+
+	c.rootCmd.Subcommands["telemetry"] = telemetryCmd
+
+	// Auto-answer commands
+	autoAnswerCmd := &Command{
+		Name:        "auto-answer",
+		Description: "Manage automatic replies to common worker questions",
+		Subcommands: make(map[string]*Command),
+	}
+
+	autoAnswerCmd.Subcommands["list"] = &Command{
+		Name:        "list",
+		Description: "List auto-answer rules and built-in templates",
+		Usage:       "multiclaude auto-answer list [--repo <repo>]",
+		Run:         c.listAutoAnswers,
+	}
+
+	autoAnswerCmd.Subcommands["add"] = &Command{
+		Name:        "add",
+		Description: "Add an auto-answer rule (pattern is a case-insensitive regex)",
+		Usage:       "multiclaude auto-answer add <pattern> <reply> [--repo <repo>]",
+		Run:         c.addAutoAnswer,
+	}
+
+	autoAnswerCmd.Subcommands["rm"] = &Command{
+		Name:        "rm",
+		Description: "Remove an auto-answer rule by number",
+		Usage:       "multiclaude auto-answer rm <#> [--repo <repo>]",
+		Run:         c.removeAutoAnswer,
+	}
+
+	c.rootCmd.Subcommands["auto-answer"] = autoAnswerCmd
+
 	// Bug report command
 	c.rootCmd.Subcommands["bug"] = &Command{
 		Name:        "bug",
@@ -641,6 +1300,13 @@ func (c *CLI) registerCommands() {
 		Run:         c.bugReport,
 	}
 
+	c.rootCmd.Subcommands["whoami"] = &Command{
+		Name:        "whoami",
+		Description: "Show who the daemon thinks you are and what you may do",
+		Usage:       "multiclaude whoami [--repo <repo>]",
+		Run:         c.whoami,
+	}
+
 	// Version command
 	c.rootCmd.Subcommands["version"] = &Command{
 		Name:        "version",
@@ -666,7 +1332,7 @@ func (c *CLI) registerCommands() {
 	agentsCmd.Subcommands["spawn"] = &Command{
 		Name:        "spawn",
 		Description: "Spawn an agent from a prompt file",
-		Usage:       "multiclaude agents spawn --name <name> --class <class> --prompt-file <file> [--repo <repo>] [--task <task>]",
+		Usage:       "multiclaude agents spawn --name <name> --class <class> (--prompt-file <file> | --definition <name>) [--repo <repo>] [--task <task>] [--read-only] [--headless]",
 		Run:         c.spawnAgentFromFile,
 	}
 
@@ -683,41 +1349,392 @@ func (c *CLI) registerCommands() {
 // Daemon command implementations
 
 func (c *CLI) startDaemon(args []string) error {
-	return daemon.RunDetached()
-}
-
-func (c *CLI) runDaemon(args []string) error {
-	return daemon.Run()
-}
-
-func (c *CLI) stopDaemon(args []string) error {
-	_, err := c.sendDaemonRequest("stop", nil)
-	if err != nil {
+	flags, _ := ParseFlags(args)
+	if _, err := parseSpeedup(flags); err != nil {
 		return err
 	}
-
-	fmt.Println("Daemon stopped successfully")
-	return nil
-}
-
-func (c *CLI) daemonStatus(args []string) error {
-	// Check PID file first
-	pidFile := daemon.NewPIDFile(c.paths.DaemonPID)
-	running, pid, err := pidFile.IsRunning()
-	if err != nil {
-		return fmt.Errorf("failed to check daemon status: %w", err)
+	// Check the chaos settings here; the detached daemon inherits the
+	// environment and would only report them in its log
+	if spec := os.Getenv(daemon.ChaosEnv); spec != "" {
+		if _, err := daemon.ParseChaos(spec); err != nil {
+			return err
+		}
 	}
-
-	if !running {
-		fmt.Println("Daemon is not running")
-		return nil
+	if spec := os.Getenv(notify.EmailEnv); spec != "" {
+		if _, err := notify.ParseEmailConfig(spec); err != nil {
+			return err
+		}
+	}
+	if spec := os.Getenv(notify.WebhookEnv); spec != "" {
+		if _, err := notify.ParseWebhookConfig(spec, os.Getenv(notify.WebhookSecretEnv)); err != nil {
+			return err
+		}
+	}
+	if spec := os.Getenv(notify.IncidentEnv); spec != "" {
+		if _, err := notify.ParseIncidentConfig(spec, os.Getenv(notify.IncidentKeyEnv)); err != nil {
+			return err
+		}
+	}
+	if spec := os.Getenv(notify.DesktopEnv); spec != "" {
+		if _, err := notify.ParseDesktopTypes(spec); err != nil {
+			return err
+		}
+	}
+	if spec := os.Getenv(notify.EventRetentionEnv); spec != "" {
+		if _, err := notify.ParseEventRetention(spec); err != nil {
+			return err
+		}
+	}
+	if spec := os.Getenv(notify.RoutesEnv); spec != "" {
+		if _, err := notify.ParseRoutes(spec); err != nil {
+			return err
+		}
+	}
+	if _, err := loadConfigFile(c.paths.ConfigFile()); err != nil {
+		return err
+	}
+	if speedup := flags["speedup"]; speedup != "" {
+		return daemon.RunDetached("--speedup", speedup)
+	}
+	return daemon.RunDetached()
+}
+
+func (c *CLI) runDaemon(args []string) error {
+	flags, _ := ParseFlags(args)
+	speedup, err := parseSpeedup(flags)
+	if err != nil {
+		return err
+	}
+	var opts []daemon.Option
+	if speedup > 0 {
+		opts = append(opts, daemon.WithClock(clock.Scaled(speedup)))
+	}
+	if spec := os.Getenv(daemon.ChaosEnv); spec != "" {
+		cfg, err := daemon.ParseChaos(spec)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, daemon.WithChaos(cfg))
+	}
+	// The notification and event settings live in the config file; these
+	// variables override its matching sections
+	if spec := os.Getenv(notify.EmailEnv); spec != "" {
+		cfg, err := notify.ParseEmailConfig(spec)
+		if err != nil {
+			return err
+		}
+		cfg.Password = os.Getenv(notify.SMTPPasswordEnv)
+		opts = append(opts, daemon.WithEmail(cfg))
+	}
+	if spec := os.Getenv(notify.WebhookEnv); spec != "" {
+		cfg, err := notify.ParseWebhookConfig(spec, os.Getenv(notify.WebhookSecretEnv))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, daemon.WithWebhook(cfg))
+	}
+	if spec := os.Getenv(notify.IncidentEnv); spec != "" {
+		cfg, err := notify.ParseIncidentConfig(spec, os.Getenv(notify.IncidentKeyEnv))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, daemon.WithIncidents(cfg))
+	}
+	if spec := os.Getenv(notify.DesktopEnv); spec != "" {
+		types, err := notify.ParseDesktopTypes(spec)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, daemon.WithDesktop(types))
+	}
+	if spec := os.Getenv(notify.EventRetentionEnv); spec != "" {
+		retention, err := notify.ParseEventRetention(spec)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, daemon.WithEventRetention(retention))
+	}
+	if spec := os.Getenv(notify.RoutesEnv); spec != "" {
+		routes, err := notify.ParseRoutes(spec)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, daemon.WithRoutes(routes))
+	}
+	defer tracing.Init("multiclaude-daemon")()
+	return daemon.Run(opts...)
+}
+
+// parseSpeedup reads --speedup, which runs the daemon's clock faster than
+// real time. It is only allowed in simulation runs (MULTICLAUDE_TEST_MODE=1),
+// where no Claude instances are started. Returns 0 if the flag is unset.
+func parseSpeedup(flags map[string]string) (float64, error) {
+	value, ok := flags["speedup"]
+	if !ok {
+		return 0, nil
+	}
+	speedup, err := strconv.ParseFloat(value, 64)
+	if err != nil || speedup <= 0 {
+		return 0, errors.InvalidArgument("--speedup", value, "a positive number like 60")
+	}
+	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		return 0, errors.InvalidUsage("--speedup is only available in simulation runs (MULTICLAUDE_TEST_MODE=1)")
+	}
+	return speedup, nil
+}
+
+func (c *CLI) stopDaemon(args []string) error {
+	_, err := c.sendDaemonRequest("stop", nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Daemon stopped successfully")
+	return nil
+}
+
+// reloadDaemon has the daemon re-read the config file, as SIGHUP does.
+// Agents and their tmux sessions keep running.
+func (c *CLI) reloadDaemon(args []string) error {
+	resp, err := c.sendDaemonRequest("reload_config", nil)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	if data, ok := resp.Data.(map[string]interface{}); ok {
+		if list, ok := data["changed"].([]interface{}); ok {
+			for _, v := range list {
+				if s, ok := v.(string); ok {
+					changed = append(changed, s)
+				}
+			}
+		}
+	}
+	if len(changed) == 0 {
+		fmt.Printf("Reloaded %s: nothing changed\n", c.paths.ConfigFile())
+		return nil
+	}
+	fmt.Printf("Reloaded %s: applied %s settings\n", c.paths.ConfigFile(), strings.Join(changed, ", "))
+	return nil
+}
+
+// shareDaemon lets members of a Unix group reach the daemon socket. Who may
+// act on each repository is then set with config --allow-*.
+func (c *CLI) shareDaemon(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	var group string
+	switch {
+	case flags["off"] == "true":
+	case len(posArgs) == 1:
+		group = posArgs[0]
+	default:
+		return errors.InvalidUsage("usage: multiclaude daemon share <group> | --off")
+	}
+
+	if _, err := c.sendDaemonRequest("set_socket_group", map[string]interface{}{"group": group}); err != nil {
+		return err
+	}
+
+	if group == "" {
+		fmt.Println("Daemon socket is private to its user again")
+		return nil
+	}
+	fmt.Printf("Members of group %s can now use this daemon\n", group)
+	fmt.Printf("They need to be able to reach %s (e.g. chmod g+x on the directories above it)\n", c.paths.DaemonSock)
+	fmt.Println("Limit who may spawn, remove, merge, or reconfigure per repository:")
+	fmt.Println("  multiclaude config <repo> --allow-remove=alice,@release-team")
+	return nil
+}
+
+// whoami shows how the daemon identifies the caller and what they may do
+func (c *CLI) whoami(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	reqArgs := map[string]interface{}{}
+	if repoName, err := c.resolveRepo(flags); err == nil {
+		reqArgs["repo"] = repoName
+	}
+	resp, err := c.sendDaemonRequest("whoami", reqArgs)
+	if err != nil {
+		return err
+	}
+
+	data, _ := resp.Data.(map[string]interface{})
+	if user, ok := data["user"].(string); ok {
+		fmt.Printf("User: %s (uid %v)\n", user, data["uid"])
+	} else {
+		fmt.Println("User: not identified by the daemon")
+	}
+	if owner, _ := data["owner"].(bool); owner {
+		fmt.Println("Daemon owner: yes")
+	}
+	if group, _ := data["socket_group"].(string); group != "" {
+		fmt.Printf("Shared with group: %s\n", group)
+	}
+	if repoName, ok := reqArgs["repo"].(string); ok {
+		var perms []string
+		if list, _ := data["permissions"].([]interface{}); len(list) > 0 {
+			for _, item := range list {
+				if s, ok := item.(string); ok {
+					perms = append(perms, s)
+				}
+			}
+		}
+		if len(perms) == 0 {
+			perms = []string{"none"}
+		}
+		fmt.Printf("Permissions on %s: %s\n", repoName, strings.Join(perms, ", "))
+	}
+	return nil
+}
+
+// printLastRestore prints what the daemon did with each repository and agent
+// when it restored them at startup
+func printLastRestore(report map[string]interface{}) {
+	startedAt, _ := report["started_at"].(string)
+	if t, err := time.Parse(time.RFC3339Nano, startedAt); err == nil {
+		format.Header("Last restore (%s):", format.TimeAgo(t))
+	} else {
+		format.Header("Last restore:")
+	}
+	fmt.Println()
+
+	repos, _ := report["repos"].([]interface{})
+	if len(repos) == 0 {
+		fmt.Println("No tracked repositories")
+		return
+	}
+
+	table := format.NewColoredTable("REPO", "ACTION", "AGENT", "TYPE", "OUTCOME", "DETAIL")
+	for _, r := range repos {
+		repo, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := repo["repo"].(string)
+		action, _ := repo["action"].(string)
+		reason, _ := repo["reason"].(string)
+		actionCell := format.ColorCell(action, format.Green)
+		switch action {
+		case "failed":
+			actionCell = format.ColorCell(action, format.Red)
+		case "skipped":
+			actionCell = format.ColorCell(action, format.Dim)
+		}
+
+		agents, _ := repo["agents"].([]interface{})
+		if len(agents) == 0 {
+			table.AddRow(format.Cell(name), actionCell, format.ColorCell("-", format.Dim),
+				format.ColorCell("-", format.Dim), format.ColorCell("-", format.Dim), format.Cell(reason))
+			continue
+		}
+		for i, a := range agents {
+			agent, ok := a.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			agentName, _ := agent["name"].(string)
+			agentType, _ := agent["type"].(string)
+			outcome, _ := agent["outcome"].(string)
+			detail, _ := agent["error"].(string)
+
+			outcomeCell := format.ColorCell(outcome, format.Green)
+			switch outcome {
+			case "dropped", "left_to_health":
+				outcomeCell = format.ColorCell(outcome, format.Yellow)
+			case "failed":
+				outcomeCell = format.ColorCell(outcome, format.Red)
+			}
+			// Only the first row of a repository names it
+			repoCell, repoAction := format.Cell(name), actionCell
+			if i > 0 {
+				repoCell, repoAction = format.Cell(""), format.Cell("")
+			} else if detail == "" {
+				detail = reason
+			}
+			table.AddRow(repoCell, repoAction, format.Cell(agentName), format.ColorCell(agentType, format.Dim),
+				outcomeCell, format.Cell(format.Truncate(detail, 50)))
+		}
+	}
+	table.Print()
+}
+
+// printLastRestoreSummary prints a one-line count of the startup restoration's
+// repository actions, pointing at --last-restore for details
+func printLastRestoreSummary(report map[string]interface{}) {
+	repos, _ := report["repos"].([]interface{})
+	counts := make(map[string]int)
+	for _, r := range repos {
+		if repo, ok := r.(map[string]interface{}); ok {
+			action, _ := repo["action"].(string)
+			counts[action]++
+		}
+	}
+	actions := make([]string, 0, len(counts))
+	for action := range counts {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	parts := make([]string, 0, len(actions))
+	for _, action := range actions {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[action], action))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no repositories")
+	}
+	fmt.Printf("  Last restore: %s (see --last-restore)\n", strings.Join(parts, ", "))
+}
+
+// printGitHubStats prints the daemon's GitHub API usage, if it has made any
+// calls
+func printGitHubStats(gh map[string]interface{}) {
+	number := func(key string) int64 {
+		n, _ := gh[key].(float64)
+		return int64(n)
+	}
+	requests, cached, notModified, stale := number("requests"), number("cache_hits"), number("not_modified"), number("stale")
+	if requests+cached+stale+number("throttled") == 0 {
+		return
+	}
+	fmt.Printf("  GitHub API: %d requests (%d cached, %d not modified, %d stale)", requests, cached, notModified, stale)
+	if limit := number("limit"); limit > 0 {
+		fmt.Printf(", %d/%d left", number("remaining"), limit)
+	}
+	fmt.Println()
+	if until, err := time.Parse(time.RFC3339, fmt.Sprint(gh["backoff_until"])); err == nil && time.Until(until) > 0 {
+		fmt.Printf("  GitHub API: backing off until %s after %d failures\n", until.Local().Format("15:04:05"), number("consecutive_failures"))
+	}
+}
+
+func (c *CLI) daemonStatus(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	// Check PID file first; a remote daemon can only be asked
+	running, pid := true, 0
+	if c.remote == nil {
+		var err error
+		pidFile := daemon.NewPIDFile(c.paths.DaemonPID)
+		running, pid, err = pidFile.IsRunning()
+		if err != nil {
+			return fmt.Errorf("failed to check daemon status: %w", err)
+		}
+	}
+
+	if !running {
+		fmt.Println("Daemon is not running")
+		return nil
+	}
+
+	// Try to connect to daemon
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "status",
+	})
+	if err != nil && c.remote != nil {
+		return errors.DaemonCommunicationFailed("status", err)
 	}
-
-	// Try to connect to daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "status",
-	})
 	if err != nil {
 		fmt.Printf("Daemon PID file exists (PID: %d) but daemon is not responding\n", pid)
 		return nil
@@ -727,6 +1744,28 @@ func (c *CLI) daemonStatus(args []string) error {
 		return fmt.Errorf("status check failed: %s", resp.Error)
 	}
 
+	if flags["last-restore"] == "true" {
+		statusMap, _ := resp.Data.(map[string]interface{})
+		report, ok := statusMap["last_restore"].(map[string]interface{})
+		if flags["json"] == "true" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(statusMap["last_restore"])
+		}
+		if !ok {
+			fmt.Println("The daemon has not finished restoring repositories yet")
+			return nil
+		}
+		printLastRestore(report)
+		return nil
+	}
+
+	if flags["json"] == "true" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resp.Data)
+	}
+
 	// Pretty print status
 	fmt.Println("Daemon Status:")
 	if statusMap, ok := resp.Data.(map[string]interface{}); ok {
@@ -735,6 +1774,28 @@ func (c *CLI) daemonStatus(args []string) error {
 		fmt.Printf("  Repos: %v\n", statusMap["repos"])
 		fmt.Printf("  Agents: %v\n", statusMap["agents"])
 		fmt.Printf("  Socket: %v\n", statusMap["socket_path"])
+		if group, _ := statusMap["socket_group"].(string); group != "" {
+			fmt.Printf("  Shared with group: %s\n", group)
+		}
+		if backend, _ := statusMap["multiplexer"].(string); backend != "" {
+			fmt.Printf("  Multiplexer: %s\n", backend)
+		}
+		if addr, _ := statusMap["remote_listen"].(string); addr != "" {
+			fmt.Printf("  Remote socket: %s\n", addr)
+		}
+		if lanes, ok := statusMap["lanes"].(map[string]interface{}); ok {
+			fmt.Printf("  Background jobs: %v running, %v queued (%v workers)\n",
+				lanes["background_running"], lanes["background_queued"], lanes["background_workers"])
+		}
+		if depth, ok := statusMap["merge_queue_depth"]; ok {
+			fmt.Printf("  Merge queue: %v waiting\n", depth)
+		}
+		if gh, ok := statusMap["github"].(map[string]interface{}); ok {
+			printGitHubStats(gh)
+		}
+		if report, ok := statusMap["last_restore"].(map[string]interface{}); ok {
+			printLastRestoreSummary(report)
+		}
 	} else {
 		// Fallback: print as JSON
 		jsonData, _ := json.MarshalIndent(resp.Data, "  ", "  ")
@@ -777,7 +1838,7 @@ func (c *CLI) stopAll(args []string) error {
 
 	// Get list of repos (try daemon first, then state file)
 	var repos []string
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{Command: "list_repos"})
 	if err == nil && resp.Success {
 		// Daemon is running, get repos from it
@@ -830,11 +1891,15 @@ func (c *CLI) stopAll(args []string) error {
 
 	fmt.Println("Stopping all multiclaude sessions...")
 
-	// Kill all multiclaude tmux sessions
+	// Kill all multiclaude tmux sessions. Sessions in a multiplexer the
+	// daemon hosts stop with the daemon.
 	tmuxClient := tmux.NewClient()
-	if tmuxClient.IsTmuxAvailable() {
+	if localMultiplexer(client) != nil && tmuxClient.IsTmuxAvailable() {
+		namer := c.namer()
+		known := make(map[string]bool)
 		for _, repo := range repos {
-			sessionName := fmt.Sprintf("mc-%s", repo)
+			sessionName := c.tmuxSession(repo)
+			known[sessionName] = true
 			exists, err := tmuxClient.HasSession(context.Background(), sessionName)
 			if err == nil && exists {
 				fmt.Printf("Killing tmux session: %s\n", sessionName)
@@ -844,23 +1909,14 @@ func (c *CLI) stopAll(args []string) error {
 			}
 		}
 
-		// Also check for any mc-* sessions we might have missed
+		// Also check for any sessions with our prefixes we might have missed
 		sessions, err := tmuxClient.ListSessions(context.Background())
 		if err == nil {
 			for _, session := range sessions {
-				if strings.HasPrefix(session, "mc-") {
-					exists := false
-					for _, repo := range repos {
-						if fmt.Sprintf("mc-%s", repo) == session {
-							exists = true
-							break
-						}
-					}
-					if !exists {
-						fmt.Printf("Killing orphaned tmux session: %s\n", session)
-						if err := tmuxClient.KillSession(context.Background(), session); err != nil {
-							fmt.Printf("Warning: failed to kill session %s: %v\n", session, err)
-						}
+				if namer.OwnsSession(session) && !known[session] {
+					fmt.Printf("Killing orphaned tmux session: %s\n", session)
+					if err := tmuxClient.KillSession(context.Background(), session); err != nil {
+						fmt.Printf("Warning: failed to kill session %s: %v\n", session, err)
 					}
 				}
 			}
@@ -909,9 +1965,9 @@ func (c *CLI) stopAll(args []string) error {
 
 			fmt.Printf("  Repository: %s\n", repoName)
 
-			// Delete work/* and multiclaude/* branches
+			// Delete the branches multiclaude created
 			wt := worktree.NewManager(repoPath)
-			for _, prefix := range []string{"work/", "multiclaude/"} {
+			for _, prefix := range c.branchPrefixes(repoName) {
 				branches, err := c.listBranchesWithPrefix(repoPath, prefix)
 				if err != nil {
 					fmt.Printf("    Warning: failed to list %s branches: %v\n", prefix, err)
@@ -969,7 +2025,7 @@ func (c *CLI) initRepo(args []string) error {
 	flags, posArgs := ParseFlags(args)
 
 	if len(posArgs) < 1 {
-		return errors.InvalidUsage("usage: multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned]")
+		return errors.InvalidUsage("usage: multiclaude init <github-url> [name] [--no-merge-queue] [--mq-track=all|author|assigned] [--partial|--filter=<spec>] [--mirror[=<upstream-url>]]")
 	}
 
 	githubURL := strings.TrimRight(posArgs[0], "/")
@@ -1015,6 +2071,20 @@ func (c *CLI) initRepo(args []string) error {
 		TrackMode: mqTrackMode,
 	}
 
+	// Partial clone: --partial is shorthand for --filter=blob:none
+	cloneFilter := flags["filter"]
+	if cloneFilter == "" && flags["partial"] == "true" {
+		cloneFilter = worktree.BloblessFilter
+	}
+	if cloneFilter != "" {
+		if err := worktree.ValidateCloneFilter(cloneFilter); err != nil {
+			return errors.InvalidUsage(err.Error())
+		}
+		if err := worktree.RequireGitFeature(worktree.FeaturePartialClone); err != nil {
+			return errors.Wrap(errors.CategoryConfig, "partial clone unavailable", err)
+		}
+	}
+
 	fmt.Printf("Initializing repository: %s\n", repoName)
 	fmt.Printf("GitHub URL: %s\n", githubURL)
 	if mqEnabled {
@@ -1022,24 +2092,57 @@ func (c *CLI) initRepo(args []string) error {
 	} else {
 		fmt.Printf("Merge queue: disabled\n")
 	}
+	if cloneFilter != "" {
+		fmt.Printf("Partial clone: --filter=%s (file contents are fetched on demand)\n", cloneFilter)
+	}
+
+	// Shared mirror: --mirror mirrors the repo itself, --mirror=<url> mirrors
+	// an upstream so several forks of it share one object store
+	var mirrorURL, mirrorPath string
+	if mirrorFlag, ok := flags["mirror"]; ok {
+		mirrorURL = githubURL
+		if mirrorFlag != "true" {
+			mirrorURL = strings.TrimRight(mirrorFlag, "/")
+		}
+		mirrorPath = c.paths.MirrorDir(worktree.MirrorName(mirrorURL))
+		fmt.Printf("Shared mirror: %s\n", mirrorURL)
+	}
 
 	// Check if daemon is running
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	_, err := client.Send(socket.Request{Command: "ping"})
 	if err != nil {
 		return errors.DaemonNotRunning()
 	}
 
+	// Create or update the shared mirror before cloning so the clone can
+	// borrow its objects
+	if mirrorPath != "" {
+		fmt.Printf("Updating mirror: %s\n", mirrorPath)
+		if err := worktree.EnsureMirror(mirrorPath, mirrorURL); err != nil {
+			return errors.GitOperationFailed("mirror", err)
+		}
+	}
+
 	// Clone repository
 	repoPath := c.paths.RepoDir(repoName)
 	fmt.Printf("Cloning to: %s\n", repoPath)
 
-	cmd := exec.Command("git", "clone", githubURL, repoPath)
+	cmd := gitCommand(worktree.CloneArgs(githubURL, repoPath, worktree.CloneOptions{
+		Filter:            cloneFilter,
+		Reference:         mirrorPath,
+		RecurseSubmodules: true,
+	})...)
+	// Git LFS content is downloaded in one batch after the clone
+	cmd.Env = append(os.Environ(), worktree.LFSSkipSmudgeEnv)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
 		return errors.GitOperationFailed("clone", err)
 	}
+	if err := worktree.SetupLFS(repoPath, true, os.Stdout); err != nil {
+		return errors.GitOperationFailed("lfs pull", err)
+	}
 
 	// Copy agent templates to per-repo agents directory
 	agentsDir := c.paths.RepoAgentsDir(repoName)
@@ -1049,22 +2152,64 @@ func (c *CLI) initRepo(args []string) error {
 	}
 
 	// Create tmux session
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-	if tmuxSession == "mc-" {
+	namer := c.namer()
+	tmuxSession := namer.Session(repoName)
+	if tmuxSession == namer.Prefix() {
 		return fmt.Errorf("invalid tmux session name: repository name cannot be empty")
 	}
 
+	repoArgs := map[string]interface{}{
+		"name":          repoName,
+		"github_url":    githubURL,
+		"tmux_session":  tmuxSession,
+		"mq_enabled":    mqConfig.Enabled,
+		"mq_track_mode": string(mqConfig.TrackMode),
+		"clone_filter":  cloneFilter,
+		"mirror":        mirrorPath,
+	}
+
+	// The CLI can only create tmux windows; other multiplexers are hosted by
+	// the daemon, which then creates the session and starts the agents
+	if backend := daemonMultiplexer(client); backend != mux.BackendTmux {
+		fmt.Printf("Starting agents in the daemon's %s multiplexer...\n", backend)
+		repoArgs["start_agents"] = true
+		resp, err := client.Send(socket.Request{Command: "add_repo", Args: repoArgs})
+		if err != nil {
+			return fmt.Errorf("failed to register repository with daemon: %w", err)
+		}
+		if !resp.Success {
+			return fmt.Errorf("failed to register repository: %s", resp.Error)
+		}
+		var agents []string
+		if data, ok := resp.Data.(map[string]interface{}); ok {
+			if list, ok := data["agents"].([]interface{}); ok {
+				for _, a := range list {
+					if name, ok := a.(string); ok {
+						agents = append(agents, name)
+					}
+				}
+			}
+		}
+
+		fmt.Println()
+		fmt.Println("✓ Repository initialized successfully!")
+		fmt.Printf("  Session: %s (%s multiplexer)\n", tmuxSession, backend)
+		fmt.Printf("  Agents: %s\n", strings.Join(agents, ", "))
+		fmt.Printf("\nFollow an agent with: multiclaude logs supervisor -f\n")
+		return nil
+	}
+
 	fmt.Printf("Creating tmux session: %s\n", tmuxSession)
 
 	// Create session with supervisor window
-	cmd = exec.Command("tmux", "new-session", "-d", "-s", tmuxSession, "-n", "supervisor", "-c", repoPath)
+	cmd = tmuxCommand("new-session", "-d", "-s", tmuxSession, "-n", "supervisor", "-c", repoPath)
 	if err := cmd.Run(); err != nil {
 		return errors.TmuxOperationFailed("create session", err)
 	}
 
 	// Create merge-queue window only if enabled
 	if mqEnabled {
-		cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", "merge-queue", "-c", repoPath)
+		cmd = tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", "merge-queue", "-c", repoPath)
 		if err := cmd.Run(); err != nil {
 			return errors.TmuxOperationFailed("create merge-queue window", err)
 		}
@@ -1113,44 +2258,35 @@ func (c *CLI) initRepo(args []string) error {
 		}
 
 		fmt.Println("Starting Claude Code in supervisor window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, "supervisor", repoPath, supervisorSessionID, supervisorPromptFile, repoName, "")
+		pid, err := c.startClaudeInWindow(tmux.NewClient(), claudeBinary, tmuxSession, "supervisor", repoPath, supervisorSessionID, supervisorPromptFile, repoName, state.AgentTypeSupervisor, "")
 		if err != nil {
 			return fmt.Errorf("failed to start supervisor Claude: %w", err)
 		}
 		supervisorPID = pid
 
 		// Set up output capture for supervisor
-		if err := c.setupOutputCapture(tmuxSession, "supervisor", repoName, "supervisor", "supervisor"); err != nil {
+		if err := c.setupOutputCapture(tmux.NewClient(), tmuxSession, "supervisor", repoName, "supervisor", "supervisor"); err != nil {
 			fmt.Printf("Warning: failed to setup output capture for supervisor: %v\n", err)
 		}
 
 		// Start Claude in merge-queue window only if enabled
 		if mqEnabled {
 			fmt.Println("Starting Claude Code in merge-queue window...")
-			pid, err = c.startClaudeInTmux(claudeBinary, tmuxSession, "merge-queue", repoPath, mergeQueueSessionID, mergeQueuePromptFile, repoName, "")
+			pid, err = c.startClaudeInWindow(tmux.NewClient(), claudeBinary, tmuxSession, "merge-queue", repoPath, mergeQueueSessionID, mergeQueuePromptFile, repoName, state.AgentTypeMergeQueue, "")
 			if err != nil {
 				return fmt.Errorf("failed to start merge-queue Claude: %w", err)
 			}
 			mergeQueuePID = pid
 
 			// Set up output capture for merge-queue
-			if err := c.setupOutputCapture(tmuxSession, "merge-queue", repoName, "merge-queue", "merge-queue"); err != nil {
+			if err := c.setupOutputCapture(tmux.NewClient(), tmuxSession, "merge-queue", repoName, "merge-queue", "merge-queue"); err != nil {
 				fmt.Printf("Warning: failed to setup output capture for merge-queue: %v\n", err)
 			}
 		}
 	}
 
 	// Add repository to daemon state (with merge queue config)
-	resp, err := client.Send(socket.Request{
-		Command: "add_repo",
-		Args: map[string]interface{}{
-			"name":          repoName,
-			"github_url":    githubURL,
-			"tmux_session":  tmuxSession,
-			"mq_enabled":    mqConfig.Enabled,
-			"mq_track_mode": string(mqConfig.TrackMode),
-		},
-	})
+	resp, err := client.Send(socket.Request{Command: "add_repo", Args: repoArgs})
 	if err != nil {
 		return fmt.Errorf("failed to register repository with daemon: %w", err)
 	}
@@ -1201,7 +2337,7 @@ func (c *CLI) initRepo(args []string) error {
 	}
 
 	// Create default workspace worktree
-	wt := worktree.NewManager(repoPath)
+	wt := worktree.NewManager(repoPath, worktree.WithProgress(os.Stdout))
 	workspacePath := c.paths.AgentWorktree(repoName, "default")
 
 	// Check for and migrate legacy "workspace" branch to "workspace/default"
@@ -1226,7 +2362,7 @@ func (c *CLI) initRepo(args []string) error {
 	}
 
 	// Create default workspace tmux window (detached so it doesn't switch focus)
-	cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", "default", "-c", workspacePath)
+	cmd = tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", "default", "-c", workspacePath)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to create workspace window: %w", err)
 	}
@@ -1258,14 +2394,14 @@ func (c *CLI) initRepo(args []string) error {
 		}
 
 		fmt.Println("Starting Claude Code in default workspace window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, "default", workspacePath, workspaceSessionID, workspacePromptFile, repoName, "")
+		pid, err := c.startClaudeInWindow(tmux.NewClient(), claudeBinary, tmuxSession, "default", workspacePath, workspaceSessionID, workspacePromptFile, repoName, state.AgentTypeWorkspace, "")
 		if err != nil {
 			return fmt.Errorf("failed to start default workspace Claude: %w", err)
 		}
 		workspacePID = pid
 
 		// Set up output capture for default workspace
-		if err := c.setupOutputCapture(tmuxSession, "default", repoName, "default", "workspace"); err != nil {
+		if err := c.setupOutputCapture(tmux.NewClient(), tmuxSession, "default", repoName, "default", "workspace"); err != nil {
 			fmt.Printf("Warning: failed to setup output capture for default workspace: %v\n", err)
 		}
 	}
@@ -1304,20 +2440,45 @@ func (c *CLI) initRepo(args []string) error {
 	return nil
 }
 
+// daemonMultiplexer asks the daemon which multiplexer it runs agents in.
+// Daemons that don't say use tmux.
+func daemonMultiplexer(client daemonSender) string {
+	resp, err := client.Send(socket.Request{Command: "status"})
+	if err != nil || !resp.Success {
+		return mux.BackendTmux
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	if backend, ok := data["multiplexer"].(string); ok && backend != "" {
+		return backend
+	}
+	return mux.BackendTmux
+}
+
+// localMultiplexer returns the multiplexer the CLI creates and drives agent
+// windows in itself: tmux, when the daemon runs its agents there. Other
+// multiplexers host their windows inside the daemon, so it returns nil and
+// callers leave those windows to the daemon.
+func localMultiplexer(client daemonSender) mux.Multiplexer {
+	if daemonMultiplexer(client) != mux.BackendTmux {
+		return nil
+	}
+	return tmux.NewClient()
+}
+
 func (c *CLI) listRepos(args []string) error {
-	resp, err := c.sendDaemonRequest("list_repos", map[string]interface{}{
-		"rich": true,
-	})
+	flags, _ := ParseFlags(args)
+
+	repos, err := c.fetchRichRepos(flags["group"])
 	if err != nil {
 		return err
 	}
 
-	repos, ok := resp.Data.([]interface{})
-	if !ok {
-		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
-	}
-
 	if len(repos) == 0 {
+		if group := flags["group"]; group != "" {
+			fmt.Printf("No repositories in group '%s'\n", group)
+			format.Dimmed("\nAdd a repository to a group with: multiclaude config <repo> --groups=%s", group)
+			return nil
+		}
 		fmt.Println("No repositories tracked")
 		format.Dimmed("\nInitialize a repository with: multiclaude init <github-url>")
 		return nil
@@ -1326,2745 +2487,7246 @@ func (c *CLI) listRepos(args []string) error {
 	format.Header("Tracked repositories (%d):", len(repos))
 	fmt.Println()
 
-	table := format.NewColoredTable("REPO", "AGENTS", "STATUS", "SESSION")
-	for _, repo := range repos {
-		if repoMap, ok := repo.(map[string]interface{}); ok {
-			name, _ := repoMap["name"].(string)
-			totalAgents := 0
-			if v, ok := repoMap["total_agents"].(float64); ok {
-				totalAgents = int(v)
-			}
-			workerCount := 0
-			if v, ok := repoMap["worker_count"].(float64); ok {
-				workerCount = int(v)
-			}
-			sessionHealthy, _ := repoMap["session_healthy"].(bool)
-			tmuxSession, _ := repoMap["tmux_session"].(string)
-
-			// Format agent count
-			agentStr := fmt.Sprintf("%d total", totalAgents)
-			if workerCount > 0 {
-				agentStr = fmt.Sprintf("%d (%d workers)", totalAgents, workerCount)
-			}
+	table := format.NewColoredTable("REPO", "GROUPS", "AGENTS", "STATUS", "SESSION")
+	for _, repoMap := range repos {
+		name, _ := repoMap["name"].(string)
+		groups := strings.Join(repoGroupsFromMap(repoMap), ",")
+		if groups == "" {
+			groups = "-"
+		}
+		tmuxSession, _ := repoMap["tmux_session"].(string)
 
-			// Format status
-			var statusCell format.ColoredCell
-			if sessionHealthy {
-				statusCell = format.ColorCell(format.ColoredStatus(format.StatusHealthy), nil)
-			} else {
-				statusCell = format.ColorCell(format.ColoredStatus(format.StatusError), nil)
-			}
+		table.AddRow(
+			format.Cell(name),
+			format.ColorCell(groups, format.Dim),
+			format.Cell(repoAgentSummary(repoMap)),
+			repoStatusCell(repoMap),
+			format.ColorCell(tmuxSession, format.Dim),
+		)
+	}
+	table.Print()
 
-			table.AddRow(
-				format.Cell(name),
-				format.Cell(agentStr),
-				statusCell,
-				format.ColorCell(tmuxSession, format.Dim),
-			)
+	for _, repoMap := range repos {
+		if paused, _ := repoMap["refresh_paused"].(bool); paused {
+			name, _ := repoMap["name"].(string)
+			fmt.Printf("\n⚠ %s: main was force-pushed, worktree auto-refresh is paused\n", name)
+			format.Dimmed("  Resume with: multiclaude repo resume-refresh --repo %s", name)
+		}
+		if owner, _ := repoMap["locked_by"].(string); owner != "" {
+			name, _ := repoMap["name"].(string)
+			fmt.Printf("\n⚠ %s: managed by another daemon (%s), read-only here\n", name, owner)
+			format.Dimmed("  If that daemon is gone: multiclaude repo lock --repo %s --take-over", name)
 		}
 	}
-	table.Print()
 
 	return nil
 }
 
-func (c *CLI) removeRepo(args []string) error {
-	var repoName string
-	if len(args) > 0 {
-		repoName = args[0]
-	} else {
-		// Interactive selection - list repos
-		client := socket.NewClient(c.paths.DaemonSock)
-		resp, err := client.Send(socket.Request{
-			Command: "list_repos",
-			Args: map[string]interface{}{
-				"rich": true,
-			},
-		})
+// ungroupedLabel is the heading used for repositories that belong to no group
+const ungroupedLabel = "(ungrouped)"
+
+// defaultStatusWatchInterval is how often `status --watch` refreshes
+const defaultStatusWatchInterval = 3 * time.Second
+
+// statusEventLimit is how many recent events the status dashboard shows
+const statusEventLimit = 10
+
+// statusDashboard is what `multiclaude status` shows: the daemon, each
+// repository's agents, and recent events
+type statusDashboard struct {
+	Daemon map[string]interface{} `json:"daemon"`
+	Repos  []statusRepo           `json:"repos"`
+	Events []interface{}          `json:"events"`
+}
+
+// statusRepo is a repository on the status dashboard. Workers carry their
+// git state from worker_status (branch, ahead, behind, changes) under "git".
+type statusRepo struct {
+	Name           string                   `json:"name"`
+	Groups         []string                 `json:"groups,omitempty"`
+	TmuxSession    string                   `json:"tmux_session"`
+	SessionHealthy bool                     `json:"session_healthy"`
+	Agents         []map[string]interface{} `json:"agents"`
+}
+
+// showStatus prints a dashboard of the daemon, the tracked repositories
+// organized by group with their agents, and recent events, optionally
+// refreshing it until interrupted
+func (c *CLI) showStatus(args []string) error {
+	flags, _ := ParseFlags(args)
+	filter := flags["group"]
+
+	if flags["all-hosts"] == "true" {
+		return c.showFleetStatus(filter, flags["json"] == "true")
+	}
+	outputJSON := flags["json"] == "true"
+
+	watch, ok := flags["watch"]
+	if !ok || watch == "false" {
+		dash, err := c.gatherStatus(filter)
 		if err != nil {
-			return errors.DaemonCommunicationFailed("listing repositories", err)
+			return err
 		}
-		if !resp.Success {
-			return errors.Wrap(errors.CategoryRuntime, "failed to list repos", fmt.Errorf("%s", resp.Error))
+		if outputJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(dash)
 		}
+		printStatus(dash, filter)
+		return nil
+	}
 
-		repos, _ := resp.Data.([]interface{})
-		items := reposToSelectableItems(repos)
-		if len(items) == 0 {
-			return errors.NoRepositoriesFound()
+	interval := defaultStatusWatchInterval
+	if watch != "true" {
+		d, err := time.ParseDuration(watch)
+		if err != nil || d < time.Second {
+			return errors.InvalidArgument("watch", watch, "a refresh interval of at least 1s, like 5s")
 		}
-		selected, err := SelectFromList("Select repository to remove:", items)
+		interval = d
+	}
+	// JSON is written as one document per refresh, for piping into other tools
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		dash, err := c.gatherStatus(filter)
 		if err != nil {
 			return err
 		}
-		if selected == "" {
-			fmt.Println("Cancelled")
-			return nil
+		if outputJSON {
+			if err := encoder.Encode(dash); err != nil {
+				return err
+			}
+		} else {
+			fmt.Print("\033[H\033[2J")
+			format.Dimmed("Every %s, updated %s. Press Ctrl-C to stop.", interval, time.Now().Format("15:04:05"))
+			fmt.Println()
+			printStatus(dash, filter)
 		}
-		repoName = selected
+		time.Sleep(interval)
 	}
+}
 
-	fmt.Printf("Removing repository '%s'...\n", repoName)
-
-	// Get repo info from daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
+// gatherStatus collects the status dashboard from the daemon. A daemon that
+// isn't running is reported in the dashboard rather than as an error.
+func (c *CLI) gatherStatus(group string) (*statusDashboard, error) {
+	dash := &statusDashboard{Repos: []statusRepo{}, Events: []interface{}{}}
+	resp, err := c.sendDaemonRequest("status", nil)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting repo info", err)
+		dash.Daemon = map[string]interface{}{"running": false}
+		return dash, nil
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
-	}
-
-	// Get list of agents
-	agents, _ := resp.Data.([]interface{})
+	dash.Daemon, _ = resp.Data.(map[string]interface{})
 
-	// Check for any workers with uncommitted changes
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			if agentType == "worker" || agentType == "review" {
-				wtPath, _ := agentMap["worktree_path"].(string)
-				if wtPath != "" {
-					hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
-					if err == nil && hasUncommitted {
-						agentName, _ := agentMap["name"].(string)
-						fmt.Printf("\nWarning: Agent '%s' has uncommitted changes!\n", agentName)
-						fmt.Println("Files may be lost if you continue.")
-						fmt.Print("Continue with removal? [y/N]: ")
+	repos, err := c.fetchRichRepos(group)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, repoMap := range repos {
+		repo := statusRepo{Groups: repoGroupsFromMap(repoMap), Agents: []map[string]interface{}{}}
+		repo.Name, _ = repoMap["name"].(string)
+		repo.TmuxSession, _ = repoMap["tmux_session"].(string)
+		repo.SessionHealthy, _ = repoMap["session_healthy"].(bool)
+		names[repo.Name] = true
 
-						var response string
-						fmt.Scanln(&response)
-						if response != "y" && response != "Y" {
-							fmt.Println("Removal cancelled")
-							return nil
-						}
-						break // Only ask once
-					}
+		agentsResp, err := c.sendDaemonRequest("list_agents", map[string]interface{}{"repo": repo.Name, "rich": true})
+		if err != nil {
+			return nil, err
+		}
+		gitState := make(map[string]interface{})
+		if workersResp, err := c.sendDaemonRequest("worker_status", map[string]interface{}{"repo": repo.Name}); err == nil {
+			workers, _ := workersResp.Data.([]interface{})
+			for _, w := range workers {
+				if worker, ok := w.(map[string]interface{}); ok {
+					name, _ := worker["name"].(string)
+					gitState[name] = worker
 				}
 			}
 		}
+		agents, _ := agentsResp.Data.([]interface{})
+		for _, a := range agents {
+			agent, ok := a.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := agent["name"].(string)
+			if git, ok := gitState[name]; ok {
+				agent["git"] = git
+			}
+			repo.Agents = append(repo.Agents, agent)
+		}
+		sort.Slice(repo.Agents, func(i, j int) bool {
+			ni, _ := repo.Agents[i]["name"].(string)
+			nj, _ := repo.Agents[j]["name"].(string)
+			return ni < nj
+		})
+		dash.Repos = append(dash.Repos, repo)
 	}
+	sort.Slice(dash.Repos, func(i, j int) bool { return dash.Repos[i].Name < dash.Repos[j].Name })
 
-	// Kill tmux session
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxClient := tmux.NewClient()
-	if exists, err := tmuxClient.HasSession(context.Background(), tmuxSession); err == nil && exists {
-		fmt.Printf("Killing tmux session: %s\n", tmuxSession)
-		if err := tmuxClient.KillSession(context.Background(), tmuxSession); err != nil {
-			fmt.Printf("Warning: failed to kill tmux session: %v\n", err)
+	// Events aren't filtered by group, so look further back when a group
+	// might hide most of them
+	limit := statusEventLimit
+	if group != "" {
+		limit = 20 * statusEventLimit
+	}
+	eventsResp, err := c.sendDaemonRequest("list_events", map[string]interface{}{"limit": limit})
+	if err != nil {
+		return nil, err
+	}
+	list, _ := eventsResp.Data.([]interface{})
+	for _, e := range list {
+		event, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if repo, _ := event["repo"].(string); group != "" && !names[repo] {
+			continue
 		}
+		dash.Events = append(dash.Events, event)
+	}
+	if len(dash.Events) > statusEventLimit {
+		dash.Events = dash.Events[len(dash.Events)-statusEventLimit:]
 	}
+	return dash, nil
+}
 
-	// Remove worktrees for all agents
-	repoPath := c.paths.RepoDir(repoName)
-	wt := worktree.NewManager(repoPath)
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			wtPath, _ := agentMap["worktree_path"].(string)
-			agentName, _ := agentMap["name"].(string)
-			if wtPath != "" && wtPath != repoPath {
-				fmt.Printf("Removing worktree for '%s': %s\n", agentName, wtPath)
-				if err := wt.Remove(wtPath, true); err != nil {
-					fmt.Printf("Warning: failed to remove worktree: %v\n", err)
-				}
+// printStatus prints the status dashboard
+func printStatus(dash *statusDashboard, filter string) {
+	if running, _ := dash.Daemon["running"].(bool); !running {
+		fmt.Printf("Daemon: %s\n", format.Red.Sprint("not running"))
+		format.Dimmed("\nStart it with: multiclaude start")
+		return
+	}
+	fmt.Printf("Daemon: %s (PID %v)  %v repos, %v agents", format.Green.Sprint("running"), dash.Daemon["pid"], dash.Daemon["repos"], dash.Daemon["agents"])
+	if depth, _ := dash.Daemon["merge_queue_depth"].(float64); depth > 0 {
+		fmt.Printf(", %d waiting to merge", int(depth))
+	}
+	fmt.Println()
+	fmt.Println()
+
+	if len(dash.Repos) == 0 {
+		if filter != "" {
+			fmt.Printf("No repositories in group '%s'\n", filter)
+			return
+		}
+		fmt.Println("No repositories tracked")
+		format.Dimmed("\nInitialize a repository with: multiclaude init <github-url>")
+		return
+	}
+
+	// Bucket repos by group; a repo in several groups appears under each
+	byGroup := make(map[string][]statusRepo)
+	for _, repo := range dash.Repos {
+		groups := repo.Groups
+		if len(groups) == 0 {
+			groups = []string{ungroupedLabel}
+		}
+		for _, g := range groups {
+			if filter != "" && g != filter {
+				continue
 			}
+			byGroup[g] = append(byGroup[g], repo)
 		}
 	}
 
-	// Remove the worktrees directory for this repo
-	wtDir := c.paths.WorktreeDir(repoName)
-	if _, err := os.Stat(wtDir); err == nil {
-		fmt.Printf("Removing worktrees directory: %s\n", wtDir)
-		if err := os.RemoveAll(wtDir); err != nil {
-			fmt.Printf("Warning: failed to remove worktrees directory: %v\n", err)
+	groupNames := make([]string, 0, len(byGroup))
+	for g := range byGroup {
+		if g != ungroupedLabel {
+			groupNames = append(groupNames, g)
 		}
 	}
+	sort.Strings(groupNames)
+	if _, ok := byGroup[ungroupedLabel]; ok {
+		groupNames = append(groupNames, ungroupedLabel)
+	}
 
-	// Clean up messages directory for this repo
-	msgDir := filepath.Join(c.paths.MessagesDir, repoName)
-	if _, err := os.Stat(msgDir); err == nil {
-		fmt.Printf("Removing messages directory: %s\n", msgDir)
-		if err := os.RemoveAll(msgDir); err != nil {
-			fmt.Printf("Warning: failed to remove messages directory: %v\n", err)
+	for _, g := range groupNames {
+		members := byGroup[g]
+		totalWorkers := 0
+		for _, repo := range members {
+			for _, agent := range repo.Agents {
+				if agentType, _ := agent["type"].(string); agentType == string(state.AgentTypeWorker) {
+					totalWorkers++
+				}
+			}
 		}
+		format.Header("%s (%d repos, %d workers)", g, len(members), totalWorkers)
+		for _, repo := range members {
+			printStatusRepo(repo)
+		}
+		fmt.Println()
 	}
 
-	// Unregister from daemon
-	resp, err = client.Send(socket.Request{
-		Command: "remove_repo",
-		Args: map[string]interface{}{
-			"name": repoName,
-		},
-	})
-	if err != nil {
-		return errors.DaemonCommunicationFailed("removing repo", err)
+	for _, repo := range dash.Repos {
+		drifted := false
+		for _, agent := range repo.Agents {
+			if cwd, _ := agent["cwd_drift"].(string); cwd != "" {
+				name, _ := agent["name"].(string)
+				fmt.Printf("⚠ %s/%s: working in %s, outside its worktree\n", repo.Name, name, cwd)
+				drifted = true
+			}
+		}
+		if drifted {
+			format.Dimmed("  The daemon has asked them to return; check with: multiclaude attach <agent> --repo %s\n", repo.Name)
+		}
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to remove repo from state", fmt.Errorf("%s", resp.Error))
+
+	format.Header("Recent events")
+	if len(dash.Events) == 0 {
+		format.Dimmed("No events yet")
+		return
 	}
+	printEventTable(dash.Events)
+}
 
-	fmt.Println("✓ Repository removed successfully")
-	fmt.Printf("\nNote: The cloned repository at '%s' was NOT deleted.\n", repoPath)
-	fmt.Println("Delete it manually if you no longer need it.")
-	return nil
+// agentStatusCell formats the status of a rich list_agents entry
+func agentStatusCell(agent map[string]interface{}) format.ColoredCell {
+	status, _ := agent["status"].(string)
+	switch {
+	case agent["question"] != nil:
+		return format.ColorCell("waiting for answer", format.Yellow)
+	case status == "completed", status == "idle":
+		return format.ColorCell(status, format.Dim)
+	case status != "running":
+		return format.ColorCell(status, format.Red)
+	}
+	return format.ColorCell(status, format.Green)
 }
 
-func (c *CLI) setCurrentRepo(args []string) error {
-	if len(args) < 1 {
-		return errors.InvalidUsage("usage: multiclaude repo use <name>")
-	}
+// printStatusRepo prints a repository's line and agent table on the status
+// dashboard
+func printStatusRepo(repo statusRepo) {
+	health := format.ColoredStatus(format.StatusHealthy)
+	if !repo.SessionHealthy {
+		health = format.ColoredStatus(format.StatusError)
+	}
+	fmt.Printf("\n%s  %s  %s\n", format.Bold.Sprint(repo.Name), health, format.Dim.Sprint(repo.TmuxSession))
+	if len(repo.Agents) == 0 {
+		format.Dimmed("  No agents")
+		return
+	}
+
+	table := format.NewColoredTable("NAME", "TYPE", "STATUS", "BRANCH", "AHEAD", "BEHIND", "CHANGES", "MESSAGES", "TASK")
+	none := format.ColorCell("-", format.Dim)
+	for _, agent := range repo.Agents {
+		name, _ := agent["name"].(string)
+		agentType, _ := agent["type"].(string)
+		branch, _ := agent["branch"].(string)
+		task, _ := agent["task"].(string)
+		task, _, _ = strings.Cut(task, "\n")
+		statusCell := agentStatusCell(agent)
+
+		ahead, behind, changes := none, none, none
+		if git, ok := agent["git"].(map[string]interface{}); ok {
+			if errMsg, _ := git["error"].(string); errMsg == "" {
+				a, _ := git["ahead"].(float64)
+				b, _ := git["behind"].(float64)
+				ahead = format.Cell(strconv.Itoa(int(a)))
+				behind = format.Cell(strconv.Itoa(int(b)))
+				if b > 0 {
+					behind = format.ColorCell(strconv.Itoa(int(b)), format.Yellow)
+				}
+				changes = format.ColorCell("clean", format.Dim)
+				if uncommitted, _ := git["uncommitted"].(bool); uncommitted {
+					changes = format.ColorCell("uncommitted", format.Yellow)
+				}
+				if gitState, _ := git["git_state"].(string); gitState != "" {
+					changes = format.ColorCell(gitState, format.Yellow)
+				}
+			}
+		}
 
-	repoName := args[0]
+		messages := none
+		pending, _ := agent["messages_pending"].(float64)
+		total, _ := agent["messages_total"].(float64)
+		if total > 0 {
+			messages = format.ColorCell(fmt.Sprintf("%d/%d", int(pending), int(total)), format.Dim)
+			if pending > 0 {
+				messages = format.ColorCell(fmt.Sprintf("%d/%d", int(pending), int(total)), format.Yellow)
+			}
+		}
 
-	_, err := c.sendDaemonRequest("set_current_repo", map[string]interface{}{
-		"name": repoName,
-	})
-	if err != nil {
-		return err
+		branchCell := none
+		if branch != "" {
+			branchCell = format.ColorCell(branch, format.Cyan)
+		}
+		table.AddRow(
+			format.Cell(name),
+			format.ColorCell(agentType, format.Dim),
+			statusCell,
+			branchCell,
+			ahead,
+			behind,
+			changes,
+			messages,
+			format.Cell(format.Truncate(task, 40)),
+		)
 	}
+	table.Print()
+}
 
-	fmt.Printf("Current repository set to: %s\n", repoName)
-	return nil
+// uiRefreshInterval is how often `multiclaude ui` reloads agents
+const uiRefreshInterval = 2 * time.Second
+
+// runUI starts the interactive terminal UI
+func (c *CLI) runUI(args []string) error {
+	return tui.Run(&uiBackend{c: c}, uiRefreshInterval)
 }
 
-func (c *CLI) getCurrentRepo(args []string) error {
-	resp, err := c.sendDaemonRequest("get_current_repo", nil)
+// uiBackend gives the terminal UI the status dashboard's data and carries
+// out its actions with the matching CLI commands
+type uiBackend struct {
+	c *CLI
+}
+
+func (b *uiBackend) Snapshot() (tui.Snapshot, error) {
+	dash, err := b.c.gatherStatus("")
 	if err != nil {
-		return err
-	}
+		return tui.Snapshot{}, err
+	}
+	snap := tui.Snapshot{}
+	snap.DaemonRunning, _ = dash.Daemon["running"].(bool)
+	for _, repo := range dash.Repos {
+		snap.Repos = append(snap.Repos, repo.Name)
+		for _, info := range repo.Agents {
+			agent := tui.Agent{Repo: repo.Name, Session: repo.TmuxSession}
+			agent.Name, _ = info["name"].(string)
+			agent.Type, _ = info["type"].(string)
+			agent.Status, _ = info["status"].(string)
+			agent.Branch, _ = info["branch"].(string)
+			agent.Task, _ = info["task"].(string)
+			agent.Question, _ = info["question"].(string)
+			agent.Window, _ = info["tmux_window"].(string)
+			pending, _ := info["messages_pending"].(float64)
+			total, _ := info["messages_total"].(float64)
+			agent.PendingMessages, agent.TotalMessages = int(pending), int(total)
+			snap.Agents = append(snap.Agents, agent)
+		}
+	}
+	return snap, nil
+}
 
-	currentRepo, _ := resp.Data.(string)
-	if currentRepo == "" {
-		fmt.Println("No current repository set")
-		fmt.Println("\nUse 'multiclaude repo use <name>' to set one")
-	} else {
-		fmt.Printf("Current repository: %s\n", currentRepo)
+// Preview asks the daemon for the agent's screen, which works whichever
+// multiplexer it runs agents in
+func (b *uiBackend) Preview(agent tui.Agent, lines int) (string, error) {
+	screen, err := socket.Call[socket.AgentScreen](b.c.daemonClient(), "agent_screen", socket.AgentScreenArgs{
+		Repo: agent.Repo, Agent: agent.Name, Lines: lines,
+	})
+	if err != nil {
+		return "", err
 	}
-	return nil
+	return screen.Screen, nil
 }
 
-func (c *CLI) clearCurrentRepo(args []string) error {
-	_, err := c.sendDaemonRequest("clear_current_repo", nil)
+func (b *uiBackend) Messages(agent tui.Agent) ([]tui.Message, error) {
+	msgs, err := messages.NewManager(b.c.paths.MessagesDir).List(agent.Repo, agent.Name)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	list := make([]tui.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		list = append(list, tui.Message{From: msg.From, Status: string(msg.Status), Body: msg.Body, Time: msg.Timestamp})
 	}
+	return list, nil
+}
 
-	fmt.Println("Current repository cleared")
-	return nil
+func (b *uiBackend) Respond(agent tui.Agent, text string) error {
+	_, err := b.c.sendDaemonRequest("respond_agent", map[string]interface{}{
+		"repo":  agent.Repo,
+		"agent": agent.Name,
+		"text":  text,
+	})
+	return err
 }
 
-func (c *CLI) configRepo(args []string) error {
-	flags, posArgs := ParseFlags(args)
+func (b *uiBackend) Attach(agent tui.Agent) error {
+	return b.c.attachAgent([]string{agent.Name, "--repo", agent.Repo})
+}
 
-	// Determine repository
-	var repoName string
-	if len(posArgs) >= 1 {
-		repoName = posArgs[0]
-	} else {
-		// Try to infer from current directory
-		cwd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
-		}
+func (b *uiBackend) Kill(agent tui.Agent) error {
+	return b.c.removeWorker([]string{agent.Name, "--repo", agent.Repo})
+}
 
-		// Check if we're in a tracked repo
-		repos := c.getReposList()
-		for _, repo := range repos {
-			repoPath := c.paths.RepoDir(repo)
-			if strings.HasPrefix(cwd, repoPath) {
-				repoName = repo
-				break
-			}
-		}
+func (b *uiBackend) Spawn(repo, task string) error {
+	return b.c.createWorker([]string{"--repo", repo, task})
+}
 
-		if repoName == "" {
-			// If only one repo exists, use it
-			if len(repos) == 1 {
-				repoName = repos[0]
-			} else {
-				return fmt.Errorf("please specify a repository name or run from within a tracked repository")
-			}
-		}
+// fetchRichRepos returns detailed repository info from the daemon,
+// optionally restricted to a single group
+func (c *CLI) fetchRichRepos(group string) ([]map[string]interface{}, error) {
+	args := map[string]interface{}{
+		"rich": true,
 	}
-
-	// Check if any config flags are provided
-	hasMqEnabled := flags["mq-enabled"] != ""
-	hasMqTrack := flags["mq-track"] != ""
-
-	if !hasMqEnabled && !hasMqTrack {
-		// No flags - just show current config
-		return c.showRepoConfig(repoName)
+	if group != "" {
+		args["group"] = group
 	}
-
-	// Apply config changes
-	return c.updateRepoConfig(repoName, flags)
-}
-
-func (c *CLI) showRepoConfig(repoName string) error {
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "get_repo_config",
-		Args: map[string]interface{}{
-			"name": repoName,
-		},
-	})
+	resp, err := c.sendDaemonRequest("list_repos", args)
 	if err != nil {
-		return fmt.Errorf("failed to get repo config: %w (is daemon running?)", err)
-	}
-
-	if !resp.Success {
-		return fmt.Errorf("failed to get repo config: %s", resp.Error)
+		return nil, err
 	}
 
-	// Parse response
-	configMap, ok := resp.Data.(map[string]interface{})
+	repos, ok := resp.Data.([]interface{})
 	if !ok {
-		return fmt.Errorf("unexpected response format")
+		return nil, errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
 	}
 
-	fmt.Printf("Configuration for repository: %s\n\n", repoName)
-	fmt.Println("Merge Queue:")
+	result := make([]map[string]interface{}, 0, len(repos))
+	for _, repo := range repos {
+		if repoMap, ok := repo.(map[string]interface{}); ok {
+			result = append(result, repoMap)
+		}
+	}
+	return result, nil
+}
 
-	mqEnabled := true
-	if enabled, ok := configMap["mq_enabled"].(bool); ok {
-		mqEnabled = enabled
+// repoGroupsFromMap extracts the group list from a list_repos entry
+func repoGroupsFromMap(repoMap map[string]interface{}) []string {
+	raw, _ := repoMap["groups"].([]interface{})
+	groups := make([]string, 0, len(raw))
+	for _, g := range raw {
+		if name, ok := g.(string); ok {
+			groups = append(groups, name)
+		}
 	}
+	return groups
+}
 
-	mqTrackMode := "all"
-	if trackMode, ok := configMap["mq_track_mode"].(string); ok {
-		mqTrackMode = trackMode
+// repoAgentSummary formats the agent count of a list_repos entry
+func repoAgentSummary(repoMap map[string]interface{}) string {
+	totalAgents := 0
+	if v, ok := repoMap["total_agents"].(float64); ok {
+		totalAgents = int(v)
+	}
+	workerCount := 0
+	if v, ok := repoMap["worker_count"].(float64); ok {
+		workerCount = int(v)
+	}
+	if workerCount > 0 {
+		return fmt.Sprintf("%d (%d workers)", totalAgents, workerCount)
 	}
+	return fmt.Sprintf("%d total", totalAgents)
+}
 
-	if mqEnabled {
-		fmt.Printf("  Enabled: true\n")
-		fmt.Printf("  Track mode: %s\n", mqTrackMode)
-	} else {
-		fmt.Printf("  Enabled: false\n")
+// repoStatusCell formats the session health of a list_repos entry
+func repoStatusCell(repoMap map[string]interface{}) format.ColoredCell {
+	if sessionHealthy, _ := repoMap["session_healthy"].(bool); sessionHealthy {
+		return format.ColorCell(format.ColoredStatus(format.StatusHealthy), nil)
 	}
+	return format.ColorCell(format.ColoredStatus(format.StatusError), nil)
+}
 
-	fmt.Println("\nTo modify:")
-	fmt.Printf("  multiclaude config %s --mq-enabled=true|false\n", repoName)
-	fmt.Printf("  multiclaude config %s --mq-track=all|author|assigned\n", repoName)
+// relayDaemon is the remote end of fleet requests: another host pipes a
+// request to it over SSH and reads back this daemon's response
+func (c *CLI) relayDaemon(args []string) error {
+	return fleet.Relay(c.paths.DaemonSock, os.Stdin, os.Stdout)
+}
 
+func (c *CLI) addHost(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	address := flags["address"]
+	if len(posArgs) < 1 || len(posArgs) > 2 || (len(posArgs) == 1 && address == "") {
+		return errors.InvalidUsage("usage: multiclaude hosts add <name> <ssh-target> | <name> --address <host:port> [--token-file <path>] [--ca-cert <path>] [--plaintext]")
+	}
+	name := posArgs[0]
+	host := fleet.Host{
+		Name:      name,
+		Address:   address,
+		TokenFile: flags["token-file"],
+		CACert:    flags["ca-cert"],
+		Plaintext: flags["plaintext"] == "true",
+	}
+	if len(posArgs) == 2 {
+		host.SSH = posArgs[1]
+	}
+	if address != "" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return errors.InvalidArgument("--address", address, "host:port of the daemon's TCP socket")
+		}
+	}
+	for _, path := range []*string{&host.TokenFile, &host.CACert} {
+		if *path != "" {
+			abs, err := filepath.Abs(*path)
+			if err != nil {
+				return err
+			}
+			*path = abs
+		}
+	}
+	if name == fleet.LocalHost {
+		return errors.InvalidArgument("name", name, "a name other than 'local', which refers to this machine")
+	}
+	hosts, err := fleet.LoadHosts(c.paths.HostsFile())
+	if err != nil {
+		return errors.Wrap(errors.CategoryConfig, "failed to load hosts", err)
+	}
+	if _, exists := fleet.Find(hosts, name); exists {
+		return errors.New(errors.CategoryUsage, fmt.Sprintf("host '%s' already exists", name))
+	}
+	hosts = append(hosts, host)
+	if err := fleet.SaveHosts(c.paths.HostsFile(), hosts); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to save hosts", err)
+	}
+	fmt.Printf("Added host %s (%s)\n", name, host.Target())
+	format.Dimmed("Check it with: multiclaude hosts list")
 	return nil
 }
 
-func (c *CLI) updateRepoConfig(repoName string, flags map[string]string) error {
-	// Build update args
-	updateArgs := map[string]interface{}{
-		"name": repoName,
+func (c *CLI) removeHost(args []string) error {
+	if len(args) != 1 {
+		return errors.InvalidUsage("usage: multiclaude hosts remove <name>")
 	}
-
-	// Parse and validate flags
-	if mqEnabled, ok := flags["mq-enabled"]; ok {
-		switch mqEnabled {
-		case "true":
-			updateArgs["mq_enabled"] = true
-		case "false":
-			updateArgs["mq_enabled"] = false
-		default:
-			return fmt.Errorf("invalid --mq-enabled value: %s (must be 'true' or 'false')", mqEnabled)
+	hosts, err := fleet.LoadHosts(c.paths.HostsFile())
+	if err != nil {
+		return errors.Wrap(errors.CategoryConfig, "failed to load hosts", err)
+	}
+	kept := hosts[:0]
+	for _, h := range hosts {
+		if h.Name != args[0] {
+			kept = append(kept, h)
 		}
 	}
+	if len(kept) == len(hosts) {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("host '%s' not found", args[0]))
+	}
+	if err := fleet.SaveHosts(c.paths.HostsFile(), kept); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to save hosts", err)
+	}
+	fmt.Printf("Removed host %s\n", args[0])
+	return nil
+}
 
-	if mqTrack, ok := flags["mq-track"]; ok {
-		switch mqTrack {
-		case "all", "author", "assigned":
-			updateArgs["mq_track_mode"] = mqTrack
-		default:
-			return fmt.Errorf("invalid --mq-track value: %s (must be 'all', 'author', or 'assigned')", mqTrack)
+// queueOnHost gives a task to a host reached on its daemon's TCP socket.
+// Worktrees can only be created on the daemon's machine, so the task goes
+// through the host's task queue, which starts a worker for it with the
+// repository's default settings as soon as there is room.
+func (c *CLI) queueOnHost(host fleet.Host, repoName, task string, flags map[string]string) error {
+	for _, name := range []string{"branch", "push-to", "base", "path", "allowed-paths", "git-token-file", "replay", "headless", "name"} {
+		if _, ok := flags[name]; ok {
+			return errors.InvalidUsage(fmt.Sprintf("--%s needs host %s to be reached over SSH (multiclaude hosts add <name> <ssh-target>)", name, host.Name))
 		}
 	}
+	var priority string
+	if raw, ok := flags["priority"]; ok {
+		p, err := state.ParseTaskPriority(raw)
+		if err != nil {
+			return errors.InvalidArgument("--priority", raw, "P0, P1, P2, or P3")
+		}
+		priority = string(p)
+	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "update_repo_config",
-		Args:    updateArgs,
+	resp, err := remoteDaemon{host: host}.Send(socket.Request{
+		Command: "add_task",
+		Args:    map[string]interface{}{"repo": repoName, "description": task, "priority": priority},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to update repo config: %w (is daemon running?)", err)
+		return errors.DaemonCommunicationFailed("add_task", err)
 	}
-
 	if !resp.Success {
-		return fmt.Errorf("failed to update repo config: %s", resp.Error)
+		return fmt.Errorf("add_task failed: %s", resp.Error)
 	}
+	data, _ := resp.Data.(map[string]interface{})
+	fmt.Printf("✓ Queued task %v on host %s in %s\n", data["id"], host.Name, repoName)
+	format.Dimmed("Its daemon starts a worker as soon as %s has room. Follow it with: multiclaude task list --repo %s --host %s", repoName, repoName, host.Name)
+	return nil
+}
 
-	fmt.Printf("Configuration updated for repository: %s\n", repoName)
-
-	// Show the updated config
-	return c.showRepoConfig(repoName)
+// queueInsteadOfWorker queues a task for `multiclaude work --queue` when
+// the repo is at its worker limit
+func (c *CLI) queueInsteadOfWorker(repoName, task string, priority state.TaskPriority, capacity map[string]interface{}) error {
+	resp, err := c.sendDaemonRequest("add_task", map[string]interface{}{
+		"repo":        repoName,
+		"description": task,
+		"priority":    string(priority),
+	})
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	fmt.Printf("%s has %v of %v workers running; queued task %v instead\n", repoName, capacity["running"], capacity["max_workers"], data["id"])
+	format.Dimmed("It starts when a worker finishes, with the repository's default settings. Follow it with: multiclaude task list --repo %s", repoName)
+	return nil
 }
 
-func (c *CLI) createWorker(args []string) error {
+// addTask queues a task. The daemon spawns a worker for it once fewer than
+// the repository's queue_workers queued tasks are running.
+func (c *CLI) addTask(args []string) error {
 	flags, posArgs := ParseFlags(args)
-
-	// Get task description
-	task := strings.Join(posArgs, " ")
-	if task == "" {
-		return errors.InvalidUsage("usage: multiclaude work <task description>")
+	description := strings.Join(posArgs, " ")
+	if description == "" {
+		return errors.InvalidUsage("usage: multiclaude task add <description> [--priority urgent|high|normal|low|P0-P3]")
+	}
+	if p, ok := flags["priority"]; ok {
+		if _, err := state.ParseTaskPriorityName(p); err != nil {
+			return errors.InvalidArgument("--priority", p, "urgent, high, normal, low, or P0-P3")
+		}
 	}
 
-	// Determine repository
 	repoName, err := c.resolveRepo(flags)
 	if err != nil {
 		return errors.NotInRepo()
 	}
 
-	// Generate worker name (Docker-style)
-	workerName := names.Generate()
-	if name, ok := flags["name"]; ok {
-		workerName = name
-	}
-
-	// Check for --push-to flag (for iterating on existing PRs)
-	pushTo, hasPushTo := flags["push-to"]
-	if hasPushTo {
-		// --push-to requires --branch to specify the remote branch to start from
-		if _, hasBranch := flags["branch"]; !hasBranch {
-			return errors.InvalidUsage("--push-to requires --branch to specify the remote branch (e.g., --branch origin/work/jolly-hawk --push-to work/jolly-hawk)")
-		}
+	resp, err := c.sendDaemonRequest("add_task", map[string]interface{}{
+		"repo":        repoName,
+		"description": description,
+		"priority":    flags["priority"],
+	})
+	if err != nil {
+		return err
 	}
+	data, _ := resp.Data.(map[string]interface{})
+	fmt.Printf("✓ Queued task %v (%v) in %s\n", data["id"], data["priority"], repoName)
+	format.Dimmed("Follow it with: multiclaude task list --repo %s", repoName)
+	return nil
+}
 
-	// Get repository path
-	repoPath := c.paths.RepoDir(repoName)
-
-	// Fetch latest from origin before creating worktree
-	// This ensures workers start from the latest code, not stale local refs
-	// Note: We use "git fetch origin main" (not "main:main") because the latter
-	// fails when main is checked out in the bare repo with:
-	// "fatal: refusing to fetch into branch 'refs/heads/main' checked out at ..."
-	fmt.Println("Fetching latest from origin...")
-	fetchCmd := exec.Command("git", "fetch", "origin")
-	fetchCmd.Dir = repoPath
-	if err := fetchCmd.Run(); err != nil {
-		// Best effort - don't fail if offline or fetch fails
-		fmt.Printf("Warning: failed to fetch from origin: %v (continuing with local refs)\n", err)
+// listTasks shows a repository's task queue
+func (c *CLI) listTasks(args []string) error {
+	flags, _ := ParseFlags(args)
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
 	}
 
-	// Determine branch to start from
-	// Prefer origin/main if it exists (updated by fetch), otherwise fall back to HEAD
-	// This handles both normal repos and test repos without remotes
-	startBranch := "HEAD"
-	checkOriginCmd := exec.Command("git", "rev-parse", "--verify", "origin/main")
-	checkOriginCmd.Dir = repoPath
-	if err := checkOriginCmd.Run(); err == nil {
-		startBranch = "origin/main"
+	resp, err := c.sendDaemonRequest("list_tasks", map[string]interface{}{"repo": repoName})
+	if err != nil {
+		return err
 	}
-	if branch, ok := flags["branch"]; ok {
-		startBranch = branch
-		if hasPushTo {
-			fmt.Printf("Creating worker '%s' in repo '%s' to iterate on branch '%s'\n", workerName, repoName, pushTo)
-		} else {
-			fmt.Printf("Creating worker '%s' in repo '%s' from branch '%s'\n", workerName, repoName, branch)
-		}
-	} else {
-		fmt.Printf("Creating worker '%s' in repo '%s'\n", workerName, repoName)
+	data, _ := resp.Data.(map[string]interface{})
+	tasks, _ := data["tasks"].([]interface{})
+	if len(tasks) == 0 {
+		fmt.Printf("No tasks queued in %s\n", repoName)
+		format.Dimmed("\nQueue one with: multiclaude task add <description> --repo %s", repoName)
+		return nil
 	}
-	fmt.Printf("Task: %s\n", task)
 
-	// Create worktree
-	wt := worktree.NewManager(repoPath)
-	wtPath := c.paths.AgentWorktree(repoName, workerName)
-
-	var branchName string
-	if hasPushTo {
-		// When --push-to is specified, we're iterating on an existing PR branch
-		// Create a worktree that checks out the remote branch into a local branch
-		branchName = pushTo
-		fmt.Printf("Creating worktree at: %s (checking out %s)\n", wtPath, startBranch)
-		// Use git worktree add with -b to create local branch tracking the remote
-		if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-			return errors.WorktreeCreationFailed(err)
+	statuses := map[string]format.Status{
+		string(state.QueuedTaskQueued):     format.StatusPending,
+		string(state.QueuedTaskAssigned):   format.StatusRunning,
+		string(state.QueuedTaskInProgress): format.StatusRunning,
+		string(state.QueuedTaskDone):       format.StatusCompleted,
+		string(state.QueuedTaskFailed):     format.StatusError,
+	}
+	format.Header("Tasks in %s (up to %v running at once):", repoName, data["workers"])
+	table := format.NewColoredTable("ID", "STATE", "PRIORITY", "WORKER", "ADDED", "TASK")
+	var failures []string
+	for _, item := range tasks {
+		task, _ := item.(map[string]interface{})
+		id, _ := task["id"].(string)
+		taskState, _ := task["state"].(string)
+		priority, _ := task["priority"].(string)
+		agent, _ := task["agent"].(string)
+		description, _ := task["description"].(string)
+		added := ""
+		if created, _ := task["created_at"].(string); created != "" {
+			if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+				added = format.TimeAgo(t)
+			}
 		}
-	} else {
-		// Normal case: create a new branch for this worker
-		branchName = fmt.Sprintf("work/%s", workerName)
-		fmt.Printf("Creating worktree at: %s\n", wtPath)
-		if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-			return errors.WorktreeCreationFailed(err)
+		table.AddRow(
+			format.Cell(id),
+			format.ColorCell(taskState, format.StatusColor(statuses[taskState])),
+			format.Cell(priority),
+			format.Cell(agent),
+			format.Cell(added),
+			format.Cell(format.Truncate(description, 60)),
+		)
+		if msg, _ := task["error"].(string); msg != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", id, msg))
 		}
 	}
+	table.Print()
+	for _, f := range failures {
+		format.Dimmed("%s", f)
+	}
+	return nil
+}
 
-	// Get repository info to determine tmux session
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
-	if err != nil {
-		return errors.DaemonCommunicationFailed("getting repo info", err)
+// cancelTask removes a task that is still waiting in the queue
+func (c *CLI) cancelTask(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude task cancel <task-id>")
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
 	}
 
-	// Get tmux session name (it's mc-<reponame>)
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	if _, err := c.sendDaemonRequest("cancel_task", map[string]interface{}{
+		"repo": repoName,
+		"id":   posArgs[0],
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Canceled task %s\n", posArgs[0])
+	return nil
+}
 
-	// Ensure tmux session exists before creating window
-	// This handles cases where the session was killed or daemon didn't restore it
-	tmuxClient := tmux.NewClient()
-	hasSession, err := tmuxClient.HasSession(context.Background(), tmuxSession)
+func (c *CLI) listHosts(args []string) error {
+	hosts, err := fleet.LoadHosts(c.paths.HostsFile())
 	if err != nil {
-		return errors.TmuxOperationFailed("check session", err)
+		return errors.Wrap(errors.CategoryConfig, "failed to load hosts", err)
+	}
+	if len(hosts) == 0 {
+		fmt.Println("No hosts configured")
+		format.Dimmed("\nAdd one with: multiclaude hosts add <name> <ssh-target>")
+		return nil
 	}
-	if !hasSession {
-		fmt.Printf("Tmux session '%s' not found, creating it...\n", tmuxSession)
-		if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
-			return errors.TmuxOperationFailed("create session", err)
+	results := fleet.NewClient().SendAll(context.Background(), hosts, socket.Request{Command: "ping"})
+	table := format.NewColoredTable("HOST", "REACHED AT", "DAEMON")
+	for i, h := range hosts {
+		status := format.ColorCell(format.ColoredStatus(format.StatusHealthy), nil)
+		if results[i].Err != nil {
+			status = format.ColorCell(format.ColoredStatus(format.StatusError), nil)
+		}
+		target := h.SSH
+		if h.Address != "" {
+			target = "tcp " + h.Address
 		}
+		table.AddRow(format.Cell(h.Name), format.Cell(target), status)
 	}
-
-	// Create tmux window for worker (detached so it doesn't switch focus)
-	fmt.Printf("Creating tmux window: %s\n", workerName)
-	cmd := exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", workerName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
-		return errors.TmuxOperationFailed("create window", err)
+	table.Print()
+	for _, r := range results {
+		if r.Err != nil {
+			format.Dimmed("%v", r.Err)
+		}
 	}
+	return nil
+}
 
-	// Generate session ID for worker
-	workerSessionID, err := claude.GenerateSessionID()
+// fetchFleet asks the daemon for the repositories and agents of every
+// host's daemon, itself first
+func (c *CLI) fetchFleet(group string) (socket.FleetStatus, error) {
+	status, err := socket.Call[socket.FleetStatus](c.daemonClient(), "fleet_status", socket.FleetStatusArgs{Group: group})
 	if err != nil {
-		return fmt.Errorf("failed to generate worker session ID: %w", err)
+		return socket.FleetStatus{}, errors.DaemonCommunicationFailed("fleet_status", err)
 	}
+	return status, nil
+}
 
-	// Write prompt file for worker (with push-to config if specified)
-	workerConfig := WorkerConfig{}
-	if hasPushTo {
-		workerConfig.PushToBranch = pushTo
-	}
-	workerPromptFile, err := c.writeWorkerPromptFile(repoPath, workerName, workerConfig)
+// showFleetStatus prints the repositories and agents of every host's daemon
+func (c *CLI) showFleetStatus(group string, outputJSON bool) error {
+	status, err := c.fetchFleet(group)
 	if err != nil {
-		return fmt.Errorf("failed to write worker prompt: %w", err)
+		return err
 	}
-
-	// Copy hooks configuration if it exists
-	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
-		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	if outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
 	}
 
-	// Start Claude in worker window with initial task (skip in test mode)
-	var workerPID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary
-		claudeBinary, err := c.getClaudeBinary()
-		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
-		}
-
-		fmt.Println("Starting Claude Code in worker window...")
-		initialMessage := fmt.Sprintf("Task: %s", task)
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workerName, wtPath, workerSessionID, workerPromptFile, repoName, initialMessage)
-		if err != nil {
-			return fmt.Errorf("failed to start worker Claude: %w", err)
+	repoTable := format.NewColoredTable("HOST", "REPO", "AGENTS", "STATUS")
+	agentTable := format.NewColoredTable("HOST", "REPO", "AGENT", "TYPE", "STATUS")
+	repoRows, agentRows := 0, 0
+	for _, h := range status.Hosts {
+		sort.Slice(h.Repos, func(a, b int) bool {
+			na, _ := h.Repos[a]["name"].(string)
+			nb, _ := h.Repos[b]["name"].(string)
+			return na < nb
+		})
+		for _, repoMap := range h.Repos {
+			name, _ := repoMap["name"].(string)
+			repoTable.AddRow(format.Cell(h.Host), format.Cell(name), format.Cell(repoAgentSummary(repoMap)), repoStatusCell(repoMap))
+			repoRows++
 		}
-		workerPID = pid
-
-		// Set up output capture for worker
-		if err := c.setupOutputCapture(tmuxSession, workerName, repoName, workerName, "worker"); err != nil {
-			fmt.Printf("Warning: failed to setup output capture for worker: %v\n", err)
+		for _, agent := range h.Agents {
+			repo, _ := agent["repo"].(string)
+			name, _ := agent["name"].(string)
+			agentType, _ := agent["type"].(string)
+			agentTable.AddRow(format.Cell(h.Host), format.Cell(repo), format.Cell(name), format.Cell(agentType), agentStatusCell(agent))
+			agentRows++
 		}
 	}
 
-	// Register worker with daemon
-	resp, err = client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         workerName,
-			"type":          "worker",
-			"worktree_path": wtPath,
-			"tmux_window":   workerName,
-			"task":          task,
-			"session_id":    workerSessionID,
-			"pid":           workerPID,
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to register worker: %w", err)
+	format.Header("Fleet (%d hosts, %d agents, %d workers):", len(status.Hosts), status.Agents, status.Workers)
+	if repoRows == 0 {
+		fmt.Println("No repositories tracked")
+	} else {
+		repoTable.Print()
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register worker: %s", resp.Error)
+	if agentRows > 0 {
+		fmt.Println()
+		agentTable.Print()
 	}
-
-	fmt.Println()
-	fmt.Println("✓ Worker created successfully!")
-	fmt.Printf("  Name: %s\n", workerName)
-	fmt.Printf("  Branch: %s\n", branchName)
-	fmt.Printf("  Worktree: %s\n", wtPath)
-	if hasPushTo {
-		fmt.Printf("  Mode: Push to existing PR branch (%s)\n", pushTo)
+	for _, h := range status.Hosts {
+		if h.Error != "" {
+			fmt.Println()
+			format.Dimmed("%s unreachable: %s", h.Host, h.Error)
+		}
 	}
-	fmt.Printf("\nAttach to worker: tmux select-window -t %s:%s\n", tmuxSession, workerName)
-	fmt.Printf("Or use: multiclaude attach %s\n", workerName)
-
 	return nil
 }
 
-func (c *CLI) listWorkers(args []string) error {
-	flags, _ := ParseFlags(args)
-
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
+// pickWorkHost resolves work --host: a host name, local, or auto for the
+// least-loaded host tracking the repository. remote is false when the worker
+// should be created on this machine.
+func (c *CLI) pickWorkHost(repoName, target string) (host fleet.Host, remote bool, err error) {
+	if target == fleet.LocalHost {
+		return fleet.Host{Name: fleet.LocalHost}, false, nil
 	}
-
-	resp, err := c.sendDaemonRequest("list_agents", map[string]interface{}{
-		"repo": repoName,
-		"rich": true,
-	})
-	if err != nil {
-		return err
+	if target != "auto" {
+		host, err := c.resolveHost(target)
+		if err != nil {
+			return fleet.Host{}, false, err
+		}
+		return host, true, nil
 	}
 
-	agents, ok := resp.Data.([]interface{})
-	if !ok {
-		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	status, err := c.fetchFleet("")
+	if err != nil {
+		return fleet.Host{}, false, err
 	}
-
-	// Filter for workers and workspace
-	workers := []map[string]interface{}{}
-	var workspace map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			if agentType == "worker" {
-				workers = append(workers, agentMap)
-			} else if agentType == "workspace" {
-				workspace = agentMap
+	var loads []fleet.Load
+	for _, h := range status.Hosts {
+		for _, repoMap := range h.Repos {
+			if name, _ := repoMap["name"].(string); name != repoName {
+				continue
 			}
+			workers, _ := repoMap["worker_count"].(float64)
+			loads = append(loads, fleet.Load{Host: h.Host, Workers: int(workers)})
 		}
 	}
-
-	// Show workspace first if it exists
-	if workspace != nil {
-		format.Header("Workspace in '%s':", repoName)
-		status, _ := workspace["status"].(string)
-		statusCell := formatAgentStatusCell(status)
-		fmt.Printf("  workspace ")
-		fmt.Print(statusCell.Text)
-		fmt.Println()
-		fmt.Println()
-	}
-
-	if len(workers) == 0 {
-		fmt.Printf("No workers in repository '%s'\n", repoName)
-		format.Dimmed("\nCreate a worker with: multiclaude work <task>")
-		return nil
+	chosen, ok := fleet.LeastLoaded(loads)
+	if !ok {
+		return fleet.Host{}, false, errors.New(errors.CategoryNotFound, fmt.Sprintf("no reachable host tracks repository '%s'", repoName))
 	}
-
-	format.Header("Workers in '%s' (%d):", repoName, len(workers))
-	fmt.Println()
-
-	table := format.NewColoredTable("NAME", "STATUS", "BRANCH", "MSGS", "TASK")
-	for _, worker := range workers {
-		name, _ := worker["name"].(string)
-		task, _ := worker["task"].(string)
-		status, _ := worker["status"].(string)
-		branch, _ := worker["branch"].(string)
-		msgsTotal := 0
-		if v, ok := worker["messages_total"].(float64); ok {
-			msgsTotal = int(v)
-		}
-		msgsPending := 0
-		if v, ok := worker["messages_pending"].(float64); ok {
-			msgsPending = int(v)
-		}
-
-		// Format status with color
-		statusCell := formatAgentStatusCell(status)
-
-		// Format branch
-		branchCell := format.ColorCell(branch, format.Cyan)
-		if branch == "" {
-			branchCell = format.ColorCell("-", format.Dim)
-		}
-
-		// Format message count
-		msgStr := format.MessageBadge(msgsPending, msgsTotal)
-
-		// Truncate task
-		truncTask := format.Truncate(task, 40)
-
-		table.AddRow(
-			format.Cell(name),
-			statusCell,
-			branchCell,
-			format.Cell(msgStr),
-			format.Cell(truncTask),
-		)
+	if chosen == fleet.LocalHost {
+		return fleet.Host{Name: fleet.LocalHost}, false, nil
 	}
-	table.Print()
-
-	return nil
+	host, err = c.resolveHost(chosen)
+	return host, true, err
 }
 
-// listAgentDefinitions lists available agent definitions for a repository
-func (c *CLI) listAgentDefinitions(args []string) error {
+// resumeRefresh confirms a rewrite of main so the daemon resumes rebasing workers
+func (c *CLI) resumeRefresh(args []string) error {
 	flags, _ := ParseFlags(args)
-
-	// Determine repository
 	repoName, err := c.resolveRepo(flags)
 	if err != nil {
 		return errors.NotInRepo()
 	}
 
-	// Get paths to agent definition directories
-	localAgentsDir := c.paths.RepoAgentsDir(repoName)
-	repoPath := c.paths.RepoDir(repoName)
-
-	// Read and merge agent definitions
-	reader := agents.NewReader(localAgentsDir, repoPath)
-	defs, err := reader.ReadAllDefinitions()
+	resp, err := c.sendDaemonRequest("resume_refresh", map[string]interface{}{
+		"repo": repoName,
+	})
 	if err != nil {
-		return errors.Wrap(errors.CategoryRuntime, "failed to read agent definitions", err)
+		return err
 	}
 
-	if len(defs) == 0 {
-		fmt.Println("No agent definitions found.")
-		fmt.Printf("\nAgent definitions are stored in:\n")
-		fmt.Printf("  Local: %s\n", localAgentsDir)
-		fmt.Printf("  Repo:  %s/.multiclaude/agents/\n", repoPath)
+	data, _ := resp.Data.(map[string]interface{})
+	if paused, _ := data["paused"].(bool); !paused {
+		fmt.Printf("Auto-refresh is not paused for %s\n", repoName)
 		return nil
 	}
-
-	fmt.Printf("Agent definitions for %s:\n\n", repoName)
-
-	// Create colored table
-	table := format.NewColoredTable("Name", "Source", "Title", "Description")
-
-	for _, def := range defs {
-		source := string(def.Source)
-		title := def.ParseTitle()
-		desc := def.ParseDescription()
-
-		// Truncate description if too long
-		desc = format.Truncate(desc, 50)
-
-		// Color the source based on type
-		sourceCell := format.Cell(source)
-		if def.Source == agents.SourceRepo {
-			sourceCell = format.ColorCell(source, format.Green)
-		}
-
-		table.AddRow(
-			format.Cell(def.Name),
-			sourceCell,
-			format.Cell(title),
-			format.Cell(desc),
-		)
-	}
-
-	table.Print()
-
+	branch, _ := data["branch"].(string)
+	newHead, _ := data["new_head"].(string)
+	fmt.Printf("✓ Auto-refresh resumed for %s\n", repoName)
+	format.Dimmed("  Workers will be rebased onto %s at %.12s", branch, newHead)
 	return nil
 }
 
-// spawnAgentFromFile spawns an agent using a prompt file and the daemon's spawn_agent handler.
-// This is the CLI command that connects supervisor orchestration with daemon agent spawning.
-func (c *CLI) spawnAgentFromFile(args []string) error {
+// repoLock shows the daemon holding a repository's lock and optionally takes
+// it over
+func (c *CLI) repoLock(args []string) error {
 	flags, _ := ParseFlags(args)
-
-	// Get required parameters
-	agentName, ok := flags["name"]
-	if !ok || agentName == "" {
-		return errors.InvalidUsage("--name is required")
-	}
-
-	agentClass, ok := flags["class"]
-	if !ok || agentClass == "" {
-		return errors.InvalidUsage("--class is required (persistent or ephemeral)")
-	}
-	if agentClass != "persistent" && agentClass != "ephemeral" {
-		return errors.InvalidUsage("--class must be 'persistent' or 'ephemeral'")
-	}
-
-	promptFile, ok := flags["prompt-file"]
-	if !ok || promptFile == "" {
-		return errors.InvalidUsage("--prompt-file is required")
-	}
-
-	// Determine repository
 	repoName, err := c.resolveRepo(flags)
 	if err != nil {
 		return errors.NotInRepo()
 	}
 
-	// Read prompt from file
-	promptContent, err := os.ReadFile(promptFile)
+	takeOver := flags["take-over"] == "true"
+	resp, err := c.sendDaemonRequest("repo_lock", map[string]interface{}{
+		"repo":      repoName,
+		"take_over": takeOver,
+	})
 	if err != nil {
-		return errors.Wrap(errors.CategoryRuntime, "failed to read prompt file", err)
+		return err
 	}
 
-	// Get optional task parameter
-	task := flags["task"]
-
-	// Send spawn_agent request to daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	reqArgs := map[string]interface{}{
-		"repo":   repoName,
-		"name":   agentName,
-		"class":  agentClass,
-		"prompt": string(promptContent),
-	}
-	if task != "" {
-		reqArgs["task"] = task
+	data, _ := resp.Data.(map[string]interface{})
+	owner, _ := data["owner"].(string)
+	heartbeat, _ := data["heartbeat"].(string)
+	if readOnly, _ := data["read_only"].(bool); readOnly {
+		fmt.Printf("⚠ %s is managed by another daemon: %s\n", repoName, owner)
+		format.Dimmed("  Last heartbeat: %s", heartbeat)
+		format.Dimmed("  This daemon won't create, refresh, or remove its worktrees.")
+		format.Dimmed("  If that daemon is gone: multiclaude repo lock --repo %s --take-over", repoName)
+	} else if owner != "" {
+		if takeOver {
+			fmt.Printf("✓ This daemon now manages %s\n", repoName)
+		} else {
+			fmt.Printf("%s is managed by this daemon: %s\n", repoName, owner)
+		}
+	} else {
+		fmt.Printf("%s is not locked\n", repoName)
 	}
 
-	resp, err := client.Send(socket.Request{
-		Command: "spawn_agent",
-		Args:    reqArgs,
-	})
-	if err != nil {
-		return errors.DaemonCommunicationFailed("spawning agent", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to spawn agent", fmt.Errorf("%s", resp.Error))
+	if foreign, _ := data["foreign_worktrees"].([]interface{}); len(foreign) > 0 {
+		fmt.Printf("\nWorktrees on multiclaude branches outside this installation (%d):\n", len(foreign))
+		for _, path := range foreign {
+			fmt.Printf("  %v\n", path)
+		}
 	}
-
-	fmt.Printf("Agent '%s' spawned successfully (class: %s)\n", agentName, agentClass)
 	return nil
 }
 
-// resetAgentDefinitions deletes the local agent definitions and re-copies from templates.
-func (c *CLI) resetAgentDefinitions(args []string) error {
-	flags, _ := ParseFlags(args)
-
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
+// requireRepoLock returns an error if another daemon manages a repository
+func (c *CLI) requireRepoLock(repoName string) error {
+	resp, err := c.sendDaemonRequest("repo_lock", map[string]interface{}{
+		"repo": repoName,
+	})
 	if err != nil {
-		return errors.NotInRepo()
+		return err
 	}
+	data, _ := resp.Data.(map[string]interface{})
+	if readOnly, _ := data["read_only"].(bool); !readOnly {
+		return nil
+	}
+	owner, _ := data["owner"].(string)
+	return errors.New(errors.CategoryConfig, fmt.Sprintf("repository '%s' is managed by another multiclaude daemon (%s)", repoName, owner)).
+		WithSuggestion(fmt.Sprintf("run this on that machine, or if its daemon is gone: multiclaude repo lock --repo %s --take-over", repoName))
+}
 
-	// Get agents directory path
-	agentsDir := c.paths.RepoAgentsDir(repoName)
-
-	// Check if directory exists
-	if _, err := os.Stat(agentsDir); os.IsNotExist(err) {
-		fmt.Printf("No agent definitions found at %s\n", agentsDir)
-		fmt.Println("Creating new definitions from templates...")
+func (c *CLI) removeRepo(args []string) error {
+	var repoName string
+	if len(args) > 0 {
+		repoName = args[0]
 	} else {
-		// Remove existing directory
-		fmt.Printf("Removing existing agent definitions at %s...\n", agentsDir)
-		if err := os.RemoveAll(agentsDir); err != nil {
-			return errors.Wrap(errors.CategoryRuntime, "failed to remove agent definitions", err)
+		// Interactive selection - list repos
+		client := c.daemonClient()
+		resp, err := client.Send(socket.Request{
+			Command: "list_repos",
+			Args: map[string]interface{}{
+				"rich": true,
+			},
+		})
+		if err != nil {
+			return errors.DaemonCommunicationFailed("listing repositories", err)
+		}
+		if !resp.Success {
+			return errors.Wrap(errors.CategoryRuntime, "failed to list repos", fmt.Errorf("%s", resp.Error))
+		}
+
+		repos, _ := resp.Data.([]interface{})
+		items := reposToSelectableItems(repos)
+		if len(items) == 0 {
+			return errors.NoRepositoriesFound()
+		}
+		selected, err := SelectFromList("Select repository to remove:", items)
+		if err != nil {
+			return err
+		}
+		if selected == "" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+		repoName = selected
+	}
+
+	fmt.Printf("Removing repository '%s'...\n", repoName)
+
+	// Get repo info from daemon
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting repo info", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
+	}
+
+	// Get list of agents
+	agents, _ := resp.Data.([]interface{})
+
+	// Check for any workers with uncommitted changes
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			if agentType == "worker" || agentType == "review" {
+				wtPath, _ := agentMap["worktree_path"].(string)
+				if wtPath != "" {
+					hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+					if err == nil && hasUncommitted {
+						agentName, _ := agentMap["name"].(string)
+						fmt.Printf("\nWarning: Agent '%s' has uncommitted changes!\n", agentName)
+						fmt.Println("Files may be lost if you continue.")
+						fmt.Print("Continue with removal? [y/N]: ")
+
+						var response string
+						fmt.Scanln(&response)
+						if response != "y" && response != "Y" {
+							fmt.Println("Removal cancelled")
+							return nil
+						}
+						break // Only ask once
+					}
+				}
+			}
+		}
+	}
+
+	// Kill tmux session; the daemon kills sessions of a multiplexer it
+	// hosts when the repository is removed
+	tmuxSession := c.tmuxSession(repoName)
+	if m := localMultiplexer(client); m != nil {
+		if exists, err := m.HasSession(context.Background(), tmuxSession); err == nil && exists {
+			fmt.Printf("Killing tmux session: %s\n", tmuxSession)
+			if err := m.KillSession(context.Background(), tmuxSession); err != nil {
+				fmt.Printf("Warning: failed to kill tmux session: %v\n", err)
+			}
+		}
+	}
+
+	// Remove worktrees for all agents
+	repoPath := c.paths.RepoDir(repoName)
+	wt := worktree.NewManager(repoPath)
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			wtPath, _ := agentMap["worktree_path"].(string)
+			agentName, _ := agentMap["name"].(string)
+			if wtPath != "" && wtPath != repoPath {
+				fmt.Printf("Removing worktree for '%s': %s\n", agentName, wtPath)
+				if err := wt.Remove(wtPath, true); err != nil {
+					fmt.Printf("Warning: failed to remove worktree: %v\n", err)
+				}
+			}
+		}
+	}
+
+	// Remove the worktrees directory for this repo
+	wtDir := c.paths.WorktreeDir(repoName)
+	if _, err := os.Stat(wtDir); err == nil {
+		fmt.Printf("Removing worktrees directory: %s\n", wtDir)
+		if err := os.RemoveAll(wtDir); err != nil {
+			fmt.Printf("Warning: failed to remove worktrees directory: %v\n", err)
+		}
+	}
+
+	// Clean up messages directory for this repo
+	msgDir := filepath.Join(c.paths.MessagesDir, repoName)
+	if _, err := os.Stat(msgDir); err == nil {
+		fmt.Printf("Removing messages directory: %s\n", msgDir)
+		if err := os.RemoveAll(msgDir); err != nil {
+			fmt.Printf("Warning: failed to remove messages directory: %v\n", err)
+		}
+	}
+
+	// Unregister from daemon
+	resp, err = client.Send(socket.Request{
+		Command: "remove_repo",
+		Args: map[string]interface{}{
+			"name": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("removing repo", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to remove repo from state", fmt.Errorf("%s", resp.Error))
+	}
+
+	fmt.Println("✓ Repository removed successfully")
+	fmt.Printf("\nNote: The cloned repository at '%s' was NOT deleted.\n", repoPath)
+	fmt.Println("Delete it manually if you no longer need it.")
+	return nil
+}
+
+func (c *CLI) setCurrentRepo(args []string) error {
+	if len(args) < 1 {
+		return errors.InvalidUsage("usage: multiclaude repo use <name>")
+	}
+
+	repoName := args[0]
+
+	_, err := c.sendDaemonRequest("set_current_repo", map[string]interface{}{
+		"name": repoName,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Current repository set to: %s\n", repoName)
+	return nil
+}
+
+func (c *CLI) getCurrentRepo(args []string) error {
+	resp, err := c.sendDaemonRequest("get_current_repo", nil)
+	if err != nil {
+		return err
+	}
+
+	currentRepo, _ := resp.Data.(string)
+	if currentRepo == "" {
+		fmt.Println("No current repository set")
+		fmt.Println("\nUse 'multiclaude repo use <name>' to set one")
+	} else {
+		fmt.Printf("Current repository: %s\n", currentRepo)
+	}
+	return nil
+}
+
+func (c *CLI) clearCurrentRepo(args []string) error {
+	_, err := c.sendDaemonRequest("clear_current_repo", nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Current repository cleared")
+	return nil
+}
+
+// loadConfigFile reads the config file at path, including the notification
+// settings only the daemon uses, so mistakes surface before the daemon starts
+func loadConfigFile(path string) (*config.File, error) {
+	file, err := config.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := notify.ParseFileSettings(file.Notifications); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// branchPrefixes returns the prefixes of the branches multiclaude creates in
+// a repository, falling back to the built-in ones if the config file can't
+// be read
+func (c *CLI) branchPrefixes(repoName string) []string {
+	file, err := config.LoadFile(c.paths.ConfigFile())
+	if err != nil {
+		file = &config.File{}
+	}
+	return file.BranchPrefixes(repoName)
+}
+
+// validateConfigFile checks the config file and summarizes what it sets
+func (c *CLI) validateConfigFile(args []string) error {
+	flags, _ := ParseFlags(args)
+	path := c.paths.ConfigFile()
+	if f, ok := flags["file"]; ok {
+		if f == "" || f == "true" {
+			return errors.InvalidUsage("--file requires a path")
+		}
+		path = f
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+	} else if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Printf("No config file at %s; built-in defaults apply.\n", path)
+		return nil
+	}
+
+	file, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s is valid\n", format.Green.Sprint("✓"), path)
+
+	var adapters []string
+	if n := file.Notifications.Slack; n != nil {
+		adapters = append(adapters, "slack"+eventsSummary(n.Events))
+	}
+	if n := file.Notifications.Telegram; n != nil {
+		adapters = append(adapters, "telegram"+eventsSummary(n.Events))
+	}
+	if n := file.Notifications.Webhook; n != nil {
+		adapters = append(adapters, "webhook ("+n.URL+")")
+	}
+	if len(adapters) == 0 {
+		adapters = append(adapters, "none")
+	}
+	fmt.Printf("  Notifications:   %s\n", strings.Join(adapters, ", "))
+	if file.API.Listen != "" {
+		fmt.Printf("  API server:      %s (bearer token)\n", file.API.Listen)
+	} else {
+		fmt.Println("  API server:      off")
+	}
+	if quota := file.Worktrees.QuotaBytes(); quota > 0 {
+		action := "refuse new workers"
+		if file.Worktrees.OnQuota == config.QuotaClean {
+			action = "clean up completed workers, then refuse new ones"
+		}
+		fmt.Printf("  Disk quota:      %s (%s)\n", format.Bytes(quota), action)
+	}
+	if interval := file.Worktrees.RefreshInterval(); interval > 0 {
+		fmt.Printf("  Refresh:         every %s\n", interval)
+	} else {
+		fmt.Println("  Refresh:         off")
+	}
+	fmt.Printf("  Sessions:        %s<repo>\n", file.Namer().Prefix())
+	fmt.Printf("  Defaults:        %s\n", repoSettingsSummary(file.Defaults))
+	repoNames := make([]string, 0, len(file.Repos))
+	for name := range file.Repos {
+		repoNames = append(repoNames, name)
+	}
+	sort.Strings(repoNames)
+	for _, name := range repoNames {
+		fmt.Printf("  Repo %-11s %s\n", name+":", repoSettingsSummary(file.Repos[name]))
+	}
+
+	// Overrides for repositories the daemon doesn't track are probably typos
+	if resp, err := c.sendDaemonRequest("list_repos", nil); err == nil {
+		tracked := make(map[string]bool)
+		repoList, _ := resp.Data.([]interface{})
+		for _, name := range repoList {
+			if name, ok := name.(string); ok {
+				tracked[name] = true
+			}
+		}
+		for _, name := range repoNames {
+			if !tracked[name] {
+				fmt.Printf("%s repos.%s does not match a tracked repository\n", format.Yellow.Sprint("Warning:"), name)
+			}
+		}
+	}
+	if hasSecrets(file) {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+			fmt.Printf("%s %s holds secrets but other users can read it; run: chmod 600 %s\n", format.Yellow.Sprint("Warning:"), path, path)
+		}
+	}
+	format.Dimmed("Apply changes to a running daemon with: multiclaude daemon reload")
+	return nil
+}
+
+// eventsSummary describes the event types an adapter is limited to
+func eventsSummary(types []string) string {
+	if len(types) == 0 {
+		return " (all events)"
+	}
+	return " (" + strings.Join(types, ", ") + ")"
+}
+
+// repoSettingsSummary describes a defaults or repos entry of the config file
+func repoSettingsSummary(s config.RepoSettings) string {
+	var parts []string
+	if s.BranchPrefix != "" {
+		parts = append(parts, "branch prefix "+s.BranchPrefix)
+	}
+	if s.MaxWorkers != nil {
+		if *s.MaxWorkers == 0 {
+			parts = append(parts, "no worker limit")
+		} else if *s.MaxWorkers == 1 {
+			parts = append(parts, "1 worker at a time")
+		} else {
+			parts = append(parts, fmt.Sprintf("%d workers at a time", *s.MaxWorkers))
 		}
 	}
+	if len(parts) == 0 {
+		return "built-in"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// hasSecrets reports whether the config file holds tokens or webhook URLs
+// that grant access on their own
+func hasSecrets(file *config.File) bool {
+	n := file.Notifications
+	return n.Slack != nil || n.Telegram != nil || n.Webhook != nil || file.API.Token != ""
+}
+
+// repoConfigSettings are the settings `multiclaude config set` changes, named
+// as the flags of `multiclaude config`
+var repoConfigSettings = []string{
+	"mq-enabled", "mq-track", "mq-auto-merge", "mq-label", "mq-interval", "mq-required-checks", "mq-merge-method",
+	"groups", "base", "guard-paths", "guard-max-file-mb", "guard-block-binaries", "commit-style", "commit-pattern",
+	"auto-answer", "tmux-alerts", "conflict-assist", "push-remote", "lfs-skip", "warm-pool", "warm-bootstrap",
+	"reaper", "reaper-grace", "reaper-keep", "auto-recover", "recover-after", "stuck-after", "stuck-rules",
+	"stuck-quiet", "stuck-nudge", "restart-max", "queue-workers", "max-workers",
+}
+
+// setRepoConfig changes one repository setting: `config set max-workers 4`
+// does what `config <repo> --max-workers=4` does. The repository comes from
+// --repo or, as for config, the current directory.
+func (c *CLI) setRepoConfig(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 2 {
+		return errors.InvalidUsage("usage: multiclaude config set <setting> <value> [--repo <repo>]")
+	}
+	setting, value := posArgs[0], posArgs[1]
+	known := false
+	for _, name := range repoConfigSettings {
+		known = known || name == setting
+	}
+	for _, perm := range state.Permissions {
+		known = known || setting == "allow-"+string(perm)
+	}
+	if !known {
+		return errors.InvalidUsage(fmt.Sprintf("unknown setting %q; use one of the flags of multiclaude config without --, e.g. max-workers", setting))
+	}
+
+	configArgs := []string{"--" + setting + "=" + value}
+	if repo := flags["repo"]; repo != "" {
+		configArgs = append([]string{repo}, configArgs...)
+	}
+	return c.configRepo(configArgs)
+}
+
+func (c *CLI) configRepo(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	// Determine repository
+	var repoName string
+	if len(posArgs) >= 1 {
+		repoName = posArgs[0]
+	} else {
+		// Try to infer from current directory
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		// Check if we're in a tracked repo
+		repos := c.getReposList()
+		for _, repo := range repos {
+			repoPath := c.paths.RepoDir(repo)
+			if strings.HasPrefix(cwd, repoPath) {
+				repoName = repo
+				break
+			}
+		}
+
+		if repoName == "" {
+			// If only one repo exists, use it
+			if len(repos) == 1 {
+				repoName = repos[0]
+			} else {
+				return fmt.Errorf("please specify a repository name or run from within a tracked repository")
+			}
+		}
+	}
+
+	// Check if any config flags are provided
+	hasMqEnabled := flags["mq-enabled"] != ""
+	hasMqTrack := flags["mq-track"] != ""
+	_, hasMqChecks := flags["mq-required-checks"]
+	hasMqEngine := flags["mq-auto-merge"] != "" || flags["mq-label"] != "" || flags["mq-interval"] != "" || flags["mq-merge-method"] != "" || hasMqChecks
+	_, hasGroups := flags["groups"]
+	_, hasBase := flags["base"]
+	_, hasGuardPaths := flags["guard-paths"]
+	hasGuard := hasGuardPaths || flags["guard-max-file-mb"] != "" || flags["guard-block-binaries"] != ""
+
+	hasCommitPolicy := flags["commit-style"] != "" || flags["commit-pattern"] != ""
+	hasAutoAnswer := flags["auto-answer"] != ""
+	hasTmuxAlerts := flags["tmux-alerts"] != ""
+	hasConflictAssist := flags["conflict-assist"] != ""
+	hasPushRemote := flags["push-remote"] != ""
+	_, hasLFSSkip := flags["lfs-skip"]
+	_, hasWarmBootstrap := flags["warm-bootstrap"]
+	hasWarmPool := flags["warm-pool"] != "" || hasWarmBootstrap
+	_, hasReaperKeep := flags["reaper-keep"]
+	hasReaper := flags["reaper"] != "" || flags["reaper-grace"] != "" || hasReaperKeep
+	hasRecovery := flags["auto-recover"] != "" || flags["recover-after"] != ""
+	_, hasStuckRules := flags["stuck-rules"]
+	_, hasStuckQuiet := flags["stuck-quiet"]
+	hasStuck := flags["stuck-after"] != "" || hasStuckRules || hasStuckQuiet || flags["stuck-nudge"] != ""
+	hasRestart := flags["restart-max"] != ""
+	hasQueueWorkers := flags["queue-workers"] != ""
+	hasMaxWorkers := flags["max-workers"] != ""
+	hasAccess := false
+	for _, perm := range state.Permissions {
+		if _, ok := flags["allow-"+string(perm)]; ok {
+			hasAccess = true
+		}
+	}
+
+	if !hasMqEnabled && !hasMqTrack && !hasMqEngine && !hasGroups && !hasBase && !hasGuard && !hasCommitPolicy && !hasAutoAnswer && !hasTmuxAlerts && !hasConflictAssist && !hasPushRemote && !hasLFSSkip && !hasWarmPool && !hasReaper && !hasRecovery && !hasStuck && !hasRestart && !hasQueueWorkers && !hasMaxWorkers && !hasAccess {
+		// No flags - just show current config
+		return c.showRepoConfig(repoName)
+	}
+
+	// Apply config changes
+	return c.updateRepoConfig(repoName, flags)
+}
+
+func (c *CLI) showRepoConfig(repoName string) error {
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "get_repo_config",
+		Args: map[string]interface{}{
+			"name": repoName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get repo config: %w (is daemon running?)", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("failed to get repo config: %s", resp.Error)
+	}
+
+	// Parse response
+	configMap, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected response format")
+	}
+
+	fmt.Printf("Configuration for repository: %s\n\n", repoName)
+	fmt.Println("Merge Queue:")
+
+	mqEnabled := true
+	if enabled, ok := configMap["mq_enabled"].(bool); ok {
+		mqEnabled = enabled
+	}
+
+	mqTrackMode := "all"
+	if trackMode, ok := configMap["mq_track_mode"].(string); ok {
+		mqTrackMode = trackMode
+	}
+
+	if mqEnabled {
+		fmt.Printf("  Enabled: true\n")
+		fmt.Printf("  Track mode: %s\n", mqTrackMode)
+	} else {
+		fmt.Printf("  Enabled: false\n")
+	}
+	if autoMerge, _ := configMap["mq_auto_merge"].(bool); autoMerge {
+		label, _ := configMap["mq_label"].(string)
+		method, _ := configMap["mq_merge_method"].(string)
+		pollSeconds, _ := configMap["mq_poll_seconds"].(float64)
+		fmt.Printf("  Auto-merge: PRs labeled %q, %s merge, polled every %s\n", label, method, time.Duration(pollSeconds)*time.Second)
+		var checks []string
+		if list, _ := configMap["mq_required_checks"].([]interface{}); len(list) > 0 {
+			for _, item := range list {
+				if s, ok := item.(string); ok {
+					checks = append(checks, s)
+				}
+			}
+		}
+		if len(checks) > 0 {
+			fmt.Printf("  Required checks: %s\n", strings.Join(checks, ", "))
+		} else {
+			fmt.Printf("  Required checks: (the base branch's)\n")
+		}
+	} else {
+		fmt.Printf("  Auto-merge: off (the merge-queue agent merges)\n")
+	}
+
+	groups := repoGroupsFromMap(configMap)
+	if len(groups) > 0 {
+		fmt.Printf("\nGroups: %s\n", strings.Join(groups, ", "))
+	} else {
+		fmt.Printf("\nGroups: (none)\n")
+	}
+
+	if base, _ := configMap["default_base"].(string); base != "" {
+		fmt.Printf("\nDefault base: %s\n", base)
+	} else {
+		fmt.Printf("\nDefault base: (default branch)\n")
+	}
+
+	fmt.Println("\nBranch Guard:")
+	guardEnabled := false
+	if paths, _ := configMap["guard_allowed_paths"].([]interface{}); len(paths) > 0 {
+		var names []string
+		for _, p := range paths {
+			if s, ok := p.(string); ok {
+				names = append(names, s)
+			}
+		}
+		fmt.Printf("  Allowed paths: %s\n", strings.Join(names, ", "))
+		guardEnabled = true
+	}
+	if maxMB, _ := configMap["guard_max_file_mb"].(float64); maxMB > 0 {
+		fmt.Printf("  Max file size: %d MB\n", int(maxMB))
+		guardEnabled = true
+	}
+	if blockBinaries, _ := configMap["guard_block_binaries"].(bool); blockBinaries {
+		fmt.Printf("  Block new binaries: true\n")
+		guardEnabled = true
+	}
+	if !guardEnabled {
+		fmt.Printf("  Disabled\n")
+	}
+
+	fmt.Println("\nCommit Policy:")
+	commitStyle, _ := configMap["commit_style"].(string)
+	switch commitStyle {
+	case "":
+		fmt.Printf("  Disabled\n")
+	case "regex":
+		commitPattern, _ := configMap["commit_pattern"].(string)
+		fmt.Printf("  Style: regex (%s)\n", commitPattern)
+	default:
+		fmt.Printf("  Style: %s\n", commitStyle)
+	}
+
+	fmt.Println("\nAuto-answer:")
+	if enabled, ok := configMap["auto_answer_enabled"].(bool); ok && !enabled {
+		fmt.Printf("  Disabled\n")
+	} else {
+		rules, _ := configMap["auto_answer_rules"].(float64)
+		fmt.Printf("  Enabled (%d custom rules, see: multiclaude auto-answer list)\n", int(rules))
+	}
+
+	fmt.Println("\nTmux Alerts:")
+	if enabled, _ := configMap["tmux_alerts"].(bool); enabled {
+		fmt.Printf("  Ring the bell in an agent's window when it needs a human\n")
+	} else {
+		fmt.Printf("  Disabled\n")
+	}
+
+	fmt.Println("\nConflict Assist:")
+	if enabled, _ := configMap["conflict_assist"].(bool); enabled {
+		fmt.Printf("  Leave conflicting refreshes paused for the worker to resolve\n")
+	} else {
+		fmt.Printf("  Disabled (conflicting refreshes are aborted)\n")
+	}
+
+	fmt.Println("\nGit LFS Content:")
+	var lfsSkip []string
+	if list, _ := configMap["lfs_skip"].([]interface{}); len(list) > 0 {
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				lfsSkip = append(lfsSkip, s)
+			}
+		}
+		fmt.Printf("  Pointer files only for: %s\n", strings.Join(lfsSkip, ", "))
+	} else {
+		fmt.Printf("  Downloaded for every agent\n")
+	}
+
+	fmt.Println("\nWarm Pool:")
+	if size, _ := configMap["warm_pool_size"].(float64); size > 0 {
+		ready, _ := configMap["warm_pool_ready"].(float64)
+		fmt.Printf("  Size: %d (%d ready)\n", int(size), int(ready))
+		if bootstrap, _ := configMap["warm_pool_bootstrap"].(string); bootstrap != "" {
+			fmt.Printf("  Bootstrap: %s\n", bootstrap)
+		}
+	} else {
+		fmt.Printf("  Disabled\n")
+	}
+
+	fmt.Println("\nWindow Reaper:")
+	reaperMode, _ := configMap["reaper_mode"].(string)
+	if reaperMode == string(state.ReaperOff) {
+		fmt.Printf("  Disabled\n")
+	} else {
+		grace, _ := configMap["reaper_grace_minutes"].(float64)
+		fmt.Printf("  Mode: %s (grace %dm)\n", reaperMode, int(grace))
+		var keep []string
+		if list, _ := configMap["reaper_keep"].([]interface{}); len(list) > 0 {
+			for _, item := range list {
+				if s, ok := item.(string); ok {
+					keep = append(keep, s)
+				}
+			}
+			fmt.Printf("  Keep: %s\n", strings.Join(keep, ", "))
+		}
+	}
+
+	fmt.Println("\nInterrupted Rebase/Merge Recovery:")
+	recoverAfter, _ := configMap["recovery_after_minutes"].(float64)
+	if auto, _ := configMap["recovery_auto"].(bool); auto {
+		fmt.Printf("  Automatic after %dm idle\n", int(recoverAfter))
+	} else {
+		fmt.Printf("  Manual (multiclaude work recover), after %dm idle\n", int(recoverAfter))
+	}
+
+	fmt.Println("\nStuck Detection:")
+	if idle, _ := configMap["stuck_idle_minutes"].(float64); idle > 0 {
+		fmt.Printf("  Idle workers reported after %dm without output\n", int(idle))
+	} else {
+		fmt.Printf("  Idle workers not reported (output loops still are)\n")
+	}
+	for _, key := range []string{"stuck_rules", "stuck_quiet"} {
+		list, _ := configMap[key].([]interface{})
+		var rules []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				rules = append(rules, s)
+			}
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		label := "Rules"
+		if key == "stuck_quiet" {
+			label = "Quiet while output matches"
+		}
+		fmt.Printf("  %s: %s\n", label, strings.Join(rules, ", "))
+	}
+	if nudge, _ := configMap["stuck_nudge"].(bool); nudge {
+		fmt.Printf("  Idle agents are nudged in their window\n")
+	}
+
+	fmt.Println("\nCrash Restarts:")
+	if max, _ := configMap["restart_max"].(float64); max > 0 {
+		fmt.Printf("  Agents whose Claude process exits are restarted up to %d times in a row\n", int(max))
+	} else {
+		fmt.Printf("  Off\n")
+	}
+
+	fmt.Println("\nWorkers:")
+	if max, _ := configMap["max_workers"].(float64); max > 0 {
+		fmt.Printf("  At most %d running at once\n", int(max))
+	} else {
+		fmt.Printf("  No limit on workers running at once\n")
+	}
+	if workers, _ := configMap["queue_workers"].(float64); workers > 0 {
+		fmt.Printf("  Up to %d queued tasks run at once\n", int(workers))
+	}
+	if remote, _ := configMap["push_remote"].(string); remote != "" {
+		fmt.Printf("  Branches are pushed to %s\n", remote)
+	}
+
+	fmt.Println("\nAccess:")
+	for _, perm := range state.Permissions {
+		var members []string
+		if list, _ := configMap["access_"+string(perm)].([]interface{}); len(list) > 0 {
+			for _, item := range list {
+				if s, ok := item.(string); ok {
+					members = append(members, s)
+				}
+			}
+		}
+		who := "anyone with socket access"
+		if len(members) > 0 {
+			who = strings.Join(members, ", ")
+		} else if perm == state.PermAdmin {
+			who = "anyone with socket access (access changes: daemon user only)"
+		}
+		fmt.Printf("  %s: %s\n", perm, who)
+	}
+
+	fmt.Println("\nTo modify:")
+	fmt.Printf("  multiclaude config %s --mq-enabled=true|false\n", repoName)
+	fmt.Printf("  multiclaude config %s --mq-track=all|author|assigned\n", repoName)
+	fmt.Printf("  multiclaude config %s --mq-auto-merge=true [--mq-label=multiclaude] [--mq-interval=2m] [--mq-required-checks=test,lint] [--mq-merge-method=squash|merge|rebase]\n", repoName)
+	fmt.Printf("  multiclaude config %s --groups=payments,frontend  (empty to clear)\n", repoName)
+	fmt.Printf("  multiclaude config %s --base=<branch|tag|sha>  (empty for the default branch)\n", repoName)
+	fmt.Printf("  multiclaude config %s --guard-paths=src/,docs/ --guard-max-file-mb=5 --guard-block-binaries=true\n", repoName)
+	fmt.Printf("  multiclaude config %s --commit-style=none|conventional|regex [--commit-pattern=<regex>]\n", repoName)
+	fmt.Printf("  multiclaude config %s --auto-answer=true|false\n", repoName)
+	fmt.Printf("  multiclaude config %s --tmux-alerts=true|false\n", repoName)
+	fmt.Printf("  multiclaude config %s --conflict-assist=true|false\n", repoName)
+	fmt.Printf("  multiclaude config %s --push-remote=fork  (remote workers push to; origin for the default)\n", repoName)
+	fmt.Printf("  multiclaude config %s --lfs-skip=review,merge-queue  (agent types that get LFS pointer files; empty to clear)\n", repoName)
+	fmt.Printf("  multiclaude config %s --warm-pool=N [--warm-bootstrap=\"npm ci\"]  (0 to disable)\n", repoName)
+	fmt.Printf("  multiclaude config %s --reaper=dry-run|enforce|off [--reaper-grace=15m] [--reaper-keep=scratch,logs]\n", repoName)
+	fmt.Printf("  multiclaude config %s --auto-recover=true|false [--recover-after=30m]\n", repoName)
+	fmt.Printf("  multiclaude config %s --stuck-after=10m [--stuck-rules=label:docs=45m,task:failing test=5m] [--stuck-quiet=Compiling=30m] [--stuck-nudge=true]  (0 or empty to clear)\n", repoName)
+	fmt.Printf("  multiclaude config %s --restart-max=3  (restarts in a row for crashed agents; 0 to turn off)\n", repoName)
+	fmt.Printf("  multiclaude config %s --queue-workers=2  (queued tasks that run at once)\n", repoName)
+	fmt.Printf("  multiclaude config %s --max-workers=8  (workers that run at once; 0 for no limit)\n", repoName)
+	fmt.Printf("  multiclaude config %s --allow-spawn|--allow-remove|--allow-merge|--allow-admin=alice,@team  (empty for anyone)\n", repoName)
+
+	return nil
+}
+
+func (c *CLI) updateRepoConfig(repoName string, flags map[string]string) error {
+	// Build update args
+	updateArgs := map[string]interface{}{
+		"name": repoName,
+	}
+
+	// Parse and validate flags
+	if mqEnabled, ok := flags["mq-enabled"]; ok {
+		switch mqEnabled {
+		case "true":
+			updateArgs["mq_enabled"] = true
+		case "false":
+			updateArgs["mq_enabled"] = false
+		default:
+			return fmt.Errorf("invalid --mq-enabled value: %s (must be 'true' or 'false')", mqEnabled)
+		}
+	}
+
+	if mqTrack, ok := flags["mq-track"]; ok {
+		switch mqTrack {
+		case "all", "author", "assigned":
+			updateArgs["mq_track_mode"] = mqTrack
+		default:
+			return fmt.Errorf("invalid --mq-track value: %s (must be 'all', 'author', or 'assigned')", mqTrack)
+		}
+	}
+
+	if autoMerge, ok := flags["mq-auto-merge"]; ok {
+		switch autoMerge {
+		case "true":
+			updateArgs["mq_auto_merge"] = true
+		case "false":
+			updateArgs["mq_auto_merge"] = false
+		default:
+			return fmt.Errorf("invalid --mq-auto-merge value: %s (must be 'true' or 'false')", autoMerge)
+		}
+	}
+
+	if label, ok := flags["mq-label"]; ok {
+		updateArgs["mq_label"] = label
+	}
+
+	if interval, ok := flags["mq-interval"]; ok {
+		duration, err := parseTimeBudget(interval)
+		if err != nil || duration < 30*time.Second {
+			return fmt.Errorf("invalid --mq-interval value: %s (must be a duration of at least 30s, like 2m)", interval)
+		}
+		updateArgs["mq_poll_seconds"] = int(duration.Seconds())
+	}
+
+	if checks, ok := flags["mq-required-checks"]; ok {
+		updateArgs["mq_required_checks"] = splitCommaList(checks)
+	}
+
+	if method, ok := flags["mq-merge-method"]; ok {
+		if !state.ValidMergeMethod(method) {
+			return fmt.Errorf("invalid --mq-merge-method value: %s (must be 'squash', 'merge', or 'rebase')", method)
+		}
+		updateArgs["mq_merge_method"] = method
+	}
+
+	if commitStyle, ok := flags["commit-style"]; ok {
+		switch commitStyle {
+		case "none", "conventional", "regex":
+			updateArgs["commit_style"] = commitStyle
+		default:
+			return fmt.Errorf("invalid --commit-style value: %s (must be 'none', 'conventional', or 'regex')", commitStyle)
+		}
+	}
+
+	if commitPattern, ok := flags["commit-pattern"]; ok {
+		updateArgs["commit_pattern"] = commitPattern
+	}
+
+	if autoAnswer, ok := flags["auto-answer"]; ok {
+		switch autoAnswer {
+		case "true":
+			updateArgs["auto_answer_enabled"] = true
+		case "false":
+			updateArgs["auto_answer_enabled"] = false
+		default:
+			return fmt.Errorf("invalid --auto-answer value: %s (must be 'true' or 'false')", autoAnswer)
+		}
+	}
+
+	if tmuxAlerts, ok := flags["tmux-alerts"]; ok {
+		switch tmuxAlerts {
+		case "true":
+			updateArgs["tmux_alerts"] = true
+		case "false":
+			updateArgs["tmux_alerts"] = false
+		default:
+			return fmt.Errorf("invalid --tmux-alerts value: %s (must be 'true' or 'false')", tmuxAlerts)
+		}
+	}
+
+	if remote, ok := flags["push-remote"]; ok {
+		updateArgs["push_remote"] = remote
+	}
+
+	if conflictAssist, ok := flags["conflict-assist"]; ok {
+		switch conflictAssist {
+		case "true":
+			updateArgs["conflict_assist"] = true
+		case "false":
+			updateArgs["conflict_assist"] = false
+		default:
+			return fmt.Errorf("invalid --conflict-assist value: %s (must be 'true' or 'false')", conflictAssist)
+		}
+	}
+
+	if skip, ok := flags["lfs-skip"]; ok {
+		updateArgs["lfs_skip"] = splitCommaList(skip)
+	}
+
+	if guardPaths, ok := flags["guard-paths"]; ok {
+		updateArgs["guard_allowed_paths"] = splitCommaList(guardPaths)
+	}
+
+	if maxMB, ok := flags["guard-max-file-mb"]; ok {
+		n, err := strconv.Atoi(maxMB)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid --guard-max-file-mb value: %s (must be a non-negative integer)", maxMB)
+		}
+		updateArgs["guard_max_file_mb"] = n
+	}
+
+	if blockBinaries, ok := flags["guard-block-binaries"]; ok {
+		switch blockBinaries {
+		case "true":
+			updateArgs["guard_block_binaries"] = true
+		case "false":
+			updateArgs["guard_block_binaries"] = false
+		default:
+			return fmt.Errorf("invalid --guard-block-binaries value: %s (must be 'true' or 'false')", blockBinaries)
+		}
+	}
+
+	if groupsFlag, ok := flags["groups"]; ok {
+		updateArgs["groups"] = splitCommaList(groupsFlag)
+	}
+
+	if base, ok := flags["base"]; ok {
+		if base == "true" {
+			return fmt.Errorf("--base requires a branch, tag, or commit (use --base= for the default branch)")
+		}
+		updateArgs["default_base"] = base
+	}
+
+	if warmPool, ok := flags["warm-pool"]; ok {
+		n, err := strconv.Atoi(warmPool)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid --warm-pool value: %s (must be a non-negative integer)", warmPool)
+		}
+		updateArgs["warm_pool_size"] = n
+	}
+
+	if bootstrap, ok := flags["warm-bootstrap"]; ok {
+		updateArgs["warm_pool_bootstrap"] = bootstrap
+	}
+
+	if reaper, ok := flags["reaper"]; ok {
+		switch state.ReaperMode(reaper) {
+		case state.ReaperDryRun, state.ReaperEnforce, state.ReaperOff:
+			updateArgs["reaper_mode"] = reaper
+		default:
+			return fmt.Errorf("invalid --reaper value: %s (must be 'dry-run', 'enforce', or 'off')", reaper)
+		}
+	}
+
+	if grace, ok := flags["reaper-grace"]; ok {
+		duration, err := parseDuration(grace)
+		if err != nil || duration < time.Minute {
+			return fmt.Errorf("invalid --reaper-grace value: %s (must be a duration of at least 1m, like 15m or 1h)", grace)
+		}
+		updateArgs["reaper_grace_minutes"] = int(duration.Minutes())
+	}
+
+	if keep, ok := flags["reaper-keep"]; ok {
+		updateArgs["reaper_keep"] = splitCommaList(keep)
+	}
+
+	if autoRecover, ok := flags["auto-recover"]; ok {
+		switch autoRecover {
+		case "true":
+			updateArgs["recovery_auto"] = true
+		case "false":
+			updateArgs["recovery_auto"] = false
+		default:
+			return fmt.Errorf("invalid --auto-recover value: %s (must be 'true' or 'false')", autoRecover)
+		}
+	}
+
+	if after, ok := flags["recover-after"]; ok {
+		duration, err := parseDuration(after)
+		if err != nil || duration < time.Minute {
+			return fmt.Errorf("invalid --recover-after value: %s (must be a duration of at least 1m, like 30m or 2h)", after)
+		}
+		updateArgs["recovery_after_minutes"] = int(duration.Minutes())
+	}
+
+	if after, ok := flags["stuck-after"]; ok {
+		minutes := 0
+		if after != "0" {
+			duration, err := parseDuration(after)
+			if err != nil || duration < time.Minute {
+				return fmt.Errorf("invalid --stuck-after value: %s (must be a duration of at least 1m, like 10m, or 0 to stop reporting idle workers)", after)
+			}
+			minutes = int(duration.Minutes())
+		}
+		updateArgs["stuck_idle_minutes"] = minutes
+	}
+
+	if rules, ok := flags["stuck-rules"]; ok {
+		updateArgs["stuck_rules"] = splitCommaList(rules)
+	}
+
+	if quiet, ok := flags["stuck-quiet"]; ok {
+		updateArgs["stuck_quiet"] = splitCommaList(quiet)
+	}
+
+	if nudge, ok := flags["stuck-nudge"]; ok {
+		switch nudge {
+		case "true":
+			updateArgs["stuck_nudge"] = true
+		case "false":
+			updateArgs["stuck_nudge"] = false
+		default:
+			return fmt.Errorf("invalid --stuck-nudge value: %s (must be 'true' or 'false')", nudge)
+		}
+	}
+
+	if max, ok := flags["restart-max"]; ok {
+		n, err := strconv.Atoi(max)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid --restart-max value: %s (must be a number of restarts, or 0 to turn automatic restarts off)", max)
+		}
+		updateArgs["restart_max"] = n
+	}
+
+	if max, ok := flags["max-workers"]; ok {
+		n, err := strconv.Atoi(max)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid --max-workers value: %s (must be a number of workers, or 0 for no limit)", max)
+		}
+		updateArgs["max_workers"] = n
+	}
+
+	if workers, ok := flags["queue-workers"]; ok {
+		n, err := strconv.Atoi(workers)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid --queue-workers value: %s (must be a number of at least 1)", workers)
+		}
+		updateArgs["queue_workers"] = n
+	}
+
+	for _, perm := range state.Permissions {
+		if members, ok := flags["allow-"+string(perm)]; ok {
+			updateArgs["access_"+string(perm)] = splitCommaList(members)
+		}
+	}
+
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "update_repo_config",
+		Args:    updateArgs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update repo config: %w (is daemon running?)", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("failed to update repo config: %s", resp.Error)
+	}
+
+	fmt.Printf("Configuration updated for repository: %s\n", repoName)
+
+	// Show the updated config
+	return c.showRepoConfig(repoName)
+}
+
+// createWorkersForGroup runs createWorker once per repository in group,
+// passing the remaining arguments through with --repo set
+func (c *CLI) createWorkersForGroup(group string, args []string, flags map[string]string) error {
+	if group == "" || group == "true" {
+		return errors.InvalidUsage("--group requires a group name")
+	}
+	if _, hasRepo := flags["repo"]; hasRepo {
+		return errors.InvalidUsage("--group and --repo cannot be used together")
+	}
+	if _, hasName := flags["name"]; hasName {
+		return errors.InvalidUsage("--name cannot be used with --group (each repo gets its own worker)")
+	}
+	if _, hasPushTo := flags["push-to"]; hasPushTo {
+		return errors.InvalidUsage("--push-to cannot be used with --group")
+	}
+
+	resp, err := c.sendDaemonRequest("list_repos", map[string]interface{}{
+		"group": group,
+	})
+	if err != nil {
+		return err
+	}
+	repos, _ := resp.Data.([]interface{})
+	var repoNames []string
+	for _, r := range repos {
+		if name, ok := r.(string); ok {
+			repoNames = append(repoNames, name)
+		}
+	}
+	if len(repoNames) == 0 {
+		return errors.InvalidArgument("group", group, "a group with at least one repository")
+	}
+	sort.Strings(repoNames)
+
+	baseArgs := removeFlag(args, "group")
+	var failed []string
+	for _, repoName := range repoNames {
+		format.Header("[%s]", repoName)
+		repoArgs := append(append([]string{}, baseArgs...), "--repo", repoName)
+		if err := c.createWorker(repoArgs); err != nil {
+			fmt.Printf("Failed to create worker in %s: %v\n", repoName, err)
+			failed = append(failed, repoName)
+		}
+		fmt.Println()
+	}
+
+	if len(failed) > 0 {
+		return errors.New(errors.CategoryRuntime, fmt.Sprintf("failed to create workers in %d of %d repos: %s", len(failed), len(repoNames), strings.Join(failed, ", ")))
+	}
+	fmt.Printf("Created workers in %d repos of group '%s'\n", len(repoNames), group)
+	return nil
+}
+
+// removeFlag returns args without the named long flag and its value
+func removeFlag(args []string, name string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--"+name {
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+			}
+			continue
+		}
+		if strings.HasPrefix(arg, "--"+name+"=") {
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// splitCommaList splits a comma-separated flag value into a socket argument list
+func splitCommaList(value string) []interface{} {
+	items := []interface{}{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (c *CLI) createWorker(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	// Get task description
+	task := strings.Join(posArgs, " ")
+	if task == "" {
+		return errors.InvalidUsage("usage: multiclaude work <task description>")
+	}
+
+	// Fan out to every repository in a group
+	if group, ok := flags["group"]; ok {
+		return c.createWorkersForGroup(group, args, flags)
+	}
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	// Place the worker on another host's daemon
+	if target := c.host; target != "" {
+		host, remote, err := c.pickWorkHost(repoName, target)
+		if err != nil {
+			return err
+		}
+		if remote && host.SSH == "" {
+			return c.queueOnHost(host, repoName, task, flags)
+		}
+		if remote {
+			fmt.Printf("Creating worker on host %s\n", host.Name)
+			workArgs := append([]string{"work"}, removeFlag(args, "repo")...)
+			return fleet.NewClient().Run(host, append(workArgs, "--repo", repoName), os.Stdout, os.Stderr)
+		}
+	}
+
+	// Generate worker name (Docker-style)
+	workerName := names.Generate()
+	if name, ok := flags["name"]; ok {
+		workerName = name
+	}
+
+	// work rerun replays a previous spawn's snapshot, prompt included
+	var replay *snapshot.Snapshot
+	var replayPrompt string
+	if dir, ok := flags["replay"]; ok {
+		replay, replayPrompt, err = snapshot.Load(dir)
+		if err != nil {
+			return errors.Wrap(errors.CategoryConfig, "failed to load spawn snapshot", err)
+		}
+	}
+
+	// Time-boxed tasks: the daemon warns at 75% of the budget and asks the
+	// worker to wrap up at the deadline
+	var timeBudget time.Duration
+	if deadline, ok := flags["deadline"]; ok {
+		timeBudget, err = parseTimeBudget(deadline)
+		if err != nil {
+			return errors.InvalidArgument("--deadline", deadline, "a positive duration like 90m, 2h, or 1d")
+		}
+	}
+
+	// Priority flows to the worker's notifications, its place in the merge
+	// queue, and how quickly it is flagged as stuck
+	var priority state.TaskPriority
+	if raw, ok := flags["priority"]; ok {
+		priority, err = state.ParseTaskPriority(raw)
+		if err != nil {
+			return errors.InvalidArgument("--priority", raw, "P0, P1, P2, or P3")
+		}
+	}
+
+	// Check for --push-to flag (for iterating on existing PRs)
+	pushTo, hasPushTo := flags["push-to"]
+	if hasPushTo {
+		// --push-to requires --branch to specify the remote branch to start from
+		if _, hasBranch := flags["branch"]; !hasBranch {
+			return errors.InvalidUsage("--push-to requires --branch to specify the remote branch (e.g., --branch origin/work/jolly-hawk --push-to work/jolly-hawk)")
+		}
+		if _, hasBase := flags["base"]; hasBase {
+			return errors.InvalidUsage("--base cannot be used with --push-to (the existing PR keeps its base)")
+		}
+	}
+
+	// Get repository path
+	repoPath := c.paths.RepoDir(repoName)
+
+	// Refuse before creating a worktree if another machine's daemon manages
+	// this clone; it would treat the worktree as its own
+	if err := c.requireRepoLock(repoName); err != nil {
+		return err
+	}
+
+	// Stop before creating anything if the repo is at its worker limit, or
+	// queue the task with --queue
+	if resp, err := c.sendDaemonRequest("check_worker_capacity", map[string]interface{}{"repo": repoName}); err == nil {
+		data, _ := resp.Data.(map[string]interface{})
+		if available, _ := data["available"].(bool); !available {
+			if flags["queue"] != "true" {
+				msg, _ := data["error"].(string)
+				return errors.New(errors.CategoryRuntime, msg).
+					WithSuggestion("rerun with --queue to queue the task instead")
+			}
+			return c.queueInsteadOfWorker(repoName, task, priority, data)
+		}
+	}
+
+	// Fetch latest from origin before creating worktree
+	// This ensures workers start from the latest code, not stale local refs
+	// Note: We use "git fetch origin main" (not "main:main") because the latter
+	// fails when main is checked out in the bare repo with:
+	// "fatal: refusing to fetch into branch 'refs/heads/main' checked out at ..."
+	fmt.Println("Fetching latest from origin...")
+	fetchCmd := gitCommand("fetch", "origin")
+	fetchCmd.Dir = repoPath
+	if err := fetchCmd.Run(); err != nil {
+		// Best effort - don't fail if offline or fetch fails
+		fmt.Printf("Warning: failed to fetch from origin: %v (continuing with local refs)\n", err)
+	}
+
+	// Determine branch to start from
+	// Prefer origin/main if it exists (updated by fetch), otherwise fall back to HEAD
+	// This handles both normal repos and test repos without remotes
+	startBranch := "HEAD"
+	checkOriginCmd := gitCommand("rev-parse", "--verify", "origin/main")
+	checkOriginCmd.Dir = repoPath
+	if err := checkOriginCmd.Run(); err == nil {
+		startBranch = "origin/main"
+	}
+
+	// A base (--base, or the repo's default base) is the branch, tag, or
+	// commit the task builds on. A base branch is also what the daemon
+	// refreshes the worker onto and what its PR targets.
+	var base *worktree.BaseRef
+	baseName, hasBase := flags["base"]
+	if !hasBase && !hasPushTo {
+		baseName = c.repoDefaultBase(repoName)
+	}
+	if baseName != "" && !hasPushTo {
+		resolved, err := worktree.NewManager(repoPath).ResolveBase("origin", baseName)
+		if err != nil {
+			return errors.InvalidArgument("--base", baseName, "a branch, tag, or commit of origin")
+		}
+		base = &resolved
+		startBranch = base.StartPoint
+	}
+	if branch, ok := flags["branch"]; ok {
+		startBranch = branch
+		if hasPushTo {
+			fmt.Printf("Creating worker '%s' in repo '%s' to iterate on branch '%s'\n", workerName, repoName, pushTo)
+		} else {
+			fmt.Printf("Creating worker '%s' in repo '%s' from branch '%s'\n", workerName, repoName, branch)
+		}
+	} else if base != nil {
+		fmt.Printf("Creating worker '%s' in repo '%s' on base '%s' (%.12s)\n", workerName, repoName, base.Ref, base.Commit)
+	} else {
+		fmt.Printf("Creating worker '%s' in repo '%s'\n", workerName, repoName)
+	}
+	fmt.Printf("Task: %s\n", task)
+
+	// Create worktree
+	wt := c.agentWorktreeManager(repoName, state.AgentTypeWorker)
+	wtPath := c.paths.AgentWorktree(repoName, workerName)
+
+	var branchName string
+	if hasPushTo {
+		// When --push-to is specified, we're iterating on an existing PR branch
+		// Create a worktree that checks out the remote branch into a local branch
+		branchName = pushTo
+		fmt.Printf("Creating worktree at: %s (checking out %s)\n", wtPath, startBranch)
+	} else {
+		// Normal case: create a new branch for this worker
+		file, err := loadConfigFile(c.paths.ConfigFile())
+		if err != nil {
+			return err
+		}
+		branchName = file.BranchPrefix(repoName) + workerName
+		fmt.Printf("Creating worktree at: %s\n", wtPath)
+	}
+	// Take a pre-bootstrapped worktree from the repo's warm pool if one is
+	// ready; otherwise (or when starting from a specific branch) create one
+	claimed := false
+	if _, hasBranch := flags["branch"]; !hasBranch && !hasPushTo {
+		claimStart := time.Now()
+		resp, err := c.sendDaemonRequest("claim_warm_worktree", map[string]interface{}{
+			"repo":        repoName,
+			"agent":       workerName,
+			"branch":      branchName,
+			"start_point": startBranch,
+		})
+		if err == nil {
+			data, _ := resp.Data.(map[string]interface{})
+			claimed, _ = data["claimed"].(bool)
+		}
+		if claimed {
+			c.recordStep("worktree claim", claimStart, nil)
+			fmt.Println("Using a worktree from the warm pool")
+		}
+	}
+	if !claimed {
+		// With --push-to, -b creates a local branch tracking the remote one
+		wtStart := time.Now()
+		err = wt.CreateNewBranch(wtPath, branchName, startBranch)
+		c.recordStep("worktree create", wtStart, err)
+		if err != nil {
+			return errors.WorktreeCreationFailed(err)
+		}
+	}
+
+	// Provision scoped git credentials so the worker can only push its own branch
+	if tokenFile, ok := flags["git-token-file"]; ok {
+		fmt.Println("Provisioning scoped git credentials...")
+		if !worktree.WorktreeConfigEnabled(wtPath) {
+			fmt.Println("Note: enabling extensions.worktreeConfig on the repository; git older than 2.20 will refuse it")
+		}
+		creds := worktree.ScopedCredentials{
+			TokenFile:     tokenFile,
+			AllowedBranch: branchName,
+		}
+		if err := worktree.ProvisionScopedCredentials(wtPath, creds); err != nil {
+			return errors.Wrap(errors.CategoryConfig, "failed to provision scoped git credentials", err).
+				WithSuggestion("use a fine-grained token limited to this repository, readable from --git-token-file, and an HTTPS origin")
+		}
+	}
+
+	// Scope the task to a monorepo sub-project: sparse checkout, branch guard,
+	// and prompt all follow the path's preset from .multiclaude/subprojects.json
+	var subproject *scope.Preset
+	if subPath, ok := flags["path"]; ok {
+		preset, err := scope.Resolve(wtPath, subPath)
+		if err != nil {
+			return errors.Wrap(errors.CategoryConfig, "invalid --path", err)
+		}
+		if info, err := os.Stat(filepath.Join(wtPath, preset.Path)); err != nil || !info.IsDir() {
+			return errors.InvalidArgument("--path", subPath, "a directory in the repository")
+		}
+		fmt.Printf("Scoping worktree to: %s\n", strings.Join(preset.Paths(), ", "))
+		if err := worktree.SparseCheckout(wtPath, preset.Paths()); err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to set up sparse checkout", err)
+		}
+		subproject = &preset
+	}
+
+	// Run the worker definition's probe so a workspace that doesn't even
+	// build fails here instead of an hour into the task
+	if flags["skip-probe"] != "true" {
+		if def, err := c.findAgentDefinition(repoName, repoPath, "worker"); err == nil && def.Probe != "" {
+			fmt.Printf("Running probe: %s\n", def.Probe)
+			probeStart := time.Now()
+			err := def.RunProbe(context.Background(), wtPath)
+			c.recordStep("probe", probeStart, err)
+			if err != nil {
+				wt.Remove(wtPath, true)
+				if !hasPushTo {
+					wt.DeleteBranch(branchName)
+				}
+				return errors.Wrap(errors.CategoryRuntime, "worker environment probe failed", err).
+					WithSuggestion("fix the repository so the probe passes, or rerun with --skip-probe")
+			}
+		}
+	}
+
+	// Get repository info to determine tmux session
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting repo info", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to get repo info", fmt.Errorf("%s", resp.Error))
+	}
+
+	// Get tmux session name
+	tmuxSession := c.tmuxSession(repoName)
+
+	// Headless workers skip tmux entirely: the daemon runs their Claude as
+	// its own child process. Under a multiplexer the daemon hosts, the
+	// daemon creates the worker's window and starts Claude once the worker
+	// is registered.
+	headless := flags["headless"] == "true"
+	tmuxWindow := workerName
+	var m mux.Multiplexer
+	if headless {
+		tmuxWindow = ""
+	} else if m = localMultiplexer(client); m != nil {
+		// Ensure tmux session exists before creating window
+		// This handles cases where the session was killed or daemon didn't restore it
+		ctx := context.Background()
+		hasSession, err := m.HasSession(ctx, tmuxSession)
+		if err != nil {
+			return errors.TmuxOperationFailed("check session", err)
+		}
+
+		// Create tmux window for worker (detached so it doesn't switch focus)
+		if !hasSession {
+			fmt.Printf("Tmux session '%s' not found, creating it with window: %s\n", tmuxSession, workerName)
+			err = m.CreateSessionAt(ctx, tmuxSession, workerName, wtPath)
+		} else {
+			fmt.Printf("Creating tmux window: %s\n", workerName)
+			err = m.CreateWindowAt(ctx, tmuxSession, workerName, wtPath)
+		}
+		if err != nil {
+			return errors.TmuxOperationFailed("create window", err)
+		}
+	}
+	daemonWindow := !headless && m == nil
+
+	// Generate session ID for worker
+	workerSessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate worker session ID: %w", err)
+	}
+
+	// Write prompt file for worker (with push-to config if specified), or
+	// reuse the exact prompt of a snapshot being replayed
+	var workerPromptFile string
+	if replay != nil {
+		workerPromptFile, err = c.savePromptToFile(workerName, replayPrompt)
+	} else {
+		workerConfig := WorkerConfig{Subproject: subproject, Base: base}
+		if hasPushTo {
+			workerConfig.PushToBranch = pushTo
+		}
+		workerPromptFile, err = c.writeWorkerPromptFile(repoPath, workerName, workerConfig)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write worker prompt: %w", err)
+	}
+
+	// Record exactly what the worker is spawned with so `work rerun` can
+	// replay it
+	snap, err := c.snapshotWorker(repoName, repoPath, workerName, task, startBranch, workerPromptFile, flags, replay)
+	if err != nil {
+		fmt.Printf("Warning: failed to snapshot worker spawn: %v\n", err)
+		snap = &snapshot.Snapshot{}
+	}
+
+	// Copy hooks configuration if it exists
+	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
+		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	}
+
+	// Start Claude in worker window with initial task (skip in test mode).
+	// The daemon starts a headless worker, or one in a multiplexer it hosts,
+	// once it is registered.
+	var workerPID int
+	initialMessage := fmt.Sprintf("Task: %s", task)
+	if m != nil && os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		// Resolve claude binary
+		claudeBinary, err := c.getClaudeBinary()
+		if err != nil {
+			return fmt.Errorf("failed to resolve claude binary: %w", err)
+		}
+
+		fmt.Println("Starting Claude Code in worker window...")
+		pid, err := c.startClaudeInWindow(m, claudeBinary, tmuxSession, workerName, wtPath, workerSessionID, workerPromptFile, repoName, state.AgentTypeWorker, initialMessage)
+		if err != nil {
+			return fmt.Errorf("failed to start worker Claude: %w", err)
+		}
+		workerPID = pid
+
+		// Set up output capture for worker
+		if err := c.setupOutputCapture(m, tmuxSession, workerName, repoName, workerName, "worker"); err != nil {
+			fmt.Printf("Warning: failed to setup output capture for worker: %v\n", err)
+		}
+	}
+
+	// Scoped tasks default to a branch guard limited to the sub-project
+	allowedPaths := splitCommaList(flags["allowed-paths"])
+	var scopePaths []interface{}
+	if subproject != nil {
+		if len(allowedPaths) == 0 {
+			for _, p := range subproject.AllowedPaths() {
+				allowedPaths = append(allowedPaths, p)
+			}
+		}
+		for _, p := range subproject.Paths() {
+			scopePaths = append(scopePaths, p)
+		}
+	}
+
+	// Register worker with daemon
+	addArgs := map[string]interface{}{
+		"repo":                repoName,
+		"agent":               workerName,
+		"type":                "worker",
+		"worktree_path":       wtPath,
+		"tmux_window":         tmuxWindow,
+		"task":                task,
+		"session_id":          workerSessionID,
+		"pid":                 workerPID,
+		"allowed_paths":       allowedPaths,
+		"scope_paths":         scopePaths,
+		"labels":              splitCommaList(flags["label"]),
+		"time_budget_seconds": timeBudget.Seconds(),
+		"prompt_sha256":       snap.PromptSHA256,
+		"model":               snap.Model,
+		"base":                baseRefName(base),
+		"base_branch":         baseBranchName(base),
+		"priority":            string(priority),
+	}
+	if headless || daemonWindow {
+		addArgs["headless"] = headless
+		addArgs["start_window"] = daemonWindow
+		addArgs["prompt_file"] = workerPromptFile
+		addArgs["initial_message"] = initialMessage
+	}
+	resp, err = client.Send(socket.Request{
+		Command: "add_agent",
+		Args:    addArgs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register worker: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to register worker: %s", resp.Error)
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Worker created successfully!")
+	fmt.Printf("  Name: %s\n", workerName)
+	fmt.Printf("  Branch: %s\n", branchName)
+	fmt.Printf("  Worktree: %s\n", wtPath)
+	if priority != "" {
+		fmt.Printf("  Priority: %s\n", priority)
+	}
+	if timeBudget > 0 {
+		fmt.Printf("  Deadline: %s (in %s)\n", time.Now().Add(timeBudget).Format(time.Kitchen), timeBudget)
+	}
+	if subproject != nil {
+		fmt.Printf("  Scope: %s\n", subproject.Path)
+	}
+	if base != nil {
+		fmt.Printf("  Base: %s\n", base.Ref)
+	}
+	if hasPushTo {
+		fmt.Printf("  Mode: Push to existing PR branch (%s)\n", pushTo)
+	}
+	if _, ok := flags["git-token-file"]; ok {
+		fmt.Printf("  Credentials: scoped token (pushes limited to %s by a pre-push hook)\n", branchName)
+	}
+	if headless || daemonWindow {
+		if headless {
+			fmt.Println("  Mode: headless")
+		}
+		fmt.Printf("\nFollow its output: multiclaude logs %s --follow\n", workerName)
+		return nil
+	}
+	fmt.Printf("\nAttach to worker: tmux select-window -t %s:%s\n", tmuxSession, workerName)
+	fmt.Printf("Or use: multiclaude attach %s\n", workerName)
+
+	return nil
+}
+
+// repoDefaultBase returns the base a repository's new workers build on when
+// no --base is given, or "" for the default branch
+func (c *CLI) repoDefaultBase(repoName string) string {
+	resp, err := c.sendDaemonRequest("get_repo_config", map[string]interface{}{
+		"name": repoName,
+	})
+	if err != nil {
+		return ""
+	}
+	configMap, _ := resp.Data.(map[string]interface{})
+	base, _ := configMap["default_base"].(string)
+	return base
+}
+
+// baseRefName returns the ref a worker's base was given as, or "" without one
+func baseRefName(base *worktree.BaseRef) string {
+	if base == nil {
+		return ""
+	}
+	return base.Ref
+}
+
+// baseBranchName returns the remote branch of a worker's base, or "" if it
+// has none
+func baseBranchName(base *worktree.BaseRef) string {
+	if base == nil {
+		return ""
+	}
+	return base.Branch
+}
+
+// snapshotFlagsSkipped are work options that describe how a spawn was
+// invoked rather than what it was, so they are not recorded in its snapshot
+var snapshotFlagsSkipped = map[string]bool{"name": true, "group": true, "repo": true, "replay": true}
+
+// snapshotWorker saves the record of a worker spawn to its snapshot
+// directory. A replayed spawn keeps the definition of the original.
+func (c *CLI) snapshotWorker(repoName, repoPath, workerName, task, startBranch, promptFile string, flags map[string]string, replay *snapshot.Snapshot) (*snapshot.Snapshot, error) {
+	prompt, err := os.ReadFile(promptFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worker prompt: %w", err)
+	}
+
+	snap := &snapshot.Snapshot{
+		Agent:     workerName,
+		Repo:      repoName,
+		Type:      string(state.AgentTypeWorker),
+		CreatedAt: time.Now(),
+		Task:      task,
+		Flags:     make(map[string]string),
+	}
+	for key, value := range flags {
+		if !snapshotFlagsSkipped[key] {
+			snap.Flags[key] = value
+		}
+	}
+
+	cmd := gitCommand("rev-parse", "--verify", startBranch+"^{commit}")
+	cmd.Dir = repoPath
+	if output, err := cmd.Output(); err == nil {
+		snap.BaseCommit = strings.TrimSpace(string(output))
+	}
+
+	if replay != nil {
+		snap.Definition = replay.Definition
+	} else {
+		def, err := c.findAgentDefinition(repoName, repoPath, "worker")
+		if err != nil {
+			return nil, err
+		}
+		snap.Definition = snapshot.Definition{
+			Name:   def.Name,
+			Source: string(def.Source),
+			Path:   def.SourcePath,
+			SHA256: snapshot.Hash(def.Content),
+		}
+	}
+
+	if tmpl, err := launch.Load(repoPath, string(state.AgentTypeWorker)); err == nil {
+		snap.Launch = tmpl
+		snap.Model = snapshot.ModelFromArgs(tmpl.Args)
+	}
+
+	if err := snapshot.Save(c.paths.SnapshotDir(repoName, workerName), snap, string(prompt)); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// rerunArgs rebuilds the `work` arguments that replay snap. Unless latest is
+// set the worker starts from the snapshot's base commit; a --push-to spawn
+// always starts from its remote branch.
+func rerunArgs(snap *snapshot.Snapshot, snapshotDir, name string, latest bool) []string {
+	args := []string{snap.Task, "--repo=" + snap.Repo, "--replay=" + snapshotDir}
+	if name != "" {
+		args = append(args, "--name="+name)
+	}
+
+	flags := make(map[string]string, len(snap.Flags))
+	for key, value := range snap.Flags {
+		flags[key] = value
+	}
+	if _, hasPushTo := flags["push-to"]; !hasPushTo {
+		if latest {
+			delete(flags, "branch")
+		} else if snap.BaseCommit != "" {
+			flags["branch"] = snap.BaseCommit
+		}
+	}
+
+	keys := make([]string, 0, len(flags))
+	for key := range flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--"+key+"="+flags[key])
+	}
+	return args
+}
+
+// rerunWorker spawns a new worker identical to an earlier one: same task,
+// options, base commit, and prompt
+func (c *CLI) rerunWorker(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude work rerun <worker-name> [--name <new-name>] [--repo <repo>] [--latest]")
+	}
+	workerName := posArgs[0]
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	dir := c.paths.SnapshotDir(repoName, workerName)
+	snap, _, err := snapshot.Load(dir)
+	if os.IsNotExist(err) {
+		return errors.New(errors.CategoryNotFound, fmt.Sprintf("no spawn snapshot for worker '%s' in repository '%s'", workerName, repoName)).
+			WithSuggestion("snapshots are recorded for workers created with this version of multiclaude")
+	}
+	if err != nil {
+		return errors.Wrap(errors.CategoryConfig, "failed to load spawn snapshot", err)
+	}
+
+	// The replay uses the snapshot's prompt; say so if the definition moved on
+	if def, err := c.findAgentDefinition(repoName, c.paths.RepoDir(repoName), snap.Definition.Name); err == nil && snapshot.Hash(def.Content) != snap.Definition.SHA256 {
+		fmt.Printf("Note: the %s agent definition changed since '%s' was spawned; replaying the original prompt\n", snap.Definition.Name, workerName)
+	}
+	if snap.Model != "" {
+		fmt.Printf("Note: '%s' ran with model %s; the current launch template decides the model of the rerun\n", workerName, snap.Model)
+	}
+
+	_, latest := flags["latest"]
+	fmt.Printf("Rerunning '%s' (prompt %s)\n", workerName, snap.PromptSHA256[:12])
+	return c.createWorker(rerunArgs(snap, dir, flags["name"], latest))
+}
+
+func (c *CLI) listWorkers(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	reqArgs := map[string]interface{}{
+		"repo": repoName,
+		"rich": true,
+	}
+	for _, key := range []string{"status", "label", "sort"} {
+		if v := flags[key]; v != "" {
+			reqArgs[key] = v
+		}
+	}
+	for _, key := range []string{"limit", "offset"} {
+		if v, ok := flags[key]; ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return errors.InvalidArgument("--"+key, v, "a non-negative integer")
+			}
+			reqArgs[key] = n
+		}
+	}
+	// Paginate over workers only so pages aren't padded with the workspace
+	if _, ok := reqArgs["limit"]; ok {
+		reqArgs["type"] = "worker"
+	} else if _, ok := reqArgs["offset"]; ok {
+		reqArgs["type"] = "worker"
+	}
+
+	resp, err := c.sendDaemonRequest("list_agents", reqArgs)
+	if err != nil {
+		return err
+	}
+
+	// Paginated responses wrap the list with totals
+	var agents []interface{}
+	total, nextOffset := -1, -1
+	switch data := resp.Data.(type) {
+	case []interface{}:
+		agents = data
+	case map[string]interface{}:
+		agents, _ = data["agents"].([]interface{})
+		if v, ok := data["total"].(float64); ok {
+			total = int(v)
+		}
+		if v, ok := data["next_offset"].(float64); ok {
+			nextOffset = int(v)
+		}
+	default:
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+
+	// Filter for workers and workspace
+	workers := []map[string]interface{}{}
+	var workspace map[string]interface{}
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			if agentType == "worker" {
+				workers = append(workers, agentMap)
+			} else if agentType == "workspace" {
+				workspace = agentMap
+			}
+		}
+	}
+
+	// Show workspace first if it exists
+	if workspace != nil {
+		format.Header("Workspace in '%s':", repoName)
+		status, _ := workspace["status"].(string)
+		statusCell := formatAgentStatusCell(status)
+		fmt.Printf("  workspace ")
+		fmt.Print(statusCell.Text)
+		fmt.Println()
+		fmt.Println()
+	}
+
+	if len(workers) == 0 {
+		fmt.Printf("No workers in repository '%s'\n", repoName)
+		format.Dimmed("\nCreate a worker with: multiclaude work <task>")
+		return nil
+	}
+
+	if total >= 0 {
+		format.Header("Workers in '%s' (%d of %d):", repoName, len(workers), total)
+	} else {
+		format.Header("Workers in '%s' (%d):", repoName, len(workers))
+	}
+	fmt.Println()
+
+	// Row numbers let `multiclaude respond --agent <#>` address a worker
+	table := format.NewColoredTable("#", "NAME", "PRI", "STATUS", "BRANCH", "DISK", "MSGS", "TASK")
+	for i, worker := range workers {
+		name, _ := worker["name"].(string)
+		priority, _ := worker["priority"].(string)
+		task, _ := worker["task"].(string)
+		status, _ := worker["status"].(string)
+		branch, _ := worker["branch"].(string)
+		msgsTotal := 0
+		if v, ok := worker["messages_total"].(float64); ok {
+			msgsTotal = int(v)
+		}
+		msgsPending := 0
+		if v, ok := worker["messages_pending"].(float64); ok {
+			msgsPending = int(v)
+		}
+
+		// Format status with color
+		statusCell := formatAgentStatusCell(status)
+
+		// Format branch
+		branchCell := format.ColorCell(branch, format.Cyan)
+		if branch == "" {
+			branchCell = format.ColorCell("-", format.Dim)
+		}
+
+		// Disk usage is measured every few minutes, so new workers show none yet
+		diskCell := format.ColorCell("-", format.Dim)
+		if v, ok := worker["disk_bytes"].(float64); ok {
+			diskCell = format.Cell(format.Bytes(int64(v)))
+		}
+
+		// Format message count
+		msgStr := format.MessageBadge(msgsPending, msgsTotal)
+
+		// Truncate task
+		truncTask := format.Truncate(task, 40)
+
+		table.AddRow(
+			format.ColorCell(strconv.Itoa(i+1+offsetFromFlags(flags)), format.Dim),
+			format.Cell(name),
+			formatPriorityCell(priority),
+			statusCell,
+			branchCell,
+			diskCell,
+			format.Cell(msgStr),
+			format.Cell(truncTask),
+		)
+	}
+	table.Print()
+
+	if nextOffset >= 0 {
+		format.Dimmed("\nMore workers: multiclaude work list --offset %d --limit %s", nextOffset, flags["limit"])
+	}
+
+	return nil
+}
+
+// workerStatus shows each worker's branch, commits ahead of and behind its
+// base, uncommitted changes, window liveness and last activity
+func (c *CLI) workerStatus(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	resp, err := c.sendDaemonRequest("worker_status", map[string]interface{}{
+		"repo": repoName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if flags["json"] == "true" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resp.Data)
+	}
+
+	workers, _ := resp.Data.([]interface{})
+	if len(workers) == 0 {
+		fmt.Printf("No workers in repository '%s'\n", repoName)
+		return nil
+	}
+
+	format.Header("Worker status in '%s':", repoName)
+	fmt.Println()
+
+	table := format.NewColoredTable("NAME", "BRANCH", "AHEAD", "BEHIND", "CHANGES", "WINDOW", "LAST ACTIVITY")
+	for _, w := range workers {
+		worker, ok := w.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := worker["name"].(string)
+		window, _ := worker["window"].(string)
+
+		windowCell := format.ColorCell(window, format.Green)
+		switch {
+		case worker["completed"] == true:
+			windowCell = format.ColorCell(window+" (completed)", format.Dim)
+		case window == "headless":
+			windowCell = format.ColorCell(window, format.Dim)
+		case window != "alive":
+			windowCell = format.ColorCell(window, format.Red)
+		}
+
+		activity := "-"
+		if ts, _ := worker["last_activity"].(string); ts != "" {
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				activity = format.TimeAgo(t)
+			}
+		}
+
+		if errMsg, _ := worker["error"].(string); errMsg != "" {
+			table.AddRow(
+				format.Cell(name),
+				format.ColorCell(format.Truncate(errMsg, 40), format.Red),
+				format.ColorCell("-", format.Dim),
+				format.ColorCell("-", format.Dim),
+				format.ColorCell("-", format.Dim),
+				windowCell,
+				format.Cell(activity),
+			)
+			continue
+		}
+
+		branch, _ := worker["branch"].(string)
+		branchCell := format.ColorCell(branch, format.Cyan)
+		if gitState, _ := worker["git_state"].(string); gitState != "" {
+			branchCell = format.ColorCell(strings.TrimSpace(branch+" ("+gitState+")"), format.Yellow)
+		}
+		ahead, _ := worker["ahead"].(float64)
+		behind, _ := worker["behind"].(float64)
+		behindCell := format.Cell(strconv.Itoa(int(behind)))
+		if behind > 0 {
+			behindCell = format.ColorCell(strconv.Itoa(int(behind)), format.Yellow)
+		}
+		changesCell := format.ColorCell("clean", format.Dim)
+		if uncommitted, _ := worker["uncommitted"].(bool); uncommitted {
+			changesCell = format.ColorCell("uncommitted", format.Yellow)
+		}
+		if stale, _ := worker["stale_submodules"].([]interface{}); len(stale) > 0 {
+			changes := fmt.Sprintf("%d submodules stale", len(stale))
+			if len(stale) == 1 {
+				changes = "1 submodule stale"
+			}
+			if uncommitted, _ := worker["uncommitted"].(bool); uncommitted {
+				changes = "uncommitted, " + changes
+			}
+			changesCell = format.ColorCell(changes, format.Yellow)
+		}
+
+		table.AddRow(
+			format.Cell(name),
+			branchCell,
+			format.Cell(strconv.Itoa(int(ahead))),
+			behindCell,
+			changesCell,
+			windowCell,
+			format.Cell(activity),
+		)
+	}
+	table.Print()
+	return nil
+}
+
+// formatPriorityCell highlights urgent task priorities
+func formatPriorityCell(priority string) format.ColoredCell {
+	switch state.TaskPriority(priority) {
+	case state.TaskPriorityP0:
+		return format.ColorCell(priority, format.Red)
+	case state.TaskPriorityP1:
+		return format.ColorCell(priority, format.Yellow)
+	case "":
+		return format.ColorCell("-", format.Dim)
+	}
+	return format.ColorCell(priority, format.Dim)
+}
+
+// listAgentDefinitions lists available agent definitions for a repository
+func (c *CLI) listAgentDefinitions(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	// Get paths to agent definition directories
+	localAgentsDir := c.paths.RepoAgentsDir(repoName)
+	repoPath := c.paths.RepoDir(repoName)
+
+	// Read and merge agent definitions
+	reader := agents.NewReader(localAgentsDir, repoPath)
+	defs, err := reader.ReadAllDefinitions()
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read agent definitions", err)
+	}
+
+	if len(defs) == 0 {
+		fmt.Println("No agent definitions found.")
+		fmt.Printf("\nAgent definitions are stored in:\n")
+		fmt.Printf("  Local: %s\n", localAgentsDir)
+		fmt.Printf("  Repo:  %s/.multiclaude/agents/\n", repoPath)
+		return nil
+	}
+
+	fmt.Printf("Agent definitions for %s:\n\n", repoName)
+
+	// Create colored table
+	table := format.NewColoredTable("Name", "Source", "Title", "Description")
+
+	for _, def := range defs {
+		source := string(def.Source)
+		title := def.ParseTitle()
+		desc := def.ParseDescription()
+
+		// Truncate description if too long
+		desc = format.Truncate(desc, 50)
+
+		// Color the source based on type
+		sourceCell := format.Cell(source)
+		if def.Source == agents.SourceRepo {
+			sourceCell = format.ColorCell(source, format.Green)
+		}
+
+		table.AddRow(
+			format.Cell(def.Name),
+			sourceCell,
+			format.Cell(title),
+			format.Cell(desc),
+		)
+	}
+
+	table.Print()
+
+	return nil
+}
+
+// spawnAgentFromFile spawns an agent using a prompt file and the daemon's spawn_agent handler.
+// This is the CLI command that connects supervisor orchestration with daemon agent spawning.
+func (c *CLI) spawnAgentFromFile(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	// Get required parameters
+	agentName, ok := flags["name"]
+	if !ok || agentName == "" {
+		return errors.InvalidUsage("--name is required")
+	}
+
+	agentClass, ok := flags["class"]
+	if !ok || agentClass == "" {
+		return errors.InvalidUsage("--class is required (persistent or ephemeral)")
+	}
+	if agentClass != "persistent" && agentClass != "ephemeral" {
+		return errors.InvalidUsage("--class must be 'persistent' or 'ephemeral'")
+	}
+
+	// The prompt comes either from a file or from a registered definition
+	promptFile := flags["prompt-file"]
+	definition := flags["definition"]
+	if promptFile == "" && definition == "" {
+		return errors.InvalidUsage("--prompt-file is required (or --definition <name>)")
+	}
+	if promptFile != "" && definition != "" {
+		return errors.InvalidUsage("--prompt-file and --definition are mutually exclusive")
+	}
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	// Get optional task parameter
+	task := flags["task"]
+
+	// Send spawn_agent request to daemon
+	client := c.daemonClient()
+	reqArgs := map[string]interface{}{
+		"repo":  repoName,
+		"name":  agentName,
+		"class": agentClass,
+	}
+	if definition != "" {
+		reqArgs["definition"] = definition
+	} else {
+		promptContent, err := os.ReadFile(promptFile)
+		if err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to read prompt file", err)
+		}
+		reqArgs["prompt"] = string(promptContent)
+	}
+	if task != "" {
+		reqArgs["task"] = task
+	}
+	if flags["read-only"] == "true" {
+		reqArgs["read_only"] = true
+	}
+	if flags["headless"] == "true" {
+		reqArgs["headless"] = true
+	}
+
+	resp, err := client.Send(socket.Request{
+		Command: "spawn_agent",
+		Args:    reqArgs,
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("spawning agent", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to spawn agent", fmt.Errorf("%s", resp.Error))
+	}
+
+	fmt.Printf("Agent '%s' spawned successfully (class: %s)\n", agentName, agentClass)
+	return nil
+}
+
+// resetAgentDefinitions deletes the local agent definitions and re-copies from templates.
+func (c *CLI) resetAgentDefinitions(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	// Get agents directory path
+	agentsDir := c.paths.RepoAgentsDir(repoName)
+
+	// Check if directory exists
+	if _, err := os.Stat(agentsDir); os.IsNotExist(err) {
+		fmt.Printf("No agent definitions found at %s\n", agentsDir)
+		fmt.Println("Creating new definitions from templates...")
+	} else {
+		// Remove existing directory
+		fmt.Printf("Removing existing agent definitions at %s...\n", agentsDir)
+		if err := os.RemoveAll(agentsDir); err != nil {
+			return errors.Wrap(errors.CategoryRuntime, "failed to remove agent definitions", err)
+		}
+	}
+
+	// Copy templates
+	if err := templates.CopyAgentTemplates(agentsDir); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to copy agent templates", err)
+	}
+
+	// List what was copied
+	entries, err := os.ReadDir(agentsDir)
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to list agent definitions", err)
+	}
+
+	fmt.Printf("Reset complete. Agent definitions in %s:\n", agentsDir)
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".md" {
+			fmt.Printf("  - %s\n", entry.Name())
+		}
+	}
+
+	return nil
+}
+
+func (c *CLI) showHistory(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	// Get limit from flags (default 10)
+	limit := 10
+	if n, ok := flags["n"]; ok {
+		if v, err := strconv.Atoi(n); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	// Get filter options
+	statusFilter := flags["status"] // Filter by status (merged, open, closed, failed, no-pr)
+	searchQuery := flags["search"]  // Search in task descriptions
+	showFull := flags["full"] == "true"
+
+	// Validate status filter if provided
+	validStatuses := map[string]bool{
+		"merged": true, "open": true, "closed": true, "failed": true, "no-pr": true,
+	}
+	if statusFilter != "" && !validStatuses[statusFilter] {
+		return errors.InvalidUsage(fmt.Sprintf("invalid status filter: %s (valid values: merged, open, closed, failed, no-pr)", statusFilter))
+	}
+
+	// When filtering, fetch more history to ensure we get enough results
+	fetchLimit := limit
+	if statusFilter != "" || searchQuery != "" {
+		fetchLimit = limit * 10 // Fetch more to allow for filtering
+		if fetchLimit > 100 {
+			fetchLimit = 100
+		}
+	}
+
+	// Get task history from daemon
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "task_history",
+		Args: map[string]interface{}{
+			"repo":  repoName,
+			"limit": fetchLimit,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting task history", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to get task history", fmt.Errorf("%s", resp.Error))
+	}
+
+	history, ok := resp.Data.([]interface{})
+	if !ok || len(history) == 0 {
+		fmt.Printf("No task history for repository '%s'\n", repoName)
+		format.Dimmed("\nCreate workers with: multiclaude work <task>")
+		return nil
+	}
+
+	// Query GitHub for PR status for each task with a branch
+	repoPath := c.paths.RepoDir(repoName)
+
+	// Build filtered header
+	headerParts := []string{fmt.Sprintf("Task History for '%s'", repoName)}
+	if statusFilter != "" {
+		headerParts = append(headerParts, fmt.Sprintf("status=%s", statusFilter))
+	}
+	if searchQuery != "" {
+		headerParts = append(headerParts, fmt.Sprintf("search=%q", searchQuery))
+	}
+	format.Header("%s:", strings.Join(headerParts, ", "))
+	fmt.Println()
+
+	// First pass: collect entries with details to show after table
+	type entryDetails struct {
+		name          string
+		summary       string
+		failureReason string
+	}
+	var detailsToShow []entryDetails
+
+	table := format.NewColoredTable("NAME", "STATUS", "PR", "COMPLETED", "TASK")
+	displayedCount := 0
+	for _, item := range history {
+		// Stop once we've displayed enough
+		if displayedCount >= limit {
+			break
+		}
+
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		name, _ := entry["name"].(string)
+		task, _ := entry["task"].(string)
+		branch, _ := entry["branch"].(string)
+		prURL, _ := entry["pr_url"].(string)
+		completedAt, _ := entry["completed_at"].(string)
+		summary, _ := entry["summary"].(string)
+		failureReason, _ := entry["failure_reason"].(string)
+		storedStatus, _ := entry["status"].(string)
+
+		// Try to get PR status from GitHub if we have a branch
+		prStatus, prLink := c.getPRStatusForBranch(repoPath, branch, prURL)
+
+		// Use stored status if it indicates failure
+		if storedStatus == "failed" {
+			prStatus = "failed"
+		}
+
+		// Apply status filter
+		if statusFilter != "" {
+			effectiveStatus := prStatus
+			if effectiveStatus == "" {
+				effectiveStatus = "no-pr"
+			}
+			if effectiveStatus != statusFilter {
+				continue
+			}
+		}
+
+		// Apply search filter (case-insensitive)
+		if searchQuery != "" {
+			lowerQuery := strings.ToLower(searchQuery)
+			lowerTask := strings.ToLower(task)
+			lowerName := strings.ToLower(name)
+			if !strings.Contains(lowerTask, lowerQuery) && !strings.Contains(lowerName, lowerQuery) {
+				continue
+			}
+		}
+
+		displayedCount++
+
+		// Collect entries with summary or failure for detailed display
+		if summary != "" || failureReason != "" {
+			detailsToShow = append(detailsToShow, entryDetails{
+				name:          name,
+				summary:       summary,
+				failureReason: failureReason,
+			})
+		}
+
+		// Format status with color
+		var statusCell format.ColoredCell
+		switch prStatus {
+		case "merged":
+			statusCell = format.ColorCell("merged", format.Green)
+		case "open":
+			statusCell = format.ColorCell("open", format.Yellow)
+		case "closed":
+			statusCell = format.ColorCell("closed", format.Red)
+		case "failed":
+			statusCell = format.ColorCell("failed", format.Red)
+		default:
+			statusCell = format.ColorCell("no-pr", format.Dim)
+		}
+
+		// Format PR link
+		prCell := format.ColorCell("-", format.Dim)
+		if prLink != "" {
+			// Extract just the PR number for display
+			prCell = format.ColorCell(prLink, format.Cyan)
+		}
+
+		// Format completed time
+		completedCell := format.ColorCell("-", format.Dim)
+		if completedAt != "" {
+			if t, err := time.Parse(time.RFC3339, completedAt); err == nil {
+				completedCell = format.Cell(format.TimeAgo(t))
+			}
+		}
+
+		// Format task - show full or truncate
+		displayTask := task
+		if !showFull {
+			displayTask = format.Truncate(task, 50)
+		}
+
+		table.AddRow(
+			format.Cell(name),
+			statusCell,
+			prCell,
+			completedCell,
+			format.Cell(displayTask),
+		)
+	}
+
+	// Show message if no results after filtering
+	if displayedCount == 0 {
+		if statusFilter != "" || searchQuery != "" {
+			fmt.Printf("No tasks match the filter criteria\n")
+		}
+		return nil
+	}
+
+	table.Print()
+
+	// Print detailed summary/failure section if any entries have them
+	if len(detailsToShow) > 0 {
+		fmt.Println()
+		format.Header("Details:")
+		for _, d := range detailsToShow {
+			format.Bold.Printf("\n%s:\n", d.name)
+			if d.summary != "" {
+				format.Dimmed("  Summary: %s", d.summary)
+			}
+			if d.failureReason != "" {
+				format.Red.Printf("  Failure: %s\n", d.failureReason)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getPRStatusForBranch queries GitHub for the PR status of a branch
+func (c *CLI) getPRStatusForBranch(repoPath, branch, existingPRURL string) (status, prLink string) {
+	// If we already have a PR URL, just return it formatted
+	if existingPRURL != "" {
+		// Extract PR number from URL for shorter display
+		parts := strings.Split(existingPRURL, "/")
+		if len(parts) > 0 {
+			prNum := parts[len(parts)-1]
+			return "unknown", "#" + prNum
+		}
+		return "unknown", existingPRURL
+	}
+
+	// If no branch, nothing to query
+	if branch == "" {
+		return "no-pr", ""
+	}
+
+	// Query GitHub for PR associated with this branch using gh CLI
+	cmd := exec.Command("gh", "pr", "list", "--head", branch, "--state", "all", "--json", "number,state,url", "--limit", "1")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "no-pr", ""
+	}
+
+	// Parse JSON output
+	var prs []struct {
+		Number int    `json:"number"`
+		State  string `json:"state"`
+		URL    string `json:"url"`
+	}
+	if err := json.Unmarshal(output, &prs); err != nil || len(prs) == 0 {
+		return "no-pr", ""
+	}
+
+	pr := prs[0]
+	prLink = fmt.Sprintf("#%d", pr.Number)
+
+	switch strings.ToLower(pr.State) {
+	case "merged":
+		return "merged", prLink
+	case "open":
+		return "open", prLink
+	case "closed":
+		return "closed", prLink
+	default:
+		return "unknown", prLink
+	}
+}
+
+func (c *CLI) removeWorker(args []string) error {
+	flags, remainingArgs := ParseFlags(args)
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	// Get worker info
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting worker info", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to get worker info", fmt.Errorf("%s", resp.Error))
+	}
+
+	agents, _ := resp.Data.([]interface{})
+
+	// Determine worker name - from args or interactive selection
+	var workerName string
+	if len(remainingArgs) > 0 {
+		workerName = remainingArgs[0]
+	} else {
+		// Interactive selection
+		items := agentsToSelectableItems(agents, []string{"worker"})
+		if len(items) == 0 {
+			return errors.NoWorkersFound(repoName)
+		}
+		selected, err := SelectFromList("Select worker to remove:", items)
+		if err != nil {
+			return err
+		}
+		if selected == "" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+		workerName = selected
+	}
+
+	fmt.Printf("Removing worker '%s' from repo '%s'\n", workerName, repoName)
+
+	// Find worker
+	var workerInfo map[string]interface{}
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			if name, _ := agentMap["name"].(string); name == workerName {
+				workerInfo = agentMap
+				break
+			}
+		}
+	}
+
+	if workerInfo == nil {
+		return errors.AgentNotFound("worker", workerName, repoName)
+	}
+
+	// Get worktree path
+	wtPath := workerInfo["worktree_path"].(string)
+
+	// Check for uncommitted changes
+	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+	if err != nil {
+		fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
+	} else if hasUncommitted {
+		fmt.Println("\nWarning: Worker has uncommitted changes!")
+		fmt.Println("Files may be lost if you continue with cleanup.")
+		fmt.Print("Continue with cleanup? [y/N]: ")
+
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Cleanup cancelled")
+			return nil
+		}
+	}
+
+	// Check for unpushed commits
+	if err := checkUnpushedCommits(wtPath, "Worker", "cleanup"); err != nil {
+		return nil
+	}
+
+	// Kill tmux window; the daemon kills windows of a multiplexer it hosts
+	// when the agent is removed
+	tmuxSession := c.tmuxSession(repoName)
+	tmuxWindow, _ := workerInfo["tmux_window"].(string)
+	if m := localMultiplexer(client); m != nil && tmuxWindow != "" {
+		fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
+		if err := m.KillWindow(context.Background(), tmuxSession, tmuxWindow); err != nil {
+			fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
+		}
+	}
+
+	// Remove worktree
+	repoPath := c.paths.RepoDir(repoName)
+	wt := worktree.NewManager(repoPath)
+
+	fmt.Printf("Removing worktree: %s\n", wtPath)
+	if err := wt.Remove(wtPath, false); err != nil {
+		fmt.Printf("Warning: failed to remove worktree: %v\n", err)
+	}
+
+	// Unregister from daemon
+	resp, err = client.Send(socket.Request{
+		Command: "remove_agent",
+		Args: map[string]interface{}{
+			"repo":  repoName,
+			"agent": workerName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unregister worker: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to unregister worker: %s", resp.Error)
+	}
+
+	fmt.Println("✓ Worker removed successfully")
+	return nil
+}
+
+// Workspace command implementations
+
+// workspaceDefault handles `multiclaude workspace` with no subcommand or `multiclaude workspace <name>`
+func (c *CLI) workspaceDefault(args []string) error {
+	// If no args, list workspaces
+	if len(args) == 0 {
+		return c.listWorkspaces(args)
+	}
+
+	// If first arg looks like a workspace name (not a flag), treat as connect
+	if !strings.HasPrefix(args[0], "-") {
+		return c.connectWorkspace(args)
+	}
+
+	// Otherwise list with flags
+	return c.listWorkspaces(args)
+}
+
+// addWorkspace creates a new workspace
+func (c *CLI) addWorkspace(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude workspace add <name> [--branch <branch>]")
+	}
+
+	workspaceName := posArgs[0]
+
+	// Validate workspace name (same restrictions as branch names)
+	if err := validateWorkspaceName(workspaceName); err != nil {
+		return err
+	}
+
+	// Determine repository using standard resolution chain
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return err
+	}
+
+	// Determine branch to start from
+	startBranch := "HEAD" // Default to current branch/HEAD
+	if branch, ok := flags["branch"]; ok {
+		startBranch = branch
+		fmt.Printf("Creating workspace '%s' in repo '%s' from branch '%s'\n", workspaceName, repoName, branch)
+	} else {
+		fmt.Printf("Creating workspace '%s' in repo '%s'\n", workspaceName, repoName)
+	}
+
+	// Check if workspace already exists
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("checking existing workspaces", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to check existing workspaces", fmt.Errorf("%s", resp.Error))
+	}
+
+	agents, _ := resp.Data.([]interface{})
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			name, _ := agentMap["name"].(string)
+			if agentType == "workspace" && name == workspaceName {
+				return fmt.Errorf("workspace '%s' already exists in repo '%s'", workspaceName, repoName)
+			}
+		}
+	}
+
+	// Get repository path
+	repoPath := c.paths.RepoDir(repoName)
+
+	// Create worktree
+	wt := c.agentWorktreeManager(repoName, state.AgentTypeWorkspace)
+	wtPath := c.paths.AgentWorktree(repoName, workspaceName)
+	branchName := fmt.Sprintf("workspace/%s", workspaceName)
+
+	fmt.Printf("Creating worktree at: %s\n", wtPath)
+	if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
+		return errors.WorktreeCreationFailed(err)
+	}
+
+	// Get tmux session name
+	tmuxSession := c.tmuxSession(repoName)
+
+	// Create tmux window for workspace (detached so it doesn't switch focus)
+	fmt.Printf("Creating tmux window: %s\n", workspaceName)
+	cmd := tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", workspaceName, "-c", wtPath)
+	if err := cmd.Run(); err != nil {
+		return errors.TmuxOperationFailed("create window", err)
+	}
+
+	// Generate session ID for workspace
+	workspaceSessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate workspace session ID: %w", err)
+	}
+
+	// Write prompt file for workspace
+	workspacePromptFile, err := c.writePromptFile(repoPath, state.AgentTypeWorkspace, workspaceName)
+	if err != nil {
+		return fmt.Errorf("failed to write workspace prompt: %w", err)
+	}
+
+	// Copy hooks configuration if it exists
+	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
+		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	}
+
+	// Start Claude in workspace window (skip in test mode)
+	var workspacePID int
+	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		// Resolve claude binary
+		claudeBinary, err := c.getClaudeBinary()
+		if err != nil {
+			return fmt.Errorf("failed to resolve claude binary: %w", err)
+		}
+
+		fmt.Println("Starting Claude Code in workspace window...")
+		pid, err := c.startClaudeInWindow(tmux.NewClient(), claudeBinary, tmuxSession, workspaceName, wtPath, workspaceSessionID, workspacePromptFile, repoName, state.AgentTypeWorkspace, "")
+		if err != nil {
+			return fmt.Errorf("failed to start workspace Claude: %w", err)
+		}
+		workspacePID = pid
+
+		// Set up output capture for workspace
+		if err := c.setupOutputCapture(tmux.NewClient(), tmuxSession, workspaceName, repoName, workspaceName, "workspace"); err != nil {
+			fmt.Printf("Warning: failed to setup output capture for workspace: %v\n", err)
+		}
+	}
+
+	// Register workspace with daemon
+	resp, err = client.Send(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          repoName,
+			"agent":         workspaceName,
+			"type":          "workspace",
+			"worktree_path": wtPath,
+			"tmux_window":   workspaceName,
+			"session_id":    workspaceSessionID,
+			"pid":           workspacePID,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register workspace: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to register workspace: %s", resp.Error)
+	}
+
+	fmt.Println()
+	fmt.Println("✓ Workspace created successfully!")
+	fmt.Printf("  Name: %s\n", workspaceName)
+	fmt.Printf("  Branch: %s\n", branchName)
+	fmt.Printf("  Worktree: %s\n", wtPath)
+	fmt.Printf("\nConnect to workspace: multiclaude workspace connect %s\n", workspaceName)
+	fmt.Printf("Or use: multiclaude attach %s\n", workspaceName)
+
+	return nil
+}
+
+// removeWorkspace removes a workspace
+func (c *CLI) removeWorkspace(args []string) error {
+	flags, remainingArgs := ParseFlags(args)
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	// Get workspace info
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("getting workspace info", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to get workspace info", fmt.Errorf("%s", resp.Error))
+	}
+
+	agents, _ := resp.Data.([]interface{})
+
+	// Determine workspace name - from args or interactive selection
+	var workspaceName string
+	if len(remainingArgs) > 0 {
+		workspaceName = remainingArgs[0]
+	} else {
+		// Interactive selection
+		items := agentsToSelectableItems(agents, []string{"workspace"})
+		if len(items) == 0 {
+			return errors.NoWorkspacesFound(repoName)
+		}
+		selected, err := SelectFromList("Select workspace to remove:", items)
+		if err != nil {
+			return err
+		}
+		if selected == "" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+		workspaceName = selected
+	}
+
+	fmt.Printf("Removing workspace '%s' from repo '%s'\n", workspaceName, repoName)
+
+	// Find workspace
+	var workspaceInfo map[string]interface{}
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			name, _ := agentMap["name"].(string)
+			if agentType == "workspace" && name == workspaceName {
+				workspaceInfo = agentMap
+				break
+			}
+		}
+	}
+
+	if workspaceInfo == nil {
+		return errors.AgentNotFound("workspace", workspaceName, repoName)
+	}
+
+	// Get worktree path
+	wtPath := workspaceInfo["worktree_path"].(string)
+
+	// Check for uncommitted changes
+	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+	if err != nil {
+		fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
+	} else if hasUncommitted {
+		fmt.Println("\nWarning: Workspace has uncommitted changes!")
+		fmt.Println("Files may be lost if you continue with removal.")
+		fmt.Print("Continue with removal? [y/N]: ")
+
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Removal cancelled")
+			return nil
+		}
+	}
+
+	// Check for unpushed commits
+	if err := checkUnpushedCommits(wtPath, "Workspace", "removal"); err != nil {
+		return nil
+	}
+
+	// Kill tmux window; the daemon kills windows of a multiplexer it hosts
+	// when the agent is removed
+	tmuxSession := c.tmuxSession(repoName)
+	tmuxWindow, _ := workspaceInfo["tmux_window"].(string)
+	if m := localMultiplexer(client); m != nil && tmuxWindow != "" {
+		fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
+		if err := m.KillWindow(context.Background(), tmuxSession, tmuxWindow); err != nil {
+			fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
+		}
+	}
+
+	// Remove worktree
+	repoPath := c.paths.RepoDir(repoName)
+	wt := worktree.NewManager(repoPath)
+
+	fmt.Printf("Removing worktree: %s\n", wtPath)
+	if err := wt.Remove(wtPath, false); err != nil {
+		fmt.Printf("Warning: failed to remove worktree: %v\n", err)
+	}
+
+	// Unregister from daemon
+	resp, err = client.Send(socket.Request{
+		Command: "remove_agent",
+		Args: map[string]interface{}{
+			"repo":  repoName,
+			"agent": workspaceName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unregister workspace: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to unregister workspace: %s", resp.Error)
+	}
+
+	fmt.Println("✓ Workspace removed successfully")
+	return nil
+}
+
+// listWorkspaces lists all workspaces in a repository
+func (c *CLI) listWorkspaces(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+			"rich": true,
+		},
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("listing workspaces", err)
+	}
+
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to list workspaces", fmt.Errorf("%s", resp.Error))
+	}
+
+	agents, ok := resp.Data.([]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+
+	// Filter for workspaces
+	workspaces := []map[string]interface{}{}
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			if agentType == "workspace" {
+				workspaces = append(workspaces, agentMap)
+			}
+		}
+	}
+
+	if len(workspaces) == 0 {
+		fmt.Printf("No workspaces in repository '%s'\n", repoName)
+		format.Dimmed("\nCreate a workspace with: multiclaude workspace add <name>")
+		return nil
+	}
+
+	format.Header("Workspaces in '%s' (%d):", repoName, len(workspaces))
+	fmt.Println()
+
+	table := format.NewColoredTable("NAME", "BRANCH", "STATUS")
+	for _, ws := range workspaces {
+		name, _ := ws["name"].(string)
+		status, _ := ws["status"].(string)
+		branch, _ := ws["branch"].(string)
+
+		// Format status with color
+		statusCell := formatAgentStatusCell(status)
+
+		// Format branch
+		branchCell := format.ColorCell(branch, format.Cyan)
+		if branch == "" {
+			branchCell = format.ColorCell("-", format.Dim)
+		}
+
+		table.AddRow(
+			format.Cell(name),
+			branchCell,
+			statusCell,
+		)
+	}
+	table.Print()
+
+	return nil
+}
+
+// connectWorkspace attaches to a workspace
+func (c *CLI) connectWorkspace(args []string) error {
+	flags, remainingArgs := ParseFlags(args)
+
+	// Determine repository
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	// Get workspace info
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "list_agents",
+		Args: map[string]interface{}{
+			"repo": repoName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get workspace info: %w (is daemon running?)", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to get workspace info: %s", resp.Error)
+	}
+
+	agents, _ := resp.Data.([]interface{})
+
+	// Determine workspace name - from args or interactive selection
+	var workspaceName string
+	if len(remainingArgs) > 0 {
+		workspaceName = remainingArgs[0]
+	} else {
+		// Interactive selection
+		items := agentsToSelectableItems(agents, []string{"workspace"})
+		if len(items) == 0 {
+			return errors.NoWorkspacesFound(repoName)
+		}
+		selected, err := SelectFromList("Select workspace to connect:", items)
+		if err != nil {
+			return err
+		}
+		if selected == "" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+		workspaceName = selected
+	}
+
+	// Find workspace
+	var workspaceInfo map[string]interface{}
+	for _, agent := range agents {
+		if agentMap, ok := agent.(map[string]interface{}); ok {
+			agentType, _ := agentMap["type"].(string)
+			name, _ := agentMap["name"].(string)
+			if agentType == "workspace" && name == workspaceName {
+				workspaceInfo = agentMap
+				break
+			}
+		}
+	}
+
+	if workspaceInfo == nil {
+		return errors.WorkspaceNotFound(workspaceName, repoName)
+	}
+
+	// Get tmux session and window
+	tmuxSession := c.tmuxSession(repoName)
+	tmuxWindow := workspaceInfo["tmux_window"].(string)
+
+	// Attach to tmux
+	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
+
+	readOnly := flags["read-only"] == "true" || flags["r"] == "true"
+	tmuxArgs := []string{"attach", "-t", target}
+	if readOnly {
+		tmuxArgs = append(tmuxArgs, "-r")
+	}
+
+	cmd := tmuxCommand(tmuxArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// validateWorkspaceName validates that a workspace name follows branch name restrictions
+func validateWorkspaceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("workspace name cannot be empty")
+	}
+
+	// Git branch name restrictions
+	// - Cannot start with . or -
+	// - Cannot contain consecutive dots ..
+	// - Cannot contain \ or any of these characters: ~ ^ : ? * [ @ { } space
+	// - Cannot end with . or /
+	// - Cannot be "." or ".."
+
+	if name == "." || name == ".." {
+		return fmt.Errorf("workspace name cannot be '.' or '..'")
+	}
+
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("workspace name cannot start with '.' or '-'")
+	}
+
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, "/") {
+		return fmt.Errorf("workspace name cannot end with '.' or '/'")
+	}
+
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("workspace name cannot contain '..'")
+	}
+
+	invalidChars := []string{"\\", "~", "^", ":", "?", "*", "[", "@", "{", "}", " ", "\t", "\n"}
+	for _, char := range invalidChars {
+		if strings.Contains(name, char) {
+			return fmt.Errorf("workspace name cannot contain '%s'", char)
+		}
+	}
+
+	return nil
+}
+
+// getReposList is a helper to get the list of repos
+func (c *CLI) getReposList() []string {
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{Command: "list_repos"})
+	if err != nil {
+		return []string{}
+	}
+
+	if !resp.Success {
+		return []string{}
+	}
+
+	repos, ok := resp.Data.([]interface{})
+	if !ok {
+		return []string{}
+	}
+
+	result := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		if repoStr, ok := repo.(string); ok {
+			result = append(result, repoStr)
+		}
+	}
+
+	return result
+}
+
+func (c *CLI) sendMessage(args []string) error {
+	if len(args) < 2 {
+		return errors.InvalidUsage("usage: multiclaude agent send-message <to> <message>")
+	}
+
+	to := args[0]
+	body := strings.Join(args[1:], " ")
+
+	// Determine current agent and repo
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return err
+	}
+
+	// Create message manager
+	msgMgr := messages.NewManager(c.paths.MessagesDir, messages.WithAudit(audit.NewLog(c.paths.AuditFile()), socket.Origin))
+
+	// Send message
+	msg, err := msgMgr.Send(repoName, agentName, to, body)
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	// Trigger immediate routing (best-effort, polling is fallback)
+	client := c.daemonClient()
+	_, _ = client.Send(socket.Request{Command: "route_messages"})
+	// Ignore errors - 2-minute polling fallback will catch it
+
+	fmt.Printf("Message sent to %s (ID: %s)\n", to, msg.ID)
+	return nil
+}
+
+func (c *CLI) listMessages(args []string) error {
+	// Determine current agent and repo
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return err
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+
+	// List messages
+	msgs, err := msgMgr.List(repoName, agentName)
+	if err != nil {
+		return fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	if len(msgs) == 0 {
+		fmt.Println("No messages")
+		return nil
+	}
+
+	fmt.Printf("Messages for %s (%d):\n", agentName, len(msgs))
+	for _, msg := range msgs {
+		status := msg.Status
+		if msg.Status == messages.StatusAcked && msg.AckedAt != nil {
+			status = messages.Status(fmt.Sprintf("acked (%s)", formatTime(*msg.AckedAt)))
+		}
+		fmt.Printf("  [%s] %s - From: %s - %s - %s\n",
+			msg.ID,
+			formatTime(msg.Timestamp),
+			msg.From,
+			status,
+			truncateString(msg.Body, 60))
+	}
+
+	return nil
+}
+
+func (c *CLI) readMessage(args []string) error {
+	if len(args) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent read-message <message-id>")
+	}
+
+	messageID := args[0]
+
+	// Determine current agent and repo
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return err
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+
+	// Get message
+	msg, err := msgMgr.Get(repoName, agentName, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to read message: %w", err)
+	}
+
+	// Update status to read
+	if msg.Status == messages.StatusPending || msg.Status == messages.StatusDelivered {
+		if err := msgMgr.UpdateStatus(repoName, agentName, messageID, messages.StatusRead); err != nil {
+			fmt.Printf("Warning: failed to update message status: %v\n", err)
+		}
+	}
+
+	// Display message
+	fmt.Printf("Message: %s\n", msg.ID)
+	fmt.Printf("From: %s\n", msg.From)
+	fmt.Printf("To: %s\n", msg.To)
+	fmt.Printf("Time: %s\n", msg.Timestamp.Format(time.RFC3339))
+	fmt.Printf("Status: %s\n", msg.Status)
+	if msg.AckedAt != nil {
+		fmt.Printf("Acked: %s\n", msg.AckedAt.Format(time.RFC3339))
+	}
+	fmt.Println()
+	fmt.Println(msg.Body)
+
+	return nil
+}
+
+func (c *CLI) ackMessage(args []string) error {
+	if len(args) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent ack-message <message-id>")
+	}
+
+	messageID := args[0]
+
+	// Determine current agent and repo
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return err
+	}
+
+	msgMgr := messages.NewManager(c.paths.MessagesDir)
+
+	// Ack message
+	if err := msgMgr.Ack(repoName, agentName, messageID); err != nil {
+		return fmt.Errorf("failed to acknowledge message: %w", err)
+	}
+
+	fmt.Printf("Message %s acknowledged\n", messageID)
+	return nil
+}
+
+// inferRepoFromCwd infers just the repository name from the current working directory.
+// Unlike inferAgentContext, it doesn't require determining the specific agent.
+func (c *CLI) inferRepoFromCwd() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Resolve symlinks in cwd for proper path comparison
+	// This is especially important on macOS where /tmp -> /private/tmp
+	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = resolved
+	}
+
+	// Check if we're in a worktree path
+	// Path format: ~/.multiclaude/wts/<repo>/<agent>
+	if hasPathPrefix(cwd, c.paths.WorktreesDir) {
+		rel, err := filepath.Rel(c.paths.WorktreesDir, cwd)
+		if err == nil {
+			parts := strings.SplitN(rel, string(filepath.Separator), 2)
+			if len(parts) >= 1 && parts[0] != "" && parts[0] != "." {
+				return parts[0], nil
+			}
+		}
+	}
+
+	// Check if we're in a main repo path
+	// Path format: ~/.multiclaude/repos/<repo>
+	if hasPathPrefix(cwd, c.paths.ReposDir) {
+		rel, err := filepath.Rel(c.paths.ReposDir, cwd)
+		if err == nil {
+			parts := strings.SplitN(rel, string(filepath.Separator), 2)
+			if len(parts) >= 1 && parts[0] != "" && parts[0] != "." {
+				return parts[0], nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("not in a multiclaude directory")
+}
+
+// normalizeGitHubURL normalizes GitHub URLs to a common format for comparison.
+// It handles both SSH (git@github.com:user/repo.git) and HTTPS (https://github.com/user/repo) formats.
+// Returns lowercase "github.com/user/repo" format for comparison.
+func normalizeGitHubURL(url string) string {
+	url = strings.TrimSpace(url)
+	lowerURL := strings.ToLower(url)
+
+	// Handle SSH format: git@github.com:user/repo.git
+	if strings.HasPrefix(lowerURL, "git@github.com:") {
+		path := url[len("git@github.com:"):]
+		path = strings.TrimSuffix(path, ".git")
+		return strings.ToLower("github.com/" + path)
+	}
+
+	// Handle HTTPS format: https://github.com/user/repo or https://github.com/user/repo.git
+	if strings.HasPrefix(lowerURL, "https://github.com/") {
+		path := url[len("https://"):]
+		path = strings.TrimSuffix(path, ".git")
+		return strings.ToLower(path)
+	}
+
+	// Handle HTTP format: http://github.com/user/repo
+	if strings.HasPrefix(lowerURL, "http://github.com/") {
+		path := url[len("http://"):]
+		path = strings.TrimSuffix(path, ".git")
+		return strings.ToLower(path)
+	}
+
+	// Handle git:// protocol: git://github.com/user/repo.git
+	if strings.HasPrefix(lowerURL, "git://github.com/") {
+		path := url[len("git://"):]
+		path = strings.TrimSuffix(path, ".git")
+		return strings.ToLower(path)
+	}
+
+	// Return empty string for non-GitHub URLs
+	return ""
+}
+
+// findRepoFromGitRemote looks for a git remote in the current directory
+// and tries to match it against known repositories in state.
+func (c *CLI) findRepoFromGitRemote() (string, error) {
+	// Run git remote get-url origin
+	cmd := gitCommand("remote", "get-url", "origin")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git remote: %w", err)
+	}
+
+	remoteURL := strings.TrimSpace(string(output))
+	if remoteURL == "" {
+		return "", fmt.Errorf("git remote URL is empty")
+	}
+
+	normalizedRemote := normalizeGitHubURL(remoteURL)
+	if normalizedRemote == "" {
+		return "", fmt.Errorf("not a GitHub URL: %s", remoteURL)
+	}
+
+	// Load state to check against known repositories
+	st, err := c.loadState()
+	if err != nil {
+		return "", err
+	}
+
+	// Iterate through repos and find a match
+	for _, repoName := range st.ListRepos() {
+		repo, exists := st.GetRepo(repoName)
+		if !exists {
+			continue
+		}
+
+		normalizedStateURL := normalizeGitHubURL(repo.GithubURL)
+		if normalizedStateURL != "" && normalizedStateURL == normalizedRemote {
+			return repoName, nil
+		}
+	}
+
+	return "", fmt.Errorf("no matching repository found for remote: %s", remoteURL)
+}
+
+// resolveRepo determines the repository to use based on:
+// 1. Explicit --repo flag (highest priority)
+// 2. Git remote URL matching (if in a git repo with origin pointing to a tracked repo)
+// 3. Current working directory (if in a multiclaude directory)
+// 4. Current repo set via 'multiclaude repo use' (lowest priority)
+func (c *CLI) resolveRepo(flags map[string]string) (string, error) {
+	// 1. Check explicit --repo flag
+	if r, ok := flags["repo"]; ok {
+		return r, nil
+	}
+
+	// 2. Try to infer from git remote URL
+	if repoName, err := c.findRepoFromGitRemote(); err == nil {
+		return repoName, nil
+	}
+
+	// 3. Try to infer from current working directory
+	if inferred, err := c.inferRepoFromCwd(); err == nil {
+		return inferred, nil
+	}
+
+	// 4. Check current repo from daemon
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "get_current_repo",
+	})
+	if err == nil && resp.Success {
+		if currentRepo, ok := resp.Data.(string); ok && currentRepo != "" {
+			return currentRepo, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not determine repository; use --repo flag or run 'multiclaude repo use <name>'")
+}
+
+// inferAgentContext infers the current agent and repo from working directory
+func (c *CLI) inferAgentContext() (repoName, agentName string, err error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Resolve symlinks in cwd for proper path comparison
+	// This is especially important on macOS where /tmp -> /private/tmp
+	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = resolved
+	}
+
+	// Check if we're in a worktree path
+	// Path format: ~/.multiclaude/wts/<repo>/<agent>
+	if hasPathPrefix(cwd, c.paths.WorktreesDir) {
+		// Extract repo and agent from path
+		rel, err := filepath.Rel(c.paths.WorktreesDir, cwd)
+		if err == nil {
+			parts := strings.SplitN(rel, string(filepath.Separator), 2)
+			if len(parts) >= 2 {
+				return parts[0], parts[1], nil
+			}
+			if len(parts) == 1 {
+				// We're in the repo worktree dir itself
+				return parts[0], "", fmt.Errorf("cannot determine agent - in repo worktree directory")
+			}
+		}
+	}
+
+	// Check if we're in a main repo path
+	// Path format: ~/.multiclaude/repos/<repo>
+	if hasPathPrefix(cwd, c.paths.ReposDir) {
+		rel, err := filepath.Rel(c.paths.ReposDir, cwd)
+		if err == nil {
+			parts := strings.SplitN(rel, string(filepath.Separator), 2)
+			if len(parts) >= 1 {
+				// In main repo - could be supervisor or merge-queue
+				// Try to get tmux window name
+				tmuxWindow := os.Getenv("TMUX_PANE")
+				if tmuxWindow != "" {
+					// Get window name from tmux
+					cmd := tmuxCommand("display-message", "-p", "#{window_name}")
+					output, err := cmd.Output()
+					if err == nil {
+						windowName := strings.TrimSpace(string(output))
+						return parts[0], windowName, nil
+					}
+				}
+
+				// Fallback: assume supervisor
+				return parts[0], "supervisor", nil
+			}
+		}
+	}
+
+	return "", "", errors.NotInAgentContext()
+}
+
+// Helper functions
+
+// hasPathPrefix checks if path starts with prefix using proper path semantics.
+// Unlike strings.Contains or strings.HasPrefix, this ensures we're comparing
+// complete path components (e.g., "/foo/bar" is under "/foo" but not under "/fo").
+func hasPathPrefix(path, prefix string) bool {
+	// Clean both paths to normalize them
+	path = filepath.Clean(path)
+	prefix = filepath.Clean(prefix)
+
+	// Check if path equals or starts with prefix followed by separator
+	if path == prefix {
+		return true
+	}
+	// Ensure prefix ends with separator for proper prefix matching
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix = prefix + string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, prefix)
+}
+
+func formatTime(t time.Time) string {
+	if time.Since(t) < 24*time.Hour {
+		return t.Format("15:04:05")
+	}
+	return t.Format("Jan 02 15:04")
+}
+
+func truncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+// checkUnpushedCommits checks if a worktree has unpushed commits and prompts the user for confirmation.
+// Returns nil if the user wants to continue, or an error to cancel the operation.
+// The entityType parameter should be "Worker" or "Workspace" for appropriate messaging.
+// The action parameter should be "cleanup" or "removal" for appropriate messaging.
+func checkUnpushedCommits(wtPath, entityType, action string) error {
+	hasUnpushed, err := worktree.HasUnpushedCommits(wtPath)
+	if err != nil {
+		// This is ok - might not have a tracking branch
+		fmt.Printf("Note: Could not check for unpushed commits (no tracking branch?)\n")
+		return nil
+	}
+
+	if !hasUnpushed {
+		return nil
+	}
+
+	fmt.Printf("\nWarning: %s has unpushed commits!\n", entityType)
+	branch, err := worktree.GetCurrentBranch(wtPath)
+	if err == nil {
+		fmt.Printf("Branch '%s' has commits not pushed to remote.\n", branch)
+	}
+	fmt.Printf("These commits may be lost if you continue with %s.\n", action)
+	fmt.Printf("Continue with %s? [y/N]: ", action)
+
+	var response string
+	fmt.Scanln(&response)
+	if response != "y" && response != "Y" {
+		// Capitalize first letter of action for the message
+		actionCapitalized := strings.ToUpper(action[:1]) + action[1:]
+		fmt.Printf("%s cancelled\n", actionCapitalized)
+		return fmt.Errorf("cancelled by user")
+	}
+	return nil
+}
+
+func (c *CLI) completeWorker(args []string) error {
+	// Parse flags for optional summary and failure reason
+	flags, _ := ParseFlags(args)
+
+	// Determine current agent and repo
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return fmt.Errorf("failed to determine agent context: %w", err)
+	}
+
+	fmt.Printf("Marking agent '%s' as complete...\n", agentName)
+
+	// Build request args
+	reqArgs := map[string]interface{}{
+		"repo":  repoName,
+		"agent": agentName,
+	}
+
+	// Add optional summary
+	if summary, ok := flags["summary"]; ok && summary != "" {
+		reqArgs["summary"] = summary
+		fmt.Printf("Summary: %s\n", summary)
+	}
+
+	// Add optional failure reason
+	if failureReason, ok := flags["failure"]; ok && failureReason != "" {
+		reqArgs["failure_reason"] = failureReason
+		fmt.Printf("Failure reason: %s\n", failureReason)
+	}
+
+	// Optionally squash the branch into one commit
+	if flags["squash"] == "true" {
+		reqArgs["squash"] = true
+		if title := flags["title"]; title != "" {
+			reqArgs["squash_title"] = title
+		}
+		fmt.Println("Squashing branch into a single commit...")
+	} else if _, ok := flags["title"]; ok {
+		return errors.InvalidUsage("--title requires --squash")
+	}
+
+	if flags["pr"] == "true" {
+		reqArgs["pr"] = true
+		fmt.Println("Pushing branch and opening a PR...")
+	} else if flags["push"] == "true" {
+		reqArgs["push"] = true
+		fmt.Println("Pushing branch...")
+	}
+	if flags["cleanup"] == "true" {
+		reqArgs["cleanup"] = true
+	}
+
+	client := c.daemonClient()
+	resp, err := client.Send(socket.Request{
+		Command: "complete_agent",
+		Args:    reqArgs,
+	})
+	if err != nil {
+		return errors.DaemonCommunicationFailed("marking agent complete", err)
+	}
+	if !resp.Success {
+		return errors.Wrap(errors.CategoryRuntime, "failed to mark agent complete", fmt.Errorf("%s", resp.Error))
+	}
+
+	data, _ := resp.Data.(map[string]interface{})
+	if backupRef, _ := data["backup_ref"].(string); backupRef != "" {
+		fmt.Printf("✓ Branch squashed (original commits saved at %s)\n", backupRef)
+	}
+	if pushed, _ := data["pushed"].(bool); pushed {
+		fmt.Println("✓ Branch pushed to origin")
+	}
+	if prURL, _ := data["pr_url"].(string); prURL != "" {
+		fmt.Printf("✓ Pull request: %s\n", prURL)
+	}
+	fmt.Println("✓ Agent marked as complete")
+	if cleanupNow, _ := data["cleanup"].(bool); cleanupNow {
+		fmt.Println("This window and worktree are being removed now.")
+	} else {
+		fmt.Println("The daemon will clean up this agent's resources at its next health check.")
+	}
+	return nil
+}
+
+func (c *CLI) checkBranchGuard(args []string) error {
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return fmt.Errorf("failed to determine agent context: %w", err)
+	}
+
+	resp, err := c.sendDaemonRequest("check_branch_guard", map[string]interface{}{
+		"repo":  repoName,
+		"agent": agentName,
+	})
+	if err != nil {
+		return err
+	}
 
-	// Copy templates
-	if err := templates.CopyAgentTemplates(agentsDir); err != nil {
-		return errors.Wrap(errors.CategoryRuntime, "failed to copy agent templates", err)
+	data, _ := resp.Data.(map[string]interface{})
+	report, _ := data["report"].(string)
+	fmt.Println(report)
+
+	if passed, _ := data["passed"].(bool); !passed {
+		return errors.New(errors.CategoryRuntime, "branch guard failed").
+			WithSuggestion("remove or revert the listed changes before running 'multiclaude agent complete'")
+	}
+	return nil
+}
+
+// checkReviewChecklist validates a drafted review comment (from a file, or
+// stdin with "-") against the review checklists for this reviewer's PR
+func (c *CLI) checkReviewChecklist(args []string) error {
+	if len(args) != 1 {
+		return errors.InvalidUsage("usage: multiclaude agent check-review <comment-file>|-")
 	}
 
-	// List what was copied
-	entries, err := os.ReadDir(agentsDir)
+	var body []byte
+	var err error
+	if args[0] == "-" {
+		body, err = io.ReadAll(os.Stdin)
+	} else {
+		body, err = os.ReadFile(args[0])
+	}
 	if err != nil {
-		return errors.Wrap(errors.CategoryRuntime, "failed to list agent definitions", err)
+		return fmt.Errorf("failed to read review comment: %w", err)
 	}
 
-	fmt.Printf("Reset complete. Agent definitions in %s:\n", agentsDir)
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".md" {
-			fmt.Printf("  - %s\n", entry.Name())
-		}
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return fmt.Errorf("failed to determine agent context: %w", err)
+	}
+
+	resp, err := c.sendDaemonRequest("check_review_checklist", map[string]interface{}{
+		"repo":  repoName,
+		"agent": agentName,
+		"body":  string(body),
+	})
+	if err != nil {
+		return err
 	}
 
+	data, _ := resp.Data.(map[string]interface{})
+	report, _ := data["report"].(string)
+	fmt.Println(report)
+
+	if passed, _ := data["passed"].(bool); !passed {
+		return errors.New(errors.CategoryRuntime, "review checklist incomplete").
+			WithSuggestion(fmt.Sprintf("add a line for each missing item under %q before posting the comment", review.SectionHeading))
+	}
 	return nil
 }
 
-func (c *CLI) showHistory(args []string) error {
-	flags, _ := ParseFlags(args)
+// handoffWorker hands a named worker's worktree to a new worker
+func (c *CLI) handoffWorker(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude work handoff <worker-name> <task> [--summary <text>] [--name <new-name>]")
+	}
 
-	// Determine repository
 	repoName, err := c.resolveRepo(flags)
 	if err != nil {
 		return errors.NotInRepo()
 	}
+	return c.handoff(repoName, posArgs[0], strings.Join(posArgs[1:], " "), flags)
+}
 
-	// Get limit from flags (default 10)
-	limit := 10
-	if n, ok := flags["n"]; ok {
-		if v, err := strconv.Atoi(n); err == nil && v > 0 {
-			limit = v
-		}
+// handoffSelf is run by a worker to hand its own worktree to a new worker
+func (c *CLI) handoffSelf(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	task := strings.Join(posArgs, " ")
+	if task == "" {
+		return errors.InvalidUsage("usage: multiclaude agent handoff <task for the new worker> --summary <progress so far>")
 	}
 
-	// Get filter options
-	statusFilter := flags["status"] // Filter by status (merged, open, closed, failed, no-pr)
-	searchQuery := flags["search"]  // Search in task descriptions
-	showFull := flags["full"] == "true"
-
-	// Validate status filter if provided
-	validStatuses := map[string]bool{
-		"merged": true, "open": true, "closed": true, "failed": true, "no-pr": true,
-	}
-	if statusFilter != "" && !validStatuses[statusFilter] {
-		return errors.InvalidUsage(fmt.Sprintf("invalid status filter: %s (valid values: merged, open, closed, failed, no-pr)", statusFilter))
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return fmt.Errorf("failed to determine agent context: %w", err)
 	}
+	return c.handoff(repoName, agentName, task, flags)
+}
 
-	// When filtering, fetch more history to ensure we get enough results
-	fetchLimit := limit
-	if statusFilter != "" || searchQuery != "" {
-		fetchLimit = limit * 10 // Fetch more to allow for filtering
-		if fetchLimit > 100 {
-			fetchLimit = 100
-		}
+// handoff asks the daemon to transfer from's worktree to a new worker
+func (c *CLI) handoff(repoName, from, task string, flags map[string]string) error {
+	to := flags["name"]
+	if to == "" {
+		to = names.Generate()
 	}
 
-	// Get task history from daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "task_history",
-		Args: map[string]interface{}{
-			"repo":  repoName,
-			"limit": fetchLimit,
-		},
+	resp, err := c.sendDaemonRequest("handoff_agent", map[string]interface{}{
+		"repo":    repoName,
+		"from":    from,
+		"to":      to,
+		"task":    task,
+		"summary": flags["summary"],
 	})
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting task history", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get task history", fmt.Errorf("%s", resp.Error))
+		return err
 	}
 
-	history, ok := resp.Data.([]interface{})
-	if !ok || len(history) == 0 {
-		fmt.Printf("No task history for repository '%s'\n", repoName)
-		format.Dimmed("\nCreate workers with: multiclaude work <task>")
-		return nil
+	data, _ := resp.Data.(map[string]interface{})
+	fmt.Printf("✓ Handed off '%s' to new worker '%s'\n", from, to)
+	if path, _ := data["worktree_path"].(string); path != "" {
+		fmt.Printf("  Worktree: %s\n", path)
 	}
+	fmt.Printf("  Task: %s\n", task)
+	format.Dimmed("\n'%s' will be stopped and cleaned up without removing the worktree.", from)
+	return nil
+}
 
-	// Query GitHub for PR status for each task with a branch
-	repoPath := c.paths.RepoDir(repoName)
-
-	// Build filtered header
-	headerParts := []string{fmt.Sprintf("Task History for '%s'", repoName)}
-	if statusFilter != "" {
-		headerParts = append(headerParts, fmt.Sprintf("status=%s", statusFilter))
-	}
-	if searchQuery != "" {
-		headerParts = append(headerParts, fmt.Sprintf("search=%q", searchQuery))
+// pullWorkerBranch rebases a named worker's worktree onto its remote branch
+func (c *CLI) pullWorkerBranch(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude work pull <worker-name> [--repo <repo>]")
 	}
-	format.Header("%s:", strings.Join(headerParts, ", "))
-	fmt.Println()
 
-	// First pass: collect entries with details to show after table
-	type entryDetails struct {
-		name          string
-		summary       string
-		failureReason string
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
 	}
-	var detailsToShow []entryDetails
-
-	table := format.NewColoredTable("NAME", "STATUS", "PR", "COMPLETED", "TASK")
-	displayedCount := 0
-	for _, item := range history {
-		// Stop once we've displayed enough
-		if displayedCount >= limit {
-			break
-		}
-
-		entry, ok := item.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		name, _ := entry["name"].(string)
-		task, _ := entry["task"].(string)
-		branch, _ := entry["branch"].(string)
-		prURL, _ := entry["pr_url"].(string)
-		completedAt, _ := entry["completed_at"].(string)
-		summary, _ := entry["summary"].(string)
-		failureReason, _ := entry["failure_reason"].(string)
-		storedStatus, _ := entry["status"].(string)
-
-		// Try to get PR status from GitHub if we have a branch
-		prStatus, prLink := c.getPRStatusForBranch(repoPath, branch, prURL)
-
-		// Use stored status if it indicates failure
-		if storedStatus == "failed" {
-			prStatus = "failed"
-		}
-
-		// Apply status filter
-		if statusFilter != "" {
-			effectiveStatus := prStatus
-			if effectiveStatus == "" {
-				effectiveStatus = "no-pr"
-			}
-			if effectiveStatus != statusFilter {
-				continue
-			}
-		}
-
-		// Apply search filter (case-insensitive)
-		if searchQuery != "" {
-			lowerQuery := strings.ToLower(searchQuery)
-			lowerTask := strings.ToLower(task)
-			lowerName := strings.ToLower(name)
-			if !strings.Contains(lowerTask, lowerQuery) && !strings.Contains(lowerName, lowerQuery) {
-				continue
-			}
-		}
-
-		displayedCount++
+	return c.pullAgentBranch(repoName, posArgs[0])
+}
 
-		// Collect entries with summary or failure for detailed display
-		if summary != "" || failureReason != "" {
-			detailsToShow = append(detailsToShow, entryDetails{
-				name:          name,
-				summary:       summary,
-				failureReason: failureReason,
-			})
-		}
+// resolveWorkerConflict tells the daemon how to handle a worker whose
+// refresh onto main conflicted
+func (c *CLI) resolveWorkerConflict(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 2 {
+		return errors.InvalidUsage("usage: multiclaude work resolve <worker-name> assign|helper|skip [--repo <repo>]")
+	}
+	workerName, action := posArgs[0], posArgs[1]
 
-		// Format status with color
-		var statusCell format.ColoredCell
-		switch prStatus {
-		case "merged":
-			statusCell = format.ColorCell("merged", format.Green)
-		case "open":
-			statusCell = format.ColorCell("open", format.Yellow)
-		case "closed":
-			statusCell = format.ColorCell("closed", format.Red)
-		case "failed":
-			statusCell = format.ColorCell("failed", format.Red)
-		default:
-			statusCell = format.ColorCell("no-pr", format.Dim)
-		}
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
 
-		// Format PR link
-		prCell := format.ColorCell("-", format.Dim)
-		if prLink != "" {
-			// Extract just the PR number for display
-			prCell = format.ColorCell(prLink, format.Cyan)
-		}
+	resp, err := c.sendDaemonRequest("resolve_conflict", map[string]interface{}{
+		"repo":   repoName,
+		"agent":  workerName,
+		"action": action,
+	})
+	if err != nil {
+		return err
+	}
 
-		// Format completed time
-		completedCell := format.ColorCell("-", format.Dim)
-		if completedAt != "" {
-			if t, err := time.Parse(time.RFC3339, completedAt); err == nil {
-				completedCell = format.Cell(format.TimeAgo(t))
-			}
-		}
+	data, _ := resp.Data.(map[string]interface{})
+	switch state.ConflictResolution(action) {
+	case state.ConflictAssign:
+		fmt.Printf("✓ Asked %s to rebase and resolve the conflict\n", workerName)
+	case state.ConflictHelper:
+		fmt.Printf("✓ Opened window %v with the rebase stopped at the conflict\n", data["window"])
+		fmt.Printf("  Attach: %v\n", data["tmux"])
+		fmt.Println("  Resolve, `git rebase --continue`, then push with --force-with-lease and exit the window")
+	case state.ConflictSkip:
+		fmt.Printf("✓ %s will be rebased again once main moves\n", workerName)
+	}
+	return nil
+}
 
-		// Format task - show full or truncate
-		displayTask := task
-		if !showFull {
-			displayTask = format.Truncate(task, 50)
-		}
+// recoverWorker rolls back a rebase or merge left in progress in a worker's
+// worktree
+func (c *CLI) recoverWorker(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude work recover <worker-name> [--repo <repo>] [--older-than <duration>] [--force]")
+	}
+	workerName := posArgs[0]
 
-		table.AddRow(
-			format.Cell(name),
-			statusCell,
-			prCell,
-			completedCell,
-			format.Cell(displayTask),
-		)
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
 	}
 
-	// Show message if no results after filtering
-	if displayedCount == 0 {
-		if statusFilter != "" || searchQuery != "" {
-			fmt.Printf("No tasks match the filter criteria\n")
+	reqArgs := map[string]interface{}{
+		"repo":  repoName,
+		"agent": workerName,
+		"force": flags["force"] == "true",
+	}
+	if olderThan, ok := flags["older-than"]; ok {
+		duration, err := parseDuration(olderThan)
+		if err != nil {
+			return errors.InvalidArgument("--older-than", olderThan, "a duration like 10m or 1h")
 		}
-		return nil
+		reqArgs["older_than_seconds"] = duration.Seconds()
 	}
 
-	table.Print()
+	resp, err := c.sendDaemonRequest("recover_agent", reqArgs)
+	if err != nil {
+		return err
+	}
 
-	// Print detailed summary/failure section if any entries have them
-	if len(detailsToShow) > 0 {
-		fmt.Println()
-		format.Header("Details:")
-		for _, d := range detailsToShow {
-			format.Bold.Printf("\n%s:\n", d.name)
-			if d.summary != "" {
-				format.Dimmed("  Summary: %s", d.summary)
-			}
-			if d.failureReason != "" {
-				format.Red.Printf("  Failure: %s\n", d.failureReason)
-			}
-		}
+	data, _ := resp.Data.(map[string]interface{})
+	if pending, _ := data["pending"].(string); pending != "" {
+		fmt.Printf("%s has a %s in progress, last active %v; leaving it alone\n", workerName, pending, data["last_activity"])
+		format.Dimmed("  Abort it anyway with: multiclaude work recover %s --repo %s --force", workerName, repoName)
+		return nil
 	}
 
+	restored, _ := data["restored_stashes"].(float64)
+	keptStash, _ := data["kept_stash"].(string)
+	aborted, _ := data["aborted"].(string)
+	if aborted == "" && restored == 0 && keptStash == "" {
+		fmt.Printf("Nothing to recover for %s\n", workerName)
+		return nil
+	}
+	if aborted != "" {
+		fmt.Printf("✓ Aborted the %s in %s (last active %v)\n", aborted, workerName, data["last_activity"])
+	}
+	if restored > 0 {
+		fmt.Printf("✓ Restored %d refresh stash(es)\n", int(restored))
+	}
+	if keptStash != "" {
+		fmt.Printf("⚠ Kept %s because %v\n", keptStash, data["kept_reason"])
+	}
+	format.Dimmed("  %s was told what was rolled back", workerName)
 	return nil
 }
 
-// getPRStatusForBranch queries GitHub for the PR status of a branch
-func (c *CLI) getPRStatusForBranch(repoPath, branch, existingPRURL string) (status, prLink string) {
-	// If we already have a PR URL, just return it formatted
-	if existingPRURL != "" {
-		// Extract PR number from URL for shorter display
-		parts := strings.Split(existingPRURL, "/")
-		if len(parts) > 0 {
-			prNum := parts[len(parts)-1]
-			return "unknown", "#" + prNum
-		}
-		return "unknown", existingPRURL
+// pullOwnBranch is run by an agent to pick up commits pushed to its branch
+func (c *CLI) pullOwnBranch(args []string) error {
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return fmt.Errorf("failed to determine agent context: %w", err)
 	}
+	return c.pullAgentBranch(repoName, agentName)
+}
 
-	// If no branch, nothing to query
-	if branch == "" {
-		return "no-pr", ""
+// pullAgentBranch asks the daemon to rebase an agent's worktree onto its remote branch
+func (c *CLI) pullAgentBranch(repoName, agentName string) error {
+	resp, err := c.sendDaemonRequest("pull_agent_branch", map[string]interface{}{
+		"repo":  repoName,
+		"agent": agentName,
+	})
+	if err != nil {
+		return err
 	}
 
-	// Query GitHub for PR associated with this branch using gh CLI
-	cmd := exec.Command("gh", "pr", "list", "--head", branch, "--state", "all", "--json", "number,state,url", "--limit", "1")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return "no-pr", ""
+	data, _ := resp.Data.(map[string]interface{})
+	remote, _ := data["remote"].(string)
+	branch, _ := data["branch"].(string)
+	fmt.Printf("✓ Rebased %s onto %s/%s\n", agentName, remote, branch)
+	if rebased, _ := data["commits_rebased"].(float64); rebased > 0 {
+		fmt.Printf("  Local commits replayed: %d\n", int(rebased))
+	}
+	if stashed, _ := data["stashed"].(bool); stashed {
+		fmt.Println("  Uncommitted changes were stashed and restored")
 	}
+	return nil
+}
 
-	// Parse JSON output
-	var prs []struct {
-		Number int    `json:"number"`
-		State  string `json:"state"`
-		URL    string `json:"url"`
+// addScratchWorktree creates an extra worktree for the calling agent
+func (c *CLI) addScratchWorktree(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude agent scratch add <name> [--at <rev>] [--branch]")
 	}
-	if err := json.Unmarshal(output, &prs); err != nil || len(prs) == 0 {
-		return "no-pr", ""
+
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return fmt.Errorf("failed to determine agent context: %w", err)
 	}
 
-	pr := prs[0]
-	prLink = fmt.Sprintf("#%d", pr.Number)
+	reqArgs := map[string]interface{}{
+		"repo":  repoName,
+		"agent": agentName,
+		"name":  posArgs[0],
+	}
+	if rev := flags["at"]; rev != "" {
+		reqArgs["rev"] = rev
+	}
+	if flags["branch"] == "true" {
+		reqArgs["branch"] = true
+	}
+	resp, err := c.sendDaemonRequest("add_scratch_worktree", reqArgs)
+	if err != nil {
+		return err
+	}
 
-	switch strings.ToLower(pr.State) {
-	case "merged":
-		return "merged", prLink
-	case "open":
-		return "open", prLink
-	case "closed":
-		return "closed", prLink
-	default:
-		return "unknown", prLink
+	data, _ := resp.Data.(map[string]interface{})
+	path, _ := data["path"].(string)
+	commit, _ := data["commit"].(string)
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	fmt.Printf("✓ Created scratch worktree '%s' at %s\n", posArgs[0], commit)
+	fmt.Printf("  Path: %s\n", path)
+	if branch, _ := data["branch"].(string); branch != "" {
+		fmt.Printf("  Branch: %s\n", branch)
+	} else {
+		fmt.Println("  Detached HEAD; use --branch to keep commits made there")
 	}
+	format.Dimmed("\nIt is removed when you complete. Remove it sooner with: multiclaude agent scratch rm %s", posArgs[0])
+	return nil
 }
 
-func (c *CLI) removeWorker(args []string) error {
-	flags, remainingArgs := ParseFlags(args)
+// removeScratchWorktree removes one of the calling agent's scratch worktrees
+func (c *CLI) removeScratchWorktree(args []string) error {
+	_, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude agent scratch rm <name>")
+	}
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
+	repoName, agentName, err := c.inferAgentContext()
 	if err != nil {
-		return errors.NotInRepo()
+		return fmt.Errorf("failed to determine agent context: %w", err)
 	}
 
-	// Get worker info
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
+	resp, err := c.sendDaemonRequest("remove_scratch_worktree", map[string]interface{}{
+		"repo":  repoName,
+		"agent": agentName,
+		"name":  posArgs[0],
 	})
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting worker info", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get worker info", fmt.Errorf("%s", resp.Error))
+		return err
 	}
 
-	agents, _ := resp.Data.([]interface{})
+	fmt.Printf("✓ Removed scratch worktree '%s'\n", posArgs[0])
+	data, _ := resp.Data.(map[string]interface{})
+	if branch, _ := data["branch"].(string); branch != "" {
+		fmt.Printf("  Branch %s was kept\n", branch)
+	}
+	return nil
+}
 
-	// Determine worker name - from args or interactive selection
-	var workerName string
-	if len(remainingArgs) > 0 {
-		workerName = remainingArgs[0]
-	} else {
-		// Interactive selection
-		items := agentsToSelectableItems(agents, []string{"worker"})
-		if len(items) == 0 {
-			return errors.NoWorkersFound(repoName)
-		}
-		selected, err := SelectFromList("Select worker to remove:", items)
-		if err != nil {
-			return err
-		}
-		if selected == "" {
-			fmt.Println("Cancelled")
-			return nil
-		}
-		workerName = selected
+// listScratchWorktrees lists the calling agent's scratch worktrees
+func (c *CLI) listScratchWorktrees(args []string) error {
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return fmt.Errorf("failed to determine agent context: %w", err)
 	}
 
-	fmt.Printf("Removing worker '%s' from repo '%s'\n", workerName, repoName)
+	resp, err := c.sendDaemonRequest("list_scratch_worktrees", map[string]interface{}{
+		"repo":  repoName,
+		"agent": agentName,
+	})
+	if err != nil {
+		return err
+	}
 
-	// Find worker
-	var workerInfo map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			if name, _ := agentMap["name"].(string); name == workerName {
-				workerInfo = agentMap
-				break
-			}
-		}
+	scratch, _ := resp.Data.([]interface{})
+	if len(scratch) == 0 {
+		fmt.Println("No scratch worktrees")
+		format.Dimmed("\nCreate one with: multiclaude agent scratch add <name> [--at <rev>]")
+		return nil
 	}
 
-	if workerInfo == nil {
-		return errors.AgentNotFound("worker", workerName, repoName)
+	table := format.NewColoredTable("NAME", "BRANCH", "PATH")
+	for _, item := range scratch {
+		entry, _ := item.(map[string]interface{})
+		name, _ := entry["name"].(string)
+		path, _ := entry["path"].(string)
+		branchCell := format.ColorCell("(detached)", format.Dim)
+		if branch, _ := entry["branch"].(string); branch != "" {
+			branchCell = format.ColorCell(branch, format.Cyan)
+		}
+		table.AddRow(format.Cell(name), branchCell, format.Cell(path))
 	}
+	table.Print()
+	return nil
+}
 
-	// Get worktree path
-	wtPath := workerInfo["worktree_path"].(string)
+// showFeed prints a repository's recent orchestration actions, oldest first
+func (c *CLI) showFeed(args []string) error {
+	flags, _ := ParseFlags(args)
 
-	// Check for uncommitted changes
-	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
+	repoName, err := c.resolveRepo(flags)
 	if err != nil {
-		fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
-	} else if hasUncommitted {
-		fmt.Println("\nWarning: Worker has uncommitted changes!")
-		fmt.Println("Files may be lost if you continue with cleanup.")
-		fmt.Print("Continue with cleanup? [y/N]: ")
+		return errors.NotInRepo()
+	}
 
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
-			fmt.Println("Cleanup cancelled")
-			return nil
+	reqArgs := map[string]interface{}{"repo": repoName}
+	if s, ok := flags["since"]; ok {
+		d, err := parseDuration(s)
+		if err != nil {
+			return errors.InvalidArgument("since", s, "a duration like 2d, 1h, or 30m")
 		}
+		reqArgs["since"] = time.Now().Add(-d).Format(time.RFC3339)
 	}
-
-	// Check for unpushed commits
-	if err := checkUnpushedCommits(wtPath, "Worker", "cleanup"); err != nil {
-		return nil
+	if l, ok := flags["limit"]; ok {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			return errors.InvalidArgument("limit", l, "a non-negative number (0 for no limit)")
+		}
+		reqArgs["limit"] = limit
 	}
 
-	// Kill tmux window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := workerInfo["tmux_window"].(string)
-	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
-	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow))
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
+	resp, err := c.sendDaemonRequest("get_feed", reqArgs)
+	if err != nil {
+		return err
 	}
 
-	// Remove worktree
-	repoPath := c.paths.RepoDir(repoName)
-	wt := worktree.NewManager(repoPath)
-
-	fmt.Printf("Removing worktree: %s\n", wtPath)
-	if err := wt.Remove(wtPath, false); err != nil {
-		fmt.Printf("Warning: failed to remove worktree: %v\n", err)
+	if flags["json"] == "true" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resp.Data)
 	}
 
-	// Unregister from daemon
-	resp, err = client.Send(socket.Request{
-		Command: "remove_agent",
-		Args: map[string]interface{}{
-			"repo":  repoName,
-			"agent": workerName,
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to unregister worker: %w", err)
-	}
-	if !resp.Success {
-		return fmt.Errorf("failed to unregister worker: %s", resp.Error)
+	entries, _ := resp.Data.([]interface{})
+	if len(entries) == 0 {
+		fmt.Printf("No recorded actions for %s\n", repoName)
+		return nil
 	}
 
-	fmt.Println("✓ Worker removed successfully")
+	format.Header("Recent actions in %s:", repoName)
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		when := "?"
+		if ts, _ := entry["time"].(string); ts != "" {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				when = t.Local().Format("Jan 02 15:04")
+			}
+		}
+		action, _ := entry["action"].(string)
+		agent, _ := entry["agent"].(string)
+		detail, _ := entry["detail"].(string)
+		if agent == "" {
+			agent = "-"
+		}
+		fmt.Printf("  %s  %-15s %-18s %s\n", format.Dim.Sprint(when), action, agent, detail)
+	}
 	return nil
 }
 
-// Workspace command implementations
+// showAudit prints the daemon's log of state changes, oldest first
+func (c *CLI) showAudit(args []string) error {
+	flags, _ := ParseFlags(args)
 
-// workspaceDefault handles `multiclaude workspace` with no subcommand or `multiclaude workspace <name>`
-func (c *CLI) workspaceDefault(args []string) error {
-	// If no args, list workspaces
-	if len(args) == 0 {
-		return c.listWorkspaces(args)
+	auditArgs := socket.AuditLogArgs{Repo: flags["repo"]}
+	if s, ok := flags["since"]; ok {
+		d, err := parseDuration(s)
+		if err != nil {
+			return errors.InvalidArgument("since", s, "a duration like 2d, 1h, or 30m")
+		}
+		auditArgs.Since = time.Now().Add(-d).Format(time.RFC3339)
 	}
-
-	// If first arg looks like a workspace name (not a flag), treat as connect
-	if !strings.HasPrefix(args[0], "-") {
-		return c.connectWorkspace(args)
+	if l, ok := flags["limit"]; ok {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			return errors.InvalidArgument("limit", l, "a non-negative number (0 for no limit)")
+		}
+		auditArgs.Limit = limit
 	}
 
-	// Otherwise list with flags
-	return c.listWorkspaces(args)
-}
-
-// addWorkspace creates a new workspace
-func (c *CLI) addWorkspace(args []string) error {
-	flags, posArgs := ParseFlags(args)
-
-	if len(posArgs) < 1 {
-		return errors.InvalidUsage("usage: multiclaude workspace add <name> [--branch <branch>]")
+	entries, err := socket.Call[[]audit.Entry](c.daemonClient(), "list_audit", auditArgs)
+	if err != nil {
+		return errors.DaemonCommunicationFailed("list_audit", err)
 	}
 
-	workspaceName := posArgs[0]
+	if flags["json"] == "true" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
 
-	// Validate workspace name (same restrictions as branch names)
-	if err := validateWorkspaceName(workspaceName); err != nil {
-		return err
+	if len(entries) == 0 {
+		fmt.Println("No recorded state changes")
+		return nil
 	}
 
-	// Determine repository using standard resolution chain
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return err
+	table := format.NewColoredTable("TIME", "ACTION", "REPO", "AGENT", "BY", "DETAIL")
+	for _, e := range entries {
+		repo, agent := e.Repo, e.Agent
+		if repo == "" {
+			repo = "-"
+		}
+		if agent == "" {
+			agent = "-"
+		}
+		by := e.Actor
+		if e.Origin != "" {
+			by = fmt.Sprintf("%s (%s)", e.Actor, e.Origin)
+		} else if e.Actor == audit.DaemonActor {
+			by = fmt.Sprintf("daemon (%s)", e.Command)
+		}
+		table.AddRow(
+			format.ColorCell(e.Time.Local().Format("Jan 02 15:04:05"), format.Dim),
+			format.Cell(string(e.Action)),
+			format.Cell(repo),
+			format.Cell(agent),
+			format.Cell(by),
+			format.Cell(format.Truncate(e.Detail, 60)),
+		)
 	}
+	table.Print()
+	return nil
+}
 
-	// Determine branch to start from
-	startBranch := "HEAD" // Default to current branch/HEAD
-	if branch, ok := flags["branch"]; ok {
-		startBranch = branch
-		fmt.Printf("Creating workspace '%s' in repo '%s' from branch '%s'\n", workspaceName, repoName, branch)
-	} else {
-		fmt.Printf("Creating workspace '%s' in repo '%s'\n", workspaceName, repoName)
+// recordQueueEvent reports a merge queue event for a PR to the daemon
+func (c *CLI) recordQueueEvent(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent queue-event <enqueued|ci_started|ci_finished|merged|failed|closed> [--pr <number>] [--branch <branch>]")
 	}
 
-	// Check if workspace already exists
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-		},
-	})
+	repoName, err := c.resolveRepo(flags)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("checking existing workspaces", err)
-	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to check existing workspaces", fmt.Errorf("%s", resp.Error))
+		return errors.NotInRepo()
 	}
 
-	agents, _ := resp.Data.([]interface{})
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			name, _ := agentMap["name"].(string)
-			if agentType == "workspace" && name == workspaceName {
-				return fmt.Errorf("workspace '%s' already exists in repo '%s'", workspaceName, repoName)
-			}
+	reqArgs := map[string]interface{}{
+		"repo":  repoName,
+		"event": posArgs[0],
+	}
+	if branch := flags["branch"]; branch != "" {
+		reqArgs["branch"] = branch
+	}
+	if prStr, ok := flags["pr"]; ok {
+		pr, err := strconv.Atoi(strings.TrimPrefix(prStr, "#"))
+		if err != nil || pr <= 0 {
+			return errors.InvalidArgument("--pr", prStr, "a positive PR number")
 		}
+		reqArgs["pr"] = pr
+	}
+	if reqArgs["branch"] == nil && reqArgs["pr"] == nil {
+		return errors.InvalidUsage("either --pr or --branch is required")
 	}
 
-	// Get repository path
-	repoPath := c.paths.RepoDir(repoName)
-
-	// Create worktree
-	wt := worktree.NewManager(repoPath)
-	wtPath := c.paths.AgentWorktree(repoName, workspaceName)
-	branchName := fmt.Sprintf("workspace/%s", workspaceName)
-
-	fmt.Printf("Creating worktree at: %s\n", wtPath)
-	if err := wt.CreateNewBranch(wtPath, branchName, startBranch); err != nil {
-		return errors.WorktreeCreationFailed(err)
+	if _, err := c.sendDaemonRequest("merge_queue_event", reqArgs); err != nil {
+		return err
 	}
+	fmt.Printf("Recorded %s\n", posArgs[0])
+	return nil
+}
 
-	// Get tmux session name
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+// broadcastPollInterval is how often broadcast polls the daemon for replies
+const broadcastPollInterval = 2 * time.Second
 
-	// Create tmux window for workspace (detached so it doesn't switch focus)
-	fmt.Printf("Creating tmux window: %s\n", workspaceName)
-	cmd := exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", workspaceName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
-		return errors.TmuxOperationFailed("create window", err)
+// broadcastQuestion asks every active agent in a repo a question, prints
+// replies as they arrive, and ends with a summary once all have answered or
+// the timeout passes
+func (c *CLI) broadcastQuestion(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) == 0 {
+		return errors.InvalidUsage("usage: multiclaude broadcast [--repo <repo>] [--timeout <5m>] [--json] <question>")
 	}
+	question := strings.Join(posArgs, " ")
 
-	// Generate session ID for workspace
-	workspaceSessionID, err := claude.GenerateSessionID()
+	repoName, err := c.resolveRepo(flags)
 	if err != nil {
-		return fmt.Errorf("failed to generate workspace session ID: %w", err)
+		return errors.NotInRepo()
 	}
 
-	// Write prompt file for workspace
-	workspacePromptFile, err := c.writePromptFile(repoPath, state.AgentTypeWorkspace, workspaceName)
-	if err != nil {
-		return fmt.Errorf("failed to write workspace prompt: %w", err)
+	reqArgs := map[string]interface{}{"repo": repoName, "question": question}
+	if timeoutStr, ok := flags["timeout"]; ok {
+		timeout, err := parseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			return errors.InvalidArgument("--timeout", timeoutStr, "a duration like 2m or 10m")
+		}
+		reqArgs["timeout_seconds"] = timeout.Seconds()
 	}
 
-	// Copy hooks configuration if it exists
-	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
-		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	resp, err := c.sendDaemonRequest("broadcast_question", reqArgs)
+	if err != nil {
+		return err
 	}
-
-	// Start Claude in workspace window (skip in test mode)
-	var workspacePID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary
-		claudeBinary, err := c.getClaudeBinary()
-		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
+	outputJSON := flags["json"] == "true"
+	b, _ := resp.Data.(map[string]interface{})
+	id, _ := b["id"].(string)
+	replies, _ := b["replies"].([]interface{})
+	deadline, _ := time.Parse(time.RFC3339Nano, fmt.Sprint(b["deadline"]))
+	if !outputJSON {
+		fmt.Printf("Asked %d agents in %s (broadcast %s); waiting until %s for replies...\n\n",
+			len(replies), repoName, id, deadline.Local().Format(time.Kitchen))
+	}
+
+	printed := make(map[string]bool)
+	for {
+		replies, _ = b["replies"].([]interface{})
+		for _, item := range replies {
+			reply, _ := item.(map[string]interface{})
+			agent, _ := reply["agent"].(string)
+			answer, _ := reply["answer"].(string)
+			if answer == "" || printed[agent] {
+				continue
+			}
+			printed[agent] = true
+			if !outputJSON {
+				format.Bold.Printf("%s", agent)
+				fmt.Printf(": %s\n", answer)
+			}
 		}
-
-		fmt.Println("Starting Claude Code in workspace window...")
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, workspaceName, wtPath, workspaceSessionID, workspacePromptFile, repoName, "")
-		if err != nil {
-			return fmt.Errorf("failed to start workspace Claude: %w", err)
+		if done, _ := b["done"].(bool); done {
+			break
 		}
-		workspacePID = pid
 
-		// Set up output capture for workspace
-		if err := c.setupOutputCapture(tmuxSession, workspaceName, repoName, workspaceName, "workspace"); err != nil {
-			fmt.Printf("Warning: failed to setup output capture for workspace: %v\n", err)
+		time.Sleep(broadcastPollInterval)
+		resp, err = c.sendDaemonRequest("broadcast_status", map[string]interface{}{"id": id})
+		if err != nil {
+			return err
 		}
+		b, _ = resp.Data.(map[string]interface{})
 	}
 
-	// Register workspace with daemon
-	resp, err = client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         workspaceName,
-			"type":          "workspace",
-			"worktree_path": wtPath,
-			"tmux_window":   workspaceName,
-			"session_id":    workspaceSessionID,
-			"pid":           workspacePID,
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to register workspace: %w", err)
+	if outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(b)
 	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register workspace: %s", resp.Error)
+	summary, _ := b["summary"].(string)
+	if len(printed) > 0 {
+		fmt.Println()
 	}
+	format.Header("%s", summary)
+	return nil
+}
 
-	fmt.Println()
-	fmt.Println("✓ Workspace created successfully!")
-	fmt.Printf("  Name: %s\n", workspaceName)
-	fmt.Printf("  Branch: %s\n", branchName)
-	fmt.Printf("  Worktree: %s\n", wtPath)
-	fmt.Printf("\nConnect to workspace: multiclaude workspace connect %s\n", workspaceName)
-	fmt.Printf("Or use: multiclaude attach %s\n", workspaceName)
+// replyToBroadcast records this agent's answer to a broadcast question
+func (c *CLI) replyToBroadcast(args []string) error {
+	if len(args) < 2 {
+		return errors.InvalidUsage("usage: multiclaude agent reply <broadcast-id> <answer>")
+	}
+
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return err
+	}
 
+	if _, err := c.sendDaemonRequest("broadcast_reply", map[string]interface{}{
+		"id":     args[0],
+		"repo":   repoName,
+		"agent":  agentName,
+		"answer": strings.Join(args[1:], " "),
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("Reply to broadcast %s recorded\n", args[0])
 	return nil
 }
 
-// removeWorkspace removes a workspace
-func (c *CLI) removeWorkspace(args []string) error {
-	flags, remainingArgs := ParseFlags(args)
+func (c *CLI) restartAgentCmd(args []string) error {
+	// Parse flags
+	flags, remaining := ParseFlags(args)
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
+	// Get agent name from args
+	if len(remaining) < 1 {
+		return errors.InvalidUsage("usage: multiclaude agent restart <name> [--repo <repo>] [--force]")
 	}
+	agentName := remaining[0]
 
-	// Get workspace info
-	client := socket.NewClient(c.paths.DaemonSock)
+	// Get repo from flag or infer from cwd
+	repoName := flags["repo"]
+	if repoName == "" {
+		// Try to infer from cwd
+		inferred, err := c.inferRepoFromCwd()
+		if err != nil {
+			return errors.InvalidUsage("could not determine repository - use --repo flag or run from within a multiclaude worktree")
+		}
+		repoName = inferred
+	}
+
+	force := flags["force"] == "true"
+
+	fmt.Printf("Restarting agent '%s' in repository '%s'...\n", agentName, repoName)
+
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
+		Command: "restart_agent",
 		Args: map[string]interface{}{
-			"repo": repoName,
+			"repo":  repoName,
+			"agent": agentName,
+			"force": force,
 		},
 	})
 	if err != nil {
-		return errors.DaemonCommunicationFailed("getting workspace info", err)
+		return errors.DaemonCommunicationFailed("restarting agent", err)
 	}
 	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to get workspace info", fmt.Errorf("%s", resp.Error))
+		return errors.Wrap(errors.CategoryRuntime, "failed to restart agent", fmt.Errorf("%s", resp.Error))
 	}
 
-	agents, _ := resp.Data.([]interface{})
-
-	// Determine workspace name - from args or interactive selection
-	var workspaceName string
-	if len(remainingArgs) > 0 {
-		workspaceName = remainingArgs[0]
-	} else {
-		// Interactive selection
-		items := agentsToSelectableItems(agents, []string{"workspace"})
-		if len(items) == 0 {
-			return errors.NoWorkspacesFound(repoName)
-		}
-		selected, err := SelectFromList("Select workspace to remove:", items)
-		if err != nil {
-			return err
-		}
-		if selected == "" {
-			fmt.Println("Cancelled")
-			return nil
+	// Extract PID from response
+	if data, ok := resp.Data.(map[string]interface{}); ok {
+		if pid, ok := data["pid"].(float64); ok {
+			fmt.Printf("✓ Agent '%s' restarted successfully (PID: %d)\n", agentName, int(pid))
+		} else {
+			fmt.Printf("✓ Agent '%s' restarted successfully\n", agentName)
 		}
-		workspaceName = selected
+	} else {
+		fmt.Printf("✓ Agent '%s' restarted successfully\n", agentName)
 	}
 
-	fmt.Printf("Removing workspace '%s' from repo '%s'\n", workspaceName, repoName)
+	return nil
+}
 
-	// Find workspace
-	var workspaceInfo map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			name, _ := agentMap["name"].(string)
-			if agentType == "workspace" && name == workspaceName {
-				workspaceInfo = agentMap
-				break
-			}
-		}
+func (c *CLI) reviewPR(args []string) error {
+	if len(args) < 1 {
+		return errors.InvalidUsage("usage: multiclaude review <pr-url>")
 	}
 
-	if workspaceInfo == nil {
-		return errors.AgentNotFound("workspace", workspaceName, repoName)
+	prURL := args[0]
+
+	// Parse PR URL to extract owner, repo, and PR number
+	// Expected formats:
+	// - https://github.com/owner/repo/pull/123
+	// - github.com/owner/repo/pull/123
+	prURL = strings.TrimPrefix(prURL, "https://")
+	prURL = strings.TrimPrefix(prURL, "http://")
+	parts := strings.Split(prURL, "/")
+
+	if len(parts) < 5 || parts[3] != "pull" {
+		return errors.InvalidPRURL()
 	}
 
-	// Get worktree path
-	wtPath := workspaceInfo["worktree_path"].(string)
+	prNumber := parts[4]
+	fmt.Printf("Reviewing PR #%s\n", prNumber)
 
-	// Check for uncommitted changes
-	hasUncommitted, err := worktree.HasUncommittedChanges(wtPath)
-	if err != nil {
-		fmt.Printf("Warning: failed to check for uncommitted changes: %v\n", err)
-	} else if hasUncommitted {
-		fmt.Println("\nWarning: Workspace has uncommitted changes!")
-		fmt.Println("Files may be lost if you continue with removal.")
-		fmt.Print("Continue with removal? [y/N]: ")
+	// Determine repository from flag or current directory
+	flags, _ := ParseFlags(args[1:])
+	var repoName string
+	if r, ok := flags["repo"]; ok {
+		repoName = r
+	} else {
+		// Try to infer from current directory
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
 
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
-			fmt.Println("Removal cancelled")
-			return nil
+		// Check if we're in a tracked repo
+		repos := c.getReposList()
+		for _, repo := range repos {
+			repoPath := c.paths.RepoDir(repo)
+			if strings.HasPrefix(cwd, repoPath) {
+				repoName = repo
+				break
+			}
 		}
-	}
 
-	// Check for unpushed commits
-	if err := checkUnpushedCommits(wtPath, "Workspace", "removal"); err != nil {
-		return nil
+		if repoName == "" {
+			return errors.NotInRepo()
+		}
 	}
 
-	// Kill tmux window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := workspaceInfo["tmux_window"].(string)
-	fmt.Printf("Killing tmux window: %s\n", tmuxWindow)
-	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow))
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Warning: failed to kill tmux window: %v\n", err)
-	}
+	// Generate review agent name
+	reviewerName := fmt.Sprintf("review-%s", prNumber)
 
-	// Remove worktree
-	repoPath := c.paths.RepoDir(repoName)
-	wt := worktree.NewManager(repoPath)
+	fmt.Printf("Creating review agent '%s' in repo '%s'\n", reviewerName, repoName)
 
-	fmt.Printf("Removing worktree: %s\n", wtPath)
-	if err := wt.Remove(wtPath, false); err != nil {
-		fmt.Printf("Warning: failed to remove worktree: %v\n", err)
-	}
+	// Get repository path
+	repoPath := c.paths.RepoDir(repoName)
 
-	// Unregister from daemon
-	resp, err = client.Send(socket.Request{
-		Command: "remove_agent",
-		Args: map[string]interface{}{
-			"repo":  repoName,
-			"agent": workspaceName,
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to unregister workspace: %w", err)
-	}
-	if !resp.Success {
-		return fmt.Errorf("failed to unregister workspace: %s", resp.Error)
+	// Fetch the PR using GitHub's PR refs - this works for both same-repo and fork PRs
+	// The refs/pull/<number>/head ref always exists and points to the PR's head commit
+	fmt.Printf("Fetching PR #%s...\n", prNumber)
+	prRef := fmt.Sprintf("refs/pull/%s/head", prNumber)
+	localRef := fmt.Sprintf("refs/multiclaude/pr-%s", prNumber)
+	cmd := gitCommand("fetch", "origin", fmt.Sprintf("%s:%s", prRef, localRef))
+	cmd.Dir = repoPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to fetch PR #%s: %s", prNumber, strings.TrimSpace(string(output))), err).
+			WithSuggestion("ensure the PR exists and you have access to the repository")
 	}
 
-	fmt.Println("✓ Workspace removed successfully")
-	return nil
-}
-
-// listWorkspaces lists all workspaces in a repository
-func (c *CLI) listWorkspaces(args []string) error {
-	flags, _ := ParseFlags(args)
+	// Create worktree for review
+	wt := c.agentWorktreeManager(repoName, state.AgentTypeReview)
+	wtPath := c.paths.AgentWorktree(repoName, reviewerName)
+	reviewBranch := fmt.Sprintf("review/%s", reviewerName)
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
+	fmt.Printf("Creating worktree at: %s\n", wtPath)
+	if err := wt.CreateNewBranch(wtPath, reviewBranch, localRef); err != nil {
+		return fmt.Errorf("failed to create worktree: %w", err)
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
-		Args: map[string]interface{}{
-			"repo": repoName,
-			"rich": true,
-		},
-	})
-	if err != nil {
-		return errors.DaemonCommunicationFailed("listing workspaces", err)
-	}
+	// Get tmux session name
+	tmuxSession := c.tmuxSession(repoName)
 
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to list workspaces", fmt.Errorf("%s", resp.Error))
+	// Create tmux window for reviewer (detached so it doesn't switch focus)
+	fmt.Printf("Creating tmux window: %s\n", reviewerName)
+	cmd = tmuxCommand("new-window", "-d", "-t", tmuxSession, "-n", reviewerName, "-c", wtPath)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create tmux window: %w", err)
 	}
 
-	agents, ok := resp.Data.([]interface{})
-	if !ok {
-		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	// Generate session ID for reviewer
+	reviewerSessionID, err := claude.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate reviewer session ID: %w", err)
 	}
 
-	// Filter for workspaces
-	workspaces := []map[string]interface{}{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			if agentType == "workspace" {
-				workspaces = append(workspaces, agentMap)
+	// Find the review checklists that apply to the paths this PR changes
+	var checklists []review.Checklist
+	if remote, err := wt.GetUpstreamRemote(); err == nil {
+		if mainBranch, err := wt.GetDefaultBranch(remote); err == nil {
+			checklists, err = review.ForWorktree(repoPath, wtPath, remote+"/"+mainBranch)
+			if err != nil {
+				fmt.Printf("Warning: failed to load review checklists: %v\n", err)
 			}
 		}
 	}
-
-	if len(workspaces) == 0 {
-		fmt.Printf("No workspaces in repository '%s'\n", repoName)
-		format.Dimmed("\nCreate a workspace with: multiclaude workspace add <name>")
-		return nil
+	for _, checklist := range checklists {
+		fmt.Printf("Review checklist: %s (%d items)\n", checklist.Name, len(checklist.Items))
 	}
 
-	format.Header("Workspaces in '%s' (%d):", repoName, len(workspaces))
-	fmt.Println()
-
-	table := format.NewColoredTable("NAME", "BRANCH", "STATUS")
-	for _, ws := range workspaces {
-		name, _ := ws["name"].(string)
-		status, _ := ws["status"].(string)
-		branch, _ := ws["branch"].(string)
+	// Write prompt file for reviewer
+	readOnly := flags["read-only"] == "true"
+	reviewerPromptFile, err := c.writeReviewerPromptFile(repoPath, reviewerName, readOnly, checklists)
+	if err != nil {
+		return fmt.Errorf("failed to write reviewer prompt: %w", err)
+	}
 
-		// Format status with color
-		statusCell := formatAgentStatusCell(status)
+	// Copy hooks configuration if it exists
+	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
+		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	}
 
-		// Format branch
-		branchCell := format.ColorCell(branch, format.Cyan)
-		if branch == "" {
-			branchCell = format.ColorCell("-", format.Dim)
+	// Make the worktree read-only so feedback arrives as comments, not commits
+	if readOnly {
+		fmt.Println("Making worktree read-only...")
+		if err := worktree.MakeReadOnly(wtPath); err != nil {
+			return errors.GitOperationFailed("read-only setup", err)
 		}
-
-		table.AddRow(
-			format.Cell(name),
-			branchCell,
-			statusCell,
-		)
 	}
-	table.Print()
 
-	return nil
-}
+	// Start Claude in reviewer window with initial task (skip in test mode)
+	var reviewerPID int
+	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		// Resolve claude binary
+		claudeBinary, err := c.getClaudeBinary()
+		if err != nil {
+			return fmt.Errorf("failed to resolve claude binary: %w", err)
+		}
 
-// connectWorkspace attaches to a workspace
-func (c *CLI) connectWorkspace(args []string) error {
-	flags, remainingArgs := ParseFlags(args)
+		fmt.Println("Starting Claude Code in reviewer window...")
+		initialMessage := fmt.Sprintf("Review PR #%s: https://github.com/%s/%s/pull/%s", prNumber, parts[1], parts[2], prNumber)
+		pid, err := c.startClaudeInWindow(tmux.NewClient(), claudeBinary, tmuxSession, reviewerName, wtPath, reviewerSessionID, reviewerPromptFile, repoName, state.AgentTypeReview, initialMessage)
+		if err != nil {
+			return fmt.Errorf("failed to start reviewer Claude: %w", err)
+		}
+		reviewerPID = pid
 
-	// Determine repository
-	repoName, err := c.resolveRepo(flags)
-	if err != nil {
-		return errors.NotInRepo()
+		// Set up output capture for reviewer
+		if err := c.setupOutputCapture(tmux.NewClient(), tmuxSession, reviewerName, repoName, reviewerName, "review"); err != nil {
+			fmt.Printf("Warning: failed to setup output capture for reviewer: %v\n", err)
+		}
 	}
 
-	// Get workspace info
-	client := socket.NewClient(c.paths.DaemonSock)
+	// Register reviewer with daemon
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
-		Command: "list_agents",
+		Command: "add_agent",
 		Args: map[string]interface{}{
-			"repo": repoName,
+			"repo":          repoName,
+			"agent":         reviewerName,
+			"type":          "review",
+			"worktree_path": wtPath,
+			"tmux_window":   reviewerName,
+			"task":          fmt.Sprintf("Review PR #%s", prNumber),
+			"session_id":    reviewerSessionID,
+			"pid":           reviewerPID,
+			"read_only":     readOnly,
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to get workspace info: %w (is daemon running?)", err)
+		return fmt.Errorf("failed to register reviewer: %w", err)
 	}
 	if !resp.Success {
-		return fmt.Errorf("failed to get workspace info: %s", resp.Error)
+		return fmt.Errorf("failed to register reviewer: %s", resp.Error)
 	}
 
-	agents, _ := resp.Data.([]interface{})
+	fmt.Println()
+	fmt.Println("✓ Review agent created successfully!")
+	fmt.Printf("  Name: %s\n", reviewerName)
+	fmt.Printf("  Branch: %s\n", reviewBranch)
+	fmt.Printf("  Worktree: %s\n", wtPath)
+	if readOnly {
+		fmt.Println("  Mode: read-only (commits blocked)")
+	}
+	fmt.Printf("\nAttach to reviewer: tmux select-window -t %s:%s\n", tmuxSession, reviewerName)
+	fmt.Printf("Or use: multiclaude attach %s\n", reviewerName)
 
-	// Determine workspace name - from args or interactive selection
-	var workspaceName string
-	if len(remainingArgs) > 0 {
-		workspaceName = remainingArgs[0]
+	return nil
+}
+
+// Logs command implementations
+
+func (c *CLI) viewLogs(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: multiclaude logs <agent> [--lines N] [-f|--follow] [--grep <pattern>]")
+	}
+
+	agentName := args[0]
+	flags, _ := ParseFlags(args[1:])
+	if _, ok := flags["f"]; ok {
+		flags["follow"] = "true"
+	}
+
+	// Determine repository
+	var repoName string
+	if r, ok := flags["repo"]; ok {
+		repoName = r
 	} else {
-		// Interactive selection
-		items := agentsToSelectableItems(agents, []string{"workspace"})
-		if len(items) == 0 {
-			return errors.NoWorkspacesFound(repoName)
-		}
-		selected, err := SelectFromList("Select workspace to connect:", items)
-		if err != nil {
-			return err
+		repos := c.getReposList()
+		if len(repos) == 0 {
+			return fmt.Errorf("no repositories tracked")
 		}
-		if selected == "" {
-			fmt.Println("Cancelled")
-			return nil
+		if len(repos) == 1 {
+			repoName = repos[0]
+		} else {
+			return fmt.Errorf("multiple repos exist. Use --repo flag to specify which one")
 		}
-		workspaceName = selected
 	}
 
-	// Find workspace
-	var workspaceInfo map[string]interface{}
-	for _, agent := range agents {
-		if agentMap, ok := agent.(map[string]interface{}); ok {
-			agentType, _ := agentMap["type"].(string)
-			name, _ := agentMap["name"].(string)
-			if agentType == "workspace" && name == workspaceName {
-				workspaceInfo = agentMap
-				break
-			}
-		}
-	}
+	// Determine if it's a worker or system agent by checking if it exists in workers dir
+	workerLogFile := c.paths.AgentLogFile(repoName, agentName, true)
+	systemLogFile := c.paths.AgentLogFile(repoName, agentName, false)
 
-	if workspaceInfo == nil {
-		return errors.WorkspaceNotFound(workspaceName, repoName)
+	var logFile string
+	if _, err := os.Stat(workerLogFile); err == nil {
+		logFile = workerLogFile
+	} else if _, err := os.Stat(systemLogFile); err == nil {
+		logFile = systemLogFile
 	}
 
-	// Get tmux session and window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
-	tmuxWindow := workspaceInfo["tmux_window"].(string)
+	if pattern, ok := flags["grep"]; ok {
+		return c.grepAgentLog(repoName, agentName, logFile, pattern, flags["follow"] == "true")
+	}
+	if logFile == "" {
+		// The agent is gone, but its rotated logs may have been archived
+		return c.viewArchivedLog(repoName, agentName, flags)
+	}
 
-	// Attach to tmux
-	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
+	// Check for --follow flag
+	if _, ok := flags["follow"]; ok {
+		// Use tail -f
+		cmd := exec.Command("tail", "-f", logFile)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
 
-	readOnly := flags["read-only"] == "true" || flags["r"] == "true"
-	tmuxArgs := []string{"attach", "-t", target}
-	if readOnly {
-		tmuxArgs = append(tmuxArgs, "-r")
+	// Determine number of lines
+	lines := "100"
+	if l, ok := flags["lines"]; ok {
+		lines = l
 	}
 
-	cmd := exec.Command("tmux", tmuxArgs...)
-	cmd.Stdin = os.Stdin
+	// Use tail to get recent lines
+	cmd := exec.Command("tail", "-n", lines, logFile)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-
 	return cmd.Run()
 }
 
-// validateWorkspaceName validates that a workspace name follows branch name restrictions
-func validateWorkspaceName(name string) error {
-	if name == "" {
-		return fmt.Errorf("workspace name cannot be empty")
+func (c *CLI) listLogs(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	// Determine repository
+	var repoName string
+	if r, ok := flags["repo"]; ok {
+		repoName = r
 	}
 
-	// Git branch name restrictions
-	// - Cannot start with . or -
-	// - Cannot contain consecutive dots ..
-	// - Cannot contain \ or any of these characters: ~ ^ : ? * [ @ { } space
-	// - Cannot end with . or /
-	// - Cannot be "." or ".."
+	if repoName != "" {
+		// List logs for specific repo
+		return c.listLogsForRepo(repoName)
+	}
 
-	if name == "." || name == ".." {
-		return fmt.Errorf("workspace name cannot be '.' or '..'")
+	// List logs for all repos
+	repos := c.getReposList()
+	if len(repos) == 0 {
+		fmt.Println("No repositories tracked")
+		return nil
 	}
 
-	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "-") {
-		return fmt.Errorf("workspace name cannot start with '.' or '-'")
+	for _, repo := range repos {
+		if err := c.listLogsForRepo(repo); err != nil {
+			fmt.Printf("Warning: failed to list logs for %s: %v\n", repo, err)
+		}
 	}
+	return nil
+}
 
-	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, "/") {
-		return fmt.Errorf("workspace name cannot end with '.' or '/'")
+func (c *CLI) listLogsForRepo(repoName string) error {
+	repoOutputDir := c.paths.RepoOutputDir(repoName)
+
+	// Check if directory exists
+	if _, err := os.Stat(repoOutputDir); os.IsNotExist(err) {
+		fmt.Printf("No logs for %s\n", repoName)
+		return nil
 	}
 
-	if strings.Contains(name, "..") {
-		return fmt.Errorf("workspace name cannot contain '..'")
+	fmt.Printf("\n%s:\n", repoName)
+
+	// List system agent logs
+	entries, err := os.ReadDir(repoOutputDir)
+	if err != nil {
+		return err
 	}
 
-	invalidChars := []string{"\\", "~", "^", ":", "?", "*", "[", "@", "{", "}", " ", "\t", "\n"}
-	for _, char := range invalidChars {
-		if strings.Contains(name, char) {
-			return fmt.Errorf("workspace name cannot contain '%s'", char)
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() == "workers" {
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ".log") {
+			info, _ := entry.Info()
+			agentName := strings.TrimSuffix(entry.Name(), ".log")
+			if info != nil {
+				fmt.Printf("  %s (%d bytes)\n", agentName, info.Size())
+			} else {
+				fmt.Printf("  %s\n", agentName)
+			}
 		}
 	}
 
-	return nil
+	// List worker logs
+	workersDir := c.paths.WorkersOutputDir(repoName)
+	if _, err := os.Stat(workersDir); err == nil {
+		workerEntries, err := os.ReadDir(workersDir)
+		if err == nil && len(workerEntries) > 0 {
+			fmt.Println("  workers/")
+			for _, entry := range workerEntries {
+				if strings.HasSuffix(entry.Name(), ".log") {
+					info, _ := entry.Info()
+					workerName := strings.TrimSuffix(entry.Name(), ".log")
+					if info != nil {
+						fmt.Printf("    %s (%d bytes)\n", workerName, info.Size())
+					} else {
+						fmt.Printf("    %s\n", workerName)
+					}
+				}
+			}
+		}
+	}
+
+	return c.listArchivedLogs(repoName)
 }
 
-// getReposList is a helper to get the list of repos
-func (c *CLI) getReposList() []string {
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{Command: "list_repos"})
+// listArchivedLogs lists a repository's rotated log segments
+func (c *CLI) listArchivedLogs(repoName string) error {
+	store, cfg, err := c.openLogStore()
 	if err != nil {
-		return []string{}
+		return err
 	}
-
-	if !resp.Success {
-		return []string{}
+	segments, err := logstore.Segments(context.Background(), store, repoName+"/")
+	if err != nil {
+		return fmt.Errorf("failed to list rotated logs in %s: %w", cfg, err)
 	}
-
-	repos, ok := resp.Data.([]interface{})
-	if !ok {
-		return []string{}
+	if len(segments) == 0 {
+		return nil
 	}
 
-	result := make([]string, 0, len(repos))
-	for _, repo := range repos {
-		if repoStr, ok := repo.(string); ok {
-			result = append(result, repoStr)
-		}
+	fmt.Printf("  rotated (%s):\n", cfg)
+	for _, seg := range segments {
+		fmt.Printf("    %s (%d bytes)\n", strings.TrimPrefix(seg.Key, repoName+"/"), seg.Size)
 	}
-
-	return result
+	return nil
 }
 
-func (c *CLI) sendMessage(args []string) error {
-	if len(args) < 2 {
-		return errors.InvalidUsage("usage: multiclaude agent send-message <to> <message>")
+func (c *CLI) searchLogs(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: multiclaude logs search <pattern> [--repo <repo>]")
 	}
 
-	to := args[0]
-	body := strings.Join(args[1:], " ")
+	pattern := args[0]
+	flags, _ := ParseFlags(args[1:])
 
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
-	if err != nil {
-		return err
+	// Determine repository
+	var repoName string
+	if r, ok := flags["repo"]; ok {
+		repoName = r
 	}
 
-	// Create message manager
-	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	// Get search directories
+	var searchPaths []string
+	if repoName != "" {
+		repoOutputDir := c.paths.RepoOutputDir(repoName)
+		if _, err := os.Stat(repoOutputDir); err == nil {
+			searchPaths = append(searchPaths, repoOutputDir)
+		}
+	} else {
+		// Search all repos
+		repos := c.getReposList()
+		for _, repo := range repos {
+			repoOutputDir := c.paths.RepoOutputDir(repo)
+			if _, err := os.Stat(repoOutputDir); err == nil {
+				searchPaths = append(searchPaths, repoOutputDir)
+			}
+		}
+	}
 
-	// Send message
-	msg, err := msgMgr.Send(repoName, agentName, to, body)
+	// Rotated logs are searched through the log storage, wherever it is
+	archived, err := c.searchArchivedLogs(pattern, repoName)
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		fmt.Printf("Warning: failed to search rotated logs: %v\n", err)
 	}
 
-	// Trigger immediate routing (best-effort, polling is fallback)
-	client := socket.NewClient(c.paths.DaemonSock)
-	_, _ = client.Send(socket.Request{Command: "route_messages"})
-	// Ignore errors - 2-minute polling fallback will catch it
+	if len(searchPaths) == 0 {
+		if !archived {
+			fmt.Println("No log directories found")
+		}
+		return nil
+	}
 
-	fmt.Printf("Message sent to %s (ID: %s)\n", to, msg.ID)
-	return nil
+	// Use grep to search recursively
+	grepArgs := []string{"-r", "-n", "--include=*.log", pattern}
+	grepArgs = append(grepArgs, searchPaths...)
+
+	cmd := exec.Command("grep", grepArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Run grep (exit code 1 means no matches, which is fine)
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		if !archived {
+			fmt.Println("No matches found")
+		}
+		return nil
+	}
+	return err
 }
 
-func (c *CLI) listMessages(args []string) error {
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
+// searchArchivedLogs prints the lines of rotated logs matching pattern in
+// grep's file:line:text format, returning true if any matched. Patterns
+// that aren't valid regular expressions are matched literally.
+func (c *CLI) searchArchivedLogs(pattern, repoName string) (bool, error) {
+	re, err := regexp.Compile(pattern)
 	if err != nil {
-		return err
+		re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+	store, cfg, err := c.openLogStore()
+	if err != nil {
+		return false, err
 	}
 
-	msgMgr := messages.NewManager(c.paths.MessagesDir)
+	prefix := ""
+	if repoName != "" {
+		prefix = repoName + "/"
+	}
+	ctx := context.Background()
+	segments, err := logstore.Segments(ctx, store, prefix)
+	if err != nil {
+		return false, err
+	}
 
-	// List messages
-	msgs, err := msgMgr.List(repoName, agentName)
+	matched := false
+	for _, seg := range segments {
+		r, err := store.Open(ctx, seg.Key)
+		if err != nil {
+			return matched, err
+		}
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for n := 1; scanner.Scan(); n++ {
+			if re.MatchString(scanner.Text()) {
+				fmt.Printf("%s/%s:%d:%s\n", cfg, seg.Key, n, scanner.Text())
+				matched = true
+			}
+		}
+		r.Close()
+		if err := scanner.Err(); err != nil {
+			return matched, fmt.Errorf("%s: %w", seg.Key, err)
+		}
+	}
+	return matched, nil
+}
+
+func (c *CLI) cleanLogs(args []string) error {
+	flags, _ := ParseFlags(args)
+
+	olderThan, ok := flags["older-than"]
+	if !ok {
+		return fmt.Errorf("usage: multiclaude logs clean --older-than <duration> (e.g., 7d, 24h)")
+	}
+
+	// Parse duration
+	duration, err := parseDuration(olderThan)
 	if err != nil {
-		return fmt.Errorf("failed to list messages: %w", err)
+		return fmt.Errorf("invalid duration: %v", err)
 	}
 
-	if len(msgs) == 0 {
-		fmt.Println("No messages")
+	cutoff := time.Now().Add(-duration)
+	fmt.Printf("Cleaning logs older than %s...\n", cutoff.Format(time.RFC3339))
+
+	var deletedCount, deletedBytes int64
+
+	// Walk output directory
+	err = filepath.Walk(c.paths.OutputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if !strings.HasSuffix(path, ".log") {
+			return nil
+		}
+		if info.ModTime().Before(cutoff) {
+			deletedBytes += info.Size()
+			if err := os.Remove(path); err != nil {
+				fmt.Printf("Warning: failed to remove %s: %v\n", path, err)
+			} else {
+				deletedCount++
+			}
+		}
 		return nil
-	}
+	})
 
-	fmt.Printf("Messages for %s (%d):\n", agentName, len(msgs))
-	for _, msg := range msgs {
-		status := msg.Status
-		if msg.Status == messages.StatusAcked && msg.AckedAt != nil {
-			status = messages.Status(fmt.Sprintf("acked (%s)", formatTime(*msg.AckedAt)))
+	if err != nil {
+		return fmt.Errorf("failed to walk output directory: %w", err)
+	}
+
+	// Rotated logs, wherever they are stored
+	if store, cfg, err := c.openLogStore(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	} else {
+		count, size, err := logstore.Prune(context.Background(), store, cutoff)
+		deletedCount += int64(count)
+		deletedBytes += size
+		if err != nil {
+			fmt.Printf("Warning: failed to remove rotated logs from %s: %v\n", cfg, err)
 		}
-		fmt.Printf("  [%s] %s - From: %s - %s - %s\n",
-			msg.ID,
-			formatTime(msg.Timestamp),
-			msg.From,
-			status,
-			truncateString(msg.Body, 60))
 	}
 
+	fmt.Printf("Deleted %d files (%.2f MB)\n", deletedCount, float64(deletedBytes)/(1024*1024))
 	return nil
 }
 
-func (c *CLI) readMessage(args []string) error {
-	if len(args) < 1 {
-		return errors.InvalidUsage("usage: multiclaude agent read-message <message-id>")
+// openLogStore opens the storage holding rotated agent logs
+func (c *CLI) openLogStore() (logstore.Store, logstore.Config, error) {
+	st, err := c.loadState()
+	if err != nil {
+		return nil, logstore.Config{}, err
 	}
-
-	messageID := args[0]
-
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
+	cfg := st.GetLogStorage()
+	store, err := logstore.Open(cfg, c.paths.OutputDir)
 	if err != nil {
-		return err
+		return nil, cfg, fmt.Errorf("failed to open log storage %s: %w", cfg, err)
 	}
+	return store, cfg, nil
+}
 
-	msgMgr := messages.NewManager(c.paths.MessagesDir)
+// agentLogSegments returns an agent's rotated logs, oldest first
+func agentLogSegments(ctx context.Context, store logstore.Store, repoName, agentName string) ([]logstore.Object, error) {
+	var segments []logstore.Object
+	for _, isWorker := range []bool{false, true} {
+		found, err := logstore.Segments(ctx, store, logstore.AgentPrefix(repoName, agentName, isWorker))
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, found...)
+	}
+	return segments, nil
+}
 
-	// Get message
-	msg, err := msgMgr.Get(repoName, agentName, messageID)
+// grepAgentLog prints the lines of an agent's transcript matching pattern,
+// oldest first: its rotated logs, then the live log (logFile, empty once
+// the agent is gone). Lines are matched and printed as they rendered,
+// without escape sequences. With follow, it then keeps printing matching
+// lines as the agent writes them.
+func (c *CLI) grepAgentLog(repoName, agentName, logFile, pattern string, follow bool) error {
+	re, err := regexp.Compile(pattern)
 	if err != nil {
-		return fmt.Errorf("failed to read message: %w", err)
+		re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+	if follow && logFile == "" {
+		return fmt.Errorf("agent %s has no live log to follow", agentName)
 	}
 
-	// Update status to read
-	if msg.Status == messages.StatusPending || msg.Status == messages.StatusDelivered {
-		if err := msgMgr.UpdateStatus(repoName, agentName, messageID, messages.StatusRead); err != nil {
-			fmt.Printf("Warning: failed to update message status: %v\n", err)
+	printMatches := func(r io.Reader) (bool, error) {
+		matched := false
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if line := loopdetect.Plain(scanner.Text()); re.MatchString(line) {
+				fmt.Println(line)
+				matched = true
+			}
 		}
+		return matched, scanner.Err()
 	}
 
-	// Display message
-	fmt.Printf("Message: %s\n", msg.ID)
-	fmt.Printf("From: %s\n", msg.From)
-	fmt.Printf("To: %s\n", msg.To)
-	fmt.Printf("Time: %s\n", msg.Timestamp.Format(time.RFC3339))
-	fmt.Printf("Status: %s\n", msg.Status)
-	if msg.AckedAt != nil {
-		fmt.Printf("Acked: %s\n", msg.AckedAt.Format(time.RFC3339))
+	found := logFile != ""
+	matched := false
+	store, cfg, err := c.openLogStore()
+	if err == nil {
+		ctx := context.Background()
+		segments, err := agentLogSegments(ctx, store, repoName, agentName)
+		if err != nil {
+			return fmt.Errorf("failed to list rotated logs in %s: %w", cfg, err)
+		}
+		for _, seg := range segments {
+			found = true
+			r, err := store.Open(ctx, seg.Key)
+			if err != nil {
+				return err
+			}
+			m, err := printMatches(r)
+			r.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", seg.Key, err)
+			}
+			matched = matched || m
+		}
+	}
+	if !found {
+		return fmt.Errorf("no log file found for agent %s in repo %s", agentName, repoName)
+	}
+
+	if follow {
+		// tail prints the live log from its start, then what the agent writes next
+		cmd := exec.Command("tail", "-n", "+1", "-f", logFile)
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		_, err = printMatches(out)
+		if waitErr := cmd.Wait(); err == nil {
+			err = waitErr
+		}
+		return err
 	}
-	fmt.Println()
-	fmt.Println(msg.Body)
 
+	if logFile != "" {
+		f, err := os.Open(logFile)
+		if err != nil {
+			return err
+		}
+		m, err := printMatches(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		matched = matched || m
+	}
+	if !matched {
+		fmt.Println("No matches found")
+	}
 	return nil
 }
 
-func (c *CLI) ackMessage(args []string) error {
-	if len(args) < 1 {
-		return errors.InvalidUsage("usage: multiclaude agent ack-message <message-id>")
+// viewArchivedLog prints the end of an agent's newest rotated log, for
+// agents whose live log is gone
+func (c *CLI) viewArchivedLog(repoName, agentName string, flags map[string]string) error {
+	store, cfg, err := c.openLogStore()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	segments, err := agentLogSegments(ctx, store, repoName, agentName)
+	if err != nil {
+		return fmt.Errorf("failed to list rotated logs in %s: %w", cfg, err)
+	}
+	if len(segments) == 0 {
+		return fmt.Errorf("no log file found for agent %s in repo %s", agentName, repoName)
+	}
+	if _, ok := flags["follow"]; ok {
+		return fmt.Errorf("agent %s has no live log to follow; use 'multiclaude logs export %s' for its rotated logs", agentName, agentName)
 	}
 
-	messageID := args[0]
+	lines := 100
+	if l, ok := flags["lines"]; ok {
+		if lines, err = strconv.Atoi(l); err != nil || lines < 1 {
+			return fmt.Errorf("invalid --lines value: %s", l)
+		}
+	}
 
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
+	latest := segments[len(segments)-1]
+	r, err := store.Open(ctx, latest.Key)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	msgMgr := messages.NewManager(c.paths.MessagesDir)
-
-	// Ack message
-	if err := msgMgr.Ack(repoName, agentName, messageID); err != nil {
-		return fmt.Errorf("failed to acknowledge message: %w", err)
+	// Keep the last lines in a ring so large segments are streamed
+	tail := make([]string, 0, lines)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(tail) == lines {
+			tail = tail[1:]
+		}
+		tail = append(tail, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
 	}
 
-	fmt.Printf("Message %s acknowledged\n", messageID)
+	fmt.Fprintf(os.Stderr, "%s has no live log; showing rotated log %s/%s\n", agentName, cfg, latest.Key)
+	for _, line := range tail {
+		fmt.Println(line)
+	}
 	return nil
 }
 
-// inferRepoFromCwd infers just the repository name from the current working directory.
-// Unlike inferAgentContext, it doesn't require determining the specific agent.
-func (c *CLI) inferRepoFromCwd() (string, error) {
-	cwd, err := os.Getwd()
+// exportLogs writes an agent's whole log, its rotated segments oldest first
+// followed by the live log, to a file or stdout
+func (c *CLI) exportLogs(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude logs export <agent-name> [--repo <repo>] [--output <file>]")
+	}
+	agentName := posArgs[0]
+	repoName, err := c.resolveRepo(flags)
 	if err != nil {
-		return "", fmt.Errorf("failed to get current directory: %w", err)
+		return errors.NotInRepo()
 	}
 
-	// Resolve symlinks in cwd for proper path comparison
-	// This is especially important on macOS where /tmp -> /private/tmp
-	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
-		cwd = resolved
+	store, cfg, err := c.openLogStore()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	segments, err := agentLogSegments(ctx, store, repoName, agentName)
+	if err != nil {
+		return fmt.Errorf("failed to list rotated logs in %s: %w", cfg, err)
 	}
 
-	// Check if we're in a worktree path
-	// Path format: ~/.multiclaude/wts/<repo>/<agent>
-	if hasPathPrefix(cwd, c.paths.WorktreesDir) {
-		rel, err := filepath.Rel(c.paths.WorktreesDir, cwd)
-		if err == nil {
-			parts := strings.SplitN(rel, string(filepath.Separator), 2)
-			if len(parts) >= 1 && parts[0] != "" && parts[0] != "." {
-				return parts[0], nil
-			}
+	var live []string
+	for _, isWorker := range []bool{false, true} {
+		path := c.paths.AgentLogFile(repoName, agentName, isWorker)
+		if _, err := os.Stat(path); err == nil {
+			live = append(live, path)
 		}
 	}
+	if len(segments) == 0 && len(live) == 0 {
+		return fmt.Errorf("no log file found for agent %s in repo %s", agentName, repoName)
+	}
 
-	// Check if we're in a main repo path
-	// Path format: ~/.multiclaude/repos/<repo>
-	if hasPathPrefix(cwd, c.paths.ReposDir) {
-		rel, err := filepath.Rel(c.paths.ReposDir, cwd)
-		if err == nil {
-			parts := strings.SplitN(rel, string(filepath.Separator), 2)
-			if len(parts) >= 1 && parts[0] != "" && parts[0] != "." {
-				return parts[0], nil
-			}
+	out := io.Writer(os.Stdout)
+	if path := flags["output"]; path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
 		}
+		defer f.Close()
+		out = f
 	}
 
-	return "", fmt.Errorf("not in a multiclaude directory")
+	var written int64
+	for _, seg := range segments {
+		r, err := store.Open(ctx, seg.Key)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(out, r)
+		r.Close()
+		written += n
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", seg.Key, err)
+		}
+	}
+	for _, path := range live {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(out, f)
+		f.Close()
+		written += n
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", path, err)
+		}
+	}
+
+	if path := flags["output"]; path != "" {
+		fmt.Printf("Exported %d bytes (%d rotated segment(s) from %s) to %s\n", written, len(segments), cfg, path)
+	}
+	return nil
 }
 
-// normalizeGitHubURL normalizes GitHub URLs to a common format for comparison.
-// It handles both SSH (git@github.com:user/repo.git) and HTTPS (https://github.com/user/repo) formats.
-// Returns lowercase "github.com/user/repo" format for comparison.
-func normalizeGitHubURL(url string) string {
-	url = strings.TrimSpace(url)
-	lowerURL := strings.ToLower(url)
+// logStorage shows where rotated agent logs are kept, or changes it. Flags
+// not given keep their current value.
+func (c *CLI) logStorage(args []string) error {
+	flags, _ := ParseFlags(args)
 
-	// Handle SSH format: git@github.com:user/repo.git
-	if strings.HasPrefix(lowerURL, "git@github.com:") {
-		path := url[len("git@github.com:"):]
-		path = strings.TrimSuffix(path, ".git")
-		return strings.ToLower("github.com/" + path)
+	st, err := c.loadState()
+	if err != nil {
+		return err
 	}
+	cfg := st.GetLogStorage()
 
-	// Handle HTTPS format: https://github.com/user/repo or https://github.com/user/repo.git
-	if strings.HasPrefix(lowerURL, "https://github.com/") {
-		path := url[len("https://"):]
-		path = strings.TrimSuffix(path, ".git")
-		return strings.ToLower(path)
+	if len(flags) == 0 {
+		fmt.Printf("Rotated logs: %s\n", cfg)
+		if cfg.Region != "" {
+			fmt.Printf("  Region: %s\n", cfg.Region)
+		}
+		if cfg.Endpoint != "" {
+			fmt.Printf("  Endpoint: %s\n", cfg.Endpoint)
+		}
+		if cfg.RetentionDays > 0 {
+			fmt.Printf("  Retention: %d days\n", cfg.RetentionDays)
+		} else {
+			fmt.Println("  Retention: forever")
+		}
+		fmt.Println()
+		fmt.Println("To change:")
+		fmt.Println("  multiclaude logs storage --backend=s3 --bucket=<name> [--prefix=<path>] [--region=<region>]")
+		fmt.Println("  multiclaude logs storage --backend=gcs --bucket=<name> [--prefix=<path>]")
+		fmt.Println("  multiclaude logs storage --retention=30d")
+		return nil
 	}
 
-	// Handle HTTP format: http://github.com/user/repo
-	if strings.HasPrefix(lowerURL, "http://github.com/") {
-		path := url[len("http://"):]
-		path = strings.TrimSuffix(path, ".git")
-		return strings.ToLower(path)
+	if v, ok := flags["backend"]; ok {
+		if v != cfg.Backend {
+			// Bucket settings don't carry over between backends
+			cfg = logstore.Config{RetentionDays: cfg.RetentionDays}
+		}
+		cfg.Backend = v
 	}
-
-	// Handle git:// protocol: git://github.com/user/repo.git
-	if strings.HasPrefix(lowerURL, "git://github.com/") {
-		path := url[len("git://"):]
-		path = strings.TrimSuffix(path, ".git")
-		return strings.ToLower(path)
+	for flag, field := range map[string]*string{"bucket": &cfg.Bucket, "prefix": &cfg.Prefix, "region": &cfg.Region, "endpoint": &cfg.Endpoint} {
+		if v, ok := flags[flag]; ok {
+			*field = v
+		}
+	}
+	if v, ok := flags["retention"]; ok {
+		if v == "off" || v == "0" {
+			cfg.RetentionDays = 0
+		} else {
+			d, err := parseDuration(v)
+			if err != nil || d < 24*time.Hour {
+				return errors.InvalidArgument("retention", v, "a number of days such as 30d, or off")
+			}
+			cfg.RetentionDays = int(d / (24 * time.Hour))
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return errors.InvalidUsage(err.Error())
 	}
 
-	// Return empty string for non-GitHub URLs
-	return ""
-}
-
-// findRepoFromGitRemote looks for a git remote in the current directory
-// and tries to match it against known repositories in state.
-func (c *CLI) findRepoFromGitRemote() (string, error) {
-	// Run git remote get-url origin
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	output, err := cmd.Output()
+	resp, err := c.sendDaemonRequest("set_log_storage", map[string]interface{}{
+		"backend":        cfg.Backend,
+		"bucket":         cfg.Bucket,
+		"prefix":         cfg.Prefix,
+		"region":         cfg.Region,
+		"endpoint":       cfg.Endpoint,
+		"retention_days": cfg.RetentionDays,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get git remote: %w", err)
+		return err
 	}
-
-	remoteURL := strings.TrimSpace(string(output))
-	if remoteURL == "" {
-		return "", fmt.Errorf("git remote URL is empty")
+	data, _ := resp.Data.(map[string]interface{})
+	fmt.Printf("Rotated logs will be kept in %v\n", data["storage"])
+	if cfg.Remote() {
+		fmt.Println("The daemon uploads them on its next health check and removes the local copies")
 	}
+	return nil
+}
 
-	normalizedRemote := normalizeGitHubURL(remoteURL)
-	if normalizedRemote == "" {
-		return "", fmt.Errorf("not a GitHub URL: %s", remoteURL)
+// parseDuration parses a duration string like "7d", "24h", "30m"
+func parseDuration(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("duration too short")
 	}
 
-	// Load state to check against known repositories
-	st, err := c.loadState()
-	if err != nil {
-		return "", err
-	}
+	unit := s[len(s)-1]
+	valueStr := s[:len(s)-1]
 
-	// Iterate through repos and find a match
-	for _, repoName := range st.ListRepos() {
-		repo, exists := st.GetRepo(repoName)
-		if !exists {
-			continue
-		}
+	var value int
+	if _, err := fmt.Sscanf(valueStr, "%d", &value); err != nil {
+		return 0, err
+	}
 
-		normalizedStateURL := normalizeGitHubURL(repo.GithubURL)
-		if normalizedStateURL != "" && normalizedStateURL == normalizedRemote {
-			return repoName, nil
-		}
+	switch unit {
+	case 'd':
+		return time.Duration(value) * 24 * time.Hour, nil
+	case 'h':
+		return time.Duration(value) * time.Hour, nil
+	case 'm':
+		return time.Duration(value) * time.Minute, nil
+	default:
+		return 0, fmt.Errorf("unknown unit: %c (use d, h, or m)", unit)
 	}
-
-	return "", fmt.Errorf("no matching repository found for remote: %s", remoteURL)
 }
 
-// resolveRepo determines the repository to use based on:
-// 1. Explicit --repo flag (highest priority)
-// 2. Git remote URL matching (if in a git repo with origin pointing to a tracked repo)
-// 3. Current working directory (if in a multiclaude directory)
-// 4. Current repo set via 'multiclaude repo use' (lowest priority)
-func (c *CLI) resolveRepo(flags map[string]string) (string, error) {
-	// 1. Check explicit --repo flag
-	if r, ok := flags["repo"]; ok {
-		return r, nil
+// parseTimeBudget parses a --deadline value. Go durations such as "1h30m" are
+// accepted as well as the day-based units understood by parseDuration.
+func parseTimeBudget(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		d, err = parseDuration(s)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
 	}
+	return d, nil
+}
 
-	// 2. Try to infer from git remote URL
-	if repoName, err := c.findRepoFromGitRemote(); err == nil {
-		return repoName, nil
+// runOneShot runs a single headless agent in a temporary worktree and emits
+// its work as a diff on stdout (or --diff file) or as a pull request. The
+// exit status tells CI what happened: 0 changes, 3 no changes, 4 the agent
+// failed, 5 timed out.
+func (c *CLI) runOneShot(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	task := flags["task"]
+	if task == "" {
+		task = strings.Join(posArgs, " ")
+	}
+	if task == "" {
+		return errors.InvalidUsage("usage: multiclaude run [--repo <path|repo>] --task <task> [--timeout <30m>] [--diff <file>] [--pr]")
 	}
 
-	// 3. Try to infer from current working directory
-	if inferred, err := c.inferRepoFromCwd(); err == nil {
-		return inferred, nil
+	// --repo is a path, or the name of a tracked repository
+	repoDir := flags["repo"]
+	if repoDir == "" {
+		repoDir = "."
+	}
+	if info, err := os.Stat(repoDir); err != nil || !info.IsDir() {
+		repoDir = c.paths.RepoDir(repoDir)
 	}
 
-	// 4. Check current repo from daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "get_current_repo",
-	})
-	if err == nil && resp.Success {
-		if currentRepo, ok := resp.Data.(string); ok && currentRepo != "" {
-			return currentRepo, nil
+	timeout := oneshot.DefaultTimeout
+	if raw, ok := flags["timeout"]; ok {
+		var err error
+		if timeout, err = parseTimeBudget(raw); err != nil {
+			return errors.InvalidArgument("--timeout", raw, "a positive duration like 30m or 2h")
 		}
 	}
 
-	return "", fmt.Errorf("could not determine repository; use --repo flag or run 'multiclaude repo use <name>'")
-}
+	runner := claude.NewRunner(claude.WithBinaryPath(claude.ResolveBinaryPath()))
+	if !runner.IsBinaryAvailable() {
+		return errors.ClaudeNotFound(nil)
+	}
 
-// inferAgentContext infers the current agent and repo from working directory
-func (c *CLI) inferAgentContext() (repoName, agentName string, err error) {
-	cwd, err := os.Getwd()
+	// Ctrl-C or a CI job's SIGTERM stops the agent; Run still removes the
+	// worktree before returning
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The agent's output goes to stderr so stdout carries only the diff
+	fmt.Fprintf(os.Stderr, "Running one-shot agent (timeout %s): %s\n", timeout, task)
+	result, err := oneshot.Run(ctx, oneshot.Options{
+		RepoDir:      repoDir,
+		Task:         task,
+		Base:         flags["base"],
+		Branch:       flags["branch"],
+		Timeout:      timeout,
+		Runner:       runner,
+		Output:       os.Stderr,
+		KeepWorktree: flags["keep-worktree"] == "true",
+	})
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get current directory: %w", err)
+		return errors.Wrap(errors.CategoryRuntime, "one-shot run failed", err)
 	}
 
-	// Resolve symlinks in cwd for proper path comparison
-	// This is especially important on macOS where /tmp -> /private/tmp
-	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
-		cwd = resolved
+	fmt.Fprintf(os.Stderr, "\nAgent finished in %s: %d commit(s) on %s\n", result.Duration.Round(time.Second), result.Commits, result.Branch)
+	if result.Worktree != "" {
+		fmt.Fprintf(os.Stderr, "Worktree kept at %s\n", result.Worktree)
 	}
 
-	// Check if we're in a worktree path
-	// Path format: ~/.multiclaude/wts/<repo>/<agent>
-	if hasPathPrefix(cwd, c.paths.WorktreesDir) {
-		// Extract repo and agent from path
-		rel, err := filepath.Rel(c.paths.WorktreesDir, cwd)
-		if err == nil {
-			parts := strings.SplitN(rel, string(filepath.Separator), 2)
-			if len(parts) >= 2 {
-				return parts[0], parts[1], nil
+	if result.Changed() {
+		if path := flags["diff"]; path != "" {
+			if err := os.WriteFile(path, []byte(result.Diff), 0644); err != nil {
+				return errors.Wrap(errors.CategoryRuntime, "failed to write diff", err)
 			}
-			if len(parts) == 1 {
-				// We're in the repo worktree dir itself
-				return parts[0], "", fmt.Errorf("cannot determine agent - in repo worktree directory")
+			fmt.Fprintf(os.Stderr, "Diff written to %s\n", path)
+		} else if flags["pr"] != "true" {
+			fmt.Print(result.Diff)
+		}
+		if flags["pr"] == "true" && result.AgentErr == nil {
+			url, err := oneshot.OpenPR(repoDir, result, oneshot.PROptions{
+				Base:  flags["base"],
+				Title: task,
+				Body:  fmt.Sprintf("Opened by `multiclaude run` (%d commit(s), %s).", result.Commits, result.Duration.Round(time.Second)),
+			})
+			if err != nil {
+				return errors.Wrap(errors.CategoryRuntime, "failed to open pull request", err)
 			}
+			fmt.Println(url)
 		}
 	}
 
-	// Check if we're in a main repo path
-	// Path format: ~/.multiclaude/repos/<repo>
-	if hasPathPrefix(cwd, c.paths.ReposDir) {
-		rel, err := filepath.Rel(c.paths.ReposDir, cwd)
-		if err == nil {
-			parts := strings.SplitN(rel, string(filepath.Separator), 2)
-			if len(parts) >= 1 {
-				// In main repo - could be supervisor or merge-queue
-				// Try to get tmux window name
-				tmuxWindow := os.Getenv("TMUX_PANE")
-				if tmuxWindow != "" {
-					// Get window name from tmux
-					cmd := exec.Command("tmux", "display-message", "-p", "#{window_name}")
-					output, err := cmd.Output()
-					if err == nil {
-						windowName := strings.TrimSpace(string(output))
-						return parts[0], windowName, nil
-					}
-				}
-
-				// Fallback: assume supervisor
-				return parts[0], "supervisor", nil
-			}
-		}
+	switch {
+	case result.Interrupted:
+		return errors.Wrap(errors.CategoryRuntime, "agent did not finish", result.AgentErr).WithExitCode(oneshot.ExitInterrupted)
+	case result.TimedOut:
+		return errors.Wrap(errors.CategoryRuntime, "agent did not finish", result.AgentErr).WithExitCode(oneshot.ExitTimedOut)
+	case result.AgentErr != nil:
+		return errors.Wrap(errors.CategoryRuntime, "agent failed", result.AgentErr).WithExitCode(oneshot.ExitAgentFailed)
+	case !result.Changed():
+		return errors.New(errors.CategoryRuntime, "agent finished without changes").WithExitCode(oneshot.ExitNoChanges)
 	}
-
-	return "", "", errors.NotInAgentContext()
+	return nil
 }
 
-// Helper functions
-
-// hasPathPrefix checks if path starts with prefix using proper path semantics.
-// Unlike strings.Contains or strings.HasPrefix, this ensures we're comparing
-// complete path components (e.g., "/foo/bar" is under "/foo" but not under "/fo").
-func hasPathPrefix(path, prefix string) bool {
-	// Clean both paths to normalize them
-	path = filepath.Clean(path)
-	prefix = filepath.Clean(prefix)
+// exportMetrics asks the daemon to export a metrics snapshot and prints it
+func (c *CLI) exportMetrics(args []string) error {
+	flags, _ := ParseFlags(args)
 
-	// Check if path equals or starts with prefix followed by separator
-	if path == prefix {
-		return true
-	}
-	// Ensure prefix ends with separator for proper prefix matching
-	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
-		prefix = prefix + string(filepath.Separator)
+	reqArgs := map[string]interface{}{}
+	if date, ok := flags["date"]; ok {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return errors.InvalidArgument("--date", date, "a date in YYYY-MM-DD format")
+		}
+		reqArgs["date"] = date
 	}
-	return strings.HasPrefix(path, prefix)
-}
 
-func formatTime(t time.Time) string {
-	if time.Since(t) < 24*time.Hour {
-		return t.Format("15:04:05")
+	resp, err := c.sendDaemonRequest("export_metrics", reqArgs)
+	if err != nil {
+		return err
 	}
-	return t.Format("Jan 02 15:04")
-}
 
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
 	}
-	return s[:maxLen-3] + "..."
-}
+	snapshots, _ := data["snapshots"].([]interface{})
+	dir, _ := data["dir"].(string)
 
-// checkUnpushedCommits checks if a worktree has unpushed commits and prompts the user for confirmation.
-// Returns nil if the user wants to continue, or an error to cancel the operation.
-// The entityType parameter should be "Worker" or "Workspace" for appropriate messaging.
-// The action parameter should be "cleanup" or "removal" for appropriate messaging.
-func checkUnpushedCommits(wtPath, entityType, action string) error {
-	hasUnpushed, err := worktree.HasUnpushedCommits(wtPath)
-	if err != nil {
-		// This is ok - might not have a tracking branch
-		fmt.Printf("Note: Could not check for unpushed commits (no tracking branch?)\n")
+	if len(snapshots) == 0 {
+		fmt.Println("No repositories tracked; nothing exported")
 		return nil
 	}
 
-	if !hasUnpushed {
-		return nil
-	}
+	format.Header("Metrics snapshot:")
+	fmt.Println()
 
-	fmt.Printf("\nWarning: %s has unpushed commits!\n", entityType)
-	branch, err := worktree.GetCurrentBranch(wtPath)
-	if err == nil {
-		fmt.Printf("Branch '%s' has commits not pushed to remote.\n", branch)
+	table := format.NewColoredTable("DATE", "REPO", "STARTED", "COMPLETED", "FAILED", "PRS OPENED", "PRS MERGED", "MEAN DURATION")
+	for _, item := range snapshots {
+		snap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		num := func(key string) string {
+			v, _ := snap[key].(float64)
+			return fmt.Sprintf("%d", int(v))
+		}
+		date, _ := snap["date"].(string)
+		repo, _ := snap["repo"].(string)
+		meanSecs, _ := snap["mean_task_duration_seconds"].(float64)
+		duration := "-"
+		if meanSecs > 0 {
+			duration = (time.Duration(meanSecs) * time.Second).String()
+		}
+		table.AddRow(
+			format.Cell(date),
+			format.Cell(repo),
+			format.Cell(num("tasks_started")),
+			format.ColorCell(num("tasks_completed"), format.Green),
+			format.ColorCell(num("tasks_failed"), format.Red),
+			format.Cell(num("prs_opened")),
+			format.Cell(num("prs_merged")),
+			format.Cell(duration),
+		)
 	}
-	fmt.Printf("These commits may be lost if you continue with %s.\n", action)
-	fmt.Printf("Continue with %s? [y/N]: ", action)
+	table.Print()
 
-	var response string
-	fmt.Scanln(&response)
-	if response != "y" && response != "Y" {
-		// Capitalize first letter of action for the message
-		actionCapitalized := strings.ToUpper(action[:1]) + action[1:]
-		fmt.Printf("%s cancelled\n", actionCapitalized)
-		return fmt.Errorf("cancelled by user")
-	}
+	format.Dimmed("\nAppended to %s/metrics.csv and metrics.jsonl", dir)
 	return nil
 }
 
-func (c *CLI) completeWorker(args []string) error {
-	// Parse flags for optional summary and failure reason
-	flags, _ := ParseFlags(args)
+// showEventSchema prints the daemon's event JSON Schema document
+func (c *CLI) showEventSchema(args []string) error {
+	resp, err := c.sendDaemonRequest("event_schema", nil)
+	if err != nil {
+		return err
+	}
 
-	// Determine current agent and repo
-	repoName, agentName, err := c.inferAgentContext()
+	data, err := json.MarshalIndent(resp.Data, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to determine agent context: %w", err)
+		return fmt.Errorf("failed to format schema: %w", err)
 	}
+	fmt.Println(string(data))
+	return nil
+}
 
-	fmt.Printf("Marking agent '%s' as complete...\n", agentName)
+// listEvents prints notification events from the daemon's event store,
+// oldest first
+func (c *CLI) listEvents(args []string) error {
+	flags, _ := ParseFlags(args)
 
-	// Build request args
-	reqArgs := map[string]interface{}{
-		"repo":  repoName,
-		"agent": agentName,
+	reqArgs := map[string]interface{}{}
+	for _, flag := range []string{"since", "until"} {
+		value, ok := flags[flag]
+		if !ok {
+			continue
+		}
+		t, err := parseEventTime(value)
+		if err != nil {
+			return errors.InvalidArgument(flag, value, "a duration ago like 2d, 1h, or 30m, or an RFC 3339 time")
+		}
+		reqArgs[flag] = t.Format(time.RFC3339)
 	}
-
-	// Add optional summary
-	if summary, ok := flags["summary"]; ok && summary != "" {
-		reqArgs["summary"] = summary
-		fmt.Printf("Summary: %s\n", summary)
+	if repo := flags["repo"]; repo != "" {
+		reqArgs["repo"] = repo
 	}
-
-	// Add optional failure reason
-	if failureReason, ok := flags["failure"]; ok && failureReason != "" {
-		reqArgs["failure_reason"] = failureReason
-		fmt.Printf("Failure reason: %s\n", failureReason)
+	if eventType := flags["type"]; eventType != "" {
+		reqArgs["type"] = eventType
+	}
+	if l, ok := flags["limit"]; ok {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			return errors.InvalidArgument("limit", l, "a non-negative number (0 for no limit)")
+		}
+		reqArgs["limit"] = limit
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "complete_agent",
-		Args:    reqArgs,
-	})
+	resp, err := c.sendDaemonRequest("list_events", reqArgs)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("marking agent complete", err)
+		return err
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to mark agent complete", fmt.Errorf("%s", resp.Error))
+
+	if flags["json"] == "true" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resp.Data)
 	}
 
-	fmt.Println("✓ Agent marked as complete")
-	fmt.Println("The daemon will clean up this agent's resources shortly.")
+	list, _ := resp.Data.([]interface{})
+	if len(list) == 0 {
+		fmt.Println("No events found")
+		return nil
+	}
+	printEventTable(list)
 	return nil
 }
 
-func (c *CLI) restartAgentCmd(args []string) error {
-	// Parse flags
-	flags, remaining := ParseFlags(args)
+// printEventTable prints events from list_events, one per row
+func printEventTable(list []interface{}) {
+	table := format.NewColoredTable("TIME", "TYPE", "PRIORITY", "TARGET", "TITLE")
+	for _, e := range list {
+		event, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		when := "?"
+		if ts, _ := event["timestamp"].(string); ts != "" {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				when = t.Local().Format("Jan 02 15:04:05")
+			}
+		}
+		eventType, _ := event["type"].(string)
+		priority, _ := event["priority"].(string)
+		repo, _ := event["repo"].(string)
+		agent, _ := event["agent"].(string)
+		title, _ := event["title"].(string)
+		target := repo
+		if agent != "" {
+			target = repo + "/" + agent
+		}
+		priorityCell := format.ColorCell(priority, format.Dim)
+		if priority == string(events.PriorityHigh) {
+			priorityCell = format.ColorCell(priority, format.Red)
+		}
+		table.AddRow(
+			format.ColorCell(when, format.Dim),
+			format.Cell(eventType),
+			priorityCell,
+			format.Cell(target),
+			format.Cell(format.Truncate(title, 60)),
+		)
+	}
+	table.Print()
+}
 
-	// Get agent name from args
-	if len(remaining) < 1 {
-		return errors.InvalidUsage("usage: multiclaude agent restart <name> [--repo <repo>] [--force]")
+// exportTimeline writes a repository's task timeline as markdown (a Mermaid
+// chart and a task table), a bare Mermaid chart, or JSON
+func (c *CLI) exportTimeline(args []string) error {
+	flags, _ := ParseFlags(args)
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.Wrap(errors.CategoryUsage, "could not determine repository", err).
+			WithSuggestion("use --repo flag or run from within a tracked repository")
+	}
+	outputFormat := flags["format"]
+	if outputFormat == "" {
+		outputFormat = "markdown"
+	}
+	if outputFormat != "markdown" && outputFormat != "mermaid" && outputFormat != "json" {
+		return errors.InvalidArgument("format", outputFormat, "markdown, mermaid, or json")
 	}
-	agentName := remaining[0]
 
-	// Get repo from flag or infer from cwd
-	repoName := flags["repo"]
-	if repoName == "" {
-		// Try to infer from cwd
-		inferred, err := c.inferRepoFromCwd()
+	reqArgs := map[string]interface{}{"repo": repoName}
+	for _, flag := range []string{"since", "until"} {
+		value, ok := flags[flag]
+		if !ok {
+			continue
+		}
+		t, err := parseEventTime(value)
 		if err != nil {
-			return errors.InvalidUsage("could not determine repository - use --repo flag or run from within a multiclaude worktree")
+			return errors.InvalidArgument(flag, value, "a duration ago like 7d or 12h, or an RFC 3339 time")
 		}
-		repoName = inferred
-	}
-
-	force := flags["force"] == "true"
-
-	fmt.Printf("Restarting agent '%s' in repository '%s'...\n", agentName, repoName)
-
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "restart_agent",
-		Args: map[string]interface{}{
-			"repo":  repoName,
-			"agent": agentName,
-			"force": force,
-		},
-	})
+		reqArgs[flag] = t.Format(time.RFC3339)
+	}
+
+	resp, err := c.sendDaemonRequest("timeline", reqArgs)
 	if err != nil {
-		return errors.DaemonCommunicationFailed("restarting agent", err)
+		return err
 	}
-	if !resp.Success {
-		return errors.Wrap(errors.CategoryRuntime, "failed to restart agent", fmt.Errorf("%s", resp.Error))
+	raw, err := json.Marshal(resp.Data)
+	if err != nil {
+		return fmt.Errorf("failed to read timeline: %w", err)
+	}
+	var tl timeline.Timeline
+	if err := json.Unmarshal(raw, &tl); err != nil {
+		return fmt.Errorf("failed to read timeline: %w", err)
 	}
 
-	// Extract PID from response
-	if data, ok := resp.Data.(map[string]interface{}); ok {
-		if pid, ok := data["pid"].(float64); ok {
-			fmt.Printf("✓ Agent '%s' restarted successfully (PID: %d)\n", agentName, int(pid))
-		} else {
-			fmt.Printf("✓ Agent '%s' restarted successfully\n", agentName)
+	out := io.Writer(os.Stdout)
+	if path := flags["output"]; path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
 		}
-	} else {
-		fmt.Printf("✓ Agent '%s' restarted successfully\n", agentName)
+		defer f.Close()
+		out = f
 	}
 
+	switch outputFormat {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(tl)
+	case "mermaid":
+		_, err = io.WriteString(out, timeline.Mermaid(tl))
+	default:
+		_, err = io.WriteString(out, timeline.Markdown(tl))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write timeline: %w", err)
+	}
+	if path := flags["output"]; path != "" {
+		fmt.Printf("Wrote the timeline of %d task(s) to %s\n", len(tl.Tasks), path)
+	}
 	return nil
 }
 
-func (c *CLI) reviewPR(args []string) error {
-	if len(args) < 1 {
-		return errors.InvalidUsage("usage: multiclaude review <pr-url>")
+// parseEventTime parses an events list bound: an RFC 3339 time, or a
+// duration such as 2h meaning that long ago
+func parseEventTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := parseDuration(value)
+	if err != nil {
+		return time.Time{}, err
 	}
+	return time.Now().Add(-d), nil
+}
 
-	prURL := args[0]
+// enableTelemetry opts in to local timing samples
+func (c *CLI) enableTelemetry(args []string) error {
+	recorder := telemetry.NewRecorder(c.paths.TelemetryDir())
+	if err := recorder.Enable(); err != nil {
+		return errors.Wrap(errors.CategoryConfig, "failed to enable telemetry", err)
+	}
+	fmt.Println("✓ Telemetry enabled")
+	format.Dimmed("Command names, durations, and failures are recorded to %s", filepath.Join(c.paths.TelemetryDir(), telemetry.SamplesFile))
+	format.Dimmed("Nothing is uploaded. View with: multiclaude telemetry report")
+	return nil
+}
 
-	// Parse PR URL to extract owner, repo, and PR number
-	// Expected formats:
-	// - https://github.com/owner/repo/pull/123
-	// - github.com/owner/repo/pull/123
-	prURL = strings.TrimPrefix(prURL, "https://")
-	prURL = strings.TrimPrefix(prURL, "http://")
-	parts := strings.Split(prURL, "/")
+// disableTelemetry stops recording timing samples
+func (c *CLI) disableTelemetry(args []string) error {
+	if err := telemetry.NewRecorder(c.paths.TelemetryDir()).Disable(); err != nil {
+		return errors.Wrap(errors.CategoryConfig, "failed to disable telemetry", err)
+	}
+	fmt.Println("✓ Telemetry disabled")
+	format.Dimmed("Recorded samples were kept. Delete them with: multiclaude telemetry clear")
+	return nil
+}
 
-	if len(parts) < 5 || parts[3] != "pull" {
-		return errors.InvalidPRURL()
+// clearTelemetry deletes recorded timing samples
+func (c *CLI) clearTelemetry(args []string) error {
+	if err := telemetry.NewRecorder(c.paths.TelemetryDir()).Clear(); err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to clear telemetry", err)
 	}
+	fmt.Println("✓ Telemetry samples deleted")
+	return nil
+}
 
-	prNumber := parts[4]
-	fmt.Printf("Reviewing PR #%s\n", prNumber)
+// telemetryReport summarizes recorded timing samples
+func (c *CLI) telemetryReport(args []string) error {
+	flags, _ := ParseFlags(args)
 
-	// Determine repository from flag or current directory
-	flags, _ := ParseFlags(args[1:])
-	var repoName string
-	if r, ok := flags["repo"]; ok {
-		repoName = r
-	} else {
-		// Try to infer from current directory
-		cwd, err := os.Getwd()
+	var since time.Time
+	if s, ok := flags["since"]; ok {
+		d, err := parseDuration(s)
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+			return errors.InvalidArgument("since", s, "a duration like 7d, 24h, or 30m")
 		}
+		since = time.Now().Add(-d)
+	}
 
-		// Check if we're in a tracked repo
-		repos := c.getReposList()
-		for _, repo := range repos {
-			repoPath := c.paths.RepoDir(repo)
-			if strings.HasPrefix(cwd, repoPath) {
-				repoName = repo
-				break
-			}
+	recorder := telemetry.NewRecorder(c.paths.TelemetryDir())
+	samples, err := recorder.Load()
+	if err != nil {
+		return errors.Wrap(errors.CategoryRuntime, "failed to read telemetry", err)
+	}
+	summaries := telemetry.Summarize(samples, since)
+
+	if flags["json"] == "true" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summaries)
+	}
+
+	if len(summaries) == 0 {
+		fmt.Println("No telemetry samples recorded")
+		if !recorder.Enabled() {
+			format.Dimmed("\nTelemetry is off. Turn it on with: multiclaude telemetry enable")
 		}
+		return nil
+	}
 
-		if repoName == "" {
-			return errors.NotInRepo()
+	ms := func(v float64) string {
+		if v < 1000 {
+			return fmt.Sprintf("%.0fms", v)
 		}
+		return fmt.Sprintf("%.1fs", v/1000)
 	}
 
-	// Generate review agent name
-	reviewerName := fmt.Sprintf("review-%s", prNumber)
+	format.Header("Command timings (slowest p95 first):")
+	fmt.Println()
 
-	fmt.Printf("Creating review agent '%s' in repo '%s'\n", reviewerName, repoName)
+	table := format.NewColoredTable("KIND", "NAME", "COUNT", "FAILED", "P50", "P95", "MAX")
+	for _, s := range summaries {
+		failed := format.Cell("0")
+		if s.Failures > 0 {
+			failed = format.ColorCell(fmt.Sprintf("%d (%.0f%%)", s.Failures, 100*s.FailureRate()), format.Red)
+		}
+		table.AddRow(
+			format.ColorCell(string(s.Kind), format.Dim),
+			format.Cell(s.Name),
+			format.Cell(strconv.Itoa(s.Count)),
+			failed,
+			format.Cell(ms(s.P50Ms)),
+			format.Cell(ms(s.P95Ms)),
+			format.Cell(ms(s.MaxMs)),
+		)
+	}
+	table.Print()
+	if !recorder.Enabled() {
+		format.Dimmed("\nTelemetry is currently off; these are older samples.")
+	}
+	return nil
+}
 
-	// Get repository path
-	repoPath := c.paths.RepoDir(repoName)
+// listAutoAnswers prints a repository's auto-answer rules followed by the
+// built-in templates
+func (c *CLI) listAutoAnswers(args []string) error {
+	flags, _ := ParseFlags(args)
 
-	// Fetch the PR using GitHub's PR refs - this works for both same-repo and fork PRs
-	// The refs/pull/<number>/head ref always exists and points to the PR's head commit
-	fmt.Printf("Fetching PR #%s...\n", prNumber)
-	prRef := fmt.Sprintf("refs/pull/%s/head", prNumber)
-	localRef := fmt.Sprintf("refs/multiclaude/pr-%s", prNumber)
-	cmd := exec.Command("git", "fetch", "origin", fmt.Sprintf("%s:%s", prRef, localRef))
-	cmd.Dir = repoPath
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return errors.Wrap(errors.CategoryRuntime, fmt.Sprintf("failed to fetch PR #%s: %s", prNumber, strings.TrimSpace(string(output))), err).
-			WithSuggestion("ensure the PR exists and you have access to the repository")
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
 	}
 
-	// Create worktree for review
-	wt := worktree.NewManager(repoPath)
-	wtPath := c.paths.AgentWorktree(repoName, reviewerName)
-	reviewBranch := fmt.Sprintf("review/%s", reviewerName)
+	resp, err := c.sendDaemonRequest("list_auto_answers", map[string]interface{}{"repo": repoName})
+	if err != nil {
+		return err
+	}
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
 
-	fmt.Printf("Creating worktree at: %s\n", wtPath)
-	if err := wt.CreateNewBranch(wtPath, reviewBranch, localRef); err != nil {
-		return fmt.Errorf("failed to create worktree: %w", err)
+	if disabled, _ := data["disabled"].(bool); disabled {
+		format.Dimmed("Auto-answer is disabled for '%s' (enable with: multiclaude config %s --auto-answer=true)", repoName, repoName)
 	}
 
-	// Get tmux session name
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	printRules := func(rules []interface{}, numbered bool) {
+		table := format.NewColoredTable("#", "PATTERN", "REPLY")
+		for i, r := range rules {
+			rule, _ := r.(map[string]interface{})
+			pattern, _ := rule["pattern"].(string)
+			reply, _ := rule["reply"].(string)
+			num := format.ColorCell("-", format.Dim)
+			if numbered {
+				num = format.Cell(strconv.Itoa(i + 1))
+			}
+			table.AddRow(num, format.Cell(pattern), format.Cell(format.Truncate(reply, 60)))
+		}
+		table.Print()
+	}
 
-	// Create tmux window for reviewer (detached so it doesn't switch focus)
-	fmt.Printf("Creating tmux window: %s\n", reviewerName)
-	cmd = exec.Command("tmux", "new-window", "-d", "-t", tmuxSession, "-n", reviewerName, "-c", wtPath)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create tmux window: %w", err)
+	rules, _ := data["rules"].([]interface{})
+	format.Header("Auto-answer rules for '%s' (%d):", repoName, len(rules))
+	if len(rules) == 0 {
+		format.Dimmed("  none - add one with: multiclaude auto-answer add <pattern> <reply>")
+	} else {
+		printRules(rules, true)
 	}
 
-	// Generate session ID for reviewer
-	reviewerSessionID, err := claude.GenerateSessionID()
-	if err != nil {
-		return fmt.Errorf("failed to generate reviewer session ID: %w", err)
+	defaults, _ := data["defaults"].([]interface{})
+	fmt.Println()
+	format.Header("Built-in templates (%d):", len(defaults))
+	printRules(defaults, false)
+	return nil
+}
+
+// addAutoAnswer adds an auto-answer rule to a repository
+func (c *CLI) addAutoAnswer(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) < 2 {
+		return errors.InvalidUsage("usage: multiclaude auto-answer add <pattern> <reply> [--repo <repo>]")
 	}
 
-	// Write prompt file for reviewer
-	reviewerPromptFile, err := c.writePromptFile(repoPath, state.AgentTypeReview, reviewerName)
+	repoName, err := c.resolveRepo(flags)
 	if err != nil {
-		return fmt.Errorf("failed to write reviewer prompt: %w", err)
+		return errors.NotInRepo()
 	}
 
-	// Copy hooks configuration if it exists
-	if err := hooks.CopyConfig(repoPath, wtPath); err != nil {
-		fmt.Printf("Warning: failed to copy hooks config: %v\n", err)
+	resp, err := c.sendDaemonRequest("add_auto_answer", map[string]interface{}{
+		"repo":    repoName,
+		"pattern": posArgs[0],
+		"reply":   strings.Join(posArgs[1:], " "),
+	})
+	if err != nil {
+		return err
 	}
 
-	// Start Claude in reviewer window with initial task (skip in test mode)
-	var reviewerPID int
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary
-		claudeBinary, err := c.getClaudeBinary()
-		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
-		}
-
-		fmt.Println("Starting Claude Code in reviewer window...")
-		initialMessage := fmt.Sprintf("Review PR #%s: https://github.com/%s/%s/pull/%s", prNumber, parts[1], parts[2], prNumber)
-		pid, err := c.startClaudeInTmux(claudeBinary, tmuxSession, reviewerName, wtPath, reviewerSessionID, reviewerPromptFile, repoName, initialMessage)
-		if err != nil {
-			return fmt.Errorf("failed to start reviewer Claude: %w", err)
-		}
-		reviewerPID = pid
+	num, _ := resp.Data.(float64)
+	fmt.Printf("Added auto-answer rule #%d for '%s'\n", int(num), repoName)
+	return nil
+}
 
-		// Set up output capture for reviewer
-		if err := c.setupOutputCapture(tmuxSession, reviewerName, repoName, reviewerName, "review"); err != nil {
-			fmt.Printf("Warning: failed to setup output capture for reviewer: %v\n", err)
-		}
+// removeAutoAnswer removes an auto-answer rule from a repository
+func (c *CLI) removeAutoAnswer(args []string) error {
+	flags, posArgs := ParseFlags(args)
+	if len(posArgs) != 1 {
+		return errors.InvalidUsage("usage: multiclaude auto-answer rm <#> [--repo <repo>]")
+	}
+	num, err := strconv.Atoi(posArgs[0])
+	if err != nil || num < 1 {
+		return errors.InvalidArgument("#", posArgs[0], "a rule number from 'multiclaude auto-answer list'")
 	}
 
-	// Register reviewer with daemon
-	client := socket.NewClient(c.paths.DaemonSock)
-	resp, err := client.Send(socket.Request{
-		Command: "add_agent",
-		Args: map[string]interface{}{
-			"repo":          repoName,
-			"agent":         reviewerName,
-			"type":          "review",
-			"worktree_path": wtPath,
-			"tmux_window":   reviewerName,
-			"task":          fmt.Sprintf("Review PR #%s", prNumber),
-			"session_id":    reviewerSessionID,
-			"pid":           reviewerPID,
-		},
-	})
+	repoName, err := c.resolveRepo(flags)
 	if err != nil {
-		return fmt.Errorf("failed to register reviewer: %w", err)
-	}
-	if !resp.Success {
-		return fmt.Errorf("failed to register reviewer: %s", resp.Error)
+		return errors.NotInRepo()
 	}
 
-	fmt.Println()
-	fmt.Println("✓ Review agent created successfully!")
-	fmt.Printf("  Name: %s\n", reviewerName)
-	fmt.Printf("  Branch: %s\n", reviewBranch)
-	fmt.Printf("  Worktree: %s\n", wtPath)
-	fmt.Printf("\nAttach to reviewer: tmux select-window -t %s:%s\n", tmuxSession, reviewerName)
-	fmt.Printf("Or use: multiclaude attach %s\n", reviewerName)
+	if _, err := c.sendDaemonRequest("remove_auto_answer", map[string]interface{}{"repo": repoName, "index": num}); err != nil {
+		return err
+	}
 
+	fmt.Printf("Removed auto-answer rule #%d from '%s'\n", num, repoName)
 	return nil
 }
 
-// Logs command implementations
+// showQueueMetrics prints merge queue stats, or the Prometheus text
+// exposition with --prometheus
+func (c *CLI) showQueueMetrics(args []string) error {
+	flags, _ := ParseFlags(args)
 
-func (c *CLI) viewLogs(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: multiclaude logs <agent> [--lines N] [--follow]")
+	reqArgs := map[string]interface{}{}
+	if repo := flags["repo"]; repo != "" {
+		reqArgs["repo"] = repo
+	}
+	prometheus := flags["prometheus"] == "true"
+	if prometheus {
+		reqArgs["format"] = "prometheus"
 	}
 
-	agentName := args[0]
-	flags, _ := ParseFlags(args[1:])
+	resp, err := c.sendDaemonRequest("merge_queue_stats", reqArgs)
+	if err != nil {
+		return err
+	}
 
-	// Determine repository
-	var repoName string
-	if r, ok := flags["repo"]; ok {
-		repoName = r
-	} else {
-		repos := c.getReposList()
-		if len(repos) == 0 {
-			return fmt.Errorf("no repositories tracked")
-		}
-		if len(repos) == 1 {
-			repoName = repos[0]
-		} else {
-			return fmt.Errorf("multiple repos exist. Use --repo flag to specify which one")
-		}
+	if prometheus {
+		text, _ := resp.Data.(string)
+		fmt.Print(text)
+		return nil
 	}
 
-	// Determine if it's a worker or system agent by checking if it exists in workers dir
-	workerLogFile := c.paths.AgentLogFile(repoName, agentName, true)
-	systemLogFile := c.paths.AgentLogFile(repoName, agentName, false)
+	stats, ok := resp.Data.([]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+	if len(stats) == 0 {
+		fmt.Println("No repositories tracked")
+		return nil
+	}
 
-	var logFile string
-	if _, err := os.Stat(workerLogFile); err == nil {
-		logFile = workerLogFile
-	} else if _, err := os.Stat(systemLogFile); err == nil {
-		logFile = systemLogFile
-	} else {
-		return fmt.Errorf("no log file found for agent %s in repo %s", agentName, repoName)
+	seconds := func(v interface{}) string {
+		secs, _ := v.(float64)
+		if secs <= 0 {
+			return "-"
+		}
+		return (time.Duration(secs) * time.Second).String()
 	}
 
-	// Check for --follow flag
-	if _, ok := flags["follow"]; ok {
-		// Use tail -f
-		cmd := exec.Command("tail", "-f", logFile)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		return cmd.Run()
+	format.Header("Merge queue:")
+	fmt.Println()
+
+	table := format.NewColoredTable("REPO", "DEPTH", "OLDEST", "MERGED", "FAILED", "CLOSED", "MEAN TIME IN QUEUE", "MEAN CI WAIT")
+	for _, item := range stats {
+		s, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		num := func(key string) string {
+			v, _ := s[key].(float64)
+			return fmt.Sprintf("%d", int(v))
+		}
+		repo, _ := s["repo"].(string)
+		table.AddRow(
+			format.Cell(repo),
+			format.Cell(num("depth")),
+			format.Cell(seconds(s["oldest_seconds"])),
+			format.ColorCell(num("merged"), format.Green),
+			format.ColorCell(num("failed"), format.Red),
+			format.Cell(num("closed")),
+			format.Cell(seconds(s["mean_time_in_queue_seconds"])),
+			format.Cell(seconds(s["mean_ci_wait_seconds"])),
+		)
 	}
+	table.Print()
 
-	// Determine number of lines
-	lines := "100"
-	if l, ok := flags["lines"]; ok {
-		lines = l
+	for _, item := range stats {
+		s, _ := item.(map[string]interface{})
+		pending, _ := s["pending"].([]interface{})
+		if len(pending) == 0 {
+			continue
+		}
+		fmt.Println()
+		format.Header("Waiting in %s (merge order):", s["repo"])
+		for _, p := range pending {
+			pr, _ := p.(map[string]interface{})
+			label, _ := pr["branch"].(string)
+			if num, _ := pr["pr_number"].(float64); num > 0 {
+				label = fmt.Sprintf("#%d %s", int(num), label)
+			}
+			ci := ""
+			if inCI, _ := pr["in_ci"].(bool); inCI {
+				ci = " (CI running)"
+			}
+			priority, _ := pr["priority"].(string)
+			fmt.Printf("  %s  %s  %s%s\n", priority, label, seconds(pr["time_in_queue_seconds"]), ci)
+		}
 	}
 
-	// Use tail to get recent lines
-	cmd := exec.Command("tail", "-n", lines, logFile)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	format.Dimmed("\nPrometheus textfile: %s", filepath.Join(c.paths.MetricsDir(), "merge_queue.prom"))
+	return nil
 }
 
-func (c *CLI) listLogs(args []string) error {
+// simulateMergeQueue prints what the merge queue would do with a repo's
+// pending branches right now, without merging anything
+func (c *CLI) simulateMergeQueue(args []string) error {
 	flags, _ := ParseFlags(args)
-
-	// Determine repository
-	var repoName string
-	if r, ok := flags["repo"]; ok {
-		repoName = r
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.Wrap(errors.CategoryUsage, "could not determine repository", err).
+			WithSuggestion("use --repo flag or run from within a tracked repository")
 	}
 
-	if repoName != "" {
-		// List logs for specific repo
-		return c.listLogsForRepo(repoName)
+	resp, err := c.sendDaemonRequest("merge_queue_simulate", map[string]interface{}{"repo": repoName})
+	if err != nil {
+		return err
 	}
 
-	// List logs for all repos
-	repos := c.getReposList()
-	if len(repos) == 0 {
-		fmt.Println("No repositories tracked")
-		return nil
+	if flags["json"] == "true" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resp.Data)
 	}
 
-	for _, repo := range repos {
-		if err := c.listLogsForRepo(repo); err != nil {
-			fmt.Printf("Warning: failed to list logs for %s: %v\n", repo, err)
+	sim, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		return errors.New(errors.CategoryRuntime, "unexpected response format from daemon")
+	}
+	stringList := func(v interface{}) []string {
+		items, _ := v.([]interface{})
+		out := make([]string, 0, len(items))
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
 		}
+		return out
 	}
-	return nil
-}
 
-func (c *CLI) listLogsForRepo(repoName string) error {
-	repoOutputDir := c.paths.RepoOutputDir(repoName)
+	base, _ := sim["base"].(string)
+	format.Header("Merge queue simulation for %s (nothing is merged):", repoName)
+	if enabled, _ := sim["enabled"].(bool); !enabled {
+		format.Dimmed("The merge-queue agent is disabled for this repository")
+	}
+	if required := stringList(sim["required_checks"]); len(required) > 0 {
+		fmt.Printf("Required checks on %s: %s\n", base, strings.Join(required, ", "))
+	}
+	if strict, _ := sim["strict"].(bool); strict {
+		fmt.Printf("%s requires branches to be up to date, so each merge leaves the rest of the queue behind\n", base)
+	}
+	for _, warning := range stringList(sim["warnings"]) {
+		format.Yellow.Printf("Warning: %s\n", warning)
+	}
+	fmt.Println()
 
-	// Check if directory exists
-	if _, err := os.Stat(repoOutputDir); os.IsNotExist(err) {
-		fmt.Printf("No logs for %s\n", repoName)
+	entries, _ := sim["entries"].([]interface{})
+	if len(entries) == 0 {
+		fmt.Println("The merge queue is empty")
 		return nil
 	}
 
-	fmt.Printf("\n%s:\n", repoName)
+	table := format.NewColoredTable("#", "PR", "BRANCH", "PRIORITY", "BEHIND", "CHECKS", "VERDICT")
+	var details []string
+	for _, item := range entries {
+		e, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		position, _ := e["position"].(float64)
+		branch, _ := e["branch"].(string)
+		priority, _ := e["priority"].(string)
+		behind, _ := e["behind"].(float64)
+		checks, _ := e["checks"].(string)
+		verdict, _ := e["verdict"].(string)
 
-	// List system agent logs
-	entries, err := os.ReadDir(repoOutputDir)
-	if err != nil {
-		return err
-	}
+		pr := "-"
+		if num, _ := e["pr_number"].(float64); num > 0 {
+			pr = fmt.Sprintf("#%d", int(num))
+		}
+		behindText := fmt.Sprintf("%d", int(behind))
+		if conflicts, _ := e["conflicts"].(bool); conflicts {
+			behindText += " (conflicts)"
+		}
+		if checks == "" {
+			checks = "-"
+		}
 
-	for _, entry := range entries {
-		if entry.IsDir() && entry.Name() == "workers" {
-			continue
+		verdictColor := format.Yellow
+		switch verdict {
+		case "ready":
+			verdictColor = format.Green
+		case "failing", "needs_rebase":
+			verdictColor = format.Red
+		case "unknown", "no_pr":
+			verdictColor = format.Dim
 		}
-		if strings.HasSuffix(entry.Name(), ".log") {
-			info, _ := entry.Info()
-			agentName := strings.TrimSuffix(entry.Name(), ".log")
-			if info != nil {
-				fmt.Printf("  %s (%d bytes)\n", agentName, info.Size())
-			} else {
-				fmt.Printf("  %s\n", agentName)
-			}
+		table.AddRow(
+			format.Cell(fmt.Sprintf("%d", int(position))),
+			format.Cell(pr),
+			format.Cell(format.Truncate(branch, 40)),
+			format.Cell(priority),
+			format.Cell(behindText),
+			format.Cell(checks),
+			format.ColorCell(verdict, verdictColor),
+		)
+
+		label := pr
+		if label == "-" {
+			label = branch
+		}
+		if failing := stringList(e["failing_checks"]); len(failing) > 0 {
+			details = append(details, fmt.Sprintf("%s fails required checks: %s", label, strings.Join(failing, ", ")))
+		}
+		if pending := stringList(e["pending_checks"]); len(pending) > 0 {
+			details = append(details, fmt.Sprintf("%s is waiting on: %s", label, strings.Join(pending, ", ")))
+		}
+		if files := stringList(e["conflict_files"]); len(files) > 0 {
+			details = append(details, fmt.Sprintf("%s conflicts with %s in: %s", label, base, strings.Join(files, ", ")))
+		}
+		if after, _ := e["rebase_after"].(string); after != "" {
+			details = append(details, fmt.Sprintf("%s will need a rebase after %s merges", label, after))
+		}
+		for _, note := range stringList(e["notes"]) {
+			details = append(details, fmt.Sprintf("%s: %s", label, note))
 		}
 	}
+	table.Print()
 
-	// List worker logs
-	workersDir := c.paths.WorkersOutputDir(repoName)
-	if _, err := os.Stat(workersDir); err == nil {
-		workerEntries, err := os.ReadDir(workersDir)
-		if err == nil && len(workerEntries) > 0 {
-			fmt.Println("  workers/")
-			for _, entry := range workerEntries {
-				if strings.HasSuffix(entry.Name(), ".log") {
-					info, _ := entry.Info()
-					workerName := strings.TrimSuffix(entry.Name(), ".log")
-					if info != nil {
-						fmt.Printf("    %s (%d bytes)\n", workerName, info.Size())
-					} else {
-						fmt.Printf("    %s\n", workerName)
-					}
-				}
-			}
+	if len(details) > 0 {
+		fmt.Println()
+		for _, detail := range details {
+			fmt.Printf("  %s\n", detail)
 		}
 	}
-
 	return nil
 }
 
-func (c *CLI) searchLogs(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: multiclaude logs search <pattern> [--repo <repo>]")
-	}
-
-	pattern := args[0]
-	flags, _ := ParseFlags(args[1:])
+// agentPath prints the worktree path for an agent, or the repository path if
+// no agent is given. With --shell it starts a subshell there instead.
+func (c *CLI) agentPath(args []string) error {
+	flags, posArgs := ParseFlags(args)
 
-	// Determine repository
-	var repoName string
-	if r, ok := flags["repo"]; ok {
-		repoName = r
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
 	}
 
-	// Get search directories
-	var searchPaths []string
-	if repoName != "" {
-		repoOutputDir := c.paths.RepoOutputDir(repoName)
-		if _, err := os.Stat(repoOutputDir); err == nil {
-			searchPaths = append(searchPaths, repoOutputDir)
-		}
-	} else {
-		// Search all repos
-		repos := c.getReposList()
-		for _, repo := range repos {
-			repoOutputDir := c.paths.RepoOutputDir(repo)
-			if _, err := os.Stat(repoOutputDir); err == nil {
-				searchPaths = append(searchPaths, repoOutputDir)
-			}
+	dir := c.paths.RepoDir(repoName)
+	if len(posArgs) > 0 {
+		dir, err = c.lookupAgentWorktree(repoName, posArgs[0])
+		if err != nil {
+			return err
 		}
 	}
 
-	if len(searchPaths) == 0 {
-		fmt.Println("No log directories found")
+	if flags["shell"] != "true" {
+		fmt.Println(dir)
 		return nil
 	}
 
-	// Use grep to search recursively
-	grepArgs := []string{"-r", "-n", "--include=*.log", pattern}
-	grepArgs = append(grepArgs, searchPaths...)
-
-	cmd := exec.Command("grep", grepArgs...)
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	fmt.Fprintf(os.Stderr, "Starting %s in %s (exit to return)\n", filepath.Base(shell), dir)
+	cmd := exec.Command(shell)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "MULTICLAUDE_REPO="+repoName)
+	if len(posArgs) > 0 {
+		cmd.Env = append(cmd.Env, "MULTICLAUDE_AGENT="+posArgs[0])
+	}
+	return cmd.Run()
+}
 
-	// Run grep (exit code 1 means no matches, which is fine)
-	err := cmd.Run()
-	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-		fmt.Println("No matches found")
-		return nil
+// lookupAgentWorktree returns an agent's worktree path, asking the daemon
+// first and falling back to the conventional worktree location
+func (c *CLI) lookupAgentWorktree(repoName, agentName string) (string, error) {
+	resp, err := c.sendDaemonRequest("list_agents", map[string]interface{}{
+		"repo": repoName,
+	})
+	if err == nil {
+		agents, _ := resp.Data.([]interface{})
+		for _, agent := range agents {
+			agentMap, ok := agent.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, _ := agentMap["name"].(string); name == agentName {
+				if path, _ := agentMap["worktree_path"].(string); path != "" {
+					return path, nil
+				}
+			}
+		}
 	}
-	return err
+
+	path := c.paths.AgentWorktree(repoName, agentName)
+	if info, statErr := os.Stat(path); statErr == nil && info.IsDir() {
+		return path, nil
+	}
+	return "", errors.AgentNotFound("agent", agentName, repoName)
 }
 
-func (c *CLI) cleanLogs(args []string) error {
-	flags, _ := ParseFlags(args)
+// shellInitScripts holds the mcd helper for each supported shell
+var shellInitScripts = map[string]string{
+	"bash": `# multiclaude shell helpers
+# Add to ~/.bashrc: eval "$(multiclaude shell-init bash)"
+mcd() {
+  local dir
+  dir="$(multiclaude path "$@")" || return
+  cd "$dir" || return
+}
+`,
+	"zsh": `# multiclaude shell helpers
+# Add to ~/.zshrc: eval "$(multiclaude shell-init zsh)"
+mcd() {
+  local dir
+  dir="$(multiclaude path "$@")" || return
+  cd "$dir" || return
+}
+`,
+	"fish": `# multiclaude shell helpers
+# Add to ~/.config/fish/config.fish: multiclaude shell-init fish | source
+function mcd
+    set -l dir (multiclaude path $argv); or return
+    cd $dir
+end
+`,
+}
 
-	olderThan, ok := flags["older-than"]
+func (c *CLI) shellInit(args []string) error {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	if len(args) > 0 {
+		shell = args[0]
+	}
+
+	script, ok := shellInitScripts[shell]
 	if !ok {
-		return fmt.Errorf("usage: multiclaude logs clean --older-than <duration> (e.g., 7d, 24h)")
+		return errors.InvalidArgument("shell", shell, "bash, zsh, or fish")
 	}
+	fmt.Print(script)
+	return nil
+}
 
-	// Parse duration
-	duration, err := parseDuration(olderThan)
+// offsetFromFlags returns the --offset value, or 0 if unset or invalid
+func offsetFromFlags(flags map[string]string) int {
+	n, err := strconv.Atoi(flags["offset"])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// askQuestion asks a human a question on behalf of the agent running it.
+// The question goes out as an agent.question event; the reply arrives in
+// the agent's window like typed input.
+func (c *CLI) askQuestion(args []string) error {
+	_, posArgs := ParseFlags(args)
+	question := strings.Join(posArgs, " ")
+	if question == "" {
+		return errors.InvalidUsage("usage: multiclaude agent ask <question>")
+	}
+
+	repoName, agentName, err := c.inferAgentContext()
 	if err != nil {
-		return fmt.Errorf("invalid duration: %v", err)
+		return fmt.Errorf("failed to determine agent context: %w", err)
 	}
 
-	cutoff := time.Now().Add(-duration)
-	fmt.Printf("Cleaning logs older than %s...\n", cutoff.Format(time.RFC3339))
+	resp, err := c.sendDaemonRequest("ask_question", map[string]interface{}{
+		"repo":     repoName,
+		"agent":    agentName,
+		"question": question,
+	})
+	if err != nil {
+		return err
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	fmt.Println("✓ Question sent. The answer will be typed into this window; carry on with other work until it arrives.")
+	format.Dimmed("Answer it with: multiclaude respond --response-id %v <reply>", data["response_id"])
+	return nil
+}
 
-	var deletedCount, deletedBytes int64
+// agentHeartbeat tells the daemon the agent running it is still working, so
+// a long quiet stretch (a slow build, a big test run) isn't reported as stuck
+func (c *CLI) agentHeartbeat(args []string) error {
+	repoName, agentName, err := c.inferAgentContext()
+	if err != nil {
+		return fmt.Errorf("failed to determine agent context: %w", err)
+	}
 
-	// Walk output directory
-	err = filepath.Walk(c.paths.OutputDir, func(path string, info os.FileInfo, err error) error {
+	_, err = c.sendDaemonRequest("agent_heartbeat", map[string]interface{}{
+		"repo":  repoName,
+		"agent": agentName,
+	})
+	return err
+}
+
+func (c *CLI) respondToAgent(args []string) error {
+	flags, posArgs := ParseFlags(args)
+
+	reply := strings.Join(posArgs, " ")
+	if reply == "" {
+		return errors.InvalidUsage("usage: multiclaude respond [--agent <name>|<#>] [--response-id <id>] <reply>")
+	}
+
+	// A question's response ID identifies the agent that asked it
+	_, hasRepo := flags["repo"]
+	if responseID := flags["response-id"]; responseID != "" && flags["agent"] == "" && !hasRepo {
+		resp, err := c.sendDaemonRequest("respond_agent", map[string]interface{}{
+			"response_id": responseID,
+			"text":        reply,
+		})
 		if err != nil {
-			return nil // Skip errors
-		}
-		if info.IsDir() {
-			return nil
-		}
-		if !strings.HasSuffix(path, ".log") {
-			return nil
-		}
-		if info.ModTime().Before(cutoff) {
-			deletedBytes += info.Size()
-			if err := os.Remove(path); err != nil {
-				fmt.Printf("Warning: failed to remove %s: %v\n", path, err)
-			} else {
-				deletedCount++
-			}
+			return err
 		}
+		data, _ := resp.Data.(map[string]interface{})
+		fmt.Printf("✓ Reply sent to '%v'\n", data["agent"])
 		return nil
-	})
+	}
 
+	repoName, err := c.resolveRepo(flags)
 	if err != nil {
-		return fmt.Errorf("failed to walk output directory: %w", err)
+		return errors.NotInRepo()
 	}
 
-	fmt.Printf("Deleted %d files (%.2f MB)\n", deletedCount, float64(deletedBytes)/(1024*1024))
-	return nil
-}
+	resp, err := c.sendDaemonRequest("list_agents", map[string]interface{}{
+		"repo": repoName,
+	})
+	if err != nil {
+		return err
+	}
+	agents, _ := resp.Data.([]interface{})
 
-// parseDuration parses a duration string like "7d", "24h", "30m"
-func parseDuration(s string) (time.Duration, error) {
-	if len(s) < 2 {
-		return 0, fmt.Errorf("duration too short")
+	agentName := flags["agent"]
+	if agentName == "" {
+		items := agentsToSelectableItems(agents, nil)
+		if len(items) == 0 {
+			return errors.NoAgentsFound(repoName)
+		}
+		selected, err := SelectFromList("Select agent to reply to:", items)
+		if err != nil {
+			return err
+		}
+		if selected == "" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+		agentName = selected
+	} else if index, err := strconv.Atoi(agentName); err == nil {
+		// Numbers refer to the # column of `multiclaude work list`
+		items := agentsToSelectableItems(agents, []string{"worker"})
+		if index < 1 || index > len(items) {
+			return errors.InvalidArgument("--agent", agentName, fmt.Sprintf("a worker number between 1 and %d (see: multiclaude work list)", len(items)))
+		}
+		agentName = items[index-1].Name
 	}
 
-	unit := s[len(s)-1]
-	valueStr := s[:len(s)-1]
-
-	var value int
-	if _, err := fmt.Sscanf(valueStr, "%d", &value); err != nil {
-		return 0, err
+	reqArgs := map[string]interface{}{
+		"repo":  repoName,
+		"agent": agentName,
+		"text":  reply,
 	}
-
-	switch unit {
-	case 'd':
-		return time.Duration(value) * 24 * time.Hour, nil
-	case 'h':
-		return time.Duration(value) * time.Hour, nil
-	case 'm':
-		return time.Duration(value) * time.Minute, nil
-	default:
-		return 0, fmt.Errorf("unknown unit: %c (use d, h, or m)", unit)
+	if responseID := flags["response-id"]; responseID != "" {
+		reqArgs["response_id"] = responseID
+	}
+	if _, err := c.sendDaemonRequest("respond_agent", reqArgs); err != nil {
+		return err
 	}
+
+	fmt.Printf("✓ Reply sent to '%s'\n", agentName)
+	return nil
 }
 
 func (c *CLI) attachAgent(args []string) error {
 	flags, remainingArgs := ParseFlags(args)
 	readOnly := flags["read-only"] == "true" || flags["r"] == "true"
+	control := flags["control"] == "true"
+
+	if control && os.Getenv("TMUX") != "" {
+		return errors.InvalidUsage("--control cannot be used from inside tmux; run it from an iTerm2 (or other control-mode) terminal")
+	}
 
 	// Determine repository
 	repoName, err := c.resolveRepo(flags)
@@ -4072,8 +9734,14 @@ func (c *CLI) attachAgent(args []string) error {
 		return errors.NotInRepo()
 	}
 
+	// In control mode without an agent, attach the whole session so every
+	// agent window becomes a native window/tab in the terminal
+	if control && len(remainingArgs) == 0 {
+		return runTmuxAttach(buildAttachArgs(c.tmuxSession(repoName), readOnly, control))
+	}
+
 	// Get agent info to find tmux session and window
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	resp, err := client.Send(socket.Request{
 		Command: "list_agents",
 		Args: map[string]interface{}{
@@ -4126,18 +9794,32 @@ func (c *CLI) attachAgent(args []string) error {
 	}
 
 	// Get tmux session and window
-	tmuxSession := sanitizeTmuxSessionName(repoName)
+	tmuxSession := c.tmuxSession(repoName)
 	tmuxWindow := agentInfo["tmux_window"].(string)
 
 	// Attach to tmux
 	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
+	return runTmuxAttach(buildAttachArgs(target, readOnly, control))
+}
 
-	tmuxArgs := []string{"attach", "-t", target}
+// buildAttachArgs returns the tmux arguments to attach to target. Control mode
+// (-CC) lets terminals like iTerm2 render tmux windows natively and lets other
+// programs drive the session through tmux's control protocol.
+func buildAttachArgs(target string, readOnly, control bool) []string {
+	var tmuxArgs []string
+	if control {
+		tmuxArgs = append(tmuxArgs, "-CC")
+	}
+	tmuxArgs = append(tmuxArgs, "attach", "-t", target)
 	if readOnly {
 		tmuxArgs = append(tmuxArgs, "-r")
 	}
+	return tmuxArgs
+}
 
-	cmd := exec.Command("tmux", tmuxArgs...)
+// runTmuxAttach runs tmux with the terminal connected
+func runTmuxAttach(tmuxArgs []string) error {
+	cmd := tmuxCommand(tmuxArgs...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -4145,11 +9827,188 @@ func (c *CLI) attachAgent(args []string) error {
 	return cmd.Run()
 }
 
+// overviewWindow names the tmux window `multiclaude overview` builds
+const overviewWindow = "overview"
+
+// defaultOverviewWorkers is how many of the newest workers the overview shows
+const defaultOverviewWorkers = 4
+
+// overviewPane is an agent window shown in the overview
+type overviewPane struct {
+	Name   string
+	Window string
+}
+
+// showOverview builds a "mission control" window in the repository's tmux
+// session with one pane per agent, tiled, and switches to it. The panes
+// mirror the agents' screens, so the agents' own windows are left alone.
+func (c *CLI) showOverview(args []string) error {
+	flags, positional := ParseFlags(args)
+	if len(positional) > 0 {
+		flags["repo"] = positional[0]
+	}
+	repoName, err := c.resolveRepo(flags)
+	if err != nil {
+		return errors.NotInRepo()
+	}
+
+	workers := defaultOverviewWorkers
+	if v, ok := flags["workers"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return errors.InvalidUsage("--workers must be a non-negative number")
+		}
+		workers = n
+	}
+
+	resp, err := c.sendDaemonRequest("list_agents", map[string]interface{}{"repo": repoName})
+	if err != nil {
+		return err
+	}
+	agents, _ := resp.Data.([]interface{})
+	panes := overviewPanes(agents, workers)
+	if len(panes) == 0 {
+		return errors.NoAgentsFound(repoName)
+	}
+
+	// The overview tiles tmux panes, which other multiplexers don't have
+	if backend := daemonMultiplexer(c.daemonClient()); backend != mux.BackendTmux {
+		return errors.New(errors.CategoryConfig, fmt.Sprintf("the overview needs tmux, but the daemon runs agents in the %s multiplexer", backend)).
+			WithSuggestion("follow an agent with: multiclaude logs <agent> --follow")
+	}
+
+	session := c.tmuxSession(repoName)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := buildOverview(ctx, tmux.NewClient(), session, c.paths.Root, panes); err != nil {
+		return fmt.Errorf("failed to build overview: %w", err)
+	}
+
+	target := fmt.Sprintf("%s:%s", session, overviewWindow)
+	if flags["no-attach"] == "true" {
+		fmt.Printf("Overview ready in %s (%d panes)\n", target, len(panes))
+		return nil
+	}
+	if os.Getenv("TMUX") != "" {
+		return runTmuxAttach([]string{"switch-client", "-t", target})
+	}
+	return runTmuxAttach(buildAttachArgs(target, false, false))
+}
+
+// overviewPanes picks the agents the overview shows: the supervisor, the
+// merge queue, then up to workers workers, newest first
+func overviewPanes(agents []interface{}, workers int) []overviewPane {
+	var panes, workerPanes []overviewPane
+	created := make(map[string]string)
+	for _, wantType := range []string{string(state.AgentTypeSupervisor), string(state.AgentTypeMergeQueue), string(state.AgentTypeWorker)} {
+		for _, raw := range agents {
+			agent, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			agentType, _ := agent["type"].(string)
+			if agentType != wantType {
+				continue
+			}
+			pane := overviewPane{}
+			pane.Name, _ = agent["name"].(string)
+			pane.Window, _ = agent["tmux_window"].(string)
+			if pane.Window == "" {
+				continue
+			}
+			if agentType != string(state.AgentTypeWorker) {
+				panes = append(panes, pane)
+				continue
+			}
+			created[pane.Name], _ = agent["created_at"].(string)
+			workerPanes = append(workerPanes, pane)
+		}
+	}
+	// RFC 3339 timestamps from the daemon sort as strings
+	sort.SliceStable(workerPanes, func(i, j int) bool {
+		return created[workerPanes[i].Name] > created[workerPanes[j].Name]
+	})
+	if len(workerPanes) > workers {
+		workerPanes = workerPanes[:workers]
+	}
+	return append(panes, workerPanes...)
+}
+
+// buildOverview replaces the session's overview window with one tiled pane
+// per agent, each titled with the agent's name
+func buildOverview(ctx context.Context, client *tmux.Client, session, dir string, panes []overviewPane) error {
+	if exists, err := client.HasWindow(ctx, session, overviewWindow); err != nil {
+		return err
+	} else if exists {
+		if err := client.KillWindow(ctx, session, overviewWindow); err != nil {
+			return err
+		}
+	}
+	if err := client.CreateWindowAt(ctx, session, overviewWindow, dir); err != nil {
+		return err
+	}
+	// The overview isn't an agent; keep the reaper and orphan cleanup off it
+	for option, value := range map[string]string{
+		cleanup.KeepWindowOption: "on",
+		"pane-border-status":     "top",
+		"pane-border-format":     " #{pane_title} ",
+	} {
+		if err := client.SetWindowOption(ctx, session, overviewWindow, option, value); err != nil {
+			return err
+		}
+	}
+
+	existing, err := client.ListPanes(ctx, session, overviewWindow)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return fmt.Errorf("overview window has no panes")
+	}
+	for i, pane := range panes {
+		command := overviewMirrorCommand(session + ":" + pane.Window)
+		id := existing[0].ID
+		if i == 0 {
+			err = client.RespawnPane(ctx, id, command)
+		} else {
+			id, err = client.SplitWindow(ctx, session, overviewWindow, command)
+		}
+		if err != nil {
+			return err
+		}
+		if err := client.SetPaneTitle(ctx, id, pane.Name); err != nil {
+			return err
+		}
+		// Re-tile after every split so the window always has room for the next
+		if err := client.SelectLayout(ctx, session, overviewWindow, tmux.LayoutTiled); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overviewMirrorCommand returns a command that redraws its pane with the
+// bottom of target's screen every two seconds, cropped to the pane's size
+func overviewMirrorCommand(target string) string {
+	script := fmt.Sprintf(`while :; do `+
+		`s=$(tmux capture-pane -p -t %s 2>/dev/null) || s="(window closed)"; `+
+		`h=$(tput lines); w=$(tput cols); `+
+		`out=$(printf '%%s\n' "$s" | awk -v n="$((h-1))" '{a[NR]=$0} NF{l=NR} END{for(i=(l>n?l-n+1:1);i<=l;i++) print a[i]}' | cut -c1-"$w"); `+
+		`printf '\033[H\033[2J%%s' "$out"; sleep 2; done`, shellQuote(target))
+	return "sh -c " + shellQuote(script)
+}
+
+// shellQuote quotes a string for safe use in a POSIX shell command
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 func (c *CLI) cleanup(args []string) error {
 	flags, _ := ParseFlags(args)
 	dryRun := flags["dry-run"] == "true"
 	verbose := flags["verbose"] == "true" || flags["v"] == "true"
 	cleanMerged := flags["merged"] == "true"
+	repoName := flags["repo"]
 
 	if dryRun {
 		fmt.Println("Running cleanup in dry-run mode (no changes will be made)...")
@@ -4162,13 +10021,13 @@ func (c *CLI) cleanup(args []string) error {
 		return c.cleanupMergedBranches(dryRun, verbose)
 	}
 
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 
 	// Check if daemon is running
 	_, err := client.Send(socket.Request{Command: "ping"})
 	if err != nil {
 		fmt.Println("Daemon is not running. Running local cleanup...")
-		return c.localCleanup(dryRun, verbose)
+		return c.localCleanup(repoName, dryRun, verbose)
 	}
 
 	// Trigger daemon cleanup
@@ -4176,6 +10035,7 @@ func (c *CLI) cleanup(args []string) error {
 		Command: "trigger_cleanup",
 		Args: map[string]interface{}{
 			"dry_run": dryRun,
+			"repo":    repoName,
 		},
 	})
 	if err != nil {
@@ -4185,10 +10045,75 @@ func (c *CLI) cleanup(args []string) error {
 		return fmt.Errorf("cleanup failed: %s", resp.Error)
 	}
 
-	fmt.Println("Cleanup completed")
+	raw, _ := resp.Data.([]interface{})
+	items := make([]cleanup.Item, 0, len(raw))
+	for _, entry := range raw {
+		itemMap, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		item := cleanup.Item{}
+		item.Repo, _ = itemMap["repo"].(string)
+		kind, _ := itemMap["kind"].(string)
+		item.Kind = cleanup.Kind(kind)
+		item.Target, _ = itemMap["target"].(string)
+		item.Removed, _ = itemMap["removed"].(bool)
+		item.Error, _ = itemMap["error"].(string)
+		items = append(items, item)
+	}
+
+	printCleanupItems(items, dryRun)
 	return nil
 }
 
+// printCleanupItems prints what a cleanup run removed, or would remove in
+// a dry run, followed by a one-line summary
+func printCleanupItems(items []cleanup.Item, dryRun bool) {
+	fmt.Println()
+	if len(items) == 0 {
+		if dryRun {
+			fmt.Println("✓ Dry run completed: no issues found")
+		} else {
+			fmt.Println("✓ Cleanup completed: no orphaned resources found")
+		}
+		return
+	}
+
+	failed := 0
+	table := format.NewColoredTable("REPO", "KIND", "TARGET", "RESULT")
+	for _, item := range items {
+		repoCell := format.Cell(item.Repo)
+		if item.Repo == "" {
+			repoCell = format.ColorCell("-", format.Dim)
+		}
+
+		var resultCell format.ColoredCell
+		switch {
+		case dryRun:
+			resultCell = format.ColorCell("would remove", format.Yellow)
+		case item.Error != "":
+			resultCell = format.ColorCell("failed: "+format.Truncate(item.Error, 50), format.Red)
+			failed++
+		default:
+			resultCell = format.ColorCell("removed", format.Green)
+		}
+
+		table.AddRow(repoCell, format.Cell(string(item.Kind)), format.Cell(item.Target), resultCell)
+	}
+	table.Print()
+
+	fmt.Println()
+	switch {
+	case dryRun:
+		fmt.Printf("✓ Dry run completed: would remove %d item(s)\n", len(items))
+		format.Dimmed("Run without --dry-run to remove them")
+	case failed > 0:
+		fmt.Printf("⚠ Cleanup completed: removed %d item(s), %d failed\n", len(items)-failed, failed)
+	default:
+		fmt.Printf("✓ Cleanup completed: removed %d item(s)\n", len(items))
+	}
+}
+
 // cleanupMergedBranches cleans up branches that have been merged upstream
 func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 	fmt.Println("\nChecking for branches merged upstream...")
@@ -4225,9 +10150,13 @@ func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 		}
 
 		wt := worktree.NewManager(repoPath)
+		pushRemote := state.DefaultPushRemote
+		if repo, exists := st.GetRepo(repoName); exists {
+			pushRemote = repo.WorkerPushRemote()
+		}
 
-		// Check for merged branches with common prefixes
-		for _, prefix := range []string{"multiclaude/", "work/"} {
+		// Check for merged branches with multiclaude's prefixes
+		for _, prefix := range c.branchPrefixes(repoName) {
 			mergedBranches, err := wt.FindMergedUpstreamBranches(prefix)
 			if err != nil {
 				if verbose {
@@ -4255,538 +10184,391 @@ func (c *CLI) cleanupMergedBranches(dryRun bool, verbose bool) error {
 			activeBranches := make(map[string]bool)
 			for _, wtInfo := range worktrees {
 				if wtInfo.Branch != "" {
-					activeBranches[wtInfo.Branch] = true
-				}
-			}
-
-			fmt.Printf("\nMerged branches with prefix %s for %s:\n", prefix, repoName)
-			for _, branch := range mergedBranches {
-				if activeBranches[branch] {
-					if verbose {
-						fmt.Printf("  Skipping %s (still checked out)\n", branch)
-					}
-					continue
-				}
-
-				totalFound++
-				if dryRun {
-					fmt.Printf("  Would delete: %s\n", branch)
-				} else {
-					// Delete local branch
-					if err := wt.DeleteBranch(branch); err != nil {
-						fmt.Printf("  Failed to delete %s: %v\n", branch, err)
-						continue
-					}
-					fmt.Printf("  Deleted: %s\n", branch)
-					totalDeleted++
-
-					// Try to delete remote branch from origin (the fork)
-					if err := wt.DeleteRemoteBranch("origin", branch); err != nil {
-						if verbose {
-							fmt.Printf("    (remote branch deletion failed: %v)\n", err)
-						}
-					} else if verbose {
-						fmt.Printf("    (also deleted from origin)\n")
-					}
-				}
-			}
-		}
-	}
-
-	if dryRun {
-		if totalFound > 0 {
-			fmt.Printf("\nFound %d merged branch(es) that would be deleted\n", totalFound)
-		} else {
-			fmt.Println("\nNo merged branches found to clean up")
-		}
-	} else {
-		if totalDeleted > 0 {
-			fmt.Printf("\nDeleted %d merged branch(es)\n", totalDeleted)
-		} else {
-			fmt.Println("\nNo merged branches found to clean up")
-		}
-	}
-
-	return nil
-}
-
-// cleanupOrphanedBranchesWithPrefix removes orphaned branches matching the given prefix
-func (c *CLI) cleanupOrphanedBranchesWithPrefix(wt *worktree.Manager, branchPrefix, repoName string, dryRun, verbose bool) (removed int, issues int) {
-	orphanedBranches, err := wt.FindOrphanedBranches(branchPrefix)
-	if err != nil && verbose {
-		fmt.Printf("  Warning: failed to find orphaned %s branches: %v\n", branchPrefix, err)
-		return 0, 0
-	}
-
-	if len(orphanedBranches) == 0 {
-		if verbose {
-			branchType := "work"
-			if branchPrefix == "workspace/" {
-				branchType = "workspace"
-			}
-			fmt.Printf("  No orphaned %s branches\n", branchType)
-		}
-		return 0, 0
-	}
-
-	branchType := "work"
-	if branchPrefix == "workspace/" {
-		branchType = "workspace"
-	}
-	fmt.Printf("\nOrphaned %s branches (%d) for %s:\n", branchType, len(orphanedBranches), repoName)
-
-	for _, branch := range orphanedBranches {
-		if dryRun {
-			fmt.Printf("  Would delete branch: %s\n", branch)
-			issues++
-		} else {
-			if err := wt.DeleteBranch(branch); err != nil {
-				fmt.Printf("  Failed to delete %s: %v\n", branch, err)
-			} else {
-				fmt.Printf("  Deleted branch: %s\n", branch)
-				removed++
-			}
-		}
-	}
-
-	return removed, issues
-}
-
-func (c *CLI) localCleanup(dryRun bool, verbose bool) error {
-	// Clean up orphaned worktrees, tmux sessions, and other resources
-	fmt.Println("\nChecking for orphaned resources...")
-
-	totalRemoved := 0
-	totalIssues := 0
-
-	// Load state for reference
-	st, err := state.Load(c.paths.StateFile)
-	if err != nil {
-		fmt.Printf("Warning: could not load state file: %v\n", err)
-		st = state.New(c.paths.StateFile)
-	}
-
-	// Check for orphaned tmux sessions (mc-* sessions not in state)
-	tmuxClient := tmux.NewClient()
-	if tmuxClient.IsTmuxAvailable() {
-		sessions, err := tmuxClient.ListSessions(context.Background())
-		if err == nil {
-			repos := st.ListRepos()
-			validSessions := make(map[string]bool)
-			for _, repo := range repos {
-				validSessions[fmt.Sprintf("mc-%s", repo)] = true
-			}
-
-			orphanedSessions := []string{}
-			for _, session := range sessions {
-				if strings.HasPrefix(session, "mc-") && !validSessions[session] {
-					orphanedSessions = append(orphanedSessions, session)
-				}
-			}
-
-			if len(orphanedSessions) > 0 {
-				fmt.Printf("\nOrphaned tmux sessions (%d):\n", len(orphanedSessions))
-				for _, session := range orphanedSessions {
-					if dryRun {
-						fmt.Printf("  Would kill: %s\n", session)
-					} else {
-						if err := tmuxClient.KillSession(context.Background(), session); err != nil {
-							fmt.Printf("  Failed to kill %s: %v\n", session, err)
-						} else {
-							fmt.Printf("  Killed: %s\n", session)
-							totalRemoved++
-						}
-					}
-				}
-			} else if verbose {
-				fmt.Println("\nNo orphaned tmux sessions found")
-			}
-		}
-	}
-
-	// Check for orphaned worktree directories (in wts/ but not in any repo's git worktrees)
-	entries, err := os.ReadDir(c.paths.WorktreesDir)
-	if err != nil && !os.IsNotExist(err) {
-		fmt.Printf("Warning: failed to read worktrees directory: %v\n", err)
-	} else if err == nil {
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
-
-			repoName := entry.Name()
-			repoPath := c.paths.RepoDir(repoName)
-			wtRootDir := c.paths.WorktreeDir(repoName)
-
-			// Check if the repo still exists
-			if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-				fmt.Printf("\nOrphaned worktree directory (repo missing): %s\n", wtRootDir)
-				if !dryRun {
-					if err := os.RemoveAll(wtRootDir); err != nil {
-						fmt.Printf("  Failed to remove: %v\n", err)
-					} else {
-						fmt.Printf("  Removed\n")
-						totalRemoved++
-					}
-				}
-				continue
-			}
-
-			if verbose {
-				fmt.Printf("\nRepository: %s\n", repoName)
-			}
-
-			wt := worktree.NewManager(repoPath)
-
-			// Cleanup orphaned worktree directories
-			if !dryRun {
-				removed, err := worktree.CleanupOrphaned(wtRootDir, wt)
-				if err != nil {
-					fmt.Printf("  Warning: failed to cleanup worktrees: %v\n", err)
-				} else if len(removed) > 0 {
-					for _, path := range removed {
-						fmt.Printf("  Removed: %s\n", path)
-					}
-					totalRemoved += len(removed)
-				} else if verbose {
-					fmt.Println("  No orphaned worktrees")
+					activeBranches[wtInfo.Branch] = true
 				}
-			} else {
-				// Dry run: just check what would be removed
-				gitWorktrees, _ := wt.List()
-				gitPaths := make(map[string]bool)
-				for _, gwt := range gitWorktrees {
-					absPath, _ := filepath.Abs(gwt.Path)
-					evalPath, err := filepath.EvalSymlinks(absPath)
-					if err != nil {
-						evalPath = absPath
+			}
+
+			fmt.Printf("\nMerged branches with prefix %s for %s:\n", prefix, repoName)
+			for _, branch := range mergedBranches {
+				if activeBranches[branch] {
+					if verbose {
+						fmt.Printf("  Skipping %s (still checked out)\n", branch)
 					}
-					gitPaths[evalPath] = true
+					continue
 				}
 
-				dirEntries, _ := os.ReadDir(wtRootDir)
-				for _, de := range dirEntries {
-					if !de.IsDir() {
+				totalFound++
+				if dryRun {
+					fmt.Printf("  Would delete: %s\n", branch)
+				} else {
+					// Delete local branch
+					if err := wt.DeleteBranch(branch); err != nil {
+						fmt.Printf("  Failed to delete %s: %v\n", branch, err)
 						continue
 					}
-					path := filepath.Join(wtRootDir, de.Name())
-					absPath, _ := filepath.Abs(path)
-					evalPath, err := filepath.EvalSymlinks(absPath)
-					if err != nil {
-						evalPath = absPath
-					}
-					if !gitPaths[evalPath] {
-						fmt.Printf("  Would remove: %s\n", path)
-						totalIssues++
-					}
-				}
-			}
+					fmt.Printf("  Deleted: %s\n", branch)
+					totalDeleted++
 
-			// Prune git worktree references
-			if !dryRun {
-				if err := wt.Prune(); err != nil && verbose {
-					fmt.Printf("  Warning: failed to prune worktrees: %v\n", err)
+					// Try to delete the branch from where workers push it (origin or the fork)
+					if err := wt.DeleteRemoteBranch(pushRemote, branch); err != nil {
+						if verbose {
+							fmt.Printf("    (remote branch deletion failed: %v)\n", err)
+						}
+					} else if verbose {
+						fmt.Printf("    (also deleted from %s)\n", pushRemote)
+					}
 				}
 			}
+		}
+	}
 
-			// Clean up orphaned work/* and workspace/* branches
-			removed, issues := c.cleanupOrphanedBranchesWithPrefix(wt, "work/", repoName, dryRun, verbose)
-			totalRemoved += removed
-			totalIssues += issues
-
-			removed, issues = c.cleanupOrphanedBranchesWithPrefix(wt, "workspace/", repoName, dryRun, verbose)
-			totalRemoved += removed
-			totalIssues += issues
+	if dryRun {
+		if totalFound > 0 {
+			fmt.Printf("\nFound %d merged branch(es) that would be deleted\n", totalFound)
+		} else {
+			fmt.Println("\nNo merged branches found to clean up")
+		}
+	} else {
+		if totalDeleted > 0 {
+			fmt.Printf("\nDeleted %d merged branch(es)\n", totalDeleted)
+		} else {
+			fmt.Println("\nNo merged branches found to clean up")
 		}
 	}
 
-	// Check for orphaned message directories
-	msgEntries, err := os.ReadDir(c.paths.MessagesDir)
-	if err != nil && !os.IsNotExist(err) {
-		fmt.Printf("Warning: failed to read messages directory: %v\n", err)
-	} else if err == nil {
-		for _, entry := range msgEntries {
-			if !entry.IsDir() {
-				continue
-			}
+	return nil
+}
+
+func (c *CLI) localCleanup(repoName string, dryRun bool, verbose bool) error {
+	// Clean up orphaned worktrees, tmux windows, and other resources
+	fmt.Println("\nChecking for orphaned resources...")
 
-			repoName := entry.Name()
-			validAgents, _ := st.ListAgents(repoName)
+	// Load state for reference
+	st, err := state.Load(c.paths.StateFile)
+	if err != nil {
+		fmt.Printf("Warning: could not load state file: %v\n", err)
+		st = state.New(c.paths.StateFile)
+	}
 
-			msgMgr := messages.NewManager(c.paths.MessagesDir)
+	// Left nil rather than holding a nil *tmux.Client when tmux is missing
+	var tmuxClient mux.Multiplexer
+	if client := tmux.NewClient(); client.IsTmuxAvailable() {
+		tmuxClient = client
+	} else if verbose {
+		fmt.Println("tmux is not available; skipping windows and sessions")
+	}
 
-			if !dryRun {
-				count, err := msgMgr.CleanupOrphaned(repoName, validAgents)
-				if err != nil && verbose {
-					fmt.Printf("Warning: failed to cleanup messages for %s: %v\n", repoName, err)
-				} else if count > 0 {
-					fmt.Printf("Cleaned up %d orphaned message dir(s) for %s\n", count, repoName)
-					totalRemoved += count
-				}
-			} else {
-				// Dry run check
-				repoDir := filepath.Join(c.paths.MessagesDir, repoName)
-				agentEntries, _ := os.ReadDir(repoDir)
-				validAgentMap := make(map[string]bool)
-				for _, a := range validAgents {
-					validAgentMap[a] = true
-				}
-				for _, ae := range agentEntries {
-					if ae.IsDir() && !validAgentMap[ae.Name()] {
-						fmt.Printf("Would remove orphaned message dir: %s/%s\n", repoName, ae.Name())
-						totalIssues++
-					}
-				}
-			}
-		}
+	items, err := cleanup.New(c.paths, st, tmuxClient).Run(context.Background(), cleanup.Options{
+		Repo:   repoName,
+		DryRun: dryRun,
+		Namer:  c.namer(),
+	})
+	if err != nil {
+		return err
 	}
 
 	// Check for stale socket and PID files (when daemon not running)
 	pidFile := daemon.NewPIDFile(c.paths.DaemonPID)
-	if running, _, _ := pidFile.IsRunning(); !running {
-		// Daemon not running, check for stale files
-		if _, err := os.Stat(c.paths.DaemonPID); err == nil {
-			if dryRun {
-				fmt.Printf("\nWould remove stale PID file: %s\n", c.paths.DaemonPID)
-				totalIssues++
-			} else {
-				if err := os.Remove(c.paths.DaemonPID); err == nil {
-					fmt.Printf("Removed stale PID file: %s\n", c.paths.DaemonPID)
-					totalRemoved++
-				}
+	if running, _, _ := pidFile.IsRunning(); !running && repoName == "" {
+		for _, path := range []string{c.paths.DaemonPID, c.paths.DaemonSock} {
+			if _, err := os.Stat(path); err != nil {
+				continue
 			}
-		}
-		if _, err := os.Stat(c.paths.DaemonSock); err == nil {
-			if dryRun {
-				fmt.Printf("Would remove stale socket file: %s\n", c.paths.DaemonSock)
-				totalIssues++
-			} else {
-				if err := os.Remove(c.paths.DaemonSock); err == nil {
-					fmt.Printf("Removed stale socket file: %s\n", c.paths.DaemonSock)
-					totalRemoved++
+			item := cleanup.Item{Kind: "daemon-file", Target: path}
+			if !dryRun {
+				if err := os.Remove(path); err != nil {
+					item.Error = err.Error()
+				} else {
+					item.Removed = true
 				}
 			}
+			items = append(items, item)
 		}
 	}
 
-	fmt.Println()
-	if dryRun {
-		if totalIssues > 0 {
-			fmt.Printf("✓ Dry run completed: would fix %d issue(s)\n", totalIssues)
-		} else {
-			fmt.Println("✓ Dry run completed: no issues found")
-		}
-	} else {
-		if totalRemoved > 0 {
-			fmt.Printf("✓ Cleanup completed: removed %d item(s)\n", totalRemoved)
-		} else {
-			fmt.Println("✓ Cleanup completed: no orphaned resources found")
-		}
-	}
-
+	printCleanupItems(items, dryRun)
 	return nil
 }
 
 func (c *CLI) repair(args []string) error {
 	flags, _ := ParseFlags(args)
-	verbose := flags["verbose"] == "true" || flags["v"] == "true"
+	dryRun := flags["dry-run"] == "true"
+	skipConfirm := flags["yes"] == "true"
 
-	fmt.Println("Repairing state...")
+	if spec, ok := flags["from-backup"]; ok {
+		backup := 0
+		if spec != "true" {
+			n, err := strconv.Atoi(spec)
+			if err != nil || n < 1 || n > state.BackupCount {
+				return errors.InvalidArgument("--from-backup", spec, fmt.Sprintf("a backup number from 1 to %d", state.BackupCount))
+			}
+			backup = n
+		}
+		if restored, err := c.restoreBackup(backup, dryRun, skipConfirm); err != nil || !restored {
+			return err
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("Checking state against tmux and worktrees...")
 
 	// Check if daemon is running
-	client := socket.NewClient(c.paths.DaemonSock)
+	client := c.daemonClient()
 	_, err := client.Send(socket.Request{Command: "ping"})
 	if err != nil {
 		// Daemon not running - do local repair
 		fmt.Println("Daemon is not running. Performing local repair...")
-		return c.localRepair(verbose)
+		return c.localRepair(dryRun, skipConfirm)
 	}
 
-	// Trigger state repair via daemon
 	resp, err := client.Send(socket.Request{
 		Command: "repair_state",
+		Args:    map[string]interface{}{"dry_run": true},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to trigger repair: %w", err)
+		return fmt.Errorf("failed to check state: %w", err)
 	}
 	if !resp.Success {
 		return fmt.Errorf("repair failed: %s", resp.Error)
 	}
 
-	fmt.Println("✓ State repaired successfully")
-	if data, ok := resp.Data.(map[string]interface{}); ok {
-		if removed, ok := data["agents_removed"].(float64); ok && removed > 0 {
-			fmt.Printf("  Removed %d dead agent(s)\n", int(removed))
-		}
-		if fixed, ok := data["issues_fixed"].(float64); ok && fixed > 0 {
-			fmt.Printf("  Fixed %d issue(s)\n", int(fixed))
-		}
+	issues := repairIssuesFromResponse(resp.Data)
+	if !confirmRepair(issues, dryRun, skipConfirm) {
+		return nil
 	}
 
-	return nil
-}
-
-// localRepair performs state repair without the daemon running
-func (c *CLI) localRepair(verbose bool) error {
-	// Load state from disk
-	st, err := c.loadState()
+	// The daemon plans again, so agents fixed meanwhile are left alone
+	resp, err = client.Send(socket.Request{
+		Command: "repair_state",
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to trigger repair: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("repair failed: %s", resp.Error)
 	}
 
-	tmuxClient := tmux.NewClient()
-	agentsRemoved := 0
-	issuesFixed := 0
-
-	// Track orphaned tmux sessions
-	orphanedSessions := []string{}
+	printRepairResults(repairIssuesFromResponse(resp.Data))
+	return nil
+}
 
-	// Get all tmux sessions and find orphaned ones
-	sessions, err := tmuxClient.ListSessions(context.Background())
-	if err == nil {
-		repos := st.ListRepos()
-		validSessions := make(map[string]bool)
-		for _, repo := range repos {
-			validSessions[fmt.Sprintf("mc-%s", repo)] = true
+// restoreBackup replaces the state with backup n of the state file, or the
+// newest valid one when n is 0, through the daemon if it is running. It
+// reports whether the state was restored.
+func (c *CLI) restoreBackup(n int, dryRun, skipConfirm bool) (bool, error) {
+	client := c.daemonClient()
+	_, pingErr := client.Send(socket.Request{Command: "ping"})
+	daemonRunning := pingErr == nil
+
+	var backups []state.Backup
+	var picked state.Backup
+	if daemonRunning {
+		plan, err := socket.Call[socket.RestoreState](client, "restore_state", socket.RestoreStateArgs{Backup: n, DryRun: true})
+		if err != nil {
+			return false, fmt.Errorf("can't restore the state: %w", err)
 		}
-		for _, session := range sessions {
-			if strings.HasPrefix(session, "mc-") && !validSessions[session] {
-				orphanedSessions = append(orphanedSessions, session)
+		backups = plan.Backups
+		for _, backup := range backups {
+			if backup.N == plan.Backup {
+				picked = backup
 			}
 		}
-	}
-
-	// Check each repo and its agents
-	repos := st.GetAllRepos()
-	for repoName, repo := range repos {
-		if verbose {
-			fmt.Printf("\nChecking repository: %s\n", repoName)
+	} else {
+		backups = state.ListBackups(c.paths.StateFile)
+		var err error
+		if picked, err = state.PickBackup(backups, n); err != nil {
+			printBackups(backups, 0)
+			return false, fmt.Errorf("can't restore the state: %w", err)
 		}
+	}
 
-		// Check if tmux session exists
-		hasSession, err := tmuxClient.HasSession(context.Background(), repo.TmuxSession)
-		if err != nil && verbose {
-			fmt.Printf("  Warning: failed to check session %s: %v\n", repo.TmuxSession, err)
-			continue
+	printBackups(backups, picked.N)
+	if dryRun {
+		format.Dimmed("Run without --dry-run to restore backup %d", picked.N)
+		return false, nil
+	}
+	if !skipConfirm {
+		fmt.Printf("Replace the current state with backup %d (%d repos, %d agents)? [y/N]: ", picked.N, picked.Repos, picked.Agents)
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Restore cancelled")
+			return false, nil
 		}
+	}
 
-		if !hasSession {
-			if verbose {
-				fmt.Printf("  Tmux session %s not found\n", repo.TmuxSession)
-			}
-			// Remove all agents for this repo
-			for agentName := range repo.Agents {
-				if verbose {
-					fmt.Printf("  Removing agent %s (session gone)\n", agentName)
-				}
-				if err := st.RemoveAgent(repoName, agentName); err == nil {
-					agentsRemoved++
-				}
-			}
-			issuesFixed++
-			continue
+	if daemonRunning {
+		if _, err := socket.Call[socket.RestoreState](client, "restore_state", socket.RestoreStateArgs{Backup: picked.N}); err != nil {
+			return false, errors.DaemonCommunicationFailed("restore_state", err)
 		}
+	} else if _, err := state.New(c.paths.StateFile).RestoreBackup(picked.N); err != nil {
+		return false, fmt.Errorf("failed to restore backup %d: %w", picked.N, err)
+	}
+	fmt.Printf("✓ Restored state from backup %d; the state it replaced is now backup 1\n", picked.N)
+	return true, nil
+}
 
-		// Check each agent
-		for agentName, agent := range repo.Agents {
-			// Check if window exists
-			hasWindow, _ := tmuxClient.HasWindow(context.Background(), repo.TmuxSession, agent.TmuxWindow)
-			if !hasWindow {
-				if verbose {
-					fmt.Printf("  Removing agent %s (window %s not found)\n", agentName, agent.TmuxWindow)
-				}
-				if err := st.RemoveAgent(repoName, agentName); err == nil {
-					agentsRemoved++
-					issuesFixed++
-				}
-				continue
-			}
-
-			// Check if worktree exists (for workers)
-			if agent.Type == state.AgentTypeWorker && agent.WorktreePath != "" {
-				if _, err := os.Stat(agent.WorktreePath); os.IsNotExist(err) {
-					if verbose {
-						fmt.Printf("  Warning: worktree missing for %s: %s\n", agentName, agent.WorktreePath)
-					}
-					// Don't remove - window exists, user may have manually deleted worktree
-				}
-			}
-
-			if verbose {
-				fmt.Printf("  Agent %s: OK\n", agentName)
-			}
+// printBackups lists the state file's backups, marking the one to restore
+func printBackups(backups []state.Backup, picked int) {
+	if len(backups) == 0 {
+		fmt.Println("No backups of the state file")
+		return
+	}
+	table := format.NewColoredTable("BACKUP", "SAVED", "REPOS", "AGENTS", "")
+	for _, backup := range backups {
+		note := format.ColorCell("", format.Dim)
+		switch {
+		case backup.Error != "":
+			note = format.ColorCell("unreadable: "+backup.Error, format.Red)
+		case backup.N == picked:
+			note = format.ColorCell("← restore", format.Green)
 		}
+		table.AddRow(
+			format.Cell(strconv.Itoa(backup.N)),
+			format.Cell(backup.ModTime.Local().Format("Jan 02 15:04:05")),
+			format.Cell(strconv.Itoa(backup.Repos)),
+			format.Cell(strconv.Itoa(backup.Agents)),
+			note,
+		)
 	}
+	table.Print()
+	fmt.Println()
+}
 
-	// Clean up orphaned worktrees
-	for _, repoName := range st.ListRepos() {
-		repoPath := c.paths.RepoDir(repoName)
-		wtRootDir := c.paths.WorktreeDir(repoName)
-
-		if _, err := os.Stat(wtRootDir); os.IsNotExist(err) {
+// repairIssuesFromResponse decodes the issues in a repair_state response
+func repairIssuesFromResponse(data interface{}) []repair.Issue {
+	dataMap, _ := data.(map[string]interface{})
+	raw, _ := dataMap["issues"].([]interface{})
+	issues := make([]repair.Issue, 0, len(raw))
+	for _, entry := range raw {
+		issueMap, ok := entry.(map[string]interface{})
+		if !ok {
 			continue
 		}
+		issue := repair.Issue{}
+		issue.Repo, _ = issueMap["repo"].(string)
+		issue.Agent, _ = issueMap["agent"].(string)
+		issue.Problem, _ = issueMap["problem"].(string)
+		action, _ := issueMap["action"].(string)
+		issue.Action = repair.Action(action)
+		issue.Reason, _ = issueMap["reason"].(string)
+		issue.Done, _ = issueMap["done"].(bool)
+		issue.Error, _ = issueMap["error"].(string)
+		issues = append(issues, issue)
+	}
+	return issues
+}
 
-		wt := worktree.NewManager(repoPath)
-		removed, err := worktree.CleanupOrphaned(wtRootDir, wt)
-		if err != nil {
-			if verbose {
-				fmt.Printf("  Warning: failed to cleanup worktrees for %s: %v\n", repoName, err)
-			}
-			continue
-		}
+// confirmRepair shows the planned repairs and reports whether to apply them
+func confirmRepair(issues []repair.Issue, dryRun, skipConfirm bool) bool {
+	fmt.Println()
+	if len(issues) == 0 {
+		fmt.Println("✓ State matches tmux and worktrees: no issues found")
+		return false
+	}
 
-		if len(removed) > 0 {
-			if verbose {
-				fmt.Printf("  Cleaned up %d orphaned worktree(s) for %s\n", len(removed), repoName)
-			}
-			issuesFixed += len(removed)
+	table := format.NewColoredTable("REPO", "AGENT", "PROBLEM", "ACTION")
+	for _, issue := range issues {
+		actionCell := format.ColorCell("recreate and restart", format.Green)
+		if issue.Action == repair.ActionDrop {
+			actionCell = format.ColorCell("drop from state ("+issue.Reason+")", format.Yellow)
 		}
+		table.AddRow(format.Cell(issue.Repo), format.Cell(issue.Agent), format.ColorCell(issue.Problem, format.Red), actionCell)
+	}
+	table.Print()
+	fmt.Println()
 
-		// Prune git worktree references
-		if err := wt.Prune(); err != nil && verbose {
-			fmt.Printf("  Warning: failed to prune worktrees for %s: %v\n", repoName, err)
-		}
+	if dryRun {
+		format.Dimmed("Run without --dry-run to apply these repairs")
+		return false
+	}
+	if skipConfirm {
+		return true
 	}
 
-	// Clean up orphaned message directories
-	msgMgr := messages.NewManager(c.paths.MessagesDir)
-	for _, repoName := range st.ListRepos() {
-		validAgents, _ := st.ListAgents(repoName)
-		if count, err := msgMgr.CleanupOrphaned(repoName, validAgents); err == nil && count > 0 {
-			if verbose {
-				fmt.Printf("  Cleaned up %d orphaned message dir(s) for %s\n", count, repoName)
-			}
-			issuesFixed += count
-		}
+	fmt.Print("Apply these repairs? [y/N]: ")
+	var response string
+	fmt.Scanln(&response)
+	if response != "y" && response != "Y" {
+		fmt.Println("Repair cancelled")
+		return false
 	}
+	return true
+}
 
-	// Report orphaned tmux sessions
-	if len(orphanedSessions) > 0 {
-		fmt.Printf("\nFound %d orphaned tmux session(s) not in state:\n", len(orphanedSessions))
-		for _, session := range orphanedSessions {
-			fmt.Printf("  - %s\n", session)
+// printRepairResults prints the outcome of each applied repair
+func printRepairResults(issues []repair.Issue) {
+	fmt.Println()
+	failed := 0
+	for _, issue := range issues {
+		switch {
+		case issue.Error != "":
+			fmt.Printf("  ✗ %s/%s: %s\n", issue.Repo, issue.Agent, issue.Error)
+			failed++
+		case issue.Action == repair.ActionDrop:
+			fmt.Printf("  Dropped %s/%s from state\n", issue.Repo, issue.Agent)
+		default:
+			fmt.Printf("  Recreated %s/%s\n", issue.Repo, issue.Agent)
 		}
-		fmt.Println("To remove these, run: tmux kill-session -t <session>")
-		fmt.Println("Or use: multiclaude stop-all")
 	}
 
-	// Save updated state
-	if err := st.Save(); err != nil {
-		return fmt.Errorf("failed to save repaired state: %w", err)
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("⚠ Repair completed: %d of %d issue(s) fixed\n", len(issues)-failed, len(issues))
+	} else {
+		fmt.Printf("✓ Repair completed: %d issue(s) fixed\n", len(issues))
+	}
+	format.Dimmed("Run 'multiclaude cleanup' to remove leftover worktrees, branches and windows")
+}
+
+// localRepair repairs state without the daemon running, e.g. after a host
+// reboot before 'multiclaude start'. Recreated agents are resumed by running
+// 'multiclaude claude' in their new windows.
+func (c *CLI) localRepair(dryRun, skipConfirm bool) error {
+	// Load state from disk
+	st, err := c.loadState()
+	if err != nil {
+		return err
+	}
+
+	// Windows of a multiplexer the daemon hosts went away with it, and the
+	// daemon starts their agents again when it starts
+	if file, err := config.LoadFile(c.paths.ConfigFile()); err == nil && file.Multiplexer == mux.BackendProcess {
+		fmt.Println("Agents run in the daemon's process multiplexer: skipping repair; 'multiclaude start' restarts them")
+		return nil
+	}
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		fmt.Println("tmux is not available: skipping repair")
+		return nil
 	}
 
-	fmt.Println("\n✓ Local repair completed")
-	if agentsRemoved > 0 {
-		fmt.Printf("  Removed %d dead agent(s)\n", agentsRemoved)
+	binaryPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to resolve multiclaude binary: %w", err)
 	}
-	if issuesFixed > 0 {
-		fmt.Printf("  Fixed %d issue(s)\n", issuesFixed)
+
+	repairer := repair.New(c.paths, st, tmuxClient, func(repoName, agentName string, agent state.Agent) error {
+		repo, _ := st.GetRepo(repoName)
+		ctx := context.Background()
+		if err := tmuxClient.SendKeysLiteralWithEnter(ctx, repo.TmuxSession, agent.TmuxWindow, binaryPath+" claude"); err != nil {
+			return fmt.Errorf("failed to start Claude: %w", err)
+		}
+		if pid, err := tmuxClient.GetPanePID(ctx, repo.TmuxSession, agent.TmuxWindow); err == nil {
+			return st.UpdateAgentPID(repoName, agentName, pid)
+		}
+		return nil
+	})
+
+	issues, err := repairer.Plan(context.Background(), nil)
+	if err != nil {
+		return err
 	}
-	if agentsRemoved == 0 && issuesFixed == 0 {
-		fmt.Println("  No issues found")
+	if !confirmRepair(issues, dryRun, skipConfirm) {
+		return nil
 	}
 
+	printRepairResults(repairer.Apply(context.Background(), issues))
 	return nil
 }
 
@@ -4983,38 +10765,48 @@ func (c *CLI) savePromptToFile(agentName, promptText string) (string, error) {
 // getAgentDefinition finds an agent definition by name, copying templates if needed.
 // Returns the prompt content or an error if not found.
 func (c *CLI) getAgentDefinition(repoName, repoPath, agentDefName string) (string, error) {
+	def, err := c.findAgentDefinition(repoName, repoPath, agentDefName)
+	if err != nil {
+		return "", err
+	}
+	return def.Content, nil
+}
+
+// findAgentDefinition is getAgentDefinition returning the whole definition,
+// including where it was read from
+func (c *CLI) findAgentDefinition(repoName, repoPath, agentDefName string) (agents.Definition, error) {
 	localAgentsDir := c.paths.RepoAgentsDir(repoName)
 	reader := agents.NewReader(localAgentsDir, repoPath)
 	definitions, err := reader.ReadAllDefinitions()
 	if err != nil {
-		return "", fmt.Errorf("failed to read agent definitions: %w", err)
+		return agents.Definition{}, fmt.Errorf("failed to read agent definitions: %w", err)
 	}
 
 	// Find the definition
 	for _, def := range definitions {
 		if def.Name == agentDefName {
-			return def.Content, nil
+			return def, nil
 		}
 	}
 
 	// If not found, try to copy from templates and retry
 	if _, err := os.Stat(localAgentsDir); os.IsNotExist(err) {
 		if err := templates.CopyAgentTemplates(localAgentsDir); err != nil {
-			return "", fmt.Errorf("failed to copy agent templates: %w", err)
+			return agents.Definition{}, fmt.Errorf("failed to copy agent templates: %w", err)
 		}
 		// Re-read definitions
 		definitions, err = reader.ReadAllDefinitions()
 		if err != nil {
-			return "", fmt.Errorf("failed to read agent definitions after template copy: %w", err)
+			return agents.Definition{}, fmt.Errorf("failed to read agent definitions after template copy: %w", err)
 		}
 		for _, def := range definitions {
 			if def.Name == agentDefName {
-				return def.Content, nil
+				return def, nil
 			}
 		}
 	}
 
-	return "", fmt.Errorf("no %s agent definition found", agentDefName)
+	return agents.Definition{}, fmt.Errorf("no %s agent definition found", agentDefName)
 }
 
 // appendDocsAndSlashCommands adds CLI documentation and slash commands to prompt text.
@@ -5042,6 +10834,26 @@ func (c *CLI) writePromptFile(repoPath string, agentType state.AgentType, agentN
 	return c.savePromptToFile(agentName, promptText)
 }
 
+// writeReviewerPromptFile writes a reviewer prompt file, adding read-only
+// instructions when the reviewer's worktree is locked down and any review
+// checklists that apply to the PR.
+func (c *CLI) writeReviewerPromptFile(repoPath string, agentName string, readOnly bool, checklists []review.Checklist) (string, error) {
+	promptText, err := prompts.GetPrompt(repoPath, state.AgentTypeReview, c.documentation)
+	if err != nil {
+		return "", fmt.Errorf("failed to get prompt: %w", err)
+	}
+
+	if section := review.Prompt(checklists); section != "" {
+		promptText = section + "\n---\n\n" + promptText
+	}
+
+	if readOnly {
+		promptText = prompts.GenerateReadOnlyPrompt() + "\n\n---\n\n" + promptText
+	}
+
+	return c.savePromptToFile(agentName, promptText)
+}
+
 // writeMergeQueuePromptFile writes a merge-queue prompt file with tracking mode configuration.
 // It reads the merge-queue prompt from agent definitions (configurable agent system).
 func (c *CLI) writeMergeQueuePromptFile(repoPath string, agentName string, mqConfig state.MergeQueueConfig) (string, error) {
@@ -5057,6 +10869,9 @@ func (c *CLI) writeMergeQueuePromptFile(repoPath string, agentName string, mqCon
 
 	// Add tracking mode configuration to the prompt
 	trackingConfig := prompts.GenerateTrackingModePrompt(string(mqConfig.TrackMode))
+	if mqConfig.AutoMerge {
+		trackingConfig += "\n\n" + prompts.GenerateAutoMergePrompt(mqConfig.MergeLabel())
+	}
 	promptText = trackingConfig + "\n\n" + promptText
 
 	return c.savePromptToFile(agentName, promptText)
@@ -5064,7 +10879,9 @@ func (c *CLI) writeMergeQueuePromptFile(repoPath string, agentName string, mqCon
 
 // WorkerConfig holds configuration for creating worker prompts
 type WorkerConfig struct {
-	PushToBranch string // Branch to push to instead of creating a new PR (for iterating on existing PRs)
+	PushToBranch string            // Branch to push to instead of creating a new PR (for iterating on existing PRs)
+	Subproject   *scope.Preset     // Monorepo sub-project the task is scoped to (work --path)
+	Base         *worktree.BaseRef // Branch, tag, or commit the task builds on (work --base)
 }
 
 // writeWorkerPromptFile writes a worker prompt file with optional configuration.
@@ -5101,13 +10918,43 @@ Do NOT create a new PR. The existing PR will be updated automatically when you p
 		promptText = pushToConfig + promptText
 	}
 
+	// Point PRs at the base branch instead of the default branch
+	if config.Base != nil {
+		promptText = basePrompt(*config.Base) + "\n---\n\n" + promptText
+	}
+
+	// Add sub-project scope if specified
+	if config.Subproject != nil {
+		promptText = scope.Prompt(*config.Subproject) + "\n---\n\n" + promptText
+	}
+
 	return c.savePromptToFile(agentName, promptText)
 }
 
+// basePrompt tells a worker which base its task builds on and, for a base
+// branch, that its PR must target that branch
+func basePrompt(base worktree.BaseRef) string {
+	if base.Branch == "" {
+		return fmt.Sprintf(`## Base
+
+Your branch starts from %s (commit %.12s), not from the tip of the default branch.
+Keep your changes on top of it and mention the base in your PR description.
+`, base.Ref, base.Commit)
+	}
+	return fmt.Sprintf(`## Base Branch
+
+**This task builds on the %s branch, not the repository's default branch.**
+
+Your branch starts from origin/%s and is kept rebased onto it. Open your PR against it:
+
+    gh pr create --base %s
+`, base.Branch, base.Branch, base.Branch)
+}
+
 // setupOutputCapture sets up tmux pipe-pane to capture agent output to a log file.
 // It creates the necessary directories and starts the pipe-pane command.
 // The agentType should be "worker" for worker agents, anything else for system agents.
-func (c *CLI) setupOutputCapture(tmuxSession, tmuxWindow, repoName, agentName, agentType string) error {
+func (c *CLI) setupOutputCapture(m mux.Multiplexer, tmuxSession, tmuxWindow, repoName, agentName, agentType string) error {
 	// Determine log file path based on agent type
 	isWorker := agentType == "worker" || agentType == "review"
 	logFile := c.paths.AgentLogFile(repoName, agentName, isWorker)
@@ -5119,29 +10966,36 @@ func (c *CLI) setupOutputCapture(tmuxSession, tmuxWindow, repoName, agentName, a
 	}
 
 	// Set up pipe-pane
-	tmuxClient := tmux.NewClient()
-	if err := tmuxClient.StartPipePane(context.Background(), tmuxSession, tmuxWindow, logFile); err != nil {
+	if err := m.StartPipePane(context.Background(), tmuxSession, tmuxWindow, logFile); err != nil {
 		return fmt.Errorf("failed to start output capture: %w", err)
 	}
 
 	return nil
 }
 
-// startClaudeInTmux starts Claude Code in a tmux window with the given configuration
-// Returns the PID of the Claude process
-func (c *CLI) startClaudeInTmux(binaryPath, tmuxSession, tmuxWindow, workDir, sessionID, promptFile, repoName string, initialMessage string) (int, error) {
+// startClaudeInWindow starts Claude Code in a window of m with the given
+// configuration. Returns the PID of the Claude process.
+func (c *CLI) startClaudeInWindow(m mux.Multiplexer, binaryPath, tmuxSession, tmuxWindow, workDir, sessionID, promptFile, repoName string, agentType state.AgentType, initialMessage string) (int, error) {
+	// Apply the repo's launch template (wrapper, binary, flags) for this agent type
+	template, err := launch.Load(c.paths.RepoDir(repoName), string(agentType))
+	if err != nil {
+		return 0, err
+	}
+	if err := template.Validate(workDir); err != nil {
+		return 0, fmt.Errorf("invalid %s in %s: %w", launch.ConfigFile, repoName, err)
+	}
+
 	// Build Claude command - uses global ~/.claude/ for auth and slash commands are embedded in prompts
-	claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions", binaryPath, sessionID)
+	flags := fmt.Sprintf("--session-id %s --dangerously-skip-permissions", sessionID)
 
 	// Add prompt file if provided
 	if promptFile != "" {
-		claudeCmd += fmt.Sprintf(" --append-system-prompt-file %s", promptFile)
+		flags += fmt.Sprintf(" --append-system-prompt-file %s", promptFile)
 	}
+	claudeCmd := template.Command(binaryPath, workDir, flags)
 
-	// Send command to tmux window
-	target := fmt.Sprintf("%s:%s", tmuxSession, tmuxWindow)
-	cmd := exec.Command("tmux", "send-keys", "-t", target, claudeCmd, "C-m")
-	if err := cmd.Run(); err != nil {
+	// Send command to the window
+	if err := m.SendKeys(context.Background(), tmuxSession, tmuxWindow, claudeCmd); err != nil {
 		return 0, fmt.Errorf("failed to start Claude in tmux: %w", err)
 	}
 
@@ -5149,8 +11003,7 @@ func (c *CLI) startClaudeInTmux(binaryPath, tmuxSession, tmuxWindow, workDir, se
 	time.Sleep(500 * time.Millisecond)
 
 	// Get the PID of the Claude process
-	tmuxClient := tmux.NewClient()
-	pid, err := tmuxClient.GetPanePID(context.Background(), tmuxSession, tmuxWindow)
+	pid, err := m.GetPanePID(context.Background(), tmuxSession, tmuxWindow)
 	if err != nil {
 		// Non-fatal - we'll just not have the PID
 		fmt.Printf("Warning: failed to get Claude PID: %v\n", err)
@@ -5164,7 +11017,7 @@ func (c *CLI) startClaudeInTmux(binaryPath, tmuxSession, tmuxWindow, workDir, se
 
 		// Send message using atomic method to avoid race conditions (issue #63)
 		// The atomic method sends text + Enter in a single exec call
-		if err := tmuxClient.SendKeysLiteralWithEnter(context.Background(), tmuxSession, tmuxWindow, initialMessage); err != nil {
+		if err := m.SendKeysLiteralWithEnter(context.Background(), tmuxSession, tmuxWindow, initialMessage); err != nil {
 			return pid, fmt.Errorf("failed to send initial message to Claude: %w", err)
 		}
 	}
@@ -5211,7 +11064,7 @@ func (c *CLI) bugReport(args []string) error {
 
 // listBranchesWithPrefix returns all local branches with the given prefix
 func (c *CLI) listBranchesWithPrefix(repoPath, prefix string) ([]string, error) {
-	cmd := exec.Command("git", "branch", "--list", prefix+"*")
+	cmd := gitCommand("branch", "--list", prefix+"*")
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
@@ -5231,7 +11084,7 @@ func (c *CLI) listBranchesWithPrefix(repoPath, prefix string) ([]string, error)
 
 // deleteBranch deletes a local git branch
 func (c *CLI) deleteBranch(repoPath, branch string) error {
-	cmd := exec.Command("git", "branch", "-D", branch)
+	cmd := gitCommand("branch", "-D", branch)
 	cmd.Dir = repoPath
 	return cmd.Run()
 }
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,10 +10,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dlorenc/multiclaude/internal/audit"
 	"github.com/dlorenc/multiclaude/internal/daemon"
+	"github.com/dlorenc/multiclaude/internal/fleet"
 	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/snapshot"
 	"github.com/dlorenc/multiclaude/internal/socket"
 	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/telemetry"
 	"github.com/dlorenc/multiclaude/pkg/config"
 	"github.com/dlorenc/multiclaude/pkg/tmux"
 )
@@ -399,6 +404,147 @@ func TestCLIDaemonStatus(t *testing.T) {
 	}
 }
 
+func TestExtractHost(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantRest []string
+		wantHost string
+		wantErr  bool
+	}{
+		{[]string{"list"}, []string{"list"}, "", false},
+		{[]string{"--host", "build-1", "list"}, []string{"list"}, "build-1", false},
+		{[]string{"work", "fix it", "--host=10.0.0.5:7432"}, []string{"work", "fix it"}, "10.0.0.5:7432", false},
+		{[]string{"list", "--host"}, nil, "", true},
+		{[]string{"list", "--host", "--repo"}, nil, "", true},
+	}
+	for _, tt := range tests {
+		rest, host, err := extractHost(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("extractHost(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if host != tt.wantHost || strings.Join(rest, "|") != strings.Join(tt.wantRest, "|") {
+			t.Errorf("extractHost(%q) = %q, %q; want %q, %q", tt.args, rest, host, tt.wantRest, tt.wantHost)
+		}
+	}
+}
+
+func TestCLIRemoteHost(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	yaml := "remote:\n  listen: 127.0.0.1:0\n  token: s3cret\n"
+	if err := os.WriteFile(cli.paths.ConfigFile(), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Execute([]string{"daemon", "reload"}); err != nil {
+		t.Fatalf("daemon reload failed: %v", err)
+	}
+	resp, err := socket.NewClient(cli.paths.DaemonSock).Send(socket.Request{Command: "status"})
+	if err != nil || !resp.Success {
+		t.Fatalf("status = %+v, %v", resp, err)
+	}
+	addr, _ := resp.Data.(map[string]interface{})["remote_listen"].(string)
+	if addr == "" {
+		t.Fatal("daemon isn't listening on TCP after the reload")
+	}
+
+	t.Setenv("MULTICLAUDE_TOKEN", "s3cret")
+	if err := cli.Execute([]string{"list", "--host", "tcp://" + addr}); err != nil {
+		t.Errorf("list --host failed: %v", err)
+	}
+	if err := cli.Execute([]string{"daemon", "status", "--host", "tcp://" + addr}); err != nil {
+		t.Errorf("daemon status --host failed: %v", err)
+	}
+
+	t.Setenv("MULTICLAUDE_TOKEN", "guess")
+	if err := cli.Execute([]string{"list", "--host", "tcp://" + addr}); err == nil {
+		t.Error("list --host with a wrong token should fail")
+	}
+	if err := cli.Execute([]string{"list", "--host", "no-such-host"}); err == nil {
+		t.Error("list --host with an unknown host should fail")
+	}
+}
+
+func TestCLIFleetStatus(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	status, err := cli.fetchFleet("")
+	if err != nil {
+		t.Fatalf("fetchFleet() failed: %v", err)
+	}
+	if len(status.Hosts) != 1 || status.Hosts[0].Host != fleet.LocalHost || status.Hosts[0].Status["running"] != true {
+		t.Errorf("fetchFleet() = %+v, want only this daemon", status)
+	}
+	for _, args := range [][]string{{"status", "--all-hosts"}, {"status", "--all-hosts", "--json"}} {
+		if err := cli.Execute(args); err != nil {
+			t.Errorf("%v failed: %v", args, err)
+		}
+	}
+}
+
+func TestCLIAudit(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := &state.Repository{TmuxSession: "mc-test-repo", Agents: make(map[string]state.Agent)}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := cli.Execute([]string{"task", "add", "Write docs", "--repo", "test-repo"}); err != nil {
+		t.Fatalf("task add failed: %v", err)
+	}
+
+	entries, err := socket.Call[[]audit.Entry](cli.daemonClient(), "list_audit", socket.AuditLogArgs{Repo: "test-repo"})
+	if err != nil {
+		t.Fatalf("list_audit failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != audit.ActionTaskQueued || entries[0].Origin != "task add" {
+		t.Errorf("audit log = %+v, want the queued task sent by task add", entries)
+	}
+
+	for _, args := range [][]string{{"audit"}, {"audit", "--repo", "test-repo", "--since", "1h", "--json"}, {"audit", "--limit", "1"}} {
+		if err := cli.Execute(args); err != nil {
+			t.Errorf("%v failed: %v", args, err)
+		}
+	}
+	if err := cli.Execute([]string{"audit", "--since", "soon"}); err == nil {
+		t.Error("audit --since soon should fail")
+	}
+}
+
+func TestCLIRepairFromBackup(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := cli.Execute([]string{"repair", "--from-backup", "--yes"}); err == nil {
+		t.Error("repair --from-backup should fail without backups")
+	}
+	for _, name := range []string{"kept", "lost"} {
+		if err := d.GetState().AddRepo(name, &state.Repository{TmuxSession: "mc-" + name, Agents: make(map[string]state.Agent)}); err != nil {
+			t.Fatalf("Failed to add repo: %v", err)
+		}
+	}
+
+	if err := cli.Execute([]string{"repair", "--from-backup", "--dry-run"}); err != nil {
+		t.Fatalf("repair --from-backup --dry-run failed: %v", err)
+	}
+	if len(d.GetState().ListRepos()) != 2 {
+		t.Error("a dry run shouldn't restore the backup")
+	}
+	if err := cli.Execute([]string{"repair", "--from-backup", "9", "--yes"}); err == nil {
+		t.Error("repair --from-backup 9 should fail")
+	}
+
+	if err := cli.Execute([]string{"repair", "--from-backup", "1", "--yes"}); err != nil {
+		t.Fatalf("repair --from-backup 1 failed: %v", err)
+	}
+	if repos := d.GetState().ListRepos(); len(repos) != 1 || repos[0] != "kept" {
+		t.Errorf("repos after restore = %v, want only kept", repos)
+	}
+}
+
 func TestCLIWorkListEmpty(t *testing.T) {
 	cli, d, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -953,6 +1099,80 @@ func TestCLIWorkCreateWithRealTmux(t *testing.T) {
 	}
 }
 
+func TestCLIWorkRerunReplaysSnapshot(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	paths := d.GetPaths()
+	repoName := "rerun-repo"
+	repoPath := paths.RepoDir(repoName)
+	setupTestRepo(t, repoPath)
+
+	tmuxSession := "mc-rerun-repo"
+	if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), tmuxSession)
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: tmuxSession,
+		Agents:      make(map[string]state.Agent),
+	}
+	if err := d.GetState().AddRepo(repoName, repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if err := cli.Execute([]string{"work", "Fix the flaky test", "--name", "first", "--repo", repoName, "--label", "ci"}); err != nil {
+		t.Fatalf("work create failed: %v", err)
+	}
+	snap, prompt, err := snapshot.Load(paths.SnapshotDir(repoName, "first"))
+	if err != nil {
+		t.Fatalf("worker spawn was not snapshotted: %v", err)
+	}
+	if snap.Task != "Fix the flaky test" || snap.Flags["label"] != "ci" || snap.BaseCommit == "" || snap.Definition.SHA256 == "" {
+		t.Errorf("unexpected snapshot: %+v", snap)
+	}
+	first, _ := d.GetState().GetAgent(repoName, "first")
+	if first.PromptSHA256 != snap.PromptSHA256 {
+		t.Errorf("agent prompt hash = %q, want %q", first.PromptSHA256, snap.PromptSHA256)
+	}
+
+	// Move main on; the rerun must still start from the original commit
+	cmd := exec.Command("git", "commit", "--allow-empty", "-m", "later")
+	cmd.Dir = repoPath
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	if err := cli.Execute([]string{"work", "rerun", "first", "--name", "second", "--repo", repoName}); err != nil {
+		t.Fatalf("work rerun failed: %v", err)
+	}
+	second, exists := d.GetState().GetAgent(repoName, "second")
+	if !exists {
+		t.Fatal("rerun worker should exist in state")
+	}
+	if second.Task != first.Task || second.PromptSHA256 != first.PromptSHA256 || second.Labels[0] != "ci" {
+		t.Errorf("rerun worker = %+v, want the task, prompt, and labels of %+v", second, first)
+	}
+	replayed, replayedPrompt, err := snapshot.Load(paths.SnapshotDir(repoName, "second"))
+	if err != nil {
+		t.Fatalf("rerun was not snapshotted: %v", err)
+	}
+	if replayedPrompt != prompt || replayed.BaseCommit != snap.BaseCommit {
+		t.Errorf("rerun base = %s, want %s (prompt identical: %v)", replayed.BaseCommit, snap.BaseCommit, replayedPrompt == prompt)
+	}
+
+	if err := cli.Execute([]string{"work", "rerun", "nonexistent", "--repo", repoName}); err == nil {
+		t.Error("rerun of a worker without a snapshot should fail")
+	}
+}
+
 func TestCLICleanupCommand(t *testing.T) {
 	cli, _, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -1031,6 +1251,58 @@ func TestCLIUnknownCommand(t *testing.T) {
 	}
 }
 
+func TestCommandPath(t *testing.T) {
+	cli := NewWithPaths(config.NewTestPaths(t.TempDir()))
+	tests := map[string][]string{
+		"work list":  {"work", "list", "--repo", "secret-repo"},
+		"work":       {"work", "Fix the login bug"},
+		"agent pull": {"agent", "pull"},
+		"(unknown)":  {"not-a-command"},
+	}
+	for want, args := range tests {
+		if got := cli.commandPath(args); got != want {
+			t.Errorf("commandPath(%v) = %q, want %q", args, got, want)
+		}
+	}
+}
+
+func TestExecuteRecordsTelemetry(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	cli := NewWithPaths(paths)
+	recorder := telemetry.NewRecorder(paths.TelemetryDir())
+
+	if err := cli.Execute([]string{"version"}); err != nil {
+		t.Fatalf("version failed: %v", err)
+	}
+	if samples, _ := recorder.Load(); len(samples) != 0 {
+		t.Fatalf("telemetry is opt-in, got %v", samples)
+	}
+
+	if err := cli.Execute([]string{"telemetry", "enable"}); err != nil {
+		t.Fatalf("telemetry enable failed: %v", err)
+	}
+	cli.Execute([]string{"version"})
+	cli.Execute([]string{"list"}) // No daemon running
+
+	samples, err := recorder.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	byName := make(map[string]telemetry.Sample)
+	for _, s := range samples {
+		byName[string(s.Kind)+":"+s.Name] = s
+	}
+	if s, ok := byName["command:version"]; !ok || s.Failed {
+		t.Errorf("expected a successful version sample, got %+v", samples)
+	}
+	if s, ok := byName["socket:list_repos"]; !ok || !s.Failed {
+		t.Errorf("expected a failed list_repos roundtrip, got %+v", samples)
+	}
+	if socket.RoundTripObserver != nil {
+		t.Error("Execute should unset the socket observer when done")
+	}
+}
+
 func TestNewWithPaths(t *testing.T) {
 	tmpDir := t.TempDir()
 	paths := &config.Paths{
@@ -1786,6 +2058,36 @@ func TestCLIConfigRepoUpdateViaSocket(t *testing.T) {
 	}
 }
 
+func TestCLIConfigSet(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:        "https://github.com/test/repo",
+		TmuxSession:      "mc-test-repo",
+		Agents:           make(map[string]state.Agent),
+		MergeQueueConfig: state.DefaultMergeQueueConfig(),
+	}
+	if err := d.GetState().AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if err := cli.Execute([]string{"config", "set", "max-workers", "3", "--repo", "test-repo"}); err != nil {
+		t.Fatalf("config set max-workers failed: %v", err)
+	}
+	updatedRepo, _ := d.GetState().GetRepo("test-repo")
+	if updatedRepo.MaxWorkers != 3 {
+		t.Errorf("MaxWorkers = %d, want 3", updatedRepo.MaxWorkers)
+	}
+
+	if err := cli.Execute([]string{"config", "set", "no-such-setting", "3", "--repo", "test-repo"}); err == nil {
+		t.Error("config set should reject an unknown setting")
+	}
+	if err := cli.Execute([]string{"config", "set", "max-workers", "--repo", "test-repo"}); err == nil {
+		t.Error("config set should require a value")
+	}
+}
+
 func TestCLIConfigRepoNonexistent(t *testing.T) {
 	cli, _, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -1955,6 +2257,80 @@ func TestCLIReviewInvalidURL(t *testing.T) {
 	}
 }
 
+func TestCLIStatusDashboard(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	for _, name := range []string{"api", "web"} {
+		repo := &state.Repository{
+			GithubURL:   "https://github.com/test/" + name,
+			TmuxSession: "mc-" + name,
+			Groups:      []string{"payments"},
+			Agents:      make(map[string]state.Agent),
+		}
+		if name == "web" {
+			repo.Groups = nil
+		}
+		if err := d.GetState().AddRepo(name, repo); err != nil {
+			t.Fatalf("Failed to add repo: %v", err)
+		}
+	}
+	worktreePath := filepath.Join(cli.paths.WorktreesDir, "api", "swift-fox")
+	setupTestRepo(t, worktreePath)
+	if err := d.GetState().AddAgent("api", "swift-fox", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: worktreePath,
+		TmuxWindow:   "swift-fox",
+		Task:         "Add retries\nwith backoff",
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+	if _, err := messages.NewManager(cli.paths.MessagesDir).Send("api", "supervisor", "swift-fox", "status?"); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	dash, err := cli.gatherStatus("payments")
+	if err != nil {
+		t.Fatalf("gatherStatus failed: %v", err)
+	}
+	if running, _ := dash.Daemon["running"].(bool); !running {
+		t.Errorf("daemon should be reported running: %v", dash.Daemon)
+	}
+	if len(dash.Repos) != 1 || dash.Repos[0].Name != "api" || len(dash.Repos[0].Agents) != 1 {
+		t.Fatalf("group payments should show only api and its worker: %+v", dash.Repos)
+	}
+	agent := dash.Repos[0].Agents[0]
+	if agent["name"] != "swift-fox" || agent["messages_pending"] != float64(1) {
+		t.Errorf("unexpected agent %v", agent)
+	}
+	if _, ok := agent["git"].(map[string]interface{}); !ok {
+		t.Errorf("worker should carry its git state: %v", agent)
+	}
+
+	for _, args := range [][]string{{"status"}, {"status", "--json"}, {"status", "--group", "payments"}} {
+		if err := cli.Execute(args); err != nil {
+			t.Errorf("%v failed: %v", args, err)
+		}
+	}
+	if err := cli.Execute([]string{"status", "--watch=10ms"}); err == nil {
+		t.Error("status should reject a watch interval under a second")
+	}
+}
+
+func TestCLIStatusDaemonNotRunning(t *testing.T) {
+	tmpDir := t.TempDir()
+	cli := NewWithPaths(&config.Paths{Root: tmpDir, DaemonSock: filepath.Join(tmpDir, "daemon.sock")})
+
+	dash, err := cli.gatherStatus("")
+	if err != nil {
+		t.Fatalf("gatherStatus should report a stopped daemon, not fail: %v", err)
+	}
+	if running, _ := dash.Daemon["running"].(bool); running || len(dash.Repos) != 0 {
+		t.Errorf("unexpected dashboard %+v", dash)
+	}
+}
+
 func TestCLIGetReposList(t *testing.T) {
 	cli, d, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -2178,27 +2554,24 @@ func TestInitRepoNameParsing(t *testing.T) {
 	}
 }
 
-func TestSanitizeTmuxSessionName(t *testing.T) {
+func TestBuildAttachArgs(t *testing.T) {
 	tests := []struct {
-		repoName string
+		name     string
+		readOnly bool
+		control  bool
 		want     string
 	}{
-		{"my-repo", "mc-my-repo"},
-		{"demos.expanso.io", "mc-demos-expanso-io"},
-		{"repo.with.many.dots", "mc-repo-with-many-dots"},
-		{"repo:with:colons", "mc-repo-with-colons"},
-		{"repo with spaces", "mc-repo-with-spaces"},
-		{"simple", "mc-simple"},
-		{"repo/with/slashes", "mc-repo-with-slashes"},
-		{"path/to/repo.git", "mc-path-to-repo-git"},
-		{"repo\x00with\x1fnull", "mc-repowithnull"}, // control characters stripped
+		{"plain", false, false, "attach -t mc-repo:worker"},
+		{"read-only", true, false, "attach -t mc-repo:worker -r"},
+		{"control mode", false, true, "-CC attach -t mc-repo:worker"},
+		{"control read-only", true, true, "-CC attach -t mc-repo:worker -r"},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.repoName, func(t *testing.T) {
-			got := sanitizeTmuxSessionName(tt.repoName)
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(buildAttachArgs("mc-repo:worker", tt.readOnly, tt.control), " ")
 			if got != tt.want {
-				t.Errorf("sanitizeTmuxSessionName(%q) = %q, want %q", tt.repoName, got, tt.want)
+				t.Errorf("buildAttachArgs() = %q, want %q", got, tt.want)
 			}
 		})
 	}
@@ -3589,3 +3962,269 @@ func TestLoadStateFunction(t *testing.T) {
 		}
 	})
 }
+
+func TestLookupAgentWorktree(t *testing.T) {
+	cli, d, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repoName := "path-repo"
+	if err := d.GetState().AddRepo(repoName, &state.Repository{
+		GithubURL:   "https://github.com/test/path-repo",
+		TmuxSession: "mc-path-repo",
+		Agents:      make(map[string]state.Agent),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+	if err := d.GetState().AddAgent(repoName, "worker-1", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: "/tmp/somewhere/worker-1",
+		TmuxWindow:   "worker-1",
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to add agent: %v", err)
+	}
+
+	got, err := cli.lookupAgentWorktree(repoName, "worker-1")
+	if err != nil || got != "/tmp/somewhere/worker-1" {
+		t.Errorf("lookupAgentWorktree(worker-1) = %q, %v", got, err)
+	}
+
+	// Unregistered agents fall back to an existing worktree directory
+	fallback := cli.paths.AgentWorktree(repoName, "old-worker")
+	if err := os.MkdirAll(fallback, 0755); err != nil {
+		t.Fatal(err)
+	}
+	got, err = cli.lookupAgentWorktree(repoName, "old-worker")
+	if err != nil || got != fallback {
+		t.Errorf("lookupAgentWorktree(old-worker) = %q, %v; want %q", got, err, fallback)
+	}
+
+	if _, err := cli.lookupAgentWorktree(repoName, "ghost"); err == nil {
+		t.Error("expected error for unknown agent")
+	}
+}
+
+func TestShellInit(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		script, ok := shellInitScripts[shell]
+		if !ok || !strings.Contains(script, "mcd") || !strings.Contains(script, "multiclaude path") {
+			t.Errorf("shell-init script for %s is missing the mcd helper", shell)
+		}
+	}
+
+	c := &CLI{}
+	if err := c.shellInit([]string{"powershell"}); err == nil {
+		t.Error("expected error for unsupported shell")
+	}
+}
+
+func TestParseTimeBudget(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"2h", 2 * time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"1d", 24 * time.Hour, false},
+		{"0m", 0, true},
+		{"-1h", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseTimeBudget(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTimeBudget(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseTimeBudget(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLIConfigValidate(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	path := cli.paths.ConfigFile()
+
+	// No file is fine: the built-in defaults apply
+	if err := cli.Execute([]string{"config", "validate"}); err != nil {
+		t.Errorf("validate without a config file failed: %v", err)
+	}
+
+	os.WriteFile(path, []byte("defaults:\n  branch_prefix: mc/\n  max_workers: 3\nrepos:\n  untracked:\n    max_workers: 1\n"), 0600)
+	if err := cli.Execute([]string{"config", "validate"}); err != nil {
+		t.Errorf("validate of a valid file failed: %v", err)
+	}
+
+	for content, want := range map[string]string{
+		"defaults:\n  max_workers: lots\n": "line 2",
+		"notifications:\n  webhook:\n    url: https://x.test\n    secret: s\n    headers:\n      Content-Type: text/plain\n": "Content-Type",
+	} {
+		os.WriteFile(path, []byte(content), 0600)
+		if err := cli.Execute([]string{"config", "validate"}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validate of %q error = %v, want one mentioning %s", content, err, want)
+		}
+		// The daemon refuses to start with the same file
+		if _, err := loadConfigFile(path); err == nil {
+			t.Errorf("loadConfigFile(%q) should fail", content)
+		}
+	}
+
+	if err := cli.Execute([]string{"config", "validate", "--file", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("validate --file with a missing file should fail")
+	}
+}
+
+func TestCLIDaemonReload(t *testing.T) {
+	cli, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	if err := cli.Execute([]string{"daemon", "reload"}); err != nil {
+		t.Errorf("reload without a config file failed: %v", err)
+	}
+
+	os.WriteFile(cli.paths.ConfigFile(), []byte("defaults:\n  branch_prefix: mc/\n"), 0600)
+	if err := cli.Execute([]string{"daemon", "reload"}); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+
+	// An invalid file is rejected and the daemon keeps running
+	os.WriteFile(cli.paths.ConfigFile(), []byte("defaults:\n  branch_prefix: mc\n"), 0600)
+	if err := cli.Execute([]string{"daemon", "reload"}); err == nil || !strings.Contains(err.Error(), "branch_prefix") {
+		t.Errorf("reload of an invalid file error = %v, want one mentioning branch_prefix", err)
+	}
+	if _, err := cli.sendDaemonRequest("ping", nil); err != nil {
+		t.Errorf("daemon stopped answering after a failed reload: %v", err)
+	}
+}
+
+func TestGrepAgentLog(t *testing.T) {
+	paths := config.NewTestPaths(t.TempDir())
+	cli := NewWithPaths(paths)
+
+	logFile := paths.AgentLogFile("repo", "fox", true)
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rotated := logFile + ".20260101-120000"
+	if err := os.WriteFile(rotated, []byte("go test ./...\r\n\x1b[31mFAIL\x1b[0m auth_test.go\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logFile, []byte("go test ./...\r\nok   auth\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	capture := func(fn func() error) string {
+		t.Helper()
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stdout := os.Stdout
+		os.Stdout = w
+		err = fn()
+		os.Stdout = stdout
+		w.Close()
+		out, _ := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("logs failed: %v", err)
+		}
+		return string(out)
+	}
+
+	out := capture(func() error { return cli.viewLogs([]string{"fox", "--repo", "repo", "--grep", "^(FAIL|ok) "}) })
+	if out != "FAIL auth_test.go\nok   auth\n" {
+		t.Errorf("grep output = %q, want the rotated match then the live one without escapes", out)
+	}
+
+	// The window's gone, but its rotated transcript is still searchable
+	if err := os.Remove(logFile); err != nil {
+		t.Fatal(err)
+	}
+	out = capture(func() error { return cli.viewLogs([]string{"fox", "--repo", "repo", "--grep", "go test"}) })
+	if out != "go test ./...\n" {
+		t.Errorf("grep output after the live log is gone = %q", out)
+	}
+	if err := cli.viewLogs([]string{"nobody", "--repo", "repo", "--grep", "x"}); err == nil {
+		t.Error("grep for an agent without logs should fail")
+	}
+}
+
+func TestOverviewPanes(t *testing.T) {
+	agents := []interface{}{
+		map[string]interface{}{"name": "old", "type": "worker", "tmux_window": "old", "created_at": "2026-01-01T10:00:00Z"},
+		map[string]interface{}{"name": "mq", "type": "merge-queue", "tmux_window": "merge-queue"},
+		map[string]interface{}{"name": "new", "type": "worker", "tmux_window": "new", "created_at": "2026-01-03T10:00:00Z"},
+		map[string]interface{}{"name": "ws", "type": "workspace", "tmux_window": "workspace"},
+		map[string]interface{}{"name": "mid", "type": "worker", "tmux_window": "mid", "created_at": "2026-01-02T10:00:00Z"},
+		map[string]interface{}{"name": "supervisor", "type": "supervisor", "tmux_window": "supervisor"},
+	}
+
+	var got []string
+	for _, pane := range overviewPanes(agents, 2) {
+		got = append(got, pane.Name)
+	}
+	if want := "supervisor mq new mid"; strings.Join(got, " ") != want {
+		t.Errorf("overviewPanes() = %v, want %s", got, want)
+	}
+	if panes := overviewPanes(agents, 0); len(panes) != 2 {
+		t.Errorf("overviewPanes with no workers = %v", panes)
+	}
+}
+
+func TestBuildOverviewWithRealTmux(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+	ctx := context.Background()
+	session := "mc-test-overview"
+	if err := tmuxClient.CreateSession(ctx, session, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, session)
+	for _, window := range []string{"supervisor", "worker-1"} {
+		if err := tmuxClient.CreateWindow(ctx, session, window); err != nil {
+			t.Fatalf("Failed to create window: %v", err)
+		}
+	}
+	if err := tmuxClient.SendKeysLiteral(ctx, session, "worker-1", "shown-in-overview"); err != nil {
+		t.Fatal(err)
+	}
+
+	panes := []overviewPane{{Name: "supervisor", Window: "supervisor"}, {Name: "worker-1", Window: "worker-1"}}
+	// Building twice replaces the window instead of adding a second one
+	for i := 0; i < 2; i++ {
+		if err := buildOverview(ctx, tmuxClient, session, t.TempDir(), panes); err != nil {
+			t.Fatalf("buildOverview failed: %v", err)
+		}
+	}
+	windows, _ := tmuxClient.ListWindows(ctx, session)
+	if count := strings.Count(strings.Join(windows, " "), overviewWindow); count != 1 {
+		t.Errorf("windows = %v, want one overview", windows)
+	}
+	if keep, _ := tmuxClient.GetWindowOption(ctx, session, overviewWindow, "@multiclaude-keep"); keep != "on" {
+		t.Errorf("overview window keep option = %q, want on", keep)
+	}
+
+	got, err := tmuxClient.ListPanes(ctx, session, overviewWindow)
+	if err != nil || len(got) != 2 {
+		t.Fatalf("ListPanes = %v, %v, want 2 panes", got, err)
+	}
+	if got[0].Title != "supervisor" || got[1].Title != "worker-1" {
+		t.Errorf("pane titles = %q, %q", got[0].Title, got[1].Title)
+	}
+
+	var mirrored string
+	for i := 0; i < 20 && !strings.Contains(mirrored, "shown-in-overview"); i++ {
+		time.Sleep(250 * time.Millisecond)
+		out, _ := exec.Command("tmux", "capture-pane", "-p", "-t", got[1].ID).Output()
+		mirrored = string(out)
+	}
+	if !strings.Contains(mirrored, "shown-in-overview") {
+		t.Errorf("worker pane shows %q, want the worker's screen", mirrored)
+	}
+}
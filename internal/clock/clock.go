@@ -0,0 +1,216 @@
+// Package clock abstracts time for the daemon's scheduling so tests and
+// simulation runs can control it. Production code uses Real; tests use Fake
+// to advance time deterministically, and simulation runs use Scaled to run
+// time faster than the wall clock.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and creates timers
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// NewTicker returns a ticker that fires every d
+	NewTicker(d time.Duration) Ticker
+	// After returns a channel that receives the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// Ticker delivers ticks at an interval until stopped
+type Ticker interface {
+	// C returns the channel ticks are delivered on
+	C() <-chan time.Time
+	// Stop turns the ticker off
+	Stop()
+}
+
+// Real returns a clock backed by the time package
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// Scaled returns a clock that runs speedup times faster than the wall
+// clock, starting from the current time. A speedup of 60 turns the
+// daemon's two-minute health check into a two-second one.
+func Scaled(speedup float64) Clock {
+	if speedup <= 0 {
+		speedup = 1
+	}
+	return &scaledClock{start: time.Now(), speedup: speedup}
+}
+
+type scaledClock struct {
+	start   time.Time
+	speedup float64
+}
+
+func (c *scaledClock) Now() time.Time {
+	elapsed := time.Since(c.start)
+	return c.start.Add(time.Duration(float64(elapsed) * c.speedup))
+}
+
+func (c *scaledClock) After(d time.Duration) <-chan time.Time {
+	return time.After(c.scale(d))
+}
+
+func (c *scaledClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(c.scale(d))}
+}
+
+// scale converts a simulated duration to wall time, never below a
+// millisecond so tickers stay valid
+func (c *scaledClock) scale(d time.Duration) time.Duration {
+	scaled := time.Duration(float64(d) / c.speedup)
+	if scaled < time.Millisecond {
+		scaled = time.Millisecond
+	}
+	return scaled
+}
+
+// Fake is a clock that only moves when told to. Tickers and timers fire
+// during Advance, in time order, so a test controls exactly which
+// scheduled work has come due.
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending timer, or a ticker when period is non-zero
+type fakeWaiter struct {
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewFake creates a fake clock set to start
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake's current time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel that receives the time once the clock has been
+// advanced by d
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.addWaiter(d, 0).ch
+}
+
+// NewTicker returns a ticker that fires each time the clock passes another
+// multiple of d. Like time.Ticker, ticks are dropped if the receiver falls
+// behind.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return &fakeTicker{clock: f, waiter: f.addWaiter(d, d)}
+}
+
+func (f *Fake) addWaiter(d, period time.Duration) *fakeWaiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{at: f.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+	if d <= 0 {
+		f.fireDue(f.now)
+	}
+	return w
+}
+
+// Advance moves the clock forward by d, firing every timer and tick that
+// comes due along the way
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fireDue(f.now.Add(d))
+}
+
+// Set moves the clock to t, firing everything due by then. Setting it
+// backwards only changes what Now returns.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t.Before(f.now) {
+		f.now = t
+		return
+	}
+	f.fireDue(t)
+}
+
+// BlockUntil waits until at least n timers and tickers are pending, so a
+// test can be sure a goroutine is waiting on the clock before advancing it
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// fireDue delivers everything due by target in time order, then sets the
+// clock to target. The caller holds f.mu.
+func (f *Fake) fireDue(target time.Time) {
+	for {
+		sort.SliceStable(f.waiters, func(i, j int) bool { return f.waiters[i].at.Before(f.waiters[j].at) })
+		if len(f.waiters) == 0 || f.waiters[0].at.After(target) {
+			break
+		}
+		w := f.waiters[0]
+		f.now = w.at
+		select {
+		case w.ch <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.waiters = f.waiters[1:]
+		}
+	}
+	f.now = target
+}
+
+// remove stops a waiter from firing
+func (f *Fake) remove(w *fakeWaiter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, other := range f.waiters {
+		if other == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.waiter.ch }
+func (t *fakeTicker) Stop()               { t.clock.remove(t.waiter) }
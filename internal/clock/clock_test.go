@@ -0,0 +1,109 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeAfter(t *testing.T) {
+	f := NewFake(epoch)
+	ch := f.After(time.Minute)
+
+	f.Advance(59 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	f.Advance(time.Second)
+	select {
+	case got := <-ch:
+		if !got.Equal(epoch.Add(time.Minute)) {
+			t.Errorf("fired at %v, want %v", got, epoch.Add(time.Minute))
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+	if !f.Now().Equal(epoch.Add(time.Minute)) {
+		t.Errorf("Now() = %v", f.Now())
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	f := NewFake(epoch)
+	ticker := f.NewTicker(2 * time.Minute)
+
+	var ticks []time.Time
+	for i := 0; i < 3; i++ {
+		f.Advance(2 * time.Minute)
+		ticks = append(ticks, <-ticker.C())
+	}
+	for i, tick := range ticks {
+		if want := epoch.Add(time.Duration(i+1) * 2 * time.Minute); !tick.Equal(want) {
+			t.Errorf("tick %d at %v, want %v", i, tick, want)
+		}
+	}
+
+	// A receiver that falls behind gets one tick, like time.Ticker
+	f.Advance(10 * time.Minute)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Error("missed ticks were queued")
+	default:
+	}
+
+	ticker.Stop()
+	f.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Error("stopped ticker fired")
+	default:
+	}
+}
+
+func TestFakeFiresInOrder(t *testing.T) {
+	f := NewFake(epoch)
+	late := f.After(3 * time.Minute)
+	early := f.After(time.Minute)
+
+	f.Advance(5 * time.Minute)
+	if got := <-early; !got.Equal(epoch.Add(time.Minute)) {
+		t.Errorf("early fired at %v", got)
+	}
+	if got := <-late; !got.Equal(epoch.Add(3 * time.Minute)) {
+		t.Errorf("late fired at %v", got)
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	f := NewFake(epoch)
+	done := make(chan time.Time)
+	go func() {
+		done <- <-f.After(time.Second)
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(time.Second)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("goroutine never saw the timer fire")
+	}
+}
+
+func TestScaled(t *testing.T) {
+	c := Scaled(1000)
+	start := c.Now()
+	select {
+	case <-c.After(time.Minute):
+	case <-time.After(5 * time.Second):
+		t.Fatal("a scaled minute took more than 5s")
+	}
+	if elapsed := c.Now().Sub(start); elapsed < time.Minute {
+		t.Errorf("scaled clock advanced %v, want at least a minute", elapsed)
+	}
+}
@@ -0,0 +1,310 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// repoPermission is the repository permission a socket command needs, and
+// the argument that names the repository
+type repoPermission struct {
+	perm    state.Permission
+	repoArg string
+}
+
+// commandPermissions lists the socket commands limited by a repository's
+// access policy. Every registered command is here, in ownerOnlyCommands or
+// in openCommands.
+var commandPermissions = map[string]repoPermission{
+	"add_agent":               {state.PermSpawn, "repo"},
+	"spawn_agent":             {state.PermSpawn, "repo"},
+	"claim_warm_worktree":     {state.PermSpawn, "repo"},
+	"handoff_agent":           {state.PermSpawn, "repo"},
+	"restart_agent":           {state.PermSpawn, "repo"},
+	"recover_agent":           {state.PermSpawn, "repo"},
+	"add_scratch_worktree":    {state.PermSpawn, "repo"},
+	"add_task":                {state.PermSpawn, "repo"},
+	"cancel_task":             {state.PermSpawn, "repo"},
+	"respond_agent":           {state.PermSpawn, "repo"},
+	"issue_response_id":       {state.PermSpawn, "repo"},
+	"ask_question":            {state.PermSpawn, "repo"},
+	"broadcast_question":      {state.PermSpawn, "repo"},
+	"broadcast_reply":         {state.PermSpawn, "repo"},
+	"pull_agent_branch":       {state.PermSpawn, "repo"},
+	"remove_agent":            {state.PermRemove, "repo"},
+	"complete_agent":          {state.PermRemove, "repo"},
+	"remove_scratch_worktree": {state.PermRemove, "repo"},
+	"remove_repo":             {state.PermRemove, "name"},
+	"merge_queue_event":       {state.PermMerge, "repo"},
+	"resolve_conflict":        {state.PermAdmin, "repo"},
+	"update_repo_config":      {state.PermAdmin, "name"},
+	"trigger_cleanup":         {state.PermAdmin, "repo"},
+	"resume_refresh":          {state.PermAdmin, "repo"},
+	"add_auto_answer":         {state.PermAdmin, "repo"},
+	"remove_auto_answer":      {state.PermAdmin, "repo"},
+}
+
+// everyRepoCommands act on every repository when the request names none, so
+// the caller needs the command's permission on each of them
+var everyRepoCommands = map[string]bool{
+	"trigger_cleanup": true,
+}
+
+// ownerOnlyCommands affect every user of a shared daemon, so only the
+// daemon's own user may send them
+var ownerOnlyCommands = map[string]bool{
+	"stop":               true,
+	"add_repo":           true,
+	"set_current_repo":   true,
+	"clear_current_repo": true,
+	"set_socket_group":   true,
+	"set_log_storage":    true,
+	"reload_config":      true,
+	"repair_state":       true,
+	"restore_state":      true,
+}
+
+// openCommands lists the socket commands anyone who can reach the socket may
+// send, and why that is safe
+var openCommands = map[string]string{
+	"ping":                   "read",
+	"status":                 "read",
+	"list_repos":             "read",
+	"fleet_status":           "read",
+	"whoami":                 "read",
+	"get_repo_config":        "read",
+	"get_current_repo":       "read",
+	"repo_lock":              "read; take_over is owner-only (see ownerOnly)",
+	"list_agents":            "read",
+	"worker_status":          "read",
+	"agent_screen":           "read",
+	"agent_heartbeat":        "bookkeeping: only refreshes when an agent was last seen",
+	"route_messages":         "only delivers messages already queued",
+	"check_branch_guard":     "read",
+	"check_review_checklist": "read",
+	"list_scratch_worktrees": "read",
+	"get_feed":               "read",
+	"list_auto_answers":      "read",
+	"broadcast_status":       "read",
+	"check_worker_capacity":  "read",
+	"list_tasks":             "read",
+	"task_history":           "read",
+	"list_events":            "read",
+	"timeline":               "read",
+	"export_metrics":         "read",
+	"event_schema":           "read",
+	"merge_queue_stats":      "read",
+	"merge_queue_simulate":   "read",
+	"list_audit":             "read",
+}
+
+// ownerOnly returns true if only the daemon's user may send req
+func ownerOnly(req socket.Request) bool {
+	if ownerOnlyCommands[req.Command] {
+		return true
+	}
+	// Taking over a repository's lock takes it from another host's daemon;
+	// asking who holds it is open to everyone
+	takeOver, _ := req.Args["take_over"].(bool)
+	return req.Command == "repo_lock" && takeOver
+}
+
+// accessArgs are the update_repo_config arguments that change the access
+// policy itself
+var accessArgs = map[string]state.Permission{
+	"access_spawn":  state.PermSpawn,
+	"access_remove": state.PermRemove,
+	"access_merge":  state.PermMerge,
+	"access_admin":  state.PermAdmin,
+}
+
+// isDaemonOwner returns true if peer runs as the daemon's user (or root),
+// or is a remote caller on the TCP socket
+func isDaemonOwner(peer *socket.Peer) bool {
+	if peer != nil && peer.Remote != "" {
+		// Remote callers proved they hold the daemon's token
+		return true
+	}
+	return peer != nil && (peer.UID == os.Getuid() || peer.UID == 0)
+}
+
+// peerGroups returns the names of the Unix groups peer belongs to
+func peerGroups(peer *socket.Peer) []string {
+	u, err := user.LookupId(strconv.Itoa(peer.UID))
+	if err != nil {
+		return nil
+	}
+	ids, err := u.GroupIds()
+	if err != nil {
+		return nil
+	}
+	var groups []string
+	for _, id := range ids {
+		if g, err := user.LookupGroupId(id); err == nil {
+			groups = append(groups, g.Name)
+		}
+	}
+	return groups
+}
+
+// allows checks peer against one permission of a policy
+func allows(policy state.AccessPolicy, perm state.Permission, peer *socket.Peer) bool {
+	var groups []string
+	for _, member := range policy.Members(perm) {
+		if strings.HasPrefix(member, "@") {
+			groups = peerGroups(peer)
+			break
+		}
+	}
+	return policy.Allows(perm, peer.User, groups)
+}
+
+// authorize checks a request against the daemon's owner-only commands and
+// the target repository's access policy. When the caller cannot be
+// identified, only a private socket (reachable by the daemon's user alone)
+// lets the request through.
+func (d *Daemon) authorize(req socket.Request) (socket.Response, bool) {
+	peer := req.Peer
+	if isDaemonOwner(peer) {
+		return socket.Response{}, true
+	}
+	if peer == nil {
+		if d.state.GetSocketGroup() == "" {
+			return socket.Response{}, true
+		}
+		if ownerOnly(req) || commandPermissions[req.Command].perm != "" {
+			return d.deny(req, "the daemon could not identify the caller")
+		}
+		return socket.Response{}, true
+	}
+
+	if ownerOnly(req) {
+		return d.deny(req, fmt.Sprintf("only the daemon's user may run %s", req.Command))
+	}
+
+	rp, limited := commandPermissions[req.Command]
+	if !limited {
+		return socket.Response{}, true
+	}
+	repoName := d.requestRepo(req, rp.repoArg)
+	if repoName == "" && everyRepoCommands[req.Command] {
+		for name, repo := range d.state.GetAllRepos() {
+			if !allows(repo.Access, rp.perm, peer) {
+				return d.deny(req, fmt.Sprintf("%s does not have %s permission on %s", peer, rp.perm, name))
+			}
+		}
+		return socket.Response{}, true
+	}
+	if repoName == "" {
+		// Without a repository there is no policy to check against
+		return d.deny(req, fmt.Sprintf("cannot tell which repository %s acts on", req.Command))
+	}
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		// The handler reports the missing repository
+		return socket.Response{}, true
+	}
+
+	if !allows(repo.Access, rp.perm, peer) {
+		return d.deny(req, fmt.Sprintf("%s does not have %s permission on %s", peer, rp.perm, repoName))
+	}
+
+	// Changing the policy needs an explicit admin grant, so an open admin
+	// list doesn't let anyone lock everybody else out
+	if req.Command == "update_repo_config" {
+		for arg := range accessArgs {
+			if _, ok := req.Args[arg]; ok && (!repo.Access.Restricts(state.PermAdmin) || !allows(repo.Access, state.PermAdmin, peer)) {
+				return d.deny(req, fmt.Sprintf("%s may not change who can act on %s (ask the daemon's user or a repository admin)", peer, repoName))
+			}
+		}
+	}
+	return socket.Response{}, true
+}
+
+// requestRepo returns the repository a request acts on. A reply that names
+// only a response ID acts on the repository the ID was issued for.
+func (d *Daemon) requestRepo(req socket.Request, repoArg string) string {
+	repoName, _ := req.Args[repoArg].(string)
+	if repoName == "" && req.Command == "respond_agent" {
+		if id, _ := req.Args["response_id"].(string); id != "" {
+			repoName, _, _ = d.responses.Owner(id, d.clock.Now())
+		}
+	}
+	return repoName
+}
+
+// deny logs and builds the response for a refused request
+func (d *Daemon) deny(req socket.Request, reason string) (socket.Response, bool) {
+	d.logger.Warn("Denied %s from %s: %s", req.Command, req.Peer, reason)
+	return socket.Response{Success: false, Error: "permission denied: " + reason, Code: socket.CodePermissionDenied}, false
+}
+
+// handleSetSocketGroup lets a Unix group use the daemon socket, or makes it
+// private again when group is empty
+func (d *Daemon) handleSetSocketGroup(req socket.Request, args socket.SetSocketGroupArgs) (socket.SocketGroup, error) {
+	if err := d.server.Share(args.Group); err != nil {
+		return socket.SocketGroup{}, err
+	}
+	if err := d.state.SetSocketGroup(args.Group); err != nil {
+		return socket.SocketGroup{}, err
+	}
+
+	if args.Group == "" {
+		d.logger.Info("Daemon socket is private to its user")
+	} else {
+		d.logger.Info("Daemon socket shared with group %s", args.Group)
+	}
+	return socket.SocketGroup{Group: args.Group}, nil
+}
+
+// handleWhoami reports how the daemon identifies the caller and, for a
+// repository, which permissions they hold
+func (d *Daemon) handleWhoami(req socket.Request, args socket.WhoamiArgs) (socket.Whoami, error) {
+	who := socket.Whoami{
+		Owner:       isDaemonOwner(req.Peer) || (req.Peer == nil && d.state.GetSocketGroup() == ""),
+		SocketGroup: d.state.GetSocketGroup(),
+	}
+	if req.Peer != nil && req.Peer.Remote != "" {
+		who.Remote = req.Peer.Remote
+	} else if req.Peer != nil {
+		who.User = req.Peer.User
+		uid := req.Peer.UID
+		who.UID = &uid
+	}
+
+	if args.Repo != "" {
+		repo, exists := d.state.GetAllRepos()[args.Repo]
+		if !exists {
+			return socket.Whoami{}, socket.Errorf(socket.CodeNotFound, "repository %q not found", args.Repo)
+		}
+		for _, perm := range state.Permissions {
+			if who.Owner || (req.Peer != nil && allows(repo.Access, perm, req.Peer)) {
+				who.Permissions = append(who.Permissions, string(perm))
+			}
+		}
+	}
+	return who, nil
+}
+
+// parseAccessMembers validates a list of users and @groups from a socket request
+func parseAccessMembers(arg string, list []string) ([]string, error) {
+	var members []string
+	seen := make(map[string]bool)
+	for _, member := range list {
+		member = strings.TrimSpace(member)
+		if member == "" || member == "@" || strings.ContainsAny(member, " \t,") {
+			return nil, fmt.Errorf("invalid %s entry %q", arg, member)
+		}
+		if !seen[member] {
+			seen[member] = true
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
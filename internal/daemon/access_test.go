@@ -0,0 +1,195 @@
+package daemon
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestAuthorize(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.state.AddRepo("release", &state.Repository{Agents: make(map[string]state.Agent)})
+	d.state.UpdateAccessPolicy("release", state.AccessPolicy{
+		Remove: []string{"alice"},
+		Admin:  []string{"alice"},
+	})
+	d.state.AddRepo("sandbox", &state.Repository{Agents: make(map[string]state.Agent)})
+
+	owner := &socket.Peer{UID: os.Getuid(), User: "owner"}
+	alice := &socket.Peer{UID: os.Getuid() + 1001, User: "alice"}
+	intern := &socket.Peer{UID: os.Getuid() + 1002, User: "intern"}
+
+	tests := []struct {
+		name string
+		req  socket.Request
+		want bool
+	}{
+		{"owner removes", socket.Request{Command: "remove_agent", Args: map[string]interface{}{"repo": "release"}, Peer: owner}, true},
+		{"listed user removes", socket.Request{Command: "remove_agent", Args: map[string]interface{}{"repo": "release"}, Peer: alice}, true},
+		{"intern removes", socket.Request{Command: "remove_agent", Args: map[string]interface{}{"repo": "release"}, Peer: intern}, false},
+		{"intern removes repo", socket.Request{Command: "remove_repo", Args: map[string]interface{}{"name": "release"}, Peer: intern}, false},
+		{"intern spawns", socket.Request{Command: "add_agent", Args: map[string]interface{}{"repo": "release"}, Peer: intern}, true},
+		{"intern completes worker", socket.Request{Command: "complete_agent", Args: map[string]interface{}{"repo": "release", "agent": "fox"}, Peer: intern}, false},
+		{"listed user completes worker", socket.Request{Command: "complete_agent", Args: map[string]interface{}{"repo": "release", "agent": "fox"}, Peer: alice}, true},
+		{"intern cleans up", socket.Request{Command: "trigger_cleanup", Args: map[string]interface{}{"repo": "release"}, Peer: intern}, false},
+		{"intern cleans up every repo", socket.Request{Command: "trigger_cleanup", Peer: intern}, false},
+		{"admin cleans up", socket.Request{Command: "trigger_cleanup", Args: map[string]interface{}{"repo": "release"}, Peer: alice}, true},
+		{"intern cleans up sandbox", socket.Request{Command: "trigger_cleanup", Args: map[string]interface{}{"repo": "sandbox"}, Peer: intern}, true},
+		{"intern resolves conflict", socket.Request{Command: "resolve_conflict", Args: map[string]interface{}{"repo": "release", "agent": "fox", "action": "skip"}, Peer: intern}, false},
+		{"intern removes scratch worktree", socket.Request{Command: "remove_scratch_worktree", Args: map[string]interface{}{"repo": "release", "agent": "fox", "name": "spike"}, Peer: intern}, false},
+		{"intern removes elsewhere", socket.Request{Command: "remove_agent", Args: map[string]interface{}{"repo": "sandbox"}, Peer: intern}, true},
+		{"intern reads", socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "release"}, Peer: intern}, true},
+		{"intern stops daemon", socket.Request{Command: "stop", Peer: intern}, false},
+		{"intern repairs state", socket.Request{Command: "repair_state", Peer: intern}, false},
+		{"intern takes over lock", socket.Request{Command: "repo_lock", Args: map[string]interface{}{"repo": "sandbox", "take_over": true}, Peer: intern}, false},
+		{"intern reads lock", socket.Request{Command: "repo_lock", Args: map[string]interface{}{"repo": "sandbox"}, Peer: intern}, true},
+		{"owner takes over lock", socket.Request{Command: "repo_lock", Args: map[string]interface{}{"repo": "sandbox", "take_over": true}, Peer: owner}, true},
+		{"intern adds repo", socket.Request{Command: "add_repo", Args: map[string]interface{}{"name": "new"}, Peer: intern}, false},
+		{"intern switches current repo", socket.Request{Command: "set_current_repo", Args: map[string]interface{}{"name": "sandbox"}, Peer: intern}, false},
+		{"intern resumes refresh", socket.Request{Command: "resume_refresh", Args: map[string]interface{}{"repo": "release"}, Peer: intern}, false},
+		{"admin resumes refresh", socket.Request{Command: "resume_refresh", Args: map[string]interface{}{"repo": "release"}, Peer: alice}, true},
+		{"owner repairs state", socket.Request{Command: "repair_state", Peer: owner}, true},
+		{"admin changes access", socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "release", "access_remove": []interface{}{"intern"}}, Peer: alice}, true},
+		{"intern grants self", socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "sandbox", "access_admin": []interface{}{"intern"}}, Peer: intern}, false},
+		{"unidentified on private socket", socket.Request{Command: "remove_agent", Args: map[string]interface{}{"repo": "release"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, ok := d.authorize(tt.req)
+			if ok != tt.want {
+				t.Errorf("authorize() = %v (%s), want %v", ok, resp.Error, tt.want)
+			}
+			if !ok && !strings.HasPrefix(resp.Error, "permission denied") {
+				t.Errorf("denial error = %q", resp.Error)
+			}
+		})
+	}
+
+	// Typing into an agent's pane needs the spawn permission
+	d.state.UpdateAccessPolicy("sandbox", state.AccessPolicy{Spawn: []string{"alice"}})
+	respond := socket.Request{Command: "respond_agent", Args: map[string]interface{}{"repo": "sandbox", "agent": "fox", "text": "rm -rf ."}, Peer: intern}
+	if _, ok := d.authorize(respond); ok {
+		t.Error("intern should not respond to agents in a repo they can't spawn in")
+	}
+	respond.Peer = alice
+	if _, ok := d.authorize(respond); !ok {
+		t.Error("alice should respond to agents in sandbox")
+	}
+
+	// A reply naming only a response ID is checked against the ID's repo
+	id, _ := d.responses.Issue("sandbox", "fox", d.clock.Now())
+	byID := socket.Request{Command: "respond_agent", Args: map[string]interface{}{"response_id": id, "text": "rm -rf ."}, Peer: intern}
+	if _, ok := d.authorize(byID); ok {
+		t.Error("intern should not respond by response ID to agents in a repo they can't spawn in")
+	}
+	byID.Args["response_id"] = "unknown"
+	if _, ok := d.authorize(byID); ok {
+		t.Error("a reply whose repository can't be told should be denied")
+	}
+	byID.Args["response_id"] = id
+	byID.Peer = alice
+	if _, ok := d.authorize(byID); !ok {
+		t.Error("alice should respond by response ID to agents in sandbox")
+	}
+	issue := socket.Request{Command: "issue_response_id", Args: map[string]interface{}{"repo": "sandbox", "agent": "fox"}, Peer: intern}
+	if _, ok := d.authorize(issue); ok {
+		t.Error("intern should not issue response IDs for agents in a repo they can't spawn in")
+	}
+
+	// So does messaging its agents
+	for _, cmd := range []string{"ask_question", "broadcast_question", "broadcast_reply"} {
+		msg := socket.Request{Command: cmd, Args: map[string]interface{}{"repo": "sandbox", "agent": "fox"}, Peer: intern}
+		if _, ok := d.authorize(msg); ok {
+			t.Errorf("intern should not send %s to agents in a repo they can't spawn in", cmd)
+		}
+	}
+
+	// Once the socket is shared, callers must be identified
+	d.state.SetSocketGroup("devs")
+	if _, ok := d.authorize(socket.Request{Command: "remove_agent", Args: map[string]interface{}{"repo": "release"}}); ok {
+		t.Error("unidentified caller should be denied on a shared socket")
+	}
+}
+
+func TestUpdateRepoConfigAccess(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.state.AddRepo("release", &state.Repository{Agents: make(map[string]state.Agent)})
+
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":          "release",
+		"access_remove": []interface{}{"alice", "@release", "alice"},
+		"access_admin":  []interface{}{"alice"},
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	access := d.state.GetAllRepos()["release"].Access
+	if len(access.Remove) != 2 || access.Remove[1] != "@release" || len(access.Admin) != 1 {
+		t.Errorf("access = %+v", access)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":         "release",
+		"access_spawn": []interface{}{"two words"},
+	}})
+	if resp.Success {
+		t.Error("update_repo_config should reject invalid access entries")
+	}
+
+	who, err := d.handleWhoami(socket.Request{Peer: &socket.Peer{UID: os.Getuid() + 1002, User: "intern"}}, socket.WhoamiArgs{Repo: "release"})
+	if err != nil {
+		t.Fatalf("whoami failed: %v", err)
+	}
+	if perms := who.Permissions; len(perms) != 2 || perms[0] != "spawn" || perms[1] != "merge" {
+		t.Errorf("whoami permissions = %v, want [spawn merge]", perms)
+	}
+}
+
+// TestEveryCommandHasPermission fails when a command is registered without
+// saying who may send it, so new commands can't be open by accident
+func TestEveryCommandHasPermission(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	registered := make(map[string]bool)
+	for _, name := range d.commands.Names() {
+		registered[name] = true
+		_, limited := commandPermissions[name]
+		_, open := openCommands[name]
+		declared := 0
+		for _, in := range []bool{limited, ownerOnlyCommands[name], open} {
+			if in {
+				declared++
+			}
+		}
+		switch declared {
+		case 0:
+			t.Errorf("%s is in none of commandPermissions, ownerOnlyCommands and openCommands; limit it if it changes state", name)
+		case 2, 3:
+			t.Errorf("%s is in more than one of commandPermissions, ownerOnlyCommands and openCommands", name)
+		}
+	}
+	for _, table := range []map[string]bool{keysOf(commandPermissions), ownerOnlyCommands, keysOf(openCommands)} {
+		for name := range table {
+			if !registered[name] {
+				t.Errorf("permission tables list unregistered command %s", name)
+			}
+		}
+	}
+}
+
+// keysOf returns the set of a map's keys
+func keysOf[V any](m map[string]V) map[string]bool {
+	keys := make(map[string]bool, len(m))
+	for k := range m {
+		keys[k] = true
+	}
+	return keys
+}
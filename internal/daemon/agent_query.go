@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// agentSortKeys are the fields list_agents can sort by
+var agentSortKeys = map[string]bool{
+	"name":       true,
+	"repo":       true,
+	"type":       true,
+	"status":     true,
+	"created_at": true,
+	"priority":   true,
+}
+
+// agentQuery holds the filtering, sorting and pagination options of a list_agents request
+type agentQuery struct {
+	Status string
+	Type   string
+	Label  string
+	Sort   string // One of agentSortKeys (default: "name")
+	Desc   bool
+	Limit  int // 0 means no limit
+	Offset int
+}
+
+// parseAgentQuery reads the optional query arguments of a list_agents request.
+// Sort accepts a "-" prefix for descending order (e.g. "-created_at").
+func parseAgentQuery(args socket.ListAgentsArgs) (agentQuery, error) {
+	q := agentQuery{Sort: "name", Status: args.Status, Type: args.Type, Label: args.Label}
+
+	if sortArg := args.Sort; sortArg != "" {
+		if strings.HasPrefix(sortArg, "-") {
+			q.Desc = true
+			sortArg = sortArg[1:]
+		}
+		if !agentSortKeys[sortArg] {
+			return q, fmt.Errorf("invalid sort field %q (use name, repo, type, status, created_at, or priority)", sortArg)
+		}
+		q.Sort = sortArg
+	}
+
+	if args.Limit < 0 {
+		return q, fmt.Errorf("limit must not be negative")
+	}
+	q.Limit = args.Limit
+	if args.Offset < 0 {
+		return q, fmt.Errorf("offset must not be negative")
+	}
+	q.Offset = args.Offset
+	return q, nil
+}
+
+// needsStatus reports whether the query requires each agent's live status
+func (q agentQuery) needsStatus() bool {
+	return q.Status != "" || q.Sort == "status"
+}
+
+// paginated reports whether the caller asked for a page rather than the full list
+func (q agentQuery) paginated() bool {
+	return q.Limit > 0 || q.Offset > 0
+}
+
+// matches reports whether an agent passes the query's filters
+func (q agentQuery) matches(agent socket.AgentInfo) bool {
+	if q.Type != "" && string(agent.Type) != q.Type {
+		return false
+	}
+	if q.Status != "" && agent.Status != q.Status {
+		return false
+	}
+	if q.Label != "" {
+		found := false
+		for _, l := range agent.Labels {
+			if l == q.Label {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortKey returns the field of agent the query sorts by, other than
+// created_at, as a string
+func (q agentQuery) sortKey(agent socket.AgentInfo) string {
+	switch q.Sort {
+	case "repo":
+		return agent.Repo
+	case "type":
+		return string(agent.Type)
+	case "status":
+		return agent.Status
+	case "priority":
+		return string(agent.Priority)
+	}
+	return agent.Name
+}
+
+// apply filters and sorts agents, returning the requested page and the
+// number of agents that matched before pagination
+func (q agentQuery) apply(agents []socket.AgentInfo) ([]socket.AgentInfo, int) {
+	filtered := make([]socket.AgentInfo, 0, len(agents))
+	for _, agent := range agents {
+		if q.matches(agent) {
+			filtered = append(filtered, agent)
+		}
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := filtered[i], filtered[j]
+		var less, equal bool
+		if q.Sort == "created_at" {
+			less, equal = a.CreatedAt.Before(b.CreatedAt), a.CreatedAt.Equal(b.CreatedAt)
+		} else {
+			sa, sb := q.sortKey(a), q.sortKey(b)
+			less, equal = sa < sb, sa == sb
+		}
+		if equal {
+			// Fall back to repo/name so pages are stable
+			return a.Repo+"/"+a.Name < b.Repo+"/"+b.Name
+		}
+		if q.Desc {
+			return !less
+		}
+		return less
+	})
+
+	total := len(filtered)
+	if q.Offset >= total {
+		return []socket.AgentInfo{}, total
+	}
+	end := total
+	if q.Limit > 0 && q.Offset+q.Limit < total {
+		end = q.Offset + q.Limit
+	}
+	return filtered[q.Offset:end], total
+}
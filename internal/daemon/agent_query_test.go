@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestParseAgentQuery(t *testing.T) {
+	q, err := parseAgentQuery(socket.ListAgentsArgs{
+		Sort:   "-created_at",
+		Limit:  10,
+		Offset: 20,
+		Type:   "worker",
+	})
+	if err != nil {
+		t.Fatalf("parseAgentQuery failed: %v", err)
+	}
+	if q.Sort != "created_at" || !q.Desc || q.Limit != 10 || q.Offset != 20 || q.Type != "worker" {
+		t.Errorf("unexpected query: %+v", q)
+	}
+
+	invalid := []socket.ListAgentsArgs{
+		{Sort: "color"},
+		{Limit: -1},
+		{Offset: -5},
+	}
+	for _, args := range invalid {
+		if _, err := parseAgentQuery(args); err == nil {
+			t.Errorf("parseAgentQuery(%+v) should fail", args)
+		}
+	}
+}
+
+func TestAgentQueryApply(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	agents := []socket.AgentInfo{
+		{Name: "c", Repo: "r", Type: state.AgentTypeWorker, CreatedAt: base.Add(2 * time.Hour), Labels: []string{"ui"}},
+		{Name: "a", Repo: "r", Type: state.AgentTypeWorker, CreatedAt: base},
+		{Name: "b", Repo: "r", Type: state.AgentTypeSupervisor, CreatedAt: base.Add(time.Hour), Labels: []string{"ui"}},
+	}
+
+	names := func(page []socket.AgentInfo) string {
+		s := ""
+		for _, a := range page {
+			s += a.Name
+		}
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		query     agentQuery
+		want      string
+		wantTotal int
+	}{
+		{"default sort by name", agentQuery{Sort: "name"}, "abc", 3},
+		{"descending created_at", agentQuery{Sort: "created_at", Desc: true}, "cba", 3},
+		{"type filter", agentQuery{Sort: "name", Type: "worker"}, "ac", 2},
+		{"label filter", agentQuery{Sort: "name", Label: "ui"}, "bc", 2},
+		{"first page", agentQuery{Sort: "name", Limit: 2}, "ab", 3},
+		{"second page", agentQuery{Sort: "name", Limit: 2, Offset: 2}, "c", 3},
+		{"offset past end", agentQuery{Sort: "name", Offset: 5}, "", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, total := tt.query.apply(agents)
+			if got := names(page); got != tt.want {
+				t.Errorf("page = %q, want %q", got, tt.want)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestHandleListAgentsPagination(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		for _, repo := range []string{"repo-a", "repo-b"} {
+			s.AddRepo(repo, &state.Repository{
+				GithubURL:   "https://github.com/test/" + repo,
+				TmuxSession: "mc-" + repo,
+				Agents:      make(map[string]state.Agent),
+			})
+		}
+		for i, name := range []string{"w1", "w2", "w3"} {
+			s.AddAgent("repo-a", name, state.Agent{
+				Type:      state.AgentTypeWorker,
+				CreatedAt: time.Now().Add(time.Duration(i) * time.Minute),
+			})
+		}
+		s.AddAgent("repo-b", "w4", state.Agent{Type: state.AgentTypeWorker, Labels: []string{"backend"}})
+	})
+	defer cleanup()
+
+	// Without pagination the response stays a plain list
+	list, err := d.handleListAgents(socket.Request{}, socket.ListAgentsArgs{Repo: "repo-a"})
+	if err != nil || list.Paginated || len(list.Agents) != 3 {
+		t.Fatalf("expected a plain list of 3 agents, got %+v, %v", list, err)
+	}
+	if data, _ := json.Marshal(list); !strings.HasPrefix(string(data), "[") {
+		t.Errorf("unpaginated list_agents encodes as %s, want a list", data)
+	}
+
+	page, err := d.handleListAgents(socket.Request{}, socket.ListAgentsArgs{Repo: "repo-a", Limit: 2, Sort: "-created_at"})
+	if err != nil {
+		t.Fatalf("list_agents failed: %v", err)
+	}
+	if !page.Paginated || len(page.Agents) != 2 || page.Agents[0].Name != "w3" || page.Total != 3 || page.NextOffset != 2 {
+		t.Errorf("unexpected page: %+v", page)
+	}
+	data, _ := json.Marshal(page)
+	var decoded socket.AgentList
+	if err := json.Unmarshal(data, &decoded); err != nil || !decoded.Paginated || decoded.NextOffset != 2 || len(decoded.Agents) != 2 {
+		t.Errorf("page doesn't survive the wire: %s decodes to %+v, %v", data, decoded, err)
+	}
+
+	list, _ = d.handleListAgents(socket.Request{}, socket.ListAgentsArgs{AllRepos: true, Label: "backend"})
+	if len(list.Agents) != 1 || list.Agents[0].Repo != "repo-b" {
+		t.Errorf("label filter across repos returned %+v", list.Agents)
+	}
+
+	list, _ = d.handleListAgents(socket.Request{}, socket.ListAgentsArgs{Repo: "repo-a", Status: "running"})
+	if len(list.Agents) != 0 {
+		t.Errorf("no agents should be running without tmux windows, got %d", len(list.Agents))
+	}
+}
@@ -0,0 +1,247 @@
+package daemon
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// apiShutdownTimeout bounds how long in-flight API requests may run once
+// the daemon stops
+const apiShutdownTimeout = 5 * time.Second
+
+// startAPI starts the HTTP API when the config file sets api.listen. Each
+// endpoint answers with the Data of the socket command it mirrors, as JSON.
+// Every endpoint only reads, except POST /api/v1/respond, which is served
+// when webhook requests are signed and only takes signed replies.
+func (d *Daemon) startAPI() error {
+	settings := d.configFile().API
+	if settings.Listen == "" {
+		return nil
+	}
+	if settings.Token == "" {
+		return fmt.Errorf("api.token is required when api.listen is set")
+	}
+	server := &http.Server{
+		Handler:           d.apiHandler(settings.Token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// The config file's validation keeps api.listen on loopback without TLS
+	if settings.TLSCert != "" {
+		var err error
+		if server.TLSConfig, err = socket.ServerTLSConfig(settings.TLSCert, settings.TLSKey); err != nil {
+			return err
+		}
+	}
+	ln, err := net.Listen("tcp", settings.Listen)
+	if err != nil {
+		return err
+	}
+	go func() {
+		// ServeTLS takes the certificate loaded into server.TLSConfig
+		serve := server.Serve
+		if server.TLSConfig != nil {
+			serve = func(ln net.Listener) error { return server.ServeTLS(ln, "", "") }
+		}
+		if err := serve(ln); err != nil && err != http.ErrServerClosed {
+			d.logger.Error("API server stopped: %v", err)
+		}
+	}()
+	d.apiMu.Lock()
+	d.api = server
+	d.apiMu.Unlock()
+	d.logger.Info("API server listening on %s (TLS: %v)", ln.Addr(), server.TLSConfig != nil)
+	return nil
+}
+
+// stopAPI stops the HTTP API, if it is running
+func (d *Daemon) stopAPI() {
+	d.apiMu.Lock()
+	server := d.api
+	d.api = nil
+	d.apiMu.Unlock()
+	if server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		d.logger.Error("Failed to stop API server: %v", err)
+	}
+}
+
+// apiHandler routes API requests to socket commands. With a token, every
+// request must carry it as a bearer token.
+func (d *Daemon) apiHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/status", d.apiCommand(func(r *http.Request) socket.Request {
+		req := socket.Request{Command: "status"}
+		if allHosts, _ := strconv.ParseBool(r.URL.Query().Get("all_hosts")); allHosts {
+			req.Args = map[string]interface{}{"all_hosts": true}
+		}
+		return req
+	}))
+	mux.HandleFunc("GET /api/v1/repos", d.apiCommand(func(r *http.Request) socket.Request {
+		return socket.Request{Command: "list_repos", Args: map[string]interface{}{"rich": true}}
+	}))
+	mux.HandleFunc("GET /api/v1/repos/{repo}/agents", d.apiCommand(func(r *http.Request) socket.Request {
+		args := map[string]interface{}{"repo": r.PathValue("repo"), "rich": true}
+		for _, key := range []string{"status", "type", "label", "sort"} {
+			if v := r.URL.Query().Get(key); v != "" {
+				args[key] = v
+			}
+		}
+		for _, key := range []string{"limit", "offset"} {
+			if v := r.URL.Query().Get(key); v != "" {
+				// A malformed number is passed through so the command rejects it
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					n = -1
+				}
+				args[key] = float64(n)
+			}
+		}
+		return socket.Request{Command: "list_agents", Args: args}
+	}))
+	mux.HandleFunc("GET /api/v1/agents/{repo}/{agent}/screen", d.apiCommand(func(r *http.Request) socket.Request {
+		args := map[string]interface{}{"repo": r.PathValue("repo"), "agent": r.PathValue("agent")}
+		if v := r.URL.Query().Get("lines"); v != "" {
+			// A malformed count is passed through so the command rejects it
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				n = -1
+			}
+			args["lines"] = float64(n)
+		}
+		return socket.Request{Command: "agent_screen", Args: args}
+	}))
+	mux.HandleFunc("GET /api/v1/events", d.apiCommand(func(r *http.Request) socket.Request {
+		args := map[string]interface{}{}
+		for _, key := range []string{"repo", "type", "since", "until"} {
+			if v := r.URL.Query().Get(key); v != "" {
+				args[key] = v
+			}
+		}
+		if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil {
+			args["limit"] = float64(n)
+		}
+		return socket.Request{Command: "list_events", Args: args}
+	}))
+
+	if secret := d.replySecret(); secret != nil {
+		mux.HandleFunc("POST /api/v1/respond", d.apiRespond(notify.NewVerifier(secret)))
+	}
+
+	if token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="multiclaude"`)
+			writeAPIJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid bearer token"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// apiCommand answers an API request with the socket command it maps to
+func (d *Daemon) apiCommand(build func(r *http.Request) socket.Request) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d.apiDispatch(w, r, build(r))
+	}
+}
+
+// apiDispatch runs req and writes its response. A W3C traceparent header
+// makes the command's span part of the caller's trace.
+func (d *Daemon) apiDispatch(w http.ResponseWriter, r *http.Request, req socket.Request) {
+	req.Version = socket.ProtocolVersion
+	req.Traceparent = r.Header.Get("traceparent")
+	resp := d.dispatchRequest(req)
+	if !resp.Success {
+		writeAPIJSON(w, apiStatus(resp), map[string]string{"error": resp.Error, "code": string(resp.Code)})
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, resp.Data)
+}
+
+// signedReplyOrigin is the origin of replies relayed through
+// POST /api/v1/respond
+const signedReplyOrigin = "api respond"
+
+// maxReplyBody bounds the body of a relayed reply
+const maxReplyBody = 64 << 10
+
+// apiRespond relays a reply to an agent's question, sent back by a webhook
+// receiver. The request must be signed like the webhook's own requests
+// (notify.Verifier checks the signature, timestamp and nonce), and its body
+// is {"response_id": ..., "text": ...}: the question's one-time response ID
+// picks the agent.
+func (d *Daemon) apiRespond(verifier *notify.Verifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxReplyBody))
+		if err != nil {
+			writeAPIJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error(), "code": string(socket.CodeBadRequest)})
+			return
+		}
+		if err := verifier.Verify(r.Header.Get, body, d.clock.Now()); err != nil {
+			d.logger.Warn("Rejected reply from %s: %v", r.RemoteAddr, err)
+			writeAPIJSON(w, http.StatusUnauthorized, map[string]string{"error": "reply rejected: " + err.Error(), "code": string(socket.CodePermissionDenied)})
+			return
+		}
+		var reply struct {
+			ResponseID string `json:"response_id"`
+			Text       string `json:"text"`
+		}
+		if err := json.Unmarshal(body, &reply); err != nil {
+			writeAPIJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid reply: " + err.Error(), "code": string(socket.CodeBadRequest)})
+			return
+		}
+		// The signature proves the sender holds the webhook secret, which only
+		// the daemon's user configures
+		d.apiDispatch(w, r, socket.Request{
+			Command: "respond_agent",
+			Origin:  signedReplyOrigin,
+			Peer:    &socket.Peer{Remote: r.RemoteAddr},
+			Args:    map[string]interface{}{"response_id": reply.ResponseID, "text": reply.Text},
+		})
+	}
+}
+
+// apiStatus maps a failed command's error code to an HTTP status. Failures
+// the handler didn't classify are server errors.
+func apiStatus(resp socket.Response) int {
+	switch resp.Code {
+	case socket.CodeBadRequest, socket.CodeUnsupportedVersion, socket.CodeMissingArgument, socket.CodeInvalidArgument:
+		return http.StatusBadRequest
+	case socket.CodeNotFound, socket.CodeUnknownCommand:
+		return http.StatusNotFound
+	case socket.CodePermissionDenied:
+		return http.StatusForbidden
+	case socket.CodeConflict:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		data = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
@@ -0,0 +1,274 @@
+package daemon
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+func TestAPIHandler(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "fox", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "fox", Task: "Fix the bug"})
+		s.AddAgent("repo", "boss", state.Agent{Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"})
+	})
+	defer cleanup()
+
+	server := httptest.NewServer(d.apiHandler("t0ken"))
+	defer server.Close()
+
+	get := func(path, token string, v interface{}) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("GET %s Content-Type = %q", path, ct)
+		}
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Errorf("GET %s returned invalid JSON: %v", path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		if status := get("/api/v1/repos", token, nil); status != http.StatusUnauthorized {
+			t.Errorf("GET with token %q = %d, want 401", token, status)
+		}
+	}
+
+	var agents []map[string]interface{}
+	if status := get("/api/v1/repos/repo/agents", "t0ken", &agents); status != http.StatusOK {
+		t.Fatalf("GET agents = %d", status)
+	}
+	if len(agents) != 2 || agents[1]["name"] != "fox" || agents[1]["task"] != "Fix the bug" {
+		t.Errorf("agents = %v", agents)
+	}
+
+	// Filters, sorting and pagination are list_agents' own
+	agents = nil
+	if status := get("/api/v1/repos/repo/agents?type=supervisor", "t0ken", &agents); status != http.StatusOK || len(agents) != 1 || agents[0]["name"] != "boss" {
+		t.Errorf("GET agents?type=supervisor = %d %v, want the supervisor", status, agents)
+	}
+	var page map[string]interface{}
+	if status := get("/api/v1/repos/repo/agents?sort=-name&limit=1&offset=1", "t0ken", &page); status != http.StatusOK {
+		t.Errorf("GET agents page = %d", status)
+	} else if list, _ := page["agents"].([]interface{}); len(list) != 1 || list[0].(map[string]interface{})["name"] != "boss" || page["total"] != float64(2) {
+		t.Errorf("GET agents?sort=-name&limit=1&offset=1 = %v, want boss of 2", page)
+	}
+	if status := get("/api/v1/repos/repo/agents?limit=all", "t0ken", nil); status != http.StatusBadRequest {
+		t.Errorf("GET agents with a bad limit = %d, want 400", status)
+	}
+
+	var repos []map[string]interface{}
+	if status := get("/api/v1/repos", "t0ken", &repos); status != http.StatusOK || len(repos) != 1 || repos[0]["name"] != "repo" {
+		t.Errorf("GET repos = %d %v", status, repos)
+	}
+
+	var status map[string]interface{}
+	if code := get("/api/v1/status", "t0ken", &status); code != http.StatusOK || status["running"] != true {
+		t.Errorf("GET status = %d %v", code, status)
+	}
+	if _, ok := status["fleet"]; ok {
+		t.Error("GET status includes the fleet without all_hosts")
+	}
+	status = nil
+	if code := get("/api/v1/status?all_hosts=true", "t0ken", &status); code != http.StatusOK {
+		t.Errorf("GET status?all_hosts = %d", code)
+	} else if fleetStatus, _ := status["fleet"].(map[string]interface{}); fleetStatus == nil || fleetStatus["agents"] != float64(2) {
+		t.Errorf("GET status?all_hosts = %v, want the fleet with this daemon's agents", status)
+	}
+
+	var events []interface{}
+	if code := get("/api/v1/events?limit=5", "t0ken", &events); code != http.StatusOK {
+		t.Errorf("GET events = %d", code)
+	}
+
+	var apiErr map[string]string
+	if code := get("/api/v1/repos/missing/agents", "t0ken", &apiErr); code != http.StatusNotFound || apiErr["code"] != "not_found" {
+		t.Errorf("GET missing repo = %d %v, want 404 with code not_found", code, apiErr)
+	}
+	apiErr = nil
+	if code := get("/api/v1/agents/repo/nobody/screen", "t0ken", &apiErr); code != http.StatusNotFound || apiErr["code"] != "not_found" {
+		t.Errorf("GET screen of a missing agent = %d %v, want 404 with code not_found", code, apiErr)
+	}
+	if code := get("/api/v1/agents/repo/fox/screen?lines=many", "t0ken", nil); code != http.StatusBadRequest {
+		t.Errorf("GET screen with bad lines = %d, want 400", code)
+	}
+	if code := get("/api/v1/events?since=yesterday", "t0ken", nil); code != http.StatusBadRequest {
+		t.Errorf("GET events with a bad since = %d, want 400", code)
+	}
+}
+
+func TestAPIStatus(t *testing.T) {
+	for code, want := range map[socket.ErrorCode]int{
+		socket.CodeMissingArgument: http.StatusBadRequest,
+		socket.CodeNotFound:        http.StatusNotFound,
+		socket.CodeConflict:        http.StatusConflict,
+		socket.CodeFailed:          http.StatusInternalServerError,
+		"":                         http.StatusInternalServerError,
+	} {
+		// The message no longer decides the status, only the code does
+		resp := socket.Response{Error: "something not found", Code: code}
+		if got := apiStatus(resp); got != want {
+			t.Errorf("apiStatus(%q) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestAPIRespond(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-api-respond", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "fox", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "fox"})
+	})
+	defer cleanup()
+
+	// Without a webhook secret there is nothing to check replies against
+	unsigned := httptest.NewServer(d.apiHandler(""))
+	resp, err := http.Post(unsigned.URL+"/api/v1/respond", "application/json", strings.NewReader("{}"))
+	unsigned.Close()
+	if err != nil {
+		t.Fatalf("POST respond failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Error("POST respond without a webhook secret succeeded")
+	}
+
+	secret := []byte("s3cret")
+	d.webhookConfig = &notify.WebhookConfig{Secret: secret}
+	server := httptest.NewServer(d.apiHandler(""))
+	defer server.Close()
+
+	post := func(body string, headers map[string]string) (int, map[string]string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/respond", strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST respond failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var apiErr map[string]string
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return resp.StatusCode, apiErr
+	}
+
+	id, _ := d.responses.Issue("repo", "fox", d.clock.Now())
+	body := `{"response_id": "` + id + `", "text": "ship it"}`
+	if code, _ := post(body, nil); code != http.StatusUnauthorized {
+		t.Errorf("unsigned reply = %d, want 401", code)
+	}
+	if code, _ := post(body, notify.SignedHeaders([]byte("wrong"), []byte(body), d.clock.Now())); code != http.StatusUnauthorized {
+		t.Errorf("reply signed with another key = %d, want 401", code)
+	}
+	if code, _ := post(body, notify.SignedHeaders(secret, []byte(body), d.clock.Now().Add(-time.Hour))); code != http.StatusUnauthorized {
+		t.Errorf("reply signed an hour ago = %d, want 401", code)
+	}
+
+	noID := `{"text": "ship it"}`
+	if code, apiErr := post(noID, notify.SignedHeaders(secret, []byte(noID), d.clock.Now())); code != http.StatusBadRequest || apiErr["code"] != string(socket.CodeMissingArgument) {
+		t.Errorf("signed reply without a response ID = %d %v, want 400 missing_argument", code, apiErr)
+	}
+
+	// A signed reply gets through to the agent (whose window doesn't exist
+	// here), and replaying it is refused before it gets that far
+	headers := notify.SignedHeaders(secret, []byte(body), d.clock.Now())
+	if _, apiErr := post(body, headers); !strings.Contains(apiErr["error"], "failed to send reply to agent 'fox'") {
+		t.Errorf("signed reply = %v, want it delivered to fox", apiErr)
+	}
+	if code, _ := post(body, headers); code != http.StatusUnauthorized {
+		t.Errorf("replayed reply = %d, want 401", code)
+	}
+}
+
+func TestStartAPIServesTLS(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	// Find a free port for the API to listen on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	d.settings.API = config.APISettings{Listen: addr, Token: "t0ken", TLSCert: certFile, TLSKey: keyFile}
+	if err := d.startAPI(); err != nil {
+		t.Fatalf("startAPI() failed: %v", err)
+	}
+	defer d.stopAPI()
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	req, _ := http.NewRequest(http.MethodGet, "https://"+addr+"/api/v1/repos", nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET over TLS failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET over TLS = %d, want 200", resp.StatusCode)
+	}
+
+	if resp, err := http.Get("http://" + addr + "/api/v1/repos"); err == nil && resp.StatusCode == http.StatusOK {
+		resp.Body.Close()
+		t.Error("the API answered plain HTTP with TLS configured")
+	}
+}
@@ -0,0 +1,207 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// auditRule describes the audit entry a socket command's success records:
+// the action, the arguments naming the repository and agent, and a summary
+// of the other arguments worth keeping
+type auditRule struct {
+	action   audit.Action
+	repoArg  string
+	agentArg string
+	detail   func(args map[string]interface{}) string
+}
+
+// auditedCommands lists the socket commands that change state. Requests
+// with dry_run set change nothing and aren't recorded. Every registered
+// command is either here or in unauditedCommands.
+var auditedCommands = map[string]auditRule{
+	"stop":                    {audit.ActionDaemonStopped, "", "", nil},
+	"add_repo":                {audit.ActionRepoAdded, "name", "", nil},
+	"remove_repo":             {audit.ActionRepoRemoved, "name", "", nil},
+	"update_repo_config":      {audit.ActionRepoConfigured, "name", "", changedArgs},
+	"set_current_repo":        {audit.ActionCurrentRepoSet, "name", "", nil},
+	"clear_current_repo":      {audit.ActionCurrentRepoCleared, "", "", nil},
+	"repo_lock":               {audit.ActionRepoLockTaken, "repo", "", nil},
+	"resume_refresh":          {audit.ActionRefreshResumed, "repo", "", nil},
+	"add_agent":               {audit.ActionAgentSpawned, "repo", "agent", argDetail("type", "task")},
+	"spawn_agent":             {audit.ActionAgentSpawned, "repo", "name", argDetail("class", "definition", "task")},
+	"remove_agent":            {audit.ActionAgentRemoved, "repo", "agent", nil},
+	"complete_agent":          {audit.ActionAgentCompleted, "repo", "agent", argDetail("summary", "failure_reason")},
+	"handoff_agent":           {audit.ActionAgentHandedOff, "repo", "from", argDetail("to", "task")},
+	"restart_agent":           {audit.ActionAgentRestarted, "repo", "agent", nil},
+	"recover_agent":           {audit.ActionAgentRecovered, "repo", "agent", nil},
+	"pull_agent_branch":       {audit.ActionBranchPulled, "repo", "agent", nil},
+	"add_scratch_worktree":    {audit.ActionScratchAdded, "repo", "agent", argDetail("name", "rev", "branch")},
+	"remove_scratch_worktree": {audit.ActionScratchRemoved, "repo", "agent", argDetail("name")},
+	"claim_warm_worktree":     {audit.ActionWarmClaimed, "repo", "agent", argDetail("branch")},
+	"add_task":                {audit.ActionTaskQueued, "repo", "", argDetail("description")},
+	"cancel_task":             {audit.ActionTaskCancelled, "repo", "", argDetail("id")},
+	"respond_agent":           {audit.ActionMessageSent, "repo", "agent", argDetail("response_id")},
+	"broadcast_question":      {audit.ActionMessageSent, "repo", "", argDetail("question")},
+	"broadcast_reply":         {audit.ActionMessageSent, "repo", "agent", argDetail("id")},
+	"ask_question":            {audit.ActionQuestionAsked, "repo", "agent", argDetail("question")},
+	"add_auto_answer":         {audit.ActionAutoAnswerAdded, "repo", "", argDetail("pattern")},
+	"remove_auto_answer":      {audit.ActionAutoAnswerRemoved, "repo", "", argDetail("index")},
+	"merge_queue_event":       {audit.ActionMergeQueueEvent, "repo", "", argDetail("event", "pr", "branch")},
+	"resolve_conflict":        {audit.ActionConflictResolved, "repo", "agent", argDetail("action")},
+	"repair_state":            {audit.ActionStateRepaired, "", "", nil},
+	"restore_state":           {audit.ActionStateRestored, "", "", argDetail("backup")},
+	"trigger_cleanup":         {audit.ActionCleanup, "repo", "", nil},
+	"set_socket_group":        {audit.ActionDaemonConfigured, "", "", argDetail("group")},
+	"set_log_storage":         {audit.ActionDaemonConfigured, "", "", argDetail("backend")},
+	"reload_config":           {audit.ActionDaemonConfigured, "", "", nil},
+}
+
+// unchangedBy reports, for commands whose successful requests don't always
+// change state, whether a request changed nothing
+var unchangedBy = map[string]func(req socket.Request, resp socket.Response) bool{
+	// Without take_over, repo_lock only reports who holds the lock
+	"repo_lock": func(req socket.Request, _ socket.Response) bool {
+		takeOver, _ := req.Args["take_over"].(bool)
+		return !takeOver
+	},
+	// An empty warm pool leaves the caller to create a worktree itself
+	"claim_warm_worktree": func(_ socket.Request, resp socket.Response) bool {
+		data, _ := resp.Data.(map[string]interface{})
+		return data["claimed"] != true
+	},
+}
+
+// unauditedCommands lists the socket commands that only read state, or
+// whose changes are recorded elsewhere or are routine bookkeeping
+var unauditedCommands = map[string]string{
+	"ping":                   "read",
+	"status":                 "read",
+	"list_repos":             "read",
+	"fleet_status":           "read",
+	"whoami":                 "read",
+	"get_repo_config":        "read",
+	"get_current_repo":       "read",
+	"list_agents":            "read",
+	"worker_status":          "read",
+	"agent_screen":           "read",
+	"agent_heartbeat":        "bookkeeping: every agent sends one each minute",
+	"route_messages":         "each delivery is recorded as message_delivered",
+	"check_branch_guard":     "read",
+	"check_review_checklist": "read",
+	"list_scratch_worktrees": "read",
+	"get_feed":               "read",
+	"issue_response_id":      "bookkeeping: the reply that redeems the ID is recorded",
+	"list_auto_answers":      "read",
+	"broadcast_status":       "read",
+	"check_worker_capacity":  "read",
+	"list_tasks":             "read",
+	"task_history":           "read",
+	"list_events":            "read",
+	"timeline":               "read",
+	"export_metrics":         "read",
+	"event_schema":           "read",
+	"merge_queue_stats":      "read",
+	"merge_queue_simulate":   "read",
+	"list_audit":             "read",
+}
+
+// argDetail summarizes the named arguments a request set, e.g.
+// "type=worker task=Fix the login bug"
+func argDetail(names ...string) func(map[string]interface{}) string {
+	return func(args map[string]interface{}) string {
+		var parts []string
+		for _, name := range names {
+			if v, ok := args[name]; ok && v != "" && v != nil {
+				parts = append(parts, fmt.Sprintf("%s=%v", name, v))
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+}
+
+// changedArgs lists the settings an update_repo_config request changed
+func changedArgs(args map[string]interface{}) string {
+	var names []string
+	for name := range args {
+		if name != "name" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// auditRequest records a request that changed state in the audit log
+func (d *Daemon) auditRequest(req socket.Request, resp socket.Response) {
+	rule, ok := auditedCommands[req.Command]
+	if !ok || !resp.Success {
+		return
+	}
+	if dryRun, _ := req.Args["dry_run"].(bool); dryRun {
+		return
+	}
+	if unchanged := unchangedBy[req.Command]; unchanged != nil && unchanged(req, resp) {
+		return
+	}
+	entry := audit.Entry{
+		Action:  rule.action,
+		Command: req.Command,
+		Origin:  req.Origin,
+		Actor:   req.Peer.String(),
+	}
+	if rule.repoArg != "" {
+		entry.Repo, _ = req.Args[rule.repoArg].(string)
+	}
+	if rule.agentArg != "" {
+		entry.Agent, _ = req.Args[rule.agentArg].(string)
+	}
+	if rule.detail != nil {
+		entry.Detail = rule.detail(req.Args)
+	}
+	d.recordAudit(entry)
+}
+
+// recordDaemonChange records a change the daemon made on its own, during
+// task (e.g. "health_check"), in the audit log
+func (d *Daemon) recordDaemonChange(task string, action audit.Action, repoName, agentName, detail string) {
+	d.recordAudit(audit.Entry{
+		Action:  action,
+		Repo:    repoName,
+		Agent:   agentName,
+		Detail:  detail,
+		Command: task,
+		Actor:   audit.DaemonActor,
+	})
+}
+
+// recordAudit appends an entry to the audit log
+func (d *Daemon) recordAudit(entry audit.Entry) {
+	if err := d.audit.Append(entry); err != nil {
+		d.logger.Warn("Failed to record %s in the audit log: %v", entry.Action, err)
+	}
+}
+
+// auditLog answers list_audit: recorded state changes, oldest first
+func (d *Daemon) auditLog(req socket.Request, args socket.AuditLogArgs) ([]audit.Entry, error) {
+	filter := audit.Filter{Repo: args.Repo, Limit: args.Limit}
+	if args.Since != "" {
+		since, err := time.Parse(time.RFC3339, args.Since)
+		if err != nil {
+			return nil, socket.Errorf(socket.CodeInvalidArgument, "invalid since %q: %v", args.Since, err)
+		}
+		filter.Since = since
+	}
+	entries, err := d.audit.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	return entries, nil
+}
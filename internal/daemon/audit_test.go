@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestAuditLog(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddRepo("other", &state.Repository{TmuxSession: "mc-other", Agents: make(map[string]state.Agent)})
+	})
+	defer cleanup()
+	peer := &socket.Peer{UID: os.Getuid(), User: "ann"}
+
+	resp := d.dispatchRequest(socket.Request{Command: "add_agent", Origin: "work", Peer: peer, Args: map[string]interface{}{
+		"repo": "repo", "agent": "fox", "type": "worker", "worktree_path": "/tmp/fox", "tmux_window": "fox", "task": "Fix the login bug",
+	}})
+	if !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+	// Failed requests, dry runs and reads change nothing
+	d.dispatchRequest(socket.Request{Command: "remove_agent", Peer: peer, Args: map[string]interface{}{"repo": "missing", "agent": "fox"}})
+	d.dispatchRequest(socket.Request{Command: "repair_state", Peer: peer, Args: map[string]interface{}{"dry_run": true}})
+	d.dispatchRequest(socket.Request{Command: "list_agents", Peer: peer, Args: map[string]interface{}{"repo": "repo"}})
+	resp = d.dispatchRequest(socket.Request{Command: "add_task", Origin: "task add", Peer: peer, Args: map[string]interface{}{
+		"repo": "other", "description": "Write docs",
+	}})
+	if !resp.Success {
+		t.Fatalf("add_task failed: %s", resp.Error)
+	}
+	d.cleanupDeadAgents(map[string][]string{"repo": {"fox"}}, "health_check")
+
+	entries, err := d.auditLog(socket.Request{}, socket.AuditLogArgs{})
+	if err != nil {
+		t.Fatalf("auditLog() failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("audit log = %+v, want add_agent, add_task and the cleanup", entries)
+	}
+	spawned := entries[0]
+	if spawned.Action != audit.ActionAgentSpawned || spawned.Repo != "repo" || spawned.Agent != "fox" ||
+		spawned.Command != "add_agent" || spawned.Origin != "work" || spawned.Actor != peer.String() {
+		t.Errorf("add_agent entry = %+v", spawned)
+	}
+	if !strings.Contains(spawned.Detail, "task=Fix the login bug") {
+		t.Errorf("add_agent detail = %q, want the task", spawned.Detail)
+	}
+	if removed := entries[2]; removed.Action != audit.ActionAgentRemoved || removed.Actor != audit.DaemonActor || removed.Command != "health_check" {
+		t.Errorf("cleanup entry = %+v", removed)
+	}
+
+	// list_audit filters by repository and time
+	resp = d.dispatchRequest(socket.Request{Command: "list_audit", Version: socket.ProtocolVersion, Args: map[string]interface{}{"repo": "other"}})
+	if list, _ := resp.Data.([]audit.Entry); !resp.Success || len(list) != 1 || list[0].Action != audit.ActionTaskQueued {
+		t.Errorf("list_audit repo=other = %+v", resp)
+	}
+	since := time.Now().Add(time.Hour).Format(time.RFC3339)
+	if entries, _ := d.auditLog(socket.Request{}, socket.AuditLogArgs{Since: since}); len(entries) != 0 {
+		t.Errorf("auditLog(since the future) = %+v", entries)
+	}
+	resp = d.dispatchRequest(socket.Request{Command: "list_audit", Version: socket.ProtocolVersion, Args: map[string]interface{}{"since": "yesterday"}})
+	if resp.Success || resp.Code != socket.CodeInvalidArgument {
+		t.Errorf("list_audit with a bad since = %+v, want %s", resp, socket.CodeInvalidArgument)
+	}
+}
+
+// TestEveryCommandAudited fails when a command is registered without saying
+// whether its requests are audited, so new state-changing commands can't
+// slip past the audit log
+func TestEveryCommandAudited(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	registered := make(map[string]bool)
+	for _, name := range d.commands.Names() {
+		registered[name] = true
+		_, audited := auditedCommands[name]
+		_, unaudited := unauditedCommands[name]
+		switch {
+		case audited && unaudited:
+			t.Errorf("%s is in both auditedCommands and unauditedCommands", name)
+		case !audited && !unaudited:
+			t.Errorf("%s is in neither auditedCommands nor unauditedCommands; audit it if it changes state", name)
+		}
+	}
+	for name := range auditedCommands {
+		if !registered[name] {
+			t.Errorf("auditedCommands lists unregistered command %s", name)
+		}
+	}
+	for name := range unauditedCommands {
+		if !registered[name] {
+			t.Errorf("unauditedCommands lists unregistered command %s", name)
+		}
+	}
+}
+
+func TestAuditSkipsUnchangedRequests(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+	})
+	defer cleanup()
+
+	d.auditRequest(socket.Request{Command: "repo_lock", Args: map[string]interface{}{"repo": "repo"}}, socket.Response{Success: true})
+	d.auditRequest(socket.Request{Command: "claim_warm_worktree", Args: map[string]interface{}{"repo": "repo", "agent": "fox"}},
+		socket.Response{Success: true, Data: map[string]interface{}{"claimed": false}})
+	d.auditRequest(socket.Request{Command: "repo_lock", Args: map[string]interface{}{"repo": "repo", "take_over": true}}, socket.Response{Success: true})
+
+	entries, _ := d.auditLog(socket.Request{}, socket.AuditLogArgs{})
+	if len(entries) != 1 || entries[0].Action != audit.ActionRepoLockTaken {
+		t.Errorf("audit log = %+v, want only the lock takeover", entries)
+	}
+}
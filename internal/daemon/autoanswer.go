@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// autoAnswerSender is the sender name workers see on auto-answers
+const autoAnswerSender = "auto-answer"
+
+// defaultAutoAnswers are the built-in templates for questions workers ask
+// over and over. Repository rules are tried first.
+var defaultAutoAnswers = []state.AutoAnswerRule{
+	{
+		Pattern: `\b(add|install|introduce)\b.*\b(new )?(dependency|dependencies|package|library)\b`,
+		Reply: "Only add a dependency if the standard library and existing dependencies can't reasonably do the job. " +
+			"If you add one, pin its version and explain why in the PR description.",
+	},
+	{
+		Pattern: `\b(update|regenerate|refresh|accept)\b.*\b(snapshots?|golden files?|fixtures?)\b`,
+		Reply: "Update snapshots or golden files only when the new output is the intended result of your task, " +
+			"and call it out in the PR description. Never update them just to make a failing test pass.",
+	},
+	{
+		Pattern: `\b(should|do|may|can) i\b.*\brun\b.*\btests?\b`,
+		Reply:   "Yes. Run the relevant tests before completing and fix any failures your change introduced.",
+	},
+}
+
+// matchAutoAnswer returns the first rule matching body, trying the
+// repository's rules before the built-in templates
+func matchAutoAnswer(config state.AutoAnswerConfig, body string) (state.AutoAnswerRule, bool) {
+	if config.Disabled {
+		return state.AutoAnswerRule{}, false
+	}
+	rules := append(append([]state.AutoAnswerRule(nil), config.Rules...), defaultAutoAnswers...)
+	for _, rule := range rules {
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(body) {
+			return rule, true
+		}
+	}
+	return state.AutoAnswerRule{}, false
+}
+
+// autoAnswer replies to a worker's message to the supervisor when it matches
+// an auto-answer rule, returning true if the message was handled. Only
+// questions are considered, and each worker gets one auto-answer per rule;
+// asking again reaches the supervisor.
+func (d *Daemon) autoAnswer(repoName string, repo *state.Repository, msg *messages.Message) bool {
+	sender, exists := repo.Agents[msg.From]
+	if !exists || sender.Type != state.AgentTypeWorker || !strings.Contains(msg.Body, "?") {
+		return false
+	}
+
+	rule, ok := matchAutoAnswer(repo.AutoAnswer, msg.Body)
+	if !ok {
+		return false
+	}
+
+	key := fmt.Sprintf("%s/%s/%s", repoName, msg.From, rule.Pattern)
+	d.autoAnsweredMu.Lock()
+	answered := d.autoAnswered[key]
+	d.autoAnswered[key] = true
+	d.autoAnsweredMu.Unlock()
+	if answered {
+		return false
+	}
+
+	msgMgr := d.getMessageManager()
+	reply := rule.Reply + "\n\n(Automatic reply. If it doesn't fit your situation, ask the supervisor again with more context.)"
+	if _, err := msgMgr.Send(repoName, autoAnswerSender, msg.From, reply); err != nil {
+		d.logger.Error("Failed to send auto-answer to %s/%s: %v", repoName, msg.From, err)
+		return false
+	}
+	if err := msgMgr.Ack(repoName, "supervisor", msg.ID); err != nil {
+		d.logger.Warn("Failed to ack auto-answered message %s: %v", msg.ID, err)
+	}
+
+	d.logger.Info("Auto-answered %s/%s (rule %q): %q", repoName, msg.From, rule.Pattern, msg.Body)
+	go d.routeMessages()
+	return true
+}
+
+// handleListAutoAnswers returns a repository's auto-answer rules and the
+// built-in templates
+func (d *Daemon) handleListAutoAnswers(req socket.Request, args socket.RepoArgs) (socket.AutoAnswers, error) {
+	config, err := d.state.GetAutoAnswerConfig(args.Repo)
+	if err != nil {
+		return socket.AutoAnswers{}, err
+	}
+	return socket.AutoAnswers{Disabled: config.Disabled, Rules: config.Rules, Defaults: defaultAutoAnswers}, nil
+}
+
+// handleAddAutoAnswer appends a rule to a repository's auto-answers and
+// returns how many rules it has
+func (d *Daemon) handleAddAutoAnswer(req socket.Request, args socket.AddAutoAnswerArgs) (int, error) {
+	if _, err := regexp.Compile("(?i)" + args.Pattern); err != nil {
+		return 0, socket.Errorf(socket.CodeInvalidArgument, "invalid pattern: %v", err)
+	}
+
+	config, err := d.state.GetAutoAnswerConfig(args.Repo)
+	if err != nil {
+		return 0, err
+	}
+	config.Rules = append(config.Rules, state.AutoAnswerRule{Pattern: args.Pattern, Reply: args.Reply})
+	if err := d.state.UpdateAutoAnswerConfig(args.Repo, config); err != nil {
+		return 0, err
+	}
+
+	d.logger.Info("Added auto-answer rule for repo %s: %q", args.Repo, args.Pattern)
+	return len(config.Rules), nil
+}
+
+// handleRemoveAutoAnswer removes a repository rule by its 1-based index
+func (d *Daemon) handleRemoveAutoAnswer(req socket.Request, args socket.RemoveAutoAnswerArgs) (struct{}, error) {
+	config, err := d.state.GetAutoAnswerConfig(args.Repo)
+	if err != nil {
+		return struct{}{}, err
+	}
+	i := args.Index
+	if i < 1 || i > len(config.Rules) {
+		return struct{}{}, socket.Errorf(socket.CodeNotFound, "no auto-answer rule #%d in repository '%s'", i, args.Repo)
+	}
+	removed := config.Rules[i-1]
+	config.Rules = append(config.Rules[:i-1], config.Rules[i:]...)
+	if err := d.state.UpdateAutoAnswerConfig(args.Repo, config); err != nil {
+		return struct{}{}, err
+	}
+
+	d.logger.Info("Removed auto-answer rule for repo %s: %q", args.Repo, removed.Pattern)
+	return struct{}{}, nil
+}
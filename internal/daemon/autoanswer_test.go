@@ -0,0 +1,121 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestMatchAutoAnswer(t *testing.T) {
+	custom := state.AutoAnswerConfig{Rules: []state.AutoAnswerRule{
+		{Pattern: `add .*dependency`, Reply: "No new dependencies in this repo."},
+	}}
+
+	tests := []struct {
+		name      string
+		config    state.AutoAnswerConfig
+		body      string
+		wantReply string
+	}{
+		{"repo rule wins over template", custom, "May I add a new dependency for YAML?", "No new dependencies in this repo."},
+		{"built-in template", state.AutoAnswerConfig{}, "Should I update the snapshots?", "Update snapshots"},
+		{"case insensitive", state.AutoAnswerConfig{}, "SHOULD I RUN THE TESTS?", "Yes. Run the relevant tests"},
+		{"no match", state.AutoAnswerConfig{}, "Which API version should I target?", ""},
+		{"opted out", state.AutoAnswerConfig{Disabled: true}, "Should I update the snapshots?", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := matchAutoAnswer(tt.config, tt.body)
+			if ok != (tt.wantReply != "") || !strings.HasPrefix(rule.Reply, tt.wantReply) {
+				t.Errorf("matchAutoAnswer = %q, %v; want reply starting with %q", rule.Reply, ok, tt.wantReply)
+			}
+		})
+	}
+}
+
+func TestAutoAnswer(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "supervisor", state.Agent{Type: state.AgentTypeSupervisor})
+		s.AddAgent("repo", "worker-a", state.Agent{Type: state.AgentTypeWorker})
+	})
+	defer cleanup()
+
+	msgMgr := d.getMessageManager()
+	ask := func(body string) bool {
+		msg, err := msgMgr.Send("repo", "worker-a", "supervisor", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, _ := d.state.GetRepo("repo")
+		return d.autoAnswer("repo", repo, msg)
+	}
+
+	if ask("I updated the snapshots.") {
+		t.Error("statements should not be auto-answered")
+	}
+	if !ask("Should I update the snapshots?") {
+		t.Fatal("expected the question to be auto-answered")
+	}
+
+	replies, _ := msgMgr.List("repo", "worker-a")
+	if len(replies) != 1 || replies[0].From != autoAnswerSender || !strings.Contains(replies[0].Body, "Automatic reply") {
+		t.Errorf("worker replies = %+v", replies)
+	}
+	unread, _ := msgMgr.ListUnread("repo", "supervisor")
+	for _, m := range unread {
+		if strings.Contains(m.Body, "Should I update") {
+			t.Error("auto-answered question should not reach the supervisor")
+		}
+	}
+
+	if ask("Should I really update the snapshots?") {
+		t.Error("asking again should escalate to the supervisor")
+	}
+
+	if err := d.state.UpdateAutoAnswerConfig("repo", state.AutoAnswerConfig{Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if ask("Should I run the tests?") {
+		t.Error("repositories that opted out should not be auto-answered")
+	}
+}
+
+func TestAutoAnswerRuleHandlers(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+	})
+	defer cleanup()
+
+	resp := d.handleRequest(socket.Request{Command: "add_auto_answer", Args: map[string]interface{}{
+		"repo": "repo", "pattern": "(unclosed", "reply": "x",
+	}})
+	if resp.Success || !strings.Contains(resp.Error, "invalid pattern") {
+		t.Errorf("invalid pattern should be rejected, got %+v", resp)
+	}
+
+	for _, pattern := range []string{"migrations?", "feature flags?"} {
+		resp := d.handleRequest(socket.Request{Command: "add_auto_answer", Args: map[string]interface{}{
+			"repo": "repo", "pattern": pattern, "reply": "ask the supervisor",
+		}})
+		if !resp.Success {
+			t.Fatalf("add_auto_answer failed: %s", resp.Error)
+		}
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "remove_auto_answer", Args: map[string]interface{}{"repo": "repo", "index": float64(1)}})
+	if !resp.Success {
+		t.Fatalf("remove_auto_answer failed: %s", resp.Error)
+	}
+	resp = d.handleRequest(socket.Request{Command: "remove_auto_answer", Args: map[string]interface{}{"repo": "repo", "index": float64(5)}})
+	if resp.Success {
+		t.Error("removing a missing rule should fail")
+	}
+
+	config, _ := d.state.GetAutoAnswerConfig("repo")
+	if len(config.Rules) != 1 || config.Rules[0].Pattern != "feature flags?" {
+		t.Errorf("rules = %+v", config.Rules)
+	}
+}
@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+	"github.com/dlorenc/multiclaude/pkg/mux"
+)
+
+// restartGrace is how long after an agent starts or restarts before a shell
+// in its pane counts as Claude having exited, since Claude isn't the pane's
+// foreground command until it has launched
+const restartGrace = 2 * time.Minute
+
+// claudeExitReason says why the agent's Claude process looks gone while its
+// window is still open, or returns "" if Claude appears to be running. The
+// pane's PID is its shell, which outlives Claude, so a pane back at its shell
+// prompt is the usual sign of a crash.
+func claudeExitReason(agent state.Agent, window mux.WindowInfo, now time.Time) string {
+	started := agent.CreatedAt
+	if agent.LastRestart.After(started) {
+		started = agent.LastRestart
+	}
+	switch {
+	case window.PaneDead:
+		return "its pane died"
+	case agent.PID > 0 && !isProcessAlive(agent.PID):
+		return fmt.Sprintf("its process (PID %d) is gone", agent.PID)
+	case isShellCommand(window.PaneCommand) && now.Sub(started) >= restartGrace:
+		return fmt.Sprintf("Claude exited to the %s prompt", window.PaneCommand)
+	}
+	return ""
+}
+
+// restartMax is the restart_max config value: the restart limit, or 0 when
+// automatic restarts are off
+func restartMax(policy state.RestartPolicy) int {
+	if policy.Off {
+		return 0
+	}
+	return policy.Limit()
+}
+
+// autoRestartAgent restarts an agent whose Claude process exited without the
+// agent completing, with its original prompt and session, up to the
+// repository's restart limit. Each restart, and giving up, emits an
+// agent.error event.
+func (d *Daemon) autoRestartAgent(repoName, agentName string, agent state.Agent, repo *state.Repository, reason string, now time.Time) {
+	if repo.Restart.Off || agent.Type == state.AgentTypeWorkspace {
+		return
+	}
+
+	// Crashes long ago don't count against an agent that has since run steadily
+	if !agent.LastRestart.IsZero() && now.Sub(agent.LastRestart) >= state.RestartStableAfter {
+		agent.Restarts = 0
+	}
+
+	key := repoName + "/" + agentName
+	limit := repo.Restart.Limit()
+	if agent.Restarts >= limit {
+		d.restartGaveUpMu.Lock()
+		reported := d.restartGaveUp[key]
+		d.restartGaveUp[key] = true
+		d.restartGaveUpMu.Unlock()
+		if !reported {
+			d.logger.Error("Agent %s/%s crashed (%s) and was already restarted %d times; not restarting it again", repoName, agentName, reason, agent.Restarts)
+			event := events.NewTypedEvent(repoName, agentName, fmt.Sprintf("%s keeps crashing; automatic restarts stopped", agentName),
+				events.AgentErrorPayload{Error: fmt.Sprintf("%s after %d automatic restarts; restart it with: multiclaude agent restart %s --repo %s", reason, agent.Restarts, agentName, repoName)})
+			event.Priority = events.PriorityHigh
+			d.emitEvent(event)
+		}
+		return
+	}
+
+	d.logger.Warn("Agent %s/%s crashed: %s; restarting it (%d of %d)", repoName, agentName, reason, agent.Restarts+1, limit)
+	if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
+		d.logger.Error("Failed to restart agent %s/%s: %v", repoName, agentName, err)
+		event := events.NewTypedEvent(repoName, agentName, fmt.Sprintf("%s crashed and could not be restarted", agentName),
+			events.AgentErrorPayload{Error: fmt.Sprintf("%s; restart failed: %v", reason, err)})
+		event.Priority = events.PriorityHigh
+		d.emitEvent(event)
+		return
+	}
+
+	d.recordDaemonChange("auto_restart", audit.ActionAgentRestarted, repoName, agentName, reason)
+
+	// restartAgent recorded the new PID, so build on the stored agent
+	restarts := agent.Restarts + 1
+	if updated, exists := d.state.GetAgent(repoName, agentName); exists {
+		agent = updated
+	}
+	agent.Restarts = restarts
+	agent.LastRestart = now
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		d.logger.Error("Failed to record restart of %s/%s: %v", repoName, agentName, err)
+	}
+	d.restartGaveUpMu.Lock()
+	delete(d.restartGaveUp, key)
+	d.restartGaveUpMu.Unlock()
+
+	d.emitEvent(events.NewTypedEvent(repoName, agentName, fmt.Sprintf("%s crashed and was restarted", agentName),
+		events.AgentErrorPayload{Error: fmt.Sprintf("%s; restarted automatically (%d of %d)", reason, agent.Restarts, limit)}))
+}
@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+	"github.com/dlorenc/multiclaude/pkg/events"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestClaudeExitReason(t *testing.T) {
+	now := time.Now()
+	old := state.Agent{CreatedAt: now.Add(-time.Hour)}
+
+	tests := []struct {
+		name   string
+		agent  state.Agent
+		window tmux.WindowInfo
+		want   string
+	}{
+		{"running", old, tmux.WindowInfo{PaneCommand: "claude"}, ""},
+		{"running a tool", old, tmux.WindowInfo{PaneCommand: "node"}, ""},
+		{"back at the shell", old, tmux.WindowInfo{PaneCommand: "zsh"}, "Claude exited to the zsh prompt"},
+		{"dead pane", old, tmux.WindowInfo{PaneDead: true}, "its pane died"},
+		{"just created", state.Agent{CreatedAt: now}, tmux.WindowInfo{PaneCommand: "bash"}, ""},
+		{"just restarted", state.Agent{CreatedAt: now.Add(-time.Hour), LastRestart: now}, tmux.WindowInfo{PaneCommand: "bash"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := claudeExitReason(tt.agent, tt.window, now); got != tt.want {
+				t.Errorf("claudeExitReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoRestartAgent(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	// A "Claude" that exits at once leaves the pane at its shell again
+	d.claudeRunner = claude.NewRunner(claude.WithTerminal(d.tmux), claude.WithBinaryPath("true"),
+		claude.WithStartupDelay(10*time.Millisecond))
+
+	ctx := context.Background()
+	session := fmt.Sprintf("mc-test-restart-%d", time.Now().UnixNano())
+	if err := tmuxClient.CreateSessionAt(ctx, session, "supervisor", os.TempDir()); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, session)
+
+	d.state.AddRepo("repo", &state.Repository{
+		TmuxSession: session,
+		Restart:     state.RestartPolicy{MaxRestarts: 2},
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", SessionID: "sid", CreatedAt: time.Now().Add(-time.Hour)},
+		},
+	})
+
+	crash := func(now time.Time) {
+		t.Helper()
+		repo, _ := d.state.GetRepo("repo")
+		agent, _ := d.state.GetAgent("repo", "supervisor")
+		d.autoRestartAgent("repo", "supervisor", agent, repo, "Claude exited to the bash prompt", now)
+	}
+
+	now := time.Now()
+	crash(now)
+	crash(now.Add(5 * time.Minute))
+	agent, _ := d.state.GetAgent("repo", "supervisor")
+	if agent.Restarts != 2 || !agent.LastRestart.Equal(now.Add(5*time.Minute)) {
+		t.Fatalf("Restarts = %d, LastRestart = %v; want 2 restarts recorded", agent.Restarts, agent.LastRestart)
+	}
+
+	// Past the limit the daemon gives up, and says so once
+	crash(now.Add(10 * time.Minute))
+	crash(now.Add(15 * time.Minute))
+	agent, _ = d.state.GetAgent("repo", "supervisor")
+	if agent.Restarts != 2 {
+		t.Errorf("Restarts = %d, want no restarts past the limit", agent.Restarts)
+	}
+	recent := d.notify.Recent(0)
+	if len(recent) != 3 {
+		t.Fatalf("got %d events, want 2 restarts and 1 give-up", len(recent))
+	}
+	for _, e := range recent {
+		if e.Type != events.EventAgentError {
+			t.Errorf("event type = %s, want agent.error", e.Type)
+		}
+	}
+	if !strings.Contains(recent[0].Title, "automatic restarts stopped") || recent[0].Priority != events.PriorityHigh {
+		t.Errorf("give-up event = %+v", recent[0])
+	}
+	if payload := recent[1].Payload.(events.AgentErrorPayload); !strings.Contains(payload.Error, "restarted automatically (2 of 2)") {
+		t.Errorf("restart event error = %q", payload.Error)
+	}
+
+	// After running steadily, earlier crashes no longer count
+	crash(now.Add(5*time.Minute + state.RestartStableAfter))
+	agent, _ = d.state.GetAgent("repo", "supervisor")
+	if agent.Restarts != 1 {
+		t.Errorf("Restarts = %d, want the count to start over", agent.Restarts)
+	}
+}
+
+func TestAutoRestartAgentSkipped(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("off", &state.Repository{TmuxSession: "mc-test-restart-off", Restart: state.RestartPolicy{Off: true}, Agents: make(map[string]state.Agent)})
+		s.AddAgent("off", "worker", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker"})
+		s.AddRepo("on", &state.Repository{TmuxSession: "mc-test-restart-on", Agents: make(map[string]state.Agent)})
+		s.AddAgent("on", "workspace", state.Agent{Type: state.AgentTypeWorkspace, TmuxWindow: "workspace"})
+	})
+	defer cleanup()
+
+	for repoName, agentName := range map[string]string{"off": "worker", "on": "workspace"} {
+		repo, _ := d.state.GetRepo(repoName)
+		agent, _ := d.state.GetAgent(repoName, agentName)
+		d.autoRestartAgent(repoName, agentName, agent, repo, "its pane died", time.Now())
+		if agent, _ := d.state.GetAgent(repoName, agentName); agent.Restarts != 0 {
+			t.Errorf("%s/%s was restarted", repoName, agentName)
+		}
+	}
+	if n := len(d.notify.Recent(0)); n != 0 {
+		t.Errorf("got %d events, want none", n)
+	}
+	if got := restartMax(state.RestartPolicy{Off: true, MaxRestarts: 5}); got != 0 {
+		t.Errorf("restartMax(off) = %d, want 0", got)
+	}
+}
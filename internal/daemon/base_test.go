@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+func TestRefreshFollowsWorkerBase(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoName := "base-repo"
+	repoPath := d.paths.RepoDir(repoName)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	commitFile := func(dir, name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGitIn(t, dir, "add", name)
+		runGitIn(t, dir, "commit", "-m", "Add "+name)
+	}
+	runGitIn(t, repoPath, "init", "-b", "main")
+	runGitIn(t, repoPath, "config", "user.email", "test@example.com")
+	runGitIn(t, repoPath, "config", "user.name", "Test User")
+	commitFile(repoPath, "README")
+	runGitIn(t, repoPath, "branch", "release")
+	runGitIn(t, repoPath, "remote", "add", "origin", repoPath)
+	runGitIn(t, repoPath, "fetch", "origin")
+
+	wtPath := d.paths.AgentWorktree(repoName, "worker")
+	if err := worktree.NewManager(repoPath).CreateNewBranch(wtPath, "work/worker", "origin/release"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	commitFile(wtPath, "worker.txt")
+
+	// main and release both move on; the worker must only follow release
+	commitFile(repoPath, "main.txt")
+	runGitIn(t, repoPath, "checkout", "-q", "release")
+	commitFile(repoPath, "release.txt")
+	runGitIn(t, repoPath, "checkout", "-q", "main")
+
+	d.state.AddRepo(repoName, &state.Repository{
+		TmuxSession: "mc-base-repo",
+		Agents: map[string]state.Agent{
+			"worker": {Type: state.AgentTypeWorker, WorktreePath: wtPath, TmuxWindow: "worker", Base: "release", BaseBranch: "release"},
+		},
+	})
+
+	d.refreshWorktrees()
+	if _, err := os.Stat(filepath.Join(wtPath, "release.txt")); err != nil {
+		t.Error("worker should be rebased onto its base branch")
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "main.txt")); err == nil {
+		t.Error("worker on a base branch must not pick up main")
+	}
+
+	agent, _ := d.state.GetAgent(repoName, "worker")
+	if ref, err := d.agentBaseRef(repoName, agent); err != nil || ref != "origin/release" {
+		t.Errorf("agentBaseRef = %q, %v; want origin/release", ref, err)
+	}
+
+	// The repo default base is validated against origin
+	update := func(base string) socket.Response {
+		return d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+			"name": repoName, "default_base": base,
+		}})
+	}
+	if resp := update("no-such-branch"); resp.Success {
+		t.Error("update_repo_config should reject a base origin doesn't have")
+	}
+	if resp := update("origin/release"); !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	resp := d.handleRequest(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": repoName}})
+	if base := resp.Data.(socket.RepoConfig).DefaultBase; base != "release" {
+		t.Errorf("default_base = %v, want release", base)
+	}
+}
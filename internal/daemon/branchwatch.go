@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// checkExternalPush tells a worker (and humans, via the notification hub)
+// when someone else pushed commits to its branch. It returns true while the
+// worktree is behind its remote branch, so callers can hold off rebasing it
+// onto main until the pushed commits are pulled in.
+func (d *Daemon) checkExternalPush(repoName, agentName string, agent state.Agent, remote string) bool {
+	push, err := worktree.DetectExternalPush(agent.WorktreePath, remote)
+	if err != nil {
+		d.logger.Debug("Could not check %s/%s for external pushes: %v", repoName, agentName, err)
+		return false
+	}
+	if push == nil {
+		return false
+	}
+	if push.Head == agent.ExternalHead {
+		// Already reported this push
+		return true
+	}
+
+	agent.ExternalHead = push.Head
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		d.logger.Error("Failed to record external push for %s/%s: %v", repoName, agentName, err)
+		return true
+	}
+
+	by := "someone else"
+	if len(push.Authors) > 0 {
+		by = strings.Join(push.Authors, ", ")
+	}
+	message := fmt.Sprintf("%d new commit(s) were pushed to your branch %s/%s by %s. Your worktree does not have them yet, so pushing now would fail or overwrite their work.\n"+
+		"Run `multiclaude agent pull` to rebase onto them (uncommitted changes are stashed and restored), then review what changed with `git log --stat -%d`.",
+		push.Commits, push.Remote, push.Branch, by, push.Commits)
+	if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, message); err != nil {
+		d.logger.Warn("Failed to tell %s about pushes to its branch: %v", agentName, err)
+	}
+	go d.routeMessages()
+
+	event := events.NewTypedEvent(repoName, agentName,
+		fmt.Sprintf("%d commit(s) pushed to %s's branch %s", push.Commits, agentName, push.Branch),
+		events.BranchPushedPayload{
+			Branch:  push.Branch,
+			Head:    push.Head,
+			Commits: push.Commits,
+			Authors: push.Authors,
+		})
+	event.Message = fmt.Sprintf("Run `multiclaude work pull %s` to rebase the worktree onto them.", agentName)
+	d.emitEvent(event)
+
+	d.logger.Info("Detected %d external commit(s) on %s/%s branch %s", push.Commits, repoName, agentName, push.Branch)
+	d.recordAction(repoName, feed.ActionBranchPushed, agentName, fmt.Sprintf("%d commit(s) by %s", push.Commits, by))
+	return true
+}
+
+// handlePullAgentBranch rebases an agent's worktree onto its remote branch,
+// picking up commits pushed by someone else
+func (d *Daemon) handlePullAgentBranch(req socket.Request, args socket.AgentArgs) (socket.PulledBranch, error) {
+	agent, exists := d.state.GetAgent(args.Repo, args.Agent)
+	if !exists {
+		return socket.PulledBranch{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", args.Agent, args.Repo)
+	}
+	if agent.WorktreePath == "" {
+		return socket.PulledBranch{}, fmt.Errorf("agent '%s' has no worktree", args.Agent)
+	}
+
+	remote := d.pushRemote(args.Repo)
+	result := worktree.PullBranch(agent.WorktreePath, remote)
+	if result.Skipped {
+		return socket.PulledBranch{}, fmt.Errorf("cannot pull: %s", result.SkipReason)
+	}
+	if result.Error != nil {
+		if result.HasConflicts {
+			return socket.PulledBranch{}, fmt.Errorf("rebase onto %s/%s conflicts in %s; the rebase was aborted and the worktree is unchanged",
+				remote, result.Branch, strings.Join(result.ConflictFiles, ", "))
+		}
+		return socket.PulledBranch{}, result.Error
+	}
+
+	d.logger.Info("Pulled %s/%s into %s/%s (%d local commits replayed)", remote, result.Branch, args.Repo, args.Agent, result.CommitsRebased)
+	return socket.PulledBranch{
+		Branch:         result.Branch,
+		Remote:         remote,
+		CommitsRebased: result.CommitsRebased,
+		Stashed:        result.WasStashed,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestCheckExternalPushAndPullAgentBranch(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "push-repo")
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "Agent work")
+
+	// Publish the worker branch, then push to it from a human's clone
+	tmp := t.TempDir()
+	remotePath := filepath.Join(tmp, "remote.git")
+	runGitIn(t, tmp, "init", "--bare", remotePath)
+	runGitIn(t, repoPath, "remote", "set-url", "origin", remotePath)
+	runGitIn(t, repoPath, "push", "origin", "main", "work/push-repo")
+
+	humanPath := filepath.Join(tmp, "human")
+	runGitIn(t, tmp, "clone", "--branch", "work/push-repo", remotePath, humanPath)
+	if err := os.WriteFile(filepath.Join(humanPath, "fix.txt"), []byte("fix\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGitIn(t, humanPath, "add", "fix.txt")
+	runGitIn(t, humanPath, "-c", "user.name=Human", "-c", "user.email=human@example.com", "commit", "-m", "Fix typo")
+	runGitIn(t, humanPath, "push", "origin", "work/push-repo")
+	runGitIn(t, repoPath, "fetch", "origin")
+
+	d.state.AddRepo("push-repo", &state.Repository{TmuxSession: "mc-push-repo", Agents: make(map[string]state.Agent)})
+	d.state.AddAgent("push-repo", "worker", state.Agent{Type: state.AgentTypeWorker, WorktreePath: repoPath})
+
+	agent, _ := d.state.GetAgent("push-repo", "worker")
+	if !d.checkExternalPush("push-repo", "worker", agent, "origin") {
+		t.Fatal("checkExternalPush() should report the human's push")
+	}
+	agent, _ = d.state.GetAgent("push-repo", "worker")
+	if agent.ExternalHead == "" {
+		t.Error("ExternalHead should be recorded")
+	}
+	msgs, err := d.getMessageManager().List("push-repo", "worker")
+	if err != nil || len(msgs) != 1 || !strings.Contains(msgs[0].Body, "multiclaude agent pull") {
+		t.Fatalf("expected one pull notice, got %v (err %v)", msgs, err)
+	}
+
+	// The same push is only reported once
+	if !d.checkExternalPush("push-repo", "worker", agent, "origin") {
+		t.Error("checkExternalPush() should keep holding refreshes until the push is pulled")
+	}
+	if msgs, _ := d.getMessageManager().List("push-repo", "worker"); len(msgs) != 1 {
+		t.Errorf("expected the push to be reported once, got %d messages", len(msgs))
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "pull_agent_branch", Args: map[string]interface{}{
+		"repo": "push-repo", "agent": "worker",
+	}})
+	if !resp.Success {
+		t.Fatalf("pull_agent_branch failed: %s", resp.Error)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "fix.txt")); err != nil {
+		t.Errorf("pushed commit should be in the worktree: %v", err)
+	}
+	if d.checkExternalPush("push-repo", "worker", agent, "origin") {
+		t.Error("checkExternalPush() should be clear after pulling")
+	}
+}
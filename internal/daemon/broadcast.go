@@ -0,0 +1,191 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// defaultBroadcastTimeout is how long agents have to answer a broadcast
+// question when the asker doesn't say
+const defaultBroadcastTimeout = 5 * time.Minute
+
+// broadcastRetention is how long a closed broadcast's replies are kept for
+// late status requests
+const broadcastRetention = time.Hour
+
+// broadcastSender is who broadcast questions are from in agents' messages
+const broadcastSender = "broadcast"
+
+// handleBroadcastQuestion messages a question to every active agent in
+// "repo" except the workspace, which is the asker's own. Agents answer with
+// `multiclaude agent reply`; poll broadcast_status for the replies.
+func (d *Daemon) handleBroadcastQuestion(req socket.Request, args socket.BroadcastQuestionArgs) (socket.Broadcast, error) {
+	timeout := defaultBroadcastTimeout
+	if args.TimeoutSeconds != nil {
+		if *args.TimeoutSeconds <= 0 {
+			return socket.Broadcast{}, socket.Errorf(socket.CodeInvalidArgument, "timeout must be positive")
+		}
+		timeout = time.Duration(*args.TimeoutSeconds * float64(time.Second))
+	}
+
+	repo, exists := d.state.GetAllRepos()[args.Repo]
+	if !exists {
+		return socket.Broadcast{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", args.Repo)
+	}
+
+	now := d.clock.Now()
+	b := &socket.Broadcast{
+		ID:       uuid.New().String()[:8],
+		Repo:     args.Repo,
+		Question: args.Question,
+		AskedAt:  now,
+		Deadline: now.Add(timeout),
+		Replies:  []socket.BroadcastReply{},
+	}
+	for name, agent := range repo.Agents {
+		if agent.Type == state.AgentTypeWorkspace || agent.ReadyForCleanup {
+			continue
+		}
+		b.Replies = append(b.Replies, socket.BroadcastReply{Agent: name, Type: agent.Type, Task: agent.Task})
+	}
+	if len(b.Replies) == 0 {
+		return socket.Broadcast{}, fmt.Errorf("no active agents in '%s' to ask", args.Repo)
+	}
+	sort.Slice(b.Replies, func(i, j int) bool { return b.Replies[i].Agent < b.Replies[j].Agent })
+
+	msg := fmt.Sprintf("A human is asking every agent (broadcast %s): %s\n\n"+
+		"Answer within %s with `multiclaude agent reply %s \"<your answer>\"`, even if the answer is no. "+
+		"Start with yes or no when the question allows it, keep it to a sentence or two, then carry on with your task.",
+		b.ID, args.Question, timeout.Round(time.Second), b.ID)
+	msgMgr := d.getMessageManager()
+	for _, reply := range b.Replies {
+		if _, err := msgMgr.Send(args.Repo, broadcastSender, reply.Agent, msg); err != nil {
+			return socket.Broadcast{}, fmt.Errorf("failed to message %s: %v", reply.Agent, err)
+		}
+	}
+	go d.routeMessages()
+
+	d.broadcastsMu.Lock()
+	d.pruneBroadcastsLocked(now)
+	d.broadcasts[b.ID] = b
+	snapshot := snapshotBroadcast(b, now)
+	d.broadcastsMu.Unlock()
+
+	d.logger.Info("Broadcast %s asked %d agents in %s: %s", b.ID, len(b.Replies), args.Repo, args.Question)
+	return snapshot, nil
+}
+
+// handleBroadcastReply records an agent's answer to a broadcast question
+func (d *Daemon) handleBroadcastReply(req socket.Request, args socket.BroadcastReplyArgs) (socket.Broadcast, error) {
+	now := d.clock.Now()
+	d.broadcastsMu.Lock()
+	defer d.broadcastsMu.Unlock()
+
+	b, exists := d.broadcasts[args.ID]
+	if !exists || b.Repo != args.Repo {
+		return socket.Broadcast{}, socket.Errorf(socket.CodeNotFound, "no broadcast %s in '%s'", args.ID, args.Repo)
+	}
+	if !now.Before(b.Deadline) {
+		return socket.Broadcast{}, fmt.Errorf("broadcast %s closed at %s", args.ID, b.Deadline.Format(time.Kitchen))
+	}
+	for i := range b.Replies {
+		if b.Replies[i].Agent == args.Agent {
+			b.Replies[i].Answer = args.Answer
+			b.Replies[i].RepliedAt = now
+			d.logger.Info("Broadcast %s: %s replied", args.ID, args.Agent)
+			return snapshotBroadcast(b, now), nil
+		}
+	}
+	return socket.Broadcast{}, fmt.Errorf("agent '%s' was not asked in broadcast %s", args.Agent, args.ID)
+}
+
+// handleBroadcastStatus returns a broadcast's replies so far
+func (d *Daemon) handleBroadcastStatus(req socket.Request, args socket.BroadcastStatusArgs) (socket.Broadcast, error) {
+	d.broadcastsMu.Lock()
+	defer d.broadcastsMu.Unlock()
+	b, exists := d.broadcasts[args.ID]
+	if !exists {
+		return socket.Broadcast{}, socket.Errorf(socket.CodeNotFound, "no broadcast %s", args.ID)
+	}
+	return snapshotBroadcast(b, d.clock.Now()), nil
+}
+
+// snapshotBroadcast returns a copy of b as of now with Done and Summary
+// filled in. The caller holds broadcastsMu.
+func snapshotBroadcast(b *socket.Broadcast, now time.Time) socket.Broadcast {
+	snapshot := *b
+	snapshot.Replies = append([]socket.BroadcastReply(nil), b.Replies...)
+	snapshot.Done = !now.Before(b.Deadline)
+	if !snapshot.Done {
+		snapshot.Done = true
+		for _, reply := range b.Replies {
+			if reply.RepliedAt.IsZero() {
+				snapshot.Done = false
+				break
+			}
+		}
+	}
+	snapshot.Summary = summarizeBroadcast(snapshot)
+	return snapshot
+}
+
+// pruneBroadcastsLocked forgets broadcasts closed longer than broadcastRetention
+func (d *Daemon) pruneBroadcastsLocked(now time.Time) {
+	for id, b := range d.broadcasts {
+		if now.Sub(b.Deadline) > broadcastRetention {
+			delete(d.broadcasts, id)
+		}
+	}
+}
+
+// summarizeBroadcast tallies the replies, counting answers that start with
+// yes or no, e.g. "3 of 4 agents replied: 1 yes, 2 no. No reply from: w3"
+func summarizeBroadcast(b socket.Broadcast) string {
+	var replied, yes, no int
+	var silent []string
+	for _, reply := range b.Replies {
+		if reply.RepliedAt.IsZero() {
+			silent = append(silent, reply.Agent)
+			continue
+		}
+		replied++
+		switch leadingWord(reply.Answer) {
+		case "yes", "yep", "yeah":
+			yes++
+		case "no", "nope":
+			no++
+		}
+	}
+
+	summary := fmt.Sprintf("%d of %d agents replied", replied, len(b.Replies))
+	if yes+no > 0 {
+		summary += fmt.Sprintf(": %d yes, %d no", yes, no)
+		if other := replied - yes - no; other > 0 {
+			summary += fmt.Sprintf(", %d other", other)
+		}
+	}
+	summary += "."
+	if len(silent) > 0 {
+		summary += " No reply from: " + strings.Join(silent, ", ")
+	}
+	return summary
+}
+
+// leadingWord returns the first word of s, lowercased and without punctuation
+func leadingWord(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestBroadcastQuestion(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("fleet", &state.Repository{
+			TmuxSession: "mc-fleet",
+			Agents: map[string]state.Agent{
+				"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+				"workspace":  {Type: state.AgentTypeWorkspace, TmuxWindow: "workspace"},
+				"w1":         {Type: state.AgentTypeWorker, TmuxWindow: "w1", Task: "add caching"},
+				"w2":         {Type: state.AgentTypeWorker, TmuxWindow: "w2", Task: "rename package"},
+				"done":       {Type: state.AgentTypeWorker, TmuxWindow: "done", ReadyForCleanup: true},
+			},
+		})
+	})
+	defer cleanup()
+	fake := clock.NewFake(time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	d.clock = fake
+
+	timeout := float64(120)
+	b, err := d.handleBroadcastQuestion(socket.Request{}, socket.BroadcastQuestionArgs{
+		Repo: "fleet", Question: "Does anyone depend on pkg/legacy?", TimeoutSeconds: &timeout,
+	})
+	if err != nil {
+		t.Fatalf("broadcast failed: %v", err)
+	}
+	if len(b.Replies) != 3 || b.Replies[0].Agent != "supervisor" || b.Replies[1].Agent != "w1" || b.Replies[2].Agent != "w2" {
+		t.Fatalf("should ask the supervisor and active workers only, asked %+v", b.Replies)
+	}
+
+	msgs, err := d.getMessageManager().List("fleet", "w1")
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("w1 should have one message, got %v (%v)", msgs, err)
+	}
+	if msgs[0].From != broadcastSender || !strings.Contains(msgs[0].Body, "pkg/legacy") || !strings.Contains(msgs[0].Body, "multiclaude agent reply "+b.ID) {
+		t.Errorf("unexpected message %+v", msgs[0])
+	}
+
+	reply := func(agent, answer string) (socket.Broadcast, error) {
+		return d.handleBroadcastReply(socket.Request{}, socket.BroadcastReplyArgs{
+			ID: b.ID, Repo: "fleet", Agent: agent, Answer: answer,
+		})
+	}
+	if _, err := reply("w1", "Yes, internal/cache imports it."); err != nil {
+		t.Fatalf("reply failed: %v", err)
+	}
+	if got, err := reply("w2", "No."); err != nil || got.Done {
+		t.Fatalf("reply failed or broadcast closed early: %+v, %v", got, err)
+	}
+	if _, err := reply("workspace", "me too"); err == nil {
+		t.Error("an agent that wasn't asked should not be able to reply")
+	}
+
+	status := func() socket.Broadcast {
+		got, err := d.handleBroadcastStatus(socket.Request{}, socket.BroadcastStatusArgs{ID: b.ID})
+		if err != nil {
+			t.Fatalf("status failed: %v", err)
+		}
+		return got
+	}
+	if got := status().Summary; got != "2 of 3 agents replied: 1 yes, 1 no. No reply from: supervisor" {
+		t.Errorf("summary = %q", got)
+	}
+
+	fake.Advance(2 * time.Minute)
+	if !status().Done {
+		t.Error("broadcast should be done once the timeout passes")
+	}
+	if _, err := reply("supervisor", "no"); err == nil {
+		t.Error("replies after the deadline should be rejected")
+	}
+}
+
+func TestBroadcastQuestionNeedsAgents(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("empty", &state.Repository{
+			TmuxSession: "mc-empty",
+			Agents:      map[string]state.Agent{"workspace": {Type: state.AgentTypeWorkspace}},
+		})
+	})
+	defer cleanup()
+
+	_, err := d.handleBroadcastQuestion(socket.Request{}, socket.BroadcastQuestionArgs{Repo: "empty", Question: "anyone?"})
+	if err == nil || !strings.Contains(err.Error(), "no active agents") {
+		t.Errorf("broadcast to a repo with only a workspace should fail, got %v", err)
+	}
+}
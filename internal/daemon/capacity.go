@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// activeWorkers counts a repository's workers that haven't completed. repo
+// must be a copy from GetAllRepos, since completing workers are removed from
+// the state's own maps concurrently.
+func activeWorkers(repo *state.Repository) int {
+	n := 0
+	for _, agent := range repo.Agents {
+		if agent.Type == state.AgentTypeWorker && !agent.ReadyForCleanup {
+			n++
+		}
+	}
+	return n
+}
+
+// workerCapacityError returns an error if the repository already runs as
+// many workers as its max_workers setting, or the config file's, allows, or
+// if agents' worktrees have reached their disk quota
+func (d *Daemon) workerCapacityError(repoName string) error {
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return nil
+	}
+	max := d.maxWorkers(repoName, repo)
+	if n := activeWorkers(repo); max > 0 && n >= max {
+		return fmt.Errorf("repository '%s' already has %d of its %d workers running; queue the task to start when one finishes with: multiclaude task add \"<task>\" --repo %s, or raise the limit with: multiclaude config %s --max-workers=<n>",
+			repoName, n, max, repoName, repoName)
+	}
+	return d.diskQuotaError()
+}
+
+// handleCheckWorkerCapacity reports whether another worker may be started in
+// a repository, so `multiclaude work` can stop before creating a worktree
+func (d *Daemon) handleCheckWorkerCapacity(req socket.Request, args socket.RepoArgs) (socket.WorkerCapacity, error) {
+	repo, exists := d.state.GetAllRepos()[args.Repo]
+	if !exists {
+		return socket.WorkerCapacity{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", args.Repo)
+	}
+	capacity := socket.WorkerCapacity{
+		Running:    activeWorkers(repo),
+		MaxWorkers: d.maxWorkers(args.Repo, repo),
+		Available:  true,
+	}
+	if err := d.workerCapacityError(args.Repo); err != nil {
+		capacity.Available = false
+		capacity.Error = err.Error()
+	}
+	if quota := d.configFile().Worktrees.QuotaBytes(); quota > 0 {
+		capacity.DiskBytes = d.totalDiskUsage()
+		capacity.DiskQuota = quota
+	}
+	return capacity, nil
+}
@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestMaxWorkers(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "supervisor", state.Agent{Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"})
+		s.AddAgent("repo", "fox", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "fox"})
+		s.AddAgent("repo", "done", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "done", ReadyForCleanup: true})
+	})
+	defer cleanup()
+
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "repo", "max_workers": float64(1),
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+
+	addWorker := func(name string) socket.Response {
+		return d.handleRequest(socket.Request{Command: "add_agent", Args: map[string]interface{}{
+			"repo": "repo", "agent": name, "type": "worker", "worktree_path": "/tmp/" + name, "tmux_window": name,
+		}})
+	}
+
+	// Only fox counts: the supervisor isn't a worker and done has completed
+	resp = addWorker("owl")
+	if resp.Success || !strings.Contains(resp.Error, "multiclaude task add") {
+		t.Errorf("add_agent at the limit = %+v, want an error suggesting the task queue", resp)
+	}
+
+	capacity, err := d.handleCheckWorkerCapacity(socket.Request{}, socket.RepoArgs{Repo: "repo"})
+	if err != nil || capacity.Available || capacity.Running != 1 {
+		t.Errorf("check_worker_capacity = %+v, %v", capacity, err)
+	}
+
+	// Queued tasks wait while the repository is full
+	d.state.AddQueuedTask("repo", state.QueuedTask{ID: "task-1", Description: "Later", State: state.QueuedTaskQueued})
+	d.dispatchTaskQueue("repo")
+	if repo, _ := d.state.GetRepo("repo"); repo.Tasks[0].State != state.QueuedTaskQueued {
+		t.Errorf("task state = %s, want it to stay queued", repo.Tasks[0].State)
+	}
+
+	// Clearing the limit dispatches the queue, so empty it first
+	d.state.RemoveQueuedTask("repo", "task-1")
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "repo", "max_workers": float64(0),
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	if capacity, _ := d.handleCheckWorkerCapacity(socket.Request{}, socket.RepoArgs{Repo: "repo"}); !capacity.Available {
+		t.Errorf("check_worker_capacity without a limit = %+v", capacity)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "repo", "max_workers": float64(-1),
+	}})
+	if resp.Success {
+		t.Error("a negative max_workers should be rejected")
+	}
+}
@@ -0,0 +1,262 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// ChaosEnv is the environment variable that enables chaos mode, a developer
+// tool that injects failures into a running daemon so the repair and retry
+// paths can be exercised before users hit those states. It is either "1" for
+// the default rates or a comma-separated list of settings:
+//
+//	MULTICLAUDE_CHAOS=kill-window=0.2,corrupt-index=0,delay=0.5,max-delay=3s,drop-send=0.1,interval=30s,seed=42
+//
+// Never enable it on a daemon whose agents are doing real work.
+const ChaosEnv = "MULTICLAUDE_CHAOS"
+
+// ChaosConfig sets how often chaos mode injects each kind of failure.
+// Probabilities are between 0 and 1.
+type ChaosConfig struct {
+	// KillWindow is the chance per agent and strike that its tmux window is killed
+	KillWindow float64
+	// CorruptIndex is the chance per worktree and strike that its git index
+	// is overwritten with garbage
+	CorruptIndex float64
+	// Delay is the chance that a socket response is delayed by up to MaxDelay
+	Delay    float64
+	MaxDelay time.Duration
+	// DropSend is the chance that an event is not delivered to an adapter
+	DropSend float64
+	// Interval is the time between strikes on windows and worktrees
+	Interval time.Duration
+	// Seed seeds the random source; 0 seeds from the current time
+	Seed int64
+}
+
+// DefaultChaosConfig returns the rates used for MULTICLAUDE_CHAOS=1
+func DefaultChaosConfig() ChaosConfig {
+	return ChaosConfig{
+		KillWindow:   0.1,
+		CorruptIndex: 0.05,
+		Delay:        0.2,
+		MaxDelay:     2 * time.Second,
+		DropSend:     0.1,
+		Interval:     time.Minute,
+	}
+}
+
+// ParseChaos parses the value of MULTICLAUDE_CHAOS. Settings that are not
+// given keep their default.
+func ParseChaos(spec string) (ChaosConfig, error) {
+	cfg := DefaultChaosConfig()
+	spec = strings.TrimSpace(spec)
+	if spec == "1" || spec == "on" {
+		return cfg, nil
+	}
+
+	for _, field := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return cfg, fmt.Errorf("invalid chaos setting %q: expected key=value", field)
+		}
+		var err error
+		switch key {
+		case "kill-window":
+			cfg.KillWindow, err = parseProbability(value)
+		case "corrupt-index":
+			cfg.CorruptIndex, err = parseProbability(value)
+		case "delay":
+			cfg.Delay, err = parseProbability(value)
+		case "drop-send":
+			cfg.DropSend, err = parseProbability(value)
+		case "max-delay":
+			cfg.MaxDelay, err = time.ParseDuration(value)
+		case "interval":
+			cfg.Interval, err = time.ParseDuration(value)
+			if err == nil && cfg.Interval <= 0 {
+				err = fmt.Errorf("must be positive")
+			}
+		case "seed":
+			cfg.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return cfg, fmt.Errorf("unknown chaos setting %q", key)
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("invalid chaos setting %s=%s: %w", key, value, err)
+		}
+	}
+	return cfg, nil
+}
+
+func parseProbability(value string) (float64, error) {
+	p, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 1 {
+		return 0, fmt.Errorf("must be between 0 and 1")
+	}
+	return p, nil
+}
+
+// String summarizes the config for the daemon log
+func (c ChaosConfig) String() string {
+	return fmt.Sprintf("kill-window=%g corrupt-index=%g delay=%g max-delay=%s drop-send=%g interval=%s",
+		c.KillWindow, c.CorruptIndex, c.Delay, c.MaxDelay, c.DropSend, c.Interval)
+}
+
+// WithChaos enables chaos mode
+func WithChaos(cfg ChaosConfig) Option {
+	return func(d *Daemon) {
+		seed := cfg.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		d.chaos = &chaosMonkey{cfg: cfg, rng: rand.New(rand.NewSource(seed))}
+	}
+}
+
+// chaosMonkey holds chaos mode's config and random source
+type chaosMonkey struct {
+	cfg ChaosConfig
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// roll reports whether an event with probability p happens
+func (c *chaosMonkey) roll(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < p
+}
+
+// duration returns a random duration up to max
+func (c *chaosMonkey) duration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.rng.Int63n(int64(max)))
+}
+
+// chaosDelay holds up a socket response when chaos mode rolls for it, so
+// client timeouts and retries get exercised
+func (d *Daemon) chaosDelay(command string) {
+	if d.chaos == nil || !d.chaos.roll(d.chaos.cfg.Delay) {
+		return
+	}
+	delay := d.chaos.duration(d.chaos.cfg.MaxDelay)
+	d.logger.Warn("Chaos: delaying %s response by %s", command, delay)
+	select {
+	case <-time.After(delay):
+	case <-d.ctx.Done():
+	}
+}
+
+// chaosAdapter drops a share of the events sent to the adapter it wraps
+type chaosAdapter struct {
+	notify.Adapter
+	chaos *chaosMonkey
+}
+
+func (a *chaosAdapter) Send(ctx context.Context, event events.Event) error {
+	if a.chaos.roll(a.chaos.cfg.DropSend) {
+		return fmt.Errorf("chaos: dropped %s event", event.Type)
+	}
+	return a.Adapter.Send(ctx, event)
+}
+
+// registerAdapter adds an adapter to the notification hub, wrapped so chaos
+// mode can drop its sends
+func (d *Daemon) registerAdapter(adapter notify.Adapter) {
+	if d.chaos != nil {
+		adapter = &chaosAdapter{Adapter: adapter, chaos: d.chaos}
+	}
+	d.notify.Register(adapter)
+}
+
+// chaosLoop strikes agents' windows and worktrees at the configured interval
+func (d *Daemon) chaosLoop() {
+	d.periodicLoop("chaos", d.chaos.cfg.Interval, nil, d.chaosStrike)
+}
+
+// chaosStrike rolls for every agent whether to kill its tmux window and
+// whether to corrupt its worktree's git index. Read-only repositories are
+// left alone.
+func (d *Daemon) chaosStrike() {
+	repos := d.state.GetAllRepos()
+	repoNames := make([]string, 0, len(repos))
+	for name := range repos {
+		repoNames = append(repoNames, name)
+	}
+	sort.Strings(repoNames)
+
+	for _, repoName := range repoNames {
+		if d.isReadOnlyRepo(repoName, "chaos") {
+			continue
+		}
+		repo := repos[repoName]
+		agentNames := make([]string, 0, len(repo.Agents))
+		for name := range repo.Agents {
+			agentNames = append(agentNames, name)
+		}
+		sort.Strings(agentNames)
+
+		for _, agentName := range agentNames {
+			agent := repo.Agents[agentName]
+			if d.chaos.roll(d.chaos.cfg.KillWindow) && !agent.Headless {
+				if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
+					d.logger.Debug("Chaos: failed to kill window %s:%s: %v", repo.TmuxSession, agent.TmuxWindow, err)
+				} else {
+					d.logger.Warn("Chaos: killed window of %s/%s", repoName, agentName)
+				}
+			}
+			// The main clone is shared by every agent of the repository, so
+			// only indexes of agents' own worktrees are corrupted
+			if agent.WorktreePath == "" || agent.WorktreePath == d.paths.RepoDir(repoName) {
+				continue
+			}
+			if d.chaos.roll(d.chaos.cfg.CorruptIndex) {
+				if err := corruptWorktreeIndex(agent.WorktreePath); err != nil {
+					d.logger.Debug("Chaos: failed to corrupt index of %s/%s: %v", repoName, agentName, err)
+				} else {
+					d.logger.Warn("Chaos: corrupted git index of %s/%s", repoName, agentName)
+				}
+			}
+		}
+	}
+}
+
+// corruptWorktreeIndex overwrites the git index of a linked worktree with
+// bytes git rejects
+func corruptWorktreeIndex(worktreePath string) error {
+	gitFile := filepath.Join(worktreePath, ".git")
+	data, err := os.ReadFile(gitFile)
+	if err != nil {
+		return err
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return fmt.Errorf("%s is not a linked worktree", worktreePath)
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(worktreePath, gitDir)
+	}
+	return os.WriteFile(filepath.Join(gitDir, "index"), []byte("chaos: not a git index\n"), 0644)
+}
@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestParseChaos(t *testing.T) {
+	cfg, err := ParseChaos("1")
+	if err != nil || cfg != DefaultChaosConfig() {
+		t.Errorf("ParseChaos(1) = %+v, %v; want defaults", cfg, err)
+	}
+
+	cfg, err = ParseChaos("kill-window=0.5, delay=1,max-delay=50ms,interval=10s,seed=7")
+	if err != nil {
+		t.Fatalf("ParseChaos failed: %v", err)
+	}
+	if cfg.KillWindow != 0.5 || cfg.Delay != 1 || cfg.MaxDelay != 50*time.Millisecond || cfg.Interval != 10*time.Second || cfg.Seed != 7 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg.DropSend != DefaultChaosConfig().DropSend {
+		t.Errorf("unset settings should keep their default, got drop-send=%g", cfg.DropSend)
+	}
+
+	for _, spec := range []string{"kill-window", "kill-window=2", "delay=-0.1", "interval=0s", "explode=1", "seed=x"} {
+		if _, err := ParseChaos(spec); err == nil {
+			t.Errorf("ParseChaos(%q) should fail", spec)
+		}
+	}
+}
+
+func TestChaosDropsSends(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	WithChaos(ChaosConfig{DropSend: 1, Seed: 1})(d)
+	adapter := &recordingAdapter{}
+	d.RegisterAdapter(adapter)
+
+	err := d.notify.Notify(context.Background(), events.NewEvent(events.EventAgentCompleted, "repo", "worker-1", "done"))
+	if err == nil {
+		t.Error("dropped send should be reported as a delivery failure")
+	}
+	if len(adapter.events) != 0 {
+		t.Errorf("adapter received %d events, want 0", len(adapter.events))
+	}
+
+	d.chaos.cfg.DropSend = 0
+	if err := d.notify.Notify(context.Background(), events.NewEvent(events.EventAgentCompleted, "repo", "worker-1", "done")); err != nil {
+		t.Errorf("Notify failed: %v", err)
+	}
+	if len(adapter.events) != 1 {
+		t.Errorf("adapter received %d events, want 1", len(adapter.events))
+	}
+}
+
+func TestChaosDelaysResponses(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	WithChaos(ChaosConfig{Delay: 1, MaxDelay: 50 * time.Millisecond, Seed: 1})(d)
+	resp := d.dispatchRequest(socket.Request{Command: "ping"})
+	if !resp.Success {
+		t.Errorf("delayed request failed: %s", resp.Error)
+	}
+}
+
+func TestChaosStrikeCorruptsIndex(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoName := "chaos-repo"
+	repoPath := d.paths.RepoDir(repoName)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGitIn(t, repoPath, "init", "-b", "main")
+	runGitIn(t, repoPath, "config", "user.email", "test@example.com")
+	runGitIn(t, repoPath, "config", "user.name", "Test User")
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "Initial commit")
+
+	wtPath := d.paths.AgentWorktree(repoName, "worker")
+	if err := worktree.NewManager(repoPath).CreateNewBranch(wtPath, "work/worker", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	d.state.AddRepo(repoName, &state.Repository{
+		TmuxSession: "mc-chaos-repo",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, WorktreePath: repoPath, TmuxWindow: "supervisor"},
+			"worker":     {Type: state.AgentTypeWorker, WorktreePath: wtPath, TmuxWindow: "worker"},
+		},
+	})
+
+	WithChaos(ChaosConfig{CorruptIndex: 1, Seed: 1})(d)
+	d.chaosStrike()
+
+	status := func(dir string) error {
+		cmd := exec.Command("git", "status")
+		cmd.Dir = dir
+		return cmd.Run()
+	}
+	if err := status(wtPath); err == nil {
+		t.Error("git status should fail in the worktree with a corrupted index")
+	}
+	if err := status(repoPath); err != nil {
+		t.Errorf("the main clone's index must not be touched: %v", err)
+	}
+}
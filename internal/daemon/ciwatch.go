@@ -0,0 +1,134 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// ciWatchSubsystem is the GitHub budget watching workers' CI is charged to
+const ciWatchSubsystem = "ci-watch"
+
+// ciWatchInterval is how often workers' pushed branches are checked
+const ciWatchInterval = time.Minute
+
+// ciWatchLoop reports CI results on workers' pushed branches
+func (d *Daemon) ciWatchLoop() {
+	d.periodicLoop("CI watch", ciWatchInterval, nil, d.watchWorkerCI)
+}
+
+// watchWorkerCI checks the CI of every worker branch pushed to a GitHub
+// repository
+func (d *Daemon) watchWorkerCI() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		if repo.GithubURL == "" {
+			continue
+		}
+		owner, name, err := github.ParseRepoURL(repo.GithubURL)
+		if err != nil {
+			continue
+		}
+		remote := repo.WorkerPushRemote()
+		for agentName, agent := range repo.Agents {
+			if agent.Type != state.AgentTypeWorker || agent.WorktreePath == "" {
+				continue
+			}
+			d.checkWorkerCI(repoName, agentName, agent, owner, name, remote)
+		}
+	}
+}
+
+// checkWorkerCI reports the CI result of a worker's pushed branch head once
+// every check has finished or one has failed. A failure is sent to the
+// worker's inbox so it can fix it; each head is reported once.
+func (d *Daemon) checkWorkerCI(repoName, agentName string, agent state.Agent, owner, name, remote string) {
+	branch, err := worktree.GetCurrentBranch(agent.WorktreePath)
+	if err != nil || branch == "" || branch == "HEAD" {
+		return
+	}
+	head, err := worktree.NewManager(d.paths.RepoDir(repoName)).RemoteHead(remote, branch)
+	if err != nil {
+		// Not pushed yet
+		return
+	}
+	if head == agent.CIHead {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, 30*time.Second)
+	defer cancel()
+	results, err := d.github.GetCheckResults(ctx, ciWatchSubsystem, owner, name, head)
+	if err != nil {
+		d.logger.Debug("Could not get CI results of %s/%s: %v", repoName, branch, err)
+		return
+	}
+	if len(results) == 0 {
+		// No CI has reported, or the repository has none
+		return
+	}
+	failing, pending := classifyChecks(results, nil)
+	if len(failing) == 0 && len(pending) > 0 {
+		return
+	}
+
+	result := github.CheckPassed
+	if len(failing) > 0 {
+		result = github.CheckFailed
+	}
+	current, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return
+	}
+	current.CIHead = head
+	current.CIResult = result
+	if err := d.state.UpdateAgent(repoName, agentName, current); err != nil {
+		d.logger.Error("Failed to record CI result for %s/%s: %v", repoName, agentName, err)
+		return
+	}
+
+	short := head
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if result == github.CheckPassed {
+		checks := make([]string, 0, len(results))
+		for check := range results {
+			checks = append(checks, check)
+		}
+		sort.Strings(checks)
+		event := events.NewTypedEvent(repoName, agentName, fmt.Sprintf("CI passed on %s", branch),
+			events.BranchCIPassedPayload{Branch: branch, SHA: head, PRNumber: agent.PRNumber, Checks: checks})
+		event.Priority = events.PriorityLow
+		d.emitEvent(event)
+		d.logger.Info("CI passed on %s/%s at %s", repoName, branch, short)
+		return
+	}
+
+	message := fmt.Sprintf("CI failed on your branch %s at %s: %s.\n"+
+		"See why with `gh run list --branch %s` and `gh run view <run-id> --log-failed`, then fix it, commit, and push.",
+		branch, short, strings.Join(failing, ", "), branch)
+	if len(pending) > 0 {
+		message += fmt.Sprintf(" Still running: %s.", strings.Join(pending, ", "))
+	}
+	if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, message); err != nil {
+		d.logger.Warn("Failed to tell %s about CI failures: %v", agentName, err)
+	}
+	go d.routeMessages()
+
+	event := events.NewTypedEvent(repoName, agentName, fmt.Sprintf("CI failed on %s", branch),
+		events.BranchCIFailedPayload{Branch: branch, SHA: head, PRNumber: agent.PRNumber, Failed: failing, Pending: pending})
+	event.Priority = events.PriorityHigh
+	event.Message = fmt.Sprintf("%s was asked to fix: %s", agentName, strings.Join(failing, ", "))
+	d.emitEvent(event)
+
+	d.logger.Info("CI failed on %s/%s at %s: %s", repoName, branch, short, strings.Join(failing, ", "))
+	d.recordAction(repoName, feed.ActionCIFailed, agentName, fmt.Sprintf("%s at %s: %s", branch, short, strings.Join(failing, ", ")))
+}
@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// fakeCheckRuns serves check runs for any commit from a mutable map of
+// check name to status ("queued", or a conclusion such as "failure")
+type fakeCheckRuns struct {
+	mu     sync.Mutex
+	checks map[string]string
+}
+
+func (f *fakeCheckRuns) set(checks map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checks = checks
+}
+
+func (f *fakeCheckRuns) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/check-runs"):
+		runs := []map[string]string{}
+		for name, result := range f.checks {
+			if result == "queued" {
+				runs = append(runs, map[string]string{"name": name, "status": "queued"})
+			} else {
+				runs = append(runs, map[string]string{"name": name, "status": "completed", "conclusion": result})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"check_runs": runs})
+	case strings.HasSuffix(r.URL.Path, "/status"):
+		json.NewEncoder(w).Encode(map[string]interface{}{"statuses": []interface{}{}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// TestWatchWorkerCI checks that a worker is told once when CI fails on its
+// pushed branch, and that a passing head is reported as ci.passed
+func TestWatchWorkerCI(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	recorder := &recordingAdapter{}
+	d.RegisterAdapter(recorder)
+
+	checks := &fakeCheckRuns{}
+	server := httptest.NewServer(checks)
+	defer server.Close()
+	d.github = github.NewClient(github.WithBaseURL(server.URL), github.WithToken("test-token"), github.WithCacheTTL(0))
+
+	repoPath := initWorkerBranchRepo(t, d, "ci-repo")
+	d.state.AddRepo("ci-repo", &state.Repository{
+		GithubURL:   "https://github.com/o/r",
+		TmuxSession: "mc-ci-repo",
+		Agents:      make(map[string]state.Agent),
+	})
+	d.state.AddAgent("ci-repo", "worker", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: repoPath,
+		TmuxWindow:   "worker",
+		CreatedAt:    time.Now(),
+	})
+
+	// Not pushed yet
+	checks.set(map[string]string{"test": "failure"})
+	d.watchWorkerCI()
+	if len(recorder.events) != 0 {
+		t.Fatalf("unpushed branch reported %d events", len(recorder.events))
+	}
+
+	runGitIn(t, repoPath, "fetch", "origin")
+	checks.set(map[string]string{"test": "queued", "lint": "success"})
+	d.watchWorkerCI()
+	if len(recorder.events) != 0 {
+		t.Fatalf("pending checks reported %d events", len(recorder.events))
+	}
+
+	checks.set(map[string]string{"test": "failure", "lint": "success", "e2e": "queued"})
+	d.watchWorkerCI()
+	d.watchWorkerCI()
+	if len(recorder.events) != 1 {
+		t.Fatalf("got %d events, want 1 ci.failed", len(recorder.events))
+	}
+	failed, ok := recorder.events[0].Payload.(events.BranchCIFailedPayload)
+	if recorder.events[0].Type != events.EventBranchCIFailed || !ok || failed.Branch != "work/ci-repo" ||
+		len(failed.Failed) != 1 || failed.Failed[0] != "test" || len(failed.Pending) != 1 {
+		t.Errorf("unexpected event %+v", recorder.events[0])
+	}
+	if recorder.events[0].Priority != events.PriorityHigh {
+		t.Errorf("ci.failed priority = %s, want high", recorder.events[0].Priority)
+	}
+	msgs, err := d.getMessageManager().List("ci-repo", "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Body, "CI failed on your branch work/ci-repo") || !strings.Contains(msgs[0].Body, "test") {
+		t.Errorf("worker messages = %+v, want one CI failure", msgs)
+	}
+	agent, _ := d.state.GetAgent("ci-repo", "worker")
+	if agent.CIHead != failed.SHA || agent.CIResult != github.CheckFailed {
+		t.Errorf("agent CI = %s %s, want %s failed", agent.CIHead, agent.CIResult, failed.SHA)
+	}
+
+	// A fix is pushed and passes
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "Fix test")
+	runGitIn(t, repoPath, "fetch", "origin")
+	checks.set(map[string]string{"test": "success", "lint": "success", "e2e": "skipped"})
+	d.watchWorkerCI()
+	if len(recorder.events) != 2 {
+		t.Fatalf("got %d events, want ci.passed after ci.failed", len(recorder.events))
+	}
+	passed, ok := recorder.events[1].Payload.(events.BranchCIPassedPayload)
+	if recorder.events[1].Type != events.EventBranchCIPassed || !ok || passed.SHA == failed.SHA || len(passed.Checks) != 3 {
+		t.Errorf("unexpected event %+v", recorder.events[1])
+	}
+	if msgs, _ := d.getMessageManager().List("ci-repo", "worker"); len(msgs) != 1 {
+		t.Errorf("passing CI should not message the worker, has %d messages", len(msgs))
+	}
+}
+
+// TestWatchWorkerCINoChecks checks that a repository without CI reports
+// nothing
+func TestWatchWorkerCINoChecks(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	recorder := &recordingAdapter{}
+	d.RegisterAdapter(recorder)
+
+	server := httptest.NewServer(&fakeCheckRuns{})
+	defer server.Close()
+	d.github = github.NewClient(github.WithBaseURL(server.URL), github.WithToken("test-token"), github.WithCacheTTL(0))
+
+	repoPath := initWorkerBranchRepo(t, d, "quiet-repo")
+	runGitIn(t, repoPath, "fetch", "origin")
+	d.state.AddRepo("quiet-repo", &state.Repository{GithubURL: "https://github.com/o/r", Agents: make(map[string]state.Agent)})
+	d.state.AddAgent("quiet-repo", "worker", state.Agent{Type: state.AgentTypeWorker, WorktreePath: repoPath, CreatedAt: time.Now()})
+
+	d.watchWorkerCI()
+	if len(recorder.events) != 0 {
+		t.Errorf("got %d events from a repository without CI", len(recorder.events))
+	}
+	if agent, _ := d.state.GetAgent("quiet-repo", "worker"); agent.CIHead != "" {
+		t.Errorf("CIHead = %q, want nothing recorded", agent.CIHead)
+	}
+}
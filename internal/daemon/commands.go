@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// newCommandRegistry returns the commands the daemon serves. Each handler
+// takes its arguments as a struct and answers with a typed value, so the
+// registry can reject unknown commands, requests missing a required argument
+// and, from version 1, arguments of the wrong type before they reach it.
+func (d *Daemon) newCommandRegistry() *socket.Registry {
+	r := socket.NewRegistry()
+	for _, cmd := range []socket.Command{
+		socket.TypedCommand("ping", func(socket.Request, socket.NoArgs) (string, error) {
+			return "pong", nil
+		}),
+		socket.TypedCommand("status", d.handleStatus),
+		socket.TypedCommand("stop", func(socket.Request, socket.NoArgs) (string, error) {
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				d.Stop()
+			}()
+			return "Daemon stopping", nil
+		}),
+		socket.TypedCommand("list_repos", d.handleListRepos),
+		socket.TypedCommand("fleet_status", d.fleetStatus),
+		socket.TypedCommand("whoami", d.handleWhoami),
+		socket.TypedCommand("set_socket_group", d.handleSetSocketGroup),
+		socket.TypedCommand("set_log_storage", d.handleSetLogStorage),
+		socket.TypedCommand("reload_config", d.handleReloadConfig),
+
+		// Repositories
+		socket.TypedCommand("add_repo", d.handleAddRepo),
+		socket.TypedCommand("remove_repo", d.handleRemoveRepo),
+		socket.TypedCommand("get_repo_config", d.handleGetRepoConfig),
+		socket.TypedCommand("update_repo_config", d.handleUpdateRepoConfig),
+		socket.TypedCommand("set_current_repo", d.handleSetCurrentRepo),
+		socket.TypedCommand("get_current_repo", d.handleGetCurrentRepo),
+		socket.TypedCommand("clear_current_repo", d.handleClearCurrentRepo),
+		socket.TypedCommand("repo_lock", d.handleRepoLock),
+		socket.TypedCommand("resume_refresh", d.handleResumeRefresh),
+
+		// Agents. add_agent's tmux_window is only required of agents that
+		// aren't headless, which the handler checks. With start_window the
+		// daemon creates that window and starts the agent's Claude in it.
+		socket.TypedCommand("add_agent", d.handleAddAgent),
+		socket.TypedCommand("spawn_agent", d.handleSpawnAgent),
+		socket.TypedCommand("remove_agent", d.handleRemoveAgent),
+		// list_agents names a repo unless all_repos is set, which the
+		// handler checks
+		socket.TypedCommand("list_agents", d.handleListAgents),
+		socket.TypedCommand("worker_status", d.handleWorkerStatus),
+		socket.TypedCommand("agent_screen", d.agentScreen),
+		socket.TypedCommand("pull_agent_branch", d.handlePullAgentBranch),
+		socket.TypedCommand("recover_agent", d.handleRecoverAgent),
+		socket.TypedCommand("complete_agent", d.handleCompleteAgent),
+		socket.TypedCommand("handoff_agent", d.handleHandoffAgent),
+		socket.TypedCommand("restart_agent", d.handleRestartAgent),
+		socket.TypedCommand("agent_heartbeat", d.handleAgentHeartbeat),
+		socket.TypedCommand("trigger_cleanup", d.handleTriggerCleanup),
+		socket.TypedCommand("repair_state", d.handleRepairState),
+		socket.TypedCommand("restore_state", d.restoreState),
+		socket.TypedCommand("route_messages", func(socket.Request, socket.NoArgs) (string, error) {
+			go d.routeMessages()
+			return "Message routing triggered", nil
+		}),
+
+		// Worktrees and branches
+		socket.TypedCommand("check_branch_guard", d.handleCheckBranchGuard),
+		socket.TypedCommand("check_review_checklist", d.handleCheckReviewChecklist),
+		socket.TypedCommand("add_scratch_worktree", d.handleAddScratchWorktree),
+		socket.TypedCommand("remove_scratch_worktree", d.handleRemoveScratchWorktree),
+		socket.TypedCommand("list_scratch_worktrees", d.handleListScratchWorktrees),
+		socket.TypedCommand("claim_warm_worktree", d.handleClaimWarmWorktree),
+
+		// Questions and replies
+		socket.TypedCommand("get_feed", d.handleGetFeed),
+		socket.TypedCommand("respond_agent", d.handleRespondAgent),
+		socket.TypedCommand("resolve_conflict", d.handleResolveConflict),
+		socket.TypedCommand("ask_question", d.handleAskQuestion),
+		socket.TypedCommand("issue_response_id", d.handleIssueResponseID),
+		socket.TypedCommand("list_auto_answers", d.handleListAutoAnswers),
+		socket.TypedCommand("add_auto_answer", d.handleAddAutoAnswer),
+		socket.TypedCommand("remove_auto_answer", d.handleRemoveAutoAnswer),
+		socket.TypedCommand("broadcast_question", d.handleBroadcastQuestion),
+		socket.TypedCommand("broadcast_reply", d.handleBroadcastReply),
+		socket.TypedCommand("broadcast_status", d.handleBroadcastStatus),
+
+		// Tasks
+		socket.TypedCommand("add_task", d.handleAddTask),
+		socket.TypedCommand("check_worker_capacity", d.handleCheckWorkerCapacity),
+		socket.TypedCommand("list_tasks", d.handleListTasks),
+		socket.TypedCommand("cancel_task", d.handleCancelTask),
+		socket.TypedCommand("task_history", d.handleTaskHistory),
+
+		// Events, metrics and the merge queue
+		socket.TypedCommand("list_events", d.handleListEvents),
+		socket.TypedCommand("timeline", d.handleTimeline),
+		socket.TypedCommand("export_metrics", d.handleExportMetrics),
+		socket.TypedCommand("event_schema", func(socket.Request, socket.NoArgs) (map[string]interface{}, error) {
+			return events.JSONSchema(), nil
+		}),
+		socket.TypedCommand("merge_queue_event", d.handleMergeQueueEvent),
+		socket.TypedCommand("merge_queue_stats", d.handleMergeQueueStats),
+		socket.TypedCommand("merge_queue_simulate", d.handleMergeQueueSimulate),
+		socket.TypedCommand("list_audit", d.auditLog),
+	} {
+		r.Register(cmd)
+	}
+	return r
+}
@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestDispatchProtocolVersions(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+	})
+	defer cleanup()
+
+	tests := []struct {
+		name string
+		req  socket.Request
+		want socket.ErrorCode
+	}{
+		{"unknown command", socket.Request{Command: "launch_rocket"}, socket.CodeUnknownCommand},
+		{"newer protocol", socket.Request{Command: "ping", Version: socket.ProtocolVersion + 1}, socket.CodeUnsupportedVersion},
+		{"missing argument", socket.Request{Command: "list_agents"}, socket.CodeMissingArgument},
+		{"mistyped argument", socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": float64(1)}}, socket.CodeInvalidArgument},
+		{"missing agent", socket.Request{Command: "agent_screen", Args: map[string]interface{}{"repo": "repo", "agent": "nobody"}}, socket.CodeNotFound},
+		{"missing repo", socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "missing"}}, socket.CodeNotFound},
+		// repo has no agents to ask
+		{"untyped failure", socket.Request{Command: "broadcast_question", Args: map[string]interface{}{"repo": "repo", "question": "ready?"}}, socket.CodeFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v1 := tt.req
+			if v1.Version == 0 {
+				v1.Version = socket.ProtocolVersion
+			}
+			resp := d.dispatchRequest(v1)
+			if resp.Success || resp.Code != tt.want || resp.Version != socket.ProtocolVersion {
+				t.Fatalf("v1 response = %+v, want code %s", resp, tt.want)
+			}
+
+			// Version 0 clients get the same message, without code or version
+			if tt.req.Version != 0 {
+				return
+			}
+			v0 := d.dispatchRequest(tt.req)
+			if tt.want == socket.CodeInvalidArgument {
+				// Version 0 requests aren't type-checked; the handler treats
+				// the mistyped repo as missing
+				if v0.Success || v0.Code != "" {
+					t.Errorf("v0 response = %+v, want a plain failure", v0)
+				}
+				return
+			}
+			if v0.Success || v0.Error != resp.Error || v0.Code != "" || v0.Version != 0 {
+				t.Errorf("v0 response = %+v, want %q without code or version", v0, resp.Error)
+			}
+		})
+	}
+
+	// Optional arguments are type-checked too; version 0 handlers ignore a
+	// mistyped one
+	mistyped := socket.Request{Command: "list_events", Args: map[string]interface{}{"limit": "ten"}}
+	if v0 := d.dispatchRequest(mistyped); !v0.Success {
+		t.Errorf("v0 list_events with limit=\"ten\" = %+v, want it ignored", v0)
+	}
+	mistyped.Version = socket.ProtocolVersion
+	if v1 := d.dispatchRequest(mistyped); v1.Success || v1.Code != socket.CodeInvalidArgument {
+		t.Errorf("v1 list_events with limit=\"ten\" = %+v, want %s", v1, socket.CodeInvalidArgument)
+	}
+
+	resp := d.dispatchRequest(socket.Request{Command: "status", Version: socket.ProtocolVersion})
+	if !resp.Success || resp.Data.(socket.Status).ProtocolVersion != socket.ProtocolVersion {
+		t.Errorf("status = %+v, want the protocol version", resp)
+	}
+}
+
+func TestRegisteredCommandsRequireTheirArguments(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for _, name := range d.commands.Names() {
+		cmd, _ := d.commands.Lookup(name)
+		for _, arg := range cmd.Args {
+			if !arg.Required {
+				continue
+			}
+			if arg.Description == "" {
+				t.Errorf("%s: required argument '%s' has no description", name, arg.Name)
+			}
+			// The handler itself must reject the request too, so that
+			// callers bypassing the registry get the same error
+			resp := d.handleRequest(socket.Request{Command: name, Args: map[string]interface{}{}})
+			if resp.Success {
+				t.Errorf("%s without arguments succeeded", name)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/cleanup"
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// conflictActions are the choices offered when a refresh conflicts
+var conflictActions = []string{string(state.ConflictAssign), string(state.ConflictHelper), string(state.ConflictSkip)}
+
+// pendingRefreshConflict returns true if the worker's last rebase onto the
+// current main head conflicted, so retrying would only conflict again
+func pendingRefreshConflict(agent state.Agent, mainHead string) bool {
+	return agent.RefreshConflict != nil && mainHead != "" && agent.RefreshConflict.OntoHead == mainHead
+}
+
+// reportRefreshConflict records a conflicting refresh on the agent and asks
+// humans how to resolve it: assign it to the agent, open a helper window,
+// or skip until main moves again
+func (d *Daemon) reportRefreshConflict(repoName, agentName string, agent state.Agent, onto, ontoHead string, result worktree.RefreshResult) {
+	agent.RefreshConflict = &state.RefreshConflict{
+		Onto:       onto,
+		OntoHead:   ontoHead,
+		Files:      result.ConflictFiles,
+		DetectedAt: d.clock.Now(),
+	}
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		d.logger.Error("Failed to record refresh conflict for %s/%s: %v", repoName, agentName, err)
+		return
+	}
+
+	files := make([]events.ConflictedFile, len(result.Conflicts))
+	summary := make([]string, len(result.Conflicts))
+	for i, c := range result.Conflicts {
+		files[i] = events.ConflictedFile{Path: c.Path, Hunks: c.Hunks, Lines: c.Lines}
+		summary[i] = c.String()
+	}
+
+	responseID, expires := d.responses.Issue(repoName, agentName, d.clock.Now())
+	event := events.NewTypedEvent(repoName, agentName,
+		fmt.Sprintf("Rebasing %s onto %s conflicts in %d file(s)", agentName, onto, len(files)),
+		events.RefreshConflictPayload{
+			Branch:            result.Branch,
+			Onto:              onto,
+			OntoHead:          ontoHead,
+			Files:             files,
+			Actions:           conflictActions,
+			ResponseID:        responseID,
+			ResponseExpiresAt: expires,
+		})
+	event.Priority = events.PriorityHigh
+	event.Message = fmt.Sprintf("Conflicts:\n- %s\n\nThe rebase was aborted and the worktree is unchanged. Pick one:\n"+
+		"- assign: `multiclaude work resolve %s assign --repo %s` (the worker resolves it)\n"+
+		"- helper: `multiclaude work resolve %s helper --repo %s` (opens a window stopped at the conflict)\n"+
+		"- skip: `multiclaude work resolve %s skip --repo %s` (retry when main moves)",
+		strings.Join(summary, "\n- "), agentName, repoName, agentName, repoName, agentName, repoName)
+	d.emitEvent(event)
+
+	d.recordAction(repoName, feed.ActionConflict, agentName, fmt.Sprintf("rebase onto %s conflicts in %s", onto, strings.Join(result.ConflictFiles, ", ")))
+}
+
+// assistRefreshConflict handles a refresh that conflicted in a repository
+// with conflict assist on. The rebase is left paused in the worktree; the
+// worker gets a report of the conflicting files and hunks in its inbox, and
+// an agent.question event asks it, or a human, to resolve them.
+func (d *Daemon) assistRefreshConflict(repoName, agentName, onto, ontoHead string, result worktree.RefreshResult) {
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return
+	}
+
+	now := d.clock.Now()
+	if agent.Question != nil {
+		d.responses.Revoke(agent.Question.ResponseID)
+	}
+	responseID, expires := d.responses.Issue(repoName, agentName, now)
+	question := fmt.Sprintf("Rebasing %s onto %s stopped at conflicts in %d file(s): %s. The rebase is paused in its worktree. How should it be resolved?",
+		agentName, onto, len(result.ConflictFiles), strings.Join(result.ConflictFiles, ", "))
+	agent.Question = &state.PendingQuestion{Text: question, ResponseID: responseID, AskedAt: now}
+	agent.RefreshConflict = &state.RefreshConflict{
+		Onto:       onto,
+		OntoHead:   ontoHead,
+		Files:      result.ConflictFiles,
+		DetectedAt: now,
+		Resolution: state.ConflictAssign,
+		Paused:     true,
+		QuestionID: responseID,
+	}
+	// Later passes find the worktree mid-rebase; the report already says so
+	agent.RefreshSkip = refreshSkipReasons["mid-rebase"]
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		d.logger.Error("Failed to record refresh conflict for %s/%s: %v", repoName, agentName, err)
+		return
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "Rebasing your branch onto %s stopped at conflicts. The rebase is paused in your worktree for you to resolve:\n", onto)
+	for _, c := range result.Conflicts {
+		fmt.Fprintf(&report, "- %s\n", c)
+	}
+	report.WriteString("Fix each file, `git add` it, and run `git rebase --continue` until the rebase is done (or `git rebase --abort` to give up). Then run the tests and push with `git push --force-with-lease`.")
+	if result.WasStashed {
+		report.WriteString(" Your uncommitted changes were stashed first; run `git stash pop` once the rebase is finished.")
+	}
+	if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, report.String()); err != nil {
+		d.logger.Warn("Failed to send conflict report to %s/%s: %v", repoName, agentName, err)
+	}
+	go d.routeMessages()
+
+	event := events.NewTypedEvent(repoName, agentName, fmt.Sprintf("Rebasing %s onto %s paused at conflicts", agentName, onto),
+		events.AgentQuestionPayload{
+			Question:          question,
+			ResponseID:        responseID,
+			ResponseExpiresAt: expires,
+		})
+	event.Priority = events.PriorityHigh
+	event.Message = fmt.Sprintf("%s\n\nThe worker was asked to resolve it. Reply with: multiclaude respond --response-id %s <reply>", question, responseID)
+	d.emitEvent(event)
+
+	d.recordAction(repoName, feed.ActionConflict, agentName, fmt.Sprintf("rebase onto %s paused at conflicts in %s", onto, strings.Join(result.ConflictFiles, ", ")))
+}
+
+// handleResolveConflict carries out the action a human chose for a worker's
+// refresh conflict
+func (d *Daemon) handleResolveConflict(req socket.Request, args socket.ResolveConflictArgs) (socket.ConflictResolution, error) {
+	repoName, agentName := args.Repo, args.Agent
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.ConflictResolution{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+	}
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return socket.ConflictResolution{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+	if agent.RefreshConflict == nil {
+		return socket.ConflictResolution{}, fmt.Errorf("agent '%s' has no refresh conflict to resolve", agentName)
+	}
+
+	// Choices relayed from outside (e.g. a chat button) carry the one-time
+	// response ID from the event
+	if args.ResponseID != "" {
+		if err := d.responses.Redeem(args.ResponseID, repoName, agentName, d.clock.Now()); err != nil {
+			return socket.ConflictResolution{}, fmt.Errorf("choice rejected: %v", err)
+		}
+	}
+
+	conflict := *agent.RefreshConflict
+	result := socket.ConflictResolution{Action: args.Action}
+	switch resolution := state.ConflictResolution(args.Action); resolution {
+	case state.ConflictAssign:
+		msg := fmt.Sprintf("Rebasing your branch onto %s conflicts in: %s. The daemon aborted its rebase and will not retry until main moves again.\n"+
+			"Please resolve it now: commit or stash your work, run `git fetch && git rebase %s`, fix the conflicts, run the tests, and push with `git push --force-with-lease`.",
+			conflict.Onto, strings.Join(conflict.Files, ", "), conflict.Onto)
+		if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, msg); err != nil {
+			return socket.ConflictResolution{}, fmt.Errorf("failed to message %s: %v", agentName, err)
+		}
+		go d.routeMessages()
+
+	case state.ConflictHelper:
+		window, err := d.openConflictHelper(repo.TmuxSession, agentName, agent.WorktreePath, conflict.Onto)
+		if err != nil {
+			return socket.ConflictResolution{}, err
+		}
+		attach := events.NewAttachTarget(repoName, agentName, repo.TmuxSession, window)
+		result.Window = window
+		result.Tmux = attach.Tmux
+		msg := fmt.Sprintf("A human is resolving the conflict between your branch and %s in tmux window %s. Don't commit or run git commands until you're told the rebase is done.", conflict.Onto, window)
+		if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, msg); err != nil {
+			d.logger.Warn("Failed to tell %s about the conflict helper: %v", agentName, err)
+		}
+		go d.routeMessages()
+
+	case state.ConflictSkip:
+		// The record stays, so the refresh loop waits for main to move
+
+	default:
+		return socket.ConflictResolution{}, socket.Errorf(socket.CodeInvalidArgument, "invalid action %q: use assign, helper, or skip", args.Action)
+	}
+
+	conflict.Resolution = state.ConflictResolution(args.Action)
+	agent.RefreshConflict = &conflict
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		return socket.ConflictResolution{}, err
+	}
+
+	d.logger.Info("Refresh conflict for %s/%s resolved with %s by %s", repoName, agentName, args.Action, req.Peer)
+	d.recordAction(repoName, feed.ActionConflict, agentName, fmt.Sprintf("resolution: %s", args.Action))
+	return result, nil
+}
+
+// openConflictHelper opens a tmux window in the worktree and starts the
+// rebase there, leaving it stopped at the conflict for a human to resolve
+func (d *Daemon) openConflictHelper(session, agentName, worktreePath, onto string) (string, error) {
+	window := "resolve-" + agentName
+	if exists, _ := d.tmux.HasWindow(d.ctx, session, window); exists {
+		return window, nil
+	}
+
+	script := fmt.Sprintf("git fetch --quiet; git rebase %s; git status; exec ${SHELL:-sh}", onto)
+	if err := d.tmux.CreateWindowWithCommand(d.ctx, session, window, worktreePath, script); err != nil {
+		return "", fmt.Errorf("failed to open conflict helper window: %w", err)
+	}
+	// No agent owns the window; keep the reaper away until the human closes it
+	if err := d.tmux.SetWindowOption(d.ctx, session, window, cleanup.KeepWindowOption, "on"); err != nil {
+		d.logger.Warn("Failed to protect conflict helper window %s from the reaper: %v", window, err)
+	}
+	return window, nil
+}
@@ -0,0 +1,197 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/events"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestRefreshConflictFlow(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoName := "conflict-repo"
+	repoPath := d.paths.RepoDir(repoName)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(dir, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "shared.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGitIn(t, repoPath, "init", "-b", "main")
+	runGitIn(t, repoPath, "config", "user.email", "test@example.com")
+	runGitIn(t, repoPath, "config", "user.name", "Test User")
+	write(repoPath, "one\ntwo\nthree\n")
+	runGitIn(t, repoPath, "add", "shared.txt")
+	runGitIn(t, repoPath, "commit", "-m", "Add shared file")
+	runGitIn(t, repoPath, "remote", "add", "origin", repoPath)
+	runGitIn(t, repoPath, "fetch", "origin")
+
+	wtPath := d.paths.AgentWorktree(repoName, "worker")
+	if err := worktree.NewManager(repoPath).CreateNewBranch(wtPath, "work/worker", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	write(wtPath, "one\nTWO from the worker\nthree\n")
+	runGitIn(t, wtPath, "commit", "-am", "Worker change")
+	write(repoPath, "one\nTWO from main\nthree\n")
+	runGitIn(t, repoPath, "commit", "-am", "Main change")
+
+	ctx := context.Background()
+	session := fmt.Sprintf("mc-test-conflict-%d", time.Now().UnixNano())
+	if err := tmuxClient.CreateSession(ctx, session, true); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, session)
+
+	d.state.AddRepo(repoName, &state.Repository{
+		TmuxSession: session,
+		Agents: map[string]state.Agent{
+			"worker": {Type: state.AgentTypeWorker, WorktreePath: wtPath, TmuxWindow: "worker"},
+		},
+	})
+
+	d.refreshWorktrees()
+	agent, _ := d.state.GetAgent(repoName, "worker")
+	if agent.RefreshConflict == nil || len(agent.RefreshConflict.Files) != 1 || agent.RefreshConflict.Onto != "origin/main" {
+		t.Fatalf("refresh conflict not recorded: %+v", agent.RefreshConflict)
+	}
+	recent := d.notify.Recent(0)
+	if len(recent) != 1 || recent[0].Type != events.EventRefreshConflict || recent[0].Priority != events.PriorityHigh {
+		t.Fatalf("events = %+v, want one high-priority refresh conflict", recent)
+	}
+	payload := recent[0].Payload.(events.RefreshConflictPayload)
+	if payload.Files[0].Path != "shared.txt" || payload.Files[0].Hunks != 1 || len(payload.Actions) != 3 || payload.ResponseID == "" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	// The same main head is not retried or reported again
+	d.refreshWorktrees()
+	if n := len(d.notify.Recent(0)); n != 1 {
+		t.Errorf("conflict reported %d times, want once", n)
+	}
+
+	resolve := func(action, responseID string) socket.Response {
+		return d.handleRequest(socket.Request{Command: "resolve_conflict", Args: map[string]interface{}{
+			"repo": repoName, "agent": "worker", "action": action, "response_id": responseID,
+		}})
+	}
+	if resp := resolve("rebase-harder", ""); resp.Success {
+		t.Error("resolve_conflict should reject unknown actions")
+	}
+	if resp := resolve("skip", payload.ResponseID); !resp.Success {
+		t.Fatalf("skip failed: %s", resp.Error)
+	}
+	if resp := resolve("assign", payload.ResponseID); resp.Success {
+		t.Error("a response ID should only be redeemable once")
+	}
+	if resp := resolve("helper", ""); !resp.Success {
+		t.Fatalf("helper failed: %s", resp.Error)
+	}
+	if exists, _ := tmuxClient.HasWindow(ctx, session, "resolve-worker"); !exists {
+		t.Error("helper should open a resolve-worker window")
+	}
+	agent, _ = d.state.GetAgent(repoName, "worker")
+	if agent.RefreshConflict.Resolution != state.ConflictHelper {
+		t.Errorf("resolution = %q, want helper", agent.RefreshConflict.Resolution)
+	}
+}
+
+func TestConflictAssistPausesRebase(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoName := "assist-repo"
+	repoPath := d.paths.RepoDir(repoName)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(dir, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "shared.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGitIn(t, repoPath, "init", "-b", "main")
+	runGitIn(t, repoPath, "config", "user.email", "test@example.com")
+	runGitIn(t, repoPath, "config", "user.name", "Test User")
+	write(repoPath, "one\ntwo\nthree\n")
+	runGitIn(t, repoPath, "add", "shared.txt")
+	runGitIn(t, repoPath, "commit", "-m", "Add shared file")
+	runGitIn(t, repoPath, "remote", "add", "origin", repoPath)
+	runGitIn(t, repoPath, "fetch", "origin")
+
+	wtPath := d.paths.AgentWorktree(repoName, "worker")
+	if err := worktree.NewManager(repoPath).CreateNewBranch(wtPath, "work/worker", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	write(wtPath, "one\nTWO from the worker\nthree\n")
+	runGitIn(t, wtPath, "commit", "-am", "Worker change")
+	write(repoPath, "one\nTWO from main\nthree\n")
+	runGitIn(t, repoPath, "commit", "-am", "Main change")
+
+	d.state.AddRepo(repoName, &state.Repository{
+		TmuxSession:    "mc-assist-repo",
+		ConflictAssist: true,
+		Agents: map[string]state.Agent{
+			"worker": {Type: state.AgentTypeWorker, WorktreePath: wtPath, TmuxWindow: "worker"},
+		},
+	})
+
+	d.refreshWorktrees()
+	agent, _ := d.state.GetAgent(repoName, "worker")
+	if agent.RefreshConflict == nil || !agent.RefreshConflict.Paused {
+		t.Fatalf("paused conflict not recorded: %+v", agent.RefreshConflict)
+	}
+	if agent.Question == nil || agent.Question.ResponseID != agent.RefreshConflict.QuestionID {
+		t.Errorf("question = %+v, want one for the conflict", agent.Question)
+	}
+	wtState, err := worktree.GetWorktreeState(wtPath, "origin", "main")
+	if err != nil || !wtState.IsMidRebase {
+		t.Fatalf("worktree should be left mid-rebase: %+v, %v", wtState, err)
+	}
+	recent := d.notify.Recent(0)
+	if len(recent) != 1 || recent[0].Type != events.EventAgentQuestion || recent[0].Priority != events.PriorityHigh {
+		t.Fatalf("events = %+v, want one high-priority question", recent)
+	}
+	msgs, err := d.getMessageManager().List(repoName, "worker")
+	if err != nil || len(msgs) != 1 || !strings.Contains(msgs[0].Body, "shared.txt (1 hunk at line 2)") || !strings.Contains(msgs[0].Body, "git rebase --continue") {
+		t.Fatalf("messages = %+v, %v; want a conflict report", msgs, err)
+	}
+
+	// While the rebase is paused, later passes stay quiet
+	d.refreshWorktrees()
+	if n := len(d.notify.Recent(0)); n != 1 {
+		t.Errorf("%d events after a second pass, want 1", n)
+	}
+	if msgs, _ := d.getMessageManager().List(repoName, "worker"); len(msgs) != 1 {
+		t.Errorf("%d messages after a second pass, want 1", len(msgs))
+	}
+
+	// Once the worker finishes the rebase the conflict and question are settled
+	write(wtPath, "one\nTWO from both\nthree\n")
+	runGitIn(t, wtPath, "add", "shared.txt")
+	runGitIn(t, wtPath, "-c", "core.editor=true", "rebase", "--continue")
+	d.refreshWorktrees()
+	agent, _ = d.state.GetAgent(repoName, "worker")
+	if agent.RefreshConflict != nil || agent.Question != nil {
+		t.Errorf("conflict %+v and question %+v should be cleared", agent.RefreshConflict, agent.Question)
+	}
+}
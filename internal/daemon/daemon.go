@@ -2,46 +2,249 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/dlorenc/multiclaude/internal/agents"
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/cleanup"
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/github"
 	"github.com/dlorenc/multiclaude/internal/hooks"
+	"github.com/dlorenc/multiclaude/internal/launch"
 	"github.com/dlorenc/multiclaude/internal/logging"
+	"github.com/dlorenc/multiclaude/internal/logstore"
+	"github.com/dlorenc/multiclaude/internal/loopdetect"
 	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/metrics"
+	"github.com/dlorenc/multiclaude/internal/notify"
 	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/repair"
 	"github.com/dlorenc/multiclaude/internal/socket"
 	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/tracing"
 	"github.com/dlorenc/multiclaude/internal/worktree"
 	"github.com/dlorenc/multiclaude/pkg/claude"
 	"github.com/dlorenc/multiclaude/pkg/config"
-	"github.com/dlorenc/multiclaude/pkg/tmux"
+	"github.com/dlorenc/multiclaude/pkg/events"
+	"github.com/dlorenc/multiclaude/pkg/mux"
 )
 
 // Daemon represents the main daemon process
 type Daemon struct {
 	paths        *config.Paths
 	state        *state.State
-	tmux         *tmux.Client
+	tmux         mux.Multiplexer // tmux, or the process backend where tmux isn't available
 	logger       *logging.Logger
 	server       *socket.Server
 	pidFile      *PIDFile
 	claudeRunner *claude.Runner
+	clock        clock.Clock
+	notify       *notify.Hub
+	github       *github.Client
+	feed         *feed.Manager
+	audit        *audit.Log
+	responses    *notify.ResponseIDs
+	lanes        *laneScheduler
+	commands     *socket.Registry
+	chaos        *chaosMonkey // nil unless chaos mode is enabled
+
+	// outputLoops tracks per-agent output loop detection state
+	outputLoops   map[string]outputLoopState
+	outputLoopsMu sync.Mutex
+	// idleReported holds, per agent, the output time of the idle stretch
+	// last reported as stuck
+	idleReported   map[string]time.Time
+	idleReportedMu sync.Mutex
+	// restartGaveUp marks agents past their restart limit whose last crash
+	// was already reported
+	restartGaveUp   map[string]bool
+	restartGaveUpMu sync.Mutex
+	// taskQueueMu serializes task queue dispatch so a task isn't dispatched twice
+	taskQueueMu sync.Mutex
+	// mergeEnginePolled holds when each repository's merge queue was last
+	// polled, mergeEngineHeldAt the head of each PR set aside after failing
+	// CI or conflicting, and mergeEngineUnchecked when the merge queue first
+	// saw a PR head no check had reported on; mergeEngineRunMu serializes
+	// passes
+	mergeEnginePolled    map[string]time.Time
+	mergeEngineHeldAt    map[string]map[int]string
+	mergeEngineUnchecked map[string]map[int]uncheckedHead
+	mergeEngineMu        sync.Mutex
+	mergeEngineRunMu     sync.Mutex
+
+	// autoAnswered tracks which worker questions were already auto-answered
+	autoAnswered   map[string]bool
+	autoAnsweredMu sync.Mutex
+
+	// broadcasts holds questions asked of every agent, by ID
+	broadcasts   map[string]*socket.Broadcast
+	broadcastsMu sync.Mutex
+
+	// headlessRuns holds the running Claude process of each headless agent
+	// by repo/agent, and headlessQueued the prompts waiting for it to finish
+	headlessRuns   map[string]*headlessRun
+	headlessQueued map[string][]string
+	headlessMu     sync.Mutex
+
+	// zombieWindows tracks unowned tmux windows during their grace period
+	zombieWindows   map[string]zombieWindow
+	zombieWindowsMu sync.Mutex
+
+	// warmPoolMu serializes filling and draining warm worktree pools, and
+	// warmGitMu the git commands that add, move and remove their worktrees,
+	// which contend for the same lock files in the repository
+	warmPoolMu sync.Mutex
+	warmGitMu  sync.Mutex
+
+	// lastRefresh and lastWarmFill are when the worktree refresh loop last
+	// refreshed workers and filled warm pools; only that loop uses them
+	lastRefresh  time.Time
+	lastWarmFill time.Time
+
+	// diskUsage holds the bytes each agent worktree took when last measured,
+	// by worktree path
+	diskUsage   map[string]int64
+	diskUsageMu sync.Mutex
+
+	// lockOwner identifies this daemon in repository lock files, and
+	// foreignLocks records repositories another daemon holds the lock on
+	lockOwner    worktree.LockOwner
+	foreignLocks map[string]worktree.LockOwner
+	repoLocksMu  sync.Mutex
+
+	// emailConfig mails events when the email adapter is configured
+	// (WithEmail), in place of the config file's email settings
+	emailConfig *notify.EmailConfig
+	// emails are every email adapter created, including ones a reload
+	// replaced, so shutdown can mail their pending digests
+	emails []*notify.EmailAdapter
+
+	// webhookConfig POSTs events when the webhook adapter is configured
+	// (WithWebhook); envWebhook records that it was, so the config file's
+	// webhook settings are ignored
+	webhookConfig *notify.WebhookConfig
+	envWebhook    bool
+	// webhooks are every webhook adapter created, including ones a reload
+	// replaced, so shutdown can wait for their retries
+	webhooks []*notify.WebhookAdapter
+
+	// incidentConfig enables the PagerDuty or Opsgenie adapter (WithIncidents)
+	// in place of the config file's incident settings
+	incidentConfig *notify.IncidentConfig
+
+	// desktopTypes are the events shown as desktop notifications (WithDesktop)
+	// in place of the config file's desktop settings
+	desktopTypes []events.EventType
+	// routes limit which events routed adapters receive. envRoutes records
+	// that they were set with WithRoutes, so the config file's routes are
+	// ignored.
+	routes    notify.Routes
+	envRoutes bool
+
+	// eventRetention is how long the event store keeps events
+	// (WithEventRetention, or the config file's events.retention)
+	eventRetention time.Duration
+
+	// settings are read from the config file (paths.ConfigFile) at startup
+	// and on reload; fileAdapterNames are the adapters it enabled
+	settings         *config.File
+	fileAdapterNames []string
+	settingsMu       sync.RWMutex
+	reloadMu         sync.Mutex
+	// api serves the read-only HTTP API when the config file enables it
+	api   *http.Server
+	apiMu sync.Mutex
+
+	remote   *socket.RemoteServer // TCP socket; nil unless the config file enables it
+	remoteMu sync.Mutex
+
+	// lastRestore is the report of the restoration run at startup
+	lastRestore   *events.DaemonRestoredPayload
+	lastRestoreMu sync.Mutex
 
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 }
 
+// Option configures a Daemon
+type Option func(*Daemon)
+
+// WithClock sets the clock the daemon schedules its loops, deadlines, and
+// events by. Tests pass a fake clock to advance time deterministically.
+func WithClock(c clock.Clock) Option {
+	return func(d *Daemon) {
+		d.clock = c
+	}
+}
+
+// WithEmail emails events over SMTP (see notify.EmailEnv), overriding the
+// config file's email settings
+func WithEmail(cfg notify.EmailConfig) Option {
+	return func(d *Daemon) {
+		d.emailConfig = &cfg
+	}
+}
+
+// WithWebhook POSTs signed events to a URL (see notify.WebhookEnv). Events
+// that can't be delivered go to the paths' dead-letter file unless cfg names
+// another.
+func WithWebhook(cfg notify.WebhookConfig) Option {
+	return func(d *Daemon) {
+		d.webhookConfig = &cfg
+	}
+}
+
+// WithIncidents opens PagerDuty or Opsgenie incidents for agents that keep
+// failing (see notify.IncidentEnv), overriding the config file's incident
+// settings
+func WithIncidents(cfg notify.IncidentConfig) Option {
+	return func(d *Daemon) {
+		d.incidentConfig = &cfg
+	}
+}
+
+// WithDesktop shows events of the given types as desktop notifications (see
+// notify.DesktopEnv), overriding the config file's desktop settings
+func WithDesktop(types []events.EventType) Option {
+	return func(d *Daemon) {
+		d.desktopTypes = types
+	}
+}
+
+// WithRoutes sends events to the adapters the routes name only when they
+// match (see notify.RoutesEnv), overriding the config file's routes
+func WithRoutes(routes notify.Routes) Option {
+	return func(d *Daemon) {
+		d.routes = routes
+	}
+}
+
+// WithEventRetention sets how long the event store keeps events (see
+// notify.EventRetentionEnv), overriding the config file's events.retention
+func WithEventRetention(retention time.Duration) Option {
+	return func(d *Daemon) {
+		d.eventRetention = retention
+	}
+}
+
 // New creates a new daemon instance
-func New(paths *config.Paths) (*Daemon, error) {
+func New(paths *config.Paths, opts ...Option) (*Daemon, error) {
 	// Ensure directories exist
 	if err := paths.EnsureDirectories(); err != nil {
 		return nil, fmt.Errorf("failed to create directories: %w", err)
@@ -59,22 +262,105 @@ func New(paths *config.Paths) (*Daemon, error) {
 		return nil, fmt.Errorf("failed to load state: %w", err)
 	}
 
+	settings, err := config.LoadFile(paths.ConfigFile())
+	if err != nil {
+		return nil, err
+	}
+
+	multiplexer, err := mux.New(settings.Multiplexer)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
-	tmuxClient := tmux.NewClient()
 	d := &Daemon{
-		paths:        paths,
-		state:        st,
-		tmux:         tmuxClient,
-		logger:       logger,
-		pidFile:      NewPIDFile(paths.DaemonPID),
-		claudeRunner: claude.NewRunner(claude.WithTerminal(tmuxClient)),
-		ctx:          ctx,
-		cancel:       cancel,
+		paths:                paths,
+		state:                st,
+		settings:             settings,
+		tmux:                 multiplexer,
+		logger:               logger,
+		pidFile:              NewPIDFile(paths.DaemonPID),
+		claudeRunner:         claude.NewRunner(claude.WithTerminal(multiplexer)),
+		clock:                clock.Real(),
+		feed:                 feed.NewManager(paths.FeedDir()),
+		audit:                audit.NewLog(paths.AuditFile()),
+		responses:            notify.NewResponseIDs(),
+		lanes:                newLaneScheduler(defaultBackgroundWorkers),
+		outputLoops:          make(map[string]outputLoopState),
+		idleReported:         make(map[string]time.Time),
+		restartGaveUp:        make(map[string]bool),
+		mergeEnginePolled:    make(map[string]time.Time),
+		mergeEngineHeldAt:    make(map[string]map[int]string),
+		mergeEngineUnchecked: make(map[string]map[int]uncheckedHead),
+		autoAnswered:         make(map[string]bool),
+		broadcasts:           make(map[string]*socket.Broadcast),
+		zombieWindows:        make(map[string]zombieWindow),
+		headlessRuns:         make(map[string]*headlessRun),
+		headlessQueued:       make(map[string][]string),
+		diskUsage:            make(map[string]int64),
+		lockOwner:            worktree.NewLockOwner(paths.Root),
+		foreignLocks:         make(map[string]worktree.LockOwner),
+		ctx:                  ctx,
+		cancel:               cancel,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.responses.Load(responseTickets(st.GetResponseIDs()), d.clock.Now())
+	d.responses.Persist = d.saveResponseIDs
+	d.envWebhook = d.webhookConfig != nil
+	d.envRoutes = d.routes != nil
+	fileAdapters, err := d.fileAdapters(settings)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid notification settings in %s: %w", paths.ConfigFile(), err)
+	}
+	d.routes = d.fileRoutes(settings)
+	if d.eventRetention == 0 && settings.Events.Retention != "" {
+		d.eventRetention, _ = config.ParseRetention(settings.Events.Retention)
+	}
+	hubOpts := []notify.HubOption{notify.WithClock(d.clock), notify.WithRoutes(d.routes)}
+	if store, err := notify.OpenStore(paths.EventsFile(), d.eventRetention, d.clock.Now()); err != nil {
+		logger.Warn("Event store disabled, events are kept in memory only: %v", err)
+	} else {
+		hubOpts = append(hubOpts, notify.WithStore(store))
 	}
+	d.notify = notify.NewHub(hubOpts...)
+	// Everything that polls GitHub shares one client, and so one cache and
+	// rate limit budget
+	d.github = github.NewClient(github.WithClock(d.clock))
+	d.applyGitHubBudgets(nil, settings.GitHub.Budgets)
+
+	// Events are always written to the daemon log
+	d.notify.Register(notify.NewLogAdapter(logger.Info))
+	d.registerAdapter(&tmuxBellAdapter{d: d, ring: multiplexer.RingBell})
+	if d.emailConfig != nil {
+		d.registerAdapter(d.newEmailAdapter(*d.emailConfig))
+	}
+	if d.webhookConfig != nil {
+		d.registerAdapter(d.newWebhookAdapter(*d.webhookConfig))
+	}
+	for _, adapter := range fileAdapters {
+		d.registerAdapter(adapter)
+		d.fileAdapterNames = append(d.fileAdapterNames, adapter.Name())
+	}
+	if d.incidentConfig != nil {
+		d.registerAdapter(notify.NewIncidentAdapter(*d.incidentConfig, notify.WithIncidentClock(d.clock)))
+	}
+	if len(d.desktopTypes) > 0 {
+		if desktop, err := notify.NewDesktopAdapter(d.desktopTypes); err != nil {
+			logger.Warn("Desktop notifications disabled: %v", err)
+		} else {
+			d.registerAdapter(desktop)
+		}
+	}
+	d.warnUnroutedAdapters()
+	d.reportRestoredState()
 
 	// Create socket server
-	d.server = socket.NewServer(paths.DaemonSock, socket.HandlerFunc(d.handleRequest))
+	d.commands = d.newCommandRegistry()
+	d.server = socket.NewServer(paths.DaemonSock, socket.HandlerFunc(d.dispatchRequest))
 
 	return d, nil
 }
@@ -83,6 +369,20 @@ func New(paths *config.Paths) (*Daemon, error) {
 func (d *Daemon) Start() error {
 	d.logger.Info("Starting daemon")
 
+	// Refuse to start with a git too old for worktrees, and warn about
+	// features that will be unavailable instead of failing later
+	gitVersion, err := worktree.CheckGitVersion()
+	if err != nil {
+		return err
+	}
+	d.logger.Info("Using git %s", gitVersion)
+	if backend := mux.Backend(d.tmux); backend != mux.BackendTmux {
+		d.logger.Info("Running agents in the %s multiplexer; they stop when the daemon does", backend)
+	}
+	for _, f := range worktree.UnsupportedGitFeatures(gitVersion) {
+		d.logger.Warn("git %s does not support %s (requires %s); it will be unavailable", gitVersion, f.Name, f.MinVersion)
+	}
+
 	// Check and claim PID file
 	if err := d.pidFile.CheckAndClaim(); err != nil {
 		return err
@@ -94,20 +394,61 @@ func (d *Daemon) Start() error {
 	}
 
 	d.logger.Info("Socket server started at %s", d.paths.DaemonSock)
+	if group := d.state.GetSocketGroup(); group != "" {
+		if err := d.server.Share(group); err != nil {
+			d.logger.Error("Failed to share socket with group %s: %v", group, err)
+		} else {
+			d.logger.Info("Socket shared with group %s", group)
+		}
+	}
+
+	if err := d.startAPI(); err != nil {
+		d.logger.Error("API server disabled: %v", err)
+	}
+	if err := d.startRemote(); err != nil {
+		d.logger.Error("Remote socket disabled: %v", err)
+	}
 
 	d.logger.Info("Daemon started successfully")
 
+	// Lock tracked repos before touching their worktrees, so repos another
+	// daemon manages (e.g. clones on a shared network mount) stay read-only
+	d.claimRepoLocks()
+
+	// Sessions named with an earlier prefix take the configured one before
+	// restoration looks for them
+	d.migrateSessionNames(d.configFile().Namer())
+
 	// Restore agents for tracked repos BEFORE starting health checks
 	// This prevents race conditions where health check cleans up agents being restored
 	d.restoreTrackedRepos()
 
+	// Resend events adapters never accepted before the last shutdown
+	if replayed, err := d.notify.Replay(d.ctx); err != nil {
+		d.logger.Warn("Replayed %d undelivered events with failures: %v", replayed, err)
+	} else if replayed > 0 {
+		d.logger.Info("Replayed %d undelivered events", replayed)
+	}
+
 	// Start core loops after restore completes
-	d.wg.Add(5)
+	d.wg.Add(11)
 	go d.healthCheckLoop()
 	go d.messageRouterLoop()
 	go d.wakeLoop()
 	go d.serverLoop()
 	go d.worktreeRefreshLoop()
+	go d.metricsLoop()
+	go d.taskQueueLoop()
+	go d.mergeEngineLoop()
+	go d.ciWatchLoop()
+	go d.signalLoop()
+	go d.diskUsageLoop()
+
+	if d.chaos != nil {
+		d.logger.Warn("Chaos mode enabled: %s", d.chaos.cfg)
+		d.wg.Add(1)
+		go d.chaosLoop()
+	}
 
 	return nil
 }
@@ -127,11 +468,27 @@ func (d *Daemon) GetPaths() *config.Paths {
 	return d.paths
 }
 
+// RegisterAdapter adds a notification adapter alongside the daemon log, e.g.
+// to capture events in integration tests
+func (d *Daemon) RegisterAdapter(adapter notify.Adapter) {
+	d.registerAdapter(adapter)
+}
+
 // TriggerHealthCheck triggers an immediate health check (for testing)
 func (d *Daemon) TriggerHealthCheck() {
 	d.checkAgentHealth()
 }
 
+// TriggerOutputLoopDetection triggers an immediate output loop check (for testing)
+func (d *Daemon) TriggerOutputLoopDetection() {
+	d.detectOutputLoops()
+}
+
+// GetNotifyHub returns the daemon's notification hub (for testing)
+func (d *Daemon) GetNotifyHub() *notify.Hub {
+	return d.notify
+}
+
 // TriggerMessageRouting triggers an immediate message routing (for testing)
 func (d *Daemon) TriggerMessageRouting() {
 	d.routeMessages()
@@ -152,6 +509,28 @@ func (d *Daemon) Stop() error {
 	// Wait for all goroutines to finish
 	d.wg.Wait()
 
+	// Mail the events waiting for the next email digest
+	d.settingsMu.RLock()
+	emails := d.emails
+	d.settingsMu.RUnlock()
+	for _, email := range emails {
+		if err := email.Flush(); err != nil {
+			d.logger.Error("Failed to send email digest: %v", err)
+		}
+	}
+
+	// Webhook retries stopped with the context; wait for them to be
+	// dead-lettered
+	d.settingsMu.RLock()
+	webhooks := d.webhooks
+	d.settingsMu.RUnlock()
+	for _, webhook := range webhooks {
+		webhook.Wait()
+	}
+
+	d.stopAPI()
+	d.stopRemote()
+
 	// Stop socket server
 	if err := d.server.Stop(); err != nil {
 		d.logger.Error("Failed to stop socket server: %v", err)
@@ -162,6 +541,17 @@ func (d *Daemon) Stop() error {
 		d.logger.Error("Failed to save state: %v", err)
 	}
 
+	// Agents in a multiplexer the daemon hosts, such as the process backend,
+	// can't outlive it
+	if closer, ok := d.tmux.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			d.logger.Error("Failed to stop agents: %v", err)
+		}
+	}
+
+	// Release repository locks
+	d.releaseRepoLocks()
+
 	// Remove PID file
 	if err := d.pidFile.Remove(); err != nil {
 		d.logger.Error("Failed to remove PID file: %v", err)
@@ -171,19 +561,6 @@ func (d *Daemon) Stop() error {
 	return nil
 }
 
-// getRequiredStringArg extracts a required string argument from request Args.
-// Returns the value and true if present, or an error response and false if missing.
-func getRequiredStringArg(args map[string]interface{}, key, description string) (string, socket.Response, bool) {
-	val, ok := args[key].(string)
-	if !ok || val == "" {
-		return "", socket.Response{
-			Success: false,
-			Error:   fmt.Sprintf("missing '%s': %s", key, description),
-		}, false
-	}
-	return val, socket.Response{}, true
-}
-
 // periodicLoop runs a function periodically at the specified interval.
 // If onStartup is provided, it's called immediately before entering the loop.
 // The onTick function is called on each timer tick.
@@ -191,7 +568,7 @@ func (d *Daemon) periodicLoop(name string, interval time.Duration, onStartup, on
 	defer d.wg.Done()
 	d.logger.Info("Starting %s loop", name)
 
-	ticker := time.NewTicker(interval)
+	ticker := d.clock.NewTicker(interval)
 	defer ticker.Stop()
 
 	// Run startup tasks if provided
@@ -201,7 +578,7 @@ func (d *Daemon) periodicLoop(name string, interval time.Duration, onStartup, on
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			onTick()
 		case <-d.ctx.Done():
 			d.logger.Info("%s loop stopped", name)
@@ -234,8 +611,16 @@ func (d *Daemon) serverLoop() {
 // healthCheckLoop periodically checks agent health
 func (d *Daemon) healthCheckLoop() {
 	startup := func() {
+		d.claimRepoLocks()
 		d.checkAgentHealth()
+		d.reapZombieWindows(d.clock.Now())
+		d.checkDeadlines(d.clock.Now())
+		d.recoverInterruptedWorktrees(d.clock.Now())
+		d.detectOutputLoops()
+		d.detectIdleAgents(d.clock.Now())
+		d.checkWorkingDirectories()
 		d.rotateLogsIfNeeded()
+		d.archiveLogs(d.clock.Now())
 		d.cleanupMergedBranches()
 	}
 	d.periodicLoop("health check", 2*time.Minute, startup, startup)
@@ -250,6 +635,25 @@ func (d *Daemon) checkAgentHealth() {
 	// Get a snapshot of repos to avoid concurrent map access
 	repos := d.state.GetAllRepos()
 	for repoName, repo := range repos {
+		if d.isReadOnlyRepo(repoName, "health check") {
+			continue
+		}
+
+		// Headless agents have no window to check and don't depend on the
+		// session; they are only cleaned up once they complete
+		for agentName, agent := range repo.Agents {
+			if !agent.Headless {
+				continue
+			}
+			if agent.ReadyForCleanup {
+				d.logger.Info("Agent %s is ready for cleanup", agentName)
+				appendToSliceMap(deadAgents, repoName, agentName)
+				continue
+			}
+			// Prompts queued behind a run a previous daemon started
+			d.drainHeadless(repoName, agentName)
+		}
+
 		// Check if tmux session exists
 		hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
 		if err != nil {
@@ -260,11 +664,13 @@ func (d *Daemon) checkAgentHealth() {
 		if !hasSession {
 			d.logger.Warn("Tmux session %s not found for repo %s, attempting restoration", repo.TmuxSession, repoName)
 			// Try to restore the session and agents instead of cleaning up
-			if err := d.restoreRepoAgents(repoName, repo); err != nil {
+			if _, err := d.restoreRepoAgents(repoName, repo); err != nil {
 				d.logger.Error("Failed to restore repo %s: %v, marking all agents for cleanup", repoName, err)
 				// Only mark for cleanup if restoration failed
-				for agentName := range repo.Agents {
-					appendToSliceMap(deadAgents, repoName, agentName)
+				for agentName, agent := range repo.Agents {
+					if !agent.Headless {
+						appendToSliceMap(deadAgents, repoName, agentName)
+					}
 				}
 			} else {
 				d.logger.Info("Successfully restored tmux session and agents for repo %s", repoName)
@@ -272,8 +678,19 @@ func (d *Daemon) checkAgentHealth() {
 			continue
 		}
 
+		windows := make(map[string]mux.WindowInfo)
+		if infos, err := d.tmux.ListWindowInfo(d.ctx, repo.TmuxSession); err == nil {
+			for _, info := range infos {
+				windows[info.Name] = info
+			}
+		}
+
 		// Check each agent
 		for agentName, agent := range repo.Agents {
+			if agent.Headless {
+				continue
+			}
+
 			// Check if agent is marked as ready for cleanup
 			if agent.ReadyForCleanup {
 				d.logger.Info("Agent %s is ready for cleanup", agentName)
@@ -294,35 +711,202 @@ func (d *Daemon) checkAgentHealth() {
 				continue
 			}
 
-			// Check if process is alive (if we have a PID)
-			if agent.PID > 0 {
-				if !isProcessAlive(agent.PID) {
-					d.logger.Warn("Agent %s process (PID %d) not running", agentName, agent.PID)
-
-					// For persistent agents, attempt auto-restart
-					if agent.Type.IsPersistent() {
-						d.logger.Info("Attempting to auto-restart agent %s", agentName)
-						if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
-							d.logger.Error("Failed to restart agent %s: %v", agentName, err)
-						} else {
-							d.logger.Info("Successfully restarted agent %s", agentName)
-						}
-					}
-					// For transient agents (workers, review), don't auto-restart - they complete and clean up
-				}
+			// Restart agents whose Claude process exited without completing
+			if reason := claudeExitReason(agent, windows[agent.TmuxWindow], d.clock.Now()); reason != "" {
+				d.autoRestartAgent(repoName, agentName, agent, repo, reason, d.clock.Now())
 			}
 		}
 	}
 
 	// Clean up dead agents
 	if len(deadAgents) > 0 {
-		d.cleanupDeadAgents(deadAgents)
+		d.cleanupDeadAgents(deadAgents, "health_check")
 	}
 
 	// Clean up orphaned worktrees
 	d.cleanupOrphanedWorktrees()
 }
 
+// emitEvent sends an event through the notification hub, logging delivery failures
+func (d *Daemon) emitEvent(event events.Event) {
+	// Events report the daemon's time, which is simulated under a fake or
+	// scaled clock
+	event.Timestamp = d.clock.Now()
+	event.Priority = d.inheritTaskPriority(event)
+	if event.AgentType == "" && event.Agent != "" {
+		if agent, exists := d.state.GetAgent(event.Repo, event.Agent); exists {
+			event.AgentType = string(agent.Type)
+		}
+	}
+	if event.Attach == nil {
+		event.Attach = d.attachTarget(event.Repo, event.Agent)
+	}
+	if err := d.notify.Notify(d.ctx, event); err != nil {
+		d.logger.Warn("Failed to deliver event: %v", err)
+	}
+}
+
+// inheritTaskPriority returns the priority an agent's event should carry
+// given its task's priority: P0 and P1 tasks raise it to high, and P3 tasks
+// lower normal events to low. High-priority events are never lowered.
+func (d *Daemon) inheritTaskPriority(event events.Event) events.Priority {
+	if event.Agent == "" {
+		return event.Priority
+	}
+	agent, exists := d.state.GetAgent(event.Repo, event.Agent)
+	if !exists {
+		return event.Priority
+	}
+	switch agent.Priority.Effective() {
+	case state.TaskPriorityP0, state.TaskPriorityP1:
+		return events.PriorityHigh
+	case state.TaskPriorityP3:
+		if event.Priority == events.PriorityNormal || event.Priority == "" {
+			return events.PriorityLow
+		}
+	}
+	return event.Priority
+}
+
+// attachTarget returns where a human can find the agent an event is about.
+// Repository-wide events point at the supervisor. Returns nil if the agent
+// has no tmux window.
+func (d *Daemon) attachTarget(repoName, agentName string) *events.AttachTarget {
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists || repo.TmuxSession == "" {
+		return nil
+	}
+	if agentName == "" {
+		agentName = "supervisor"
+	}
+	agent, exists := repo.Agents[agentName]
+	if !exists || agent.TmuxWindow == "" || agent.Headless {
+		return nil
+	}
+	return events.NewAttachTarget(repoName, agentName, repo.TmuxSession, agent.TmuxWindow)
+}
+
+// outputLoopTailBytes is how much of an agent's captured output is examined for loops
+const outputLoopTailBytes = 64 * 1024
+
+// outputLoopState remembers what was last seen for an agent's output log
+type outputLoopState struct {
+	size     int64  // log size at the last check
+	reported string // snippet of the last reported loop
+}
+
+// detectOutputLoops scans captured agent output for repeated identical blocks,
+// which indicate an agent re-running the same failing command. Unlike idleness,
+// a loop means the log keeps growing with the same content, so agents whose
+// output hasn't changed since the last check are skipped.
+func (d *Daemon) detectOutputLoops() {
+	repos := d.state.GetAllRepos()
+	for repoName, repo := range repos {
+		for agentName, agent := range repo.Agents {
+			if agent.ReadyForCleanup {
+				continue
+			}
+
+			isWorker := agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview
+			logFile := d.paths.AgentLogFile(repoName, agentName, isWorker)
+			key := repoName + "/" + agentName
+
+			info, err := os.Stat(logFile)
+			if err != nil {
+				continue
+			}
+
+			d.outputLoopsMu.Lock()
+			prev := d.outputLoops[key]
+			d.outputLoopsMu.Unlock()
+			if info.Size() == prev.size {
+				continue
+			}
+
+			tail, err := readFileTail(logFile, outputLoopTailBytes)
+			if err != nil {
+				d.logger.Debug("Failed to read output for %s: %v", key, err)
+				continue
+			}
+
+			loop, found := loopdetect.Detect(tail, loopConfig(agent.Priority))
+			next := outputLoopState{size: info.Size(), reported: prev.reported}
+			if found && loop.Snippet != prev.reported {
+				next.reported = loop.Snippet
+				d.reportOutputLoop(repoName, agentName, agent, loop)
+			}
+
+			d.outputLoopsMu.Lock()
+			d.outputLoops[key] = next
+			d.outputLoopsMu.Unlock()
+		}
+	}
+}
+
+// loopConfig returns the loop detection settings for a task's priority.
+// Urgent work is flagged after fewer repeats so it isn't left spinning.
+func loopConfig(priority state.TaskPriority) loopdetect.Config {
+	cfg := loopdetect.DefaultConfig()
+	switch priority.Effective() {
+	case state.TaskPriorityP0:
+		cfg.MinRepeats = 2
+	case state.TaskPriorityP1:
+		cfg.MinRepeats = 3
+	}
+	return cfg
+}
+
+// reportOutputLoop emits an agent.stuck event for a detected loop and lets the
+// supervisor know so it can intervene
+func (d *Daemon) reportOutputLoop(repoName, agentName string, agent state.Agent, loop loopdetect.Loop) {
+	d.logger.Warn("Agent %s/%s appears to be looping (%d repeats of a %d-line block)", repoName, agentName, loop.Repeats, loop.BlockLines)
+
+	event := events.NewTypedEvent(repoName, agentName,
+		fmt.Sprintf("Agent %s is repeating the same output", agentName),
+		events.AgentStuckPayload{
+			Reason:     "output_loop",
+			Repeats:    loop.Repeats,
+			BlockLines: loop.BlockLines,
+			Snippet:    loop.Snippet,
+		})
+	event.Priority = events.PriorityHigh
+	event.Message = loop.Snippet
+	d.emitEvent(event)
+
+	if agent.Type == state.AgentTypeSupervisor {
+		return
+	}
+	msg := fmt.Sprintf("Agent '%s' appears stuck in a loop: the following output repeated %d times:\n\n%s",
+		agentName, loop.Repeats, format.Truncate(loop.Snippet, 500))
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", msg); err != nil {
+		d.logger.Error("Failed to send loop notification to supervisor: %v", err)
+	}
+}
+
+// readFileTail returns up to maxBytes from the end of a file
+func readFileTail(path string, maxBytes int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	offset := info.Size() - maxBytes
+	if offset < 0 {
+		offset = 0
+	}
+	buf := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return "", err
+	}
+	return string(buf), nil
+}
+
 // messageRouterLoop watches for new messages and delivers them
 func (d *Daemon) messageRouterLoop() {
 	d.periodicLoop("message router", 2*time.Minute, nil, d.routeMessages)
@@ -347,6 +931,12 @@ func (d *Daemon) routeMessages() {
 				continue
 			}
 
+			// Headless agents get messages between runs; they stay pending
+			// until the current run has finished
+			if agent.Headless && d.headlessBusy(repoName, agentName) {
+				continue
+			}
+
 			// Get unread messages (pending or delivered but not yet read)
 			unreadMsgs, err := msgMgr.ListUnread(repoName, agentName)
 			if err != nil {
@@ -361,12 +951,19 @@ func (d *Daemon) routeMessages() {
 					continue
 				}
 
+				// Common worker questions are answered before reaching the supervisor
+				if agent.Type == state.AgentTypeSupervisor && d.autoAnswer(repoName, repo, msg) {
+					continue
+				}
+
 				// Format message for delivery
 				messageText := fmt.Sprintf("📨 Message from %s: %s", msg.From, msg.Body)
 
 				// Send via tmux using atomic method to avoid race conditions
-				// where Enter might be lost between separate exec calls (issue #63)
-				if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, messageText); err != nil {
+				// where Enter might be lost between separate exec calls (issue #63).
+				// A headless agent's first message starts a run and the rest
+				// are queued behind it.
+				if err := d.sendToAgent(repoName, repo.TmuxSession, agentName, agent, messageText); err != nil {
 					d.logger.Error("Failed to deliver message %s to %s/%s: %v", msg.ID, repoName, agentName, err)
 					continue
 				}
@@ -378,6 +975,14 @@ func (d *Daemon) routeMessages() {
 				}
 
 				d.logger.Info("Delivered message %s from %s to %s/%s", msg.ID, msg.From, repoName, agentName)
+				d.recordAudit(audit.Entry{
+					Action:  audit.ActionMessageDelivered,
+					Repo:    repoName,
+					Agent:   agentName,
+					Detail:  "id=" + msg.ID,
+					Command: "route_messages",
+					Actor:   msg.From,
+				})
 			}
 		}
 	}
@@ -388,6 +993,19 @@ func (d *Daemon) getMessageManager() *messages.Manager {
 	return messages.NewManager(d.paths.MessagesDir)
 }
 
+// agentWorktreeManager returns a worktree manager for creating worktrees of
+// agents of the given type, which skip Git LFS content if the repository's
+// config says so. LFS download progress goes to the daemon log.
+func (d *Daemon) agentWorktreeManager(repoName string, agentType state.AgentType) *worktree.Manager {
+	fetchLFS := true
+	if repo, exists := d.state.GetAllRepos()[repoName]; exists {
+		fetchLFS = repo.LFSContentFor(agentType)
+	}
+	return worktree.NewManager(d.paths.RepoDir(repoName),
+		worktree.WithLFSContent(fetchLFS),
+		worktree.WithProgress(d.logger.InfoWriter()))
+}
+
 // wakeLoop periodically wakes agents with status checks
 func (d *Daemon) wakeLoop() {
 	d.periodicLoop("wake", 2*time.Minute, nil, d.wakeAgents)
@@ -397,7 +1015,7 @@ func (d *Daemon) wakeLoop() {
 func (d *Daemon) wakeAgents() {
 	d.logger.Debug("Waking agents")
 
-	now := time.Now()
+	now := d.clock.Now()
 
 	// Get a snapshot of repos to avoid concurrent map access
 	repos := d.state.GetAllRepos()
@@ -413,6 +1031,11 @@ func (d *Daemon) wakeAgents() {
 				continue
 			}
 
+			// A headless agent that is still running is working, not waiting
+			if agent.Headless && d.headlessBusy(repoName, agentName) {
+				continue
+			}
+
 			// Send wake message based on agent type
 			var message string
 			switch agent.Type {
@@ -429,7 +1052,7 @@ func (d *Daemon) wakeAgents() {
 			}
 
 			// Send message using atomic method to avoid race conditions (issue #63)
-			if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, agent.TmuxWindow, message); err != nil {
+			if err := d.sendToAgent(repoName, repo.TmuxSession, agentName, agent, message); err != nil {
 				d.logger.Error("Failed to send wake message to agent %s: %v", agentName, err)
 				continue
 			}
@@ -445,40 +1068,12 @@ func (d *Daemon) wakeAgents() {
 	}
 }
 
-// worktreeRefreshLoop periodically syncs worker worktrees with main branch
-func (d *Daemon) worktreeRefreshLoop() {
-	defer d.wg.Done()
-	d.logger.Info("Starting worktree refresh loop")
-
-	// Run every 5 minutes
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	// Run once after a short delay on startup (respecting context cancellation)
-	select {
-	case <-time.After(30 * time.Second):
-		d.refreshWorktrees()
-	case <-d.ctx.Done():
-		d.logger.Info("Worktree refresh loop stopped")
-		return
-	}
-
-	for {
-		select {
-		case <-ticker.C:
-			d.refreshWorktrees()
-		case <-d.ctx.Done():
-			d.logger.Info("Worktree refresh loop stopped")
-			return
-		}
-	}
-}
-
 // refreshWorktrees syncs worker worktrees that are behind main
 func (d *Daemon) refreshWorktrees() {
 	d.logger.Debug("Checking worker worktrees for refresh")
 
 	repos := d.state.GetAllRepos()
+	updatedMirrors := make(map[string]bool)
 	for repoName, repo := range repos {
 		repoPath := d.paths.RepoDir(repoName)
 
@@ -486,6 +1081,18 @@ func (d *Daemon) refreshWorktrees() {
 		if _, err := os.Stat(repoPath); os.IsNotExist(err) {
 			continue
 		}
+		if d.isReadOnlyRepo(repoName, "worktree refresh") {
+			continue
+		}
+
+		// Update the shared mirror first (once per pass) so the repo's own
+		// fetch only downloads objects the mirror doesn't have
+		if repo.Mirror != "" && !updatedMirrors[repo.Mirror] {
+			updatedMirrors[repo.Mirror] = true
+			if err := worktree.UpdateMirror(repo.Mirror); err != nil {
+				d.logger.Warn("Could not update mirror %s: %v", repo.Mirror, err)
+			}
+		}
 
 		wt := worktree.NewManager(repoPath)
 
@@ -508,6 +1115,21 @@ func (d *Daemon) refreshWorktrees() {
 			continue
 		}
 
+		// Workers in a fork workflow push somewhere else; fetch that too to
+		// see what others pushed to their branches
+		pushRemote := repo.WorkerPushRemote()
+		if pushRemote != remote {
+			if err := wt.FetchRemote(pushRemote); err != nil {
+				d.logger.Debug("Could not fetch from push remote for %s: %v", repoName, err)
+			}
+		}
+
+		// Never rebase workers onto a rewritten main without confirmation
+		if d.checkMainRewrite(repoName, repo, wt, remote, mainBranch) {
+			continue
+		}
+		mainHead, _ := wt.RemoteHead(remote, mainBranch)
+
 		// Check each worker agent's worktree
 		for agentName, agent := range repo.Agents {
 			// Only refresh worker worktrees
@@ -525,37 +1147,106 @@ func (d *Daemon) refreshWorktrees() {
 				continue
 			}
 
+			// Don't rebase onto main while commits someone else pushed to the
+			// branch are missing from the worktree
+			if d.checkExternalPush(repoName, agentName, agent, pushRemote) {
+				continue
+			}
+
+			// Workers based on another branch follow that branch instead of
+			// main; tags and commits are fixed bases with nothing to follow
+			target, targetHead := mainBranch, mainHead
+			if agent.BaseBranch != "" && agent.BaseBranch != mainBranch {
+				target = agent.BaseBranch
+				if targetHead, err = wt.RemoteHead(remote, target); err != nil {
+					d.logger.Debug("Skipping refresh for %s/%s: %v", repoName, agentName, err)
+					continue
+				}
+			} else if agent.Base != "" && agent.BaseBranch == "" {
+				d.logger.Debug("Skipping refresh for %s/%s: based on fixed revision %s", repoName, agentName, agent.Base)
+				continue
+			}
+
 			// Check worktree state
-			wtState, err := worktree.GetWorktreeState(agent.WorktreePath, remote, mainBranch)
+			wtState, err := worktree.GetWorktreeState(agent.WorktreePath, remote, target)
 			if err != nil {
 				d.logger.Debug("Could not get worktree state for %s/%s: %v", repoName, agentName, err)
 				continue
 			}
 
+			// A conflict is settled once the worktree has caught up with its base
+			if agent.RefreshConflict != nil && wtState.CommitsBehind == 0 && !wtState.IsMidRebase {
+				if agent.Question != nil && agent.Question.ResponseID == agent.RefreshConflict.QuestionID {
+					d.responses.Revoke(agent.Question.ResponseID)
+					agent.Question = nil
+				}
+				agent.RefreshConflict = nil
+				if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+					d.logger.Warn("Failed to clear refresh conflict for %s/%s: %v", repoName, agentName, err)
+				}
+			}
+
 			// Skip if can't refresh (detached HEAD, mid-rebase, mid-merge, on main, or up to date)
 			if !wtState.CanRefresh {
 				d.logger.Debug("Skipping refresh for %s/%s: %s", repoName, agentName, wtState.RefreshReason)
+				if reason, ok := refreshSkipReasons[wtState.RefreshReason]; ok {
+					d.reportRefreshSkipped(repoName, agentName, target, reason)
+				} else if wtState.RefreshReason == "already up to date" {
+					d.reportRefreshSkipped(repoName, agentName, target, "")
+				}
+				continue
+			}
+
+			// Scoped workers only need a rebase when their base changed their sub-project
+			if len(agent.ScopePaths) > 0 {
+				changed, err := worktree.UpstreamChangesIn(agent.WorktreePath, remote+"/"+target, agent.ScopePaths)
+				if err == nil && !changed {
+					d.logger.Debug("Skipping refresh for %s/%s: no upstream changes in %v", repoName, agentName, agent.ScopePaths)
+					continue
+				}
+			}
+
+			// Rebasing onto the same head again would conflict again
+			if pendingRefreshConflict(agent, targetHead) {
+				d.logger.Debug("Skipping refresh for %s/%s: conflict with %s/%s pending (%s)", repoName, agentName, remote, target, agent.RefreshConflict.Resolution)
 				continue
 			}
 
 			// Refresh the worktree
 			d.logger.Info("Refreshing worktree for %s/%s (%d commits behind)", repoName, agentName, wtState.CommitsBehind)
-			result := worktree.RefreshWorktree(agent.WorktreePath, remote, mainBranch)
+			refresh := worktree.RefreshWorktree
+			if repo.ConflictAssist {
+				refresh = worktree.RefreshWorktreeKeepingConflicts
+			}
+			result := refresh(agent.WorktreePath, remote, target)
 
 			if result.Error != nil {
-				if result.HasConflicts {
+				if result.Paused {
+					d.logger.Warn("Worktree refresh for %s/%s paused at conflicts in: %v", repoName, agentName, result.ConflictFiles)
+					d.assistRefreshConflict(repoName, agentName, remote+"/"+target, targetHead, result)
+				} else if result.HasConflicts {
 					d.logger.Warn("Worktree refresh for %s/%s has conflicts in: %v", repoName, agentName, result.ConflictFiles)
+					d.reportRefreshConflict(repoName, agentName, agent, remote+"/"+target, targetHead, result)
+					d.reportRefreshSkipped(repoName, agentName, target, fmt.Sprintf("rebasing conflicts in %s. The rebase was aborted and your worktree is unchanged; keep working while a human decides how to resolve it",
+						strings.Join(result.ConflictFiles, ", ")))
 				} else {
 					d.logger.Error("Failed to refresh worktree for %s/%s: %v", repoName, agentName, result.Error)
+					d.reportRefreshSkipped(repoName, agentName, target, "the rebase failed: "+strings.SplitN(result.Error.Error(), "\n", 2)[0])
 				}
 			} else if result.Skipped {
 				d.logger.Debug("Worktree refresh for %s/%s skipped: %s", repoName, agentName, result.SkipReason)
+				d.reportRefreshSkipped(repoName, agentName, target, result.SkipReason)
 			} else {
 				d.logger.Info("Refreshed worktree for %s/%s: rebased %d commits", repoName, agentName, result.CommitsRebased)
+				d.reportRefreshSkipped(repoName, agentName, target, "")
+				d.recordAction(repoName, feed.ActionRefreshed, agentName, fmt.Sprintf("rebased %d commits onto %s/%s", result.CommitsRebased, remote, target))
 
 				// Notify the agent that their worktree was refreshed
 				msgMgr := d.getMessageManager()
-				msg := fmt.Sprintf("Your worktree has been automatically synced with main (rebased %d commits). Run 'git log --oneline -5' to see recent changes.", result.CommitsRebased)
+				msg := fmt.Sprintf("Your worktree has been automatically synced with %s (rebased %d commits). Run 'git log --oneline -5' to see recent changes.", target, result.CommitsRebased)
+				if result.SubmodulesUpdated {
+					msg += " Submodules were updated to the commits it records."
+				}
 				if _, err := msgMgr.Send(repoName, "daemon", agentName, msg); err != nil {
 					d.logger.Debug("Could not send refresh notification to %s/%s: %v", repoName, agentName, err)
 				}
@@ -564,134 +1255,229 @@ func (d *Daemon) refreshWorktrees() {
 	}
 }
 
-// TriggerWorktreeRefresh triggers an immediate worktree refresh (for testing)
-func (d *Daemon) TriggerWorktreeRefresh() {
-	d.refreshWorktrees()
+// metricsLoop exports the previous day's metrics snapshot once per day, keeps
+// the merge queue textfile current, and drops expired events from the event
+// store. It checks every minute so a daemon that was down at midnight still
+// catches up.
+func (d *Daemon) metricsLoop() {
+	refresh := func() {
+		d.exportDailyMetricsIfDue()
+		d.writeMergeQueueMetrics()
+		d.compactEventStore()
+	}
+	d.periodicLoop("metrics", time.Minute, refresh, refresh)
 }
 
-// handleRequest handles incoming socket requests
-func (d *Daemon) handleRequest(req socket.Request) socket.Response {
-	d.logger.Debug("Handling request: %s", req.Command)
-
-	switch req.Command {
-	case "ping":
-		return socket.Response{Success: true, Data: "pong"}
-
-	case "status":
-		return d.handleStatus(req)
+// metricsMarkerFile records the last day whose metrics were exported
+func (d *Daemon) metricsMarkerFile() string {
+	return filepath.Join(d.paths.MetricsDir(), ".last-export")
+}
 
-	case "stop":
-		go func() {
-			time.Sleep(100 * time.Millisecond)
-			d.Stop()
-		}()
-		return socket.Response{Success: true, Data: "Daemon stopping"}
+// exportDailyMetricsIfDue exports yesterday's snapshot unless already done
+func (d *Daemon) exportDailyMetricsIfDue() {
+	yesterday := d.clock.Now().AddDate(0, 0, -1)
+	date := yesterday.Format(metrics.DateLayout)
 
-	case "list_repos":
-		return d.handleListRepos(req)
+	if data, err := os.ReadFile(d.metricsMarkerFile()); err == nil && strings.TrimSpace(string(data)) == date {
+		return
+	}
 
-	case "add_repo":
-		return d.handleAddRepo(req)
+	if _, err := d.exportMetrics(yesterday); err != nil {
+		d.logger.Error("Failed to export daily metrics: %v", err)
+		return
+	}
 
-	case "remove_repo":
-		return d.handleRemoveRepo(req)
+	if err := os.WriteFile(d.metricsMarkerFile(), []byte(date+"\n"), 0644); err != nil {
+		d.logger.Warn("Failed to record metrics export marker: %v", err)
+	}
+}
 
-	case "add_agent":
-		return d.handleAddAgent(req)
+// exportMetrics computes snapshots for every repository on the given day,
+// appends them to metrics.csv and metrics.jsonl, and posts them as events
+func (d *Daemon) exportMetrics(day time.Time) ([]metrics.Snapshot, error) {
+	if err := os.MkdirAll(d.paths.MetricsDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create metrics directory: %w", err)
+	}
 
-	case "remove_agent":
-		return d.handleRemoveAgent(req)
+	repos := d.state.GetAllRepos()
+	names := make([]string, 0, len(repos))
+	for name := range repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	case "list_agents":
-		return d.handleListAgents(req)
+	snapshots := make([]metrics.Snapshot, 0, len(names))
+	for _, name := range names {
+		snapshots = append(snapshots, metrics.ComputeDaily(name, repos[name], day))
+	}
+	if len(snapshots) == 0 {
+		return snapshots, nil
+	}
 
-	case "complete_agent":
-		return d.handleCompleteAgent(req)
+	if err := metrics.AppendCSV(filepath.Join(d.paths.MetricsDir(), "metrics.csv"), snapshots); err != nil {
+		return nil, err
+	}
+	if err := metrics.AppendJSONL(filepath.Join(d.paths.MetricsDir(), "metrics.jsonl"), snapshots); err != nil {
+		return nil, err
+	}
 
-	case "restart_agent":
-		return d.handleRestartAgent(req)
+	for _, snap := range snapshots {
+		event := events.NewTypedEvent(snap.Repo, "",
+			fmt.Sprintf("Daily metrics for %s on %s: %d started, %d completed, %d failed, %d PRs merged",
+				snap.Repo, snap.Date, snap.TasksStarted, snap.TasksCompleted, snap.TasksFailed, snap.PRsMerged),
+			events.MetricsDailyPayload{Snapshot: snap})
+		event.Priority = events.PriorityLow
+		d.emitEvent(event)
+	}
 
-	case "trigger_cleanup":
-		return d.handleTriggerCleanup(req)
+	d.logger.Info("Exported metrics for %d repositories (%s)", len(snapshots), day.Format(metrics.DateLayout))
+	return snapshots, nil
+}
 
-	case "repair_state":
-		return d.handleRepairState(req)
+// handleExportMetrics exports a metrics snapshot on demand, for today unless
+// a date is given
+func (d *Daemon) handleExportMetrics(req socket.Request, args socket.ExportMetricsArgs) (socket.ExportedMetrics, error) {
+	day := d.clock.Now()
+	if args.Date != "" {
+		parsed, err := time.ParseInLocation(metrics.DateLayout, args.Date, time.Local)
+		if err != nil {
+			return socket.ExportedMetrics{}, socket.Errorf(socket.CodeInvalidArgument, "invalid date %q: expected YYYY-MM-DD", args.Date)
+		}
+		day = parsed
+	}
 
-	case "get_repo_config":
-		return d.handleGetRepoConfig(req)
+	snapshots, err := d.exportMetrics(day)
+	if err != nil {
+		return socket.ExportedMetrics{}, err
+	}
+	return socket.ExportedMetrics{Snapshots: snapshots, Dir: d.paths.MetricsDir()}, nil
+}
 
-	case "update_repo_config":
-		return d.handleUpdateRepoConfig(req)
-
-	case "set_current_repo":
-		return d.handleSetCurrentRepo(req)
+// TriggerWorktreeRefresh triggers an immediate worktree refresh (for testing)
+func (d *Daemon) TriggerWorktreeRefresh() {
+	d.refreshWorktrees()
+}
 
-	case "get_current_repo":
-		return d.handleGetCurrentRepo(req)
+// dispatchRequest checks a socket request against the command registry and
+// the caller's permissions, then routes it through its priority lane so that
+// heavy maintenance commands never delay interactive ones. With tracing on,
+// each request is a span, joining the client's trace if it sent one.
+func (d *Daemon) dispatchRequest(req socket.Request) socket.Response {
+	_, span := tracing.Start(tracing.WithTraceparent(d.ctx, req.Traceparent), "handle "+req.Command)
+	span.SetAttr("multiclaude.command", req.Command)
+	span.SetAttr("multiclaude.protocol_version", req.Version)
+	resp := d.dispatch(req, span)
+	if resp.Code != "" {
+		span.SetAttr("multiclaude.error_code", string(resp.Code))
+	}
+	span.End(resp.Err())
+	return resp
+}
 
-	case "clear_current_repo":
-		return d.handleClearCurrentRepo(req)
+// dispatch checks and runs a request for dispatchRequest
+func (d *Daemon) dispatch(req socket.Request, span *tracing.Span) socket.Response {
+	if err := d.commands.Check(req); err != nil {
+		return socket.Finish(req, err.Response())
+	}
+	if resp, ok := d.authorize(req); !ok {
+		return socket.Finish(req, resp)
+	}
+	if resp, ok := d.checkRepoLock(req); !ok {
+		return socket.Finish(req, resp)
+	}
 
-	case "route_messages":
-		go d.routeMessages()
-		return socket.Response{Success: true, Data: "Message routing triggered"}
+	d.chaosDelay(req.Command)
 
-	case "task_history":
-		return d.handleTaskHistory(req)
+	l := commandLane(req.Command)
+	start := d.clock.Now()
+	queued := time.Now()
+	span.SetAttr("multiclaude.lane", l.String())
+	resp := d.lanes.run(d.ctx, l, func() socket.Response {
+		span.SetAttr("multiclaude.lane_wait_ms", int(time.Since(queued).Milliseconds()))
+		return d.handleRequest(req)
+	})
+	d.logger.Debug("Handled %s in %s lane (%s)", req.Command, l, time.Since(start))
+	d.auditRequest(req, resp)
+	return socket.Finish(req, resp)
+}
 
-	case "spawn_agent":
-		return d.handleSpawnAgent(req)
+// handleRequest runs the handler of a request's command
+func (d *Daemon) handleRequest(req socket.Request) socket.Response {
+	d.logger.Debug("Handling request: %s", req.Command)
 
-	default:
-		return socket.Response{
-			Success: false,
-			Error:   fmt.Sprintf("unknown command: %q. Run 'multiclaude --help' for available commands", req.Command),
-		}
+	cmd, ok := d.commands.Lookup(req.Command)
+	if !ok {
+		return socket.Errorf(socket.CodeUnknownCommand, "unknown command: %q. Run 'multiclaude --help' for available commands", req.Command).Response()
 	}
+	return cmd.Handler.Handle(req)
 }
 
-// handleStatus returns daemon status
-func (d *Daemon) handleStatus(req socket.Request) socket.Response {
+// handleStatus returns daemon status, with all_hosts the fleet_status of
+// every host as "fleet"
+func (d *Daemon) handleStatus(req socket.Request, args socket.StatusArgs) (socket.Status, error) {
 	repos := d.state.ListRepos()
 	agentCount := 0
 	for _, repo := range repos {
 		agents, _ := d.state.ListAgents(repo)
 		agentCount += len(agents)
 	}
-
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"running":     true,
-			"pid":         os.Getpid(),
-			"repos":       len(repos),
-			"agents":      agentCount,
-			"socket_path": d.paths.DaemonSock,
-		},
+	mergeQueueDepth := 0
+	for _, stats := range d.mergeQueueStats("") {
+		mergeQueueDepth += stats.Depth
+	}
+
+	status := socket.Status{
+		Running:         true,
+		PID:             os.Getpid(),
+		Repos:           len(repos),
+		Agents:          agentCount,
+		SocketPath:      d.paths.DaemonSock,
+		SocketGroup:     d.state.GetSocketGroup(),
+		Lanes:           d.lanes.stats(),
+		MergeQueueDepth: mergeQueueDepth,
+		GitHub:          d.github.Stats(),
+		Multiplexer:     mux.Backend(d.tmux),
+		ProtocolVersion: socket.ProtocolVersion,
+		RemoteListen:    d.remoteAddr(),
+		LastRestore:     d.getLastRestore(),
+	}
+	if args.AllHosts {
+		fleetStatus, err := d.fleetStatus(req, socket.FleetStatusArgs{})
+		if err != nil {
+			return socket.Status{}, err
+		}
+		status.Fleet = &fleetStatus
 	}
+	return status, nil
 }
 
-// handleListRepos lists all repositories with detailed status
-func (d *Daemon) handleListRepos(req socket.Request) socket.Response {
+// handleListRepos lists the repositories' names or, with rich, their status
+func (d *Daemon) handleListRepos(req socket.Request, args socket.ListReposArgs) (socket.RepoList, error) {
 	repos := d.state.GetAllRepos()
 
-	// Check if rich format is requested
-	rich, _ := req.Args["rich"].(bool)
-	if !rich {
+	// Optionally restrict to a single repo group
+	if args.Group != "" {
+		for name, repo := range repos {
+			if !repo.InGroup(args.Group) {
+				delete(repos, name)
+			}
+		}
+	}
+
+	if !args.Rich {
 		// Return simple list for backward compatibility
 		repoNames := make([]string, 0, len(repos))
 		for name := range repos {
 			repoNames = append(repoNames, name)
 		}
-		return socket.Response{Success: true, Data: repoNames}
+		return socket.RepoList{Names: repoNames}, nil
 	}
 
 	// Return detailed repo info
-	repoDetails := make([]map[string]interface{}, 0, len(repos))
+	repoDetails := make([]socket.RepoInfo, 0, len(repos))
 	for repoName, repo := range repos {
 		// Count agents by type
 		workerCount := 0
-		totalAgents := len(repo.Agents)
 		for _, agent := range repo.Agents {
 			if agent.Type == state.AgentTypeWorker {
 				workerCount++
@@ -704,278 +1490,593 @@ func (d *Daemon) handleListRepos(req socket.Request) socket.Response {
 			sessionHealthy = hasSession
 		}
 
-		repoDetails = append(repoDetails, map[string]interface{}{
-			"name":            repoName,
-			"github_url":      repo.GithubURL,
-			"tmux_session":    repo.TmuxSession,
-			"total_agents":    totalAgents,
-			"worker_count":    workerCount,
-			"session_healthy": sessionHealthy,
-		})
+		details := socket.RepoInfo{
+			Name:           repoName,
+			GithubURL:      repo.GithubURL,
+			TmuxSession:    repo.TmuxSession,
+			TotalAgents:    len(repo.Agents),
+			WorkerCount:    workerCount,
+			SessionHealthy: sessionHealthy,
+			Groups:         repo.Groups,
+			RefreshPaused:  repo.HistoryRewrite != nil,
+		}
+		for agentName, agent := range repo.Agents {
+			if agent.CwdDrift != "" {
+				if details.CwdDrift == nil {
+					details.CwdDrift = make(map[string]string)
+				}
+				details.CwdDrift[agentName] = agent.CwdDrift
+			}
+		}
+		if owner, foreign := d.foreignLock(repoName); foreign {
+			details.LockedBy = owner.String()
+		}
+		repoDetails = append(repoDetails, details)
 	}
-
-	return socket.Response{Success: true, Data: repoDetails}
+	return socket.RepoList{Rich: true, Repos: repoDetails}, nil
 }
 
 // handleAddRepo adds a new repository
-func (d *Daemon) handleAddRepo(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required (e.g., 'my-project')")
-	if !ok {
-		return errResp
-	}
-
-	githubURL, errResp, ok := getRequiredStringArg(req.Args, "github_url", "GitHub repository URL is required (e.g., 'https://github.com/owner/repo')")
-	if !ok {
-		return errResp
-	}
-
-	tmuxSession, errResp, ok := getRequiredStringArg(req.Args, "tmux_session", "tmux session name is required")
-	if !ok {
-		return errResp
-	}
-
+func (d *Daemon) handleAddRepo(req socket.Request, args socket.AddRepoArgs) (socket.AddedRepo, error) {
 	// Parse merge queue configuration (optional, defaults to enabled with "all" tracking)
 	mqConfig := state.DefaultMergeQueueConfig()
-	if mqEnabled, ok := req.Args["mq_enabled"].(bool); ok {
-		mqConfig.Enabled = mqEnabled
+	if args.MQEnabled != nil {
+		mqConfig.Enabled = *args.MQEnabled
 	}
-	if mqTrackMode, ok := req.Args["mq_track_mode"].(string); ok {
-		switch mqTrackMode {
-		case "all":
-			mqConfig.TrackMode = state.TrackModeAll
-		case "author":
-			mqConfig.TrackMode = state.TrackModeAuthor
-		case "assigned":
-			mqConfig.TrackMode = state.TrackModeAssigned
-		}
+	switch args.MQTrackMode {
+	case "all":
+		mqConfig.TrackMode = state.TrackModeAll
+	case "author":
+		mqConfig.TrackMode = state.TrackModeAuthor
+	case "assigned":
+		mqConfig.TrackMode = state.TrackModeAssigned
 	}
 
 	repo := &state.Repository{
-		GithubURL:        githubURL,
-		TmuxSession:      tmuxSession,
+		GithubURL:        args.GithubURL,
+		TmuxSession:      args.TmuxSession,
 		Agents:           make(map[string]state.Agent),
 		MergeQueueConfig: mqConfig,
+		CloneFilter:      args.CloneFilter,
+		Mirror:           args.Mirror,
 	}
 
-	if err := d.state.AddRepo(name, repo); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+	if err := d.state.AddRepo(args.Name, repo); err != nil {
+		return socket.AddedRepo{}, err
 	}
 
-	d.logger.Info("Added repository: %s (merge queue: enabled=%v, track=%s)", name, mqConfig.Enabled, mqConfig.TrackMode)
-	return socket.Response{Success: true}
+	d.logger.Info("Added repository: %s (merge queue: enabled=%v, track=%s)", args.Name, mqConfig.Enabled, mqConfig.TrackMode)
+	d.claimRepoLock(args.Name)
+
+	// Only tmux windows can be created by the CLI; with other multiplexers
+	// init asks the daemon to create the session and start the agents
+	if !args.StartAgents {
+		return socket.AddedRepo{}, nil
+	}
+	restored, err := d.restoreRepoAgents(args.Name, repo)
+	if err != nil {
+		return socket.AddedRepo{}, fmt.Errorf("failed to start agents: %v", err)
+	}
+	var started socket.AddedRepo
+	for _, agent := range restored {
+		if agent.Outcome == restoreStarted {
+			started.Agents = append(started.Agents, agent.Name)
+		}
+	}
+	return started, nil
 }
 
 // handleRemoveRepo removes a repository from state
-func (d *Daemon) handleRemoveRepo(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
-	if !ok {
-		return errResp
+func (d *Daemon) handleRemoveRepo(req socket.Request, args socket.RemoveRepoArgs) (struct{}, error) {
+	name := args.Name
+
+	// Leave the worktrees of a repository another daemon manages alone
+	if _, foreign := d.foreignLock(name); !foreign {
+		d.drainWarmPool(name)
+		d.releaseRepoLock(name)
 	}
 
+	repo, exists := d.state.GetAllRepos()[name]
 	if err := d.state.RemoveRepo(name); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+		return struct{}{}, err
 	}
-
-	d.logger.Info("Removed repository: %s", name)
-	return socket.Response{Success: true}
-}
-
-// handleAddAgent adds a new agent
-func (d *Daemon) handleAddAgent(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
-	if !ok {
-		return errResp
+	if exists && d.hostsWindows() {
+		if err := d.tmux.KillSession(d.ctx, repo.TmuxSession); err != nil {
+			d.logger.Warn("Failed to kill session %s: %v", repo.TmuxSession, err)
+		}
 	}
 
-	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
-	if !ok {
-		return errResp
-	}
+	d.repoLocksMu.Lock()
+	delete(d.foreignLocks, name)
+	d.repoLocksMu.Unlock()
 
-	agentTypeStr, errResp, ok := getRequiredStringArg(req.Args, "type", "agent type is required (supervisor, worker, merge-queue, or reviewer)")
-	if !ok {
-		return errResp
+	if err := d.feed.Remove(name); err != nil {
+		d.logger.Warn("Failed to remove feed for %s: %v", name, err)
 	}
 
-	worktreePath, errResp, ok := getRequiredStringArg(req.Args, "worktree_path", "path to the agent's git worktree is required")
-	if !ok {
-		return errResp
-	}
+	d.logger.Info("Removed repository: %s", name)
+	return struct{}{}, nil
+}
 
-	tmuxWindow, errResp, ok := getRequiredStringArg(req.Args, "tmux_window", "tmux window name is required")
-	if !ok {
-		return errResp
+// handleAddAgent adds a new agent
+func (d *Daemon) handleAddAgent(req socket.Request, args socket.AddAgentArgs) (struct{}, error) {
+	repoName, agentName := args.Repo, args.Agent
+
+	// Headless agents run without a tmux window (see headless.go)
+	if args.TmuxWindow == "" && !args.Headless {
+		return struct{}{}, socket.Errorf(socket.CodeMissingArgument, "missing 'tmux_window': tmux window name is required")
 	}
 
 	// Get session ID from args or generate one
-	sessionID, _, ok := getRequiredStringArg(req.Args, "session_id", "")
-	if !ok {
+	sessionID := args.SessionID
+	if sessionID == "" {
 		sessionID = fmt.Sprintf("agent-%d", time.Now().UnixNano())
 	}
 
-	// Get PID from args (optional)
-	var pid int
-	if pidFloat, ok := req.Args["pid"].(float64); ok {
-		pid = int(pidFloat)
-	} else if pidInt, ok := req.Args["pid"].(int); ok {
-		pid = pidInt
+	if state.AgentType(args.Type) == state.AgentTypeWorker {
+		if err := d.workerCapacityError(repoName); err != nil {
+			return struct{}{}, err
+		}
 	}
 
 	agent := state.Agent{
-		Type:         state.AgentType(agentTypeStr),
-		WorktreePath: worktreePath,
-		TmuxWindow:   tmuxWindow,
+		Type:         state.AgentType(args.Type),
+		WorktreePath: args.WorktreePath,
+		TmuxWindow:   args.TmuxWindow,
 		SessionID:    sessionID,
-		PID:          pid,
-		CreatedAt:    time.Now(),
+		PID:          args.PID,
+		Headless:     args.Headless,
+		CreatedAt:    d.clock.Now(),
+		Task:         args.Task,
+		ReadOnly:     args.ReadOnly,
+		PromptSHA256: args.PromptSHA256,
+		Model:        args.Model,
+		Base:         args.Base,
+		BaseBranch:   args.BaseBranch,
+		Labels:       nonEmpty(args.Labels),
+		AllowedPaths: nonEmpty(args.AllowedPaths),
+		ScopePaths:   nonEmpty(args.ScopePaths),
+	}
+
+	// Optional task priority
+	if args.Priority != "" {
+		priority, err := state.ParseTaskPriority(args.Priority)
+		if err != nil {
+			return struct{}{}, err
+		}
+		agent.Priority = priority
 	}
 
-	// Optional task field for workers
-	if task, ok := req.Args["task"].(string); ok {
-		agent.Task = task
+	// Optional time budget for time-boxed workers
+	if args.TimeBudgetSeconds > 0 {
+		agent.Deadline = agent.CreatedAt.Add(time.Duration(args.TimeBudgetSeconds * float64(time.Second)))
 	}
 
 	if err := d.state.AddAgent(repoName, agentName, agent); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+		return struct{}{}, err
 	}
 
-	d.logger.Info("Added agent %s to repo %s", agentName, repoName)
-	return socket.Response{Success: true}
-}
+	// The daemon runs a headless agent's Claude itself, starting with the
+	// prompt file and first message the caller prepared
+	if args.Headless && args.PromptFile != "" && os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		if _, err := d.startHeadless(repoName, agentName, agent, args.PromptFile, args.InitialMessage); err != nil {
+			d.state.RemoveAgent(repoName, agentName)
+			return struct{}{}, fmt.Errorf("failed to start headless agent: %v", err)
+		}
+	}
 
-// handleRemoveAgent removes an agent
-func (d *Daemon) handleRemoveAgent(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
-	if !ok {
-		return errResp
+	// The CLI can only create tmux windows, so under another multiplexer it
+	// has the daemon create the agent's window and start its Claude
+	if args.StartWindow && !args.Headless {
+		repo := d.state.GetAllRepos()[repoName]
+		pid, err := d.startAgentWindow(repoName, repo, agentStartConfig{
+			agentName:      agentName,
+			agentType:      agent.Type,
+			promptFile:     args.PromptFile,
+			workDir:        args.WorktreePath,
+			initialMessage: args.InitialMessage,
+		}, sessionID)
+		if err != nil {
+			d.state.RemoveAgent(repoName, agentName)
+			return struct{}{}, fmt.Errorf("failed to start agent: %v", err)
+		}
+		if err := d.state.UpdateAgentPID(repoName, agentName, pid); err != nil {
+			d.logger.Warn("Failed to record PID of %s/%s: %v", repoName, agentName, err)
+		}
 	}
 
-	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
-	if !ok {
-		return errResp
+	d.logger.Info("Added agent %s to repo %s", agentName, repoName)
+	detail := string(agent.Type)
+	if agent.Task != "" {
+		detail += ": " + agent.Task
+	}
+	d.recordAction(repoName, feed.ActionSpawned, agentName, detail)
+	return struct{}{}, nil
+}
+
+// nonEmpty returns list without its empty strings, or nil if none are left
+func nonEmpty(list []string) []string {
+	var kept []string
+	for _, s := range list {
+		if s != "" {
+			kept = append(kept, s)
+		}
 	}
+	return kept
+}
 
+// handleRemoveAgent removes an agent
+func (d *Daemon) handleRemoveAgent(req socket.Request, args socket.AgentArgs) (struct{}, error) {
+	repoName, agentName := args.Repo, args.Agent
+	agent, _ := d.state.GetAgent(repoName, agentName)
+	repo, exists := d.state.GetAllRepos()[repoName]
 	if err := d.state.RemoveAgent(repoName, agentName); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+		return struct{}{}, err
+	}
+	d.removeScratchWorktrees(repoName, agentName, agent)
+	if exists && agent.TmuxWindow != "" && d.hostsWindows() {
+		if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
+			d.logger.Warn("Failed to kill window %s:%s: %v", repo.TmuxSession, agent.TmuxWindow, err)
+		}
 	}
 
 	d.logger.Info("Removed agent %s from repo %s", agentName, repoName)
-	return socket.Response{Success: true}
+	d.recordAction(repoName, feed.ActionRemoved, agentName, "")
+	return struct{}{}, nil
 }
 
-// handleListAgents lists agents for a repository
-func (d *Daemon) handleListAgents(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
-	if !ok {
-		return errResp
+// handleListAgents lists the agents of a repository, or with all_repos of
+// every repository
+func (d *Daemon) handleListAgents(req socket.Request, args socket.ListAgentsArgs) (socket.AgentList, error) {
+	var repoNames []string
+	if args.AllRepos {
+		for name := range d.state.GetAllRepos() {
+			repoNames = append(repoNames, name)
+		}
+	} else {
+		if args.Repo == "" {
+			return socket.AgentList{}, socket.Errorf(socket.CodeMissingArgument, "missing 'repo': repository name is required")
+		}
+		repoNames = []string{args.Repo}
 	}
 
-	agents, err := d.state.ListAgents(repoName)
+	query, err := parseAgentQuery(args)
 	if err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+		return socket.AgentList{}, socket.Errorf(socket.CodeInvalidArgument, "%v", err)
 	}
 
-	// Check if rich format is requested
-	rich, _ := req.Args["rich"].(bool)
-
-	// Get repository to check session
-	repo, repoExists := d.state.GetRepo(repoName)
-
-	// Get full agent details
-	agentDetails := make([]map[string]interface{}, 0, len(agents))
-	for _, agentName := range agents {
-		agent, exists := d.state.GetAgent(repoName, agentName)
-		if !exists {
-			continue
+	allRepos := d.state.GetAllRepos()
+	var agentDetails []socket.AgentInfo
+	for _, repoName := range repoNames {
+		if _, exists := allRepos[repoName]; !exists {
+			return socket.AgentList{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
 		}
-
-		detail := map[string]interface{}{
-			"name":          agentName,
-			"type":          agent.Type,
-			"worktree_path": agent.WorktreePath,
-			"tmux_window":   agent.TmuxWindow,
-			"task":          agent.Task,
-			"created_at":    agent.CreatedAt,
+		agents, err := d.state.ListAgents(repoName)
+		if err != nil {
+			return socket.AgentList{}, err
 		}
 
-		// Add rich status information if requested
-		if rich {
-			// Determine agent status
-			status := "unknown"
-			if agent.ReadyForCleanup {
-				status = "completed"
-			} else if repoExists {
-				// Check if window exists (means agent is running)
-				hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
-				if err == nil && hasWindow {
-					status = "running"
-				} else {
-					status = "stopped"
-				}
+		// Get repository to check session
+		repo, repoExists := allRepos[repoName]
+
+		for _, agentName := range agents {
+			agent, exists := d.state.GetAgent(repoName, agentName)
+			if !exists {
+				continue
+			}
+
+			detail := socket.AgentInfo{
+				Name:         agentName,
+				Repo:         repoName,
+				Type:         agent.Type,
+				WorktreePath: agent.WorktreePath,
+				TmuxWindow:   agent.TmuxWindow,
+				Task:         agent.Task,
+				CreatedAt:    agent.CreatedAt,
+				ReadOnly:     agent.ReadOnly,
+				Labels:       agent.Labels,
+				Priority:     agent.Priority.Effective(),
+				CwdDrift:     agent.CwdDrift,
+			}
+			if !agent.Deadline.IsZero() {
+				deadline := agent.Deadline
+				detail.Deadline = &deadline
+			}
+			if agent.Question != nil {
+				detail.Question = agent.Question.Text
+			}
+			if !agent.LastHeartbeat.IsZero() {
+				heartbeat := agent.LastHeartbeat
+				detail.LastHeartbeat = &heartbeat
 			}
-			detail["status"] = status
 
-			// Get current branch from worktree
-			branch := ""
-			if agent.WorktreePath != "" {
-				if b, err := worktree.GetCurrentBranch(agent.WorktreePath); err == nil {
-					branch = b
+			// Status is part of the rich format, but also needed to filter or sort by it
+			if args.Rich || query.needsStatus() {
+				// Determine agent status
+				status := "unknown"
+				if agent.ReadyForCleanup {
+					status = "completed"
+				} else if agent.Headless {
+					// Between runs a headless agent waits for its next prompt
+					status = "idle"
+					if d.headlessBusy(repoName, agentName) {
+						status = "running"
+					}
+				} else if repoExists {
+					// Check if window exists (means agent is running)
+					hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
+					if err == nil && hasWindow {
+						status = "running"
+					} else {
+						status = "stopped"
+					}
 				}
+				detail.Status = status
 			}
-			detail["branch"] = branch
+
+			agentDetails = append(agentDetails, detail)
+		}
+	}
+
+	page, total := query.apply(agentDetails)
+
+	// Add the remaining rich information only for agents being returned
+	if args.Rich {
+		msgManager := messages.NewManager(d.paths.MessagesDir)
+		// Read every worktree's branch at once rather than one git call at a time
+		paths := make([]string, len(page))
+		for i, detail := range page {
+			paths[i] = detail.WorktreePath
+		}
+		branches := worktree.CollectBranches(paths, worktree.DefaultCollectWorkers)
+		for i := range page {
+			detail := &socket.AgentDetail{Branch: branches[i]}
+			page[i].AgentDetail = detail
 
 			// Get message counts
-			msgManager := messages.NewManager(d.paths.MessagesDir)
-			allMsgs, _ := msgManager.List(repoName, agentName)
+			allMsgs, _ := msgManager.List(page[i].Repo, page[i].Name)
 			pendingCount := 0
 			for _, msg := range allMsgs {
 				if msg.Status == messages.StatusPending || msg.Status == messages.StatusDelivered {
 					pendingCount++
 				}
 			}
-			detail["messages_total"] = len(allMsgs)
-			detail["messages_pending"] = pendingCount
-		}
+			detail.MessagesTotal = len(allMsgs)
+			detail.MessagesPending = pendingCount
 
-		agentDetails = append(agentDetails, detail)
+			if path := page[i].WorktreePath; path != "" {
+				if bytes, ok := d.worktreeDiskUsage(path); ok {
+					detail.DiskBytes = bytes
+				}
+			}
+		}
 	}
 
-	return socket.Response{Success: true, Data: agentDetails}
+	list := socket.AgentList{Agents: page, Paginated: query.paginated()}
+	if list.Paginated {
+		list.Total, list.Offset, list.Limit = total, query.Offset, query.Limit
+		if next := query.Offset + len(page); next < total {
+			list.NextOffset = next
+		}
+	}
+	return list, nil
 }
 
-// handleCompleteAgent marks an agent as ready for cleanup
-func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
-	if !ok {
-		return errResp
+// handleRespondAgent types a reply into an agent's window, answering whatever
+// prompt or question the agent is waiting on. A reply relayed from outside
+// may name only the response ID of the agent's question; the ID identifies
+// the agent.
+func (d *Daemon) handleRespondAgent(req socket.Request, args socket.RespondAgentArgs) (socket.AgentRef, error) {
+	responseID, repoName, agentName := args.ResponseID, args.Repo, args.Agent
+	// A signed reply relayed from a notification answers one question, so
+	// it must carry that question's response ID
+	if req.Origin == signedReplyOrigin && responseID == "" {
+		return socket.AgentRef{}, socket.Errorf(socket.CodeMissingArgument, "missing 'response_id': signed replies must carry the response ID of the question they answer")
+	}
+	if responseID != "" && repoName == "" && agentName == "" {
+		var err error
+		repoName, agentName, err = d.responses.Owner(responseID, d.clock.Now())
+		if err != nil {
+			return socket.AgentRef{}, socket.Errorf(socket.CodeInvalidArgument, "reply rejected: %v", err)
+		}
+	}
+	if repoName == "" {
+		return socket.AgentRef{}, socket.Errorf(socket.CodeMissingArgument, "repository name is required")
+	}
+	if agentName == "" {
+		return socket.AgentRef{}, socket.Errorf(socket.CodeMissingArgument, "agent name is required")
 	}
 
-	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
-	if !ok {
-		return errResp
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.AgentRef{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
 	}
 
 	agent, exists := d.state.GetAgent(repoName, agentName)
 	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s' - check available agents with: multiclaude work list --repo %s", agentName, repoName, repoName)}
+		return socket.AgentRef{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s' - check available agents with: multiclaude work list --repo %s", agentName, repoName, repoName)
+	}
+
+	// Replies relayed from outside (e.g. a webhook receiver) carry a one-time
+	// response ID so a captured reply can't be replayed
+	if responseID != "" {
+		if err := d.responses.Redeem(responseID, repoName, agentName, d.clock.Now()); err != nil {
+			return socket.AgentRef{}, socket.Errorf(socket.CodeInvalidArgument, "reply rejected: %v", err)
+		}
+	}
+
+	if err := d.sendToAgent(repoName, repo.TmuxSession, agentName, agent, args.Text); err != nil {
+		return socket.AgentRef{}, fmt.Errorf("failed to send reply to agent '%s': %v", agentName, err)
+	}
+
+	d.resolveQuestion(repoName, agentName)
+	d.logger.Info("Sent reply to %s/%s", repoName, agentName)
+	d.recordAction(repoName, feed.ActionAnswered, agentName, "")
+	return socket.AgentRef{Repo: repoName, Agent: agentName}, nil
+}
+
+// handleIssueResponseID issues a one-time response ID that authorizes a
+// single respond_agent call for the agent until it expires
+func (d *Daemon) handleIssueResponseID(req socket.Request, args socket.AgentArgs) (socket.ResponseID, error) {
+	if _, exists := d.state.GetAgent(args.Repo, args.Agent); !exists {
+		return socket.ResponseID{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", args.Agent, args.Repo)
+	}
+
+	id, expires := d.responses.Issue(args.Repo, args.Agent, d.clock.Now())
+	return socket.ResponseID{ID: id, ExpiresAt: expires}, nil
+}
+
+// completeCleanupGrace is how long the daemon waits after answering
+// complete_agent with cleanup before killing the agent's window, so the
+// command running in that window can print its result
+const completeCleanupGrace = 3 * time.Second
+
+// handleCompleteAgent marks an agent as ready for cleanup. With push it first
+// pushes the agent's branch to origin; with cleanup it removes the agent's
+// window and worktree right away instead of at the next health check.
+func (d *Daemon) handleCompleteAgent(req socket.Request, args socket.CompleteAgentArgs) (socket.CompletedAgent, error) {
+	repoName, agentName := args.Repo, args.Agent
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.CompletedAgent{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s' - check available agents with: multiclaude work list --repo %s", agentName, repoName, repoName)
 	}
 
 	// Mark as ready for cleanup
 	agent.ReadyForCleanup = true
 
 	// Optional: capture summary and failure reason for task history
-	if summary, ok := req.Args["summary"].(string); ok && summary != "" {
-		agent.Summary = summary
+	if args.Summary != "" {
+		agent.Summary = args.Summary
+	}
+	if args.FailureReason != "" {
+		agent.FailureReason = args.FailureReason
+	}
+
+	// Optionally squash the branch into a single commit before hand-off
+	var completed socket.CompletedAgent
+	if args.Squash && agent.Type == state.AgentTypeWorker {
+		ref, err := d.squashAgentBranch(agent, repoName, args.SquashTitle)
+		if err != nil {
+			return completed, fmt.Errorf("failed to squash branch: %v", err)
+		}
+		completed.BackupRef = ref
+		d.logger.Info("Squashed branch for %s/%s (originals at %s)", repoName, agentName, ref)
+	}
+
+	// Block hand-off to the merge queue if the branch breaks the repo's guard
+	if agent.Type == state.AgentTypeWorker && agent.FailureReason == "" {
+		problem, err := d.enforceCommitPolicy(repoName, agentName, agent)
+		if err != nil {
+			d.logger.Warn("Commit policy check skipped for %s/%s: %v", repoName, agentName, err)
+		} else if problem != "" {
+			d.logger.Info("Commit policy blocked completion of %s/%s", repoName, agentName)
+			if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, problem); err != nil {
+				d.logger.Error("Failed to send commit policy message to %s: %v", agentName, err)
+			}
+			return completed, errors.New(problem)
+		}
+
+		report, err := d.checkBranchGuard(repoName, agent)
+		if err != nil {
+			d.logger.Warn("Branch guard check skipped for %s/%s: %v", repoName, agentName, err)
+		} else if report != nil && !report.Passed() {
+			d.logger.Info("Branch guard blocked completion of %s/%s (%d violations)", repoName, agentName, len(report.Violations))
+			return completed, errors.New(report.String() + "\n\nRemove or revert these changes, then run 'multiclaude agent complete' again.")
+		}
+
+		generated, err := d.findCommittedGeneratedFiles(repoName, agent)
+		if err != nil {
+			d.logger.Warn("Generated file check skipped for %s/%s: %v", repoName, agentName, err)
+		} else if len(generated) > 0 {
+			d.logger.Info("Generated files blocked completion of %s/%s: %s", repoName, agentName, strings.Join(generated, ", "))
+			return completed, fmt.Errorf("Branch commits files generated by multiclaude:\n  %s\n\nUntrack them with 'git rm --cached %s', commit, then run 'multiclaude agent complete' again.",
+				strings.Join(generated, "\n  "), strings.Join(generated, " "))
+		}
 	}
-	if failureReason, ok := req.Args["failure_reason"].(string); ok && failureReason != "" {
-		agent.FailureReason = failureReason
+
+	var branch string
+	if agent.WorktreePath != "" {
+		branch, _ = worktree.GetCurrentBranch(agent.WorktreePath)
+	}
+	// Opening a PR needs the branch on the remote
+	openPR := args.PR && agent.Type == state.AgentTypeWorker && agent.FailureReason == ""
+	if (args.Push || openPR) && agent.WorktreePath != "" {
+		if branch == "" || branch == "HEAD" {
+			return completed, fmt.Errorf("cannot push: worktree is not on a branch")
+		}
+		remote := d.pushRemote(repoName)
+		if err := worktree.NewManager(d.paths.RepoDir(repoName)).PushBranch(remote, branch); err != nil {
+			return completed, fmt.Errorf("failed to push branch %s: %v", branch, err)
+		}
+		completed.Pushed = true
+		d.logger.Info("Pushed branch %s to %s for %s/%s", branch, remote, repoName, agentName)
+	}
+
+	var pr *github.PullRequest
+	if openPR {
+		if !completed.Pushed {
+			return completed, fmt.Errorf("cannot open a PR: agent has no worktree to push")
+		}
+		var created bool
+		var err error
+		pr, created, err = d.openAgentPR(repoName, agentName, agent, branch)
+		if err != nil {
+			return completed, fmt.Errorf("branch %s was pushed but opening a PR failed: %v", branch, err)
+		}
+		agent.PRURL = pr.URL
+		agent.PRNumber = pr.Number
+		if created {
+			d.logger.Info("Opened PR %s for %s/%s", pr.URL, repoName, agentName)
+			d.recordAction(repoName, feed.ActionPROpened, agentName, pr.URL)
+		}
 	}
 
 	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+		return completed, err
 	}
 
 	d.logger.Info("Agent %s/%s marked as ready for cleanup", repoName, agentName)
+	switch {
+	case agent.FailureReason != "":
+		d.recordAction(repoName, feed.ActionCompleted, agentName, "failed: "+agent.FailureReason)
+	case agent.Summary != "":
+		d.recordAction(repoName, feed.ActionCompleted, agentName, agent.Summary)
+	default:
+		d.recordAction(repoName, feed.ActionCompleted, agentName, agent.Task)
+	}
+
+	title := fmt.Sprintf("Agent %s completed", agentName)
+	if agent.FailureReason != "" {
+		title = fmt.Sprintf("Agent %s failed: %s", agentName, agent.FailureReason)
+	}
+	d.emitEvent(events.NewTypedEvent(repoName, agentName, title, events.AgentCompletedPayload{
+		Task:          agent.Task,
+		Summary:       agent.Summary,
+		FailureReason: agent.FailureReason,
+		Branch:        branch,
+		PRURL:         agent.PRURL,
+	}))
+	if pr != nil {
+		d.emitEvent(events.NewTypedEvent(repoName, agentName, fmt.Sprintf("Agent %s opened PR #%d", agentName, pr.Number), events.PRCreatedPayload{
+			PRURL:    pr.URL,
+			PRNumber: pr.Number,
+			Title:    pr.Title,
+			Branch:   branch,
+			Base:     pr.Base.Ref,
+			Task:     agent.Task,
+		}))
+	}
+
+	// A queued task is finished with its worker, freeing its slot
+	if agent.Type == state.AgentTypeWorker {
+		d.finishQueuedTask(repoName, agentName, agent.FailureReason)
+		go d.dispatchTaskQueue(repoName)
+	}
+
+	// Start the merge queue clock for the finished branch
+	if agent.Type == state.AgentTypeWorker && agent.FailureReason == "" {
+		d.enqueueWorkerBranch(repoName, agentName, agent)
+	}
 
 	// Notify supervisor and merge-queue that worker or review agent completed
 	if agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview {
@@ -1015,272 +2116,876 @@ func (d *Daemon) handleCompleteAgent(req socket.Request) socket.Response {
 		go d.routeMessages()
 	}
 
-	// Trigger immediate cleanup check
-	go d.checkAgentHealth()
+	// Without cleanup the next health check removes the agent, which leaves
+	// it time to wrap up in its window
+	if args.Cleanup {
+		go d.cleanupCompletedAgent(repoName, agentName)
+	}
 
-	return socket.Response{Success: true}
+	completed.Cleanup = args.Cleanup
+	if pr != nil {
+		completed.PRURL = pr.URL
+		completed.PRNumber = pr.Number
+	}
+	return completed, nil
 }
 
-// handleRestartAgent restarts an agent that has crashed or exited
-func (d *Daemon) handleRestartAgent(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
-	if !ok {
-		return errResp
+// cleanupCompletedAgent removes a completed agent's window, worktree and
+// state after completeCleanupGrace
+func (d *Daemon) cleanupCompletedAgent(repoName, agentName string) {
+	select {
+	case <-d.clock.After(completeCleanupGrace):
+	case <-d.ctx.Done():
+		return
 	}
+	// The health check may have cleaned the agent up in the meantime
+	if agent, exists := d.state.GetAgent(repoName, agentName); !exists || !agent.ReadyForCleanup {
+		return
+	}
+	d.cleanupDeadAgents(map[string][]string{repoName: {agentName}}, "completed_cleanup")
+}
 
-	agentName, errResp, ok := getRequiredStringArg(req.Args, "agent", "agent name is required")
-	if !ok {
-		return errResp
+// squashAgentBranch squashes an agent's branch into one commit whose message is
+// synthesized from the task and diff, pushing it if the branch was already pushed.
+// It returns the ref holding the original commits.
+func (d *Daemon) squashAgentBranch(agent state.Agent, repoName, title string) (string, error) {
+	base, err := d.agentBaseRef(repoName, agent)
+	if err != nil {
+		return "", err
+	}
+	commits, err := worktree.ListBranchCommits(agent.WorktreePath, base)
+	if err != nil {
+		return "", err
+	}
+	diff, err := worktree.SummarizeDiff(agent.WorktreePath, base)
+	if err != nil {
+		return "", err
+	}
+
+	message := worktree.SynthesizeSquashMessage(title, agent.Task, commits, diff)
+	backupRef, err := worktree.SquashBranch(agent.WorktreePath, base, message)
+	if err != nil {
+		return "", err
+	}
+
+	if worktree.HasUpstream(agent.WorktreePath) {
+		if err := worktree.ForcePushWithLease(agent.WorktreePath); err != nil {
+			return "", fmt.Errorf("squashed locally (originals at %s) but %w", backupRef, err)
+		}
+	}
+	return backupRef, nil
+}
+
+// checkBranchGuard checks an agent's branch against the repo's branch guard.
+// It returns a nil report if no guard is configured.
+func (d *Daemon) checkBranchGuard(repoName string, agent state.Agent) (*worktree.GuardReport, error) {
+	guard, err := d.state.GetBranchGuardConfig(repoName)
+	if err != nil {
+		return nil, err
+	}
+	if len(agent.AllowedPaths) > 0 {
+		guard.AllowedPaths = agent.AllowedPaths
+	}
+	if !guard.Enabled() {
+		return nil, nil
+	}
+
+	base, err := d.agentBaseRef(repoName, agent)
+	if err != nil {
+		return nil, err
+	}
+
+	return worktree.CheckBranchGuard(agent.WorktreePath, base, worktree.GuardPolicy{
+		AllowedPaths:  guard.AllowedPaths,
+		MaxFileBytes:  int64(guard.MaxFileMB) * 1024 * 1024,
+		BlockBinaries: guard.BlockBinaries,
+	})
+}
+
+// findCommittedGeneratedFiles returns the orchestrator-generated files an
+// agent's branch adds. It also refreshes the clone's exclude entries so the
+// files stay out of the agent's next commit.
+func (d *Daemon) findCommittedGeneratedFiles(repoName string, agent state.Agent) ([]string, error) {
+	if err := worktree.NewManager(d.paths.RepoDir(repoName)).EnsureExcludes(); err != nil {
+		d.logger.Warn("Failed to update exclude entries for %s: %v", repoName, err)
+	}
+
+	base, err := d.agentBaseRef(repoName, agent)
+	if err != nil {
+		return nil, err
+	}
+	return worktree.FindCommittedGeneratedFiles(agent.WorktreePath, base)
+}
+
+// agentBaseRef returns the revision an agent's branch is measured against:
+// its base's remote-tracking branch, its fixed base commit, or (without a
+// base) the repo's default branch
+func (d *Daemon) agentBaseRef(repoName string, agent state.Agent) (string, error) {
+	switch {
+	case agent.BaseBranch != "":
+		remote, err := worktree.NewManager(d.paths.RepoDir(repoName)).GetUpstreamRemote()
+		if err != nil {
+			return "", err
+		}
+		return remote + "/" + agent.BaseBranch, nil
+	case agent.Base != "":
+		return agent.Base, nil
+	default:
+		return d.upstreamBaseRef(repoName)
+	}
+}
+
+// upstreamBaseRef returns the remote-tracking ref of the repo's default branch (e.g. "origin/main")
+func (d *Daemon) upstreamBaseRef(repoName string) (string, error) {
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	remote, err := wt.GetUpstreamRemote()
+	if err != nil {
+		return "", err
+	}
+	mainBranch, err := wt.GetDefaultBranch(remote)
+	if err != nil {
+		return "", err
 	}
+	return remote + "/" + mainBranch, nil
+}
 
-	force, _ := req.Args["force"].(bool)
+// commitPolicyPattern compiles the subject pattern for a commit policy.
+// It returns nil if the policy is disabled.
+func commitPolicyPattern(policy state.CommitPolicyConfig) (*regexp.Regexp, error) {
+	switch policy.Style {
+	case state.CommitStyleNone:
+		return nil, nil
+	case state.CommitStyleConventional:
+		return regexp.MustCompile(worktree.ConventionalCommitPattern), nil
+	case state.CommitStyleRegex:
+		if policy.Pattern == "" {
+			return nil, fmt.Errorf("commit style 'regex' requires a pattern")
+		}
+		return regexp.Compile(policy.Pattern)
+	default:
+		return nil, fmt.Errorf("invalid commit style: %s", policy.Style)
+	}
+}
+
+// enforceCommitPolicy lints the subjects of an agent's commits against the
+// repo's commit policy. Conventional subjects are reworded automatically when
+// the type can be inferred (and force-pushed if the branch was already pushed).
+// It returns a non-empty explanation if the agent must fix its commits itself.
+func (d *Daemon) enforceCommitPolicy(repoName, agentName string, agent state.Agent) (string, error) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return "", socket.Errorf(socket.CodeNotFound, "repository %q not found", repoName)
+	}
+	pattern, err := commitPolicyPattern(repo.CommitPolicy)
+	if err != nil || pattern == nil {
+		return "", err
+	}
+
+	base, err := d.agentBaseRef(repoName, agent)
+	if err != nil {
+		return "", err
+	}
+	commits, err := worktree.ListBranchCommits(agent.WorktreePath, base)
+	if err != nil {
+		return "", err
+	}
+	bad := worktree.LintCommitSubjects(commits, pattern)
+	if len(bad) == 0 {
+		return "", nil
+	}
+
+	// Try to fix every offending subject automatically
+	fixes := make(map[string]string)
+	if repo.CommitPolicy.Style == state.CommitStyleConventional {
+		for _, c := range bad {
+			if c.Merge {
+				break
+			}
+			if subject, ok := worktree.SuggestConventionalSubject(c.Subject); ok {
+				fixes[c.SHA] = subject
+			}
+		}
+	}
+	if len(fixes) == len(bad) {
+		if err := worktree.RewordCommits(agent.WorktreePath, base, fixes); err != nil {
+			d.logger.Warn("Failed to reword commits for %s/%s: %v", repoName, agentName, err)
+		} else {
+			d.logger.Info("Reworded %d commit(s) for %s/%s to match commit policy", len(fixes), repoName, agentName)
+			if !worktree.HasUpstream(agent.WorktreePath) {
+				return "", nil
+			}
+			if err := worktree.ForcePushWithLease(agent.WorktreePath); err == nil {
+				return "", nil
+			}
+			return "Your commits were reworded to match the commit policy, but pushing them failed.\n" +
+				"Run 'git push --force-with-lease' and then 'multiclaude agent complete' again.", nil
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d commit(s) do not match the commit message policy for %s:\n", len(bad), repoName)
+	for _, c := range bad {
+		fmt.Fprintf(&b, "  %s %s\n", c.SHA[:12], c.Subject)
+	}
+	if repo.CommitPolicy.Style == state.CommitStyleConventional {
+		b.WriteString("\nExpected format: <type>[(scope)]: <description>\n")
+		b.WriteString("  where <type> is one of feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert\n")
+		b.WriteString("  e.g. \"fix(cli): handle empty task description\"\n")
+	} else {
+		fmt.Fprintf(&b, "\nExpected subjects to match: %s\n", repo.CommitPolicy.Pattern)
+	}
+	b.WriteString("\nReword them (e.g. git rebase -i " + base + "), push, then run 'multiclaude agent complete' again.")
+	return b.String(), nil
+}
+
+// handleCheckBranchGuard runs the branch guard for an agent without completing it
+func (d *Daemon) handleCheckBranchGuard(req socket.Request, args socket.AgentArgs) (socket.BranchGuardCheck, error) {
+	repoName := args.Repo
+
+	agentName := args.Agent
 
 	agent, exists := d.state.GetAgent(repoName, agentName)
 	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in repository '%s' - check available agents with: multiclaude work list --repo %s", agentName, repoName, repoName)}
+		return socket.BranchGuardCheck{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+
+	report, err := d.checkBranchGuard(repoName, agent)
+	if err != nil {
+		return socket.BranchGuardCheck{}, fmt.Errorf("failed to check branch: %v", err)
+	}
+	if report == nil {
+		return socket.BranchGuardCheck{Passed: true, Report: "No branch guard configured for this repository"}, nil
+	}
+
+	return socket.BranchGuardCheck{
+		Enabled:    true,
+		Passed:     report.Passed(),
+		Violations: len(report.Violations),
+		Report:     report.String(),
+	}, nil
+}
+
+// handleRestartAgent restarts an agent that has crashed or exited
+func (d *Daemon) handleRestartAgent(req socket.Request, args socket.RestartAgentArgs) (socket.RestartedAgent, error) {
+	repoName := args.Repo
+
+	agentName := args.Agent
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.RestartedAgent{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s' - check available agents with: multiclaude work list --repo %s", agentName, repoName, repoName)
 	}
 
 	// Check if agent is marked for cleanup (completed)
 	if agent.ReadyForCleanup {
-		return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is marked as complete and pending cleanup - cannot restart a completed agent", agentName)}
+		return socket.RestartedAgent{}, fmt.Errorf("agent '%s' is marked as complete and pending cleanup - cannot restart a completed agent", agentName)
 	}
 
 	// Check if tmux window exists
-	repo, exists := d.state.GetRepo(repoName)
+	repo, exists := d.state.GetAllRepos()[repoName]
 	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("repository '%s' not found in state", repoName)}
+		return socket.RestartedAgent{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found in state", repoName)
 	}
 
-	hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agentName)
-	if err != nil {
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to check tmux window: %v", err)}
-	}
-	if !hasWindow {
-		return socket.Response{Success: false, Error: fmt.Sprintf("tmux window '%s' does not exist - the agent may need to be recreated", agentName)}
+	if !agent.Headless {
+		hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agentName)
+		if err != nil {
+			return socket.RestartedAgent{}, fmt.Errorf("failed to check tmux window: %v", err)
+		}
+		if !hasWindow {
+			return socket.RestartedAgent{}, fmt.Errorf("tmux window '%s' does not exist - the agent may need to be recreated", agentName)
+		}
 	}
 
 	// Check if agent is already running
 	if agent.PID > 0 && isProcessAlive(agent.PID) {
-		if !force {
-			return socket.Response{Success: false, Error: fmt.Sprintf("agent '%s' is already running with PID %d - use --force to restart anyway", agentName, agent.PID)}
+		if !args.Force {
+			return socket.RestartedAgent{}, fmt.Errorf("agent '%s' is already running with PID %d - use --force to restart anyway", agentName, agent.PID)
 		}
 		d.logger.Info("Force restarting agent %s (PID %d was still running)", agentName, agent.PID)
 	}
 
 	// Restart the agent
 	if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to restart agent: %v", err)}
+		return socket.RestartedAgent{}, fmt.Errorf("failed to restart agent: %v", err)
 	}
 
-	// Get updated PID from state
+	// A manual restart gives the agent a fresh set of automatic restarts
 	updatedAgent, _ := d.state.GetAgent(repoName, agentName)
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"agent":   agentName,
-			"repo":    repoName,
-			"pid":     updatedAgent.PID,
-			"message": fmt.Sprintf("Agent '%s' restarted successfully", agentName),
-		},
+	if updatedAgent.Restarts > 0 {
+		updatedAgent.Restarts = 0
+		if err := d.state.UpdateAgent(repoName, agentName, updatedAgent); err != nil {
+			d.logger.Warn("Failed to reset restart count of %s/%s: %v", repoName, agentName, err)
+		}
 	}
+	d.restartGaveUpMu.Lock()
+	delete(d.restartGaveUp, repoName+"/"+agentName)
+	d.restartGaveUpMu.Unlock()
+	return socket.RestartedAgent{
+		Agent:   agentName,
+		Repo:    repoName,
+		PID:     updatedAgent.PID,
+		Message: fmt.Sprintf("Agent '%s' restarted successfully", agentName),
+	}, nil
 }
 
 // handleTriggerCleanup manually triggers cleanup operations
-func (d *Daemon) handleTriggerCleanup(req socket.Request) socket.Response {
-	d.logger.Info("Manual cleanup triggered")
+func (d *Daemon) handleTriggerCleanup(req socket.Request, args socket.TriggerCleanupArgs) ([]cleanup.Item, error) {
+	dryRun, repoName := args.DryRun, args.Repo
+	d.logger.Info("Manual cleanup triggered (dry_run=%v, repo=%q)", dryRun, repoName)
 
-	// Run health check to find dead agents
-	d.checkAgentHealth()
+	// Remove dead agents first so their leftovers count as orphaned
+	if !dryRun {
+		d.checkAgentHealth()
+	}
+
+	cleaner := cleanup.New(d.paths, d.state, d.tmux)
+	items, err := cleaner.Run(d.ctx, cleanup.Options{
+		Repo:   repoName,
+		DryRun: dryRun,
+		Skip: func(repo string) bool {
+			return d.isReadOnlyRepo(repo, "cleanup")
+		},
+		Namer: d.configFile().Namer(),
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return socket.Response{
-		Success: true,
-		Data:    "Cleanup triggered",
+	for _, item := range items {
+		switch {
+		case item.Error != "":
+			d.logger.Warn("Cleanup failed to remove %s %s: %s", item.Kind, item.Target, item.Error)
+		case item.Removed:
+			d.logger.Info("Cleanup removed %s %s", item.Kind, item.Target)
+		}
 	}
+
+	return items, nil
 }
 
 // handleRepairState repairs state inconsistencies
-func (d *Daemon) handleRepairState(req socket.Request) socket.Response {
-	d.logger.Info("State repair triggered")
+func (d *Daemon) handleRepairState(req socket.Request, args socket.RepairStateArgs) (socket.StateRepair, error) {
+	d.logger.Info("State repair triggered (dry_run=%v)", args.DryRun)
+
+	repairer := repair.New(d.paths, d.state, d.tmux, func(repoName, agentName string, agent state.Agent) error {
+		repo, exists := d.state.GetAllRepos()[repoName]
+		if !exists {
+			return socket.Errorf(socket.CodeNotFound, "repository %q not found", repoName)
+		}
+		return d.restartAgent(repoName, agentName, agent, repo)
+	})
+	issues, err := repairer.Plan(d.ctx, func(repo string) bool {
+		return d.isReadOnlyRepo(repo, "repair")
+	})
+	if err != nil {
+		return socket.StateRepair{}, err
+	}
+	if args.DryRun {
+		return socket.StateRepair{Issues: issues}, nil
+	}
 
+	issues = repairer.Apply(d.ctx, issues)
 	agentsRemoved := 0
 	issuesFixed := 0
+	for _, issue := range issues {
+		switch {
+		case issue.Error != "":
+			d.logger.Warn("Failed to %s %s/%s (%s): %s", issue.Action, issue.Repo, issue.Agent, issue.Problem, issue.Error)
+		case issue.Action == repair.ActionDrop:
+			d.logger.Info("Dropped %s/%s from state (%s)", issue.Repo, issue.Agent, issue.Problem)
+			agentsRemoved++
+			issuesFixed++
+		default:
+			d.logger.Info("Recreated %s/%s (%s)", issue.Repo, issue.Agent, issue.Problem)
+			issuesFixed++
+		}
+	}
 
-	// Get a snapshot of repos to avoid concurrent map access
-	repos := d.state.GetAllRepos()
+	d.logger.Info("State repair completed: %d agents removed, %d issues fixed", agentsRemoved, issuesFixed)
 
-	// Check all agents and verify resources exist
-	for repoName, repo := range repos {
-		// Check tmux session
-		hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
-		if err != nil {
-			d.logger.Error("Failed to check session %s: %v", repo.TmuxSession, err)
-			continue
+	return socket.StateRepair{Issues: issues, AgentsRemoved: &agentsRemoved, IssuesFixed: &issuesFixed}, nil
+}
+
+// handleGetRepoConfig returns the configuration for a repository
+func (d *Daemon) handleGetRepoConfig(req socket.Request, args socket.RepoNameArgs) (socket.RepoConfig, error) {
+	name := args.Name
+
+	repo, exists := d.state.GetAllRepos()[name]
+	if !exists {
+		return socket.RepoConfig{}, socket.Errorf(socket.CodeNotFound, "repository %q not found", name)
+	}
+
+	// Get merge queue config (use default if not set for backward compatibility)
+	mqConfig := repo.MergeQueueConfig
+	if mqConfig.TrackMode == "" {
+		mqConfig = state.DefaultMergeQueueConfig()
+	}
+
+	return socket.RepoConfig{
+		MQEnabled:   mqConfig.Enabled,
+		MQTrackMode: string(mqConfig.TrackMode),
+		Groups:      repo.Groups,
+		DefaultBase: repo.DefaultBase,
+
+		MQAutoMerge:      mqConfig.AutoMerge,
+		MQLabel:          mqConfig.MergeLabel(),
+		MQPollSeconds:    int(mqConfig.PollInterval().Seconds()),
+		MQRequiredChecks: mqConfig.RequiredChecks,
+		MQMergeMethod:    mqConfig.Method(),
+
+		GuardAllowedPaths:  repo.BranchGuard.AllowedPaths,
+		GuardMaxFileMB:     repo.BranchGuard.MaxFileMB,
+		GuardBlockBinaries: repo.BranchGuard.BlockBinaries,
+
+		CommitStyle:   string(repo.CommitPolicy.Style),
+		CommitPattern: repo.CommitPolicy.Pattern,
+
+		AutoAnswerEnabled: !repo.AutoAnswer.Disabled,
+		AutoAnswerRules:   len(repo.AutoAnswer.Rules),
+
+		WarmPoolSize:      repo.WarmPool.Size,
+		WarmPoolBootstrap: repo.WarmPool.Bootstrap,
+		WarmPoolReady:     len(repo.WarmWorktrees),
+
+		ReaperMode:         string(repo.WindowReaper.EffectiveMode()),
+		ReaperGraceMinutes: int(repo.WindowReaper.Grace().Minutes()),
+		ReaperKeep:         repo.WindowReaper.Keep,
+
+		RecoveryAuto:         repo.Recovery.Auto,
+		RecoveryAfterMinutes: int(repo.Recovery.After().Minutes()),
+		StuckIdleMinutes:     repo.Stuck.IdleMinutes,
+		StuckRules:           stuckRuleStrings(repo.Stuck.Rules),
+		StuckQuiet:           quietRuleStrings(repo.Stuck.Quiet),
+		StuckNudge:           repo.Stuck.Nudge,
+		RestartMax:           restartMax(repo.Restart),
+		QueueWorkers:         repo.TaskQueue.Limit(),
+
+		TmuxAlerts:     repo.TmuxAlerts,
+		ConflictAssist: repo.ConflictAssist,
+		PushRemote:     repo.WorkerPushRemote(),
+		MaxWorkers:     repo.MaxWorkers,
+		LFSSkip:        repo.LFSSkip,
+
+		AccessSpawn:  repo.Access.Spawn,
+		AccessRemove: repo.Access.Remove,
+		AccessMerge:  repo.Access.Merge,
+		AccessAdmin:  repo.Access.Admin,
+	}, nil
+}
+
+// handleUpdateRepoConfig updates the configuration for a repository
+func (d *Daemon) handleUpdateRepoConfig(req socket.Request, args socket.UpdateRepoConfigArgs) (struct{}, error) {
+	name := args.Name
+
+	// Get current merge queue config
+	currentMQConfig, err := d.state.GetMergeQueueConfig(name)
+	if err != nil {
+		return struct{}{}, err
+	}
+
+	// Update merge queue config with provided values
+	mqUpdated := false
+	if args.MQEnabled != nil {
+		currentMQConfig.Enabled = *args.MQEnabled
+		mqUpdated = true
+	}
+	if args.MQTrackMode != nil {
+		switch mqTrackMode := *args.MQTrackMode; mqTrackMode {
+		case "all":
+			currentMQConfig.TrackMode = state.TrackModeAll
+		case "author":
+			currentMQConfig.TrackMode = state.TrackModeAuthor
+		case "assigned":
+			currentMQConfig.TrackMode = state.TrackModeAssigned
+		default:
+			return struct{}{}, fmt.Errorf("invalid track mode: %s", mqTrackMode)
+		}
+		mqUpdated = true
+	}
+	if args.MQAutoMerge != nil {
+		currentMQConfig.AutoMerge = *args.MQAutoMerge
+		mqUpdated = true
+	}
+	if args.MQLabel != nil {
+		currentMQConfig.Label = *args.MQLabel
+		mqUpdated = true
+	}
+	if args.MQPollSeconds != nil {
+		if *args.MQPollSeconds < 0 {
+			return struct{}{}, fmt.Errorf("mq_poll_seconds must not be negative")
 		}
+		currentMQConfig.PollSeconds = *args.MQPollSeconds
+		mqUpdated = true
+	}
+	if args.MQRequiredChecks != nil {
+		currentMQConfig.RequiredChecks = nonEmpty(args.MQRequiredChecks)
+		mqUpdated = true
+	}
+	if args.MQMergeMethod != nil {
+		method := *args.MQMergeMethod
+		if method != "" && !state.ValidMergeMethod(method) {
+			return struct{}{}, fmt.Errorf("invalid merge method: %s", method)
+		}
+		currentMQConfig.MergeMethod = method
+		mqUpdated = true
+	}
 
-		if !hasSession {
-			d.logger.Warn("Tmux session %s not found, removing all agents for repo %s", repo.TmuxSession, repoName)
-			// Remove all agents for this repo
-			for agentName := range repo.Agents {
-				if err := d.state.RemoveAgent(repoName, agentName); err == nil {
-					agentsRemoved++
-				}
-			}
-			issuesFixed++
-			continue
+	if mqUpdated {
+		if err := d.state.UpdateMergeQueueConfig(name, currentMQConfig); err != nil {
+			return struct{}{}, err
 		}
+		d.logger.Info("Updated merge queue config for repo %s: enabled=%v, track=%s, auto_merge=%v", name, currentMQConfig.Enabled, currentMQConfig.TrackMode, currentMQConfig.AutoMerge)
+	}
 
-		// Check each agent's resources
-		for agentName, agent := range repo.Agents {
-			hasWindow, _ := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
-			if !hasWindow {
-				d.logger.Info("Removing agent %s (window not found)", agentName)
-				if err := d.state.RemoveAgent(repoName, agentName); err == nil {
-					agentsRemoved++
-					issuesFixed++
-				}
-				continue
-			}
+	guard, err := d.state.GetBranchGuardConfig(name)
+	if err != nil {
+		return struct{}{}, err
+	}
+	guardUpdated := false
+	if args.GuardAllowedPaths != nil {
+		guard.AllowedPaths = nonEmpty(args.GuardAllowedPaths)
+		guardUpdated = true
+	}
+	if args.GuardMaxFileMB != nil {
+		if *args.GuardMaxFileMB < 0 {
+			return struct{}{}, fmt.Errorf("guard_max_file_mb must not be negative")
+		}
+		guard.MaxFileMB = *args.GuardMaxFileMB
+		guardUpdated = true
+	}
+	if args.GuardBlockBinaries != nil {
+		guard.BlockBinaries = *args.GuardBlockBinaries
+		guardUpdated = true
+	}
+	if guardUpdated {
+		if err := d.state.UpdateBranchGuardConfig(name, guard); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated branch guard for repo %s: paths=%v, max_file_mb=%d, block_binaries=%v", name, guard.AllowedPaths, guard.MaxFileMB, guard.BlockBinaries)
+	}
 
-			// Check if worktree exists (for workers and review agents)
-			if (agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview) && agent.WorktreePath != "" {
-				if _, err := os.Stat(agent.WorktreePath); os.IsNotExist(err) {
-					d.logger.Warn("Worktree missing for agent %s, but window exists - keeping agent", agentName)
-					// Don't remove - user might have manually deleted worktree
-				}
+	if args.CommitStyle != nil || args.CommitPattern != nil {
+		policy := d.state.GetAllRepos()[name].CommitPolicy
+		if args.CommitStyle != nil {
+			style := *args.CommitStyle
+			if style == "none" {
+				style = ""
 			}
+			policy.Style = state.CommitStyle(style)
+		}
+		if args.CommitPattern != nil {
+			policy.Pattern = *args.CommitPattern
+		}
+		if _, err := commitPolicyPattern(policy); err != nil {
+			return struct{}{}, fmt.Errorf("invalid commit policy: %v", err)
+		}
+		if err := d.state.UpdateCommitPolicy(name, policy); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated commit policy for repo %s: style=%q pattern=%q", name, policy.Style, policy.Pattern)
+	}
+
+	if args.AutoAnswerEnabled != nil {
+		enabled := *args.AutoAnswerEnabled
+		config, err := d.state.GetAutoAnswerConfig(name)
+		if err != nil {
+			return struct{}{}, err
+		}
+		config.Disabled = !enabled
+		if err := d.state.UpdateAutoAnswerConfig(name, config); err != nil {
+			return struct{}{}, err
 		}
+		d.logger.Info("Updated auto-answer for repo %s: enabled=%v", name, enabled)
 	}
 
-	// Clean up orphaned worktrees
-	d.cleanupOrphanedWorktrees()
+	if args.RestartMax != nil {
+		max := *args.RestartMax
+		if max < 0 || max != float64(int(max)) {
+			return struct{}{}, fmt.Errorf("invalid restart_max %v: must be a whole number, 0 to disable", max)
+		}
+		policy := state.RestartPolicy{Off: max == 0, MaxRestarts: int(max)}
+		if err := d.state.UpdateRestartPolicy(name, policy); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated automatic restarts for repo %s: max=%d", name, int(max))
+	}
 
-	// Clean up orphaned message directories
-	msgMgr := d.getMessageManager()
-	repoNames := d.state.ListRepos()
-	for _, repoName := range repoNames {
-		validAgents, _ := d.state.ListAgents(repoName)
-		if count, err := msgMgr.CleanupOrphaned(repoName, validAgents); err == nil && count > 0 {
-			issuesFixed += count
+	if args.MaxWorkers != nil {
+		max := *args.MaxWorkers
+		if max < 0 || max != float64(int(max)) {
+			return struct{}{}, fmt.Errorf("invalid max_workers %v: must be a whole number, 0 for no limit", max)
 		}
+		if err := d.state.UpdateMaxWorkers(name, int(max)); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated worker limit for repo %s: max=%d", name, int(max))
+		go d.dispatchTaskQueue(name)
 	}
 
-	d.logger.Info("State repair completed: %d agents removed, %d issues fixed", agentsRemoved, issuesFixed)
+	if args.QueueWorkers != nil {
+		workers := *args.QueueWorkers
+		if workers < 1 || workers != float64(int(workers)) {
+			return struct{}{}, fmt.Errorf("invalid queue_workers %v: must be a whole number of at least 1", workers)
+		}
+		if err := d.state.UpdateTaskQueueConfig(name, state.TaskQueueConfig{Workers: int(workers)}); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated task queue for repo %s: workers=%d", name, int(workers))
+		go d.dispatchTaskQueue(name)
+	}
 
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"agents_removed": agentsRemoved,
-			"issues_fixed":   issuesFixed,
-		},
+	if args.TmuxAlerts != nil {
+		if err := d.state.UpdateTmuxAlerts(name, *args.TmuxAlerts); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated tmux alerts for repo %s: enabled=%v", name, *args.TmuxAlerts)
 	}
-}
 
-// handleGetRepoConfig returns the configuration for a repository
-func (d *Daemon) handleGetRepoConfig(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
-	if !ok {
-		return errResp
+	if args.PushRemote != nil {
+		remote := *args.PushRemote
+		wt := worktree.NewManager(d.paths.RepoDir(name))
+		if remote != "" {
+			if _, err := wt.RemoteURL(remote); err != nil {
+				return struct{}{}, fmt.Errorf("invalid push_remote: %v; add it first with: git -C %s remote add %s <url>", err, d.paths.RepoDir(name), remote)
+			}
+		}
+		if remote == state.DefaultPushRemote {
+			remote = ""
+		}
+		if err := d.state.UpdatePushRemote(name, remote); err != nil {
+			return struct{}{}, err
+		}
+		// Plain `git push` in workers' worktrees goes to the push remote too
+		if err := wt.SetPushDefault(remote); err != nil {
+			d.logger.Warn("Failed to set the default push remote of %s: %v", name, err)
+		}
+		d.logger.Info("Updated push remote for repo %s: %s", name, d.pushRemote(name))
 	}
 
-	repo, exists := d.state.GetRepo(name)
-	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", name)}
+	if args.ConflictAssist != nil {
+		if err := d.state.UpdateConflictAssist(name, *args.ConflictAssist); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated conflict assist for repo %s: enabled=%v", name, *args.ConflictAssist)
+	}
+
+	if args.LFSSkip != nil {
+		var skip []state.AgentType
+		for _, name := range args.LFSSkip {
+			switch t := state.AgentType(name); t {
+			case state.AgentTypeSupervisor, state.AgentTypeWorker, state.AgentTypeMergeQueue,
+				state.AgentTypeWorkspace, state.AgentTypeReview, state.AgentTypeGenericPersistent:
+				skip = append(skip, t)
+			default:
+				return struct{}{}, fmt.Errorf("invalid lfs_skip agent type %q", name)
+			}
+		}
+		if err := d.state.UpdateLFSSkip(name, skip); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated Git LFS content skip for repo %s: %v", name, skip)
 	}
 
-	// Get merge queue config (use default if not set for backward compatibility)
-	mqConfig := repo.MergeQueueConfig
-	if mqConfig.TrackMode == "" {
-		mqConfig = state.DefaultMergeQueueConfig()
+	if args.WarmPoolSize != nil || args.WarmPoolBootstrap != nil {
+		repo, exists := d.state.GetAllRepos()[name]
+		if !exists {
+			return struct{}{}, socket.Errorf(socket.CodeNotFound, "repository %q not found", name)
+		}
+		pool := repo.WarmPool
+		if args.WarmPoolSize != nil {
+			if *args.WarmPoolSize < 0 {
+				return struct{}{}, fmt.Errorf("warm_pool_size must not be negative")
+			}
+			pool.Size = *args.WarmPoolSize
+		}
+		if args.WarmPoolBootstrap != nil {
+			pool.Bootstrap = *args.WarmPoolBootstrap
+		}
+		if err := d.state.UpdateWarmPoolConfig(name, pool); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated warm pool for repo %s: size=%d bootstrap=%q", name, pool.Size, pool.Bootstrap)
+		// A new bootstrap command only applies to worktrees created from now on
+		d.refillWarmPool(name)
 	}
 
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"mq_enabled":    mqConfig.Enabled,
-			"mq_track_mode": string(mqConfig.TrackMode),
-		},
+	if args.ReaperMode != nil || args.ReaperGraceMinutes != nil || args.ReaperKeep != nil {
+		repo, exists := d.state.GetAllRepos()[name]
+		if !exists {
+			return struct{}{}, socket.Errorf(socket.CodeNotFound, "repository %q not found", name)
+		}
+		reaper := repo.WindowReaper
+		if args.ReaperMode != nil {
+			switch mode := state.ReaperMode(*args.ReaperMode); mode {
+			case state.ReaperDryRun, state.ReaperEnforce, state.ReaperOff:
+				reaper.Mode = mode
+			default:
+				return struct{}{}, fmt.Errorf("invalid reaper_mode %q (must be dry-run, enforce, or off)", *args.ReaperMode)
+			}
+		}
+		if args.ReaperGraceMinutes != nil {
+			if *args.ReaperGraceMinutes < 1 {
+				return struct{}{}, fmt.Errorf("reaper_grace_minutes must be at least 1")
+			}
+			reaper.GraceMinutes = *args.ReaperGraceMinutes
+		}
+		if args.ReaperKeep != nil {
+			reaper.Keep = nonEmpty(args.ReaperKeep)
+		}
+		if err := d.state.UpdateWindowReaperConfig(name, reaper); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated window reaper for repo %s: mode=%s grace=%s keep=%v", name, reaper.EffectiveMode(), reaper.Grace(), reaper.Keep)
 	}
-}
 
-// handleUpdateRepoConfig updates the configuration for a repository
-func (d *Daemon) handleUpdateRepoConfig(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
-	if !ok {
-		return errResp
+	if args.RecoveryAuto != nil || args.RecoveryAfterMinutes != nil {
+		repo, exists := d.state.GetAllRepos()[name]
+		if !exists {
+			return struct{}{}, socket.Errorf(socket.CodeNotFound, "repository %q not found", name)
+		}
+		recovery := repo.Recovery
+		if args.RecoveryAuto != nil {
+			recovery.Auto = *args.RecoveryAuto
+		}
+		if args.RecoveryAfterMinutes != nil {
+			if *args.RecoveryAfterMinutes < 1 {
+				return struct{}{}, fmt.Errorf("recovery_after_minutes must be at least 1")
+			}
+			recovery.AfterMinutes = *args.RecoveryAfterMinutes
+		}
+		if err := d.state.UpdateRecoveryConfig(name, recovery); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated worktree recovery for repo %s: auto=%v after=%s", name, recovery.Auto, recovery.After())
 	}
 
-	// Get current merge queue config
-	currentMQConfig, err := d.state.GetMergeQueueConfig(name)
-	if err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+	if err := d.updateStuckConfig(name, args); err != nil {
+		return struct{}{}, err
 	}
 
-	// Update merge queue config with provided values
-	mqUpdated := false
-	if mqEnabled, ok := req.Args["mq_enabled"].(bool); ok {
-		currentMQConfig.Enabled = mqEnabled
-		mqUpdated = true
+	access, accessUpdated := state.AccessPolicy{}, false
+	for arg, list := range map[string][]string{
+		"access_spawn":  args.AccessSpawn,
+		"access_remove": args.AccessRemove,
+		"access_merge":  args.AccessMerge,
+		"access_admin":  args.AccessAdmin,
+	} {
+		if list == nil {
+			continue
+		}
+		if !accessUpdated {
+			repo, exists := d.state.GetAllRepos()[name]
+			if !exists {
+				return struct{}{}, socket.Errorf(socket.CodeNotFound, "repository %q not found", name)
+			}
+			access, accessUpdated = repo.Access, true
+		}
+		members, err := parseAccessMembers(arg, list)
+		if err != nil {
+			return struct{}{}, err
+		}
+		access.SetMembers(accessArgs[arg], members)
 	}
-	if mqTrackMode, ok := req.Args["mq_track_mode"].(string); ok {
-		switch mqTrackMode {
-		case "all":
-			currentMQConfig.TrackMode = state.TrackModeAll
-		case "author":
-			currentMQConfig.TrackMode = state.TrackModeAuthor
-		case "assigned":
-			currentMQConfig.TrackMode = state.TrackModeAssigned
-		default:
-			return socket.Response{Success: false, Error: fmt.Sprintf("invalid track mode: %s", mqTrackMode)}
+	if accessUpdated {
+		if err := d.state.UpdateAccessPolicy(name, access); err != nil {
+			return struct{}{}, err
 		}
-		mqUpdated = true
+		d.logger.Info("Updated access for repo %s by %s: spawn=%v remove=%v merge=%v admin=%v", name, req.Peer, access.Spawn, access.Remove, access.Merge, access.Admin)
 	}
 
-	if mqUpdated {
-		if err := d.state.UpdateMergeQueueConfig(name, currentMQConfig); err != nil {
-			return socket.Response{Success: false, Error: err.Error()}
+	if args.DefaultBase != nil {
+		defaultBase := *args.DefaultBase
+		// An empty base goes back to the default branch
+		if defaultBase != "" {
+			wt := worktree.NewManager(d.paths.RepoDir(name))
+			remote, err := wt.GetUpstreamRemote()
+			if err != nil {
+				return struct{}{}, err
+			}
+			base, err := wt.ResolveBase(remote, defaultBase)
+			if err != nil {
+				return struct{}{}, fmt.Errorf("invalid default base: %v", err)
+			}
+			defaultBase = base.Ref
+		}
+		if err := d.state.SetDefaultBase(name, defaultBase); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated default base for repo %s: %q", name, defaultBase)
+	}
+
+	if args.Groups != nil {
+		groups, err := parseRepoGroups(args.Groups)
+		if err != nil {
+			return struct{}{}, err
 		}
-		d.logger.Info("Updated merge queue config for repo %s: enabled=%v, track=%s", name, currentMQConfig.Enabled, currentMQConfig.TrackMode)
+		if err := d.state.SetRepoGroups(name, groups); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Updated groups for repo %s: %v", name, groups)
 	}
 
-	return socket.Response{Success: true}
+	return struct{}{}, nil
 }
 
-// handleSetCurrentRepo sets the current/default repository
-func (d *Daemon) handleSetCurrentRepo(req socket.Request) socket.Response {
-	name, errResp, ok := getRequiredStringArg(req.Args, "name", "repository name is required")
-	if !ok {
-		return errResp
+// parseRepoGroups validates a list of group names from a socket request.
+// Names are lowercased and deduplicated; an empty list clears all groups.
+func parseRepoGroups(list []string) ([]string, error) {
+	var groups []string
+	seen := make(map[string]bool)
+	for _, name := range list {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		for _, r := range name {
+			if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' {
+				return nil, fmt.Errorf("invalid group name %q: use letters, digits, '-' or '_'", name)
+			}
+		}
+		if !seen[name] {
+			seen[name] = true
+			groups = append(groups, name)
+		}
 	}
+	return groups, nil
+}
+
+// handleSetCurrentRepo sets the current/default repository
+func (d *Daemon) handleSetCurrentRepo(req socket.Request, args socket.RepoNameArgs) (string, error) {
+	name := args.Name
 
 	if err := d.state.SetCurrentRepo(name); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+		return "", err
 	}
 
 	d.logger.Info("Set current repository to: %s", name)
-	return socket.Response{Success: true, Data: name}
+	return name, nil
 }
 
 // handleGetCurrentRepo returns the current/default repository
-func (d *Daemon) handleGetCurrentRepo(req socket.Request) socket.Response {
+func (d *Daemon) handleGetCurrentRepo(req socket.Request, args socket.NoArgs) (string, error) {
 	currentRepo := d.state.GetCurrentRepo()
 	if currentRepo == "" {
-		return socket.Response{Success: false, Error: "no current repository set"}
+		return "", fmt.Errorf("no current repository set")
 	}
-	return socket.Response{Success: true, Data: currentRepo}
+	return currentRepo, nil
 }
 
 // handleClearCurrentRepo clears the current/default repository
-func (d *Daemon) handleClearCurrentRepo(req socket.Request) socket.Response {
+func (d *Daemon) handleClearCurrentRepo(req socket.Request, args socket.NoArgs) (struct{}, error) {
 	if err := d.state.ClearCurrentRepo(); err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+		return struct{}{}, err
 	}
 
 	d.logger.Info("Cleared current repository")
-	return socket.Response{Success: true}
+	return struct{}{}, nil
 }
 
-// cleanupDeadAgents removes dead agents from state
-func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
+// cleanupDeadAgents removes dead agents from state. task names the daemon
+// task cleaning them up in the audit log.
+func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string, task string) {
 	for repoName, agentNames := range deadAgents {
 		for _, agentName := range agentNames {
 			d.logger.Info("Cleaning up dead agent %s/%s", repoName, agentName)
@@ -1302,8 +3007,10 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 				d.recordTaskHistory(repoName, agentName, agent)
 			}
 
-			// Kill tmux window
-			if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
+			// Kill tmux window, or the Claude process of a headless agent
+			if agent.Headless {
+				d.stopHeadless(repoName, agentName)
+			} else if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
 				d.logger.Warn("Failed to kill tmux window %s: %v", agent.TmuxWindow, err)
 			} else {
 				d.logger.Info("Killed tmux window for agent %s: %s", agentName, agent.TmuxWindow)
@@ -1313,6 +3020,8 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 			if err := d.state.RemoveAgent(repoName, agentName); err != nil {
 				d.logger.Error("Failed to remove agent %s/%s from state: %v", repoName, agentName, err)
 			}
+			d.recordAction(repoName, feed.ActionCleaned, agentName, "")
+			d.recordDaemonChange(task, audit.ActionAgentRemoved, repoName, agentName, "")
 
 			// Clean up worktree if it exists (workers and review agents have worktrees)
 			if agent.WorktreePath != "" && (agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview) {
@@ -1324,6 +3033,7 @@ func (d *Daemon) cleanupDeadAgents(deadAgents map[string][]string) {
 					d.logger.Info("Removed worktree for dead agent: %s", agent.WorktreePath)
 				}
 			}
+			d.removeScratchWorktrees(repoName, agentName, agent)
 
 			// Clean up message directory
 			msgMgr := d.getMessageManager()
@@ -1344,7 +3054,7 @@ func (d *Daemon) recordTaskHistory(repoName, agentName string, agent state.Agent
 			branch = b
 		} else {
 			// Fallback: construct expected branch name
-			branch = "work/" + agentName
+			branch = d.workerBranch(repoName, agentName)
 		}
 	}
 
@@ -1352,17 +3062,21 @@ func (d *Daemon) recordTaskHistory(repoName, agentName string, agent state.Agent
 	status := state.TaskStatusUnknown
 	if agent.FailureReason != "" {
 		status = state.TaskStatusFailed
+	} else if agent.PRURL != "" {
+		status = state.TaskStatusOpen
 	}
 
 	entry := state.TaskHistoryEntry{
 		Name:          agentName,
 		Task:          agent.Task,
 		Branch:        branch,
+		PRURL:         agent.PRURL,
+		PRNumber:      agent.PRNumber,
 		Status:        status, // Will be updated when displaying if a PR exists
 		Summary:       agent.Summary,
 		FailureReason: agent.FailureReason,
 		CreatedAt:     agent.CreatedAt,
-		CompletedAt:   time.Now(),
+		CompletedAt:   d.clock.Now(),
 	}
 
 	if err := d.state.AddTaskHistory(repoName, entry); err != nil {
@@ -1373,41 +3087,22 @@ func (d *Daemon) recordTaskHistory(repoName, agentName string, agent state.Agent
 }
 
 // handleTaskHistory returns the task history for a repository
-func (d *Daemon) handleTaskHistory(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
-	if !ok {
-		return errResp
-	}
+func (d *Daemon) handleTaskHistory(req socket.Request, args socket.TaskHistoryArgs) ([]state.TaskHistoryEntry, error) {
+	repoName := args.Repo
 
-	// Get optional limit
 	limit := 10 // default
-	if l, ok := req.Args["limit"].(float64); ok {
-		limit = int(l)
+	if args.Limit != nil {
+		limit = *args.Limit
 	}
 
 	history, err := d.state.GetTaskHistory(repoName, limit)
 	if err != nil {
-		return socket.Response{Success: false, Error: err.Error()}
+		return nil, err
 	}
-
-	// Convert to interface slice for JSON serialization
-	result := make([]map[string]interface{}, len(history))
-	for i, entry := range history {
-		result[i] = map[string]interface{}{
-			"name":           entry.Name,
-			"task":           entry.Task,
-			"branch":         entry.Branch,
-			"pr_url":         entry.PRURL,
-			"pr_number":      entry.PRNumber,
-			"status":         string(entry.Status),
-			"summary":        entry.Summary,
-			"failure_reason": entry.FailureReason,
-			"created_at":     entry.CreatedAt,
-			"completed_at":   entry.CompletedAt,
-		}
+	if history == nil {
+		history = []state.TaskHistoryEntry{}
 	}
-
-	return socket.Response{Success: true, Data: result}
+	return history, nil
 }
 
 // handleSpawnAgent spawns a new agent with an inline prompt (no hardcoded type).
@@ -1416,49 +3111,70 @@ func (d *Daemon) handleTaskHistory(req socket.Request) socket.Response {
 //   - repo: repository name
 //   - name: agent name (used for tmux window and worktree)
 //   - class: "persistent" or "ephemeral"
-//   - prompt: full prompt text to use as system prompt
+//   - prompt: full prompt text to use as system prompt, or
+//   - definition: name of a registered agent definition to use instead
 //   - task: optional task description (for ephemeral/worker agents)
-func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
-	repoName, errResp, ok := getRequiredStringArg(req.Args, "repo", "repository name is required")
-	if !ok {
-		return errResp
-	}
+//
+// Agents calling this are limited by checkSpawnPolicy.
+func (d *Daemon) handleSpawnAgent(req socket.Request, args socket.SpawnAgentArgs) (socket.SpawnedAgent, error) {
+	repoName := args.Repo
 
-	agentName, errResp, ok := getRequiredStringArg(req.Args, "name", "agent name is required")
-	if !ok {
-		return errResp
-	}
+	agentName := args.Name
 
-	agentClass, errResp, ok := getRequiredStringArg(req.Args, "class", "agent class is required (persistent or ephemeral)")
-	if !ok {
-		return errResp
-	}
+	agentClass := args.Class
 
-	promptText, errResp, ok := getRequiredStringArg(req.Args, "prompt", "prompt text is required")
-	if !ok {
-		return errResp
+	promptText, definition := args.Prompt, args.Definition
+	switch {
+	case promptText == "" && definition == "":
+		return socket.SpawnedAgent{}, fmt.Errorf("prompt text or a definition name is required")
+	case promptText != "" && definition != "":
+		return socket.SpawnedAgent{}, fmt.Errorf("give either prompt text or a definition name, not both")
+	case len(promptText) > maxSpawnPromptBytes:
+		return socket.SpawnedAgent{}, fmt.Errorf("prompt is %d bytes; the limit is %d", len(promptText), maxSpawnPromptBytes)
 	}
 
 	// Validate class
 	if agentClass != "persistent" && agentClass != "ephemeral" {
-		return socket.Response{
-			Success: false,
-			Error:   fmt.Sprintf("invalid agent class %q: must be 'persistent' or 'ephemeral'", agentClass),
+		return socket.SpawnedAgent{}, fmt.Errorf("invalid agent class %q: must be 'persistent' or 'ephemeral'", agentClass)
+	}
+
+	caller := d.identifySpawnCaller(req.Peer)
+	if err := checkSpawnPolicy(caller, repoName, agentClass, definition != ""); err != nil {
+		d.logger.Warn("Denied spawn_agent of %s/%s: %v", repoName, agentName, err)
+		return socket.SpawnedAgent{}, fmt.Errorf("permission denied: %v", err)
+	}
+
+	var def agents.Definition
+	if definition != "" {
+		found, text, err := d.findDefinition(repoName, definition)
+		if err != nil {
+			return socket.SpawnedAgent{}, err
 		}
+		def, promptText = found, text
+	}
+
+	task := args.Task
+
+	// Read-only is for ephemeral agents only, since persistent agents work
+	// directly in the repository clone
+	readOnly := args.ReadOnly
+	if readOnly && agentClass == "persistent" {
+		return socket.SpawnedAgent{}, fmt.Errorf("read_only is only supported for ephemeral agents")
 	}
 
-	// Get optional task
-	task, _ := req.Args["task"].(string)
+	// Headless runs Claude as a child of the daemon instead of in a tmux
+	// window
+	headless := args.Headless
 
 	// Get repository
-	repo, exists := d.state.GetRepo(repoName)
+	repo, exists := d.state.GetAllRepos()[repoName]
 	if !exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("repository %q not found", repoName)}
+		return socket.SpawnedAgent{}, socket.Errorf(socket.CodeNotFound, "repository %q not found", repoName)
 	}
 
 	// Check if agent already exists
 	if _, exists := d.state.GetAgent(repoName, agentName); exists {
-		return socket.Response{Success: false, Error: fmt.Sprintf("agent %q already exists in repository %q", agentName, repoName)}
+		return socket.SpawnedAgent{}, fmt.Errorf("agent %q already exists in repository %q", agentName, repoName)
 	}
 
 	// Determine agent type based on class
@@ -1479,11 +3195,17 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 		}
 	}
 
+	if agentType == state.AgentTypeWorker {
+		if err := d.workerCapacityError(repoName); err != nil {
+			return socket.SpawnedAgent{}, err
+		}
+	}
+
 	// Create worktree for the agent
 	repoPath := d.paths.RepoDir(repoName)
 	worktreePath := d.paths.AgentWorktree(repoName, agentName)
 
-	wt := worktree.NewManager(repoPath)
+	wt := d.agentWorktreeManager(repoName, agentType)
 
 	// Create worktree - persistent agents use repo dir, ephemeral get their own branch
 	if agentClass == "persistent" {
@@ -1491,31 +3213,50 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 		worktreePath = repoPath
 	} else {
 		// Ephemeral agents get their own worktree with a new branch
-		branchName := fmt.Sprintf("work/%s", agentName)
-		if err := wt.CreateNewBranch(worktreePath, branchName, "HEAD"); err != nil {
-			return socket.Response{Success: false, Error: fmt.Sprintf("failed to create worktree: %v", err)}
+		if err := wt.CreateNewBranch(worktreePath, d.workerBranch(repoName, agentName), "HEAD"); err != nil {
+			return socket.SpawnedAgent{}, fmt.Errorf("failed to create worktree: %v", err)
 		}
 	}
 
-	// Create tmux window with working directory
-	cmd := exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", agentName, "-c", worktreePath)
-	if err := cmd.Run(); err != nil {
-		// Clean up worktree on failure (only for ephemeral agents that have their own worktree)
+	// Check the environment works before an agent spends time in it
+	if err := def.RunProbe(d.ctx, worktreePath); err != nil {
+		d.logger.Warn("Not spawning %s/%s: %v", repoName, agentName, err)
 		if agentClass != "persistent" {
 			wt.Remove(worktreePath, true)
+			wt.DeleteBranch(d.workerBranch(repoName, agentName))
+		}
+		return socket.SpawnedAgent{}, err
+	}
+
+	// Create tmux window with working directory
+	killWindow := func() {
+		if !headless {
+			d.tmux.KillWindow(d.ctx, repo.TmuxSession, agentName)
+		}
+	}
+	if !headless {
+		if err := d.tmux.CreateWindowAt(d.ctx, repo.TmuxSession, agentName, worktreePath); err != nil {
+			// Clean up worktree on failure (only for ephemeral agents that have their own worktree)
+			if agentClass != "persistent" {
+				wt.Remove(worktreePath, true)
+			}
+			return socket.SpawnedAgent{}, fmt.Errorf("failed to create tmux window: %v", err)
 		}
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to create tmux window: %v", err)}
 	}
 
 	// Write prompt to file
 	promptDir := filepath.Join(d.paths.Root, "prompts")
 	if err := os.MkdirAll(promptDir, 0755); err != nil {
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to create prompt directory: %v", err)}
+		return socket.SpawnedAgent{}, fmt.Errorf("failed to create prompt directory: %v", err)
+	}
+
+	if readOnly {
+		promptText = prompts.GenerateReadOnlyPrompt() + "\n\n---\n\n" + promptText
 	}
 
 	promptPath := filepath.Join(promptDir, fmt.Sprintf("%s.md", agentName))
 	if err := os.WriteFile(promptPath, []byte(promptText), 0644); err != nil {
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to write prompt file: %v", err)}
+		return socket.SpawnedAgent{}, fmt.Errorf("failed to write prompt file: %v", err)
 	}
 
 	// Copy hooks config
@@ -1523,47 +3264,61 @@ func (d *Daemon) handleSpawnAgent(req socket.Request) socket.Response {
 		d.logger.Warn("Failed to copy hooks config: %v", err)
 	}
 
+	// Lock down the worktree after hooks config is in place
+	if readOnly {
+		if err := worktree.MakeReadOnly(worktreePath); err != nil {
+			killWindow()
+			wt.Remove(worktreePath, true)
+			return socket.SpawnedAgent{}, fmt.Errorf("failed to make worktree read-only: %v", err)
+		}
+	}
+
 	// Start Claude in the tmux window
 	cfg := agentStartConfig{
 		agentName:  agentName,
 		agentType:  agentType,
 		promptFile: promptPath,
 		workDir:    worktreePath,
+		headless:   headless,
+	}
+	if headless && task != "" {
+		cfg.initialMessage = fmt.Sprintf("Task: %s", task)
 	}
 
 	if err := d.startAgentWithConfig(repoName, repo, cfg); err != nil {
 		// Clean up on failure
-		d.tmux.KillWindow(d.ctx, repo.TmuxSession, agentName)
+		killWindow()
 		if agentClass != "persistent" {
 			wt.Remove(worktreePath, true)
 		}
-		return socket.Response{Success: false, Error: fmt.Sprintf("failed to start agent: %v", err)}
+		return socket.SpawnedAgent{}, fmt.Errorf("failed to start agent: %v", err)
 	}
 
-	// Update task if provided
-	if task != "" {
+	// Update task and read-only flag if provided
+	if task != "" || readOnly {
 		agent, _ := d.state.GetAgent(repoName, agentName)
 		agent.Task = task
+		agent.ReadOnly = readOnly
 		d.state.UpdateAgent(repoName, agentName, agent)
 	}
 
-	d.logger.Info("Spawned agent %s/%s (class=%s, type=%s)", repoName, agentName, agentClass, agentType)
+	d.logger.Info("Spawned agent %s/%s (class=%s, type=%s) for %s", repoName, agentName, agentClass, agentType, caller)
 
-	return socket.Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"name":          agentName,
-			"class":         agentClass,
-			"type":          string(agentType),
-			"worktree_path": worktreePath,
-		},
-	}
+	return socket.SpawnedAgent{
+		Name:         agentName,
+		Class:        agentClass,
+		Type:         agentType,
+		WorktreePath: worktreePath,
+	}, nil
 }
 
 // cleanupOrphanedWorktrees removes worktree directories without git tracking
 func (d *Daemon) cleanupOrphanedWorktrees() {
 	repoNames := d.state.ListRepos()
 	for _, repoName := range repoNames {
+		if d.isReadOnlyRepo(repoName, "orphaned worktree cleanup") {
+			continue
+		}
 		repoPath := d.paths.RepoDir(repoName)
 		wtRootDir := d.paths.WorktreeDir(repoName)
 
@@ -1573,6 +3328,7 @@ func (d *Daemon) cleanupOrphanedWorktrees() {
 		}
 
 		wt := worktree.NewManager(repoPath)
+		d.cleanupOrphanedScratch(repoName, wt)
 		removed, err := worktree.CleanupOrphaned(wtRootDir, wt)
 		if err != nil {
 			d.logger.Error("Failed to cleanup orphaned worktrees for %s: %v", repoName, err)
@@ -1605,12 +3361,15 @@ func (d *Daemon) cleanupMergedBranches() {
 		if _, err := os.Stat(repoPath); os.IsNotExist(err) {
 			continue
 		}
+		if d.isReadOnlyRepo(repoName, "merged branch cleanup") {
+			continue
+		}
 
 		wt := worktree.NewManager(repoPath)
 
-		// Clean up merged branches with common multiclaude prefixes
-		for _, prefix := range []string{"multiclaude/", "work/"} {
-			deleted, err := wt.CleanupMergedBranches(prefix, true)
+		// Clean up merged branches with multiclaude's prefixes
+		for _, prefix := range d.configFile().BranchPrefixes(repoName) {
+			deleted, err := wt.CleanupMergedBranches(prefix, d.pushRemote(repoName))
 			if err != nil {
 				d.logger.Debug("Failed to cleanup merged branches with prefix %s for %s: %v", prefix, repoName, err)
 				continue
@@ -1627,44 +3386,98 @@ func (d *Daemon) cleanupMergedBranches() {
 }
 
 // restoreTrackedRepos restores agents for tracked repos that are missing their tmux sessions
-// or have dead Claude processes
+// or have dead Claude processes. What it did to each repo and agent is recorded in a
+// restoration report (see recordRestore).
 func (d *Daemon) restoreTrackedRepos() {
 	d.logger.Info("Checking tracked repos for restoration")
+	report := events.DaemonRestoredPayload{StartedAt: d.clock.Now(), Repos: []events.RestoredRepo{}}
 
 	repos := d.state.GetAllRepos()
-	for repoName, repo := range repos {
+	repoNames := make([]string, 0, len(repos))
+	for name := range repos {
+		repoNames = append(repoNames, name)
+	}
+	sort.Strings(repoNames)
+
+	for _, repoName := range repoNames {
+		repo := repos[repoName]
+		entry := events.RestoredRepo{Repo: repoName}
+
+		if owner, ok := d.foreignLock(repoName); ok {
+			entry.Action = restoreSkipped
+			entry.Reason = fmt.Sprintf("managed by %s", owner)
+			report.Repos = append(report.Repos, entry)
+			continue
+		}
+
 		// Check if tmux session exists
 		hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
 		if err != nil {
 			d.logger.Error("Failed to check session %s: %v", repo.TmuxSession, err)
+			entry.Action = restoreRepoFailed
+			entry.Reason = fmt.Sprintf("failed to check tmux session: %v", err)
+			report.Repos = append(report.Repos, entry)
 			continue
 		}
 
 		if hasSession {
 			d.logger.Debug("Tmux session %s exists for repo %s", repo.TmuxSession, repoName)
 			// Session exists but agents might have dead processes - check and restart them
-			d.restoreDeadAgents(repoName, repo)
+			entry.Action = restoreSessionKept
+			entry.Reason = "tmux session exists"
+			entry.Agents = d.restoreDeadAgents(repoName, repo)
+			report.Repos = append(report.Repos, entry)
 			continue
 		}
 
 		// Session doesn't exist - restore it
 		d.logger.Info("Restoring agents for repo %s (tmux session %s was missing)", repoName, repo.TmuxSession)
-		if err := d.restoreRepoAgents(repoName, repo); err != nil {
+		entry.Action = restoreSessionRecreated
+		entry.Reason = "tmux session was missing"
+		agents, err := d.restoreRepoAgents(repoName, repo)
+		entry.Agents = agents
+		if err != nil {
 			d.logger.Error("Failed to restore agents for repo %s: %v", repoName, err)
+			entry.Action = restoreRepoFailed
+			entry.Reason = err.Error()
 		}
+		report.Repos = append(report.Repos, entry)
 	}
+
+	report.FinishedAt = d.clock.Now()
+	d.recordRestore(report)
 }
 
 // restoreDeadAgents restarts agents that have dead Claude processes but existing tmux windows.
 // This is called on daemon startup when the tmux session exists but Claude processes may have died
-// (e.g., after a system restart or Claude crash).
-func (d *Daemon) restoreDeadAgents(repoName string, repo *state.Repository) {
+// (e.g., after a system restart or Claude crash). It returns what it did with each agent.
+func (d *Daemon) restoreDeadAgents(repoName string, repo *state.Repository) []events.RestoredAgent {
 	d.logger.Debug("Checking for dead agents in repo %s", repoName)
 
-	for agentName, agent := range repo.Agents {
+	agentNames := make([]string, 0, len(repo.Agents))
+	for name := range repo.Agents {
+		agentNames = append(agentNames, name)
+	}
+	sort.Strings(agentNames)
+
+	var restored []events.RestoredAgent
+	for _, agentName := range agentNames {
+		agent := repo.Agents[agentName]
+		result := events.RestoredAgent{Name: agentName, Type: string(agent.Type), Outcome: restoreLeftToCleanup}
+
+		// Headless agents don't live in the session; their runs resume
+		// their sessions as prompts arrive
+		if agent.Headless {
+			result.Outcome = restoreHeadless
+			restored = append(restored, result)
+			continue
+		}
+
 		// Skip agents without a PID (shouldn't happen, but be safe)
 		if agent.PID <= 0 {
 			d.logger.Debug("Agent %s has no PID, skipping", agentName)
+			result.Error = "no PID recorded"
+			restored = append(restored, result)
 			continue
 		}
 
@@ -1672,17 +3485,23 @@ func (d *Daemon) restoreDeadAgents(repoName string, repo *state.Repository) {
 		hasWindow, err := d.tmux.HasWindow(d.ctx, repo.TmuxSession, agent.TmuxWindow)
 		if err != nil {
 			d.logger.Error("Failed to check window for agent %s: %v", agentName, err)
+			result.Error = fmt.Sprintf("failed to check window: %v", err)
+			restored = append(restored, result)
 			continue
 		}
 
 		if !hasWindow {
 			d.logger.Debug("Agent %s window not found, will be handled by health check", agentName)
+			result.Error = "window missing"
+			restored = append(restored, result)
 			continue
 		}
 
 		// Check if the process is still alive
 		if isProcessAlive(agent.PID) {
 			d.logger.Debug("Agent %s process (PID %d) is alive", agentName, agent.PID)
+			result.Outcome = restoreAlive
+			restored = append(restored, result)
 			continue
 		}
 
@@ -1693,37 +3512,66 @@ func (d *Daemon) restoreDeadAgents(repoName string, repo *state.Repository) {
 		if agent.Type.IsPersistent() {
 			if err := d.restartAgent(repoName, agentName, agent, repo); err != nil {
 				d.logger.Error("Failed to restart agent %s: %v", agentName, err)
+				result.Outcome = restoreAgentFailed
+				result.Error = err.Error()
 			} else {
 				d.logger.Info("Successfully restarted agent %s with --resume", agentName)
+				result.Outcome = restoreRestarted
 			}
 		} else {
 			d.logger.Debug("Skipping transient agent %s (type %s) - will be cleaned up", agentName, agent.Type)
+			result.Error = "process dead"
 		}
+		restored = append(restored, result)
 	}
+	return restored
 }
 
-// restoreRepoAgents restores the tmux session and agents for a tracked repo
-func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) error {
+// restoreRepoAgents restores the tmux session and agents for a tracked repo. It returns
+// what it did with each agent: the stale ones it dropped and the ones it started.
+func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) ([]events.RestoredAgent, error) {
 	repoPath := d.paths.RepoDir(repoName)
 
 	// Verify the repo still exists on disk
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-		return fmt.Errorf("repository path does not exist: %s", repoPath)
+		return nil, fmt.Errorf("repository path does not exist: %s", repoPath)
 	}
 
-	// Clear any stale agents from state (their tmux session is gone)
+	// Clear any stale agents from state (their tmux session is gone).
+	// Headless agents never lived in it and are kept.
+	staleNames := make([]string, 0, len(repo.Agents))
 	for agentName := range repo.Agents {
+		staleNames = append(staleNames, agentName)
+	}
+	sort.Strings(staleNames)
+
+	var restored []events.RestoredAgent
+	for _, agentName := range staleNames {
+		if agent := repo.Agents[agentName]; agent.Headless {
+			restored = append(restored, events.RestoredAgent{Name: agentName, Type: string(agent.Type), Outcome: restoreHeadless})
+			continue
+		}
 		d.logger.Debug("Removing stale agent %s/%s from state", repoName, agentName)
+		result := events.RestoredAgent{Name: agentName, Type: string(repo.Agents[agentName].Type), Outcome: restoreDropped}
 		if err := d.state.RemoveAgent(repoName, agentName); err != nil {
 			d.logger.Warn("Failed to remove stale agent %s/%s: %v", repoName, agentName, err)
+			result.Error = err.Error()
+		}
+		restored = append(restored, result)
+	}
+	started := func(agentName string, agentType state.AgentType, err error) {
+		result := events.RestoredAgent{Name: agentName, Type: string(agentType), Outcome: restoreStarted}
+		if err != nil {
+			result.Outcome = restoreAgentFailed
+			result.Error = err.Error()
 		}
+		restored = append(restored, result)
 	}
 
 	// Create tmux session with supervisor window
 	d.logger.Info("Creating tmux session %s for repo %s", repo.TmuxSession, repoName)
-	cmd := exec.Command("tmux", "new-session", "-d", "-s", repo.TmuxSession, "-n", "supervisor", "-c", repoPath)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create tmux session: %w", err)
+	if err := d.tmux.CreateSessionAt(d.ctx, repo.TmuxSession, "supervisor", repoPath); err != nil {
+		return restored, fmt.Errorf("failed to create tmux session: %w", err)
 	}
 
 	// Get merge queue config (use default if not set for backward compatibility)
@@ -1733,9 +3581,11 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 	}
 
 	// Start supervisor agent
-	if err := d.startAgent(repoName, repo, "supervisor", state.AgentTypeSupervisor, repoPath); err != nil {
+	err := d.startAgent(repoName, repo, "supervisor", state.AgentTypeSupervisor, repoPath)
+	if err != nil {
 		d.logger.Error("Failed to start supervisor for %s: %v", repoName, err)
 	}
+	started("supervisor", state.AgentTypeSupervisor, err)
 
 	// Send agent definitions to supervisor (includes merge-queue config for supervisor to decide)
 	if err := d.sendAgentDefinitionsToSupervisor(repoName, repoPath, mqConfig); err != nil {
@@ -1747,7 +3597,7 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 	if _, err := os.Stat(workspacePath); os.IsNotExist(err) {
 		// Workspace worktree doesn't exist, create it
 		d.logger.Info("Creating workspace worktree for %s", repoName)
-		wt := worktree.NewManager(repoPath)
+		wt := d.agentWorktreeManager(repoName, state.AgentTypeWorkspace)
 
 		// Prune stale worktree references first - this handles the case where
 		// worktree directories were deleted but git still has references to them
@@ -1784,17 +3634,21 @@ func (d *Daemon) restoreRepoAgents(repoName string, repo *state.Repository) erro
 
 	// Now start the workspace agent if worktree exists
 	if _, err := os.Stat(workspacePath); err == nil {
-		cmd = exec.Command("tmux", "new-window", "-d", "-t", repo.TmuxSession, "-n", "workspace", "-c", workspacePath)
-		if err := cmd.Run(); err != nil {
+		if err := d.tmux.CreateWindowAt(d.ctx, repo.TmuxSession, "workspace", workspacePath); err != nil {
 			d.logger.Error("Failed to create workspace window: %v", err)
+			started("workspace", state.AgentTypeWorkspace, fmt.Errorf("failed to create window: %w", err))
 		} else {
-			if err := d.startAgent(repoName, repo, "workspace", state.AgentTypeWorkspace, workspacePath); err != nil {
+			err := d.startAgent(repoName, repo, "workspace", state.AgentTypeWorkspace, workspacePath)
+			if err != nil {
 				d.logger.Error("Failed to start workspace for %s: %v", repoName, err)
 			}
+			started("workspace", state.AgentTypeWorkspace, err)
 		}
+	} else {
+		started("workspace", state.AgentTypeWorkspace, fmt.Errorf("workspace worktree missing"))
 	}
 
-	return nil
+	return restored, nil
 }
 
 // sendAgentDefinitionsToSupervisor reads agent definitions and sends them to the supervisor.
@@ -1836,6 +3690,10 @@ func (d *Daemon) sendAgentDefinitionsToSupervisor(repoName, repoPath string, mqC
 			trackModePrompt := prompts.GenerateTrackingModePrompt(string(mqConfig.TrackMode))
 			sb.WriteString(trackModePrompt)
 			sb.WriteString("\n\n")
+			if mqConfig.AutoMerge {
+				sb.WriteString(prompts.GenerateAutoMergePrompt(mqConfig.MergeLabel()))
+				sb.WriteString("\n\n")
+			}
 		}
 
 		sb.WriteString(def.Content)
@@ -1846,8 +3704,9 @@ func (d *Daemon) sendAgentDefinitionsToSupervisor(repoName, repoPath string, mqC
 	sb.WriteString("For each agent, decide:\n")
 	sb.WriteString("- Class: Is it persistent (long-running, auto-restarts) or ephemeral (task-based, cleans up)?\n")
 	sb.WriteString("- Spawn now: Should this agent start immediately on repository init?\n\n")
-	sb.WriteString("To spawn an agent, save the prompt to a file and use:\n")
-	sb.WriteString(fmt.Sprintf("  multiclaude agents spawn --repo %s --name <agent-name> --class <persistent|ephemeral> --prompt-file <file>\n", repoName))
+	sb.WriteString("To spawn an ephemeral agent from one of these definitions, use:\n")
+	sb.WriteString(fmt.Sprintf("  multiclaude agents spawn --repo %s --name <agent-name> --class ephemeral --definition <definition-name>\n", repoName))
+	sb.WriteString("Persistent agents must be spawned by a human; ask them if one should run.\n")
 
 	// Send message to supervisor
 	msgMgr := d.getMessageManager()
@@ -1874,6 +3733,11 @@ type agentStartConfig struct {
 	agentType  state.AgentType
 	promptFile string
 	workDir    string
+	// initialMessage is typed into Claude once it has started (e.g. the task)
+	initialMessage string
+	// headless runs Claude as a child of the daemon instead of in the
+	// agent's tmux window; initialMessage is its first prompt
+	headless bool
 }
 
 // startAgentWithConfig is the unified agent start function that handles all common logic
@@ -1890,35 +3754,18 @@ func (d *Daemon) startAgentWithConfig(repoName string, repo *state.Repository, c
 		d.logger.Warn("Failed to copy hooks config: %v", err)
 	}
 
-	var pid int
-
-	// Skip actual Claude startup in test mode
-	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
-		// Resolve claude binary path
-		binaryPath, err := d.getClaudeBinaryPath()
-		if err != nil {
-			return fmt.Errorf("failed to resolve claude binary: %w", err)
-		}
-
-		// Build CLI command
-		claudeCmd := fmt.Sprintf("%s --session-id %s --dangerously-skip-permissions --append-system-prompt-file %s",
-			binaryPath, sessionID, cfg.promptFile)
-
-		// Send command to tmux window
-		target := fmt.Sprintf("%s:%s", repo.TmuxSession, cfg.agentName)
-		cmd := exec.Command("tmux", "send-keys", "-t", target, claudeCmd, "C-m")
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to start Claude in tmux: %w", err)
-		}
+	template, err := d.launchTemplate(repoName, cfg)
+	if err != nil {
+		return err
+	}
 
-		// Wait a moment for Claude to start
-		time.Sleep(500 * time.Millisecond)
+	if cfg.headless {
+		return d.startHeadlessAgent(repoName, cfg, sessionID)
+	}
 
-		// Get PID
-		pid, err = d.tmux.GetPanePID(d.ctx, repo.TmuxSession, cfg.agentName)
-		if err != nil {
-			return fmt.Errorf("failed to get Claude PID: %w", err)
-		}
+	pid, err := d.launchClaude(repoName, repo, cfg, sessionID, template)
+	if err != nil {
+		return err
 	}
 
 	// Register agent with state
@@ -1928,7 +3775,7 @@ func (d *Daemon) startAgentWithConfig(repoName string, repo *state.Repository, c
 		TmuxWindow:   cfg.agentName,
 		SessionID:    sessionID,
 		PID:          pid,
-		CreatedAt:    time.Now(),
+		CreatedAt:    d.clock.Now(),
 	}
 
 	if err := d.state.AddAgent(repoName, cfg.agentName, agent); err != nil {
@@ -1939,6 +3786,102 @@ func (d *Daemon) startAgentWithConfig(repoName string, repo *state.Repository, c
 	return nil
 }
 
+// hostsWindows returns true if the daemon's multiplexer keeps its windows
+// in the daemon process, where only the daemon can create or kill them
+func (d *Daemon) hostsWindows() bool {
+	return mux.Backend(d.tmux) != mux.BackendTmux
+}
+
+// launchTemplate loads and checks the repo's launch template for an agent
+func (d *Daemon) launchTemplate(repoName string, cfg agentStartConfig) (launch.Template, error) {
+	template, err := launch.Load(d.paths.RepoDir(repoName), string(cfg.agentType))
+	if err != nil {
+		return launch.Template{}, err
+	}
+	if err := template.Validate(cfg.workDir); err != nil {
+		return launch.Template{}, fmt.Errorf("invalid %s in %s: %w", launch.ConfigFile, repoName, err)
+	}
+	return template, nil
+}
+
+// launchClaude types the command starting Claude into an agent's window,
+// then its initial message, and returns Claude's PID
+func (d *Daemon) launchClaude(repoName string, repo *state.Repository, cfg agentStartConfig, sessionID string, template launch.Template) (int, error) {
+	// Skip actual Claude startup in test mode
+	if os.Getenv("MULTICLAUDE_TEST_MODE") == "1" {
+		return 0, nil
+	}
+
+	// Resolve claude binary path
+	binaryPath, err := d.getClaudeBinaryPath()
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve claude binary: %w", err)
+	}
+
+	// Record the window's output from Claude's launch onwards
+	d.startTranscript(repoName, repo.TmuxSession, cfg.agentName, cfg.agentType)
+
+	// Build CLI command
+	flags := fmt.Sprintf("--session-id %s --dangerously-skip-permissions --append-system-prompt-file %s",
+		sessionID, cfg.promptFile)
+	claudeCmd := template.Command(binaryPath, cfg.workDir, flags)
+
+	// Type the command into the agent's window
+	if err := d.tmux.SendKeys(d.ctx, repo.TmuxSession, cfg.agentName, claudeCmd); err != nil {
+		return 0, fmt.Errorf("failed to start Claude in tmux: %w", err)
+	}
+
+	// Wait a moment for Claude to start
+	time.Sleep(500 * time.Millisecond)
+
+	// Get PID
+	pid, err := d.tmux.GetPanePID(d.ctx, repo.TmuxSession, cfg.agentName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get Claude PID: %w", err)
+	}
+
+	if cfg.initialMessage != "" {
+		// Give Claude time to be ready for input
+		time.Sleep(1 * time.Second)
+		if err := d.tmux.SendKeysLiteralWithEnter(d.ctx, repo.TmuxSession, cfg.agentName, cfg.initialMessage); err != nil {
+			return pid, fmt.Errorf("failed to send initial message to Claude: %w", err)
+		}
+	}
+	return pid, nil
+}
+
+// startAgentWindow creates an agent's window in the daemon's multiplexer,
+// and its session if that is missing, then starts Claude in it with the
+// caller's session ID. The CLI can only create tmux windows, so agents it
+// registers under another multiplexer are started this way. Returns
+// Claude's PID.
+func (d *Daemon) startAgentWindow(repoName string, repo *state.Repository, cfg agentStartConfig, sessionID string) (int, error) {
+	template, err := d.launchTemplate(repoName, cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	hasSession, err := d.tmux.HasSession(d.ctx, repo.TmuxSession)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check session: %w", err)
+	}
+	if hasSession {
+		err = d.tmux.CreateWindowAt(d.ctx, repo.TmuxSession, cfg.agentName, cfg.workDir)
+	} else {
+		err = d.tmux.CreateSessionAt(d.ctx, repo.TmuxSession, cfg.agentName, cfg.workDir)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to create window: %w", err)
+	}
+
+	pid, err := d.launchClaude(repoName, repo, cfg, sessionID, template)
+	if err != nil {
+		d.tmux.KillWindow(d.ctx, repo.TmuxSession, cfg.agentName)
+		return 0, err
+	}
+	return pid, nil
+}
+
 // startAgent starts a Claude agent in a tmux window and registers it with state
 func (d *Daemon) startAgent(repoName string, repo *state.Repository, agentName string, agentType state.AgentType, workDir string) error {
 	promptFile, err := d.writePromptFile(repoName, agentType, agentName)
@@ -1987,6 +3930,16 @@ func (d *Daemon) writePromptFileWithPrefix(repoName string, agentType state.Agen
 // It uses --resume to continue the existing session if history exists.
 // This works for all agent types: supervisor, merge-queue, workspace, workers, and review agents.
 func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, repo *state.Repository) error {
+	if agent.Headless {
+		// A headless agent is restarted by a new run resuming its session
+		d.stopHeadless(repoName, agentName)
+		if err := d.deliverHeadless(repoName, agentName, agent, headlessResumePrompt); err != nil {
+			return fmt.Errorf("failed to restart Claude: %w", err)
+		}
+		d.recordAction(repoName, feed.ActionRestarted, agentName, "headless")
+		return nil
+	}
+
 	// Check if the session has history
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -2002,23 +3955,28 @@ func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, rep
 		hasHistory = true
 	}
 
-	// Get the existing prompt file path
-	promptFile := filepath.Join(d.paths.Root, "prompts", agentName+".md")
-	if _, err := os.Stat(promptFile); os.IsNotExist(err) {
-		// Regenerate the prompt file if it doesn't exist
-		promptFile, err = d.writePromptFile(repoName, prompts.AgentType(agent.Type), agentName)
-		if err != nil {
-			return fmt.Errorf("failed to regenerate prompt file: %w", err)
-		}
+	promptFile, err := d.agentPromptFile(repoName, agentName, agent)
+	if err != nil {
+		return err
+	}
+
+	template, err := launch.Load(d.paths.RepoDir(repoName), string(agent.Type))
+	if err != nil {
+		return err
+	}
+	if err := template.Validate(agent.WorktreePath); err != nil {
+		return fmt.Errorf("invalid %s in %s: %w", launch.ConfigFile, repoName, err)
 	}
 
 	// Restart Claude using the runner
 	// Note: Slash commands are embedded in prompts, not via CLAUDE_CONFIG_DIR
-	result, err := d.claudeRunner.Start(d.ctx, repo.TmuxSession, agentName, claude.Config{
+	claudeCfg := claude.Config{
 		SessionID:        agent.SessionID,
 		Resume:           hasHistory,
 		SystemPromptFile: promptFile,
-	})
+	}
+	template.Apply(&claudeCfg, agent.WorktreePath)
+	result, err := d.claudeRunner.Start(d.ctx, repo.TmuxSession, agentName, claudeCfg)
 	if err != nil {
 		return fmt.Errorf("failed to restart Claude: %w", err)
 	}
@@ -2029,9 +3987,23 @@ func (d *Daemon) restartAgent(repoName, agentName string, agent state.Agent, rep
 	}
 
 	d.logger.Info("Restarted agent %s with PID %d (resumed=%v)", agentName, result.PID, hasHistory)
+	d.recordAction(repoName, feed.ActionRestarted, agentName, fmt.Sprintf("PID %d", result.PID))
 	return nil
 }
 
+// agentPromptFile returns the path of the prompt file an agent was started
+// with, regenerating it if it is gone
+func (d *Daemon) agentPromptFile(repoName, agentName string, agent state.Agent) (string, error) {
+	promptFile := filepath.Join(d.paths.Root, "prompts", agentName+".md")
+	if _, err := os.Stat(promptFile); os.IsNotExist(err) {
+		promptFile, err = d.writePromptFile(repoName, prompts.AgentType(agent.Type), agentName)
+		if err != nil {
+			return "", fmt.Errorf("failed to regenerate prompt file: %w", err)
+		}
+	}
+	return promptFile, nil
+}
+
 // writePromptFile writes the agent prompt to a file and returns the path
 func (d *Daemon) writePromptFile(repoName string, agentType state.AgentType, agentName string) (string, error) {
 	return d.writePromptFileWithPrefix(repoName, agentType, agentName, "")
@@ -2058,13 +4030,13 @@ func appendToSliceMap(m map[string][]string, key, value string) {
 }
 
 // Run runs the daemon in the foreground
-func Run() error {
+func Run(opts ...Option) error {
 	paths, err := config.DefaultPaths()
 	if err != nil {
 		return fmt.Errorf("failed to get paths: %w", err)
 	}
 
-	d, err := New(paths)
+	d, err := New(paths, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to create daemon: %w", err)
 	}
@@ -2079,8 +4051,9 @@ func Run() error {
 	return nil
 }
 
-// RunDetached starts the daemon in detached mode
-func RunDetached() error {
+// RunDetached starts the daemon in detached mode. runArgs are passed on to
+// the foreground daemon command.
+func RunDetached(runArgs ...string) error {
 	paths, err := config.DefaultPaths()
 	if err != nil {
 		return fmt.Errorf("failed to get paths: %w", err)
@@ -2122,7 +4095,8 @@ func RunDetached() error {
 	}
 
 	// Start daemon process
-	process, err := os.StartProcess(executable, []string{executable, "daemon", "_run"}, attr)
+	argv := append([]string{executable, "daemon", "_run"}, runArgs...)
+	process, err := os.StartProcess(executable, argv, attr)
 	if err != nil {
 		return fmt.Errorf("failed to start daemon process: %w", err)
 	}
@@ -2159,6 +4133,7 @@ func (d *Daemon) rotateLogsIfNeeded() {
 				d.logger.Error("Failed to rotate log %s: %v", path, err)
 			} else {
 				d.logger.Info("Rotated log %s (was %d bytes)", path, info.Size())
+				d.reopenTranscript(path)
 			}
 		}
 		return nil
@@ -2172,16 +4147,15 @@ func (d *Daemon) rotateLogsIfNeeded() {
 // rotateLog rotates a single log file by renaming it with a timestamp suffix
 func (d *Daemon) rotateLog(logPath string) error {
 	// Generate rotated filename with timestamp
-	timestamp := time.Now().Format("20060102-150405")
-	rotatedPath := logPath + "." + timestamp
+	rotatedPath := logPath + "." + time.Now().Format(logstore.SegmentTimeFormat)
 
 	// Rename the current log file
 	if err := os.Rename(logPath, rotatedPath); err != nil {
 		return fmt.Errorf("failed to rename log: %w", err)
 	}
 
-	// The tmux pipe-pane will create a new file automatically when it next writes
-	// No need to recreate the file or restart the pipe
+	// The agent's pipe still writes to the renamed file; rotateLogsIfNeeded
+	// restarts it so a new log file is created
 
 	return nil
 }
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,12 +10,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dlorenc/multiclaude/internal/clock"
 	"github.com/dlorenc/multiclaude/internal/hooks"
 	"github.com/dlorenc/multiclaude/internal/messages"
 	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/repair"
 	"github.com/dlorenc/multiclaude/internal/socket"
 	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
 	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/events"
 	"github.com/dlorenc/multiclaude/pkg/tmux"
 )
 
@@ -173,7 +178,7 @@ func TestCleanupDeadAgents(t *testing.T) {
 	}
 
 	// Call cleanup
-	d.cleanupDeadAgents(deadAgents)
+	d.cleanupDeadAgents(deadAgents, "health_check")
 
 	// Verify agent was removed
 	_, exists = d.state.GetAgent("test-repo", "test-agent")
@@ -209,7 +214,7 @@ func TestHandleCompleteAgent(t *testing.T) {
 	}
 
 	// Test missing repo argument
-	resp := d.handleCompleteAgent(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "complete_agent",
 		Args: map[string]interface{}{
 			"agent": "test-agent",
@@ -220,7 +225,7 @@ func TestHandleCompleteAgent(t *testing.T) {
 	}
 
 	// Test missing agent argument
-	resp = d.handleCompleteAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "complete_agent",
 		Args: map[string]interface{}{
 			"repo": "test-repo",
@@ -231,7 +236,7 @@ func TestHandleCompleteAgent(t *testing.T) {
 	}
 
 	// Test non-existent agent
-	resp = d.handleCompleteAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "complete_agent",
 		Args: map[string]interface{}{
 			"repo":  "test-repo",
@@ -243,7 +248,7 @@ func TestHandleCompleteAgent(t *testing.T) {
 	}
 
 	// Test successful completion
-	resp = d.handleCompleteAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "complete_agent",
 		Args: map[string]interface{}{
 			"repo":  "test-repo",
@@ -289,7 +294,7 @@ func TestHandleRestartAgent(t *testing.T) {
 	}
 
 	// Test missing repo argument
-	resp := d.handleRestartAgent(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "restart_agent",
 		Args: map[string]interface{}{
 			"agent": "test-agent",
@@ -303,7 +308,7 @@ func TestHandleRestartAgent(t *testing.T) {
 	}
 
 	// Test missing agent argument
-	resp = d.handleRestartAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "restart_agent",
 		Args: map[string]interface{}{
 			"repo": "test-repo",
@@ -317,7 +322,7 @@ func TestHandleRestartAgent(t *testing.T) {
 	}
 
 	// Test non-existent agent
-	resp = d.handleRestartAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "restart_agent",
 		Args: map[string]interface{}{
 			"repo":  "test-repo",
@@ -341,7 +346,7 @@ func TestHandleRestartAgent(t *testing.T) {
 		t.Fatalf("Failed to add completed agent: %v", err)
 	}
 
-	resp = d.handleRestartAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "restart_agent",
 		Args: map[string]interface{}{
 			"repo":  "test-repo",
@@ -356,7 +361,7 @@ func TestHandleRestartAgent(t *testing.T) {
 	}
 
 	// Test non-existent repo
-	resp = d.handleRestartAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "restart_agent",
 		Args: map[string]interface{}{
 			"repo":  "non-existent-repo",
@@ -407,27 +412,21 @@ func TestHandleStatus(t *testing.T) {
 		t.Fatalf("Failed to add agent: %v", err)
 	}
 
-	resp := d.handleStatus(socket.Request{Command: "status"})
-
-	if !resp.Success {
-		t.Errorf("handleStatus() success = false, want true")
-	}
-
-	data, ok := resp.Data.(map[string]interface{})
-	if !ok {
-		t.Fatal("handleStatus() data is not a map")
+	status, err := d.handleStatus(socket.Request{}, socket.StatusArgs{})
+	if err != nil {
+		t.Fatalf("handleStatus() failed: %v", err)
 	}
 
-	if running, ok := data["running"].(bool); !ok || !running {
+	if !status.Running {
 		t.Error("handleStatus() running = false, want true")
 	}
 
-	if repos, ok := data["repos"].(int); !ok || repos != 1 {
-		t.Errorf("handleStatus() repos = %v, want 1", data["repos"])
+	if status.Repos != 1 {
+		t.Errorf("handleStatus() repos = %v, want 1", status.Repos)
 	}
 
-	if agents, ok := data["agents"].(int); !ok || agents != 1 {
-		t.Errorf("handleStatus() agents = %v, want 1", data["agents"])
+	if status.Agents != 1 {
+		t.Errorf("handleStatus() agents = %v, want 1", status.Agents)
 	}
 }
 
@@ -436,17 +435,12 @@ func TestHandleListRepos(t *testing.T) {
 	defer cleanup()
 
 	// Initially empty
-	resp := d.handleListRepos(socket.Request{Command: "list_repos"})
-	if !resp.Success {
-		t.Error("handleListRepos() success = false, want true")
-	}
-
-	repos, ok := resp.Data.([]string)
-	if !ok {
-		t.Fatal("handleListRepos() data is not a []string")
+	repos, err := d.handleListRepos(socket.Request{}, socket.ListReposArgs{})
+	if err != nil {
+		t.Fatalf("handleListRepos() failed: %v", err)
 	}
-	if len(repos) != 0 {
-		t.Errorf("handleListRepos() returned %d repos, want 0", len(repos))
+	if len(repos.Names) != 0 {
+		t.Errorf("handleListRepos() returned %d repos, want 0", len(repos.Names))
 	}
 
 	// Add repos
@@ -461,17 +455,12 @@ func TestHandleListRepos(t *testing.T) {
 		}
 	}
 
-	resp = d.handleListRepos(socket.Request{Command: "list_repos"})
-	if !resp.Success {
-		t.Error("handleListRepos() success = false, want true")
-	}
-
-	repos, ok = resp.Data.([]string)
-	if !ok {
-		t.Fatal("handleListRepos() data is not a []string")
+	repos, err = d.handleListRepos(socket.Request{}, socket.ListReposArgs{})
+	if err != nil {
+		t.Fatalf("handleListRepos() failed: %v", err)
 	}
-	if len(repos) != 2 {
-		t.Errorf("handleListRepos() returned %d repos, want 2", len(repos))
+	if len(repos.Names) != 2 {
+		t.Errorf("handleListRepos() returned %d repos, want 2", len(repos.Names))
 	}
 }
 
@@ -480,7 +469,7 @@ func TestHandleAddRepo(t *testing.T) {
 	defer cleanup()
 
 	// Missing name
-	resp := d.handleAddRepo(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "add_repo",
 		Args: map[string]interface{}{
 			"github_url":   "https://github.com/test/repo",
@@ -492,7 +481,7 @@ func TestHandleAddRepo(t *testing.T) {
 	}
 
 	// Missing github_url
-	resp = d.handleAddRepo(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "add_repo",
 		Args: map[string]interface{}{
 			"name":         "test-repo",
@@ -504,7 +493,7 @@ func TestHandleAddRepo(t *testing.T) {
 	}
 
 	// Missing tmux_session
-	resp = d.handleAddRepo(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "add_repo",
 		Args: map[string]interface{}{
 			"name":       "test-repo",
@@ -516,7 +505,7 @@ func TestHandleAddRepo(t *testing.T) {
 	}
 
 	// Valid request
-	resp = d.handleAddRepo(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "add_repo",
 		Args: map[string]interface{}{
 			"name":         "test-repo",
@@ -550,7 +539,7 @@ func TestHandleRemoveRepo(t *testing.T) {
 	}
 
 	// Missing name
-	resp := d.handleRemoveRepo(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "remove_repo",
 		Args:    map[string]interface{}{},
 	})
@@ -559,7 +548,7 @@ func TestHandleRemoveRepo(t *testing.T) {
 	}
 
 	// Non-existent repo
-	resp = d.handleRemoveRepo(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "remove_repo",
 		Args: map[string]interface{}{
 			"name": "nonexistent",
@@ -570,7 +559,7 @@ func TestHandleRemoveRepo(t *testing.T) {
 	}
 
 	// Valid request
-	resp = d.handleRemoveRepo(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "remove_repo",
 		Args: map[string]interface{}{
 			"name": "test-repo",
@@ -602,7 +591,7 @@ func TestHandleAddAgent(t *testing.T) {
 	}
 
 	// Missing repo
-	resp := d.handleAddAgent(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "add_agent",
 		Args: map[string]interface{}{
 			"agent":         "test-agent",
@@ -616,7 +605,7 @@ func TestHandleAddAgent(t *testing.T) {
 	}
 
 	// Missing agent name
-	resp = d.handleAddAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "add_agent",
 		Args: map[string]interface{}{
 			"repo":          "test-repo",
@@ -630,7 +619,7 @@ func TestHandleAddAgent(t *testing.T) {
 	}
 
 	// Valid request with PID as float64 (JSON default)
-	resp = d.handleAddAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "add_agent",
 		Args: map[string]interface{}{
 			"repo":          "test-repo",
@@ -685,7 +674,7 @@ func TestHandleRemoveAgent(t *testing.T) {
 	}
 
 	// Missing repo
-	resp := d.handleRemoveAgent(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "remove_agent",
 		Args: map[string]interface{}{
 			"agent": "test-agent",
@@ -696,7 +685,7 @@ func TestHandleRemoveAgent(t *testing.T) {
 	}
 
 	// Missing agent
-	resp = d.handleRemoveAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "remove_agent",
 		Args: map[string]interface{}{
 			"repo": "test-repo",
@@ -707,7 +696,7 @@ func TestHandleRemoveAgent(t *testing.T) {
 	}
 
 	// Valid request
-	resp = d.handleRemoveAgent(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "remove_agent",
 		Args: map[string]interface{}{
 			"repo":  "test-repo",
@@ -740,7 +729,7 @@ func TestHandleListAgents(t *testing.T) {
 	}
 
 	// Missing repo
-	resp := d.handleListAgents(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "list_agents",
 		Args:    map[string]interface{}{},
 	})
@@ -749,7 +738,7 @@ func TestHandleListAgents(t *testing.T) {
 	}
 
 	// Valid request (empty)
-	resp = d.handleListAgents(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "list_agents",
 		Args: map[string]interface{}{
 			"repo": "test-repo",
@@ -759,12 +748,12 @@ func TestHandleListAgents(t *testing.T) {
 		t.Errorf("handleListAgents() failed: %s", resp.Error)
 	}
 
-	agents, ok := resp.Data.([]map[string]interface{})
+	agents, ok := resp.Data.(socket.AgentList)
 	if !ok {
-		t.Fatal("handleListAgents() data is not []map[string]interface{}")
+		t.Fatal("handleListAgents() data is not socket.AgentList")
 	}
-	if len(agents) != 0 {
-		t.Errorf("handleListAgents() returned %d agents, want 0", len(agents))
+	if len(agents.Agents) != 0 {
+		t.Errorf("handleListAgents() returned %d agents, want 0", len(agents.Agents))
 	}
 
 	// Add agents
@@ -782,7 +771,7 @@ func TestHandleListAgents(t *testing.T) {
 		}
 	}
 
-	resp = d.handleListAgents(socket.Request{
+	resp = d.handleRequest(socket.Request{
 		Command: "list_agents",
 		Args: map[string]interface{}{
 			"repo": "test-repo",
@@ -792,12 +781,12 @@ func TestHandleListAgents(t *testing.T) {
 		t.Errorf("handleListAgents() failed: %s", resp.Error)
 	}
 
-	agents, ok = resp.Data.([]map[string]interface{})
+	agents, ok = resp.Data.(socket.AgentList)
 	if !ok {
-		t.Fatal("handleListAgents() data is not []map[string]interface{}")
+		t.Fatal("handleListAgents() data is not socket.AgentList")
 	}
-	if len(agents) != 2 {
-		t.Errorf("handleListAgents() returned %d agents, want 2", len(agents))
+	if len(agents.Agents) != 2 {
+		t.Errorf("handleListAgents() returned %d agents, want 2", len(agents.Agents))
 	}
 }
 
@@ -1928,6 +1917,100 @@ func TestRestoreTrackedReposExistingSession(t *testing.T) {
 	}
 }
 
+func TestRestoreTrackedReposReport(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	recorder := &recordingAdapter{}
+	d.RegisterAdapter(recorder)
+
+	if d.getLastRestore() != nil {
+		t.Fatal("no report should exist before restoring")
+	}
+
+	// "locked" is managed by another daemon; "vanished" lost its session and
+	// its clone, so it can't be restored
+	for _, name := range []string{"locked", "vanished"} {
+		d.state.AddRepo(name, &state.Repository{
+			TmuxSession: "mc-test-report-" + name,
+			Agents: map[string]state.Agent{
+				"worker": {Type: state.AgentTypeWorker, TmuxWindow: "worker"},
+			},
+		})
+	}
+	d.foreignLocks["locked"] = worktree.LockOwner{Host: "elsewhere", PID: 4242, Root: d.paths.Root}
+
+	d.restoreTrackedRepos()
+
+	report := d.getLastRestore()
+	if report == nil {
+		t.Fatal("restoreTrackedRepos should record a report")
+	}
+	if report.StartedAt.IsZero() || report.FinishedAt.Before(report.StartedAt) {
+		t.Errorf("bad report times %v - %v", report.StartedAt, report.FinishedAt)
+	}
+	if len(report.Repos) != 2 {
+		t.Fatalf("report has %d repos, want 2: %+v", len(report.Repos), report.Repos)
+	}
+	if got := report.Repos[0]; got.Repo != "locked" || got.Action != restoreSkipped || !strings.Contains(got.Reason, "elsewhere") {
+		t.Errorf("locked repo = %+v, want skipped because another daemon manages it", got)
+	}
+	if got := report.Repos[1]; got.Repo != "vanished" || got.Action != restoreRepoFailed || !strings.Contains(got.Reason, "does not exist") {
+		t.Errorf("vanished repo = %+v, want failed because its clone is gone", got)
+	}
+
+	if len(recorder.events) != 1 || recorder.events[0].Type != events.EventDaemonRestored {
+		t.Fatalf("expected one daemon.restored event, got %+v", recorder.events)
+	}
+	if recorder.events[0].Priority != events.PriorityNormal {
+		t.Errorf("a failed restore should raise the priority, got %s", recorder.events[0].Priority)
+	}
+	if !strings.Contains(recorder.events[0].Message, "vanished: failed") {
+		t.Errorf("event message %q should summarize each repo", recorder.events[0].Message)
+	}
+
+	if status, _ := d.handleStatus(socket.Request{}, socket.StatusArgs{}); status.LastRestore == nil {
+		t.Error("status should include the last restore report")
+	}
+}
+
+func TestRestoreDeadAgentsReport(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	sessionName := "mc-test-restore-report"
+	if err := tmuxClient.CreateSession(context.Background(), sessionName, true); err != nil {
+		t.Skipf("cannot create tmux sessions in this environment: %v", err)
+	}
+	defer tmuxClient.KillSession(context.Background(), sessionName)
+
+	repo := &state.Repository{
+		TmuxSession: sessionName,
+		Agents: map[string]state.Agent{
+			"no-pid":   {Type: state.AgentTypeWorker, TmuxWindow: "no-pid"},
+			"windowed": {Type: state.AgentTypeWorker, TmuxWindow: "missing-window", PID: os.Getpid()},
+		},
+	}
+	got := d.restoreDeadAgents("test-repo", repo)
+	if len(got) != 2 {
+		t.Fatalf("got %+v, want one entry per agent", got)
+	}
+	for _, agent := range got {
+		if agent.Outcome != restoreLeftToCleanup || agent.Error == "" {
+			t.Errorf("%s: outcome %q (%q), want left to the health check with a reason", agent.Name, agent.Outcome, agent.Error)
+		}
+	}
+}
+
 func TestRestoreRepoAgentsMissingRepoPath(t *testing.T) {
 	d, cleanup := setupTestDaemon(t)
 	defer cleanup()
@@ -1939,7 +2022,7 @@ func TestRestoreRepoAgentsMissingRepoPath(t *testing.T) {
 		Agents:      make(map[string]state.Agent),
 	}
 
-	err := d.restoreRepoAgents("nonexistent-repo", repo)
+	_, err := d.restoreRepoAgents("nonexistent-repo", repo)
 	if err == nil {
 		t.Error("restoreRepoAgents should fail when repo path doesn't exist")
 	}
@@ -2163,7 +2246,7 @@ func TestHandleGetRepoConfigMissingName(t *testing.T) {
 	d, cleanup := setupTestDaemon(t)
 	defer cleanup()
 
-	resp := d.handleGetRepoConfig(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "get_repo_config",
 		Args:    map[string]interface{}{},
 	})
@@ -2180,7 +2263,7 @@ func TestHandleGetRepoConfigNonexistentRepo(t *testing.T) {
 	d, cleanup := setupTestDaemon(t)
 	defer cleanup()
 
-	resp := d.handleGetRepoConfig(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "get_repo_config",
 		Args: map[string]interface{}{
 			"name": "nonexistent",
@@ -2213,7 +2296,7 @@ func TestHandleGetRepoConfigSuccess(t *testing.T) {
 		t.Fatalf("Failed to add repo: %v", err)
 	}
 
-	resp := d.handleGetRepoConfig(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "get_repo_config",
 		Args: map[string]interface{}{
 			"name": "test-repo",
@@ -2224,16 +2307,16 @@ func TestHandleGetRepoConfigSuccess(t *testing.T) {
 		t.Errorf("handleGetRepoConfig() failed: %s", resp.Error)
 	}
 
-	data, ok := resp.Data.(map[string]interface{})
+	data, ok := resp.Data.(socket.RepoConfig)
 	if !ok {
-		t.Fatal("Response data should be a map")
+		t.Fatalf("Response data should be socket.RepoConfig, got %T", resp.Data)
 	}
 
-	if data["mq_enabled"] != true {
-		t.Errorf("mq_enabled = %v, want true", data["mq_enabled"])
+	if !data.MQEnabled {
+		t.Errorf("mq_enabled = %v, want true", data.MQEnabled)
 	}
-	if data["mq_track_mode"] != "author" {
-		t.Errorf("mq_track_mode = %v, want 'author'", data["mq_track_mode"])
+	if data.MQTrackMode != "author" {
+		t.Errorf("mq_track_mode = %v, want 'author'", data.MQTrackMode)
 	}
 }
 
@@ -2251,7 +2334,7 @@ func TestHandleUpdateRepoConfigInvalidTrackMode(t *testing.T) {
 		t.Fatalf("Failed to add repo: %v", err)
 	}
 
-	resp := d.handleUpdateRepoConfig(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "update_repo_config",
 		Args: map[string]interface{}{
 			"name":          "test-repo",
@@ -2283,7 +2366,7 @@ func TestHandleUpdateRepoConfigSuccess(t *testing.T) {
 	}
 
 	// Update config
-	resp := d.handleUpdateRepoConfig(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "update_repo_config",
 		Args: map[string]interface{}{
 			"name":          "test-repo",
@@ -2306,6 +2389,78 @@ func TestHandleUpdateRepoConfigSuccess(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateRepoConfigGroups(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	for _, name := range []string{"api", "billing", "web"} {
+		repo := &state.Repository{
+			GithubURL:   "https://github.com/test/" + name,
+			TmuxSession: "mc-" + name,
+			Agents:      make(map[string]state.Agent),
+		}
+		if err := d.state.AddRepo(name, repo); err != nil {
+			t.Fatalf("Failed to add repo: %v", err)
+		}
+	}
+
+	for _, name := range []string{"api", "billing"} {
+		resp := d.handleRequest(socket.Request{
+			Command: "update_repo_config",
+			Args: map[string]interface{}{
+				"name":   name,
+				"groups": []interface{}{"Payments", "payments", "backend"},
+			},
+		})
+		if !resp.Success {
+			t.Fatalf("handleUpdateRepoConfig() failed: %s", resp.Error)
+		}
+	}
+
+	repo, _ := d.state.GetRepo("api")
+	if len(repo.Groups) != 2 || repo.Groups[0] != "payments" || repo.Groups[1] != "backend" {
+		t.Errorf("Groups = %v, want [payments backend]", repo.Groups)
+	}
+
+	list, _ := d.handleListRepos(socket.Request{}, socket.ListReposArgs{Group: "payments"})
+	if len(list.Names) != 2 {
+		t.Errorf("list_repos --group payments returned %v, want api and billing", list.Names)
+	}
+
+	config, err := d.handleGetRepoConfig(socket.Request{}, socket.RepoNameArgs{Name: "api"})
+	if err != nil || len(config.Groups) != 2 {
+		t.Errorf("get_repo_config groups = %v (%v)", config.Groups, err)
+	}
+
+	// Invalid names are rejected
+	resp := d.handleRequest(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":   "web",
+			"groups": []interface{}{"bad group!"},
+		},
+	})
+	if resp.Success {
+		t.Error("Should reject invalid group name")
+	}
+
+	// An empty list clears the groups
+	resp = d.handleRequest(socket.Request{
+		Command: "update_repo_config",
+		Args: map[string]interface{}{
+			"name":   "api",
+			"groups": []interface{}{},
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleUpdateRepoConfig() failed: %s", resp.Error)
+	}
+	repo, _ = d.state.GetRepo("api")
+	if len(repo.Groups) != 0 {
+		t.Errorf("Groups should be cleared, got %v", repo.Groups)
+	}
+}
+
 func TestHandleListReposRichFormat(t *testing.T) {
 	tmuxClient := tmux.NewClient()
 	d, cleanup := setupTestDaemon(t)
@@ -2341,7 +2496,7 @@ func TestHandleListReposRichFormat(t *testing.T) {
 	}
 
 	// Request rich format
-	resp := d.handleListRepos(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "list_repos",
 		Args: map[string]interface{}{
 			"rich": true,
@@ -2352,28 +2507,28 @@ func TestHandleListReposRichFormat(t *testing.T) {
 		t.Errorf("handleListRepos(rich) failed: %s", resp.Error)
 	}
 
-	data, ok := resp.Data.([]map[string]interface{})
-	if !ok {
-		t.Fatal("Rich response should be []map[string]interface{}")
+	data, ok := resp.Data.(socket.RepoList)
+	if !ok || !data.Rich {
+		t.Fatalf("Rich response should be a rich socket.RepoList, got %v", resp.Data)
 	}
 
-	if len(data) != 1 {
-		t.Fatalf("Expected 1 repo, got %d", len(data))
+	if len(data.Repos) != 1 {
+		t.Fatalf("Expected 1 repo, got %d", len(data.Repos))
 	}
 
-	repoData := data[0]
-	if repoData["name"] != "test-repo" {
-		t.Errorf("name = %v, want 'test-repo'", repoData["name"])
+	repoData := data.Repos[0]
+	if repoData.Name != "test-repo" {
+		t.Errorf("name = %v, want 'test-repo'", repoData.Name)
 	}
-	if repoData["total_agents"].(int) != 1 {
-		t.Errorf("total_agents = %v, want 1", repoData["total_agents"])
+	if repoData.TotalAgents != 1 {
+		t.Errorf("total_agents = %v, want 1", repoData.TotalAgents)
 	}
-	if repoData["worker_count"].(int) != 1 {
-		t.Errorf("worker_count = %v, want 1", repoData["worker_count"])
+	if repoData.WorkerCount != 1 {
+		t.Errorf("worker_count = %v, want 1", repoData.WorkerCount)
 	}
 
 	// session_healthy should match whether we created a real session
-	if sessionExists && !repoData["session_healthy"].(bool) {
+	if sessionExists && !repoData.SessionHealthy {
 		t.Error("session_healthy should be true when session exists")
 	}
 }
@@ -2556,7 +2711,7 @@ func TestHandleTaskHistoryEmptyHistory(t *testing.T) {
 	}
 
 	// Should return empty array
-	data, ok := resp.Data.([]map[string]interface{})
+	data, ok := resp.Data.([]state.TaskHistoryEntry)
 	if !ok {
 		t.Errorf("handleTaskHistory() data should be array, got %T", resp.Data)
 	}
@@ -2687,21 +2842,18 @@ func TestHandleListAgentsMixed(t *testing.T) {
 	}
 
 	// Verify both agents are returned
-	data, ok := resp.Data.([]map[string]interface{})
+	data, ok := resp.Data.(socket.AgentList)
 	if !ok {
-		t.Fatalf("list_agents data should be []map[string]interface{}, got %T", resp.Data)
+		t.Fatalf("list_agents data should be socket.AgentList, got %T", resp.Data)
 	}
-	if len(data) != 2 {
-		t.Errorf("list_agents should return 2 agents, got %d", len(data))
+	if len(data.Agents) != 2 {
+		t.Errorf("list_agents should return 2 agents, got %d", len(data.Agents))
 	}
 
 	// Verify agent types are present
 	types := make(map[string]bool)
-	for _, agent := range data {
-		// Type is stored as state.AgentType which is a string alias
-		if agentType, ok := agent["type"].(state.AgentType); ok {
-			types[string(agentType)] = true
-		}
+	for _, agent := range data.Agents {
+		types[string(agent.Type)] = true
 	}
 	if !types["worker"] {
 		t.Error("list_agents should include worker agent")
@@ -2884,7 +3036,7 @@ func TestHandleSpawnAgent(t *testing.T) {
 				"class": "ephemeral",
 			},
 			wantSuccess: false,
-			wantError:   "prompt text is required",
+			wantError:   "prompt text or a definition name is required",
 		},
 		{
 			name:      "invalid class value",
@@ -2954,7 +3106,7 @@ func TestHandleSpawnAgent(t *testing.T) {
 				}
 			}
 
-			resp := d.handleSpawnAgent(socket.Request{
+			resp := d.handleRequest(socket.Request{
 				Command: "spawn_agent",
 				Args:    tt.args,
 			})
@@ -3177,8 +3329,8 @@ A test worker agent for unit testing.
 		if !strings.Contains(msgContent.Body, "multiclaude agents spawn") {
 			t.Error("Message should include spawn command")
 		}
-		if !strings.Contains(msgContent.Body, "--class <persistent|ephemeral>") {
-			t.Error("Message should include class flag in spawn command")
+		if !strings.Contains(msgContent.Body, "--class ephemeral --definition <definition-name>") {
+			t.Error("Message should include class and definition flags in spawn command")
 		}
 	})
 }
@@ -3263,7 +3415,7 @@ func TestHandleListAgentsRichFormat(t *testing.T) {
 	}
 
 	t.Run("lists agents without rich format", func(t *testing.T) {
-		resp := d.handleListAgents(socket.Request{
+		resp := d.handleRequest(socket.Request{
 			Command: "list_agents",
 			Args: map[string]interface{}{
 				"repo": "test-repo",
@@ -3274,20 +3426,17 @@ func TestHandleListAgentsRichFormat(t *testing.T) {
 			t.Errorf("Expected success, got error: %s", resp.Error)
 		}
 
-		data, ok := resp.Data.([]map[string]interface{})
-		if !ok {
-			t.Fatal("Expected slice of maps")
-		}
-		if len(data) != 1 {
-			t.Errorf("Expected 1 agent, got %d", len(data))
+		data, ok := resp.Data.(socket.AgentList)
+		if !ok || len(data.Agents) != 1 {
+			t.Fatalf("Expected 1 agent, got %v", resp.Data)
 		}
-		if data[0]["name"] != "test-agent" {
-			t.Errorf("Expected agent name 'test-agent', got %v", data[0]["name"])
+		if data.Agents[0].Name != "test-agent" {
+			t.Errorf("Expected agent name 'test-agent', got %v", data.Agents[0].Name)
 		}
 	})
 
 	t.Run("lists agents with rich format", func(t *testing.T) {
-		resp := d.handleListAgents(socket.Request{
+		resp := d.handleRequest(socket.Request{
 			Command: "list_agents",
 			Args: map[string]interface{}{
 				"repo": "test-repo",
@@ -3299,31 +3448,30 @@ func TestHandleListAgentsRichFormat(t *testing.T) {
 			t.Errorf("Expected success, got error: %s", resp.Error)
 		}
 
-		data, ok := resp.Data.([]map[string]interface{})
-		if !ok {
-			t.Fatal("Expected slice of maps")
-		}
-		if len(data) != 1 {
-			t.Errorf("Expected 1 agent, got %d", len(data))
+		list, ok := resp.Data.(socket.AgentList)
+		if !ok || len(list.Agents) != 1 {
+			t.Fatalf("Expected 1 agent, got %v", resp.Data)
 		}
 
-		// Rich format should include status and message counts
-		if _, hasStatus := data[0]["status"]; !hasStatus {
-			t.Error("Rich format should include status")
-		}
-		if _, hasBranch := data[0]["branch"]; !hasBranch {
-			t.Error("Rich format should include branch")
+		// Rich format should include status and message counts, even when
+		// they're zero
+		encoded, err := json.Marshal(list)
+		if err != nil {
+			t.Fatalf("Failed to encode agents: %v", err)
 		}
-		if _, hasTotal := data[0]["messages_total"]; !hasTotal {
-			t.Error("Rich format should include messages_total")
+		var data []map[string]interface{}
+		if err := json.Unmarshal(encoded, &data); err != nil {
+			t.Fatalf("Failed to decode agents: %v", err)
 		}
-		if _, hasPending := data[0]["messages_pending"]; !hasPending {
-			t.Error("Rich format should include messages_pending")
+		for _, key := range []string{"status", "branch", "messages_total", "messages_pending"} {
+			if _, ok := data[0][key]; !ok {
+				t.Errorf("Rich format should include %s", key)
+			}
 		}
 	})
 
 	t.Run("returns error for missing repo", func(t *testing.T) {
-		resp := d.handleListAgents(socket.Request{
+		resp := d.handleRequest(socket.Request{
 			Command: "list_agents",
 			Args:    map[string]interface{}{},
 		})
@@ -3361,7 +3509,22 @@ func TestHandleRepairState(t *testing.T) {
 		t.Fatalf("Failed to add agent: %v", err)
 	}
 
-	resp := d.handleRepairState(socket.Request{
+	dryRun := d.handleRequest(socket.Request{
+		Command: "repair_state",
+		Args:    map[string]interface{}{"dry_run": true},
+	})
+	if !dryRun.Success {
+		t.Fatalf("Dry run failed: %s", dryRun.Error)
+	}
+	planned := dryRun.Data.(socket.StateRepair).Issues
+	if len(planned) != 1 || planned[0].Agent != "test-agent" || planned[0].Action != repair.ActionDrop {
+		t.Errorf("Dry run planned %+v, want test-agent dropped", planned)
+	}
+	if _, exists := d.state.GetAgent("test-repo", "test-agent"); !exists {
+		t.Error("Dry run must not change state")
+	}
+
+	resp := d.handleRequest(socket.Request{
 		Command: "repair_state",
 	})
 
@@ -3369,18 +3532,21 @@ func TestHandleRepairState(t *testing.T) {
 		t.Errorf("Expected success, got error: %s", resp.Error)
 	}
 
-	data, ok := resp.Data.(map[string]interface{})
+	data, ok := resp.Data.(socket.StateRepair)
 	if !ok {
-		t.Fatal("Expected map response")
+		t.Fatalf("Expected socket.StateRepair, got %T", resp.Data)
 	}
 
 	// Should have processed the repair (agent with nonexistent session)
-	if _, hasRemoved := data["agents_removed"]; !hasRemoved {
+	if data.AgentsRemoved == nil {
 		t.Error("Response should include agents_removed")
 	}
-	if _, hasFixed := data["issues_fixed"]; !hasFixed {
+	if data.IssuesFixed == nil {
 		t.Error("Response should include issues_fixed")
 	}
+	if _, exists := d.state.GetAgent("test-repo", "test-agent"); exists {
+		t.Error("Agent without worktree or branch should have been dropped")
+	}
 }
 
 // TestHandleTaskHistoryExtended tests handleTaskHistory with various scenarios
@@ -3414,7 +3580,7 @@ func TestHandleTaskHistoryExtended(t *testing.T) {
 	}
 
 	t.Run("returns error for missing repo", func(t *testing.T) {
-		resp := d.handleTaskHistory(socket.Request{
+		resp := d.handleRequest(socket.Request{
 			Command: "task_history",
 			Args:    map[string]interface{}{},
 		})
@@ -3425,7 +3591,7 @@ func TestHandleTaskHistoryExtended(t *testing.T) {
 	})
 
 	t.Run("returns error for nonexistent repo", func(t *testing.T) {
-		resp := d.handleTaskHistory(socket.Request{
+		resp := d.handleRequest(socket.Request{
 			Command: "task_history",
 			Args: map[string]interface{}{
 				"repo": "nonexistent-repo",
@@ -3438,7 +3604,7 @@ func TestHandleTaskHistoryExtended(t *testing.T) {
 	})
 
 	t.Run("returns task history", func(t *testing.T) {
-		resp := d.handleTaskHistory(socket.Request{
+		resp := d.handleRequest(socket.Request{
 			Command: "task_history",
 			Args: map[string]interface{}{
 				"repo": "history-repo",
@@ -3449,10 +3615,9 @@ func TestHandleTaskHistoryExtended(t *testing.T) {
 			t.Errorf("Expected success, got error: %s", resp.Error)
 		}
 
-		// Response comes as []map[string]interface{} when returned from handler
-		data, ok := resp.Data.([]map[string]interface{})
+		data, ok := resp.Data.([]state.TaskHistoryEntry)
 		if !ok {
-			t.Fatalf("Expected []map[string]interface{}, got %T", resp.Data)
+			t.Fatalf("Expected []state.TaskHistoryEntry, got %T", resp.Data)
 		}
 		if len(data) != 2 {
 			t.Errorf("Expected 2 history entries, got %d", len(data))
@@ -3460,7 +3625,7 @@ func TestHandleTaskHistoryExtended(t *testing.T) {
 	})
 
 	t.Run("limits results with limit param", func(t *testing.T) {
-		resp := d.handleTaskHistory(socket.Request{
+		resp := d.handleRequest(socket.Request{
 			Command: "task_history",
 			Args: map[string]interface{}{
 				"repo":  "history-repo",
@@ -3472,9 +3637,9 @@ func TestHandleTaskHistoryExtended(t *testing.T) {
 			t.Errorf("Expected success, got error: %s", resp.Error)
 		}
 
-		data, ok := resp.Data.([]map[string]interface{})
+		data, ok := resp.Data.([]state.TaskHistoryEntry)
 		if !ok {
-			t.Fatalf("Expected []map[string]interface{}, got %T", resp.Data)
+			t.Fatalf("Expected []state.TaskHistoryEntry, got %T", resp.Data)
 		}
 		if len(data) != 1 {
 			t.Errorf("Expected 1 history entry with limit=1, got %d", len(data))
@@ -3482,7 +3647,7 @@ func TestHandleTaskHistoryExtended(t *testing.T) {
 	})
 
 	t.Run("returns entries with correct fields", func(t *testing.T) {
-		resp := d.handleTaskHistory(socket.Request{
+		resp := d.handleRequest(socket.Request{
 			Command: "task_history",
 			Args: map[string]interface{}{
 				"repo": "history-repo",
@@ -3493,9 +3658,9 @@ func TestHandleTaskHistoryExtended(t *testing.T) {
 			t.Errorf("Expected success, got error: %s", resp.Error)
 		}
 
-		data, ok := resp.Data.([]map[string]interface{})
+		data, ok := resp.Data.([]state.TaskHistoryEntry)
 		if !ok {
-			t.Fatalf("Expected []map[string]interface{}, got %T", resp.Data)
+			t.Fatalf("Expected []state.TaskHistoryEntry, got %T", resp.Data)
 		}
 		if len(data) == 0 {
 			t.Fatal("Expected at least one entry")
@@ -3503,14 +3668,219 @@ func TestHandleTaskHistoryExtended(t *testing.T) {
 
 		// Verify entry has expected fields
 		entry := data[0]
-		if _, hasName := entry["name"]; !hasName {
+		if entry.Name == "" {
 			t.Error("Entry should have 'name' field")
 		}
-		if _, hasTask := entry["task"]; !hasTask {
+		if entry.Task == "" {
 			t.Error("Entry should have 'task' field")
 		}
-		if _, hasStatus := entry["status"]; !hasStatus {
+		if entry.Status == "" {
 			t.Error("Entry should have 'status' field")
 		}
 	})
 }
+
+func TestDetectOutputLoops(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"loopy-worker": {Type: state.AgentTypeWorker, TmuxWindow: "loopy-worker", CreatedAt: time.Now()},
+			"busy-worker":  {Type: state.AgentTypeWorker, TmuxWindow: "busy-worker", CreatedAt: time.Now()},
+		},
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	if err := os.MkdirAll(d.paths.WorkersOutputDir("test-repo"), 0755); err != nil {
+		t.Fatalf("Failed to create output dir: %v", err)
+	}
+	block := "$ npm test\nError: Cannot find module 'left-pad' required by src/index.js\n"
+	loopLog := d.paths.AgentLogFile("test-repo", "loopy-worker", true)
+	if err := os.WriteFile(loopLog, []byte(strings.Repeat(block, 6)), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	busyLog := d.paths.AgentLogFile("test-repo", "busy-worker", true)
+	var varied strings.Builder
+	for i := 0; i < 50; i++ {
+		varied.WriteString(strings.Repeat("step ", i+1) + "completed successfully\n")
+	}
+	if err := os.WriteFile(busyLog, []byte(varied.String()), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	d.TriggerOutputLoopDetection()
+
+	events := d.GetNotifyHub().Recent(0)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d: %+v", len(events), events)
+	}
+	if events[0].Type != "agent.stuck" || events[0].Agent != "loopy-worker" {
+		t.Errorf("unexpected event: %+v", events[0])
+	}
+	if !strings.Contains(events[0].Message, "left-pad") {
+		t.Errorf("event should include the repeated snippet, got %q", events[0].Message)
+	}
+
+	// Supervisor should have been told about the loop
+	msgs, err := d.getMessageManager().List("test-repo", "supervisor")
+	if err != nil || len(msgs) != 1 {
+		t.Errorf("expected 1 supervisor message, got %d (err=%v)", len(msgs), err)
+	}
+
+	// Same loop with more output should not be reported again
+	f, _ := os.OpenFile(loopLog, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(block)
+	f.Close()
+	d.TriggerOutputLoopDetection()
+	if got := len(d.GetNotifyHub().Recent(0)); got != 1 {
+		t.Errorf("loop should only be reported once, got %d events", got)
+	}
+}
+
+func TestHandleExportMetrics(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	// Use midday so the fixture doesn't straddle midnight
+	y, m, day := time.Now().Date()
+	now := time.Date(y, m, day, 12, 0, 0, 0, time.Local)
+	repo := &state.Repository{
+		GithubURL:   "https://github.com/test/repo",
+		TmuxSession: "mc-test-repo",
+		Agents: map[string]state.Agent{
+			"worker-1": {Type: state.AgentTypeWorker, CreatedAt: now},
+		},
+		TaskHistory: []state.TaskHistoryEntry{
+			{Name: "done", Status: state.TaskStatusMerged, CreatedAt: now.Add(-time.Hour), CompletedAt: now},
+		},
+	}
+	if err := d.state.AddRepo("test-repo", repo); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "export_metrics"})
+	if !resp.Success {
+		t.Fatalf("export_metrics failed: %s", resp.Error)
+	}
+
+	csvData, err := os.ReadFile(filepath.Join(d.paths.MetricsDir(), "metrics.csv"))
+	if err != nil {
+		t.Fatalf("metrics.csv not written: %v", err)
+	}
+	if !strings.Contains(string(csvData), "test-repo,2,1,0,1,1,3600") {
+		t.Errorf("unexpected metrics.csv contents:\n%s", csvData)
+	}
+	if _, err := os.Stat(filepath.Join(d.paths.MetricsDir(), "metrics.jsonl")); err != nil {
+		t.Errorf("metrics.jsonl not written: %v", err)
+	}
+
+	events := d.GetNotifyHub().Recent(0)
+	if len(events) != 1 || events[0].Type != "metrics.daily" {
+		t.Errorf("expected one metrics.daily event, got %+v", events)
+	}
+
+	if resp := d.handleRequest(socket.Request{Command: "export_metrics", Args: map[string]interface{}{"date": "not-a-date"}}); resp.Success {
+		t.Error("expected invalid date to fail")
+	}
+}
+
+func TestExportDailyMetricsIfDue(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("test-repo", &state.Repository{TmuxSession: "mc-test-repo"}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	d.exportDailyMetricsIfDue()
+	d.exportDailyMetricsIfDue()
+
+	data, err := os.ReadFile(filepath.Join(d.paths.MetricsDir(), "metrics.csv"))
+	if err != nil {
+		t.Fatalf("metrics.csv not written: %v", err)
+	}
+	// Header plus a single row: the second call is a no-op for the same day
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Errorf("expected 2 lines, got %d:\n%s", len(lines), data)
+	}
+}
+
+func TestEmitEventAddsAttachTarget(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.state.AddRepo("my-repo", &state.Repository{
+		TmuxSession: "mc-my-repo",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor"},
+			"worker-3":   {Type: state.AgentTypeWorker, TmuxWindow: "worker-3"},
+		},
+	})
+
+	d.emitEvent(events.NewEvent(events.EventAgentStuck, "my-repo", "worker-3", "stuck"))
+	d.emitEvent(events.NewEvent(events.EventMainRewritten, "my-repo", "", "main rewritten"))
+	d.emitEvent(events.NewEvent(events.EventAgentStuck, "other-repo", "worker-1", "stuck"))
+
+	events := d.notify.Recent(3)
+	if a := events[2].Attach; a == nil || a.Window != "worker-3" || a.Command != "multiclaude attach worker-3 --repo my-repo" {
+		t.Errorf("agent event attach = %+v", a)
+	}
+	if a := events[1].Attach; a == nil || a.Window != "supervisor" {
+		t.Errorf("repo event should point at the supervisor, got %+v", a)
+	}
+	if events[0].Attach != nil {
+		t.Errorf("event for an untracked repo should have no attach target, got %+v", events[0].Attach)
+	}
+}
+
+func TestPeriodicLoopFollowsClock(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	d.clock = fake
+
+	ticks := make(chan time.Time, 10)
+	d.wg.Add(1)
+	go d.periodicLoop("test", 2*time.Minute, nil, func() { ticks <- d.clock.Now() })
+	defer func() {
+		d.cancel()
+		d.wg.Wait()
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	select {
+	case <-ticks:
+		t.Fatal("loop ticked before its interval elapsed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fake.Advance(time.Minute)
+	select {
+	case now := <-ticks:
+		if want := time.Date(2024, 1, 1, 0, 2, 0, 0, time.UTC); !now.Equal(want) {
+			t.Errorf("tick ran at %v, want %v", now, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("loop did not tick after its interval elapsed")
+	}
+}
+
+func TestEmitEventUsesClock(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	d.clock = clock.NewFake(now)
+
+	d.emitEvent(events.NewEvent(events.EventAgentStuck, "my-repo", "worker-1", "stuck"))
+
+	recent := d.notify.Recent(1)
+	if len(recent) != 1 || !recent[0].Timestamp.Equal(now) {
+		t.Fatalf("event timestamp = %v, want %v", recent, now)
+	}
+}
@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// deadlineWarnFraction is the share of a time-boxed worker's budget after
+// which it is warned to start wrapping up
+const deadlineWarnFraction = 0.75
+
+// deadlineStage returns the stage a time-boxed agent should be in at now
+func deadlineStage(agent state.Agent, now time.Time) state.DeadlineStage {
+	if agent.Deadline.IsZero() {
+		return state.DeadlineStageNone
+	}
+	if !now.Before(agent.Deadline) {
+		return state.DeadlineStageExpired
+	}
+	budget := agent.Deadline.Sub(agent.CreatedAt)
+	if now.Sub(agent.CreatedAt) >= time.Duration(float64(budget)*deadlineWarnFraction) {
+		return state.DeadlineStageWarned
+	}
+	return state.DeadlineStageNone
+}
+
+// checkDeadlines warns time-boxed workers nearing their deadline and tells
+// those past it to commit, summarize, and complete
+func (d *Daemon) checkDeadlines(now time.Time) {
+	notified := false
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			if agent.Deadline.IsZero() || agent.ReadyForCleanup {
+				continue
+			}
+			stage := deadlineStage(agent, now)
+			if stage == agent.DeadlineStage || stage == state.DeadlineStageNone {
+				continue
+			}
+			// Never step back from expired, e.g. if the deadline was extended.
+			// An agent whose warning was missed while the daemon was down goes
+			// straight to the wrap-up instruction.
+			if agent.DeadlineStage == state.DeadlineStageExpired {
+				continue
+			}
+
+			if err := d.state.UpdateAgentDeadlineStage(repoName, agentName, stage); err != nil {
+				d.logger.Error("Failed to record deadline stage for %s/%s: %v", repoName, agentName, err)
+				continue
+			}
+
+			if stage == state.DeadlineStageWarned {
+				d.warnDeadline(repoName, agentName, agent, now)
+			} else {
+				d.expireDeadline(repoName, agentName, agent)
+			}
+			notified = true
+		}
+	}
+	if notified {
+		go d.routeMessages()
+	}
+}
+
+// warnDeadline tells an agent most of its time budget is used
+func (d *Daemon) warnDeadline(repoName, agentName string, agent state.Agent, now time.Time) {
+	remaining := strings.TrimSuffix(agent.Deadline.Sub(now).Round(time.Minute).String(), "0s")
+	message := fmt.Sprintf("Time check: about %s left before your deadline (%s). Start wrapping up: finish the current step, commit your work, and avoid starting anything new.",
+		remaining, agent.Deadline.Format(time.Kitchen))
+	if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, message); err != nil {
+		d.logger.Warn("Failed to send deadline warning to %s: %v", agentName, err)
+	}
+	d.logger.Info("Warned %s/%s about its deadline (%s left)", repoName, agentName, remaining)
+}
+
+// expireDeadline instructs an agent to wrap up now and emits a timeout event
+func (d *Daemon) expireDeadline(repoName, agentName string, agent state.Agent) {
+	message := "Your time budget is up. Stop starting new work and wrap up now:\n" +
+		"1. Commit what you have (WIP commits are fine) and push your branch.\n" +
+		"2. Write a short status summary of what is done and what remains.\n" +
+		"3. Run `multiclaude agent complete --summary \"<status>\"`, or if the work is not usable, " +
+		"`multiclaude agent complete --failure-reason \"<why>\"` to abort cleanly."
+	msgMgr := d.getMessageManager()
+	if _, err := msgMgr.Send(repoName, "daemon", agentName, message); err != nil {
+		d.logger.Warn("Failed to send deadline instructions to %s: %v", agentName, err)
+	}
+	if _, err := msgMgr.Send(repoName, "daemon", "supervisor",
+		fmt.Sprintf("Worker '%s' reached its deadline and was told to wrap up: %s", agentName, agent.Task)); err != nil {
+		d.logger.Warn("Failed to notify supervisor of deadline: %v", err)
+	}
+
+	d.emitEvent(events.NewTypedEvent(repoName, agentName,
+		fmt.Sprintf("Agent %s reached its deadline", agentName),
+		events.AgentTimeoutPayload{
+			Task:          agent.Task,
+			Deadline:      agent.Deadline,
+			BudgetSeconds: agent.Deadline.Sub(agent.CreatedAt).Seconds(),
+		}))
+	d.logger.Info("Deadline reached for %s/%s", repoName, agentName)
+	d.recordAction(repoName, feed.ActionTimedOut, agentName, agent.Task)
+	d.recordDaemonChange("deadline", audit.ActionAgentTimedOut, repoName, agentName, "")
+}
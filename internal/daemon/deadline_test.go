@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestDeadlineStage(t *testing.T) {
+	start := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	agent := state.Agent{CreatedAt: start, Deadline: start.Add(2 * time.Hour)}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want state.DeadlineStage
+	}{
+		{"early", start.Add(time.Hour), state.DeadlineStageNone},
+		{"at 75%", start.Add(90 * time.Minute), state.DeadlineStageWarned},
+		{"at deadline", start.Add(2 * time.Hour), state.DeadlineStageExpired},
+		{"past deadline", start.Add(3 * time.Hour), state.DeadlineStageExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deadlineStage(agent, tt.now); got != tt.want {
+				t.Errorf("deadlineStage = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := deadlineStage(state.Agent{CreatedAt: start}, start.Add(time.Hour)); got != state.DeadlineStageNone {
+		t.Errorf("agents without a deadline should never be notified, got %q", got)
+	}
+}
+
+func TestCheckDeadlines(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "worker-a", state.Agent{
+			Type:      state.AgentTypeWorker,
+			Task:      "explore caching",
+			CreatedAt: start,
+			Deadline:  start.Add(2 * time.Hour),
+		})
+		s.AddAgent("repo", "worker-b", state.Agent{Type: state.AgentTypeWorker, CreatedAt: start})
+	})
+	defer cleanup()
+
+	stage := func() state.DeadlineStage {
+		agent, _ := d.state.GetAgent("repo", "worker-a")
+		return agent.DeadlineStage
+	}
+	messages := func(agent string) []string {
+		msgs, _ := d.getMessageManager().List("repo", agent)
+		var bodies []string
+		for _, m := range msgs {
+			bodies = append(bodies, m.Body)
+		}
+		return bodies
+	}
+
+	d.checkDeadlines(start.Add(time.Hour))
+	if stage() != state.DeadlineStageNone || len(messages("worker-a")) != 0 {
+		t.Fatalf("no notice expected halfway through the budget")
+	}
+
+	d.checkDeadlines(start.Add(100 * time.Minute))
+	d.checkDeadlines(start.Add(110 * time.Minute))
+	if stage() != state.DeadlineStageWarned {
+		t.Fatalf("stage = %q, want warned", stage())
+	}
+	if msgs := messages("worker-a"); len(msgs) != 1 || !strings.Contains(msgs[0], "Start wrapping up") {
+		t.Fatalf("expected a single warning, got %v", msgs)
+	}
+
+	d.checkDeadlines(start.Add(2 * time.Hour))
+	d.checkDeadlines(start.Add(3 * time.Hour))
+	if stage() != state.DeadlineStageExpired {
+		t.Fatalf("stage = %q, want expired", stage())
+	}
+	msgs := strings.Join(messages("worker-a"), "\n")
+	if len(messages("worker-a")) != 2 || !strings.Contains(msgs, "multiclaude agent complete") {
+		t.Fatalf("expected wrap-up instructions once, got %v", msgs)
+	}
+	if sup := messages("supervisor"); len(sup) != 1 || !strings.Contains(sup[0], "explore caching") {
+		t.Errorf("supervisor should be told about the deadline, got %v", sup)
+	}
+	if len(messages("worker-b")) != 0 {
+		t.Errorf("workers without a deadline should not be messaged")
+	}
+}
+
+func TestHandleAddAgentTimeBudget(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+	})
+	defer cleanup()
+
+	resp := d.handleRequest(socket.Request{Command: "add_agent", Args: map[string]interface{}{
+		"repo":                "repo",
+		"agent":               "worker-a",
+		"type":                "worker",
+		"worktree_path":       "/tmp/wt",
+		"tmux_window":         "worker-a",
+		"time_budget_seconds": float64(7200),
+	}})
+	if !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+
+	agent, _ := d.state.GetAgent("repo", "worker-a")
+	if got := agent.Deadline.Sub(agent.CreatedAt); got != 2*time.Hour {
+		t.Errorf("deadline is %s after creation, want 2h", got)
+	}
+}
@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/format"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+// diskUsageInterval is how often agents' worktrees are measured. Walking a
+// large checkout is slow, so usage is cached between measurements.
+const diskUsageInterval = 5 * time.Minute
+
+// diskUsageLoop periodically measures agents' worktrees and enforces the
+// config file's worktree quota
+func (d *Daemon) diskUsageLoop() {
+	measure := func() {
+		d.refreshDiskUsage()
+		d.enforceDiskQuota()
+	}
+	d.periodicLoop("disk usage", diskUsageInterval, measure, measure)
+}
+
+// refreshDiskUsage measures the worktree of every agent that has its own.
+// The supervisor and merge queue work in the repository's clone, which
+// isn't counted.
+func (d *Daemon) refreshDiskUsage() {
+	usage := make(map[string]int64)
+	for repoName, repo := range d.state.GetAllRepos() {
+		repoPath := d.paths.RepoDir(repoName)
+		manager := worktree.NewManager(repoPath)
+		for agentName, agent := range repo.Agents {
+			if agent.WorktreePath == "" || agent.WorktreePath == repoPath {
+				continue
+			}
+			bytes, err := manager.DiskUsage(agent.WorktreePath)
+			if err != nil {
+				d.logger.Debug("Failed to measure worktree of %s/%s: %v", repoName, agentName, err)
+				continue
+			}
+			usage[agent.WorktreePath] = bytes
+		}
+	}
+
+	d.diskUsageMu.Lock()
+	d.diskUsage = usage
+	d.diskUsageMu.Unlock()
+}
+
+// worktreeDiskUsage returns the bytes a worktree took when last measured
+func (d *Daemon) worktreeDiskUsage(path string) (int64, bool) {
+	d.diskUsageMu.Lock()
+	defer d.diskUsageMu.Unlock()
+	bytes, ok := d.diskUsage[path]
+	return bytes, ok
+}
+
+// totalDiskUsage returns the bytes all agents' worktrees took when last
+// measured
+func (d *Daemon) totalDiskUsage() int64 {
+	d.diskUsageMu.Lock()
+	defer d.diskUsageMu.Unlock()
+	var total int64
+	for _, bytes := range d.diskUsage {
+		total += bytes
+	}
+	return total
+}
+
+// enforceDiskQuota cleans up completed workers, oldest first, while
+// worktrees exceed their quota and the config file sets on_quota: clean.
+// It returns the total usage afterwards.
+func (d *Daemon) enforceDiskQuota() int64 {
+	total := d.totalDiskUsage()
+	settings := d.configFile().Worktrees
+	quota := settings.QuotaBytes()
+	if quota <= 0 || total < quota || settings.OnQuota != config.QuotaClean {
+		return total
+	}
+
+	type completed struct {
+		repo, name, path string
+		created          time.Time
+	}
+	var candidates []completed
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			if agent.Type == state.AgentTypeWorker && agent.ReadyForCleanup && agent.WorktreePath != "" {
+				candidates = append(candidates, completed{repoName, agentName, agent.WorktreePath, agent.CreatedAt})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].created.Before(candidates[j].created)
+	})
+
+	for _, c := range candidates {
+		if total < quota {
+			break
+		}
+		bytes, _ := d.worktreeDiskUsage(c.path)
+		d.logger.Info("Worktrees use %s of their %s quota, cleaning up completed worker %s/%s (%s)",
+			format.Bytes(total), format.Bytes(quota), c.repo, c.name, format.Bytes(bytes))
+		d.recordDaemonChange("disk_quota", audit.ActionCleanup, c.repo, c.name,
+			fmt.Sprintf("worktrees used %s of their %s quota, freed %s", format.Bytes(total), format.Bytes(quota), format.Bytes(bytes)))
+		d.cleanupDeadAgents(map[string][]string{c.repo: {c.name}}, "disk_quota")
+
+		d.diskUsageMu.Lock()
+		delete(d.diskUsage, c.path)
+		d.diskUsageMu.Unlock()
+		total -= bytes
+	}
+	if total >= quota {
+		d.logger.Warn("Worktrees use %s of their %s quota; new workers are refused", format.Bytes(total), format.Bytes(quota))
+	}
+	return total
+}
+
+// diskQuotaError returns an error if agents' worktrees have reached the
+// config file's quota, after cleaning up completed workers if it allows
+func (d *Daemon) diskQuotaError() error {
+	quota := d.configFile().Worktrees.QuotaBytes()
+	if quota <= 0 {
+		return nil
+	}
+	if total := d.enforceDiskQuota(); total >= quota {
+		return fmt.Errorf("agent worktrees use %s of their %s disk quota; remove finished workers with: multiclaude work rm <name>, or raise worktrees.quota in %s",
+			format.Bytes(total), format.Bytes(quota), d.paths.ConfigFile())
+	}
+	return nil
+}
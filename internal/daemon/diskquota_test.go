@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+func TestDiskQuota(t *testing.T) {
+	dir := t.TempDir()
+	worktrees := map[string]string{}
+	for name, size := range map[string]int{"old": 6 << 10, "newer": 3 << 10, "busy": 2 << 10} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(path, 0755)
+		os.WriteFile(filepath.Join(path, "build.out"), make([]byte, size), 0644)
+		worktrees[name] = path
+	}
+	start := time.Now().Add(-time.Hour)
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "old", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "old", WorktreePath: worktrees["old"], ReadyForCleanup: true, CreatedAt: start})
+		s.AddAgent("repo", "newer", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "newer", WorktreePath: worktrees["newer"], ReadyForCleanup: true, CreatedAt: start.Add(time.Minute)})
+		s.AddAgent("repo", "busy", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "busy", WorktreePath: worktrees["busy"], CreatedAt: start})
+	})
+	defer cleanup()
+
+	d.refreshDiskUsage()
+	if got := d.totalDiskUsage(); got != 11<<10 {
+		t.Fatalf("totalDiskUsage = %d, want %d", got, 11<<10)
+	}
+	resp := d.handleRequest(socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "repo", "rich": true}})
+	agents, _ := resp.Data.(socket.AgentList)
+	found := false
+	for _, agent := range agents.Agents {
+		if agent.Name == "busy" {
+			found = true
+			if agent.AgentDetail == nil || agent.DiskBytes != 2<<10 {
+				t.Errorf("busy = %+v, want %d disk bytes", agent, 2<<10)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("list_agents = %+v, want busy", resp.Data)
+	}
+
+	// Refusing leaves completed workers alone
+	file, err := config.ParseFile([]byte("worktrees:\n  quota: 8KB\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.settings = file
+	if err := d.workerCapacityError("repo"); err == nil || !strings.Contains(err.Error(), "disk quota") {
+		t.Errorf("workerCapacityError = %v, want the disk quota", err)
+	}
+	if _, exists := d.state.GetAgent("repo", "old"); !exists {
+		t.Error("on_quota: refuse cleaned up a worker")
+	}
+
+	// Cleaning removes the oldest completed worker, which is enough
+	file, err = config.ParseFile([]byte("worktrees:\n  quota: 8KB\n  on_quota: clean\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.settings = file
+	if err := d.workerCapacityError("repo"); err != nil {
+		t.Errorf("workerCapacityError = %v, want room after cleaning", err)
+	}
+	if _, exists := d.state.GetAgent("repo", "old"); exists {
+		t.Error("the oldest completed worker was not cleaned up")
+	}
+	for _, name := range []string{"newer", "busy"} {
+		if _, exists := d.state.GetAgent("repo", name); !exists {
+			t.Errorf("%s was cleaned up", name)
+		}
+	}
+
+	capacity, _ := d.handleCheckWorkerCapacity(socket.Request{}, socket.RepoArgs{Repo: "repo"})
+	if !capacity.Available || capacity.DiskBytes != 5<<10 || capacity.DiskQuota != 8<<10 {
+		t.Errorf("check_worker_capacity = %+v", capacity)
+	}
+}
@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+)
+
+// checkWorkingDirectories compares each agent pane's working directory with
+// the agent's worktree. An agent that wanders off (e.g. into the main clone)
+// is told once to go back, and the drift is recorded for status until it
+// returns.
+func (d *Daemon) checkWorkingDirectories() {
+	warned := false
+	for repoName, repo := range d.state.GetAllRepos() {
+		windows, err := d.tmux.ListWindowInfo(d.ctx, repo.TmuxSession)
+		if err != nil {
+			// Missing sessions are restored by the health check
+			continue
+		}
+		paths := make(map[string]string, len(windows))
+		for _, window := range windows {
+			if !window.PaneDead {
+				paths[window.Name] = window.PanePath
+			}
+		}
+
+		for agentName, agent := range repo.Agents {
+			cwd, ok := paths[agent.TmuxWindow]
+			if !ok || cwd == "" || agent.WorktreePath == "" || agent.ReadyForCleanup {
+				continue
+			}
+			drift := ""
+			if !withinDir(cwd, agent.WorktreePath) {
+				drift = cwd
+			}
+			if drift == agent.CwdDrift {
+				continue
+			}
+
+			previous := agent.CwdDrift
+			agent.CwdDrift = drift
+			if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+				d.logger.Error("Failed to record working directory of %s/%s: %v", repoName, agentName, err)
+				continue
+			}
+			if drift == "" {
+				d.logger.Info("%s/%s is back in its worktree", repoName, agentName)
+				continue
+			}
+			// Moving from one stray directory to another doesn't repeat the warning
+			if previous != "" {
+				continue
+			}
+
+			d.logger.Warn("%s/%s is working in %s, outside its worktree %s", repoName, agentName, drift, agent.WorktreePath)
+			d.recordAction(repoName, feed.ActionCwdDrift, agentName, drift)
+			message := fmt.Sprintf("You are working in %s, which is outside your worktree. Changes made there affect other agents or the main clone. Run `cd %s` and continue from your worktree.",
+				drift, agent.WorktreePath)
+			if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, message); err != nil {
+				d.logger.Warn("Failed to warn %s about its working directory: %v", agentName, err)
+				continue
+			}
+			warned = true
+		}
+	}
+	if warned {
+		go d.routeMessages()
+	}
+}
+
+// withinDir reports whether path is dir or inside it, resolving symlinks
+// (e.g. /tmp on macOS) so equivalent spellings compare equal
+func withinDir(path, dir string) bool {
+	path, dir = resolvePath(path), resolvePath(dir)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// resolvePath cleans path and resolves its symlinks where it exists
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/messages"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestWithinDir(t *testing.T) {
+	tests := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"/wts/repo/worker", "/wts/repo/worker", true},
+		{"/wts/repo/worker/src/pkg", "/wts/repo/worker", true},
+		{"/wts/repo/worker/", "/wts/repo/worker", true},
+		{"/wts/repo/worker-2", "/wts/repo/worker", false},
+		{"/repos/repo", "/wts/repo/worker", false},
+		{"/wts/repo", "/wts/repo/worker", false},
+		{"/wts/repo/worker/../other", "/wts/repo/worker", false},
+	}
+	for _, tt := range tests {
+		if got := withinDir(tt.path, tt.dir); got != tt.want {
+			t.Errorf("withinDir(%q, %q) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestCheckWorkingDirectories(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sessionName := fmt.Sprintf("mc-test-drift-%d", time.Now().UnixNano())
+	if err := tmuxClient.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("tmux is required for this test but cannot create sessions in this environment: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, sessionName)
+	if err := tmuxClient.CreateWindow(ctx, sessionName, "worker"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	worktree := filepath.Join(t.TempDir(), "worker")
+	mainClone := filepath.Join(t.TempDir(), "repo")
+	for _, dir := range []string{worktree, mainClone} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+
+	d.state.AddRepo("drift-repo", &state.Repository{TmuxSession: sessionName, Agents: make(map[string]state.Agent)})
+	d.state.AddAgent("drift-repo", "worker", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker", WorktreePath: worktree})
+
+	// cd and wait for tmux to see the pane's new directory
+	cd := func(dir string) {
+		t.Helper()
+		if err := tmuxClient.SendKeys(ctx, sessionName, "worker", "cd "+dir); err != nil {
+			t.Fatalf("SendKeys failed: %v", err)
+		}
+		for i := 0; i < 50; i++ {
+			windows, _ := tmuxClient.ListWindowInfo(ctx, sessionName)
+			for _, w := range windows {
+				if w.Name == "worker" && withinDir(w.PanePath, dir) && withinDir(dir, w.PanePath) {
+					return
+				}
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("pane never moved to %s", dir)
+	}
+	drift := func() string {
+		agent, _ := d.state.GetAgent("drift-repo", "worker")
+		return agent.CwdDrift
+	}
+	warnings := func() int {
+		msgs, _ := d.getMessageManager().List("drift-repo", "worker")
+		n := 0
+		for _, m := range msgs {
+			if strings.Contains(m.Body, "outside your worktree") {
+				n++
+			}
+		}
+		return n
+	}
+
+	cd(worktree)
+	d.checkWorkingDirectories()
+	if drift() != "" || warnings() != 0 {
+		t.Fatalf("agent in its worktree flagged: drift %q, %d warnings", drift(), warnings())
+	}
+
+	cd(mainClone)
+	d.checkWorkingDirectories()
+	d.checkWorkingDirectories()
+	if !withinDir(drift(), mainClone) {
+		t.Errorf("drift = %q, want %s", drift(), mainClone)
+	}
+	if warnings() != 1 {
+		t.Errorf("agent warned %d times, want once", warnings())
+	}
+
+	// The warning is typed into the pane; let it land before typing again
+	for i := 0; i < 50; i++ {
+		msgs, _ := d.getMessageManager().List("drift-repo", "worker")
+		if len(msgs) > 0 && msgs[0].Status != messages.StatusPending {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	exec.Command("tmux", "send-keys", "-t", sessionName+":worker", "C-c").Run()
+
+	cd(worktree)
+	d.checkWorkingDirectories()
+	if drift() != "" {
+		t.Errorf("drift not cleared after returning: %q", drift())
+	}
+}
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// defaultEventLimit is how many events list_events returns when no limit is given
+const defaultEventLimit = 100
+
+// compactEventStore drops events past their retention from the event store
+func (d *Daemon) compactEventStore() {
+	store := d.notify.Store()
+	if store == nil {
+		return
+	}
+	if err := store.Compact(d.clock.Now()); err != nil {
+		d.logger.Warn("Failed to compact the event store: %v", err)
+	}
+}
+
+// handleListEvents returns stored notification events, oldest first.
+// Optional args: "since" and "until" (RFC 3339), "repo", "type", and "limit".
+// Without an event store it falls back to the hub's in-memory history.
+func (d *Daemon) handleListEvents(req socket.Request, args socket.ListEventsArgs) ([]events.Event, error) {
+	var q notify.EventQuery
+	if err := parseTimeArg("since", args.Since, &q.Since); err != nil {
+		return nil, err
+	}
+	if err := parseTimeArg("until", args.Until, &q.Until); err != nil {
+		return nil, err
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && q.Until.Before(q.Since) {
+		return nil, socket.Errorf(socket.CodeInvalidArgument, "until must not be before since")
+	}
+	q.Repo, q.Type = args.Repo, events.EventType(args.Type)
+	q.Limit = defaultEventLimit
+	if args.Limit != nil {
+		q.Limit = *args.Limit
+	}
+
+	store := d.notify.Store()
+	if store == nil {
+		return filterRecentEvents(d.notify.Recent(0), q), nil
+	}
+	list, err := store.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []events.Event{}
+	}
+	return list, nil
+}
+
+// parseTimeArg parses an RFC 3339 time argument into dst, leaving dst alone
+// if the argument wasn't given
+func parseTimeArg(name, value string, dst *time.Time) error {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return socket.Errorf(socket.CodeInvalidArgument, "invalid %s %q: %v", name, value, err)
+	}
+	*dst = t
+	return nil
+}
+
+// filterRecentEvents applies q to the hub's newest-first history and returns
+// the matches oldest first
+func filterRecentEvents(recent []events.Event, q notify.EventQuery) []events.Event {
+	list := []events.Event{}
+	for i := len(recent) - 1; i >= 0; i-- {
+		if q.Matches(recent[i]) {
+			list = append(list, recent[i])
+		}
+	}
+	if q.Limit > 0 && len(list) > q.Limit {
+		list = list[len(list)-q.Limit:]
+	}
+	return list
+}
@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestHandleListEvents(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	start := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	d.clock = fake
+
+	for _, e := range []events.Event{
+		events.NewEvent(events.EventAgentStuck, "alpha", "w1", "w1 is stuck"),
+		events.NewEvent(events.EventAgentCompleted, "beta", "w2", "w2 finished"),
+		events.NewEvent(events.EventAgentStuck, "alpha", "w3", "w3 is stuck"),
+	} {
+		d.emitEvent(e)
+		fake.Advance(time.Hour)
+	}
+
+	list := func(args map[string]interface{}) []events.Event {
+		t.Helper()
+		resp := d.handleRequest(socket.Request{Command: "list_events", Args: args})
+		if !resp.Success {
+			t.Fatalf("list_events %v failed: %s", args, resp.Error)
+		}
+		return resp.Data.([]events.Event)
+	}
+
+	if got := list(nil); len(got) != 3 || got[0].Title != "w1 is stuck" {
+		t.Errorf("all events = %+v, want three, oldest first", got)
+	}
+	got := list(map[string]interface{}{
+		"since": start.Add(30 * time.Minute).Format(time.RFC3339),
+		"until": start.Add(3 * time.Hour).Format(time.RFC3339),
+		"type":  string(events.EventAgentStuck),
+	})
+	if len(got) != 1 || got[0].Agent != "w3" {
+		t.Errorf("range query = %+v, want only w3", got)
+	}
+	if got := list(map[string]interface{}{"repo": "beta"}); len(got) != 1 || got[0].Agent != "w2" {
+		t.Errorf("repo query = %+v, want only w2", got)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "list_events", Args: map[string]interface{}{
+		"since": start.Format(time.RFC3339), "until": start.Add(-time.Hour).Format(time.RFC3339),
+	}})
+	if resp.Success {
+		t.Error("an until before since should be rejected")
+	}
+}
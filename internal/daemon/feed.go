@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// defaultFeedLimit is how many entries get_feed returns when no limit is given
+const defaultFeedLimit = 50
+
+// recordAction appends an orchestration action to a repository's feed
+func (d *Daemon) recordAction(repoName string, action feed.Action, agentName, detail string) {
+	if err := d.feed.Append(repoName, feed.Entry{Action: action, Agent: agentName, Detail: detail}); err != nil {
+		d.logger.Warn("Failed to record %s in the %s feed: %v", action, repoName, err)
+	}
+}
+
+// describeQueueItem summarizes a merge queue event for the feed, e.g.
+// "merged PR #42 (work/fox)"
+func describeQueueItem(event state.MergeQueueEvent, item state.MergeQueueItem) string {
+	switch {
+	case item.PRNumber > 0 && item.Branch != "":
+		return fmt.Sprintf("%s PR #%d (%s)", event, item.PRNumber, item.Branch)
+	case item.PRNumber > 0:
+		return fmt.Sprintf("%s PR #%d", event, item.PRNumber)
+	default:
+		return fmt.Sprintf("%s %s", event, item.Branch)
+	}
+}
+
+// handleGetFeed returns a repository's recent orchestration actions, oldest
+// first. Optional args: "since" (RFC 3339) and "limit".
+func (d *Daemon) handleGetFeed(req socket.Request, args socket.GetFeedArgs) ([]feed.Entry, error) {
+	repoName := args.Repo
+	if _, exists := d.state.GetAllRepos()[repoName]; !exists {
+		return nil, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+	}
+
+	var since time.Time
+	if err := parseTimeArg("since", args.Since, &since); err != nil {
+		return nil, err
+	}
+	limit := defaultFeedLimit
+	if args.Limit != nil {
+		limit = *args.Limit
+	}
+
+	entries, err := d.feed.List(repoName, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []feed.Entry{}
+	}
+	return entries, nil
+}
@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestHandleGetFeed(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	d.state.AddRepo("feed-repo", &state.Repository{TmuxSession: "mc-feed-repo", Agents: make(map[string]state.Agent)})
+
+	resp := d.handleRequest(socket.Request{Command: "add_agent", Args: map[string]interface{}{
+		"repo": "feed-repo", "agent": "fox", "type": "worker",
+		"worktree_path": "/tmp/fox", "tmux_window": "fox", "task": "Fix login",
+	}})
+	if !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+	d.handleRequest(socket.Request{Command: "merge_queue_event", Args: map[string]interface{}{
+		"repo": "feed-repo", "event": "merged", "branch": "work/fox", "pr": float64(42),
+	}})
+	d.handleRequest(socket.Request{Command: "remove_agent", Args: map[string]interface{}{"repo": "feed-repo", "agent": "fox"}})
+
+	resp = d.handleRequest(socket.Request{Command: "get_feed", Args: map[string]interface{}{"repo": "feed-repo"}})
+	if !resp.Success {
+		t.Fatalf("get_feed failed: %s", resp.Error)
+	}
+	entries, ok := resp.Data.([]feed.Entry)
+	if !ok || len(entries) != 3 {
+		t.Fatalf("get_feed returned %#v, want 3 entries", resp.Data)
+	}
+	want := []struct {
+		action feed.Action
+		detail string
+	}{
+		{feed.ActionSpawned, "worker: Fix login"},
+		{feed.ActionMergeQueue, "merged PR #42 (work/fox)"},
+		{feed.ActionRemoved, ""},
+	}
+	for i, w := range want {
+		if entries[i].Action != w.action || entries[i].Detail != w.detail {
+			t.Errorf("entry %d = %+v, want %s %q", i, entries[i], w.action, w.detail)
+		}
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "get_feed", Args: map[string]interface{}{"repo": "feed-repo", "limit": float64(1)}})
+	if entries := resp.Data.([]feed.Entry); len(entries) != 1 || entries[0].Action != feed.ActionRemoved {
+		t.Errorf("get_feed limit 1 = %+v", entries)
+	}
+
+	if resp := d.handleRequest(socket.Request{Command: "get_feed", Args: map[string]interface{}{"repo": "missing"}}); resp.Success {
+		t.Error("get_feed should fail for an unknown repo")
+	}
+}
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/fleet"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// fleetStatus answers fleet_status: this daemon's status, repositories and
+// agents, and those of every host in the hosts file. Hosts are asked in
+// parallel, over SSH or their TCP socket, with one local fleet_status
+// request each; one that doesn't answer is listed with its error.
+func (d *Daemon) fleetStatus(req socket.Request, args socket.FleetStatusArgs) (socket.FleetStatus, error) {
+	status := socket.FleetStatus{Hosts: []socket.FleetHost{d.localFleetHost(args.Group)}}
+	if !args.Local {
+		hosts, err := fleet.LoadHosts(d.paths.HostsFile())
+		if err != nil {
+			return socket.FleetStatus{}, fmt.Errorf("failed to load hosts: %w", err)
+		}
+		hostArgs := map[string]interface{}{"local": true}
+		if args.Group != "" {
+			hostArgs["group"] = args.Group
+		}
+		results := fleet.NewClient().SendAll(d.ctx, hosts, socket.Request{Command: "fleet_status", Args: hostArgs})
+		for i, r := range results {
+			entry := socket.FleetHost{Host: hosts[i].Name, Target: hosts[i].Target()}
+			if r.Err != nil {
+				entry.Error = r.Err.Error()
+			} else if remote, err := decodeFleetHost(r.Response.Data); err != nil {
+				entry.Error = fmt.Sprintf("host %s: %v", hosts[i].Name, err)
+			} else {
+				entry.Status, entry.Repos, entry.Agents = remote.Status, remote.Repos, remote.Agents
+			}
+			status.Hosts = append(status.Hosts, entry)
+		}
+	}
+
+	for _, h := range status.Hosts {
+		for _, repo := range h.Repos {
+			status.Agents += responseInt(repo["total_agents"])
+			status.Workers += responseInt(repo["worker_count"])
+		}
+	}
+	return status, nil
+}
+
+// localFleetHost returns this daemon's status, repositories and agents,
+// limited to a repo group if one is given
+func (d *Daemon) localFleetHost(group string) socket.FleetHost {
+	local := socket.FleetHost{Host: fleet.LocalHost}
+	localStatus, _ := d.handleStatus(socket.Request{}, socket.StatusArgs{})
+	local.Status = responseMap(localStatus)
+	localRepos, _ := d.handleListRepos(socket.Request{}, socket.ListReposArgs{Group: group, Rich: true})
+	local.Repos = responseMaps(localRepos)
+	localAgents, _ := d.handleListAgents(socket.Request{}, socket.ListAgentsArgs{AllRepos: true, Rich: true})
+	local.Agents = fleetAgents(local.Repos, responseMaps(localAgents))
+	return local
+}
+
+// decodeFleetHost reads the one host a local fleet_status answers with
+func decodeFleetHost(data interface{}) (socket.FleetHost, error) {
+	var status socket.FleetStatus
+	encoded, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(encoded, &status)
+	}
+	if err != nil {
+		return socket.FleetHost{}, fmt.Errorf("failed to decode fleet status: %w", err)
+	}
+	if len(status.Hosts) == 0 {
+		return socket.FleetHost{}, fmt.Errorf("fleet status lists no hosts")
+	}
+	return status.Hosts[0], nil
+}
+
+// fleetAgents keeps the agents of the listed repositories, which leaves out
+// other groups' agents when fleet_status is limited to one
+func fleetAgents(repos, agents []map[string]interface{}) []map[string]interface{} {
+	names := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		if name, ok := repo["name"].(string); ok {
+			names[name] = true
+		}
+	}
+	kept := []map[string]interface{}{}
+	for _, agent := range agents {
+		if repo, _ := agent["repo"].(string); names[repo] {
+			kept = append(kept, agent)
+		}
+	}
+	return kept
+}
+
+// responseMap returns a response's object, whether it came from a handler
+// in this process or was decoded from JSON
+func responseMap(data interface{}) map[string]interface{} {
+	if m, ok := data.(map[string]interface{}); ok {
+		return m
+	}
+	var m map[string]interface{}
+	if encoded, err := json.Marshal(data); err == nil {
+		_ = json.Unmarshal(encoded, &m)
+	}
+	return m
+}
+
+// responseMaps returns the objects in a response's list, whether it came
+// from a handler in this process or was decoded from JSON
+func responseMaps(data interface{}) []map[string]interface{} {
+	maps := []map[string]interface{}{}
+	switch list := data.(type) {
+	case []map[string]interface{}:
+		maps = append(maps, list...)
+	case []interface{}:
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok {
+				maps = append(maps, m)
+			}
+		}
+	default:
+		// A typed response reads as it would to a client
+		if encoded, err := json.Marshal(data); err == nil {
+			_ = json.Unmarshal(encoded, &maps)
+		}
+	}
+	return maps
+}
+
+// responseInt reads a number from a response, whether it came from a
+// handler in this process or was decoded from JSON
+func responseInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
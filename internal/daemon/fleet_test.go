@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/fleet"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// addRepoWithWorker tracks a repository with one worker
+func addRepoWithWorker(s *state.State, repo, worker string, groups ...string) {
+	s.AddRepo(repo, &state.Repository{TmuxSession: "mc-" + repo, Groups: groups, Agents: make(map[string]state.Agent)})
+	s.AddAgent(repo, worker, state.Agent{Type: state.AgentTypeWorker, TmuxWindow: worker})
+}
+
+func TestFleetStatus(t *testing.T) {
+	remote, cleanupRemote := setupTestDaemonWithState(t, func(s *state.State) {
+		addRepoWithWorker(s, "api", "fox")
+		addRepoWithWorker(s, "docs", "owl", "writing")
+	})
+	defer cleanupRemote()
+	remote.settings.Remote.Listen = "127.0.0.1:0"
+	remote.settings.Remote.Token = "t0ken"
+	if err := remote.startRemote(); err != nil {
+		t.Fatalf("startRemote() failed: %v", err)
+	}
+	defer remote.stopRemote()
+
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		addRepoWithWorker(s, "api", "elk")
+	})
+	defer cleanup()
+	tokenFile := filepath.Join(d.paths.Root, "b1.token")
+	os.WriteFile(tokenFile, []byte("t0ken"), 0600)
+	if err := fleet.SaveHosts(d.paths.HostsFile(), []fleet.Host{
+		{Name: "b1", Address: remote.remoteAddr(), TokenFile: tokenFile, Plaintext: true},
+		{Name: "b2", Address: "127.0.0.1:1", TokenFile: tokenFile, Plaintext: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "fleet_status"})
+	if !resp.Success {
+		t.Fatalf("fleet_status failed: %s", resp.Error)
+	}
+	status := resp.Data.(socket.FleetStatus)
+	if len(status.Hosts) != 3 || status.Hosts[0].Host != fleet.LocalHost || status.Hosts[1].Host != "b1" || status.Hosts[2].Host != "b2" {
+		t.Fatalf("hosts = %+v, want local, b1 and b2", status.Hosts)
+	}
+	if status.Agents != 3 || status.Workers != 3 {
+		t.Errorf("totals = %d agents, %d workers; want 3 of each", status.Agents, status.Workers)
+	}
+	if b1 := status.Hosts[1]; b1.Error != "" || len(b1.Repos) != 2 || len(b1.Agents) != 2 || b1.Status["running"] != true {
+		t.Errorf("b1 = %+v, want its status, both repositories and both workers", b1)
+	}
+	if b2 := status.Hosts[2]; b2.Error == "" || b2.Target != "127.0.0.1:1" {
+		t.Errorf("b2 = %+v, want an unreachable host", b2)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "fleet_status", Args: map[string]interface{}{"group": "writing"}})
+	status = resp.Data.(socket.FleetStatus)
+	if b1 := status.Hosts[1]; len(b1.Repos) != 1 || len(b1.Agents) != 1 || b1.Agents[0]["name"] != "owl" {
+		t.Errorf("b1 in group writing = %+v, want only docs and its worker", b1)
+	}
+	if len(status.Hosts[0].Repos) != 0 || len(status.Hosts[0].Agents) != 0 {
+		t.Errorf("local in group writing = %+v, want nothing", status.Hosts[0])
+	}
+
+	// A local request, which is what hosts are sent, leaves out the hosts file
+	resp = d.handleRequest(socket.Request{Command: "fleet_status", Args: map[string]interface{}{"local": true}})
+	status = resp.Data.(socket.FleetStatus)
+	if len(status.Hosts) != 1 || status.Hosts[0].Host != fleet.LocalHost || status.Workers != 1 {
+		t.Errorf("local fleet_status = %+v, want only this daemon and its worker", status)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "status", Args: map[string]interface{}{"all_hosts": true}})
+	if fleetStatus := resp.Data.(socket.Status).Fleet; fleetStatus == nil || len(fleetStatus.Hosts) != 3 {
+		t.Errorf("status with all_hosts = %+v, want the fleet", resp.Data)
+	}
+}
+
+func TestListAgentsAllReposOverSocket(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		addRepoWithWorker(s, "api", "fox")
+		addRepoWithWorker(s, "docs", "owl")
+	})
+	defer cleanup()
+
+	resp := d.dispatchRequest(socket.Request{Command: "list_agents", Version: socket.ProtocolVersion, Args: map[string]interface{}{"all_repos": true}})
+	if list, _ := resp.Data.(socket.AgentList); !resp.Success || len(list.Agents) != 2 {
+		t.Errorf("list_agents with all_repos = %+v, want both workers", resp)
+	}
+}
@@ -1,15 +1,21 @@
 package daemon
 
 import (
+	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/dlorenc/multiclaude/internal/clock"
 	"github.com/dlorenc/multiclaude/internal/messages"
 	"github.com/dlorenc/multiclaude/internal/socket"
 	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
 	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/events"
 )
 
 // setupTestDaemonWithState creates a test daemon with a pre-configured state for testing.
@@ -220,7 +226,7 @@ func TestHandleAddAgentTableDriven(t *testing.T) {
 			d, cleanup := setupTestDaemonWithState(t, tt.setupState)
 			defer cleanup()
 
-			resp := d.handleAddAgent(socket.Request{
+			resp := d.handleRequest(socket.Request{
 				Command: "add_agent",
 				Args:    tt.args,
 			})
@@ -368,7 +374,7 @@ func TestHandleRemoveAgentTableDriven(t *testing.T) {
 			d, cleanup := setupTestDaemonWithState(t, tt.setupState)
 			defer cleanup()
 
-			resp := d.handleRemoveAgent(socket.Request{
+			resp := d.handleRequest(socket.Request{
 				Command: "remove_agent",
 				Args:    tt.args,
 			})
@@ -548,7 +554,7 @@ func TestHandleCompleteAgentTableDriven(t *testing.T) {
 			d, cleanup := setupTestDaemonWithState(t, tt.setupState)
 			defer cleanup()
 
-			resp := d.handleCompleteAgent(socket.Request{
+			resp := d.handleRequest(socket.Request{
 				Command: "complete_agent",
 				Args:    tt.args,
 			})
@@ -620,7 +626,7 @@ func TestHandleCompleteAgentSendsMessages(t *testing.T) {
 			})
 			defer cleanup()
 
-			resp := d.handleCompleteAgent(socket.Request{
+			resp := d.handleRequest(socket.Request{
 				Command: "complete_agent",
 				Args: map[string]interface{}{
 					"repo":  "test-repo",
@@ -801,6 +807,30 @@ func TestHandleAddRepoTableDriven(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "successful add with partial clone filter and mirror",
+			args: map[string]interface{}{
+				"name":         "partial-repo",
+				"github_url":   "https://github.com/owner/repo",
+				"tmux_session": "mc-partial-repo",
+				"clone_filter": "blob:none",
+				"mirror":       "/mirrors/github.com-owner-repo.git",
+			},
+			wantSuccess: true,
+			checkState: func(t *testing.T, d *Daemon) {
+				repo, exists := d.state.GetRepo("partial-repo")
+				if !exists {
+					t.Error("Repo should exist after add")
+					return
+				}
+				if repo.CloneFilter != "blob:none" {
+					t.Errorf("CloneFilter = %q, want blob:none", repo.CloneFilter)
+				}
+				if repo.Mirror != "/mirrors/github.com-owner-repo.git" {
+					t.Errorf("Mirror = %q, want the mirror path", repo.Mirror)
+				}
+			},
+		},
 		{
 			name: "duplicate repo name fails",
 			args: map[string]interface{}{
@@ -825,7 +855,7 @@ func TestHandleAddRepoTableDriven(t *testing.T) {
 			d, cleanup := setupTestDaemonWithState(t, tt.setupState)
 			defer cleanup()
 
-			resp := d.handleAddRepo(socket.Request{
+			resp := d.handleRequest(socket.Request{
 				Command: "add_repo",
 				Args:    tt.args,
 			})
@@ -913,7 +943,7 @@ func TestHandleRemoveRepoTableDriven(t *testing.T) {
 			d, cleanup := setupTestDaemonWithState(t, tt.setupState)
 			defer cleanup()
 
-			resp := d.handleRemoveRepo(socket.Request{
+			resp := d.handleRequest(socket.Request{
 				Command: "remove_repo",
 				Args:    tt.args,
 			})
@@ -946,7 +976,7 @@ func TestHandleAddAgentSessionIDGeneration(t *testing.T) {
 	defer cleanup()
 
 	// Add agent without session_id
-	resp := d.handleAddAgent(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "add_agent",
 		Args: map[string]interface{}{
 			"repo":          "test-repo",
@@ -988,7 +1018,7 @@ func TestHandleAddAgentCreatedAtIsSet(t *testing.T) {
 
 	beforeAdd := time.Now()
 
-	resp := d.handleAddAgent(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "add_agent",
 		Args: map[string]interface{}{
 			"repo":          "test-repo",
@@ -1024,7 +1054,7 @@ func TestHandleAddRepoEmptyAgentsMap(t *testing.T) {
 	d, cleanup := setupTestDaemonWithState(t, nil)
 	defer cleanup()
 
-	resp := d.handleAddRepo(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "add_repo",
 		Args: map[string]interface{}{
 			"name":         "new-repo",
@@ -1064,7 +1094,7 @@ func TestHandleCompleteAgentWithEmptyTask(t *testing.T) {
 	})
 	defer cleanup()
 
-	resp := d.handleCompleteAgent(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "complete_agent",
 		Args: map[string]interface{}{
 			"repo":  "test-repo",
@@ -1112,7 +1142,7 @@ func TestArgumentTypeCoercion(t *testing.T) {
 	defer cleanup()
 
 	// Test that non-string types for string arguments are handled
-	resp := d.handleAddAgent(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "add_agent",
 		Args: map[string]interface{}{
 			"repo":          123, // wrong type
@@ -1163,7 +1193,7 @@ func TestHandleGetCurrentRepo(t *testing.T) {
 			d, cleanup := setupTestDaemonWithState(t, tt.setupState)
 			defer cleanup()
 
-			resp := d.handleGetCurrentRepo(socket.Request{
+			resp := d.handleRequest(socket.Request{
 				Command: "get_current_repo",
 			})
 
@@ -1192,27 +1222,15 @@ func TestNilArgsMap(t *testing.T) {
 	d, cleanup := setupTestDaemonWithState(t, nil)
 	defer cleanup()
 
-	tests := []struct {
-		name    string
-		command string
-		handler func(socket.Request) socket.Response
-	}{
-		{"handleAddAgent", "add_agent", d.handleAddAgent},
-		{"handleRemoveAgent", "remove_agent", d.handleRemoveAgent},
-		{"handleCompleteAgent", "complete_agent", d.handleCompleteAgent},
-		{"handleAddRepo", "add_repo", d.handleAddRepo},
-		{"handleRemoveRepo", "remove_repo", d.handleRemoveRepo},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			resp := tt.handler(socket.Request{
-				Command: tt.command,
+	for _, command := range []string{"add_agent", "remove_agent", "complete_agent", "add_repo", "remove_repo"} {
+		t.Run(command, func(t *testing.T) {
+			resp := d.handleRequest(socket.Request{
+				Command: command,
 				Args:    nil,
 			})
 
 			if resp.Success {
-				t.Errorf("%s should fail with nil Args", tt.name)
+				t.Errorf("%s should fail with nil Args", command)
 			}
 		})
 	}
@@ -1270,7 +1288,7 @@ func TestHandleSetCurrentRepo(t *testing.T) {
 			d, cleanup := setupTestDaemonWithState(t, tt.setupState)
 			defer cleanup()
 
-			resp := d.handleSetCurrentRepo(socket.Request{
+			resp := d.handleRequest(socket.Request{
 				Command: "set_current_repo",
 				Args:    tt.args,
 			})
@@ -1303,7 +1321,7 @@ func TestHandleClearCurrentRepo(t *testing.T) {
 		t.Fatal("Setup failed: current repo not set")
 	}
 
-	resp := d.handleClearCurrentRepo(socket.Request{
+	resp := d.handleRequest(socket.Request{
 		Command: "clear_current_repo",
 	})
 
@@ -1316,3 +1334,753 @@ func TestHandleClearCurrentRepo(t *testing.T) {
 		t.Errorf("Current repo not cleared, got: %s", d.state.GetCurrentRepo())
 	}
 }
+
+func TestHandleAddAgentReadOnly(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "mc-test-repo",
+			Agents:      make(map[string]state.Agent),
+		})
+	})
+	defer cleanup()
+
+	resp := d.handleRequest(socket.Request{
+		Command: "add_agent",
+		Args: map[string]interface{}{
+			"repo":          "test-repo",
+			"agent":         "review-42",
+			"type":          "review",
+			"worktree_path": "/tmp/review-42",
+			"tmux_window":   "review-42",
+			"read_only":     true,
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleAddAgent failed: %s", resp.Error)
+	}
+
+	agent, exists := d.state.GetAgent("test-repo", "review-42")
+	if !exists {
+		t.Fatal("agent not found after add")
+	}
+	if !agent.ReadOnly {
+		t.Error("agent.ReadOnly should be true")
+	}
+}
+
+func TestHandleSpawnAgentReadOnlyRequiresEphemeral(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "mc-test-repo",
+			Agents:      make(map[string]state.Agent),
+		})
+	})
+	defer cleanup()
+
+	resp := d.handleRequest(socket.Request{
+		Command: "spawn_agent",
+		Args: map[string]interface{}{
+			"repo":      "test-repo",
+			"name":      "watcher",
+			"class":     "persistent",
+			"prompt":    "watch things",
+			"read_only": true,
+		},
+	})
+	if resp.Success {
+		t.Fatal("expected read_only persistent spawn to fail")
+	}
+}
+
+// runGitIn runs a git command in dir, failing the test on error
+func runGitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+// initWorkerBranchRepo creates a git repo at the daemon's repo path for repoName
+// with an origin/main ref, and checks out a work branch on top of it
+func initWorkerBranchRepo(t *testing.T, d *Daemon, repoName string) string {
+	t.Helper()
+	repoPath := d.paths.RepoDir(repoName)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	runGitIn(t, repoPath, "init", "-b", "main")
+	runGitIn(t, repoPath, "config", "user.email", "test@example.com")
+	runGitIn(t, repoPath, "config", "user.name", "Test User")
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "Initial commit")
+	runGitIn(t, repoPath, "remote", "add", "origin", repoPath)
+	runGitIn(t, repoPath, "fetch", "origin")
+	runGitIn(t, repoPath, "checkout", "-b", "work/"+repoName)
+	return repoPath
+}
+
+// TestHandleCompleteAgentBranchGuard verifies that a worker whose branch breaks
+// the repo's branch guard cannot complete until the violation is fixed
+func TestHandleCompleteAgentBranchGuard(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "guard-repo")
+	runGit := func(args ...string) {
+		t.Helper()
+		runGitIn(t, repoPath, args...)
+	}
+
+	if err := os.MkdirAll(filepath.Join(repoPath, "vendor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "vendor", "junk.go"), []byte("package junk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit("add", "-A")
+	runGit("commit", "-m", "Add vendored code")
+
+	d.state.AddRepo("guard-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/guard-repo",
+		TmuxSession: "mc-guard-repo",
+		Agents:      make(map[string]state.Agent),
+		BranchGuard: state.BranchGuardConfig{AllowedPaths: []string{"internal/"}},
+	})
+	d.state.AddAgent("guard-repo", "guarded-worker", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: repoPath,
+		TmuxWindow:   "guarded-worker",
+		Task:         "Fix internals",
+		CreatedAt:    time.Now(),
+	})
+
+	req := socket.Request{
+		Command: "complete_agent",
+		Args: map[string]interface{}{
+			"repo":  "guard-repo",
+			"agent": "guarded-worker",
+		},
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "check_branch_guard", Args: req.Args})
+	if !resp.Success {
+		t.Fatalf("handleCheckBranchGuard() failed: %s", resp.Error)
+	}
+	if data, _ := resp.Data.(socket.BranchGuardCheck); data.Passed {
+		t.Error("check_branch_guard should report a violation")
+	}
+
+	resp = d.handleRequest(req)
+	if resp.Success {
+		t.Fatal("complete_agent should be blocked by the branch guard")
+	}
+	if !strings.Contains(resp.Error, "vendor/junk.go") {
+		t.Errorf("error should include the guard report, got: %s", resp.Error)
+	}
+	if agent, _ := d.state.GetAgent("guard-repo", "guarded-worker"); agent.ReadyForCleanup {
+		t.Error("blocked agent should not be marked ready for cleanup")
+	}
+
+	// A per-task allow list overrides the repo's paths
+	agent, _ := d.state.GetAgent("guard-repo", "guarded-worker")
+	agent.AllowedPaths = []string{"vendor/"}
+	d.state.UpdateAgent("guard-repo", "guarded-worker", agent)
+
+	resp = d.handleRequest(req)
+	if !resp.Success {
+		t.Fatalf("complete_agent should pass with task allow list, got: %s", resp.Error)
+	}
+}
+
+// TestHandleCompleteAgentGeneratedFiles verifies that a worker cannot hand
+// off a branch that commits files multiclaude generated in its worktree
+func TestHandleCompleteAgentGeneratedFiles(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "generated-repo")
+	settings := filepath.Join(repoPath, ".claude", "settings.json")
+	if err := os.MkdirAll(filepath.Dir(settings), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(settings, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGitIn(t, repoPath, "add", "-f", ".claude/settings.json")
+	runGitIn(t, repoPath, "commit", "-m", "Add settings")
+
+	d.state.AddRepo("generated-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/generated-repo",
+		TmuxSession: "mc-generated-repo",
+		Agents:      make(map[string]state.Agent),
+	})
+	d.state.AddAgent("generated-repo", "worker", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: repoPath,
+		TmuxWindow:   "worker",
+		Task:         "Fix things",
+		CreatedAt:    time.Now(),
+	})
+
+	req := socket.Request{
+		Command: "complete_agent",
+		Args: map[string]interface{}{
+			"repo":  "generated-repo",
+			"agent": "worker",
+		},
+	}
+
+	resp := d.handleRequest(req)
+	if resp.Success {
+		t.Fatal("complete_agent should be blocked by the committed settings file")
+	}
+	if !strings.Contains(resp.Error, ".claude/settings.json") {
+		t.Errorf("error should name the generated file, got: %s", resp.Error)
+	}
+
+	runGitIn(t, repoPath, "rm", "--cached", "-q", ".claude/settings.json")
+	runGitIn(t, repoPath, "commit", "-m", "Untrack settings")
+
+	// The exclude entries written at completion keep it out of `git add -A`
+	runGitIn(t, repoPath, "add", "-A")
+	if output, _ := exec.Command("git", "-C", repoPath, "status", "--porcelain").Output(); len(output) != 0 {
+		t.Errorf("generated file should be ignored, got status: %s", output)
+	}
+
+	resp = d.handleRequest(req)
+	if !resp.Success {
+		t.Fatalf("complete_agent should pass once the file is untracked, got: %s", resp.Error)
+	}
+}
+
+// TestHandleCompleteAgentCommitPolicy verifies that commit subjects are linted
+// at completion, fixed automatically when possible and reported otherwise
+func TestHandleCompleteAgentCommitPolicy(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "lint-repo")
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "Fix flaky router test")
+
+	d.state.AddRepo("lint-repo", &state.Repository{
+		GithubURL:    "https://github.com/test/lint-repo",
+		TmuxSession:  "mc-lint-repo",
+		Agents:       make(map[string]state.Agent),
+		CommitPolicy: state.CommitPolicyConfig{Style: state.CommitStyleConventional},
+	})
+	addWorker := func(name string) {
+		d.state.AddAgent("lint-repo", name, state.Agent{
+			Type:         state.AgentTypeWorker,
+			WorktreePath: repoPath,
+			TmuxWindow:   name,
+			Task:         "Fix router",
+			CreatedAt:    time.Now(),
+		})
+	}
+	complete := func(name string) socket.Response {
+		return d.handleRequest(socket.Request{
+			Command: "complete_agent",
+			Args:    map[string]interface{}{"repo": "lint-repo", "agent": name},
+		})
+	}
+
+	addWorker("fixable")
+	if resp := complete("fixable"); !resp.Success {
+		t.Fatalf("fixable subject should be reworded, got: %s", resp.Error)
+	}
+	out, _ := exec.Command("git", "-C", repoPath, "log", "-1", "--format=%s").Output()
+	if got := strings.TrimSpace(string(out)); got != "fix: flaky router test" {
+		t.Errorf("subject after reword = %q", got)
+	}
+
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "WIP")
+	addWorker("unfixable")
+	resp := complete("unfixable")
+	if resp.Success {
+		t.Fatal("unfixable subject should block completion")
+	}
+	if !strings.Contains(resp.Error, "WIP") || !strings.Contains(resp.Error, "Expected format") {
+		t.Errorf("error should list the commit and expected format, got: %s", resp.Error)
+	}
+
+	msgs, err := messages.NewManager(d.paths.MessagesDir).List("lint-repo", "unfixable")
+	if err != nil || len(msgs) != 1 {
+		t.Errorf("expected one policy message to the agent, got %d (err=%v)", len(msgs), err)
+	}
+
+	resp = d.handleRequest(socket.Request{
+		Command: "update_repo_config",
+		Args:    map[string]interface{}{"name": "lint-repo", "commit_style": "regex", "commit_pattern": "("},
+	})
+	if resp.Success {
+		t.Error("invalid commit pattern should be rejected")
+	}
+}
+
+// TestHandleCompleteAgentSquash verifies that complete_agent can squash a
+// worker's branch and keep the original commits on a backup ref
+func TestHandleCompleteAgentSquash(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "squash-repo")
+	for _, msg := range []string{"wip", "wip 2"} {
+		runGitIn(t, repoPath, "commit", "--allow-empty", "-m", msg)
+	}
+
+	d.state.AddRepo("squash-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/squash-repo",
+		TmuxSession: "mc-squash-repo",
+		Agents:      make(map[string]state.Agent),
+	})
+	d.state.AddAgent("squash-repo", "squasher", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: repoPath,
+		TmuxWindow:   "squasher",
+		Task:         "improve error messages",
+		CreatedAt:    time.Now(),
+	})
+
+	resp := d.handleRequest(socket.Request{
+		Command: "complete_agent",
+		Args: map[string]interface{}{
+			"repo":   "squash-repo",
+			"agent":  "squasher",
+			"squash": true,
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("handleCompleteAgent() failed: %s", resp.Error)
+	}
+
+	backupRef := resp.Data.(socket.CompletedAgent).BackupRef
+	if backupRef == "" {
+		t.Fatal("response should include the backup ref")
+	}
+
+	out, _ := exec.Command("git", "-C", repoPath, "log", "--format=%s", "origin/main..HEAD").Output()
+	if got := strings.TrimSpace(string(out)); got != "Improve error messages" {
+		t.Errorf("commits after squash = %q, want a single synthesized commit", got)
+	}
+	out, _ = exec.Command("git", "-C", repoPath, "rev-list", "--count", "origin/main.."+backupRef).Output()
+	if got := strings.TrimSpace(string(out)); got != "2" {
+		t.Errorf("backup ref should hold 2 original commits, got %s", got)
+	}
+}
+
+type recordingAdapter struct{ events []events.Event }
+
+func (a *recordingAdapter) Name() string { return "recording" }
+
+func (a *recordingAdapter) Send(ctx context.Context, event events.Event) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+// TestHandleCompleteAgentPushAndCleanup verifies that complete_agent pushes
+// the branch, emits agent.completed, and with cleanup removes the agent after
+// the grace period rather than at the next health check
+func TestHandleCompleteAgentPushAndCleanup(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	d.clock = fake
+	recorder := &recordingAdapter{}
+	d.RegisterAdapter(recorder)
+
+	repoPath := initWorkerBranchRepo(t, d, "push-repo")
+	d.state.AddRepo("push-repo", &state.Repository{
+		TmuxSession: "mc-push-repo",
+		Agents:      make(map[string]state.Agent),
+	})
+	for _, name := range []string{"pusher", "lingerer"} {
+		d.state.AddAgent("push-repo", name, state.Agent{
+			Type:         state.AgentTypeWorker,
+			WorktreePath: repoPath,
+			TmuxWindow:   name,
+			Task:         "ship it",
+			CreatedAt:    time.Now(),
+		})
+	}
+
+	complete := func(agent string, args map[string]interface{}) socket.CompletedAgent {
+		t.Helper()
+		args["repo"] = "push-repo"
+		args["agent"] = agent
+		resp := d.handleRequest(socket.Request{Command: "complete_agent", Args: args})
+		if !resp.Success {
+			t.Fatalf("handleCompleteAgent(%s) failed: %s", agent, resp.Error)
+		}
+		return resp.Data.(socket.CompletedAgent)
+	}
+
+	data := complete("lingerer", map[string]interface{}{"summary": "done"})
+	if data.Pushed || data.Cleanup {
+		t.Errorf("unexpected response %+v", data)
+	}
+
+	data = complete("pusher", map[string]interface{}{"push": true, "cleanup": true})
+	if !data.Pushed || !data.Cleanup {
+		t.Errorf("unexpected response %+v", data)
+	}
+	if !worktree.HasUpstream(repoPath) {
+		t.Error("push should set the branch's upstream")
+	}
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("got %d events, want 2", len(recorder.events))
+	}
+	payload, ok := recorder.events[0].Payload.(events.AgentCompletedPayload)
+	if recorder.events[0].Type != events.EventAgentCompleted || !ok || payload.Summary != "done" || payload.Branch != "work/push-repo" {
+		t.Errorf("unexpected event %+v", recorder.events[0])
+	}
+
+	fake.BlockUntil(1)
+	fake.Advance(completeCleanupGrace)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, exists := d.state.GetAgent("push-repo", "pusher"); !exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pusher should be cleaned up after the grace period")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, exists := d.state.GetAgent("push-repo", "lingerer"); !exists {
+		t.Error("lingerer should wait for the next health check")
+	}
+}
+
+// TestHandleRespondAgentValidation verifies argument and agent validation for respond_agent
+func TestHandleRespondAgentValidation(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "mc-test-repo-respond",
+			Agents:      make(map[string]state.Agent),
+		})
+		s.AddAgent("test-repo", "worker-1", state.Agent{
+			Type:       state.AgentTypeWorker,
+			TmuxWindow: "worker-1",
+			CreatedAt:  time.Now(),
+		})
+	})
+	defer cleanup()
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{"missing text", map[string]interface{}{"repo": "test-repo", "agent": "worker-1"}, "reply text is required"},
+		{"unknown repo", map[string]interface{}{"repo": "nope", "agent": "worker-1", "text": "yes"}, "repository 'nope' not found"},
+		{"unknown agent", map[string]interface{}{"repo": "test-repo", "agent": "ghost", "text": "yes"}, "agent 'ghost' not found"},
+		{"no tmux window", map[string]interface{}{"repo": "test-repo", "agent": "worker-1", "text": "yes"}, "failed to send reply"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := d.handleRequest(socket.Request{Command: "respond_agent", Args: tt.args})
+			if resp.Success {
+				t.Fatal("expected failure")
+			}
+			if !strings.Contains(resp.Error, tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", resp.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestHandleRespondAgentResponseID verifies that relayed replies need a valid,
+// unused response ID
+func TestHandleRespondAgentResponseID(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			GithubURL:   "https://github.com/test/repo",
+			TmuxSession: "mc-test-repo-response-id",
+			Agents:      make(map[string]state.Agent),
+		})
+		s.AddAgent("test-repo", "worker-1", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker-1"})
+	})
+	defer cleanup()
+
+	resp := d.handleRequest(socket.Request{Command: "issue_response_id", Args: map[string]interface{}{
+		"repo": "test-repo", "agent": "worker-1",
+	}})
+	if !resp.Success {
+		t.Fatalf("issue_response_id failed: %s", resp.Error)
+	}
+	id := resp.Data.(socket.ResponseID).ID
+
+	respond := func(responseID string) socket.Response {
+		return d.handleRequest(socket.Request{Command: "respond_agent", Args: map[string]interface{}{
+			"repo": "test-repo", "agent": "worker-1", "text": "yes", "response_id": responseID,
+		}})
+	}
+
+	if resp := respond("forged"); !strings.Contains(resp.Error, "reply rejected") {
+		t.Errorf("unknown response ID should be rejected, got %q", resp.Error)
+	}
+	// The valid ID is accepted; sending then fails only because there is no tmux window
+	if resp := respond(id); !strings.Contains(resp.Error, "failed to send reply") {
+		t.Errorf("valid response ID should pass, got %q", resp.Error)
+	}
+	if resp := respond(id); !strings.Contains(resp.Error, "reply rejected") {
+		t.Errorf("a response ID must not be reusable, got %q", resp.Error)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "issue_response_id", Args: map[string]interface{}{
+		"repo": "test-repo", "agent": "ghost",
+	}})
+	if resp.Success {
+		t.Error("issuing an ID for an unknown agent should fail")
+	}
+}
+
+// TestMergeQueueMetrics verifies that completing a worker enqueues its branch
+// and that merge queue events feed the stats and Prometheus output
+func TestMergeQueueMetrics(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "mq-repo")
+	d.state.AddRepo("mq-repo", &state.Repository{
+		GithubURL:   "https://github.com/test/mq-repo",
+		TmuxSession: "mc-mq-repo",
+		Agents:      make(map[string]state.Agent),
+	})
+	d.state.AddAgent("mq-repo", "finisher", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: repoPath,
+		TmuxWindow:   "finisher",
+		CreatedAt:    time.Now(),
+	})
+
+	resp := d.handleRequest(socket.Request{Command: "complete_agent", Args: map[string]interface{}{
+		"repo":  "mq-repo",
+		"agent": "finisher",
+	}})
+	if !resp.Success {
+		t.Fatalf("handleCompleteAgent() failed: %s", resp.Error)
+	}
+
+	stats := d.mergeQueueStats("mq-repo")
+	if len(stats) != 1 || stats[0].Depth != 1 || stats[0].Pending[0].Branch != "work/mq-repo" {
+		t.Fatalf("completed worker should be enqueued, got %+v", stats)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"event": "ci_started", "branch": "work/mq-repo", "pr": float64(12)},
+		{"event": "ci_finished", "pr": float64(12)},
+		{"event": "merged", "pr": float64(12)},
+	} {
+		args["repo"] = "mq-repo"
+		if resp := d.handleRequest(socket.Request{Command: "merge_queue_event", Args: args}); !resp.Success {
+			t.Fatalf("merge_queue_event %v failed: %s", args, resp.Error)
+		}
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "merge_queue_event", Args: map[string]interface{}{
+		"repo": "mq-repo", "event": "exploded", "pr": float64(12),
+	}})
+	if resp.Success {
+		t.Error("invalid event should be rejected")
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "merge_queue_stats", Args: map[string]interface{}{
+		"repo": "mq-repo", "format": "prometheus",
+	}})
+	text := resp.Data.(socket.MergeQueueStats).Prometheus
+	for _, want := range []string{
+		`multiclaude_merge_queue_depth{repo="mq-repo"} 0`,
+		`multiclaude_merge_queue_resolved_total{repo="mq-repo",outcome="merged"} 1`,
+		`multiclaude_merge_queue_ci_runs_total{repo="mq-repo"} 1`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("prometheus output missing %q:\n%s", want, text)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(d.paths.MetricsDir(), mergeQueueMetricsFile))
+	if err != nil {
+		t.Fatalf("textfile not written: %v", err)
+	}
+	if !strings.Contains(string(data), `outcome="merged"} 1`) {
+		t.Errorf("textfile is stale:\n%s", data)
+	}
+
+	if resp := d.handleRequest(socket.Request{Command: "merge_queue_stats", Args: map[string]interface{}{"repo": "nope"}}); resp.Success {
+		t.Error("stats for an unknown repo should fail")
+	}
+}
+
+// TestHandleWorkerStatus verifies that worker_status reports each worker's
+// git state and window liveness
+func TestHandleWorkerStatus(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "status-repo")
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "worker commit")
+	if err := os.WriteFile(filepath.Join(repoPath, "dirty.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d.state.AddRepo("status-repo", &state.Repository{
+		TmuxSession: "mc-status-repo-nonexistent",
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, WorktreePath: repoPath, TmuxWindow: "supervisor"},
+			"busy":       {Type: state.AgentTypeWorker, WorktreePath: repoPath, TmuxWindow: "busy"},
+			"gone":       {Type: state.AgentTypeWorker, WorktreePath: filepath.Join(repoPath, "missing"), TmuxWindow: "gone"},
+		},
+	})
+
+	resp := d.handleRequest(socket.Request{Command: "worker_status", Args: map[string]interface{}{"repo": "status-repo"}})
+	if !resp.Success {
+		t.Fatalf("handleWorkerStatus failed: %s", resp.Error)
+	}
+	statuses, _ := resp.Data.([]socket.WorkerStatus)
+	if len(statuses) != 2 {
+		t.Fatalf("got %d statuses, want 2 workers: %v", len(statuses), statuses)
+	}
+
+	busy := statuses[0]
+	if busy.Name != "busy" || busy.Branch != "work/status-repo" || busy.Ahead != 1 || busy.Behind != 0 || !busy.Uncommitted {
+		t.Errorf("unexpected status for busy: %+v", busy)
+	}
+	if busy.Window != windowMissing {
+		t.Errorf("window without a session should be missing, got %v", busy.Window)
+	}
+
+	gone := statuses[1]
+	if gone.Name != "gone" || gone.Error == "" {
+		t.Errorf("worker with a missing worktree should report an error: %v", gone)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "worker_status", Args: map[string]interface{}{"repo": "nope"}})
+	if resp.Success {
+		t.Error("unknown repository should fail")
+	}
+}
+
+func TestCheckSpawnPolicy(t *testing.T) {
+	supervisor := spawnCaller{repo: "repo", name: "supervisor", agent: state.Agent{Type: state.AgentTypeSupervisor}}
+	worker := spawnCaller{repo: "repo", name: "w1", agent: state.Agent{Type: state.AgentTypeWorker}}
+
+	tests := []struct {
+		name         string
+		caller       spawnCaller
+		repo, class  string
+		byDefinition bool
+		wantErr      bool
+	}{
+		{"human raw prompt", spawnCaller{human: true}, "repo", "persistent", false, false},
+		{"supervisor ephemeral definition", supervisor, "repo", "ephemeral", true, false},
+		{"supervisor raw prompt", supervisor, "repo", "ephemeral", false, true},
+		{"supervisor persistent", supervisor, "repo", "persistent", true, true},
+		{"supervisor other repo", supervisor, "other", "ephemeral", true, true},
+		{"worker", worker, "repo", "ephemeral", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSpawnPolicy(tt.caller, tt.repo, tt.class, tt.byDefinition)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkSpawnPolicy() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleSpawnAgentCallerPolicy(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("test-repo", &state.Repository{
+			TmuxSession: "mc-test-repo",
+			Agents: map[string]state.Agent{
+				// The test process stands in for the supervisor's Claude process
+				"supervisor": {Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", PID: os.Getpid()},
+			},
+		})
+	})
+	defer cleanup()
+
+	if caller := d.identifySpawnCaller(nil); !caller.human {
+		t.Errorf("caller without a peer = %s, want a human", caller)
+	}
+	peer := &socket.Peer{PID: os.Getpid()}
+	if caller := d.identifySpawnCaller(peer); caller.human || caller.name != "supervisor" {
+		t.Fatalf("caller = %s, want the supervisor", caller)
+	}
+
+	spawn := func(args map[string]interface{}) socket.Response {
+		args["repo"] = "test-repo"
+		args["name"] = "reviewer-1"
+		return d.handleRequest(socket.Request{Command: "spawn_agent", Args: args, Peer: peer})
+	}
+
+	resp := spawn(map[string]interface{}{"class": "ephemeral", "prompt": "review things"})
+	if resp.Success || !strings.Contains(resp.Error, "permission denied") {
+		t.Errorf("supervisor raw prompt: %+v, want permission denied", resp)
+	}
+	resp = spawn(map[string]interface{}{"class": "persistent", "definition": "reviewer"})
+	if resp.Success || !strings.Contains(resp.Error, "permission denied") {
+		t.Errorf("supervisor persistent spawn: %+v, want permission denied", resp)
+	}
+	resp = spawn(map[string]interface{}{"class": "ephemeral", "definition": "no-such-definition"})
+	if resp.Success || strings.Contains(resp.Error, "permission denied") {
+		t.Errorf("unknown definition: %+v, want a lookup error", resp)
+	}
+	resp = spawn(map[string]interface{}{"class": "ephemeral", "prompt": "x", "definition": "reviewer"})
+	if resp.Success || !strings.Contains(resp.Error, "not both") {
+		t.Errorf("prompt and definition: %+v, want rejection", resp)
+	}
+	resp = spawn(map[string]interface{}{"class": "ephemeral", "prompt": strings.Repeat("x", maxSpawnPromptBytes+1)})
+	if resp.Success || !strings.Contains(resp.Error, "limit") {
+		t.Errorf("oversized prompt: %+v, want size rejection", resp)
+	}
+}
+
+func TestHandleSpawnAgentProbeFailureBlocksSpawn(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("probe-repo", &state.Repository{
+			TmuxSession: "mc-probe-repo",
+			Agents:      make(map[string]state.Agent),
+		})
+	})
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "probe-repo")
+	agentsDir := d.paths.RepoAgentsDir("probe-repo")
+	if err := os.MkdirAll(agentsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	definition := "---\nprobe: echo 'cannot find package foo'; exit 1\n---\n# Builder\n"
+	if err := os.WriteFile(filepath.Join(agentsDir, "builder.md"), []byte(definition), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "spawn_agent", Args: map[string]interface{}{
+		"repo":       "probe-repo",
+		"name":       "builder-1",
+		"class":      "ephemeral",
+		"definition": "builder",
+	}})
+	if resp.Success {
+		t.Fatal("spawn should fail when the probe fails")
+	}
+	if !strings.Contains(resp.Error, "cannot find package foo") {
+		t.Errorf("error %q should include the probe output", resp.Error)
+	}
+	if _, err := os.Stat(d.paths.AgentWorktree("probe-repo", "builder-1")); !os.IsNotExist(err) {
+		t.Error("the worktree should be removed after a failed probe")
+	}
+	if exists, _ := worktree.NewManager(repoPath).BranchExists("work/builder-1"); exists {
+		t.Error("the work branch should be removed after a failed probe")
+	}
+	if _, exists := d.state.GetAgent("probe-repo", "builder-1"); exists {
+		t.Error("no agent should be registered")
+	}
+}
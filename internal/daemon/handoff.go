@@ -0,0 +1,143 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// handleHandoffAgent transfers a worker's worktree and branch to a newly
+// spawned worker. The new worker starts with the old one's summary and a new
+// task; the old worker is told to stop and is cleaned up without removing the
+// worktree it no longer owns.
+func (d *Daemon) handleHandoffAgent(req socket.Request, args socket.HandoffAgentArgs) (socket.Handoff, error) {
+	repoName := args.Repo
+
+	fromName := args.From
+
+	toName := args.To
+
+	task := args.Task
+
+	summary := args.Summary
+
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.Handoff{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+	}
+
+	from, exists := d.state.GetAgent(repoName, fromName)
+	if !exists {
+		return socket.Handoff{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", fromName, repoName)
+	}
+	if from.Type != state.AgentTypeWorker {
+		return socket.Handoff{}, fmt.Errorf("only workers can hand off, '%s' is a %s", fromName, from.Type)
+	}
+	if from.ReadyForCleanup || from.WorktreePath == "" {
+		return socket.Handoff{}, fmt.Errorf("worker '%s' has already completed or handed off", fromName)
+	}
+	if _, exists := d.state.GetAgent(repoName, toName); exists {
+		return socket.Handoff{}, fmt.Errorf("agent '%s' already exists in repository '%s'", toName, repoName)
+	}
+
+	if err := d.tmux.CreateWindowAt(d.ctx, repo.TmuxSession, toName, from.WorktreePath); err != nil {
+		return socket.Handoff{}, fmt.Errorf("failed to create tmux window: %v", err)
+	}
+
+	promptFile, err := d.writePromptFileWithPrefix(repoName, state.AgentTypeWorker, toName, handoffBriefing(fromName, from, task, summary))
+	if err != nil {
+		d.tmux.KillWindow(d.ctx, repo.TmuxSession, toName)
+		return socket.Handoff{}, fmt.Errorf("failed to write prompt file: %v", err)
+	}
+
+	cfg := agentStartConfig{
+		agentName:  toName,
+		agentType:  state.AgentTypeWorker,
+		promptFile: promptFile,
+		workDir:    from.WorktreePath,
+	}
+	if err := d.startAgentWithConfig(repoName, repo, cfg); err != nil {
+		d.tmux.KillWindow(d.ctx, repo.TmuxSession, toName)
+		return socket.Handoff{}, fmt.Errorf("failed to start agent: %v", err)
+	}
+
+	if err := d.completeHandoff(repoName, fromName, toName, task, summary); err != nil {
+		return socket.Handoff{}, err
+	}
+
+	return socket.Handoff{From: fromName, To: toName, WorktreePath: from.WorktreePath}, nil
+}
+
+// completeHandoff moves ownership of the old worker's worktree to the new,
+// already registered worker and messages both of them
+func (d *Daemon) completeHandoff(repoName, fromName, toName, task, summary string) error {
+	from, exists := d.state.GetAgent(repoName, fromName)
+	if !exists {
+		return socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", fromName, repoName)
+	}
+	to, exists := d.state.GetAgent(repoName, toName)
+	if !exists {
+		return socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", toName, repoName)
+	}
+
+	to.Task = task
+	to.WorktreePath = from.WorktreePath
+	to.AllowedPaths = from.AllowedPaths
+	to.ScopePaths = from.ScopePaths
+	to.Labels = from.Labels
+	to.HandoffFrom = fromName
+	if err := d.state.UpdateAgent(repoName, toName, to); err != nil {
+		return fmt.Errorf("failed to update new worker: %w", err)
+	}
+
+	// Clearing the worktree path keeps cleanup of the old worker from
+	// deleting the worktree the new worker now owns
+	from.WorktreePath = ""
+	from.ReadyForCleanup = true
+	from.Summary = fmt.Sprintf("Handed off to %s", toName)
+	if summary != "" {
+		from.Summary += ": " + summary
+	}
+	if err := d.state.UpdateAgent(repoName, fromName, from); err != nil {
+		return fmt.Errorf("failed to update worker '%s': %w", fromName, err)
+	}
+
+	msgMgr := d.getMessageManager()
+	toMessage := fmt.Sprintf("You have taken over '%s''s worktree and branch. Your task: %s", fromName, task)
+	if summary != "" {
+		toMessage += "\n\nProgress so far: " + summary
+	}
+	if _, err := msgMgr.Send(repoName, "daemon", toName, toMessage); err != nil {
+		d.logger.Warn("Failed to send handoff message to %s: %v", toName, err)
+	}
+	fromMessage := fmt.Sprintf("Your worktree has been handed off to '%s'. Stop working now; your session will be closed.", toName)
+	if _, err := msgMgr.Send(repoName, "daemon", fromName, fromMessage); err != nil {
+		d.logger.Warn("Failed to send handoff message to %s: %v", fromName, err)
+	}
+	if _, err := msgMgr.Send(repoName, "daemon", "supervisor", fmt.Sprintf("Worker '%s' handed off to '%s': %s", fromName, toName, task)); err != nil {
+		d.logger.Warn("Failed to notify supervisor of handoff: %v", err)
+	}
+
+	d.logger.Info("Handed off %s/%s to %s", repoName, fromName, toName)
+	d.recordAction(repoName, feed.ActionHandedOff, fromName, fmt.Sprintf("to %s: %s", toName, task))
+	go d.routeMessages()
+	return nil
+}
+
+// handoffBriefing is prepended to the new worker's prompt
+func handoffBriefing(fromName string, from state.Agent, task, summary string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Handoff\n\nYou are continuing work started by worker '%s' in this worktree. ", fromName)
+	b.WriteString("Its commits and any uncommitted changes are already here; review them with `git status` and `git log` before you start.\n")
+	if from.Task != "" {
+		fmt.Fprintf(&b, "\nOriginal task: %s\n", from.Task)
+	}
+	if summary != "" {
+		fmt.Fprintf(&b, "\nProgress so far: %s\n", summary)
+	}
+	fmt.Fprintf(&b, "\nYour task: %s\n", task)
+	return b.String()
+}
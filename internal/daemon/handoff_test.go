@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestHandleHandoffAgentValidation(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo-handoff", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "worker-a", state.Agent{Type: state.AgentTypeWorker, WorktreePath: "/tmp/wt-a"})
+		s.AddAgent("repo", "worker-b", state.Agent{Type: state.AgentTypeWorker, WorktreePath: "/tmp/wt-b"})
+		s.AddAgent("repo", "done", state.Agent{Type: state.AgentTypeWorker, ReadyForCleanup: true, WorktreePath: "/tmp/wt-done"})
+		s.AddAgent("repo", "supervisor", state.Agent{Type: state.AgentTypeSupervisor})
+	})
+	defer cleanup()
+
+	args := func(from, to string) map[string]interface{} {
+		return map[string]interface{}{"repo": "repo", "from": from, "to": to, "task": "write tests"}
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{"missing task", map[string]interface{}{"repo": "repo", "from": "worker-a", "to": "new"}, "task for the new worker is required"},
+		{"unknown source", args("ghost", "new"), "agent 'ghost' not found"},
+		{"not a worker", args("supervisor", "new"), "only workers can hand off"},
+		{"already completed", args("done", "new"), "already completed or handed off"},
+		{"target exists", args("worker-a", "worker-b"), "agent 'worker-b' already exists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := d.handleRequest(socket.Request{Command: "handoff_agent", Args: tt.args})
+			if resp.Success || !strings.Contains(resp.Error, tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", resp.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompleteHandoff(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "worker-a", state.Agent{
+			Type:         state.AgentTypeWorker,
+			WorktreePath: "/tmp/wt-a",
+			Task:         "build the feature",
+			Labels:       []string{"backend"},
+			CreatedAt:    time.Now(),
+		})
+		// The new worker as registered by startAgentWithConfig
+		s.AddAgent("repo", "worker-b", state.Agent{Type: state.AgentTypeWorker, WorktreePath: "/tmp/wt-a"})
+	})
+	defer cleanup()
+
+	if err := d.completeHandoff("repo", "worker-a", "worker-b", "add tests", "feature done, tests missing"); err != nil {
+		t.Fatalf("completeHandoff failed: %v", err)
+	}
+
+	to, _ := d.state.GetAgent("repo", "worker-b")
+	if to.Task != "add tests" || to.HandoffFrom != "worker-a" || to.WorktreePath != "/tmp/wt-a" || len(to.Labels) != 1 {
+		t.Errorf("new worker not updated: %+v", to)
+	}
+
+	from, _ := d.state.GetAgent("repo", "worker-a")
+	if from.WorktreePath != "" || !from.ReadyForCleanup {
+		t.Errorf("old worker should release the worktree and await cleanup: %+v", from)
+	}
+	if !strings.Contains(from.Summary, "Handed off to worker-b: feature done") {
+		t.Errorf("old worker summary = %q", from.Summary)
+	}
+
+	msgs, err := d.getMessageManager().List("repo", "worker-b")
+	if err != nil || len(msgs) != 1 || !strings.Contains(msgs[0].Body, "Progress so far: feature done") {
+		t.Errorf("new worker should be briefed, got %+v (err %v)", msgs, err)
+	}
+	if msgs, _ := d.getMessageManager().List("repo", "worker-a"); len(msgs) != 1 {
+		t.Errorf("old worker should be told to stop, got %d messages", len(msgs))
+	}
+}
+
+func TestHandoffBriefing(t *testing.T) {
+	got := handoffBriefing("worker-a", state.Agent{Task: "build the feature"}, "add tests", "feature done")
+	for _, want := range []string{"worker 'worker-a'", "Original task: build the feature", "Progress so far: feature done", "Your task: add tests"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("briefing missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/launch"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// headlessStartPrompt is the first prompt of a headless agent spawned
+// without a task
+const headlessStartPrompt = "Start working on your assignment as described in your instructions."
+
+// headlessResumePrompt is the prompt of the run a restarted headless agent
+// resumes its session with
+const headlessResumePrompt = "You were restarted. Continue where you left off."
+
+// headlessKillGrace is how long a stopped headless run has to exit before it
+// is killed
+const headlessKillGrace = 5 * time.Second
+
+// headlessRun is a headless agent's running Claude process
+type headlessRun struct {
+	cmd  *exec.Cmd
+	done chan struct{} // closed once the process has exited
+}
+
+// Headless agents run Claude without a terminal, as a child of the daemon in
+// print mode (claude -p). Each run works on one prompt and exits; the next
+// prompt (a message, a reply, a nudge) resumes the same Claude session in a
+// new run. Output is appended to the agent's log file and the running
+// process is recorded in a PID file, so a restarted daemon leaves a run it
+// didn't start alone until it finishes.
+
+// headlessKey identifies an agent in the headless run maps
+func headlessKey(repoName, agentName string) string {
+	return repoName + "/" + agentName
+}
+
+// headlessBusy reports whether a headless agent's Claude process is running,
+// including one started by a previous daemon
+func (d *Daemon) headlessBusy(repoName, agentName string) bool {
+	d.headlessMu.Lock()
+	defer d.headlessMu.Unlock()
+	return d.headlessBusyLocked(repoName, agentName)
+}
+
+// headlessBusyLocked is headlessBusy with headlessMu held
+func (d *Daemon) headlessBusyLocked(repoName, agentName string) bool {
+	if _, running := d.headlessRuns[headlessKey(repoName, agentName)]; running {
+		return true
+	}
+	pidFile := NewPIDFile(d.paths.HeadlessPIDFile(repoName, agentName))
+	alive, _, _ := pidFile.IsRunning()
+	if !alive {
+		pidFile.Remove()
+	}
+	return alive
+}
+
+// startHeadless starts a headless agent's first run, which creates its
+// Claude session, and returns the run's PID
+func (d *Daemon) startHeadless(repoName, agentName string, agent state.Agent, promptFile, prompt string) (int, error) {
+	if prompt == "" {
+		prompt = headlessStartPrompt
+	}
+	d.headlessMu.Lock()
+	defer d.headlessMu.Unlock()
+	return d.startHeadlessRunLocked(repoName, agentName, agent, promptFile, prompt, false)
+}
+
+// deliverHeadless gives a headless agent a prompt: in a new run resuming its
+// session, or once the current run has finished when it is busy
+func (d *Daemon) deliverHeadless(repoName, agentName string, agent state.Agent, prompt string) error {
+	d.headlessMu.Lock()
+	defer d.headlessMu.Unlock()
+	key := headlessKey(repoName, agentName)
+	if d.headlessBusyLocked(repoName, agentName) {
+		d.headlessQueued[key] = append(d.headlessQueued[key], prompt)
+		return nil
+	}
+	promptFile, err := d.agentPromptFile(repoName, agentName, agent)
+	if err != nil {
+		return err
+	}
+	_, err = d.startHeadlessRunLocked(repoName, agentName, agent, promptFile, prompt, true)
+	return err
+}
+
+// startHeadlessRunLocked runs Claude in print mode on prompt in the agent's
+// worktree, under the repository's launch template, and waits for it in the
+// background. headlessMu must be held.
+func (d *Daemon) startHeadlessRunLocked(repoName, agentName string, agent state.Agent, promptFile, prompt string, resume bool) (int, error) {
+	template, err := launch.Load(d.paths.RepoDir(repoName), string(agent.Type))
+	if err != nil {
+		return 0, err
+	}
+	if err := template.Validate(agent.WorktreePath); err != nil {
+		return 0, fmt.Errorf("invalid %s in %s: %w", launch.ConfigFile, repoName, err)
+	}
+	binaryPath, err := d.getClaudeBinaryPath()
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve claude binary: %w", err)
+	}
+
+	sessionFlag := "--session-id"
+	if resume {
+		sessionFlag = "--resume"
+	}
+	// The prompt is read from stdin, which spares quoting it for the shell
+	flags := fmt.Sprintf("%s %s --dangerously-skip-permissions --append-system-prompt-file %s -p",
+		sessionFlag, agent.SessionID, promptFile)
+
+	isWorker := agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview
+	logPath := d.paths.AgentLogFile(repoName, agentName, isWorker)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open agent log: %w", err)
+	}
+	fmt.Fprintf(logFile, "\n[multiclaude %s] %s\n\n", d.clock.Now().Format("2006-01-02 15:04:05"), prompt)
+
+	cmd := exec.Command("sh", "-c", template.Command(binaryPath, agent.WorktreePath, flags))
+	cmd.Dir = agent.WorktreePath
+	cmd.Stdin = strings.NewReader(prompt)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	setHeadlessProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return 0, fmt.Errorf("failed to start Claude: %w", err)
+	}
+
+	pid := cmd.Process.Pid
+	pidPath := d.paths.HeadlessPIDFile(repoName, agentName)
+	if err := os.MkdirAll(filepath.Dir(pidPath), 0755); err != nil {
+		d.logger.Warn("Failed to create headless PID directory: %v", err)
+	} else if err := os.WriteFile(pidPath, []byte(fmt.Sprintf("%d\n", pid)), 0644); err != nil {
+		d.logger.Warn("Failed to write PID file of %s/%s: %v", repoName, agentName, err)
+	}
+	if err := d.state.UpdateAgentPID(repoName, agentName, pid); err != nil {
+		d.logger.Debug("Failed to update PID of %s/%s: %v", repoName, agentName, err)
+	}
+
+	run := &headlessRun{cmd: cmd, done: make(chan struct{})}
+	key := headlessKey(repoName, agentName)
+	d.headlessRuns[key] = run
+	d.logger.Info("Started headless run of %s/%s (PID %d, resume=%v)", repoName, agentName, pid, resume)
+
+	go func() {
+		err := cmd.Wait()
+		logFile.Close()
+		NewPIDFile(pidPath).Remove()
+		if err != nil {
+			d.logger.Warn("Headless run of %s/%s exited: %v", repoName, agentName, err)
+		} else {
+			d.logger.Info("Headless run of %s/%s finished", repoName, agentName)
+		}
+
+		d.headlessMu.Lock()
+		if d.headlessRuns[key] == run {
+			delete(d.headlessRuns, key)
+		}
+		close(run.done)
+		d.headlessMu.Unlock()
+
+		if d.ctx.Err() == nil {
+			d.drainHeadless(repoName, agentName)
+		}
+	}()
+	return pid, nil
+}
+
+// drainHeadless starts a run for the prompts queued for an idle headless
+// agent, all at once
+func (d *Daemon) drainHeadless(repoName, agentName string) {
+	d.headlessMu.Lock()
+	defer d.headlessMu.Unlock()
+	key := headlessKey(repoName, agentName)
+	queued := d.headlessQueued[key]
+	if len(queued) == 0 || d.headlessBusyLocked(repoName, agentName) {
+		return
+	}
+	delete(d.headlessQueued, key)
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists || agent.ReadyForCleanup {
+		return
+	}
+	promptFile, err := d.agentPromptFile(repoName, agentName, agent)
+	if err == nil {
+		_, err = d.startHeadlessRunLocked(repoName, agentName, agent, promptFile, strings.Join(queued, "\n\n"), true)
+	}
+	if err != nil {
+		d.logger.Error("Failed to resume headless agent %s/%s: %v", repoName, agentName, err)
+	}
+}
+
+// stopHeadless terminates a headless agent's running Claude process and
+// drops the prompts queued for it
+func (d *Daemon) stopHeadless(repoName, agentName string) {
+	d.headlessMu.Lock()
+	key := headlessKey(repoName, agentName)
+	delete(d.headlessQueued, key)
+	run := d.headlessRuns[key]
+	d.headlessMu.Unlock()
+
+	pidFile := NewPIDFile(d.paths.HeadlessPIDFile(repoName, agentName))
+	if run != nil {
+		pid := run.cmd.Process.Pid
+		killHeadlessProcess(pid, false)
+		select {
+		case <-run.done:
+		case <-time.After(headlessKillGrace):
+			killHeadlessProcess(pid, true)
+			<-run.done
+		}
+		return
+	}
+	// A run a previous daemon started, which can't be waited for
+	if alive, pid, _ := pidFile.IsRunning(); alive {
+		killHeadlessProcess(pid, true)
+	}
+	pidFile.Remove()
+}
+
+// sendToAgent delivers text to an agent as if typed at its prompt: into its
+// tmux window, or as the next prompt of a headless agent
+func (d *Daemon) sendToAgent(repoName, session, agentName string, agent state.Agent, text string) error {
+	if agent.Headless {
+		return d.deliverHeadless(repoName, agentName, agent, text)
+	}
+	return d.tmux.SendKeysLiteralWithEnter(d.ctx, session, agent.TmuxWindow, text)
+}
+
+// headlessOutputTail returns the last n non-blank lines a headless agent
+// wrote to its log, standing in for the screen it doesn't have
+func (d *Daemon) headlessOutputTail(repoName, agentName string, agent state.Agent, n int) []string {
+	isWorker := agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview
+	tail, err := readFileTail(d.paths.AgentLogFile(repoName, agentName, isWorker), outputLoopTailBytes)
+	if err != nil {
+		return nil
+	}
+	return lastLines(tail, n)
+}
+
+// startHeadlessAgent registers a headless agent and starts its first run
+func (d *Daemon) startHeadlessAgent(repoName string, cfg agentStartConfig, sessionID string) error {
+	agent := state.Agent{
+		Type:         cfg.agentType,
+		WorktreePath: cfg.workDir,
+		SessionID:    sessionID,
+		Headless:     true,
+		CreatedAt:    d.clock.Now(),
+	}
+	if err := d.state.AddAgent(repoName, cfg.agentName, agent); err != nil {
+		return fmt.Errorf("failed to register agent: %w", err)
+	}
+
+	// Skip actual Claude startup in test mode
+	if os.Getenv("MULTICLAUDE_TEST_MODE") != "1" {
+		if _, err := d.startHeadless(repoName, cfg.agentName, agent, cfg.promptFile, cfg.initialMessage); err != nil {
+			d.state.RemoveAgent(repoName, cfg.agentName)
+			return err
+		}
+	}
+
+	d.logger.Info("Started and registered headless agent %s/%s", repoName, cfg.agentName)
+	return nil
+}
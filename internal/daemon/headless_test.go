@@ -0,0 +1,163 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// fakeClaude puts a claude on PATH that prints its arguments and prompt,
+// then sleeps for as long as the prompt says (e.g. "sleep 1")
+func fakeClaude(t *testing.T) {
+	t.Helper()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+echo "args: $*"
+prompt=$(cat)
+echo "prompt: $prompt"
+case "$prompt" in
+sleep*) $prompt ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// waitForCondition polls cond until it holds or five seconds pass
+func waitForCondition(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestHeadlessAgentRuns(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("MULTICLAUDE_TEST_MODE", "0")
+	fakeClaude(t)
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("ci-repo", &state.Repository{TmuxSession: "mc-ci-repo", Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatal(err)
+	}
+	workDir := t.TempDir()
+	promptFile := filepath.Join(d.paths.Root, "prompts", "quiet-owl.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	if err := os.WriteFile(promptFile, []byte("You are a worker."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := d.startAgentWithConfig("ci-repo", nil, agentStartConfig{
+		agentName:      "quiet-owl",
+		agentType:      state.AgentTypeWorker,
+		promptFile:     promptFile,
+		workDir:        workDir,
+		initialMessage: "sleep 1",
+		headless:       true,
+	})
+	if err != nil {
+		t.Fatalf("starting headless agent failed: %v", err)
+	}
+	agent, exists := d.state.GetAgent("ci-repo", "quiet-owl")
+	if !exists || !agent.Headless || agent.TmuxWindow != "" || agent.PID <= 0 {
+		t.Fatalf("registered agent = %+v, want a headless agent with a PID", agent)
+	}
+	if !d.headlessBusy("ci-repo", "quiet-owl") {
+		t.Error("agent isn't busy during its first run")
+	}
+	pidPath := d.paths.HeadlessPIDFile("ci-repo", "quiet-owl")
+	if _, err := os.Stat(pidPath); err != nil {
+		t.Errorf("PID file missing during the run: %v", err)
+	}
+
+	// A reply during the run waits for it, then resumes the session
+	resp := d.handleRequest(socket.Request{Command: "respond_agent", Args: map[string]interface{}{
+		"repo": "ci-repo", "agent": "quiet-owl", "text": "use the staging fixtures",
+	}})
+	if !resp.Success {
+		t.Fatalf("respond_agent failed: %s", resp.Error)
+	}
+
+	logPath := d.paths.AgentLogFile("ci-repo", "quiet-owl", true)
+	waitForCondition(t, "the queued reply's run to finish", func() bool {
+		data, _ := os.ReadFile(logPath)
+		return strings.Contains(string(data), "prompt: use the staging fixtures") && !d.headlessBusy("ci-repo", "quiet-owl")
+	})
+	data, _ := os.ReadFile(logPath)
+	log := string(data)
+	if !strings.Contains(log, "args: --session-id "+agent.SessionID) || !strings.Contains(log, "--append-system-prompt-file "+promptFile+" -p") {
+		t.Errorf("first run wasn't started with its session and prompt file:\n%s", log)
+	}
+	if !strings.Contains(log, "args: --resume "+agent.SessionID) {
+		t.Errorf("reply didn't resume the session:\n%s", log)
+	}
+	if _, err := os.Stat(pidPath); !os.IsNotExist(err) {
+		t.Errorf("PID file still exists after the runs finished: %v", err)
+	}
+
+	screen := d.handleRequest(socket.Request{Command: "agent_screen", Args: map[string]interface{}{
+		"repo": "ci-repo", "agent": "quiet-owl",
+	}})
+	if !screen.Success || !strings.Contains(screen.Data.(socket.AgentScreen).Screen, "prompt: use the staging fixtures") {
+		t.Errorf("agent_screen = %+v, want the end of the agent's output", screen)
+	}
+}
+
+func TestStopHeadless(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("MULTICLAUDE_TEST_MODE", "0")
+	fakeClaude(t)
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	if err := d.state.AddRepo("ci-repo", &state.Repository{Agents: make(map[string]state.Agent)}); err != nil {
+		t.Fatal(err)
+	}
+	agent := state.Agent{Type: state.AgentTypeWorker, WorktreePath: t.TempDir(), SessionID: "s-1", Headless: true}
+	if err := d.state.AddAgent("ci-repo", "busy-fox", agent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.startHeadless("ci-repo", "busy-fox", agent, "prompt.md", "sleep 30"); err != nil {
+		t.Fatalf("startHeadless failed: %v", err)
+	}
+	if err := d.deliverHeadless("ci-repo", "busy-fox", agent, "never delivered"); err != nil {
+		t.Fatalf("deliverHeadless failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.stopHeadless("ci-repo", "busy-fox")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("stopHeadless didn't return")
+	}
+	if d.headlessBusy("ci-repo", "busy-fox") {
+		t.Error("agent is still busy after stopHeadless")
+	}
+	d.headlessMu.Lock()
+	queued := len(d.headlessQueued[headlessKey("ci-repo", "busy-fox")])
+	d.headlessMu.Unlock()
+	if queued != 0 {
+		t.Errorf("%d prompts still queued after stopHeadless", queued)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setHeadlessProcessGroup puts a headless run in its own process group, so
+// stopping it reaches every process Claude started, and so it outlives a
+// daemon restart
+func setHeadlessProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killHeadlessProcess signals the process group of a headless run: SIGTERM,
+// or SIGKILL when force is set
+func killHeadlessProcess(pid int, force bool) error {
+	signal := syscall.SIGTERM
+	if force {
+		signal = syscall.SIGKILL
+	}
+	return syscall.Kill(-pid, signal)
+}
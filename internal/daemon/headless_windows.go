@@ -0,0 +1,22 @@
+package daemon
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setHeadlessProcessGroup starts a headless run in a new process group
+func setHeadlessProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// killHeadlessProcess ends the process tree of a headless run, forcibly when
+// force is set
+func killHeadlessProcess(pid int, force bool) error {
+	args := []string{"/T", "/PID", strconv.Itoa(pid)}
+	if force {
+		args = append([]string{"/F"}, args...)
+	}
+	return exec.Command("taskkill", args...).Run()
+}
@@ -0,0 +1,113 @@
+package daemon
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// lane classifies socket commands by how quickly they must respond
+type lane int
+
+const (
+	// laneInteractive commands are quick reads and small state updates that
+	// run immediately so the CLI stays responsive
+	laneInteractive lane = iota
+	// laneBackground commands do heavy maintenance (git, tmux, filesystem scans)
+	// and run through a small bounded pool
+	laneBackground
+)
+
+// String returns the lane name
+func (l lane) String() string {
+	if l == laneBackground {
+		return "background"
+	}
+	return "interactive"
+}
+
+// backgroundCommands lists the socket commands that run in the background lane.
+// Everything else is interactive.
+var backgroundCommands = map[string]bool{
+	"trigger_cleanup":      true,
+	"repair_state":         true,
+	"spawn_agent":          true,
+	"handoff_agent":        true,
+	"restart_agent":        true,
+	"route_messages":       true,
+	"export_metrics":       true,
+	"pull_agent_branch":    true,
+	"recover_agent":        true,
+	"add_scratch_worktree": true,
+	"worker_status":        true,
+	"merge_queue_simulate": true,
+	"timeline":             true,
+	"fleet_status":         true,
+}
+
+// commandLane returns the lane a command belongs to
+func commandLane(command string) lane {
+	if backgroundCommands[command] {
+		return laneBackground
+	}
+	return laneInteractive
+}
+
+// defaultBackgroundWorkers is how many background commands may run at once
+const defaultBackgroundWorkers = 2
+
+// laneScheduler runs interactive commands immediately and bounds how many
+// background commands run concurrently, so a slow cleanup can't pile up
+// git/tmux work that makes quick reads lag.
+type laneScheduler struct {
+	background chan struct{}
+
+	queued    atomic.Int64
+	running   atomic.Int64
+	completed atomic.Int64
+}
+
+// newLaneScheduler creates a scheduler with the given number of background workers
+func newLaneScheduler(workers int) *laneScheduler {
+	if workers < 1 {
+		workers = 1
+	}
+	return &laneScheduler{background: make(chan struct{}, workers)}
+}
+
+// run executes fn in the given lane. Background work waits for a free worker
+// and gives up if ctx is cancelled while queued.
+func (s *laneScheduler) run(ctx context.Context, l lane, fn func() socket.Response) socket.Response {
+	if l == laneInteractive {
+		return fn()
+	}
+
+	s.queued.Add(1)
+	select {
+	case s.background <- struct{}{}:
+		s.queued.Add(-1)
+	case <-ctx.Done():
+		s.queued.Add(-1)
+		return socket.Response{Success: false, Error: "daemon is shutting down"}
+	}
+
+	s.running.Add(1)
+	defer func() {
+		s.running.Add(-1)
+		s.completed.Add(1)
+		<-s.background
+	}()
+
+	return fn()
+}
+
+// stats returns a snapshot of background lane activity
+func (s *laneScheduler) stats() socket.LaneStats {
+	return socket.LaneStats{
+		BackgroundWorkers:   cap(s.background),
+		BackgroundQueued:    s.queued.Load(),
+		BackgroundRunning:   s.running.Load(),
+		BackgroundCompleted: s.completed.Load(),
+	}
+}
@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+func TestCommandLane(t *testing.T) {
+	tests := []struct {
+		command string
+		want    lane
+	}{
+		{"ping", laneInteractive},
+		{"status", laneInteractive},
+		{"list_agents", laneInteractive},
+		{"trigger_cleanup", laneBackground},
+		{"repair_state", laneBackground},
+		{"spawn_agent", laneBackground},
+		{"fleet_status", laneBackground},
+		{"unknown_command", laneInteractive},
+	}
+
+	for _, tt := range tests {
+		if got := commandLane(tt.command); got != tt.want {
+			t.Errorf("commandLane(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestLaneSchedulerInteractiveNotBlockedByBackground(t *testing.T) {
+	s := newLaneScheduler(1)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Occupy the only background worker, then queue a second background job
+	go func() {
+		defer wg.Done()
+		s.run(ctx, laneBackground, func() socket.Response {
+			close(started)
+			<-release
+			return socket.Response{Success: true}
+		})
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		s.run(ctx, laneBackground, func() socket.Response { return socket.Response{Success: true} })
+	}()
+
+	// Wait for the second job to be queued
+	deadline := time.Now().Add(time.Second)
+	for s.queued.Load() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if s.queued.Load() != 1 {
+		t.Fatalf("expected 1 queued background job, got %d", s.queued.Load())
+	}
+
+	// Interactive work must run immediately
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx, laneInteractive, func() socket.Response { return socket.Response{Success: true} })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("interactive command was blocked by background work")
+	}
+
+	close(release)
+	wg.Wait()
+
+	stats := s.stats()
+	if stats.BackgroundCompleted != 2 {
+		t.Errorf("expected 2 completed background jobs, got %v", stats.BackgroundCompleted)
+	}
+	if stats.BackgroundRunning != 0 || stats.BackgroundQueued != 0 {
+		t.Errorf("expected idle scheduler, got %+v", stats)
+	}
+}
+
+func TestLaneSchedulerCancelledWhileQueued(t *testing.T) {
+	s := newLaneScheduler(1)
+	s.background <- struct{}{} // occupy the only worker
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resp := s.run(ctx, laneBackground, func() socket.Response {
+		t.Error("background work should not run after cancellation")
+		return socket.Response{Success: true}
+	})
+	if resp.Success {
+		t.Error("expected failure response when cancelled")
+	}
+}
@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/logstore"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// archiveLogs moves rotated agent logs to the configured log storage and
+// deletes segments past its retention
+func (d *Daemon) archiveLogs(now time.Time) {
+	cfg := d.state.GetLogStorage()
+	if !cfg.Remote() && cfg.RetentionDays == 0 {
+		return
+	}
+	store, err := logstore.Open(cfg, d.paths.OutputDir)
+	if err != nil {
+		d.logger.Error("Failed to open log storage %s: %v", cfg, err)
+		return
+	}
+
+	if cfg.Remote() {
+		if n, err := d.uploadLogSegments(store); err != nil {
+			d.logger.Error("Failed to archive logs to %s: %v", cfg, err)
+		} else if n > 0 {
+			d.logger.Info("Archived %d log segment(s) to %s", n, cfg)
+		}
+	}
+
+	if cfg.RetentionDays > 0 {
+		cutoff := now.Add(-time.Duration(cfg.RetentionDays) * 24 * time.Hour)
+		count, size, err := logstore.Prune(d.ctx, store, cutoff)
+		if err != nil {
+			d.logger.Error("Failed to apply log retention in %s: %v", cfg, err)
+		}
+		if count > 0 {
+			d.logger.Info("Deleted %d log segment(s) (%d bytes) older than %d days from %s", count, size, cfg.RetentionDays, cfg)
+		}
+	}
+}
+
+// uploadLogSegments streams the rotated logs in the output directory to store
+// and removes the local copies. A segment that fails to upload stays on disk
+// and is retried on the next health check.
+func (d *Daemon) uploadLogSegments(store logstore.Store) (int, error) {
+	local := logstore.NewLocalStore(d.paths.OutputDir)
+	segments, err := logstore.Segments(d.ctx, local, "")
+	if err != nil {
+		return 0, err
+	}
+
+	uploaded := 0
+	for _, seg := range segments {
+		if err := d.uploadLogSegment(store, seg); err != nil {
+			return uploaded, fmt.Errorf("%s: %w", seg.Key, err)
+		}
+		uploaded++
+	}
+	return uploaded, nil
+}
+
+// uploadLogSegment uploads one local segment and removes it
+func (d *Daemon) uploadLogSegment(store logstore.Store, seg logstore.Object) error {
+	path := filepath.Join(d.paths.OutputDir, filepath.FromSlash(seg.Key))
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := store.Put(d.ctx, seg.Key, f, seg.Size); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// handleSetLogStorage changes where rotated agent logs are kept, starting
+// with the next health check. Segments already archived elsewhere stay where
+// they are.
+func (d *Daemon) handleSetLogStorage(req socket.Request, args socket.SetLogStorageArgs) (socket.LogStorage, error) {
+	cfg := logstore.Config{
+		Backend:       args.Backend,
+		Bucket:        args.Bucket,
+		Prefix:        args.Prefix,
+		Region:        args.Region,
+		Endpoint:      args.Endpoint,
+		RetentionDays: args.RetentionDays,
+	}
+
+	// Opening the store checks the config and, for S3, the credentials the
+	// daemon will upload with
+	if _, err := logstore.Open(cfg, d.paths.OutputDir); err != nil {
+		return socket.LogStorage{}, err
+	}
+	if err := d.state.SetLogStorage(cfg); err != nil {
+		return socket.LogStorage{}, err
+	}
+
+	d.logger.Info("Log storage set to %s", cfg)
+	return socket.LogStorage{Storage: cfg.String()}, nil
+}
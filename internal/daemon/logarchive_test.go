@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/logstore"
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+func TestUploadLogSegments(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	workersDir := d.paths.WorkersOutputDir("repo")
+	if err := os.MkdirAll(workersDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	live := filepath.Join(workersDir, "swift-fox.log")
+	segment := live + ".20260102-150405"
+	os.WriteFile(live, []byte("live\n"), 0644)
+	os.WriteFile(segment, []byte("rotated\n"), 0644)
+
+	// Any store works as the destination; a second directory stands in for
+	// a bucket
+	archive := logstore.NewLocalStore(t.TempDir())
+	n, err := d.uploadLogSegments(archive)
+	if err != nil {
+		t.Fatalf("uploadLogSegments failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("uploaded %d segments, want 1", n)
+	}
+	if _, err := os.Stat(segment); !os.IsNotExist(err) {
+		t.Error("uploaded segment should be removed locally")
+	}
+	if _, err := os.Stat(live); err != nil {
+		t.Error("live log must stay in place")
+	}
+	archived, _ := archive.List(d.ctx, "repo/workers/swift-fox.log.")
+	if len(archived) != 1 || archived[0].Key != "repo/workers/swift-fox.log.20260102-150405" {
+		t.Errorf("archived = %+v", archived)
+	}
+}
+
+func TestArchiveLogsRetention(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	resp := d.handleRequest(socket.Request{Command: "set_log_storage", Args: map[string]interface{}{"backend": "azure"}})
+	if resp.Success {
+		t.Fatal("set_log_storage should reject unknown backends")
+	}
+	resp = d.handleRequest(socket.Request{Command: "set_log_storage", Args: map[string]interface{}{"retention_days": float64(7)}})
+	if !resp.Success {
+		t.Fatalf("set_log_storage failed: %s", resp.Error)
+	}
+
+	repoDir := d.paths.RepoOutputDir("repo")
+	os.MkdirAll(repoDir, 0755)
+	oldSegment := filepath.Join(repoDir, "supervisor.log.20260101-000000")
+	newSegment := filepath.Join(repoDir, "supervisor.log.20260110-000000")
+	os.WriteFile(oldSegment, []byte("old\n"), 0644)
+	os.WriteFile(newSegment, []byte("new\n"), 0644)
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	os.Chtimes(oldSegment, old, old)
+
+	d.archiveLogs(time.Now())
+
+	if _, err := os.Stat(oldSegment); !os.IsNotExist(err) {
+		t.Error("segment past retention should be deleted")
+	}
+	if _, err := os.Stat(newSegment); err != nil {
+		t.Error("recent segment should be kept")
+	}
+}
@@ -0,0 +1,417 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// mergeEngineSubsystem is the GitHub budget the daemon's merge queue is
+// charged to
+const mergeEngineSubsystem = "merge-queue"
+
+// mergeEngineTick is how often the merge queue loop looks for repositories
+// whose poll interval has passed
+const mergeEngineTick = 15 * time.Second
+
+// mergeEngineTimeout bounds one pass over a repository's queue
+const mergeEngineTimeout = 5 * time.Minute
+
+// mergeEngineBranch is the local branch PRs are rebased on
+const mergeEngineBranch = "multiclaude/merge-queue"
+
+// holdLabel pauses a PR, as it does for the merge-queue agent
+const holdLabel = "needs-human-input"
+
+// noChecksGrace is how long the merge queue waits for the first check on a
+// PR head when no checks are required, before deciding the repository has
+// no CI to wait for
+const noChecksGrace = 10 * time.Minute
+
+// uncheckedHead is a PR head no check has reported on, and when the merge
+// queue first saw it
+type uncheckedHead struct {
+	sha   string
+	since time.Time
+}
+
+// mergeEngineLoop merges labeled PRs in repositories with auto-merge on
+func (d *Daemon) mergeEngineLoop() {
+	d.periodicLoop("merge queue engine", mergeEngineTick, nil, d.runMergeEngines)
+}
+
+// runMergeEngines processes the queue of every auto-merge repository whose
+// poll interval has passed
+func (d *Daemon) runMergeEngines() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		cfg := repo.MergeQueueConfig
+		if !cfg.AutoMerge || repo.GithubURL == "" || !d.mergeEngineDue(repoName, cfg.PollInterval()) {
+			continue
+		}
+		if d.isReadOnlyRepo(repoName, "merge queue") {
+			continue
+		}
+		d.processMergeQueue(repoName)
+	}
+}
+
+// mergeEngineDue reports whether a repository's queue is due a poll,
+// marking it polled if so
+func (d *Daemon) mergeEngineDue(repoName string, interval time.Duration) bool {
+	d.mergeEngineMu.Lock()
+	defer d.mergeEngineMu.Unlock()
+	now := d.clock.Now()
+	if last, ok := d.mergeEnginePolled[repoName]; ok && now.Sub(last) < interval {
+		return false
+	}
+	d.mergeEnginePolled[repoName] = now
+	return true
+}
+
+// mergeEngineHeld reports whether a PR was set aside at its current head,
+// after failing CI or conflicting. Pushing a new head puts it back in line.
+func (d *Daemon) mergeEngineHeld(repoName string, pr github.PullRequest) bool {
+	d.mergeEngineMu.Lock()
+	defer d.mergeEngineMu.Unlock()
+	return d.mergeEngineHeldAt[repoName][pr.Number] == pr.Head.SHA
+}
+
+// awaitingFirstCheck reports whether a PR head no check has reported on is
+// still within noChecksGrace of when the merge queue first saw it. Checks
+// take a while to appear after a push, so merging at once would merge a
+// freshly rebased head before CI has started.
+func (d *Daemon) awaitingFirstCheck(repoName string, pr *github.PullRequest) bool {
+	d.mergeEngineMu.Lock()
+	defer d.mergeEngineMu.Unlock()
+	now := d.clock.Now()
+	seen, ok := d.mergeEngineUnchecked[repoName][pr.Number]
+	if !ok || seen.sha != pr.Head.SHA {
+		if d.mergeEngineUnchecked[repoName] == nil {
+			d.mergeEngineUnchecked[repoName] = make(map[int]uncheckedHead)
+		}
+		d.mergeEngineUnchecked[repoName][pr.Number] = uncheckedHead{sha: pr.Head.SHA, since: now}
+		return true
+	}
+	return now.Sub(seen.since) < noChecksGrace
+}
+
+// holdPR sets a PR aside until its head changes
+func (d *Daemon) holdPR(repoName string, pr *github.PullRequest) {
+	d.mergeEngineMu.Lock()
+	defer d.mergeEngineMu.Unlock()
+	if d.mergeEngineHeldAt[repoName] == nil {
+		d.mergeEngineHeldAt[repoName] = make(map[int]string)
+	}
+	d.mergeEngineHeldAt[repoName][pr.Number] = pr.Head.SHA
+}
+
+// processMergeQueue takes a repository's labeled PRs in order and moves the
+// first one that isn't set aside forward: rebasing it onto its base when it
+// is behind or conflicts, waiting while its checks run, and merging it once
+// they pass. Merged and failed PRs let the next one move in the same pass.
+func (d *Daemon) processMergeQueue(repoName string) {
+	d.mergeEngineRunMu.Lock()
+	defer d.mergeEngineRunMu.Unlock()
+
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return
+	}
+	cfg, err := d.state.GetMergeQueueConfig(repoName)
+	if err != nil {
+		return
+	}
+	owner, name, err := github.ParseRepoURL(repo.GithubURL)
+	if err != nil {
+		d.logger.Warn("Merge queue skipped for %s: %v", repoName, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, mergeEngineTimeout)
+	defer cancel()
+
+	prs, err := d.github.ListLabeledPullRequests(ctx, mergeEngineSubsystem, owner, name, cfg.MergeLabel())
+	if err != nil {
+		d.logger.Warn("Merge queue for %s could not list PRs: %v", repoName, err)
+		return
+	}
+	d.sortMergeQueue(repoName, prs)
+
+	for _, listed := range prs {
+		if listed.Draft || listed.HasLabel(holdLabel) || d.mergeEngineHeld(repoName, listed) {
+			continue
+		}
+		pr, err := d.github.GetPullRequest(ctx, mergeEngineSubsystem, owner, name, listed.Number)
+		if err != nil {
+			d.logger.Warn("Merge queue for %s could not fetch PR #%d: %v", repoName, listed.Number, err)
+			return
+		}
+		if !d.advanceQueuedPR(ctx, repoName, owner, name, cfg, pr) {
+			return
+		}
+	}
+}
+
+// sortMergeQueue orders PRs by the priority of their worker's task, then by
+// age
+func (d *Daemon) sortMergeQueue(repoName string, prs []github.PullRequest) {
+	priority := make(map[string]state.TaskPriority)
+	if repo, exists := d.state.GetRepo(repoName); exists {
+		for _, item := range repo.MergeQueue {
+			if item.Pending() && item.Branch != "" {
+				priority[item.Branch] = item.Priority
+			}
+		}
+	}
+	sort.SliceStable(prs, func(i, j int) bool {
+		ri, rj := priority[prs[i].Head.Ref].Rank(), priority[prs[j].Head.Ref].Rank()
+		if ri != rj {
+			return ri < rj
+		}
+		return prs[i].Number < prs[j].Number
+	})
+}
+
+// advanceQueuedPR moves one PR forward. It returns true when the PR no longer
+// holds up the queue: it was merged, or set aside until its author pushes.
+func (d *Daemon) advanceQueuedPR(ctx context.Context, repoName, owner, name string, cfg state.MergeQueueConfig, pr *github.PullRequest) bool {
+	key := state.MergeQueueItem{Branch: pr.Head.Ref, PRNumber: pr.Number, Worker: d.branchWorker(repoName, pr.Head.Ref)}
+	item := d.pendingQueueItem(repoName, key)
+	if item == nil {
+		if _, err := d.recordMergeQueueEvent(repoName, key, state.MergeQueueEnqueued); err != nil {
+			d.logger.Warn("Failed to enqueue PR #%d for %s: %v", pr.Number, repoName, err)
+		}
+		item = d.pendingQueueItem(repoName, key)
+	}
+
+	switch pr.MergeableState {
+	case "dirty", "behind":
+		d.rebaseQueuedPR(repoName, key, pr)
+		return d.mergeEngineHeld(repoName, *pr)
+	case "", "unknown":
+		// GitHub is still working out whether the PR can merge
+		return false
+	}
+
+	required := cfg.RequiredChecks
+	if len(required) == 0 {
+		protection, err := d.github.GetRequiredChecks(ctx, mergeEngineSubsystem, owner, name, pr.Base.Ref)
+		if err != nil {
+			d.logger.Warn("Merge queue for %s could not read required checks of %s: %v", repoName, pr.Base.Ref, err)
+			return false
+		}
+		required = protection.Contexts
+	}
+	results, err := d.github.GetCheckResults(ctx, mergeEngineSubsystem, owner, name, pr.Head.SHA)
+	if err != nil {
+		d.logger.Warn("Merge queue for %s could not read checks of PR #%d: %v", repoName, pr.Number, err)
+		return false
+	}
+	failing, pending := classifyChecks(results, required)
+	// With nothing required, a head no check has reported on yet may simply
+	// not have started CI
+	waiting := len(pending) > 0 || (len(required) == 0 && len(results) == 0 && d.awaitingFirstCheck(repoName, pr))
+
+	switch {
+	case len(failing) > 0:
+		if item != nil && !item.CIStartedAt.IsZero() && item.CIFinishedAt.IsZero() {
+			d.recordMergeQueueEvent(repoName, key, state.MergeQueueCIFinished)
+		}
+		d.recordMergeQueueEvent(repoName, key, state.MergeQueueFailed)
+		d.holdPR(repoName, pr)
+		d.auditMergeEngine(repoName, key, "failed")
+
+		event := events.NewTypedEvent(repoName, key.Worker, fmt.Sprintf("CI failed on PR #%d: %s", pr.Number, strings.Join(failing, ", ")), events.CIFailedPayload{
+			PRURL:    pr.URL,
+			PRNumber: pr.Number,
+			Branch:   pr.Head.Ref,
+			SHA:      pr.Head.SHA,
+			Failed:   failing,
+		})
+		event.Priority = events.PriorityHigh
+		d.emitEvent(event)
+		d.tellSupervisor(repoName, fmt.Sprintf("Merge queue: PR #%d (%s) failed required checks: %s. It stays out of the queue until its branch is pushed again.",
+			pr.Number, pr.URL, strings.Join(failing, ", ")))
+		return true
+
+	case waiting:
+		if item != nil && item.CIStartedAt.IsZero() {
+			d.recordMergeQueueEvent(repoName, key, state.MergeQueueCIStarted)
+		}
+		return false
+
+	case pr.MergeableState == "blocked":
+		// Checks passed but something else, like a required review, is missing
+		d.logger.Debug("Merge queue for %s: PR #%d is blocked, skipping", repoName, pr.Number)
+		return true
+	}
+
+	if item != nil && !item.CIStartedAt.IsZero() && item.CIFinishedAt.IsZero() {
+		d.recordMergeQueueEvent(repoName, key, state.MergeQueueCIFinished)
+	}
+	sha, err := d.github.MergePullRequest(ctx, mergeEngineSubsystem, owner, name, pr.Number, cfg.Method(), pr.Head.SHA)
+	if err != nil {
+		d.logger.Warn("Merge queue for %s could not merge PR #%d: %v", repoName, pr.Number, err)
+		return false
+	}
+	d.recordMergeQueueEvent(repoName, key, state.MergeQueueMerged)
+	d.auditMergeEngine(repoName, key, "merged")
+	d.emitEvent(events.NewTypedEvent(repoName, key.Worker, fmt.Sprintf("Merged PR #%d", pr.Number), events.PRMergedPayload{
+		PRURL:    pr.URL,
+		PRNumber: pr.Number,
+		Branch:   pr.Head.Ref,
+		Base:     pr.Base.Ref,
+		Method:   cfg.Method(),
+		SHA:      sha,
+	}))
+	d.tellSupervisor(repoName, fmt.Sprintf("Merge queue: merged PR #%d (%s).", pr.Number, pr.URL))
+	return true
+}
+
+// rebaseQueuedPR rebases a PR's branch onto its base in the merge queue's
+// worktree and pushes it, which restarts its checks. A PR that conflicts or
+// otherwise can't be rebased and pushed, such as one from a fork, is set
+// aside. Failing to fetch affects every PR alike, so it sets none aside.
+func (d *Daemon) rebaseQueuedPR(repoName string, key state.MergeQueueItem, pr *github.PullRequest) {
+	repoPath := d.paths.RepoDir(repoName)
+	m := worktree.NewManager(repoPath)
+	remote, err := m.GetUpstreamRemote()
+	if err != nil {
+		d.logger.Warn("Merge queue for %s cannot rebase PR #%d: %v", repoName, pr.Number, err)
+		return
+	}
+	if err := m.FetchRemote(remote); err != nil {
+		d.logger.Warn("Merge queue for %s cannot rebase PR #%d: %v", repoName, pr.Number, err)
+		return
+	}
+
+	wtPath := d.paths.MergeQueueWorktree(repoName)
+	removeMergeEngineWorktree(m, wtPath)
+	if err := m.CreateNewBranch(wtPath, mergeEngineBranch, remote+"/"+pr.Head.Ref); err != nil {
+		// A fork's branch isn't on the remote the queue pushes to
+		d.setAsideUnrebasablePR(repoName, key, pr, fmt.Errorf("cannot check out %s/%s (is the PR from a fork?): %w", remote, pr.Head.Ref, err))
+		return
+	}
+	defer removeMergeEngineWorktree(m, wtPath)
+
+	result := worktree.RefreshWorktree(wtPath, remote, pr.Base.Ref)
+	if result.HasConflicts {
+		d.holdPR(repoName, pr)
+		d.auditMergeEngine(repoName, key, "conflicted")
+		d.recordAction(repoName, feed.ActionConflict, key.Worker, fmt.Sprintf("PR #%d conflicts with %s in %s", pr.Number, pr.Base.Ref, strings.Join(result.ConflictFiles, ", ")))
+		d.tellSupervisor(repoName, fmt.Sprintf("Merge queue: PR #%d (%s) conflicts with %s in %s and needs a manual rebase. It stays out of the queue until its branch is pushed again.",
+			pr.Number, pr.URL, pr.Base.Ref, strings.Join(result.ConflictFiles, ", ")))
+		return
+	}
+	if result.Error != nil {
+		d.setAsideUnrebasablePR(repoName, key, pr, fmt.Errorf("rebase failed: %w", result.Error))
+		return
+	}
+	if err := worktree.PushHeadWithLease(wtPath, remote, pr.Head.Ref, pr.Head.SHA); err != nil {
+		d.setAsideUnrebasablePR(repoName, key, pr, fmt.Errorf("pushing the rebased branch failed: %w", err))
+		return
+	}
+	d.logger.Info("Merge queue rebased PR #%d onto %s for %s", pr.Number, pr.Base.Ref, repoName)
+	d.auditMergeEngine(repoName, key, "rebased")
+	d.recordMergeQueueEvent(repoName, key, state.MergeQueueCIStarted)
+}
+
+// setAsideUnrebasablePR holds a PR the merge queue failed to rebase or push,
+// so it doesn't stall the PRs behind it, and tells the supervisor why
+func (d *Daemon) setAsideUnrebasablePR(repoName string, key state.MergeQueueItem, pr *github.PullRequest, reason error) {
+	d.logger.Warn("Merge queue for %s set aside PR #%d: %v", repoName, pr.Number, reason)
+	d.holdPR(repoName, pr)
+	d.auditMergeEngine(repoName, key, "rebase_failed")
+	d.tellSupervisor(repoName, fmt.Sprintf("Merge queue: could not rebase PR #%d (%s) onto %s: %v. It stays out of the queue until its branch is pushed again.",
+		pr.Number, pr.URL, pr.Base.Ref, reason))
+}
+
+// auditMergeEngine records what the merge queue did to a PR in the audit log
+func (d *Daemon) auditMergeEngine(repoName string, key state.MergeQueueItem, event string) {
+	d.recordDaemonChange("merge_queue", audit.ActionMergeQueueEvent, repoName, key.Worker,
+		fmt.Sprintf("event=%s pr=%d branch=%s", event, key.PRNumber, key.Branch))
+}
+
+// removeMergeEngineWorktree removes the merge queue's worktree and branch,
+// ignoring errors from ones that don't exist
+func removeMergeEngineWorktree(m *worktree.Manager, wtPath string) {
+	_ = m.Remove(wtPath, true)
+	_ = os.RemoveAll(wtPath)
+	_ = m.Prune()
+	_ = m.DeleteBranch(mergeEngineBranch)
+}
+
+// pendingQueueItem returns the pending merge queue item for key's PR or
+// branch, or nil
+func (d *Daemon) pendingQueueItem(repoName string, key state.MergeQueueItem) *state.MergeQueueItem {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return nil
+	}
+	for i := len(repo.MergeQueue) - 1; i >= 0; i-- {
+		item := repo.MergeQueue[i]
+		if item.Pending() && (item.PRNumber == key.PRNumber || item.Branch == key.Branch) {
+			return &item
+		}
+	}
+	return nil
+}
+
+// branchWorker returns the worker whose worktree is on branch, if any
+func (d *Daemon) branchWorker(repoName, branch string) string {
+	agents, err := d.state.ListAgents(repoName)
+	if err != nil {
+		return ""
+	}
+	for _, agentName := range agents {
+		agent, _ := d.state.GetAgent(repoName, agentName)
+		if agent.Type != state.AgentTypeWorker || agent.WorktreePath == "" {
+			continue
+		}
+		if current, err := worktree.GetCurrentBranch(agent.WorktreePath); err == nil && current == branch {
+			return agentName
+		}
+	}
+	return ""
+}
+
+// tellSupervisor messages a repository's supervisor on the daemon's behalf
+func (d *Daemon) tellSupervisor(repoName, message string) {
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", message); err != nil {
+		d.logger.Error("Failed to message supervisor of %s: %v", repoName, err)
+	}
+}
+
+// classifyChecks sorts the required checks, or every reported check if none
+// are required, into failing and pending. A required check that hasn't
+// reported yet is pending.
+func classifyChecks(results map[string]string, required []string) (failing, pending []string) {
+	names := required
+	if len(names) == 0 {
+		for check := range results {
+			names = append(names, check)
+		}
+	}
+	for _, check := range names {
+		switch results[check] {
+		case github.CheckPassed:
+		case github.CheckFailed:
+			failing = append(failing, check)
+		default:
+			pending = append(pending, check)
+		}
+	}
+	sort.Strings(failing)
+	sort.Strings(pending)
+	return failing, pending
+}
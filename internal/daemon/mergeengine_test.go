@@ -0,0 +1,392 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// fakeMergeGitHub serves the GitHub endpoints the merge queue uses from a
+// set of PRs and check results
+type fakeMergeGitHub struct {
+	mu     sync.Mutex
+	prs    map[int]map[string]interface{}
+	checks map[string]string // head sha -> conclusion of the "test" check
+	merged map[int]map[string]string
+}
+
+func newFakeMergeGitHub(t *testing.T, d *Daemon) *fakeMergeGitHub {
+	t.Helper()
+	f := &fakeMergeGitHub{
+		prs:    make(map[int]map[string]interface{}),
+		checks: make(map[string]string),
+		merged: make(map[int]map[string]string),
+	}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(server.Close)
+	d.github = github.NewClient(github.WithBaseURL(server.URL), github.WithToken("test-token"), github.WithCacheTTL(0))
+	return f
+}
+
+// addPR adds an open PR from branch at sha onto main
+func (f *fakeMergeGitHub) addPR(number int, branch, sha, mergeable string, labels ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var labelObjs []map[string]string
+	for _, l := range labels {
+		labelObjs = append(labelObjs, map[string]string{"name": l})
+	}
+	f.prs[number] = map[string]interface{}{
+		"number": number, "state": "open", "html_url": "https://github.com/o/r/pull/" + branch,
+		"labels":          labelObjs,
+		"head":            map[string]string{"ref": branch, "sha": sha},
+		"base":            map[string]string{"ref": "main"},
+		"mergeable_state": mergeable,
+	}
+}
+
+func (f *fakeMergeGitHub) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reply := func(v interface{}) { json.NewEncoder(w).Encode(v) }
+	path := r.URL.Path
+	switch {
+	case path == "/repos/o/r/pulls":
+		var list []map[string]interface{}
+		for n := 1; n <= 10; n++ {
+			if pr, ok := f.prs[n]; ok {
+				list = append(list, pr)
+			}
+		}
+		reply(list)
+	case path == "/repos/o/r/branches/main":
+		reply(map[string]interface{}{})
+	case strings.HasPrefix(path, "/repos/o/r/commits/") && strings.HasSuffix(path, "/check-runs"):
+		sha := strings.TrimSuffix(strings.TrimPrefix(path, "/repos/o/r/commits/"), "/check-runs")
+		var runs []map[string]string
+		if conclusion, ok := f.checks[sha]; ok {
+			status := "completed"
+			if conclusion == "" {
+				status = "in_progress"
+			}
+			runs = append(runs, map[string]string{"name": "test", "status": status, "conclusion": conclusion})
+		}
+		reply(map[string]interface{}{"check_runs": runs})
+	case strings.HasPrefix(path, "/repos/o/r/commits/") && strings.HasSuffix(path, "/status"):
+		reply(map[string]interface{}{"statuses": []interface{}{}})
+	case strings.HasPrefix(path, "/repos/o/r/pulls/"):
+		rest := strings.TrimPrefix(path, "/repos/o/r/pulls/")
+		merge := strings.HasSuffix(rest, "/merge")
+		n, err := strconv.Atoi(strings.TrimSuffix(rest, "/merge"))
+		if err != nil || f.prs[n] == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if merge && r.Method == http.MethodPut {
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			f.merged[n] = body
+			delete(f.prs, n)
+			reply(map[string]interface{}{"merged": true, "sha": "merge-" + body["sha"]})
+			return
+		}
+		reply(f.prs[n])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func setupMergeEngineRepo(t *testing.T, d *Daemon) {
+	t.Helper()
+	d.state.AddRepo("mq-repo", &state.Repository{
+		GithubURL:        "https://github.com/o/r.git",
+		TmuxSession:      "mc-mq-repo",
+		Agents:           make(map[string]state.Agent),
+		MergeQueueConfig: state.MergeQueueConfig{Enabled: true, TrackMode: state.TrackModeAll, AutoMerge: true},
+	})
+}
+
+// TestMergeEngineMergesPassingPRs checks that labeled PRs whose checks pass
+// are merged in order, and that unlabeled and held PRs are left alone
+func TestMergeEngineMergesPassingPRs(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	recorder := &recordingAdapter{}
+	d.RegisterAdapter(recorder)
+	gh := newFakeMergeGitHub(t, d)
+	setupMergeEngineRepo(t, d)
+
+	gh.addPR(1, "work/a", "sha-a", "clean", "multiclaude")
+	gh.addPR(2, "work/b", "sha-b", "clean", "multiclaude")
+	gh.addPR(3, "work/other", "sha-c", "clean")
+	gh.addPR(4, "work/held", "sha-d", "clean", "multiclaude", "needs-human-input")
+	gh.checks["sha-a"] = "success"
+	gh.checks["sha-b"] = "success"
+	gh.checks["sha-c"] = "success"
+	gh.checks["sha-d"] = "success"
+
+	d.processMergeQueue("mq-repo")
+
+	if len(gh.merged) != 2 || gh.merged[1]["sha"] != "sha-a" || gh.merged[1]["merge_method"] != "squash" || gh.merged[2] == nil {
+		t.Fatalf("merged = %v, want #1 and #2 squashed at their heads", gh.merged)
+	}
+	var mergedEvents []events.PRMergedPayload
+	for _, e := range recorder.events {
+		if p, ok := e.Payload.(events.PRMergedPayload); ok {
+			mergedEvents = append(mergedEvents, p)
+		}
+	}
+	if len(mergedEvents) != 2 || mergedEvents[0].PRNumber != 1 || mergedEvents[0].SHA != "merge-sha-a" || mergedEvents[0].Branch != "work/a" {
+		t.Errorf("merged events = %+v", mergedEvents)
+	}
+
+	repo, _ := d.state.GetRepo("mq-repo")
+	if repo.MergeQueueTotals.Merged != 2 {
+		t.Errorf("merged total = %d, want 2", repo.MergeQueueTotals.Merged)
+	}
+
+	entries, _ := d.auditLog(socket.Request{}, socket.AuditLogArgs{})
+	var merges []audit.Entry
+	for _, e := range entries {
+		if e.Command == "merge_queue" && strings.HasPrefix(e.Detail, "event=merged") {
+			merges = append(merges, e)
+		}
+	}
+	if len(merges) != 2 || merges[0].Actor != audit.DaemonActor || merges[0].Repo != "mq-repo" || !strings.Contains(merges[0].Detail, "pr=1") {
+		t.Errorf("merge audit entries = %+v, want one per merged PR", merges)
+	}
+}
+
+// TestMergeEngineCIFailure checks that a failing required check emits
+// pr.ci_failed once, sets the PR aside until its head changes, and lets the
+// next PR merge; a pending check holds up the queue
+func TestMergeEngineCIFailure(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	recorder := &recordingAdapter{}
+	d.RegisterAdapter(recorder)
+	gh := newFakeMergeGitHub(t, d)
+	setupMergeEngineRepo(t, d)
+
+	gh.addPR(1, "work/red", "red-1", "unstable", "multiclaude")
+	gh.addPR(2, "work/slow", "slow-1", "clean", "multiclaude")
+	gh.addPR(3, "work/next", "next-1", "clean", "multiclaude")
+	gh.checks["red-1"] = "failure"
+	gh.checks["slow-1"] = ""
+	gh.checks["next-1"] = "success"
+
+	ciFailed := func() []events.Event {
+		var found []events.Event
+		for _, e := range recorder.events {
+			if e.Type == events.EventCIFailed {
+				found = append(found, e)
+			}
+		}
+		return found
+	}
+
+	d.processMergeQueue("mq-repo")
+	d.processMergeQueue("mq-repo")
+
+	failed := ciFailed()
+	if len(failed) != 1 || failed[0].Priority != events.PriorityHigh {
+		t.Fatalf("pr.ci_failed events = %+v, want one with high priority", failed)
+	}
+	if p := failed[0].Payload.(events.CIFailedPayload); p.PRNumber != 1 || p.SHA != "red-1" || len(p.Failed) != 1 || p.Failed[0] != "test" {
+		t.Errorf("unexpected payload %+v", p)
+	}
+	if len(gh.merged) != 0 {
+		t.Errorf("merged %v while #2's checks are running", gh.merged)
+	}
+
+	// Once the slow PR passes, it and the next one merge
+	gh.checks["slow-1"] = "success"
+	d.processMergeQueue("mq-repo")
+	if gh.merged[2] == nil || gh.merged[3] == nil {
+		t.Fatalf("merged = %v, want #2 and #3", gh.merged)
+	}
+
+	// A new push puts the failed PR back in line
+	gh.addPR(1, "work/red", "red-2", "clean", "multiclaude")
+	gh.checks["red-2"] = "success"
+	d.processMergeQueue("mq-repo")
+	if gh.merged[1]["sha"] != "red-2" {
+		t.Errorf("merged = %v, want #1 at its new head", gh.merged)
+	}
+}
+
+// TestMergeEngineWaitsForFirstCheck checks that with no required checks, a
+// head nothing has reported on isn't merged until the grace period passes,
+// and that a new push starts the wait over
+func TestMergeEngineWaitsForFirstCheck(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	fake := clock.NewFake(time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	d.clock = fake
+	gh := newFakeMergeGitHub(t, d)
+	setupMergeEngineRepo(t, d)
+
+	gh.addPR(1, "work/fresh", "fresh-1", "clean", "multiclaude")
+	d.processMergeQueue("mq-repo")
+	if len(gh.merged) != 0 {
+		t.Fatalf("merged %v before any check reported", gh.merged)
+	}
+	item := d.pendingQueueItem("mq-repo", state.MergeQueueItem{PRNumber: 1, Branch: "work/fresh"})
+	if item == nil || item.CIStartedAt.IsZero() {
+		t.Errorf("PR should be waiting on CI, item = %+v", item)
+	}
+
+	fake.Advance(noChecksGrace - time.Minute)
+	gh.addPR(1, "work/fresh", "fresh-2", "clean", "multiclaude")
+	d.processMergeQueue("mq-repo")
+	fake.Advance(2 * time.Minute)
+	d.processMergeQueue("mq-repo")
+	if len(gh.merged) != 0 {
+		t.Fatalf("merged %v before the new head's grace period passed", gh.merged)
+	}
+
+	fake.Advance(noChecksGrace)
+	d.processMergeQueue("mq-repo")
+	if gh.merged[1]["sha"] != "fresh-2" {
+		t.Errorf("merged = %v, want #1 once no check appeared", gh.merged)
+	}
+}
+
+// TestMergeEngineRebasesBehindPRs checks that a PR behind main is rebased
+// onto it and pushed, and that a conflicting one is set aside untouched
+func TestMergeEngineRebasesBehindPRs(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	gh := newFakeMergeGitHub(t, d)
+	repoPath := initWorkerBranchRepo(t, d, "mq-repo")
+	setupMergeEngineRepo(t, d)
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	revParse := func(ref string) string {
+		t.Helper()
+		out, err := exec.Command("git", "-C", repoPath, "rev-parse", ref).Output()
+		if err != nil {
+			t.Fatalf("rev-parse %s: %v", ref, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	for _, branch := range []string{"work/behind", "work/clash"} {
+		runGitIn(t, repoPath, "checkout", "-q", "-b", branch, "main")
+		write(filepath.Base(branch)+".txt", branch)
+		if branch == "work/clash" {
+			write("shared.txt", "from the branch\n")
+		}
+		runGitIn(t, repoPath, "add", ".")
+		runGitIn(t, repoPath, "commit", "-q", "-m", branch)
+	}
+	runGitIn(t, repoPath, "checkout", "-q", "main")
+	write("shared.txt", "from main\n")
+	runGitIn(t, repoPath, "add", ".")
+	runGitIn(t, repoPath, "commit", "-q", "-m", "main moves on")
+	runGitIn(t, repoPath, "checkout", "-q", "work/mq-repo")
+
+	clashHead := revParse("work/clash")
+	gh.addPR(1, "work/clash", clashHead, "dirty", "multiclaude")
+	gh.addPR(2, "work/behind", revParse("work/behind"), "behind", "multiclaude")
+
+	d.processMergeQueue("mq-repo")
+
+	if err := exec.Command("git", "-C", repoPath, "merge-base", "--is-ancestor", "main", "work/behind").Run(); err != nil {
+		t.Error("work/behind should be rebased onto main")
+	}
+	if got := revParse("work/clash"); got != clashHead {
+		t.Error("a conflicting PR should not be pushed")
+	}
+	clash := github.PullRequest{Number: 1}
+	clash.Head.SHA = clashHead
+	if !d.mergeEngineHeld("mq-repo", clash) {
+		t.Error("a conflicting PR should be set aside")
+	}
+	if _, err := os.Stat(d.paths.MergeQueueWorktree("mq-repo")); !os.IsNotExist(err) {
+		t.Errorf("merge queue worktree should be removed, stat err = %v", err)
+	}
+	if len(gh.merged) != 0 {
+		t.Errorf("nothing should merge before CI reruns, merged %v", gh.merged)
+	}
+
+	item := d.pendingQueueItem("mq-repo", state.MergeQueueItem{PRNumber: 2, Branch: "work/behind"})
+	if item == nil || item.CIStartedAt.IsZero() {
+		t.Errorf("rebased PR should be waiting on CI, item = %+v", item)
+	}
+}
+
+// TestMergeEngineSetsAsideUnrebasablePRs checks that a PR whose branch can't
+// be checked out, like one from a fork, is set aside instead of stalling the
+// PRs behind it
+func TestMergeEngineSetsAsideUnrebasablePRs(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	gh := newFakeMergeGitHub(t, d)
+	repoPath := initWorkerBranchRepo(t, d, "mq-repo")
+	setupMergeEngineRepo(t, d)
+
+	runGitIn(t, repoPath, "checkout", "-q", "-b", "work/behind", "main")
+	if err := os.WriteFile(filepath.Join(repoPath, "behind.txt"), []byte("behind"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGitIn(t, repoPath, "add", ".")
+	runGitIn(t, repoPath, "commit", "-q", "-m", "behind")
+	runGitIn(t, repoPath, "checkout", "-q", "main")
+	runGitIn(t, repoPath, "commit", "-q", "--allow-empty", "-m", "main moves on")
+	runGitIn(t, repoPath, "checkout", "-q", "work/mq-repo")
+
+	// The fork's branch only exists in the fork
+	gh.addPR(1, "contributor-feature", "f0f0f0f0", "behind", "multiclaude")
+	head, err := exec.Command("git", "-C", repoPath, "rev-parse", "work/behind").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gh.addPR(2, "work/behind", strings.TrimSpace(string(head)), "behind", "multiclaude")
+
+	d.processMergeQueue("mq-repo")
+
+	fork := github.PullRequest{Number: 1}
+	fork.Head.SHA = "f0f0f0f0"
+	if !d.mergeEngineHeld("mq-repo", fork) {
+		t.Error("a PR whose branch can't be checked out should be set aside")
+	}
+	if err := exec.Command("git", "-C", repoPath, "merge-base", "--is-ancestor", "main", "work/behind").Run(); err != nil {
+		t.Error("the PR behind the fork's should still be rebased onto main")
+	}
+	if _, err := os.Stat(d.paths.MergeQueueWorktree("mq-repo")); !os.IsNotExist(err) {
+		t.Errorf("merge queue worktree should be removed, stat err = %v", err)
+	}
+}
+
+func TestClassifyChecks(t *testing.T) {
+	results := map[string]string{"test": github.CheckPassed, "lint": github.CheckFailed, "e2e": github.CheckPending}
+
+	failing, pending := classifyChecks(results, nil)
+	if strings.Join(failing, ",") != "lint" || strings.Join(pending, ",") != "e2e" {
+		t.Errorf("every check: failing=%v pending=%v", failing, pending)
+	}
+	failing, pending = classifyChecks(results, []string{"test", "build"})
+	if len(failing) != 0 || strings.Join(pending, ",") != "build" {
+		t.Errorf("required checks: failing=%v pending=%v", failing, pending)
+	}
+}
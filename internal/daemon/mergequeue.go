@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/metrics"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// mergeQueueMetricsFile is the Prometheus textfile kept in the metrics directory
+const mergeQueueMetricsFile = "merge_queue.prom"
+
+// enqueueWorkerBranch records a completed worker's branch as entering the merge queue
+func (d *Daemon) enqueueWorkerBranch(repoName, agentName string, agent state.Agent) {
+	branch, err := worktree.GetCurrentBranch(agent.WorktreePath)
+	if err != nil || branch == "" || branch == "HEAD" {
+		d.logger.Debug("Not enqueueing %s/%s: no branch (%v)", repoName, agentName, err)
+		return
+	}
+
+	key := state.MergeQueueItem{Branch: branch, PRNumber: agent.PRNumber, Worker: agentName, Priority: agent.Priority}
+	if _, err := d.state.RecordMergeQueueEvent(repoName, key, state.MergeQueueEnqueued, d.clock.Now()); err != nil {
+		d.logger.Warn("Failed to enqueue %s for %s: %v", branch, repoName, err)
+		return
+	}
+	d.writeMergeQueueMetrics()
+}
+
+// handleMergeQueueEvent records a merge queue event for a PR, identified by
+// "branch" and/or "pr" (number). The merge-queue agent reports these as it
+// works so queue depth, time-in-queue, and CI wait can be measured.
+func (d *Daemon) handleMergeQueueEvent(req socket.Request, args socket.MergeQueueEventArgs) (state.MergeQueueItem, error) {
+	repoName := args.Repo
+	eventName := args.Event
+
+	event := state.MergeQueueEvent(eventName)
+	if !event.Valid() {
+		return state.MergeQueueItem{}, fmt.Errorf("invalid event %q: use enqueued, ci_started, ci_finished, merged, failed, or closed", eventName)
+	}
+
+	key := state.MergeQueueItem{Branch: args.Branch, PRNumber: args.PR}
+	if key.Branch == "" && key.PRNumber <= 0 {
+		return state.MergeQueueItem{}, fmt.Errorf("a branch or PR number is required")
+	}
+
+	return d.recordMergeQueueEvent(repoName, key, event)
+}
+
+// recordMergeQueueEvent records a merge queue event in state, the feed, and
+// the metrics textfile
+func (d *Daemon) recordMergeQueueEvent(repoName string, key state.MergeQueueItem, event state.MergeQueueEvent) (state.MergeQueueItem, error) {
+	item, err := d.state.RecordMergeQueueEvent(repoName, key, event, d.clock.Now())
+	if err != nil {
+		return item, err
+	}
+	d.logger.Info("Merge queue event %s for %s (branch %q, PR #%d)", event, repoName, item.Branch, item.PRNumber)
+	d.recordAction(repoName, feed.ActionMergeQueue, item.Worker, describeQueueItem(event, item))
+	d.writeMergeQueueMetrics()
+	return item, nil
+}
+
+// handleMergeQueueStats returns merge queue stats for one repo ("repo") or
+// all repos. With "format": "prometheus" it returns the text exposition instead.
+func (d *Daemon) handleMergeQueueStats(req socket.Request, args socket.MergeQueueStatsArgs) (socket.MergeQueueStats, error) {
+	repoName := args.Repo
+	if repoName != "" {
+		if _, exists := d.state.GetAllRepos()[repoName]; !exists {
+			return socket.MergeQueueStats{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+		}
+	}
+
+	stats := socket.MergeQueueStats{Repos: d.mergeQueueStats(repoName)}
+	if args.Format == "prometheus" {
+		var buf bytes.Buffer
+		if err := metrics.WritePrometheus(&buf, stats.Repos); err != nil {
+			return socket.MergeQueueStats{}, err
+		}
+		stats.Prometheus = buf.String()
+	}
+	return stats, nil
+}
+
+// mergeQueueStats computes merge queue stats for repoName, or every repo if empty
+func (d *Daemon) mergeQueueStats(repoName string) []metrics.MergeQueueStats {
+	repos := d.state.GetAllRepos()
+	names := make([]string, 0, len(repos))
+	for name := range repos {
+		if repoName == "" || name == repoName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	now := d.clock.Now()
+	stats := make([]metrics.MergeQueueStats, 0, len(names))
+	for _, name := range names {
+		stats = append(stats, metrics.ComputeMergeQueue(name, repos[name], now))
+	}
+	return stats
+}
+
+// writeMergeQueueMetrics rewrites the merge queue textfile for Prometheus
+// (node_exporter's textfile collector). The file is replaced atomically so a
+// scrape never sees a partial write.
+func (d *Daemon) writeMergeQueueMetrics() {
+	if err := os.MkdirAll(d.paths.MetricsDir(), 0755); err != nil {
+		d.logger.Warn("Failed to create metrics directory: %v", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := metrics.WritePrometheus(&buf, d.mergeQueueStats("")); err != nil {
+		d.logger.Warn("Failed to render merge queue metrics: %v", err)
+		return
+	}
+
+	path := filepath.Join(d.paths.MetricsDir(), mergeQueueMetricsFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		d.logger.Warn("Failed to write merge queue metrics: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		d.logger.Warn("Failed to write merge queue metrics: %v", err)
+	}
+}
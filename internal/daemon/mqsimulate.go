@@ -0,0 +1,212 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/metrics"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// mergeSimSubsystem is the GitHub client subsystem simulations are charged to
+const mergeSimSubsystem = "merge-queue-simulate"
+
+// mergeSimTimeout bounds the GitHub calls of one simulation
+const mergeSimTimeout = 30 * time.Second
+
+// What a simulation expects to happen to a queue entry
+const (
+	simReady       = "ready"        // would merge now
+	simNeedsRebase = "needs_rebase" // conflicts with the base, or the base requires up-to-date branches
+	simFailing     = "failing"      // a required check failed
+	simWaiting     = "waiting"      // required checks are running or haven't reported
+	simNoPR        = "no_pr"        // there is no open PR to merge
+	simUnknown     = "unknown"      // GitHub could not be asked
+)
+
+// handleMergeQueueSimulate reports, without merging anything, the order the
+// repo's queued branches would merge in, which would fail required checks,
+// and which need a rebase
+func (d *Daemon) handleMergeQueueSimulate(req socket.Request, args socket.RepoArgs) (socket.MergeQueueSimulation, error) {
+	repoName := args.Repo
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.MergeQueueSimulation{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mergeSimTimeout)
+	defer cancel()
+	return d.simulateMergeQueue(ctx, repoName, repo), nil
+}
+
+// simulateMergeQueue builds the simulation for one repository. Anything that
+// can't be checked is reported as a warning or an unknown verdict rather
+// than failing the whole simulation.
+func (d *Daemon) simulateMergeQueue(ctx context.Context, repoName string, repo *state.Repository) socket.MergeQueueSimulation {
+	sim := socket.MergeQueueSimulation{
+		Repo:           repoName,
+		Enabled:        repo.MergeQueueConfig.Enabled,
+		RequiredChecks: []string{},
+		Entries:        []socket.SimulatedMerge{},
+	}
+	warn := func(format string, args ...interface{}) {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	repoPath := d.paths.RepoDir(repoName)
+	wt := worktree.NewManager(repoPath)
+	remote, err := wt.GetUpstreamRemote()
+	if err != nil {
+		warn("cannot compare branches: %v", err)
+	} else if err := wt.FetchRemote(remote); err != nil {
+		warn("fetch failed, comparing against the last fetched refs: %v", err)
+	}
+	base, err := d.upstreamBaseRef(repoName)
+	if err != nil {
+		warn("cannot determine the default branch: %v", err)
+	}
+	sim.Base = base
+	baseBranch := strings.TrimPrefix(base, remote+"/")
+
+	gh := true
+	owner, name, err := github.ParseRepoURL(repo.GithubURL)
+	if err != nil {
+		warn("checks and PRs not looked up: %v", err)
+		gh = false
+	}
+	if gh && baseBranch != "" {
+		required, err := d.github.GetRequiredChecks(ctx, mergeSimSubsystem, owner, name, baseBranch)
+		if err != nil {
+			warn("checks and PRs not looked up: %v", err)
+			gh = false
+		} else {
+			sim.RequiredChecks = append(sim.RequiredChecks, required.Contexts...)
+			sim.Strict = required.Strict
+			if len(required.Contexts) == 0 {
+				warn("%s has no required checks; every reported check is treated as required", baseBranch)
+			}
+		}
+	}
+
+	// The first entry that would merge leaves every later one behind when
+	// the base requires up-to-date branches
+	mergesFirst := ""
+	pending := metrics.ComputeMergeQueue(repoName, repo, d.clock.Now()).Pending
+	for i, queued := range pending {
+		entry := socket.SimulatedMerge{
+			Position:           i + 1,
+			Branch:             queued.Branch,
+			PRNumber:           queued.PRNumber,
+			Worker:             queued.Worker,
+			Priority:           queued.Priority,
+			TimeInQueueSeconds: queued.TimeInQueueSeconds,
+			Checks:             simUnknown,
+		}
+
+		if entry.Branch != "" && base != "" {
+			if div, err := worktree.CompareWithBase(repoPath, remote, entry.Branch, base); err != nil {
+				entry.Notes = append(entry.Notes, err.Error())
+			} else {
+				entry.Behind = div.Behind
+				entry.Conflicts = div.Conflicts
+				entry.ConflictFiles = div.ConflictFiles
+			}
+		}
+
+		if gh {
+			d.simulateChecks(ctx, owner, name, sim.RequiredChecks, &entry)
+		}
+
+		entry.NeedsRebase = entry.Conflicts || entry.MergeableState == "dirty" || entry.MergeableState == "behind" ||
+			(sim.Strict && entry.Behind > 0)
+		entry.Verdict = simVerdict(entry, gh)
+		if sim.Strict && entry.Verdict == simReady {
+			if mergesFirst == "" {
+				mergesFirst = simLabel(entry)
+			} else {
+				entry.RebaseAfter = mergesFirst
+			}
+		}
+		sim.Entries = append(sim.Entries, entry)
+	}
+	return sim
+}
+
+// simulateChecks fills in entry's PR, mergeable state, and check results
+func (d *Daemon) simulateChecks(ctx context.Context, owner, name string, required []string, entry *socket.SimulatedMerge) {
+	var pr *github.PullRequest
+	var err error
+	if entry.PRNumber > 0 {
+		pr, err = d.github.GetPullRequest(ctx, mergeSimSubsystem, owner, name, entry.PRNumber)
+	} else if entry.Branch != "" {
+		pr, err = d.github.FindPullRequest(ctx, mergeSimSubsystem, owner, name, entry.Branch)
+	}
+	if err != nil {
+		entry.Notes = append(entry.Notes, fmt.Sprintf("PR lookup failed: %v", err))
+		return
+	}
+	if pr == nil {
+		entry.Checks = ""
+		return
+	}
+	entry.PRNumber = pr.Number
+	entry.PRURL = pr.URL
+	entry.MergeableState = pr.MergeableState
+	if pr.State != "open" {
+		entry.Notes = append(entry.Notes, fmt.Sprintf("PR is %s", pr.State))
+		entry.Checks = ""
+		return
+	}
+	if pr.Draft {
+		entry.Notes = append(entry.Notes, "PR is a draft")
+	}
+
+	results, err := d.github.GetCheckResults(ctx, mergeSimSubsystem, owner, name, pr.Head.SHA)
+	if err != nil {
+		entry.Notes = append(entry.Notes, fmt.Sprintf("check lookup failed: %v", err))
+		return
+	}
+	entry.FailingChecks, entry.PendingChecks = classifyChecks(results, required)
+	switch {
+	case len(entry.FailingChecks) > 0:
+		entry.Checks = github.CheckFailed
+	case len(entry.PendingChecks) > 0:
+		entry.Checks = github.CheckPending
+	case len(required) == 0 && len(results) == 0:
+		// The merge queue waits for CI to start on a head before merging it
+		entry.Checks = github.CheckPending
+		entry.Notes = append(entry.Notes, "no checks reported yet")
+	default:
+		entry.Checks = github.CheckPassed
+	}
+}
+
+// simVerdict decides what the merge queue would do with entry
+func simVerdict(entry socket.SimulatedMerge, gh bool) string {
+	switch {
+	case entry.NeedsRebase:
+		return simNeedsRebase
+	case !gh || entry.Checks == simUnknown:
+		return simUnknown
+	case entry.Checks == "":
+		return simNoPR
+	case entry.Checks == github.CheckFailed:
+		return simFailing
+	case entry.Checks == github.CheckPending:
+		return simWaiting
+	}
+	return simReady
+}
+
+// simLabel names an entry by its PR number if it has one, else its branch
+func simLabel(entry socket.SimulatedMerge) string {
+	if entry.PRNumber > 0 {
+		return fmt.Sprintf("#%d", entry.PRNumber)
+	}
+	return entry.Branch
+}
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// TestHandleMergeQueueSimulate checks the merge order, check results, and
+// rebase needs reported for a queue, against a fake GitHub API
+func TestHandleMergeQueueSimulate(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "sim-repo")
+	runGit := func(args ...string) {
+		t.Helper()
+		runGitIn(t, repoPath, args...)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Four branches off main: two clean, one failing CI, and one that
+	// conflicts with main once main moves on
+	for _, branch := range []string{"work/green", "work/red", "work/clash", "work/nopr"} {
+		runGit("checkout", "-q", "-b", branch, "main")
+		write(filepath.Base(branch)+".txt", branch)
+		if branch == "work/clash" {
+			write("shared.txt", "from the branch\n")
+		}
+		runGit("add", ".")
+		runGit("commit", "-q", "-m", branch)
+	}
+	runGit("checkout", "-q", "main")
+	write("shared.txt", "from main\n")
+	runGit("add", ".")
+	runGit("commit", "-q", "-m", "main moves on")
+	runGit("checkout", "-q", "work/sim-repo")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := func(v interface{}) { json.NewEncoder(w).Encode(v) }
+		pr := func(number int, sha string) map[string]interface{} {
+			return map[string]interface{}{
+				"number": number, "state": "open", "html_url": "https://github.com/o/r/pull/" + sha,
+				"head": map[string]string{"sha": sha}, "mergeable_state": "clean",
+			}
+		}
+		switch r.URL.Path {
+		case "/repos/o/r/branches/main":
+			reply(map[string]interface{}{"protection": map[string]interface{}{
+				"required_status_checks": map[string]interface{}{"contexts": []string{"test"}, "checks": []map[string]string{{"context": "lint"}}},
+			}})
+		case "/repos/o/r/pulls":
+			switch r.URL.Query().Get("head") {
+			case "o:work/red":
+				reply([]map[string]int{{"number": 2}})
+			case "o:work/clash":
+				reply([]map[string]int{{"number": 3}})
+			default:
+				reply([]interface{}{})
+			}
+		case "/repos/o/r/pulls/1":
+			reply(pr(1, "green"))
+		case "/repos/o/r/pulls/2":
+			reply(pr(2, "red"))
+		case "/repos/o/r/pulls/3":
+			reply(pr(3, "clash"))
+		case "/repos/o/r/commits/green/check-runs", "/repos/o/r/commits/clash/check-runs":
+			reply(map[string]interface{}{"check_runs": []map[string]string{{"name": "test", "status": "completed", "conclusion": "success"}}})
+		case "/repos/o/r/commits/red/check-runs":
+			reply(map[string]interface{}{"check_runs": []map[string]string{
+				{"name": "test", "status": "completed", "conclusion": "failure"},
+				{"name": "optional", "status": "in_progress"},
+			}})
+		case "/repos/o/r/commits/green/status", "/repos/o/r/commits/clash/status":
+			reply(map[string]interface{}{"statuses": []map[string]string{{"context": "lint", "state": "success"}}})
+		case "/repos/o/r/commits/red/status":
+			reply(map[string]interface{}{"statuses": []map[string]string{{"context": "lint", "state": "pending"}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	d.github = github.NewClient(github.WithBaseURL(server.URL), github.WithToken("test-token"))
+
+	now := time.Now()
+	repo := &state.Repository{
+		GithubURL:        "https://github.com/o/r.git",
+		TmuxSession:      "mc-sim-repo",
+		Agents:           map[string]state.Agent{},
+		MergeQueueConfig: state.DefaultMergeQueueConfig(),
+	}
+	if err := d.state.AddRepo("sim-repo", repo); err != nil {
+		t.Fatal(err)
+	}
+	for i, item := range []state.MergeQueueItem{
+		{Branch: "work/green", PRNumber: 1},
+		{Branch: "work/red", Priority: state.TaskPriorityP0},
+		{Branch: "work/clash"},
+		{Branch: "work/nopr"},
+	} {
+		if _, err := d.state.RecordMergeQueueEvent("sim-repo", item, state.MergeQueueEnqueued, now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "merge_queue_simulate", Args: map[string]interface{}{"repo": "sim-repo"}})
+	if !resp.Success {
+		t.Fatalf("simulate failed: %s", resp.Error)
+	}
+	sim := resp.Data.(socket.MergeQueueSimulation)
+	if sim.Base != "origin/main" || len(sim.RequiredChecks) != 2 {
+		t.Errorf("base = %q, required checks = %v", sim.Base, sim.RequiredChecks)
+	}
+
+	want := []struct {
+		branch  string
+		verdict string
+	}{
+		{"work/red", simFailing}, // P0 goes first
+		{"work/green", simReady},
+		{"work/clash", simNeedsRebase},
+		{"work/nopr", simNoPR},
+	}
+	if len(sim.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(sim.Entries), len(want), sim.Entries)
+	}
+	for i, w := range want {
+		entry := sim.Entries[i]
+		if entry.Branch != w.branch || entry.Verdict != w.verdict {
+			t.Errorf("entry %d = %s %s, want %s %s (%+v)", i+1, entry.Branch, entry.Verdict, w.branch, w.verdict, entry)
+		}
+	}
+
+	red := sim.Entries[0]
+	if len(red.FailingChecks) != 1 || red.FailingChecks[0] != "test" || len(red.PendingChecks) != 1 || red.PendingChecks[0] != "lint" {
+		t.Errorf("only required checks should count: failing %v, pending %v", red.FailingChecks, red.PendingChecks)
+	}
+	clash := sim.Entries[2]
+	if clash.Behind != 1 || !clash.Conflicts || len(clash.ConflictFiles) != 1 || clash.ConflictFiles[0] != "shared.txt" {
+		t.Errorf("clash should conflict in shared.txt: %+v", clash)
+	}
+	if green := sim.Entries[1]; green.Conflicts || green.NeedsRebase || green.Behind != 1 {
+		t.Errorf("green is behind without conflicts and the base isn't strict: %+v", green)
+	}
+
+	if resp := d.handleRequest(socket.Request{Command: "merge_queue_simulate", Args: map[string]interface{}{"repo": "nope"}}); resp.Success {
+		t.Error("simulating an unknown repo should fail")
+	}
+}
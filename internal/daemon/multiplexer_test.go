@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/mux"
+)
+
+func TestAddRepoStartsAgentsInProcessMultiplexer(t *testing.T) {
+	t.Setenv("MULTICLAUDE_TEST_MODE", "1")
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+	process := mux.NewProcess()
+	defer process.Close()
+	d.tmux = process
+
+	repoPath := d.paths.RepoDir("proc-repo")
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("git", "init", repoPath).Run(); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "add_repo", Args: map[string]interface{}{
+		"name":         "proc-repo",
+		"github_url":   "https://github.com/test/proc-repo",
+		"tmux_session": "mc-proc-repo",
+		"mq_enabled":   false,
+		"start_agents": true,
+	}})
+	if !resp.Success {
+		t.Fatalf("add_repo failed: %s", resp.Error)
+	}
+	agents := resp.Data.(socket.AddedRepo).Agents
+	if len(agents) == 0 || agents[0] != "supervisor" {
+		t.Errorf("started agents = %v, want the supervisor first", agents)
+	}
+
+	if exists, err := process.HasWindow(context.Background(), "mc-proc-repo", "supervisor"); err != nil || !exists {
+		t.Errorf("supervisor window in the process multiplexer = %v, %v; want it to exist", exists, err)
+	}
+	if _, exists := d.state.GetAgent("proc-repo", "supervisor"); !exists {
+		t.Error("supervisor wasn't registered")
+	}
+
+	status, _ := d.handleStatus(socket.Request{}, socket.StatusArgs{})
+	if got := status.Multiplexer; got != mux.BackendProcess {
+		t.Errorf("status multiplexer = %v, want %s", got, mux.BackendProcess)
+	}
+}
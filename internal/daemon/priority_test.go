@@ -0,0 +1,127 @@
+package daemon
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestHandleAddAgentPriority(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+	})
+	defer cleanup()
+
+	add := func(name, priority string) socket.Response {
+		return d.handleRequest(socket.Request{Command: "add_agent", Args: map[string]interface{}{
+			"repo":          "repo",
+			"agent":         name,
+			"type":          "worker",
+			"worktree_path": "/tmp/" + name,
+			"tmux_window":   name,
+			"priority":      priority,
+		}})
+	}
+
+	if resp := add("urgent", "p0"); !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+	if agent, _ := d.state.GetAgent("repo", "urgent"); agent.Priority != state.TaskPriorityP0 {
+		t.Errorf("priority = %q, want P0", agent.Priority)
+	}
+	if resp := add("bogus", "P9"); resp.Success {
+		t.Error("add_agent should reject an invalid priority")
+	}
+	if resp := add("plain", ""); !resp.Success {
+		t.Fatalf("add_agent failed: %s", resp.Error)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "list_agents", Args: map[string]interface{}{
+		"repo": "repo",
+		"sort": "priority",
+	}})
+	if !resp.Success {
+		t.Fatalf("list_agents failed: %s", resp.Error)
+	}
+	agents := resp.Data.(socket.AgentList).Agents
+	if len(agents) != 2 || agents[0].Name != "urgent" || agents[1].Priority != state.DefaultTaskPriority {
+		t.Errorf("list sorted by priority = %+v", agents)
+	}
+}
+
+func TestEmitEventInheritsTaskPriority(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.state.AddRepo("repo", &state.Repository{
+		TmuxSession: "mc-repo",
+		Agents: map[string]state.Agent{
+			"hotfix":   {Type: state.AgentTypeWorker, Priority: state.TaskPriorityP0},
+			"refactor": {Type: state.AgentTypeWorker, Priority: state.TaskPriorityP3},
+			"feature":  {Type: state.AgentTypeWorker},
+		},
+	})
+
+	emit := func(agent string, priority events.Priority) events.Priority {
+		event := events.NewEvent(events.EventAgentCompleted, "repo", agent, "done")
+		event.Priority = priority
+		d.emitEvent(event)
+		return d.notify.Recent(1)[0].Priority
+	}
+
+	tests := []struct {
+		agent string
+		event events.Priority
+		want  events.Priority
+	}{
+		{"hotfix", events.PriorityNormal, events.PriorityHigh},
+		{"hotfix", events.PriorityLow, events.PriorityHigh},
+		{"refactor", events.PriorityNormal, events.PriorityLow},
+		{"refactor", events.PriorityHigh, events.PriorityHigh},
+		{"feature", events.PriorityNormal, events.PriorityNormal},
+		{"", events.PriorityNormal, events.PriorityNormal},
+	}
+	for _, tt := range tests {
+		if got := emit(tt.agent, tt.event); got != tt.want {
+			t.Errorf("%s event with %s priority delivered as %s, want %s", tt.agent, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestDetectOutputLoopsStricterForUrgentTasks(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	d.state.AddRepo("repo", &state.Repository{
+		TmuxSession: "mc-repo",
+		Agents: map[string]state.Agent{
+			"hotfix":  {Type: state.AgentTypeWorker, TmuxWindow: "hotfix", Priority: state.TaskPriorityP0},
+			"feature": {Type: state.AgentTypeWorker, TmuxWindow: "feature"},
+		},
+	})
+	if err := os.MkdirAll(d.paths.WorkersOutputDir("repo"), 0755); err != nil {
+		t.Fatalf("Failed to create output dir: %v", err)
+	}
+
+	// Three repeats: below the default threshold of four
+	block := "$ go test ./...\nFAIL: TestCheckout expected 200 but got 500 from the payments API\n"
+	for _, name := range []string{"hotfix", "feature"} {
+		if err := os.WriteFile(d.paths.AgentLogFile("repo", name, true), []byte(strings.Repeat(block, 3)), 0644); err != nil {
+			t.Fatalf("Failed to write log: %v", err)
+		}
+	}
+
+	d.detectOutputLoops()
+
+	recent := d.notify.Recent(0)
+	if len(recent) != 1 || recent[0].Agent != "hotfix" {
+		t.Fatalf("expected only the P0 worker to be flagged, got %+v", recent)
+	}
+	if recent[0].Priority != events.PriorityHigh {
+		t.Errorf("stuck event priority = %s, want high", recent[0].Priority)
+	}
+}
@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// prCreateSubsystem is the GitHub budget opening PRs for completed workers
+// is charged to
+const prCreateSubsystem = "pr-create"
+
+// maxPRTitleLength keeps titles taken from a task's first line readable
+const maxPRTitleLength = 72
+
+// openAgentPR opens a pull request for a completed worker's pushed branch,
+// targeting its base branch or the repository's default branch. If the
+// branch already has an open PR, that one is returned instead, so a worker
+// can retry a completion that failed after the PR was opened.
+func (d *Daemon) openAgentPR(repoName, agentName string, agent state.Agent, branch string) (pr *github.PullRequest, created bool, err error) {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return nil, false, fmt.Errorf("repository '%s' not found", repoName)
+	}
+	owner, name, err := github.ParseRepoURL(repo.GithubURL)
+	if err != nil {
+		return nil, false, err
+	}
+	if branch == "" || branch == "HEAD" {
+		return nil, false, fmt.Errorf("worktree is not on a branch")
+	}
+
+	base := agent.BaseBranch
+	if base == "" {
+		ref, err := d.upstreamBaseRef(repoName)
+		if err != nil {
+			return nil, false, fmt.Errorf("cannot determine the default branch: %w", err)
+		}
+		_, base, _ = strings.Cut(ref, "/")
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, 30*time.Second)
+	defer cancel()
+
+	head := d.prHead(repoName, owner, branch)
+	if existing, err := d.github.FindPullRequest(ctx, prCreateSubsystem, owner, name, head); err != nil {
+		return nil, false, err
+	} else if existing != nil {
+		return existing, false, nil
+	}
+
+	pr, err = d.github.CreatePullRequest(ctx, prCreateSubsystem, owner, name, github.NewPullRequest{
+		Title: agentPRTitle(agentName, agent),
+		Head:  head,
+		Base:  base,
+		Body:  agentPRBody(repoName, agentName, repo.TmuxSession, agent),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return pr, true, nil
+}
+
+// agentPRTitle is the first line of the worker's summary or task
+func agentPRTitle(agentName string, agent state.Agent) string {
+	title := agent.Summary
+	if title == "" {
+		title = agent.Task
+	}
+	title, _, _ = strings.Cut(strings.TrimSpace(title), "\n")
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return fmt.Sprintf("Work from %s", agentName)
+	}
+	if len(title) > maxPRTitleLength {
+		title = strings.TrimSpace(title[:maxPRTitleLength-3]) + "..."
+	}
+	return title
+}
+
+// agentPRBody describes the worker's task and where to find the worker
+func agentPRBody(repoName, agentName, session string, agent state.Agent) string {
+	var b strings.Builder
+	if agent.Summary != "" {
+		fmt.Fprintf(&b, "## Summary\n\n%s\n\n", agent.Summary)
+	}
+	task := agent.Task
+	if task == "" {
+		task = "(no task recorded)"
+	}
+	fmt.Fprintf(&b, "## Task\n\n%s\n\n", task)
+
+	window := agent.TmuxWindow
+	if window == "" {
+		window = agentName
+	}
+	attach := events.NewAttachTarget(repoName, agentName, session, window)
+	fmt.Fprintf(&b, "## Worker\n\nOpened by multiclaude worker `%s`. While it is still running, watch it with:\n\n", agentName)
+	fmt.Fprintf(&b, "    %s\n\nor\n\n    %s\n", attach.Command, attach.Tmux)
+	return b.String()
+}
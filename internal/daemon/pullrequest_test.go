@@ -0,0 +1,237 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// TestHandleCompleteAgentOpensPR checks that complete_agent with pr pushes
+// the branch, opens a PR against the default branch with a templated title
+// and body, and reports it in the response, task history, and events
+func TestHandleCompleteAgentOpensPR(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	recorder := &recordingAdapter{}
+	d.RegisterAdapter(recorder)
+
+	var created []github.NewPullRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/pulls":
+			json.NewEncoder(w).Encode([]interface{}{})
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/o/r/pulls":
+			var pr github.NewPullRequest
+			if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+				t.Errorf("bad request body: %v", err)
+			}
+			created = append(created, pr)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"number": 7, "title": pr.Title, "state": "open", "html_url": "https://github.com/o/r/pull/7",
+				"base": map[string]string{"ref": pr.Base},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	d.github = github.NewClient(github.WithBaseURL(server.URL), github.WithToken("test-token"))
+
+	repoPath := initWorkerBranchRepo(t, d, "pr-repo")
+	d.state.AddRepo("pr-repo", &state.Repository{
+		GithubURL:   "https://github.com/o/r.git",
+		TmuxSession: "mc-pr-repo",
+		Agents:      make(map[string]state.Agent),
+	})
+	d.state.AddAgent("pr-repo", "swift-fox", state.Agent{
+		Type:         state.AgentTypeWorker,
+		WorktreePath: repoPath,
+		TmuxWindow:   "swift-fox",
+		Task:         "Fix the login redirect\n\nUsers land on /home instead of where they started.",
+		CreatedAt:    time.Now(),
+	})
+
+	resp := d.handleRequest(socket.Request{Command: "complete_agent", Args: map[string]interface{}{
+		"repo": "pr-repo", "agent": "swift-fox", "pr": true,
+	}})
+	if !resp.Success {
+		t.Fatalf("handleCompleteAgent failed: %s", resp.Error)
+	}
+	data := resp.Data.(socket.CompletedAgent)
+	if !data.Pushed || data.PRURL != "https://github.com/o/r/pull/7" || data.PRNumber != 7 {
+		t.Errorf("unexpected response %+v", data)
+	}
+
+	if len(created) != 1 {
+		t.Fatalf("opened %d PRs, want 1", len(created))
+	}
+	pr := created[0]
+	if pr.Title != "Fix the login redirect" || pr.Head != "work/pr-repo" || pr.Base != "main" {
+		t.Errorf("unexpected PR %+v", pr)
+	}
+	for _, want := range []string{"Users land on /home", "`swift-fox`", "multiclaude attach swift-fox --repo pr-repo", "tmux attach -t mc-pr-repo"} {
+		if !strings.Contains(pr.Body, want) {
+			t.Errorf("PR body missing %q:\n%s", want, pr.Body)
+		}
+	}
+
+	agent, _ := d.state.GetAgent("pr-repo", "swift-fox")
+	if agent.PRURL != "https://github.com/o/r/pull/7" || agent.PRNumber != 7 {
+		t.Errorf("agent PR = %q #%d", agent.PRURL, agent.PRNumber)
+	}
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("got %d events, want 2", len(recorder.events))
+	}
+	if completed, ok := recorder.events[0].Payload.(events.AgentCompletedPayload); !ok || completed.PRURL != agent.PRURL {
+		t.Errorf("unexpected completion event %+v", recorder.events[0])
+	}
+	opened := recorder.events[1]
+	payload, ok := opened.Payload.(events.PRCreatedPayload)
+	if opened.Type != events.EventPRCreated || !ok || payload.PRNumber != 7 || payload.Base != "main" || payload.Branch != "work/pr-repo" {
+		t.Errorf("unexpected PR event %+v", opened)
+	}
+	if opened.Context["pr_url"] != "https://github.com/o/r/pull/7" {
+		t.Errorf("PR URL missing from event context: %v", opened.Context)
+	}
+}
+
+// TestCompleteAgentPushesToFork checks that a repository with a push remote
+// pushes workers' branches there and opens PRs from the fork's owner
+func TestCompleteAgentPushesToFork(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	var heads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/pulls":
+			heads = append(heads, r.URL.Query().Get("head"))
+			json.NewEncoder(w).Encode([]interface{}{})
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/o/r/pulls":
+			var pr github.NewPullRequest
+			json.NewDecoder(r.Body).Decode(&pr)
+			heads = append(heads, pr.Head)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"number": 9, "html_url": "https://github.com/o/r/pull/9"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	d.github = github.NewClient(github.WithBaseURL(server.URL), github.WithToken("test-token"))
+
+	repoPath := initWorkerBranchRepo(t, d, "fork-repo")
+	forkDir := t.TempDir()
+	runGitIn(t, forkDir, "init", "--bare", "-q")
+	runGitIn(t, repoPath, "remote", "add", "fork", "https://github.com/me/r.git")
+	runGitIn(t, repoPath, "remote", "set-url", "--push", "fork", forkDir)
+	d.state.AddRepo("fork-repo", &state.Repository{
+		GithubURL:   "https://github.com/o/r.git",
+		TmuxSession: "mc-fork-repo",
+		Agents:      make(map[string]state.Agent),
+	})
+	d.state.AddAgent("fork-repo", "fox", state.Agent{
+		Type: state.AgentTypeWorker, WorktreePath: repoPath, TmuxWindow: "fox", Task: "Fix it", CreatedAt: time.Now(),
+	})
+
+	update := func(remote string) socket.Response {
+		return d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "fork-repo", "push_remote": remote}})
+	}
+	if resp := update("nope"); resp.Success {
+		t.Error("update_repo_config should reject a remote the clone doesn't have")
+	}
+	if resp := update("fork"); !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	if out, err := exec.Command("git", "-C", repoPath, "config", "remote.pushDefault").Output(); err != nil || strings.TrimSpace(string(out)) != "fork" {
+		t.Errorf("remote.pushDefault = %q, %v; want fork", out, err)
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "complete_agent", Args: map[string]interface{}{
+		"repo": "fork-repo", "agent": "fox", "pr": true,
+	}})
+	if !resp.Success {
+		t.Fatalf("handleCompleteAgent failed: %s", resp.Error)
+	}
+	if out, err := exec.Command("git", "-C", forkDir, "rev-parse", "--verify", "refs/heads/work/fork-repo").Output(); err != nil {
+		t.Errorf("branch not pushed to the fork: %s %v", out, err)
+	}
+	if len(heads) != 2 || heads[0] != "me:work/fork-repo" || heads[1] != "me:work/fork-repo" {
+		t.Errorf("PR heads = %v, want me:work/fork-repo", heads)
+	}
+
+	// Setting origin again goes back to the default
+	if resp := update("origin"); !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	if repo, _ := d.state.GetRepo("fork-repo"); repo.PushRemote != "" {
+		t.Errorf("PushRemote = %q, want empty", repo.PushRemote)
+	}
+}
+
+// TestOpenAgentPRReusesOpenPR checks that completing again after a PR was
+// opened returns the existing PR instead of failing to create a duplicate
+func TestOpenAgentPRReusesOpenPR(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/pulls":
+			json.NewEncoder(w).Encode([]map[string]int{{"number": 3}})
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/pulls/3":
+			json.NewEncoder(w).Encode(map[string]interface{}{"number": 3, "html_url": "https://github.com/o/r/pull/3"})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	d.github = github.NewClient(github.WithBaseURL(server.URL), github.WithToken("test-token"))
+
+	d.state.AddRepo("pr-repo", &state.Repository{
+		GithubURL: "https://github.com/o/r.git",
+		Agents:    make(map[string]state.Agent),
+	})
+	agent := state.Agent{Type: state.AgentTypeWorker, Task: "fix it", BaseBranch: "release"}
+
+	pr, created, err := d.openAgentPR("pr-repo", "w", agent, "work/w")
+	if err != nil {
+		t.Fatalf("openAgentPR failed: %v", err)
+	}
+	if created || pr.Number != 3 {
+		t.Errorf("got PR #%d (created=%v), want the existing #3", pr.Number, created)
+	}
+}
+
+func TestAgentPRTitle(t *testing.T) {
+	long := strings.Repeat("word ", 30)
+	tests := []struct {
+		name  string
+		agent state.Agent
+		want  string
+	}{
+		{"summary first", state.Agent{Summary: "Add retries", Task: "Make the client robust"}, "Add retries"},
+		{"task first line", state.Agent{Task: "  Fix flaky test\nIt fails on CI."}, "Fix flaky test"},
+		{"fallback", state.Agent{}, "Work from w"},
+		{"truncated", state.Agent{Task: long}, strings.TrimSpace(long[:maxPRTitleLength-3]) + "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := agentPRTitle("w", tt.agent); got != tt.want {
+				t.Errorf("agentPRTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package daemon
+
+import (
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// pushRemote returns the remote a repository's workers push their branches
+// to: origin, or the fork set with --push-remote
+func (d *Daemon) pushRemote(repoName string) string {
+	repo, exists := d.state.GetRepo(repoName)
+	if !exists {
+		return state.DefaultPushRemote
+	}
+	return repo.WorkerPushRemote()
+}
+
+// prHead returns the head a pull request into owner's repository names for
+// a worker's branch. Branches pushed to a fork owned by someone else are
+// qualified with the fork's owner, as GitHub requires.
+func (d *Daemon) prHead(repoName, owner, branch string) string {
+	url, err := worktree.NewManager(d.paths.RepoDir(repoName)).RemoteURL(d.pushRemote(repoName))
+	if err != nil {
+		return branch
+	}
+	forkOwner, _, err := github.ParseRepoURL(url)
+	if err != nil || forkOwner == owner {
+		return branch
+	}
+	return forkOwner + ":" + branch
+}
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// handleAskQuestion records a question an agent needs a human to answer and
+// emits an agent.question event carrying a response ID. A reply sent to
+// respond_agent with just that ID reaches the agent's window.
+func (d *Daemon) handleAskQuestion(req socket.Request, args socket.AskQuestionArgs) (socket.ResponseID, error) {
+	repoName := args.Repo
+
+	agentName := args.Agent
+
+	question := args.Question
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.ResponseID{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+
+	// A new question replaces one still waiting, whose ID stops working
+	now := d.clock.Now()
+	if agent.Question != nil {
+		d.responses.Revoke(agent.Question.ResponseID)
+	}
+	responseID, expires := d.responses.Issue(repoName, agentName, now)
+	agent.Question = &state.PendingQuestion{Text: question, ResponseID: responseID, AskedAt: now}
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		return socket.ResponseID{}, fmt.Errorf("failed to record question: %v", err)
+	}
+
+	event := events.NewTypedEvent(repoName, agentName, fmt.Sprintf("%s has a question", agentName),
+		events.AgentQuestionPayload{
+			Question:          question,
+			ResponseID:        responseID,
+			ResponseExpiresAt: expires,
+		})
+	event.Message = fmt.Sprintf("%s\n\nReply with: multiclaude respond --response-id %s <reply>", question, responseID)
+	d.emitEvent(event)
+
+	d.logger.Info("%s/%s asked: %s", repoName, agentName, question)
+	return socket.ResponseID{ID: responseID, ExpiresAt: expires}, nil
+}
+
+// resolveQuestion clears the agent's pending question once it has been
+// answered. A reply that didn't use the question's response ID still
+// answers it, so the ID is revoked rather than left to be replayed.
+func (d *Daemon) resolveQuestion(repoName, agentName string) {
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists || agent.Question == nil {
+		return
+	}
+	d.responses.Revoke(agent.Question.ResponseID)
+	agent.Question = nil
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		d.logger.Error("Failed to resolve question of %s/%s: %v", repoName, agentName, err)
+	}
+}
+
+// responseTickets converts the response IDs saved in state for the
+// in-memory store
+func responseTickets(saved map[string]state.ResponseTicket) map[string]notify.ResponseTicket {
+	tickets := make(map[string]notify.ResponseTicket, len(saved))
+	for id, ticket := range saved {
+		tickets[id] = notify.ResponseTicket{Repo: ticket.Repo, Agent: ticket.Agent, Expires: ticket.Expires}
+	}
+	return tickets
+}
+
+// saveResponseIDs saves the outstanding response IDs in state, so a reply to
+// a question asked before a restart is still accepted, once
+func (d *Daemon) saveResponseIDs(tickets map[string]notify.ResponseTicket) {
+	saved := make(map[string]state.ResponseTicket, len(tickets))
+	for id, ticket := range tickets {
+		saved[id] = state.ResponseTicket{Repo: ticket.Repo, Agent: ticket.Agent, Expires: ticket.Expires}
+	}
+	if err := d.state.SetResponseIDs(saved); err != nil {
+		d.logger.Error("Failed to save response IDs: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestQuestionAnsweredByResponseID(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	session := fmt.Sprintf("mc-test-question-%d", time.Now().UnixNano())
+	if err := tmuxClient.CreateSessionAt(ctx, session, "worker", os.TempDir()); err != nil {
+		t.Fatalf("Failed to create tmux session: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, session)
+
+	d.state.AddRepo("repo", &state.Repository{
+		TmuxSession: session,
+		Agents: map[string]state.Agent{
+			"worker": {Type: state.AgentTypeWorker, TmuxWindow: "worker"},
+		},
+	})
+
+	ask := func(question string) string {
+		t.Helper()
+		resp := d.handleRequest(socket.Request{Command: "ask_question", Args: map[string]interface{}{
+			"repo": "repo", "agent": "worker", "question": question,
+		}})
+		if !resp.Success {
+			t.Fatalf("ask_question failed: %s", resp.Error)
+		}
+		return resp.Data.(socket.ResponseID).ID
+	}
+	respond := func(responseID string) socket.Response {
+		return d.handleRequest(socket.Request{Command: "respond_agent", Args: map[string]interface{}{
+			"response_id": responseID, "text": "use the v2 API",
+		}})
+	}
+
+	first := ask("Which API version should the client target?")
+	second := ask("Which API version should the client target, v1 or v2?")
+
+	recent := d.notify.Recent(0)
+	if len(recent) != 2 || recent[0].Type != events.EventAgentQuestion {
+		t.Fatalf("events = %+v, want two agent.question events, newest first", recent)
+	}
+	payload := recent[0].Payload.(events.AgentQuestionPayload)
+	if payload.ResponseID != second || !strings.Contains(payload.Question, "v1 or v2") || !strings.Contains(recent[0].Message, second) {
+		t.Errorf("event = %+v", recent[0])
+	}
+	agent, _ := d.state.GetAgent("repo", "worker")
+	if agent.Question == nil || agent.Question.ResponseID != second {
+		t.Fatalf("pending question = %+v, want the second question", agent.Question)
+	}
+
+	// Asking again replaced the first question, so its ID no longer works
+	if resp := respond(first); resp.Success || !strings.Contains(resp.Error, "reply rejected") {
+		t.Errorf("reply to the replaced question = %+v, want it rejected", resp)
+	}
+
+	resp := respond(second)
+	if !resp.Success {
+		t.Fatalf("respond_agent failed: %s", resp.Error)
+	}
+	if data := resp.Data.(socket.AgentRef); data.Agent != "worker" || data.Repo != "repo" {
+		t.Errorf("reply went to %v, want repo/worker", data)
+	}
+	agent, _ = d.state.GetAgent("repo", "worker")
+	if agent.Question != nil {
+		t.Errorf("question still pending after the reply: %+v", agent.Question)
+	}
+	if resp := respond(second); resp.Success {
+		t.Error("a response ID must not be reusable")
+	}
+}
+
+// TestQuestionAnsweredByName verifies that replying to the agent by name
+// resolves its question and revokes the question's response ID
+func TestQuestionAnsweredByName(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-test-question-name", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "worker", state.Agent{
+			Type:     state.AgentTypeWorker,
+			Question: &state.PendingQuestion{Text: "Ship it?", ResponseID: "stale"},
+		})
+	})
+	defer cleanup()
+	d.responses.Issue("repo", "worker", d.clock.Now())
+
+	d.resolveQuestion("repo", "worker")
+	agent, _ := d.state.GetAgent("repo", "worker")
+	if agent.Question != nil {
+		t.Errorf("question = %+v, want it resolved", agent.Question)
+	}
+	if _, _, err := d.responses.Owner("stale", d.clock.Now()); err == nil {
+		t.Error("the resolved question's response ID should be revoked")
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "respond_agent", Args: map[string]interface{}{"text": "yes"}})
+	if resp.Success || !strings.Contains(resp.Error, "repository name is required") {
+		t.Errorf("reply without an agent or response ID = %+v", resp)
+	}
+}
+
+// TestResponseIDsSurviveRestart verifies that outstanding response IDs are
+// saved in state and accepted by the next daemon
+func TestResponseIDsSurviveRestart(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-test-restart", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "worker", state.Agent{Type: state.AgentTypeWorker})
+	})
+	defer cleanup()
+
+	kept, _ := d.responses.Issue("repo", "worker", d.clock.Now())
+	used, _ := d.responses.Issue("repo", "worker", d.clock.Now())
+	if err := d.responses.Redeem(used, "repo", "worker", d.clock.Now()); err != nil {
+		t.Fatalf("Redeem failed: %v", err)
+	}
+
+	restarted, err := New(d.paths)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if repo, agent, err := restarted.responses.Owner(kept, restarted.clock.Now()); err != nil || repo != "repo" || agent != "worker" {
+		t.Errorf("Owner(%s) after restart = %s/%s, %v", kept, repo, agent, err)
+	}
+	if _, _, err := restarted.responses.Owner(used, restarted.clock.Now()); err == nil {
+		t.Error("a used response ID must stay used after a restart")
+	}
+}
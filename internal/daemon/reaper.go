@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/cleanup"
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/mux"
+)
+
+// zombieWindow tracks an unowned tmux window during its grace period
+type zombieWindow struct {
+	firstSeen time.Time
+	reported  bool // dry-run already logged it
+}
+
+// reapZombieWindows kills tmux windows that no agent in state owns once they
+// have been unowned for the repository's grace period. The grace period also
+// covers the moment between a window being created and its agent being
+// registered. In dry-run mode (the default) the windows are only logged.
+func (d *Daemon) reapZombieWindows(now time.Time) {
+	seen := make(map[string]bool)
+	for repoName, repo := range d.state.GetAllRepos() {
+		config := repo.WindowReaper
+		mode := config.EffectiveMode()
+		if mode == state.ReaperOff {
+			continue
+		}
+
+		windows, err := d.tmux.ListWindowInfo(d.ctx, repo.TmuxSession)
+		if err != nil {
+			// Missing sessions are restored by the health check
+			continue
+		}
+
+		keep := cleanup.KeptWindows(repo)
+
+		open := len(windows)
+		for _, window := range windows {
+			if keep[window.Name] {
+				continue
+			}
+			if value, err := d.tmux.GetWindowOption(d.ctx, repo.TmuxSession, window.Name, cleanup.KeepWindowOption); err == nil && value != "" && value != "off" {
+				continue
+			}
+
+			key := repo.TmuxSession + ":" + window.Name
+			seen[key] = true
+			d.zombieWindowsMu.Lock()
+			zombie, tracked := d.zombieWindows[key]
+			if !tracked {
+				zombie = zombieWindow{firstSeen: now}
+				d.zombieWindows[key] = zombie
+			}
+			d.zombieWindowsMu.Unlock()
+
+			unowned := now.Sub(zombie.firstSeen)
+			if unowned < config.Grace() {
+				continue
+			}
+			reason := describeZombieWindow(window)
+
+			if mode == state.ReaperDryRun {
+				if !zombie.reported {
+					d.logger.Info("Window reaper (dry run) would kill %s: no agent owns it (%s) for %s. Enable with `multiclaude config %s --reaper=enforce`, or keep it with --reaper-keep=%s",
+						key, reason, unowned.Round(time.Minute), repoName, window.Name)
+					zombie.reported = true
+					d.zombieWindowsMu.Lock()
+					d.zombieWindows[key] = zombie
+					d.zombieWindowsMu.Unlock()
+				}
+				continue
+			}
+
+			// Killing the last window would end the session
+			if open <= 1 {
+				continue
+			}
+			if err := d.tmux.KillWindow(d.ctx, repo.TmuxSession, window.Name); err != nil {
+				d.logger.Warn("Failed to reap zombie window %s: %v", key, err)
+				continue
+			}
+			open--
+			d.zombieWindowsMu.Lock()
+			delete(d.zombieWindows, key)
+			d.zombieWindowsMu.Unlock()
+			d.logger.Info("Reaped zombie window %s (%s, unowned for %s)", key, reason, unowned.Round(time.Minute))
+			d.recordAction(repoName, feed.ActionWindowReaped, "", fmt.Sprintf("%s (%s)", window.Name, reason))
+		}
+	}
+
+	// Forget windows that were closed or claimed by an agent
+	d.zombieWindowsMu.Lock()
+	for key := range d.zombieWindows {
+		if !seen[key] {
+			delete(d.zombieWindows, key)
+		}
+	}
+	d.zombieWindowsMu.Unlock()
+}
+
+// describeZombieWindow summarizes what an unowned window's pane is doing
+func describeZombieWindow(window mux.WindowInfo) string {
+	switch {
+	case window.PaneDead:
+		return "pane is dead"
+	case isShellCommand(window.PaneCommand):
+		return "idle " + window.PaneCommand + " shell"
+	case window.PaneCommand == "":
+		return "unknown command"
+	default:
+		return "running " + window.PaneCommand
+	}
+}
+
+// isShellCommand reports whether a pane's foreground command is a shell
+// waiting at its prompt
+func isShellCommand(command string) bool {
+	switch command {
+	case "sh", "bash", "zsh", "fish", "dash", "ksh", "tcsh", "csh":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/cleanup"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestReapZombieWindows(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	d, teardown := setupTestDaemon(t)
+	defer teardown()
+
+	ctx := context.Background()
+	sessionName := fmt.Sprintf("mc-test-reaper-%d", time.Now().UnixNano())
+	if err := tmuxClient.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("tmux is required for this test but cannot create sessions in this environment: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, sessionName)
+
+	for _, window := range []string{"worker", "zombie", "scratch", "tagged"} {
+		if err := tmuxClient.CreateWindow(ctx, sessionName, window); err != nil {
+			t.Fatalf("Failed to create window %s: %v", window, err)
+		}
+	}
+	if err := exec.Command("tmux", "set-option", "-w", "-t", sessionName+":tagged", cleanup.KeepWindowOption, "on").Run(); err != nil {
+		t.Fatalf("Failed to tag window: %v", err)
+	}
+
+	d.state.AddRepo("reaper-repo", &state.Repository{
+		TmuxSession:  sessionName,
+		Agents:       make(map[string]state.Agent),
+		WindowReaper: state.WindowReaperConfig{Keep: []string{"scratch"}},
+	})
+	d.state.AddAgent("reaper-repo", "worker", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker"})
+
+	hasWindow := func(name string) bool {
+		t.Helper()
+		ok, err := tmuxClient.HasWindow(ctx, sessionName, name)
+		if err != nil {
+			t.Fatalf("HasWindow failed: %v", err)
+		}
+		return ok
+	}
+
+	// Dry run (the default) never kills
+	start := time.Now()
+	d.reapZombieWindows(start)
+	d.reapZombieWindows(start.Add(time.Hour))
+	if !hasWindow("zombie") {
+		t.Fatal("dry-run mode should not kill windows")
+	}
+
+	d.state.UpdateWindowReaperConfig("reaper-repo", state.WindowReaperConfig{Mode: state.ReaperEnforce, GraceMinutes: 5, Keep: []string{"scratch"}})
+	if err := tmuxClient.CreateWindow(ctx, sessionName, "late"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	// zombie has been unowned since the first pass; late was just noticed
+	d.reapZombieWindows(start.Add(time.Hour + time.Minute))
+	if hasWindow("zombie") {
+		t.Error("zombie window should be reaped after the grace period")
+	}
+	if !hasWindow("late") {
+		t.Fatal("a newly seen window should survive its grace period")
+	}
+
+	d.reapZombieWindows(start.Add(time.Hour + 10*time.Minute))
+	if hasWindow("late") {
+		t.Error("late window should be reaped once its grace period passes")
+	}
+	for _, window := range []string{"worker", "scratch", "tagged"} {
+		if !hasWindow(window) {
+			t.Errorf("window %s should be kept", window)
+		}
+	}
+}
+
+func TestTriggerCleanupKillsUnownedWindows(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+
+	d, teardown := setupTestDaemon(t)
+	defer teardown()
+
+	ctx := context.Background()
+	sessionName := fmt.Sprintf("mc-test-cleanup-%d", time.Now().UnixNano())
+	if err := tmuxClient.CreateSession(ctx, sessionName, true); err != nil {
+		t.Fatalf("tmux is required for this test but cannot create sessions in this environment: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, sessionName)
+
+	for _, window := range []string{"worker", "stray", "tagged"} {
+		if err := tmuxClient.CreateWindow(ctx, sessionName, window); err != nil {
+			t.Fatalf("Failed to create window %s: %v", window, err)
+		}
+	}
+	if err := exec.Command("tmux", "set-option", "-w", "-t", sessionName+":tagged", cleanup.KeepWindowOption, "on").Run(); err != nil {
+		t.Fatalf("Failed to tag window: %v", err)
+	}
+
+	d.state.AddRepo("cleanup-repo", &state.Repository{TmuxSession: sessionName, Agents: make(map[string]state.Agent)})
+	d.state.AddAgent("cleanup-repo", "worker", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "worker"})
+
+	trigger := func(dryRun bool) []cleanup.Item {
+		t.Helper()
+		resp := d.handleRequest(socket.Request{Command: "trigger_cleanup", Args: map[string]interface{}{
+			"dry_run": dryRun,
+			"repo":    "cleanup-repo",
+		}})
+		if !resp.Success {
+			t.Fatalf("trigger_cleanup failed: %s", resp.Error)
+		}
+		items, _ := resp.Data.([]cleanup.Item)
+		return items
+	}
+
+	hasStray := func(items []cleanup.Item) bool {
+		for _, item := range items {
+			if item.Kind == cleanup.KindWindow && item.Target == sessionName+":stray" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if items := trigger(true); !hasStray(items) {
+		t.Fatalf("dry run should report the stray window, got %+v", items)
+	}
+	if ok, _ := tmuxClient.HasWindow(ctx, sessionName, "stray"); !ok {
+		t.Fatal("dry run should not kill windows")
+	}
+
+	if items := trigger(false); !hasStray(items) {
+		t.Fatalf("cleanup should report the stray window, got %+v", items)
+	}
+	if ok, _ := tmuxClient.HasWindow(ctx, sessionName, "stray"); ok {
+		t.Error("stray window should be killed")
+	}
+	for _, window := range []string{"worker", "tagged"} {
+		if ok, _ := tmuxClient.HasWindow(ctx, sessionName, window); !ok {
+			t.Errorf("window %s should be kept", window)
+		}
+	}
+}
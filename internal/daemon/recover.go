@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// recoverInterruptedWorktrees rolls back rebases and merges left idle in
+// worker worktrees of repositories with automatic recovery enabled
+func (d *Daemon) recoverInterruptedWorktrees(now time.Time) {
+	for repoName, repo := range d.state.GetAllRepos() {
+		if !repo.Recovery.Auto || d.isReadOnlyRepo(repoName, "worktree recovery") {
+			continue
+		}
+		for agentName, agent := range repo.Agents {
+			if agent.Type != state.AgentTypeWorker || agent.WorktreePath == "" {
+				continue
+			}
+			// A human resolving a conflict in the helper window, or the worker
+			// one paused for it, may take a while
+			if c := agent.RefreshConflict; c != nil && (c.Resolution == state.ConflictHelper || c.Paused) {
+				continue
+			}
+			if _, err := d.recoverWorktree(repoName, agentName, agent, repo.Recovery.After(), now); err != nil {
+				d.logger.Warn("Failed to recover worktree of %s/%s: %v", repoName, agentName, err)
+			}
+		}
+	}
+}
+
+// recoverWorktree aborts an interrupted rebase or merge in an agent's
+// worktree, restores refresh stashes, and tells the agent what was rolled back
+func (d *Daemon) recoverWorktree(repoName, agentName string, agent state.Agent, olderThan time.Duration, now time.Time) (worktree.RecoveryResult, error) {
+	result, err := worktree.Recover(agent.WorktreePath, olderThan, now)
+	if err != nil || (!result.Changed() && result.KeptStash == "") {
+		return result, err
+	}
+
+	summary := describeRecovery(result)
+	d.logger.Info("Recovered worktree of %s/%s: %s", repoName, agentName, summary)
+	d.recordAction(repoName, feed.ActionRecovered, agentName, summary)
+
+	msg := fmt.Sprintf("The daemon recovered your worktree: %s. Run `git status` and `git log --oneline -5` to check where you are before continuing.", summary)
+	if result.Aborted != nil {
+		msg += fmt.Sprintf(" If you still need the %s, start it again.", result.Aborted.Kind)
+	}
+	if result.KeptStash != "" {
+		msg += fmt.Sprintf(" Your stashed changes are still in %s; apply them with `git stash pop %s` once you're ready.", result.KeptStash, result.KeptStash)
+	}
+	if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, msg); err != nil {
+		d.logger.Warn("Failed to tell %s about the worktree recovery: %v", agentName, err)
+	}
+	go d.routeMessages()
+	return result, nil
+}
+
+// describeRecovery summarizes a recovery, e.g. "aborted a rebase idle since
+// 14:02; restored 1 refresh stash"
+func describeRecovery(result worktree.RecoveryResult) string {
+	var parts []string
+	if op := result.Aborted; op != nil {
+		parts = append(parts, fmt.Sprintf("aborted a %s idle since %s", op.Kind, op.LastActivity.Format(time.Kitchen)))
+	}
+	if n := len(result.RestoredStash); n == 1 {
+		parts = append(parts, "restored 1 refresh stash")
+	} else if n > 1 {
+		parts = append(parts, fmt.Sprintf("restored %d refresh stashes", n))
+	}
+	if result.KeptStash != "" {
+		parts = append(parts, fmt.Sprintf("kept refresh stash %s because %s", result.KeptStash, result.KeptReason))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// handleRecoverAgent recovers one agent's worktree on request. Operations
+// more recent than older_than_seconds (default: the repo's threshold) are
+// left alone unless force is set.
+func (d *Daemon) handleRecoverAgent(req socket.Request, args socket.RecoverAgentArgs) (socket.RecoveredWorktree, error) {
+	repoName := args.Repo
+	agentName := args.Agent
+
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.RecoveredWorktree{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+	}
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return socket.RecoveredWorktree{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+	if agent.WorktreePath == "" {
+		return socket.RecoveredWorktree{}, fmt.Errorf("agent '%s' has no worktree", agentName)
+	}
+
+	olderThan := repo.Recovery.After()
+	if seconds := args.OlderThanSeconds; seconds != nil && *seconds >= 0 {
+		olderThan = time.Duration(*seconds * float64(time.Second))
+	}
+	if args.Force {
+		olderThan = 0
+	}
+
+	result, err := d.recoverWorktree(repoName, agentName, agent, olderThan, d.clock.Now())
+	if err != nil {
+		return socket.RecoveredWorktree{}, err
+	}
+
+	recovered := socket.RecoveredWorktree{
+		RestoredStashes: len(result.RestoredStash),
+		KeptStash:       result.KeptStash,
+		KeptReason:      result.KeptReason,
+	}
+	if op := result.Aborted; op != nil {
+		recovered.Aborted = op.Kind
+		recovered.LastActivity = op.LastActivity.Format(time.RFC3339)
+	}
+	if op := result.Pending; op != nil {
+		recovered.Pending = op.Kind
+		recovered.LastActivity = op.LastActivity.Format(time.RFC3339)
+		recovered.OlderThanSeconds = olderThan.Seconds()
+	}
+	return recovered, nil
+}
@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+func TestRecoverInterruptedMerge(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoName := "recover-repo"
+	repoPath := d.paths.RepoDir(repoName)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(dir, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "shared.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGitIn(t, repoPath, "init", "-b", "main")
+	runGitIn(t, repoPath, "config", "user.email", "test@example.com")
+	runGitIn(t, repoPath, "config", "user.name", "Test User")
+	write(repoPath, "base\n")
+	runGitIn(t, repoPath, "add", "shared.txt")
+	runGitIn(t, repoPath, "commit", "-m", "Add shared file")
+
+	wtPath := d.paths.AgentWorktree(repoName, "worker")
+	if err := worktree.NewManager(repoPath).CreateNewBranch(wtPath, "work/worker", "main"); err != nil {
+		t.Fatal(err)
+	}
+	write(wtPath, "worker\n")
+	runGitIn(t, wtPath, "commit", "-am", "Worker change")
+	write(repoPath, "main\n")
+	runGitIn(t, repoPath, "commit", "-am", "Main change")
+	merge := exec.Command("git", "merge", "main")
+	merge.Dir = wtPath
+	if merge.Run() == nil {
+		t.Fatal("merge should have stopped at the conflict")
+	}
+
+	d.state.AddRepo(repoName, &state.Repository{
+		TmuxSession: "mc-recover-repo",
+		Agents: map[string]state.Agent{
+			"worker": {Type: state.AgentTypeWorker, WorktreePath: wtPath, TmuxWindow: "worker"},
+		},
+	})
+
+	// Automatic recovery is off by default, and a fresh merge isn't stale
+	d.recoverInterruptedWorktrees(time.Now().Add(time.Hour))
+	if op, _ := worktree.DetectInterrupted(wtPath); op == nil {
+		t.Fatal("recovery should only run automatically when enabled")
+	}
+	recoverAgent := func(args map[string]interface{}) socket.Response {
+		args["repo"], args["agent"] = repoName, "worker"
+		return d.handleRequest(socket.Request{Command: "recover_agent", Args: args})
+	}
+	resp := recoverAgent(map[string]interface{}{})
+	if data, _ := resp.Data.(socket.RecoveredWorktree); !resp.Success || data.Pending != "merge" {
+		t.Fatalf("recover_agent on a fresh merge = %+v", resp)
+	}
+
+	resp = recoverAgent(map[string]interface{}{"force": true})
+	if data, _ := resp.Data.(socket.RecoveredWorktree); !resp.Success || data.Aborted != "merge" {
+		t.Fatalf("recover_agent --force = %+v", resp)
+	}
+	if op, _ := worktree.DetectInterrupted(wtPath); op != nil {
+		t.Errorf("merge still in progress: %+v", op)
+	}
+
+	msgs, err := d.getMessageManager().List(repoName, "worker")
+	if err != nil || len(msgs) != 1 || !strings.Contains(msgs[0].Body, "aborted a merge") {
+		t.Errorf("worker messages = %+v, %v", msgs, err)
+	}
+}
@@ -0,0 +1,90 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+)
+
+// worktreeRefreshTick is how often the refresh loop checks whether workers
+// are due a refresh, so a reloaded interval takes effect within a minute
+const worktreeRefreshTick = time.Minute
+
+// worktreeRefreshDelay postpones the first refresh after startup
+const worktreeRefreshDelay = 30 * time.Second
+
+// warmPoolInterval is how often warm worktree pools are topped up
+const warmPoolInterval = 5 * time.Minute
+
+// refreshSkipReasons explain to a worker, by the reason GetWorktreeState
+// gives, why its worktree can't be refreshed and how to fix that. Other
+// reasons (up to date, on main) need nothing from the worker.
+var refreshSkipReasons = map[string]string{
+	"detached HEAD": "HEAD is detached; check out your branch so it can be synced",
+	"mid-rebase":    "a rebase is in progress; finish it with 'git rebase --continue' or 'git rebase --abort'",
+	"mid-merge":     "a merge is in progress; finish it with 'git merge --continue' or 'git merge --abort'",
+}
+
+// worktreeRefreshLoop rebases workers' worktrees onto their base at the
+// config file's worktrees.refresh interval and tops up warm worktree pools
+func (d *Daemon) worktreeRefreshLoop() {
+	defer d.wg.Done()
+	d.logger.Info("Starting worktree refresh loop")
+
+	// Run once after a short delay on startup (respecting context cancellation)
+	select {
+	case <-d.clock.After(worktreeRefreshDelay):
+		d.runWorktreeRefresh(d.clock.Now())
+	case <-d.ctx.Done():
+		d.logger.Info("Worktree refresh loop stopped")
+		return
+	}
+
+	ticker := d.clock.NewTicker(worktreeRefreshTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			d.runWorktreeRefresh(d.clock.Now())
+		case <-d.ctx.Done():
+			d.logger.Info("Worktree refresh loop stopped")
+			return
+		}
+	}
+}
+
+// runWorktreeRefresh refreshes workers and fills warm pools if each is due.
+// An interval of 0 (refresh: off) leaves workers alone.
+func (d *Daemon) runWorktreeRefresh(now time.Time) {
+	if interval := d.configFile().Worktrees.RefreshInterval(); interval > 0 && (d.lastRefresh.IsZero() || now.Sub(d.lastRefresh) >= interval) {
+		d.lastRefresh = now
+		d.refreshWorktrees()
+	}
+	if d.lastWarmFill.IsZero() || now.Sub(d.lastWarmFill) >= warmPoolInterval {
+		d.lastWarmFill = now
+		d.fillWarmPools()
+	}
+}
+
+// reportRefreshSkipped tells a worker why its worktree wasn't synced with
+// target. Each reason is sent once; an empty reason, after a refresh or once
+// the worktree is up to date, forgets the last one.
+func (d *Daemon) reportRefreshSkipped(repoName, agentName, target, reason string) {
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists || agent.RefreshSkip == reason {
+		return
+	}
+	agent.RefreshSkip = reason
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		d.logger.Warn("Failed to record refresh skip for %s/%s: %v", repoName, agentName, err)
+		return
+	}
+	if reason == "" {
+		return
+	}
+
+	msg := fmt.Sprintf("Your worktree could not be synced with %s: %s. The daemon retries every %s.",
+		target, reason, d.configFile().Worktrees.RefreshInterval())
+	if _, err := d.getMessageManager().Send(repoName, "daemon", agentName, msg); err != nil {
+		d.logger.Debug("Could not send refresh skip notice to %s/%s: %v", repoName, agentName, err)
+	}
+}
@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+func TestRunWorktreeRefreshInterval(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	file, err := config.ParseFile([]byte("worktrees:\n  refresh: 10m\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.settings = file
+
+	start := time.Now()
+	d.runWorktreeRefresh(start)
+	if !d.lastRefresh.Equal(start) || !d.lastWarmFill.Equal(start) {
+		t.Fatalf("first pass did not run: refresh %v, warm fill %v", d.lastRefresh, d.lastWarmFill)
+	}
+	d.runWorktreeRefresh(start.Add(5 * time.Minute))
+	if !d.lastRefresh.Equal(start) {
+		t.Error("refreshed before the 10m interval passed")
+	}
+	if !d.lastWarmFill.Equal(start.Add(5 * time.Minute)) {
+		t.Error("warm pools should still fill every 5 minutes")
+	}
+	d.runWorktreeRefresh(start.Add(10 * time.Minute))
+	if !d.lastRefresh.Equal(start.Add(10 * time.Minute)) {
+		t.Error("did not refresh once the interval passed")
+	}
+
+	d.settings = &config.File{Worktrees: config.WorktreeSettings{Refresh: config.RefreshOff}}
+	d.runWorktreeRefresh(start.Add(time.Hour))
+	if !d.lastRefresh.Equal(start.Add(10 * time.Minute)) {
+		t.Error("refreshed with refresh: off")
+	}
+}
+
+func TestRefreshSkipNotifiesWorker(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoName := "skip-repo"
+	repoPath := d.paths.RepoDir(repoName)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	commitFile := func(dir, name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGitIn(t, dir, "add", name)
+		runGitIn(t, dir, "commit", "-m", "Add "+name)
+	}
+	runGitIn(t, repoPath, "init", "-b", "main")
+	runGitIn(t, repoPath, "config", "user.email", "test@example.com")
+	runGitIn(t, repoPath, "config", "user.name", "Test User")
+	commitFile(repoPath, "README")
+	runGitIn(t, repoPath, "remote", "add", "origin", repoPath)
+	runGitIn(t, repoPath, "fetch", "origin")
+
+	wtPath := d.paths.AgentWorktree(repoName, "worker")
+	if err := worktree.NewManager(repoPath).CreateNewBranch(wtPath, "work/worker", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	commitFile(wtPath, "worker.txt")
+	runGitIn(t, wtPath, "checkout", "-q", "--detach")
+	commitFile(repoPath, "main.txt")
+
+	d.state.AddRepo(repoName, &state.Repository{
+		TmuxSession: "mc-skip-repo",
+		Agents: map[string]state.Agent{
+			"worker": {Type: state.AgentTypeWorker, WorktreePath: wtPath, TmuxWindow: "worker"},
+		},
+	})
+	messages := func() []string {
+		t.Helper()
+		msgs, err := d.getMessageManager().List(repoName, "worker")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies := make([]string, len(msgs))
+		for i, m := range msgs {
+			bodies[i] = m.Body
+		}
+		return bodies
+	}
+
+	// A detached worktree is skipped, and the worker told once
+	d.refreshWorktrees()
+	d.refreshWorktrees()
+	got := messages()
+	if len(got) != 1 || !strings.Contains(got[0], "HEAD is detached") {
+		t.Fatalf("messages = %q, want one about the detached HEAD", got)
+	}
+	if agent, _ := d.state.GetAgent(repoName, "worker"); agent.RefreshSkip == "" {
+		t.Error("the skip reason was not recorded")
+	}
+
+	// Back on its branch, the worker is rebased and the reason forgotten
+	runGitIn(t, wtPath, "checkout", "-q", "work/worker")
+	d.refreshWorktrees()
+	if _, err := os.Stat(filepath.Join(wtPath, "main.txt")); err != nil {
+		t.Error("worker should be rebased onto main")
+	}
+	if agent, _ := d.state.GetAgent(repoName, "worker"); agent.RefreshSkip != "" {
+		t.Errorf("RefreshSkip = %q after a refresh", agent.RefreshSkip)
+	}
+	if got := messages(); len(got) != 2 || !strings.Contains(got[1], "synced with main") {
+		t.Errorf("messages = %q, want the refresh notice last", got)
+	}
+}
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+// reloadConfig re-reads the config file and applies what changed without
+// touching agents: notification adapters are replaced, GitHub budgets
+// reset, the HTTP API and TCP socket restarted, tmux sessions renamed if their prefix
+// changed, and task queues dispatched again in case max_workers rose. It
+// returns the sections of the file that changed. An invalid file leaves the running settings in place.
+func (d *Daemon) reloadConfig() ([]string, error) {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	file, err := config.LoadFile(d.paths.ConfigFile())
+	if err != nil {
+		return nil, err
+	}
+	old := d.configFile()
+
+	var changed []string
+	if !reflect.DeepEqual(old.Notifications, file.Notifications) {
+		adapters, err := d.fileAdapters(file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification settings in %s: %w", d.paths.ConfigFile(), err)
+		}
+		for _, name := range d.fileAdapterNames {
+			d.notify.Unregister(name)
+		}
+		d.fileAdapterNames = nil
+		for _, adapter := range adapters {
+			d.registerAdapter(adapter)
+			d.fileAdapterNames = append(d.fileAdapterNames, adapter.Name())
+		}
+		d.routes = d.fileRoutes(file)
+		d.notify.SetRoutes(d.routes)
+		d.warnUnroutedAdapters()
+		changed = append(changed, "notifications")
+	}
+
+	d.settingsMu.Lock()
+	d.settings = file
+	d.settingsMu.Unlock()
+
+	if !reflect.DeepEqual(old.GitHub, file.GitHub) {
+		d.applyGitHubBudgets(old.GitHub.Budgets, file.GitHub.Budgets)
+		changed = append(changed, "github")
+	}
+	if !reflect.DeepEqual(old.API, file.API) {
+		d.stopAPI()
+		if err := d.startAPI(); err != nil {
+			d.logger.Error("API server disabled: %v", err)
+		}
+		changed = append(changed, "api")
+	}
+	if !reflect.DeepEqual(old.Remote, file.Remote) {
+		d.stopRemote()
+		if err := d.startRemote(); err != nil {
+			d.logger.Error("Remote socket disabled: %v", err)
+		}
+		changed = append(changed, "remote")
+	}
+	if !reflect.DeepEqual(old.Tmux, file.Tmux) {
+		// The prefix being replaced counts as an earlier one even if the
+		// file doesn't list it
+		namer := config.NewNamer(file.Tmux.SessionPrefix,
+			append(append([]string(nil), file.Tmux.PreviousSessionPrefixes...), old.Namer().Prefix())...)
+		d.migrateSessionNames(namer)
+		changed = append(changed, "tmux")
+	}
+	if !reflect.DeepEqual(old.Events, file.Events) {
+		// The event store was opened with the old retention
+		d.logger.Warn("The events.retention setting takes effect when the daemon restarts")
+		changed = append(changed, "events")
+	}
+	if old.Multiplexer != file.Multiplexer {
+		// Agents can't move between multiplexers while they run
+		d.logger.Warn("The multiplexer setting takes effect when the daemon restarts")
+		changed = append(changed, "multiplexer")
+	}
+	limitsChanged := false
+	if !reflect.DeepEqual(old.Worktrees, file.Worktrees) {
+		changed = append(changed, "worktrees")
+		limitsChanged = true
+	}
+	if !reflect.DeepEqual(old.Defaults, file.Defaults) || !reflect.DeepEqual(old.Repos, file.Repos) {
+		changed = append(changed, "repos")
+		limitsChanged = true
+	}
+	if limitsChanged {
+		// A higher max_workers or disk quota may leave room for queued tasks
+		go d.dispatchAllTaskQueues()
+	}
+
+	if len(changed) == 0 {
+		d.logger.Info("Reloaded %s: nothing changed", d.paths.ConfigFile())
+	} else {
+		d.logger.Info("Reloaded %s: %v changed", d.paths.ConfigFile(), changed)
+	}
+	return changed, nil
+}
+
+// signalLoop reloads the config file whenever the daemon receives SIGHUP
+func (d *Daemon) signalLoop() {
+	defer d.wg.Done()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-hup:
+			if _, err := d.reloadConfig(); err != nil {
+				d.logger.Error("Failed to reload config file: %v", err)
+			}
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// handleReloadConfig re-reads the config file, as SIGHUP does
+func (d *Daemon) handleReloadConfig(req socket.Request, args socket.NoArgs) (socket.ReloadedConfig, error) {
+	changed, err := d.reloadConfig()
+	if err != nil {
+		return socket.ReloadedConfig{}, err
+	}
+	if changed == nil {
+		changed = []string{}
+	}
+	return socket.ReloadedConfig{Changed: changed}, nil
+}
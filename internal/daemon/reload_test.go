@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestReloadConfig(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "fox", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "fox"})
+	})
+	defer cleanup()
+
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(d.paths.ConfigFile(), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reload := func() socket.Response {
+		t.Helper()
+		return d.handleRequest(socket.Request{Command: "reload_config"})
+	}
+
+	write("notifications:\n  slack:\n    webhook_url: https://hooks.slack.test/x\ndefaults:\n  max_workers: 1\n")
+	resp := reload()
+	if !resp.Success {
+		t.Fatalf("reload_config failed: %s", resp.Error)
+	}
+	data, _ := resp.Data.(socket.ReloadedConfig)
+	if got := data.Changed; !reflect.DeepEqual(got, []string{"notifications", "repos"}) {
+		t.Errorf("changed = %v, want notifications and repos", got)
+	}
+	if adapters := strings.Join(d.notify.Adapters(), ","); !strings.Contains(adapters, "slack") {
+		t.Errorf("adapters = %s, want slack", adapters)
+	}
+	if err := d.workerCapacityError("repo"); err == nil {
+		t.Error("workerCapacityError = nil, want the reloaded limit of 1")
+	}
+
+	// Swapping Slack for Telegram replaces the adapter, and the agent stays
+	write("notifications:\n  telegram:\n    bot_token: t\n    chat_id: c\ndefaults:\n  max_workers: 1\n")
+	if resp := reload(); !resp.Success {
+		t.Fatalf("reload_config failed: %s", resp.Error)
+	}
+	adapters := strings.Join(d.notify.Adapters(), ",")
+	if strings.Contains(adapters, "slack") || !strings.Contains(adapters, "telegram") {
+		t.Errorf("adapters = %s, want telegram only", adapters)
+	}
+	if _, exists := d.state.GetAgent("repo", "fox"); !exists {
+		t.Error("reload removed an agent")
+	}
+
+	// Routes are replaced with the adapters
+	write("notifications:\n  telegram:\n    bot_token: t\n    chat_id: c\n  routes:\n    - types: [agent.question]\n      to: [telegram]\ndefaults:\n  max_workers: 1\n")
+	if resp := reload(); !resp.Success {
+		t.Fatalf("reload_config failed: %s", resp.Error)
+	}
+	if !d.routes.Routed("telegram") {
+		t.Errorf("routes after reload = %+v, want telegram routed", d.routes)
+	}
+
+	// Nothing changed
+	resp = reload()
+	data, _ = resp.Data.(socket.ReloadedConfig)
+	if !resp.Success || len(data.Changed) != 0 {
+		t.Errorf("reload_config = %+v, want no changes", resp)
+	}
+
+	// An invalid file keeps the running settings
+	write("defaults:\n  max_workers: -2\n")
+	if resp := reload(); resp.Success || !strings.Contains(resp.Error, "max_workers") {
+		t.Errorf("reload_config with an invalid file = %+v, want an error", resp)
+	}
+	if got := d.configFile().Defaults.MaxWorkers; got == nil || *got != 1 {
+		t.Errorf("max_workers after a failed reload = %v, want 1", got)
+	}
+}
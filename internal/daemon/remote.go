@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"crypto/tls"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// startRemote starts the TCP socket when the config file sets
+// remote.listen. It serves every socket command, as the Unix socket does, to
+// clients sending remote.token. Callers holding the token count as the
+// daemon's user.
+func (d *Daemon) startRemote() error {
+	settings := d.configFile().Remote
+	if settings.Listen == "" {
+		return nil
+	}
+	var tlsConfig *tls.Config
+	if settings.TLSCert != "" {
+		var err error
+		if tlsConfig, err = socket.ServerTLSConfig(settings.TLSCert, settings.TLSKey); err != nil {
+			return err
+		}
+	}
+	server := socket.NewRemoteServer(settings.Listen, settings.Token, tlsConfig, socket.HandlerFunc(d.dispatchRequest))
+	if err := server.Start(); err != nil {
+		return err
+	}
+	go func() {
+		if err := server.Serve(); err != nil && d.ctx.Err() == nil {
+			d.logger.Debug("Remote socket stopped: %v", err)
+		}
+	}()
+	d.remoteMu.Lock()
+	d.remote = server
+	d.remoteMu.Unlock()
+	d.logger.Info("Remote socket listening on %s (TLS: %v)", server.Addr(), tlsConfig != nil)
+	return nil
+}
+
+// stopRemote stops the TCP socket, if it is running. Requests already
+// received still finish.
+func (d *Daemon) stopRemote() {
+	d.remoteMu.Lock()
+	server := d.remote
+	d.remote = nil
+	d.remoteMu.Unlock()
+	if server == nil {
+		return
+	}
+	if err := server.Stop(); err != nil {
+		d.logger.Error("Failed to stop remote socket: %v", err)
+	}
+}
+
+// remoteAddr returns the address the TCP socket listens on, or "" when it
+// is off
+func (d *Daemon) remoteAddr() string {
+	d.remoteMu.Lock()
+	defer d.remoteMu.Unlock()
+	if d.remote == nil {
+		return ""
+	}
+	return d.remote.Addr().String()
+}
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestRemoteSocket(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+	})
+	defer cleanup()
+
+	if err := d.startRemote(); err != nil || d.remoteAddr() != "" {
+		t.Fatalf("startRemote() without remote.listen = %v, %q; want it off", err, d.remoteAddr())
+	}
+
+	d.settings.Remote.Listen = "127.0.0.1:0"
+	d.settings.Remote.Token = "t0ken"
+	if err := d.startRemote(); err != nil {
+		t.Fatalf("startRemote() failed: %v", err)
+	}
+	defer d.stopRemote()
+	addr := d.remoteAddr()
+	if addr == "" {
+		t.Fatal("remote socket has no address")
+	}
+
+	client := socket.NewRemoteClient(addr, "t0ken", nil)
+	resp, err := client.Send(socket.Request{Command: "whoami"})
+	if err != nil || !resp.Success {
+		t.Fatalf("whoami = %+v, %v", resp, err)
+	}
+	data := resp.Data.(map[string]interface{})
+	if data["owner"] != true || data["remote"] == nil {
+		t.Errorf("whoami = %v, want a remote caller counted as the owner", data)
+	}
+
+	resp, err = client.Send(socket.Request{Command: "list_agents", Args: map[string]interface{}{"repo": "repo"}})
+	if err != nil || !resp.Success {
+		t.Errorf("list_agents over TCP = %+v, %v", resp, err)
+	}
+
+	resp, err = socket.NewRemoteClient(addr, "wrong", nil).Send(socket.Request{Command: "list_repos"})
+	if err != nil || resp.Success || resp.Code != socket.CodePermissionDenied {
+		t.Errorf("list_repos with a wrong token = %+v, %v; want %s", resp, err, socket.CodePermissionDenied)
+	}
+
+	d.stopRemote()
+	if d.remoteAddr() != "" {
+		t.Error("remote socket still listening after stopRemote()")
+	}
+}
@@ -0,0 +1,179 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// lockedCommands are the socket commands that create, remove, or rewrite
+// worktrees, and the argument that names the repository. They are refused
+// for repositories another daemon holds the lock on.
+var lockedCommands = map[string]string{
+	"add_agent":               "repo",
+	"spawn_agent":             "repo",
+	"claim_warm_worktree":     "repo",
+	"handoff_agent":           "repo",
+	"restart_agent":           "repo",
+	"remove_agent":            "repo",
+	"pull_agent_branch":       "repo",
+	"resolve_conflict":        "repo",
+	"resume_refresh":          "repo",
+	"recover_agent":           "repo",
+	"add_scratch_worktree":    "repo",
+	"remove_scratch_worktree": "repo",
+}
+
+// claimRepoLocks acquires or refreshes the lock of every tracked repository.
+// Repositories locked by a live daemon elsewhere are put in read-only mode:
+// this daemon keeps reporting on them but stops touching their worktrees.
+func (d *Daemon) claimRepoLocks() {
+	for _, repoName := range d.state.ListRepos() {
+		d.claimRepoLock(repoName)
+	}
+}
+
+// claimRepoLock acquires or refreshes one repository's lock and updates its
+// read-only mode. Returns an error if the lock is held by another daemon.
+func (d *Daemon) claimRepoLock(repoName string) error {
+	repoPath := d.paths.RepoDir(repoName)
+	if _, err := os.Stat(repoPath); err != nil {
+		return nil
+	}
+
+	wt := worktree.NewManager(repoPath)
+	err := wt.AcquireLock(d.lockOwner, d.clock.Now())
+
+	var held *worktree.LockHeldError
+	if errors.As(err, &held) {
+		d.repoLocksMu.Lock()
+		_, known := d.foreignLocks[repoName]
+		d.foreignLocks[repoName] = held.Owner
+		d.repoLocksMu.Unlock()
+		if !known {
+			d.logger.Warn("Repository %s is managed by another daemon (%s); treating it as read-only", repoName, held.Owner)
+		}
+		return err
+	}
+	if err != nil {
+		// A lock that can't be read or written (e.g. a read-only mount) is
+		// not a sign of another daemon, so carry on without one
+		d.logger.Warn("Failed to lock repository %s: %v", repoName, err)
+		return nil
+	}
+
+	d.repoLocksMu.Lock()
+	previous, wasForeign := d.foreignLocks[repoName]
+	delete(d.foreignLocks, repoName)
+	d.repoLocksMu.Unlock()
+	if wasForeign {
+		d.logger.Info("Took over repository %s from %s", repoName, previous)
+	}
+
+	if foreign, err := wt.ForeignWorktrees(d.paths.WorktreeDir(repoName), d.paths.WarmPoolDir(repoName), d.paths.ScratchDir(repoName)); err == nil && len(foreign) > 0 {
+		d.logger.Warn("Repository %s has %d worktree(s) outside %s; another multiclaude may have used this clone", repoName, len(foreign), d.paths.Root)
+	}
+	return nil
+}
+
+// foreignLock returns the daemon holding a repository's lock if it isn't
+// this one
+func (d *Daemon) foreignLock(repoName string) (worktree.LockOwner, bool) {
+	d.repoLocksMu.Lock()
+	defer d.repoLocksMu.Unlock()
+	owner, ok := d.foreignLocks[repoName]
+	return owner, ok
+}
+
+// isReadOnlyRepo returns true if another daemon manages the repository, and
+// logs why a background task is skipping it
+func (d *Daemon) isReadOnlyRepo(repoName, task string) bool {
+	owner, ok := d.foreignLock(repoName)
+	if ok {
+		d.logger.Debug("Skipping %s for %s: managed by %s", task, repoName, owner)
+	}
+	return ok
+}
+
+// releaseRepoLocks removes the locks this daemon holds, so another daemon
+// can take over without waiting for them to go stale
+func (d *Daemon) releaseRepoLocks() {
+	for _, repoName := range d.state.ListRepos() {
+		d.releaseRepoLock(repoName)
+	}
+}
+
+// releaseRepoLock removes one repository's lock if this daemon holds it
+func (d *Daemon) releaseRepoLock(repoName string) {
+	repoPath := d.paths.RepoDir(repoName)
+	if _, err := os.Stat(repoPath); err != nil {
+		return
+	}
+	if err := worktree.NewManager(repoPath).ReleaseLock(d.lockOwner); err != nil {
+		d.logger.Warn("Failed to release lock of repository %s: %v", repoName, err)
+	}
+}
+
+// checkRepoLock refuses commands that would change the worktrees of a
+// repository another daemon manages
+func (d *Daemon) checkRepoLock(req socket.Request) (socket.Response, bool) {
+	repoArg, ok := lockedCommands[req.Command]
+	if !ok {
+		return socket.Response{}, true
+	}
+	repoName, _ := req.Args[repoArg].(string)
+	owner, foreign := d.foreignLock(repoName)
+	if !foreign {
+		return socket.Response{}, true
+	}
+	return socket.Response{
+		Success: false,
+		Error: fmt.Sprintf("repository '%s' is managed by another multiclaude daemon (%s); this daemon is read-only for it. "+
+			"If that daemon is gone, run: multiclaude repo lock --repo %s --take-over", repoName, owner, repoName),
+		Code: socket.CodeConflict,
+	}, false
+}
+
+// handleRepoLock reports who holds a repository's lock, and takes it over
+// when take_over is set
+func (d *Daemon) handleRepoLock(req socket.Request, args socket.RepoLockArgs) (socket.RepoLock, error) {
+	repoName := args.Repo
+	if _, exists := d.state.GetAllRepos()[repoName]; !exists {
+		return socket.RepoLock{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+	}
+
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	if args.TakeOver {
+		previous, err := wt.TakeOverLock(d.lockOwner, d.clock.Now())
+		if err != nil {
+			return socket.RepoLock{}, err
+		}
+		if previous != nil && !previous.Same(d.lockOwner) {
+			d.logger.Warn("Repository %s taken over from %s", repoName, previous)
+		}
+	}
+	d.claimRepoLock(repoName)
+
+	var lock socket.RepoLock
+	owner, err := wt.ReadLock()
+	if err != nil {
+		return socket.RepoLock{}, err
+	}
+	if owner != nil {
+		lock.Owner = owner.String()
+		lock.Heartbeat = owner.Heartbeat.Format(time.RFC3339)
+		lock.ReadOnly = !owner.Same(d.lockOwner)
+	}
+
+	foreign, err := wt.ForeignWorktrees(d.paths.WorktreeDir(repoName), d.paths.WarmPoolDir(repoName), d.paths.ScratchDir(repoName))
+	if err == nil {
+		for _, f := range foreign {
+			lock.ForeignWorktrees = append(lock.ForeignWorktrees, f.Path)
+		}
+	}
+	return lock, nil
+}
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+func TestForeignRepoLockIsReadOnly(t *testing.T) {
+	d, cleanup := setupTestDaemon(t)
+	defer cleanup()
+
+	repoName := "shared-repo"
+	repoPath := d.paths.RepoDir(repoName)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGitIn(t, repoPath, "init", "-b", "main")
+	d.state.AddRepo(repoName, &state.Repository{
+		TmuxSession: "mc-shared-repo",
+		Agents:      make(map[string]state.Agent),
+	})
+
+	// A daemon on another machine sharing the clone holds a fresh lock
+	wt := worktree.NewManager(repoPath)
+	other := worktree.LockOwner{Host: "elsewhere", PID: 4242, Root: d.paths.Root}
+	if err := wt.AcquireLock(other, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	d.claimRepoLocks()
+	if _, foreign := d.foreignLock(repoName); !foreign {
+		t.Fatal("repository locked by another daemon should be read-only")
+	}
+
+	resp := d.dispatchRequest(socket.Request{Command: "add_agent", Args: map[string]interface{}{
+		"repo": repoName, "agent": "worker", "type": "worker", "worktree_path": "/tmp/x", "tmux_window": "worker",
+	}})
+	if resp.Success || !strings.Contains(resp.Error, "elsewhere") || !strings.Contains(resp.Error, "--take-over") {
+		t.Errorf("add_agent on read-only repo = %+v, want refusal naming the owner", resp)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "list_repos", Args: map[string]interface{}{"rich": true}})
+	repos := resp.Data.(socket.RepoList).Repos
+	if owner := repos[0].LockedBy; !strings.Contains(owner, "elsewhere") {
+		t.Errorf("list_repos locked_by = %q", owner)
+	}
+
+	// Taking over makes the repository writable again
+	resp = d.handleRequest(socket.Request{Command: "repo_lock", Args: map[string]interface{}{
+		"repo": repoName, "take_over": true,
+	}})
+	if !resp.Success {
+		t.Fatalf("repo_lock take over failed: %s", resp.Error)
+	}
+	if data := resp.Data.(socket.RepoLock); data.ReadOnly {
+		t.Errorf("repo_lock after take over = %+v", data)
+	}
+	if _, foreign := d.foreignLock(repoName); foreign {
+		t.Error("repository should be writable after take over")
+	}
+	owner, _ := wt.ReadLock()
+	if owner == nil || !owner.Same(d.lockOwner) {
+		t.Errorf("lock owner after take over = %+v", owner)
+	}
+
+	// Stopping releases the lock so the other daemon needn't wait
+	d.releaseRepoLocks()
+	if owner, _ := wt.ReadLock(); owner != nil {
+		t.Errorf("lock not released: %+v", owner)
+	}
+}
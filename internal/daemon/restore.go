@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// restoreState answers restore_state: it replaces the daemon's state with a
+// backup of the state file, the newest valid one unless args names one.
+// Agents the backup lists that no longer run are left for repair_state.
+func (d *Daemon) restoreState(req socket.Request, args socket.RestoreStateArgs) (socket.RestoreState, error) {
+	result := socket.RestoreState{Backups: state.ListBackups(d.paths.StateFile)}
+	if result.Backups == nil {
+		result.Backups = []state.Backup{}
+	}
+	if args.Backup < 0 || args.Backup > state.BackupCount {
+		return result, socket.Errorf(socket.CodeInvalidArgument, "backup must be between 1 and %d", state.BackupCount)
+	}
+
+	if args.DryRun {
+		backup, err := state.PickBackup(result.Backups, args.Backup)
+		if err != nil {
+			return result, socket.Errorf(socket.CodeNotFound, "%v", err)
+		}
+		result.Backup = backup.N
+		return result, nil
+	}
+
+	n, err := d.state.RestoreBackup(args.Backup)
+	if err != nil {
+		return result, socket.Errorf(socket.CodeNotFound, "%v", err)
+	}
+	result.Backup = n
+	d.logger.Warn("Restored state from backup %d of %s", n, d.paths.StateFile)
+	return result, nil
+}
+
+// reportRestoredState records that the state file was unreadable at startup
+// and Load fell back to a backup
+func (d *Daemon) reportRestoredState() {
+	n := d.state.RestoredFrom()
+	if n == 0 {
+		return
+	}
+	d.logger.Warn("State file %s could not be read; loaded backup %d instead", d.paths.StateFile, n)
+	d.recordDaemonChange("startup", audit.ActionStateRestored, "", "", fmt.Sprintf("backup=%d", n))
+}
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestRestoreState(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	if _, err := d.restoreState(socket.Request{}, socket.RestoreStateArgs{DryRun: true}); err == nil {
+		t.Error("restore_state without backups should fail")
+	}
+
+	d.state.AddRepo("kept", &state.Repository{TmuxSession: "mc-kept"})
+	d.state.AddRepo("lost", &state.Repository{TmuxSession: "mc-lost"})
+
+	plan, err := d.restoreState(socket.Request{}, socket.RestoreStateArgs{DryRun: true})
+	if err != nil || plan.Backup != 1 || len(plan.Backups) != 1 || plan.Backups[0].Repos != 1 {
+		t.Fatalf("restore_state dry run = %+v, %v", plan, err)
+	}
+	if len(d.state.ListRepos()) != 2 {
+		t.Error("a dry run shouldn't change the state")
+	}
+
+	resp := d.dispatchRequest(socket.Request{Command: "restore_state", Version: socket.ProtocolVersion,
+		Peer: &socket.Peer{UID: os.Getuid(), User: "ann"}})
+	if !resp.Success {
+		t.Fatalf("restore_state failed: %s", resp.Error)
+	}
+	if repos := d.state.ListRepos(); len(repos) != 1 || repos[0] != "kept" {
+		t.Errorf("repos after restore = %v, want only kept", repos)
+	}
+	entries, _ := d.audit.List(audit.Filter{})
+	if len(entries) != 1 || entries[0].Action != audit.ActionStateRestored {
+		t.Errorf("audit log = %+v, want the restore", entries)
+	}
+
+	resp = d.dispatchRequest(socket.Request{Command: "restore_state", Version: socket.ProtocolVersion,
+		Args: map[string]interface{}{"backup": 4}, Peer: &socket.Peer{UID: os.Getuid(), User: "ann"}})
+	if resp.Success || resp.Code != socket.CodeNotFound {
+		t.Errorf("restore_state of a missing backup = %+v, want %s", resp, socket.CodeNotFound)
+	}
+}
+
+func TestNewLoadsBackupOfCorruptState(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("first", &state.Repository{TmuxSession: "mc-first"})
+		s.AddRepo("second", &state.Repository{TmuxSession: "mc-second"})
+	})
+	defer cleanup()
+
+	if err := os.WriteFile(d.paths.StateFile, []byte(`{"repos": {`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	restarted, err := New(d.paths)
+	if err != nil {
+		t.Fatalf("New() with a corrupted state file failed: %v", err)
+	}
+	if restarted.state.RestoredFrom() != 1 || len(restarted.state.ListRepos()) != 1 {
+		t.Errorf("New() loaded backup %d with repos %v, want backup 1", restarted.state.RestoredFrom(), restarted.state.ListRepos())
+	}
+	entries, _ := restarted.audit.List(audit.Filter{})
+	if len(entries) != 1 || entries[0].Action != audit.ActionStateRestored || entries[0].Actor != audit.DaemonActor {
+		t.Errorf("audit log = %+v, want the startup restore", entries)
+	}
+}
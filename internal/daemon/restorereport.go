@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// What startup restoration did with a repository
+const (
+	restoreSessionKept      = "session_kept"      // the tmux session survived; dead agents were checked
+	restoreSessionRecreated = "session_recreated" // the tmux session was gone and was rebuilt
+	restoreSkipped          = "skipped"           // another daemon manages the repository
+	restoreRepoFailed       = "failed"            // the repository could not be checked or restored
+)
+
+// What startup restoration did with an agent
+const (
+	restoreAlive         = "alive"          // its process was still running
+	restoreRestarted     = "restarted"      // its process was dead and it was resumed
+	restoreStarted       = "started"        // it was started in a recreated session
+	restoreDropped       = "dropped"        // it was removed from state with its session
+	restoreLeftToCleanup = "left_to_health" // the health check will restart or clean it up
+	restoreAgentFailed   = "failed"         // restarting or starting it failed
+	restoreHeadless      = "headless"       // it runs without a window and was left alone
+)
+
+// recordRestore keeps the restoration report for daemon status, writes it to
+// the log and emits it through the hub
+func (d *Daemon) recordRestore(report events.DaemonRestoredPayload) {
+	d.lastRestoreMu.Lock()
+	d.lastRestore = &report
+	d.lastRestoreMu.Unlock()
+
+	for _, repo := range report.Repos {
+		d.logger.Info("Restore: %s %s", repo.Repo, restoreRepoSummary(repo))
+	}
+
+	title := fmt.Sprintf("Daemon restored %d repositories", len(report.Repos))
+	if len(report.Repos) == 1 {
+		title = "Daemon restored 1 repository"
+	}
+	event := events.NewTypedEvent("", "", title, report)
+	event.Message = restoreMessage(report)
+	event.Priority = events.PriorityLow
+	for _, repo := range report.Repos {
+		if restoreNeedsAttention(repo) {
+			event.Priority = events.PriorityNormal
+			break
+		}
+	}
+	d.emitEvent(event)
+}
+
+// getLastRestore returns the report of the restoration this daemon ran when
+// it started, or nil before it has finished
+func (d *Daemon) getLastRestore() *events.DaemonRestoredPayload {
+	d.lastRestoreMu.Lock()
+	defer d.lastRestoreMu.Unlock()
+	return d.lastRestore
+}
+
+// restoreNeedsAttention reports whether anything was lost or failed while
+// restoring repo
+func restoreNeedsAttention(repo events.RestoredRepo) bool {
+	if repo.Action == restoreRepoFailed {
+		return true
+	}
+	for _, agent := range repo.Agents {
+		if agent.Outcome == restoreDropped || agent.Outcome == restoreAgentFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreRepoSummary describes one repository's restoration on one line,
+// e.g. "session_recreated (tmux session was missing): supervisor started, w1 dropped"
+func restoreRepoSummary(repo events.RestoredRepo) string {
+	var b strings.Builder
+	b.WriteString(repo.Action)
+	if repo.Reason != "" {
+		fmt.Fprintf(&b, " (%s)", repo.Reason)
+	}
+	for i, agent := range repo.Agents {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s %s", agent.Name, agent.Outcome)
+		if agent.Error != "" {
+			fmt.Fprintf(&b, " (%s)", agent.Error)
+		}
+	}
+	return b.String()
+}
+
+// restoreMessage renders the report for chat destinations, one line per
+// repository
+func restoreMessage(report events.DaemonRestoredPayload) string {
+	lines := make([]string, 0, len(report.Repos))
+	for _, repo := range report.Repos {
+		lines = append(lines, fmt.Sprintf("%s: %s", repo.Repo, restoreRepoSummary(repo)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/review"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// handleCheckReviewChecklist validates a reviewer's PR comment against the
+// review checklists that apply to the PR checked out in its worktree
+func (d *Daemon) handleCheckReviewChecklist(req socket.Request, args socket.CheckReviewChecklistArgs) (socket.ReviewChecklistCheck, error) {
+	repoName := args.Repo
+
+	agentName := args.Agent
+
+	body := args.Body
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.ReviewChecklistCheck{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+	if agent.Type != state.AgentTypeReview || agent.WorktreePath == "" {
+		return socket.ReviewChecklistCheck{}, fmt.Errorf("agent '%s' is not a review agent", agentName)
+	}
+
+	base, err := d.upstreamBaseRef(repoName)
+	if err != nil {
+		return socket.ReviewChecklistCheck{}, fmt.Errorf("failed to determine base branch: %v", err)
+	}
+	checklists, err := review.ForWorktree(d.paths.RepoDir(repoName), agent.WorktreePath, base)
+	if err != nil {
+		return socket.ReviewChecklistCheck{}, fmt.Errorf("failed to load review checklists: %v", err)
+	}
+	if len(checklists) == 0 {
+		return socket.ReviewChecklistCheck{Passed: true, Report: "No review checklists apply to this PR"}, nil
+	}
+
+	result := review.Validate(body, checklists)
+	return socket.ReviewChecklistCheck{
+		Passed:    result.Valid(),
+		Missing:   result.Missing,
+		Unchecked: result.Unchecked,
+		Report:    result.String(),
+	}, nil
+}
@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/review"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestHandleCheckReviewChecklist(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "review-repo")
+	for path, content := range map[string]string{
+		".multiclaude/" + review.ConfigFile: `{"checklists": [
+			{"name": "Migrations", "paths": ["migrations/"], "items": ["Rollback note"]},
+			{"name": "Auth", "paths": ["auth/"], "items": ["Security signoff"]}
+		]}`,
+		"migrations/0001_init.sql": "CREATE TABLE users (id int);\n",
+	} {
+		full := filepath.Join(repoPath, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGitIn(t, repoPath, "add", "migrations")
+	runGitIn(t, repoPath, "commit", "-m", "Add users table")
+
+	d.state.AddRepo("review-repo", &state.Repository{TmuxSession: "mc-review-repo", Agents: make(map[string]state.Agent)})
+	d.state.AddAgent("review-repo", "review-7", state.Agent{Type: state.AgentTypeReview, WorktreePath: repoPath})
+	d.state.AddAgent("review-repo", "worker", state.Agent{Type: state.AgentTypeWorker, WorktreePath: repoPath})
+
+	check := func(agent, body string) socket.Response {
+		return d.handleRequest(socket.Request{Command: "check_review_checklist", Args: map[string]interface{}{
+			"repo": "review-repo", "agent": agent, "body": body,
+		}})
+	}
+
+	if resp := check("worker", "LGTM"); resp.Success || !strings.Contains(resp.Error, "not a review agent") {
+		t.Errorf("workers should be rejected, got %+v", resp)
+	}
+
+	resp := check("review-7", "LGTM")
+	if !resp.Success {
+		t.Fatalf("check_review_checklist failed: %s", resp.Error)
+	}
+	data := resp.Data.(socket.ReviewChecklistCheck)
+	if data.Passed || !strings.Contains(data.Report, "Rollback note") {
+		t.Errorf("expected the migrations item to be missing, got %+v", data)
+	}
+	if strings.Contains(data.Report, "Security signoff") {
+		t.Errorf("auth checklist should not apply to this PR: %s", data.Report)
+	}
+
+	resp = check("review-7", "LGTM\n\n## Review Checklist\n- [x] Rollback note - included\n")
+	if data := resp.Data.(socket.ReviewChecklistCheck); !data.Passed {
+		t.Errorf("expected checklist to pass, got %+v", data)
+	}
+}
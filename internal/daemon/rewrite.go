@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// checkMainRewrite compares the freshly fetched default branch with the head
+// seen on the previous pass. When the old head is no longer an ancestor, main
+// was force-pushed: rebasing workers onto it would drag rewritten or dropped
+// commits into their branches, so auto-refresh is paused until a human
+// confirms. It returns true while refresh is paused for the repository.
+func (d *Daemon) checkMainRewrite(repoName string, repo *state.Repository, wt *worktree.Manager, remote, mainBranch string) bool {
+	if repo.HistoryRewrite != nil {
+		d.logger.Debug("Skipping refresh for %s: waiting for confirmation after %s was rewritten", repoName, repo.HistoryRewrite.Branch)
+		return true
+	}
+
+	head, err := wt.RemoteHead(remote, mainBranch)
+	if err != nil {
+		d.logger.Debug("Could not resolve %s/%s for %s: %v", remote, mainBranch, repoName, err)
+		return false
+	}
+	if head == repo.MainHead {
+		return false
+	}
+
+	if repo.MainHead != "" {
+		rewritten, err := wt.IsHistoryRewrite(repo.MainHead, head)
+		if err != nil {
+			// Don't record the new head, so the comparison is retried next pass
+			d.logger.Warn("Could not check %s/%s of %s for history rewrites: %v", remote, mainBranch, repoName, err)
+			return true
+		}
+		if rewritten {
+			d.pauseRefreshForRewrite(repoName, repo, &state.HistoryRewrite{
+				Branch:     remote + "/" + mainBranch,
+				OldHead:    repo.MainHead,
+				NewHead:    head,
+				DetectedAt: d.clock.Now(),
+			})
+			return true
+		}
+	}
+
+	if err := d.state.SetMainHead(repoName, head); err != nil {
+		d.logger.Error("Failed to record %s head for %s: %v", mainBranch, repoName, err)
+	}
+	return false
+}
+
+// pauseRefreshForRewrite records a rewrite of main and tells the supervisor,
+// the workers, and humans about it
+func (d *Daemon) pauseRefreshForRewrite(repoName string, repo *state.Repository, rewrite *state.HistoryRewrite) {
+	if err := d.state.SetHistoryRewrite(repoName, rewrite); err != nil {
+		d.logger.Error("Failed to record history rewrite for %s: %v", repoName, err)
+		return
+	}
+	d.logger.Warn("%s of %s was force-pushed (%.12s is no longer an ancestor of %.12s); auto-refresh paused",
+		rewrite.Branch, repoName, rewrite.OldHead, rewrite.NewHead)
+	d.recordAction(repoName, feed.ActionRefreshPaused, "", fmt.Sprintf("%s force-pushed from %.12s to %.12s", rewrite.Branch, rewrite.OldHead, rewrite.NewHead))
+
+	msgMgr := d.getMessageManager()
+	if _, err := msgMgr.Send(repoName, "daemon", "supervisor",
+		fmt.Sprintf("%s was force-pushed: %.12s is no longer part of its history (now at %.12s). "+
+			"Automatic worktree refresh is paused until a human runs `multiclaude repo resume-refresh --repo %s`.",
+			rewrite.Branch, rewrite.OldHead, rewrite.NewHead, repoName)); err != nil {
+		d.logger.Warn("Failed to notify supervisor of history rewrite: %v", err)
+	}
+	for agentName, agent := range repo.Agents {
+		if agent.Type != state.AgentTypeWorker {
+			continue
+		}
+		if _, err := msgMgr.Send(repoName, "daemon", agentName,
+			fmt.Sprintf("%s was force-pushed and its history no longer matches what your branch was based on. "+
+				"Do not rebase onto or merge %s until a human confirms; keep committing to your own branch.",
+				rewrite.Branch, rewrite.Branch)); err != nil {
+			d.logger.Warn("Failed to tell %s about the history rewrite: %v", agentName, err)
+		}
+	}
+	go d.routeMessages()
+
+	event := events.NewTypedEvent(repoName, "",
+		fmt.Sprintf("%s of %s was force-pushed", rewrite.Branch, repoName),
+		events.MainRewrittenPayload{
+			Branch:  rewrite.Branch,
+			OldHead: rewrite.OldHead,
+			NewHead: rewrite.NewHead,
+		})
+	event.Priority = events.PriorityHigh
+	event.Message = fmt.Sprintf("Auto-refresh of worker worktrees is paused. Check the rewrite, then run `multiclaude repo resume-refresh --repo %s`.", repoName)
+	d.emitEvent(event)
+}
+
+// handleResumeRefresh confirms a rewrite of main and resumes auto-refresh
+func (d *Daemon) handleResumeRefresh(req socket.Request, args socket.RepoArgs) (socket.ResumedRefresh, error) {
+	repoName := args.Repo
+
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.ResumedRefresh{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+	}
+	rewrite := repo.HistoryRewrite
+	if rewrite == nil {
+		return socket.ResumedRefresh{}, nil
+	}
+
+	// Accept the rewritten head as the new baseline
+	if err := d.state.SetMainHead(repoName, rewrite.NewHead); err != nil {
+		return socket.ResumedRefresh{}, err
+	}
+	if err := d.state.SetHistoryRewrite(repoName, nil); err != nil {
+		return socket.ResumedRefresh{}, err
+	}
+
+	d.logger.Info("Auto-refresh resumed for %s after %s was rewritten", repoName, rewrite.Branch)
+	d.recordAction(repoName, feed.ActionRefreshResume, "", fmt.Sprintf("%s accepted at %.12s", rewrite.Branch, rewrite.NewHead))
+	return socket.ResumedRefresh{
+		Paused:  true,
+		Branch:  rewrite.Branch,
+		OldHead: rewrite.OldHead,
+		NewHead: rewrite.NewHead,
+	}, nil
+}
@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+func TestCheckMainRewriteAndResumeRefresh(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "rewrite-repo")
+	runGitIn(t, repoPath, "checkout", "main")
+	d.state.AddRepo("rewrite-repo", &state.Repository{TmuxSession: "mc-rewrite-repo", Agents: make(map[string]state.Agent)})
+	d.state.AddAgent("rewrite-repo", "worker", state.Agent{Type: state.AgentTypeWorker, WorktreePath: repoPath})
+
+	wt := worktree.NewManager(repoPath)
+	check := func() bool {
+		t.Helper()
+		runGitIn(t, repoPath, "fetch", "origin")
+		repo := d.state.GetAllRepos()["rewrite-repo"]
+		return d.checkMainRewrite("rewrite-repo", repo, wt, "origin", "main")
+	}
+
+	if check() {
+		t.Fatal("first pass should only record the head")
+	}
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "Normal change")
+	if check() {
+		t.Fatal("a fast-forward of main should not pause refresh")
+	}
+
+	// Force-push: drop the last commit and replace it
+	runGitIn(t, repoPath, "reset", "--hard", "HEAD~1")
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "Rewritten change")
+	if !check() {
+		t.Fatal("a rewrite of main should pause refresh")
+	}
+	repo, _ := d.state.GetRepo("rewrite-repo")
+	if repo.HistoryRewrite == nil || repo.HistoryRewrite.Branch != "origin/main" {
+		t.Fatalf("HistoryRewrite = %+v, want a rewrite of origin/main", repo.HistoryRewrite)
+	}
+	msgs, _ := d.getMessageManager().List("rewrite-repo", "supervisor")
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Body, "resume-refresh") {
+		t.Errorf("supervisor should be told how to resume, got %v", msgs)
+	}
+	if !check() {
+		t.Error("refresh should stay paused until confirmed")
+	}
+
+	resp := d.handleRequest(socket.Request{Command: "resume_refresh", Args: map[string]interface{}{"repo": "rewrite-repo"}})
+	if !resp.Success {
+		t.Fatalf("resume_refresh failed: %s", resp.Error)
+	}
+	if check() {
+		t.Error("refresh should resume after confirmation")
+	}
+	if msgs, _ := d.getMessageManager().List("rewrite-repo", "supervisor"); len(msgs) != 1 {
+		t.Errorf("the rewrite should be reported once, got %d messages", len(msgs))
+	}
+}
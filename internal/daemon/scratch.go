@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// maxScratchWorktrees bounds how many scratch worktrees one agent may hold
+const maxScratchWorktrees = 3
+
+// scratchNamePattern restricts scratch names to a single path and branch
+// name component
+var scratchNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// findScratch returns the index of the agent's scratch worktree with the
+// given name, or -1
+func findScratch(agent state.Agent, name string) int {
+	for i, s := range agent.Scratch {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// handleAddScratchWorktree creates an extra worktree for an agent under
+// scratch/<repo>/<agent>/<name>, detached at a revision of the agent's own
+// worktree or on a new branch named after the agent's
+func (d *Daemon) handleAddScratchWorktree(req socket.Request, args socket.AddScratchWorktreeArgs) (socket.ScratchWorktree, error) {
+	repoName := args.Repo
+	agentName := args.Agent
+	name := args.Name
+	if !scratchNamePattern.MatchString(name) {
+		return socket.ScratchWorktree{}, fmt.Errorf("invalid scratch worktree name %q: use letters, digits, '-' and '_'", name)
+	}
+	rev := args.Rev
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.ScratchWorktree{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+	if agent.WorktreePath == "" {
+		return socket.ScratchWorktree{}, fmt.Errorf("agent '%s' has no worktree", agentName)
+	}
+	if findScratch(agent, name) >= 0 {
+		return socket.ScratchWorktree{}, fmt.Errorf("agent '%s' already has a scratch worktree named '%s'", agentName, name)
+	}
+	if len(agent.Scratch) >= maxScratchWorktrees {
+		return socket.ScratchWorktree{}, fmt.Errorf("agent '%s' already has %d scratch worktrees; remove one first", agentName, len(agent.Scratch))
+	}
+
+	branch := ""
+	if args.Branch {
+		current, err := worktree.GetCurrentBranch(agent.WorktreePath)
+		if err != nil || current == "HEAD" {
+			return socket.ScratchWorktree{}, fmt.Errorf("agent '%s' is not on a branch to name the scratch branch after", agentName)
+		}
+		branch = current + "-" + name
+	}
+
+	path := d.paths.ScratchWorktree(repoName, agentName, name)
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	commit, err := wt.CreateScratch(path, agent.WorktreePath, rev, branch)
+	if err != nil {
+		return socket.ScratchWorktree{}, err
+	}
+
+	agent.Scratch = append(append([]state.ScratchWorktree(nil), agent.Scratch...), state.ScratchWorktree{
+		Name:      name,
+		Path:      path,
+		Branch:    branch,
+		CreatedAt: d.clock.Now(),
+	})
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		_ = wt.Remove(path, true)
+		return socket.ScratchWorktree{}, err
+	}
+
+	d.logger.Info("Created scratch worktree %s for %s/%s at %s", name, repoName, agentName, commit)
+	return socket.ScratchWorktree{Name: name, Path: path, Branch: branch, Commit: commit}, nil
+}
+
+// handleRemoveScratchWorktree removes one of an agent's scratch worktrees
+func (d *Daemon) handleRemoveScratchWorktree(req socket.Request, args socket.ScratchWorktreeArgs) (socket.ScratchWorktree, error) {
+	repoName := args.Repo
+	agentName := args.Agent
+	name := args.Name
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return socket.ScratchWorktree{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+	i := findScratch(agent, name)
+	if i < 0 {
+		return socket.ScratchWorktree{}, fmt.Errorf("agent '%s' has no scratch worktree named '%s'", agentName, name)
+	}
+
+	scratch := agent.Scratch[i]
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	if err := wt.Remove(scratch.Path, true); err != nil {
+		if _, statErr := os.Stat(scratch.Path); statErr == nil {
+			return socket.ScratchWorktree{}, err
+		}
+	}
+
+	agent.Scratch = append(append([]state.ScratchWorktree(nil), agent.Scratch[:i]...), agent.Scratch[i+1:]...)
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		return socket.ScratchWorktree{}, err
+	}
+
+	d.logger.Info("Removed scratch worktree %s of %s/%s", name, repoName, agentName)
+	return socket.ScratchWorktree{Name: name, Branch: scratch.Branch}, nil
+}
+
+// handleListScratchWorktrees lists an agent's scratch worktrees
+func (d *Daemon) handleListScratchWorktrees(req socket.Request, args socket.AgentArgs) ([]state.ScratchWorktree, error) {
+	repoName := args.Repo
+	agentName := args.Agent
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return nil, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+	scratch := agent.Scratch
+	if scratch == nil {
+		scratch = []state.ScratchWorktree{}
+	}
+	return scratch, nil
+}
+
+// removeScratchWorktrees removes all of an agent's scratch worktrees once
+// the agent itself is gone. Their branches are left for branch cleanup.
+func (d *Daemon) removeScratchWorktrees(repoName, agentName string, agent state.Agent) {
+	if len(agent.Scratch) == 0 {
+		return
+	}
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	for _, scratch := range agent.Scratch {
+		if err := wt.Remove(scratch.Path, true); err != nil {
+			d.logger.Warn("Failed to remove scratch worktree %s: %v", scratch.Path, err)
+			continue
+		}
+		d.logger.Info("Removed scratch worktree %s of %s/%s", scratch.Name, repoName, agentName)
+	}
+	os.RemoveAll(filepath.Join(d.paths.ScratchDir(repoName), agentName))
+}
+
+// cleanupOrphanedScratch removes scratch worktrees of agents that no longer
+// exist, e.g. when the daemon stopped before cleaning up after them
+func (d *Daemon) cleanupOrphanedScratch(repoName string, wt *worktree.Manager) {
+	scratchDir := d.paths.ScratchDir(repoName)
+	entries, err := os.ReadDir(scratchDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if _, exists := d.state.GetAgent(repoName, entry.Name()); exists {
+			continue
+		}
+		agentDir := filepath.Join(scratchDir, entry.Name())
+		if worktrees, err := os.ReadDir(agentDir); err == nil {
+			for _, w := range worktrees {
+				_ = wt.Remove(filepath.Join(agentDir, w.Name()), true)
+			}
+		}
+		if err := os.RemoveAll(agentDir); err != nil {
+			d.logger.Warn("Failed to remove orphaned scratch worktrees %s: %v", agentDir, err)
+			continue
+		}
+		d.logger.Info("Removed orphaned scratch worktrees of %s/%s", repoName, entry.Name())
+	}
+}
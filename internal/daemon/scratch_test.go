@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestScratchWorktrees(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+	created := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	d.clock = clock.NewFake(created)
+
+	repoPath := initWorkerBranchRepo(t, d, "scratch-repo")
+	runGitIn(t, repoPath, "commit", "--allow-empty", "-m", "worker commit")
+	d.state.AddRepo("scratch-repo", &state.Repository{
+		TmuxSession: "mc-scratch-repo",
+		Agents: map[string]state.Agent{
+			"busy": {Type: state.AgentTypeWorker, WorktreePath: repoPath, TmuxWindow: "busy"},
+		},
+	})
+
+	send := func(command string, args map[string]interface{}) socket.Response {
+		t.Helper()
+		args["repo"] = "scratch-repo"
+		args["agent"] = "busy"
+		return d.handleRequest(socket.Request{Command: command, Args: args})
+	}
+
+	resp := send("add_scratch_worktree", map[string]interface{}{"name": "bisect", "rev": "HEAD~1"})
+	if !resp.Success {
+		t.Fatalf("add_scratch_worktree failed: %s", resp.Error)
+	}
+	bisect := resp.Data.(socket.ScratchWorktree)
+	bisectPath := bisect.Path
+	if bisectPath != d.paths.ScratchWorktree("scratch-repo", "busy", "bisect") || bisect.Branch != "" {
+		t.Errorf("unexpected scratch worktree: %v", bisect)
+	}
+	head, _ := exec.Command("git", "-C", bisectPath, "rev-parse", "HEAD").Output()
+	want, _ := exec.Command("git", "-C", repoPath, "rev-parse", "HEAD~1").Output()
+	if len(head) == 0 || string(head) != string(want) {
+		t.Errorf("scratch worktree HEAD = %s, want the worker's HEAD~1 %s", head, want)
+	}
+
+	resp = send("add_scratch_worktree", map[string]interface{}{"name": "spike", "branch": true})
+	if !resp.Success {
+		t.Fatalf("add_scratch_worktree --branch failed: %s", resp.Error)
+	}
+	spike := resp.Data.(socket.ScratchWorktree)
+	if spike.Branch != "work/scratch-repo-spike" {
+		t.Errorf("spike branch = %v, want work/scratch-repo-spike", spike.Branch)
+	}
+
+	for name, args := range map[string]map[string]interface{}{
+		"duplicate": {"name": "spike"},
+		"bad name":  {"name": "../escape"},
+		"bad rev":   {"name": "other", "rev": "no-such-rev"},
+	} {
+		if resp := send("add_scratch_worktree", args); resp.Success {
+			t.Errorf("%s: add_scratch_worktree should fail", name)
+		}
+	}
+
+	agent, _ := d.state.GetAgent("scratch-repo", "busy")
+	if len(agent.Scratch) != 2 {
+		t.Fatalf("agent has %d scratch worktrees in state, want 2", len(agent.Scratch))
+	}
+	if !agent.Scratch[0].CreatedAt.Equal(created) {
+		t.Errorf("scratch worktree created at %v, want the daemon clock's %v", agent.Scratch[0].CreatedAt, created)
+	}
+
+	if resp := send("remove_scratch_worktree", map[string]interface{}{"name": "bisect"}); !resp.Success {
+		t.Fatalf("remove_scratch_worktree failed: %s", resp.Error)
+	}
+	if _, err := os.Stat(bisectPath); !os.IsNotExist(err) {
+		t.Errorf("removed scratch worktree still exists: %v", err)
+	}
+	resp = send("list_scratch_worktrees", map[string]interface{}{})
+	if list, _ := resp.Data.([]state.ScratchWorktree); len(list) != 1 || list[0].Name != "spike" {
+		t.Errorf("list_scratch_worktrees = %v, want only spike", resp.Data)
+	}
+
+	// Removing the agent removes its remaining scratch worktrees
+	if resp := send("remove_agent", map[string]interface{}{}); !resp.Success {
+		t.Fatalf("remove_agent failed: %s", resp.Error)
+	}
+	if _, err := os.Stat(spike.Path); !os.IsNotExist(err) {
+		t.Errorf("scratch worktree should be removed with its agent: %v", err)
+	}
+	if out, _ := exec.Command("git", "-C", repoPath, "worktree", "list").Output(); strings.Contains(string(out), "/scratch/") {
+		t.Errorf("git still lists scratch worktrees:\n%s", out)
+	}
+}
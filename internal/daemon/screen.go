@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// maxScreenLines bounds the scrollback agent_screen returns
+const maxScreenLines = 5000
+
+// headlessScreenLines is how many lines of output stand in for the screen
+// of a headless agent
+const headlessScreenLines = 50
+
+// agentScreen returns what an agent's tmux pane currently shows, plus up to
+// args.Lines lines of scrollback above it (none by default). Headless agents
+// get the end of their output instead.
+func (d *Daemon) agentScreen(req socket.Request, args socket.AgentScreenArgs) (socket.AgentScreen, error) {
+	if args.Lines < 0 || args.Lines > maxScreenLines {
+		return socket.AgentScreen{}, socket.Errorf(socket.CodeInvalidArgument, "lines must be between 0 and %d", maxScreenLines)
+	}
+
+	repo, exists := d.state.GetRepo(args.Repo)
+	if !exists {
+		return socket.AgentScreen{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", args.Repo)
+	}
+	agent, exists := repo.Agents[args.Agent]
+	if !exists {
+		return socket.AgentScreen{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", args.Agent, args.Repo)
+	}
+
+	if agent.Headless {
+		// Without a pane, the end of the agent's output is the closest thing
+		// to its screen
+		return socket.AgentScreen{
+			Repo:     args.Repo,
+			Agent:    args.Agent,
+			Headless: true,
+			Screen:   strings.Join(d.headlessOutputTail(args.Repo, args.Agent, agent, headlessScreenLines+args.Lines), "\n"),
+		}, nil
+	}
+
+	screen, err := d.tmux.CapturePane(d.ctx, repo.TmuxSession, agent.TmuxWindow, args.Lines)
+	if err != nil {
+		return socket.AgentScreen{}, fmt.Errorf("failed to capture agent '%s' screen: %w", args.Agent, err)
+	}
+	return socket.AgentScreen{
+		Repo:   args.Repo,
+		Agent:  args.Agent,
+		Window: agent.TmuxWindow,
+		Screen: screen,
+	}, nil
+}
+
+// screenTail returns the last n non-blank lines an agent's pane shows, or
+// nil when the pane can't be captured
+func (d *Daemon) screenTail(session, window string, n int) []string {
+	if window == "" {
+		// Headless agents have no pane
+		return nil
+	}
+	screen, err := d.tmux.CapturePane(d.ctx, session, window, 0)
+	if err != nil {
+		return nil
+	}
+	return lastLines(screen, n)
+}
+
+// lastLines returns the last n non-blank lines of text
+func lastLines(text string, n int) []string {
+	var tail []string
+	lines := strings.Split(text, "\n")
+	for i := len(lines) - 1; i >= 0 && len(tail) < n; i-- {
+		if line := strings.TrimRight(lines[i], " \r"); strings.TrimSpace(line) != "" {
+			tail = append([]string{line}, tail...)
+		}
+	}
+	return tail
+}
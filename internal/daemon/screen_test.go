@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestHandleAgentScreen(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+	ctx := context.Background()
+	session := "mc-test-screen"
+	if err := tmuxClient.CreateSession(ctx, session, true); err != nil {
+		t.Fatalf("tmux is required for this test but cannot create sessions in this environment: %v", err)
+	}
+	defer tmuxClient.KillSession(ctx, session)
+	if err := tmuxClient.CreateWindow(ctx, session, "fox"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: session, Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "fox", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "fox"})
+		s.AddAgent("repo", "gone", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "gone"})
+	})
+	defer cleanup()
+
+	if err := tmuxClient.SendKeysLiteralWithEnter(ctx, session, "fox", "echo on-screen-$((6*7))"); err != nil {
+		t.Fatal(err)
+	}
+	var screen string
+	for i := 0; i < 20 && !strings.Contains(screen, "on-screen-42"); i++ {
+		time.Sleep(250 * time.Millisecond)
+		resp := d.handleRequest(socket.Request{Command: "agent_screen", Args: map[string]interface{}{"repo": "repo", "agent": "fox", "lines": float64(50)}})
+		if !resp.Success {
+			t.Fatalf("agent_screen failed: %s", resp.Error)
+		}
+		screen = resp.Data.(socket.AgentScreen).Screen
+	}
+	if !strings.Contains(screen, "on-screen-42") {
+		t.Errorf("screen = %q, want the command's output", screen)
+	}
+	if tail := d.screenTail(session, "fox", 3); len(tail) == 0 || strings.TrimSpace(tail[len(tail)-1]) == "" {
+		t.Errorf("screenTail = %q, want the last non-blank lines", tail)
+	}
+	if tail := d.screenTail(session, "gone", 3); tail != nil {
+		t.Errorf("screenTail of a missing window = %q, want nil", tail)
+	}
+
+	for _, tc := range []struct {
+		name string
+		args map[string]interface{}
+	}{
+		{"missing agent", map[string]interface{}{"repo": "repo", "agent": "nobody"}},
+		{"missing window", map[string]interface{}{"repo": "repo", "agent": "gone"}},
+		{"negative lines", map[string]interface{}{"repo": "repo", "agent": "fox", "lines": float64(-1)}},
+		{"no agent", map[string]interface{}{"repo": "repo"}},
+	} {
+		if resp := d.handleRequest(socket.Request{Command: "agent_screen", Args: tc.args}); resp.Success {
+			t.Errorf("%s: agent_screen should fail", tc.name)
+		}
+	}
+}
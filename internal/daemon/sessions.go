@@ -0,0 +1,42 @@
+package daemon
+
+import "github.com/dlorenc/multiclaude/pkg/config"
+
+// migrateSessionNames renames the tmux sessions of repositories still named
+// with one of namer's earlier prefixes and records the new names, so a
+// changed tmux.session_prefix applies to running repositories. A session
+// whose new name is already taken is left alone; one that no longer exists
+// is simply recorded under the new name for restoration to create.
+func (d *Daemon) migrateSessionNames(namer config.Namer) {
+	for repoName, repo := range d.state.GetAllRepos() {
+		old := repo.TmuxSession
+		if !namer.IsFormerSession(repoName, old) {
+			continue
+		}
+		if _, ok := d.foreignLock(repoName); ok {
+			continue
+		}
+		want := namer.Session(repoName)
+
+		exists, err := d.tmux.HasSession(d.ctx, old)
+		if err != nil {
+			d.logger.Error("Failed to check session %s: %v", old, err)
+			continue
+		}
+		if exists {
+			if taken, err := d.tmux.HasSession(d.ctx, want); err != nil || taken {
+				d.logger.Warn("Not renaming tmux session %s for repo %s: %s already exists", old, repoName, want)
+				continue
+			}
+			if err := d.tmux.RenameSession(d.ctx, old, want); err != nil {
+				d.logger.Error("Failed to rename tmux session %s to %s: %v", old, want, err)
+				continue
+			}
+		}
+		if err := d.state.SetTmuxSession(repoName, want); err != nil {
+			d.logger.Error("Failed to record tmux session %s for repo %s: %v", want, repoName, err)
+			continue
+		}
+		d.logger.Info("Renamed tmux session %s to %s for repo %s", old, want, repoName)
+	}
+}
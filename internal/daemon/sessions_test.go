@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+func TestMigrateSessionNames(t *testing.T) {
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Fatal("tmux is required for this test but not available")
+	}
+	ctx := context.Background()
+	for _, session := range []string{"mc-migrate-live", "mc-migrate-taken", "mctest-migrate-taken"} {
+		if err := tmuxClient.CreateSession(ctx, session, true); err != nil {
+			t.Fatalf("tmux is required for this test but cannot create sessions in this environment: %v", err)
+		}
+		defer tmuxClient.KillSession(ctx, session)
+	}
+	defer tmuxClient.KillSession(ctx, "mctest-migrate-live")
+
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		for name, session := range map[string]string{
+			"migrate-live":  "mc-migrate-live",     // renamed
+			"migrate-gone":  "mc-migrate-gone",     // no session left; just recorded
+			"migrate-taken": "mc-migrate-taken",    // new name in use
+			"migrate-own":   "mc-migrate-own-1234", // not a generated name
+		} {
+			s.AddRepo(name, &state.Repository{TmuxSession: session, Agents: make(map[string]state.Agent)})
+		}
+	})
+	defer cleanup()
+
+	d.migrateSessionNames(config.NewNamer("mctest-"))
+
+	want := map[string]string{
+		"migrate-live":  "mctest-migrate-live",
+		"migrate-gone":  "mctest-migrate-gone",
+		"migrate-taken": "mc-migrate-taken",
+		"migrate-own":   "mc-migrate-own-1234",
+	}
+	for repoName, session := range want {
+		repo, _ := d.state.GetRepo(repoName)
+		if repo.TmuxSession != session {
+			t.Errorf("repo %s session = %q, want %q", repoName, repo.TmuxSession, session)
+		}
+	}
+	if exists, _ := tmuxClient.HasSession(ctx, "mctest-migrate-live"); !exists {
+		t.Error("mc-migrate-live should have been renamed to mctest-migrate-live")
+	}
+	if exists, _ := tmuxClient.HasSession(ctx, "mc-migrate-taken"); !exists {
+		t.Error("a session whose new name is taken should be left alone")
+	}
+}
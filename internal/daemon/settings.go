@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+// configFile returns the settings last read from the config file
+func (d *Daemon) configFile() *config.File {
+	d.settingsMu.RLock()
+	defer d.settingsMu.RUnlock()
+	return d.settings
+}
+
+// fileAdapters creates the notification adapters the config file enables.
+// The webhook, email, incident and desktop adapters are left to the
+// environment's settings when they configure them.
+func (d *Daemon) fileAdapters(file *config.File) ([]notify.Adapter, error) {
+	cfg, err := notify.ParseFileSettings(file.Notifications)
+	if err != nil {
+		return nil, err
+	}
+	var adapters []notify.Adapter
+	if cfg.Slack != nil {
+		adapters = append(adapters, notify.NewSlackAdapter(*cfg.Slack))
+	}
+	if cfg.Telegram != nil {
+		adapters = append(adapters, notify.NewTelegramAdapter(*cfg.Telegram))
+	}
+	if cfg.Webhook != nil && !d.envWebhook {
+		adapters = append(adapters, d.newWebhookAdapter(*cfg.Webhook))
+	}
+	if cfg.Email != nil && d.emailConfig == nil {
+		adapters = append(adapters, d.newEmailAdapter(*cfg.Email))
+	}
+	if cfg.Incident != nil && d.incidentConfig == nil {
+		adapters = append(adapters, notify.NewIncidentAdapter(*cfg.Incident, notify.WithIncidentClock(d.clock)))
+	}
+	if cfg.Desktop != nil && d.desktopTypes == nil {
+		if desktop, err := notify.NewDesktopAdapter(cfg.Desktop); err != nil {
+			d.logger.Warn("Desktop notifications disabled: %v", err)
+		} else {
+			adapters = append(adapters, desktop)
+		}
+	}
+	return adapters, nil
+}
+
+// fileRoutes returns the notification routes to apply: the environment's
+// when they are set, otherwise the config file's
+func (d *Daemon) fileRoutes(file *config.File) notify.Routes {
+	if d.envRoutes {
+		return d.routes
+	}
+	cfg, err := notify.ParseFileSettings(file.Notifications)
+	if err != nil {
+		return nil
+	}
+	return cfg.Routes
+}
+
+// replySecret returns the key webhook requests are signed with, which
+// replies relayed back to the API must be signed with too; nil when no
+// webhook is configured
+func (d *Daemon) replySecret() []byte {
+	if d.webhookConfig != nil {
+		return d.webhookConfig.Secret
+	}
+	cfg, err := notify.ParseFileSettings(d.configFile().Notifications)
+	if err != nil || cfg.Webhook == nil {
+		return nil
+	}
+	return cfg.Webhook.Secret
+}
+
+// newWebhookAdapter creates a webhook adapter, dead-lettering to the
+// daemon's file unless cfg names another
+func (d *Daemon) newWebhookAdapter(cfg notify.WebhookConfig) *notify.WebhookAdapter {
+	if cfg.DeadLetter == "" {
+		cfg.DeadLetter = d.paths.WebhookDeadLetterFile()
+	}
+	webhook := notify.NewWebhookAdapter(cfg, notify.WithWebhookClock(d.clock))
+	d.settingsMu.Lock()
+	d.webhooks = append(d.webhooks, webhook)
+	d.settingsMu.Unlock()
+	return webhook
+}
+
+// newEmailAdapter creates an email adapter that acknowledges digested events
+// in the event store once their digest is mailed
+func (d *Daemon) newEmailAdapter(cfg notify.EmailConfig) *notify.EmailAdapter {
+	var email *notify.EmailAdapter
+	email = notify.NewEmailAdapter(cfg,
+		notify.WithEmailClock(d.clock),
+		notify.WithEmailAcknowledge(func(eventID string) error {
+			return d.notify.Acknowledge(eventID, email.Name())
+		}))
+	d.settingsMu.Lock()
+	d.emails = append(d.emails, email)
+	d.settingsMu.Unlock()
+	return email
+}
+
+// warnUnroutedAdapters logs notification routes that name an adapter
+// nothing enabled
+func (d *Daemon) warnUnroutedAdapters() {
+	registered := make(map[string]bool)
+	for _, name := range d.notify.Adapters() {
+		registered[name] = true
+	}
+	for _, name := range d.routes.Adapters() {
+		if !registered[name] {
+			d.logger.Warn("Notification routes name adapter %q, which is not enabled", name)
+		}
+	}
+}
+
+// applyGitHubBudgets sets the GitHub client's per-subsystem budgets,
+// removing those the config file no longer sets
+func (d *Daemon) applyGitHubBudgets(old, budgets map[string]float64) {
+	for subsystem := range old {
+		if _, ok := budgets[subsystem]; !ok {
+			d.github.SetBudget(subsystem, 0)
+		}
+	}
+	for subsystem, share := range budgets {
+		d.github.SetBudget(subsystem, share)
+	}
+}
+
+// maxWorkers is how many workers a repository may run at once, or 0 for no
+// limit. The repository's own max_workers setting wins over the config file.
+func (d *Daemon) maxWorkers(repoName string, repo *state.Repository) int {
+	if repo.MaxWorkers > 0 {
+		return repo.MaxWorkers
+	}
+	return d.configFile().MaxWorkers(repoName)
+}
+
+// workerBranch is the branch a new worker in a repository is created on
+func (d *Daemon) workerBranch(repoName, agentName string) string {
+	return d.configFile().BranchPrefix(repoName) + agentName
+}
@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/config"
+)
+
+func TestConfigFileSettings(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddAgent("repo", "fox", state.Agent{Type: state.AgentTypeWorker, TmuxWindow: "fox"})
+		s.AddRepo("other", &state.Repository{TmuxSession: "mc-other", Agents: make(map[string]state.Agent)})
+	})
+	defer cleanup()
+
+	file, err := config.ParseFile([]byte("defaults:\n  max_workers: 1\n  branch_prefix: mc/\nrepos:\n  other:\n    max_workers: 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.settings = file
+
+	if got := d.workerBranch("repo", "owl"); got != "mc/owl" {
+		t.Errorf("workerBranch = %q, want mc/owl", got)
+	}
+
+	// The file's default limit applies to repo, which sets none itself
+	capacity, err := d.handleCheckWorkerCapacity(socket.Request{}, socket.RepoArgs{Repo: "repo"})
+	if err != nil || capacity.Available || capacity.MaxWorkers != 1 {
+		t.Errorf("check_worker_capacity(repo) = %+v, %v; want full at 1", capacity, err)
+	}
+	// other overrides it with no limit
+	capacity, err = d.handleCheckWorkerCapacity(socket.Request{}, socket.RepoArgs{Repo: "other"})
+	if err != nil || !capacity.Available || capacity.MaxWorkers != 0 {
+		t.Errorf("check_worker_capacity(other) = %+v, %v; want unlimited", capacity, err)
+	}
+
+	// The repository's own setting wins over the file
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{"name": "repo", "max_workers": float64(2)}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	if err := d.workerCapacityError("repo"); err != nil {
+		t.Errorf("workerCapacityError = %v, want room for a second worker", err)
+	}
+}
+
+func TestNewReadsConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	paths := config.NewTestPaths(dir)
+
+	os.WriteFile(paths.ConfigFile(), []byte("notifications:\n  slack:\n    webhook_url: https://hooks.slack.test/x\n  telegram:\n    bot_token: t\n    chat_id: c\n"), 0600)
+	d, err := New(paths)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	adapters := strings.Join(d.notify.Adapters(), ",")
+	if !strings.Contains(adapters, "slack") || !strings.Contains(adapters, "telegram") {
+		t.Errorf("adapters = %s, want slack and telegram", adapters)
+	}
+
+	// Email, incidents, routes and retention come from the file too, unless
+	// the environment's settings override them
+	os.WriteFile(paths.ConfigFile(), []byte(`notifications:
+  email:
+    host: smtp.example.com
+    from: bot@example.com
+    to: [me@example.com]
+  incident:
+    provider: pagerduty
+    key: k
+  routes:
+    - types: [agent.question]
+      to: [email]
+events:
+  retention: 30d
+`), 0600)
+	d, err = New(paths)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	adapters = strings.Join(d.notify.Adapters(), ",")
+	if !strings.Contains(adapters, "email") || !strings.Contains(adapters, "pagerduty") {
+		t.Errorf("adapters = %s, want email and pagerduty", adapters)
+	}
+	if !d.routes.Routed("email") || d.eventRetention != 30*24*time.Hour {
+		t.Errorf("routes = %+v, retention = %s; want the file's", d.routes, d.eventRetention)
+	}
+	envRoutes, _ := notify.ParseRoutes("type=agent.stuck,to=pagerduty")
+	d, err = New(paths, WithRoutes(envRoutes), WithEventRetention(time.Hour),
+		WithEmail(notify.EmailConfig{Host: "mail.test", From: "env@example.com", To: []string{"me@example.com"}}))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if d.routes.Routed("email") || !d.routes.Routed("pagerduty") || d.eventRetention != time.Hour {
+		t.Errorf("routes = %+v, retention = %s; want the environment's", d.routes, d.eventRetention)
+	}
+	if emails := strings.Count(strings.Join(d.notify.Adapters(), ","), "email"); emails != 1 || len(d.emails) != 1 {
+		t.Errorf("got %d email adapters, want only the environment's", emails)
+	}
+
+	os.WriteFile(paths.ConfigFile(), []byte("defaults:\n  branch_prefix: work\n"), 0600)
+	if _, err := New(paths); err == nil || !strings.Contains(err.Error(), "branch_prefix") {
+		t.Errorf("New with an invalid config file error = %v", err)
+	}
+}
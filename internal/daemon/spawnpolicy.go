@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/agents"
+	"github.com/dlorenc/multiclaude/internal/prompts"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// maxSpawnPromptBytes caps the prompt text spawn_agent accepts
+const maxSpawnPromptBytes = 64 * 1024
+
+// maxCallerDepth bounds the walk up a caller's process tree
+const maxCallerDepth = 32
+
+// spawnCaller is who sent a spawn_agent request. Requests from processes
+// that don't run under an agent come from humans.
+type spawnCaller struct {
+	repo  string
+	name  string
+	agent state.Agent
+	human bool
+}
+
+// String names the caller for denials and logs
+func (c spawnCaller) String() string {
+	if c.human {
+		return "a human"
+	}
+	return fmt.Sprintf("agent %s/%s", c.repo, c.name)
+}
+
+// identifySpawnCaller finds the agent whose process the peer runs under by
+// walking up the peer's process tree to an agent's PID. Callers that can't
+// be traced to an agent, including peers the platform can't identify, are
+// treated as humans.
+func (d *Daemon) identifySpawnCaller(peer *socket.Peer) spawnCaller {
+	if peer == nil || peer.PID <= 0 {
+		return spawnCaller{human: true}
+	}
+
+	type agentRef struct {
+		repo, name string
+		agent      state.Agent
+	}
+	byPID := make(map[int]agentRef)
+	for repoName, repo := range d.state.GetAllRepos() {
+		for name, agent := range repo.Agents {
+			if agent.PID > 0 {
+				byPID[agent.PID] = agentRef{repoName, name, agent}
+			}
+		}
+	}
+
+	pid := peer.PID
+	for depth := 0; depth < maxCallerDepth && pid > 1; depth++ {
+		if ref, ok := byPID[pid]; ok {
+			return spawnCaller{repo: ref.repo, name: ref.name, agent: ref.agent}
+		}
+		parent, err := parentPID(pid)
+		if err != nil || parent == pid {
+			break
+		}
+		pid = parent
+	}
+	return spawnCaller{human: true}
+}
+
+// parentPID returns the parent of a process, from /proc where available
+// and ps elsewhere
+func parentPID(pid int) (int, error) {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		// The command name in field 2 may contain spaces, so parse after it
+		stat := string(data)
+		if end := strings.LastIndexByte(stat, ')'); end >= 0 {
+			fields := strings.Fields(stat[end+1:])
+			if len(fields) >= 2 {
+				return strconv.Atoi(fields[1])
+			}
+		}
+		return 0, fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+
+	output, err := exec.Command("ps", "-o", "ppid=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(output)))
+}
+
+// checkSpawnPolicy decides whether caller may spawn an agent of the given
+// class in repoName. Humans may spawn anything. The supervisor may only
+// spawn ephemeral agents from the repository's registered definitions.
+// Other agents may not use spawn_agent.
+func checkSpawnPolicy(caller spawnCaller, repoName, class string, byDefinition bool) error {
+	if caller.human {
+		return nil
+	}
+	if caller.agent.Type != state.AgentTypeSupervisor {
+		return fmt.Errorf("%s (%s) may not spawn agents; ask the supervisor", caller, caller.agent.Type)
+	}
+	if caller.repo != repoName {
+		return fmt.Errorf("%s may only spawn agents in its own repository", caller)
+	}
+	if class != "ephemeral" {
+		return fmt.Errorf("%s may only spawn ephemeral agents; a human must spawn persistent ones", caller)
+	}
+	if !byDefinition {
+		return fmt.Errorf("%s must spawn agents from a registered definition (definition: <name>) rather than prompt text", caller)
+	}
+	return nil
+}
+
+// findDefinition returns a registered agent definition and the prompt for
+// it. The merge-queue definition gets the repository's tracking mode, as in
+// the definitions sent to the supervisor.
+func (d *Daemon) findDefinition(repoName, name string) (agents.Definition, string, error) {
+	reader := agents.NewReader(d.paths.RepoAgentsDir(repoName), d.paths.RepoDir(repoName))
+	def, err := reader.FindDefinition(name)
+	if err != nil {
+		return def, "", err
+	}
+	if name == "merge-queue" {
+		if mqConfig, err := d.state.GetMergeQueueConfig(repoName); err == nil && mqConfig.Enabled {
+			content := prompts.GenerateTrackingModePrompt(string(mqConfig.TrackMode)) + "\n\n"
+			if mqConfig.AutoMerge {
+				content += prompts.GenerateAutoMergePrompt(mqConfig.MergeLabel()) + "\n\n"
+			}
+			return def, content + def.Content, nil
+		}
+	}
+	return def, def.Content, nil
+}
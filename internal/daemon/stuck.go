@@ -0,0 +1,209 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/loopdetect"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// idleTailBytes is how much of an agent's output is read to find its last line
+const idleTailBytes = 4 * 1024
+
+// idleScreenLines is how many lines of an idle agent's screen its stuck
+// report quotes
+const idleScreenLines = 5
+
+// detectIdleAgents reports workers and reviewers that have produced no
+// output and sent no heartbeat for longer than their repository's stuck
+// config allows (see state.StuckConfig). Each idle stretch is reported once;
+// new output or a heartbeat starts a new one.
+func (d *Daemon) detectIdleAgents(now time.Time) {
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			if agent.ReadyForCleanup || (agent.Type != state.AgentTypeWorker && agent.Type != state.AgentTypeReview) {
+				continue
+			}
+			// A headless run only writes its output when it finishes, so
+			// silence while it runs says nothing
+			if agent.Headless && d.headlessBusy(repoName, agentName) {
+				continue
+			}
+			key := repoName + "/" + agentName
+			var lastActive time.Time
+			if info, err := os.Stat(d.paths.AgentLogFile(repoName, agentName, true)); err == nil {
+				lastActive = info.ModTime()
+			}
+			if agent.LastHeartbeat.After(lastActive) {
+				lastActive = agent.LastHeartbeat
+			}
+			if lastActive.IsZero() {
+				// Without captured output or heartbeats there is nothing to
+				// measure idleness by
+				continue
+			}
+
+			d.idleReportedMu.Lock()
+			reported, wasReported := d.idleReported[key]
+			d.idleReportedMu.Unlock()
+			if wasReported && reported.Equal(lastActive) {
+				continue
+			}
+
+			since := lastActive
+			if agent.CreatedAt.After(since) {
+				since = agent.CreatedAt
+			}
+			idle := now.Sub(since)
+			if idle <= 0 {
+				continue
+			}
+			tail, _ := readFileTail(d.paths.AgentLogFile(repoName, agentName, true), idleTailBytes)
+			lastLine := loopdetect.LastLine(tail)
+			var screen []string
+			if lastLine == "" {
+				// No captured output to go by; use what the pane shows
+				screen = d.screenTail(repo.TmuxSession, agent.TmuxWindow, idleScreenLines)
+				if len(screen) > 0 {
+					lastLine = screen[len(screen)-1]
+				}
+			}
+			threshold := repo.Stuck.IdleThreshold(agent, lastLine)
+			if threshold <= 0 || idle < threshold {
+				continue
+			}
+
+			d.idleReportedMu.Lock()
+			d.idleReported[key] = lastActive
+			d.idleReportedMu.Unlock()
+			if screen == nil {
+				screen = d.screenTail(repo.TmuxSession, agent.TmuxWindow, idleScreenLines)
+			}
+			d.reportIdleAgent(repoName, agentName, idle, threshold, screen)
+			if repo.Stuck.Nudge {
+				d.nudgeIdleAgent(repo.TmuxSession, repoName, agentName, agent, idle)
+			}
+		}
+	}
+}
+
+// reportIdleAgent emits an agent.stuck event for an idle agent and lets the
+// supervisor know, quoting the bottom of the agent's screen when it has one
+func (d *Daemon) reportIdleAgent(repoName, agentName string, idle, threshold time.Duration, screen []string) {
+	idle = idle.Round(time.Minute)
+	d.logger.Warn("Agent %s/%s has produced no output or heartbeat for %s (threshold %s)", repoName, agentName, idle, threshold)
+
+	event := events.NewTypedEvent(repoName, agentName,
+		fmt.Sprintf("Agent %s has been idle for %s", agentName, idle),
+		events.AgentStuckPayload{Reason: "idle", IdleSeconds: int(idle.Seconds())})
+	d.emitEvent(event)
+
+	msg := fmt.Sprintf("Agent '%s' has produced no output or heartbeat for %s, longer than the %s expected for its task. Check whether it is stuck.",
+		agentName, idle, threshold)
+	if len(screen) > 0 {
+		msg += "\n\nIts screen currently shows:\n" + strings.Join(screen, "\n")
+	}
+	if _, err := d.getMessageManager().Send(repoName, "daemon", "supervisor", msg); err != nil {
+		d.logger.Error("Failed to send idle notification to supervisor: %v", err)
+	}
+}
+
+// nudgeIdleAgent types a reminder into an idle agent's window, which wakes
+// an agent waiting at its prompt; a headless agent gets it as its next prompt
+func (d *Daemon) nudgeIdleAgent(session, repoName, agentName string, agent state.Agent, idle time.Duration) {
+	msg := fmt.Sprintf("[multiclaude] You have been quiet for %s. If you are still working, run 'multiclaude agent heartbeat'. If you are blocked, ask for help with 'multiclaude agent ask <question>'; if you are done, run 'multiclaude agent complete'.",
+		idle.Round(time.Minute))
+	if err := d.sendToAgent(repoName, session, agentName, agent, msg); err != nil {
+		d.logger.Error("Failed to nudge idle agent %s/%s: %v", repoName, agentName, err)
+	}
+}
+
+// handleAgentHeartbeat records that an agent is still working, which holds
+// off idle stuck reports while it is busy without printing anything
+func (d *Daemon) handleAgentHeartbeat(req socket.Request, args socket.AgentArgs) (struct{}, error) {
+	repoName := args.Repo
+
+	agentName := args.Agent
+
+	agent, exists := d.state.GetAgent(repoName, agentName)
+	if !exists {
+		return struct{}{}, socket.Errorf(socket.CodeNotFound, "agent '%s' not found in repository '%s'", agentName, repoName)
+	}
+
+	agent.LastHeartbeat = d.clock.Now()
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		return struct{}{}, fmt.Errorf("failed to record heartbeat: %v", err)
+	}
+	return struct{}{}, nil
+}
+
+// updateStuckConfig applies the stuck_idle_minutes, stuck_rules,
+// stuck_quiet, and stuck_nudge args of update_repo_config, if any. It
+// returns an error if they are invalid.
+func (d *Daemon) updateStuckConfig(repoName string, args socket.UpdateRepoConfigArgs) error {
+	if args.StuckIdleMinutes == nil && args.StuckRules == nil && args.StuckQuiet == nil && args.StuckNudge == nil {
+		return nil
+	}
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.Errorf(socket.CodeNotFound, "repository %q not found", repoName)
+	}
+
+	stuck := repo.Stuck
+	if args.StuckIdleMinutes != nil {
+		if *args.StuckIdleMinutes < 0 {
+			return fmt.Errorf("stuck_idle_minutes must not be negative")
+		}
+		stuck.IdleMinutes = *args.StuckIdleMinutes
+	}
+	if args.StuckRules != nil {
+		stuck.Rules = nil
+		for _, spec := range args.StuckRules {
+			rule, err := state.ParseStuckRule(spec)
+			if err != nil {
+				return err
+			}
+			stuck.Rules = append(stuck.Rules, rule)
+		}
+	}
+	if args.StuckQuiet != nil {
+		stuck.Quiet = nil
+		for _, spec := range args.StuckQuiet {
+			rule, err := state.ParseQuietRule(spec)
+			if err != nil {
+				return err
+			}
+			stuck.Quiet = append(stuck.Quiet, rule)
+		}
+	}
+	if args.StuckNudge != nil {
+		stuck.Nudge = *args.StuckNudge
+	}
+	if err := d.state.UpdateStuckConfig(repoName, stuck); err != nil {
+		return err
+	}
+	d.logger.Info("Updated stuck detection for repo %s: idle=%dm rules=%v quiet=%v nudge=%v",
+		repoName, stuck.IdleMinutes, stuckRuleStrings(stuck.Rules), quietRuleStrings(stuck.Quiet), stuck.Nudge)
+	return nil
+}
+
+func stuckRuleStrings(rules []state.StuckRule) []string {
+	list := []string{}
+	for _, r := range rules {
+		list = append(list, r.String())
+	}
+	return list
+}
+
+func quietRuleStrings(rules []state.QuietRule) []string {
+	list := []string{}
+	for _, q := range rules {
+		list = append(list, q.String())
+	}
+	return list
+}
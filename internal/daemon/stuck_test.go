@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestDetectIdleAgents(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	created := time.Now().Add(-2 * time.Hour)
+	d.state.AddRepo("repo", &state.Repository{
+		TmuxSession: "mc-repo",
+		Agents: map[string]state.Agent{
+			"docs":  {Type: state.AgentTypeWorker, TmuxWindow: "docs", Task: "Write docs", Labels: []string{"docs"}, CreatedAt: created},
+			"fix":   {Type: state.AgentTypeWorker, TmuxWindow: "fix", Task: "Fix the failing test", CreatedAt: created},
+			"build": {Type: state.AgentTypeWorker, TmuxWindow: "build", Task: "Port to Rust", CreatedAt: created},
+		},
+	})
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":               "repo",
+		"stuck_idle_minutes": float64(10),
+		"stuck_rules":        []interface{}{"label:docs=45m"},
+		"stuck_quiet":        []interface{}{"Compiling=1h"},
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+
+	if err := os.MkdirAll(d.paths.WorkersOutputDir("repo"), 0755); err != nil {
+		t.Fatalf("Failed to create output dir: %v", err)
+	}
+	lastOutput := time.Now().Add(-20 * time.Minute)
+	for name, output := range map[string]string{"docs": "Writing README\n", "fix": "$ go test ./...\n", "build": "\x1b[32m   Compiling\x1b[0m tokio v1.0\n"} {
+		path := d.paths.AgentLogFile("repo", name, true)
+		if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+			t.Fatalf("Failed to write log: %v", err)
+		}
+		os.Chtimes(path, lastOutput, lastOutput)
+	}
+
+	// Only fix is past its threshold: docs gets 45m and build is compiling
+	d.detectIdleAgents(time.Now())
+	d.detectIdleAgents(time.Now())
+	recent := d.notify.Recent(0)
+	if len(recent) != 1 || recent[0].Agent != "fix" || recent[0].Type != events.EventAgentStuck {
+		t.Fatalf("expected one stuck event for fix, got %+v", recent)
+	}
+	if p, ok := recent[0].Payload.(events.AgentStuckPayload); !ok || p.Reason != "idle" || p.IdleSeconds != 20*60 {
+		t.Errorf("unexpected payload %+v", recent[0].Payload)
+	}
+
+	// New output starts a new idle stretch
+	path := d.paths.AgentLogFile("repo", "fix", true)
+	later := time.Now().Add(-15 * time.Minute)
+	os.Chtimes(path, later, later)
+	d.detectIdleAgents(time.Now())
+	if got := len(d.notify.Recent(0)); got != 2 {
+		t.Errorf("expected fix to be reported again after new output, got %d events", got)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name": "repo", "stuck_rules": []interface{}{"docs=45m"},
+	}})
+	if resp.Success {
+		t.Error("a rule without a match kind should be rejected")
+	}
+}
+
+func TestAgentHeartbeatDefersIdle(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	created := time.Now().Add(-2 * time.Hour)
+	d.state.AddRepo("repo", &state.Repository{
+		TmuxSession: "mc-repo",
+		Stuck:       state.StuckConfig{IdleMinutes: 10},
+		Agents: map[string]state.Agent{
+			"build": {Type: state.AgentTypeWorker, TmuxWindow: "build", Task: "Run the full test suite", CreatedAt: created},
+		},
+	})
+	if err := os.MkdirAll(d.paths.WorkersOutputDir("repo"), 0755); err != nil {
+		t.Fatalf("Failed to create output dir: %v", err)
+	}
+	path := d.paths.AgentLogFile("repo", "build", true)
+	if err := os.WriteFile(path, []byte("$ make test\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	lastOutput := time.Now().Add(-30 * time.Minute)
+	os.Chtimes(path, lastOutput, lastOutput)
+
+	resp := d.handleRequest(socket.Request{Command: "agent_heartbeat", Args: map[string]interface{}{
+		"repo": "repo", "agent": "build",
+	}})
+	if !resp.Success {
+		t.Fatalf("agent_heartbeat failed: %s", resp.Error)
+	}
+	agent, _ := d.state.GetAgent("repo", "build")
+	if agent.LastHeartbeat.IsZero() {
+		t.Fatal("heartbeat was not recorded")
+	}
+
+	// Quiet output alone isn't enough while heartbeats keep coming
+	d.detectIdleAgents(time.Now())
+	if got := len(d.notify.Recent(0)); got != 0 {
+		t.Fatalf("expected no stuck events after a heartbeat, got %d", got)
+	}
+
+	// Once both go quiet the agent is reported
+	d.detectIdleAgents(agent.LastHeartbeat.Add(15 * time.Minute))
+	recent := d.notify.Recent(0)
+	if len(recent) != 1 || recent[0].Type != events.EventAgentStuck {
+		t.Fatalf("expected one stuck event, got %+v", recent)
+	}
+	if p, _ := recent[0].Payload.(events.AgentStuckPayload); p.IdleSeconds != 15*60 {
+		t.Errorf("IdleSeconds = %d, want time since the heartbeat", p.IdleSeconds)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "agent_heartbeat", Args: map[string]interface{}{
+		"repo": "repo", "agent": "missing",
+	}})
+	if resp.Success {
+		t.Error("heartbeat from an unknown agent should fail")
+	}
+}
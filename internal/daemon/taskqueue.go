@@ -0,0 +1,388 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/names"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/google/uuid"
+)
+
+// taskQueueInterval is how often queues are dispatched besides when a task
+// is added or a queued task's worker completes
+const taskQueueInterval = 30 * time.Second
+
+// taskQueueLoop periodically dispatches every repository's task queue
+func (d *Daemon) taskQueueLoop() {
+	d.periodicLoop("task queue", taskQueueInterval, d.dispatchAllTaskQueues, d.dispatchAllTaskQueues)
+}
+
+// dispatchAllTaskQueues dispatches the task queue of each repository
+func (d *Daemon) dispatchAllTaskQueues() {
+	for repoName, repo := range d.state.GetAllRepos() {
+		if len(repo.Tasks) > 0 {
+			d.dispatchTaskQueue(repoName)
+		}
+	}
+}
+
+// dispatchTaskQueue brings the states of a repository's dispatched tasks up
+// to date with their workers, then spawns a worker for each of the most
+// urgent queued tasks while fewer than the queue's worker limit are running
+// and the repository is below its max_workers
+func (d *Daemon) dispatchTaskQueue(repoName string) {
+	if d.isReadOnlyRepo(repoName, "task dispatch") {
+		return
+	}
+
+	d.taskQueueMu.Lock()
+	defer d.taskQueueMu.Unlock()
+
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return
+	}
+	d.syncQueuedTasks(repoName, repo)
+
+	if repo, exists = d.state.GetAllRepos()[repoName]; !exists {
+		return
+	}
+	running := 0
+	var queued []state.QueuedTask
+	for _, task := range repo.Tasks {
+		switch task.State {
+		case state.QueuedTaskAssigned, state.QueuedTaskInProgress:
+			running++
+		case state.QueuedTaskQueued:
+			queued = append(queued, task)
+		}
+	}
+	sortQueuedTasks(queued)
+	active := activeWorkers(repo)
+	maxWorkers := d.maxWorkers(repoName, repo)
+
+	for _, task := range queued {
+		if running >= repo.TaskQueue.Limit() || (maxWorkers > 0 && active >= maxWorkers) {
+			return
+		}
+		if err := d.diskQuotaError(); err != nil {
+			d.logger.Warn("Not dispatching queued tasks in %s: %v", repoName, err)
+			return
+		}
+		agentName, err := d.spawnQueuedWorker(repoName, repo, task)
+		now := d.clock.Now()
+		if err != nil {
+			d.logger.Error("Failed to spawn a worker for task %s in %s: %v", task.ID, repoName, err)
+			d.updateQueuedTask(repoName, task.ID, func(t *state.QueuedTask) {
+				t.State = state.QueuedTaskFailed
+				t.Error = fmt.Sprintf("failed to spawn a worker: %v", err)
+				t.FinishedAt = now
+			})
+			continue
+		}
+		running++
+		active++
+		d.updateQueuedTask(repoName, task.ID, func(t *state.QueuedTask) {
+			t.State = state.QueuedTaskAssigned
+			t.Agent = agentName
+			t.AssignedAt = now
+		})
+		d.logger.Info("Dispatched task %s in %s to %s", task.ID, repoName, agentName)
+		d.recordAction(repoName, feed.ActionSpawned, agentName, fmt.Sprintf("queued task %s: %s", task.ID, task.Description))
+		d.recordDaemonChange("task_queue", audit.ActionTaskAssigned, repoName, agentName, fmt.Sprintf("id=%s description=%s", task.ID, task.Description))
+	}
+}
+
+// syncQueuedTasks moves a dispatched task to in-progress once its worker
+// shows signs of life, and finishes tasks whose worker completed or went
+// away. Completion normally finishes a task right away (see
+// finishQueuedTask); this catches anything that slipped past it.
+func (d *Daemon) syncQueuedTasks(repoName string, repo *state.Repository) {
+	now := d.clock.Now()
+	for _, task := range repo.Tasks {
+		if task.State != state.QueuedTaskAssigned && task.State != state.QueuedTaskInProgress {
+			continue
+		}
+		agent, exists := repo.Agents[task.Agent]
+		switch {
+		case !exists:
+			d.updateQueuedTask(repoName, task.ID, func(t *state.QueuedTask) {
+				t.State = state.QueuedTaskFailed
+				t.Error = fmt.Sprintf("worker %s went away before completing", task.Agent)
+				t.FinishedAt = now
+			})
+		case agent.ReadyForCleanup:
+			d.finishQueuedTask(repoName, task.Agent, agent.FailureReason)
+		case task.State == state.QueuedTaskAssigned && d.agentActiveSince(repoName, task.Agent, agent, task.AssignedAt):
+			d.updateQueuedTask(repoName, task.ID, func(t *state.QueuedTask) {
+				t.State = state.QueuedTaskInProgress
+			})
+		}
+	}
+}
+
+// agentActiveSince reports whether the agent produced output or sent a
+// heartbeat after t
+func (d *Daemon) agentActiveSince(repoName, agentName string, agent state.Agent, t time.Time) bool {
+	if agent.LastHeartbeat.After(t) {
+		return true
+	}
+	info, err := os.Stat(d.paths.AgentLogFile(repoName, agentName, true))
+	return err == nil && info.ModTime().After(t)
+}
+
+// finishQueuedTask marks the task dispatched to a worker as done, or as
+// failed when the worker reported a failure
+func (d *Daemon) finishQueuedTask(repoName, agentName, failureReason string) {
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return
+	}
+	now := d.clock.Now()
+	for _, task := range repo.Tasks {
+		if task.Agent != agentName || task.State.Finished() {
+			continue
+		}
+		d.updateQueuedTask(repoName, task.ID, func(t *state.QueuedTask) {
+			t.State = state.QueuedTaskDone
+			if failureReason != "" {
+				t.State = state.QueuedTaskFailed
+				t.Error = failureReason
+			}
+			t.FinishedAt = now
+		})
+		return
+	}
+}
+
+// updateQueuedTask updates a queued task, logging failures
+func (d *Daemon) updateQueuedTask(repoName, id string, update func(*state.QueuedTask)) {
+	if err := d.state.UpdateQueuedTask(repoName, id, update); err != nil {
+		d.logger.Error("Failed to update task %s in %s: %v", id, repoName, err)
+	}
+}
+
+// sortQueuedTasks orders tasks most urgent first, then oldest first
+func sortQueuedTasks(tasks []state.QueuedTask) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if ri, rj := tasks[i].Priority.Rank(), tasks[j].Priority.Rank(); ri != rj {
+			return ri < rj
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+}
+
+// spawnQueuedWorker starts a worker on a queued task the way `multiclaude
+// work` does: in its own worktree on a new branch from the repo's default
+// base, taken from the warm pool when one is ready. It returns the worker's
+// name.
+func (d *Daemon) spawnQueuedWorker(repoName string, repo *state.Repository, task state.QueuedTask) (string, error) {
+	agentName := names.Generate()
+	for i := 0; ; i++ {
+		if _, exists := repo.Agents[agentName]; !exists {
+			break
+		}
+		agentName = fmt.Sprintf("%s-%d", names.Generate(), i)
+	}
+
+	repoPath := d.paths.RepoDir(repoName)
+	wt := d.agentWorktreeManager(repoName, state.AgentTypeWorker)
+	var base *worktree.BaseRef
+	startPoint := "HEAD"
+	if repo.DefaultBase != "" {
+		resolved, err := wt.ResolveBase("origin", repo.DefaultBase)
+		if err != nil {
+			return "", fmt.Errorf("invalid default base %q: %w", repo.DefaultBase, err)
+		}
+		base = &resolved
+		startPoint = resolved.StartPoint
+	} else if exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "--quiet", "origin/main").Run() == nil {
+		startPoint = "origin/main"
+	}
+
+	branch := d.workerBranch(repoName, agentName)
+	wtPath, claimed, err := d.claimWarmWorktree(repoName, agentName, branch, startPoint)
+	if err != nil {
+		return "", err
+	}
+	if claimed {
+		d.recordDaemonChange("task_queue", audit.ActionWarmClaimed, repoName, agentName, "branch="+branch)
+	} else {
+		wtPath = d.paths.AgentWorktree(repoName, agentName)
+		if err := wt.CreateNewBranch(wtPath, branch, startPoint); err != nil {
+			return "", fmt.Errorf("failed to create worktree: %w", err)
+		}
+	}
+	cleanup := func() {
+		d.tmux.KillWindow(d.ctx, repo.TmuxSession, agentName)
+		wt.Remove(wtPath, true)
+		wt.DeleteBranch(branch)
+	}
+
+	if err := d.tmux.CreateWindowAt(d.ctx, repo.TmuxSession, agentName, wtPath); err != nil {
+		cleanup()
+		return "", fmt.Errorf("failed to create tmux window: %w", err)
+	}
+
+	d.startTranscript(repoName, repo.TmuxSession, agentName, state.AgentTypeWorker)
+
+	prefix := ""
+	if base != nil {
+		prefix = queuedBasePrompt(*base)
+	}
+	promptFile, err := d.writePromptFileWithPrefix(repoName, state.AgentTypeWorker, agentName, prefix)
+	if err != nil {
+		cleanup()
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+
+	cfg := agentStartConfig{
+		agentName:      agentName,
+		agentType:      state.AgentTypeWorker,
+		promptFile:     promptFile,
+		workDir:        wtPath,
+		initialMessage: "Task: " + task.Description,
+	}
+	if err := d.startAgentWithConfig(repoName, repo, cfg); err != nil {
+		cleanup()
+		return "", err
+	}
+
+	agent, _ := d.state.GetAgent(repoName, agentName)
+	agent.Task = task.Description
+	agent.Priority = task.Priority
+	if base != nil {
+		agent.Base = base.Ref
+		agent.BaseBranch = base.Branch
+	}
+	if err := d.state.UpdateAgent(repoName, agentName, agent); err != nil {
+		d.logger.Error("Failed to record task of %s/%s: %v", repoName, agentName, err)
+	}
+	return agentName, nil
+}
+
+// queuedBasePrompt tells a queued task's worker which base it builds on
+func queuedBasePrompt(base worktree.BaseRef) string {
+	if base.Branch == "" {
+		return fmt.Sprintf("## Base\n\nYour branch starts from %s (commit %.12s), not from the tip of the default branch.\n", base.Ref, base.Commit)
+	}
+	return fmt.Sprintf("## Base Branch\n\nThis task builds on the %s branch. Open your PR against it:\n\n    gh pr create --base %s\n", base.Branch, base.Branch)
+}
+
+// handleAddTask adds a task to a repository's task queue and dispatches the
+// queue
+func (d *Daemon) handleAddTask(req socket.Request, args socket.AddTaskArgs) (socket.AddedTask, error) {
+	repoName := args.Repo
+
+	description := args.Description
+
+	var priority state.TaskPriority
+	if raw := args.Priority; raw != "" {
+		p, err := state.ParseTaskPriorityName(raw)
+		if err != nil {
+			return socket.AddedTask{}, err
+		}
+		priority = p
+	}
+
+	task := state.QueuedTask{
+		ID:          "task-" + uuid.New().String()[:8],
+		Description: description,
+		Priority:    priority,
+		State:       state.QueuedTaskQueued,
+		CreatedAt:   d.clock.Now(),
+	}
+	if err := d.state.AddQueuedTask(repoName, task); err != nil {
+		return socket.AddedTask{}, err
+	}
+	d.logger.Info("Queued task %s in %s (%s): %s", task.ID, repoName, task.Priority.Effective(), description)
+
+	go d.dispatchTaskQueue(repoName)
+
+	return socket.AddedTask{ID: task.ID, Priority: task.Priority.Effective()}, nil
+}
+
+// handleListTasks lists a repository's task queue: running tasks, then
+// queued tasks in the order they will be dispatched, then finished tasks,
+// most recent first
+func (d *Daemon) handleListTasks(req socket.Request, args socket.RepoArgs) (socket.TaskList, error) {
+	repoName := args.Repo
+
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return socket.TaskList{}, socket.Errorf(socket.CodeNotFound, "repository %q not found", repoName)
+	}
+
+	var running, queued, finished []state.QueuedTask
+	for _, task := range repo.Tasks {
+		switch {
+		case task.State == state.QueuedTaskQueued:
+			queued = append(queued, task)
+		case task.State.Finished():
+			finished = append(finished, task)
+		default:
+			running = append(running, task)
+		}
+	}
+	sortQueuedTasks(queued)
+	sort.SliceStable(finished, func(i, j int) bool { return finished[i].FinishedAt.After(finished[j].FinishedAt) })
+
+	list := socket.TaskList{Tasks: []socket.TaskInfo{}, Workers: repo.TaskQueue.Limit()}
+	for _, group := range [][]state.QueuedTask{running, queued, finished} {
+		for _, task := range group {
+			item := socket.TaskInfo{
+				ID:          task.ID,
+				Description: task.Description,
+				Priority:    task.Priority.Effective(),
+				State:       task.State,
+				CreatedAt:   task.CreatedAt,
+				Agent:       task.Agent,
+				Error:       task.Error,
+			}
+			if !task.FinishedAt.IsZero() {
+				finished := task.FinishedAt
+				item.FinishedAt = &finished
+			}
+			list.Tasks = append(list.Tasks, item)
+		}
+	}
+	return list, nil
+}
+
+// handleCancelTask removes a task that is still waiting in the queue. A
+// dispatched task belongs to its worker, which is stopped with `work rm`.
+func (d *Daemon) handleCancelTask(req socket.Request, args socket.CancelTaskArgs) (struct{}, error) {
+	repoName := args.Repo
+
+	id := args.ID
+
+	d.taskQueueMu.Lock()
+	defer d.taskQueueMu.Unlock()
+
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return struct{}{}, socket.Errorf(socket.CodeNotFound, "repository %q not found", repoName)
+	}
+	for _, task := range repo.Tasks {
+		if task.ID != id {
+			continue
+		}
+		if task.State != state.QueuedTaskQueued {
+			return struct{}{}, fmt.Errorf("task %s is %s, not queued; stop its worker with: multiclaude work rm %s", id, task.State, task.Agent)
+		}
+		if err := d.state.RemoveQueuedTask(repoName, id); err != nil {
+			return struct{}{}, err
+		}
+		d.logger.Info("Canceled queued task %s in %s", id, repoName)
+		return struct{}{}, nil
+	}
+	return struct{}{}, socket.Errorf(socket.CodeNotFound, "task %q not found in repository %q", id, repoName)
+}
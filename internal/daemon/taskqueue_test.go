@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestTaskQueue(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	assigned := time.Now().Add(-time.Hour)
+	d.state.AddRepo("repo", &state.Repository{
+		TmuxSession: "mc-repo",
+		TaskQueue:   state.TaskQueueConfig{Workers: 1},
+		Agents: map[string]state.Agent{
+			"fox": {Type: state.AgentTypeWorker, TmuxWindow: "fox", LastHeartbeat: time.Now()},
+		},
+	})
+	// The queue's only slot is taken, so nothing new is dispatched
+	d.state.AddQueuedTask("repo", state.QueuedTask{ID: "task-running", Description: "Running", State: state.QueuedTaskAssigned, Agent: "fox", AssignedAt: assigned, CreatedAt: assigned})
+
+	add := func(description, priority string) string {
+		t.Helper()
+		resp := d.handleRequest(socket.Request{Command: "add_task", Args: map[string]interface{}{
+			"repo": "repo", "description": description, "priority": priority,
+		}})
+		if !resp.Success {
+			t.Fatalf("add_task failed: %s", resp.Error)
+		}
+		return resp.Data.(socket.AddedTask).ID
+	}
+	low := add("Tidy docs", "low")
+	high := add("Fix login", "high")
+	normal := add("Add tests", "")
+
+	resp := d.handleRequest(socket.Request{Command: "add_task", Args: map[string]interface{}{
+		"repo": "repo", "description": "Whenever", "priority": "someday",
+	}})
+	if resp.Success {
+		t.Error("add_task should reject an unknown priority")
+	}
+
+	d.dispatchTaskQueue("repo")
+	resp = d.handleRequest(socket.Request{Command: "list_tasks", Args: map[string]interface{}{"repo": "repo"}})
+	if !resp.Success {
+		t.Fatalf("list_tasks failed: %s", resp.Error)
+	}
+	list := resp.Data.(socket.TaskList).Tasks
+	var order []string
+	for _, item := range list {
+		order = append(order, item.ID+"="+string(item.State))
+	}
+	want := []string{"task-running=in-progress", high + "=queued", normal + "=queued", low + "=queued"}
+	if len(order) != len(want) {
+		t.Fatalf("tasks = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("tasks = %v, want %v", order, want)
+		}
+	}
+
+	for _, id := range []string{low, high, normal} {
+		resp = d.handleRequest(socket.Request{Command: "cancel_task", Args: map[string]interface{}{"repo": "repo", "id": id}})
+		if !resp.Success {
+			t.Fatalf("cancel_task failed: %s", resp.Error)
+		}
+	}
+	resp = d.handleRequest(socket.Request{Command: "cancel_task", Args: map[string]interface{}{"repo": "repo", "id": "task-running"}})
+	if resp.Success {
+		t.Error("cancel_task should refuse a dispatched task")
+	}
+
+	// Completing the worker finishes its task
+	d.finishQueuedTask("repo", "fox", "tests would not pass")
+	repo, _ := d.state.GetRepo("repo")
+	if len(repo.Tasks) != 1 || repo.Tasks[0].State != state.QueuedTaskFailed || repo.Tasks[0].Error != "tests would not pass" {
+		t.Errorf("tasks after completion = %+v", repo.Tasks)
+	}
+}
+
+func TestSyncQueuedTasksWorkerGone(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+		s.AddQueuedTask("repo", state.QueuedTask{ID: "task-1", State: state.QueuedTaskInProgress, Agent: "gone"})
+	})
+	defer cleanup()
+
+	repo, _ := d.state.GetRepo("repo")
+	d.syncQueuedTasks("repo", repo)
+	repo, _ = d.state.GetRepo("repo")
+	if task := repo.Tasks[0]; task.State != state.QueuedTaskFailed || task.FinishedAt.IsZero() {
+		t.Errorf("task = %+v, want failed once its worker is gone", task)
+	}
+}
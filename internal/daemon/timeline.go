@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/timeline"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// defaultTimelinePeriod is how far back the timeline goes without a since
+const defaultTimelinePeriod = 7 * 24 * time.Hour
+
+// handleTimeline returns a repository's task timeline (see
+// timeline.Timeline). Optional args: "since" and "until" (RFC 3339),
+// defaulting to the last seven days.
+func (d *Daemon) handleTimeline(req socket.Request, args socket.TimelineArgs) (timeline.Timeline, error) {
+	repoName := args.Repo
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return timeline.Timeline{}, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+	}
+
+	until := d.clock.Now()
+	var since time.Time
+	if err := parseTimeArg("since", args.Since, &since); err != nil {
+		return timeline.Timeline{}, err
+	}
+	if err := parseTimeArg("until", args.Until, &until); err != nil {
+		return timeline.Timeline{}, err
+	}
+	if since.IsZero() {
+		since = until.Add(-defaultTimelinePeriod)
+	}
+	if until.Before(since) {
+		return timeline.Timeline{}, fmt.Errorf("until must not be before since")
+	}
+
+	entries, err := d.feed.List(repoName, since, 0)
+	if err != nil {
+		return timeline.Timeline{}, err
+	}
+	// Questions asked before the period can still be waiting in it
+	q := notify.EventQuery{Repo: repoName, Until: until}
+	var evts []events.Event
+	if store := d.notify.Store(); store != nil {
+		if evts, err = store.Query(q); err != nil {
+			return timeline.Timeline{}, err
+		}
+	} else {
+		evts = filterRecentEvents(d.notify.Recent(0), q)
+	}
+
+	return timeline.Build(repoName, repo, entries, evts, since, until), nil
+}
@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/timeline"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestHandleTimeline(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	d.state.AddRepo("tl-repo", &state.Repository{TmuxSession: "mc-tl-repo", Agents: map[string]state.Agent{
+		"fox": {Type: state.AgentTypeWorker, Task: "Fix login", CreatedAt: time.Now().Add(-time.Hour)},
+	}})
+	d.emitEvent(events.NewEvent(events.EventAgentQuestion, "tl-repo", "fox", "fox has a question"))
+	time.Sleep(10 * time.Millisecond)
+	d.recordAction("tl-repo", feed.ActionAnswered, "fox", "")
+
+	resp := d.handleRequest(socket.Request{Command: "timeline", Args: map[string]interface{}{"repo": "tl-repo"}})
+	if !resp.Success {
+		t.Fatalf("timeline failed: %s", resp.Error)
+	}
+	tl := resp.Data.(timeline.Timeline)
+	if len(tl.Tasks) != 1 || !tl.Tasks[0].Running || len(tl.Tasks[0].Waiting) != 1 {
+		t.Fatalf("timeline = %+v, want fox running with one waiting period", tl)
+	}
+	if w := tl.Tasks[0].Waiting[0]; !w.End.Before(tl.Until) {
+		t.Errorf("the answer should end the waiting period, got %+v", w)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"repo": "missing"},
+		{"repo": "tl-repo", "since": "yesterday"},
+		{"repo": "tl-repo", "since": time.Now().Format(time.RFC3339), "until": time.Now().Add(-time.Hour).Format(time.RFC3339)},
+	} {
+		if resp := d.handleRequest(socket.Request{Command: "timeline", Args: args}); resp.Success {
+			t.Errorf("timeline %v should fail", args)
+		}
+	}
+}
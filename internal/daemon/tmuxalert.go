@@ -0,0 +1,31 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// bellFunc rings the bell in a tmux window
+type bellFunc func(ctx context.Context, session, window string) error
+
+// tmuxBellAdapter rings the bell in an agent's tmux window when an event
+// needs a human, so someone attached to the session notices questions in
+// windows they aren't looking at. Repositories opt in with tmux_alerts.
+type tmuxBellAdapter struct {
+	d    *Daemon
+	ring bellFunc
+}
+
+func (a *tmuxBellAdapter) Name() string { return "tmux-bell" }
+
+func (a *tmuxBellAdapter) Send(ctx context.Context, event events.Event) error {
+	if !event.ActionRequired() || event.Attach == nil {
+		return nil
+	}
+	repo, exists := a.d.state.GetRepo(event.Repo)
+	if !exists || !repo.TmuxAlerts {
+		return nil
+	}
+	return a.ring(ctx, event.Attach.Session, event.Attach.Window)
+}
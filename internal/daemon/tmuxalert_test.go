@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestTmuxBellAdapter(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("alerts", &state.Repository{TmuxSession: "mc-alerts", TmuxAlerts: true, Agents: map[string]state.Agent{
+			"worker-1": {Type: state.AgentTypeWorker, TmuxWindow: "worker-1"},
+		}})
+		s.AddRepo("quiet", &state.Repository{TmuxSession: "mc-quiet", Agents: map[string]state.Agent{
+			"worker-1": {Type: state.AgentTypeWorker, TmuxWindow: "worker-1"},
+		}})
+	})
+	defer cleanup()
+
+	var rung []string
+	adapter := &tmuxBellAdapter{d: d, ring: func(ctx context.Context, session, window string) error {
+		rung = append(rung, session+":"+window)
+		return nil
+	}}
+
+	send := func(eventType events.EventType, repo string) {
+		event := events.NewEvent(eventType, repo, "worker-1", "event")
+		event.Attach = d.attachTarget(repo, "worker-1")
+		if err := adapter.Send(context.Background(), event); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	send(events.EventAgentQuestion, "alerts")
+	send(events.EventAgentCompleted, "alerts")
+	send(events.EventAgentQuestion, "quiet")
+	if len(rung) != 1 || rung[0] != "mc-alerts:worker-1" {
+		t.Errorf("rang %v, want only mc-alerts:worker-1", rung)
+	}
+}
+
+func TestUpdateRepoConfigTmuxAlerts(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, func(s *state.State) {
+		s.AddRepo("repo", &state.Repository{TmuxSession: "mc-repo", Agents: make(map[string]state.Agent)})
+	})
+	defer cleanup()
+
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":        "repo",
+		"tmux_alerts": true,
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "get_repo_config", Args: map[string]interface{}{"name": "repo"}})
+	if !resp.Success {
+		t.Fatalf("get_repo_config failed: %s", resp.Error)
+	}
+	if config, _ := resp.Data.(socket.RepoConfig); !config.TmuxAlerts {
+		t.Errorf("tmux_alerts = %v, want true", config.TmuxAlerts)
+	}
+}
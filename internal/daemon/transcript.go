@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// startTranscript pipes an agent window's output into the agent's log
+// file, so `multiclaude logs` can show and search it after the window is
+// gone. Windows that are already piped are left alone.
+func (d *Daemon) startTranscript(repoName, session, agentName string, agentType state.AgentType) {
+	isWorker := agentType == state.AgentTypeWorker || agentType == state.AgentTypeReview
+	logFile := d.paths.AgentLogFile(repoName, agentName, isWorker)
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		d.logger.Warn("Failed to create output directory for %s/%s: %v", repoName, agentName, err)
+		return
+	}
+	if err := d.tmux.StartPipePane(d.ctx, session, agentName, logFile); err != nil {
+		d.logger.Warn("Failed to capture output of %s/%s: %v", repoName, agentName, err)
+	}
+}
+
+// reopenTranscript restarts the pipe of the agent writing to logPath after
+// the log was rotated. The pipe's `cat` keeps appending to the file it
+// opened, which is now the rotated segment, until it is restarted.
+func (d *Daemon) reopenTranscript(logPath string) {
+	for repoName, repo := range d.state.GetAllRepos() {
+		for agentName, agent := range repo.Agents {
+			isWorker := agent.Type == state.AgentTypeWorker || agent.Type == state.AgentTypeReview
+			if d.paths.AgentLogFile(repoName, agentName, isWorker) != logPath {
+				continue
+			}
+			if agent.Headless {
+				// Each run opens the log anew
+				return
+			}
+			if err := d.tmux.StopPipePane(d.ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
+				d.logger.Debug("Failed to stop output capture of %s/%s: %v", repoName, agentName, err)
+				return
+			}
+			if err := d.tmux.StartPipePane(d.ctx, repo.TmuxSession, agent.TmuxWindow, logPath); err != nil {
+				d.logger.Warn("Failed to restart output capture of %s/%s after rotation: %v", repoName, agentName, err)
+			}
+			return
+		}
+	}
+}
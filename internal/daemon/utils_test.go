@@ -8,114 +8,6 @@ import (
 	"github.com/dlorenc/multiclaude/internal/state"
 )
 
-// Tests for getRequiredStringArg helper function
-
-func TestGetRequiredStringArg(t *testing.T) {
-	tests := []struct {
-		name        string
-		args        map[string]interface{}
-		key         string
-		description string
-		wantValue   string
-		wantOK      bool
-	}{
-		{
-			name:        "valid string",
-			args:        map[string]interface{}{"name": "test-value"},
-			key:         "name",
-			description: "name is required",
-			wantValue:   "test-value",
-			wantOK:      true,
-		},
-		{
-			name:        "missing key",
-			args:        map[string]interface{}{"other": "value"},
-			key:         "name",
-			description: "name is required",
-			wantValue:   "",
-			wantOK:      false,
-		},
-		{
-			name:        "empty string",
-			args:        map[string]interface{}{"name": ""},
-			key:         "name",
-			description: "name is required",
-			wantValue:   "",
-			wantOK:      false,
-		},
-		{
-			name:        "wrong type - int",
-			args:        map[string]interface{}{"name": 123},
-			key:         "name",
-			description: "name is required",
-			wantValue:   "",
-			wantOK:      false,
-		},
-		{
-			name:        "wrong type - bool",
-			args:        map[string]interface{}{"name": true},
-			key:         "name",
-			description: "name is required",
-			wantValue:   "",
-			wantOK:      false,
-		},
-		{
-			name:        "nil value",
-			args:        map[string]interface{}{"name": nil},
-			key:         "name",
-			description: "name is required",
-			wantValue:   "",
-			wantOK:      false,
-		},
-		{
-			name:        "nil args map",
-			args:        nil,
-			key:         "name",
-			description: "name is required",
-			wantValue:   "",
-			wantOK:      false,
-		},
-		{
-			name:        "whitespace only string",
-			args:        map[string]interface{}{"name": "   "},
-			key:         "name",
-			description: "name is required",
-			wantValue:   "   ",
-			wantOK:      true, // Note: whitespace strings are technically valid
-		},
-		{
-			name:        "float type",
-			args:        map[string]interface{}{"name": 3.14},
-			key:         "name",
-			description: "name is required",
-			wantValue:   "",
-			wantOK:      false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			value, resp, ok := getRequiredStringArg(tt.args, tt.key, tt.description)
-
-			if ok != tt.wantOK {
-				t.Errorf("getRequiredStringArg() ok = %v, want %v", ok, tt.wantOK)
-			}
-
-			if value != tt.wantValue {
-				t.Errorf("getRequiredStringArg() value = %q, want %q", value, tt.wantValue)
-			}
-
-			if !ok && resp.Success {
-				t.Error("Response should indicate failure when ok=false")
-			}
-
-			if !ok && resp.Error == "" {
-				t.Error("Response should contain error message when ok=false")
-			}
-		})
-	}
-}
-
 // Tests for recordTaskHistory function
 
 func TestRecordTaskHistory(t *testing.T) {
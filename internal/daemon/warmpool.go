@@ -0,0 +1,205 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/names"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// warmBootstrapTimeout bounds a warm worktree's bootstrap command
+const warmBootstrapTimeout = 20 * time.Minute
+
+// fillWarmPools tops up every repository's warm worktree pool
+func (d *Daemon) fillWarmPools() {
+	for _, repoName := range d.state.ListRepos() {
+		d.fillWarmPool(repoName)
+	}
+}
+
+// refillWarmPool tops up a repository's warm pool in the background
+func (d *Daemon) refillWarmPool(repoName string) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.fillWarmPool(repoName)
+	}()
+}
+
+// fillWarmPool creates bootstrapped worktrees until a repository's pool
+// reaches its configured size, and discards extras if the size was lowered
+func (d *Daemon) fillWarmPool(repoName string) {
+	if d.ctx.Err() != nil || d.isReadOnlyRepo(repoName, "warm pool fill") {
+		return
+	}
+
+	d.warmPoolMu.Lock()
+	defer d.warmPoolMu.Unlock()
+
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return
+	}
+	for ready := len(repo.WarmWorktrees); ready > repo.WarmPool.Size; ready-- {
+		warm, ok, err := d.state.TakeWarmWorktree(repoName)
+		if err != nil || !ok {
+			break
+		}
+		d.discardWarmWorktree(repoName, warm)
+	}
+	if len(repo.WarmWorktrees) >= repo.WarmPool.Size {
+		return
+	}
+
+	repoPath := d.paths.RepoDir(repoName)
+	if _, err := os.Stat(repoPath); err != nil {
+		return
+	}
+	// Warm worktrees become workers
+	wt := d.agentWorktreeManager(repoName, state.AgentTypeWorker)
+	startPoint := "HEAD"
+	if remote, err := wt.GetUpstreamRemote(); err == nil {
+		if mainBranch, err := wt.GetDefaultBranch(remote); err == nil {
+			startPoint = remote + "/" + mainBranch
+		}
+	}
+
+	for ready := len(repo.WarmWorktrees); ready < repo.WarmPool.Size; ready++ {
+		warm, err := d.createWarmWorktree(repoName, wt, startPoint, repo.WarmPool.Bootstrap)
+		if err != nil {
+			// Try again on the next refresh rather than retrying a broken bootstrap in a loop
+			d.logger.Warn("Failed to create warm worktree for %s: %v", repoName, err)
+			return
+		}
+		if err := d.state.AddWarmWorktree(repoName, warm); err != nil {
+			d.logger.Error("Failed to record warm worktree for %s: %v", repoName, err)
+			d.discardWarmWorktree(repoName, warm)
+			return
+		}
+		d.logger.Info("Added warm worktree %s to %s pool (%d/%d)", filepath.Base(warm.Path), repoName, ready+1, repo.WarmPool.Size)
+	}
+}
+
+// createWarmWorktree checks out a worktree on a warm/<id> branch and runs the
+// pool's bootstrap command in it
+func (d *Daemon) createWarmWorktree(repoName string, wt *worktree.Manager, startPoint, bootstrap string) (state.WarmWorktree, error) {
+	id := names.Generate()
+	path := filepath.Join(d.paths.WarmPoolDir(repoName), id)
+	for attempt := 0; attempt < 5; attempt++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		id = names.Generate()
+		path = filepath.Join(d.paths.WarmPoolDir(repoName), id)
+	}
+	warm := state.WarmWorktree{Path: path, Branch: "warm/" + id, CreatedAt: d.clock.Now()}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return warm, fmt.Errorf("failed to create warm pool directory: %w", err)
+	}
+	d.warmGitMu.Lock()
+	err := wt.CreateNewBranch(path, warm.Branch, startPoint)
+	d.warmGitMu.Unlock()
+	if err != nil {
+		return warm, err
+	}
+
+	if bootstrap != "" {
+		ctx, cancel := context.WithTimeout(d.ctx, warmBootstrapTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "sh", "-c", bootstrap)
+		cmd.Dir = path
+		if output, err := cmd.CombinedOutput(); err != nil {
+			d.discardWarmWorktree(repoName, warm)
+			return warm, fmt.Errorf("bootstrap %q failed: %w\nOutput: %s", bootstrap, err, output)
+		}
+	}
+	return warm, nil
+}
+
+// discardWarmWorktree removes a warm worktree and its branch
+func (d *Daemon) discardWarmWorktree(repoName string, warm state.WarmWorktree) {
+	d.warmGitMu.Lock()
+	defer d.warmGitMu.Unlock()
+
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	if err := wt.Remove(warm.Path, true); err != nil {
+		d.logger.Debug("Failed to remove warm worktree %s: %v", warm.Path, err)
+		os.RemoveAll(warm.Path)
+		wt.Prune()
+	}
+	if err := wt.DeleteBranch(warm.Branch); err != nil {
+		d.logger.Debug("Failed to delete warm branch %s: %v", warm.Branch, err)
+	}
+}
+
+// drainWarmPool discards every warm worktree of a repository
+func (d *Daemon) drainWarmPool(repoName string) {
+	d.warmPoolMu.Lock()
+	defer d.warmPoolMu.Unlock()
+
+	for {
+		warm, ok, err := d.state.TakeWarmWorktree(repoName)
+		if err != nil || !ok {
+			return
+		}
+		d.discardWarmWorktree(repoName, warm)
+	}
+}
+
+// handleClaimWarmWorktree assigns a warm worktree to a new agent: it is reset
+// to the start point, its branch renamed, and it is moved to the agent's
+// worktree path. Claiming from an empty pool is not an error; the caller
+// creates a worktree the slow way.
+func (d *Daemon) handleClaimWarmWorktree(req socket.Request, args socket.ClaimWarmWorktreeArgs) (socket.WarmWorktreeClaim, error) {
+	repoName := args.Repo
+
+	agentName := args.Agent
+
+	branch := args.Branch
+
+	startPoint := args.StartPoint
+	if startPoint == "" {
+		startPoint = "HEAD"
+	}
+
+	wtPath, claimed, err := d.claimWarmWorktree(repoName, agentName, branch, startPoint)
+	if err != nil {
+		return socket.WarmWorktreeClaim{}, err
+	}
+	return socket.WarmWorktreeClaim{Claimed: claimed, WorktreePath: wtPath}, nil
+}
+
+// claimWarmWorktree moves a worktree from the repo's warm pool to the
+// agent's worktree path on a new branch from startPoint, and returns the
+// path. It reports false, with no error, when no warm worktree could be
+// used.
+func (d *Daemon) claimWarmWorktree(repoName, agentName, branch, startPoint string) (string, bool, error) {
+	warm, ok, err := d.state.TakeWarmWorktree(repoName)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	// Replace what was taken once the claim is done with git
+	defer d.refillWarmPool(repoName)
+
+	wtPath := d.paths.AgentWorktree(repoName, agentName)
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	d.warmGitMu.Lock()
+	err = wt.Reassign(warm.Path, warm.Branch, wtPath, branch, startPoint)
+	d.warmGitMu.Unlock()
+	if err != nil {
+		d.logger.Warn("Failed to assign warm worktree %s to %s/%s: %v", warm.Path, repoName, agentName, err)
+		d.discardWarmWorktree(repoName, warm)
+		return "", false, nil
+	}
+
+	d.logger.Info("Assigned warm worktree %s to %s/%s", filepath.Base(warm.Path), repoName, agentName)
+	return wtPath, true, nil
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+func TestWarmPoolFillAndClaim(t *testing.T) {
+	d, cleanup := setupTestDaemonWithState(t, nil)
+	defer cleanup()
+
+	repoPath := initWorkerBranchRepo(t, d, "warm-repo")
+	runGitIn(t, repoPath, "checkout", "main")
+	if err := os.WriteFile(filepath.Join(repoPath, ".gitignore"), []byte("deps/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGitIn(t, repoPath, "add", ".gitignore")
+	runGitIn(t, repoPath, "commit", "-m", "Ignore deps")
+	runGitIn(t, repoPath, "fetch", "origin")
+	d.state.AddRepo("warm-repo", &state.Repository{TmuxSession: "mc-warm-repo", Agents: make(map[string]state.Agent)})
+
+	resp := d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":                "warm-repo",
+		"warm_pool_size":      float64(2),
+		"warm_pool_bootstrap": "mkdir -p deps && touch deps/installed",
+	}})
+	if !resp.Success {
+		t.Fatalf("update_repo_config failed: %s", resp.Error)
+	}
+	d.wg.Wait()
+
+	repo := d.state.GetAllRepos()["warm-repo"]
+	if len(repo.WarmWorktrees) != 2 {
+		t.Fatalf("pool has %d worktrees, want 2", len(repo.WarmWorktrees))
+	}
+	for _, warm := range repo.WarmWorktrees {
+		if _, err := os.Stat(filepath.Join(warm.Path, "deps", "installed")); err != nil {
+			t.Errorf("warm worktree %s was not bootstrapped", warm.Path)
+		}
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "claim_warm_worktree", Args: map[string]interface{}{
+		"repo":        "warm-repo",
+		"agent":       "swift-fox",
+		"branch":      "work/swift-fox",
+		"start_point": "origin/main",
+	}})
+	data, _ := resp.Data.(socket.WarmWorktreeClaim)
+	if !resp.Success || !data.Claimed {
+		t.Fatalf("claim_warm_worktree = %+v, want a claimed worktree", resp)
+	}
+	wtPath := d.paths.AgentWorktree("warm-repo", "swift-fox")
+	if data.WorktreePath != wtPath {
+		t.Errorf("worktree_path = %v, want %s", data.WorktreePath, wtPath)
+	}
+	if branch, _ := worktree.GetCurrentBranch(wtPath); branch != "work/swift-fox" {
+		t.Errorf("claimed worktree is on %q, want work/swift-fox", branch)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "deps", "installed")); err != nil {
+		t.Error("claimed worktree should keep its bootstrapped dependencies")
+	}
+
+	// The claim refills the pool in the background, tracked with the
+	// daemon's other goroutines
+	d.wg.Wait()
+	if ready := len(d.state.GetAllRepos()["warm-repo"].WarmWorktrees); ready != 2 {
+		t.Errorf("pool should be refilled after a claim, has %d", ready)
+	}
+
+	// Shrinking the pool discards the extras
+	d.handleRequest(socket.Request{Command: "update_repo_config", Args: map[string]interface{}{
+		"name":           "warm-repo",
+		"warm_pool_size": float64(0),
+	}})
+	d.wg.Wait()
+	if ready := len(d.state.GetAllRepos()["warm-repo"].WarmWorktrees); ready != 0 {
+		t.Errorf("disabled pool still has %d worktrees", ready)
+	}
+	if entries, _ := os.ReadDir(d.paths.WarmPoolDir("warm-repo")); len(entries) != 0 {
+		t.Errorf("warm pool directory should be empty, has %d entries", len(entries))
+	}
+
+	resp = d.handleRequest(socket.Request{Command: "claim_warm_worktree", Args: map[string]interface{}{
+		"repo": "warm-repo", "agent": "calm-owl", "branch": "work/calm-owl",
+	}})
+	if data, _ := resp.Data.(socket.WarmWorktreeClaim); !resp.Success || data.Claimed {
+		t.Errorf("claiming from an empty pool = %+v, want claimed=false", resp)
+	}
+}
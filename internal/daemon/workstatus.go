@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/mux"
+)
+
+// Window liveness reported by worker_status
+const (
+	windowAlive    = "alive"    // the pane's process is running
+	windowDead     = "dead"     // the pane's process exited but tmux kept the pane
+	windowMissing  = "missing"  // no window with the agent's name
+	windowHeadless = "headless" // the agent runs without a window
+)
+
+// handleWorkerStatus reports each worker's git state (branch, commits ahead
+// of and behind its base, uncommitted changes, stale submodules) with its tmux window's
+// liveness and last activity
+func (d *Daemon) handleWorkerStatus(req socket.Request, args socket.RepoArgs) ([]socket.WorkerStatus, error) {
+	repoName := args.Repo
+	repo, exists := d.state.GetAllRepos()[repoName]
+	if !exists {
+		return nil, socket.Errorf(socket.CodeNotFound, "repository '%s' not found", repoName)
+	}
+
+	windows := make(map[string]mux.WindowInfo)
+	if infos, err := d.tmux.ListWindowInfo(d.ctx, repo.TmuxSession); err == nil {
+		for _, info := range infos {
+			windows[info.Name] = info
+		}
+	}
+
+	remote, defaultBranch := "origin", "main"
+	wt := worktree.NewManager(d.paths.RepoDir(repoName))
+	if r, err := wt.GetUpstreamRemote(); err == nil {
+		remote = r
+		if b, err := wt.GetDefaultBranch(remote); err == nil {
+			defaultBranch = b
+		}
+	}
+
+	names := make([]string, 0, len(repo.Agents))
+	for name, agent := range repo.Agents {
+		if agent.Type == state.AgentTypeWorker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// Inspect the worktrees concurrently; repos with many workers would
+	// otherwise wait on several git commands per worker in turn
+	queries := make([]worktree.StateQuery, len(names))
+	for i, name := range names {
+		agent := repo.Agents[name]
+		base := defaultBranch
+		if agent.BaseBranch != "" {
+			base = agent.BaseBranch
+		}
+		queries[i] = worktree.StateQuery{Path: agent.WorktreePath, Remote: remote, MainBranch: base}
+	}
+	wtStates, wtErrs := worktree.CollectStates(queries, worktree.DefaultCollectWorkers)
+
+	statuses := make([]socket.WorkerStatus, 0, len(names))
+	for i, name := range names {
+		agent := repo.Agents[name]
+		status := socket.WorkerStatus{
+			Name:      name,
+			Window:    windowMissing,
+			Base:      queries[i].Remote + "/" + queries[i].MainBranch,
+			Completed: agent.ReadyForCleanup,
+		}
+		if agent.Headless {
+			status.Window = windowHeadless
+		} else if info, ok := windows[agent.TmuxWindow]; ok {
+			status.Window = windowAlive
+			if info.PaneDead {
+				status.Window = windowDead
+			}
+			if !info.Activity.IsZero() {
+				status.LastActivity = info.Activity.Format(time.RFC3339)
+			}
+		}
+
+		if err := wtErrs[i]; err != nil {
+			status.Error = err.Error()
+			statuses = append(statuses, status)
+			continue
+		}
+		wtState := wtStates[i]
+		status.Branch = wtState.Branch
+		status.Ahead = wtState.CommitsAhead
+		status.Behind = wtState.CommitsBehind
+		status.Uncommitted = wtState.HasUncommitted
+		status.StaleSubmodules = wtState.StaleSubmodules
+		switch {
+		case wtState.IsMidRebase:
+			status.GitState = "mid-rebase"
+		case wtState.IsMidMerge:
+			status.GitState = "mid-merge"
+		case wtState.IsDetachedHEAD:
+			status.GitState = "detached HEAD"
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
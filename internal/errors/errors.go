@@ -28,6 +28,7 @@ type CLIError struct {
 	Message    string
 	Suggestion string // Optional hint for how to fix the error
 	Cause      error  // Wrapped error
+	Code       int    // Process exit status; 0 means the default of 1
 }
 
 // Error implements the error interface
@@ -63,6 +64,21 @@ func (e *CLIError) WithSuggestion(suggestion string) *CLIError {
 	return e
 }
 
+// WithExitCode sets the status the process exits with, for commands whose
+// callers (e.g. CI) tell failures apart by exit status
+func (e *CLIError) WithExitCode(code int) *CLIError {
+	e.Code = code
+	return e
+}
+
+// ExitCode returns the status the process should exit with for err
+func ExitCode(err error) int {
+	if cliErr, ok := err.(*CLIError); ok && cliErr.Code != 0 {
+		return cliErr.Code
+	}
+	return 1
+}
+
 // Format returns a user-friendly formatted error message
 func Format(err error) string {
 	if err == nil {
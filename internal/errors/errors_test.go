@@ -238,6 +238,18 @@ func TestWithSuggestion_Chaining(t *testing.T) {
 	}
 }
 
+func TestExitCode(t *testing.T) {
+	if got := ExitCode(New(CategoryRuntime, "failed")); got != 1 {
+		t.Errorf("ExitCode(no code) = %d, want 1", got)
+	}
+	if got := ExitCode(New(CategoryRuntime, "timed out").WithExitCode(5)); got != 5 {
+		t.Errorf("ExitCode(WithExitCode(5)) = %d, want 5", got)
+	}
+	if got := ExitCode(errors.New("plain")); got != 1 {
+		t.Errorf("ExitCode(plain error) = %d, want 1", got)
+	}
+}
+
 func TestTmuxOperationFailed_SpecificSuggestions(t *testing.T) {
 	tests := []struct {
 		name         string
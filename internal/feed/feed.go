@@ -0,0 +1,200 @@
+// Package feed keeps a per-repository, chronological log of orchestration
+// actions taken by the daemon (agents spawned, worktrees refreshed, PRs
+// merged, ...) so the supervisor can catch up on what happened without
+// relying on the messages it happened to receive.
+package feed
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Action identifies what the daemon did
+type Action string
+
+const (
+	ActionSpawned       Action = "spawned"
+	ActionRemoved       Action = "removed"
+	ActionCompleted     Action = "completed"
+	ActionRestarted     Action = "restarted"
+	ActionHandedOff     Action = "handed_off"
+	ActionCleaned       Action = "cleaned"
+	ActionRefreshed     Action = "refreshed"
+	ActionRefreshPaused Action = "refresh_paused"
+	ActionRefreshResume Action = "refresh_resumed"
+	ActionBranchPushed  Action = "branch_pushed"
+	ActionTimedOut      Action = "timed_out"
+	ActionMergeQueue    Action = "merge_queue"
+	ActionWindowReaped  Action = "window_reaped"
+	ActionConflict      Action = "conflict"
+	ActionRecovered     Action = "recovered"
+	ActionCwdDrift      Action = "cwd_drift"
+	ActionAnswered      Action = "answered"
+	ActionPROpened      Action = "pr_opened"
+	ActionCIFailed      Action = "ci_failed"
+)
+
+const (
+	// MaxEntries is how many entries a repository's feed keeps once trimmed
+	MaxEntries = 2000
+	// trimBytes is the file size that triggers trimming to MaxEntries
+	trimBytes = 1 << 20
+)
+
+// Entry is one action in a repository's feed
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action Action    `json:"action"`
+	Agent  string    `json:"agent,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Manager reads and appends feed files, one JSON lines file per repository
+type Manager struct {
+	root string
+	mu   sync.Mutex
+}
+
+// NewManager creates a feed manager storing files under root
+func NewManager(root string) *Manager {
+	return &Manager{root: root}
+}
+
+// path returns the feed file for a repository
+func (m *Manager) path(repoName string) string {
+	return filepath.Join(m.root, repoName+".jsonl")
+}
+
+// Append adds an entry to a repository's feed, stamping it with the current
+// time if unset
+func (m *Manager) Append(repoName string, entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.root, 0755); err != nil {
+		return fmt.Errorf("failed to create feed directory: %w", err)
+	}
+	f, err := os.OpenFile(m.path(repoName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open feed: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	info, statErr := f.Stat()
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write feed: %w", err)
+	}
+
+	if statErr == nil && info.Size() > trimBytes {
+		return m.trim(repoName)
+	}
+	return nil
+}
+
+// List returns a repository's entries at or after since (zero for all),
+// oldest first, keeping only the newest limit entries when limit > 0
+func (m *Manager) List(repoName string, since time.Time, limit int) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries, err := m.read(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := entries[:0]
+	for _, e := range entries {
+		if !e.Time.Before(since) {
+			filtered = append(filtered, e)
+		}
+	}
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
+	}
+	return filtered, nil
+}
+
+// Remove deletes a repository's feed
+func (m *Manager) Remove(repoName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.Remove(m.path(repoName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// read loads every entry in a repository's feed. Malformed lines are skipped.
+func (m *Manager) read(repoName string) ([]Entry, error) {
+	f, err := os.Open(m.path(repoName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feed: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.Action != "" {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	return entries, nil
+}
+
+// trim rewrites a repository's feed keeping the newest MaxEntries entries
+func (m *Manager) trim(repoName string) error {
+	entries, err := m.read(repoName)
+	if err != nil {
+		return err
+	}
+	if len(entries) > MaxEntries {
+		entries = entries[len(entries)-MaxEntries:]
+	}
+
+	tmp := m.path(repoName) + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to trim feed: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("failed to trim feed: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to trim feed: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to trim feed: %w", err)
+	}
+	return os.Rename(tmp, m.path(repoName))
+}
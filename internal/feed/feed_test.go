@@ -0,0 +1,73 @@
+package feed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendAndList(t *testing.T) {
+	m := NewManager(filepath.Join(t.TempDir(), "feed"))
+
+	if entries, err := m.List("repo", time.Time{}, 0); err != nil || len(entries) != 0 {
+		t.Fatalf("List() on a missing feed = %v, %v", entries, err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	m.Append("repo", Entry{Time: old, Action: ActionSpawned, Agent: "fox", Detail: "worker: Fix bug"})
+	m.Append("repo", Entry{Action: ActionRefreshed, Agent: "fox"})
+	m.Append("repo", Entry{Action: ActionMergeQueue, Detail: "merged PR #7"})
+	m.Append("other", Entry{Action: ActionSpawned, Agent: "owl"})
+
+	entries, err := m.List("repo", time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Action != ActionSpawned || entries[2].Detail != "merged PR #7" {
+		t.Errorf("List() = %+v", entries)
+	}
+	if entries[1].Time.IsZero() {
+		t.Error("Append() should stamp entries without a time")
+	}
+
+	if entries, _ := m.List("repo", time.Now().Add(-time.Hour), 0); len(entries) != 2 {
+		t.Errorf("List(since 1h) returned %d entries, want 2", len(entries))
+	}
+	if entries, _ := m.List("repo", time.Time{}, 1); len(entries) != 1 || entries[0].Action != ActionMergeQueue {
+		t.Errorf("List(limit 1) = %+v, want the newest entry", entries)
+	}
+
+	if err := m.Remove("repo"); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if entries, _ := m.List("repo", time.Time{}, 0); len(entries) != 0 {
+		t.Errorf("Remove() left %d entries", len(entries))
+	}
+}
+
+func TestAppendTrims(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir)
+
+	f, err := os.Create(filepath.Join(dir, "repo.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := []byte(`{"time":"2026-01-01T00:00:00Z","action":"refreshed","agent":"fox","detail":"rebased 3 commits onto origin/main"}` + "\n")
+	for written := 0; written <= trimBytes; written += len(line) {
+		f.Write(line)
+	}
+	f.Close()
+
+	if err := m.Append("repo", Entry{Action: ActionCleaned, Agent: "fox"}); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	entries, _ := m.List("repo", time.Time{}, 0)
+	if len(entries) != MaxEntries {
+		t.Fatalf("feed has %d entries after trimming, want %d", len(entries), MaxEntries)
+	}
+	if last := entries[len(entries)-1]; last.Action != ActionCleaned {
+		t.Errorf("newest entry should survive trimming, got %+v", last)
+	}
+}
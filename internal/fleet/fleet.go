@@ -0,0 +1,296 @@
+// Package fleet lets one machine's CLI reach the daemons on other hosts, so
+// a fleet of build machines can be inspected and given work as one. Hosts
+// are listed in a local file and reached over SSH: a request is piped to
+// `multiclaude daemon relay` on the host, which forwards it to the daemon
+// there. The remote daemon sees the SSH user as the caller, so its access
+// policies apply as usual. A host with an address is reached on its
+// daemon's TCP socket instead, with the daemon's token, and the caller
+// counts as the daemon's user.
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+// Host is another machine running a multiclaude daemon
+type Host struct {
+	Name string `json:"name"`
+	// SSH is the ssh destination, e.g. "ci@build-1" or a Host from ~/.ssh/config
+	SSH string `json:"ssh,omitempty"`
+
+	// Address is the host:port of the daemon's TCP socket. When set,
+	// requests go there instead of over SSH.
+	Address string `json:"address,omitempty"`
+	// TokenFile holds the token of the daemon's TCP socket; without one,
+	// the token is taken from $MULTICLAUDE_TOKEN
+	TokenFile string `json:"token_file,omitempty"`
+	// CACert is the certificate the daemon's TLS certificate must be signed
+	// by; empty for the system's roots
+	CACert string `json:"ca_cert,omitempty"`
+	// Plaintext connects without TLS, to a daemon listening on loopback
+	// (e.g. through an SSH tunnel)
+	Plaintext bool `json:"plaintext,omitempty"`
+}
+
+// TokenEnv is the environment variable a host's token is read from when it
+// has no token file
+const TokenEnv = "MULTICLAUDE_TOKEN"
+
+// Target returns where the host is reached: its address or ssh destination
+func (h Host) Target() string {
+	if h.Address != "" {
+		return h.Address
+	}
+	return h.SSH
+}
+
+// SocketClient returns a client for the host's TCP socket
+func (h Host) SocketClient() (*socket.Client, error) {
+	token := os.Getenv(TokenEnv)
+	if h.TokenFile != "" {
+		data, err := os.ReadFile(h.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token of host %s: %w", h.Name, err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return nil, fmt.Errorf("host %s has no token: give it a token file or set %s", h.Name, TokenEnv)
+	}
+	if h.Plaintext {
+		return socket.NewRemoteClient(h.Address, token, nil), nil
+	}
+	tlsConfig, err := socket.ClientTLSConfig(h.CACert)
+	if err != nil {
+		return nil, err
+	}
+	return socket.NewRemoteClient(h.Address, token, tlsConfig), nil
+}
+
+// LocalHost is the name results for this machine's daemon carry
+const LocalHost = "local"
+
+// LoadHosts reads the hosts file. A missing file means no hosts.
+func LoadHosts(path string) ([]Host, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts: %w", err)
+	}
+	var hosts []Host
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return hosts, nil
+}
+
+// SaveHosts writes the hosts file, sorted by name
+func SaveHosts(path string, hosts []Host) error {
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+	data, err := json.MarshalIndent(hosts, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write hosts: %w", err)
+	}
+	return nil
+}
+
+// Find returns the host with the given name
+func Find(hosts []Host, name string) (Host, bool) {
+	for _, h := range hosts {
+		if h.Name == name {
+			return h, true
+		}
+	}
+	return Host{}, false
+}
+
+// DefaultTimeout bounds each request to a host, including the SSH connection
+const DefaultTimeout = 30 * time.Second
+
+// Client sends daemon requests to hosts over SSH
+type Client struct {
+	// SSH is the ssh command and its options; the destination and remote
+	// command are appended
+	SSH     []string
+	Timeout time.Duration
+}
+
+// NewClient creates a client that runs ssh non-interactively
+func NewClient() *Client {
+	return &Client{
+		SSH:     []string{"ssh", "-o", "BatchMode=yes", "-o", "ConnectTimeout=10"},
+		Timeout: DefaultTimeout,
+	}
+}
+
+// Send forwards req to the host's daemon and returns its response
+func (c *Client) Send(ctx context.Context, host Host, req socket.Request) (*socket.Response, error) {
+	if host.Address != "" {
+		return c.sendTCP(ctx, host, req)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	args := append(append([]string{}, c.SSH[1:]...), host.SSH, "multiclaude", "daemon", "relay")
+	cmd := exec.CommandContext(ctx, c.SSH[0], args...)
+	cmd.Stdin = bytes.NewReader(body)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("host %s (%s): %s", host.Name, host.SSH, msg)
+	}
+
+	var resp socket.Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("host %s: invalid relay response: %w", host.Name, err)
+	}
+	return &resp, nil
+}
+
+// sendTCP sends req to the host's TCP socket
+func (c *Client) sendTCP(ctx context.Context, host Host, req socket.Request) (*socket.Response, error) {
+	client, err := host.SocketClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	type result struct {
+		resp *socket.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := client.Send(req)
+		done <- result{resp, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, fmt.Errorf("host %s (%s): %w", host.Name, host.Address, r.err)
+		}
+		return r.resp, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("host %s (%s): %w", host.Name, host.Address, ctx.Err())
+	}
+}
+
+// Result is one host's answer to a request sent to the whole fleet
+type Result struct {
+	Host     string
+	Response *socket.Response
+	Err      error
+}
+
+// SendAll sends req to every host at once and returns the results in the
+// order of hosts
+func (c *Client) SendAll(ctx context.Context, hosts []Host, req socket.Request) []Result {
+	results := make([]Result, len(hosts))
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h Host) {
+			defer wg.Done()
+			resp, err := c.Send(ctx, h, req)
+			if err == nil && !resp.Success {
+				err = fmt.Errorf("host %s: %s", h.Name, resp.Error)
+			}
+			results[i] = Result{Host: h.Name, Response: resp, Err: err}
+		}(i, h)
+	}
+	wg.Wait()
+	return results
+}
+
+// Run runs a multiclaude command on the host, streaming its output. Only
+// hosts reached over SSH can run commands.
+func (c *Client) Run(host Host, args []string, stdout, stderr io.Writer) error {
+	if host.SSH == "" {
+		return fmt.Errorf("host %s is reached on its TCP socket, which can't run commands", host.Name)
+	}
+	sshArgs := append(append([]string{}, c.SSH[1:]...), host.SSH, "multiclaude "+ShellQuote(args))
+	cmd := exec.Command(c.SSH[0], sshArgs...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("host %s: multiclaude %s: %w", host.Name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// Relay reads one request from in, sends it to the daemon at socketPath,
+// and writes the response to out. It is the remote end of Client.Send.
+func Relay(socketPath string, in io.Reader, out io.Writer) error {
+	var req socket.Request
+	if err := json.NewDecoder(in).Decode(&req); err != nil {
+		return fmt.Errorf("failed to read relayed request: %w", err)
+	}
+	resp, err := socket.NewClient(socketPath).Send(req)
+	if err != nil {
+		resp = &socket.Response{Success: false, Error: err.Error()}
+	}
+	return json.NewEncoder(out).Encode(resp)
+}
+
+// Load is how busy a host is for a repository
+type Load struct {
+	Host    string
+	Workers int
+}
+
+// LeastLoaded returns the host with the fewest workers. Ties go to the
+// earliest host in loads, so callers list the local host first to prefer it.
+func LeastLoaded(loads []Load) (string, bool) {
+	if len(loads) == 0 {
+		return "", false
+	}
+	best := loads[0]
+	for _, l := range loads[1:] {
+		if l.Workers < best.Workers {
+			best = l
+		}
+	}
+	return best.Host, true
+}
+
+// ShellQuote quotes args for the remote shell ssh runs its command in
+func ShellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && strings.IndexFunc(a, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:@,+%", r))
+		}) < 0 {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,146 @@
+package fleet
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/internal/socket"
+)
+
+func TestHostsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+
+	hosts, err := LoadHosts(path)
+	if err != nil || len(hosts) != 0 {
+		t.Fatalf("LoadHosts(missing) = %v, %v; want no hosts", hosts, err)
+	}
+
+	if err := SaveHosts(path, []Host{{Name: "build-2", SSH: "ci@build-2"}, {Name: "build-1", SSH: "build-1"}}); err != nil {
+		t.Fatalf("SaveHosts() error = %v", err)
+	}
+	hosts, err = LoadHosts(path)
+	if err != nil {
+		t.Fatalf("LoadHosts() error = %v", err)
+	}
+	if len(hosts) != 2 || hosts[0].Name != "build-1" || hosts[1].SSH != "ci@build-2" {
+		t.Errorf("LoadHosts() = %+v, want both hosts sorted by name", hosts)
+	}
+	if h, ok := Find(hosts, "build-2"); !ok || h.SSH != "ci@build-2" {
+		t.Errorf("Find(build-2) = %+v, %v", h, ok)
+	}
+	if _, ok := Find(hosts, "build-3"); ok {
+		t.Error("Find(build-3) found a host that wasn't added")
+	}
+}
+
+// fakeSSH writes a script standing in for ssh: it checks the remote command
+// and prints a canned relay response
+func fakeSSH(t *testing.T, response string) *Client {
+	t.Helper()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ssh")
+	body := "#!/bin/sh\n" +
+		"cat > " + filepath.Join(dir, "request") + "\n" +
+		"echo \"$@\" > " + filepath.Join(dir, "args") + "\n" +
+		"case \"$1\" in down) echo 'ssh: connect to host down: Connection refused' >&2; exit 255;; esac\n" +
+		"echo '" + response + "'\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &Client{SSH: []string{script}, Timeout: DefaultTimeout}
+}
+
+func TestClientSend(t *testing.T) {
+	c := fakeSSH(t, `{"success":true,"data":"pong"}`)
+	resp, err := c.Send(context.Background(), Host{Name: "b1", SSH: "build-1"}, socket.Request{Command: "ping"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !resp.Success || resp.Data != "pong" {
+		t.Errorf("Send() = %+v, want the relayed response", resp)
+	}
+
+	dir := filepath.Dir(c.SSH[0])
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if got := strings.TrimSpace(string(args)); got != "build-1 multiclaude daemon relay" {
+		t.Errorf("ssh args = %q", got)
+	}
+	req, _ := os.ReadFile(filepath.Join(dir, "request"))
+	if !strings.Contains(string(req), `"command":"ping"`) {
+		t.Errorf("relayed request = %s", req)
+	}
+}
+
+func TestClientSendAll(t *testing.T) {
+	c := fakeSSH(t, `{"success":false,"error":"permission denied"}`)
+	results := c.SendAll(context.Background(), []Host{{Name: "a", SSH: "up"}, {Name: "b", SSH: "down"}}, socket.Request{Command: "list_repos"})
+	if len(results) != 2 || results[0].Host != "a" || results[1].Host != "b" {
+		t.Fatalf("SendAll() = %+v, want a result per host in order", results)
+	}
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "permission denied") {
+		t.Errorf("failed response error = %v", results[0].Err)
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "Connection refused") {
+		t.Errorf("unreachable host error = %v", results[1].Err)
+	}
+}
+
+func TestLeastLoaded(t *testing.T) {
+	if _, ok := LeastLoaded(nil); ok {
+		t.Error("LeastLoaded(nil) picked a host")
+	}
+	got, _ := LeastLoaded([]Load{{LocalHost, 2}, {"b1", 1}, {"b2", 1}})
+	if got != "b1" {
+		t.Errorf("LeastLoaded() = %s, want the first of the least loaded", got)
+	}
+	got, _ = LeastLoaded([]Load{{LocalHost, 1}, {"b1", 1}})
+	if got != LocalHost {
+		t.Errorf("LeastLoaded() = %s, want ties to go to the first host", got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	got := ShellQuote([]string{"work", "fix the login bug", "--repo", "api", "it's", ""})
+	want := `work 'fix the login bug' --repo api 'it'\''s' ''`
+	if got != want {
+		t.Errorf("ShellQuote() = %s, want %s", got, want)
+	}
+}
+
+func TestClientSendTCP(t *testing.T) {
+	server := socket.NewRemoteServer("127.0.0.1:0", "s3cret", nil, socket.HandlerFunc(func(req socket.Request) socket.Response {
+		return socket.Response{Success: true, Data: req.Command}
+	}))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer server.Stop()
+	go server.Serve()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("s3cret\n"), 0600)
+	host := Host{Name: "b1", Address: server.Addr().String(), TokenFile: tokenFile, Plaintext: true}
+
+	c := &Client{Timeout: DefaultTimeout}
+	resp, err := c.Send(context.Background(), host, socket.Request{Command: "ping"})
+	if err != nil || !resp.Success || resp.Data != "ping" {
+		t.Fatalf("Send() over TCP = %+v, %v", resp, err)
+	}
+
+	t.Setenv(TokenEnv, "")
+	host.TokenFile = ""
+	if _, err := c.Send(context.Background(), host, socket.Request{Command: "ping"}); err == nil || !strings.Contains(err.Error(), TokenEnv) {
+		t.Errorf("Send() without a token = %v, want it to name %s", err, TokenEnv)
+	}
+	t.Setenv(TokenEnv, "s3cret")
+	if resp, err := c.Send(context.Background(), host, socket.Request{Command: "ping"}); err != nil || !resp.Success {
+		t.Errorf("Send() with %s = %+v, %v", TokenEnv, resp, err)
+	}
+
+	if err := c.Run(host, []string{"list"}, nil, nil); err == nil {
+		t.Error("Run() on a host without SSH should fail")
+	}
+}
@@ -114,6 +114,20 @@ func TimeAgo(t time.Time) string {
 	}
 }
 
+// Bytes formats a byte count in powers of 1024, e.g. "1.5 GB"
+func Bytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
+
 // Truncate truncates a string to maxLen, adding "..." if truncated
 func Truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -229,6 +229,26 @@ func TestMessageBadge(t *testing.T) {
 	}
 }
 
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KB"},
+		{5 << 20, "5.0 MB"},
+		{3 << 29, "1.5 GB"},
+		{2 << 40, "2.0 TB"},
+		{2048 << 40, "2048.0 TB"},
+	}
+	for _, tt := range tests {
+		if got := Bytes(tt.n); got != tt.want {
+			t.Errorf("Bytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
 func TestColoredTableTotalWidth(t *testing.T) {
 	// Test totalWidth through the ColoredTable
 	table := NewColoredTable("Name", "Status", "Task")
@@ -0,0 +1,391 @@
+// Package github is the daemon's shared GitHub REST API client. Every
+// subsystem that polls GitHub (PR status, CI, issues) goes through one
+// Client so that they share a response cache, a rate-limit budget, and a
+// backoff, instead of each burning through the hourly limit on its own.
+//
+// Responses are cached and revalidated with conditional requests (ETag and
+// Last-Modified), which GitHub does not count against the rate limit when
+// nothing changed. When a subsystem is over its budget or the client is
+// backing off, the last cached response is served instead if there is one.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+)
+
+// DefaultBaseURL is the public GitHub API
+const DefaultBaseURL = "https://api.github.com"
+
+// DefaultCacheTTL is how long a response is served without revalidating it
+const DefaultCacheTTL = 30 * time.Second
+
+// maxCacheEntries bounds the response cache; the least recently fetched
+// entries are dropped first
+const maxCacheEntries = 500
+
+// Client is a rate-limit-aware GitHub API client. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL  string
+	http     *http.Client
+	clock    clock.Clock
+	cacheTTL time.Duration
+
+	tokenOnce sync.Once
+	token     string
+	tokenFunc func() string
+
+	mu      sync.Mutex
+	cache   map[string]*cacheEntry
+	limiter *limiter
+}
+
+// cacheEntry is a response body and the validators to revalidate it with
+type cacheEntry struct {
+	body         []byte
+	etag         string
+	lastModified string
+	fetched      time.Time
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL points the client at a GitHub Enterprise server or a test
+// server
+func WithBaseURL(u string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+// WithToken sets the token requests are authenticated with
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.tokenFunc = func() string { return token }
+	}
+}
+
+// WithHTTPClient sets the HTTP client requests are sent with
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) {
+		c.http = h
+	}
+}
+
+// WithClock sets the clock cache freshness, budgets, and backoff are
+// measured by
+func WithClock(clk clock.Clock) Option {
+	return func(c *Client) {
+		c.clock = clk
+	}
+}
+
+// WithCacheTTL sets how long responses are served without revalidating
+func WithCacheTTL(d time.Duration) Option {
+	return func(c *Client) {
+		c.cacheTTL = d
+	}
+}
+
+// NewClient creates a client. Unless WithToken is given, it authenticates
+// with GITHUB_TOKEN or GH_TOKEN, falling back to `gh auth token`; the token
+// is looked up on the first request. GITHUB_API_URL overrides the base URL.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		baseURL:   DefaultBaseURL,
+		http:      &http.Client{Timeout: 30 * time.Second},
+		clock:     clock.Real(),
+		cacheTTL:  DefaultCacheTTL,
+		tokenFunc: defaultToken,
+		cache:     make(map[string]*cacheEntry),
+		limiter:   newLimiter(),
+	}
+	if u := os.Getenv("GITHUB_API_URL"); u != "" {
+		c.baseURL = strings.TrimSuffix(u, "/")
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// defaultToken finds a token the way gh does
+func defaultToken() string {
+	for _, env := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(env); token != "" {
+			return token
+		}
+	}
+	output, err := exec.Command("gh", "auth", "token").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// Get fetches path (e.g. "/repos/owner/repo/pulls?state=open") on behalf of
+// subsystem, which is charged against its budget, and decodes the JSON
+// response into v.
+//
+// If the subsystem is over budget or the client is backing off, the cached
+// response is decoded instead when there is one; otherwise the error wraps
+// ErrRateLimited.
+func (c *Client) Get(ctx context.Context, subsystem, path string, v interface{}) error {
+	body, err := c.get(ctx, subsystem, path)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("github: decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, subsystem, path string) ([]byte, error) {
+	url := c.baseURL + "/" + strings.TrimPrefix(path, "/")
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	entry := c.cache[url]
+	if entry != nil && now.Sub(entry.fetched) < c.cacheTTL {
+		c.limiter.stats.CacheHits++
+		c.mu.Unlock()
+		return entry.body, nil
+	}
+	if err := c.limiter.admit(subsystem, now); err != nil {
+		if entry != nil {
+			c.limiter.stats.Stale++
+			c.mu.Unlock()
+			return entry.body, nil
+		}
+		c.limiter.stats.Throttled++
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token := c.authToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if entry != nil {
+		if entry.etag != "" {
+			req.Header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.mu.Lock()
+		c.limiter.fail(c.clock.Now(), 0)
+		c.mu.Unlock()
+		return c.staleOr(url, fmt.Errorf("github: GET %s: %w", path, err))
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("github: reading %s: %w", path, err)
+	}
+
+	now = c.clock.Now()
+	c.mu.Lock()
+	c.limiter.observe(resp.Header, now)
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		c.limiter.succeed()
+		c.limiter.refund(subsystem)
+		c.limiter.stats.NotModified++
+		entry.fetched = now
+		c.mu.Unlock()
+		return entry.body, nil
+
+	case resp.StatusCode/100 == 2:
+		c.limiter.succeed()
+		c.store(url, &cacheEntry{
+			body:         body,
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+			fetched:      now,
+		})
+		c.mu.Unlock()
+		return body, nil
+
+	case isRateLimited(resp):
+		c.limiter.fail(now, retryAfter(resp.Header, now))
+		c.mu.Unlock()
+		return c.staleOr(url, fmt.Errorf("github: GET %s: %s: %w", path, resp.Status, ErrRateLimited))
+
+	case resp.StatusCode >= 500:
+		c.limiter.fail(now, 0)
+		c.mu.Unlock()
+		return c.staleOr(url, statusError(http.MethodGet, path, resp.Status, body))
+
+	default:
+		// A 404 or 422 says nothing about GitHub's health
+		c.limiter.succeed()
+		c.mu.Unlock()
+		return nil, statusError(http.MethodGet, path, resp.Status, body)
+	}
+}
+
+// Post sends body as JSON to the API path and decodes the response into v.
+// Writes aren't cached and count against the subsystem's budget like any
+// other request, but there is no cached response to fall back on.
+func (c *Client) Post(ctx context.Context, subsystem, path string, body, v interface{}) error {
+	return c.write(ctx, http.MethodPost, subsystem, path, body, v)
+}
+
+// Put is Post with the PUT method
+func (c *Client) Put(ctx context.Context, subsystem, path string, body, v interface{}) error {
+	return c.write(ctx, http.MethodPut, subsystem, path, body, v)
+}
+
+func (c *Client) write(ctx context.Context, method, subsystem, path string, body, v interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("github: encoding %s: %w", path, err)
+	}
+	url := c.baseURL + "/" + strings.TrimPrefix(path, "/")
+
+	c.mu.Lock()
+	if err := c.limiter.admit(subsystem, c.clock.Now()); err != nil {
+		c.limiter.stats.Throttled++
+		c.mu.Unlock()
+		return err
+	}
+	c.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token := c.authToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.mu.Lock()
+		c.limiter.fail(c.clock.Now(), 0)
+		c.limiter.stats.Errors++
+		c.mu.Unlock()
+		return fmt.Errorf("github: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("github: reading %s: %w", path, err)
+	}
+
+	now := c.clock.Now()
+	c.mu.Lock()
+	c.limiter.observe(resp.Header, now)
+	switch {
+	case resp.StatusCode/100 == 2:
+		c.limiter.succeed()
+		c.mu.Unlock()
+	case isRateLimited(resp):
+		c.limiter.fail(now, retryAfter(resp.Header, now))
+		c.limiter.stats.Errors++
+		c.mu.Unlock()
+		return fmt.Errorf("github: %s %s: %s: %w", method, path, resp.Status, ErrRateLimited)
+	case resp.StatusCode >= 500:
+		c.limiter.fail(now, 0)
+		c.limiter.stats.Errors++
+		c.mu.Unlock()
+		return statusError(method, path, resp.Status, respBody)
+	default:
+		c.limiter.succeed()
+		c.mu.Unlock()
+		return statusError(method, path, resp.Status, respBody)
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, v); err != nil {
+		return fmt.Errorf("github: decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+// staleOr returns url's cached body if there is one, counting it as a
+// stale hit, and err otherwise
+func (c *Client) staleOr(url string, err error) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry := c.cache[url]; entry != nil {
+		c.limiter.stats.Stale++
+		return entry.body, nil
+	}
+	c.limiter.stats.Errors++
+	return nil, err
+}
+
+// store caches a response, evicting the oldest entry when full. The caller
+// holds c.mu.
+func (c *Client) store(url string, entry *cacheEntry) {
+	if _, exists := c.cache[url]; !exists && len(c.cache) >= maxCacheEntries {
+		var oldest string
+		for key, e := range c.cache {
+			if oldest == "" || e.fetched.Before(c.cache[oldest].fetched) {
+				oldest = key
+			}
+		}
+		delete(c.cache, oldest)
+	}
+	c.cache[url] = entry
+}
+
+// authToken returns the token, looking it up on first use
+func (c *Client) authToken() string {
+	c.tokenOnce.Do(func() { c.token = c.tokenFunc() })
+	return c.token
+}
+
+// statusError describes an unsuccessful response with the start of its body
+func statusError(method, path, status string, body []byte) error {
+	var apiErr struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+		msg := apiErr.Message
+		for _, e := range apiErr.Errors {
+			if e.Message != "" {
+				msg += ": " + e.Message
+			}
+		}
+		return fmt.Errorf("github: %s %s: %s: %s", method, path, status, msg)
+	}
+	return fmt.Errorf("github: %s %s: %s", method, path, status)
+}
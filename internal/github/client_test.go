@@ -0,0 +1,241 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestClient returns a client for a test server and the fake clock it
+// runs on
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, *clock.Fake) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	fake := clock.NewFake(epoch)
+	return NewClient(WithBaseURL(server.URL), WithToken("test-token"), WithClock(fake)), fake
+}
+
+// rateHeaders sets GitHub's rate limit headers
+func rateHeaders(w http.ResponseWriter, limit, remaining int, reset time.Time) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
+
+func TestGetConditionalRequests(t *testing.T) {
+	var hits, conditional atomic.Int32
+	client, fake := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		rateHeaders(w, 5000, 4999, epoch.Add(time.Hour))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"number": 7}`)
+	})
+
+	var pr struct{ Number int }
+	for i := 0; i < 3; i++ {
+		if err := client.Get(context.Background(), "prs", "/repos/o/r/pulls/7", &pr); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	}
+	if pr.Number != 7 || hits.Load() != 1 {
+		t.Fatalf("number = %d, hits = %d; want 7 from one request", pr.Number, hits.Load())
+	}
+
+	// Once the entry is stale it is revalidated rather than refetched
+	fake.Advance(DefaultCacheTTL)
+	pr.Number = 0
+	if err := client.Get(context.Background(), "prs", "/repos/o/r/pulls/7", &pr); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if pr.Number != 7 || conditional.Load() != 1 {
+		t.Fatalf("number = %d, conditional = %d; want 7 from a 304", pr.Number, conditional.Load())
+	}
+
+	stats := client.Stats()
+	if stats.Requests != 2 || stats.CacheHits != 2 || stats.NotModified != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.Subsystems["prs"] != 1 {
+		t.Errorf("prs charged %d requests, want 1 (304s are free)", stats.Subsystems["prs"])
+	}
+	if stats.Limit != 5000 || stats.Remaining != 4999 {
+		t.Errorf("limit = %d, remaining = %d", stats.Limit, stats.Remaining)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	client, fake := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		rateHeaders(w, 100, 90, epoch.Add(time.Hour))
+		fmt.Fprint(w, `{}`)
+	})
+	client.SetBudget("ci", 0.02) // 2 of 100 requests
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := client.Get(ctx, "ci", fmt.Sprintf("/runs/%d", i), nil); err != nil {
+			t.Fatalf("request %d within budget failed: %v", i, err)
+		}
+	}
+	if err := client.Get(ctx, "ci", "/runs/2", nil); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("request over budget: err = %v, want ErrRateLimited", err)
+	}
+
+	// Cached responses are still served, and other subsystems are unaffected
+	fake.Advance(time.Minute)
+	if err := client.Get(ctx, "ci", "/runs/0", nil); err != nil {
+		t.Errorf("stale response was not served: %v", err)
+	}
+	if err := client.Get(ctx, "issues", "/issues", nil); err != nil {
+		t.Errorf("unbudgeted subsystem was throttled: %v", err)
+	}
+
+	// The budget renews with GitHub's window
+	fake.Advance(time.Hour)
+	if err := client.Get(ctx, "ci", "/runs/3", nil); err != nil {
+		t.Errorf("budget did not renew: %v", err)
+	}
+
+	stats := client.Stats()
+	if stats.Throttled != 1 || stats.Stale != 1 {
+		t.Errorf("throttled = %d, stale = %d; want 1 and 1", stats.Throttled, stats.Stale)
+	}
+}
+
+func TestRateLimitedBacksOffUntilReset(t *testing.T) {
+	var limited atomic.Bool
+	var hits atomic.Int32
+	client, fake := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if limited.Load() {
+			rateHeaders(w, 5000, 0, epoch.Add(10*time.Minute))
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+			return
+		}
+		rateHeaders(w, 5000, 10, epoch.Add(10*time.Minute))
+		fmt.Fprint(w, `{"state": "open"}`)
+	})
+
+	ctx := context.Background()
+	if err := client.Get(ctx, "prs", "/pr", nil); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	limited.Store(true)
+	fake.Advance(time.Minute)
+	var pr struct{ State string }
+	if err := client.Get(ctx, "prs", "/pr", &pr); err != nil || pr.State != "open" {
+		t.Fatalf("rate limited request should serve the cached response: state %q, err %v", pr.State, err)
+	}
+	if err := client.Get(ctx, "prs", "/other", nil); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+
+	stats := client.Stats()
+	if !stats.BackoffUntil.Equal(epoch.Add(10 * time.Minute)) {
+		t.Errorf("backing off until %v, want the reset time", stats.BackoffUntil)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("%d requests reached GitHub while backing off", hits.Load()-2)
+	}
+
+	limited.Store(false)
+	fake.Advance(10 * time.Minute)
+	if err := client.Get(ctx, "prs", "/other", nil); err != nil {
+		t.Errorf("requests did not resume after the reset: %v", err)
+	}
+	if stats := client.Stats(); stats.Failures != 0 || !stats.BackoffUntil.IsZero() {
+		t.Errorf("backoff not cleared: %+v", stats)
+	}
+}
+
+func TestServerErrorsBackOffExponentially(t *testing.T) {
+	client, fake := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	ctx := context.Background()
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if err := client.Get(ctx, "prs", "/pr", nil); err == nil || errors.Is(err, ErrRateLimited) {
+			t.Fatalf("attempt %d: err = %v, want the server error", i, err)
+		}
+		stats := client.Stats()
+		if got := stats.BackoffUntil.Sub(fake.Now()); got != want {
+			t.Fatalf("attempt %d: backoff = %v, want %v", i, got, want)
+		}
+		if err := client.Get(ctx, "prs", "/pr", nil); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("attempt %d: request during backoff: err = %v", i, err)
+		}
+		fake.Advance(want)
+	}
+}
+
+func TestNotFoundDoesNotBackOff(t *testing.T) {
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	})
+
+	err := client.Get(context.Background(), "prs", "/missing", nil)
+	if err == nil || errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want a not found error", err)
+	}
+	if stats := client.Stats(); stats.Failures != 0 {
+		t.Errorf("404 counted as a failure: %+v", stats)
+	}
+}
+
+func TestPost(t *testing.T) {
+	var posts atomic.Int32
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s", r.Method)
+		}
+		if posts.Add(1) == 2 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message": "Validation Failed", "errors": [{"message": "A pull request already exists for o:work/w."}]}`)
+			return
+		}
+		var body NewPullRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Head != "work/w" || body.Base != "main" {
+			t.Errorf("body = %+v (%v)", body, err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number": 9, "html_url": "https://github.com/o/r/pull/9"}`)
+	})
+
+	pr, err := client.CreatePullRequest(context.Background(), "prs", "o", "r", NewPullRequest{Title: "t", Head: "work/w", Base: "main"})
+	if err != nil || pr.Number != 9 || pr.URL != "https://github.com/o/r/pull/9" {
+		t.Fatalf("CreatePullRequest = %+v, %v", pr, err)
+	}
+
+	// Writes are never served from the cache
+	_, err = client.CreatePullRequest(context.Background(), "prs", "o", "r", NewPullRequest{Title: "t", Head: "work/w", Base: "main"})
+	if err == nil || !strings.Contains(err.Error(), "A pull request already exists") {
+		t.Fatalf("err = %v, want GitHub's validation message", err)
+	}
+	if stats := client.Stats(); stats.Subsystems["prs"] != 2 || stats.Failures != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,248 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ParseRepoURL returns the owner and name of a GitHub repository from its
+// HTTPS or SSH clone URL
+func ParseRepoURL(repoURL string) (owner, name string, err error) {
+	path := strings.TrimSpace(repoURL)
+	switch {
+	case strings.HasPrefix(path, "git@"):
+		_, path, _ = strings.Cut(path, ":")
+	case strings.Contains(path, "://"):
+		u, err := url.Parse(path)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid repository URL %q: %w", repoURL, err)
+		}
+		path = u.Path
+	}
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("not a GitHub repository URL: %q", repoURL)
+	}
+	return parts[0], parts[1], nil
+}
+
+// PullRequest is the part of GitHub's pull request object the daemon uses
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	URL    string `json:"html_url"`
+	Draft  bool   `json:"draft"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Head struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	// MergeableState is GitHub's verdict on merging right now: clean,
+	// behind, dirty (conflicts), blocked, unstable, draft, or unknown while
+	// GitHub is still computing it
+	MergeableState string `json:"mergeable_state"`
+}
+
+// GetPullRequest fetches pull request number in owner/repo
+func (c *Client) GetPullRequest(ctx context.Context, subsystem, owner, repo string, number int) (*PullRequest, error) {
+	var pr PullRequest
+	if err := c.Get(ctx, subsystem, fmt.Sprintf("/repos/%s/%s/pulls/%d", owner, repo, number), &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// FindPullRequest returns the open pull request from branch in owner/repo,
+// or nil if there is none. A branch in a fork is qualified with its owner,
+// as in "fork-owner:branch". The list endpoint leaves out mergeable_state, so
+// the pull request is fetched again in full.
+func (c *Client) FindPullRequest(ctx context.Context, subsystem, owner, repo, branch string) (*PullRequest, error) {
+	head := branch
+	if !strings.Contains(head, ":") {
+		head = owner + ":" + branch
+	}
+	var prs []PullRequest
+	path := fmt.Sprintf("/repos/%s/%s/pulls?state=open&head=%s", owner, repo, url.QueryEscape(head))
+	if err := c.Get(ctx, subsystem, path, &prs); err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return c.GetPullRequest(ctx, subsystem, owner, repo, prs[0].Number)
+}
+
+// HasLabel reports whether the pull request carries label
+func (pr *PullRequest) HasLabel(label string) bool {
+	for _, l := range pr.Labels {
+		if l.Name == label {
+			return true
+		}
+	}
+	return false
+}
+
+// ListLabeledPullRequests returns the open pull requests in owner/repo that
+// carry label, oldest first. Like FindPullRequest's list, these leave out
+// mergeable_state.
+func (c *Client) ListLabeledPullRequests(ctx context.Context, subsystem, owner, repo, label string) ([]PullRequest, error) {
+	var prs []PullRequest
+	path := fmt.Sprintf("/repos/%s/%s/pulls?state=open&sort=created&direction=asc&per_page=100", owner, repo)
+	if err := c.Get(ctx, subsystem, path, &prs); err != nil {
+		return nil, err
+	}
+	labeled := prs[:0]
+	for _, pr := range prs {
+		if pr.HasLabel(label) {
+			labeled = append(labeled, pr)
+		}
+	}
+	return labeled, nil
+}
+
+// MergePullRequest merges pull request number in owner/repo with method
+// (merge, squash, or rebase), only if its head is still sha. It returns
+// the merge commit.
+func (c *Client) MergePullRequest(ctx context.Context, subsystem, owner, repo string, number int, method, sha string) (string, error) {
+	var resp struct {
+		SHA     string `json:"sha"`
+		Merged  bool   `json:"merged"`
+		Message string `json:"message"`
+	}
+	body := map[string]string{"merge_method": method, "sha": sha}
+	if err := c.Put(ctx, subsystem, fmt.Sprintf("/repos/%s/%s/pulls/%d/merge", owner, repo, number), body, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Merged {
+		return "", fmt.Errorf("github: pull request #%d was not merged: %s", number, resp.Message)
+	}
+	return resp.SHA, nil
+}
+
+// RequiredChecks describes a branch's required status checks
+type RequiredChecks struct {
+	// Contexts are the names of the checks that must pass
+	Contexts []string
+	// Strict requires branches to be up to date with the base before merging
+	Strict bool
+}
+
+// GetRequiredChecks returns the required status checks of branch in
+// owner/repo. Unprotected branches have none. It reads the protection
+// summary on the branch, which unlike the protection endpoint doesn't
+// need admin access.
+func (c *Client) GetRequiredChecks(ctx context.Context, subsystem, owner, repo, branch string) (RequiredChecks, error) {
+	var resp struct {
+		Protection struct {
+			RequiredStatusChecks struct {
+				Contexts []string `json:"contexts"`
+				Checks   []struct {
+					Context string `json:"context"`
+				} `json:"checks"`
+				Strict bool `json:"strict"`
+			} `json:"required_status_checks"`
+		} `json:"protection"`
+	}
+	if err := c.Get(ctx, subsystem, fmt.Sprintf("/repos/%s/%s/branches/%s", owner, repo, url.PathEscape(branch)), &resp); err != nil {
+		return RequiredChecks{}, err
+	}
+
+	checks := resp.Protection.RequiredStatusChecks
+	seen := make(map[string]bool)
+	required := RequiredChecks{Strict: checks.Strict}
+	for _, name := range checks.Contexts {
+		if !seen[name] {
+			seen[name] = true
+			required.Contexts = append(required.Contexts, name)
+		}
+	}
+	for _, check := range checks.Checks {
+		if !seen[check.Context] {
+			seen[check.Context] = true
+			required.Contexts = append(required.Contexts, check.Context)
+		}
+	}
+	sort.Strings(required.Contexts)
+	return required, nil
+}
+
+// Check results, simplified from check run conclusions and commit status states
+const (
+	CheckPassed  = "passed"
+	CheckFailed  = "failed"
+	CheckPending = "pending"
+)
+
+// GetCheckResults returns the result of every check run and commit status
+// reported for sha, keyed by name
+func (c *Client) GetCheckResults(ctx context.Context, subsystem, owner, repo, sha string) (map[string]string, error) {
+	var runs struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		} `json:"check_runs"`
+	}
+	if err := c.Get(ctx, subsystem, fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs?per_page=100", owner, repo, sha), &runs); err != nil {
+		return nil, err
+	}
+	var statuses struct {
+		Statuses []struct {
+			Context string `json:"context"`
+			State   string `json:"state"`
+		} `json:"statuses"`
+	}
+	if err := c.Get(ctx, subsystem, fmt.Sprintf("/repos/%s/%s/commits/%s/status", owner, repo, sha), &statuses); err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]string)
+	for _, run := range runs.CheckRuns {
+		switch {
+		case run.Status != "completed":
+			results[run.Name] = CheckPending
+		case run.Conclusion == "success" || run.Conclusion == "neutral" || run.Conclusion == "skipped":
+			results[run.Name] = CheckPassed
+		default:
+			results[run.Name] = CheckFailed
+		}
+	}
+	for _, status := range statuses.Statuses {
+		switch status.State {
+		case "success":
+			results[status.Context] = CheckPassed
+		case "pending":
+			results[status.Context] = CheckPending
+		default:
+			results[status.Context] = CheckFailed
+		}
+	}
+	return results, nil
+}
+
+// NewPullRequest is the request body for opening a pull request
+type NewPullRequest struct {
+	Title string `json:"title"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+	Body  string `json:"body,omitempty"`
+	Draft bool   `json:"draft,omitempty"`
+}
+
+// CreatePullRequest opens a pull request in owner/repo
+func (c *Client) CreatePullRequest(ctx context.Context, subsystem, owner, repo string, pr NewPullRequest) (*PullRequest, error) {
+	var created PullRequest
+	if err := c.Post(ctx, subsystem, fmt.Sprintf("/repos/%s/%s/pulls", owner, repo), pr, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
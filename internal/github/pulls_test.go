@@ -0,0 +1,22 @@
+package github
+
+import "testing"
+
+func TestParseRepoURL(t *testing.T) {
+	for _, url := range []string{
+		"https://github.com/octo/widgets",
+		"https://github.com/octo/widgets.git",
+		"git@github.com:octo/widgets.git",
+		"ssh://git@github.com/octo/widgets",
+	} {
+		owner, name, err := ParseRepoURL(url)
+		if err != nil || owner != "octo" || name != "widgets" {
+			t.Errorf("ParseRepoURL(%q) = %q, %q, %v", url, owner, name, err)
+		}
+	}
+	for _, url := range []string{"", "https://github.com/octo", "/local/path/to/repo"} {
+		if _, _, err := ParseRepoURL(url); err == nil {
+			t.Errorf("ParseRepoURL(%q) should fail", url)
+		}
+	}
+}
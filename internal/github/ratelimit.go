@@ -0,0 +1,211 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrRateLimited is wrapped by errors for requests that were not sent, or
+// were refused by GitHub, because of rate limiting or backoff
+var ErrRateLimited = errors.New("rate limited")
+
+// DefaultRateLimit is the authenticated hourly limit budgets are computed
+// against until GitHub reports the real one
+const DefaultRateLimit = 5000
+
+const (
+	// minBackoff is the wait after the first failure; each further
+	// consecutive failure doubles it
+	minBackoff = time.Second
+	// maxBackoff caps the wait between retries
+	maxBackoff = 10 * time.Minute
+)
+
+// Stats describes the client's API usage, for daemon status
+type Stats struct {
+	// Requests is how many requests were sent to GitHub
+	Requests int64 `json:"requests"`
+	// CacheHits is how many calls were answered from a fresh cache entry
+	CacheHits int64 `json:"cache_hits"`
+	// NotModified is how many requests GitHub answered with 304, which
+	// don't count against the rate limit
+	NotModified int64 `json:"not_modified"`
+	// Stale is how many calls were answered from an outdated cache entry
+	// because of the budget, backoff, or an error
+	Stale int64 `json:"stale"`
+	// Throttled is how many calls failed because of the budget or backoff
+	// with nothing cached
+	Throttled int64 `json:"throttled"`
+	// Errors is how many requests failed with nothing cached
+	Errors int64 `json:"errors"`
+
+	// Limit, Remaining, and Reset are GitHub's view of the rate limit as
+	// of the last response
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+
+	// Failures counts consecutive failed requests; BackoffUntil is when
+	// requests will be sent again
+	Failures     int       `json:"consecutive_failures"`
+	BackoffUntil time.Time `json:"backoff_until"`
+
+	// Subsystems maps each subsystem to the requests it sent in the
+	// current rate limit window
+	Subsystems map[string]int `json:"subsystems,omitempty"`
+}
+
+// limiter tracks GitHub's rate limit, per-subsystem budgets, and backoff.
+// The client's mutex guards it.
+type limiter struct {
+	budgets map[string]float64
+	used    map[string]int
+	window  time.Time // Reset time the used counts belong to
+
+	backoffUntil time.Time
+	stats        Stats
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		budgets: make(map[string]float64),
+		used:    make(map[string]int),
+	}
+}
+
+// SetBudget caps the share (0-1] of the hourly rate limit subsystem may
+// spend. Subsystems without a budget are only stopped by GitHub's own
+// limit. A share of 0 removes the budget.
+func (c *Client) SetBudget(subsystem string, share float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if share <= 0 {
+		delete(c.limiter.budgets, subsystem)
+		return
+	}
+	c.limiter.budgets[subsystem] = share
+}
+
+// Stats returns a snapshot of the client's API usage
+func (c *Client) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.limiter.stats
+	stats.BackoffUntil = c.limiter.backoffUntil
+	stats.Subsystems = make(map[string]int, len(c.limiter.used))
+	for name, n := range c.limiter.used {
+		stats.Subsystems[name] = n
+	}
+	return stats
+}
+
+// admit decides whether subsystem may send a request now, charging it if
+// so
+func (l *limiter) admit(subsystem string, now time.Time) error {
+	if now.Before(l.backoffUntil) {
+		return fmt.Errorf("github: backing off until %s: %w", l.backoffUntil.Format(time.Kitchen), ErrRateLimited)
+	}
+	if !l.stats.Reset.IsZero() && !now.Before(l.stats.Reset) {
+		// The window GitHub reported has ended
+		l.newWindow(time.Time{})
+		l.stats.Remaining = l.stats.Limit
+		l.stats.Reset = time.Time{}
+	}
+	if l.stats.Limit > 0 && l.stats.Remaining <= 0 {
+		return fmt.Errorf("github: rate limit exhausted until %s: %w", l.stats.Reset.Format(time.Kitchen), ErrRateLimited)
+	}
+	if share, ok := l.budgets[subsystem]; ok {
+		limit := l.stats.Limit
+		if limit == 0 {
+			limit = DefaultRateLimit
+		}
+		if allowed := int(share * float64(limit)); l.used[subsystem] >= allowed {
+			return fmt.Errorf("github: %s used its budget of %d requests this hour: %w", subsystem, allowed, ErrRateLimited)
+		}
+	}
+	l.used[subsystem]++
+	l.stats.Requests++
+	return nil
+}
+
+// refund returns a request's charge, for responses GitHub doesn't count
+func (l *limiter) refund(subsystem string) {
+	if l.used[subsystem] > 0 {
+		l.used[subsystem]--
+	}
+}
+
+// observe records the rate limit headers of a response
+func (l *limiter) observe(h http.Header, now time.Time) {
+	if limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit")); err == nil {
+		l.stats.Limit = limit
+	}
+	if remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining")); err == nil {
+		l.stats.Remaining = remaining
+	}
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		resetAt := time.Unix(reset, 0)
+		switch {
+		case l.window.IsZero():
+			// Requests so far were made in this window
+			l.window = resetAt
+		case !resetAt.Equal(l.window):
+			l.newWindow(resetAt)
+		}
+		l.stats.Reset = resetAt
+	}
+}
+
+// newWindow starts counting subsystem usage afresh
+func (l *limiter) newWindow(reset time.Time) {
+	l.window = reset
+	l.used = make(map[string]int)
+}
+
+// fail records a failed request and backs off for wait, or exponentially
+// if wait is zero
+func (l *limiter) fail(now time.Time, wait time.Duration) {
+	l.stats.Failures++
+	if wait <= 0 {
+		wait = minBackoff << (l.stats.Failures - 1)
+		if wait > maxBackoff || wait <= 0 {
+			wait = maxBackoff
+		}
+	}
+	l.backoffUntil = now.Add(wait)
+}
+
+// succeed clears the backoff after GitHub answers normally
+func (l *limiter) succeed() {
+	l.stats.Failures = 0
+	l.backoffUntil = time.Time{}
+}
+
+// isRateLimited reports whether GitHub refused a request because of its
+// primary or secondary rate limit
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode == http.StatusForbidden &&
+		(resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "")
+}
+
+// retryAfter returns how long GitHub asked to wait, or zero if it didn't
+// say
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	if secs, err := strconv.Atoi(h.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			if wait := time.Unix(reset, 0).Sub(now); wait > 0 {
+				return wait
+			}
+		}
+	}
+	return 0
+}
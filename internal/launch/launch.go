@@ -0,0 +1,225 @@
+// Package launch customizes how Claude Code is started for each agent.
+//
+// By default agents run `claude --session-id ... --dangerously-skip-permissions
+// --append-system-prompt-file ...` in their worktree. A repository can change
+// that in .multiclaude/launch.json, per agent type, to run Claude through a
+// wrapper (`aws-vault exec dev --`, `direnv exec .`, a container shim), with a
+// different binary or extra flags, from a subdirectory, or after a shell
+// snippet. Templates are validated before an agent is started so a typo fails
+// the spawn instead of leaving a dead tmux window.
+package launch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+// ConfigFile is the launch template file name inside a repository's .multiclaude directory
+const ConfigFile = "launch.json"
+
+// Template describes how to start Claude. Empty fields keep the default.
+type Template struct {
+	// Binary is the claude executable (a name on PATH or an absolute path)
+	Binary string `json:"binary,omitempty"`
+	// Wrapper is a command that runs Claude, e.g. ["aws-vault", "exec", "dev", "--"]
+	Wrapper []string `json:"wrapper,omitempty"`
+	// Args are extra flags appended after the ones multiclaude passes
+	Args []string `json:"args,omitempty"`
+	// WorkDir is a directory relative to the agent's worktree to start in
+	WorkDir string `json:"workdir,omitempty"`
+	// PreLaunch is a shell snippet run before Claude; Claude only starts if it succeeds
+	PreLaunch string `json:"pre_launch,omitempty"`
+}
+
+// Config is the contents of launch.json
+type Config struct {
+	// Default applies to every agent type
+	Default Template `json:"default"`
+	// AgentTypes overrides the default per agent type ("worker", "supervisor",
+	// "merge-queue", "workspace", "review")
+	AgentTypes map[string]Template `json:"agent_types,omitempty"`
+}
+
+// LoadConfig reads launch templates from a repository checkout. A missing
+// file is not an error and yields an empty config.
+func LoadConfig(repoPath string) (*Config, error) {
+	configPath := filepath.Join(repoPath, ".multiclaude", ConfigFile)
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read launch templates: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+	return &config, nil
+}
+
+// Resolve returns the template for an agent type: the default with the
+// type's non-empty fields layered on top
+func (c *Config) Resolve(agentType string) Template {
+	t := c.Default
+	override, ok := c.AgentTypes[agentType]
+	if !ok {
+		return t
+	}
+	if override.Binary != "" {
+		t.Binary = override.Binary
+	}
+	if override.Wrapper != nil {
+		t.Wrapper = override.Wrapper
+	}
+	if override.Args != nil {
+		t.Args = override.Args
+	}
+	if override.WorkDir != "" {
+		t.WorkDir = override.WorkDir
+	}
+	if override.PreLaunch != "" {
+		t.PreLaunch = override.PreLaunch
+	}
+	return t
+}
+
+// Load reads a repository's launch config and resolves the template for an
+// agent type
+func Load(repoPath, agentType string) (Template, error) {
+	config, err := LoadConfig(repoPath)
+	if err != nil {
+		return Template{}, err
+	}
+	return config.Resolve(agentType), nil
+}
+
+// IsZero reports whether the template changes nothing
+func (t Template) IsZero() bool {
+	return t.Binary == "" && len(t.Wrapper) == 0 && len(t.Args) == 0 && t.WorkDir == "" && t.PreLaunch == ""
+}
+
+// Validate checks that the binary and wrapper can be found, the working
+// directory stays inside the worktree (and exists, if worktree is set), and
+// the pre-launch snippet parses
+func (t Template) Validate(worktree string) error {
+	if t.Binary != "" {
+		if err := findExecutable(t.Binary); err != nil {
+			return fmt.Errorf("launch binary: %w", err)
+		}
+	}
+	if len(t.Wrapper) > 0 {
+		if err := findExecutable(t.Wrapper[0]); err != nil {
+			return fmt.Errorf("launch wrapper: %w", err)
+		}
+	}
+	if t.WorkDir != "" {
+		cleaned := path.Clean(filepath.ToSlash(t.WorkDir))
+		if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return fmt.Errorf("launch workdir %q must be relative to the worktree", t.WorkDir)
+		}
+		if worktree != "" {
+			if info, err := os.Stat(t.dir(worktree)); err != nil || !info.IsDir() {
+				return fmt.Errorf("launch workdir %q does not exist in %s", t.WorkDir, worktree)
+			}
+		}
+	}
+	if t.PreLaunch != "" {
+		if output, err := exec.Command("sh", "-n", "-c", t.PreLaunch).CombinedOutput(); err != nil {
+			return fmt.Errorf("launch pre_launch snippet has a syntax error: %s", strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// findExecutable resolves name on PATH, or checks it directly if it is a path
+func findExecutable(name string) error {
+	if strings.Contains(name, "/") {
+		info, err := os.Stat(name)
+		if err != nil {
+			return fmt.Errorf("%s not found", name)
+		}
+		if info.IsDir() || info.Mode()&0111 == 0 {
+			return fmt.Errorf("%s is not executable", name)
+		}
+		return nil
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s not found in PATH", name)
+	}
+	return nil
+}
+
+// dir returns the directory Claude starts in
+func (t Template) dir(worktree string) string {
+	return filepath.Join(worktree, filepath.FromSlash(t.WorkDir))
+}
+
+// prefix returns the pre-launch snippet and wrapper that precede the binary
+func (t Template) prefix() string {
+	var prefix string
+	if t.PreLaunch != "" {
+		prefix = "{ " + t.PreLaunch + "; } && "
+	}
+	if len(t.Wrapper) > 0 {
+		prefix += quoteArgs(t.Wrapper) + " "
+	}
+	return prefix
+}
+
+// Command returns the shell command that starts Claude under the template.
+// flags are the arguments multiclaude passes (session, permissions, prompt);
+// defaultBinary is used unless the template names a binary.
+func (t Template) Command(defaultBinary, worktree, flags string) string {
+	var cmd string
+	if t.WorkDir != "" {
+		cmd = fmt.Sprintf("cd %s && ", shellQuote(t.dir(worktree)))
+	}
+	binary := defaultBinary
+	if t.Binary != "" {
+		binary = t.Binary
+	}
+	cmd += t.prefix() + binary
+	if flags != "" {
+		cmd += " " + flags
+	}
+	if len(t.Args) > 0 {
+		cmd += " " + quoteArgs(t.Args)
+	}
+	return cmd
+}
+
+// Apply sets the template's fields on a claude.Runner config
+func (t Template) Apply(cfg *claude.Config, worktree string) {
+	if t.WorkDir != "" {
+		cfg.WorkDir = t.dir(worktree)
+	}
+	cfg.BinaryPath = t.Binary
+	cfg.CommandPrefix = t.prefix()
+	cfg.ExtraArgs = t.Args
+}
+
+// quoteArgs shell-quotes each argument that needs it and joins them
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes s for a POSIX shell unless it only contains safe characters
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=./:@,+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,109 @@
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+func TestLoadConfig(t *testing.T) {
+	repo := t.TempDir()
+
+	template, err := Load(repo, "worker")
+	if err != nil || !template.IsZero() {
+		t.Fatalf("Load without config = %+v, %v", template, err)
+	}
+
+	dir := filepath.Join(repo, ".multiclaude")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `{
+		"default": {"wrapper": ["direnv", "exec", "."], "args": ["--model", "sonnet"]},
+		"agent_types": {"worker": {"args": ["--model", "opus"], "pre_launch": "make deps"}}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, ConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	template, err = Load(repo, "worker")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := Template{Wrapper: []string{"direnv", "exec", "."}, Args: []string{"--model", "opus"}, PreLaunch: "make deps"}
+	if !reflect.DeepEqual(template, want) {
+		t.Errorf("worker template = %+v, want %+v", template, want)
+	}
+
+	template, _ = Load(repo, "supervisor")
+	if !reflect.DeepEqual(template.Args, []string{"--model", "sonnet"}) || template.PreLaunch != "" {
+		t.Errorf("supervisor template = %+v, want the default", template)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ConfigFile), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(repo, "worker"); err == nil {
+		t.Error("Load should fail on malformed JSON")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	worktree := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(worktree, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := Template{Binary: "sh", Wrapper: []string{"env", "FOO=1"}, WorkDir: "app", PreLaunch: "test -d . && echo ok"}
+	if err := valid.Validate(worktree); err != nil {
+		t.Errorf("Validate(%+v) failed: %v", valid, err)
+	}
+
+	invalid := []Template{
+		{Binary: "definitely-not-a-claude-binary"},
+		{Binary: filepath.Join(worktree, "app")},
+		{Wrapper: []string{"no-such-wrapper", "exec"}},
+		{WorkDir: "../outside"},
+		{WorkDir: "/abs"},
+		{WorkDir: "missing"},
+		{PreLaunch: "if then"},
+	}
+	for _, tmpl := range invalid {
+		if err := tmpl.Validate(worktree); err == nil {
+			t.Errorf("Validate(%+v) should fail", tmpl)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	flags := "--session-id abc --dangerously-skip-permissions"
+
+	if got := (Template{}).Command("claude", "/wt", flags); got != "claude "+flags {
+		t.Errorf("empty template Command() = %q", got)
+	}
+
+	tmpl := Template{
+		Binary:    "/opt/claude",
+		Wrapper:   []string{"aws-vault", "exec", "dev", "--"},
+		Args:      []string{"--model", "opus", "a b"},
+		WorkDir:   "app",
+		PreLaunch: "source .env",
+	}
+	want := "cd /wt/app && { source .env; } && aws-vault exec dev -- /opt/claude " + flags + " --model opus 'a b'"
+	if got := tmpl.Command("claude", "/wt", flags); got != want {
+		t.Errorf("Command() = %q, want %q", got, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tmpl := Template{Wrapper: []string{"direnv", "exec", "."}, Args: []string{"--verbose"}, WorkDir: "app"}
+	cfg := claude.Config{SessionID: "abc"}
+	tmpl.Apply(&cfg, "/wt")
+
+	if cfg.WorkDir != "/wt/app" || cfg.CommandPrefix != "direnv exec . " || !reflect.DeepEqual(cfg.ExtraArgs, []string{"--verbose"}) {
+		t.Errorf("Apply() = %+v", cfg)
+	}
+}
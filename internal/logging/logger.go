@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 	"sync"
 )
 
@@ -62,6 +63,34 @@ func (l *Logger) log(level, format string, args ...interface{}) {
 	l.logger.Printf("[%s] %s", level, msg)
 }
 
+// InfoWriter returns a writer that logs each line written to it at INFO
+// level, e.g. to log a subprocess's progress output. Carriage returns end a
+// line too, so progress meters log each update.
+func (l *Logger) InfoWriter() io.Writer {
+	return &lineWriter{logf: l.Info}
+}
+
+// lineWriter calls logf for each complete, non-empty line written to it
+type lineWriter struct {
+	logf    func(format string, args ...interface{})
+	partial string
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	lines := strings.FieldsFunc(w.partial+string(p), func(r rune) bool { return r == '\n' || r == '\r' })
+	w.partial = ""
+	if n := len(p); n > 0 && p[n-1] != '\n' && p[n-1] != '\r' && len(lines) > 0 {
+		w.partial = lines[len(lines)-1]
+		lines = lines[:len(lines)-1]
+	}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			w.logf("%s", line)
+		}
+	}
+	return len(p), nil
+}
+
 // Close closes the logger (if backed by a file)
 func (l *Logger) Close() error {
 	if f, ok := l.writer.(*os.File); ok {
@@ -189,3 +189,20 @@ func TestLoggerThreadSafety(t *testing.T) {
 		t.Errorf("Expected 1000 log lines, got %d", len(lines))
 	}
 }
+
+func TestLoggerInfoWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := New(buf).InfoWriter()
+
+	w.Write([]byte("Downloading 1 of 3\rDownloading 2 of 3\r"))
+	w.Write([]byte("Downloading 3"))
+	w.Write([]byte(" of 3\n\n"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want 3:\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[2], "[INFO] Downloading 3 of 3") {
+		t.Errorf("a line split across writes should be logged whole, got %q", lines[2])
+	}
+}
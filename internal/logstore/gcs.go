@@ -0,0 +1,227 @@
+package logstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gcsTokenLifetime is how long a token from gcloud is reused. gcloud hands
+// out tokens valid for an hour.
+const gcsTokenLifetime = 45 * time.Minute
+
+// gcsMetadataTokenURL is where GCE, GKE, and Cloud Run hand out the
+// service account's token
+const gcsMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCSStore keeps segments in a Google Cloud Storage bucket through the JSON
+// API. It authenticates with GOOGLE_OAUTH_ACCESS_TOKEN if set, otherwise
+// with `gcloud auth print-access-token`, otherwise with the metadata
+// server's service account.
+type GCSStore struct {
+	cfg    Config
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newGCSStore(cfg Config) *GCSStore {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://storage.googleapis.com"
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	return &GCSStore{cfg: cfg, client: http.DefaultClient}
+}
+
+// objectURL returns the JSON API URL of key
+func (s *GCSStore) objectURL(key string) string {
+	return fmt.Sprintf("%s/storage/v1/b/%s/o/%s", s.cfg.Endpoint, url.PathEscape(s.cfg.Bucket), url.PathEscape(withPrefix(s.cfg.Prefix, key)))
+}
+
+// Put uploads key with a single streaming media upload
+func (s *GCSStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", s.cfg.Endpoint, url.PathEscape(s.cfg.Bucket),
+		url.Values{"uploadType": {"media"}, "name": {withPrefix(s.cfg.Prefix, key)}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, io.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return statusError("gcs put", key, resp.Status, resp.Body)
+	}
+	return nil
+}
+
+// Open downloads key
+func (s *GCSStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key)+"?alt=media", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("gcs get %s: %w", key, os.ErrNotExist)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, statusError("gcs get", key, resp.Status, resp.Body)
+	}
+	return resp.Body, nil
+}
+
+// gcsObjectList is the part of an objects.list response we use
+type gcsObjectList struct {
+	Items []struct {
+		Name    string    `json:"name"`
+		Size    string    `json:"size"` // int64 as a JSON string
+		Updated time.Time `json:"updated"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// List pages through objects.list
+func (s *GCSStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		q := url.Values{"prefix": {withPrefix(s.cfg.Prefix, prefix)}, "fields": {"items(name,size,updated),nextPageToken"}}
+		if token != "" {
+			q.Set("pageToken", token)
+		}
+		u := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", s.cfg.Endpoint, url.PathEscape(s.cfg.Bucket), q.Encode())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode/100 != 2 {
+			defer resp.Body.Close()
+			return nil, statusError("gcs list", prefix, resp.Status, resp.Body)
+		}
+		var result gcsObjectList
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("gcs list %s: failed to parse response: %w", prefix, err)
+		}
+
+		for _, item := range result.Items {
+			size, _ := strconv.ParseInt(item.Size, 10, 64)
+			objects = append(objects, Object{Key: trimPrefix(s.cfg.Prefix, item.Name), Size: size, Modified: item.Updated})
+		}
+		if result.NextPageToken == "" {
+			break
+		}
+		token = result.NextPageToken
+	}
+	return objects, nil
+}
+
+// Delete removes key
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return statusError("gcs delete", key, resp.Status, resp.Body)
+	}
+	return nil
+}
+
+// do authenticates and sends a request
+func (s *GCSStore) do(req *http.Request) (*http.Response, error) {
+	token, err := s.accessToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return s.client.Do(req)
+}
+
+// accessToken returns an OAuth token for the storage API, reusing it until
+// it is about to expire
+func (s *GCSStore) accessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+
+	if out, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output(); err == nil {
+		if token := strings.TrimSpace(string(out)); token != "" {
+			s.token, s.expires = token, time.Now().Add(gcsTokenLifetime)
+			return s.token, nil
+		}
+	}
+
+	token, lifetime, err := metadataToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("gcs log storage needs GOOGLE_OAUTH_ACCESS_TOKEN, a logged-in gcloud, or a GCE service account: %w", err)
+	}
+	s.token, s.expires = token, time.Now().Add(lifetime*3/4)
+	return s.token, nil
+}
+
+// metadataToken asks the metadata server for the service account's token
+func metadataToken(ctx context.Context) (string, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcsMetadataTokenURL, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, statusError("metadata", "token", resp.Status, resp.Body)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", 0, err
+	}
+	return body.AccessToken, time.Duration(body.ExpiresIn) * time.Second, nil
+}
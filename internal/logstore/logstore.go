@@ -0,0 +1,196 @@
+// Package logstore keeps rotated agent output logs ("segments") in local
+// storage, Amazon S3, or Google Cloud Storage. The live log of an agent is
+// always a local file tmux pipes into; once the daemon rotates it, the
+// segment can be moved to a bucket so long-running installations don't fill
+// their disk. Keys mirror paths under the output directory, e.g.
+// "my-repo/workers/swift-fox.log.20260102-150405".
+package logstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backend names
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+	BackendGCS   = "gcs"
+)
+
+// SegmentTimeFormat is the timestamp suffix of rotated logs
+const SegmentTimeFormat = "20060102-150405"
+
+// segmentPattern matches the keys of rotated logs
+var segmentPattern = regexp.MustCompile(`\.log\.\d{8}-\d{6}$`)
+
+// Config selects where log segments are kept. The zero value keeps them in
+// the local output directory forever.
+type Config struct {
+	Backend       string `json:"backend,omitempty"`        // local (default), s3, or gcs
+	Bucket        string `json:"bucket,omitempty"`         // Bucket name for s3 and gcs
+	Prefix        string `json:"prefix,omitempty"`         // Key prefix inside the bucket
+	Region        string `json:"region,omitempty"`         // S3 region (default: $AWS_REGION or us-east-1)
+	Endpoint      string `json:"endpoint,omitempty"`       // Custom endpoint, e.g. MinIO or a GCS emulator
+	RetentionDays int    `json:"retention_days,omitempty"` // Delete segments older than this (0: keep forever)
+}
+
+// Remote returns true if segments are moved off the local disk
+func (c Config) Remote() bool {
+	return c.Backend == BackendS3 || c.Backend == BackendGCS
+}
+
+// Validate checks that the config names a known backend with what it needs
+func (c Config) Validate() error {
+	switch c.Backend {
+	case "", BackendLocal:
+	case BackendS3, BackendGCS:
+		if c.Bucket == "" {
+			return fmt.Errorf("%s log storage needs a bucket", c.Backend)
+		}
+	default:
+		return fmt.Errorf("unknown log storage backend %q (use local, s3, or gcs)", c.Backend)
+	}
+	if c.RetentionDays < 0 {
+		return fmt.Errorf("retention must not be negative")
+	}
+	return nil
+}
+
+// String describes where segments are kept, e.g. "s3://logs/multiclaude"
+func (c Config) String() string {
+	if !c.Remote() {
+		return BackendLocal
+	}
+	scheme := c.Backend
+	if scheme == BackendGCS {
+		scheme = "gs"
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%s://%s/%s", scheme, c.Bucket, c.Prefix), "/")
+}
+
+// Object describes a stored segment
+type Object struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+}
+
+// Store reads and writes log segments
+type Store interface {
+	// Put streams size bytes from r to key
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open returns a reader for key; the error wraps os.ErrNotExist if
+	// there is no such key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns the objects whose key starts with prefix, sorted by key
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Delete removes key
+	Delete(ctx context.Context, key string) error
+}
+
+// Open returns the store cfg describes. Local segments live in outputDir.
+func Open(cfg Config, outputDir string) (Store, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Backend {
+	case BackendS3:
+		return newS3Store(cfg)
+	case BackendGCS:
+		return newGCSStore(cfg), nil
+	default:
+		return NewLocalStore(outputDir), nil
+	}
+}
+
+// IsSegment returns true if key names a rotated log
+func IsSegment(key string) bool {
+	return segmentPattern.MatchString(key)
+}
+
+// SegmentKey returns the key of the segment rotated from the log at rel (a
+// slash-separated path relative to the output directory) at time t
+func SegmentKey(rel string, t time.Time) string {
+	return rel + "." + t.Format(SegmentTimeFormat)
+}
+
+// AgentPrefix returns the key prefix shared by an agent's segments
+func AgentPrefix(repoName, agentName string, isWorker bool) string {
+	if isWorker {
+		return path.Join(repoName, "workers", agentName) + ".log."
+	}
+	return path.Join(repoName, agentName) + ".log."
+}
+
+// Segments lists the segments under prefix, oldest first
+func Segments(ctx context.Context, store Store, prefix string) ([]Object, error) {
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	segments := objects[:0]
+	for _, obj := range objects {
+		if IsSegment(obj.Key) {
+			segments = append(segments, obj)
+		}
+	}
+	// The timestamp suffix sorts chronologically
+	sort.Slice(segments, func(i, j int) bool { return segments[i].Key < segments[j].Key })
+	return segments, nil
+}
+
+// Prune deletes the segments last modified before cutoff, returning how many
+// were deleted and their total size
+func Prune(ctx context.Context, store Store, cutoff time.Time) (int, int64, error) {
+	segments, err := Segments(ctx, store, "")
+	if err != nil {
+		return 0, 0, err
+	}
+	var count int
+	var size int64
+	for _, obj := range segments {
+		if !obj.Modified.Before(cutoff) {
+			continue
+		}
+		if err := store.Delete(ctx, obj.Key); err != nil {
+			return count, size, err
+		}
+		count++
+		size += obj.Size
+	}
+	return count, size, nil
+}
+
+// withPrefix joins a configured key prefix and a key
+func withPrefix(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + key
+}
+
+// trimPrefix undoes withPrefix
+func trimPrefix(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// statusError builds the error for an unexpected HTTP response, including
+// the start of its body
+func statusError(op, key string, status string, body io.Reader) error {
+	snippet, _ := io.ReadAll(io.LimitReader(body, 512))
+	msg := strings.TrimSpace(string(snippet))
+	if msg == "" {
+		return fmt.Errorf("%s %s: %s", op, key, status)
+	}
+	return fmt.Errorf("%s %s: %s: %s", op, key, status, msg)
+}
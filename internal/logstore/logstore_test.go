@@ -0,0 +1,272 @@
+package logstore
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBucket is an in-memory bucket shared by the fake S3 and GCS servers
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func newFakeBucket() *fakeBucket {
+	return &fakeBucket{objects: make(map[string]string)}
+}
+
+// keys lists the keys under prefix; callers hold mu while serving
+func (b *fakeBucket) keys(prefix string) []string {
+	var keys []string
+	for k := range b.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fakeS3 serves path-style S3 requests for bucket "logs"
+func fakeS3(t *testing.T, b *fakeBucket) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Errorf("%s %s not signed: %q", r.Method, r.URL, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/logs/")
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			keys := b.keys(r.URL.Query().Get("prefix"))
+			var result struct {
+				XMLName  xml.Name `xml:"ListBucketResult"`
+				Contents []struct {
+					Key          string
+					LastModified time.Time
+					Size         int
+				}
+			}
+			for _, k := range keys {
+				result.Contents = append(result.Contents, struct {
+					Key          string
+					LastModified time.Time
+					Size         int
+				}{k, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), len(b.objects[k])})
+			}
+			xml.NewEncoder(w).Encode(result)
+		case r.Method == http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			b.objects[key] = string(data)
+		case r.Method == http.MethodGet:
+			data, ok := b.objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, data)
+		case r.Method == http.MethodDelete:
+			delete(b.objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+// fakeGCS serves JSON API requests for bucket "logs"
+func fakeGCS(t *testing.T, b *fakeBucket) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("%s %s without token", r.Method, r.URL)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		key := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/logs/o/")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/logs/o":
+			data, _ := io.ReadAll(r.Body)
+			b.objects[r.URL.Query().Get("name")] = string(data)
+		case r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/logs/o":
+			keys := b.keys(r.URL.Query().Get("prefix"))
+			items := []map[string]string{}
+			for _, k := range keys {
+				items = append(items, map[string]string{"name": k, "size": "3", "updated": "2026-01-02T00:00:00Z"})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+		case r.Method == http.MethodGet:
+			data, ok := b.objects[key]
+			if !ok || r.URL.Query().Get("alt") != "media" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, data)
+		case r.Method == http.MethodDelete:
+			delete(b.objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+// exerciseStore runs the operations the daemon and CLI rely on
+func exerciseStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, key := range []string{
+		"repo/workers/swift-fox.log.20260102-150405",
+		"repo/workers/swift-fox.log.20260101-090000",
+		"repo/workers/swift-fox-2.log.20260101-090000",
+		"repo/supervisor.log.20260101-090000",
+	} {
+		if err := store.Put(ctx, key, strings.NewReader("abc"), 3); err != nil {
+			t.Fatalf("Put(%s) failed: %v", key, err)
+		}
+	}
+
+	segments, err := Segments(ctx, store, AgentPrefix("repo", "swift-fox", true))
+	if err != nil {
+		t.Fatalf("Segments failed: %v", err)
+	}
+	if len(segments) != 2 || segments[0].Key != "repo/workers/swift-fox.log.20260101-090000" {
+		t.Fatalf("Segments = %+v, want swift-fox's two segments oldest first", segments)
+	}
+
+	r, err := store.Open(ctx, segments[1].Key)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "abc" {
+		t.Errorf("Open read %q, want abc", data)
+	}
+	if _, err := store.Open(ctx, "repo/missing.log.20260101-090000"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open of a missing key = %v, want os.ErrNotExist", err)
+	}
+
+	if err := store.Delete(ctx, segments[0].Key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	all, err := Segments(ctx, store, "repo/")
+	if err != nil {
+		t.Fatalf("Segments failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("after Delete, Segments = %+v, want 3", all)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStore(dir)
+	exerciseStore(t, store)
+
+	// Live logs share the directory but aren't segments
+	os.WriteFile(filepath.Join(dir, "repo", "supervisor.log"), []byte("live"), 0644)
+	segments, err := Segments(context.Background(), store, "repo/supervisor.log")
+	if err != nil || len(segments) != 1 {
+		t.Errorf("Segments = %+v, %v; want only the rotated supervisor log", segments, err)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	bucket := newFakeBucket()
+	server := fakeS3(t, bucket)
+	defer server.Close()
+
+	store, err := Open(Config{Backend: BackendS3, Bucket: "logs", Prefix: "mc", Endpoint: server.URL}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	exerciseStore(t, store)
+
+	if keys := bucket.keys(""); len(keys) != 3 || !strings.HasPrefix(keys[0], "mc/repo/") {
+		t.Errorf("bucket keys = %v, want 3 under mc/", keys)
+	}
+}
+
+func TestS3StoreNeedsCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := Open(Config{Backend: BackendS3, Bucket: "logs"}, t.TempDir()); err == nil {
+		t.Error("Open should fail without AWS credentials")
+	}
+}
+
+func TestGCSStore(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "test-token")
+	bucket := newFakeBucket()
+	server := fakeGCS(t, bucket)
+	defer server.Close()
+
+	store, err := Open(Config{Backend: BackendGCS, Bucket: "logs", Endpoint: server.URL}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	exerciseStore(t, store)
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStore(dir)
+	ctx := context.Background()
+	store.Put(ctx, "repo/old.log.20250101-000000", strings.NewReader("old"), 3)
+	store.Put(ctx, "repo/new.log.20260101-000000", strings.NewReader("new"), 3)
+	store.Put(ctx, "repo/live.log", strings.NewReader("live"), 4)
+
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	for _, name := range []string{"old.log.20250101-000000", "live.log"} {
+		os.Chtimes(filepath.Join(dir, "repo", name), old, old)
+	}
+
+	count, size, err := Prune(ctx, store, time.Now().Add(-30*24*time.Hour))
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if count != 1 || size != 3 {
+		t.Errorf("Prune = %d, %d; want the one old segment", count, size)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "repo", "live.log")); err != nil {
+		t.Error("Prune must not delete live logs")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		cfg     Config
+		wantErr bool
+	}{
+		{Config{}, false},
+		{Config{Backend: BackendLocal, RetentionDays: 7}, false},
+		{Config{Backend: BackendS3, Bucket: "logs"}, false},
+		{Config{Backend: BackendGCS}, true},
+		{Config{Backend: "azure", Bucket: "logs"}, true},
+		{Config{RetentionDays: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) = %v, wantErr %v", tt.cfg, err, tt.wantErr)
+		}
+	}
+
+	if got := (Config{Backend: BackendGCS, Bucket: "logs", Prefix: "mc/"}).String(); got != "gs://logs/mc" {
+		t.Errorf("String() = %q, want gs://logs/mc", got)
+	}
+}
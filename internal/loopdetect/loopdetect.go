@@ -0,0 +1,148 @@
+// Package loopdetect finds repeated blocks of output that indicate an agent is
+// stuck re-running the same command, as opposed to simply being idle.
+package loopdetect
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Config controls what counts as a loop
+type Config struct {
+	// MaxBlockLines is the largest block (in lines) considered for repetition
+	MaxBlockLines int
+	// MinRepeats is how many consecutive copies of a block make a loop
+	MinRepeats int
+	// MinBlockChars ignores blocks with less content than this (spinners, prompts)
+	MinBlockChars int
+	// MaxLines limits how many trailing lines are examined
+	MaxLines int
+}
+
+// DefaultConfig returns the default detection settings
+func DefaultConfig() Config {
+	return Config{
+		MaxBlockLines: 20,
+		MinRepeats:    4,
+		MinBlockChars: 40,
+		MaxLines:      2000,
+	}
+}
+
+// Loop describes a detected repetition
+type Loop struct {
+	Snippet    string // The repeated block
+	BlockLines int    // Number of lines in the block
+	Repeats    int    // Consecutive copies observed
+}
+
+// ansiPattern matches terminal escape sequences (CSI and OSC)
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[()][0-9A-Za-z]`)
+
+// normalize strips escape sequences and blank lines so that redraws of the
+// same content compare equal
+func normalize(text string) []string {
+	text = ansiPattern.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		// Carriage returns overwrite the line; keep the final rendering
+		if idx := strings.LastIndex(line, "\r"); idx >= 0 {
+			line = line[idx+1:]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// blocksEqual reports whether lines[a:a+k] equals lines[b:b+k]
+func blocksEqual(lines []string, a, b, k int) bool {
+	for i := 0; i < k; i++ {
+		if lines[a+i] != lines[b+i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isPrimitive reports whether lines[i:i+k] is not itself a repetition of a
+// shorter block, so that loops are reported at their natural period
+func isPrimitive(lines []string, i, k int) bool {
+	for d := 1; d < k; d++ {
+		if k%d != 0 {
+			continue
+		}
+		periodic := true
+		for j := i + d; j < i+k; j += d {
+			if !blocksEqual(lines, i, j, d) {
+				periodic = false
+				break
+			}
+		}
+		if periodic {
+			return false
+		}
+	}
+	return true
+}
+
+// Detect looks for the most repeated block of consecutive lines in text.
+// It returns the loop and true if some block repeats at least MinRepeats times.
+func Detect(text string, cfg Config) (Loop, bool) {
+	lines := normalize(text)
+	if cfg.MaxLines > 0 && len(lines) > cfg.MaxLines {
+		lines = lines[len(lines)-cfg.MaxLines:]
+	}
+
+	var best Loop
+	n := len(lines)
+	for k := 1; k <= cfg.MaxBlockLines && k*cfg.MinRepeats <= n; k++ {
+		for i := 0; i+k <= n; {
+			repeats := 1
+			for j := i + k; j+k <= n && blocksEqual(lines, i, j, k); j += k {
+				repeats++
+			}
+
+			if repeats >= cfg.MinRepeats && repeats > best.Repeats && isPrimitive(lines, i, k) {
+				block := strings.Join(lines[i:i+k], "\n")
+				if len(block) >= cfg.MinBlockChars {
+					best = Loop{Snippet: block, BlockLines: k, Repeats: repeats}
+				}
+			}
+
+			if repeats > 1 {
+				i += (repeats - 1) * k
+			} else {
+				i++
+			}
+		}
+	}
+
+	return best, best.Repeats > 0
+}
+
+// LastLine returns the last non-blank line of text as it would render,
+// without escape sequences
+func LastLine(text string) string {
+	lines := normalize(text)
+	if len(lines) == 0 {
+		return ""
+	}
+	return lines[len(lines)-1]
+}
+
+// Plain returns a captured line as it rendered on screen: without escape
+// sequences, and without text a carriage return overwrote
+func Plain(line string) string {
+	line = ansiPattern.ReplaceAllString(line, "")
+	line = strings.TrimSuffix(line, "\r")
+	if idx := strings.LastIndex(line, "\r"); idx >= 0 {
+		line = line[idx+1:]
+	}
+	return line
+}
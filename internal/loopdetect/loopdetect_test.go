@@ -0,0 +1,102 @@
+package loopdetect
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	failing := "$ go test ./...\n--- FAIL: TestSomething (0.00s)\n    foo_test.go:12: expected 1, got 2\nFAIL\n"
+
+	tests := []struct {
+		name       string
+		text       string
+		wantLoop   bool
+		wantLines  int
+		minRepeats int
+	}{
+		{
+			name:     "empty",
+			text:     "",
+			wantLoop: false,
+		},
+		{
+			name:     "varied output",
+			text:     "building package one\ncompiling module two\nrunning tests three\nall tests passed four\n",
+			wantLoop: false,
+		},
+		{
+			name:       "same failing command repeated",
+			text:       "starting work\n" + strings.Repeat(failing, 5),
+			wantLoop:   true,
+			wantLines:  4,
+			minRepeats: 5,
+		},
+		{
+			name:     "below repeat threshold",
+			text:     strings.Repeat(failing, 3),
+			wantLoop: false,
+		},
+		{
+			name:     "short spinner lines ignored",
+			text:     strings.Repeat("⠋\n", 50),
+			wantLoop: false,
+		},
+		{
+			name:       "ansi escapes and redraws normalized",
+			text:       strings.Repeat("\x1b[31m"+"Error: cannot find module 'left-pad' in node_modules"+"\x1b[0m\r\n", 6),
+			wantLoop:   true,
+			wantLines:  1,
+			minRepeats: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loop, found := Detect(tt.text, DefaultConfig())
+			if found != tt.wantLoop {
+				t.Fatalf("Detect() found = %v, want %v (loop=%+v)", found, tt.wantLoop, loop)
+			}
+			if !found {
+				return
+			}
+			if loop.BlockLines != tt.wantLines {
+				t.Errorf("BlockLines = %d, want %d", loop.BlockLines, tt.wantLines)
+			}
+			if loop.Repeats < tt.minRepeats {
+				t.Errorf("Repeats = %d, want >= %d", loop.Repeats, tt.minRepeats)
+			}
+			if strings.Contains(loop.Snippet, "\x1b") {
+				t.Errorf("Snippet should not contain escape sequences: %q", loop.Snippet)
+			}
+		})
+	}
+}
+
+func TestDetectMaxLines(t *testing.T) {
+	failing := "$ make build\nerror: undefined reference to symbol main\n"
+	text := strings.Repeat(failing, 10)
+	for i := 0; i < 100; i++ {
+		text += "distinct output line " + strings.Repeat("x", i) + "\n"
+	}
+
+	cfg := DefaultConfig()
+	cfg.MaxLines = 50
+	if _, found := Detect(text, cfg); found {
+		t.Error("loop outside the examined window should not be detected")
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tests := map[string]string{
+		"plain text":                          "plain text",
+		"\x1b[1;32mok\x1b[0m  tests passed\r": "ok  tests passed",
+		"Building 10%\rBuilding 100%":         "Building 100%",
+		"\x1b]0;title\x07prompt $ ":           "prompt $ ",
+	}
+	for in, want := range tests {
+		if got := Plain(in); got != want {
+			t.Errorf("Plain(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
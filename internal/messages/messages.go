@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/dlorenc/multiclaude/internal/audit"
 	"github.com/google/uuid"
 )
 
@@ -34,11 +35,29 @@ type Message struct {
 // Manager handles message filesystem operations
 type Manager struct {
 	messagesRoot string
+	audit        *audit.Log
+	origin       string
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithAudit records every message sent in an audit log. origin is the CLI
+// command sending them, if any.
+func WithAudit(log *audit.Log, origin string) Option {
+	return func(m *Manager) {
+		m.audit = log
+		m.origin = origin
+	}
 }
 
 // NewManager creates a new message manager
-func NewManager(messagesRoot string) *Manager {
-	return &Manager{messagesRoot: messagesRoot}
+func NewManager(messagesRoot string, opts ...Option) *Manager {
+	m := &Manager{messagesRoot: messagesRoot}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // Send creates a new message file
@@ -56,6 +75,19 @@ func (m *Manager) Send(repoName, from, to, body string) (*Message, error) {
 		return nil, err
 	}
 
+	if m.audit != nil {
+		// The message is sent; failing to record it is no reason to say otherwise
+		_ = m.audit.Append(audit.Entry{
+			Action:  audit.ActionMessageSent,
+			Repo:    repoName,
+			Agent:   to,
+			Detail:  "id=" + msg.ID,
+			Command: "send_message",
+			Origin:  m.origin,
+			Actor:   from,
+		})
+	}
+
 	return msg, nil
 }
 
@@ -209,14 +241,32 @@ func (m *Manager) read(repoName, agentName, filename string) (*Message, error) {
 
 // CleanupOrphaned removes message directories for non-existent agents
 func (m *Manager) CleanupOrphaned(repoName string, validAgents []string) (int, error) {
+	orphaned, err := m.FindOrphaned(repoName, validAgents)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, agentName := range orphaned {
+		if err := m.RemoveAgent(repoName, agentName); err == nil {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// FindOrphaned returns the agents in repoName that have a message directory
+// but are not in validAgents, without removing anything
+func (m *Manager) FindOrphaned(repoName string, validAgents []string) ([]string, error) {
 	repoDir := filepath.Join(m.messagesRoot, repoName)
 
 	entries, err := os.ReadDir(repoDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return 0, nil
+			return nil, nil
 		}
-		return 0, fmt.Errorf("failed to read repo messages dir: %w", err)
+		return nil, fmt.Errorf("failed to read repo messages dir: %w", err)
 	}
 
 	validAgentMap := make(map[string]bool)
@@ -224,20 +274,17 @@ func (m *Manager) CleanupOrphaned(repoName string, validAgents []string) (int, e
 		validAgentMap[agent] = true
 	}
 
-	count := 0
+	var orphaned []string
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		if !validAgentMap[entry.Name()] {
-			// This is an orphaned agent directory
-			path := filepath.Join(repoDir, entry.Name())
-			if err := os.RemoveAll(path); err == nil {
-				count++
-			}
+		if entry.IsDir() && !validAgentMap[entry.Name()] {
+			orphaned = append(orphaned, entry.Name())
 		}
 	}
 
-	return count, nil
+	return orphaned, nil
+}
+
+// RemoveAgent deletes an agent's message directory and everything in it
+func (m *Manager) RemoveAgent(repoName, agentName string) error {
+	return os.RemoveAll(m.agentDir(repoName, agentName))
 }
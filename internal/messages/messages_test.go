@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/dlorenc/multiclaude/internal/audit"
 )
 
 func TestNewManager(t *testing.T) {
@@ -61,6 +63,30 @@ func TestSendMessage(t *testing.T) {
 	}
 }
 
+func TestSendMessageAudited(t *testing.T) {
+	tmpDir := t.TempDir()
+	log := audit.NewLog(filepath.Join(tmpDir, "audit.jsonl"))
+	m := NewManager(filepath.Join(tmpDir, "messages"), WithAudit(log, "message send"))
+
+	msg, err := m.Send("test-repo", "supervisor", "worker1", "Rebase please")
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	entries, err := log.List(audit.Filter{})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("audit log = %+v, want the sent message", entries)
+	}
+	e := entries[0]
+	if e.Action != audit.ActionMessageSent || e.Repo != "test-repo" || e.Agent != "worker1" ||
+		e.Actor != "supervisor" || e.Origin != "message send" || e.Detail != "id="+msg.ID {
+		t.Errorf("audit entry = %+v", e)
+	}
+}
+
 func TestListMessages(t *testing.T) {
 	tmpDir := t.TempDir()
 	m := NewManager(tmpDir)
@@ -350,6 +376,14 @@ func TestCleanupOrphaned(t *testing.T) {
 	// Only agent1 and agent3 are valid now
 	validAgents := []string{"agent1", "agent3"}
 
+	orphaned, err := m.FindOrphaned(repoName, validAgents)
+	if err != nil {
+		t.Fatalf("FindOrphaned() failed: %v", err)
+	}
+	if len(orphaned) != 1 || orphaned[0] != "agent2" {
+		t.Errorf("FindOrphaned() = %v, want [agent2]", orphaned)
+	}
+
 	count, err := m.CleanupOrphaned(repoName, validAgents)
 	if err != nil {
 		t.Fatalf("CleanupOrphaned() failed: %v", err)
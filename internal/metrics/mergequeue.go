@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+// QueuedPR describes one pending item in a merge queue
+type QueuedPR struct {
+	Branch             string             `json:"branch,omitempty"`
+	PRNumber           int                `json:"pr_number,omitempty"`
+	Worker             string             `json:"worker,omitempty"`
+	Priority           state.TaskPriority `json:"priority"`
+	TimeInQueueSeconds float64            `json:"time_in_queue_seconds"`
+	InCI               bool               `json:"in_ci"`
+}
+
+// MergeQueueStats summarizes a repository's merge queue at a point in time
+type MergeQueueStats struct {
+	Repo                   string     `json:"repo"`
+	Depth                  int        `json:"depth"`
+	OldestSeconds          float64    `json:"oldest_seconds"`
+	Merged                 int        `json:"merged"`
+	Failed                 int        `json:"failed"`
+	Closed                 int        `json:"closed"`
+	MeanTimeInQueueSeconds float64    `json:"mean_time_in_queue_seconds"`
+	CIRuns                 int        `json:"ci_runs"`
+	MeanCIWaitSeconds      float64    `json:"mean_ci_wait_seconds"`
+	TimeInQueueSecondsSum  float64    `json:"time_in_queue_seconds_sum"`
+	CIWaitSecondsSum       float64    `json:"ci_wait_seconds_sum"`
+	Pending                []QueuedPR `json:"pending"`
+}
+
+// ComputeMergeQueue builds the merge queue stats for a repository as of now.
+// Depth and per-PR ages come from pending items; counts and means come from
+// the repository's monotonic totals.
+func ComputeMergeQueue(repoName string, repo *state.Repository, now time.Time) MergeQueueStats {
+	totals := repo.MergeQueueTotals
+	stats := MergeQueueStats{
+		Repo:    repoName,
+		Merged:  totals.Merged,
+		Failed:  totals.Failed,
+		Closed:  totals.Closed,
+		CIRuns:  totals.CIRuns,
+		Pending: []QueuedPR{},
+
+		TimeInQueueSecondsSum: totals.TimeInQueueSeconds,
+		CIWaitSecondsSum:      totals.CIWaitSeconds,
+	}
+
+	if resolved := totals.Merged + totals.Failed + totals.Closed; resolved > 0 {
+		stats.MeanTimeInQueueSeconds = totals.TimeInQueueSeconds / float64(resolved)
+	}
+	if totals.CIRuns > 0 {
+		stats.MeanCIWaitSeconds = totals.CIWaitSeconds / float64(totals.CIRuns)
+	}
+
+	for _, item := range repo.MergeQueue {
+		if !item.Pending() {
+			continue
+		}
+		age := now.Sub(item.EnqueuedAt).Seconds()
+		stats.Depth++
+		if age > stats.OldestSeconds {
+			stats.OldestSeconds = age
+		}
+		stats.Pending = append(stats.Pending, QueuedPR{
+			Branch:             item.Branch,
+			PRNumber:           item.PRNumber,
+			Worker:             item.Worker,
+			Priority:           item.Priority.Effective(),
+			TimeInQueueSeconds: age,
+			InCI:               !item.CIStartedAt.IsZero() && item.CIFinishedAt.IsZero(),
+		})
+	}
+
+	// Merge order: most urgent first, then longest waiting
+	sort.Slice(stats.Pending, func(i, j int) bool {
+		a, b := stats.Pending[i], stats.Pending[j]
+		if a.Priority.Rank() != b.Priority.Rank() {
+			return a.Priority.Rank() < b.Priority.Rank()
+		}
+		return a.TimeInQueueSeconds > b.TimeInQueueSeconds
+	})
+	return stats
+}
+
+// promMetric is one metric family in the Prometheus text exposition format
+type promMetric struct {
+	name    string
+	kind    string // "gauge" or "counter"
+	help    string
+	samples []string
+}
+
+// promLabels renders a label set, escaping values per the exposition format
+func promLabels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteString("{")
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1])
+		fmt.Fprintf(&b, "%s=\"%s\"", pairs[i], value)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// promValue formats a sample value
+func promValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// WritePrometheus writes merge queue stats in the Prometheus text exposition
+// format, suitable for a scrape endpoint or node_exporter's textfile collector
+func WritePrometheus(w io.Writer, stats []MergeQueueStats) error {
+	families := []*promMetric{
+		{name: "multiclaude_merge_queue_depth", kind: "gauge", help: "PRs waiting in the merge queue."},
+		{name: "multiclaude_merge_queue_oldest_seconds", kind: "gauge", help: "Time the oldest waiting PR has spent in the queue."},
+		{name: "multiclaude_merge_queue_pr_time_in_queue_seconds", kind: "gauge", help: "Time each waiting PR has spent in the queue."},
+		{name: "multiclaude_merge_queue_resolved_total", kind: "counter", help: "PRs that left the merge queue, by outcome."},
+		{name: "multiclaude_merge_queue_time_in_queue_seconds_total", kind: "counter", help: "Total time resolved PRs spent in the queue."},
+		{name: "multiclaude_merge_queue_ci_runs_total", kind: "counter", help: "Finished CI runs for queued PRs."},
+		{name: "multiclaude_merge_queue_ci_wait_seconds_total", kind: "counter", help: "Total time queued PRs spent waiting on CI."},
+	}
+	depth, oldest, perPR, resolved, queueSum, ciRuns, ciSum := families[0], families[1], families[2], families[3], families[4], families[5], families[6]
+
+	for _, s := range stats {
+		repo := promLabels("repo", s.Repo)
+		depth.samples = append(depth.samples, repo+" "+strconv.Itoa(s.Depth))
+		oldest.samples = append(oldest.samples, repo+" "+promValue(s.OldestSeconds))
+		for _, pr := range s.Pending {
+			labels := promLabels("repo", s.Repo, "branch", pr.Branch, "pr", strconv.Itoa(pr.PRNumber))
+			perPR.samples = append(perPR.samples, labels+" "+promValue(pr.TimeInQueueSeconds))
+		}
+		for _, outcome := range []struct {
+			name  string
+			count int
+		}{{"merged", s.Merged}, {"failed", s.Failed}, {"closed", s.Closed}} {
+			labels := promLabels("repo", s.Repo, "outcome", outcome.name)
+			resolved.samples = append(resolved.samples, labels+" "+strconv.Itoa(outcome.count))
+		}
+		queueSum.samples = append(queueSum.samples, repo+" "+promValue(s.TimeInQueueSecondsSum))
+		ciRuns.samples = append(ciRuns.samples, repo+" "+strconv.Itoa(s.CIRuns))
+		ciSum.samples = append(ciSum.samples, repo+" "+promValue(s.CIWaitSecondsSum))
+	}
+
+	for _, m := range families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind); err != nil {
+			return err
+		}
+		for _, sample := range m.samples {
+			if _, err := fmt.Fprintf(w, "%s%s\n", m.name, sample); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package metrics computes per-repository throughput aggregates from state and
+// appends them to local CSV/JSON files for reporting.
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// DateLayout is the format used for snapshot dates
+const DateLayout = "2006-01-02"
+
+// Snapshot holds the aggregates for one repository on one day. It is the
+// public events type so metrics.daily payloads carry it unchanged.
+type Snapshot = events.MetricsSnapshot
+
+// csvHeader lists the CSV columns in order
+var csvHeader = []string{
+	"date", "repo", "tasks_started", "tasks_completed", "tasks_failed",
+	"prs_opened", "prs_merged", "mean_task_duration_seconds",
+}
+
+// sameDay reports whether t falls on the given local calendar day
+func sameDay(t time.Time, day time.Time) bool {
+	if t.IsZero() {
+		return false
+	}
+	y1, m1, d1 := t.Local().Date()
+	y2, m2, d2 := day.Local().Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+// ComputeDaily builds the snapshot for a repository on the given day.
+// Started counts workers (active or finished) created that day; completion,
+// failure, PR, and duration figures come from task history entries completed
+// that day. PR status is whatever was last recorded in history.
+func ComputeDaily(repoName string, repo *state.Repository, day time.Time) Snapshot {
+	snap := Snapshot{
+		Date: day.Local().Format(DateLayout),
+		Repo: repoName,
+	}
+
+	for _, agent := range repo.Agents {
+		if agent.Type == state.AgentTypeWorker && sameDay(agent.CreatedAt, day) {
+			snap.TasksStarted++
+		}
+	}
+
+	var totalDuration time.Duration
+	for _, entry := range repo.TaskHistory {
+		if sameDay(entry.CreatedAt, day) {
+			snap.TasksStarted++
+		}
+		if !sameDay(entry.CompletedAt, day) {
+			continue
+		}
+
+		if entry.Status == state.TaskStatusFailed {
+			snap.TasksFailed++
+		} else {
+			snap.TasksCompleted++
+		}
+
+		if entry.PRURL != "" || entry.Status == state.TaskStatusOpen ||
+			entry.Status == state.TaskStatusMerged || entry.Status == state.TaskStatusClosed {
+			snap.PRsOpened++
+		}
+		if entry.Status == state.TaskStatusMerged {
+			snap.PRsMerged++
+		}
+
+		if !entry.CreatedAt.IsZero() && entry.CompletedAt.After(entry.CreatedAt) {
+			totalDuration += entry.CompletedAt.Sub(entry.CreatedAt)
+		}
+	}
+
+	if finished := snap.TasksCompleted + snap.TasksFailed; finished > 0 {
+		snap.MeanDurationSeconds = (totalDuration / time.Duration(finished)).Seconds()
+	}
+
+	return snap
+}
+
+// AppendCSV appends snapshots to a CSV file, writing the header if the file is new
+func AppendCSV(path string, snapshots []Snapshot) error {
+	_, statErr := os.Stat(path)
+	isNew := os.IsNotExist(statErr)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics file: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if isNew {
+		if err := w.Write(csvHeader); err != nil {
+			return fmt.Errorf("failed to write metrics header: %w", err)
+		}
+	}
+	for _, s := range snapshots {
+		record := []string{
+			s.Date,
+			s.Repo,
+			strconv.Itoa(s.TasksStarted),
+			strconv.Itoa(s.TasksCompleted),
+			strconv.Itoa(s.TasksFailed),
+			strconv.Itoa(s.PRsOpened),
+			strconv.Itoa(s.PRsMerged),
+			strconv.FormatFloat(s.MeanDurationSeconds, 'f', 0, 64),
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("failed to write metrics record: %w", err)
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// AppendJSONL appends snapshots to a JSON Lines file (one object per line)
+func AppendJSONL(path string, snapshots []Snapshot) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics file: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, s := range snapshots {
+		if err := enc.Encode(s); err != nil {
+			return fmt.Errorf("failed to write metrics record: %w", err)
+		}
+	}
+	return nil
+}
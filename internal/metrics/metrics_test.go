@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+)
+
+func TestComputeDaily(t *testing.T) {
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	yesterday := day.AddDate(0, 0, -1)
+
+	repo := &state.Repository{
+		Agents: map[string]state.Agent{
+			"supervisor": {Type: state.AgentTypeSupervisor, CreatedAt: day},
+			"active":     {Type: state.AgentTypeWorker, CreatedAt: day},
+			"old":        {Type: state.AgentTypeWorker, CreatedAt: yesterday},
+		},
+		TaskHistory: []state.TaskHistoryEntry{
+			{Name: "merged", Status: state.TaskStatusMerged, PRURL: "https://github.com/o/r/pull/1",
+				CreatedAt: day.Add(-2 * time.Hour), CompletedAt: day},
+			{Name: "open", Status: state.TaskStatusUnknown, PRURL: "https://github.com/o/r/pull/2",
+				CreatedAt: yesterday, CompletedAt: day.Add(-4 * time.Hour)},
+			{Name: "failed", Status: state.TaskStatusFailed,
+				CreatedAt: day.Add(-1 * time.Hour), CompletedAt: day},
+			{Name: "other-day", Status: state.TaskStatusMerged,
+				CreatedAt: yesterday, CompletedAt: yesterday},
+		},
+	}
+
+	snap := ComputeDaily("my-repo", repo, day)
+
+	if snap.Date != "2026-03-10" || snap.Repo != "my-repo" {
+		t.Errorf("unexpected date/repo: %+v", snap)
+	}
+	// active worker + merged + failed started today
+	if snap.TasksStarted != 3 {
+		t.Errorf("TasksStarted = %d, want 3", snap.TasksStarted)
+	}
+	if snap.TasksCompleted != 2 {
+		t.Errorf("TasksCompleted = %d, want 2", snap.TasksCompleted)
+	}
+	if snap.TasksFailed != 1 {
+		t.Errorf("TasksFailed = %d, want 1", snap.TasksFailed)
+	}
+	if snap.PRsOpened != 2 {
+		t.Errorf("PRsOpened = %d, want 2", snap.PRsOpened)
+	}
+	if snap.PRsMerged != 1 {
+		t.Errorf("PRsMerged = %d, want 1", snap.PRsMerged)
+	}
+	if snap.MeanDurationSeconds <= 0 {
+		t.Errorf("MeanDurationSeconds = %v, want > 0", snap.MeanDurationSeconds)
+	}
+}
+
+func TestComputeDailyEmpty(t *testing.T) {
+	snap := ComputeDaily("empty", &state.Repository{Agents: map[string]state.Agent{}}, time.Now())
+	if snap.TasksStarted != 0 || snap.TasksCompleted != 0 || snap.MeanDurationSeconds != 0 {
+		t.Errorf("expected zero snapshot, got %+v", snap)
+	}
+}
+
+func TestAppendCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	snaps := []Snapshot{{Date: "2026-03-10", Repo: "a", TasksStarted: 2, MeanDurationSeconds: 90.4}}
+
+	if err := AppendCSV(path, snaps); err != nil {
+		t.Fatalf("AppendCSV failed: %v", err)
+	}
+	if err := AppendCSV(path, snaps); err != nil {
+		t.Fatalf("AppendCSV failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header + 2 rows, got %d lines:\n%s", len(lines), data)
+	}
+	if !strings.HasPrefix(lines[0], "date,repo,") {
+		t.Errorf("missing header: %q", lines[0])
+	}
+	if lines[1] != "2026-03-10,a,2,0,0,0,0,90" {
+		t.Errorf("unexpected row: %q", lines[1])
+	}
+}
+
+func TestAppendJSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.jsonl")
+	snaps := []Snapshot{{Date: "2026-03-10", Repo: "a"}, {Date: "2026-03-10", Repo: "b", PRsMerged: 1}}
+
+	if err := AppendJSONL(path, snaps); err != nil {
+		t.Fatalf("AppendJSONL failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	var s Snapshot
+	if err := json.Unmarshal([]byte(lines[1]), &s); err != nil {
+		t.Fatalf("invalid JSON line: %v", err)
+	}
+	if s.Repo != "b" || s.PRsMerged != 1 {
+		t.Errorf("unexpected snapshot: %+v", s)
+	}
+}
+
+func TestComputeMergeQueue(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &state.Repository{
+		MergeQueue: []state.MergeQueueItem{
+			{Branch: "work/old", PRNumber: 3, EnqueuedAt: now.Add(-2 * time.Hour), CIStartedAt: now.Add(-time.Minute)},
+			{Branch: "work/new", EnqueuedAt: now.Add(-10 * time.Minute)},
+			{Branch: "work/done", EnqueuedAt: now.Add(-5 * time.Hour), ResolvedAt: now.Add(-4 * time.Hour), Outcome: state.MergeQueueMerged},
+		},
+		MergeQueueTotals: state.MergeQueueTotals{
+			Merged: 3, Failed: 1, TimeInQueueSeconds: 4000, CIRuns: 2, CIWaitSeconds: 600,
+		},
+	}
+
+	stats := ComputeMergeQueue("my-repo", repo, now)
+	if stats.Depth != 2 || stats.OldestSeconds != 7200 {
+		t.Errorf("depth/oldest = %d/%v, want 2/7200", stats.Depth, stats.OldestSeconds)
+	}
+	if stats.MeanTimeInQueueSeconds != 1000 || stats.MeanCIWaitSeconds != 300 {
+		t.Errorf("means = %v/%v, want 1000/300", stats.MeanTimeInQueueSeconds, stats.MeanCIWaitSeconds)
+	}
+	if len(stats.Pending) != 2 || stats.Pending[0].Branch != "work/old" || !stats.Pending[0].InCI {
+		t.Errorf("unexpected pending list: %+v", stats.Pending)
+	}
+
+	var buf strings.Builder
+	if err := WritePrometheus(&buf, []MergeQueueStats{stats}); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE multiclaude_merge_queue_depth gauge\n",
+		`multiclaude_merge_queue_depth{repo="my-repo"} 2`,
+		`multiclaude_merge_queue_pr_time_in_queue_seconds{repo="my-repo",branch="work/old",pr="3"} 7200`,
+		`multiclaude_merge_queue_resolved_total{repo="my-repo",outcome="merged"} 3`,
+		`multiclaude_merge_queue_ci_wait_seconds_total{repo="my-repo"} 600`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPromLabelsEscaping(t *testing.T) {
+	got := promLabels("branch", `a"b\c`)
+	if want := `{branch="a\"b\\c"}`; got != want {
+		t.Errorf("promLabels = %s, want %s", got, want)
+	}
+}
+
+func TestComputeMergeQueueOrdersByPriority(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &state.Repository{
+		MergeQueue: []state.MergeQueueItem{
+			{Branch: "work/bulk", Priority: state.TaskPriorityP3, EnqueuedAt: now.Add(-3 * time.Hour)},
+			{Branch: "work/old", EnqueuedAt: now.Add(-2 * time.Hour)},
+			{Branch: "work/hotfix", Priority: state.TaskPriorityP0, EnqueuedAt: now.Add(-time.Minute)},
+			{Branch: "work/new", Priority: state.TaskPriorityP2, EnqueuedAt: now.Add(-10 * time.Minute)},
+		},
+	}
+
+	stats := ComputeMergeQueue("my-repo", repo, now)
+	var order []string
+	for _, pr := range stats.Pending {
+		order = append(order, string(pr.Priority)+" "+pr.Branch)
+	}
+	want := []string{"P0 work/hotfix", "P2 work/old", "P2 work/new", "P3 work/bulk"}
+	if strings.Join(order, ",") != strings.Join(want, ",") {
+		t.Errorf("merge order = %v, want %v", order, want)
+	}
+}
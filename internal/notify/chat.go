@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// telegramAPI is the Telegram Bot API's base URL
+const telegramAPI = "https://api.telegram.org"
+
+// SlackConfig configures the Slack adapter
+type SlackConfig struct {
+	// WebhookURL is the Slack incoming webhook events are posted to
+	WebhookURL string
+	// Types limits the adapter to these event types; empty means all
+	Types   []events.EventType
+	Timeout time.Duration
+}
+
+// TelegramConfig configures the Telegram adapter
+type TelegramConfig struct {
+	BotToken string
+	ChatID   string
+	// Types limits the adapter to these event types; empty means all
+	Types []events.EventType
+	// APIURL overrides the Bot API's base URL, e.g. for a local Bot API server
+	APIURL  string
+	Timeout time.Duration
+}
+
+// chatAdapter posts events as text messages to a chat service
+type chatAdapter struct {
+	name  string
+	types map[events.EventType]bool
+	http  *http.Client
+	// request builds the URL and JSON body that post text
+	request func(text string) (string, map[string]interface{})
+}
+
+// NewSlackAdapter creates an adapter that posts events to a Slack channel
+// through an incoming webhook
+func NewSlackAdapter(cfg SlackConfig) Adapter {
+	return newChatAdapter("slack", cfg.Types, cfg.Timeout, func(text string) (string, map[string]interface{}) {
+		return cfg.WebhookURL, map[string]interface{}{"text": text}
+	})
+}
+
+// NewTelegramAdapter creates an adapter that sends events to a Telegram chat
+// as a bot
+func NewTelegramAdapter(cfg TelegramConfig) Adapter {
+	base := cfg.APIURL
+	if base == "" {
+		base = telegramAPI
+	}
+	target := strings.TrimSuffix(base, "/") + "/bot" + cfg.BotToken + "/sendMessage"
+	return newChatAdapter("telegram", cfg.Types, cfg.Timeout, func(text string) (string, map[string]interface{}) {
+		return target, map[string]interface{}{
+			"chat_id":                  cfg.ChatID,
+			"text":                     text,
+			"disable_web_page_preview": true,
+		}
+	})
+}
+
+func newChatAdapter(name string, types []events.EventType, timeout time.Duration, request func(string) (string, map[string]interface{})) *chatAdapter {
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	a := &chatAdapter{name: name, http: &http.Client{Timeout: timeout}, request: request}
+	if len(types) > 0 {
+		a.types = make(map[events.EventType]bool)
+		for _, t := range types {
+			a.types[t] = true
+		}
+	}
+	return a
+}
+
+// Name implements Adapter
+func (a *chatAdapter) Name() string {
+	return a.name
+}
+
+// Send implements Adapter. Events of types the adapter isn't configured
+// for are ignored.
+func (a *chatAdapter) Send(ctx context.Context, event events.Event) error {
+	if a.types != nil && !a.types[event.Type] {
+		return nil
+	}
+	target, body := a.request(chatText(event))
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", a.name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		// The URL holds the webhook path or bot token, so keep it out of logs
+		return fmt.Errorf("%s request failed: %w", a.name, redactURL(err))
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %s: %s", a.name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// chatText is an event as a chat message: who it is about, then its text
+func chatText(event events.Event) string {
+	who := event.Repo
+	if event.Agent != "" {
+		who = event.Repo + "/" + event.Agent
+	}
+	return truncateRunes(fmt.Sprintf("[%s] %s", who, event.Text()), 3500)
+}
+
+// redactURL drops the URL from an HTTP client error
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
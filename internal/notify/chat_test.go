@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestSlackAdapter(t *testing.T) {
+	server, requests := incidentServer(t)
+	a := NewSlackAdapter(SlackConfig{WebhookURL: server.URL + "/services/T/B/X", Types: []events.EventType{events.EventAgentQuestion}})
+	if a.Name() != "slack" {
+		t.Errorf("Name() = %q, want slack", a.Name())
+	}
+
+	question := events.NewEvent(events.EventAgentQuestion, "app", "swift-fox", "swift-fox has a question")
+	question.Message = "Which database?"
+	if err := a.Send(context.Background(), question); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	// Other types are ignored
+	if err := a.Send(context.Background(), events.NewEvent(events.EventAgentCompleted, "app", "swift-fox", "done")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("got %d requests, want 1", len(got))
+	}
+	if got[0].path != "/services/T/B/X" {
+		t.Errorf("posted to %s", got[0].path)
+	}
+	text, _ := got[0].body["text"].(string)
+	if !strings.HasPrefix(text, "[app/swift-fox] swift-fox has a question") || !strings.Contains(text, "Which database?") {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestTelegramAdapter(t *testing.T) {
+	server, requests := incidentServer(t)
+	a := NewTelegramAdapter(TelegramConfig{BotToken: "123:abc", ChatID: "-1001", APIURL: server.URL})
+
+	if err := a.Send(context.Background(), events.NewEvent(events.EventAgentCompleted, "app", "", "merged")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("got %d requests, want 1", len(got))
+	}
+	if got[0].path != "/bot123:abc/sendMessage" {
+		t.Errorf("posted to %s", got[0].path)
+	}
+	if got[0].body["chat_id"] != "-1001" || got[0].body["text"] != "[app] merged" {
+		t.Errorf("body = %v", got[0].body)
+	}
+}
+
+func TestChatAdapterErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	a := NewSlackAdapter(SlackConfig{WebhookURL: server.URL + "/services/secret"})
+	err := a.Send(context.Background(), events.NewEvent(events.EventAgentCompleted, "app", "", "merged"))
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("Send error = %v, want the 403 response", err)
+	}
+
+	// Connection errors don't leak the bot token
+	a = NewTelegramAdapter(TelegramConfig{BotToken: "secret-token", ChatID: "1", APIURL: "http://127.0.0.1:1", Timeout: time.Second})
+	err = a.Send(context.Background(), events.NewEvent(events.EventAgentCompleted, "app", "", "merged"))
+	if err == nil || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Send error = %v, want a failure without the token", err)
+	}
+}
+
+func TestParseFileSettings(t *testing.T) {
+	retries := 2
+	cfg, err := ParseFileSettings(config.NotificationSettings{
+		Slack:    &config.SlackSettings{WebhookURL: "https://hooks.slack.test/x", Events: []string{"agent.question"}},
+		Telegram: &config.TelegramSettings{BotToken: "t", ChatID: "c"},
+		Webhook: &config.WebhookSettings{
+			URL:     "https://example.com/hook",
+			Secret:  "s3cret",
+			Headers: map[string]string{"x-team": "infra, platform"},
+			Retries: &retries,
+			Backoff: "2s",
+		},
+	})
+	if err != nil {
+		t.Fatalf("ParseFileSettings failed: %v", err)
+	}
+	if cfg.Slack == nil || len(cfg.Slack.Types) != 1 || cfg.Slack.Types[0] != events.EventAgentQuestion {
+		t.Errorf("slack = %+v", cfg.Slack)
+	}
+	if cfg.Telegram == nil || cfg.Telegram.ChatID != "c" || cfg.Telegram.Types != nil {
+		t.Errorf("telegram = %+v", cfg.Telegram)
+	}
+	w := cfg.Webhook
+	if w == nil || w.Attempts != 3 || w.Backoff != 2*time.Second || w.Timeout != DefaultWebhookTimeout || string(w.Secret) != "s3cret" || w.Headers["X-Team"] != "infra, platform" {
+		t.Errorf("webhook = %+v", w)
+	}
+
+	empty, err := ParseFileSettings(config.NotificationSettings{})
+	if err != nil || empty.Slack != nil || empty.Telegram != nil || empty.Webhook != nil {
+		t.Errorf("ParseFileSettings(empty) = %+v, %v", empty, err)
+	}
+
+	for _, s := range []config.NotificationSettings{
+		{Slack: &config.SlackSettings{WebhookURL: "https://x.test", Events: []string{"agent.sneezed"}}},
+		{Webhook: &config.WebhookSettings{URL: "https://x.test"}},
+		{Webhook: &config.WebhookSettings{URL: "https://x.test", Secret: "s", Headers: map[string]string{"X-Multiclaude-Event": "x"}}},
+	} {
+		if _, err := ParseFileSettings(s); err == nil {
+			t.Errorf("ParseFileSettings(%+v) should fail", s)
+		}
+	}
+}
+
+func TestParseFileSettingsEmailDesktopIncidentRoutes(t *testing.T) {
+	cfg, err := ParseFileSettings(config.NotificationSettings{
+		Email:    &config.EmailSettings{Host: "smtp.example.com", Password: "pw", From: "bot@example.com", To: []string{"a@example.com", "b@example.com"}, TLS: "implicit", Port: 465, Digest: "15m"},
+		Desktop:  &config.DesktopSettings{},
+		Incident: &config.IncidentSettings{Provider: ProviderOpsgenie, Key: "k", Window: "30m", AgentTypes: []string{"merge-queue"}},
+		Routes: []config.RouteSettings{
+			{Types: []string{"agent.*"}, MinPriority: "high", To: []string{"email"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ParseFileSettings failed: %v", err)
+	}
+	e := cfg.Email
+	if e == nil || e.Port != 465 || !e.ImplicitTLS || len(e.To) != 2 || e.Password != "pw" || e.MinPriority != events.PriorityHigh || e.Digest != 15*time.Minute {
+		t.Errorf("email = %+v", e)
+	}
+	if len(cfg.Desktop) != len(DefaultDesktopTypes) {
+		t.Errorf("desktop without events = %v, want the default types", cfg.Desktop)
+	}
+	i := cfg.Incident
+	if i == nil || i.Key != "k" || i.Threshold != DefaultIncidentThreshold || i.Window != 30*time.Minute || len(i.AgentTypes) != 1 {
+		t.Errorf("incident = %+v", i)
+	}
+	if len(cfg.Routes) != 1 || !cfg.Routes.Routed("email") || cfg.Routes[0].MinPriority != events.PriorityHigh {
+		t.Errorf("routes = %+v", cfg.Routes)
+	}
+
+	for _, s := range []config.NotificationSettings{
+		{Email: &config.EmailSettings{Host: "smtp.example.com", To: []string{"a@example.com"}}},
+		{Email: &config.EmailSettings{Host: "smtp.example.com", From: "bot@example.com", To: []string{"a@example.com"}, TLS: "none"}},
+		{Desktop: &config.DesktopSettings{Events: []string{"agent.sneezed"}}},
+		{Incident: &config.IncidentSettings{Provider: ProviderPagerDuty}},
+		{Routes: []config.RouteSettings{{Types: []string{"agent.question"}}}},
+	} {
+		if _, err := ParseFileSettings(s); err == nil {
+			t.Errorf("ParseFileSettings(%+v) should fail", s)
+		}
+	}
+}
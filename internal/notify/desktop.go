@@ -0,0 +1,204 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// DesktopEnv is the environment variable that enables desktop notifications
+// for a daemon running on the user's machine. It is "1" for the default event
+// types or a comma-separated list of types, e.g. agent.question,agent.stuck.
+// Setting it overrides the config file's notifications.desktop.
+const DesktopEnv = "MULTICLAUDE_DESKTOP"
+
+// DefaultDesktopTypes are the events shown on the desktop by default: the
+// ones where an agent is waiting on or has failed the user
+var DefaultDesktopTypes = []events.EventType{events.EventAgentQuestion, events.EventAgentError}
+
+// ParseDesktopTypes parses the value of MULTICLAUDE_DESKTOP
+func ParseDesktopTypes(spec string) ([]events.EventType, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "1" || spec == "on" {
+		return DefaultDesktopTypes, nil
+	}
+	var types []events.EventType
+	for _, field := range strings.Split(spec, ",") {
+		eventType := events.EventType(strings.TrimSpace(field))
+		if eventType == "" {
+			continue
+		}
+		if _, ok := events.LookupSchema(eventType); !ok {
+			return nil, fmt.Errorf("unknown event type %q in %s", eventType, DesktopEnv)
+		}
+		types = append(types, eventType)
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("%s lists no event types", DesktopEnv)
+	}
+	return types, nil
+}
+
+// Desktop notification tools, in order of preference per platform
+const (
+	terminalNotifier = "terminal-notifier" // macOS; runs a command on click
+	osascript        = "osascript"         // macOS built-in; no click action
+	notifySend       = "notify-send"       // Linux (libnotify); actions need 0.7.9+
+)
+
+// DesktopAdapter shows events as native desktop notifications. Clicking a
+// notification opens a terminal attached to the agent, where the platform's
+// tool supports it.
+type DesktopAdapter struct {
+	tool  string
+	types map[events.EventType]bool
+	// exec runs a command and returns its standard output
+	exec func(name string, args ...string) ([]byte, error)
+
+	// clicks tracks notifications waiting for a click
+	clicks sync.WaitGroup
+}
+
+// NewDesktopAdapter creates an adapter for the given event types using the
+// first notification tool found for this platform
+func NewDesktopAdapter(types []events.EventType) (*DesktopAdapter, error) {
+	var candidates []string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = []string{terminalNotifier, osascript}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		candidates = []string{notifySend}
+	default:
+		return nil, fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	for _, tool := range candidates {
+		if _, err := exec.LookPath(tool); err == nil {
+			return newDesktopAdapter(tool, types), nil
+		}
+	}
+	return nil, fmt.Errorf("no desktop notification tool found (looked for %s)", strings.Join(candidates, ", "))
+}
+
+func newDesktopAdapter(tool string, types []events.EventType) *DesktopAdapter {
+	a := &DesktopAdapter{tool: tool, types: make(map[events.EventType]bool)}
+	for _, t := range types {
+		a.types[t] = true
+	}
+	a.exec = func(name string, args ...string) ([]byte, error) {
+		return exec.Command(name, args...).Output()
+	}
+	return a
+}
+
+// Name implements Adapter
+func (a *DesktopAdapter) Name() string {
+	return "desktop"
+}
+
+// Send implements Adapter. Events of other types are ignored.
+func (a *DesktopAdapter) Send(ctx context.Context, event events.Event) error {
+	if !a.types[event.Type] {
+		return nil
+	}
+
+	subtitle := event.Repo
+	if event.Agent != "" {
+		subtitle = event.Repo + "/" + event.Agent
+	}
+	body := event.Message
+	if body == "" {
+		body = string(event.Type)
+	}
+	if len(body) > 240 {
+		body = body[:237] + "..."
+	}
+	attach := ""
+	if event.Attach != nil {
+		attach = event.Attach.Command
+	}
+
+	switch a.tool {
+	case terminalNotifier:
+		args := []string{"-title", "multiclaude", "-subtitle", subtitle + ": " + event.Title, "-message", body, "-group", event.ID}
+		if attach != "" {
+			args = append(args, "-execute", macTerminalScript(attach))
+		}
+		_, err := a.exec(terminalNotifier, args...)
+		return err
+
+	case osascript:
+		if attach != "" {
+			body += "\nJump in: " + attach
+		}
+		script := fmt.Sprintf("display notification %s with title %s subtitle %s",
+			appleScriptString(body), appleScriptString("multiclaude"), appleScriptString(subtitle+": "+event.Title))
+		_, err := a.exec(osascript, "-e", script)
+		return err
+
+	default:
+		args := []string{"--app-name=multiclaude", "--urgency=" + desktopUrgency(event.Priority), event.Title, subtitle + "\n" + body}
+		if attach == "" {
+			_, err := a.exec(notifySend, args...)
+			return err
+		}
+		// --wait blocks until the notification is clicked or dismissed, so
+		// it is watched in the background
+		a.clicks.Add(1)
+		go func() {
+			defer a.clicks.Done()
+			output, err := a.exec(notifySend, append([]string{"--action=attach=Attach", "--wait"}, args...)...)
+			if err != nil {
+				// libnotify before 0.7.9 has no actions
+				a.exec(notifySend, args...)
+				return
+			}
+			if strings.TrimSpace(string(output)) == "attach" {
+				a.openTerminal(attach)
+			}
+		}()
+		return nil
+	}
+}
+
+// openTerminal runs command in a new terminal window on Linux, using
+// $TERMINAL or the Debian-style x-terminal-emulator alternative
+func (a *DesktopAdapter) openTerminal(command string) {
+	terminal := os.Getenv("TERMINAL")
+	if terminal == "" {
+		terminal = "x-terminal-emulator"
+	}
+	a.exec(terminal, "-e", "sh", "-c", command)
+}
+
+// macTerminalScript is a shell command that opens Terminal running command
+func macTerminalScript(command string) string {
+	script := fmt.Sprintf("tell application \"Terminal\" to do script %s", appleScriptString(command))
+	return "osascript -e " + shellQuote(script) + " -e 'tell application \"Terminal\" to activate'"
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// shellQuote single-quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// desktopUrgency maps an event's priority to a libnotify urgency
+func desktopUrgency(p events.Priority) string {
+	switch p {
+	case events.PriorityHigh:
+		return "critical"
+	case events.PriorityLow:
+		return "low"
+	}
+	return "normal"
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// commandRecorder records the commands a DesktopAdapter runs
+type commandRecorder struct {
+	mu       sync.Mutex
+	commands [][]string
+	output   map[string]string // Output per command name
+}
+
+func (r *commandRecorder) exec(name string, args ...string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, append([]string{name}, args...))
+	return []byte(r.output[name]), nil
+}
+
+func questionEvent() events.Event {
+	event := events.NewEvent(events.EventAgentQuestion, "repo", "worker-1", "worker-1 has a question")
+	event.Message = "Postgres or SQLite?"
+	event.Priority = events.PriorityHigh
+	event.Attach = events.NewAttachTarget("repo", "worker-1", "mc-repo", "worker-1")
+	return event
+}
+
+func TestParseDesktopTypes(t *testing.T) {
+	types, err := ParseDesktopTypes("1")
+	if err != nil || len(types) != len(DefaultDesktopTypes) {
+		t.Errorf("ParseDesktopTypes(1) = %v, %v; want the defaults", types, err)
+	}
+	types, err = ParseDesktopTypes("agent.stuck, agent.question")
+	if err != nil || len(types) != 2 || types[0] != events.EventAgentStuck {
+		t.Errorf("ParseDesktopTypes(list) = %v, %v", types, err)
+	}
+	for _, spec := range []string{"agent.bogus", ","} {
+		if _, err := ParseDesktopTypes(spec); err == nil {
+			t.Errorf("ParseDesktopTypes(%q) should fail", spec)
+		}
+	}
+}
+
+func TestDesktopAdapterNotifySend(t *testing.T) {
+	recorder := &commandRecorder{output: map[string]string{notifySend: "attach\n"}}
+	adapter := newDesktopAdapter(notifySend, DefaultDesktopTypes)
+	adapter.exec = recorder.exec
+
+	completed := events.NewEvent(events.EventAgentCompleted, "repo", "worker-1", "done")
+	if err := adapter.Send(context.Background(), completed); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := adapter.Send(context.Background(), questionEvent()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	adapter.clicks.Wait()
+
+	if len(recorder.commands) != 2 {
+		t.Fatalf("ran %v, want the notification and the terminal opened by the click", recorder.commands)
+	}
+	notification := strings.Join(recorder.commands[0], " ")
+	for _, want := range []string{"--action=attach=Attach", "--urgency=critical", "worker-1 has a question", "Postgres or SQLite?"} {
+		if !strings.Contains(notification, want) {
+			t.Errorf("notification %q should contain %q", notification, want)
+		}
+	}
+	terminal := recorder.commands[1]
+	if terminal[len(terminal)-1] != "multiclaude attach worker-1 --repo repo" {
+		t.Errorf("click should attach to the agent, ran %v", terminal)
+	}
+}
+
+func TestDesktopAdapterMacOS(t *testing.T) {
+	recorder := &commandRecorder{}
+	adapter := newDesktopAdapter(terminalNotifier, DefaultDesktopTypes)
+	adapter.exec = recorder.exec
+	if err := adapter.Send(context.Background(), questionEvent()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	args := recorder.commands[0]
+	if args[0] != terminalNotifier || args[len(args)-2] != "-execute" || !strings.Contains(args[len(args)-1], "multiclaude attach worker-1") {
+		t.Errorf("terminal-notifier should run the attach command on click, ran %v", args)
+	}
+
+	recorder.commands = nil
+	adapter = newDesktopAdapter(osascript, DefaultDesktopTypes)
+	adapter.exec = recorder.exec
+	event := questionEvent()
+	event.Message = `Use "Postgres"?`
+	if err := adapter.Send(context.Background(), event); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	script := recorder.commands[0][2]
+	if !strings.Contains(script, `display notification "Use \"Postgres\"?`) || !strings.Contains(script, "Jump in: multiclaude attach") {
+		t.Errorf("unexpected AppleScript %q", script)
+	}
+}
@@ -0,0 +1,396 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// EmailEnv is the environment variable that enables the email adapter. It is
+// a comma-separated list of settings; to may be repeated:
+//
+//	MULTICLAUDE_EMAIL=host=smtp.example.com,port=587,from=bot@example.com,to=me@example.com,min-priority=high,digest=15m
+//
+// The SMTP password is read from SMTPPasswordEnv so it stays out of the spec.
+// Setting it overrides the config file's notifications.email.
+const EmailEnv = "MULTICLAUDE_EMAIL"
+
+// SMTPPasswordEnv holds the password for the email adapter's SMTP login
+const SMTPPasswordEnv = "MULTICLAUDE_SMTP_PASSWORD"
+
+// EmailConfig configures the email adapter
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string // Defaults to From when a password is set
+	Password string
+	From     string
+	To       []string
+	// ImplicitTLS connects over TLS (SMTPS, usually port 465). Otherwise the
+	// connection must be upgraded with STARTTLS; plaintext is never used.
+	ImplicitTLS bool
+	// MinPriority is the lowest priority that is emailed
+	MinPriority events.Priority
+	// Digest batches events into one email per interval; 0 sends each event
+	// as it happens
+	Digest time.Duration
+}
+
+// ParseEmailConfig parses the value of MULTICLAUDE_EMAIL
+func ParseEmailConfig(spec string) (EmailConfig, error) {
+	cfg := defaultEmailConfig()
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return cfg, fmt.Errorf("invalid email setting %q: expected key=value", field)
+		}
+		if err := cfg.set(key, value); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, cfg.finish()
+}
+
+func defaultEmailConfig() EmailConfig {
+	return EmailConfig{Port: 587, MinPriority: events.PriorityHigh}
+}
+
+// set applies one email setting
+func (cfg *EmailConfig) set(key, value string) error {
+	var err error
+	switch key {
+	case "host":
+		cfg.Host = value
+	case "port":
+		cfg.Port, err = strconv.Atoi(value)
+		if err == nil && (cfg.Port <= 0 || cfg.Port > 65535) {
+			err = fmt.Errorf("must be between 1 and 65535")
+		}
+	case "user":
+		cfg.Username = value
+	case "from":
+		cfg.From = value
+	case "to":
+		cfg.To = append(cfg.To, value)
+	case "tls":
+		switch value {
+		case "implicit":
+			cfg.ImplicitTLS = true
+		case "starttls":
+			cfg.ImplicitTLS = false
+		default:
+			err = fmt.Errorf("must be starttls or implicit")
+		}
+	case "min-priority":
+		cfg.MinPriority = events.Priority(value)
+		if priorityRank(cfg.MinPriority) < 0 {
+			err = fmt.Errorf("must be low, normal, or high")
+		}
+	case "digest":
+		cfg.Digest, err = time.ParseDuration(value)
+		if err == nil && cfg.Digest < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+	default:
+		return fmt.Errorf("unknown email setting %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid email setting %s=%s: %w", key, value, err)
+	}
+	return nil
+}
+
+// finish checks the settings are complete
+func (cfg *EmailConfig) finish() error {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return fmt.Errorf("email settings need host, from, and at least one to")
+	}
+	return nil
+}
+
+// priorityRank orders priorities from low to high; unknown priorities rank -1
+func priorityRank(p events.Priority) int {
+	switch p {
+	case events.PriorityLow:
+		return 0
+	case events.PriorityNormal:
+		return 1
+	case events.PriorityHigh:
+		return 2
+	}
+	return -1
+}
+
+// EmailAdapter emails events over SMTP, either one message per event or a
+// periodic digest
+type EmailAdapter struct {
+	cfg   EmailConfig
+	clock clock.Clock
+	send  func(cfg EmailConfig, msg []byte) error
+	ack   func(eventID string) error // nil when nothing tracks deliveries
+
+	mu      sync.Mutex
+	pending []events.Event
+}
+
+// EmailOption configures an EmailAdapter
+type EmailOption func(*EmailAdapter)
+
+// WithEmailClock sets the clock digests are timed by
+func WithEmailClock(c clock.Clock) EmailOption {
+	return func(a *EmailAdapter) {
+		a.clock = c
+	}
+}
+
+// WithEmailAcknowledge sets the function called for each digested event once
+// its digest has been mailed, typically wrapping Hub.Acknowledge
+func WithEmailAcknowledge(ack func(eventID string) error) EmailOption {
+	return func(a *EmailAdapter) {
+		a.ack = ack
+	}
+}
+
+// NewEmailAdapter creates an adapter that emails events according to cfg
+func NewEmailAdapter(cfg EmailConfig, opts ...EmailOption) *EmailAdapter {
+	if cfg.MinPriority == "" {
+		cfg.MinPriority = events.PriorityHigh
+	}
+	if cfg.Username == "" && cfg.Password != "" {
+		cfg.Username = cfg.From
+	}
+	a := &EmailAdapter{cfg: cfg, clock: clock.Real(), send: sendSMTP}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Name implements Adapter
+func (a *EmailAdapter) Name() string {
+	return "email"
+}
+
+// Send implements Adapter. Events below the minimum priority are dropped.
+// In digest mode the first event of a batch starts the digest timer and the
+// batch is mailed when it fires (or when ctx is done); Send returns
+// ErrDeferred so the event stays undelivered until Flush mails it.
+func (a *EmailAdapter) Send(ctx context.Context, event events.Event) error {
+	if priorityRank(event.Priority) < priorityRank(a.cfg.MinPriority) {
+		return nil
+	}
+	if a.cfg.Digest <= 0 {
+		return a.mail([]events.Event{event})
+	}
+
+	a.mu.Lock()
+	a.pending = append(a.pending, event)
+	first := len(a.pending) == 1
+	a.mu.Unlock()
+
+	if first {
+		timer := a.clock.After(a.cfg.Digest)
+		go func() {
+			select {
+			case <-timer:
+			case <-ctx.Done():
+			}
+			a.Flush()
+		}()
+	}
+	return ErrDeferred
+}
+
+// Flush mails the pending digest, if any, and acknowledges its events. A
+// digest that fails to send is not retried here; its events stay
+// unacknowledged and are replayed after a restart.
+func (a *EmailAdapter) Flush() error {
+	a.mu.Lock()
+	batch := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := a.mail(batch); err != nil {
+		return err
+	}
+	if a.ack == nil {
+		return nil
+	}
+	var failed []string
+	for _, event := range batch {
+		if err := a.ack(event.ID); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to acknowledge digested events: %v", failed)
+	}
+	return nil
+}
+
+// mail sends one message covering batch
+func (a *EmailAdapter) mail(batch []events.Event) error {
+	msg, err := buildEmail(a.cfg, a.clock.Now(), batch)
+	if err != nil {
+		return err
+	}
+	return a.send(a.cfg, msg)
+}
+
+// emailSubject is the subject line for batch
+func emailSubject(batch []events.Event) string {
+	if len(batch) == 1 {
+		return "[multiclaude] " + batch[0].Title
+	}
+	return fmt.Sprintf("[multiclaude] %d events", len(batch))
+}
+
+var emailTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif">
+{{range .}}<div style="margin-bottom: 1.5em">
+<h3 style="margin: 0">{{.Title}}</h3>
+<p style="margin: 0.25em 0; color: #666">{{.Type}} &middot; {{.Priority}}{{if .Repo}} &middot; {{.Repo}}{{if .Agent}}/{{.Agent}}{{end}}{{end}} &middot; {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}</p>
+{{if .Message}}<pre style="white-space: pre-wrap">{{.Message}}</pre>{{end}}
+{{if .Attach}}<p>Jump in: <code>{{.Attach.Command}}</code><br>or: <code>{{.Attach.Tmux}}</code></p>{{end}}
+</div>
+{{end}}</body></html>
+`))
+
+// buildEmail renders batch as a multipart message with a plain-text and an
+// HTML part
+func buildEmail(cfg EmailConfig, now time.Time, batch []events.Event) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	var text bytes.Buffer
+	for i, event := range batch {
+		if i > 0 {
+			text.WriteString("\n\n----\n\n")
+		}
+		text.WriteString(event.Text())
+	}
+	var html bytes.Buffer
+	if err := emailTemplate.Execute(&html, batch); err != nil {
+		return nil, fmt.Errorf("failed to render email: %w", err)
+	}
+
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", emailSubject(batch)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// smtpTimeout bounds a whole SMTP conversation
+const smtpTimeout = 30 * time.Second
+
+// sendSMTP delivers msg over TLS, either from the start or after STARTTLS
+func sendSMTP(cfg EmailConfig, msg []byte) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if cfg.ImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if !cfg.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS; refusing to send in plaintext", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
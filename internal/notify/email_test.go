@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// mailbox records the messages an EmailAdapter sends
+type mailbox struct {
+	mu       sync.Mutex
+	messages []string
+	err      error // returned instead of accepting a message
+}
+
+func (m *mailbox) send(cfg EmailConfig, msg []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, string(msg))
+	return nil
+}
+
+func (m *mailbox) received() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.messages...)
+}
+
+func TestParseEmailConfig(t *testing.T) {
+	cfg, err := ParseEmailConfig("host=smtp.example.com,from=bot@example.com,to=a@example.com,to=b@example.com,digest=15m")
+	if err != nil {
+		t.Fatalf("ParseEmailConfig failed: %v", err)
+	}
+	if cfg.Host != "smtp.example.com" || cfg.Port != 587 || cfg.ImplicitTLS {
+		t.Errorf("unexpected connection settings %+v", cfg)
+	}
+	if len(cfg.To) != 2 || cfg.MinPriority != events.PriorityHigh || cfg.Digest != 15*time.Minute {
+		t.Errorf("unexpected delivery settings %+v", cfg)
+	}
+
+	for _, spec := range []string{
+		"host=smtp.example.com,to=a@example.com",
+		"host=smtp.example.com,from=bot@example.com,to=a@example.com,port=0",
+		"host=smtp.example.com,from=bot@example.com,to=a@example.com,tls=none",
+		"host=smtp.example.com,from=bot@example.com,to=a@example.com,min-priority=urgent",
+		"host=smtp.example.com,from=bot@example.com,to=a@example.com,colour=blue",
+	} {
+		if _, err := ParseEmailConfig(spec); err == nil {
+			t.Errorf("ParseEmailConfig(%q) should fail", spec)
+		}
+	}
+}
+
+func TestEmailAdapterFiltersByPriority(t *testing.T) {
+	box := &mailbox{}
+	adapter := NewEmailAdapter(EmailConfig{From: "bot@example.com", To: []string{"me@example.com"}})
+	adapter.send = box.send
+
+	low := events.NewEvent(events.EventAgentCompleted, "repo", "worker-1", "done")
+	if err := adapter.Send(context.Background(), low); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(box.received()) != 0 {
+		t.Fatal("normal-priority event should not be emailed with the default minimum")
+	}
+
+	high := events.NewEvent(events.EventAgentQuestion, "repo", "worker-1", "worker-1 asks <which> DB?")
+	high.Priority = events.PriorityHigh
+	high.Message = "Postgres or SQLite?"
+	if err := adapter.Send(context.Background(), high); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	messages := box.received()
+	if len(messages) != 1 {
+		t.Fatalf("got %d emails, want 1", len(messages))
+	}
+	msg := messages[0]
+	for _, want := range []string{"To: me@example.com", "Subject: [multiclaude] worker-1 asks <which> DB?", "text/html", "&lt;which&gt;", "Postgres or SQLite?"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("email should contain %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestEmailAdapterDigest(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	box := &mailbox{}
+	adapter := NewEmailAdapter(EmailConfig{
+		From:        "bot@example.com",
+		To:          []string{"me@example.com"},
+		MinPriority: events.PriorityLow,
+		Digest:      10 * time.Minute,
+	}, WithEmailClock(fake))
+	adapter.send = box.send
+
+	for _, title := range []string{"first", "second", "third"} {
+		if err := adapter.Send(context.Background(), events.NewEvent(events.EventAgentCompleted, "repo", "w", title)); !errors.Is(err, ErrDeferred) {
+			t.Fatalf("Send = %v, want ErrDeferred", err)
+		}
+	}
+	fake.BlockUntil(1)
+	if len(box.received()) != 0 {
+		t.Fatal("digest should not be sent before its interval")
+	}
+
+	fake.Advance(10 * time.Minute)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(box.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	messages := box.received()
+	if len(messages) != 1 {
+		t.Fatalf("got %d emails, want one digest", len(messages))
+	}
+	if !strings.Contains(messages[0], "3 events") || !strings.Contains(messages[0], "third") {
+		t.Errorf("digest should cover all three events:\n%s", messages[0])
+	}
+
+	if err := adapter.Flush(); err != nil || len(box.received()) != 1 {
+		t.Errorf("flushing an empty digest should send nothing (err %v)", err)
+	}
+}
+
+func TestEmailDigestAcknowledgedOnlyOnceSent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	now := time.Now()
+	cfg := EmailConfig{
+		From:        "bot@example.com",
+		To:          []string{"me@example.com"},
+		MinPriority: events.PriorityLow,
+		Digest:      time.Hour,
+	}
+	// newDaemon wires an email adapter to a hub over the store at path, the
+	// way the daemon does
+	newDaemon := func(box *mailbox) (*Hub, *EmailAdapter) {
+		store, err := OpenStore(path, 0, now)
+		if err != nil {
+			t.Fatalf("OpenStore failed: %v", err)
+		}
+		hub := NewHub(WithStore(store))
+		var adapter *EmailAdapter
+		adapter = NewEmailAdapter(cfg, WithEmailAcknowledge(func(eventID string) error {
+			return hub.Acknowledge(eventID, adapter.Name())
+		}))
+		adapter.send = box.send
+		hub.Register(adapter)
+		return hub, adapter
+	}
+
+	hub, adapter := newDaemon(&mailbox{err: errors.New("smtp down")})
+	if err := hub.Notify(context.Background(), events.NewEvent(events.EventAgentCompleted, "repo", "w", "done")); err != nil {
+		t.Fatalf("a deferred delivery should not fail Notify: %v", err)
+	}
+	if pending, _ := hub.Store().Pending(); len(pending) != 1 {
+		t.Fatalf("a digested event should stay pending until mailed, got %d", len(pending))
+	}
+	if err := adapter.Flush(); err == nil {
+		t.Fatal("Flush should report the failed digest")
+	}
+	if pending, _ := hub.Store().Pending(); len(pending) != 1 {
+		t.Fatalf("an unsent digest should stay pending, got %d", len(pending))
+	}
+
+	// After a restart the event is replayed into the next digest
+	box := &mailbox{}
+	restarted, adapter := newDaemon(box)
+	if replayed, err := restarted.Replay(context.Background()); err != nil || replayed != 1 {
+		t.Fatalf("Replay() = %d, %v; want 1", replayed, err)
+	}
+	if err := adapter.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if messages := box.received(); len(messages) != 1 || !strings.Contains(messages[0], "done") {
+		t.Errorf("the replayed event should be mailed once, got %d emails", len(messages))
+	}
+	if pending, _ := restarted.Store().Pending(); len(pending) != 0 {
+		t.Errorf("a mailed digest should be acknowledged, %d events still pending", len(pending))
+	}
+}
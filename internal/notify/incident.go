@@ -0,0 +1,393 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// IncidentEnv is the environment variable that enables the incident adapter,
+// which pages through PagerDuty or Opsgenie when an agent keeps failing. It
+// is a comma-separated list of settings; agent-type may be repeated:
+//
+//	MULTICLAUDE_INCIDENTS=provider=pagerduty,threshold=3,window=1h,agent-type=merge-queue
+//
+// The PagerDuty routing key or Opsgenie API key is read from IncidentKeyEnv.
+// Setting it overrides the config file's notifications.incident.
+const IncidentEnv = "MULTICLAUDE_INCIDENTS"
+
+// IncidentKeyEnv holds the PagerDuty integration (routing) key or the
+// Opsgenie API key
+const IncidentKeyEnv = "MULTICLAUDE_INCIDENT_KEY"
+
+// Incident providers
+const (
+	ProviderPagerDuty = "pagerduty"
+	ProviderOpsgenie  = "opsgenie"
+)
+
+// Incident defaults
+const (
+	DefaultIncidentThreshold = 3
+	DefaultIncidentWindow    = time.Hour
+	DefaultIncidentSeverity  = "error"
+	pagerDutyURL             = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieURL              = "https://api.opsgenie.com/v2/alerts"
+)
+
+// IncidentConfig configures the incident adapter
+type IncidentConfig struct {
+	Provider string
+	Key      string
+	// Threshold is how many agent.error or agent.stuck events an agent
+	// produces within Window before an incident is opened
+	Threshold int
+	Window    time.Duration
+	// AgentTypes limits incidents to these agent types; empty means all
+	AgentTypes []string
+	// Severity is the PagerDuty severity (critical, error, warning, info);
+	// it picks the Opsgenie priority too
+	Severity string
+	// URL overrides the provider's API endpoint, e.g. for Opsgenie's EU region
+	URL     string
+	Timeout time.Duration
+}
+
+// ParseIncidentConfig parses the value of MULTICLAUDE_INCIDENTS. key is the
+// value of MULTICLAUDE_INCIDENT_KEY.
+func ParseIncidentConfig(spec, key string) (IncidentConfig, error) {
+	cfg := defaultIncidentConfig()
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return cfg, fmt.Errorf("invalid incident setting %q: expected key=value", field)
+		}
+		if err := cfg.set(name, value); err != nil {
+			return cfg, err
+		}
+	}
+	if cfg.Provider != "" && key == "" {
+		return cfg, fmt.Errorf("%s must be set to open %s incidents", IncidentKeyEnv, cfg.Provider)
+	}
+	return cfg, cfg.finish(key)
+}
+
+func defaultIncidentConfig() IncidentConfig {
+	return IncidentConfig{
+		Threshold: DefaultIncidentThreshold,
+		Window:    DefaultIncidentWindow,
+		Severity:  DefaultIncidentSeverity,
+		Timeout:   DefaultWebhookTimeout,
+	}
+}
+
+// set applies one incident setting
+func (cfg *IncidentConfig) set(name, value string) error {
+	var err error
+	switch name {
+	case "provider":
+		cfg.Provider = value
+		if value != ProviderPagerDuty && value != ProviderOpsgenie {
+			err = fmt.Errorf("must be %s or %s", ProviderPagerDuty, ProviderOpsgenie)
+		}
+	case "threshold":
+		cfg.Threshold, err = strconv.Atoi(value)
+		if err == nil && cfg.Threshold < 1 {
+			err = fmt.Errorf("must be at least 1")
+		}
+	case "window":
+		cfg.Window, err = time.ParseDuration(value)
+		if err == nil && cfg.Window <= 0 {
+			err = fmt.Errorf("must be positive")
+		}
+	case "agent-type":
+		cfg.AgentTypes = append(cfg.AgentTypes, value)
+	case "severity":
+		cfg.Severity = value
+		if opsgeniePriority(value) == "" {
+			err = fmt.Errorf("must be critical, error, warning, or info")
+		}
+	case "url":
+		cfg.URL = value
+		if u, perr := url.Parse(value); perr != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			err = fmt.Errorf("must be an http or https URL")
+		}
+	case "timeout":
+		cfg.Timeout, err = time.ParseDuration(value)
+		if err == nil && cfg.Timeout <= 0 {
+			err = fmt.Errorf("must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown incident setting %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid incident setting %s=%s: %w", name, value, err)
+	}
+	return nil
+}
+
+// finish checks the settings are complete and sets the provider's key
+func (cfg *IncidentConfig) finish(key string) error {
+	if cfg.Provider == "" {
+		return fmt.Errorf("incident settings need a provider (%s or %s)", ProviderPagerDuty, ProviderOpsgenie)
+	}
+	if key == "" {
+		return fmt.Errorf("incident settings need the %s key", cfg.Provider)
+	}
+	cfg.Key = key
+	return nil
+}
+
+// opsgeniePriority maps a PagerDuty severity to an Opsgenie priority, or ""
+// for an unknown severity
+func opsgeniePriority(severity string) string {
+	switch severity {
+	case "critical":
+		return "P1"
+	case "error":
+		return "P2"
+	case "warning":
+		return "P3"
+	case "info":
+		return "P5"
+	}
+	return ""
+}
+
+// IncidentAdapter opens an incident when an agent produces Threshold
+// agent.error or agent.stuck events within Window, and resolves it when the
+// agent next sends agent.completed. Incidents are deduplicated per agent, so
+// an agent has at most one open incident. Which incidents are open is kept
+// in memory: one left open across a daemon restart is resolved by hand.
+type IncidentAdapter struct {
+	cfg   IncidentConfig
+	clock clock.Clock
+	http  *http.Client
+
+	mu sync.Mutex
+	// failures holds the recent error and stuck times per agent key
+	failures map[string][]time.Time
+	// open holds the agents with an open incident
+	open map[string]bool
+}
+
+// IncidentOption configures an IncidentAdapter
+type IncidentOption func(*IncidentAdapter)
+
+// WithIncidentClock sets the clock failures are counted by
+func WithIncidentClock(c clock.Clock) IncidentOption {
+	return func(a *IncidentAdapter) {
+		a.clock = c
+	}
+}
+
+// NewIncidentAdapter creates an adapter that pages according to cfg
+func NewIncidentAdapter(cfg IncidentConfig, opts ...IncidentOption) *IncidentAdapter {
+	if cfg.Threshold < 1 {
+		cfg.Threshold = DefaultIncidentThreshold
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultIncidentWindow
+	}
+	if cfg.Severity == "" {
+		cfg.Severity = DefaultIncidentSeverity
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultWebhookTimeout
+	}
+	if cfg.URL == "" {
+		cfg.URL = pagerDutyURL
+		if cfg.Provider == ProviderOpsgenie {
+			cfg.URL = opsgenieURL
+		}
+	}
+	a := &IncidentAdapter{
+		cfg:      cfg,
+		clock:    clock.Real(),
+		http:     &http.Client{Timeout: cfg.Timeout},
+		failures: make(map[string][]time.Time),
+		open:     make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Name implements Adapter
+func (a *IncidentAdapter) Name() string {
+	return a.cfg.Provider
+}
+
+// Send implements Adapter. Events other than agent.error, agent.stuck, and
+// agent.completed, and events about agents of other types, are ignored.
+func (a *IncidentAdapter) Send(ctx context.Context, event events.Event) error {
+	if event.Agent == "" || !a.watches(event.AgentType) {
+		return nil
+	}
+	key := incidentKey(event)
+
+	switch event.Type {
+	case events.EventAgentError, events.EventAgentStuck:
+		a.mu.Lock()
+		cutoff := a.clock.Now().Add(-a.cfg.Window)
+		recent := []time.Time{}
+		for _, t := range a.failures[key] {
+			if t.After(cutoff) {
+				recent = append(recent, t)
+			}
+		}
+		recent = append(recent, a.clock.Now())
+		a.failures[key] = recent
+		trigger := len(recent) >= a.cfg.Threshold && !a.open[key]
+		a.mu.Unlock()
+		if !trigger {
+			return nil
+		}
+		if err := a.trigger(ctx, key, event, len(recent)); err != nil {
+			return err
+		}
+		a.mu.Lock()
+		a.open[key] = true
+		a.mu.Unlock()
+
+	case events.EventAgentCompleted:
+		a.mu.Lock()
+		delete(a.failures, key)
+		wasOpen := a.open[key]
+		a.mu.Unlock()
+		if !wasOpen {
+			return nil
+		}
+		if err := a.resolve(ctx, key, event); err != nil {
+			return err
+		}
+		a.mu.Lock()
+		delete(a.open, key)
+		a.mu.Unlock()
+	}
+	return nil
+}
+
+// watches reports whether incidents are opened for agents of agentType
+func (a *IncidentAdapter) watches(agentType string) bool {
+	if len(a.cfg.AgentTypes) == 0 {
+		return true
+	}
+	for _, t := range a.cfg.AgentTypes {
+		if t == agentType {
+			return true
+		}
+	}
+	return false
+}
+
+// incidentKey identifies an agent's incident to the provider (PagerDuty's
+// dedup_key, Opsgenie's alias)
+func incidentKey(event events.Event) string {
+	return "multiclaude/" + event.Repo + "/" + event.Agent
+}
+
+// trigger opens the incident for key
+func (a *IncidentAdapter) trigger(ctx context.Context, key string, event events.Event, failures int) error {
+	summary := fmt.Sprintf("%s/%s failed %d times in %s: %s", event.Repo, event.Agent, failures, a.cfg.Window, event.Title)
+	details := map[string]interface{}{
+		"repo":       event.Repo,
+		"agent":      event.Agent,
+		"agent_type": event.AgentType,
+		"last_event": event.Type,
+		"failures":   failures,
+	}
+	if event.Attach != nil {
+		details["attach"] = event.Attach.Command
+	}
+
+	if a.cfg.Provider == ProviderOpsgenie {
+		return a.post(ctx, a.cfg.URL, map[string]interface{}{
+			"message":     truncateRunes(summary, 130),
+			"alias":       key,
+			"description": event.Text(),
+			"priority":    opsgeniePriority(a.cfg.Severity),
+			"source":      "multiclaude",
+			"details":     details,
+		})
+	}
+	return a.post(ctx, a.cfg.URL, map[string]interface{}{
+		"routing_key":  a.cfg.Key,
+		"event_action": "trigger",
+		"dedup_key":    key,
+		"payload": map[string]interface{}{
+			"summary":        truncateRunes(summary, 1024),
+			"source":         "multiclaude",
+			"severity":       a.cfg.Severity,
+			"timestamp":      event.Timestamp.Format(time.RFC3339),
+			"custom_details": details,
+		},
+	})
+}
+
+// resolve closes the incident for key
+func (a *IncidentAdapter) resolve(ctx context.Context, key string, event events.Event) error {
+	if a.cfg.Provider == ProviderOpsgenie {
+		closeURL := strings.TrimSuffix(a.cfg.URL, "/") + "/" + url.PathEscape(key) + "/close?identifierType=alias"
+		return a.post(ctx, closeURL, map[string]interface{}{
+			"source": "multiclaude",
+			"note":   event.Title,
+		})
+	}
+	return a.post(ctx, a.cfg.URL, map[string]interface{}{
+		"routing_key":  a.cfg.Key,
+		"event_action": "resolve",
+		"dedup_key":    key,
+	})
+}
+
+// post sends body to the provider
+func (a *IncidentAdapter) post(ctx context.Context, target string, body map[string]interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", a.cfg.Provider, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.cfg.Provider == ProviderOpsgenie {
+		req.Header.Set("Authorization", "GenieKey "+a.cfg.Key)
+	}
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", a.cfg.Provider, err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %s: %s", a.cfg.Provider, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// truncateRunes shortens s to at most n runes, as providers cap summary lengths
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestParseIncidentConfig(t *testing.T) {
+	cfg, err := ParseIncidentConfig("provider=opsgenie,threshold=2,window=30m,agent-type=merge-queue,severity=critical", "k")
+	if err != nil {
+		t.Fatalf("ParseIncidentConfig failed: %v", err)
+	}
+	if cfg.Provider != ProviderOpsgenie || cfg.Threshold != 2 || cfg.Window != 30*time.Minute || cfg.AgentTypes[0] != "merge-queue" || cfg.Key != "k" {
+		t.Errorf("unexpected settings %+v", cfg)
+	}
+
+	for _, tc := range []struct{ spec, key string }{
+		{"provider=pagerduty", ""},
+		{"threshold=2", "k"},
+		{"provider=victorops", "k"},
+		{"provider=pagerduty,threshold=0", "k"},
+		{"provider=pagerduty,severity=urgent", "k"},
+		{"provider=pagerduty,colour=blue", "k"},
+	} {
+		if _, err := ParseIncidentConfig(tc.spec, tc.key); err == nil {
+			t.Errorf("ParseIncidentConfig(%q, %q) should fail", tc.spec, tc.key)
+		}
+	}
+}
+
+type incidentRequest struct {
+	path string
+	auth string
+	body map[string]interface{}
+}
+
+func incidentServer(t *testing.T) (*httptest.Server, func() []incidentRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []incidentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		json.Unmarshal(data, &body)
+		mu.Lock()
+		requests = append(requests, incidentRequest{path: r.URL.RequestURI(), auth: r.Header.Get("Authorization"), body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []incidentRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]incidentRequest(nil), requests...)
+	}
+}
+
+func TestIncidentAdapterPagerDuty(t *testing.T) {
+	server, requests := incidentServer(t)
+	fake := clock.NewFake(time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	a := NewIncidentAdapter(IncidentConfig{Provider: ProviderPagerDuty, Key: "routing", Threshold: 2, Window: time.Hour, AgentTypes: []string{"merge-queue"}, URL: server.URL}, WithIncidentClock(fake))
+
+	send := func(eventType events.EventType, agentType string) {
+		t.Helper()
+		event := events.NewEvent(eventType, "repo", "merge-queue", string(eventType))
+		event.AgentType = agentType
+		if err := a.Send(context.Background(), event); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	// Failures outside the window and of other agent types don't count
+	send(events.EventAgentError, "merge-queue")
+	fake.Advance(2 * time.Hour)
+	send(events.EventAgentStuck, "worker")
+	send(events.EventAgentStuck, "merge-queue")
+	if got := requests(); len(got) != 0 {
+		t.Fatalf("no incident expected yet, got %+v", got)
+	}
+
+	send(events.EventAgentError, "merge-queue")
+	send(events.EventAgentError, "merge-queue") // already open
+	send(events.EventAgentCompleted, "merge-queue")
+	send(events.EventAgentCompleted, "merge-queue") // already resolved
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("expected a trigger and a resolve, got %+v", got)
+	}
+	if got[0].body["event_action"] != "trigger" || got[0].body["routing_key"] != "routing" || got[0].body["dedup_key"] != "multiclaude/repo/merge-queue" {
+		t.Errorf("unexpected trigger %+v", got[0].body)
+	}
+	if got[1].body["event_action"] != "resolve" || got[1].body["dedup_key"] != got[0].body["dedup_key"] {
+		t.Errorf("unexpected resolve %+v", got[1].body)
+	}
+}
+
+func TestIncidentAdapterOpsgenie(t *testing.T) {
+	server, requests := incidentServer(t)
+	a := NewIncidentAdapter(IncidentConfig{Provider: ProviderOpsgenie, Key: "api-key", Threshold: 1, URL: server.URL + "/v2/alerts"})
+
+	ctx := context.Background()
+	if err := a.Send(ctx, events.NewEvent(events.EventAgentStuck, "repo", "w1", "w1 is stuck")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := a.Send(ctx, events.NewEvent(events.EventAgentCompleted, "repo", "w1", "w1 completed")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("expected create and close, got %+v", got)
+	}
+	if got[0].path != "/v2/alerts" || got[0].auth != "GenieKey api-key" || got[0].body["alias"] != "multiclaude/repo/w1" || got[0].body["priority"] != "P2" {
+		t.Errorf("unexpected create %+v", got[0])
+	}
+	if got[1].path != "/v2/alerts/multiclaude%2Frepo%2Fw1/close?identifierType=alias" {
+		t.Errorf("unexpected close %+v", got[1])
+	}
+}
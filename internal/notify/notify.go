@@ -0,0 +1,293 @@
+// Package notify provides the hub that fans the daemon's notification events
+// (see pkg/events) out to registered adapters (log, chat, webhooks, etc.).
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// Adapter delivers events to an external destination
+type Adapter interface {
+	// Name returns a short identifier for the adapter (e.g. "log", "slack")
+	Name() string
+	// Send delivers a single event
+	Send(ctx context.Context, event events.Event) error
+}
+
+// ErrDeferred is returned by an adapter's Send when it has accepted the event
+// but will deliver it later (e.g. in a digest). The hub neither counts it as
+// a failure nor records the delivery; the adapter calls Hub.Acknowledge once
+// the event is actually delivered, so an unsent event is replayed after a
+// restart.
+var ErrDeferred = errors.New("delivery deferred")
+
+// DefaultRecentEvents is the number of events kept in memory by the hub
+const DefaultRecentEvents = 100
+
+// Hub fans events out to registered adapters and keeps a short in-memory history
+type Hub struct {
+	mu        sync.RWMutex
+	adapters  []Adapter
+	recent    []events.Event
+	maxRecent int
+	clock     clock.Clock
+	store     *Store // nil keeps events in memory only
+	routes    Routes // nil sends every event to every adapter
+}
+
+// HubOption configures a Hub
+type HubOption func(*Hub)
+
+// WithClock sets the clock used to stamp events that have no timestamp
+func WithClock(c clock.Clock) HubOption {
+	return func(h *Hub) {
+		h.clock = c
+	}
+}
+
+// WithStore records every event and its deliveries in s, so they can be
+// queried later and replayed after a restart
+func WithStore(s *Store) HubOption {
+	return func(h *Hub) {
+		h.store = s
+	}
+}
+
+// WithRoutes limits which events the adapters the routes name receive
+func WithRoutes(routes Routes) HubOption {
+	return func(h *Hub) {
+		h.routes = routes
+	}
+}
+
+// NewHub creates an empty hub
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{maxRecent: DefaultRecentEvents, clock: clock.Real()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Register adds an adapter to the hub
+func (h *Hub) Register(adapter Adapter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.adapters = append(h.adapters, adapter)
+}
+
+// SetRoutes replaces the hub's routes, e.g. when settings are reloaded; nil
+// sends every event to every adapter
+func (h *Hub) SetRoutes(routes Routes) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.routes = routes
+}
+
+// Unregister removes the adapters with the given name, e.g. to replace them
+// when settings are reloaded. Events already being delivered still reach
+// them.
+func (h *Hub) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var kept []Adapter
+	for _, a := range h.adapters {
+		if a.Name() != name {
+			kept = append(kept, a)
+		}
+	}
+	h.adapters = kept
+}
+
+// Adapters returns the names of the registered adapters
+func (h *Hub) Adapters() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	names := make([]string, len(h.adapters))
+	for i, a := range h.adapters {
+		names[i] = a.Name()
+	}
+	return names
+}
+
+// Notify validates the event's payload, records the event, and sends it to
+// every adapter its routes allow. Invalid events are rejected without being delivered. All
+// adapters are attempted even if some fail; the returned error summarizes
+// the failures.
+func (h *Hub) Notify(ctx context.Context, event events.Event) error {
+	if err := event.Validate(); err != nil {
+		return err
+	}
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = h.clock.Now()
+	}
+	if event.Priority == "" {
+		event.Priority = events.PriorityNormal
+	}
+
+	h.mu.Lock()
+	h.recent = append(h.recent, event)
+	if len(h.recent) > h.maxRecent {
+		h.recent = h.recent[len(h.recent)-h.maxRecent:]
+	}
+	var adapters []Adapter
+	for _, a := range h.adapters {
+		if h.routes.Allows(a.Name(), event) {
+			adapters = append(adapters, a)
+		}
+	}
+	h.mu.Unlock()
+
+	var failed []string
+	if h.store != nil {
+		names := make([]string, len(adapters))
+		for i, a := range adapters {
+			names[i] = a.Name()
+		}
+		if err := h.store.Append(event, names); err != nil {
+			failed = append(failed, fmt.Sprintf("store: %v", err))
+		}
+	}
+	failed = append(failed, h.deliver(ctx, event, adapters)...)
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to deliver event %s: %v", event.Type, failed)
+	}
+	return nil
+}
+
+// deliver sends event to each adapter, acknowledging successful deliveries
+// in the store, and returns the failures
+func (h *Hub) deliver(ctx context.Context, event events.Event, adapters []Adapter) []string {
+	var failed []string
+	for _, a := range adapters {
+		if err := a.Send(ctx, event); errors.Is(err, ErrDeferred) {
+			continue
+		} else if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", a.Name(), err))
+			continue
+		}
+		if h.store != nil {
+			if err := h.store.Delivered(event.ID, a.Name()); err != nil {
+				failed = append(failed, fmt.Sprintf("store: %v", err))
+			}
+		}
+	}
+	return failed
+}
+
+// Acknowledge records in the store that the named adapter delivered the
+// event, for adapters that deferred it (see ErrDeferred)
+func (h *Hub) Acknowledge(eventID, adapter string) error {
+	if h.store == nil {
+		return nil
+	}
+	return h.store.Delivered(eventID, adapter)
+}
+
+// Replay sends stored events that some adapters never acknowledged to those
+// adapters again, oldest first, and returns how many events it resent.
+// Adapters that are no longer registered are skipped. Replayed events are
+// not added to the in-memory history.
+func (h *Hub) Replay(ctx context.Context) (int, error) {
+	if h.store == nil {
+		return 0, nil
+	}
+	pending, err := h.store.Pending()
+	if err != nil {
+		return 0, err
+	}
+
+	h.mu.RLock()
+	registered := make(map[string]Adapter, len(h.adapters))
+	for _, a := range h.adapters {
+		registered[a.Name()] = a
+	}
+	h.mu.RUnlock()
+
+	var replayed int
+	var failed []string
+	for _, p := range pending {
+		var adapters []Adapter
+		for _, name := range p.Adapters {
+			if a, ok := registered[name]; ok {
+				adapters = append(adapters, a)
+			}
+		}
+		if len(adapters) == 0 {
+			continue
+		}
+		replayed++
+		failed = append(failed, h.deliver(ctx, p.Event, adapters)...)
+	}
+	if len(failed) > 0 {
+		return replayed, fmt.Errorf("failed to replay events: %v", failed)
+	}
+	return replayed, nil
+}
+
+// Store returns the hub's event store, or nil if events are kept in memory
+// only
+func (h *Hub) Store() *Store {
+	return h.store
+}
+
+// Recent returns up to limit of the most recent events, newest first.
+// A limit of 0 returns all retained events.
+func (h *Hub) Recent(limit int) []events.Event {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n := len(h.recent)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	result := make([]events.Event, n)
+	for i := 0; i < n; i++ {
+		result[i] = h.recent[len(h.recent)-1-i]
+	}
+	return result
+}
+
+// LogFunc is the signature used by LogAdapter to write a line
+type LogFunc func(format string, args ...interface{})
+
+// LogAdapter writes events to a log function (typically the daemon logger)
+type LogAdapter struct {
+	logf LogFunc
+}
+
+// NewLogAdapter creates an adapter that writes events using logf
+func NewLogAdapter(logf LogFunc) *LogAdapter {
+	return &LogAdapter{logf: logf}
+}
+
+// Name implements Adapter
+func (a *LogAdapter) Name() string {
+	return "log"
+}
+
+// Send implements Adapter
+func (a *LogAdapter) Send(ctx context.Context, event events.Event) error {
+	target := event.Repo
+	if event.Agent != "" {
+		target = event.Repo + "/" + event.Agent
+	}
+	if event.Attach != nil {
+		a.logf("Event %s [%s] %s: %s (%s)", event.Type, event.Priority, target, event.Title, event.Attach.Command)
+		return nil
+	}
+	a.logf("Event %s [%s] %s: %s", event.Type, event.Priority, target, event.Title)
+	return nil
+}
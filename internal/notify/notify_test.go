@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+type recordingAdapter struct {
+	name   string
+	events []events.Event
+	err    error
+}
+
+func (r *recordingAdapter) Name() string { return r.name }
+
+func (r *recordingAdapter) Send(ctx context.Context, event events.Event) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestHubNotifyFansOut(t *testing.T) {
+	hub := NewHub()
+	a := &recordingAdapter{name: "a"}
+	b := &recordingAdapter{name: "b"}
+	hub.Register(a)
+	hub.Register(b)
+
+	event := events.NewEvent(events.EventAgentStuck, "repo", "worker-1", "stuck")
+	if err := hub.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if len(a.events) != 1 || len(b.events) != 1 {
+		t.Fatalf("expected both adapters to receive the event, got %d and %d", len(a.events), len(b.events))
+	}
+	if got := hub.Adapters(); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("Adapters() = %v", got)
+	}
+}
+
+func TestHubUnregister(t *testing.T) {
+	hub := NewHub()
+	a := &recordingAdapter{name: "a"}
+	b := &recordingAdapter{name: "b"}
+	hub.Register(a)
+	hub.Register(b)
+	hub.Unregister("a")
+	hub.Unregister("missing")
+
+	if err := hub.Notify(context.Background(), events.NewEvent(events.EventAgentStuck, "repo", "worker-1", "stuck")); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(a.events) != 0 || len(b.events) != 1 {
+		t.Errorf("got %d and %d events, want only b to receive one", len(a.events), len(b.events))
+	}
+	if got := hub.Adapters(); len(got) != 1 || got[0] != "b" {
+		t.Errorf("Adapters() = %v", got)
+	}
+}
+
+func TestHubNotifyContinuesAfterFailure(t *testing.T) {
+	hub := NewHub()
+	failing := &recordingAdapter{name: "failing", err: errors.New("boom")}
+	ok := &recordingAdapter{name: "ok"}
+	hub.Register(failing)
+	hub.Register(ok)
+
+	err := hub.Notify(context.Background(), events.Event{Type: events.EventAgentError, Title: "x"})
+	if err == nil || !strings.Contains(err.Error(), "failing") {
+		t.Errorf("expected error mentioning failing adapter, got %v", err)
+	}
+	if len(ok.events) != 1 {
+		t.Error("healthy adapter should still receive the event")
+	}
+	if ok.events[0].ID == "" || ok.events[0].Timestamp.IsZero() || ok.events[0].Priority != events.PriorityNormal {
+		t.Errorf("Notify should fill in defaults, got %+v", ok.events[0])
+	}
+}
+
+func TestHubRejectsInvalidEvents(t *testing.T) {
+	hub := NewHub()
+	a := &recordingAdapter{name: "a"}
+	hub.Register(a)
+
+	invalid := events.NewTypedEvent("r", "", "x", events.AgentStuckPayload{})
+	if err := hub.Notify(context.Background(), invalid); err == nil || !strings.Contains(err.Error(), "reason is required") {
+		t.Errorf("Notify error = %v, want the validation error", err)
+	}
+	if len(a.events) != 0 || len(hub.Recent(0)) != 0 {
+		t.Error("invalid events must not be delivered or recorded")
+	}
+}
+
+func TestHubRecent(t *testing.T) {
+	hub := NewHub()
+	for i := 0; i < DefaultRecentEvents+10; i++ {
+		hub.Notify(context.Background(), events.Event{Type: events.EventAgentStuck, Title: fmt.Sprintf("event-%d", i)})
+	}
+
+	all := hub.Recent(0)
+	if len(all) != DefaultRecentEvents {
+		t.Fatalf("expected %d retained events, got %d", DefaultRecentEvents, len(all))
+	}
+	if all[0].Title != fmt.Sprintf("event-%d", DefaultRecentEvents+9) {
+		t.Errorf("newest event should come first, got %s", all[0].Title)
+	}
+
+	if got := hub.Recent(3); len(got) != 3 {
+		t.Errorf("Recent(3) returned %d events", len(got))
+	}
+}
+
+func TestLogAdapter(t *testing.T) {
+	var lines []string
+	adapter := NewLogAdapter(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+
+	event := events.NewEvent(events.EventAgentStuck, "repo", "worker-1", "Agent is looping")
+	event.Priority = events.PriorityHigh
+	if err := adapter.Send(context.Background(), event); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if len(lines) != 1 {
+		t.Fatalf("expected one log line, got %d", len(lines))
+	}
+	for _, want := range []string{"agent.stuck", "high", "repo/worker-1", "Agent is looping"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("log line %q missing %q", lines[0], want)
+		}
+	}
+}
+
+func TestHubStampsWithClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	hub := NewHub(WithClock(clock.NewFake(now)))
+
+	event := events.NewEvent(events.EventAgentStuck, "repo", "worker", "stuck")
+	event.Timestamp = time.Time{}
+	if err := hub.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got := hub.Recent(1)[0].Timestamp; !got.Equal(now) {
+		t.Errorf("Timestamp = %v, want %v", got, now)
+	}
+}
@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// RoutesEnv is the environment variable that routes events to specific
+// adapters. It is a semicolon-separated list of rules; each rule is a
+// comma-separated list of settings like MULTICLAUDE_EMAIL's:
+//
+//	MULTICLAUDE_ROUTES=type=agent.question,to=desktop;repo=payments,min-priority=high,to=email,to=webhook
+//
+// A rule matches events by type (a trailing * matches a prefix, e.g.
+// agent.*), priority, min-priority, repo, and agent-type; every setting but
+// min-priority may be repeated to match any of its values. An adapter named
+// by some rule's to only receives the events its rules match. Adapters no
+// rule names, such as the daemon log, receive every event. Setting it
+// overrides the config file's notifications.routes.
+const RoutesEnv = "MULTICLAUDE_ROUTES"
+
+// Route sends the events it matches to adapters. Empty fields match
+// everything.
+type Route struct {
+	Types       []string // event types; a trailing * matches a prefix
+	Priorities  []events.Priority
+	MinPriority events.Priority
+	Repos       []string
+	AgentTypes  []string
+	To          []string // adapter names
+}
+
+// Matches reports whether the route selects event
+func (r Route) Matches(event events.Event) bool {
+	if len(r.Types) > 0 && !matchesAny(r.Types, string(event.Type), true) {
+		return false
+	}
+	if len(r.Priorities) > 0 {
+		found := false
+		for _, p := range r.Priorities {
+			found = found || p == event.Priority
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.MinPriority != "" && priorityRank(event.Priority) < priorityRank(r.MinPriority) {
+		return false
+	}
+	if len(r.Repos) > 0 && !matchesAny(r.Repos, event.Repo, false) {
+		return false
+	}
+	return len(r.AgentTypes) == 0 || matchesAny(r.AgentTypes, event.AgentType, false)
+}
+
+// matchesAny reports whether value is one of patterns, which may end in *
+// if prefix is set
+func matchesAny(patterns []string, value string, prefix bool) bool {
+	for _, p := range patterns {
+		if prefix && strings.HasSuffix(p, "*") && strings.HasPrefix(value, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+		if p == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Routes is a set of routing rules for the hub's adapters
+type Routes []Route
+
+// ParseRoutes parses the value of MULTICLAUDE_ROUTES
+func ParseRoutes(spec string) (Routes, error) {
+	var routes Routes
+	for _, ruleSpec := range strings.Split(spec, ";") {
+		if strings.TrimSpace(ruleSpec) == "" {
+			continue
+		}
+		var r Route
+		for _, field := range strings.Split(ruleSpec, ",") {
+			if strings.TrimSpace(field) == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+			if !ok || value == "" {
+				return nil, fmt.Errorf("invalid route setting %q: expected key=value", field)
+			}
+			if err := r.set(key, value); err != nil {
+				return nil, err
+			}
+		}
+		if len(r.To) == 0 {
+			return nil, fmt.Errorf("route %q names no adapter: add to=<adapter>", strings.TrimSpace(ruleSpec))
+		}
+		routes = append(routes, r)
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("%s lists no routes", RoutesEnv)
+	}
+	return routes, nil
+}
+
+// set applies one route setting
+func (r *Route) set(key, value string) error {
+	switch key {
+	case "type":
+		if _, known := events.LookupSchema(events.EventType(value)); !known && !strings.HasSuffix(value, "*") {
+			return fmt.Errorf("unknown event type %q in route", value)
+		}
+		r.Types = append(r.Types, value)
+	case "priority":
+		if priorityRank(events.Priority(value)) < 0 {
+			return fmt.Errorf("invalid route setting priority=%s: must be low, normal, or high", value)
+		}
+		r.Priorities = append(r.Priorities, events.Priority(value))
+	case "min-priority":
+		if priorityRank(events.Priority(value)) < 0 {
+			return fmt.Errorf("invalid route setting min-priority=%s: must be low, normal, or high", value)
+		}
+		r.MinPriority = events.Priority(value)
+	case "repo":
+		r.Repos = append(r.Repos, value)
+	case "agent-type":
+		r.AgentTypes = append(r.AgentTypes, value)
+	case "to":
+		r.To = append(r.To, value)
+	default:
+		return fmt.Errorf("unknown route setting %q", key)
+	}
+	return nil
+}
+
+// Routed reports whether some route names the adapter, which then only
+// receives the events its routes match
+func (rs Routes) Routed(adapter string) bool {
+	for _, r := range rs {
+		for _, to := range r.To {
+			if to == adapter {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allows reports whether event should be sent to the adapter
+func (rs Routes) Allows(adapter string, event events.Event) bool {
+	if !rs.Routed(adapter) {
+		return true
+	}
+	for _, r := range rs {
+		for _, to := range r.To {
+			if to == adapter && r.Matches(event) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Adapters returns the adapter names the routes send events to, for
+// checking them against the registered adapters
+func (rs Routes) Adapters() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, r := range rs {
+		for _, to := range r.To {
+			if !seen[to] {
+				seen[to] = true
+				names = append(names, to)
+			}
+		}
+	}
+	return names
+}
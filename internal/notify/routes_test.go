@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("type=agent.question,to=desktop; repo=payments,repo=billing,agent-type=worker,min-priority=high,to=email,to=webhook")
+	if err != nil {
+		t.Fatalf("ParseRoutes failed: %v", err)
+	}
+	if len(routes) != 2 || len(routes[1].Repos) != 2 || len(routes[1].To) != 2 || routes[1].MinPriority != events.PriorityHigh {
+		t.Errorf("ParseRoutes() = %+v", routes)
+	}
+	if _, err := ParseRoutes("type=agent.*,to=log"); err != nil {
+		t.Errorf("a type prefix should parse: %v", err)
+	}
+
+	for _, spec := range []string{"", "type=agent.question", "type=ci.flaky,to=email", "priority=urgent,to=email", "channel=ci,to=email", "to"} {
+		if _, err := ParseRoutes(spec); err == nil {
+			t.Errorf("ParseRoutes(%q) should fail", spec)
+		}
+	}
+}
+
+func TestHubRoutesEvents(t *testing.T) {
+	routes, err := ParseRoutes("type=agent.question,to=desktop;repo=payments,type=agent.*,min-priority=high,to=email;agent-type=review,to=email")
+	if err != nil {
+		t.Fatalf("ParseRoutes failed: %v", err)
+	}
+	hub := NewHub(WithRoutes(routes))
+	log := &recordingAdapter{name: "log"}
+	desktop := &recordingAdapter{name: "desktop"}
+	email := &recordingAdapter{name: "email"}
+	hub.Register(log)
+	hub.Register(desktop)
+	hub.Register(email)
+
+	send := func(eventType events.EventType, repo, agentType string, priority events.Priority) {
+		t.Helper()
+		event := events.NewEvent(eventType, repo, "agent", string(eventType)+" in "+repo)
+		event.AgentType = agentType
+		event.Priority = priority
+		if err := hub.Notify(context.Background(), event); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+	send(events.EventAgentQuestion, "web", "worker", events.PriorityHigh)    // desktop
+	send(events.EventAgentStuck, "payments", "worker", events.PriorityHigh)  // email
+	send(events.EventAgentStuck, "payments", "worker", events.PriorityLow)   // neither
+	send(events.EventAgentCompleted, "web", "review", events.PriorityNormal) // email
+
+	if len(log.events) != 4 {
+		t.Errorf("unrouted log adapter got %d events, want all 4", len(log.events))
+	}
+	if len(desktop.events) != 1 || desktop.events[0].Type != events.EventAgentQuestion {
+		t.Errorf("desktop got %+v, want only the question", desktop.events)
+	}
+	if len(email.events) != 2 || email.events[0].Repo != "payments" || email.events[1].AgentType != "review" {
+		t.Errorf("email got %+v, want the high-priority payments event and the review event", email.events)
+	}
+}
@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// FileConfig holds the adapters the config file's notifications section
+// enables; nil means the file leaves that adapter off
+type FileConfig struct {
+	Slack    *SlackConfig
+	Telegram *TelegramConfig
+	Webhook  *WebhookConfig
+	Email    *EmailConfig
+	Desktop  []events.EventType // nil leaves desktop notifications off
+	Incident *IncidentConfig
+	Routes   Routes // nil sends every event to every adapter
+}
+
+// ParseFileSettings converts the config file's notification settings (see
+// config.File) into adapter configurations
+func ParseFileSettings(s config.NotificationSettings) (FileConfig, error) {
+	var cfg FileConfig
+	if s.Slack != nil {
+		types, err := settingsEventTypes("slack", s.Slack.Events)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Slack = &SlackConfig{WebhookURL: s.Slack.WebhookURL, Types: types}
+	}
+	if s.Telegram != nil {
+		types, err := settingsEventTypes("telegram", s.Telegram.Events)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Telegram = &TelegramConfig{BotToken: s.Telegram.BotToken, ChatID: s.Telegram.ChatID, Types: types}
+	}
+	if w := s.Webhook; w != nil {
+		webhook := defaultWebhookConfig()
+		settings := [][2]string{{"url", w.URL}, {"backoff", w.Backoff}, {"timeout", w.Timeout}, {"dead-letter", w.DeadLetter}}
+		if w.Retries != nil {
+			settings = append(settings, [2]string{"retries", strconv.Itoa(*w.Retries)})
+		}
+		for name, value := range w.Headers {
+			settings = append(settings, [2]string{"header", name + ":" + value})
+		}
+		if err := applySettings(settings, webhook.set); err != nil {
+			return cfg, err
+		}
+		if err := webhook.finish(w.Secret); err != nil {
+			return cfg, err
+		}
+		cfg.Webhook = &webhook
+	}
+	if e := s.Email; e != nil {
+		email := defaultEmailConfig()
+		settings := [][2]string{{"host", e.Host}, {"user", e.User}, {"from", e.From}, {"tls", e.TLS}, {"min-priority", e.MinPriority}, {"digest", e.Digest}}
+		if e.Port != 0 {
+			settings = append(settings, [2]string{"port", strconv.Itoa(e.Port)})
+		}
+		for _, to := range e.To {
+			settings = append(settings, [2]string{"to", to})
+		}
+		if err := applySettings(settings, email.set); err != nil {
+			return cfg, err
+		}
+		if err := email.finish(); err != nil {
+			return cfg, err
+		}
+		email.Password = e.Password
+		cfg.Email = &email
+	}
+	if d := s.Desktop; d != nil {
+		types, err := settingsEventTypes("desktop", d.Events)
+		if err != nil {
+			return cfg, err
+		}
+		if len(types) == 0 {
+			types = DefaultDesktopTypes
+		}
+		cfg.Desktop = types
+	}
+	if i := s.Incident; i != nil {
+		incident := defaultIncidentConfig()
+		settings := [][2]string{{"provider", i.Provider}, {"window", i.Window}, {"severity", i.Severity}, {"url", i.URL}, {"timeout", i.Timeout}}
+		if i.Threshold != 0 {
+			settings = append(settings, [2]string{"threshold", strconv.Itoa(i.Threshold)})
+		}
+		for _, agentType := range i.AgentTypes {
+			settings = append(settings, [2]string{"agent-type", agentType})
+		}
+		if err := applySettings(settings, incident.set); err != nil {
+			return cfg, err
+		}
+		if err := incident.finish(i.Key); err != nil {
+			return cfg, err
+		}
+		cfg.Incident = &incident
+	}
+	for i, r := range s.Routes {
+		var route Route
+		settings := [][2]string{{"min-priority", r.MinPriority}}
+		for _, list := range []struct {
+			key    string
+			values []string
+		}{{"type", r.Types}, {"priority", r.Priorities}, {"repo", r.Repos}, {"agent-type", r.AgentTypes}, {"to", r.To}} {
+			for _, value := range list.values {
+				settings = append(settings, [2]string{list.key, value})
+			}
+		}
+		if err := applySettings(settings, route.set); err != nil {
+			return cfg, fmt.Errorf("notification route %d: %w", i+1, err)
+		}
+		if len(route.To) == 0 {
+			return cfg, fmt.Errorf("notification route %d names no adapter", i+1)
+		}
+		cfg.Routes = append(cfg.Routes, route)
+	}
+	return cfg, nil
+}
+
+// applySettings passes each setting that has a value to set
+func applySettings(settings [][2]string, set func(key, value string) error) error {
+	for _, setting := range settings {
+		if setting[1] == "" {
+			continue
+		}
+		if err := set(setting[0], setting[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settingsEventTypes checks the event types an adapter is limited to
+func settingsEventTypes(adapter string, names []string) ([]events.EventType, error) {
+	var types []events.EventType
+	for _, name := range names {
+		t := events.EventType(name)
+		if _, ok := events.LookupSchema(t); !ok {
+			return nil, fmt.Errorf("unknown event type %q in %s notification settings", name, adapter)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
@@ -0,0 +1,227 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers carried by signed webhook requests in both directions: events sent
+// by the daemon and responses relayed back to it.
+const (
+	SignatureHeader = "X-Multiclaude-Signature"
+	TimestampHeader = "X-Multiclaude-Timestamp"
+	NonceHeader     = "X-Multiclaude-Nonce"
+)
+
+// DefaultMaxSkew is how far a signed request's timestamp may drift from the
+// receiver's clock. Requests outside the window are rejected as replays.
+const DefaultMaxSkew = 5 * time.Minute
+
+// Sign computes the signature for a body sent at timestamp with nonce. The
+// timestamp and nonce are part of the signed message, so neither can be
+// changed without invalidating the signature.
+func Sign(secret []byte, timestamp time.Time, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.%s.", timestamp.Unix(), nonce)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewNonce returns a random 128-bit nonce
+func NewNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// SignedHeaders returns the signature, timestamp, and nonce headers for body
+func SignedHeaders(secret, body []byte, now time.Time) map[string]string {
+	nonce := NewNonce()
+	return map[string]string{
+		SignatureHeader: Sign(secret, now, nonce, body),
+		TimestampHeader: strconv.FormatInt(now.Unix(), 10),
+		NonceHeader:     nonce,
+	}
+}
+
+// Verifier checks signed requests: the signature must match, the timestamp
+// must be within MaxSkew of now, and each nonce is accepted only once.
+type Verifier struct {
+	Secret  []byte
+	MaxSkew time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time // nonce -> timestamp of the request that used it
+}
+
+// NewVerifier creates a verifier for secret using DefaultMaxSkew
+func NewVerifier(secret []byte) *Verifier {
+	return &Verifier{Secret: secret, MaxSkew: DefaultMaxSkew, seen: make(map[string]time.Time)}
+}
+
+// Verify checks a request's headers (looked up with get, e.g. http.Header.Get)
+// and body. Nonces are remembered for twice the skew window, which covers
+// every timestamp that could still be accepted.
+func (v *Verifier) Verify(get func(string) string, body []byte, now time.Time) error {
+	signature, tsHeader, nonce := get(SignatureHeader), get(TimestampHeader), get(NonceHeader)
+	if signature == "" || tsHeader == "" || nonce == "" {
+		return fmt.Errorf("missing %s, %s, or %s header", SignatureHeader, TimestampHeader, NonceHeader)
+	}
+
+	unix, err := strconv.ParseInt(strings.TrimSpace(tsHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", tsHeader)
+	}
+	timestamp := time.Unix(unix, 0)
+	if skew := now.Sub(timestamp); skew > v.MaxSkew || skew < -v.MaxSkew {
+		return fmt.Errorf("timestamp outside the allowed %s window", v.MaxSkew)
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(v.Secret, timestamp, nonce, body))) {
+		return fmt.Errorf("signature mismatch")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for n, ts := range v.seen {
+		if now.Sub(ts) > 2*v.MaxSkew {
+			delete(v.seen, n)
+		}
+	}
+	if _, used := v.seen[nonce]; used {
+		return fmt.Errorf("nonce already used")
+	}
+	v.seen[nonce] = timestamp
+	return nil
+}
+
+// DefaultResponseTTL is how long a response ID stays valid
+const DefaultResponseTTL = 24 * time.Hour
+
+// ResponseTicket is an issued, unredeemed response ID: the agent it
+// authorizes a reply to, and when it expires
+type ResponseTicket struct {
+	Repo    string
+	Agent   string
+	Expires time.Time
+}
+
+// ResponseIDs issues one-time IDs that authorize a single reply to a specific
+// agent. An ID is consumed when redeemed and rejected after it expires.
+type ResponseIDs struct {
+	TTL time.Duration
+	// Persist, if set, is called with the outstanding tickets after every
+	// change, so they can be saved and given to Load after a restart
+	Persist func(map[string]ResponseTicket)
+
+	mu      sync.Mutex
+	tickets map[string]ResponseTicket
+}
+
+// NewResponseIDs creates an empty store using DefaultResponseTTL
+func NewResponseIDs() *ResponseIDs {
+	return &ResponseIDs{TTL: DefaultResponseTTL, tickets: make(map[string]ResponseTicket)}
+}
+
+// Load adds persisted tickets to the store, dropping expired ones
+func (r *ResponseIDs) Load(tickets map[string]ResponseTicket, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, ticket := range tickets {
+		if !now.After(ticket.Expires) {
+			r.tickets[id] = ticket
+		}
+	}
+}
+
+// Issue returns a new response ID for repo/agent and its expiry
+func (r *ResponseIDs) Issue(repo, agent string, now time.Time) (string, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneUnlocked(now)
+
+	id := NewNonce()
+	expires := now.Add(r.TTL)
+	r.tickets[id] = ResponseTicket{Repo: repo, Agent: agent, Expires: expires}
+	r.persistUnlocked()
+	return id, expires
+}
+
+// Redeem consumes a response ID. It fails if the ID is unknown, already used,
+// expired, or was issued for a different agent.
+func (r *ResponseIDs) Redeem(id, repo, agent string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ticket, ok := r.tickets[id]
+	if !ok {
+		return fmt.Errorf("response ID is unknown or was already used")
+	}
+	if now.After(ticket.Expires) {
+		delete(r.tickets, id)
+		r.persistUnlocked()
+		return fmt.Errorf("response ID expired at %s", ticket.Expires.Format(time.RFC3339))
+	}
+	if ticket.Repo != repo || ticket.Agent != agent {
+		return fmt.Errorf("response ID was issued for %s/%s", ticket.Repo, ticket.Agent)
+	}
+	delete(r.tickets, id)
+	r.persistUnlocked()
+	return nil
+}
+
+// Owner returns the repository and agent a response ID was issued for,
+// without consuming it, so a reply can name the ID alone
+func (r *ResponseIDs) Owner(id string, now time.Time) (repo, agent string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ticket, ok := r.tickets[id]
+	if !ok {
+		return "", "", fmt.Errorf("response ID is unknown or was already used")
+	}
+	if now.After(ticket.Expires) {
+		return "", "", fmt.Errorf("response ID expired at %s", ticket.Expires.Format(time.RFC3339))
+	}
+	return ticket.Repo, ticket.Agent, nil
+}
+
+// Revoke drops a response ID, e.g. when its question was answered another way
+func (r *ResponseIDs) Revoke(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tickets[id]; ok {
+		delete(r.tickets, id)
+		r.persistUnlocked()
+	}
+}
+
+// pruneUnlocked drops expired tickets
+func (r *ResponseIDs) pruneUnlocked(now time.Time) {
+	for id, ticket := range r.tickets {
+		if now.After(ticket.Expires) {
+			delete(r.tickets, id)
+		}
+	}
+}
+
+// persistUnlocked hands a copy of the outstanding tickets to Persist
+func (r *ResponseIDs) persistUnlocked() {
+	if r.Persist == nil {
+		return
+	}
+	tickets := make(map[string]ResponseTicket, len(r.tickets))
+	for id, ticket := range r.tickets {
+		tickets[id] = ticket
+	}
+	r.Persist(tickets)
+}
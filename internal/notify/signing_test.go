@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifier(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"type":"agent.question"}`)
+	now := time.Unix(1_800_000_000, 0)
+
+	headers := SignedHeaders(secret, body, now)
+	get := func(h map[string]string) func(string) string {
+		return func(k string) string { return h[k] }
+	}
+
+	v := NewVerifier(secret)
+	if err := v.Verify(get(headers), body, now.Add(time.Minute)); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+	if err := v.Verify(get(headers), body, now.Add(time.Minute)); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("replayed request should be rejected, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		body    []byte
+		now     time.Time
+		wantErr string
+	}{
+		{"too old", SignedHeaders(secret, body, now), body, now.Add(DefaultMaxSkew + time.Second), "window"},
+		{"from the future", SignedHeaders(secret, body, now), body, now.Add(-DefaultMaxSkew - time.Second), "window"},
+		{"tampered body", SignedHeaders(secret, body, now), []byte(`{}`), now, "signature"},
+		{"wrong secret", SignedHeaders([]byte("other"), body, now), body, now, "signature"},
+		{"missing headers", map[string]string{}, body, now, "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(get(tt.headers), tt.body, tt.now)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Changing the timestamp invalidates the signature
+	shifted := SignedHeaders(secret, body, now)
+	shifted[TimestampHeader] = strconv.FormatInt(now.Unix()+1, 10)
+	if err := v.Verify(get(shifted), body, now); err == nil {
+		t.Error("a re-timestamped request should be rejected")
+	}
+}
+
+func TestResponseIDs(t *testing.T) {
+	ids := NewResponseIDs()
+	now := time.Now()
+
+	id, expires := ids.Issue("repo", "worker-1", now)
+	if !expires.Equal(now.Add(DefaultResponseTTL)) {
+		t.Errorf("expires = %v", expires)
+	}
+	if err := ids.Redeem(id, "repo", "worker-2", now); err == nil {
+		t.Error("an ID issued for another agent must be rejected")
+	}
+	if err := ids.Redeem(id, "repo", "worker-1", now); err != nil {
+		t.Fatalf("Redeem failed: %v", err)
+	}
+	if err := ids.Redeem(id, "repo", "worker-1", now); err == nil {
+		t.Error("an ID can only be redeemed once")
+	}
+
+	expired, _ := ids.Issue("repo", "worker-1", now)
+	if err := ids.Redeem(expired, "repo", "worker-1", now.Add(DefaultResponseTTL+time.Second)); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expired ID should be rejected, got %v", err)
+	}
+}
+
+func TestResponseIDsPersist(t *testing.T) {
+	ids := NewResponseIDs()
+	var saved map[string]ResponseTicket
+	ids.Persist = func(tickets map[string]ResponseTicket) { saved = tickets }
+	now := time.Now()
+
+	id, _ := ids.Issue("repo", "worker-1", now)
+	redeemed, _ := ids.Issue("repo", "worker-2", now)
+	if err := ids.Redeem(redeemed, "repo", "worker-2", now); err != nil {
+		t.Fatalf("Redeem failed: %v", err)
+	}
+	if len(saved) != 1 || saved[id].Agent != "worker-1" {
+		t.Fatalf("persisted tickets = %+v, want only %s", saved, id)
+	}
+
+	// A restarted store accepts the outstanding ID once
+	restarted := NewResponseIDs()
+	restarted.Load(saved, now)
+	if err := restarted.Redeem(id, "repo", "worker-1", now); err != nil {
+		t.Errorf("Redeem after Load failed: %v", err)
+	}
+	expired := NewResponseIDs()
+	expired.Load(saved, now.Add(DefaultResponseTTL+time.Second))
+	if _, _, err := expired.Owner(id, now); err == nil {
+		t.Error("Load should drop expired tickets")
+	}
+}
@@ -0,0 +1,254 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// EventRetentionEnv is the environment variable that sets how long the event
+// store keeps events, as a Go duration or a number of days, e.g. "72h" or "30d".
+// Setting it overrides the config file's events.retention.
+const EventRetentionEnv = "MULTICLAUDE_EVENT_RETENTION"
+
+// DefaultEventRetention is how long events are kept when EventRetentionEnv
+// is unset
+const DefaultEventRetention = 7 * 24 * time.Hour
+
+// compactInterval is how often Compact actually rewrites the store
+const compactInterval = time.Hour
+
+// ParseEventRetention parses the value of MULTICLAUDE_EVENT_RETENTION
+func ParseEventRetention(spec string) (time.Duration, error) {
+	retention, err := config.ParseRetention(spec)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: use a positive duration such as 72h or 30d", EventRetentionEnv, spec)
+	}
+	return retention, nil
+}
+
+// storeRecord is a line of the store file. An event line names the adapters
+// it was handed to; a delivery line acknowledges one of them.
+type storeRecord struct {
+	Event     *events.Event `json:"event,omitempty"`
+	Adapters  []string      `json:"adapters,omitempty"`
+	Delivered string        `json:"delivered,omitempty"` // event ID
+	Adapter   string        `json:"adapter,omitempty"`
+}
+
+// PendingEvent is a stored event some adapters never acknowledged, e.g.
+// because the daemon stopped while delivering it or the adapter failed
+type PendingEvent struct {
+	Event    events.Event
+	Adapters []string
+}
+
+// EventQuery selects stored events. Zero fields match everything.
+type EventQuery struct {
+	Since time.Time
+	Until time.Time
+	Repo  string
+	Type  events.EventType
+	// Limit keeps only the newest Limit matches
+	Limit int
+}
+
+// Matches reports whether e is selected by q, ignoring Limit
+func (q EventQuery) Matches(e events.Event) bool {
+	return (q.Since.IsZero() || !e.Timestamp.Before(q.Since)) &&
+		(q.Until.IsZero() || !e.Timestamp.After(q.Until)) &&
+		(q.Repo == "" || e.Repo == q.Repo) &&
+		(q.Type == "" || e.Type == q.Type)
+}
+
+// Store is an append-only JSON lines log of every event the hub delivers and
+// which adapters accepted it. It outlives the hub's in-memory history, so
+// events can be queried by time and undelivered ones replayed after a
+// restart.
+type Store struct {
+	mu          sync.Mutex
+	path        string
+	retention   time.Duration
+	lastCompact time.Time
+}
+
+// OpenStore opens the store at path, creating its directory, and drops
+// events older than retention (DefaultEventRetention if zero)
+func OpenStore(path string, retention time.Duration, now time.Time) (*Store, error) {
+	if retention <= 0 {
+		retention = DefaultEventRetention
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create event store directory: %w", err)
+	}
+	s := &Store{path: path, retention: retention}
+	if err := s.Compact(now); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Append records an event before it is handed to adapters
+func (s *Store) Append(event events.Event, adapters []string) error {
+	return s.write(storeRecord{Event: &event, Adapters: adapters})
+}
+
+// Delivered records that adapter accepted the event with the given ID
+func (s *Store) Delivered(id, adapter string) error {
+	return s.write(storeRecord{Delivered: id, Adapter: adapter})
+}
+
+func (s *Store) write(record storeRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode event record: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event store: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write event store: %w", err)
+	}
+	return nil
+}
+
+// Query returns the stored events q selects, oldest first
+func (s *Store) Query(q EventQuery) ([]events.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending, err := s.readLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []events.Event
+	for _, p := range pending {
+		if q.Matches(p.Event) {
+			result = append(result, p.Event)
+		}
+	}
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result, nil
+}
+
+// Pending returns the events, oldest first, that some of the adapters they
+// were handed to never acknowledged
+func (s *Store) Pending() ([]PendingEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.readLocked()
+	if err != nil {
+		return nil, err
+	}
+	var pending []PendingEvent
+	for _, p := range all {
+		if len(p.Adapters) > 0 {
+			pending = append(pending, p)
+		}
+	}
+	return pending, nil
+}
+
+// Compact rewrites the store without events older than the retention,
+// folding delivery lines into the events they acknowledge. It does nothing if it already ran within
+// the last hour, so callers may call it often.
+func (s *Store) Compact(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.lastCompact.IsZero() && now.Sub(s.lastCompact) < compactInterval {
+		return nil
+	}
+	s.lastCompact = now
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+
+	all, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-s.retention)
+	var buf strings.Builder
+	for _, p := range all {
+		if p.Event.Timestamp.Before(cutoff) {
+			continue
+		}
+		line, err := json.Marshal(storeRecord{Event: &p.Event, Adapters: p.Adapters})
+		if err != nil {
+			return fmt.Errorf("failed to encode event record: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(buf.String()), 0644); err != nil {
+		return fmt.Errorf("failed to compact event store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to compact event store: %w", err)
+	}
+	return nil
+}
+
+// readLocked reads every event in the store, oldest first, with Adapters
+// narrowed to those that never acknowledged it. Lines that don't parse,
+// such as a line cut short by a crash, are skipped.
+func (s *Store) readLocked() ([]PendingEvent, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event store: %w", err)
+	}
+	defer f.Close()
+
+	var all []PendingEvent
+	byID := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		var record storeRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		switch {
+		case record.Event != nil:
+			byID[record.Event.ID] = len(all)
+			all = append(all, PendingEvent{Event: *record.Event, Adapters: record.Adapters})
+		case record.Delivered != "":
+			if i, ok := byID[record.Delivered]; ok {
+				all[i].Adapters = removeString(all[i].Adapters, record.Adapter)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event store: %w", err)
+	}
+	return all, nil
+}
+
+// removeString returns list without the first occurrence of s
+func removeString(list []string, s string) []string {
+	for i, item := range list {
+		if item == s {
+			return append(list[:i:i], list[i+1:]...)
+		}
+	}
+	return list
+}
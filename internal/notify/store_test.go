@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestParseEventRetention(t *testing.T) {
+	for spec, want := range map[string]time.Duration{"72h": 72 * time.Hour, "30d": 30 * 24 * time.Hour, "90m": 90 * time.Minute} {
+		if got, err := ParseEventRetention(spec); err != nil || got != want {
+			t.Errorf("ParseEventRetention(%q) = %v, %v; want %v", spec, got, err, want)
+		}
+	}
+	for _, spec := range []string{"", "0d", "-1h", "week", "d"} {
+		if _, err := ParseEventRetention(spec); err == nil {
+			t.Errorf("ParseEventRetention(%q) should fail", spec)
+		}
+	}
+}
+
+func TestStoreQueryAndCompact(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	path := filepath.Join(t.TempDir(), "output", "events.jsonl")
+	store, err := OpenStore(path, 48*time.Hour, start)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	hub := NewHub(WithClock(fake), WithStore(store))
+	hub.Register(&recordingAdapter{name: "log"})
+
+	for i, repo := range []string{"a", "b", "a"} {
+		event := events.NewEvent(events.EventAgentStuck, repo, "worker", "stuck")
+		event.Timestamp = time.Time{}
+		if err := hub.Notify(context.Background(), event); err != nil {
+			t.Fatalf("Notify %d failed: %v", i, err)
+		}
+		fake.Advance(24 * time.Hour)
+	}
+
+	all, err := store.Query(EventQuery{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Query() = %d events, %v; want 3", len(all), err)
+	}
+	ranged, _ := store.Query(EventQuery{Since: start.Add(time.Hour), Until: start.Add(48 * time.Hour), Repo: "a"})
+	if len(ranged) != 1 || !ranged[0].Timestamp.Equal(start.Add(48*time.Hour)) {
+		t.Errorf("range query = %+v, want the third event", ranged)
+	}
+	if newest, _ := store.Query(EventQuery{Limit: 1}); len(newest) != 1 || newest[0].Repo != "a" {
+		t.Errorf("Limit should keep the newest event, got %+v", newest)
+	}
+	if pending, _ := store.Pending(); len(pending) != 0 {
+		t.Errorf("delivered events should not be pending: %+v", pending)
+	}
+
+	// Compacting at start+72h drops the event from start, which is past the
+	// 48h retention
+	if err := store.Compact(fake.Now()); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if kept, _ := store.Query(EventQuery{}); len(kept) != 2 || kept[0].Repo != "b" {
+		t.Errorf("after compaction got %+v, want the last two events", kept)
+	}
+}
+
+func TestHubReplaysUndeliveredEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	now := time.Now()
+	store, err := OpenStore(path, 0, now)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+
+	hub := NewHub(WithStore(store))
+	hub.Register(&recordingAdapter{name: "log"})
+	hub.Register(&recordingAdapter{name: "email", err: errors.New("smtp down")})
+	if err := hub.Notify(context.Background(), events.NewEvent(events.EventAgentError, "repo", "worker", "failed")); err == nil {
+		t.Fatal("Notify should report the failed email")
+	}
+	// A line cut short by a crash must not hide the rest of the store
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"event":{"type":"agent.`)
+	f.Close()
+
+	// After a restart only the email adapter gets the event again, once
+	reopened, err := OpenStore(path, 0, now)
+	if err != nil {
+		t.Fatalf("reopening the store failed: %v", err)
+	}
+	restarted := NewHub(WithStore(reopened))
+	log := &recordingAdapter{name: "log"}
+	email := &recordingAdapter{name: "email"}
+	restarted.Register(log)
+	restarted.Register(email)
+
+	replayed, err := restarted.Replay(context.Background())
+	if err != nil || replayed != 1 {
+		t.Fatalf("Replay() = %d, %v; want 1", replayed, err)
+	}
+	if len(email.events) != 1 || email.events[0].Title != "failed" || len(log.events) != 0 {
+		t.Errorf("replay should only resend to email: email %d, log %d", len(email.events), len(log.events))
+	}
+	if replayed, _ := restarted.Replay(context.Background()); replayed != 0 {
+		t.Errorf("an acknowledged event was replayed again")
+	}
+}
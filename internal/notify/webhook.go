@@ -0,0 +1,339 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/clock"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// WebhookEnv is the environment variable that enables the webhook adapter.
+// It is a comma-separated list of settings; header may be repeated:
+//
+//	MULTICLAUDE_WEBHOOK=url=https://example.com/hook,header=X-Team:infra,retries=5,backoff=2s
+//
+// Every request is signed with the key in WebhookSecretEnv, which is required.
+const WebhookEnv = "MULTICLAUDE_WEBHOOK"
+
+// WebhookSecretEnv holds the HMAC key webhook requests are signed with
+const WebhookSecretEnv = "MULTICLAUDE_WEBHOOK_SECRET"
+
+// EventHeader names the event type of a webhook request, so receivers can
+// route it without decoding the body
+const EventHeader = "X-Multiclaude-Event"
+
+// Webhook delivery defaults
+const (
+	DefaultWebhookAttempts = 5
+	DefaultWebhookBackoff  = time.Second
+	DefaultWebhookTimeout  = 10 * time.Second
+	// maxWebhookBackoff caps the wait between attempts, including waits a
+	// receiver asks for with Retry-After
+	maxWebhookBackoff = 5 * time.Minute
+)
+
+// WebhookConfig configures the webhook adapter
+type WebhookConfig struct {
+	URL     string
+	Secret  []byte
+	Headers map[string]string
+	// Attempts is how many times an event is sent before it is given up on
+	Attempts int
+	// Backoff is the wait before the first retry; it doubles after each one
+	Backoff time.Duration
+	// Timeout bounds each request
+	Timeout time.Duration
+	// DeadLetter is the JSON lines file events that permanently failed are
+	// appended to; empty drops them
+	DeadLetter string
+}
+
+// ParseWebhookConfig parses the value of MULTICLAUDE_WEBHOOK. secret is the
+// value of MULTICLAUDE_WEBHOOK_SECRET.
+func ParseWebhookConfig(spec, secret string) (WebhookConfig, error) {
+	cfg := defaultWebhookConfig()
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return cfg, fmt.Errorf("invalid webhook setting %q: expected key=value", field)
+		}
+		if err := cfg.set(key, value); err != nil {
+			return cfg, err
+		}
+	}
+	if secret == "" && cfg.URL != "" {
+		return cfg, fmt.Errorf("%s must be set to sign webhook requests", WebhookSecretEnv)
+	}
+	return cfg, cfg.finish(secret)
+}
+
+func defaultWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		Headers:  make(map[string]string),
+		Attempts: DefaultWebhookAttempts,
+		Backoff:  DefaultWebhookBackoff,
+		Timeout:  DefaultWebhookTimeout,
+	}
+}
+
+// set applies one webhook setting
+func (cfg *WebhookConfig) set(key, value string) error {
+	var err error
+	switch key {
+	case "url":
+		cfg.URL = value
+		if u, perr := url.Parse(value); perr != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			err = fmt.Errorf("must be an http or https URL")
+		}
+	case "header":
+		name, headerValue, found := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			err = fmt.Errorf("must be Name:Value")
+		} else {
+			cfg.Headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(headerValue)
+		}
+	case "retries":
+		var retries int
+		retries, err = strconv.Atoi(value)
+		if err == nil && retries < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+		cfg.Attempts = retries + 1
+	case "backoff":
+		cfg.Backoff, err = time.ParseDuration(value)
+		if err == nil && cfg.Backoff <= 0 {
+			err = fmt.Errorf("must be positive")
+		}
+	case "timeout":
+		cfg.Timeout, err = time.ParseDuration(value)
+		if err == nil && cfg.Timeout <= 0 {
+			err = fmt.Errorf("must be positive")
+		}
+	case "dead-letter":
+		cfg.DeadLetter = value
+	default:
+		return fmt.Errorf("unknown webhook setting %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid webhook setting %s=%s: %w", key, value, err)
+	}
+	return nil
+}
+
+// finish checks the settings are complete and sets the signing key
+func (cfg *WebhookConfig) finish(secret string) error {
+	if cfg.URL == "" {
+		return fmt.Errorf("webhook settings need a url")
+	}
+	if secret == "" {
+		return fmt.Errorf("webhook settings need a secret to sign requests")
+	}
+	cfg.Secret = []byte(secret)
+	for name := range cfg.Headers {
+		switch name {
+		case SignatureHeader, TimestampHeader, NonceHeader, EventHeader, "Content-Type":
+			return fmt.Errorf("webhook header %s is set by multiclaude", name)
+		}
+	}
+	return nil
+}
+
+// DeadLetter is a line of the dead-letter file: an event that could not be
+// delivered, and why
+type DeadLetter struct {
+	Event    events.Event `json:"event"`
+	URL      string       `json:"url"`
+	Attempts int          `json:"attempts"`
+	Error    string       `json:"error"`
+	FailedAt time.Time    `json:"failed_at"`
+}
+
+// WebhookAdapter POSTs each event as JSON to a URL, signed with
+// SignedHeaders. Failed deliveries are retried with exponential backoff in
+// the background, so a slow receiver never holds up other adapters; events
+// that still fail are appended to the dead-letter file.
+type WebhookAdapter struct {
+	cfg   WebhookConfig
+	clock clock.Clock
+	http  *http.Client
+
+	// deliveries tracks events still being sent or retried
+	deliveries sync.WaitGroup
+	// deadMu serializes appends to the dead-letter file
+	deadMu sync.Mutex
+}
+
+// WebhookOption configures a WebhookAdapter
+type WebhookOption func(*WebhookAdapter)
+
+// WithWebhookClock sets the clock retries are timed and requests are signed by
+func WithWebhookClock(c clock.Clock) WebhookOption {
+	return func(a *WebhookAdapter) {
+		a.clock = c
+	}
+}
+
+// NewWebhookAdapter creates an adapter that delivers events according to cfg
+func NewWebhookAdapter(cfg WebhookConfig, opts ...WebhookOption) *WebhookAdapter {
+	if cfg.Attempts < 1 {
+		cfg.Attempts = 1
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultWebhookBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultWebhookTimeout
+	}
+	a := &WebhookAdapter{cfg: cfg, clock: clock.Real(), http: &http.Client{Timeout: cfg.Timeout}}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Name implements Adapter
+func (a *WebhookAdapter) Name() string {
+	return "webhook"
+}
+
+// Send implements Adapter. The event is delivered in the background; when
+// ctx is done, pending retries stop and the event is dead-lettered.
+func (a *WebhookAdapter) Send(ctx context.Context, event events.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	a.deliveries.Add(1)
+	go func() {
+		defer a.deliveries.Done()
+		attempts, err := a.deliver(ctx, event, body)
+		if err != nil {
+			a.deadLetter(event, attempts, err)
+		}
+	}()
+	return nil
+}
+
+// Wait blocks until every event handed to Send has been delivered or
+// dead-lettered
+func (a *WebhookAdapter) Wait() {
+	a.deliveries.Wait()
+}
+
+// deliver sends body until it is accepted, fails permanently, or runs out of
+// attempts, and returns how many attempts were made
+func (a *WebhookAdapter) deliver(ctx context.Context, event events.Event, body []byte) (int, error) {
+	backoff := a.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := a.post(ctx, event, body)
+		if err == nil {
+			return attempt, nil
+		}
+		if _, permanent := err.(permanentError); permanent || attempt >= a.cfg.Attempts {
+			return attempt, err
+		}
+
+		wait := backoff
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if wait > maxWebhookBackoff {
+			wait = maxWebhookBackoff
+		}
+		select {
+		case <-a.clock.After(wait):
+		case <-ctx.Done():
+			return attempt, fmt.Errorf("gave up retrying when the daemon stopped: %w", err)
+		}
+		backoff *= 2
+	}
+}
+
+// permanentError is a failure retrying won't fix, e.g. a 4xx response
+type permanentError struct{ error }
+
+// post makes one delivery attempt. It returns how long the receiver asked
+// to wait with Retry-After, if it did.
+func (a *WebhookAdapter) post(ctx context.Context, event events.Event, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, permanentError{err}
+	}
+	for name, value := range a.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	// Each attempt gets a fresh nonce, since receivers accept a nonce once
+	for name, value := range SignedHeaders(a.cfg.Secret, body, a.clock.Now()) {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+	err = fmt.Errorf("%s responded %s", a.cfg.URL, resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout:
+		// Throttled or timed out: worth retrying
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return 0, permanentError{err}
+	}
+	if secs, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil && secs > 0 {
+		return time.Duration(secs) * time.Second, err
+	}
+	return 0, err
+}
+
+// deadLetter appends an undeliverable event to the dead-letter file
+func (a *WebhookAdapter) deadLetter(event events.Event, attempts int, cause error) {
+	if a.cfg.DeadLetter == "" {
+		return
+	}
+	line, err := json.Marshal(DeadLetter{
+		Event:    event,
+		URL:      a.cfg.URL,
+		Attempts: attempts,
+		Error:    cause.Error(),
+		FailedAt: a.clock.Now(),
+	})
+	if err != nil {
+		return
+	}
+
+	a.deadMu.Lock()
+	defer a.deadMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.cfg.DeadLetter), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(a.cfg.DeadLetter, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
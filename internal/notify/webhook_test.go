@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestParseWebhookConfig(t *testing.T) {
+	cfg, err := ParseWebhookConfig("url=https://example.com/hook,header=x-team: infra,retries=2,backoff=3s", "s3cret")
+	if err != nil {
+		t.Fatalf("ParseWebhookConfig failed: %v", err)
+	}
+	if cfg.URL != "https://example.com/hook" || cfg.Attempts != 3 || cfg.Backoff != 3*time.Second || cfg.Timeout != DefaultWebhookTimeout {
+		t.Errorf("unexpected settings %+v", cfg)
+	}
+	if cfg.Headers["X-Team"] != "infra" || string(cfg.Secret) != "s3cret" {
+		t.Errorf("unexpected headers or secret %+v", cfg)
+	}
+
+	for _, tc := range []struct{ spec, secret string }{
+		{"url=https://example.com/hook", ""},
+		{"header=X-Team:infra", "s"},
+		{"url=ftp://example.com/hook", "s"},
+		{"url=https://example.com/hook,retries=-1", "s"},
+		{"url=https://example.com/hook,header=" + SignatureHeader + ":forged", "s"},
+		{"url=https://example.com/hook,colour=blue", "s"},
+	} {
+		if _, err := ParseWebhookConfig(tc.spec, tc.secret); err == nil {
+			t.Errorf("ParseWebhookConfig(%q, %q) should fail", tc.spec, tc.secret)
+		}
+	}
+}
+
+func TestWebhookAdapterSignsAndRetries(t *testing.T) {
+	secret := []byte("s3cret")
+	verifier := NewVerifier(secret)
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := verifier.Verify(r.Header.Get, body, time.Now()); err != nil {
+			t.Errorf("attempt %d: %v", attempts.Load()+1, err)
+		}
+		if r.Header.Get("X-Team") != "infra" || r.Header.Get(EventHeader) != string(events.EventAgentQuestion) {
+			t.Errorf("missing headers: %v", r.Header)
+		}
+		var event events.Event
+		if err := json.Unmarshal(body, &event); err != nil || event.Title != "worker-1 has a question" {
+			t.Errorf("body should be the event: %v %s", err, body)
+		}
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	adapter := NewWebhookAdapter(WebhookConfig{
+		URL:        server.URL,
+		Secret:     secret,
+		Headers:    map[string]string{"X-Team": "infra"},
+		Attempts:   3,
+		Backoff:    time.Millisecond,
+		DeadLetter: filepath.Join(t.TempDir(), "dead.jsonl"),
+	})
+	if err := adapter.Send(context.Background(), questionEvent()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	adapter.Wait()
+
+	if attempts.Load() != 3 {
+		t.Errorf("got %d attempts, want 3", attempts.Load())
+	}
+	if _, err := os.Stat(adapter.cfg.DeadLetter); !os.IsNotExist(err) {
+		t.Error("a delivered event should not be dead-lettered")
+	}
+}
+
+func TestWebhookAdapterDeadLetter(t *testing.T) {
+	var attempts atomic.Int32
+	status := http.StatusBadGateway
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	deadLetter := filepath.Join(t.TempDir(), "dead.jsonl")
+	adapter := NewWebhookAdapter(WebhookConfig{URL: server.URL, Secret: []byte("s"), Attempts: 3, Backoff: time.Millisecond, DeadLetter: deadLetter})
+
+	// Server errors are retried until the attempts run out
+	adapter.Send(context.Background(), questionEvent())
+	adapter.Wait()
+	if attempts.Load() != 3 {
+		t.Errorf("got %d attempts for a 502, want 3", attempts.Load())
+	}
+
+	// Client errors fail at once
+	status = http.StatusUnauthorized
+	attempts.Store(0)
+	adapter.Send(context.Background(), events.NewEvent(events.EventAgentCompleted, "repo", "worker-2", "done"))
+	adapter.Wait()
+	if attempts.Load() != 1 {
+		t.Errorf("got %d attempts for a 401, want 1", attempts.Load())
+	}
+
+	data, err := os.ReadFile(deadLetter)
+	if err != nil {
+		t.Fatalf("dead-letter file not written: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d dead letters, want 2:\n%s", len(lines), data)
+	}
+	var letter DeadLetter
+	if err := json.Unmarshal([]byte(lines[1]), &letter); err != nil {
+		t.Fatalf("invalid dead letter: %v", err)
+	}
+	if letter.Event.Agent != "worker-2" || letter.Attempts != 1 || !strings.Contains(letter.Error, "401") {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+}
@@ -0,0 +1,278 @@
+// Package oneshot runs a single agent on a single task to completion without
+// the daemon or tmux, for CI jobs. The agent works in a temporary worktree on
+// a new branch and runs Claude in print mode; when it exits, its work is
+// committed and returned as a diff, ready to print or push as a PR.
+package oneshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/names"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+// DefaultTimeout bounds a run when no timeout is given
+const DefaultTimeout = 30 * time.Minute
+
+// Exit statuses of `multiclaude run`, so CI can tell outcomes apart. A run
+// that produced changes exits 0; other errors exit 1.
+const (
+	ExitNoChanges   = 3
+	ExitAgentFailed = 4
+	ExitTimedOut    = 5
+	// ExitInterrupted follows the shell's 128+SIGINT convention
+	ExitInterrupted = 130
+)
+
+// Options configures a run
+type Options struct {
+	RepoDir string // Any directory inside the git repository
+	Task    string
+	Base    string // Commit-ish to start from (default HEAD)
+	Branch  string // Branch to create (default multiclaude/run-<name>)
+	Timeout time.Duration
+	Runner  *claude.Runner
+	// Output receives the agent's output as it works
+	Output io.Writer
+	// KeepWorktree leaves the worktree in place for inspection
+	KeepWorktree bool
+}
+
+// Result is what a run produced
+type Result struct {
+	Branch   string
+	Base     string // Commit the branch started from
+	Worktree string // Empty once removed
+	Commits  int    // Commits on Branch after Base, including the leftovers commit
+	Diff     string // Changes from Base to the branch head, in git apply format
+	Duration time.Duration
+	// AgentErr is why the agent exited unsuccessfully, if it did
+	AgentErr error
+	TimedOut bool
+	// Interrupted is set when the context was cancelled, e.g. by Ctrl-C
+	Interrupted bool
+}
+
+// Changed reports whether the run left any changes on its branch
+func (r *Result) Changed() bool {
+	return r.Diff != ""
+}
+
+// Run creates the worktree, runs the agent on the task until it exits, the
+// timeout passes or ctx is cancelled, and collects its work. The worktree is
+// removed however the agent stops, and the branch is kept when it has changes
+// and deleted otherwise. Errors are returned for setup failures; agent
+// failures, timeouts and interruptions are reported in the Result.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	if strings.TrimSpace(opts.Task) == "" {
+		return nil, fmt.Errorf("task is required")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Runner == nil {
+		opts.Runner = claude.NewRunner(claude.WithBinaryPath(claude.ResolveBinaryPath()))
+	}
+	if opts.Output == nil {
+		opts.Output = io.Discard
+	}
+
+	repoPath, err := git(opts.RepoDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", opts.RepoDir, err)
+	}
+	base := opts.Base
+	if base == "" {
+		base = "HEAD"
+	}
+	baseSHA, err := git(repoPath, "rev-parse", "--verify", base+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown base %q: %w", base, err)
+	}
+	branch := opts.Branch
+	if branch == "" {
+		branch = "multiclaude/run-" + names.Generate()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp("", "multiclaude-run-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	wtPath := filepath.Join(tmpDir, "worktree")
+	wt := worktree.NewManager(repoPath)
+	if err := wt.CreateNewBranch(wtPath, branch, baseSHA); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, fmt.Errorf("failed to create worktree: %w", err)
+	}
+
+	result := &Result{Branch: branch, Base: baseSHA, Worktree: wtPath}
+	defer func() {
+		if opts.KeepWorktree {
+			return
+		}
+		if err := wt.Remove(wtPath, true); err == nil {
+			result.Worktree = ""
+		}
+		os.RemoveAll(tmpDir)
+		if !result.Changed() {
+			wt.DeleteBranch(branch)
+		}
+	}()
+
+	promptFile := filepath.Join(tmpDir, "prompt.md")
+	if err := os.WriteFile(promptFile, []byte(systemPrompt(branch)), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write prompt: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	cmd, err := opts.Runner.HeadlessCommand(runCtx, claude.Config{WorkDir: wtPath, SystemPromptFile: promptFile}, opts.Task)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = opts.Output
+	cmd.Stderr = opts.Output
+	// Interrupt Claude rather than killing it, and give it a moment to exit
+	// after being signaled before giving up
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = 10 * time.Second
+
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		switch {
+		case ctx.Err() != nil:
+			result.Interrupted = true
+			err = fmt.Errorf("interrupted")
+		case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+			result.TimedOut = true
+			err = fmt.Errorf("timed out after %s", opts.Timeout)
+		}
+		result.AgentErr = err
+	}
+	result.Duration = time.Since(start)
+
+	// Work the agent left uncommitted is part of the result
+	if err := commitLeftovers(wtPath, opts.Task); err != nil {
+		return result, err
+	}
+	count, err := git(wtPath, "rev-list", "--count", baseSHA+"..HEAD")
+	if err != nil {
+		return result, err
+	}
+	result.Commits, _ = strconv.Atoi(count)
+	if result.Diff, err = gitRaw(wtPath, "diff", "--binary", baseSHA, "HEAD"); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// commitLeftovers commits changes in the worktree that the agent didn't
+func commitLeftovers(wtPath, task string) error {
+	status, err := git(wtPath, "status", "--porcelain")
+	if err != nil || status == "" {
+		return err
+	}
+	if _, err := git(wtPath, "add", "-A"); err != nil {
+		return err
+	}
+	args := []string{"commit", "--no-verify", "-m", commitSubject(task)}
+	// CI checkouts often have no identity configured
+	if email, _ := git(wtPath, "config", "user.email"); email == "" {
+		args = append([]string{"-c", "user.name=multiclaude", "-c", "user.email=multiclaude@localhost"}, args...)
+	}
+	_, err = git(wtPath, args...)
+	return err
+}
+
+// commitSubject is the first line of the task, shortened for a commit subject
+func commitSubject(task string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(task), "\n")
+	if r := []rune(subject); len(r) > 72 {
+		subject = string(r[:69]) + "..."
+	}
+	return subject
+}
+
+// systemPrompt tells the agent it runs unattended
+func systemPrompt(branch string) string {
+	return fmt.Sprintf(`You are running non-interactively as a one-shot task, for example in CI.
+Nobody will answer questions, so make reasonable decisions and note them in your commit messages.
+
+- You are on branch %s in a temporary worktree. Commit your work to it.
+- Don't push, open pull requests, or switch branches; the caller does that with your commits.
+- Don't run multiclaude commands: there is no daemon.
+- Finish by summarizing what you changed and anything left undone.
+`, branch)
+}
+
+// git runs git in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	out, err := gitRaw(dir, args...)
+	return strings.TrimSpace(out), err
+}
+
+// gitRaw runs git in dir and returns its output as is
+func gitRaw(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// PROptions describe the pull request a run's branch is pushed as
+type PROptions struct {
+	Remote string // default origin
+	Base   string // branch the PR targets (default: the repository's default branch)
+	Title  string
+	Body   string
+}
+
+// OpenPR pushes the run's branch and opens a pull request with the gh CLI,
+// returning its URL
+func OpenPR(repoDir string, result *Result, opts PROptions) (string, error) {
+	remote := opts.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	if _, err := git(repoDir, "push", "--set-upstream", remote, result.Branch); err != nil {
+		return "", fmt.Errorf("failed to push %s: %w", result.Branch, err)
+	}
+
+	args := []string{"pr", "create", "--head", result.Branch, "--title", opts.Title, "--body", opts.Body}
+	if opts.Base != "" {
+		args = append(args, "--base", opts.Base)
+	}
+	cmd := exec.Command("gh", args...)
+	cmd.Dir = repoDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("gh pr create: %s", strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,176 @@
+package oneshot
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/pkg/claude"
+)
+
+// setupRepo creates a repository with one commit
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test User"},
+		{"commit", "--allow-empty", "-m", "Initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+// fakeClaude writes a script standing in for claude that runs body in the
+// worktree
+func fakeClaude(t *testing.T, body string) *claude.Runner {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "claude")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return claude.NewRunner(claude.WithBinaryPath(path))
+}
+
+func branchExists(t *testing.T, repo, branch string) bool {
+	t.Helper()
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	cmd.Dir = repo
+	return cmd.Run() == nil
+}
+
+func TestRunCollectsChanges(t *testing.T) {
+	repo := setupRepo(t)
+	var output strings.Builder
+	result, err := Run(context.Background(), Options{
+		RepoDir: repo,
+		Task:    "Update the CHANGELOG\n\nMention the new flag.",
+		Branch:  "multiclaude/run-test",
+		Runner:  fakeClaude(t, `echo "working on: $2"; echo "- new flag" > CHANGELOG.md`),
+		Output:  &output,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.AgentErr != nil || result.TimedOut {
+		t.Errorf("agent error = %v, timed out = %v", result.AgentErr, result.TimedOut)
+	}
+	if !result.Changed() || result.Commits != 1 || !strings.Contains(result.Diff, "+- new flag") {
+		t.Errorf("result = %+v, want one commit adding CHANGELOG.md", result)
+	}
+	if !strings.Contains(output.String(), "working on: Update the CHANGELOG") {
+		t.Errorf("agent output = %q, want the task passed with -p", output.String())
+	}
+	if result.Worktree != "" {
+		t.Errorf("worktree %s should have been removed", result.Worktree)
+	}
+	if !branchExists(t, repo, "multiclaude/run-test") {
+		t.Error("branch with changes should be kept")
+	}
+
+	log, _ := git(repo, "log", "-1", "--format=%s", "multiclaude/run-test")
+	if log != "Update the CHANGELOG" {
+		t.Errorf("leftovers commit subject = %q, want the task's first line", log)
+	}
+}
+
+func TestRunWithoutChanges(t *testing.T) {
+	repo := setupRepo(t)
+	result, err := Run(context.Background(), Options{
+		RepoDir: repo,
+		Task:    "Look around",
+		Branch:  "multiclaude/run-idle",
+		Runner:  fakeClaude(t, "exit 0"),
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Changed() || result.Commits != 0 {
+		t.Errorf("result = %+v, want no changes", result)
+	}
+	if branchExists(t, repo, "multiclaude/run-idle") {
+		t.Error("branch without changes should be deleted")
+	}
+}
+
+func TestRunAgentFailureAndTimeout(t *testing.T) {
+	repo := setupRepo(t)
+	result, err := Run(context.Background(), Options{
+		RepoDir: repo,
+		Task:    "Break",
+		Runner:  fakeClaude(t, "echo partial > notes.txt; exit 2"),
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.AgentErr == nil || result.TimedOut || !result.Changed() {
+		t.Errorf("result = %+v, want a failed agent whose partial work is kept", result)
+	}
+
+	result, err = Run(context.Background(), Options{
+		RepoDir: repo,
+		Task:    "Hang",
+		Timeout: 200 * time.Millisecond,
+		Runner:  fakeClaude(t, "exec sleep 30"),
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.TimedOut || result.AgentErr == nil {
+		t.Errorf("result = %+v, want a timeout", result)
+	}
+	if result.Duration > 15*time.Second {
+		t.Errorf("run took %s, want it stopped at the timeout", result.Duration)
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	repo := setupRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	result, err := Run(ctx, Options{
+		RepoDir: repo,
+		Task:    "Hang",
+		Branch:  "multiclaude/run-interrupted",
+		Runner:  fakeClaude(t, "exec sleep 30"),
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.Interrupted || result.TimedOut || result.AgentErr == nil {
+		t.Errorf("result = %+v, want an interruption", result)
+	}
+	if result.Duration > 15*time.Second {
+		t.Errorf("run took %s, want it stopped when cancelled", result.Duration)
+	}
+	if result.Worktree != "" {
+		t.Errorf("worktree %s should have been removed", result.Worktree)
+	}
+	if branchExists(t, repo, "multiclaude/run-interrupted") {
+		t.Error("branch without changes should be deleted")
+	}
+}
+
+func TestRunValidation(t *testing.T) {
+	if _, err := Run(context.Background(), Options{RepoDir: t.TempDir(), Task: "x"}); err == nil {
+		t.Error("Run() outside a git repository should fail")
+	}
+	repo := setupRepo(t)
+	if _, err := Run(context.Background(), Options{RepoDir: repo, Task: " "}); err == nil {
+		t.Error("Run() without a task should fail")
+	}
+	if _, err := Run(context.Background(), Options{RepoDir: repo, Task: "x", Base: "no-such-branch"}); err == nil {
+		t.Error("Run() from an unknown base should fail")
+	}
+}
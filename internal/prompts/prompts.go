@@ -172,6 +172,19 @@ Monitor and process all multiclaude-labeled PRs regardless of author or assignee
 	}
 }
 
+// GenerateAutoMergePrompt tells the merge-queue agent that the daemon merges
+// PRs carrying label itself
+func GenerateAutoMergePrompt(label string) string {
+	return fmt.Sprintf(`## Daemon Auto-Merge
+
+**IMPORTANT**: The daemon merges PRs labeled %[1]q itself: it rebases them onto their base branch, waits
+for their required checks, and merges them in order. Do NOT run `+"`gh pr merge`"+` on those PRs.
+
+Your job is everything around that: label PRs with %[1]q once they are ready to merge, add
+"needs-human-input" to hold one back, and help when the daemon reports a PR whose checks failed or
+that conflicts with its base. The daemon skips such a PR until its branch is pushed again.`, label)
+}
+
 // GetSlashCommandsPrompt returns a formatted prompt section containing all available
 // slash commands. This can be included in agent prompts to document the available
 // commands.
@@ -192,3 +205,14 @@ func GetSlashCommandsPrompt() string {
 
 	return builder.String()
 }
+
+// GenerateReadOnlyPrompt generates prompt text for agents whose worktree is read-only.
+// Commits are blocked in such worktrees, so feedback must be delivered another way.
+func GenerateReadOnlyPrompt() string {
+	return `## Read-Only Worktree
+
+**IMPORTANT**: Your worktree is read-only. Files are not writable and commits are blocked by a pre-commit hook.
+
+Do NOT attempt to modify, commit, or push code. Deliver your feedback as PR review comments (` + "`gh pr review`" + `)
+or as messages to other agents (` + "`multiclaude agent send-message`" + `).`
+}
@@ -0,0 +1,265 @@
+// Package repair reconciles state.json with what actually exists after a
+// crash or host reboot. It finds agents whose tmux session, window or
+// worktree is gone and either re-creates the missing pieces and restarts the
+// agent, or drops the agent from state when it cannot be brought back.
+package repair
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/mux"
+)
+
+// Action is what a repair does about an issue
+type Action string
+
+const (
+	// ActionRecreate re-creates the agent's missing worktree, window or
+	// session and restarts the agent in it
+	ActionRecreate Action = "recreate"
+	// ActionDrop removes the agent from state
+	ActionDrop Action = "drop"
+)
+
+// Issue is an agent whose resources no longer match state
+type Issue struct {
+	Repo    string `json:"repo"`
+	Agent   string `json:"agent"`
+	Problem string `json:"problem"` // e.g. "session gone", "window gone, worktree gone"
+	Action  Action `json:"action"`
+	Reason  string `json:"reason,omitempty"` // why the agent is dropped rather than recreated
+	Done    bool   `json:"done"`
+	Error   string `json:"error,omitempty"`
+}
+
+// StartFunc starts an agent's Claude session in its (freshly created) tmux
+// window, resuming the previous conversation where possible
+type StartFunc func(repoName, agentName string, agent state.Agent) error
+
+// Repairer plans and applies repairs
+type Repairer struct {
+	paths *config.Paths
+	state *state.State
+	tmux  mux.Multiplexer
+	start StartFunc
+}
+
+// New creates a repairer. start is called for every recreated agent.
+func New(paths *config.Paths, st *state.State, tmuxClient mux.Multiplexer, start StartFunc) *Repairer {
+	return &Repairer{
+		paths: paths,
+		state: st,
+		tmux:  tmuxClient,
+		start: start,
+	}
+}
+
+// agentCheck is what Plan found out about one agent
+type agentCheck struct {
+	sessionUp  bool
+	windowUp   bool
+	worktreeUp bool
+	branch     string // branch to re-create a missing worktree from
+}
+
+// Plan lists the agents that need repair without changing anything.
+// Repositories for which skip returns true are left out. Agents already
+// marked ready for cleanup are left to the daemon.
+func (r *Repairer) Plan(ctx context.Context, skip func(repo string) bool) ([]Issue, error) {
+	repos := r.state.GetAllRepos()
+	repoNames := make([]string, 0, len(repos))
+	for name := range repos {
+		repoNames = append(repoNames, name)
+	}
+	sort.Strings(repoNames)
+
+	var issues []Issue
+	for _, repoName := range repoNames {
+		if skip != nil && skip(repoName) {
+			continue
+		}
+		repo := repos[repoName]
+		sessionUp, err := r.tmux.HasSession(ctx, repo.TmuxSession)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check session %s: %w", repo.TmuxSession, err)
+		}
+
+		agentNames := make([]string, 0, len(repo.Agents))
+		for name := range repo.Agents {
+			agentNames = append(agentNames, name)
+		}
+		sort.Strings(agentNames)
+
+		for _, agentName := range agentNames {
+			agent := repo.Agents[agentName]
+			if agent.ReadyForCleanup {
+				continue
+			}
+			check := r.check(ctx, repoName, repo, agentName, agent, sessionUp)
+			if check.windowUp && check.worktreeUp {
+				continue
+			}
+			issues = append(issues, planIssue(repoName, agentName, check))
+		}
+	}
+	return issues, nil
+}
+
+// check looks up which of an agent's resources still exist
+func (r *Repairer) check(ctx context.Context, repoName string, repo *state.Repository, agentName string, agent state.Agent, sessionUp bool) agentCheck {
+	check := agentCheck{sessionUp: sessionUp, worktreeUp: true}
+	if sessionUp {
+		check.windowUp, _ = r.tmux.HasWindow(ctx, repo.TmuxSession, agent.TmuxWindow)
+	}
+	if agent.WorktreePath != "" {
+		if _, err := os.Stat(agent.WorktreePath); os.IsNotExist(err) {
+			check.worktreeUp = false
+			check.branch = r.worktreeBranch(repoName, agentName, agent)
+		}
+	}
+	return check
+}
+
+// worktreeBranch returns the branch a missing worktree can be re-created
+// from, or "" if there is none. Only workers and workspaces own a branch
+// named after them (workers' under the configured branch prefix).
+func (r *Repairer) worktreeBranch(repoName, agentName string, agent state.Agent) string {
+	var branch string
+	switch agent.Type {
+	case state.AgentTypeWorker:
+		prefix := config.DefaultBranchPrefix
+		if file, err := config.LoadFile(r.paths.ConfigFile()); err == nil {
+			prefix = file.BranchPrefix(repoName)
+		}
+		branch = prefix + agentName
+	case state.AgentTypeWorkspace:
+		branch = "workspace/" + agentName
+	default:
+		return ""
+	}
+	if agent.WorktreePath != r.paths.AgentWorktree(repoName, agentName) {
+		return ""
+	}
+	exists, err := worktree.NewManager(r.paths.RepoDir(repoName)).BranchExists(branch)
+	if err != nil || !exists {
+		return ""
+	}
+	return branch
+}
+
+// planIssue decides what to do about an agent
+func planIssue(repoName, agentName string, check agentCheck) Issue {
+	var problems []string
+	switch {
+	case !check.sessionUp:
+		problems = append(problems, "session gone")
+	case !check.windowUp:
+		problems = append(problems, "window gone")
+	}
+	if !check.worktreeUp {
+		problems = append(problems, "worktree gone")
+	}
+
+	issue := Issue{
+		Repo:    repoName,
+		Agent:   agentName,
+		Problem: strings.Join(problems, ", "),
+		Action:  ActionRecreate,
+	}
+	if !check.worktreeUp && check.branch == "" {
+		issue.Action = ActionDrop
+		issue.Reason = "no branch to re-create the worktree from"
+	}
+	return issue
+}
+
+// Apply carries out planned issues and returns them with Done and Error
+// filled in. Each agent is checked again first, so issues fixed in the
+// meantime are skipped.
+func (r *Repairer) Apply(ctx context.Context, issues []Issue) []Issue {
+	applied := make([]Issue, 0, len(issues))
+	for _, issue := range issues {
+		if err := r.apply(ctx, issue); err != nil {
+			issue.Error = err.Error()
+		} else {
+			issue.Done = true
+		}
+		applied = append(applied, issue)
+	}
+	return applied
+}
+
+func (r *Repairer) apply(ctx context.Context, issue Issue) error {
+	repo, exists := r.state.GetAllRepos()[issue.Repo]
+	if !exists {
+		return fmt.Errorf("repository %q not found", issue.Repo)
+	}
+	agent, exists := repo.Agents[issue.Agent]
+	if !exists {
+		return fmt.Errorf("agent %q not found", issue.Agent)
+	}
+
+	sessionUp, err := r.tmux.HasSession(ctx, repo.TmuxSession)
+	if err != nil {
+		return err
+	}
+	check := r.check(ctx, issue.Repo, repo, issue.Agent, agent, sessionUp)
+	if check.windowUp && check.worktreeUp {
+		return nil
+	}
+
+	if issue.Action == ActionDrop {
+		if check.windowUp {
+			if err := r.tmux.KillWindow(ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
+				return err
+			}
+		}
+		return r.state.RemoveAgent(issue.Repo, issue.Agent)
+	}
+
+	if !check.worktreeUp {
+		if check.branch == "" {
+			return fmt.Errorf("no branch to re-create the worktree from")
+		}
+		wt := worktree.NewManager(r.paths.RepoDir(issue.Repo), worktree.WithLFSContent(repo.LFSContentFor(agent.Type)))
+		// Git still lists the deleted worktree until it is pruned
+		if err := wt.Prune(); err != nil {
+			return err
+		}
+		if err := wt.Create(agent.WorktreePath, check.branch); err != nil {
+			return err
+		}
+	}
+
+	// A window left in a deleted worktree has nothing useful running in it
+	if check.windowUp {
+		if err := r.tmux.KillWindow(ctx, repo.TmuxSession, agent.TmuxWindow); err != nil {
+			return err
+		}
+		sessionUp, err = r.tmux.HasSession(ctx, repo.TmuxSession)
+		if err != nil {
+			return err
+		}
+	}
+
+	dir := agent.WorktreePath
+	if dir == "" {
+		dir = r.paths.RepoDir(issue.Repo)
+	}
+	if sessionUp {
+		err = r.tmux.CreateWindowAt(ctx, repo.TmuxSession, agent.TmuxWindow, dir)
+	} else {
+		err = r.tmux.CreateSessionAt(ctx, repo.TmuxSession, agent.TmuxWindow, dir)
+	}
+	if err != nil {
+		return err
+	}
+	return r.start(issue.Repo, issue.Agent, agent)
+}
@@ -0,0 +1,170 @@
+package repair
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// setupRepairer creates a repository "repo" with a live tmux session and
+// three workers:
+//   - windowless: worktree present, window gone
+//   - branched: worktree deleted, work/branched branch still there
+//   - lost: worktree deleted and no branch
+func setupRepairer(t *testing.T) (*Repairer, *state.State, *config.Paths, *[]string) {
+	t.Helper()
+
+	ctx := context.Background()
+	tmuxClient := tmux.NewClient()
+	if !tmuxClient.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	paths := config.NewTestPaths(t.TempDir())
+	repoPath := paths.RepoDir("repo")
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "--allow-empty", "-m", "Initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	session := fmt.Sprintf("mc-repair-test-%d", time.Now().UnixNano())
+	if err := tmuxClient.CreateSessionAt(ctx, session, "supervisor", repoPath); err != nil {
+		t.Skipf("cannot create tmux sessions in this environment: %v", err)
+	}
+	t.Cleanup(func() { tmuxClient.KillSession(context.Background(), session) })
+
+	st := state.New(paths.StateFile)
+	st.AddRepo("repo", &state.Repository{TmuxSession: session, Agents: make(map[string]state.Agent)})
+	st.AddAgent("repo", "supervisor", state.Agent{Type: state.AgentTypeSupervisor, TmuxWindow: "supervisor", WorktreePath: repoPath})
+
+	wt := worktree.NewManager(repoPath)
+	for _, name := range []string{"windowless", "branched", "lost"} {
+		path := paths.AgentWorktree("repo", name)
+		if err := wt.CreateNewBranch(path, "work/"+name, "main"); err != nil {
+			t.Fatalf("Failed to create worktree: %v", err)
+		}
+		st.AddAgent("repo", name, state.Agent{Type: state.AgentTypeWorker, TmuxWindow: name, WorktreePath: path})
+	}
+	for _, name := range []string{"branched", "lost"} {
+		if err := os.RemoveAll(paths.AgentWorktree("repo", name)); err != nil {
+			t.Fatalf("Failed to remove worktree: %v", err)
+		}
+	}
+	if err := wt.Prune(); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if err := wt.DeleteBranch("work/lost"); err != nil {
+		t.Fatalf("Failed to delete branch: %v", err)
+	}
+
+	var started []string
+	repairer := New(paths, st, tmuxClient, func(repoName, agentName string, agent state.Agent) error {
+		started = append(started, agentName)
+		return nil
+	})
+	return repairer, st, paths, &started
+}
+
+func TestPlanAndApply(t *testing.T) {
+	repairer, st, paths, started := setupRepairer(t)
+	ctx := context.Background()
+
+	issues, err := repairer.Plan(ctx, nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	want := []Issue{
+		{Repo: "repo", Agent: "branched", Problem: "window gone, worktree gone", Action: ActionRecreate},
+		{Repo: "repo", Agent: "lost", Problem: "window gone, worktree gone", Action: ActionDrop, Reason: "no branch to re-create the worktree from"},
+		{Repo: "repo", Agent: "windowless", Problem: "window gone", Action: ActionRecreate},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %+v, want %+v", issues, want)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], want[i])
+		}
+	}
+	if len(*started) != 0 {
+		t.Error("Plan must not start agents")
+	}
+
+	applied := repairer.Apply(ctx, issues)
+	for _, issue := range applied {
+		if !issue.Done || issue.Error != "" {
+			t.Errorf("%s: done=%v error=%q", issue.Agent, issue.Done, issue.Error)
+		}
+	}
+
+	if _, exists := st.GetAgent("repo", "lost"); exists {
+		t.Error("lost should have been dropped from state")
+	}
+	if _, err := os.Stat(paths.AgentWorktree("repo", "branched")); err != nil {
+		t.Errorf("branched worktree should have been re-created: %v", err)
+	}
+	if len(*started) != 2 || (*started)[0] != "branched" || (*started)[1] != "windowless" {
+		t.Errorf("started %v, want [branched windowless]", *started)
+	}
+
+	issues, err = repairer.Plan(ctx, nil)
+	if err != nil {
+		t.Fatalf("second Plan failed: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("second Plan found %+v, want nothing", issues)
+	}
+}
+
+func TestPlanSessionGone(t *testing.T) {
+	repairer, st, _, started := setupRepairer(t)
+	ctx := context.Background()
+
+	repo, _ := st.GetRepo("repo")
+	if err := repairer.tmux.KillSession(ctx, repo.TmuxSession); err != nil {
+		t.Fatalf("Failed to kill session: %v", err)
+	}
+
+	issues, err := repairer.Plan(ctx, func(name string) bool { return false })
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(issues) != 4 || issues[3].Agent != "windowless" || issues[3].Problem != "session gone" {
+		t.Fatalf("unexpected plan: %+v", issues)
+	}
+
+	repairer.Apply(ctx, issues)
+	if exists, _ := repairer.tmux.HasSession(ctx, repo.TmuxSession); !exists {
+		t.Error("session should have been re-created")
+	}
+	if len(*started) != 3 {
+		t.Errorf("started %v, want supervisor, branched and windowless", *started)
+	}
+
+	skipped, err := repairer.Plan(ctx, func(name string) bool { return name == "repo" })
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("skipped repository reported %+v", skipped)
+	}
+}
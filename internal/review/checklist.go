@@ -0,0 +1,212 @@
+// Package review provides path-based review checklists for reviewer agents.
+//
+// A repository lists checklists in .multiclaude/review-checklists.json. Each
+// checklist applies when a PR changes a file under one of its paths; its items
+// are injected into the reviewer's prompt, and the reviewer's PR comment must
+// answer every item in a "Review Checklist" section.
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/worktree"
+)
+
+// ConfigFile is the checklist file name inside a repository's .multiclaude directory
+const ConfigFile = "review-checklists.json"
+
+// SectionHeading is the heading reviewers put above checklist answers
+const SectionHeading = "## Review Checklist"
+
+// Checklist is a set of review items required when matching paths change
+type Checklist struct {
+	Name string `json:"name"`
+	// Paths lists path prefixes (e.g. "migrations/") or globs (e.g. "auth/*.go").
+	// An empty list applies the checklist to every PR.
+	Paths []string `json:"paths,omitempty"`
+	Items []string `json:"items"`
+}
+
+// Config is the contents of review-checklists.json
+type Config struct {
+	Checklists []Checklist `json:"checklists"`
+}
+
+// LoadConfig reads the checklist config from a repository checkout. A missing
+// file is not an error and yields an empty config.
+func LoadConfig(repoPath string) (*Config, error) {
+	configPath := filepath.Join(repoPath, ".multiclaude", ConfigFile)
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review checklists: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+	for i, checklist := range config.Checklists {
+		if checklist.Name == "" {
+			return nil, fmt.Errorf("review checklist #%d has no name", i+1)
+		}
+		if len(checklist.Items) == 0 {
+			return nil, fmt.Errorf("review checklist %q has no items", checklist.Name)
+		}
+	}
+	return &config, nil
+}
+
+// Match returns the checklists that apply to a PR changing files
+func (c *Config) Match(files []string) []Checklist {
+	var matched []Checklist
+	for _, checklist := range c.Checklists {
+		if len(checklist.Paths) == 0 {
+			matched = append(matched, checklist)
+			continue
+		}
+		for _, f := range files {
+			if worktree.PathAllowed(f, checklist.Paths) {
+				matched = append(matched, checklist)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// ForWorktree returns the checklists from repoPath's config that apply to the
+// branch checked out in worktreePath, compared against base (e.g. "origin/main")
+func ForWorktree(repoPath, worktreePath, base string) ([]Checklist, error) {
+	config, err := LoadConfig(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Checklists) == 0 {
+		return nil, nil
+	}
+
+	diff, err := worktree.SummarizeDiff(worktreePath, base)
+	if err != nil {
+		return nil, err
+	}
+	return config.Match(diff.Files), nil
+}
+
+// Prompt returns the reviewer prompt section for the matched checklists, or
+// an empty string if none apply
+func Prompt(checklists []Checklist) string {
+	if len(checklists) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## Required Review Checklists\n\n")
+	b.WriteString("This PR touches paths that have required review checklists. Check every item below.\n\n")
+	for _, checklist := range checklists {
+		fmt.Fprintf(&b, "### %s", checklist.Name)
+		if len(checklist.Paths) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(checklist.Paths, ", "))
+		}
+		b.WriteString("\n")
+		for _, item := range checklist.Items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Your PR comment must include a `%s` section with one line per item, copying the item text:\n\n", SectionHeading)
+	b.WriteString("```markdown\n" + SectionHeading + "\n")
+	b.WriteString("- [x] <item> - how the PR satisfies it\n")
+	b.WriteString("- [ ] <item> - what is missing\n")
+	b.WriteString("```\n\n")
+	b.WriteString("An unchecked item is a **[BLOCKING]** issue; report it to the merge-queue as one.\n")
+	b.WriteString("Before posting, validate the comment with `multiclaude agent check-review <comment-file>`.\n")
+	return b.String()
+}
+
+// checklistLine matches "- [x] text" and "* [ ] text" lines
+var checklistLine = regexp.MustCompile(`^\s*[-*]\s*\[([ xX])\]\s*(.+)$`)
+
+// Result is the outcome of validating a review comment against checklists
+type Result struct {
+	Missing   []string // Items with no line in the checklist section
+	Unchecked []string // Items answered but not satisfied
+}
+
+// Valid returns true if every item was answered. Unchecked items are valid
+// answers; they are reported so callers can treat them as blocking.
+func (r *Result) Valid() bool {
+	return len(r.Missing) == 0
+}
+
+// String formats the result for agents
+func (r *Result) String() string {
+	var b strings.Builder
+	if r.Valid() {
+		b.WriteString("Review checklist complete")
+	} else {
+		fmt.Fprintf(&b, "Review checklist is missing %d item(s) under %q:\n", len(r.Missing), SectionHeading)
+		for _, item := range r.Missing {
+			fmt.Fprintf(&b, "  - [ ] %s\n", item)
+		}
+	}
+	if len(r.Unchecked) > 0 {
+		fmt.Fprintf(&b, "\n%d item(s) are not satisfied and must be reported as BLOCKING:\n", len(r.Unchecked))
+		for _, item := range r.Unchecked {
+			fmt.Fprintf(&b, "  - %s\n", item)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Validate checks that a review comment answers every checklist item in its
+// SectionHeading section. Items match case-insensitively on their text.
+func Validate(body string, checklists []Checklist) *Result {
+	answers := make(map[string]bool) // normalized line text -> checked
+	inSection := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			inSection = strings.EqualFold(strings.TrimSpace(strings.TrimLeft(trimmed, "#")), strings.TrimLeft(SectionHeading, "# "))
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if m := checklistLine.FindStringSubmatch(line); m != nil {
+			answers[strings.ToLower(m[2])] = m[1] != " "
+		}
+	}
+
+	result := &Result{}
+	for _, checklist := range checklists {
+		for _, item := range checklist.Items {
+			checked, found := findAnswer(answers, item)
+			switch {
+			case !found:
+				result.Missing = append(result.Missing, item)
+			case !checked:
+				result.Unchecked = append(result.Unchecked, item)
+			}
+		}
+	}
+	return result
+}
+
+// findAnswer looks up the answer line that starts with item
+func findAnswer(answers map[string]bool, item string) (checked, found bool) {
+	want := strings.ToLower(strings.TrimSpace(item))
+	for text, isChecked := range answers {
+		if strings.HasPrefix(text, want) {
+			return isChecked, true
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,99 @@
+package review
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testChecklists = []Checklist{
+	{Name: "Migrations", Paths: []string{"migrations/"}, Items: []string{"Rollback note", "Tested on a copy of production data"}},
+	{Name: "Auth", Paths: []string{"auth/"}, Items: []string{"Security signoff"}},
+	{Name: "Docs", Paths: []string{"docs/*.md"}, Items: []string{"Links checked"}},
+}
+
+func TestLoadConfig(t *testing.T) {
+	repo := t.TempDir()
+
+	config, err := LoadConfig(repo)
+	if err != nil || len(config.Checklists) != 0 {
+		t.Fatalf("missing config should be empty, got %+v, %v", config, err)
+	}
+
+	dir := filepath.Join(repo, ".multiclaude")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, ConfigFile), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"checklists": [{"name": "Migrations", "paths": ["migrations/"], "items": ["Rollback note"]}]}`)
+	config, err = LoadConfig(repo)
+	if err != nil || len(config.Checklists) != 1 || config.Checklists[0].Items[0] != "Rollback note" {
+		t.Fatalf("LoadConfig = %+v, %v", config, err)
+	}
+
+	write(`{"checklists": [{"name": "Empty", "paths": ["x/"]}]}`)
+	if _, err := LoadConfig(repo); err == nil || !strings.Contains(err.Error(), "has no items") {
+		t.Errorf("checklists without items should be rejected, got %v", err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	config := &Config{Checklists: append([]Checklist{{Name: "All", Items: []string{"Changelog updated"}}}, testChecklists...)}
+
+	var names []string
+	for _, c := range config.Match([]string{"migrations/0042_users.sql", "docs/guide.md", "cmd/main.go"}) {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "All,Migrations,Docs" {
+		t.Errorf("matched checklists = %s, want All,Migrations,Docs", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	checklists := testChecklists[:2]
+	body := `Looks good overall.
+
+## Review Checklist
+- [x] Rollback note - down migration included
+- [ ] tested on a copy of production data - not mentioned in the PR
+
+## Suggestions
+- [x] Security signoff (outside the checklist section, ignored)
+`
+	result := Validate(body, checklists)
+	if result.Valid() {
+		t.Fatal("expected missing items")
+	}
+	if len(result.Missing) != 1 || result.Missing[0] != "Security signoff" {
+		t.Errorf("Missing = %v", result.Missing)
+	}
+	if len(result.Unchecked) != 1 || result.Unchecked[0] != "Tested on a copy of production data" {
+		t.Errorf("Unchecked = %v", result.Unchecked)
+	}
+	if report := result.String(); !strings.Contains(report, "missing 1 item") || !strings.Contains(report, "BLOCKING") {
+		t.Errorf("report = %q", report)
+	}
+
+	body += "\n### review checklist\n* [X] Security signoff - approved by the security team\n"
+	if result := Validate(body, checklists); !result.Valid() {
+		t.Errorf("all items answered, got missing %v", result.Missing)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	if Prompt(nil) != "" {
+		t.Error("no checklists should produce no prompt section")
+	}
+	prompt := Prompt(testChecklists[:1])
+	for _, want := range []string{"### Migrations (migrations/)", "- Rollback note", SectionHeading, "multiclaude agent check-review"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package scope restricts worker tasks to a sub-project of a monorepo.
+//
+// A task started with `multiclaude work --path services/billing` gets a sparse
+// checkout of that directory, a branch guard limited to it, and prompt
+// instructions naming the sub-project's test and lint commands. Per-path
+// presets live in .multiclaude/subprojects.json.
+package scope
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFile is the preset file name inside a repository's .multiclaude directory
+const ConfigFile = "subprojects.json"
+
+// Preset describes how tasks scoped to a sub-project are set up
+type Preset struct {
+	// Path is the sub-project directory relative to the repository root
+	Path string `json:"path"`
+	// Include lists shared directories the sub-project also needs (e.g. "libs/common")
+	Include []string `json:"include,omitempty"`
+	// Test is the command that runs the sub-project's tests
+	Test string `json:"test,omitempty"`
+	// Lint is the command that lints the sub-project
+	Lint string `json:"lint,omitempty"`
+}
+
+// Config is the contents of subprojects.json
+type Config struct {
+	Subprojects []Preset `json:"subprojects"`
+}
+
+// NormalizePath cleans a sub-project path and rejects paths outside the repository
+func NormalizePath(p string) (string, error) {
+	cleaned := path.Clean(strings.TrimSpace(filepath.ToSlash(p)))
+	if cleaned == "." || cleaned == "" {
+		return "", fmt.Errorf("sub-project path is empty")
+	}
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("sub-project path %q must be relative to the repository root", p)
+	}
+	return cleaned, nil
+}
+
+// LoadConfig reads the sub-project presets from a repository checkout. A
+// missing file is not an error and yields an empty config.
+func LoadConfig(repoPath string) (*Config, error) {
+	configPath := filepath.Join(repoPath, ".multiclaude", ConfigFile)
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sub-project presets: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+	for i := range config.Subprojects {
+		preset := &config.Subprojects[i]
+		if preset.Path, err = NormalizePath(preset.Path); err != nil {
+			return nil, fmt.Errorf("sub-project preset #%d: %w", i+1, err)
+		}
+		for j, include := range preset.Include {
+			if preset.Include[j], err = NormalizePath(include); err != nil {
+				return nil, fmt.Errorf("sub-project %s: %w", preset.Path, err)
+			}
+		}
+	}
+	return &config, nil
+}
+
+// Preset returns the preset for a normalized sub-project path, or a bare
+// preset with no commands if none is configured
+func (c *Config) Preset(subPath string) Preset {
+	for _, preset := range c.Subprojects {
+		if preset.Path == subPath {
+			return preset
+		}
+	}
+	return Preset{Path: subPath}
+}
+
+// Resolve normalizes subPath and returns its preset from repoPath's config
+func Resolve(repoPath, subPath string) (Preset, error) {
+	normalized, err := NormalizePath(subPath)
+	if err != nil {
+		return Preset{}, err
+	}
+	config, err := LoadConfig(repoPath)
+	if err != nil {
+		return Preset{}, err
+	}
+	return config.Preset(normalized), nil
+}
+
+// Paths returns the sub-project path followed by its included directories
+func (p Preset) Paths() []string {
+	return append([]string{p.Path}, p.Include...)
+}
+
+// AllowedPaths returns the branch guard prefixes for the sub-project
+func (p Preset) AllowedPaths() []string {
+	var allowed []string
+	for _, dir := range p.Paths() {
+		allowed = append(allowed, dir+"/")
+	}
+	return allowed
+}
+
+// Prompt returns the worker prompt section for a scoped task
+func Prompt(p Preset) string {
+	var b strings.Builder
+	b.WriteString("## Sub-project Scope\n\n")
+	fmt.Fprintf(&b, "**This task is scoped to `%s`.** ", p.Path)
+	b.WriteString("Your worktree is a sparse checkout: only this sub-project")
+	if len(p.Include) > 0 {
+		fmt.Fprintf(&b, ", the shared directories %s,", quoteList(p.Include))
+	}
+	b.WriteString(" and files at the repository root are present.\n\n")
+	b.WriteString("- Only change files under the paths above; the branch guard rejects changes elsewhere\n")
+	fmt.Fprintf(&b, "- Run commands from `%s` unless they need the repository root\n", p.Path)
+	if p.Test != "" {
+		fmt.Fprintf(&b, "- Run tests with: `%s`\n", p.Test)
+	}
+	if p.Lint != "" {
+		fmt.Fprintf(&b, "- Lint with: `%s`\n", p.Lint)
+	}
+	b.WriteString("- If the task needs changes outside this scope, ask the supervisor instead of widening it yourself\n")
+	return b.String()
+}
+
+// quoteList formats paths as a comma-separated list of code spans
+func quoteList(paths []string) string {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = "`" + p + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
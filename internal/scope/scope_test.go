@@ -0,0 +1,80 @@
+package scope
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePath(t *testing.T) {
+	tests := map[string]string{
+		"services/billing":    "services/billing",
+		"./services/billing/": "services/billing",
+		"services//billing":   "services/billing",
+	}
+	for in, want := range tests {
+		if got, err := NormalizePath(in); err != nil || got != want {
+			t.Errorf("NormalizePath(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", ".", "/etc", "..", "../other", "services/../../x"} {
+		if _, err := NormalizePath(in); err == nil {
+			t.Errorf("NormalizePath(%q) should fail", in)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	repo := t.TempDir()
+
+	preset, err := Resolve(repo, "services/billing/")
+	if err != nil || !reflect.DeepEqual(preset, Preset{Path: "services/billing"}) {
+		t.Fatalf("Resolve without config = %+v, %v", preset, err)
+	}
+
+	dir := filepath.Join(repo, ".multiclaude")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"subprojects": [
+		{"path": "./services/billing", "include": ["libs/money/"], "test": "go test ./...", "lint": "golangci-lint run"}
+	]}`
+	if err := os.WriteFile(filepath.Join(dir, ConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	preset, err = Resolve(repo, "services/billing")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if preset.Test != "go test ./..." || preset.Lint != "golangci-lint run" {
+		t.Errorf("preset commands not loaded: %+v", preset)
+	}
+	if got, want := preset.Paths(), []string{"services/billing", "libs/money"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %v, want %v", got, want)
+	}
+	if got, want := preset.AllowedPaths(), []string{"services/billing/", "libs/money/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AllowedPaths() = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ConfigFile), []byte(`{"subprojects": [{"path": "../x"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Resolve(repo, "services/billing"); err == nil {
+		t.Error("presets outside the repository should be rejected")
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	prompt := Prompt(Preset{Path: "services/billing", Include: []string{"libs/money"}, Test: "make test"})
+	for _, want := range []string{"`services/billing`", "`libs/money`", "Run tests with: `make test`"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "Lint with") {
+		t.Errorf("prompt should omit lint without a command:\n%s", prompt)
+	}
+}
@@ -0,0 +1,115 @@
+// Package snapshot records exactly what an agent was spawned with: the
+// rendered prompt, the agent definition it came from, the model and launch
+// template, and the options given on the command line. A snapshot lets a
+// spawn be replayed later (`multiclaude work rerun`) to debug prompt
+// regressions.
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/launch"
+)
+
+const (
+	// File holds the snapshot metadata inside a snapshot directory
+	File = "snapshot.json"
+	// PromptFile holds the exact prompt the agent was started with
+	PromptFile = "prompt.md"
+)
+
+// Definition identifies the agent definition a prompt was rendered from
+type Definition struct {
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"` // "local" or "repo"
+	Path   string `json:"path,omitempty"`
+	SHA256 string `json:"sha256"`
+}
+
+// Snapshot describes one agent spawn
+type Snapshot struct {
+	Agent     string    `json:"agent"`
+	Repo      string    `json:"repo"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Task      string    `json:"task,omitempty"`
+	// Flags are the spawn's command-line options (--branch, --path, ...)
+	Flags map[string]string `json:"flags,omitempty"`
+	// BaseCommit is the commit the agent's worktree started from
+	BaseCommit string     `json:"base_commit,omitempty"`
+	Definition Definition `json:"definition"`
+	// Model is the --model passed through the launch template; empty means
+	// Claude's default
+	Model        string          `json:"model,omitempty"`
+	Launch       launch.Template `json:"launch,omitempty"`
+	PromptSHA256 string          `json:"prompt_sha256"`
+}
+
+// Hash returns the hex SHA-256 of text
+func Hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// ModelFromArgs returns the value of --model in Claude launch arguments
+func ModelFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--model" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(arg, "--model=") {
+			return strings.TrimPrefix(arg, "--model=")
+		}
+	}
+	return ""
+}
+
+// Save writes the snapshot and its prompt to dir, replacing any previous
+// snapshot there
+func Save(dir string, snap *Snapshot, prompt string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	snap.PromptSHA256 = Hash(prompt)
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, PromptFile), []byte(prompt), 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot prompt: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, File), data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load reads a snapshot and its prompt from dir, verifying that the prompt
+// has not changed since it was saved
+func Load(dir string) (*Snapshot, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, File))
+	if err != nil {
+		return nil, "", err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, "", fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+
+	prompt, err := os.ReadFile(filepath.Join(dir, PromptFile))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read snapshot prompt: %w", err)
+	}
+	if Hash(string(prompt)) != snap.PromptSHA256 {
+		return nil, "", fmt.Errorf("snapshot prompt in %s was modified after the spawn", dir)
+	}
+	return &snap, string(prompt), nil
+}
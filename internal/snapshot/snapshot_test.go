@@ -0,0 +1,67 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshots", "swift-fox")
+	snap := &Snapshot{
+		Agent:      "swift-fox",
+		Repo:       "repo",
+		Type:       "worker",
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+		Task:       "Fix the flaky test",
+		Flags:      map[string]string{"path": "services/billing"},
+		BaseCommit: "abc123",
+		Definition: Definition{Name: "worker", Source: "repo", SHA256: Hash("definition")},
+		Model:      "opus",
+	}
+	if err := Save(dir, snap, "You are a worker."); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, prompt, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if prompt != "You are a worker." {
+		t.Errorf("prompt = %q", prompt)
+	}
+	if !reflect.DeepEqual(loaded, snap) {
+		t.Errorf("Load() = %+v, want %+v", loaded, snap)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, PromptFile), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Load(dir); err == nil {
+		t.Error("Load should reject a prompt edited after the spawn")
+	}
+
+	if _, _, err := Load(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("Load(missing) error = %v, want not-exist", err)
+	}
+}
+
+func TestModelFromArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"--verbose"}, ""},
+		{[]string{"--model", "opus"}, "opus"},
+		{[]string{"--verbose", "--model=sonnet"}, "sonnet"},
+		{[]string{"--model"}, ""},
+	}
+	for _, tt := range tests {
+		if got := ModelFromArgs(tt.args); got != tt.want {
+			t.Errorf("ModelFromArgs(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
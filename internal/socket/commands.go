@@ -0,0 +1,1085 @@
+package socket
+
+// Typed arguments and responses of the commands served with Typed. The json
+// tags are the wire names, so version 0 clients sending plain argument maps
+// and Go clients using Call see the same protocol.
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/github"
+	"github.com/dlorenc/multiclaude/internal/metrics"
+	"github.com/dlorenc/multiclaude/internal/repair"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// NoArgs are the arguments of a command that takes none
+type NoArgs struct{}
+
+// RepoArgs are the arguments of a command on one repository
+type RepoArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+}
+
+// AgentArgs are the arguments of a command on one agent
+type AgentArgs struct {
+	Repo  string `json:"repo" socket:"required,repository name is required"`
+	Agent string `json:"agent" socket:"required,agent name is required"`
+}
+
+// SetSocketGroupArgs are the arguments of set_socket_group
+type SetSocketGroupArgs struct {
+	// Group may use the socket; empty makes it private to the daemon's user
+	Group string `json:"group,omitempty"`
+}
+
+// SocketGroup is what set_socket_group returns
+type SocketGroup struct {
+	Group string `json:"group"`
+}
+
+// WhoamiArgs are the arguments of whoami
+type WhoamiArgs struct {
+	// Repo asks for the caller's permissions on a repository
+	Repo string `json:"repo,omitempty"`
+}
+
+// Whoami is what whoami returns: how the daemon identifies the caller
+type Whoami struct {
+	Owner       bool   `json:"owner"`
+	SocketGroup string `json:"socket_group"`
+	// Remote is the address of a caller on the remote socket
+	Remote string `json:"remote,omitempty"`
+	User   string `json:"user,omitempty"`
+	UID    *int   `json:"uid,omitempty"`
+	// Permissions on the repository asked about
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// AgentScreenArgs are the arguments of agent_screen
+type AgentScreenArgs struct {
+	Repo  string `json:"repo" socket:"required,repository name is required"`
+	Agent string `json:"agent" socket:"required,agent name is required"`
+	// Lines of scrollback to include above the visible screen
+	Lines int `json:"lines,omitempty"`
+}
+
+// AgentScreen is what agent_screen returns: the agent's pane, or the end of
+// its output for a headless agent
+type AgentScreen struct {
+	Repo     string `json:"repo"`
+	Agent    string `json:"agent"`
+	Window   string `json:"window,omitempty"`
+	Headless bool   `json:"headless,omitempty"`
+	Screen   string `json:"screen"`
+}
+
+// AuditLogArgs are the arguments of list_audit
+type AuditLogArgs struct {
+	// Repo limits entries to one repository
+	Repo string `json:"repo,omitempty"`
+	// Since is an RFC 3339 time; older entries are left out
+	Since string `json:"since,omitempty"`
+	// Limit keeps the newest entries
+	Limit int `json:"limit,omitempty"`
+}
+
+// RestoreStateArgs are the arguments of restore_state
+type RestoreStateArgs struct {
+	// Backup is the backup to restore; 0 picks the newest valid one
+	Backup int `json:"backup,omitempty"`
+	// DryRun picks the backup without restoring it
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// RestoreState is what restore_state returns
+type RestoreState struct {
+	// Backup is the backup restored, or with dry_run the one that would be
+	Backup int `json:"backup"`
+	// Backups are the state file's backups before the restore, newest first
+	Backups []state.Backup `json:"backups"`
+}
+
+// FleetStatusArgs are the arguments of fleet_status
+type FleetStatusArgs struct {
+	// Group limits repositories and agents to one repo group
+	Group string `json:"group,omitempty"`
+	// Local leaves out the hosts file, answering for this daemon only. It
+	// is what a daemon asks each host in its hosts file.
+	Local bool `json:"local,omitempty"`
+}
+
+// FleetHost is one daemon's part of fleet_status. Status, Repos and Agents
+// are what its status, list_repos and list_agents return in their rich
+// format.
+type FleetHost struct {
+	Host string `json:"host"`
+	// SSH destination or TCP address; empty for the daemon answering
+	Target string `json:"target,omitempty"`
+	// Why the host couldn't be asked, or only partly
+	Error  string                   `json:"error,omitempty"`
+	Status map[string]interface{}   `json:"status,omitempty"`
+	Repos  []map[string]interface{} `json:"repos"`
+	Agents []map[string]interface{} `json:"agents"`
+}
+
+// FleetStatus is what fleet_status returns: the daemon answering, then each
+// host in its hosts file. The totals count reachable hosts only.
+type FleetStatus struct {
+	Hosts   []FleetHost `json:"hosts"`
+	Agents  int         `json:"agents"`
+	Workers int         `json:"workers"`
+}
+
+// AutoAnswers is what list_auto_answers returns: a repository's rules, then
+// the built-in templates tried after them
+type AutoAnswers struct {
+	Disabled bool                   `json:"disabled"`
+	Rules    []state.AutoAnswerRule `json:"rules"`
+	Defaults []state.AutoAnswerRule `json:"defaults"`
+}
+
+// AddAutoAnswerArgs are the arguments of add_auto_answer
+type AddAutoAnswerArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+	// Pattern is a case-insensitive regular expression
+	Pattern string `json:"pattern" socket:"required,pattern is required"`
+	Reply   string `json:"reply" socket:"required,reply is required"`
+}
+
+// RemoveAutoAnswerArgs are the arguments of remove_auto_answer
+type RemoveAutoAnswerArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+	// Index is the rule's 1-based position in list_auto_answers
+	Index int `json:"index" socket:"required,rule index is required"`
+}
+
+// PulledBranch is what pull_agent_branch returns
+type PulledBranch struct {
+	Branch string `json:"branch"`
+	Remote string `json:"remote"`
+	// CommitsRebased are the local commits replayed onto the remote branch
+	CommitsRebased int  `json:"commits_rebased"`
+	Stashed        bool `json:"stashed"`
+}
+
+// BroadcastQuestionArgs are the arguments of broadcast_question
+type BroadcastQuestionArgs struct {
+	Repo     string `json:"repo" socket:"required,repository name is required"`
+	Question string `json:"question" socket:"required,question is required"`
+	// TimeoutSeconds is how long agents have to answer; five minutes if nil
+	TimeoutSeconds *float64 `json:"timeout_seconds,omitempty"`
+}
+
+// BroadcastReplyArgs are the arguments of broadcast_reply
+type BroadcastReplyArgs struct {
+	ID     string `json:"id" socket:"required,broadcast ID is required"`
+	Repo   string `json:"repo" socket:"required,repository name is required"`
+	Agent  string `json:"agent" socket:"required,agent name is required"`
+	Answer string `json:"answer" socket:"required,answer is required"`
+}
+
+// BroadcastStatusArgs are the arguments of broadcast_status
+type BroadcastStatusArgs struct {
+	ID string `json:"id" socket:"required,broadcast ID is required"`
+}
+
+// BroadcastReply is one asked agent's answer, empty until it replies
+type BroadcastReply struct {
+	Agent     string          `json:"agent"`
+	Type      state.AgentType `json:"type"`
+	Task      string          `json:"task,omitempty"`
+	Answer    string          `json:"answer,omitempty"`
+	RepliedAt time.Time       `json:"replied_at,omitempty"`
+}
+
+// Broadcast is a question asked of every active agent in a repository, as
+// broadcast_question, broadcast_reply and broadcast_status return it
+type Broadcast struct {
+	ID       string           `json:"id"`
+	Repo     string           `json:"repo"`
+	Question string           `json:"question"`
+	AskedAt  time.Time        `json:"asked_at"`
+	Deadline time.Time        `json:"deadline"`
+	Replies  []BroadcastReply `json:"replies"` // One per asked agent, by name
+	// Done is set once every agent replied or the deadline passed
+	Done    bool   `json:"done"`
+	Summary string `json:"summary"`
+}
+
+// WorkerCapacity is what check_worker_capacity returns: whether another
+// worker may start in a repository
+type WorkerCapacity struct {
+	Running int `json:"running"`
+	// MaxWorkers is 0 when the repository has no limit
+	MaxWorkers int  `json:"max_workers"`
+	Available  bool `json:"available"`
+	// Error says why no worker may start
+	Error string `json:"error,omitempty"`
+	// DiskBytes and DiskQuota are set when worktrees have a disk quota
+	DiskBytes int64 `json:"disk_bytes,omitempty"`
+	DiskQuota int64 `json:"disk_quota,omitempty"`
+}
+
+// ResolveConflictArgs are the arguments of resolve_conflict
+type ResolveConflictArgs struct {
+	Repo   string `json:"repo" socket:"required,repository name is required"`
+	Agent  string `json:"agent" socket:"required,agent name is required"`
+	Action string `json:"action" socket:"required,action is required (assign, helper, or skip)"`
+	// ResponseID is the one-time ID of a choice relayed from outside
+	ResponseID string `json:"response_id,omitempty"`
+}
+
+// ConflictResolution is what resolve_conflict returns. Window and Tmux,
+// the command attaching to it, are set for the helper action.
+type ConflictResolution struct {
+	Action string `json:"action"`
+	Window string `json:"window,omitempty"`
+	Tmux   string `json:"tmux,omitempty"`
+}
+
+// ExportMetricsArgs are the arguments of export_metrics
+type ExportMetricsArgs struct {
+	// Date is the day to export, as YYYY-MM-DD; today if empty
+	Date string `json:"date,omitempty"`
+}
+
+// ExportedMetrics is what export_metrics returns
+type ExportedMetrics struct {
+	Snapshots []metrics.Snapshot `json:"snapshots"`
+	// Dir is where the metrics files were written
+	Dir string `json:"dir"`
+}
+
+// StatusArgs are the arguments of status
+type StatusArgs struct {
+	// AllHosts adds the fleet_status of every host
+	AllHosts bool `json:"all_hosts,omitempty"`
+}
+
+// Status is what status returns: the daemon's health at a glance
+type Status struct {
+	Running         bool         `json:"running"`
+	PID             int          `json:"pid"`
+	Repos           int          `json:"repos"`
+	Agents          int          `json:"agents"`
+	SocketPath      string       `json:"socket_path"`
+	SocketGroup     string       `json:"socket_group"`
+	Lanes           LaneStats    `json:"lanes"`
+	MergeQueueDepth int          `json:"merge_queue_depth"`
+	GitHub          github.Stats `json:"github"`
+	// Multiplexer is the backend agents' windows run in
+	Multiplexer     string `json:"multiplexer"`
+	ProtocolVersion int    `json:"protocol_version"`
+	// RemoteListen is the remote socket's address, if it is enabled
+	RemoteListen string `json:"remote_listen"`
+	// LastRestore reports the agents restored when the daemon started
+	LastRestore *events.DaemonRestoredPayload `json:"last_restore,omitempty"`
+	Fleet       *FleetStatus                  `json:"fleet,omitempty"`
+}
+
+// LaneStats is a snapshot of the background lane's activity
+type LaneStats struct {
+	BackgroundWorkers   int   `json:"background_workers"`
+	BackgroundQueued    int64 `json:"background_queued"`
+	BackgroundRunning   int64 `json:"background_running"`
+	BackgroundCompleted int64 `json:"background_completed"`
+}
+
+// ListReposArgs are the arguments of list_repos
+type ListReposArgs struct {
+	// Group limits the list to one repo group
+	Group string `json:"group,omitempty"`
+	// Rich lists each repository's status rather than only its name
+	Rich bool `json:"rich,omitempty"`
+}
+
+// RepoList is what list_repos returns: repository names, or with rich their
+// status. On the wire it is a plain list of either, as it always was.
+type RepoList struct {
+	Rich  bool
+	Names []string
+	Repos []RepoInfo
+}
+
+// MarshalJSON encodes the list list_repos was asked for
+func (l RepoList) MarshalJSON() ([]byte, error) {
+	if l.Rich {
+		if l.Repos == nil {
+			return []byte("[]"), nil
+		}
+		return json.Marshal(l.Repos)
+	}
+	if l.Names == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l.Names)
+}
+
+// UnmarshalJSON decodes either list
+func (l *RepoList) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		*l = RepoList{Names: names}
+		return nil
+	}
+	var repos []RepoInfo
+	if err := json.Unmarshal(data, &repos); err != nil {
+		return err
+	}
+	*l = RepoList{Rich: true, Repos: repos}
+	for _, repo := range repos {
+		l.Names = append(l.Names, repo.Name)
+	}
+	return nil
+}
+
+// RepoInfo is one repository in a rich list_repos
+type RepoInfo struct {
+	Name           string   `json:"name"`
+	GithubURL      string   `json:"github_url"`
+	TmuxSession    string   `json:"tmux_session"`
+	TotalAgents    int      `json:"total_agents"`
+	WorkerCount    int      `json:"worker_count"`
+	SessionHealthy bool     `json:"session_healthy"`
+	Groups         []string `json:"groups"`
+	// RefreshPaused is set while a history rewrite pauses worktree refreshes
+	RefreshPaused bool `json:"refresh_paused"`
+	// CwdDrift maps agents to the directory they wandered off to
+	CwdDrift map[string]string `json:"cwd_drift,omitempty"`
+	// LockedBy is the other daemon managing the repository
+	LockedBy string `json:"locked_by,omitempty"`
+}
+
+// AddRepoArgs are the arguments of add_repo
+type AddRepoArgs struct {
+	Name        string `json:"name" socket:"required,repository name is required (e.g., 'my-project')"`
+	GithubURL   string `json:"github_url" socket:"required,GitHub repository URL is required (e.g., 'https://github.com/owner/repo')"`
+	TmuxSession string `json:"tmux_session" socket:"required,tmux session name is required"`
+	// CloneFilter is the partial clone filter the repository was cloned with
+	CloneFilter string `json:"clone_filter,omitempty"`
+	// Mirror is the local mirror the clone borrows objects from
+	Mirror string `json:"mirror,omitempty"`
+	// MQEnabled turns the merge queue on or off; on if nil
+	MQEnabled   *bool  `json:"mq_enabled,omitempty"`
+	MQTrackMode string `json:"mq_track_mode,omitempty"`
+	// StartAgents has the daemon create the session and start the
+	// repository's agents, for multiplexers the CLI can't create windows in
+	StartAgents bool `json:"start_agents,omitempty"`
+}
+
+// AddedRepo is what add_repo returns: the agents it started, if asked to
+type AddedRepo struct {
+	Agents []string `json:"agents,omitempty"`
+}
+
+// RemoveRepoArgs are the arguments of remove_repo
+type RemoveRepoArgs struct {
+	Name string `json:"name" socket:"required,repository name is required"`
+}
+
+// AddAgentArgs are the arguments of add_agent. TmuxWindow is only
+// required of agents that aren't headless.
+type AddAgentArgs struct {
+	Repo         string `json:"repo" socket:"required,repository name is required"`
+	Agent        string `json:"agent" socket:"required,agent name is required"`
+	Type         string `json:"type" socket:"required,agent type is required (supervisor, worker, merge-queue, or reviewer)"`
+	WorktreePath string `json:"worktree_path" socket:"required,path to the agent's git worktree is required"`
+	TmuxWindow   string `json:"tmux_window,omitempty"`
+	// SessionID is the agent's Claude session; one is made up if empty
+	SessionID string `json:"session_id,omitempty"`
+	PID       int    `json:"pid,omitempty"`
+	Headless  bool   `json:"headless,omitempty"`
+	// StartWindow has the daemon create the agent's window and start its
+	// Claude, for multiplexers the CLI can't create windows in
+	StartWindow bool `json:"start_window,omitempty"`
+	// PromptFile and InitialMessage start an agent the daemon runs
+	PromptFile     string `json:"prompt_file,omitempty"`
+	InitialMessage string `json:"initial_message,omitempty"`
+	Task           string `json:"task,omitempty"`
+	ReadOnly       bool   `json:"read_only,omitempty"`
+	PromptSHA256   string `json:"prompt_sha256,omitempty"`
+	Model          string `json:"model,omitempty"`
+	// Base is what the task builds on instead of the default branch
+	Base       string `json:"base,omitempty"`
+	BaseBranch string `json:"base_branch,omitempty"`
+	Priority   string `json:"priority,omitempty"`
+	// Labels filter list_agents
+	Labels []string `json:"labels,omitempty"`
+	// TimeBudgetSeconds time-boxes a worker
+	TimeBudgetSeconds float64 `json:"time_budget_seconds,omitempty"`
+	// AllowedPaths overrides the repository's branch guard paths
+	AllowedPaths []string `json:"allowed_paths,omitempty"`
+	// ScopePaths limit the agent to a monorepo's sub-projects
+	ScopePaths []string `json:"scope_paths,omitempty"`
+}
+
+// ListAgentsArgs are the arguments of list_agents. Repo is required unless
+// AllRepos is set.
+type ListAgentsArgs struct {
+	Repo     string `json:"repo,omitempty"`
+	AllRepos bool   `json:"all_repos,omitempty"`
+	// Rich adds each agent's status, branch, messages and disk usage
+	Rich bool `json:"rich,omitempty"`
+	// Status, Type and Label filter the agents
+	Status string `json:"status,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Label  string `json:"label,omitempty"`
+	// Sort names the field to sort by, with a "-" prefix for descending
+	// order: name, repo, type, status, created_at or priority
+	Sort string `json:"sort,omitempty"`
+	// Limit and Offset ask for one page of the agents
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+// AgentList is what list_agents returns. On the wire a paginated list is an
+// object with the page and its position, and any other a plain list of
+// agents, as it always was.
+type AgentList struct {
+	Agents     []AgentInfo `json:"agents"`
+	Total      int         `json:"total"`
+	Offset     int         `json:"offset"`
+	Limit      int         `json:"limit"`
+	NextOffset int         `json:"next_offset,omitempty"`
+	Paginated  bool        `json:"-"`
+}
+
+// agentPage is AgentList encoded as an object
+type agentPage AgentList
+
+// MarshalJSON encodes the list in the shape the request asked for
+func (l AgentList) MarshalJSON() ([]byte, error) {
+	if l.Paginated {
+		return json.Marshal(agentPage(l))
+	}
+	if l.Agents == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l.Agents)
+}
+
+// UnmarshalJSON decodes either shape
+func (l *AgentList) UnmarshalJSON(data []byte) error {
+	var agents []AgentInfo
+	if err := json.Unmarshal(data, &agents); err == nil {
+		*l = AgentList{Agents: agents}
+		return nil
+	}
+	var page agentPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return err
+	}
+	*l = AgentList(page)
+	l.Paginated = true
+	return nil
+}
+
+// AgentInfo is one agent in list_agents. Status is set in the rich format
+// and when filtering or sorting by it; Branch, the message counts and
+// DiskBytes only in the rich format.
+type AgentInfo struct {
+	Name         string             `json:"name"`
+	Repo         string             `json:"repo"`
+	Type         state.AgentType    `json:"type"`
+	WorktreePath string             `json:"worktree_path"`
+	TmuxWindow   string             `json:"tmux_window"`
+	Task         string             `json:"task"`
+	CreatedAt    time.Time          `json:"created_at"`
+	ReadOnly     bool               `json:"read_only"`
+	Labels       []string           `json:"labels"`
+	Priority     state.TaskPriority `json:"priority"`
+	Deadline     *time.Time         `json:"deadline,omitempty"`
+	CwdDrift     string             `json:"cwd_drift,omitempty"`
+	// Question is what the agent is waiting on an answer to
+	Question      string     `json:"question,omitempty"`
+	LastHeartbeat *time.Time `json:"last_heartbeat,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	*AgentDetail
+}
+
+// AgentDetail is the rest of an agent's rich format, which list_agents
+// reads only for the agents on the page it returns
+type AgentDetail struct {
+	Branch          string `json:"branch"`
+	MessagesTotal   int    `json:"messages_total"`
+	MessagesPending int    `json:"messages_pending"`
+	DiskBytes       int64  `json:"disk_bytes,omitempty"`
+}
+
+// AgentRef names an agent in a response
+type AgentRef struct {
+	Repo  string `json:"repo"`
+	Agent string `json:"agent"`
+}
+
+// RespondAgentArgs are the arguments of respond_agent. A reply naming only
+// a response ID goes to the agent the ID was issued for.
+type RespondAgentArgs struct {
+	Text       string `json:"text" socket:"required,reply text is required"`
+	Repo       string `json:"repo,omitempty"`
+	Agent      string `json:"agent,omitempty"`
+	ResponseID string `json:"response_id,omitempty"`
+}
+
+// ResponseID is what issue_response_id returns: a one-time ID authorizing a
+// reply to the agent until it expires
+type ResponseID struct {
+	ID        string    `json:"response_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CompleteAgentArgs are the arguments of complete_agent
+type CompleteAgentArgs struct {
+	Repo          string `json:"repo" socket:"required,repository name is required"`
+	Agent         string `json:"agent" socket:"required,agent name is required"`
+	Summary       string `json:"summary,omitempty"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	// Squash the branch into one commit, titled SquashTitle if given
+	Squash      bool   `json:"squash,omitempty"`
+	SquashTitle string `json:"squash_title,omitempty"`
+	Push        bool   `json:"push,omitempty"`
+	// PR pushes the branch and opens a pull request for it
+	PR bool `json:"pr,omitempty"`
+	// Cleanup removes the agent now rather than at the next health check
+	Cleanup bool `json:"cleanup,omitempty"`
+}
+
+// CompletedAgent is what complete_agent returns
+type CompletedAgent struct {
+	Pushed  bool `json:"pushed"`
+	Cleanup bool `json:"cleanup"`
+	// BackupRef holds the original commits of a squashed branch
+	BackupRef string `json:"backup_ref,omitempty"`
+	PRURL     string `json:"pr_url,omitempty"`
+	PRNumber  int    `json:"pr_number,omitempty"`
+}
+
+// BranchGuardCheck is what check_branch_guard returns
+type BranchGuardCheck struct {
+	// Enabled is false when the repository has no branch guard, which
+	// passes every branch
+	Enabled    bool   `json:"enabled"`
+	Passed     bool   `json:"passed"`
+	Violations int    `json:"violations,omitempty"`
+	Report     string `json:"report"`
+}
+
+// RestartAgentArgs are the arguments of restart_agent
+type RestartAgentArgs struct {
+	Repo  string `json:"repo" socket:"required,repository name is required"`
+	Agent string `json:"agent" socket:"required,agent name is required"`
+	// Force restarts an agent whose process is still running
+	Force bool `json:"force,omitempty"`
+}
+
+// RestartedAgent is what restart_agent returns
+type RestartedAgent struct {
+	Agent   string `json:"agent"`
+	Repo    string `json:"repo"`
+	PID     int    `json:"pid"`
+	Message string `json:"message"`
+}
+
+// TriggerCleanupArgs are the arguments of trigger_cleanup, which answers
+// with the cleanup.Items it removed or, with DryRun, would remove
+type TriggerCleanupArgs struct {
+	// Repo limits cleanup to one repository
+	Repo   string `json:"repo,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+}
+
+// RepairStateArgs are the arguments of repair_state
+type RepairStateArgs struct {
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// StateRepair is what repair_state returns: the issues found and, unless
+// it was a dry run, what fixing them did
+type StateRepair struct {
+	Issues        []repair.Issue `json:"issues"`
+	AgentsRemoved *int           `json:"agents_removed,omitempty"`
+	IssuesFixed   *int           `json:"issues_fixed,omitempty"`
+}
+
+// RepoNameArgs are the arguments of a repository command that names it
+// "name" rather than "repo"
+type RepoNameArgs struct {
+	Name string `json:"name" socket:"required,repository name is required"`
+}
+
+// RepoConfig is what get_repo_config returns. Its fields are named like
+// the update_repo_config arguments that change them.
+type RepoConfig struct {
+	MQEnabled   bool     `json:"mq_enabled"`
+	MQTrackMode string   `json:"mq_track_mode"`
+	Groups      []string `json:"groups"`
+	DefaultBase string   `json:"default_base"`
+
+	MQAutoMerge      bool     `json:"mq_auto_merge"`
+	MQLabel          string   `json:"mq_label"`
+	MQPollSeconds    int      `json:"mq_poll_seconds"`
+	MQRequiredChecks []string `json:"mq_required_checks"`
+	MQMergeMethod    string   `json:"mq_merge_method"`
+
+	GuardAllowedPaths  []string `json:"guard_allowed_paths"`
+	GuardMaxFileMB     int      `json:"guard_max_file_mb"`
+	GuardBlockBinaries bool     `json:"guard_block_binaries"`
+
+	CommitStyle   string `json:"commit_style"`
+	CommitPattern string `json:"commit_pattern"`
+
+	AutoAnswerEnabled bool `json:"auto_answer_enabled"`
+	AutoAnswerRules   int  `json:"auto_answer_rules"`
+
+	WarmPoolSize      int    `json:"warm_pool_size"`
+	WarmPoolBootstrap string `json:"warm_pool_bootstrap"`
+	WarmPoolReady     int    `json:"warm_pool_ready"`
+
+	ReaperMode         string   `json:"reaper_mode"`
+	ReaperGraceMinutes int      `json:"reaper_grace_minutes"`
+	ReaperKeep         []string `json:"reaper_keep"`
+
+	RecoveryAuto         bool     `json:"recovery_auto"`
+	RecoveryAfterMinutes int      `json:"recovery_after_minutes"`
+	StuckIdleMinutes     int      `json:"stuck_idle_minutes"`
+	StuckRules           []string `json:"stuck_rules"`
+	StuckQuiet           []string `json:"stuck_quiet"`
+	StuckNudge           bool     `json:"stuck_nudge"`
+	RestartMax           int      `json:"restart_max"`
+	QueueWorkers         int      `json:"queue_workers"`
+
+	TmuxAlerts     bool              `json:"tmux_alerts"`
+	ConflictAssist bool              `json:"conflict_assist"`
+	PushRemote     string            `json:"push_remote"`
+	MaxWorkers     int               `json:"max_workers"`
+	LFSSkip        []state.AgentType `json:"lfs_skip"`
+
+	AccessSpawn  []string `json:"access_spawn"`
+	AccessRemove []string `json:"access_remove"`
+	AccessMerge  []string `json:"access_merge"`
+	AccessAdmin  []string `json:"access_admin"`
+}
+
+// UpdateRepoConfigArgs are the arguments of update_repo_config. A nil
+// field leaves its setting alone, so the lists have no omitempty: an empty
+// one clears its setting.
+type UpdateRepoConfigArgs struct {
+	Name string `json:"name" socket:"required,repository name is required"`
+
+	MQEnabled        *bool    `json:"mq_enabled,omitempty"`
+	MQTrackMode      *string  `json:"mq_track_mode,omitempty"`
+	MQAutoMerge      *bool    `json:"mq_auto_merge,omitempty"`
+	MQLabel          *string  `json:"mq_label,omitempty"`
+	MQPollSeconds    *int     `json:"mq_poll_seconds,omitempty"`
+	MQRequiredChecks []string `json:"mq_required_checks"`
+	MQMergeMethod    *string  `json:"mq_merge_method,omitempty"`
+
+	GuardAllowedPaths  []string `json:"guard_allowed_paths"`
+	GuardMaxFileMB     *int     `json:"guard_max_file_mb,omitempty"`
+	GuardBlockBinaries *bool    `json:"guard_block_binaries,omitempty"`
+
+	// CommitStyle "none" turns the commit policy's style off
+	CommitStyle   *string `json:"commit_style,omitempty"`
+	CommitPattern *string `json:"commit_pattern,omitempty"`
+
+	AutoAnswerEnabled *bool `json:"auto_answer_enabled,omitempty"`
+
+	// Whole numbers; the handler explains a fractional one
+	RestartMax   *float64 `json:"restart_max,omitempty"`
+	MaxWorkers   *float64 `json:"max_workers,omitempty"`
+	QueueWorkers *float64 `json:"queue_workers,omitempty"`
+
+	TmuxAlerts     *bool    `json:"tmux_alerts,omitempty"`
+	PushRemote     *string  `json:"push_remote,omitempty"`
+	ConflictAssist *bool    `json:"conflict_assist,omitempty"`
+	LFSSkip        []string `json:"lfs_skip"`
+
+	WarmPoolSize      *int    `json:"warm_pool_size,omitempty"`
+	WarmPoolBootstrap *string `json:"warm_pool_bootstrap,omitempty"`
+
+	ReaperMode         *string  `json:"reaper_mode,omitempty"`
+	ReaperGraceMinutes *int     `json:"reaper_grace_minutes,omitempty"`
+	ReaperKeep         []string `json:"reaper_keep"`
+
+	RecoveryAuto         *bool `json:"recovery_auto,omitempty"`
+	RecoveryAfterMinutes *int  `json:"recovery_after_minutes,omitempty"`
+
+	StuckIdleMinutes *int     `json:"stuck_idle_minutes,omitempty"`
+	StuckRules       []string `json:"stuck_rules"`
+	StuckQuiet       []string `json:"stuck_quiet"`
+	StuckNudge       *bool    `json:"stuck_nudge,omitempty"`
+
+	// Users and @groups holding each permission
+	AccessSpawn  []string `json:"access_spawn"`
+	AccessRemove []string `json:"access_remove"`
+	AccessMerge  []string `json:"access_merge"`
+	AccessAdmin  []string `json:"access_admin"`
+
+	// DefaultBase "" goes back to the default branch
+	DefaultBase *string  `json:"default_base,omitempty"`
+	Groups      []string `json:"groups"`
+}
+
+// TaskHistoryArgs are the arguments of task_history, which answers with the
+// repository's state.TaskHistoryEntry list, newest first
+type TaskHistoryArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+	// Limit is how many entries to return (default: 10)
+	Limit *int `json:"limit,omitempty"`
+}
+
+// SpawnAgentArgs are the arguments of spawn_agent. The agent's prompt is
+// either Prompt or the registered agent definition named by Definition.
+type SpawnAgentArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+	Name string `json:"name" socket:"required,agent name is required"`
+	// Class is "persistent" or "ephemeral"
+	Class      string `json:"class" socket:"required,agent class is required (persistent or ephemeral)"`
+	Prompt     string `json:"prompt,omitempty"`
+	Definition string `json:"definition,omitempty"`
+	Task       string `json:"task,omitempty"`
+	// ReadOnly is for ephemeral agents only
+	ReadOnly bool `json:"read_only,omitempty"`
+	Headless bool `json:"headless,omitempty"`
+}
+
+// SpawnedAgent is what spawn_agent returns
+type SpawnedAgent struct {
+	Name         string          `json:"name"`
+	Class        string          `json:"class"`
+	Type         state.AgentType `json:"type"`
+	WorktreePath string          `json:"worktree_path"`
+}
+
+// ListEventsArgs are the arguments of list_events, which answers with the
+// matching events.Event list, oldest first. Times are RFC 3339.
+type ListEventsArgs struct {
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+	Repo  string `json:"repo,omitempty"`
+	Type  string `json:"type,omitempty"`
+	// Limit is how many of the newest matches to return (default: 100)
+	Limit *int `json:"limit,omitempty"`
+}
+
+// GetFeedArgs are the arguments of get_feed, which answers with the
+// repository's feed.Entry list, oldest first
+type GetFeedArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+	// Since is an RFC 3339 time
+	Since string `json:"since,omitempty"`
+	Limit *int   `json:"limit,omitempty"`
+}
+
+// TimelineArgs are the arguments of timeline, which answers with a
+// timeline.Timeline. Times are RFC 3339; the default is the last seven days.
+type TimelineArgs struct {
+	Repo  string `json:"repo" socket:"required,repository name is required"`
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+}
+
+// HandoffAgentArgs are the arguments of handoff_agent
+type HandoffAgentArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+	From string `json:"from" socket:"required,name of the worker handing off is required"`
+	To   string `json:"to" socket:"required,name for the new worker is required"`
+	Task string `json:"task" socket:"required,task for the new worker is required"`
+	// Summary of the progress so far, for the new worker
+	Summary string `json:"summary,omitempty"`
+}
+
+// Handoff is what handoff_agent returns
+type Handoff struct {
+	From         string `json:"from"`
+	To           string `json:"to"`
+	WorktreePath string `json:"worktree_path"`
+}
+
+// SetLogStorageArgs are the arguments of set_log_storage; see
+// logstore.Config
+type SetLogStorageArgs struct {
+	Backend       string `json:"backend,omitempty"`
+	Bucket        string `json:"bucket,omitempty"`
+	Prefix        string `json:"prefix,omitempty"`
+	Region        string `json:"region,omitempty"`
+	Endpoint      string `json:"endpoint,omitempty"`
+	RetentionDays int    `json:"retention_days,omitempty"`
+}
+
+// LogStorage is what set_log_storage returns: the new storage, described
+type LogStorage struct {
+	Storage string `json:"storage"`
+}
+
+// MergeQueueEventArgs are the arguments of merge_queue_event, which answers
+// with the state.MergeQueueItem it updated. The PR is identified by Branch,
+// PR or both.
+type MergeQueueEventArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+	// Event is enqueued, ci_started, ci_finished, merged, failed, or closed
+	Event  string `json:"event" socket:"required,event is required (enqueued, ci_started, ci_finished, merged, failed, or closed)"`
+	Branch string `json:"branch,omitempty"`
+	PR     int    `json:"pr,omitempty"`
+}
+
+// MergeQueueStatsArgs are the arguments of merge_queue_stats
+type MergeQueueStatsArgs struct {
+	// Repo limits the stats to one repository
+	Repo string `json:"repo,omitempty"`
+	// Format "prometheus" asks for the text exposition format
+	Format string `json:"format,omitempty"`
+}
+
+// MergeQueueStats is what merge_queue_stats returns: each repository's
+// stats or, in the Prometheus format, their text exposition. On the wire it
+// is the list or the text, as it always was.
+type MergeQueueStats struct {
+	Repos      []metrics.MergeQueueStats
+	Prometheus string
+}
+
+// MarshalJSON encodes the stats in the format merge_queue_stats was asked for
+func (s MergeQueueStats) MarshalJSON() ([]byte, error) {
+	if s.Prometheus != "" {
+		return json.Marshal(s.Prometheus)
+	}
+	if s.Repos == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s.Repos)
+}
+
+// UnmarshalJSON decodes either format
+func (s *MergeQueueStats) UnmarshalJSON(data []byte) error {
+	*s = MergeQueueStats{}
+	if err := json.Unmarshal(data, &s.Prometheus); err == nil {
+		return nil
+	}
+	return json.Unmarshal(data, &s.Repos)
+}
+
+// SimulatedMerge is one queue entry in a merge queue simulation
+type SimulatedMerge struct {
+	Position           int                `json:"position"`
+	Branch             string             `json:"branch,omitempty"`
+	PRNumber           int                `json:"pr_number,omitempty"`
+	PRURL              string             `json:"pr_url,omitempty"`
+	Worker             string             `json:"worker,omitempty"`
+	Priority           state.TaskPriority `json:"priority"`
+	TimeInQueueSeconds float64            `json:"time_in_queue_seconds"`
+	Behind             int                `json:"behind"`
+	Conflicts          bool               `json:"conflicts"`
+	ConflictFiles      []string           `json:"conflict_files,omitempty"`
+	NeedsRebase        bool               `json:"needs_rebase"`
+	// RebaseAfter names the entry whose merge will leave this one behind
+	// when the base requires up-to-date branches
+	RebaseAfter    string   `json:"rebase_after,omitempty"`
+	Checks         string   `json:"checks"` // passed, failed, pending, unknown, or empty without an open PR
+	FailingChecks  []string `json:"failing_checks,omitempty"`
+	PendingChecks  []string `json:"pending_checks,omitempty"`
+	MergeableState string   `json:"mergeable_state,omitempty"`
+	Verdict        string   `json:"verdict"`
+	Notes          []string `json:"notes,omitempty"`
+}
+
+// MergeQueueSimulation is what merge_queue_simulate returns: what the merge
+// queue would do with a repository's pending branches, in merge order
+type MergeQueueSimulation struct {
+	Repo           string           `json:"repo"`
+	Base           string           `json:"base"`
+	Enabled        bool             `json:"enabled"`
+	RequiredChecks []string         `json:"required_checks"`
+	Strict         bool             `json:"strict"`
+	Entries        []SimulatedMerge `json:"entries"`
+	Warnings       []string         `json:"warnings,omitempty"`
+}
+
+// AskQuestionArgs are the arguments of ask_question, which answers with the
+// ResponseID a reply to the question must carry
+type AskQuestionArgs struct {
+	Repo     string `json:"repo" socket:"required,repository name is required"`
+	Agent    string `json:"agent" socket:"required,agent name is required"`
+	Question string `json:"question" socket:"required,question is required"`
+}
+
+// RecoverAgentArgs are the arguments of recover_agent
+type RecoverAgentArgs struct {
+	Repo  string `json:"repo" socket:"required,repository name is required"`
+	Agent string `json:"agent" socket:"required,agent name is required"`
+	// OlderThanSeconds overrides the repository's recovery threshold:
+	// interrupted operations more recent than it are left alone
+	OlderThanSeconds *float64 `json:"older_than_seconds,omitempty"`
+	// Force recovers however recent the operation is
+	Force bool `json:"force,omitempty"`
+}
+
+// RecoveredWorktree is what recover_agent returns. Aborted names the git
+// operation it aborted; Pending one it left alone as too recent.
+type RecoveredWorktree struct {
+	RestoredStashes  int     `json:"restored_stashes"`
+	KeptStash        string  `json:"kept_stash"`
+	KeptReason       string  `json:"kept_reason"`
+	Aborted          string  `json:"aborted,omitempty"`
+	Pending          string  `json:"pending,omitempty"`
+	LastActivity     string  `json:"last_activity,omitempty"`
+	OlderThanSeconds float64 `json:"older_than_seconds,omitempty"`
+}
+
+// ReloadedConfig is what reload_config returns: the settings that changed
+type ReloadedConfig struct {
+	Changed []string `json:"changed"`
+}
+
+// RepoLockArgs are the arguments of repo_lock
+type RepoLockArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+	// TakeOver claims the lock from another daemon
+	TakeOver bool `json:"take_over,omitempty"`
+}
+
+// RepoLock is what repo_lock returns: who holds the repository's lock, and
+// the worktrees of daemons that held it before
+type RepoLock struct {
+	// ReadOnly is true when another daemon holds the lock
+	ReadOnly  bool   `json:"read_only"`
+	Owner     string `json:"owner,omitempty"`
+	Heartbeat string `json:"heartbeat,omitempty"`
+	// ForeignWorktrees are the paths of worktrees this daemon didn't create
+	ForeignWorktrees []string `json:"foreign_worktrees,omitempty"`
+}
+
+// ResumedRefresh is what resume_refresh returns: the rewrite of main it
+// accepted, if auto-refresh was paused for one
+type ResumedRefresh struct {
+	Paused  bool   `json:"paused"`
+	Branch  string `json:"branch,omitempty"`
+	OldHead string `json:"old_head,omitempty"`
+	NewHead string `json:"new_head,omitempty"`
+}
+
+// CheckReviewChecklistArgs are the arguments of check_review_checklist
+type CheckReviewChecklistArgs struct {
+	Repo  string `json:"repo" socket:"required,repository name is required"`
+	Agent string `json:"agent" socket:"required,agent name is required"`
+	// Body is the review comment to check
+	Body string `json:"body" socket:"required,review comment body is required"`
+}
+
+// ReviewChecklistCheck is what check_review_checklist returns
+type ReviewChecklistCheck struct {
+	Passed    bool     `json:"passed"`
+	Missing   []string `json:"missing,omitempty"`
+	Unchecked []string `json:"unchecked,omitempty"`
+	Report    string   `json:"report"`
+}
+
+// ScratchWorktreeArgs are the arguments of remove_scratch_worktree
+type ScratchWorktreeArgs struct {
+	Repo  string `json:"repo" socket:"required,repository name is required"`
+	Agent string `json:"agent" socket:"required,agent name is required"`
+	Name  string `json:"name" socket:"required,scratch worktree name is required"`
+}
+
+// AddScratchWorktreeArgs are the arguments of add_scratch_worktree
+type AddScratchWorktreeArgs struct {
+	Repo  string `json:"repo" socket:"required,repository name is required"`
+	Agent string `json:"agent" socket:"required,agent name is required"`
+	Name  string `json:"name" socket:"required,scratch worktree name is required"`
+	// Rev of the agent's worktree to check out (default: HEAD)
+	Rev string `json:"rev,omitempty"`
+	// Branch creates a branch named after the agent's instead of detaching
+	Branch bool `json:"branch,omitempty"`
+}
+
+// ScratchWorktree is what add_scratch_worktree and remove_scratch_worktree
+// return; list_scratch_worktrees answers with the agent's
+// state.ScratchWorktree list
+type ScratchWorktree struct {
+	Name   string `json:"name"`
+	Path   string `json:"path,omitempty"`
+	Branch string `json:"branch"`
+	Commit string `json:"commit,omitempty"`
+}
+
+// AddTaskArgs are the arguments of add_task
+type AddTaskArgs struct {
+	Repo        string `json:"repo" socket:"required,repository name is required"`
+	Description string `json:"description" socket:"required,task description is required"`
+	// Priority is a state.TaskPriority name
+	Priority string `json:"priority,omitempty"`
+}
+
+// AddedTask is what add_task returns
+type AddedTask struct {
+	ID       string             `json:"id"`
+	Priority state.TaskPriority `json:"priority"`
+}
+
+// CancelTaskArgs are the arguments of cancel_task
+type CancelTaskArgs struct {
+	Repo string `json:"repo" socket:"required,repository name is required"`
+	ID   string `json:"id" socket:"required,task ID is required"`
+}
+
+// TaskList is what list_tasks returns: a repository's running tasks, then
+// its queued tasks in dispatch order, then its finished tasks, most recent
+// first
+type TaskList struct {
+	Tasks []TaskInfo `json:"tasks"`
+	// Workers is how many queued tasks may run at once
+	Workers int `json:"workers"`
+}
+
+// TaskInfo is one task in list_tasks
+type TaskInfo struct {
+	ID          string                `json:"id"`
+	Description string                `json:"description"`
+	Priority    state.TaskPriority    `json:"priority"`
+	State       state.QueuedTaskState `json:"state"`
+	CreatedAt   time.Time             `json:"created_at"`
+	Agent       string                `json:"agent,omitempty"`
+	Error       string                `json:"error,omitempty"`
+	FinishedAt  *time.Time            `json:"finished_at,omitempty"`
+}
+
+// ClaimWarmWorktreeArgs are the arguments of claim_warm_worktree
+type ClaimWarmWorktreeArgs struct {
+	Repo   string `json:"repo" socket:"required,repository name is required"`
+	Agent  string `json:"agent" socket:"required,agent name is required"`
+	Branch string `json:"branch" socket:"required,branch name for the agent is required"`
+	// StartPoint the branch starts from (default: HEAD)
+	StartPoint string `json:"start_point,omitempty"`
+}
+
+// WarmWorktreeClaim is what claim_warm_worktree returns. Claimed is false
+// when the pool had no worktree to give.
+type WarmWorktreeClaim struct {
+	Claimed      bool   `json:"claimed"`
+	WorktreePath string `json:"worktree_path,omitempty"`
+}
+
+// WorkerStatus is one worker in worker_status: its window and the git state
+// of its worktree, unless Error says why that couldn't be read
+type WorkerStatus struct {
+	Name string `json:"name"`
+	// Window is alive, dead, missing, or headless
+	Window       string `json:"window"`
+	LastActivity string `json:"last_activity,omitempty"`
+	Completed    bool   `json:"completed,omitempty"`
+	// Base is the remote branch Ahead and Behind count from
+	Base            string               `json:"base"`
+	Error           string               `json:"error,omitempty"`
+	Branch          string               `json:"branch"`
+	Ahead           int                  `json:"ahead"`
+	Behind          int                  `json:"behind"`
+	Uncommitted     bool                 `json:"uncommitted"`
+	StaleSubmodules []worktree.Submodule `json:"stale_submodules,omitempty"`
+	// GitState is mid-rebase, mid-merge, or detached HEAD, if the worktree
+	// is in one
+	GitState string `json:"git_state,omitempty"`
+}
@@ -0,0 +1,85 @@
+package socket
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// Peer identifies the process on the other end of a connection
+type Peer struct {
+	UID  int
+	GID  int
+	PID  int
+	User string // Login name for UID, or the UID itself if it has none
+
+	// Remote is the address of a caller on the TCP socket, who proved they
+	// hold the daemon's token but has no local credentials
+	Remote string
+}
+
+// String returns the peer's user name and UID, or a remote caller's address
+func (p *Peer) String() string {
+	if p == nil {
+		return "unknown caller"
+	}
+	if p.Remote != "" {
+		return "remote caller at " + p.Remote
+	}
+	return fmt.Sprintf("%s (uid %d)", p.User, p.UID)
+}
+
+// userName returns the login name for uid, falling back to the number
+func userName(uid int) string {
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(uid)
+}
+
+// PeerOf returns the credentials of the process connected on conn. It
+// returns nil where the platform cannot report them.
+func PeerOf(conn net.Conn) *Peer {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	peer, err := peerCredentials(unixConn)
+	if err != nil {
+		return nil
+	}
+	peer.User = userName(peer.UID)
+	return peer
+}
+
+// Share lets members of group connect to the socket (mode 0660). An empty
+// group makes the socket private to its owner again (mode 0600).
+func (s *Server) Share(group string) error {
+	if group == "" {
+		if err := os.Chown(s.socketPath, -1, os.Getgid()); err != nil {
+			return fmt.Errorf("failed to reset socket group: %w", err)
+		}
+		if err := os.Chmod(s.socketPath, 0600); err != nil {
+			return fmt.Errorf("failed to set socket permissions: %w", err)
+		}
+		return nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return fmt.Errorf("unknown group %q: %w", group, err)
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return fmt.Errorf("group %q has a non-numeric id %q", group, g.Gid)
+	}
+	if err := os.Chown(s.socketPath, -1, gid); err != nil {
+		return fmt.Errorf("failed to give group %s the socket: %w", group, err)
+	}
+	if err := os.Chmod(s.socketPath, 0660); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package socket
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCredentials is only implemented on Linux (SO_PEERCRED)
+func peerCredentials(conn *net.UnixConn) (*Peer, error) {
+	return nil, errors.New("peer credentials are not supported on this platform")
+}
@@ -0,0 +1,100 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProtocolVersion is the newest socket protocol version the daemon speaks.
+//
+// Version 0 is the original protocol: requests carry no version and failed
+// responses only an error message. From version 1, requests state their
+// version, the daemon checks their arguments' types, and failed responses
+// carry an ErrorCode. A request without a version is answered in version 0,
+// so older clients keep working unchanged.
+const ProtocolVersion = 1
+
+// ErrorCode classifies why a request failed, so clients can react to a
+// failure without parsing its message
+type ErrorCode string
+
+const (
+	// CodeBadRequest means the request couldn't be decoded
+	CodeBadRequest ErrorCode = "bad_request"
+	// CodeUnsupportedVersion means the request is newer than the daemon
+	CodeUnsupportedVersion ErrorCode = "unsupported_version"
+	// CodeUnknownCommand means the daemon has no such command
+	CodeUnknownCommand ErrorCode = "unknown_command"
+	// CodeMissingArgument means a required argument is absent or empty
+	CodeMissingArgument ErrorCode = "missing_argument"
+	// CodeInvalidArgument means an argument has the wrong type or value
+	CodeInvalidArgument ErrorCode = "invalid_argument"
+	// CodeNotFound means a repository, agent or other object doesn't exist
+	CodeNotFound ErrorCode = "not_found"
+	// CodePermissionDenied means the caller may not run the command
+	CodePermissionDenied ErrorCode = "permission_denied"
+	// CodeConflict means the command conflicts with the daemon's state, such
+	// as a repository another daemon manages
+	CodeConflict ErrorCode = "conflict"
+	// CodeFailed is any other failure
+	CodeFailed ErrorCode = "failed"
+)
+
+// Error is a failure with an ErrorCode
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Errorf returns an Error with the given code and formatted message
+func Errorf(code ErrorCode, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Response returns the failed response reporting e
+func (e *Error) Response() Response {
+	return Response{Success: false, Error: e.Message, Code: e.Code}
+}
+
+// ErrorResponse returns the failed response reporting err, with its code if
+// it wraps an Error and CodeFailed otherwise
+func ErrorResponse(err error) Response {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return Response{Success: false, Error: err.Error(), Code: coded.Code}
+	}
+	return Response{Success: false, Error: err.Error(), Code: CodeFailed}
+}
+
+// Err returns the failure a response reports as an error, or nil if it
+// succeeded. The error is an *Error when the response carries a code.
+func (r *Response) Err() error {
+	if r.Success {
+		return nil
+	}
+	if r.Code == "" {
+		return errors.New(r.Error)
+	}
+	return &Error{Code: r.Code, Message: r.Error}
+}
+
+// Finish prepares the response to req for the version req speaks: failures
+// without a code get CodeFailed, and version 0 requests get the response
+// without a code or version, as the original protocol had it
+func Finish(req Request, resp Response) Response {
+	if req.Version == 0 {
+		resp.Code = ""
+		resp.Version = 0
+		return resp
+	}
+	if !resp.Success && resp.Code == "" {
+		resp.Code = CodeFailed
+	}
+	resp.Version = ProtocolVersion
+	return resp
+}
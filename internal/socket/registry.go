@@ -0,0 +1,289 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ArgType is the JSON type an argument must have
+type ArgType string
+
+const (
+	ArgString ArgType = "string"
+	ArgNumber ArgType = "number"
+	ArgBool   ArgType = "bool"
+	ArgList   ArgType = "list"
+	ArgObject ArgType = "object"
+	ArgAny    ArgType = "any"
+)
+
+// Arg describes one argument of a command
+type Arg struct {
+	Name     string
+	Type     ArgType
+	Required bool
+	// Description is the error message when a required argument is missing
+	Description string
+}
+
+// Command is a command the daemon serves: its name, the arguments it
+// takes, and its handler
+type Command struct {
+	Name    string
+	Args    []Arg
+	Handler Handler
+}
+
+// Registry holds the commands a server knows, and checks requests against
+// them before they reach a handler
+type Registry struct {
+	commands map[string]Command
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{commands: make(map[string]Command)}
+}
+
+// Register adds a command. Registering a name twice is a programming error
+// and panics.
+func (r *Registry) Register(cmd Command) {
+	if _, exists := r.commands[cmd.Name]; exists {
+		panic(fmt.Sprintf("socket: command %q registered twice", cmd.Name))
+	}
+	r.commands[cmd.Name] = cmd
+}
+
+// Lookup returns the command with the given name
+func (r *Registry) Lookup(name string) (Command, bool) {
+	cmd, ok := r.commands[name]
+	return cmd, ok
+}
+
+// Names returns the names of all registered commands, sorted
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.commands))
+	for name := range r.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check validates req against its command: the protocol version, that the
+// command exists, that required arguments are present and, for version 1
+// requests and later, that arguments have their declared types. Version 0
+// requests aren't type-checked, as their handlers always tolerated
+// mistyped optional arguments.
+func (r *Registry) Check(req Request) *Error {
+	if req.Version < 0 || req.Version > ProtocolVersion {
+		return Errorf(CodeUnsupportedVersion, "unsupported protocol version %d; this daemon speaks versions 0 to %d", req.Version, ProtocolVersion)
+	}
+	cmd, ok := r.commands[req.Command]
+	if !ok {
+		return Errorf(CodeUnknownCommand, "unknown command: %q. Run 'multiclaude --help' for available commands", req.Command)
+	}
+
+	for _, arg := range cmd.Args {
+		value, present := req.Args[arg.Name]
+		if present && value != nil && req.Version >= 1 && !arg.Type.matches(value) {
+			return Errorf(CodeInvalidArgument, "argument '%s' must be a %s, got %s", arg.Name, arg.Type, jsonTypeOf(value))
+		}
+		if arg.Required && isEmpty(value) {
+			return Errorf(CodeMissingArgument, "missing '%s': %s", arg.Name, arg.Description)
+		}
+	}
+	return nil
+}
+
+// matches reports whether value, as decoded from JSON, has type t
+func (t ArgType) matches(value interface{}) bool {
+	switch t {
+	case ArgString:
+		_, ok := value.(string)
+		return ok
+	case ArgNumber:
+		switch value.(type) {
+		case float64, int:
+			return true
+		}
+		return false
+	case ArgBool:
+		_, ok := value.(bool)
+		return ok
+	case ArgList:
+		switch value.(type) {
+		case []interface{}, []string:
+			return true
+		}
+		return false
+	case ArgObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+// isEmpty reports whether a required argument counts as missing: absent,
+// null, or an empty string
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && s == ""
+}
+
+// jsonTypeOf names the JSON type of a decoded value for error messages
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64, int:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}, []string:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// Typed adapts a handler working on typed request and response structs. The
+// request's arguments are decoded into a Req by their json tags, after its
+// required fields are checked, so fn can rely on them; a returned error
+// becomes a failed response, with its code if it is an *Error, and a
+// struct{} response a successful one without data. A required argument of
+// the wrong type counts as missing, and version 0 requests leave mistyped
+// optional arguments at their zero value, as version 0 clients expect.
+func Typed[Req, Resp any](fn func(req Request, args Req) (Resp, error)) Handler {
+	var zero Req
+	declared := ArgsOf(zero)
+	return HandlerFunc(func(req Request) Response {
+		for _, arg := range declared {
+			if value := req.Args[arg.Name]; arg.Required && (isEmpty(value) || !arg.Type.matches(value)) {
+				return Errorf(CodeMissingArgument, "missing '%s': %s", arg.Name, arg.Description).Response()
+			}
+		}
+		var args Req
+		if len(req.Args) > 0 {
+			data, err := json.Marshal(req.Args)
+			if err == nil {
+				err = json.Unmarshal(data, &args)
+			}
+			var mistyped *json.UnmarshalTypeError
+			if err != nil && !(req.Version == 0 && errors.As(err, &mistyped)) {
+				return Errorf(CodeInvalidArgument, "invalid arguments for %s: %v", req.Command, err).Response()
+			}
+		}
+		resp, err := fn(req, args)
+		if err != nil {
+			return ErrorResponse(err)
+		}
+		// Commands that only report success answer without data
+		if _, none := any(resp).(struct{}); none {
+			return Response{Success: true}
+		}
+		return Response{Success: true, Data: resp}
+	})
+}
+
+// TypedCommand returns a command served by a typed handler, with its
+// arguments taken from the Req struct's fields (see ArgsOf)
+func TypedCommand[Req, Resp any](name string, fn func(req Request, args Req) (Resp, error)) Command {
+	var zero Req
+	return Command{Name: name, Args: ArgsOf(zero), Handler: Typed(fn)}
+}
+
+// ArgsOf derives the arguments of a request struct from its fields: the
+// name from the json tag, the type from the field's Go type, and whether it
+// is required from a `socket:"required,<description>"` tag
+func ArgsOf(v interface{}) []Arg {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var args []Arg
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		arg := Arg{Name: name, Type: argTypeOf(field.Type)}
+		if tag, ok := field.Tag.Lookup("socket"); ok {
+			flag, description, _ := strings.Cut(tag, ",")
+			arg.Required = flag == "required"
+			arg.Description = description
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// argTypeOf maps a Go field type to the JSON type its argument must have
+func argTypeOf(t reflect.Type) ArgType {
+	switch t.Kind() {
+	case reflect.String:
+		return ArgString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return ArgNumber
+	case reflect.Bool:
+		return ArgBool
+	case reflect.Slice, reflect.Array:
+		return ArgList
+	case reflect.Map, reflect.Struct:
+		return ArgObject
+	case reflect.Pointer:
+		// A pointer tells an argument that wasn't given from a zero one
+		return argTypeOf(t.Elem())
+	}
+	return ArgAny
+}
+
+// Sender sends requests to a daemon. *Client is one; a client for another
+// host's daemon, relayed over SSH, is another.
+type Sender interface {
+	Send(req Request) (*Response, error)
+}
+
+// Call sends a command with typed arguments and decodes a successful
+// response's data into a Resp. A failure is returned as an error, an *Error
+// when the daemon gave a code.
+func Call[Resp any](c Sender, command string, args interface{}) (Resp, error) {
+	var out Resp
+	var argMap map[string]interface{}
+	if args != nil {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return out, fmt.Errorf("failed to encode arguments: %w", err)
+		}
+		if err := json.Unmarshal(data, &argMap); err != nil {
+			return out, fmt.Errorf("failed to encode arguments: %w", err)
+		}
+	}
+
+	resp, err := c.Send(Request{Command: command, Args: argMap})
+	if err != nil {
+		return out, err
+	}
+	if err := resp.Err(); err != nil {
+		return out, err
+	}
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		return out, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,174 @@
+package socket
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type greetArgs struct {
+	Name  string `json:"name" socket:"required,name is required"`
+	Times int    `json:"times,omitempty"`
+}
+
+type greeting struct {
+	Text string `json:"text"`
+}
+
+func greet(req Request, args greetArgs) (greeting, error) {
+	if args.Times < 0 {
+		return greeting{}, Errorf(CodeInvalidArgument, "times must not be negative")
+	}
+	if args.Name == "nobody" {
+		return greeting{}, errors.New("nobody to greet")
+	}
+	text := ""
+	for i := 0; i < max(args.Times, 1); i++ {
+		text += "hello " + args.Name + " "
+	}
+	return greeting{Text: text}, nil
+}
+
+func testRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(TypedCommand("greet", greet))
+	r.Register(Command{
+		Name: "tag",
+		Args: []Arg{
+			{Name: "repo", Type: ArgString, Required: true, Description: "repository name is required"},
+			{Name: "labels", Type: ArgList},
+		},
+		Handler: HandlerFunc(func(Request) Response { return Response{Success: true} }),
+	})
+	return r
+}
+
+func TestRegistryCheck(t *testing.T) {
+	r := testRegistry()
+	tests := []struct {
+		name string
+		req  Request
+		want ErrorCode
+	}{
+		{"valid v0", Request{Command: "tag", Args: map[string]interface{}{"repo": "r"}}, ""},
+		{"valid v1", Request{Command: "tag", Version: 1, Args: map[string]interface{}{"repo": "r", "labels": []interface{}{"a"}}}, ""},
+		{"unknown command", Request{Command: "nope"}, CodeUnknownCommand},
+		{"newer version", Request{Command: "tag", Version: ProtocolVersion + 1}, CodeUnsupportedVersion},
+		{"missing arg", Request{Command: "tag", Version: 1}, CodeMissingArgument},
+		{"empty arg", Request{Command: "tag", Args: map[string]interface{}{"repo": ""}}, CodeMissingArgument},
+		{"mistyped v1", Request{Command: "tag", Version: 1, Args: map[string]interface{}{"repo": "r", "labels": "a"}}, CodeInvalidArgument},
+		{"mistyped v0 is tolerated", Request{Command: "tag", Args: map[string]interface{}{"repo": "r", "labels": "a"}}, ""},
+		{"typed required arg", Request{Command: "greet", Version: 1}, CodeMissingArgument},
+		{"typed mistyped arg", Request{Command: "greet", Version: 1, Args: map[string]interface{}{"name": "ann", "times": "2"}}, CodeInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Check(tt.req)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Check() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Code != tt.want {
+				t.Fatalf("Check() = %v, want code %s", err, tt.want)
+			}
+		})
+	}
+
+	if err := r.Check(Request{Command: "tag"}); err.Message != "missing 'repo': repository name is required" {
+		t.Errorf("missing arg message = %q, want the v0 wording", err.Message)
+	}
+}
+
+func TestRegistryRegisterTwicePanics(t *testing.T) {
+	r := testRegistry()
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a command twice should panic")
+		}
+	}()
+	r.Register(Command{Name: "tag"})
+}
+
+func TestTypedHandler(t *testing.T) {
+	h := Typed(greet)
+
+	resp := h.Handle(Request{Command: "greet", Args: map[string]interface{}{"name": "ann", "times": float64(2)}})
+	if !resp.Success || resp.Data.(greeting).Text != "hello ann hello ann " {
+		t.Errorf("greet = %+v, want two greetings", resp)
+	}
+	if resp := h.Handle(Request{Command: "greet"}); resp.Code != CodeMissingArgument {
+		t.Errorf("greet without a name = %+v, want %s", resp, CodeMissingArgument)
+	}
+	if resp := h.Handle(Request{Command: "greet", Args: map[string]interface{}{"name": "ann", "times": float64(-1)}}); resp.Code != CodeInvalidArgument {
+		t.Errorf("greet with negative times = %+v, want %s", resp, CodeInvalidArgument)
+	}
+	if resp := h.Handle(Request{Command: "greet", Args: map[string]interface{}{"name": "nobody"}}); resp.Success || resp.Code != CodeFailed {
+		t.Errorf("greet failing with a plain error = %+v, want %s", resp, CodeFailed)
+	}
+	if resp := h.Handle(Request{Command: "greet", Args: map[string]interface{}{"name": float64(7)}}); resp.Code != CodeMissingArgument {
+		t.Errorf("greet with a numeric name = %+v, want %s", resp, CodeMissingArgument)
+	}
+
+	// Version 0 handlers ignored mistyped optional arguments
+	resp = h.Handle(Request{Command: "greet", Args: map[string]interface{}{"name": "ann", "times": "2"}})
+	if !resp.Success || resp.Data.(greeting).Text != "hello ann " {
+		t.Errorf("v0 greet with mistyped times = %+v, want one greeting", resp)
+	}
+	if resp := h.Handle(Request{Command: "greet", Version: 1, Args: map[string]interface{}{"name": "ann", "times": "2"}}); resp.Code != CodeInvalidArgument {
+		t.Errorf("v1 greet with mistyped times = %+v, want %s", resp, CodeInvalidArgument)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	failed := Response{Success: false, Error: "boom"}
+
+	v0 := Finish(Request{Command: "x"}, Errorf(CodeNotFound, "gone").Response())
+	if v0.Code != "" || v0.Version != 0 || v0.Error != "gone" {
+		t.Errorf("v0 response = %+v, want the message without code or version", v0)
+	}
+	v1 := Finish(Request{Command: "x", Version: 1}, failed)
+	if v1.Code != CodeFailed || v1.Version != ProtocolVersion {
+		t.Errorf("v1 response = %+v, want code %s and version %d", v1, CodeFailed, ProtocolVersion)
+	}
+	if ok := Finish(Request{Command: "x", Version: 1}, Response{Success: true}); ok.Code != "" {
+		t.Errorf("successful v1 response has code %q", ok.Code)
+	}
+}
+
+func TestCall(t *testing.T) {
+	r := testRegistry()
+	sockPath := filepath.Join(t.TempDir(), "test.sock")
+	server := NewServer(sockPath, HandlerFunc(func(req Request) Response {
+		if err := r.Check(req); err != nil {
+			return Finish(req, err.Response())
+		}
+		cmd, _ := r.Lookup(req.Command)
+		return Finish(req, cmd.Handler.Handle(req))
+	}))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer server.Stop()
+	go server.Serve()
+	time.Sleep(50 * time.Millisecond)
+
+	client := NewClient(sockPath)
+	got, err := Call[greeting](client, "greet", greetArgs{Name: "bo"})
+	if err != nil || got.Text != "hello bo " {
+		t.Fatalf("Call(greet) = %+v, %v; want a greeting", got, err)
+	}
+
+	_, err = Call[greeting](client, "greet", greetArgs{Name: "bo", Times: -1})
+	var coded *Error
+	if !errors.As(err, &coded) || coded.Code != CodeInvalidArgument {
+		t.Errorf("Call(greet) with negative times = %v, want a %s error", err, CodeInvalidArgument)
+	}
+
+	_, err = Call[greeting](client, "wave", nil)
+	if !errors.As(err, &coded) || coded.Code != CodeUnknownCommand {
+		t.Errorf("Call(wave) = %v, want a %s error", err, CodeUnknownCommand)
+	}
+}
@@ -0,0 +1,132 @@
+package socket
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startRemote starts a remote server on a free loopback port, answering
+// every request with the caller's address
+func startRemote(t *testing.T, certFile, keyFile string) *RemoteServer {
+	t.Helper()
+	handler := HandlerFunc(func(req Request) Response {
+		if req.Token != "" {
+			return Response{Success: false, Error: "token leaked to the handler"}
+		}
+		return Finish(req, Response{Success: true, Data: req.Peer.String()})
+	})
+	server := NewRemoteServer("127.0.0.1:0", "s3cret", nil, handler)
+	if certFile != "" {
+		config, err := ServerTLSConfig(certFile, keyFile)
+		if err != nil {
+			t.Fatalf("ServerTLSConfig() failed: %v", err)
+		}
+		server = NewRemoteServer("127.0.0.1:0", "s3cret", config, handler)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+	go server.Serve()
+	return server
+}
+
+func TestRemoteServerToken(t *testing.T) {
+	server := startRemote(t, "", "")
+	addr := server.Addr().String()
+
+	resp, err := NewRemoteClient(addr, "s3cret", nil).Send(Request{Command: "whoami"})
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if caller, _ := resp.Data.(string); !resp.Success || !strings.HasPrefix(caller, "remote caller at 127.0.0.1:") {
+		t.Errorf("response = %+v, want the remote caller's address", resp)
+	}
+
+	resp, err = NewRemoteClient(addr, "guess", nil).Send(Request{Command: "whoami"})
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	var coded *Error
+	if !errors.As(resp.Err(), &coded) || coded.Code != CodePermissionDenied {
+		t.Errorf("response with a wrong token = %+v, want %s", resp, CodePermissionDenied)
+	}
+}
+
+func TestRemoteServerTLS(t *testing.T) {
+	certFile, keyFile := selfSignedCert(t)
+	server := startRemote(t, certFile, keyFile)
+	addr := server.Addr().String()
+
+	config, err := ClientTLSConfig(certFile)
+	if err != nil {
+		t.Fatalf("ClientTLSConfig() failed: %v", err)
+	}
+	resp, err := NewRemoteClient(addr, "s3cret", config).Send(Request{Command: "ping"})
+	if err != nil || !resp.Success {
+		t.Fatalf("Send() over TLS = %+v, %v", resp, err)
+	}
+
+	// Without trusting the certificate the handshake fails
+	untrusted, _ := ClientTLSConfig("")
+	if _, err := NewRemoteClient(addr, "s3cret", untrusted).Send(Request{Command: "ping"}); err == nil {
+		t.Error("Send() trusted a self-signed certificate it wasn't given")
+	}
+	// A plain TCP client gets nothing from a TLS socket
+	if resp, err := NewRemoteClient(addr, "s3cret", nil).Send(Request{Command: "ping"}); err == nil && resp.Success {
+		t.Error("plain TCP request succeeded on a TLS socket")
+	}
+}
+
+func TestRemoteServerRequiresToken(t *testing.T) {
+	server := NewRemoteServer("127.0.0.1:0", "", nil, HandlerFunc(func(Request) Response { return Response{} }))
+	if err := server.Start(); err == nil {
+		server.Stop()
+		t.Error("Start() without a token should fail")
+	}
+}
+
+// selfSignedCert writes a certificate for 127.0.0.1 and its key
+func selfSignedCert(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "multiclaude test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
@@ -1,17 +1,41 @@
 package socket
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/tracing"
 )
 
 // Request represents a request sent to the daemon
 type Request struct {
 	Command string                 `json:"command"`
 	Args    map[string]interface{} `json:"args,omitempty"`
+
+	// Version is the protocol version the client speaks; 0 for clients
+	// predating versioning
+	Version int `json:"version,omitempty"`
+
+	// Token authenticates requests on the daemon's TCP socket
+	Token string `json:"token,omitempty"`
+
+	// Traceparent is the W3C trace context of the client's span for this
+	// request, so the daemon's span joins the client's trace
+	Traceparent string `json:"traceparent,omitempty"`
+
+	// Origin is the CLI command that sent the request (e.g. "work"), which
+	// the daemon's audit log records
+	Origin string `json:"origin,omitempty"`
+
+	// Peer is the caller, filled in by the server from the connection's
+	// credentials. Clients cannot set it.
+	Peer *Peer `json:"-"`
 }
 
 // Response represents a response from the daemon
@@ -19,26 +43,82 @@ type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+
+	// Code classifies a failure. Only set for version 1 requests and later.
+	Code ErrorCode `json:"code,omitempty"`
+	// Version is the protocol version of the response, matching the
+	// request's
+	Version int `json:"version,omitempty"`
 }
 
-// Client connects to the daemon via Unix socket
+// Client connects to the daemon via its Unix socket, or via its TCP socket
+// (see NewRemoteClient)
 type Client struct {
-	socketPath string
+	network   string
+	address   string
+	token     string
+	tlsConfig *tls.Config
 }
 
 // NewClient creates a new socket client
 func NewClient(socketPath string) *Client {
-	return &Client{socketPath: socketPath}
+	return &Client{network: "unix", address: socketPath}
 }
 
+// RoundTripObserver, when set, is called after every client request with
+// the command, how long the roundtrip took, and the error if it failed
+var RoundTripObserver func(command string, elapsed time.Duration, err error)
+
+// Origin, when set, is sent as the Origin of every client request that
+// doesn't set one
+var Origin string
+
 // Send sends a request to the daemon and returns the response
 func (c *Client) Send(req Request) (*Response, error) {
-	conn, err := net.Dial("unix", c.socketPath)
+	if observe := RoundTripObserver; observe != nil {
+		start := time.Now()
+		resp, err := c.send(req)
+		failure := err
+		if err == nil && !resp.Success {
+			failure = fmt.Errorf("%s", resp.Error)
+		}
+		observe(req.Command, time.Since(start), failure)
+		return resp, err
+	}
+	return c.send(req)
+}
+
+// send sends a request, recording the round trip as a span of the caller's
+// trace
+func (c *Client) send(req Request) (*Response, error) {
+	ctx, span := tracing.StartChild(context.Background(), "socket "+req.Command)
+	if req.Traceparent == "" {
+		req.Traceparent = tracing.Traceparent(ctx)
+	}
+	if req.Origin == "" {
+		req.Origin = Origin
+	}
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		span.End(err)
+	} else {
+		span.End(resp.Err())
+	}
+	return resp, err
+}
+
+func (c *Client) roundTrip(req Request) (*Response, error) {
+	conn, err := c.dial()
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
 	}
 	defer conn.Close()
 
+	if req.Version == 0 {
+		req.Version = ProtocolVersion
+	}
+	req.Token = c.token
+
 	// Send request
 	if err := json.NewEncoder(conn).Encode(req); err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -53,6 +133,18 @@ func (c *Client) Send(req Request) (*Response, error) {
 	return &resp, nil
 }
 
+// dial connects to the daemon
+func (c *Client) dial() (net.Conn, error) {
+	if c.network == "unix" {
+		return net.Dial("unix", c.address)
+	}
+	dialer := &net.Dialer{Timeout: remoteDialTimeout}
+	if c.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, "tcp", c.address, c.tlsConfig)
+	}
+	return dialer.Dial("tcp", c.address)
+}
+
 // Server listens on a Unix socket for requests
 type Server struct {
 	socketPath string
@@ -141,12 +233,14 @@ func (s *Server) handleConnection(conn net.Conn) {
 			resp := Response{
 				Success: false,
 				Error:   fmt.Sprintf("failed to decode request: %v", err),
+				Code:    CodeBadRequest,
 			}
 			json.NewEncoder(conn).Encode(resp)
 		}
 		return
 	}
 
+	req.Peer = PeerOf(conn)
 	resp := s.handler.Handle(req)
 	if err := json.NewEncoder(conn).Encode(resp); err != nil {
 		// Can't send error response at this point
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"net"
 	"os"
+	"os/user"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -164,6 +167,39 @@ func TestServerErrorResponse(t *testing.T) {
 	}
 }
 
+func TestRoundTripObserver(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "test.sock")
+	server := NewServer(sockPath, HandlerFunc(func(req Request) Response {
+		return Response{Success: req.Command == "ok", Error: "nope"}
+	}))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer server.Stop()
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond)
+
+	observed := make(map[string]error)
+	RoundTripObserver = func(command string, elapsed time.Duration, err error) {
+		observed[command] = err
+	}
+	defer func() { RoundTripObserver = nil }()
+
+	client := NewClient(sockPath)
+	client.Send(Request{Command: "ok"})
+	resp, err := client.Send(Request{Command: "fail"})
+	if err != nil || resp.Success {
+		t.Fatalf("Send() = %+v, %v; an unsuccessful response is not a send error", resp, err)
+	}
+
+	if err, ok := observed["ok"]; !ok || err != nil {
+		t.Errorf("observed ok = %v, %v; want a successful roundtrip", err, ok)
+	}
+	if err := observed["fail"]; err == nil || err.Error() != "nope" {
+		t.Errorf("observed fail = %v, want the daemon's error", err)
+	}
+}
+
 func TestClientConnectionFailure(t *testing.T) {
 	tmpDir := t.TempDir()
 	sockPath := filepath.Join(tmpDir, "nonexistent.sock")
@@ -346,3 +382,50 @@ func TestServerStopRemovesSocket(t *testing.T) {
 		t.Error("Socket file should be removed after Stop()")
 	}
 }
+
+func TestServerReportsPeer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only read on Linux")
+	}
+
+	sockPath := filepath.Join(t.TempDir(), "test.sock")
+	peers := make(chan *Peer, 1)
+	server := NewServer(sockPath, HandlerFunc(func(req Request) Response {
+		peers <- req.Peer
+		return Response{Success: true}
+	}))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer server.Stop()
+	go server.Serve()
+
+	if _, err := NewClient(sockPath).Send(Request{Command: "test"}); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	peer := <-peers
+	if peer == nil || peer.UID != os.Getuid() || peer.PID != os.Getpid() || peer.User == "" {
+		t.Errorf("peer = %+v, want this process (uid %d, pid %d)", peer, os.Getuid(), os.Getpid())
+	}
+
+	// Sharing with our own primary group opens the socket to the group
+	g, err := user.LookupGroupId(strconv.Itoa(os.Getgid()))
+	if err != nil {
+		t.Skipf("cannot look up own group: %v", err)
+	}
+	if err := server.Share(g.Name); err != nil {
+		t.Fatalf("Share() failed: %v", err)
+	}
+	if info, _ := os.Stat(sockPath); info.Mode().Perm() != 0660 {
+		t.Errorf("shared socket mode = %v, want 0660", info.Mode().Perm())
+	}
+	if err := server.Share(""); err != nil {
+		t.Fatalf("Share(\"\") failed: %v", err)
+	}
+	if info, _ := os.Stat(sockPath); info.Mode().Perm() != 0600 {
+		t.Errorf("private socket mode = %v, want 0600", info.Mode().Perm())
+	}
+	if err := server.Share("no-such-group-multiclaude"); err == nil {
+		t.Error("Share() should fail for an unknown group")
+	}
+}
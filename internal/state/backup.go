@@ -0,0 +1,191 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// BackupCount is how many previous versions of the state file Save keeps,
+// from state.json.1 (the newest) to state.json.5
+const BackupCount = 5
+
+// BackupInterval is how old the newest backup must be before Save keeps
+// another. The daemon saves on every heartbeat, so backing up each save
+// would leave only the last few seconds of state to fall back to.
+const BackupInterval = 10 * time.Minute
+
+// Backup describes one backup of the state file
+type Backup struct {
+	N       int       `json:"n"`
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"mod_time"`
+	Repos   int       `json:"repos"`
+	Agents  int       `json:"agents"`
+	// Error is why the backup can't be restored
+	Error string `json:"error,omitempty"`
+}
+
+// backupPath returns the path of the nth backup of the state file at path
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// write replaces the state file with data, first keeping the current file
+// as the newest backup when that backup is older than BackupInterval, or
+// always when forceBackup is set
+func (s *State) write(data []byte, forceBackup bool) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if forceBackup || backupDue(s.path, time.Now()) {
+		// A failed rotation costs a backup, which is no reason to lose the
+		// change being saved
+		_ = rotateBackups(s.path)
+	}
+	return atomicWrite(s.path, data)
+}
+
+// backupDue reports whether the newest backup of the state file at path is
+// missing or was saved at least BackupInterval before now
+func backupDue(path string, now time.Time) bool {
+	info, err := os.Stat(backupPath(path, 1))
+	return err != nil || now.Sub(info.ModTime()) >= BackupInterval
+}
+
+// rotateBackups shifts each backup up one, dropping the oldest, and makes
+// the current state file the newest. The file is linked rather than moved,
+// so a concurrent Load never finds it missing.
+func rotateBackups(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	for n := BackupCount - 1; n >= 1; n-- {
+		if err := os.Rename(backupPath(path, n), backupPath(path, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	newest := backupPath(path, 1)
+	os.Remove(newest)
+	if err := os.Link(path, newest); err != nil {
+		// Some filesystems don't support hard links
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(newest, data, 0600); err != nil {
+			return err
+		}
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes a directory's entries to disk, so a rename in it survives
+// a crash. Windows can't sync a directory, and some filesystems refuse to.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open state directory: %w", err)
+	}
+	defer f.Close()
+	if err := f.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return fmt.Errorf("failed to sync state directory: %w", err)
+	}
+	return nil
+}
+
+// RestoredFrom returns the number of the backup Load fell back to because
+// the state file couldn't be read, or 0 if it loaded the state file
+func (s *State) RestoredFrom() int {
+	return s.restoredFrom
+}
+
+// ListBackups describes the backups of the state file at path, newest
+// first. Missing backups are left out.
+func ListBackups(path string) []Backup {
+	var backups []Backup
+	for n := 1; n <= BackupCount; n++ {
+		file := backupPath(path, n)
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		backup := Backup{N: n, Path: file, ModTime: info.ModTime()}
+		if s, err := readStateFile(file); err != nil {
+			backup.Error = err.Error()
+		} else {
+			backup.Repos = len(s.Repos)
+			for _, repo := range s.Repos {
+				backup.Agents += len(repo.Agents)
+			}
+		}
+		backups = append(backups, backup)
+	}
+	return backups
+}
+
+// PickBackup returns the backup RestoreBackup(n) would restore from a
+// ListBackups result: backup n, or the newest valid backup when n is 0
+func PickBackup(backups []Backup, n int) (Backup, error) {
+	for _, backup := range backups {
+		if n != 0 && backup.N != n {
+			continue
+		}
+		if backup.Error == "" {
+			return backup, nil
+		}
+		if n != 0 {
+			return Backup{}, fmt.Errorf("backup %d can't be restored: %s", n, backup.Error)
+		}
+	}
+	if n != 0 {
+		return Backup{}, fmt.Errorf("backup %d doesn't exist", n)
+	}
+	return Backup{}, fmt.Errorf("no valid backup of the state file")
+}
+
+// RestoreBackup replaces the state with backup n, or with the newest valid
+// backup when n is 0, and saves it. The state it replaces becomes the newest
+// backup, so a restore can be undone by restoring backup 1. It returns the
+// number of the backup restored.
+func (s *State) RestoreBackup(n int) (int, error) {
+	if n < 0 || n > BackupCount {
+		return 0, fmt.Errorf("backup must be between 1 and %d", BackupCount)
+	}
+	candidates := []int{n}
+	if n == 0 {
+		candidates = candidates[:0]
+		for i := 1; i <= BackupCount; i++ {
+			candidates = append(candidates, i)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, i := range candidates {
+		backup, err := readStateFile(backupPath(s.path, i))
+		if err != nil {
+			if n != 0 {
+				return 0, fmt.Errorf("backup %d can't be restored: %w", i, err)
+			}
+			continue
+		}
+		s.persisted = backup.persisted
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal state: %w", err)
+		}
+		return i, s.write(data, true)
+	}
+	return 0, fmt.Errorf("no valid backup of %s", s.path)
+}
@@ -0,0 +1,157 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ageBackup makes the newest backup of the state file at path old enough
+// for the next save to keep another
+func ageBackup(path string) {
+	old := time.Now().Add(-BackupInterval)
+	os.Chtimes(backupPath(path, 1), old, old)
+}
+
+// saveRepos saves a state with count repositories, one save per
+// BackupInterval
+func saveRepos(t *testing.T, s *State, count int) {
+	t.Helper()
+	for i := len(s.ListRepos()); i < count; i++ {
+		ageBackup(s.path)
+		if err := s.AddRepo(fmt.Sprintf("repo%d", i), &Repository{TmuxSession: fmt.Sprintf("mc-repo%d", i)}); err != nil {
+			t.Fatalf("AddRepo() failed: %v", err)
+		}
+	}
+}
+
+func TestSaveRotatesBackups(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+
+	saveRepos(t, s, 1)
+	if _, err := os.Stat(backupPath(statePath, 1)); !os.IsNotExist(err) {
+		t.Error("the first Save has no previous state to back up")
+	}
+
+	saveRepos(t, s, BackupCount+3)
+	for n := 1; n <= BackupCount; n++ {
+		backup, err := readStateFile(backupPath(statePath, n))
+		if err != nil {
+			t.Fatalf("backup %d: %v", n, err)
+		}
+		// Backup n is the state n saves ago
+		if want := BackupCount + 3 - n; len(backup.Repos) != want {
+			t.Errorf("backup %d has %d repos, want %d", n, len(backup.Repos), want)
+		}
+	}
+	if _, err := os.Stat(backupPath(statePath, BackupCount+1)); !os.IsNotExist(err) {
+		t.Errorf("Save kept more than %d backups", BackupCount)
+	}
+}
+
+func TestSaveBacksUpOncePerInterval(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+	saveRepos(t, s, 2)
+
+	// Frequent saves, like agent heartbeats, keep the backup from before them
+	for i := 0; i < 10; i++ {
+		if err := s.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.AddRepo("late", &Repository{TmuxSession: "mc-late"})
+	backups := ListBackups(statePath)
+	if len(backups) != 1 || backups[0].Repos != 1 {
+		t.Errorf("ListBackups() = %+v, want only the one-repo backup", backups)
+	}
+
+	ageBackup(statePath)
+	s.Save()
+	if backups := ListBackups(statePath); len(backups) != 2 || backups[0].Repos != 3 {
+		t.Errorf("ListBackups() after the interval = %+v, want a new three-repo backup", backups)
+	}
+}
+
+func TestLoadFallsBackToBackup(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+	saveRepos(t, s, 3)
+
+	if loaded, err := Load(statePath); err != nil || loaded.RestoredFrom() != 0 {
+		t.Fatalf("Load() of a valid state = %v, %v", loaded, err)
+	}
+
+	// A crash mid-write leaves the state file truncated, and the newest
+	// backup is corrupted too
+	os.WriteFile(statePath, []byte(`{"repos": {`), 0644)
+	os.WriteFile(backupPath(statePath, 1), []byte("not json"), 0644)
+
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() should fall back to a backup: %v", err)
+	}
+	if loaded.RestoredFrom() != 2 || len(loaded.Repos) != 1 {
+		t.Errorf("Load() restored backup %d with %d repos, want backup 2 with 1 repo", loaded.RestoredFrom(), len(loaded.Repos))
+	}
+	// Saving the loaded state writes to the state file, not the backup
+	if err := loaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := Load(statePath); err != nil || again.RestoredFrom() != 0 {
+		t.Errorf("Load() after saving the restored state = %v, %v", again, err)
+	}
+
+	// Without a valid backup, Load fails as before
+	for n := 1; n <= BackupCount; n++ {
+		os.Remove(backupPath(statePath, n))
+	}
+	os.WriteFile(statePath, []byte("garbage"), 0644)
+	if _, err := Load(statePath); err == nil {
+		t.Error("Load() should fail without a valid state file or backup")
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	s := New(statePath)
+	saveRepos(t, s, 4)
+	os.WriteFile(backupPath(statePath, 1), []byte("not json"), 0644)
+
+	backups := ListBackups(statePath)
+	if len(backups) != 3 || backups[0].N != 1 || backups[0].Error == "" || backups[1].Repos != 2 {
+		t.Fatalf("ListBackups() = %+v", backups)
+	}
+	if picked, err := PickBackup(backups, 0); err != nil || picked.N != 2 {
+		t.Errorf("PickBackup(0) = %+v, %v; want the newest valid backup, 2", picked, err)
+	}
+	if _, err := PickBackup(backups, 1); err == nil {
+		t.Error("PickBackup(1) should refuse a corrupted backup")
+	}
+	if _, err := PickBackup(backups, 5); err == nil {
+		t.Error("PickBackup(5) should refuse a missing backup")
+	}
+
+	if _, err := s.RestoreBackup(1); err == nil {
+		t.Error("RestoreBackup(1) should refuse a corrupted backup")
+	}
+	n, err := s.RestoreBackup(0)
+	if err != nil || n != 2 {
+		t.Fatalf("RestoreBackup(0) = %d, %v; want backup 2", n, err)
+	}
+	if got := len(s.ListRepos()); got != 2 {
+		t.Errorf("restored state has %d repos, want 2", got)
+	}
+	loaded, err := Load(statePath)
+	if err != nil || len(loaded.Repos) != 2 {
+		t.Fatalf("Load() after restore = %v, %v", loaded, err)
+	}
+
+	// The replaced state is the newest backup, so the restore can be undone
+	if n, err := s.RestoreBackup(1); err != nil || n != 1 || len(s.ListRepos()) != 4 {
+		t.Errorf("undoing the restore = %d, %v, %d repos", n, err, len(s.ListRepos()))
+	}
+}
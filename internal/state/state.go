@@ -2,11 +2,18 @@ package state
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/dlorenc/multiclaude/internal/logstore"
 )
 
 // AgentType represents the type of agent
@@ -52,6 +59,53 @@ type MergeQueueConfig struct {
 	Enabled bool `json:"enabled"`
 	// TrackMode determines which PRs to track: "all", "author", or "assigned" (default: "all")
 	TrackMode TrackMode `json:"track_mode"`
+	// AutoMerge has the daemon merge labeled PRs itself: rebase them onto
+	// main, wait for their checks, and merge them in order
+	AutoMerge bool `json:"auto_merge,omitempty"`
+	// Label marks the PRs the daemon merges (default: DefaultMergeLabel)
+	Label string `json:"label,omitempty"`
+	// PollSeconds is how often the daemon checks labeled PRs (0: DefaultMergePollInterval)
+	PollSeconds int `json:"poll_seconds,omitempty"`
+	// RequiredChecks must pass before a PR is merged (empty: the base
+	// branch's required checks, or every reported check if it has none)
+	RequiredChecks []string `json:"required_checks,omitempty"`
+	// MergeMethod is "merge", "squash", or "rebase" (default: "squash")
+	MergeMethod string `json:"merge_method,omitempty"`
+}
+
+// DefaultMergeLabel is the label PRs carry to be merged by the daemon
+const DefaultMergeLabel = "multiclaude"
+
+// DefaultMergePollInterval is how often the daemon checks labeled PRs
+const DefaultMergePollInterval = 2 * time.Minute
+
+// MergeLabel returns the label that marks PRs for the daemon to merge
+func (c MergeQueueConfig) MergeLabel() string {
+	if c.Label == "" {
+		return DefaultMergeLabel
+	}
+	return c.Label
+}
+
+// PollInterval returns how often labeled PRs are checked
+func (c MergeQueueConfig) PollInterval() time.Duration {
+	if c.PollSeconds <= 0 {
+		return DefaultMergePollInterval
+	}
+	return time.Duration(c.PollSeconds) * time.Second
+}
+
+// Method returns how PRs are merged
+func (c MergeQueueConfig) Method() string {
+	if c.MergeMethod == "" {
+		return "squash"
+	}
+	return c.MergeMethod
+}
+
+// ValidMergeMethod reports whether method is one GitHub accepts
+func ValidMergeMethod(method string) bool {
+	return method == "merge" || method == "squash" || method == "rebase"
 }
 
 // DefaultMergeQueueConfig returns the default merge queue configuration
@@ -62,6 +116,384 @@ func DefaultMergeQueueConfig() MergeQueueConfig {
 	}
 }
 
+// BranchGuardConfig restricts what a worker branch may contain before it is
+// handed to the merge queue. The zero value disables the guard.
+type BranchGuardConfig struct {
+	// AllowedPaths lists path prefixes or globs workers may change (empty: any path)
+	AllowedPaths []string `json:"allowed_paths,omitempty"`
+	// MaxFileMB is the largest allowed changed file in megabytes (0: no limit)
+	MaxFileMB int `json:"max_file_mb,omitempty"`
+	// BlockBinaries rejects branches that add binary files
+	BlockBinaries bool `json:"block_binaries,omitempty"`
+}
+
+// Enabled returns true if any guard rule is configured
+func (c BranchGuardConfig) Enabled() bool {
+	return len(c.AllowedPaths) > 0 || c.MaxFileMB > 0 || c.BlockBinaries
+}
+
+// CommitStyle selects how agent commit messages are linted
+type CommitStyle string
+
+const (
+	// CommitStyleNone disables commit message linting (default)
+	CommitStyleNone CommitStyle = ""
+	// CommitStyleConventional requires Conventional Commits subjects
+	CommitStyleConventional CommitStyle = "conventional"
+	// CommitStyleRegex requires subjects to match a custom pattern
+	CommitStyleRegex CommitStyle = "regex"
+)
+
+// CommitPolicyConfig holds the commit message policy for agent branches
+type CommitPolicyConfig struct {
+	Style   CommitStyle `json:"style,omitempty"`
+	Pattern string      `json:"pattern,omitempty"` // Only for CommitStyleRegex
+}
+
+// AutoAnswerRule answers worker questions matching Pattern with Reply
+type AutoAnswerRule struct {
+	Pattern string `json:"pattern"` // Case-insensitive regular expression
+	Reply   string `json:"reply"`
+}
+
+// AutoAnswerConfig holds a repository's auto-answer settings. Rules are tried
+// before the built-in templates; Disabled opts the repository out entirely.
+type AutoAnswerConfig struct {
+	Disabled bool             `json:"disabled,omitempty"`
+	Rules    []AutoAnswerRule `json:"rules,omitempty"`
+}
+
+// ReaperMode controls what the zombie window reaper does
+type ReaperMode string
+
+const (
+	// ReaperDryRun logs windows that would be killed (the default)
+	ReaperDryRun ReaperMode = "dry-run"
+	// ReaperEnforce kills windows once their grace period has passed
+	ReaperEnforce ReaperMode = "enforce"
+	// ReaperOff disables the reaper
+	ReaperOff ReaperMode = "off"
+)
+
+// DefaultReaperGraceMinutes is how long a window must be unowned before it is reaped
+const DefaultReaperGraceMinutes = 10
+
+// WindowReaperConfig controls cleanup of tmux windows no agent in state owns
+type WindowReaperConfig struct {
+	// Mode is dry-run, enforce, or off (empty: dry-run)
+	Mode ReaperMode `json:"mode,omitempty"`
+	// GraceMinutes is how long a window must be unowned (0: DefaultReaperGraceMinutes)
+	GraceMinutes int `json:"grace_minutes,omitempty"`
+	// Keep lists manually created windows that are never reaped
+	Keep []string `json:"keep,omitempty"`
+}
+
+// EffectiveMode returns the configured mode, defaulting to dry-run
+func (c WindowReaperConfig) EffectiveMode() ReaperMode {
+	if c.Mode == "" {
+		return ReaperDryRun
+	}
+	return c.Mode
+}
+
+// Grace returns the configured grace period, defaulting to DefaultReaperGraceMinutes
+func (c WindowReaperConfig) Grace() time.Duration {
+	if c.GraceMinutes <= 0 {
+		return DefaultReaperGraceMinutes * time.Minute
+	}
+	return time.Duration(c.GraceMinutes) * time.Minute
+}
+
+// DefaultRecoverAfterMinutes is how long a rebase or merge in a worktree must
+// be idle before it counts as interrupted
+const DefaultRecoverAfterMinutes = 30
+
+// RecoveryConfig controls recovery of rebases and merges left in progress in
+// worker worktrees
+type RecoveryConfig struct {
+	// Auto recovers interrupted operations during health checks instead of
+	// waiting for `work recover`
+	Auto bool `json:"auto,omitempty"`
+	// AfterMinutes is how long an operation must be idle (0: DefaultRecoverAfterMinutes)
+	AfterMinutes int `json:"after_minutes,omitempty"`
+}
+
+// After returns the configured idle threshold, defaulting to DefaultRecoverAfterMinutes
+func (c RecoveryConfig) After() time.Duration {
+	if c.AfterMinutes <= 0 {
+		return DefaultRecoverAfterMinutes * time.Minute
+	}
+	return time.Duration(c.AfterMinutes) * time.Minute
+}
+
+// DefaultMaxRestarts is how many times in a row a crashed agent is restarted
+const DefaultMaxRestarts = 3
+
+// RestartStableAfter is how long an agent must run after an automatic
+// restart before its earlier crashes stop counting toward the limit
+const RestartStableAfter = 30 * time.Minute
+
+// RestartPolicy controls automatic restarts of agents whose Claude process
+// exited while their window stayed open. Workspaces are never restarted,
+// since their user may have quit Claude on purpose.
+type RestartPolicy struct {
+	// Off disables automatic restarts
+	Off bool `json:"off,omitempty"`
+	// MaxRestarts is how many restarts in a row an agent gets (0: DefaultMaxRestarts)
+	MaxRestarts int `json:"max_restarts,omitempty"`
+}
+
+// Limit returns the configured restart limit, defaulting to DefaultMaxRestarts
+func (p RestartPolicy) Limit() int {
+	if p.MaxRestarts <= 0 {
+		return DefaultMaxRestarts
+	}
+	return p.MaxRestarts
+}
+
+// StuckConfig controls when an agent that has produced no output and sent
+// no heartbeat is reported as stuck. Rules and quiet expectations let tasks that are
+// expected to sit quietly (writing docs, long compiles) wait longer, and
+// tasks that shouldn't (fixing a failing test) be flagged sooner.
+type StuckConfig struct {
+	// IdleMinutes is how long a worker or reviewer may produce no output and
+	// send no heartbeat before it is reported (0: idle agents are never
+	// reported)
+	IdleMinutes int `json:"idle_minutes,omitempty"`
+	// Rules override IdleMinutes for matching agents; the first match wins
+	Rules []StuckRule `json:"rules,omitempty"`
+	// Quiet lets an agent whose last output matches a pattern stay quiet longer
+	Quiet []QuietRule `json:"quiet,omitempty"`
+	// Nudge types a reminder into a stuck agent's window as well as telling
+	// the supervisor
+	Nudge bool `json:"nudge,omitempty"`
+}
+
+// StuckRule sets the idle threshold for agents whose label, task, or
+// sub-project matches
+type StuckRule struct {
+	// Match is "label:<label>", "task:<text in the task>", or
+	// "scope:<sub-project path>"
+	Match       string `json:"match"`
+	IdleMinutes int    `json:"idle_minutes"`
+}
+
+// QuietRule lets an agent whose last line of output matches Pattern (a
+// regular expression) stay quiet for Minutes, e.g. during a long build
+type QuietRule struct {
+	Pattern string `json:"pattern"`
+	Minutes int    `json:"minutes"`
+}
+
+// ParseStuckRule parses a rule written as <match>=<duration>, e.g.
+// "label:docs=45m" or "task:failing test=5m"
+func ParseStuckRule(spec string) (StuckRule, error) {
+	match, minutes, err := splitMinutes(spec)
+	if err != nil {
+		return StuckRule{}, err
+	}
+	kind, value, _ := strings.Cut(match, ":")
+	if (kind != "label" && kind != "task" && kind != "scope") || value == "" {
+		return StuckRule{}, fmt.Errorf("invalid stuck rule %q: match label:<label>, task:<text>, or scope:<path>", spec)
+	}
+	return StuckRule{Match: match, IdleMinutes: minutes}, nil
+}
+
+// ParseQuietRule parses a quiet expectation written as <regex>=<duration>,
+// e.g. "Compiling=30m"
+func ParseQuietRule(spec string) (QuietRule, error) {
+	pattern, minutes, err := splitMinutes(spec)
+	if err != nil {
+		return QuietRule{}, err
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return QuietRule{}, fmt.Errorf("invalid quiet pattern %q: %w", pattern, err)
+	}
+	return QuietRule{Pattern: pattern, Minutes: minutes}, nil
+}
+
+// splitMinutes splits "<key>=<duration>" at the last '=' and returns the
+// duration in whole minutes, which must be at least one
+func splitMinutes(spec string) (string, int, error) {
+	i := strings.LastIndex(spec, "=")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("invalid rule %q: expected <match>=<duration>", spec)
+	}
+	d, err := time.ParseDuration(spec[i+1:])
+	if err != nil || d < time.Minute {
+		return "", 0, fmt.Errorf("invalid rule %q: duration must be at least 1m, like 10m or 2h", spec)
+	}
+	return strings.TrimSpace(spec[:i]), int(d.Minutes()), nil
+}
+
+// String formats the rule the way ParseStuckRule reads it
+func (r StuckRule) String() string {
+	return fmt.Sprintf("%s=%dm", r.Match, r.IdleMinutes)
+}
+
+// String formats the rule the way ParseQuietRule reads it
+func (q QuietRule) String() string {
+	return fmt.Sprintf("%s=%dm", q.Pattern, q.Minutes)
+}
+
+// Matches reports whether the rule applies to agent
+func (r StuckRule) Matches(agent Agent) bool {
+	kind, value, _ := strings.Cut(r.Match, ":")
+	switch kind {
+	case "label":
+		for _, label := range agent.Labels {
+			if label == value {
+				return true
+			}
+		}
+	case "task":
+		return strings.Contains(strings.ToLower(agent.Task), strings.ToLower(value))
+	case "scope":
+		return len(agent.ScopePaths) > 0 && agent.ScopePaths[0] == strings.Trim(value, "/")
+	}
+	return false
+}
+
+// IdleThreshold returns how long agent may produce no output before it is
+// stuck, given its last line of output; 0 means it is never reported
+func (c StuckConfig) IdleThreshold(agent Agent, lastLine string) time.Duration {
+	minutes := c.IdleMinutes
+	for _, r := range c.Rules {
+		if r.Matches(agent) {
+			minutes = r.IdleMinutes
+			break
+		}
+	}
+	if minutes <= 0 {
+		return 0
+	}
+	for _, q := range c.Quiet {
+		if re, err := regexp.Compile(q.Pattern); err == nil && re.MatchString(lastLine) && q.Minutes > minutes {
+			minutes = q.Minutes
+		}
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// clone returns a copy that shares no slices with c
+func (c StuckConfig) clone() StuckConfig {
+	c.Rules = append([]StuckRule(nil), c.Rules...)
+	c.Quiet = append([]QuietRule(nil), c.Quiet...)
+	return c
+}
+
+// Permission is an action on a repository that can be limited to some users
+type Permission string
+
+const (
+	// PermSpawn covers creating, restarting, handing off, and replying to agents
+	PermSpawn Permission = "spawn"
+	// PermRemove covers removing or completing agents and the repository itself
+	PermRemove Permission = "remove"
+	// PermMerge covers recording merge queue events
+	PermMerge Permission = "merge"
+	// PermAdmin covers changing the repository's configuration, cleaning it
+	// up, and resolving refresh conflicts
+	PermAdmin Permission = "admin"
+)
+
+// Permissions lists every Permission
+var Permissions = []Permission{PermSpawn, PermRemove, PermMerge, PermAdmin}
+
+// AccessPolicy limits who may act on a repository when several people share
+// one daemon. Each list holds user names, @group names, or "*"; an empty list
+// lets anyone who can reach the daemon socket do it. The daemon's own user
+// is always allowed.
+type AccessPolicy struct {
+	Spawn  []string `json:"spawn,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+	Merge  []string `json:"merge,omitempty"`
+	Admin  []string `json:"admin,omitempty"`
+}
+
+// Members returns who is granted perm
+func (p AccessPolicy) Members(perm Permission) []string {
+	switch perm {
+	case PermSpawn:
+		return p.Spawn
+	case PermRemove:
+		return p.Remove
+	case PermMerge:
+		return p.Merge
+	case PermAdmin:
+		return p.Admin
+	}
+	return nil
+}
+
+// SetMembers replaces who is granted perm
+func (p *AccessPolicy) SetMembers(perm Permission, members []string) {
+	switch perm {
+	case PermSpawn:
+		p.Spawn = members
+	case PermRemove:
+		p.Remove = members
+	case PermMerge:
+		p.Merge = members
+	case PermAdmin:
+		p.Admin = members
+	}
+}
+
+// Restricts returns true if perm is limited to the listed members
+func (p AccessPolicy) Restricts(perm Permission) bool {
+	return len(p.Members(perm)) > 0
+}
+
+// Allows returns true if the user, or one of the named groups they belong
+// to, is granted perm
+func (p AccessPolicy) Allows(perm Permission, user string, groups []string) bool {
+	members := p.Members(perm)
+	if len(members) == 0 {
+		return true
+	}
+	for _, member := range members {
+		if member == "*" || member == user {
+			return true
+		}
+		if group, ok := strings.CutPrefix(member, "@"); ok {
+			for _, g := range groups {
+				if g == group {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// clone returns a copy that shares no slices with p
+func (p AccessPolicy) clone() AccessPolicy {
+	var c AccessPolicy
+	for _, perm := range Permissions {
+		if members := p.Members(perm); members != nil {
+			c.SetMembers(perm, append([]string(nil), members...))
+		}
+	}
+	return c
+}
+
+// WarmPoolConfig keeps pre-created worktrees ready so new workers start
+// without waiting for checkout and dependency install
+type WarmPoolConfig struct {
+	// Size is how many warm worktrees the daemon keeps ready (0: disabled)
+	Size int `json:"size,omitempty"`
+	// Bootstrap is a shell command run in each new warm worktree (e.g. "npm ci")
+	Bootstrap string `json:"bootstrap,omitempty"`
+}
+
+// WarmWorktree is a bootstrapped worktree waiting to be assigned to a worker
+type WarmWorktree struct {
+	Path      string    `json:"path"`
+	Branch    string    `json:"branch"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // TaskStatus represents the status of a completed task
 type TaskStatus string
 
@@ -94,19 +526,295 @@ type TaskHistoryEntry struct {
 	CompletedAt   time.Time  `json:"completed_at,omitempty"`   // When the task was completed
 }
 
+// QueuedTaskState is where a task in a repository's task queue stands
+type QueuedTaskState string
+
+const (
+	// QueuedTaskQueued means the task is waiting for a worker
+	QueuedTaskQueued QueuedTaskState = "queued"
+	// QueuedTaskAssigned means a worker was spawned for the task
+	QueuedTaskAssigned QueuedTaskState = "assigned"
+	// QueuedTaskInProgress means the task's worker has started on it
+	QueuedTaskInProgress QueuedTaskState = "in-progress"
+	// QueuedTaskDone means the task's worker completed it
+	QueuedTaskDone QueuedTaskState = "done"
+	// QueuedTaskFailed means the task's worker failed, went away, or could
+	// not be spawned
+	QueuedTaskFailed QueuedTaskState = "failed"
+)
+
+// Finished reports whether a task in this state is no longer waiting or running
+func (s QueuedTaskState) Finished() bool {
+	return s == QueuedTaskDone || s == QueuedTaskFailed
+}
+
+// QueuedTask is a task waiting in, or dispatched from, a repository's task
+// queue
+type QueuedTask struct {
+	ID          string          `json:"id"`
+	Description string          `json:"description"`
+	Priority    TaskPriority    `json:"priority,omitempty"`
+	State       QueuedTaskState `json:"state"`
+	Agent       string          `json:"agent,omitempty"` // Worker the task was dispatched to
+	Error       string          `json:"error,omitempty"` // Why the task failed
+	CreatedAt   time.Time       `json:"created_at"`
+	AssignedAt  time.Time       `json:"assigned_at,omitempty"`
+	FinishedAt  time.Time       `json:"finished_at,omitempty"`
+}
+
+// maxFinishedTasks is how many done and failed tasks a repository's queue keeps
+const maxFinishedTasks = 100
+
+// DefaultQueueWorkers is how many queued tasks run at once by default
+const DefaultQueueWorkers = 2
+
+// TaskQueueConfig controls how a repository's task queue is dispatched
+type TaskQueueConfig struct {
+	// Workers is how many workers the queue runs at once (0: DefaultQueueWorkers)
+	Workers int `json:"workers,omitempty"`
+}
+
+// Limit returns the configured number of workers, defaulting to
+// DefaultQueueWorkers
+func (c TaskQueueConfig) Limit() int {
+	if c.Workers <= 0 {
+		return DefaultQueueWorkers
+	}
+	return c.Workers
+}
+
+// MergeQueueEvent is a step in a PR's trip through the merge queue
+type MergeQueueEvent string
+
+const (
+	// MergeQueueEnqueued means the branch was handed to the merge queue
+	MergeQueueEnqueued MergeQueueEvent = "enqueued"
+	// MergeQueueCIStarted means CI started running for the PR
+	MergeQueueCIStarted MergeQueueEvent = "ci_started"
+	// MergeQueueCIFinished means CI finished running for the PR
+	MergeQueueCIFinished MergeQueueEvent = "ci_finished"
+	// MergeQueueMerged means the PR was merged
+	MergeQueueMerged MergeQueueEvent = "merged"
+	// MergeQueueFailed means the merge queue gave up on the PR
+	MergeQueueFailed MergeQueueEvent = "failed"
+	// MergeQueueClosed means the PR was closed without merging
+	MergeQueueClosed MergeQueueEvent = "closed"
+)
+
+// ValidMergeQueueEvents lists the events that can be recorded, in order
+var ValidMergeQueueEvents = []MergeQueueEvent{
+	MergeQueueEnqueued, MergeQueueCIStarted, MergeQueueCIFinished,
+	MergeQueueMerged, MergeQueueFailed, MergeQueueClosed,
+}
+
+// Valid reports whether e is one of ValidMergeQueueEvents
+func (e MergeQueueEvent) Valid() bool {
+	for _, valid := range ValidMergeQueueEvents {
+		if e == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// maxResolvedMergeQueueItems caps how many resolved items are kept per repo
+const maxResolvedMergeQueueItems = 200
+
+// MergeQueueItem tracks one branch/PR from hand-off until it is resolved
+type MergeQueueItem struct {
+	Branch       string          `json:"branch,omitempty"`
+	PRNumber     int             `json:"pr_number,omitempty"`
+	Worker       string          `json:"worker,omitempty"`
+	Priority     TaskPriority    `json:"priority,omitempty"` // Priority of the worker's task; higher priority items merge first
+	EnqueuedAt   time.Time       `json:"enqueued_at"`
+	CIStartedAt  time.Time       `json:"ci_started_at,omitempty"`
+	CIFinishedAt time.Time       `json:"ci_finished_at,omitempty"`
+	ResolvedAt   time.Time       `json:"resolved_at,omitempty"`
+	Outcome      MergeQueueEvent `json:"outcome,omitempty"` // merged, failed, or closed once resolved
+}
+
+// Pending reports whether the item is still waiting in the queue
+func (i MergeQueueItem) Pending() bool {
+	return i.ResolvedAt.IsZero()
+}
+
+// MergeQueueTotals are monotonic counters for a repository's merge queue.
+// They survive pruning of resolved items, so they can back Prometheus counters.
+type MergeQueueTotals struct {
+	Merged             int     `json:"merged"`
+	Failed             int     `json:"failed"`
+	Closed             int     `json:"closed"`
+	TimeInQueueSeconds float64 `json:"time_in_queue_seconds"` // Summed over resolved items
+	CIRuns             int     `json:"ci_runs"`               // Finished CI runs
+	CIWaitSeconds      float64 `json:"ci_wait_seconds"`       // Summed over finished CI runs
+}
+
 // Agent represents an agent's state
 type Agent struct {
-	Type            AgentType `json:"type"`
-	WorktreePath    string    `json:"worktree_path"`
-	TmuxWindow      string    `json:"tmux_window"`
-	SessionID       string    `json:"session_id"`
-	PID             int       `json:"pid"`
-	Task            string    `json:"task,omitempty"`           // Only for workers
-	Summary         string    `json:"summary,omitempty"`        // Brief summary of work done (workers only)
-	FailureReason   string    `json:"failure_reason,omitempty"` // Why the task failed (workers only)
-	CreatedAt       time.Time `json:"created_at"`
-	LastNudge       time.Time `json:"last_nudge,omitempty"`
-	ReadyForCleanup bool      `json:"ready_for_cleanup,omitempty"` // Only for workers
+	Type            AgentType        `json:"type"`
+	WorktreePath    string           `json:"worktree_path"`
+	TmuxWindow      string           `json:"tmux_window"`
+	SessionID       string           `json:"session_id"`
+	PID             int              `json:"pid"`
+	Task            string           `json:"task,omitempty"`           // Only for workers
+	Summary         string           `json:"summary,omitempty"`        // Brief summary of work done (workers only)
+	FailureReason   string           `json:"failure_reason,omitempty"` // Why the task failed (workers only)
+	CreatedAt       time.Time        `json:"created_at"`
+	LastNudge       time.Time        `json:"last_nudge,omitempty"`
+	ReadyForCleanup bool             `json:"ready_for_cleanup,omitempty"` // Only for workers
+	ReadOnly        bool             `json:"read_only,omitempty"`         // Worktree is read-only (reviewers)
+	Headless        bool             `json:"headless,omitempty"`          // Runs Claude as a daemon child process instead of in a tmux window
+	AllowedPaths    []string         `json:"allowed_paths,omitempty"`     // Overrides the repo's branch guard paths for this task
+	ScopePaths      []string         `json:"scope_paths,omitempty"`       // Monorepo sub-project (then included dirs) the task is scoped to
+	Labels          []string         `json:"labels,omitempty"`            // Free-form labels for filtering (e.g. "frontend")
+	HandoffFrom     string           `json:"handoff_from,omitempty"`      // Worker whose worktree this agent took over
+	Deadline        time.Time        `json:"deadline,omitempty"`          // Time-boxed workers must wrap up by this time
+	DeadlineStage   DeadlineStage    `json:"deadline_stage,omitempty"`    // How far deadline enforcement has progressed
+	ExternalHead    string           `json:"external_head,omitempty"`     // Last externally pushed branch head the agent was told about
+	PromptSHA256    string           `json:"prompt_sha256,omitempty"`     // Hash of the prompt the agent was spawned with (see its snapshot)
+	Model           string           `json:"model,omitempty"`             // Model from the launch template (empty: Claude's default)
+	RefreshConflict *RefreshConflict `json:"refresh_conflict,omitempty"`  // Last rebase onto main that conflicted
+	RefreshSkip     string           `json:"refresh_skip,omitempty"`      // Why the last refresh was skipped, as the agent was told
+	Base            string           `json:"base,omitempty"`              // Branch, tag, or commit the task builds on (empty: default branch)
+	BaseBranch      string           `json:"base_branch,omitempty"`       // Remote branch of Base, refreshed onto and targeted by PRs (empty for tags and commits)
+	Priority        TaskPriority     `json:"priority,omitempty"`          // Urgency of the task (empty: DefaultTaskPriority)
+	CwdDrift        string           `json:"cwd_drift,omitempty"`         // Directory outside the worktree the agent's pane was last seen in
+	Question        *PendingQuestion `json:"question,omitempty"`          // Question the agent asked that hasn't been answered
+	Restarts        int              `json:"restarts,omitempty"`          // Automatic restarts after crashes since the agent last ran steadily
+	LastRestart     time.Time        `json:"last_restart,omitempty"`      // When the agent was last restarted automatically
+	LastHeartbeat   time.Time        `json:"last_heartbeat,omitempty"`    // When the agent last said it was still working (multiclaude agent heartbeat)
+	PRURL           string           `json:"pr_url,omitempty"`            // Pull request the daemon opened when the worker completed
+	PRNumber        int              `json:"pr_number,omitempty"`         // Number of that pull request
+	CIHead          string           `json:"ci_head,omitempty"`           // Pushed branch head whose CI result was last reported
+	CIResult        string           `json:"ci_result,omitempty"`         // That result: "passed" or "failed"
+
+	// Scratch lists the extra worktrees the agent created with
+	// multiclaude agent scratch
+	Scratch []ScratchWorktree `json:"scratch,omitempty"`
+}
+
+// ScratchWorktree is an extra worktree an agent asked for next to its own,
+// for a spike or a bisect. It is removed with the agent.
+type ScratchWorktree struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Branch    string    `json:"branch,omitempty"` // Empty when the worktree is detached
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PendingQuestion is a question an agent asked a human. A reply to it, typed
+// into the agent's window, resolves it.
+type PendingQuestion struct {
+	Text       string    `json:"text"`
+	ResponseID string    `json:"response_id"` // Lets a reply name the question instead of the agent
+	AskedAt    time.Time `json:"asked_at"`
+}
+
+// ConflictResolution is the action a human chose for a refresh conflict
+type ConflictResolution string
+
+const (
+	// ConflictAssign asks the agent to rebase and resolve the conflict itself
+	ConflictAssign ConflictResolution = "assign"
+	// ConflictHelper opens a tmux window with the rebase stopped at the conflict
+	ConflictHelper ConflictResolution = "helper"
+	// ConflictSkip leaves the worktree alone until main moves again
+	ConflictSkip ConflictResolution = "skip"
+)
+
+// RefreshConflict records a rebase of a worker onto main that conflicted.
+// While main still points at OntoHead the daemon does not retry the rebase.
+type RefreshConflict struct {
+	Onto       string             `json:"onto"`      // Upstream the rebase was onto (e.g. origin/main)
+	OntoHead   string             `json:"onto_head"` // Commit Onto pointed at
+	Files      []string           `json:"files"`
+	DetectedAt time.Time          `json:"detected_at"`
+	Resolution ConflictResolution `json:"resolution,omitempty"` // Empty until someone picks an action
+	// Paused is set when the rebase was left stopped at the conflict in the
+	// worktree (conflict assist) instead of aborted
+	Paused bool `json:"paused,omitempty"`
+	// QuestionID is the response ID of the question asked about a paused
+	// conflict, answered once the worktree catches up
+	QuestionID string `json:"question_id,omitempty"`
+}
+
+// DeadlineStage records which deadline notices a time-boxed worker has received
+type DeadlineStage string
+
+const (
+	// DeadlineStageNone means no notice has been sent yet
+	DeadlineStageNone DeadlineStage = ""
+	// DeadlineStageWarned means the worker was warned that most of its budget is used
+	DeadlineStageWarned DeadlineStage = "warned"
+	// DeadlineStageExpired means the worker was told to wrap up
+	DeadlineStageExpired DeadlineStage = "expired"
+)
+
+// TaskPriority ranks how urgent a task is, from P0 (drop everything) to P3
+// (bulk work). It flows from the task to its agent's notifications, its
+// place in the merge queue, and how quickly it is considered stuck.
+type TaskPriority string
+
+const (
+	TaskPriorityP0 TaskPriority = "P0"
+	TaskPriorityP1 TaskPriority = "P1"
+	TaskPriorityP2 TaskPriority = "P2"
+	TaskPriorityP3 TaskPriority = "P3"
+)
+
+// DefaultTaskPriority is the priority of tasks created without one
+const DefaultTaskPriority = TaskPriorityP2
+
+// ParseTaskPriority parses a priority such as "P0" or "p1"
+func ParseTaskPriority(s string) (TaskPriority, error) {
+	p := TaskPriority(strings.ToUpper(strings.TrimSpace(s)))
+	switch p {
+	case TaskPriorityP0, TaskPriorityP1, TaskPriorityP2, TaskPriorityP3:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid priority %q (use P0, P1, P2, or P3)", s)
+}
+
+// taskPriorityNames are the names the task queue accepts for priorities
+var taskPriorityNames = map[string]TaskPriority{
+	"urgent": TaskPriorityP0,
+	"high":   TaskPriorityP1,
+	"normal": TaskPriorityP2,
+	"low":    TaskPriorityP3,
+}
+
+// ParseTaskPriorityName parses a priority given by name (urgent, high,
+// normal, or low) or as P0-P3
+func ParseTaskPriorityName(s string) (TaskPriority, error) {
+	if p, ok := taskPriorityNames[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return p, nil
+	}
+	if p, err := ParseTaskPriority(s); err == nil {
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid priority %q (use urgent, high, normal, low, or P0-P3)", s)
+}
+
+// Effective returns the priority, or DefaultTaskPriority if it is unset
+func (p TaskPriority) Effective() TaskPriority {
+	if p == "" {
+		return DefaultTaskPriority
+	}
+	return p
+}
+
+// Rank orders priorities for sorting: 0 for P0 up to 3 for P3
+func (p TaskPriority) Rank() int {
+	switch p.Effective() {
+	case TaskPriorityP0:
+		return 0
+	case TaskPriorityP1:
+		return 1
+	case TaskPriorityP3:
+		return 3
+	default:
+		return 2
+	}
 }
 
 // Repository represents a tracked repository's state
@@ -116,32 +824,133 @@ type Repository struct {
 	Agents           map[string]Agent   `json:"agents"`
 	TaskHistory      []TaskHistoryEntry `json:"task_history,omitempty"`
 	MergeQueueConfig MergeQueueConfig   `json:"merge_queue_config,omitempty"`
+	Groups           []string           `json:"groups,omitempty"` // Repo groups (e.g. "payments") for bulk commands
+	BranchGuard      BranchGuardConfig  `json:"branch_guard,omitempty"`
+	CommitPolicy     CommitPolicyConfig `json:"commit_policy,omitempty"`
+	MergeQueue       []MergeQueueItem   `json:"merge_queue,omitempty"`
+	MergeQueueTotals MergeQueueTotals   `json:"merge_queue_totals,omitempty"`
+	AutoAnswer       AutoAnswerConfig   `json:"auto_answer,omitempty"`
+	CloneFilter      string             `json:"clone_filter,omitempty"` // Partial clone filter used at init (e.g. "blob:none")
+	Mirror           string             `json:"mirror,omitempty"`       // Shared mirror the clone borrows objects from
+	MainHead         string             `json:"main_head,omitempty"`    // Last seen head of the remote default branch
+	HistoryRewrite   *HistoryRewrite    `json:"history_rewrite,omitempty"`
+	WarmPool         WarmPoolConfig     `json:"warm_pool,omitempty"`
+	WarmWorktrees    []WarmWorktree     `json:"warm_worktrees,omitempty"`
+	WindowReaper     WindowReaperConfig `json:"window_reaper,omitempty"`
+	Access           AccessPolicy       `json:"access,omitempty"`
+	DefaultBase      string             `json:"default_base,omitempty"` // Base for new workers without --base (empty: default branch)
+	Recovery         RecoveryConfig     `json:"recovery,omitempty"`
+	Stuck            StuckConfig        `json:"stuck,omitempty"`
+	Restart          RestartPolicy      `json:"restart,omitempty"`
+	Tasks            []QueuedTask       `json:"tasks,omitempty"`
+	TaskQueue        TaskQueueConfig    `json:"task_queue,omitempty"`
+	TmuxAlerts       bool               `json:"tmux_alerts,omitempty"`     // Ring the bell in an agent's window when it needs a human
+	ConflictAssist   bool               `json:"conflict_assist,omitempty"` // Leave conflicting refreshes paused for the worker to resolve
+	PushRemote       string             `json:"push_remote,omitempty"`     // Remote workers push to, e.g. a fork (empty: origin)
+	MaxWorkers       int                `json:"max_workers,omitempty"`     // Most workers running at once (0: no limit)
+	LFSSkip          []AgentType        `json:"lfs_skip,omitempty"`        // Agent types whose worktrees get Git LFS pointers instead of content
 }
 
-// State represents the entire daemon state
-type State struct {
+// DefaultPushRemote is the remote workers push to unless a repository sets
+// its own
+const DefaultPushRemote = "origin"
+
+// WorkerPushRemote returns the remote workers' branches are pushed to
+func (r *Repository) WorkerPushRemote() string {
+	if r.PushRemote == "" {
+		return DefaultPushRemote
+	}
+	return r.PushRemote
+}
+
+// LFSContentFor reports whether worktrees of agents of type t should have
+// Git LFS file content checked out
+func (r *Repository) LFSContentFor(t AgentType) bool {
+	for _, skip := range r.LFSSkip {
+		if skip == t {
+			return false
+		}
+	}
+	return true
+}
+
+// HistoryRewrite records a force-push to a repository's default branch.
+// While set, the daemon does not rebase worker worktrees onto main.
+type HistoryRewrite struct {
+	Branch     string    `json:"branch"`
+	OldHead    string    `json:"old_head"`
+	NewHead    string    `json:"new_head"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// persisted holds the fields of State saved to the state file, so restoring
+// a backup can replace all of them at once
+type persisted struct {
 	Repos       map[string]*Repository `json:"repos"`
 	CurrentRepo string                 `json:"current_repo,omitempty"`
-	mu          sync.RWMutex
-	path        string
+	SocketGroup string                 `json:"socket_group,omitempty"` // Unix group allowed to use the daemon socket
+	LogStorage  *logstore.Config       `json:"log_storage,omitempty"`  // Where rotated agent logs are kept (default: the output directory)
+	// ResponseIDs are the one-time reply IDs issued and not yet used, so
+	// replies to questions asked before a restart still get through
+	ResponseIDs map[string]ResponseTicket `json:"response_ids,omitempty"`
+}
+
+// ResponseTicket is an outstanding response ID: the agent a reply carrying
+// it goes to, and when it stops working
+type ResponseTicket struct {
+	Repo    string    `json:"repo"`
+	Agent   string    `json:"agent"`
+	Expires time.Time `json:"expires"`
+}
+
+// State represents the entire daemon state
+type State struct {
+	persisted
+	mu   sync.RWMutex
+	path string
+
+	// writeMu serializes writing the state file and rotating its backups
+	writeMu sync.Mutex
+	// restoredFrom is the backup Load fell back to, or 0
+	restoredFrom int
 }
 
 // New creates a new empty state
 func New(path string) *State {
 	return &State{
-		Repos: make(map[string]*Repository),
-		path:  path,
+		persisted: persisted{Repos: make(map[string]*Repository)},
+		path:      path,
 	}
 }
 
-// Load loads state from disk
+// Load loads state from disk. If the state file can't be read or parsed,
+// Load falls back to the newest valid backup Save kept (see RestoredFrom);
+// only when none is valid does it fail.
 func Load(path string) (*State, error) {
+	s, err := readStateFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// No state file, return empty state
+		return New(path), nil
+	}
+	if err == nil {
+		s.path = path
+		return s, nil
+	}
+
+	for n := 1; n <= BackupCount; n++ {
+		if backup, backupErr := readStateFile(backupPath(path, n)); backupErr == nil {
+			backup.path = path
+			backup.restoredFrom = n
+			return backup, nil
+		}
+	}
+	return nil, err
+}
+
+// readStateFile reads and parses a state file, or a backup of one
+func readStateFile(path string) (*State, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// No state file, return empty state
-			return New(path), nil
-		}
 		return nil, fmt.Errorf("failed to read state file: %w", err)
 	}
 
@@ -150,8 +959,6 @@ func Load(path string) (*State, error) {
 		return nil, fmt.Errorf("failed to parse state file: %w", err)
 	}
 
-	s.path = path
-
 	// Initialize map if nil
 	if s.Repos == nil {
 		s.Repos = make(map[string]*Repository)
@@ -172,8 +979,12 @@ func atomicWrite(path string, data []byte) error {
 	}
 	tmpPath := tmpFile.Name()
 
-	// Write data and close the file
+	// Write data, flush it to disk so the rename can't expose an empty file
+	// after a crash, and close the file
 	_, writeErr := tmpFile.Write(data)
+	if writeErr == nil {
+		writeErr = tmpFile.Sync()
+	}
 	closeErr := tmpFile.Close()
 
 	// Check for write or close errors
@@ -192,7 +1003,8 @@ func atomicWrite(path string, data []byte) error {
 		return fmt.Errorf("failed to rename state file: %w", err)
 	}
 
-	return nil
+	// Make the rename itself durable
+	return syncDir(dir)
 }
 
 // Save persists state to disk
@@ -205,7 +1017,7 @@ func (s *State) Save() error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	return atomicWrite(s.path, data)
+	return s.write(data, false)
 }
 
 // AddRepo adds a new repository to the state
@@ -292,6 +1104,63 @@ func (s *State) GetCurrentRepo() string {
 	return s.CurrentRepo
 }
 
+// SetSocketGroup sets the Unix group allowed to use the daemon socket
+// (empty: only the daemon's user)
+func (s *State) SetSocketGroup(group string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.SocketGroup = group
+	return s.saveUnlocked()
+}
+
+// SetResponseIDs replaces the outstanding response IDs
+func (s *State) SetResponseIDs(tickets map[string]ResponseTicket) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ResponseIDs = tickets
+	return s.saveUnlocked()
+}
+
+// GetResponseIDs returns a copy of the outstanding response IDs
+func (s *State) GetResponseIDs() map[string]ResponseTicket {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tickets := make(map[string]ResponseTicket, len(s.ResponseIDs))
+	for id, ticket := range s.ResponseIDs {
+		tickets[id] = ticket
+	}
+	return tickets
+}
+
+// GetSocketGroup returns the Unix group allowed to use the daemon socket
+func (s *State) GetSocketGroup() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.SocketGroup
+}
+
+// SetLogStorage sets where rotated agent logs are kept
+func (s *State) SetLogStorage(cfg logstore.Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.LogStorage = &cfg
+	return s.saveUnlocked()
+}
+
+// GetLogStorage returns where rotated agent logs are kept
+func (s *State) GetLogStorage() logstore.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.LogStorage == nil {
+		return logstore.Config{}
+	}
+	return *s.LogStorage
+}
+
 // ClearCurrentRepo clears the current/default repository
 func (s *State) ClearCurrentRepo() error {
 	s.mu.Lock()
@@ -316,6 +1185,57 @@ func (s *State) GetAllRepos() map[string]*Repository {
 			TmuxSession:      repo.TmuxSession,
 			Agents:           make(map[string]Agent, len(repo.Agents)),
 			MergeQueueConfig: repo.MergeQueueConfig,
+			CloneFilter:      repo.CloneFilter,
+			Mirror:           repo.Mirror,
+			MainHead:         repo.MainHead,
+			DefaultBase:      repo.DefaultBase,
+			TmuxAlerts:       repo.TmuxAlerts,
+			ConflictAssist:   repo.ConflictAssist,
+			PushRemote:       repo.PushRemote,
+			MaxWorkers:       repo.MaxWorkers,
+		}
+		if repo.HistoryRewrite != nil {
+			rewrite := *repo.HistoryRewrite
+			repoCopy.HistoryRewrite = &rewrite
+		}
+		repoCopy.CommitPolicy = repo.CommitPolicy
+		repoCopy.WarmPool = repo.WarmPool
+		repoCopy.Access = repo.Access.clone()
+		repoCopy.WindowReaper = repo.WindowReaper
+		repoCopy.Recovery = repo.Recovery
+		repoCopy.Stuck = repo.Stuck.clone()
+		repoCopy.Restart = repo.Restart
+		repoCopy.TaskQueue = repo.TaskQueue
+		if repo.MergeQueueConfig.RequiredChecks != nil {
+			repoCopy.MergeQueueConfig.RequiredChecks = make([]string, len(repo.MergeQueueConfig.RequiredChecks))
+			copy(repoCopy.MergeQueueConfig.RequiredChecks, repo.MergeQueueConfig.RequiredChecks)
+		}
+		if repo.Tasks != nil {
+			repoCopy.Tasks = make([]QueuedTask, len(repo.Tasks))
+			copy(repoCopy.Tasks, repo.Tasks)
+		}
+		if repo.LFSSkip != nil {
+			repoCopy.LFSSkip = make([]AgentType, len(repo.LFSSkip))
+			copy(repoCopy.LFSSkip, repo.LFSSkip)
+		}
+		if repo.WindowReaper.Keep != nil {
+			repoCopy.WindowReaper.Keep = make([]string, len(repo.WindowReaper.Keep))
+			copy(repoCopy.WindowReaper.Keep, repo.WindowReaper.Keep)
+		}
+		if repo.WarmWorktrees != nil {
+			repoCopy.WarmWorktrees = make([]WarmWorktree, len(repo.WarmWorktrees))
+			copy(repoCopy.WarmWorktrees, repo.WarmWorktrees)
+		}
+		// Copy branch guard config
+		repoCopy.BranchGuard = repo.BranchGuard
+		if repo.BranchGuard.AllowedPaths != nil {
+			repoCopy.BranchGuard.AllowedPaths = make([]string, len(repo.BranchGuard.AllowedPaths))
+			copy(repoCopy.BranchGuard.AllowedPaths, repo.BranchGuard.AllowedPaths)
+		}
+		// Copy groups
+		if repo.Groups != nil {
+			repoCopy.Groups = make([]string, len(repo.Groups))
+			copy(repoCopy.Groups, repo.Groups)
 		}
 		// Copy agents
 		for agentName, agent := range repo.Agents {
@@ -326,7 +1246,19 @@ func (s *State) GetAllRepos() map[string]*Repository {
 			repoCopy.TaskHistory = make([]TaskHistoryEntry, len(repo.TaskHistory))
 			copy(repoCopy.TaskHistory, repo.TaskHistory)
 		}
-		repos[name] = repoCopy
+		// Copy merge queue ledger
+		if repo.MergeQueue != nil {
+			repoCopy.MergeQueue = make([]MergeQueueItem, len(repo.MergeQueue))
+			copy(repoCopy.MergeQueue, repo.MergeQueue)
+		}
+		repoCopy.MergeQueueTotals = repo.MergeQueueTotals
+		// Copy auto-answer config
+		repoCopy.AutoAnswer = repo.AutoAnswer
+		if repo.AutoAnswer.Rules != nil {
+			repoCopy.AutoAnswer.Rules = make([]AutoAnswerRule, len(repo.AutoAnswer.Rules))
+			copy(repoCopy.AutoAnswer.Rules, repo.AutoAnswer.Rules)
+		}
+		repos[name] = repoCopy
 	}
 	return repos
 }
@@ -387,6 +1319,27 @@ func (s *State) UpdateAgentPID(repoName, agentName string, pid int) error {
 	return s.saveUnlocked()
 }
 
+// UpdateAgentDeadlineStage updates just the deadline stage of an agent, so
+// deadline enforcement doesn't overwrite changes made to the agent meanwhile
+func (s *State) UpdateAgentDeadlineStage(repoName, agentName string, stage DeadlineStage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	agent, exists := repo.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("agent %q not found in repository %q", agentName, repoName)
+	}
+
+	agent.DeadlineStage = stage
+	repo.Agents[agentName] = agent
+	return s.saveUnlocked()
+}
+
 // RemoveAgent removes an agent from a repository
 func (s *State) RemoveAgent(repoName, agentName string) error {
 	s.mu.Lock()
@@ -463,6 +1416,369 @@ func (s *State) UpdateMergeQueueConfig(repoName string, config MergeQueueConfig)
 	return s.saveUnlocked()
 }
 
+// GetBranchGuardConfig returns the branch guard config for a repository
+func (s *State) GetBranchGuardConfig(repoName string) (BranchGuardConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return BranchGuardConfig{}, fmt.Errorf("repository %q not found", repoName)
+	}
+	config := repo.BranchGuard
+	config.AllowedPaths = append([]string(nil), repo.BranchGuard.AllowedPaths...)
+	return config, nil
+}
+
+// UpdateBranchGuardConfig updates the branch guard config for a repository
+func (s *State) UpdateBranchGuardConfig(repoName string, config BranchGuardConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.BranchGuard = config
+	return s.saveUnlocked()
+}
+
+// UpdateCommitPolicy updates the commit message policy for a repository
+func (s *State) UpdateCommitPolicy(repoName string, policy CommitPolicyConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.CommitPolicy = policy
+	return s.saveUnlocked()
+}
+
+// GetAutoAnswerConfig returns the auto-answer config for a repository
+func (s *State) GetAutoAnswerConfig(repoName string) (AutoAnswerConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return AutoAnswerConfig{}, fmt.Errorf("repository %q not found", repoName)
+	}
+	config := repo.AutoAnswer
+	config.Rules = append([]AutoAnswerRule(nil), repo.AutoAnswer.Rules...)
+	return config, nil
+}
+
+// UpdateAutoAnswerConfig updates the auto-answer config for a repository
+func (s *State) UpdateAutoAnswerConfig(repoName string, config AutoAnswerConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.AutoAnswer = config
+	return s.saveUnlocked()
+}
+
+// SetMainHead records the last seen head of a repository's remote default branch
+func (s *State) SetMainHead(repoName, head string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.MainHead = head
+	return s.saveUnlocked()
+}
+
+// SetHistoryRewrite records (or, with nil, clears) a force-push to a
+// repository's default branch
+func (s *State) SetHistoryRewrite(repoName string, rewrite *HistoryRewrite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.HistoryRewrite = rewrite
+	return s.saveUnlocked()
+}
+
+// SetDefaultBase sets the base new workers of a repository build on
+func (s *State) SetDefaultBase(repoName, base string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.DefaultBase = base
+	return s.saveUnlocked()
+}
+
+// UpdateRecoveryConfig updates the interrupted rebase/merge recovery config for a repository
+func (s *State) UpdateRecoveryConfig(repoName string, config RecoveryConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.Recovery = config
+	return s.saveUnlocked()
+}
+
+// UpdateStuckConfig updates the idle stuck-detection config for a repository
+func (s *State) UpdateStuckConfig(repoName string, config StuckConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.Stuck = config
+	return s.saveUnlocked()
+}
+
+// UpdateRestartPolicy updates the automatic restart policy for a repository
+func (s *State) UpdateRestartPolicy(repoName string, policy RestartPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.Restart = policy
+	return s.saveUnlocked()
+}
+
+// UpdateTmuxAlerts turns tmux bell alerts on or off for a repository
+func (s *State) UpdateTmuxAlerts(repoName string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.TmuxAlerts = enabled
+	return s.saveUnlocked()
+}
+
+// SetTmuxSession records the name of a repository's tmux session, after the
+// daemon renames it
+func (s *State) SetTmuxSession(repoName, session string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.TmuxSession = session
+	return s.saveUnlocked()
+}
+
+// UpdateConflictAssist turns conflict assist on or off for a repository
+func (s *State) UpdateConflictAssist(repoName string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.ConflictAssist = enabled
+	return s.saveUnlocked()
+}
+
+// UpdatePushRemote sets the remote workers push to in a repository (empty:
+// DefaultPushRemote)
+func (s *State) UpdatePushRemote(repoName, remote string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.PushRemote = remote
+	return s.saveUnlocked()
+}
+
+// UpdateMaxWorkers sets how many workers may run at once in a repository
+// (0: no limit)
+func (s *State) UpdateMaxWorkers(repoName string, max int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.MaxWorkers = max
+	return s.saveUnlocked()
+}
+
+// UpdateLFSSkip sets the agent types whose worktrees skip Git LFS content
+func (s *State) UpdateLFSSkip(repoName string, types []AgentType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.LFSSkip = types
+	return s.saveUnlocked()
+}
+
+// UpdateWarmPoolConfig updates the warm worktree pool config for a repository
+func (s *State) UpdateWarmPoolConfig(repoName string, config WarmPoolConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.WarmPool = config
+	return s.saveUnlocked()
+}
+
+// UpdateWindowReaperConfig updates the zombie window reaper config for a repository
+func (s *State) UpdateWindowReaperConfig(repoName string, config WindowReaperConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.WindowReaper = config
+	return s.saveUnlocked()
+}
+
+// UpdateAccessPolicy replaces who may act on a repository
+func (s *State) UpdateAccessPolicy(repoName string, policy AccessPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.Access = policy
+	return s.saveUnlocked()
+}
+
+// AddWarmWorktree adds a ready worktree to a repository's warm pool
+func (s *State) AddWarmWorktree(repoName string, wt WarmWorktree) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.WarmWorktrees = append(repo.WarmWorktrees, wt)
+	return s.saveUnlocked()
+}
+
+// TakeWarmWorktree removes and returns the oldest worktree in a repository's
+// warm pool. It returns false if the pool is empty.
+func (s *State) TakeWarmWorktree(repoName string) (WarmWorktree, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return WarmWorktree{}, false, fmt.Errorf("repository %q not found", repoName)
+	}
+	if len(repo.WarmWorktrees) == 0 {
+		return WarmWorktree{}, false, nil
+	}
+
+	wt := repo.WarmWorktrees[0]
+	repo.WarmWorktrees = repo.WarmWorktrees[1:]
+	if len(repo.WarmWorktrees) == 0 {
+		repo.WarmWorktrees = nil
+	}
+	return wt, true, s.saveUnlocked()
+}
+
+// SetRepoGroups replaces the groups a repository belongs to
+func (s *State) SetRepoGroups(repoName string, groups []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	if len(groups) == 0 {
+		repo.Groups = nil
+	} else {
+		repo.Groups = append([]string(nil), groups...)
+	}
+	return s.saveUnlocked()
+}
+
+// InGroup reports whether the repository belongs to the given group
+func (r *Repository) InGroup(group string) bool {
+	for _, g := range r.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// ReposInGroup returns the sorted names of repositories in a group
+func (s *State) ReposInGroup(group string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var names []string
+	for name, repo := range s.Repos {
+		if repo.InGroup(group) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // AddTaskHistory adds a completed task to the repository's history
 func (s *State) AddTaskHistory(repoName string, entry TaskHistoryEntry) error {
 	s.mu.Lock()
@@ -477,6 +1793,91 @@ func (s *State) AddTaskHistory(repoName string, entry TaskHistoryEntry) error {
 	return s.saveUnlocked()
 }
 
+// AddQueuedTask appends a task to a repository's task queue, dropping the
+// oldest finished tasks beyond maxFinishedTasks
+func (s *State) AddQueuedTask(repoName string, task QueuedTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	tasks := append(repo.Tasks, task)
+	finished := 0
+	for _, t := range tasks {
+		if t.State.Finished() {
+			finished++
+		}
+	}
+	kept := tasks[:0]
+	for _, t := range tasks {
+		if t.State.Finished() && finished > maxFinishedTasks {
+			finished--
+			continue
+		}
+		kept = append(kept, t)
+	}
+	repo.Tasks = kept
+	return s.saveUnlocked()
+}
+
+// UpdateQueuedTask applies update to the queued task with the given ID
+func (s *State) UpdateQueuedTask(repoName, id string, update func(*QueuedTask)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for i := range repo.Tasks {
+		if repo.Tasks[i].ID == id {
+			update(&repo.Tasks[i])
+			return s.saveUnlocked()
+		}
+	}
+	return fmt.Errorf("task %q not found in repository %q", id, repoName)
+}
+
+// RemoveQueuedTask removes a task from a repository's task queue
+func (s *State) RemoveQueuedTask(repoName, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	for i, t := range repo.Tasks {
+		if t.ID == id {
+			repo.Tasks = append(repo.Tasks[:i], repo.Tasks[i+1:]...)
+			if len(repo.Tasks) == 0 {
+				repo.Tasks = nil
+			}
+			return s.saveUnlocked()
+		}
+	}
+	return fmt.Errorf("task %q not found in repository %q", id, repoName)
+}
+
+// UpdateTaskQueueConfig updates how a repository's task queue is dispatched
+func (s *State) UpdateTaskQueueConfig(repoName string, config TaskQueueConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return fmt.Errorf("repository %q not found", repoName)
+	}
+
+	repo.TaskQueue = config
+	return s.saveUnlocked()
+}
+
 // GetTaskHistory returns the task history for a repository, optionally limited to N entries
 func (s *State) GetTaskHistory(repoName string, limit int) ([]TaskHistoryEntry, error) {
 	s.mu.RLock()
@@ -533,6 +1934,112 @@ func (s *State) UpdateTaskHistoryStatus(repoName, taskName string, status TaskSt
 	return fmt.Errorf("task %q not found in history", taskName)
 }
 
+// RecordMergeQueueEvent applies an event to the pending merge queue item that
+// matches key's PR number or branch, creating the item if none is pending.
+// Worker, branch, and PR number from key fill in whatever the item lacks.
+func (s *State) RecordMergeQueueEvent(repoName string, key MergeQueueItem, event MergeQueueEvent, at time.Time) (MergeQueueItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo, exists := s.Repos[repoName]
+	if !exists {
+		return MergeQueueItem{}, fmt.Errorf("repository %q not found", repoName)
+	}
+	if key.Branch == "" && key.PRNumber <= 0 {
+		return MergeQueueItem{}, fmt.Errorf("a branch or PR number is required")
+	}
+	if !event.Valid() {
+		return MergeQueueItem{}, fmt.Errorf("unknown merge queue event %q", event)
+	}
+
+	idx := -1
+	for i := len(repo.MergeQueue) - 1; i >= 0; i-- {
+		item := repo.MergeQueue[i]
+		if !item.Pending() {
+			continue
+		}
+		if (key.PRNumber > 0 && item.PRNumber == key.PRNumber) || (key.Branch != "" && item.Branch == key.Branch) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		repo.MergeQueue = append(repo.MergeQueue, MergeQueueItem{EnqueuedAt: at})
+		idx = len(repo.MergeQueue) - 1
+	}
+
+	item := &repo.MergeQueue[idx]
+	if item.Branch == "" {
+		item.Branch = key.Branch
+	}
+	if item.PRNumber <= 0 {
+		item.PRNumber = key.PRNumber
+	}
+	if item.Worker == "" {
+		item.Worker = key.Worker
+	}
+	if key.Priority != "" {
+		item.Priority = key.Priority
+	}
+
+	totals := &repo.MergeQueueTotals
+	switch event {
+	case MergeQueueEnqueued:
+		// Re-enqueueing a pending item keeps its original enqueue time
+	case MergeQueueCIStarted:
+		item.CIStartedAt = at
+		item.CIFinishedAt = time.Time{}
+	case MergeQueueCIFinished:
+		start := item.CIStartedAt
+		if start.IsZero() {
+			start = item.EnqueuedAt
+		}
+		item.CIFinishedAt = at
+		totals.CIRuns++
+		totals.CIWaitSeconds += at.Sub(start).Seconds()
+	case MergeQueueMerged, MergeQueueFailed, MergeQueueClosed:
+		item.ResolvedAt = at
+		item.Outcome = event
+		totals.TimeInQueueSeconds += at.Sub(item.EnqueuedAt).Seconds()
+		switch event {
+		case MergeQueueMerged:
+			totals.Merged++
+		case MergeQueueFailed:
+			totals.Failed++
+		default:
+			totals.Closed++
+		}
+	}
+
+	recorded := *item
+	repo.MergeQueue = pruneMergeQueue(repo.MergeQueue)
+	return recorded, s.saveUnlocked()
+}
+
+// pruneMergeQueue drops the oldest resolved items beyond maxResolvedMergeQueueItems
+func pruneMergeQueue(items []MergeQueueItem) []MergeQueueItem {
+	resolved := 0
+	for _, item := range items {
+		if !item.Pending() {
+			resolved++
+		}
+	}
+	drop := resolved - maxResolvedMergeQueueItems
+	if drop <= 0 {
+		return items
+	}
+
+	kept := make([]MergeQueueItem, 0, len(items)-drop)
+	for _, item := range items {
+		if drop > 0 && !item.Pending() {
+			drop--
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
+
 // UpdateTaskHistorySummary updates the summary and failure reason for a task by name
 func (s *State) UpdateTaskHistorySummary(repoName, taskName, summary, failureReason string) error {
 	s.mu.Lock()
@@ -568,5 +2075,5 @@ func (s *State) saveUnlocked() error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	return atomicWrite(s.path, data)
+	return s.write(data, false)
 }
@@ -1486,6 +1486,33 @@ func TestUpdateAgentPID(t *testing.T) {
 	}
 }
 
+func TestUpdateAgentDeadlineStage(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	deadline := time.Now().Add(time.Hour)
+	if err := s.AddAgent("test-repo", "worker", Agent{Type: AgentTypeWorker, PID: 1, Deadline: deadline}); err != nil {
+		t.Fatalf("AddAgent() failed: %v", err)
+	}
+
+	// A change made by someone else survives the stage update
+	if err := s.UpdateAgentPID("test-repo", "worker", 2); err != nil {
+		t.Fatalf("UpdateAgentPID() failed: %v", err)
+	}
+	if err := s.UpdateAgentDeadlineStage("test-repo", "worker", DeadlineStageWarned); err != nil {
+		t.Fatalf("UpdateAgentDeadlineStage() failed: %v", err)
+	}
+	updated, _ := s.GetAgent("test-repo", "worker")
+	if updated.DeadlineStage != DeadlineStageWarned || updated.PID != 2 {
+		t.Errorf("agent = %+v, want stage warned and PID 2", updated)
+	}
+
+	if err := s.UpdateAgentDeadlineStage("test-repo", "nonexistent", DeadlineStageExpired); err == nil {
+		t.Error("UpdateAgentDeadlineStage should fail for nonexistent agent")
+	}
+}
+
 func TestUpdateTaskHistorySummary(t *testing.T) {
 	tmpDir := t.TempDir()
 	statePath := filepath.Join(tmpDir, "state.json")
@@ -1572,3 +1599,360 @@ func TestAgentTypeIsPersistent(t *testing.T) {
 		})
 	}
 }
+
+func TestRepoGroups(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	s := New(statePath)
+
+	for _, name := range []string{"web", "api", "billing"} {
+		if err := s.AddRepo(name, &Repository{Agents: make(map[string]Agent)}); err != nil {
+			t.Fatalf("AddRepo() failed: %v", err)
+		}
+	}
+
+	if err := s.SetRepoGroups("billing", []string{"payments"}); err != nil {
+		t.Fatalf("SetRepoGroups() failed: %v", err)
+	}
+	if err := s.SetRepoGroups("api", []string{"payments", "backend"}); err != nil {
+		t.Fatalf("SetRepoGroups() failed: %v", err)
+	}
+	if err := s.SetRepoGroups("missing", []string{"payments"}); err == nil {
+		t.Error("SetRepoGroups() should fail for unknown repo")
+	}
+
+	got := s.ReposInGroup("payments")
+	if len(got) != 2 || got[0] != "api" || got[1] != "billing" {
+		t.Errorf("ReposInGroup(payments) = %v, want [api billing]", got)
+	}
+
+	// Groups survive a reload and are copied by GetAllRepos
+	loaded, err := Load(statePath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repos := loaded.GetAllRepos()
+	if !repos["api"].InGroup("backend") {
+		t.Errorf("api groups after reload = %v", repos["api"].Groups)
+	}
+	repos["api"].Groups[0] = "mutated"
+	if !loaded.GetAllRepos()["api"].InGroup("payments") {
+		t.Error("GetAllRepos() should return a copy of Groups")
+	}
+
+	if err := s.SetRepoGroups("api", nil); err != nil {
+		t.Fatalf("SetRepoGroups() failed: %v", err)
+	}
+	if got := s.ReposInGroup("backend"); len(got) != 0 {
+		t.Errorf("ReposInGroup(backend) = %v after clearing", got)
+	}
+}
+
+func TestRecordMergeQueueEvent(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	if err := s.AddRepo("repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	record := func(key MergeQueueItem, event MergeQueueEvent, offset time.Duration) MergeQueueItem {
+		t.Helper()
+		item, err := s.RecordMergeQueueEvent("repo", key, event, start.Add(offset))
+		if err != nil {
+			t.Fatalf("RecordMergeQueueEvent(%s) failed: %v", event, err)
+		}
+		return item
+	}
+
+	record(MergeQueueItem{Branch: "work/a", Worker: "a", Priority: TaskPriorityP1}, MergeQueueEnqueued, 0)
+	// The PR number attaches to the pending item found by branch
+	record(MergeQueueItem{Branch: "work/a", PRNumber: 7}, MergeQueueCIStarted, 10*time.Minute)
+	record(MergeQueueItem{PRNumber: 7}, MergeQueueCIFinished, 25*time.Minute)
+	item := record(MergeQueueItem{PRNumber: 7}, MergeQueueMerged, time.Hour)
+	if item.Worker != "a" || item.Branch != "work/a" || item.Priority != TaskPriorityP1 || item.Outcome != MergeQueueMerged {
+		t.Errorf("unexpected resolved item: %+v", item)
+	}
+
+	// An event for an unknown PR starts a new item
+	record(MergeQueueItem{PRNumber: 8}, MergeQueueFailed, 2*time.Hour)
+	record(MergeQueueItem{Branch: "work/b"}, MergeQueueEnqueued, 3*time.Hour)
+
+	repo, _ := s.GetRepo("repo")
+	if len(repo.MergeQueue) != 3 {
+		t.Fatalf("expected 3 items, got %+v", repo.MergeQueue)
+	}
+	totals := repo.MergeQueueTotals
+	if totals.Merged != 1 || totals.Failed != 1 || totals.CIRuns != 1 {
+		t.Errorf("unexpected totals: %+v", totals)
+	}
+	if totals.CIWaitSeconds != (15 * time.Minute).Seconds() {
+		t.Errorf("CIWaitSeconds = %v, want 900", totals.CIWaitSeconds)
+	}
+	if totals.TimeInQueueSeconds != time.Hour.Seconds() {
+		t.Errorf("TimeInQueueSeconds = %v, want 3600", totals.TimeInQueueSeconds)
+	}
+
+	if _, err := s.RecordMergeQueueEvent("repo", MergeQueueItem{Branch: "x"}, "bogus", start); err == nil {
+		t.Error("unknown event should fail")
+	}
+	if _, err := s.RecordMergeQueueEvent("repo", MergeQueueItem{}, MergeQueueMerged, start); err == nil {
+		t.Error("event without a branch or PR should fail")
+	}
+}
+
+func TestParseTaskPriority(t *testing.T) {
+	for input, want := range map[string]TaskPriority{"P0": TaskPriorityP0, "p1": TaskPriorityP1, " P3 ": TaskPriorityP3} {
+		got, err := ParseTaskPriority(input)
+		if err != nil || got != want {
+			t.Errorf("ParseTaskPriority(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "P4", "high"} {
+		if _, err := ParseTaskPriority(input); err == nil {
+			t.Errorf("ParseTaskPriority(%q) should fail", input)
+		}
+	}
+
+	var unset TaskPriority
+	if unset.Effective() != DefaultTaskPriority || unset.Rank() != TaskPriorityP2.Rank() {
+		t.Errorf("unset priority should act as %s", DefaultTaskPriority)
+	}
+	if TaskPriorityP0.Rank() >= TaskPriorityP1.Rank() || TaskPriorityP2.Rank() >= TaskPriorityP3.Rank() {
+		t.Error("ranks should order P0 before P3")
+	}
+}
+
+func TestPruneMergeQueue(t *testing.T) {
+	var items []MergeQueueItem
+	for i := 0; i < maxResolvedMergeQueueItems+5; i++ {
+		items = append(items, MergeQueueItem{PRNumber: i + 1, ResolvedAt: time.Now()})
+	}
+	items = append(items, MergeQueueItem{PRNumber: 9999})
+
+	pruned := pruneMergeQueue(items)
+	if len(pruned) != maxResolvedMergeQueueItems+1 {
+		t.Fatalf("len = %d, want %d", len(pruned), maxResolvedMergeQueueItems+1)
+	}
+	if pruned[0].PRNumber != 6 || !pruned[len(pruned)-1].Pending() {
+		t.Errorf("pruning should drop the oldest resolved items and keep pending ones")
+	}
+}
+
+func TestAccessPolicy(t *testing.T) {
+	policy := AccessPolicy{
+		Spawn:  []string{"*"},
+		Remove: []string{"alice", "@release"},
+	}
+
+	tests := []struct {
+		perm   Permission
+		user   string
+		groups []string
+		want   bool
+	}{
+		{PermSpawn, "intern", nil, true},
+		{PermRemove, "alice", nil, true},
+		{PermRemove, "bob", []string{"dev", "release"}, true},
+		{PermRemove, "intern", []string{"dev"}, false},
+		{PermMerge, "intern", nil, true}, // unrestricted
+	}
+	for _, tt := range tests {
+		if got := policy.Allows(tt.perm, tt.user, tt.groups); got != tt.want {
+			t.Errorf("Allows(%s, %s, %v) = %v, want %v", tt.perm, tt.user, tt.groups, got, tt.want)
+		}
+	}
+
+	tmpDir := t.TempDir()
+	s := New(filepath.Join(tmpDir, "state.json"))
+	if err := s.AddRepo("release", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s.UpdateAccessPolicy("release", policy); err != nil {
+		t.Fatalf("UpdateAccessPolicy() failed: %v", err)
+	}
+	if err := s.UpdateAccessPolicy("missing", policy); err == nil {
+		t.Error("UpdateAccessPolicy() should fail for unknown repo")
+	}
+
+	repos := s.GetAllRepos()
+	repos["release"].Access.Remove[0] = "mutated"
+	if got := s.GetAllRepos()["release"].Access.Remove[0]; got != "alice" {
+		t.Errorf("GetAllRepos() should return a copy of the access policy, got %q", got)
+	}
+}
+
+func TestUpdateLFSSkip(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s.UpdateLFSSkip("test-repo", []AgentType{AgentTypeReview}); err != nil {
+		t.Fatalf("UpdateLFSSkip() failed: %v", err)
+	}
+
+	loaded, err := Load(s.path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ := loaded.GetRepo("test-repo")
+	if repo.LFSContentFor(AgentTypeReview) {
+		t.Error("review agents should skip LFS content")
+	}
+	if !repo.LFSContentFor(AgentTypeWorker) {
+		t.Error("workers should get LFS content")
+	}
+	if err := s.UpdateLFSSkip("missing", nil); err == nil {
+		t.Error("UpdateLFSSkip() should fail for an unknown repo")
+	}
+}
+
+func TestStuckConfigIdleThreshold(t *testing.T) {
+	var cfg StuckConfig
+	for _, spec := range []string{"label:docs=45m", "task:Failing Test=5m", "scope:services/api/=20m"} {
+		rule, err := ParseStuckRule(spec)
+		if err != nil {
+			t.Fatalf("ParseStuckRule(%q) failed: %v", spec, err)
+		}
+		cfg.Rules = append(cfg.Rules, rule)
+	}
+	quiet, err := ParseQuietRule("Compiling|go build=1h")
+	if err != nil {
+		t.Fatalf("ParseQuietRule failed: %v", err)
+	}
+	cfg.Quiet = []QuietRule{quiet}
+
+	// Without IdleMinutes only agents a rule matches are reported
+	if got := cfg.IdleThreshold(Agent{Task: "Refactor auth"}, ""); got != 0 {
+		t.Errorf("unmatched agent threshold = %v, want 0", got)
+	}
+	if got := cfg.IdleThreshold(Agent{Task: "fix a failing test"}, ""); got != 5*time.Minute {
+		t.Errorf("matched agent threshold = %v, want 5m", got)
+	}
+	cfg.IdleMinutes = 10
+	for _, tc := range []struct {
+		agent    Agent
+		lastLine string
+		want     time.Duration
+	}{
+		{Agent{Task: "Refactor auth"}, "", 10 * time.Minute},
+		{Agent{Task: "Write docs", Labels: []string{"docs"}}, "", 45 * time.Minute},
+		{Agent{Task: "Fix the failing test in api"}, "", 5 * time.Minute},
+		{Agent{ScopePaths: []string{"services/api", "libs"}}, "", 20 * time.Minute},
+		{Agent{Task: "Refactor auth"}, "   Compiling serde v1.0", time.Hour},
+	} {
+		if got := cfg.IdleThreshold(tc.agent, tc.lastLine); got != tc.want {
+			t.Errorf("IdleThreshold(%+v, %q) = %v, want %v", tc.agent, tc.lastLine, got, tc.want)
+		}
+	}
+	if rule := cfg.Rules[0].String(); rule != "label:docs=45m" {
+		t.Errorf("String() = %q", rule)
+	}
+
+	for _, spec := range []string{"docs=45m", "label:docs", "label:docs=30s", "owner:bob=5m", "label:=5m"} {
+		if _, err := ParseStuckRule(spec); err == nil {
+			t.Errorf("ParseStuckRule(%q) should fail", spec)
+		}
+	}
+	if _, err := ParseQuietRule("(unclosed=5m"); err == nil {
+		t.Error("an invalid quiet pattern should fail")
+	}
+}
+
+func TestUpdateRestartPolicy(t *testing.T) {
+	if got := (RestartPolicy{}).Limit(); got != DefaultMaxRestarts {
+		t.Errorf("default Limit() = %d, want %d", got, DefaultMaxRestarts)
+	}
+
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+	if err := s.UpdateRestartPolicy("test-repo", RestartPolicy{MaxRestarts: 5}); err != nil {
+		t.Fatalf("UpdateRestartPolicy() failed: %v", err)
+	}
+
+	loaded, err := Load(s.path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	repo, _ := loaded.GetRepo("test-repo")
+	if repo.Restart.Off || repo.Restart.Limit() != 5 {
+		t.Errorf("Restart = %+v, want a limit of 5", repo.Restart)
+	}
+	if got := loaded.GetAllRepos()["test-repo"].Restart.Limit(); got != 5 {
+		t.Errorf("GetAllRepos() Limit() = %d, want 5", got)
+	}
+	if err := s.UpdateRestartPolicy("missing", RestartPolicy{}); err == nil {
+		t.Error("UpdateRestartPolicy() should fail for an unknown repo")
+	}
+}
+
+func TestQueuedTasks(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	if err := s.AddRepo("test-repo", &Repository{Agents: make(map[string]Agent)}); err != nil {
+		t.Fatalf("AddRepo() failed: %v", err)
+	}
+
+	// Finished tasks beyond the limit are dropped oldest first; waiting ones never are
+	for i := 0; i < maxFinishedTasks+2; i++ {
+		if err := s.AddQueuedTask("test-repo", QueuedTask{ID: fmt.Sprintf("done-%d", i), State: QueuedTaskDone}); err != nil {
+			t.Fatalf("AddQueuedTask() failed: %v", err)
+		}
+	}
+	if err := s.AddQueuedTask("test-repo", QueuedTask{ID: "next", State: QueuedTaskQueued, Priority: TaskPriorityP1}); err != nil {
+		t.Fatalf("AddQueuedTask() failed: %v", err)
+	}
+	if err := s.UpdateQueuedTask("test-repo", "next", func(task *QueuedTask) {
+		task.State = QueuedTaskAssigned
+		task.Agent = "fox"
+	}); err != nil {
+		t.Fatalf("UpdateQueuedTask() failed: %v", err)
+	}
+
+	loaded, err := Load(s.path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	tasks := loaded.GetAllRepos()["test-repo"].Tasks
+	if len(tasks) != maxFinishedTasks+1 || tasks[0].ID != "done-2" {
+		t.Fatalf("got %d tasks starting with %s, want %d starting with done-2", len(tasks), tasks[0].ID, maxFinishedTasks+1)
+	}
+	if last := tasks[len(tasks)-1]; last.State != QueuedTaskAssigned || last.Agent != "fox" {
+		t.Errorf("updated task = %+v", last)
+	}
+
+	if err := s.RemoveQueuedTask("test-repo", "next"); err != nil {
+		t.Fatalf("RemoveQueuedTask() failed: %v", err)
+	}
+	if err := s.UpdateQueuedTask("test-repo", "next", func(*QueuedTask) {}); err == nil {
+		t.Error("UpdateQueuedTask() should fail for a removed task")
+	}
+	if got := (TaskQueueConfig{}).Limit(); got != DefaultQueueWorkers {
+		t.Errorf("default Limit() = %d, want %d", got, DefaultQueueWorkers)
+	}
+}
+
+func TestParseTaskPriorityName(t *testing.T) {
+	for name, want := range map[string]TaskPriority{"urgent": TaskPriorityP0, "High": TaskPriorityP1, "normal": TaskPriorityP2, "LOW": TaskPriorityP3, "p1": TaskPriorityP1} {
+		if got, err := ParseTaskPriorityName(name); err != nil || got != want {
+			t.Errorf("ParseTaskPriorityName(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseTaskPriorityName("soon"); err == nil {
+		t.Error("ParseTaskPriorityName(\"soon\") should fail")
+	}
+}
+
+func TestMergeQueueConfigDefaults(t *testing.T) {
+	var cfg MergeQueueConfig
+	if cfg.MergeLabel() != DefaultMergeLabel || cfg.PollInterval() != DefaultMergePollInterval || cfg.Method() != "squash" {
+		t.Errorf("defaults = %q, %v, %q", cfg.MergeLabel(), cfg.PollInterval(), cfg.Method())
+	}
+	cfg = MergeQueueConfig{Label: "ship-it", PollSeconds: 45, MergeMethod: "rebase"}
+	if cfg.MergeLabel() != "ship-it" || cfg.PollInterval() != 45*time.Second || cfg.Method() != "rebase" {
+		t.Errorf("overrides = %q, %v, %q", cfg.MergeLabel(), cfg.PollInterval(), cfg.Method())
+	}
+	if ValidMergeMethod("fast-forward") || !ValidMergeMethod("merge") {
+		t.Error("ValidMergeMethod accepts only merge, squash, and rebase")
+	}
+}
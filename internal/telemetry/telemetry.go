@@ -0,0 +1,229 @@
+// Package telemetry records opt-in, local-only timings of CLI commands,
+// daemon socket roundtrips, and slow steps such as worktree creation.
+//
+// Samples carry only a command or step name (never arguments, repo names, or
+// paths), a duration, and whether it failed. They are written to a JSON lines
+// file under ~/.multiclaude/telemetry/ and never sent anywhere; users can
+// share a report with maintainers if they choose.
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Kind groups samples by what was measured
+type Kind string
+
+const (
+	// KindCommand is a full CLI command invocation (e.g. "work list")
+	KindCommand Kind = "command"
+	// KindSocket is a single request to the daemon (e.g. "add_agent")
+	KindSocket Kind = "socket"
+	// KindStep is a slow step inside a command (e.g. "worktree create")
+	KindStep Kind = "step"
+)
+
+const (
+	// SamplesFile holds the current samples
+	SamplesFile = "samples.jsonl"
+	// enabledMarker exists while telemetry is enabled
+	enabledMarker = "enabled"
+	// maxFileBytes is the size at which the samples file is rotated. One
+	// rotated file is kept, bounding disk use to about twice this.
+	maxFileBytes = 2 << 20
+)
+
+// Sample is a single timed operation
+type Sample struct {
+	Time       time.Time `json:"time"`
+	Kind       Kind      `json:"kind"`
+	Name       string    `json:"name"`
+	DurationMs float64   `json:"duration_ms"`
+	Failed     bool      `json:"failed,omitempty"`
+}
+
+// Recorder reads and writes samples in a telemetry directory
+type Recorder struct {
+	dir string
+}
+
+// NewRecorder returns a recorder for dir
+func NewRecorder(dir string) *Recorder {
+	return &Recorder{dir: dir}
+}
+
+func (r *Recorder) samplesPath() string {
+	return filepath.Join(r.dir, SamplesFile)
+}
+
+func (r *Recorder) rotatedPath() string {
+	return r.samplesPath() + ".1"
+}
+
+// Enabled reports whether the user opted in
+func (r *Recorder) Enabled() bool {
+	_, err := os.Stat(filepath.Join(r.dir, enabledMarker))
+	return err == nil
+}
+
+// Enable opts in to recording
+func (r *Recorder) Enable() error {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create telemetry directory: %w", err)
+	}
+	return os.WriteFile(filepath.Join(r.dir, enabledMarker), nil, 0644)
+}
+
+// Disable stops recording. Existing samples are kept until Clear.
+func (r *Recorder) Disable() error {
+	if err := os.Remove(filepath.Join(r.dir, enabledMarker)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Clear deletes all recorded samples
+func (r *Recorder) Clear() error {
+	for _, path := range []string{r.samplesPath(), r.rotatedPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record appends a sample if telemetry is enabled. Errors are returned for
+// callers that care, but recording must never fail the measured operation.
+func (r *Recorder) Record(kind Kind, name string, elapsed time.Duration, err error) error {
+	if !r.Enabled() {
+		return nil
+	}
+
+	if info, statErr := os.Stat(r.samplesPath()); statErr == nil && info.Size() >= maxFileBytes {
+		if err := os.Rename(r.samplesPath(), r.rotatedPath()); err != nil {
+			return fmt.Errorf("failed to rotate telemetry samples: %w", err)
+		}
+	}
+
+	data, jsonErr := json.Marshal(Sample{
+		Time:       time.Now().UTC(),
+		Kind:       kind,
+		Name:       name,
+		DurationMs: float64(elapsed.Microseconds()) / 1000,
+		Failed:     err != nil,
+	})
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	f, openErr := os.OpenFile(r.samplesPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if openErr != nil {
+		return fmt.Errorf("failed to open telemetry samples: %w", openErr)
+	}
+	defer f.Close()
+	_, writeErr := f.Write(append(data, '\n'))
+	return writeErr
+}
+
+// Load returns all recorded samples, oldest first. Malformed lines are skipped.
+func (r *Recorder) Load() ([]Sample, error) {
+	var samples []Sample
+	for _, path := range []string{r.rotatedPath(), r.samplesPath()} {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read telemetry samples: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var s Sample
+			if json.Unmarshal(scanner.Bytes(), &s) == nil && s.Name != "" {
+				samples = append(samples, s)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read telemetry samples: %w", err)
+		}
+	}
+	return samples, nil
+}
+
+// Summary aggregates the samples of one kind and name
+type Summary struct {
+	Kind     Kind    `json:"kind"`
+	Name     string  `json:"name"`
+	Count    int     `json:"count"`
+	Failures int     `json:"failures"`
+	P50Ms    float64 `json:"p50_ms"`
+	P95Ms    float64 `json:"p95_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// FailureRate returns the fraction of samples that failed
+func (s Summary) FailureRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Count)
+}
+
+// Summarize groups samples recorded at or after since (zero for all) by kind
+// and name, slowest p95 first
+func Summarize(samples []Sample, since time.Time) []Summary {
+	type key struct {
+		kind Kind
+		name string
+	}
+	durations := make(map[key][]float64)
+	failures := make(map[key]int)
+	for _, s := range samples {
+		if s.Time.Before(since) {
+			continue
+		}
+		k := key{s.Kind, s.Name}
+		durations[k] = append(durations[k], s.DurationMs)
+		if s.Failed {
+			failures[k]++
+		}
+	}
+
+	summaries := make([]Summary, 0, len(durations))
+	for k, ds := range durations {
+		sort.Float64s(ds)
+		summaries = append(summaries, Summary{
+			Kind:     k.kind,
+			Name:     k.name,
+			Count:    len(ds),
+			Failures: failures[k],
+			P50Ms:    percentile(ds, 0.50),
+			P95Ms:    percentile(ds, 0.95),
+			MaxMs:    ds[len(ds)-1],
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].P95Ms != summaries[j].P95Ms {
+			return summaries[i].P95Ms > summaries[j].P95Ms
+		}
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
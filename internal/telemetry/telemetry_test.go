@@ -0,0 +1,96 @@
+package telemetry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorderOptIn(t *testing.T) {
+	r := NewRecorder(filepath.Join(t.TempDir(), "telemetry"))
+
+	if err := r.Record(KindCommand, "work list", time.Second, nil); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	if samples, _ := r.Load(); len(samples) != 0 {
+		t.Fatalf("nothing should be recorded before opting in, got %v", samples)
+	}
+
+	if err := r.Enable(); err != nil {
+		t.Fatalf("Enable() failed: %v", err)
+	}
+	r.Record(KindCommand, "work list", 1500*time.Millisecond, nil)
+	r.Record(KindSocket, "add_agent", 20*time.Millisecond, errors.New("boom"))
+	samples, err := r.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(samples) != 2 || samples[0].DurationMs != 1500 || samples[0].Failed || !samples[1].Failed {
+		t.Errorf("Load() = %+v", samples)
+	}
+
+	if err := r.Disable(); err != nil {
+		t.Fatalf("Disable() failed: %v", err)
+	}
+	r.Record(KindCommand, "work list", time.Second, nil)
+	if samples, _ := r.Load(); len(samples) != 2 {
+		t.Errorf("disabled recorder should keep samples but record no more, got %d", len(samples))
+	}
+
+	if err := r.Clear(); err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if samples, _ := r.Load(); len(samples) != 0 {
+		t.Errorf("Clear() left %d samples", len(samples))
+	}
+}
+
+func TestRecorderRotation(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRecorder(dir)
+	r.Enable()
+
+	big := strings.Repeat(`{"time":"2026-01-01T00:00:00Z","kind":"command","name":"old","duration_ms":1}`+"\n", maxFileBytes/70+1)
+	if err := os.WriteFile(filepath.Join(dir, SamplesFile), []byte(big), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r.Record(KindCommand, "new", time.Millisecond, nil)
+
+	if _, err := os.Stat(filepath.Join(dir, SamplesFile+".1")); err != nil {
+		t.Fatalf("full samples file should be rotated: %v", err)
+	}
+	samples, _ := r.Load()
+	if last := samples[len(samples)-1]; last.Name != "new" {
+		t.Errorf("newest sample should load last, got %+v", last)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	now := time.Now()
+	var samples []Sample
+	for i := 1; i <= 20; i++ {
+		samples = append(samples, Sample{Time: now, Kind: KindStep, Name: "worktree create", DurationMs: float64(i * 100), Failed: i == 20})
+	}
+	samples = append(samples,
+		Sample{Time: now, Kind: KindSocket, Name: "ping", DurationMs: 2},
+		Sample{Time: now.Add(-48 * time.Hour), Kind: KindSocket, Name: "old", DurationMs: 9999},
+	)
+
+	summaries := Summarize(samples, now.Add(-time.Hour))
+	if len(summaries) != 2 {
+		t.Fatalf("Summarize() = %+v, want 2 entries", summaries)
+	}
+	wt := summaries[0]
+	if wt.Name != "worktree create" || wt.Count != 20 || wt.Failures != 1 || wt.P50Ms != 1000 || wt.P95Ms != 1900 || wt.MaxMs != 2000 {
+		t.Errorf("worktree summary = %+v", wt)
+	}
+	if rate := wt.FailureRate(); rate != 0.05 {
+		t.Errorf("FailureRate() = %v, want 0.05", rate)
+	}
+	if summaries[1].Name != "ping" {
+		t.Errorf("faster entries should sort last, got %+v", summaries[1])
+	}
+}
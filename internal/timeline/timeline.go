@@ -0,0 +1,247 @@
+// Package timeline assembles a Gantt-style view of what a repository's
+// agents did over a period, from task history, the orchestration feed, and
+// stored notification events, and renders it as Mermaid or markdown for
+// reports.
+package timeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// Timeline is a repository's tasks between Since and Until
+type Timeline struct {
+	Repo  string    `json:"repo"`
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	Tasks []Task    `json:"tasks"`
+}
+
+// Task is one agent's task as a bar on the timeline
+type Task struct {
+	Agent  string `json:"agent"`
+	Type   string `json:"type"`
+	Task   string `json:"task"`
+	Branch string `json:"branch,omitempty"`
+	// Status is the task history status, or "running"
+	Status string    `json:"status"`
+	Start  time.Time `json:"start"`
+	// End is when the task finished, or Until for running tasks
+	End     time.Time `json:"end"`
+	Running bool      `json:"running,omitempty"`
+	// Waiting are the periods the agent spent waiting on an answer
+	Waiting []Span `json:"waiting,omitempty"`
+	// Marks are refreshes, conflicts, and other moments during the task
+	Marks []Mark `json:"marks,omitempty"`
+}
+
+// Span is a period within a task
+type Span struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason"`
+}
+
+// Mark is a moment within a task
+type Mark struct {
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// StatusRunning is the status of tasks that haven't finished
+const StatusRunning = "running"
+
+// markActions are the feed actions shown as marks on a task's bar
+var markActions = map[feed.Action]bool{
+	feed.ActionRefreshed:     true,
+	feed.ActionConflict:      true,
+	feed.ActionRefreshPaused: true,
+	feed.ActionRefreshResume: true,
+	feed.ActionBranchPushed:  true,
+	feed.ActionHandedOff:     true,
+	feed.ActionRestarted:     true,
+	feed.ActionRecovered:     true,
+	feed.ActionTimedOut:      true,
+	feed.ActionCIFailed:      true,
+}
+
+// Build assembles the timeline of repo's worker and review tasks that ran
+// between since and until. entries is the repository's feed and evts its
+// stored events; both may cover more than the period. A question starts a
+// waiting period that lasts until the agent's next feed action (e.g. the
+// answer) or the end of the task.
+func Build(repoName string, repo *state.Repository, entries []feed.Entry, evts []events.Event, since, until time.Time) Timeline {
+	tl := Timeline{Repo: repoName, Since: since, Until: until, Tasks: []Task{}}
+
+	for _, h := range repo.TaskHistory {
+		tl.add(Task{
+			Agent: h.Name, Type: string(state.AgentTypeWorker), Task: h.Task, Branch: h.Branch,
+			Status: string(h.Status), Start: h.CreatedAt, End: h.CompletedAt,
+		})
+	}
+	for name, agent := range repo.Agents {
+		if agent.Type != state.AgentTypeWorker && agent.Type != state.AgentTypeReview {
+			continue
+		}
+		tl.add(Task{
+			Agent: name, Type: string(agent.Type), Task: agent.Task,
+			Status: StatusRunning, Start: agent.CreatedAt, End: until, Running: true,
+		})
+	}
+	sort.Slice(tl.Tasks, func(i, j int) bool {
+		if !tl.Tasks[i].Start.Equal(tl.Tasks[j].Start) {
+			return tl.Tasks[i].Start.Before(tl.Tasks[j].Start)
+		}
+		return tl.Tasks[i].Agent < tl.Tasks[j].Agent
+	})
+
+	for i := range tl.Tasks {
+		t := &tl.Tasks[i]
+		var actions []time.Time
+		for _, e := range entries {
+			if e.Agent != t.Agent || !t.during(e.Time) {
+				continue
+			}
+			actions = append(actions, e.Time)
+			if markActions[e.Action] {
+				t.Marks = append(t.Marks, Mark{Time: e.Time, Kind: string(e.Action), Detail: e.Detail})
+			}
+		}
+		for _, e := range evts {
+			if e.Repo != repoName || e.Agent != t.Agent || !t.during(e.Timestamp) {
+				continue
+			}
+			switch e.Type {
+			case events.EventAgentQuestion:
+				end := t.End
+				for _, a := range actions {
+					if a.After(e.Timestamp) && a.Before(end) {
+						end = a
+					}
+				}
+				t.Waiting = append(t.Waiting, Span{Start: e.Timestamp, End: end, Reason: e.Title})
+			case events.EventAgentStuck, events.EventAgentError:
+				t.Marks = append(t.Marks, Mark{Time: e.Timestamp, Kind: string(e.Type), Detail: e.Title})
+			}
+		}
+		sort.Slice(t.Marks, func(a, b int) bool { return t.Marks[a].Time.Before(t.Marks[b].Time) })
+	}
+	return tl
+}
+
+// add appends t if it overlaps the timeline's period
+func (tl *Timeline) add(t Task) {
+	if t.Start.IsZero() || t.End.Before(t.Start) {
+		return
+	}
+	if t.End.Before(tl.Since) || t.Start.After(tl.Until) {
+		return
+	}
+	tl.Tasks = append(tl.Tasks, t)
+}
+
+// during reports whether at falls within the task
+func (t Task) during(at time.Time) bool {
+	return !at.Before(t.Start) && !at.After(t.End)
+}
+
+// WaitingTime is the total time the task spent waiting
+func (t Task) WaitingTime() time.Duration {
+	var total time.Duration
+	for _, w := range t.Waiting {
+		total += w.End.Sub(w.Start)
+	}
+	return total
+}
+
+// mermaidTime is the date format of Mermaid's gantt charts below, in UTC
+const mermaidTime = "2006-01-02 15:04:05"
+
+// Mermaid renders the timeline as a Mermaid gantt chart with a section per
+// agent. Waiting periods are critical bars and marks are milestones.
+func Mermaid(tl Timeline) string {
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	fmt.Fprintf(&b, "    title %s (UTC)\n", mermaidText(tl.Repo))
+	b.WriteString("    dateFormat YYYY-MM-DD HH:mm:ss\n")
+	b.WriteString("    axisFormat %m-%d %H:%M\n")
+	for _, t := range tl.Tasks {
+		fmt.Fprintf(&b, "    section %s\n", mermaidText(t.Agent))
+		tag := "done"
+		switch {
+		case t.Running:
+			tag = "active"
+		case t.Status == string(state.TaskStatusFailed):
+			tag = "crit, done"
+		}
+		label := t.Task
+		if label == "" {
+			label = t.Type
+		}
+		fmt.Fprintf(&b, "    %s :%s, %s, %s\n", mermaidText(label), tag, mermaidDate(t.Start), mermaidDate(t.End))
+		for _, w := range t.Waiting {
+			fmt.Fprintf(&b, "    waiting :crit, %s, %s\n", mermaidDate(w.Start), mermaidDate(w.End))
+		}
+		for _, m := range t.Marks {
+			fmt.Fprintf(&b, "    %s :milestone, %s, 0s\n", mermaidText(m.Kind), mermaidDate(m.Time))
+		}
+	}
+	return b.String()
+}
+
+func mermaidDate(t time.Time) string {
+	return t.UTC().Format(mermaidTime)
+}
+
+// mermaidText makes s safe as a Mermaid task or section name, which ends at
+// a colon and can't contain # or ;
+func mermaidText(s string) string {
+	s = strings.NewReplacer(":", " -", "#", "", ";", ",", "\n", " ").Replace(s)
+	if r := []rune(s); len(r) > 60 {
+		s = string(r[:57]) + "..."
+	}
+	return strings.TrimSpace(s)
+}
+
+// Markdown renders the timeline as a markdown section: the Mermaid chart and
+// a table of the tasks
+func Markdown(tl Timeline) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s: %s to %s\n\n", tl.Repo, tl.Since.UTC().Format("2006-01-02 15:04"), tl.Until.UTC().Format("2006-01-02 15:04 MST"))
+	if len(tl.Tasks) == 0 {
+		b.WriteString("No tasks ran in this period.\n")
+		return b.String()
+	}
+	b.WriteString("```mermaid\n")
+	b.WriteString(Mermaid(tl))
+	b.WriteString("```\n\n")
+	b.WriteString("| Agent | Task | Status | Started | Duration | Waiting | Refreshes | Conflicts |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, t := range tl.Tasks {
+		var refreshes, conflicts int
+		for _, m := range t.Marks {
+			switch feed.Action(m.Kind) {
+			case feed.ActionRefreshed:
+				refreshes++
+			case feed.ActionConflict:
+				conflicts++
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %d | %d |\n",
+			markdownCell(t.Agent), markdownCell(t.Task), t.Status, t.Start.UTC().Format("2006-01-02 15:04"),
+			t.End.Sub(t.Start).Round(time.Minute), t.WaitingTime().Round(time.Minute), refreshes, conflicts)
+	}
+	return b.String()
+}
+
+// markdownCell keeps s from breaking out of a table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
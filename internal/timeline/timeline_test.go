@@ -0,0 +1,75 @@
+package timeline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/feed"
+	"github.com/dlorenc/multiclaude/internal/state"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+func TestBuild(t *testing.T) {
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	repo := &state.Repository{
+		TaskHistory: []state.TaskHistoryEntry{
+			{Name: "fox", Task: "Fix login: redirect loop", Status: state.TaskStatusMerged, CreatedAt: at(0), CompletedAt: at(120)},
+			{Name: "old", Task: "Before the period", Status: state.TaskStatusMerged, CreatedAt: at(-300), CompletedAt: at(-200)},
+		},
+		Agents: map[string]state.Agent{
+			"owl":        {Type: state.AgentTypeWorker, Task: "Add metrics", CreatedAt: at(60)},
+			"supervisor": {Type: state.AgentTypeSupervisor, CreatedAt: at(-1000)},
+		},
+	}
+	entries := []feed.Entry{
+		{Time: at(30), Action: feed.ActionRefreshed, Agent: "fox"},
+		{Time: at(50), Action: feed.ActionAnswered, Agent: "fox"},
+		{Time: at(90), Action: feed.ActionConflict, Agent: "fox", Detail: "a.go"},
+	}
+	evts := []events.Event{
+		{Type: events.EventAgentQuestion, Repo: "app", Agent: "fox", Title: "Which DB?", Timestamp: at(40)},
+		{Type: events.EventAgentQuestion, Repo: "app", Agent: "owl", Title: "May I?", Timestamp: at(150)},
+		{Type: events.EventAgentQuestion, Repo: "other", Agent: "fox", Title: "Elsewhere", Timestamp: at(45)},
+	}
+
+	tl := Build("app", repo, entries, evts, at(-60), at(180))
+	if len(tl.Tasks) != 2 || tl.Tasks[0].Agent != "fox" || tl.Tasks[1].Agent != "owl" {
+		t.Fatalf("Build() tasks = %+v, want fox then owl", tl.Tasks)
+	}
+
+	fox := tl.Tasks[0]
+	if len(fox.Waiting) != 1 || !fox.Waiting[0].End.Equal(at(50)) || fox.WaitingTime() != 10*time.Minute {
+		t.Errorf("fox should wait from the question until the answer, got %+v", fox.Waiting)
+	}
+	if len(fox.Marks) != 2 || fox.Marks[0].Kind != "refreshed" || fox.Marks[1].Kind != "conflict" {
+		t.Errorf("fox marks = %+v", fox.Marks)
+	}
+
+	owl := tl.Tasks[1]
+	if !owl.Running || !owl.End.Equal(at(180)) || len(owl.Waiting) != 1 || !owl.Waiting[0].End.Equal(at(180)) {
+		t.Errorf("running owl should still be waiting at the end of the period, got %+v", owl)
+	}
+
+	chart := Mermaid(tl)
+	for _, want := range []string{
+		"section fox",
+		"Fix login - redirect loop :done, 2026-06-01 09:00:00, 2026-06-01 11:00:00",
+		"waiting :crit, 2026-06-01 09:40:00, 2026-06-01 09:50:00",
+		"conflict :milestone, 2026-06-01 10:30:00, 0s",
+		"Add metrics :active,",
+	} {
+		if !strings.Contains(chart, want) {
+			t.Errorf("Mermaid() missing %q:\n%s", want, chart)
+		}
+	}
+
+	md := Markdown(tl)
+	if !strings.Contains(md, "```mermaid\ngantt\n") || !strings.Contains(md, "| fox | Fix login: redirect loop | merged | 2026-06-01 09:00 | 2h0m0s | 10m0s | 1 | 1 |") {
+		t.Errorf("unexpected markdown:\n%s", md)
+	}
+	if empty := Markdown(Build("app", &state.Repository{}, nil, nil, at(0), at(1))); !strings.Contains(empty, "No tasks ran") {
+		t.Errorf("empty timeline markdown = %q", empty)
+	}
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Cmd is an exec.Cmd whose Run, Output and CombinedOutput are each recorded
+// as a span named after the program and its subcommand, e.g. "git fetch"
+type Cmd struct {
+	*exec.Cmd
+	ctx context.Context
+}
+
+// Command wraps cmd so running it records a child span of ctx (or of the
+// fallback parent)
+func Command(ctx context.Context, cmd *exec.Cmd) *Cmd {
+	return &Cmd{Cmd: cmd, ctx: ctx}
+}
+
+// Run runs the command, recording a span
+func (c *Cmd) Run() error {
+	span := c.startSpan()
+	err := c.Cmd.Run()
+	c.endSpan(span, err)
+	return err
+}
+
+// Output runs the command and returns its standard output, recording a span
+func (c *Cmd) Output() ([]byte, error) {
+	span := c.startSpan()
+	out, err := c.Cmd.Output()
+	c.endSpan(span, err)
+	return out, err
+}
+
+// CombinedOutput runs the command and returns its combined standard output
+// and standard error, recording a span
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	span := c.startSpan()
+	out, err := c.Cmd.CombinedOutput()
+	c.endSpan(span, err)
+	return out, err
+}
+
+func (c *Cmd) startSpan() *Span {
+	_, span := StartChild(c.ctx, commandName(c.Args))
+	if span != nil && c.Dir != "" {
+		span.SetAttr("process.working_directory", c.Dir)
+	}
+	return span
+}
+
+func (c *Cmd) endSpan(span *Span, err error) {
+	if span == nil {
+		return
+	}
+	if c.ProcessState != nil {
+		span.SetAttr("process.exit.code", c.ProcessState.ExitCode())
+	}
+	span.End(err)
+}
+
+// commandName is the program and its subcommand: the first argument that
+// isn't an option or the value of git's -c and -C
+func commandName(args []string) string {
+	if len(args) == 0 {
+		return "exec"
+	}
+	name := filepath.Base(args[0])
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-c" || arg == "-C" {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return name + " " + arg
+		}
+	}
+	return name
+}
@@ -0,0 +1,290 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// exportInterval is how often queued spans are sent
+	exportInterval = 2 * time.Second
+	// exportBatch is how many queued spans are sent without waiting
+	exportBatch = 256
+	// queueSize bounds the spans waiting to be sent; more are dropped
+	queueSize = 4096
+	// shutdownTimeout bounds the final export when tracing stops
+	shutdownTimeout = 5 * time.Second
+)
+
+// otlpExporter batches finished spans and posts them to an OTLP/HTTP collector
+// as JSON. Spans are dropped, never blocking the traced operation, when the
+// queue is full or the collector can't be reached.
+type otlpExporter struct {
+	endpoint string
+	service  string
+	headers  map[string]string
+	client   *http.Client
+
+	queue    chan *Span
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// Init starts exporting spans when the standard OTLP environment variables
+// name a collector: OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, or
+// OTEL_EXPORTER_OTLP_ENDPOINT with /v1/traces appended.
+// OTEL_EXPORTER_OTLP_HEADERS (k=v,k2=v2) is sent with every export, and
+// OTEL_SERVICE_NAME overrides service. The returned function sends what is
+// queued and stops tracing; without a collector both do nothing.
+func Init(service string) (shutdown func()) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint == "" && base != "" {
+		endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+	}
+	if endpoint == "" || os.Getenv("OTEL_SDK_DISABLED") == "true" {
+		return func() {}
+	}
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		service = name
+	}
+
+	e := newExporter(endpoint, service, parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")))
+	mu.Lock()
+	exporter = e
+	mu.Unlock()
+	return func() {
+		mu.Lock()
+		if exporter == e {
+			exporter = nil
+			fallback = nil
+		}
+		mu.Unlock()
+		e.shutdown()
+	}
+}
+
+// parseHeaders reads OTEL_EXPORTER_OTLP_HEADERS
+func parseHeaders(spec string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); ok && key != "" {
+			headers[key] = strings.TrimSpace(value)
+		}
+	}
+	return headers
+}
+
+func newExporter(endpoint, service string, headers map[string]string) *otlpExporter {
+	e := &otlpExporter{
+		endpoint: endpoint,
+		service:  service,
+		headers:  headers,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		queue:    make(chan *Span, queueSize),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// enqueue queues a finished span, dropping it if the queue is full
+func (e *otlpExporter) enqueue(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+	}
+}
+
+// run sends queued spans in batches until shutdown, then sends the rest
+func (e *otlpExporter) run() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= exportBatch {
+				e.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = nil
+		case <-e.stop:
+			for {
+				select {
+				case s := <-e.queue:
+					batch = append(batch, s)
+				default:
+					e.send(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// shutdown sends what is queued, waiting at most shutdownTimeout
+func (e *otlpExporter) shutdown() {
+	e.stopOnce.Do(func() { close(e.stop) })
+	select {
+	case <-e.stopped:
+	case <-time.After(shutdownTimeout):
+	}
+}
+
+// send posts a batch to the collector. A failed export is dropped: the
+// traced process's output is no place to report it.
+func (e *otlpExporter) send(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	e.post(batch)
+}
+
+func (e *otlpExporter) post(batch []*Span) error {
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %d spans: %w", len(batch), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector rejected %d spans: %s", len(batch), resp.Status)
+	}
+	return nil
+}
+
+// OTLP's JSON encoding of an ExportTraceServiceRequest. IDs are hex and
+// 64-bit integers are strings, as the OTLP/HTTP JSON mapping specifies.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+const (
+	otlpKindInternal = 1
+	otlpStatusError  = 2
+)
+
+func (e *otlpExporter) encode(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attrs),
+		}
+		if s.parentID != ([8]byte{}) {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != "" {
+			span.Status = otlpStatus{Code: otlpStatusError, Message: s.err}
+		}
+		s.mu.Unlock()
+		spans = append(spans, span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(map[string]interface{}{"service.name": e.service})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "multiclaude"}, Spans: spans}},
+	}}}
+}
+
+// otlpAttributes encodes attributes sorted by key
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		var value otlpValue
+		switch v := attrs[key].(type) {
+		case bool:
+			value.BoolValue = &v
+		case int:
+			n := strconv.Itoa(v)
+			value.IntValue = &n
+		case int64:
+			n := strconv.FormatInt(v, 10)
+			value.IntValue = &n
+		default:
+			str := fmt.Sprint(v)
+			value.StringValue = &str
+		}
+		kvs = append(kvs, otlpKeyValue{Key: key, Value: value})
+	}
+	return kvs
+}
@@ -0,0 +1,202 @@
+// Package tracing records OpenTelemetry spans for CLI commands, daemon
+// socket requests, and the tmux and git subprocesses a command runs, so a
+// slow init or work can be diagnosed from a trace.
+//
+// Tracing is off unless OTEL_EXPORTER_OTLP_ENDPOINT (or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set, in which case Init starts
+// sending spans to that collector over OTLP/HTTP with JSON encoding. While
+// it is off, starting a span returns nil and ending it does nothing.
+//
+// Span names and attributes carry command names, exit status and working
+// directories, never arguments, which can hold tokens or task text. The
+// trace context crosses the daemon socket as a W3C traceparent, so a CLI
+// command and the daemon requests it sends end up in one trace.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentVersion is the W3C trace context version Traceparent writes
+const TraceparentVersion = "00"
+
+// Span is one timed operation in a trace. A nil *Span is valid and does
+// nothing, which is what Start returns while tracing is off.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]interface{}
+	err      string
+	mu       sync.Mutex
+}
+
+// spanContext identifies a span, possibly one in another process
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+type contextKey struct{}
+
+var (
+	mu       sync.RWMutex
+	exporter *otlpExporter
+	// fallback is the parent of spans started from a context without one
+	fallback *spanContext
+)
+
+// Enabled reports whether spans are being recorded
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return exporter != nil
+}
+
+// Start starts a span under the one in ctx, or the fallback parent, or as
+// the root of a new trace. The returned context carries the new span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	parent, _ := parentOf(ctx)
+	return start(ctx, name, parent)
+}
+
+// StartChild is Start for operations only worth recording inside a trace,
+// like subprocesses and socket round trips: without a parent it records
+// nothing and returns nil.
+func StartChild(ctx context.Context, name string) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	parent, ok := parentOf(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	return start(ctx, name, parent)
+}
+
+func start(ctx context.Context, name string, parent spanContext) (context.Context, *Span) {
+	s := &Span{traceID: parent.traceID, parentID: parent.spanID, name: name, start: time.Now()}
+	if s.traceID == ([16]byte{}) {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, contextKey{}, spanContext{s.traceID, s.spanID}), s
+}
+
+// parentOf returns the span ctx carries, or the fallback parent
+func parentOf(ctx context.Context) (spanContext, bool) {
+	if ctx != nil {
+		if sc, ok := ctx.Value(contextKey{}).(spanContext); ok {
+			return sc, true
+		}
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if fallback != nil {
+		return *fallback, true
+	}
+	return spanContext{}, false
+}
+
+// SetFallback makes the span in ctx the parent of spans started from
+// contexts that carry none. It suits processes running one traced
+// operation, like a CLI command, whose helpers don't thread a context
+// through. A context without a span clears it.
+func SetFallback(ctx context.Context) {
+	mu.Lock()
+	defer mu.Unlock()
+	fallback = nil
+	if sc, ok := ctx.Value(contextKey{}).(spanContext); ok {
+		fallback = &sc
+	}
+}
+
+// SetAttr records an attribute. Values should be strings, ints or bools.
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attrs == nil {
+		s.attrs = make(map[string]interface{})
+	}
+	s.attrs[key] = value
+}
+
+// End finishes the span, marking it failed with err, and queues it for
+// export
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.mu.Unlock()
+
+	mu.RLock()
+	e := exporter
+	mu.RUnlock()
+	if e != nil {
+		e.enqueue(s)
+	}
+}
+
+// Record adds a finished operation that began at start as a child span, for
+// steps timed without a context
+func Record(ctx context.Context, name string, start time.Time, err error) {
+	_, s := StartChild(ctx, name)
+	if s == nil {
+		return
+	}
+	s.start = start
+	s.End(err)
+}
+
+// Traceparent returns the W3C traceparent of the span in ctx, or of the
+// fallback parent, or "" when there is none
+func Traceparent(ctx context.Context) string {
+	sc, ok := parentOf(ctx)
+	if !ok {
+		return ""
+	}
+	return TraceparentVersion + "-" + hex.EncodeToString(sc.traceID[:]) + "-" + hex.EncodeToString(sc.spanID[:]) + "-01"
+}
+
+// WithTraceparent returns ctx with the span a W3C traceparent names as the
+// parent of spans started from it. An empty or malformed traceparent
+// returns ctx unchanged.
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	var sc spanContext
+	if _, err := hex.Decode(sc.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(sc.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	if sc.traceID == ([16]byte{}) || sc.spanID == ([8]byte{}) {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, sc)
+}
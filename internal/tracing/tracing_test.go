@@ -0,0 +1,192 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// collector is an OTLP/HTTP endpoint that keeps the spans posted to it
+type collector struct {
+	mu      sync.Mutex
+	spans   []otlpSpan
+	service string
+	headers http.Header
+}
+
+func startCollector(t *testing.T) *collector {
+	t.Helper()
+	c := &collector{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req otlpRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("collector got invalid JSON: %v", err)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.headers = r.Header.Clone()
+		for _, rs := range req.ResourceSpans {
+			for _, kv := range rs.Resource.Attributes {
+				if kv.Key == "service.name" && kv.Value.StringValue != nil {
+					c.service = *kv.Value.StringValue
+				}
+			}
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=abc")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("OTEL_SDK_DISABLED", "")
+	return c
+}
+
+// span returns the exported span with the given name
+func (c *collector) span(t *testing.T, name string) otlpSpan {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("no %q span exported, got %+v", name, c.spans)
+	return otlpSpan{}
+}
+
+func TestDisabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	shutdown := Init("test")
+	defer shutdown()
+
+	if Enabled() {
+		t.Fatal("Enabled() without a collector")
+	}
+	ctx, span := Start(context.Background(), "op")
+	if span != nil {
+		t.Error("Start() returned a span while tracing is off")
+	}
+	span.SetAttr("key", "value")
+	span.End(errors.New("ignored"))
+	if tp := Traceparent(ctx); tp != "" {
+		t.Errorf("Traceparent() = %q while tracing is off", tp)
+	}
+}
+
+func TestExport(t *testing.T) {
+	c := startCollector(t)
+	shutdown := Init("test")
+
+	ctx, root := Start(context.Background(), "work")
+	if _, orphan := StartChild(context.Background(), "socket status"); orphan != nil {
+		t.Error("StartChild() without a parent returned a span")
+	}
+	SetFallback(ctx)
+	// Outside a repository git fails, which the span records by exit code
+	Command(context.Background(), exec.Command("git", "-C", t.TempDir(), "status")).Run()
+	root.SetAttr("multiclaude.host", "build")
+	root.End(errors.New("boom"))
+	shutdown()
+
+	if Enabled() {
+		t.Error("Enabled() after shutdown")
+	}
+	if c.service != "test" {
+		t.Errorf("service.name = %q, want test", c.service)
+	}
+	if got := c.headers.Get("x-api-key"); got != "abc" {
+		t.Errorf("x-api-key header = %q, want abc", got)
+	}
+
+	work := c.span(t, "work")
+	if work.ParentSpanID != "" {
+		t.Errorf("root span has parent %q", work.ParentSpanID)
+	}
+	if work.Status.Code != otlpStatusError || work.Status.Message != "boom" {
+		t.Errorf("root span status = %+v, want an error", work.Status)
+	}
+	if len(work.Attributes) != 1 || *work.Attributes[0].Value.StringValue != "build" {
+		t.Errorf("root span attributes = %+v", work.Attributes)
+	}
+
+	git := c.span(t, "git status")
+	if git.TraceID != work.TraceID || git.ParentSpanID != work.SpanID {
+		t.Errorf("git span (trace %s, parent %s) isn't a child of %s/%s", git.TraceID, git.ParentSpanID, work.TraceID, work.SpanID)
+	}
+	var exitCode bool
+	for _, kv := range git.Attributes {
+		exitCode = exitCode || kv.Key == "process.exit.code"
+	}
+	if !exitCode {
+		t.Errorf("git span attributes = %+v, want process.exit.code", git.Attributes)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	startCollector(t)
+	defer Init("test")()
+
+	ctx, span := Start(context.Background(), "client")
+	defer span.End(nil)
+	tp := Traceparent(ctx)
+	parts := strings.Split(tp, "-")
+	if len(parts) != 4 || parts[0] != TraceparentVersion || parts[3] != "01" {
+		t.Fatalf("Traceparent() = %q", tp)
+	}
+
+	// A span started from the traceparent joins the trace
+	_, child := Start(WithTraceparent(context.Background(), tp), "server")
+	defer child.End(nil)
+	if got := Traceparent(WithTraceparent(context.Background(), tp)); got != tp {
+		t.Errorf("WithTraceparent() round trip = %q, want %q", got, tp)
+	}
+	if child.traceID != span.traceID || child.parentID != span.spanID {
+		t.Error("span started from a traceparent isn't a child of the client's span")
+	}
+
+	for _, bad := range []string{"", "garbage", "00-" + strings.Repeat("0", 32) + "-" + strings.Repeat("0", 16) + "-01", "00-xyz-abc-01"} {
+		if got := WithTraceparent(context.Background(), bad); got.Value(contextKey{}) != nil {
+			t.Errorf("WithTraceparent(%q) accepted a malformed traceparent", bad)
+		}
+	}
+}
+
+func TestCommandName(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"git", "fetch", "origin"}, "git fetch"},
+		{[]string{"/usr/bin/git", "-C", "/repo", "-c", "core.x=y", "worktree", "add"}, "git worktree"},
+		{[]string{"tmux", "new-window", "-d"}, "tmux new-window"},
+		{[]string{"git", "--version"}, "git"},
+		{nil, "exec"},
+	}
+	for _, tt := range tests {
+		if got := commandName(tt.args); got != tt.want {
+			t.Errorf("commandName(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got := parseHeaders(" a = 1 ,b=2,,bad")
+	if len(got) != 2 || got["a"] != "1" || got["b"] != "2" {
+		t.Errorf("parseHeaders() = %v", got)
+	}
+}
@@ -0,0 +1,265 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// KeyCode identifies keys that aren't plain characters
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota // A character, in Key.Rune
+	KeyUp
+	KeyDown
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyCtrlC
+)
+
+// Key is a key press
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// ParseKeys splits bytes read from the terminal into key presses. Unknown
+// escape sequences are dropped.
+func ParseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		switch {
+		case b[0] == 0x1b && len(b) >= 3 && (b[1] == '[' || b[1] == 'O'):
+			switch b[2] {
+			case 'A':
+				keys = append(keys, Key{Code: KeyUp})
+			case 'B':
+				keys = append(keys, Key{Code: KeyDown})
+			}
+			// Skip the rest of the sequence, up to its final byte
+			i := 2
+			for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+			b = b[min(i+1, len(b)):]
+		case b[0] == 0x1b:
+			keys = append(keys, Key{Code: KeyEsc})
+			b = b[1:]
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+			b = b[1:]
+		case b[0] == 0x7f || b[0] == 0x08:
+			keys = append(keys, Key{Code: KeyBackspace})
+			b = b[1:]
+		case b[0] == 0x03:
+			keys = append(keys, Key{Code: KeyCtrlC})
+			b = b[1:]
+		case b[0] < ' ':
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, Key{Code: KeyRune, Rune: r})
+			b = b[size:]
+		}
+	}
+	return keys
+}
+
+// Terminal is the controlling terminal, switched to the UI's alternate
+// screen with line editing and echo off. Modes are changed with stty, so
+// this works wherever stty does.
+type Terminal struct {
+	in    *os.File
+	out   io.Writer
+	saved string // stty settings to restore
+}
+
+// OpenTerminal takes over the terminal on stdin and stdout
+func OpenTerminal() (*Terminal, error) {
+	t := &Terminal{in: os.Stdin, out: os.Stdout}
+	saved, err := t.stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %w", err)
+	}
+	t.saved = strings.TrimSpace(saved)
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// enter switches to the UI's mode: no echo or line editing, Ctrl-C read as a
+// key, and reads that return after a tenth of a second without input so the
+// UI can refresh between key presses
+func (t *Terminal) enter() error {
+	if _, err := t.stty("-icanon", "-echo", "-isig", "min", "0", "time", "1"); err != nil {
+		return fmt.Errorf("failed to set terminal mode: %w", err)
+	}
+	fmt.Fprint(t.out, "\033[?1049h\033[?25l")
+	return nil
+}
+
+// leave restores the screen and terminal settings the UI started with
+func (t *Terminal) leave() {
+	fmt.Fprint(t.out, "\033[?25h\033[?1049l")
+	t.stty(t.saved)
+}
+
+// Close hands the terminal back
+func (t *Terminal) Close() {
+	t.leave()
+}
+
+// Suspend hands the terminal back while fn runs, e.g. to attach to tmux.
+// With pause, it waits for Enter before returning to the UI so fn's output
+// can be read.
+func (t *Terminal) Suspend(pause bool, fn func() error) error {
+	t.leave()
+	err := fn()
+	if err != nil {
+		fmt.Fprintf(t.out, "\nError: %v\n", err)
+	}
+	if pause || err != nil {
+		fmt.Fprint(t.out, "\nPress Enter to return to the UI")
+		bufio.NewReader(t.in).ReadString('\n')
+	}
+	if enterErr := t.enter(); enterErr != nil {
+		return enterErr
+	}
+	return err
+}
+
+// Size returns the terminal's width and height, or 80x24 if unknown
+func (t *Terminal) Size() (width, height int) {
+	out, err := t.stty("size")
+	if _, scanErr := fmt.Sscanf(out, "%d %d", &height, &width); err != nil || scanErr != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// ReadKeys waits briefly for key presses, returning none if there were none
+func (t *Terminal) ReadKeys() []Key {
+	buf := make([]byte, 256)
+	n, _ := t.in.Read(buf)
+	return ParseKeys(buf[:n])
+}
+
+// Draw replaces the screen with view, overwriting it line by line rather
+// than clearing it first so it doesn't flicker
+func (t *Terminal) Draw(view string) {
+	fmt.Fprint(t.out, "\033[H"+strings.ReplaceAll(view, "\n", "\033[K\r\n")+"\033[K\033[J")
+}
+
+func (t *Terminal) stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = t.in
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Run shows the UI until the user quits, refreshing agents and the preview
+// every refresh
+func Run(backend Backend, refresh time.Duration) error {
+	term, err := OpenTerminal()
+	if err != nil {
+		return err
+	}
+	defer term.Close()
+
+	m := &Model{}
+	var lastRefresh time.Time
+	var lastView string
+	width, height := term.Size()
+	for {
+		if time.Since(lastRefresh) >= refresh {
+			width, height = term.Size()
+			m.reload(backend)
+			m.refreshPreview(backend, height)
+			lastRefresh = time.Now()
+		} else if m.PreviewStale() {
+			m.refreshPreview(backend, height)
+		}
+		if view := m.View(width, height); view != lastView {
+			term.Draw(view)
+			lastView = view
+		}
+
+		keys := term.ReadKeys()
+		if len(keys) > 0 {
+			width, height = term.Size()
+		}
+		for _, k := range keys {
+			a := m.HandleKey(k)
+			switch a.kind {
+			case actionQuit:
+				return nil
+			case actionRefresh:
+				lastRefresh = time.Time{}
+			case actionMessages:
+				msgs, err := backend.Messages(a.agent)
+				if err != nil {
+					m.SetStatus("Failed to read messages: %v", err)
+					continue
+				}
+				m.SetMessages(msgs)
+			case actionRespond:
+				if err := backend.Respond(a.agent, a.text); err != nil {
+					m.SetStatus("Reply failed: %v", err)
+					continue
+				}
+				m.SetStatus("Replied to %s", a.agent.Label())
+			case actionAttach:
+				if err := term.Suspend(false, func() error { return backend.Attach(a.agent) }); err != nil {
+					m.SetStatus("Attach failed: %v", err)
+				}
+				lastRefresh, lastView = time.Time{}, ""
+			case actionKill:
+				if err := term.Suspend(true, func() error { return backend.Kill(a.agent) }); err != nil {
+					m.SetStatus("Kill failed: %v", err)
+				} else {
+					m.SetStatus("Removed %s", a.agent.Label())
+				}
+				lastRefresh, lastView = time.Time{}, ""
+			case actionSpawn:
+				if err := term.Suspend(true, func() error { return backend.Spawn(a.repo, a.text) }); err != nil {
+					m.SetStatus("Spawn failed: %v", err)
+				} else {
+					m.SetStatus("Spawned a worker in %s", a.repo)
+				}
+				lastRefresh, lastView = time.Time{}, ""
+			}
+		}
+	}
+}
+
+// reload refreshes the agent list
+func (m *Model) reload(backend Backend) {
+	snap, err := backend.Snapshot()
+	if err != nil {
+		m.SetStatus("Refresh failed: %v", err)
+		return
+	}
+	m.SetSnapshot(snap)
+}
+
+// refreshPreview captures the selected agent's screen
+func (m *Model) refreshPreview(backend Backend, lines int) {
+	agent, ok := m.Selected()
+	if !ok {
+		m.SetPreview(agent, "")
+		return
+	}
+	screen, err := backend.Preview(agent, lines)
+	if err != nil {
+		screen = fmt.Sprintf("No preview: %v", err)
+	}
+	m.SetPreview(agent, screen)
+}
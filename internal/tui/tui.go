@@ -0,0 +1,521 @@
+// Package tui is multiclaude's interactive terminal UI (`multiclaude ui`). It
+// lists every repository's agents with a live preview of the selected
+// agent's screen, and binds keys to attach to, kill, spawn, and reply to
+// agents and to read their message queues. The UI only draws and reads keys;
+// a Backend supplied by the CLI talks to the daemon and tmux.
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Agent is a row in the agent list
+type Agent struct {
+	Repo            string
+	Name            string
+	Type            string
+	Status          string
+	Branch          string
+	Task            string
+	Question        string // Pending question the agent asked, if any
+	PendingMessages int
+	TotalMessages   int
+	Session         string // tmux session and window, for previews
+	Window          string
+}
+
+// Label is how the agent is named in the UI
+func (a Agent) Label() string {
+	return a.Repo + "/" + a.Name
+}
+
+// Killable reports whether the UI may remove the agent. Supervisors,
+// merge queues, and workspaces are managed by the repository.
+func (a Agent) Killable() bool {
+	return a.Type == "worker" || a.Type == "review"
+}
+
+// Snapshot is the state the UI shows, refreshed periodically
+type Snapshot struct {
+	DaemonRunning bool
+	Repos         []string
+	Agents        []Agent // Ordered by repository, then name
+}
+
+// Message is an entry in an agent's message queue
+type Message struct {
+	From   string
+	Status string
+	Body   string
+	Time   time.Time
+}
+
+// Backend reads state for the UI and carries out its actions. Attach, Kill,
+// and Spawn run with the terminal handed back (see Terminal.Suspend), so
+// they may print and prompt.
+type Backend interface {
+	Snapshot() (Snapshot, error)
+	Preview(agent Agent, lines int) (string, error)
+	Messages(agent Agent) ([]Message, error)
+	Respond(agent Agent, text string) error
+	Attach(agent Agent) error
+	Kill(agent Agent) error
+	Spawn(repo, task string) error
+}
+
+// mode is what the UI is doing with keys
+type mode int
+
+const (
+	modeList     mode = iota // Moving through agents
+	modeMessages             // Reading the selected agent's messages
+	modeHelp                 // Showing key bindings
+	modeInput                // Typing a task or reply
+	modeConfirm              // Confirming a kill
+)
+
+// actionKind is what a key asks the UI to do
+type actionKind int
+
+const (
+	actionNone actionKind = iota
+	actionQuit
+	actionAttach
+	actionKill
+	actionSpawn
+	actionRespond
+	actionMessages
+	actionRefresh
+)
+
+// action is a key's effect outside the model, carried out by Run
+type action struct {
+	kind  actionKind
+	agent Agent
+	repo  string
+	text  string
+}
+
+// Model is the UI's state. HandleKey and View don't touch the terminal or
+// the backend, so the UI can be tested without either.
+type Model struct {
+	snap      Snapshot
+	selected  int
+	offset    int // First agent row shown
+	mode      mode
+	preview   string
+	previewOf string // Label of the agent preview shows
+	messages  []Message
+	status    string // Result of the last action
+
+	// Input line for modeInput, and what it is for
+	prompt  string
+	input   []rune
+	pending action
+}
+
+// SetSnapshot replaces what the UI shows, keeping the same agent selected
+// when it still exists
+func (m *Model) SetSnapshot(snap Snapshot) {
+	var current string
+	if agent, ok := m.Selected(); ok {
+		current = agent.Label()
+	}
+	m.snap = snap
+	m.selected = 0
+	for i, agent := range snap.Agents {
+		if agent.Label() == current {
+			m.selected = i
+			break
+		}
+	}
+}
+
+// SetPreview sets the selected agent's screen
+func (m *Model) SetPreview(agent Agent, screen string) {
+	m.preview = screen
+	m.previewOf = agent.Label()
+}
+
+// PreviewStale reports whether the preview shows another agent than the
+// selected one
+func (m *Model) PreviewStale() bool {
+	agent, _ := m.Selected()
+	return agent.Label() != m.previewOf
+}
+
+// SetMessages sets the selected agent's message queue and shows it
+func (m *Model) SetMessages(msgs []Message) {
+	m.messages = msgs
+	m.mode = modeMessages
+}
+
+// SetStatus sets the line reporting the last action
+func (m *Model) SetStatus(format string, args ...interface{}) {
+	m.status = fmt.Sprintf(format, args...)
+}
+
+// Selected returns the selected agent
+func (m *Model) Selected() (Agent, bool) {
+	if m.selected < 0 || m.selected >= len(m.snap.Agents) {
+		return Agent{}, false
+	}
+	return m.snap.Agents[m.selected], true
+}
+
+// HandleKey updates the model for a key press and returns what else the
+// key asks for
+func (m *Model) HandleKey(k Key) action {
+	switch m.mode {
+	case modeInput:
+		return m.handleInputKey(k)
+	case modeConfirm:
+		m.mode = modeList
+		if k.Rune == 'y' || k.Rune == 'Y' {
+			return m.pending
+		}
+		m.status = "Kill cancelled"
+		return action{}
+	case modeHelp, modeMessages:
+		if k.Code == KeyEsc || k.Rune == 'q' || k.Rune == '?' || k.Rune == 'm' {
+			m.mode = modeList
+			return action{}
+		}
+	}
+
+	if k.Code == KeyCtrlC {
+		return action{kind: actionQuit}
+	}
+	switch {
+	case k.Code == KeyUp || k.Rune == 'k':
+		m.move(-1)
+	case k.Code == KeyDown || k.Rune == 'j':
+		m.move(1)
+	case k.Rune == 'q':
+		return action{kind: actionQuit}
+	case k.Rune == '?':
+		m.mode = modeHelp
+	case k.Rune == 'g':
+		return action{kind: actionRefresh}
+	case k.Code == KeyEnter || k.Rune == 'a':
+		if agent, ok := m.Selected(); ok {
+			return action{kind: actionAttach, agent: agent}
+		}
+	case k.Rune == 'm':
+		if agent, ok := m.Selected(); ok {
+			return action{kind: actionMessages, agent: agent}
+		}
+	case k.Rune == 'x':
+		agent, ok := m.Selected()
+		if !ok {
+			break
+		}
+		if !agent.Killable() {
+			m.status = fmt.Sprintf("%s is a %s; only workers and reviewers can be killed here", agent.Label(), agent.Type)
+			break
+		}
+		m.pending = action{kind: actionKill, agent: agent}
+		m.mode = modeConfirm
+	case k.Rune == 's':
+		repo := m.selectedRepo()
+		if repo == "" {
+			m.status = "No repository to spawn a worker in"
+			break
+		}
+		m.startInput(fmt.Sprintf("Task for a new worker in %s: ", repo), action{kind: actionSpawn, repo: repo})
+	case k.Rune == 'r':
+		agent, ok := m.Selected()
+		if !ok {
+			break
+		}
+		m.startInput(fmt.Sprintf("Reply to %s: ", agent.Label()), action{kind: actionRespond, agent: agent})
+	}
+	return action{}
+}
+
+// handleInputKey edits the input line, returning the pending action with
+// the typed text once Enter is pressed
+func (m *Model) handleInputKey(k Key) action {
+	switch k.Code {
+	case KeyEsc, KeyCtrlC:
+		m.mode = modeList
+		m.status = "Cancelled"
+	case KeyEnter:
+		m.mode = modeList
+		text := strings.TrimSpace(string(m.input))
+		if text == "" {
+			m.status = "Cancelled"
+			return action{}
+		}
+		a := m.pending
+		a.text = text
+		return a
+	case KeyBackspace:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	case KeyRune:
+		m.input = append(m.input, k.Rune)
+	}
+	return action{}
+}
+
+func (m *Model) startInput(prompt string, pending action) {
+	m.mode = modeInput
+	m.prompt = prompt
+	m.input = nil
+	m.pending = pending
+}
+
+func (m *Model) move(delta int) {
+	if len(m.snap.Agents) == 0 {
+		return
+	}
+	m.selected += delta
+	if m.selected < 0 {
+		m.selected = 0
+	}
+	if m.selected >= len(m.snap.Agents) {
+		m.selected = len(m.snap.Agents) - 1
+	}
+}
+
+// selectedRepo is the repository new workers are spawned in: the selected
+// agent's, or the only one tracked
+func (m *Model) selectedRepo() string {
+	if agent, ok := m.Selected(); ok {
+		return agent.Repo
+	}
+	if len(m.snap.Repos) == 1 {
+		return m.snap.Repos[0]
+	}
+	return ""
+}
+
+// ANSI styles used by View
+const (
+	styleReset   = "\033[0m"
+	styleBold    = "\033[1m"
+	styleDim     = "\033[2m"
+	styleReverse = "\033[7m"
+	styleRed     = "\033[31m"
+	styleGreen   = "\033[32m"
+	styleYellow  = "\033[33m"
+)
+
+// View renders the whole screen at the given size, one line per row
+func (m *Model) View(width, height int) string {
+	if width < 20 || height < 8 {
+		return "Terminal too small"
+	}
+	var lines []string
+	if m.snap.DaemonRunning {
+		header := fmt.Sprintf("multiclaude ui  %d repos, %d agents", len(m.snap.Repos), len(m.snap.Agents))
+		lines = append(lines, styleBold+fit(header, width)+styleReset)
+	} else {
+		lines = append(lines, styleBold+styleRed+fit("multiclaude ui  daemon not running", width)+styleReset)
+	}
+
+	footer := m.footer(width)
+	body := height - 1 - len(footer)
+
+	switch m.mode {
+	case modeHelp:
+		lines = append(lines, boxLines(helpText, width, body)...)
+	default:
+		// The agent list takes up to two fifths of the screen; the rest
+		// previews the selected agent
+		listHeight := len(m.snap.Agents) + 1
+		if max := body * 2 / 5; listHeight > max {
+			listHeight = max
+		}
+		if listHeight < 2 {
+			listHeight = 2
+		}
+		lines = append(lines, m.agentList(width, listHeight)...)
+
+		title, content := m.previewTitle(), m.preview
+		if m.mode == modeMessages {
+			title, content = m.messagesTitle(), m.messageText()
+		}
+		lines = append(lines, styleDim+fit("── "+title+" "+strings.Repeat("─", width), width)+styleReset)
+		lines = append(lines, boxLines(content, width, body-listHeight-1)...)
+	}
+	lines = append(lines, footer...)
+	return strings.Join(lines, "\n")
+}
+
+// agentList renders the column header and the visible agent rows
+func (m *Model) agentList(width, height int) []string {
+	lines := []string{styleBold + fit(agentRow("AGENT", "TYPE", "STATUS", "MSGS", "BRANCH", "TASK"), width) + styleReset}
+	rows := height - 1
+	if m.snap.DaemonRunning && len(m.snap.Agents) == 0 {
+		lines = append(lines, styleDim+fit("No agents. Press s to spawn a worker.", width)+styleReset)
+	}
+	// Keep the selection in view
+	if m.selected < m.offset {
+		m.offset = m.selected
+	}
+	if m.selected >= m.offset+rows {
+		m.offset = m.selected - rows + 1
+	}
+	for i := m.offset; i < len(m.snap.Agents) && i < m.offset+rows; i++ {
+		agent := m.snap.Agents[i]
+		status, color := agent.Status, styleGreen
+		switch {
+		case agent.Question != "":
+			status, color = "question", styleYellow
+		case agent.Status == "completed":
+			color = styleDim
+		case agent.Status != "running":
+			color = styleRed
+		}
+		msgs := "-"
+		if agent.TotalMessages > 0 {
+			msgs = fmt.Sprintf("%d/%d", agent.PendingMessages, agent.TotalMessages)
+		}
+		task, _, _ := strings.Cut(agent.Task, "\n")
+		row := []rune(fit(agentRow(agent.Label(), agent.Type, status, msgs, agent.Branch, task), width))
+		if i == m.selected {
+			lines = append(lines, styleReverse+string(row)+styleReset)
+			continue
+		}
+		// Color just the status column
+		start := labelWidth + 1 + typeWidth + 1
+		end := start + statusWidth
+		if len(row) < end {
+			lines = append(lines, string(row))
+			continue
+		}
+		lines = append(lines, string(row[:start])+color+string(row[start:end])+styleReset+string(row[end:]))
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// Widths of the agent list's columns; the task takes the rest of the line
+const (
+	labelWidth    = 28
+	typeWidth     = 12
+	statusWidth   = 10
+	messagesWidth = 6
+	branchWidth   = 24
+)
+
+// agentRow lays out the agent list's columns
+func agentRow(label, agentType, status, msgs, branch, task string) string {
+	return fit(label, labelWidth) + " " + fit(agentType, typeWidth) + " " + fit(status, statusWidth) + " " +
+		fit(msgs, messagesWidth) + " " + fit(branch, branchWidth) + " " + task
+}
+
+func (m *Model) previewTitle() string {
+	agent, ok := m.Selected()
+	if !ok {
+		return "preview"
+	}
+	if agent.Question != "" {
+		return fmt.Sprintf("%s asks: %s", agent.Label(), agent.Question)
+	}
+	return agent.Label()
+}
+
+func (m *Model) messagesTitle() string {
+	agent, _ := m.Selected()
+	return fmt.Sprintf("messages for %s (%d)", agent.Label(), len(m.messages))
+}
+
+func (m *Model) messageText() string {
+	if len(m.messages) == 0 {
+		return "No messages"
+	}
+	var b strings.Builder
+	for _, msg := range m.messages {
+		fmt.Fprintf(&b, "%s  from %s  [%s]\n", msg.Time.Local().Format("Jan 02 15:04"), msg.From, msg.Status)
+		for _, line := range strings.Split(strings.TrimRight(msg.Body, "\n"), "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// footer is the input line, confirmation, or key hints, then the status line
+func (m *Model) footer(width int) []string {
+	var first string
+	switch m.mode {
+	case modeInput:
+		first = fit(m.prompt+string(m.input)+"█", width)
+	case modeConfirm:
+		first = styleYellow + fit(fmt.Sprintf("Kill %s and remove its worktree? (y/n)", m.pending.agent.Label()), width) + styleReset
+	case modeHelp, modeMessages:
+		first = styleDim + fit("esc back  q quit", width) + styleReset
+	default:
+		first = styleDim + fit("↑/↓ select  a attach  x kill  s spawn  r reply  m messages  g refresh  ? help  q quit", width) + styleReset
+	}
+	return []string{first, fit(m.status, width)}
+}
+
+const helpText = `Keys
+
+  ↑/↓, k/j   Select an agent
+  a, Enter   Attach to the agent's tmux window (detach with Ctrl-b d to come back)
+  x          Kill the selected worker or reviewer and remove its worktree
+  s          Spawn a worker in the selected agent's repository
+  r          Reply to the agent, answering its pending question
+  m          Show the agent's message queue
+  g          Refresh now (the UI refreshes every few seconds)
+  ?          Toggle this help
+  q          Quit`
+
+// boxLines returns the last height lines of text, each fitted to width,
+// padded with blank lines
+func boxLines(text string, width, height int) []string {
+	if height <= 0 {
+		return nil
+	}
+	all := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(all) > height {
+		all = all[len(all)-height:]
+	}
+	lines := make([]string, 0, height)
+	for _, line := range all {
+		lines = append(lines, fit(line, width))
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// fit pads or truncates s to exactly width runes, or trims trailing
+// whitespace when width is 0. Tabs become spaces and other control
+// characters are dropped, so pane contents can't move the cursor.
+func fit(s string, width int) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			r = ' '
+		case r < ' ' || r == 0x7f || r == utf8.RuneError:
+			continue
+		}
+		if width > 0 && n == width {
+			break
+		}
+		b.WriteRune(r)
+		n++
+	}
+	if width == 0 {
+		return strings.TrimRight(b.String(), " ")
+	}
+	for ; n < width; n++ {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,206 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testSnapshot() Snapshot {
+	return Snapshot{
+		DaemonRunning: true,
+		Repos:         []string{"app"},
+		Agents: []Agent{
+			{Repo: "app", Name: "supervisor", Type: "supervisor", Status: "running"},
+			{Repo: "app", Name: "swift-fox", Type: "worker", Status: "running", Branch: "work/swift-fox", Task: "Fix the login bug", PendingMessages: 2, TotalMessages: 3},
+			{Repo: "app", Name: "calm-owl", Type: "worker", Status: "blocked", Question: "Which database?"},
+		},
+	}
+}
+
+func typeKeys(m *Model, s string) action {
+	var last action
+	for _, k := range ParseKeys([]byte(s)) {
+		last = m.HandleKey(k)
+	}
+	return last
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Key
+	}{
+		{"runes", "jé", []Key{{Code: KeyRune, Rune: 'j'}, {Code: KeyRune, Rune: 'é'}}},
+		{"arrows", "\x1b[A\x1bOB", []Key{{Code: KeyUp}, {Code: KeyDown}}},
+		{"unknown sequence dropped", "\x1b[1;5Cx", []Key{{Code: KeyRune, Rune: 'x'}}},
+		{"escape", "\x1b", []Key{{Code: KeyEsc}}},
+		{"enter and backspace", "\r\x7f", []Key{{Code: KeyEnter}, {Code: KeyBackspace}}},
+		{"ctrl-c", "\x03", []Key{{Code: KeyCtrlC}}},
+		{"other control bytes dropped", "\x01", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseKeys([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseKeys(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSnapshotKeepsSelection(t *testing.T) {
+	m := &Model{}
+	m.SetSnapshot(testSnapshot())
+	typeKeys(m, "jj")
+	if agent, _ := m.Selected(); agent.Name != "calm-owl" {
+		t.Fatalf("selected %q, want calm-owl", agent.Name)
+	}
+
+	// The selected agent moves up when another is removed
+	snap := testSnapshot()
+	snap.Agents = append(snap.Agents[:1], snap.Agents[2:]...)
+	m.SetSnapshot(snap)
+	if agent, _ := m.Selected(); agent.Name != "calm-owl" {
+		t.Errorf("after refresh selected %q, want calm-owl", agent.Name)
+	}
+
+	// And the selection resets when it is gone
+	m.SetSnapshot(Snapshot{Agents: snap.Agents[:1]})
+	if agent, _ := m.Selected(); agent.Name != "supervisor" {
+		t.Errorf("after removal selected %q, want supervisor", agent.Name)
+	}
+	if !m.PreviewStale() {
+		t.Error("preview should be stale after the selection changed")
+	}
+	m.SetPreview(snap.Agents[0], "screen")
+	if m.PreviewStale() {
+		t.Error("preview should not be stale once set for the selected agent")
+	}
+}
+
+func TestHandleKeyKill(t *testing.T) {
+	m := &Model{}
+	m.SetSnapshot(testSnapshot())
+
+	// The supervisor can't be killed
+	if a := typeKeys(m, "x"); a.kind != actionNone {
+		t.Errorf("kill supervisor returned %v, want no action", a.kind)
+	}
+	if !strings.Contains(m.status, "only workers and reviewers") {
+		t.Errorf("status = %q, want explanation", m.status)
+	}
+
+	// Declining the confirmation cancels
+	typeKeys(m, "j")
+	if a := typeKeys(m, "xn"); a.kind != actionNone {
+		t.Errorf("declined kill returned %v, want no action", a.kind)
+	}
+	if m.mode != modeList {
+		t.Errorf("mode = %v, want list", m.mode)
+	}
+
+	a := typeKeys(m, "xy")
+	if a.kind != actionKill || a.agent.Name != "swift-fox" {
+		t.Errorf("confirmed kill returned %+v, want kill of swift-fox", a)
+	}
+}
+
+func TestHandleKeyInput(t *testing.T) {
+	m := &Model{}
+	m.SetSnapshot(testSnapshot())
+
+	a := typeKeys(m, "sAdd testz\x7fs\r")
+	if a.kind != actionSpawn || a.repo != "app" || a.text != "Add tests" {
+		t.Errorf("spawn returned %+v, want spawn in app with task %q", a, "Add tests")
+	}
+
+	// Keys typed into the input line aren't bindings
+	typeKeys(m, "jj")
+	a = typeKeys(m, "rqx Postgres\r")
+	if a.kind != actionRespond || a.agent.Name != "calm-owl" || a.text != "qx Postgres" {
+		t.Errorf("reply returned %+v, want reply to calm-owl", a)
+	}
+
+	// Escape and empty input cancel
+	for _, input := range []string{"rabc\x1b", "r  \r"} {
+		if a := typeKeys(m, input); a.kind != actionNone {
+			t.Errorf("%q returned %v, want no action", input, a.kind)
+		}
+		if m.mode != modeList {
+			t.Errorf("%q left mode %v, want list", input, m.mode)
+		}
+	}
+}
+
+func TestHandleKeyModes(t *testing.T) {
+	m := &Model{}
+	m.SetSnapshot(testSnapshot())
+
+	if a := typeKeys(m, "m"); a.kind != actionMessages || a.agent.Name != "supervisor" {
+		t.Errorf("m returned %+v, want messages for supervisor", a)
+	}
+	m.SetMessages([]Message{{From: "swift-fox", Status: "pending", Body: "done"}})
+	if m.mode != modeMessages {
+		t.Fatalf("mode = %v, want messages", m.mode)
+	}
+	// q closes the message view rather than quitting
+	if a := typeKeys(m, "q"); a.kind != actionNone || m.mode != modeList {
+		t.Errorf("q in messages returned %v in mode %v, want list", a.kind, m.mode)
+	}
+
+	typeKeys(m, "?")
+	if m.mode != modeHelp {
+		t.Errorf("mode = %v, want help", m.mode)
+	}
+	typeKeys(m, "\x1b")
+
+	for input, want := range map[string]actionKind{"a": actionAttach, "\r": actionAttach, "g": actionRefresh, "q": actionQuit, "\x03": actionQuit} {
+		if a := typeKeys(m, input); a.kind != want {
+			t.Errorf("%q returned %v, want %v", input, a.kind, want)
+		}
+	}
+}
+
+func TestView(t *testing.T) {
+	m := &Model{}
+	m.SetSnapshot(testSnapshot())
+	typeKeys(m, "j")
+	m.SetPreview(testSnapshot().Agents[1], "$ go test ./...\nok")
+
+	view := m.View(120, 20)
+	lines := strings.Split(view, "\n")
+	if len(lines) != 20 {
+		t.Errorf("view has %d lines, want 20", len(lines))
+	}
+	for _, want := range []string{"app/swift-fox", "app/calm-owl", "work/swift-fox", "Fix the login bug", "$ go test ./...", "question"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	m.SetSnapshot(Snapshot{})
+	if view := m.View(80, 10); !strings.Contains(view, "not running") {
+		t.Errorf("view without daemon should say so:\n%s", view)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abcd"},
+		{"héllo", 3, "hél"},
+		{"a\tb", 3, "a b"},
+		{"a\x1b[31mb", 4, "a[31"},
+		{"abc  ", 0, "abc"},
+	}
+	for _, tt := range tests {
+		if got := fit(tt.s, tt.width); got != tt.want {
+			t.Errorf("fit(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
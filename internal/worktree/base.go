@@ -0,0 +1,50 @@
+package worktree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BaseRef is a resolved base a task builds on: a remote branch, a tag, or a
+// commit
+type BaseRef struct {
+	Ref        string // As given (e.g. "release-2.1", "v2.1.0", or a SHA)
+	Branch     string // Remote branch name; empty for tags and commits
+	StartPoint string // Revision to create the worktree from (e.g. "origin/release-2.1")
+	Commit     string // Commit StartPoint resolved to
+}
+
+// ResolveBase checks that ref names a branch of remote, a tag, or a commit,
+// fetching from remote if it isn't known locally yet. Branches win over tags
+// of the same name, and a leading "<remote>/" is accepted.
+func (m *Manager) ResolveBase(remote, ref string) (BaseRef, error) {
+	ref = strings.TrimPrefix(ref, remote+"/")
+	if ref == "" {
+		return BaseRef{}, fmt.Errorf("base must not be empty")
+	}
+
+	if base, ok := m.lookupBase(remote, ref); ok {
+		return base, nil
+	}
+
+	// Fetch branches and tags, then the ref itself for commits no branch
+	// contains. Both are best effort: the lookup below reports what's missing.
+	runGit(m.repoPath, "fetch", "--tags", remote)
+	runGit(m.repoPath, "fetch", remote, ref)
+
+	if base, ok := m.lookupBase(remote, ref); ok {
+		return base, nil
+	}
+	return BaseRef{}, fmt.Errorf("%q is not a branch, tag, or commit of %s", ref, remote)
+}
+
+// lookupBase resolves ref against what the repository already has
+func (m *Manager) lookupBase(remote, ref string) (BaseRef, bool) {
+	if commit, err := runGit(m.repoPath, "rev-parse", "--verify", "--quiet", fmt.Sprintf("refs/remotes/%s/%s^{commit}", remote, ref)); err == nil {
+		return BaseRef{Ref: ref, Branch: ref, StartPoint: remote + "/" + ref, Commit: commit}, true
+	}
+	if commit, err := runGit(m.repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+		return BaseRef{Ref: ref, StartPoint: commit, Commit: commit}, true
+	}
+	return BaseRef{}, false
+}
@@ -0,0 +1,73 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveBase(t *testing.T) {
+	upstream, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	git(upstream, "branch", "release-1")
+	git(upstream, "tag", "v1.0")
+	first := git(upstream, "rev-parse", "HEAD")
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	git(filepath.Dir(clone), "clone", "-q", upstream, clone)
+	m := NewManager(clone)
+
+	tests := []struct {
+		ref        string
+		wantBranch string
+		wantStart  string
+	}{
+		{"release-1", "release-1", "origin/release-1"},
+		{"origin/release-1", "release-1", "origin/release-1"},
+		{"v1.0", "", first},
+		{first[:10], "", first},
+	}
+	for _, tt := range tests {
+		base, err := m.ResolveBase("origin", tt.ref)
+		if err != nil {
+			t.Errorf("ResolveBase(%q) failed: %v", tt.ref, err)
+			continue
+		}
+		if base.Branch != tt.wantBranch || base.StartPoint != tt.wantStart || base.Commit != first {
+			t.Errorf("ResolveBase(%q) = %+v", tt.ref, base)
+		}
+	}
+
+	// Refs created upstream after cloning are fetched on demand
+	os.WriteFile(filepath.Join(upstream, "new.txt"), []byte("x"), 0644)
+	git(upstream, "checkout", "-q", "-b", "develop")
+	git(upstream, "add", "new.txt")
+	git(upstream, "commit", "-q", "-m", "Develop work")
+	git(upstream, "tag", "v2.0")
+	develop := git(upstream, "rev-parse", "HEAD")
+
+	if base, err := m.ResolveBase("origin", "develop"); err != nil || base.Branch != "develop" || base.Commit != develop {
+		t.Errorf("ResolveBase(develop) = %+v, %v", base, err)
+	}
+	if base, err := m.ResolveBase("origin", "v2.0"); err != nil || base.Branch != "" || base.Commit != develop {
+		t.Errorf("ResolveBase(v2.0) = %+v, %v", base, err)
+	}
+
+	if _, err := m.ResolveBase("origin", "no-such-branch"); err == nil {
+		t.Error("ResolveBase should fail for a ref origin doesn't have")
+	}
+}
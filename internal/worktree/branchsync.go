@@ -0,0 +1,100 @@
+package worktree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExternalPush describes commits on a worktree branch's remote counterpart
+// that the worktree does not have and never had, i.e. pushed by someone else
+type ExternalPush struct {
+	Branch  string   // Local branch name
+	Remote  string   // Remote the branch is pushed to (e.g. "origin")
+	Head    string   // Commit the remote branch points at
+	Commits int      // Commits on the remote branch missing from the worktree
+	Authors []string // Distinct authors of those commits
+}
+
+// DetectExternalPush checks whether remote/<branch> has commits the worktree
+// lacks. It reads the remote-tracking ref, so fetch first. Remote heads the
+// branch's reflog has seen (e.g. the agent's own push before an amend) are not
+// reported. Returns nil when there is nothing new.
+func DetectExternalPush(worktreePath, remote string) (*ExternalPush, error) {
+	branch, err := GetCurrentBranch(worktreePath)
+	if err != nil || branch == "" || branch == "HEAD" {
+		return nil, err
+	}
+
+	remoteRef := remote + "/" + branch
+	head, err := runGit(worktreePath, "rev-parse", "--verify", "--quiet", "refs/remotes/"+remoteRef)
+	if err != nil || head == "" {
+		// Branch never pushed
+		return nil, nil
+	}
+
+	count, err := runGit(worktreePath, "rev-list", "--count", "HEAD.."+remoteRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare with %s: %w", remoteRef, err)
+	}
+	commits, _ := strconv.Atoi(count)
+	if commits == 0 {
+		return nil, nil
+	}
+
+	// The worktree produced this head itself and later rewrote it
+	if reflog, err := runGit(worktreePath, "reflog", "show", "--format=%H", "refs/heads/"+branch); err == nil {
+		for _, sha := range strings.Split(reflog, "\n") {
+			if sha == head {
+				return nil, nil
+			}
+		}
+	}
+
+	push := &ExternalPush{Branch: branch, Remote: remote, Head: head, Commits: commits}
+	authors, err := runGit(worktreePath, "log", "--format=%an", "HEAD.."+remoteRef)
+	if err == nil {
+		seen := make(map[string]bool)
+		for _, author := range strings.Split(authors, "\n") {
+			if author != "" && !seen[author] {
+				seen[author] = true
+				push.Authors = append(push.Authors, author)
+			}
+		}
+	}
+	return push, nil
+}
+
+// PullBranch rebases the worktree's branch onto its remote counterpart so it
+// picks up commits pushed by someone else. Uncommitted changes are stashed
+// and restored; a conflicting rebase is aborted.
+func PullBranch(worktreePath, remote string) RefreshResult {
+	result := RefreshResult{WorktreePath: worktreePath}
+
+	state, err := GetWorktreeState(worktreePath, remote, "")
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	switch {
+	case state.IsDetachedHEAD:
+		result.Skipped, result.SkipReason = true, "detached HEAD (checkout a branch first)"
+		return result
+	case state.IsMidRebase:
+		result.Skipped, result.SkipReason = true, "mid-rebase (run 'git rebase --continue' or 'git rebase --abort')"
+		return result
+	case state.IsMidMerge:
+		result.Skipped, result.SkipReason = true, "mid-merge (run 'git merge --continue' or 'git merge --abort')"
+		return result
+	}
+	result.Branch = state.Branch
+
+	if _, err := runGit(worktreePath, "fetch", remote, state.Branch); err != nil {
+		result.Error = fmt.Errorf("failed to fetch %s from %s: %w", state.Branch, remote, err)
+		return result
+	}
+
+	rebaseWithStash(worktreePath, remote+"/"+state.Branch, false, &result)
+	updateSubmodulesAfterRefresh(worktreePath, &result)
+	return result
+}
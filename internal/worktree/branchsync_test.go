@@ -0,0 +1,100 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectExternalPushAndPullBranch(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	commit := func(dir, name, author string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(dir, "add", name)
+		git(dir, "-c", "user.name="+author, "-c", "user.email=test@example.com", "commit", "-m", "Add "+name)
+	}
+
+	tmp := t.TempDir()
+	remotePath := filepath.Join(tmp, "remote.git")
+	git(tmp, "init", "--bare", remotePath)
+	git(repoPath, "remote", "add", "origin", remotePath)
+	git(repoPath, "push", "origin", "main")
+
+	// The agent's worktree pushes its branch
+	wtPath := filepath.Join(tmp, "wt")
+	if err := NewManager(repoPath).CreateNewBranch(wtPath, "work/agent", "main"); err != nil {
+		t.Fatalf("CreateNewBranch failed: %v", err)
+	}
+	commit(wtPath, "agent.txt", "Agent")
+	git(wtPath, "push", "origin", "work/agent")
+
+	if push, err := DetectExternalPush(wtPath, "origin"); err != nil || push != nil {
+		t.Fatalf("DetectExternalPush() = %v, %v; want nil for the agent's own push", push, err)
+	}
+
+	// Rewriting its own pushed commit is not an external push
+	git(wtPath, "commit", "--amend", "-m", "Add agent.txt (amended)")
+	if push, err := DetectExternalPush(wtPath, "origin"); err != nil || push != nil {
+		t.Fatalf("DetectExternalPush() = %v, %v; want nil after amending", push, err)
+	}
+	git(wtPath, "push", "--force", "origin", "work/agent")
+
+	// A human pushes to the same branch from another clone
+	otherPath := filepath.Join(tmp, "other")
+	git(tmp, "clone", "--branch", "work/agent", remotePath, otherPath)
+	commit(otherPath, "human.txt", "Human")
+	git(otherPath, "push", "origin", "work/agent")
+	git(wtPath, "fetch", "origin")
+
+	push, err := DetectExternalPush(wtPath, "origin")
+	if err != nil {
+		t.Fatalf("DetectExternalPush failed: %v", err)
+	}
+	if push == nil {
+		t.Fatal("DetectExternalPush() = nil, want the human's commit")
+	}
+	if push.Branch != "work/agent" || push.Commits != 1 || len(push.Authors) != 1 || push.Authors[0] != "Human" {
+		t.Errorf("DetectExternalPush() = %+v, want 1 commit on work/agent by Human", push)
+	}
+	if want := git(otherPath, "rev-parse", "HEAD"); push.Head != want {
+		t.Errorf("Head = %s, want %s", push.Head, want)
+	}
+
+	// Pulling keeps uncommitted work and picks up the pushed commit
+	if err := os.WriteFile(filepath.Join(wtPath, "wip.txt"), []byte("wip\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(wtPath, "add", "wip.txt")
+	result := PullBranch(wtPath, "origin")
+	if result.Error != nil || result.Skipped {
+		t.Fatalf("PullBranch() = %+v", result)
+	}
+	if !result.WasStashed {
+		t.Error("PullBranch() should have stashed uncommitted changes")
+	}
+	for _, name := range []string{"human.txt", "wip.txt"} {
+		if _, err := os.Stat(filepath.Join(wtPath, name)); err != nil {
+			t.Errorf("%s missing after pull: %v", name, err)
+		}
+	}
+	if push, err := DetectExternalPush(wtPath, "origin"); err != nil || push != nil {
+		t.Errorf("DetectExternalPush() = %v, %v; want nil after pulling", push, err)
+	}
+}
@@ -0,0 +1,138 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BloblessFilter is the partial clone filter that skips file contents. Git
+// fetches the blobs it needs on demand (e.g. when a worktree is checked out),
+// so the initial clone only transfers commits and trees.
+const BloblessFilter = "blob:none"
+
+// ValidateCloneFilter checks that filter is a partial clone filter spec git
+// understands: "blob:none", "blob:limit=<size>", or "tree:<depth>".
+func ValidateCloneFilter(filter string) error {
+	switch {
+	case filter == BloblessFilter:
+		return nil
+	case strings.HasPrefix(filter, "blob:limit="):
+		limit := strings.TrimRight(strings.TrimPrefix(filter, "blob:limit="), "kKmMgG")
+		if _, err := strconv.ParseUint(limit, 10, 64); err == nil {
+			return nil
+		}
+	case strings.HasPrefix(filter, "tree:"):
+		if _, err := strconv.ParseUint(strings.TrimPrefix(filter, "tree:"), 10, 64); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid clone filter %q (use blob:none, blob:limit=<size>, or tree:<depth>)", filter)
+}
+
+// CloneOptions configures how a tracked repository is cloned
+type CloneOptions struct {
+	// Filter makes a partial clone (e.g. BloblessFilter)
+	Filter string
+	// Reference borrows objects from a local repository (e.g. a shared mirror)
+	// via git alternates instead of downloading them again
+	Reference string
+	// RecurseSubmodules also clones the repository's submodules
+	RecurseSubmodules bool
+}
+
+// CloneArgs returns the git arguments that clone url into path
+func CloneArgs(url, path string, opts CloneOptions) []string {
+	args := []string{"clone"}
+	if opts.Filter != "" {
+		args = append(args, "--filter="+opts.Filter)
+	}
+	if opts.Reference != "" {
+		args = append(args, "--reference", opts.Reference)
+	}
+	if opts.RecurseSubmodules {
+		args = append(args, "--recurse-submodules")
+	}
+	return append(args, url, path)
+}
+
+// MirrorName returns the directory name for the shared mirror of an upstream
+// URL, e.g. "github.com-owner-repo.git" for https://github.com/owner/repo
+func MirrorName(url string) string {
+	name := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if idx := strings.Index(name, "://"); idx != -1 {
+		name = name[idx+3:]
+	}
+	if at := strings.Index(name, "@"); at != -1 {
+		name = name[at+1:]
+	}
+	name = strings.NewReplacer("/", "-", ":", "-").Replace(name)
+	return name + ".git"
+}
+
+// EnsureMirror creates a bare mirror of url at mirrorPath, or fetches the
+// latest objects into it if it already exists. Repositories cloned with the
+// mirror as their Reference share its objects, so forks of the same upstream
+// only store and download what differs.
+func EnsureMirror(mirrorPath, url string) error {
+	if _, err := os.Stat(mirrorPath); err == nil {
+		return UpdateMirror(mirrorPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(mirrorPath), 0755); err != nil {
+		return fmt.Errorf("failed to create mirrors directory: %w", err)
+	}
+	cmd := gitCommand("clone", "--mirror", url, mirrorPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create mirror: %w\nOutput: %s", err, output)
+	}
+
+	// Borrowing repositories reference objects the mirror may later consider
+	// unreachable (e.g. after a force-push), so gc must never prune them
+	cmd = gitCommand("config", "gc.pruneExpire", "never")
+	cmd.Dir = mirrorPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to configure mirror: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// UpdateMirror fetches all refs into an existing mirror
+func UpdateMirror(mirrorPath string) error {
+	cmd := gitCommand("fetch", "--quiet", "origin")
+	cmd.Dir = mirrorPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to update mirror: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// PartialCloneFilter returns the filter repoPath was cloned with, or an empty
+// string for a full clone
+func PartialCloneFilter(repoPath string) string {
+	cmd := gitCommand("config", "--get-regexp", `^remote\..*\.partialclonefilter$`)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		// git config exits 1 when nothing matches
+		return ""
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if _, value, ok := strings.Cut(line, " "); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// partialCloneHint explains a failed worktree checkout in a partial clone,
+// where missing file contents must be fetched from the remote
+func (m *Manager) partialCloneHint() string {
+	filter := PartialCloneFilter(m.repoPath)
+	if filter == "" {
+		return ""
+	}
+	return fmt.Sprintf("\nNote: this repository is a partial clone (--filter=%s); git fetches file contents from the remote on checkout, so check network access and credentials", filter)
+}
@@ -0,0 +1,116 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateCloneFilter(t *testing.T) {
+	for _, filter := range []string{"blob:none", "blob:limit=1m", "blob:limit=1024", "tree:0"} {
+		if err := ValidateCloneFilter(filter); err != nil {
+			t.Errorf("ValidateCloneFilter(%q) = %v, want nil", filter, err)
+		}
+	}
+	for _, filter := range []string{"", "none", "blob:limit=", "blob:limit=big", "tree:", "tree:-1"} {
+		if err := ValidateCloneFilter(filter); err == nil {
+			t.Errorf("ValidateCloneFilter(%q) should fail", filter)
+		}
+	}
+}
+
+func TestCloneArgs(t *testing.T) {
+	tests := []struct {
+		opts CloneOptions
+		want []string
+	}{
+		{CloneOptions{}, []string{"clone", "url", "path"}},
+		{CloneOptions{Filter: BloblessFilter}, []string{"clone", "--filter=blob:none", "url", "path"}},
+		{CloneOptions{Reference: "/m.git"}, []string{"clone", "--reference", "/m.git", "url", "path"}},
+		{CloneOptions{RecurseSubmodules: true}, []string{"clone", "--recurse-submodules", "url", "path"}},
+	}
+	for _, tt := range tests {
+		if got := CloneArgs("url", "path", tt.opts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CloneArgs(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestPartialClone(t *testing.T) {
+	upstream, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	if got := PartialCloneFilter(upstream); got != "" {
+		t.Errorf("PartialCloneFilter() on a full repo = %q, want empty", got)
+	}
+
+	cmd := exec.Command("git", "config", "uploadpack.allowFilter", "true")
+	cmd.Dir = upstream
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git config failed: %v\n%s", err, output)
+	}
+
+	clonePath := filepath.Join(t.TempDir(), "clone")
+	cmd = exec.Command("git", CloneArgs("file://"+upstream, clonePath, CloneOptions{Filter: BloblessFilter})...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("partial clone failed: %v\n%s", err, output)
+	}
+
+	if got := PartialCloneFilter(clonePath); got != BloblessFilter {
+		t.Errorf("PartialCloneFilter() = %q, want %q", got, BloblessFilter)
+	}
+
+	// Checking out a worktree fetches the missing blobs on demand
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	if err := NewManager(clonePath).CreateNewBranch(wtPath, "work/test", "HEAD"); err != nil {
+		t.Fatalf("CreateNewBranch() in partial clone failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "README.md")); err != nil {
+		t.Errorf("README.md missing from worktree: %v", err)
+	}
+}
+
+func TestMirrorName(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/owner/repo":     "github.com-owner-repo.git",
+		"https://github.com/owner/repo.git": "github.com-owner-repo.git",
+		"git@github.com:owner/repo.git":     "github.com-owner-repo.git",
+	}
+	for url, want := range tests {
+		if got := MirrorName(url); got != want {
+			t.Errorf("MirrorName(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestMirrorReference(t *testing.T) {
+	upstream, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	mirrorPath := filepath.Join(t.TempDir(), "mirrors", MirrorName(upstream))
+	if err := EnsureMirror(mirrorPath, upstream); err != nil {
+		t.Fatalf("EnsureMirror() failed: %v", err)
+	}
+
+	clonePath := filepath.Join(t.TempDir(), "clone")
+	cmd := exec.Command("git", CloneArgs(upstream, clonePath, CloneOptions{Reference: mirrorPath})...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("clone with reference failed: %v\n%s", err, output)
+	}
+
+	alternates, err := os.ReadFile(filepath.Join(clonePath, ".git", "objects", "info", "alternates"))
+	if err != nil {
+		t.Fatalf("clone should borrow objects from the mirror: %v", err)
+	}
+	if !strings.Contains(string(alternates), mirrorPath) {
+		t.Errorf("alternates = %q, want it to reference %s", alternates, mirrorPath)
+	}
+
+	// A second call updates the existing mirror
+	if err := EnsureMirror(mirrorPath, upstream); err != nil {
+		t.Errorf("EnsureMirror() on existing mirror failed: %v", err)
+	}
+}
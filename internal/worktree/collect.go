@@ -0,0 +1,72 @@
+package worktree
+
+import "sync"
+
+// DefaultCollectWorkers bounds how many worktrees are inspected at once.
+// Each inspection runs a handful of short git commands, so a few more
+// workers than CPUs keeps them busy without flooding the machine.
+const DefaultCollectWorkers = 8
+
+// StateQuery names a worktree and the remote branch its state is measured
+// against, as passed to GetWorktreeState
+type StateQuery struct {
+	Path       string
+	Remote     string
+	MainBranch string
+}
+
+// CollectStates runs GetWorktreeState for every query, at most workers at a
+// time (DefaultCollectWorkers when workers < 1). The states and errors line
+// up with the queries.
+func CollectStates(queries []StateQuery, workers int) ([]WorktreeState, []error) {
+	states := make([]WorktreeState, len(queries))
+	errs := make([]error, len(queries))
+	forEachBounded(len(queries), workers, func(i int) {
+		q := queries[i]
+		states[i], errs[i] = GetWorktreeState(q.Path, q.Remote, q.MainBranch)
+	})
+	return states, errs
+}
+
+// CollectBranches returns the current branch of each worktree, at most
+// workers at a time. Worktrees whose branch can't be read get "".
+func CollectBranches(paths []string, workers int) []string {
+	branches := make([]string, len(paths))
+	forEachBounded(len(paths), workers, func(i int) {
+		if paths[i] == "" {
+			return
+		}
+		if b, err := GetCurrentBranch(paths[i]); err == nil {
+			branches[i] = b
+		}
+	})
+	return branches
+}
+
+// forEachBounded calls fn for 0..n-1 from at most workers goroutines and
+// waits for all calls to finish
+func forEachBounded(n, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = DefaultCollectWorkers
+	}
+	if workers > n {
+		workers = n
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
@@ -0,0 +1,90 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCollectStatesAndBranches(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	m := NewManager(repoPath)
+	parent := t.TempDir()
+	var paths []string
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(parent, fmt.Sprintf("wt%d", i))
+		if err := m.CreateNewBranch(path, fmt.Sprintf("work/w%d", i), "main"); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	if err := os.WriteFile(filepath.Join(paths[2], "dirty.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	paths = append(paths, filepath.Join(parent, "missing"))
+
+	queries := make([]StateQuery, len(paths))
+	for i, path := range paths {
+		// No remote here, so measure against the local main branch
+		queries[i] = StateQuery{Path: path, Remote: ".", MainBranch: "main"}
+	}
+	states, errs := CollectStates(queries, 2)
+	for i := 0; i < 5; i++ {
+		if errs[i] != nil {
+			t.Fatalf("CollectStates()[%d] error = %v", i, errs[i])
+		}
+		if want := fmt.Sprintf("work/w%d", i); states[i].Branch != want || states[i].Path != paths[i] {
+			t.Errorf("state %d = %+v, want branch %s", i, states[i], want)
+		}
+		if states[i].HasUncommitted != (i == 2) {
+			t.Errorf("state %d HasUncommitted = %v", i, states[i].HasUncommitted)
+		}
+	}
+	if errs[5] == nil {
+		t.Error("a missing worktree should report an error")
+	}
+
+	branches := CollectBranches(append(paths, ""), 0)
+	for i := 0; i < 5; i++ {
+		if want := fmt.Sprintf("work/w%d", i); branches[i] != want {
+			t.Errorf("CollectBranches()[%d] = %q, want %q", i, branches[i], want)
+		}
+	}
+	if branches[5] != "" || branches[6] != "" {
+		t.Errorf("unreadable worktrees should have no branch, got %q", branches[5:])
+	}
+}
+
+func TestForEachBoundedLimitsConcurrency(t *testing.T) {
+	var running, peak, calls int32
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		forEachBounded(10, 3, func(i int) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			<-release
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&calls, 1)
+		})
+		close(done)
+	}()
+	for i := 0; i < 10; i++ {
+		release <- struct{}{}
+	}
+	<-done
+	if calls != 10 || peak > 3 {
+		t.Errorf("calls = %d, peak concurrency = %d, want 10 calls with at most 3 at once", calls, peak)
+	}
+
+	forEachBounded(0, 4, func(int) { t.Error("fn called for an empty range") })
+}
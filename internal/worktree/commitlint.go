@@ -0,0 +1,181 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ConventionalCommitPattern matches subjects following the Conventional Commits spec,
+// e.g. "fix(cli): handle empty task" or "feat!: drop legacy flag".
+const ConventionalCommitPattern = `^(feat|fix|docs|style|refactor|perf|test|build|ci|chore|revert)(\([\w./-]+\))?!?: \S`
+
+// BranchCommit is a commit on a branch that is not yet on the base branch
+type BranchCommit struct {
+	SHA     string
+	Subject string
+	Merge   bool
+}
+
+// ListBranchCommits returns the commits in base..HEAD, oldest first
+func ListBranchCommits(worktreePath, base string) ([]BranchCommit, error) {
+	output, err := runGit(worktreePath, "log", "--reverse", "--format=%H%x00%P%x00%s", base+"..HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits since %s: %w", base, err)
+	}
+
+	var commits []BranchCommit
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commits = append(commits, BranchCommit{
+			SHA:     fields[0],
+			Subject: fields[2],
+			Merge:   len(strings.Fields(fields[1])) > 1,
+		})
+	}
+	return commits, nil
+}
+
+// conventionalVerb maps a leading imperative verb to a Conventional Commits type
+type conventionalVerb struct {
+	commitType string
+	keepVerb   bool // "Add X" -> "feat: add X" rather than "feat: X"
+}
+
+var conventionalVerbs = map[string]conventionalVerb{
+	"fix": {"fix", false}, "fixes": {"fix", false}, "fixed": {"fix", false},
+	"add": {"feat", true}, "implement": {"feat", true}, "introduce": {"feat", true}, "support": {"feat", true},
+	"docs": {"docs", false}, "document": {"docs", true},
+	"refactor": {"refactor", false}, "simplify": {"refactor", true}, "rename": {"refactor", true},
+	"test": {"test", false}, "tests": {"test", false},
+	"bump": {"chore", true}, "upgrade": {"chore", true},
+	"revert": {"revert", false},
+}
+
+// SuggestConventionalSubject rewrites a subject such as "Fix nil map in router"
+// into "fix: nil map in router". It returns false if the type can't be inferred.
+func SuggestConventionalSubject(subject string) (string, bool) {
+	words := strings.SplitN(strings.TrimSpace(subject), " ", 2)
+	if len(words) < 2 || strings.TrimSpace(words[1]) == "" {
+		return "", false
+	}
+
+	verb, ok := conventionalVerbs[strings.ToLower(strings.TrimSuffix(words[0], ":"))]
+	if !ok {
+		return "", false
+	}
+
+	description := strings.TrimSpace(words[1])
+	if verb.keepVerb {
+		description = strings.ToLower(words[0]) + " " + description
+	}
+	return verb.commitType + ": " + description, true
+}
+
+// LintCommitSubjects returns the commits whose subject does not match pattern
+func LintCommitSubjects(commits []BranchCommit, pattern *regexp.Regexp) []BranchCommit {
+	var bad []BranchCommit
+	for _, c := range commits {
+		if !pattern.MatchString(c.Subject) {
+			bad = append(bad, c)
+		}
+	}
+	return bad
+}
+
+// RewordCommits rewrites the subjects of commits in base..HEAD, keeping trees,
+// bodies and authorship, and moves the current branch to the rewritten history.
+// subjects maps commit SHAs to their new subject. Merge commits are not supported.
+func RewordCommits(worktreePath, base string, subjects map[string]string) error {
+	commits, err := ListBranchCommits(worktreePath, base)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+
+	oldHead, err := runGit(worktreePath, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+
+	parent, err := runGit(worktreePath, "rev-parse", commits[0].SHA+"^")
+	if err != nil {
+		return err
+	}
+
+	for _, c := range commits {
+		if c.Merge {
+			return fmt.Errorf("cannot reword merge commit %s", c.SHA[:12])
+		}
+
+		info, err := runGit(worktreePath, "log", "-1", "--format=%T%x00%an%x00%ae%x00%aD%x00%B", c.SHA)
+		if err != nil {
+			return err
+		}
+		fields := strings.SplitN(info, "\x00", 5)
+		if len(fields) != 5 {
+			return fmt.Errorf("unexpected commit format for %s", c.SHA)
+		}
+		tree, message := fields[0], fields[4]
+
+		if subject, ok := subjects[c.SHA]; ok {
+			lines := strings.SplitN(message, "\n", 2)
+			lines[0] = subject
+			message = strings.Join(lines, "\n")
+		}
+
+		cmd := gitCommand("commit-tree", tree, "-p", parent, "-F", "-")
+		cmd.Dir = worktreePath
+		cmd.Stdin = strings.NewReader(message)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME="+fields[1],
+			"GIT_AUTHOR_EMAIL="+fields[2],
+			"GIT_AUTHOR_DATE="+fields[3],
+		)
+		output, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("failed to rewrite commit %s: %w", c.SHA[:12], err)
+		}
+		parent = strings.TrimSpace(string(output))
+	}
+
+	if _, err := runGit(worktreePath, "update-ref", "-m", "multiclaude: reword commits", "HEAD", parent, oldHead); err != nil {
+		return fmt.Errorf("failed to update branch: %w", err)
+	}
+	return nil
+}
+
+// HasUpstream reports whether the worktree's current branch tracks a remote branch
+func HasUpstream(worktreePath string) bool {
+	_, err := runGit(worktreePath, "rev-parse", "--abbrev-ref", "@{upstream}")
+	return err == nil
+}
+
+// PushHeadWithLease pushes the worktree's HEAD to branch on remote,
+// replacing history only if the remote branch is still at expected
+func PushHeadWithLease(worktreePath, remote, branch, expected string) error {
+	ref := "refs/heads/" + branch
+	cmd := gitCommand("push", "--force-with-lease="+ref+":"+expected, remote, "HEAD:"+ref)
+	cmd.Dir = worktreePath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// ForcePushWithLease pushes the current branch to its upstream, replacing
+// history only if the remote still points where we last saw it
+func ForcePushWithLease(worktreePath string) error {
+	cmd := gitCommand("push", "--force-with-lease")
+	cmd.Dir = worktreePath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
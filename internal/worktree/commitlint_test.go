@@ -0,0 +1,101 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSuggestConventionalSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+		ok      bool
+	}{
+		{"Fix nil map in router", "fix: nil map in router", true},
+		{"fixed flaky test", "fix: flaky test", true},
+		{"Add retry to webhook sender", "feat: add retry to webhook sender", true},
+		{"Bump yaml to v3.0.1", "chore: bump yaml to v3.0.1", true},
+		{"WIP", "", false},
+		{"Stuff and things", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := SuggestConventionalSubject(tt.subject)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("SuggestConventionalSubject(%q) = %q, %v; want %q, %v", tt.subject, got, ok, tt.want, tt.ok)
+		}
+		if ok && !regexp.MustCompile(ConventionalCommitPattern).MatchString(got) {
+			t.Errorf("suggestion %q does not match the conventional pattern", got)
+		}
+	}
+}
+
+func TestRewordCommits(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-lint")
+	if err := manager.CreateNewBranch(wtPath, "work/lint", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	for i, msg := range []string{"feat: good subject", "Fix broken thing\n\nLonger body text."} {
+		file := filepath.Join(wtPath, "file"+string(rune('a'+i))+".txt")
+		if err := os.WriteFile(file, []byte(msg), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", msg}} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = wtPath
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v failed: %v\n%s", args, err, output)
+			}
+		}
+	}
+
+	commits, err := ListBranchCommits(wtPath, "main")
+	if err != nil {
+		t.Fatalf("ListBranchCommits failed: %v", err)
+	}
+	if len(commits) != 2 || commits[0].Subject != "feat: good subject" {
+		t.Fatalf("unexpected commits: %+v", commits)
+	}
+
+	bad := LintCommitSubjects(commits, regexp.MustCompile(ConventionalCommitPattern))
+	if len(bad) != 1 || bad[0].Subject != "Fix broken thing" {
+		t.Fatalf("LintCommitSubjects = %+v", bad)
+	}
+
+	if err := RewordCommits(wtPath, "main", map[string]string{bad[0].SHA: "fix: broken thing"}); err != nil {
+		t.Fatalf("RewordCommits failed: %v", err)
+	}
+
+	commits, err = ListBranchCommits(wtPath, "main")
+	if err != nil {
+		t.Fatalf("ListBranchCommits failed: %v", err)
+	}
+	if len(commits) != 2 || commits[1].Subject != "fix: broken thing" || commits[0].Subject != "feat: good subject" {
+		t.Errorf("commits after reword: %+v", commits)
+	}
+
+	body, err := runGit(wtPath, "log", "-1", "--format=%B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, "Longer body text.") {
+		t.Errorf("commit body not preserved: %q", body)
+	}
+
+	status, err := runGit(wtPath, "status", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "" {
+		t.Errorf("worktree should be clean after reword, got: %s", status)
+	}
+}
@@ -0,0 +1,56 @@
+package worktree
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictFile summarizes the conflict hunks left in one file by a failed
+// rebase or merge
+type ConflictFile struct {
+	Path  string `json:"path"`
+	Hunks int    `json:"hunks"`
+	// Lines are where each hunk starts (1-based)
+	Lines []int `json:"lines,omitempty"`
+}
+
+// String returns e.g. "api/server.go (2 hunks at lines 14, 80)"
+func (c ConflictFile) String() string {
+	if c.Hunks == 0 {
+		return c.Path
+	}
+	lines := make([]string, len(c.Lines))
+	for i, line := range c.Lines {
+		lines[i] = fmt.Sprint(line)
+	}
+	noun, at := "hunks", "lines"
+	if c.Hunks == 1 {
+		noun, at = "hunk", "line"
+	}
+	return fmt.Sprintf("%s (%d %s at %s %s)", c.Path, c.Hunks, noun, at, strings.Join(lines, ", "))
+}
+
+// SummarizeConflicts counts the conflict markers in each of files while the
+// conflict is still checked out. Files it cannot read (e.g. deleted on one
+// side) are listed without hunks.
+func SummarizeConflicts(worktreePath string, files []string) []ConflictFile {
+	summary := make([]ConflictFile, 0, len(files))
+	for _, file := range files {
+		conflict := ConflictFile{Path: file}
+		if f, err := os.Open(filepath.Join(worktreePath, file)); err == nil {
+			scanner := bufio.NewScanner(f)
+			for line := 1; scanner.Scan(); line++ {
+				if strings.HasPrefix(scanner.Text(), "<<<<<<< ") {
+					conflict.Hunks++
+					conflict.Lines = append(conflict.Lines, line)
+				}
+			}
+			f.Close()
+		}
+		summary = append(summary, conflict)
+	}
+	return summary
+}
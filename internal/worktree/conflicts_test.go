@@ -0,0 +1,81 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRefreshWorktreeSummarizesConflicts(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	write := func(dir, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "shared.txt"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	write(repoPath, "one\ntwo\nthree\n")
+	git(repoPath, "add", "shared.txt")
+	git(repoPath, "commit", "-m", "Add shared file")
+	git(repoPath, "remote", "add", "origin", repoPath)
+	git(repoPath, "fetch", "origin")
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-conflict")
+	if err := manager.CreateNewBranch(wtPath, "feature/conflict", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	defer manager.Remove(wtPath, true)
+
+	write(wtPath, "one\nTWO from the branch\nthree\n")
+	git(wtPath, "commit", "-am", "Change line two on the branch")
+	write(repoPath, "one\nTWO from main\nthree\n")
+	git(repoPath, "commit", "-am", "Change line two on main")
+	git(repoPath, "fetch", "origin")
+
+	result := RefreshWorktree(wtPath, "origin", "main")
+	if !result.HasConflicts {
+		t.Fatalf("expected conflicts, got %+v", result)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Path != "shared.txt" || result.Conflicts[0].Hunks != 1 || result.Conflicts[0].Lines[0] != 2 {
+		t.Errorf("Conflicts = %+v, want one hunk in shared.txt at line 2", result.Conflicts)
+	}
+	if got := result.Conflicts[0].String(); got != "shared.txt (1 hunk at line 2)" {
+		t.Errorf("String() = %q", got)
+	}
+	if result.Paused {
+		t.Error("RefreshWorktree should abort the rebase, not pause it")
+	}
+
+	// Keeping conflicts leaves the rebase paused, with local changes stashed
+	if err := os.WriteFile(filepath.Join(wtPath, "notes.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result = RefreshWorktreeKeepingConflicts(wtPath, "origin", "main")
+	if !result.Paused || !result.HasConflicts || !result.WasStashed || result.StashRestored {
+		t.Fatalf("expected a paused rebase with changes stashed, got %+v", result)
+	}
+	state, err := GetWorktreeState(wtPath, "origin", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.IsMidRebase {
+		t.Error("the worktree should be left mid-rebase")
+	}
+	if stashes, err := runGit(wtPath, "stash", "list"); err != nil || !strings.Contains(stashes, RefreshStashPrefix) {
+		t.Errorf("the refresh stash should be kept: %q, %v", stashes, err)
+	}
+}
@@ -0,0 +1,258 @@
+package worktree
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ScopedCredentials describes the git credentials a single worktree may use.
+// Instead of inheriting the user's global credential helpers, the worktree is
+// given a GIT_ASKPASS helper that reads a fine-grained, repo-scoped token from
+// TokenFile, a push URL that authenticates with it, and a default push refspec
+// and pre-push hook limited to AllowedBranch.
+//
+// What is enforced, and by what:
+//   - Which repositories the worktree can push to: only the token's scope.
+//     Classic GitHub tokens, which reach every repository the account can,
+//     are refused.
+//   - Which branch a plain "git push" updates: remote.<name>.push.
+//   - Which branches an explicit push may update: the pre-push hook, which
+//     "git push --no-verify" skips. Branch protection on the remote is the
+//     only boundary an agent can't step around.
+//
+// The agent runs as the same user, so it can read TokenFile (and a
+// GIT_ASKPASS set in its environment takes precedence over core.askPass).
+// SSH remotes are refused: they authenticate with the user's SSH keys, which
+// no per-worktree setting can scope.
+type ScopedCredentials struct {
+	// TokenFile is the path to a file containing the access token.
+	// The token is read at push time and never copied into the worktree.
+	TokenFile string
+	// Username is sent for username prompts (default: "x-access-token")
+	Username string
+	// AllowedBranch is the only remote branch the worktree may push to
+	AllowedBranch string
+	// Remote is the remote the worktree pushes to (default: "origin")
+	Remote string
+}
+
+// unscopedTokenPrefixes are GitHub token types that can't be limited to a
+// repository: classic personal access tokens, and OAuth and user-to-server
+// tokens such as the one gh uses
+var unscopedTokenPrefixes = []string{"ghp_", "gho_", "ghu_"}
+
+// WorktreeConfigEnabled reports whether the repository containing path has
+// extensions.worktreeConfig set. ProvisionScopedCredentials sets it, which
+// makes older git versions and other tools refuse the repository.
+func WorktreeConfigEnabled(path string) bool {
+	cmd := gitCommand("config", "--bool", "extensions.worktreeConfig")
+	cmd.Dir = path
+	output, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(output)) == "true"
+}
+
+// scopedPushURL returns the remote's push URL with any embedded credentials
+// replaced by username, so pushes authenticate through the askpass helper.
+// SSH and local remotes are refused.
+func scopedPushURL(worktreePath, remote, username string) (string, error) {
+	cmd := gitCommand("remote", "get-url", "--push", remote)
+	cmd.Dir = worktreePath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("remote %s not found", remote)
+	}
+	// Worktree config adds to multi-valued keys rather than replacing them,
+	// so a pushurl or push refspec in the shared config would still apply
+	for _, key := range []string{"pushurl", "push"} {
+		cmd := gitCommand("config", "--get-all", "remote."+remote+"."+key)
+		cmd.Dir = worktreePath
+		if out, _ := cmd.Output(); len(strings.TrimSpace(string(out))) > 0 {
+			return "", fmt.Errorf("remote %s sets remote.%s.%s, which a worktree can't override", remote, remote, key)
+		}
+	}
+	raw := strings.TrimSpace(string(output))
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("remote %s (%s) is not an HTTPS remote; scoped credentials can't restrict SSH keys or local paths", remote, raw)
+	}
+	u.User = url.User(username)
+	return u.String(), nil
+}
+
+// worktreeAdminDir returns the worktree's private git directory
+// (e.g. <repo>/.git/worktrees/<name>), which git removes along with the worktree.
+func worktreeAdminDir(worktreePath string) (string, error) {
+	cmd := gitCommand("rev-parse", "--absolute-git-dir")
+	cmd.Dir = worktreePath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git dir: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// setWorktreeConfig sets a config value that only applies to this worktree.
+// It enables extensions.worktreeConfig on the repository if needed.
+func setWorktreeConfig(worktreePath, key, value string) error {
+	if err := RequireGitFeature(FeatureWorktreeConfig); err != nil {
+		return err
+	}
+	cmd := gitCommand("config", "extensions.worktreeConfig", "true")
+	cmd.Dir = worktreePath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to enable worktree config: %w\nOutput: %s", err, output)
+	}
+
+	cmd = gitCommand("config", "--worktree", key, value)
+	cmd.Dir = worktreePath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set %s: %w\nOutput: %s", key, err, output)
+	}
+	return nil
+}
+
+// worktreeHooksDir returns the per-worktree hooks directory, creating it and
+// pointing core.hooksPath at it for this worktree only. Hooks installed there
+// chain to the repository's shared hook of the same name, if any.
+func worktreeHooksDir(worktreePath string) (string, error) {
+	adminDir, err := worktreeAdminDir(worktreePath)
+	if err != nil {
+		return "", err
+	}
+
+	hooksDir := filepath.Join(adminDir, "multiclaude", "hooks")
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	if err := setWorktreeConfig(worktreePath, "core.hooksPath", hooksDir); err != nil {
+		return "", err
+	}
+	return hooksDir, nil
+}
+
+// writeHook writes an executable hook script whose body runs before the
+// repository's shared hook of the same name. Bodies that consume stdin must
+// save it in $input so it can be replayed to the shared hook.
+func writeHook(hooksDir, name, body string) error {
+	script := "#!/bin/sh\n# Installed by multiclaude\n" + body +
+		"\nshared=\"$(git rev-parse --git-common-dir)/hooks/" + name + "\"\n" +
+		"if [ -x \"$shared\" ]; then\n" +
+		"  if [ -n \"${input+set}\" ]; then\n" +
+		"    printf '%s\\n' \"$input\" | \"$shared\" \"$@\"\n" +
+		"    exit $?\n" +
+		"  fi\n" +
+		"  exec \"$shared\" \"$@\"\n" +
+		"fi\nexit 0\n"
+	path := filepath.Join(hooksDir, name)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write %s hook: %w", name, err)
+	}
+	return nil
+}
+
+// shellQuote quotes a string for safe use in a POSIX shell script
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ProvisionScopedCredentials configures a worktree to use scoped credentials.
+// Global credential helpers are disabled for the worktree, core.askPass points
+// at a generated helper that reads the token file, the remote's push URL and
+// default push refspec are rewritten for this worktree, and a pre-push hook
+// rejects pushes to any branch other than AllowedBranch. See ScopedCredentials
+// for which of these can be bypassed.
+func ProvisionScopedCredentials(worktreePath string, creds ScopedCredentials) error {
+	if creds.TokenFile == "" {
+		return fmt.Errorf("token file is required")
+	}
+	if creds.AllowedBranch == "" {
+		return fmt.Errorf("allowed branch is required")
+	}
+
+	tokenFile, err := filepath.Abs(creds.TokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve token file path: %w", err)
+	}
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return fmt.Errorf("token file not accessible: %w", err)
+	}
+	for _, prefix := range unscopedTokenPrefixes {
+		if strings.HasPrefix(strings.TrimSpace(string(token)), prefix) {
+			return fmt.Errorf("token file holds a %s... token, which can push to every repository the account can; use a fine-grained token limited to this repository", prefix)
+		}
+	}
+
+	username := creds.Username
+	if username == "" {
+		username = "x-access-token"
+	}
+	remote := creds.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	pushURL, err := scopedPushURL(worktreePath, remote, username)
+	if err != nil {
+		return err
+	}
+
+	adminDir, err := worktreeAdminDir(worktreePath)
+	if err != nil {
+		return err
+	}
+	credDir := filepath.Join(adminDir, "multiclaude")
+	if err := os.MkdirAll(credDir, 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+
+	askPass := filepath.Join(credDir, "askpass.sh")
+	askPassScript := "#!/bin/sh\n# Installed by multiclaude: scoped credentials for this worktree\n" +
+		"case \"$1\" in\n" +
+		"  Username*) echo " + shellQuote(username) + " ;;\n" +
+		"  *) tr -d '\\n' < " + shellQuote(tokenFile) + "; echo ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(askPass, []byte(askPassScript), 0700); err != nil {
+		return fmt.Errorf("failed to write askpass helper: %w", err)
+	}
+
+	// An empty credential.helper resets the list inherited from global config,
+	// so git falls back to core.askPass for HTTPS authentication.
+	if err := setWorktreeConfig(worktreePath, "credential.helper", ""); err != nil {
+		return err
+	}
+	if err := setWorktreeConfig(worktreePath, "core.askPass", askPass); err != nil {
+		return err
+	}
+	// The push URL drops credentials embedded in the remote's URL, and the
+	// refspec makes a plain "git push" update only the agent's branch
+	if err := setWorktreeConfig(worktreePath, "remote."+remote+".pushurl", pushURL); err != nil {
+		return err
+	}
+	ref := "refs/heads/" + creds.AllowedBranch
+	if err := setWorktreeConfig(worktreePath, "remote."+remote+".push", ref+":"+ref); err != nil {
+		return err
+	}
+
+	hooksDir, err := worktreeHooksDir(worktreePath)
+	if err != nil {
+		return err
+	}
+	prePush := "allowed=" + shellQuote("refs/heads/"+creds.AllowedBranch) + "\n" +
+		"input=$(cat)\n" +
+		"echo \"$input\" | while read local_ref local_sha remote_ref remote_sha; do\n" +
+		"  [ -z \"$remote_ref\" ] && continue\n" +
+		"  if [ \"$remote_ref\" != \"$allowed\" ]; then\n" +
+		"    echo \"multiclaude: this worktree may only push to $allowed (refused $remote_ref)\" >&2\n" +
+		"    exit 1\n" +
+		"  fi\n" +
+		"done || exit 1\n"
+	if err := writeHook(hooksDir, "pre-push", prePush); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package worktree
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// DiskUsage returns the bytes a worktree takes on disk: its checked-out
+// files plus its private git directory (index, HEAD, logs) under the
+// repository's .git/worktrees. Objects are shared with the repository and
+// not counted.
+func (m *Manager) DiskUsage(path string) (int64, error) {
+	total, err := dirSize(path)
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := gitCommand("rev-parse", "--absolute-git-dir")
+	cmd.Dir = path
+	output, err := cmd.Output()
+	if err != nil {
+		return total, nil
+	}
+	gitDir := strings.TrimSpace(string(output))
+	// The main worktree's git directory holds the shared objects
+	if filepath.Base(filepath.Dir(gitDir)) != "worktrees" {
+		return total, nil
+	}
+	admin, err := dirSize(gitDir)
+	if err != nil {
+		return total, nil
+	}
+	return total + admin, nil
+}
+
+// dirSize sums the sizes of the regular files under root, without following
+// symlinks. Files removed while walking are skipped.
+func dirSize(root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,46 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	createBranch(t, repoPath, "usage")
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	if err := manager.Create(wtPath, "usage"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	before, err := manager.DiskUsage(wtPath)
+	if err != nil {
+		t.Fatalf("DiskUsage failed: %v", err)
+	}
+	files, err := dirSize(wtPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before <= files {
+		t.Errorf("DiskUsage = %d, want more than the %d bytes of files, counting the worktree's index", before, files)
+	}
+
+	if err := os.WriteFile(filepath.Join(wtPath, "build.out"), make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	after, err := manager.DiskUsage(wtPath)
+	if err != nil {
+		t.Fatalf("DiskUsage failed: %v", err)
+	}
+	if after-before != 4096 {
+		t.Errorf("DiskUsage grew by %d bytes, want 4096", after-before)
+	}
+
+	if _, err := manager.DiskUsage(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("DiskUsage of a missing worktree should fail")
+	}
+}
@@ -0,0 +1,64 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// BranchDivergence describes how a branch relates to the base it merges into
+type BranchDivergence struct {
+	Ref           string   // Ref the branch was read from
+	Ahead         int      // Commits on the branch missing from base
+	Behind        int      // Commits on base missing from the branch
+	Conflicts     bool     // Merging the branch into base would conflict
+	ConflictFiles []string // Files that would conflict
+}
+
+// CompareWithBase compares branch with base (e.g. "origin/main") in the
+// repository at repoPath, without touching any worktree. The local branch is
+// used if there is one, otherwise remote/<branch>. Conflicts are predicted
+// with git merge-tree, which needs git 2.38 or later; with older versions
+// Conflicts is always false.
+func CompareWithBase(repoPath, remote, branch, base string) (*BranchDivergence, error) {
+	div := &BranchDivergence{}
+	for _, ref := range []string{"refs/heads/" + branch, "refs/remotes/" + remote + "/" + branch} {
+		if _, err := runGit(repoPath, "rev-parse", "--verify", "--quiet", ref); err == nil {
+			div.Ref = ref
+			break
+		}
+	}
+	if div.Ref == "" {
+		return nil, fmt.Errorf("branch %s not found locally or on %s", branch, remote)
+	}
+
+	counts, err := runGit(repoPath, "rev-list", "--left-right", "--count", div.Ref+"..."+base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with %s: %w", branch, base, err)
+	}
+	if fields := strings.Fields(counts); len(fields) == 2 {
+		div.Ahead, _ = strconv.Atoi(fields[0])
+		div.Behind, _ = strconv.Atoi(fields[1])
+	}
+
+	if div.Behind > 0 {
+		// Exit status 1 means the merge has conflicts; the output is the
+		// resulting tree followed by the conflicted files
+		cmd := gitCommand("-c", "core.quotePath=false", "merge-tree", "--write-tree", "--name-only", "--no-messages", base, div.Ref)
+		cmd.Dir = repoPath
+		output, err := cmd.Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			div.Conflicts = true
+			lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+			for _, file := range lines[1:] {
+				if file != "" {
+					div.ConflictFiles = append(div.ConflictFiles, file)
+				}
+			}
+		}
+	}
+	return div, nil
+}
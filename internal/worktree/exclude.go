@@ -0,0 +1,120 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GeneratedFiles are files multiclaude or Claude write into worktrees that
+// must never be committed. Patterns are relative to the worktree root and
+// use path.Match syntax.
+var GeneratedFiles = []string{
+	".claude/settings.json",       // hooks config copied by hooks.CopyConfig
+	".claude/settings.local.json", // permissions Claude saves while running
+	"CONTEXT.md",                  // context notes left for the next agent
+}
+
+const (
+	excludeBegin = "# BEGIN multiclaude (managed, do not edit)"
+	excludeEnd   = "# END multiclaude"
+)
+
+// EnsureExcludes writes GeneratedFiles into a managed block of the
+// repository's info/exclude so git status and `git add -A` ignore them.
+// Git reads info/exclude from the common git directory, so the block covers
+// the clone and every worktree of it. The block is rewritten in place, and
+// entries outside it are left alone.
+func (m *Manager) EnsureExcludes() error {
+	commonDir, err := runGit(m.repoPath, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(m.repoPath, commonDir)
+	}
+
+	excludePath := filepath.Join(commonDir, "info", "exclude")
+	existing, err := os.ReadFile(excludePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", excludePath, err)
+	}
+
+	updated := replaceManagedBlock(string(existing), managedExcludeBlock())
+	if updated == string(existing) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(excludePath), err)
+	}
+	if err := os.WriteFile(excludePath, []byte(updated), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", excludePath, err)
+	}
+	return nil
+}
+
+// managedExcludeBlock returns the exclude lines for GeneratedFiles, anchored
+// to the worktree root
+func managedExcludeBlock() string {
+	var b strings.Builder
+	b.WriteString(excludeBegin + "\n")
+	for _, pattern := range GeneratedFiles {
+		b.WriteString("/" + pattern + "\n")
+	}
+	b.WriteString(excludeEnd + "\n")
+	return b.String()
+}
+
+// replaceManagedBlock swaps the managed block in content for block, or
+// appends block if content has none
+func replaceManagedBlock(content, block string) string {
+	start := strings.Index(content, excludeBegin)
+	if start >= 0 {
+		if end := strings.Index(content[start:], excludeEnd); end >= 0 {
+			end += start + len(excludeEnd)
+			if end < len(content) && content[end] == '\n' {
+				end++
+			}
+			return content[:start] + block + content[end:]
+		}
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + block
+}
+
+// IsGeneratedFile reports whether a worktree-relative path matches one of
+// GeneratedFiles
+func IsGeneratedFile(file string) bool {
+	for _, pattern := range GeneratedFiles {
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FindCommittedGeneratedFiles returns the generated files the worktree's
+// branch adds relative to its merge base with base. Files that already exist
+// on base are the repository's own and are not reported.
+func FindCommittedGeneratedFiles(worktreePath, base string) ([]string, error) {
+	mergeBase, err := runGit(worktreePath, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge base with %s: %w", base, err)
+	}
+	added, err := runGit(worktreePath, "diff", "--name-only", "--no-renames", "--diff-filter=A", mergeBase, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range strings.Split(added, "\n") {
+		if file != "" && IsGeneratedFile(file) {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
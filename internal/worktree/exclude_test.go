@@ -0,0 +1,122 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnsureExcludes(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	excludePath := filepath.Join(repoPath, ".git", "info", "exclude")
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(excludePath, []byte("*.swp"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(t.TempDir(), "worker")
+	if err := manager.CreateNewBranch(wtPath, "work/worker", "main"); err != nil {
+		t.Fatalf("CreateNewBranch failed: %v", err)
+	}
+	if err := manager.EnsureExcludes(); err != nil {
+		t.Fatalf("EnsureExcludes failed: %v", err)
+	}
+
+	data, err := os.ReadFile(excludePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	if !strings.HasPrefix(content, "*.swp\n") {
+		t.Errorf("existing entries should be kept, got:\n%s", content)
+	}
+	if strings.Count(content, excludeBegin) != 1 {
+		t.Errorf("managed block should appear once, got:\n%s", content)
+	}
+
+	// The block in the common git dir applies to linked worktrees
+	if err := os.MkdirAll(filepath.Join(wtPath, ".claude"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wtPath, ".claude", "settings.json"), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output, err := exec.Command("git", "-C", wtPath, "status", "--porcelain").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output) != 0 {
+		t.Errorf("generated file should be ignored in the worktree, got status: %s", output)
+	}
+}
+
+func TestReplaceManagedBlock(t *testing.T) {
+	block := managedExcludeBlock()
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"empty", "", block},
+		{"appends", "*.swp\n", "*.swp\n" + block},
+		{"adds newline", "*.swp", "*.swp\n" + block},
+		{"replaces stale block", "a\n" + excludeBegin + "\n/old\n" + excludeEnd + "\nb\n", "a\n" + block + "b\n"},
+		{"unchanged", "a\n" + block, "a\n" + block},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceManagedBlock(tt.content, block); got != tt.want {
+				t.Errorf("replaceManagedBlock() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindCommittedGeneratedFiles(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	// A CONTEXT.md the repository already tracks is its own file
+	if err := os.WriteFile(filepath.Join(repoPath, "CONTEXT.md"), []byte("# Context\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "CONTEXT.md")
+	git("commit", "-m", "Add context")
+	git("checkout", "-q", "-b", "work/test")
+
+	if err := os.WriteFile(filepath.Join(repoPath, "CONTEXT.md"), []byte("# Changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repoPath, ".claude"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, ".claude", "settings.local.json"), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "-A")
+	git("commit", "-m", "Work")
+
+	files, err := FindCommittedGeneratedFiles(repoPath, "main")
+	if err != nil {
+		t.Fatalf("FindCommittedGeneratedFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != ".claude/settings.local.json" {
+		t.Errorf("FindCommittedGeneratedFiles = %v, want [.claude/settings.local.json]", files)
+	}
+}
@@ -0,0 +1,130 @@
+package worktree
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// GitVersion is a parsed git release version
+type GitVersion struct {
+	Major, Minor, Patch int
+}
+
+func (v GitVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast returns true if v is the same as or newer than min
+func (v GitVersion) AtLeast(min GitVersion) bool {
+	if v.Major != min.Major {
+		return v.Major > min.Major
+	}
+	if v.Minor != min.Minor {
+		return v.Minor > min.Minor
+	}
+	return v.Patch >= min.Patch
+}
+
+// GitFeature is a git capability multiclaude relies on and the release that
+// introduced it
+type GitFeature struct {
+	Name       string
+	MinVersion GitVersion
+}
+
+var (
+	// FeatureWorktreePorcelain is `git worktree add -b` and `worktree list
+	// --porcelain`, which every agent worktree depends on
+	FeatureWorktreePorcelain = GitFeature{"worktree list --porcelain", GitVersion{2, 7, 0}}
+	// FeatureWorktreeConfig is per-worktree config, used to scope credentials
+	// and hooks to a single worktree
+	FeatureWorktreeConfig = GitFeature{"per-worktree config", GitVersion{2, 20, 0}}
+	// FeatureSparseCheckout is `git sparse-checkout`, used to scope monorepo tasks
+	FeatureSparseCheckout = GitFeature{"sparse-checkout", GitVersion{2, 25, 0}}
+	// FeaturePartialClone is `git clone --filter`
+	FeaturePartialClone = GitFeature{"partial clone (--filter)", GitVersion{2, 22, 0}}
+	// FeatureInitBranch is `git init -b <branch>`
+	FeatureInitBranch = GitFeature{"init -b", GitVersion{2, 28, 0}}
+)
+
+// MinGitVersion is the oldest git multiclaude runs with at all
+var MinGitVersion = FeatureWorktreePorcelain.MinVersion
+
+// GitFeatures lists the optional features checked at daemon start
+var GitFeatures = []GitFeature{FeatureWorktreePorcelain, FeatureWorktreeConfig, FeaturePartialClone, FeatureSparseCheckout, FeatureInitBranch}
+
+// gitVersionPattern matches "git version 2.39.2", "git version 2.45.1.windows.1",
+// and "git version 2.39.3 (Apple Git-146)"
+var gitVersionPattern = regexp.MustCompile(`git version (\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseGitVersion parses the output of `git --version`
+func ParseGitVersion(output string) (GitVersion, error) {
+	m := gitVersionPattern.FindStringSubmatch(output)
+	if m == nil {
+		return GitVersion{}, fmt.Errorf("unrecognized git version output: %q", output)
+	}
+	var v GitVersion
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+var (
+	detectOnce     sync.Once
+	detectedGit    GitVersion
+	detectedGitErr error
+)
+
+// DetectGitVersion returns the installed git version. The result is cached
+// for the life of the process.
+func DetectGitVersion() (GitVersion, error) {
+	detectOnce.Do(func() {
+		output, err := gitCommand("--version").Output()
+		if err != nil {
+			detectedGitErr = fmt.Errorf("failed to run git --version: %w", err)
+			return
+		}
+		detectedGit, detectedGitErr = ParseGitVersion(string(output))
+	})
+	return detectedGit, detectedGitErr
+}
+
+// CheckGitVersion returns an error if the installed git is older than
+// MinGitVersion
+func CheckGitVersion() (GitVersion, error) {
+	v, err := DetectGitVersion()
+	if err != nil {
+		return v, err
+	}
+	if !v.AtLeast(MinGitVersion) {
+		return v, fmt.Errorf("git %s is too old; multiclaude requires git %s or newer", v, MinGitVersion)
+	}
+	return v, nil
+}
+
+// UnsupportedGitFeatures returns the GitFeatures the installed version lacks
+func UnsupportedGitFeatures(v GitVersion) []GitFeature {
+	var missing []GitFeature
+	for _, f := range GitFeatures {
+		if !v.AtLeast(f.MinVersion) {
+			missing = append(missing, f)
+		}
+	}
+	return missing
+}
+
+// RequireGitFeature returns a descriptive error if the installed git does not
+// support f. Detection failures are not reported here; the git command that
+// follows will fail with its own error.
+func RequireGitFeature(f GitFeature) error {
+	v, err := DetectGitVersion()
+	if err != nil || v.AtLeast(f.MinVersion) {
+		return nil
+	}
+	return fmt.Errorf("%s requires git %s or newer (installed: %s); please upgrade git", f.Name, f.MinVersion, v)
+}
@@ -0,0 +1,68 @@
+package worktree
+
+import (
+	"testing"
+)
+
+func TestParseGitVersion(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    GitVersion
+		wantErr bool
+	}{
+		{"git version 2.39.2\n", GitVersion{2, 39, 2}, false},
+		{"git version 2.45.1.windows.1", GitVersion{2, 45, 1}, false},
+		{"git version 2.39.3 (Apple Git-146)", GitVersion{2, 39, 3}, false},
+		{"git version 1.8", GitVersion{1, 8, 0}, false},
+		{"not git", GitVersion{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseGitVersion(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGitVersion(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseGitVersion(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestGitVersionAtLeast(t *testing.T) {
+	min := GitVersion{2, 20, 0}
+	tests := map[GitVersion]bool{
+		{2, 20, 0}: true,
+		{2, 20, 1}: true,
+		{2, 39, 0}: true,
+		{3, 0, 0}:  true,
+		{2, 19, 9}: false,
+		{1, 99, 0}: false,
+	}
+	for v, want := range tests {
+		if got := v.AtLeast(min); got != want {
+			t.Errorf("%v.AtLeast(%v) = %v, want %v", v, min, got, want)
+		}
+	}
+}
+
+func TestUnsupportedGitFeatures(t *testing.T) {
+	if missing := UnsupportedGitFeatures(GitVersion{2, 40, 0}); len(missing) != 0 {
+		t.Errorf("git 2.40 should support every feature, missing %v", missing)
+	}
+
+	missing := UnsupportedGitFeatures(GitVersion{2, 21, 0})
+	if len(missing) != 3 || missing[0] != FeaturePartialClone || missing[2] != FeatureInitBranch {
+		t.Errorf("UnsupportedGitFeatures(2.21) = %v, want partial clone, sparse-checkout, and init -b", missing)
+	}
+}
+
+func TestCheckGitVersion(t *testing.T) {
+	// The test environment's git must be new enough to run the other tests
+	v, err := CheckGitVersion()
+	if err != nil {
+		t.Fatalf("CheckGitVersion() failed: %v", err)
+	}
+	if err := RequireGitFeature(GitFeature{"from the future", GitVersion{v.Major + 1, 0, 0}}); err == nil {
+		t.Error("RequireGitFeature() should fail for a feature newer than the installed git")
+	}
+}
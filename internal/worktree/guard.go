@@ -0,0 +1,165 @@
+package worktree
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// GuardPolicy describes what a branch may change before it is handed to the
+// merge queue. The zero value allows everything.
+type GuardPolicy struct {
+	// AllowedPaths lists path prefixes (e.g. "internal/cli/") or glob patterns
+	// (e.g. "docs/*.md") that may be changed. Empty means any path.
+	AllowedPaths []string
+	// MaxFileBytes is the largest allowed size of an added or modified file (0: no limit)
+	MaxFileBytes int64
+	// BlockBinaries rejects newly added binary files
+	BlockBinaries bool
+}
+
+// GuardViolation is a single file that breaks the guard policy
+type GuardViolation struct {
+	Path   string
+	Rule   string // "path", "size", or "binary"
+	Detail string
+}
+
+// GuardReport is the result of checking a branch against a GuardPolicy
+type GuardReport struct {
+	Base         string
+	FilesChecked int
+	Violations   []GuardViolation
+}
+
+// Passed returns true if the branch has no violations
+func (r *GuardReport) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// String formats the report for humans and agents
+func (r *GuardReport) String() string {
+	if r.Passed() {
+		return fmt.Sprintf("Branch guard passed (%d files changed since %s)", r.FilesChecked, r.Base)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Branch guard found %d violation(s) in %d changed files since %s:\n", len(r.Violations), r.FilesChecked, r.Base)
+	for _, v := range r.Violations {
+		fmt.Fprintf(&b, "  [%s] %s: %s\n", v.Rule, v.Path, v.Detail)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// PathAllowed reports whether p matches one of the allowed prefixes or globs.
+// An empty allow list permits every path.
+func PathAllowed(p string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, entry := range allowed {
+		entry = strings.TrimSuffix(strings.TrimPrefix(entry, "./"), "/")
+		if entry == "" {
+			continue
+		}
+		if p == entry || strings.HasPrefix(p, entry+"/") {
+			return true
+		}
+		if ok, _ := path.Match(entry, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// runGit runs a git command in dir and returns its trimmed stdout
+func runGit(dir string, args ...string) (string, error) {
+	cmd := gitCommand(append([]string{"-c", "core.quotePath=false"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %w\nOutput: %s", args[0], err, exitErr.Stderr)
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// CheckBranchGuard compares the worktree's HEAD with its merge base against
+// base and reports every changed file that breaks the policy.
+func CheckBranchGuard(worktreePath, base string, policy GuardPolicy) (*GuardReport, error) {
+	mergeBase, err := runGit(worktreePath, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge base with %s: %w", base, err)
+	}
+
+	nameStatus, err := runGit(worktreePath, "diff", "--name-status", "--no-renames", mergeBase, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	numstat, err := runGit(worktreePath, "diff", "--numstat", "--no-renames", mergeBase, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	// Binary files show up in numstat as "-\t-\t<path>"
+	binaries := make(map[string]bool)
+	for _, line := range strings.Split(numstat, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 3 && fields[0] == "-" && fields[1] == "-" {
+			binaries[fields[2]] = true
+		}
+	}
+
+	report := &GuardReport{Base: base}
+	for _, line := range strings.Split(nameStatus, "\n") {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		status, file := fields[0], fields[1]
+		report.FilesChecked++
+
+		if !PathAllowed(file, policy.AllowedPaths) {
+			report.Violations = append(report.Violations, GuardViolation{
+				Path:   file,
+				Rule:   "path",
+				Detail: fmt.Sprintf("outside allowed paths (%s)", strings.Join(policy.AllowedPaths, ", ")),
+			})
+		}
+
+		if status == "D" {
+			continue
+		}
+
+		if policy.BlockBinaries && status == "A" && binaries[file] {
+			report.Violations = append(report.Violations, GuardViolation{
+				Path:   file,
+				Rule:   "binary",
+				Detail: "new binary file",
+			})
+		}
+
+		if policy.MaxFileBytes > 0 {
+			sizeStr, err := runGit(worktreePath, "cat-file", "-s", "HEAD:"+file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get size of %s: %w", file, err)
+			}
+			size, err := strconv.ParseInt(sizeStr, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse size of %s: %w", file, err)
+			}
+			if size > policy.MaxFileBytes {
+				report.Violations = append(report.Violations, GuardViolation{
+					Path:   file,
+					Rule:   "size",
+					Detail: fmt.Sprintf("%d bytes exceeds limit of %d bytes", size, policy.MaxFileBytes),
+				})
+			}
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,122 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathAllowed(t *testing.T) {
+	allowed := []string{"internal/cli/", "docs/*.md", "./Makefile"}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"internal/cli/cli.go", true},
+		{"internal/cli", true},
+		{"internal/clients/x.go", false},
+		{"docs/README.md", true},
+		{"docs/sub/README.md", false},
+		{"Makefile", true},
+		{"vendor/lib.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := PathAllowed(tt.path, allowed); got != tt.want {
+			t.Errorf("PathAllowed(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if !PathAllowed("anything/at/all", nil) {
+		t.Error("empty allow list should permit every path")
+	}
+}
+
+func TestCheckBranchGuard(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-guard")
+	if err := manager.CreateNewBranch(wtPath, "work/guard", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	files := map[string][]byte{
+		"internal/app.go":    []byte("package app\n"),
+		"vendor/junk.go":     []byte("package junk\n"),
+		"assets/blob.bin":    {0x00, 0x01, 0x02, 0x00, 0xff},
+		"internal/big.txt":   []byte(strings.Repeat("x", 2048)),
+		"internal/small.txt": []byte("ok\n"),
+	}
+	for name, content := range files {
+		full := filepath.Join(wtPath, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-m", "changes"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = wtPath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	t.Run("empty policy passes", func(t *testing.T) {
+		report, err := CheckBranchGuard(wtPath, "main", GuardPolicy{})
+		if err != nil {
+			t.Fatalf("CheckBranchGuard failed: %v", err)
+		}
+		if !report.Passed() {
+			t.Errorf("expected pass, got:\n%s", report)
+		}
+		if report.FilesChecked != len(files) {
+			t.Errorf("FilesChecked = %d, want %d", report.FilesChecked, len(files))
+		}
+	})
+
+	t.Run("violations are reported", func(t *testing.T) {
+		report, err := CheckBranchGuard(wtPath, "main", GuardPolicy{
+			AllowedPaths:  []string{"internal/", "assets/"},
+			MaxFileBytes:  1024,
+			BlockBinaries: true,
+		})
+		if err != nil {
+			t.Fatalf("CheckBranchGuard failed: %v", err)
+		}
+
+		got := make(map[string]string)
+		for _, v := range report.Violations {
+			got[v.Path] = v.Rule
+		}
+		want := map[string]string{
+			"vendor/junk.go":   "path",
+			"assets/blob.bin":  "binary",
+			"internal/big.txt": "size",
+		}
+		if len(got) != len(want) {
+			t.Errorf("violations = %v, want %v", got, want)
+		}
+		for path, rule := range want {
+			if got[path] != rule {
+				t.Errorf("violation for %s = %q, want %q", path, got[path], rule)
+			}
+		}
+		if !strings.Contains(report.String(), "vendor/junk.go") {
+			t.Errorf("report should list violations, got:\n%s", report)
+		}
+	})
+
+	t.Run("unknown base", func(t *testing.T) {
+		if _, err := CheckBranchGuard(wtPath, "no-such-branch", GuardPolicy{}); err == nil {
+			t.Error("expected error for unknown base")
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package worktree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LFSSkipSmudgeEnv makes git check Git LFS files out as pointer files
+// instead of downloading them one at a time during checkout. SetupLFS then
+// downloads the content in one batch, with progress.
+const LFSSkipSmudgeEnv = "GIT_LFS_SKIP_SMUDGE=1"
+
+// UsesLFS reports whether the checkout at path stores files in Git LFS,
+// i.e. whether any tracked .gitattributes file sets filter=lfs
+func UsesLFS(path string) bool {
+	output, err := runGit(path, "ls-files", "--", ".gitattributes", "*/.gitattributes")
+	if err != nil || output == "" {
+		return false
+	}
+	for _, name := range strings.Split(output, "\n") {
+		content, err := os.ReadFile(filepath.Join(path, name))
+		if err == nil && strings.Contains(string(content), "filter=lfs") {
+			return true
+		}
+	}
+	return false
+}
+
+// LFSInstalled reports whether the git-lfs extension is available
+func LFSInstalled() bool {
+	return gitCommand("lfs", "version").Run() == nil
+}
+
+// SetupLFS prepares a checkout of a repository that uses Git LFS: it
+// installs the LFS hooks and filters and, if fetchContent is set, downloads
+// the content of the LFS files checked out as pointers, reporting progress
+// to progress. Without fetchContent the worktree is configured to keep
+// pointer files, including for files later checkouts bring in. A missing
+// git-lfs is reported to progress rather than failing, since everything
+// but the LFS files still works.
+func SetupLFS(worktreePath string, fetchContent bool, progress io.Writer) error {
+	if progress == nil {
+		progress = io.Discard
+	}
+	if !UsesLFS(worktreePath) {
+		return nil
+	}
+	if !LFSInstalled() {
+		fmt.Fprintf(progress, "Warning: %s uses Git LFS but git-lfs is not installed; LFS files are pointer files\n", worktreePath)
+		return nil
+	}
+
+	if _, err := runGit(worktreePath, "lfs", "install", "--local"); err != nil {
+		return fmt.Errorf("failed to install Git LFS: %w", err)
+	}
+
+	if !fetchContent {
+		// Per-worktree config keeps other agents' worktrees fetching content
+		if _, err := runGit(worktreePath, "config", "extensions.worktreeConfig", "true"); err != nil {
+			return fmt.Errorf("failed to enable worktree config: %w", err)
+		}
+		if _, err := runGit(worktreePath, "config", "--worktree", "lfs.fetchexclude", "*"); err != nil {
+			return fmt.Errorf("failed to skip Git LFS content: %w", err)
+		}
+		fmt.Fprintf(progress, "Skipping Git LFS content in %s (LFS files are pointer files)\n", worktreePath)
+		return nil
+	}
+
+	fmt.Fprintf(progress, "Downloading Git LFS content for %s\n", worktreePath)
+	cmd := gitCommand("lfs", "pull")
+	cmd.Dir = worktreePath
+	cmd.Stdout = progress
+	cmd.Stderr = progress
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to download Git LFS content: %w", err)
+	}
+	return nil
+}
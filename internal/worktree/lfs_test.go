@@ -0,0 +1,59 @@
+package worktree
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUsesLFS(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	if UsesLFS(repoPath) {
+		t.Fatal("a repository without .gitattributes should not use LFS")
+	}
+
+	// An untracked .gitattributes doesn't count until it's committed
+	dir := filepath.Join(repoPath, "assets")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte("*.psd filter=lfs diff=lfs merge=lfs -text\n"), 0644)
+	if UsesLFS(repoPath) {
+		t.Fatal("an untracked .gitattributes should be ignored")
+	}
+
+	cmd := exec.Command("git", "add", "assets/.gitattributes")
+	cmd.Dir = repoPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v\n%s", err, output)
+	}
+	if !UsesLFS(repoPath) {
+		t.Error("a tracked nested .gitattributes with filter=lfs should be detected")
+	}
+}
+
+func TestSetupLFSWithoutLFSInstalled(t *testing.T) {
+	if LFSInstalled() {
+		t.Skip("git-lfs is installed")
+	}
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	os.WriteFile(filepath.Join(repoPath, ".gitattributes"), []byte("*.bin filter=lfs -text\n"), 0644)
+	cmd := exec.Command("git", "add", ".gitattributes")
+	cmd.Dir = repoPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v\n%s", err, output)
+	}
+
+	var progress bytes.Buffer
+	if err := SetupLFS(repoPath, true, &progress); err != nil {
+		t.Fatalf("SetupLFS should not fail without git-lfs: %v", err)
+	}
+	if !strings.Contains(progress.String(), "git-lfs is not installed") {
+		t.Errorf("expected a warning, got %q", progress.String())
+	}
+}
@@ -0,0 +1,278 @@
+package worktree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LockFileName is the file in a repository's git directory that names the
+// multiclaude daemon managing the repository's worktrees. Clones on a network
+// mount can be seen by daemons on several machines; the lock lets them notice
+// each other instead of removing or rebasing each other's worktrees.
+const LockFileName = "multiclaude.lock"
+
+// LockStaleAfter is how long a lock may go without a heartbeat before another
+// daemon may take it over
+const LockStaleAfter = 10 * time.Minute
+
+// LockOwner identifies the daemon holding a repository lock
+type LockOwner struct {
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	Root      string    `json:"root"`
+	User      string    `json:"user,omitempty"`
+	Acquired  time.Time `json:"acquired"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+// NewLockOwner describes the current process as the daemon for the
+// multiclaude root directory root
+func NewLockOwner(root string) LockOwner {
+	owner := LockOwner{PID: os.Getpid(), Root: root}
+	owner.Host, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		owner.User = u.Username
+	}
+	return owner
+}
+
+// Same returns true if o and other are the same daemon installation. The PID
+// is ignored so a restarted daemon keeps its own lock.
+func (o LockOwner) Same(other LockOwner) bool {
+	return o.Host == other.Host && o.Root == other.Root
+}
+
+// Stale returns true if the owner has not refreshed the lock recently
+func (o LockOwner) Stale(now time.Time) bool {
+	return now.Sub(o.Heartbeat) > LockStaleAfter
+}
+
+// String describes the owner for humans, e.g. "pid 42 on build-2 (root /home/me/.multiclaude)"
+func (o LockOwner) String() string {
+	s := fmt.Sprintf("pid %d on %s (root %s", o.PID, o.Host, o.Root)
+	if o.User != "" {
+		s += ", user " + o.User
+	}
+	return s + ")"
+}
+
+// LockHeldError is returned when another daemon holds a repository lock
+type LockHeldError struct {
+	Owner LockOwner
+}
+
+func (e *LockHeldError) Error() string {
+	return fmt.Sprintf("repository is managed by another multiclaude daemon: %s, last seen %s",
+		e.Owner, e.Owner.Heartbeat.Format(time.RFC3339))
+}
+
+// LockPath returns the path of the repository's lock file. The lock lives in
+// the common git directory so every worktree of the clone shares it.
+func (m *Manager) LockPath() (string, error) {
+	cmd := gitCommand("rev-parse", "--git-common-dir")
+	cmd.Dir = m.repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git dir: %w", err)
+	}
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(m.repoPath, dir)
+	}
+	return filepath.Join(dir, LockFileName), nil
+}
+
+// ReadLock returns the repository's lock owner, or nil if it is not locked
+func (m *Manager) ReadLock() (*LockOwner, error) {
+	path, err := m.LockPath()
+	if err != nil {
+		return nil, err
+	}
+	return readLockFile(path)
+}
+
+// AcquireLock claims the repository for self, or refreshes the heartbeat of
+// a lock self already holds. Locks whose owner stopped heartbeating are taken
+// over. Returns a *LockHeldError if another daemon holds the lock.
+func (m *Manager) AcquireLock(self LockOwner, now time.Time) error {
+	path, err := m.LockPath()
+	if err != nil {
+		return err
+	}
+
+	existing, err := readLockFile(path)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		self.Acquired, self.Heartbeat = now, now
+		if err := createLockFile(path, self); err == nil {
+			return nil
+		} else if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		// Another daemon created the lock first
+		if existing, err = readLockFile(path); err != nil {
+			return err
+		}
+		if existing == nil {
+			return fmt.Errorf("lock file %s vanished while acquiring it", path)
+		}
+	}
+
+	if !existing.Same(self) {
+		if !existing.Stale(now) {
+			return &LockHeldError{Owner: *existing}
+		}
+		self.Acquired = now
+	} else if existing.PID != self.PID {
+		self.Acquired = now
+	} else {
+		self.Acquired = existing.Acquired
+	}
+	self.Heartbeat = now
+	return writeLockFile(path, self)
+}
+
+// TakeOverLock claims the repository for self even if another daemon holds
+// the lock, returning the previous owner (nil if there was none). Use it when
+// the other daemon is known to be gone before its lock went stale.
+func (m *Manager) TakeOverLock(self LockOwner, now time.Time) (*LockOwner, error) {
+	path, err := m.LockPath()
+	if err != nil {
+		return nil, err
+	}
+	previous, err := readLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	self.Acquired, self.Heartbeat = now, now
+	return previous, writeLockFile(path, self)
+}
+
+// ReleaseLock removes the repository lock if self holds it
+func (m *Manager) ReleaseLock(self LockOwner) error {
+	path, err := m.LockPath()
+	if err != nil {
+		return err
+	}
+	existing, err := readLockFile(path)
+	if err != nil || existing == nil || !existing.Same(self) {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// multiclaudeBranchPrefixes are the branch prefixes multiclaude checks
+// worktrees out on
+var multiclaudeBranchPrefixes = []string{"work/", "warm/", "multiclaude/", "workspace/"}
+
+// ForeignWorktrees returns worktrees on multiclaude branches that live
+// outside all of roots, which are left behind by daemons of other machines
+// or multiclaude installations sharing the clone
+func (m *Manager) ForeignWorktrees(roots ...string) ([]WorktreeInfo, error) {
+	worktrees, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]string, 0, len(roots))
+	for _, root := range roots {
+		if r, err := resolvePathWithSymlinks(root); err == nil {
+			resolved = append(resolved, r)
+		}
+	}
+
+	var foreign []WorktreeInfo
+	for _, wt := range worktrees {
+		if !hasAnyPrefix(wt.Branch, multiclaudeBranchPrefixes) {
+			continue
+		}
+		path, err := resolvePathWithSymlinks(wt.Path)
+		if err != nil {
+			continue
+		}
+		inside := false
+		for _, root := range resolved {
+			if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			foreign = append(foreign, wt)
+		}
+	}
+	return foreign, nil
+}
+
+// hasAnyPrefix returns true if s starts with one of prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// readLockFile reads a lock file, returning nil if it doesn't exist
+func readLockFile(path string) (*LockOwner, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	var owner LockOwner
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+	}
+	return &owner, nil
+}
+
+// createLockFile writes a new lock file, failing with os.ErrExist if one
+// already exists. O_EXCL is honoured by NFSv3 and later.
+func createLockFile(path string, owner LockOwner) error {
+	data, err := json.MarshalIndent(owner, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return f.Close()
+}
+
+// writeLockFile replaces a lock file atomically
+func writeLockFile(path string, owner LockOwner) error {
+	data, err := json.MarshalIndent(owner, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%s.%d.tmp", path, owner.Host, owner.PID)
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
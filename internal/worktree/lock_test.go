@@ -0,0 +1,97 @@
+package worktree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRepositoryLock(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	m := NewManager(repoPath)
+
+	now := time.Now()
+	self := LockOwner{Host: "alpha", PID: 100, Root: "/home/me/.multiclaude"}
+	other := LockOwner{Host: "beta", PID: 200, Root: "/home/me/.multiclaude"}
+
+	if owner, err := m.ReadLock(); err != nil || owner != nil {
+		t.Fatalf("ReadLock on unlocked repo = %v, %v", owner, err)
+	}
+	if err := m.AcquireLock(self, now); err != nil {
+		t.Fatalf("AcquireLock failed: %v", err)
+	}
+	path, _ := m.LockPath()
+	if filepath.Base(path) != LockFileName {
+		t.Errorf("LockPath = %s", path)
+	}
+
+	// Another machine sharing the root is refused while the lock is fresh
+	err := m.AcquireLock(other, now.Add(time.Minute))
+	var held *LockHeldError
+	if !errors.As(err, &held) || held.Owner.Host != "alpha" {
+		t.Fatalf("AcquireLock by another host = %v, want LockHeldError", err)
+	}
+
+	// A restarted daemon keeps its own lock and refreshes the heartbeat
+	restarted := self
+	restarted.PID = 101
+	if err := m.AcquireLock(restarted, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("AcquireLock after restart failed: %v", err)
+	}
+	owner, _ := m.ReadLock()
+	if owner.PID != 101 || !owner.Heartbeat.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("lock after restart = %+v", owner)
+	}
+
+	// Once the heartbeat goes stale, the other daemon takes over
+	if err := m.AcquireLock(other, now.Add(2*time.Minute+LockStaleAfter+time.Second)); err != nil {
+		t.Fatalf("AcquireLock of stale lock failed: %v", err)
+	}
+	if owner, _ := m.ReadLock(); owner.Host != "beta" {
+		t.Errorf("lock owner = %s, want beta", owner.Host)
+	}
+
+	// Releasing someone else's lock leaves it alone; take-over replaces it
+	if err := m.ReleaseLock(self); err != nil {
+		t.Fatal(err)
+	}
+	previous, err := m.TakeOverLock(self, now)
+	if err != nil || previous == nil || previous.Host != "beta" {
+		t.Fatalf("TakeOverLock = %v, %v", previous, err)
+	}
+	if err := m.ReleaseLock(self); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file still exists after release: %v", err)
+	}
+}
+
+func TestForeignWorktrees(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	m := NewManager(repoPath)
+
+	ours := filepath.Join(repoPath, "ours")
+	theirs := filepath.Join(repoPath, "theirs")
+	if err := m.CreateNewBranch(filepath.Join(ours, "a"), "work/a", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateNewBranch(filepath.Join(theirs, "b"), "work/b", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateNewBranch(filepath.Join(theirs, "manual"), "feature", "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	foreign, err := m.ForeignWorktrees(ours)
+	if err != nil {
+		t.Fatalf("ForeignWorktrees failed: %v", err)
+	}
+	if len(foreign) != 1 || foreign[0].Branch != "work/b" {
+		t.Errorf("ForeignWorktrees = %+v, want only work/b", foreign)
+	}
+}
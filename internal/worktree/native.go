@@ -0,0 +1,350 @@
+package worktree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Backend selects how a Manager answers read-only questions about branches
+// and worktrees. Commands that change the repository always run git.
+type Backend string
+
+const (
+	// BackendExec runs the git binary for every query (the default)
+	BackendExec Backend = "exec"
+	// BackendNative reads refs and worktree metadata straight from the
+	// repository's git directory, saving a subprocess per query. Layouts it
+	// doesn't understand, such as the reftable ref format, fall back to git.
+	BackendNative Backend = "native"
+)
+
+// BackendEnv names the environment variable that picks the backend for
+// managers created without WithBackend
+const BackendEnv = "MULTICLAUDE_GIT_BACKEND"
+
+// WithBackend sets how the manager reads branches and worktrees
+func WithBackend(b Backend) ManagerOption {
+	return func(m *Manager) {
+		m.backend = b
+	}
+}
+
+// ParseBackend checks a backend name, treating "" as the default
+func ParseBackend(name string) (Backend, error) {
+	switch Backend(name) {
+	case "", BackendExec:
+		return BackendExec, nil
+	case BackendNative:
+		return BackendNative, nil
+	}
+	return "", fmt.Errorf("unknown git backend %q (want %s or %s)", name, BackendExec, BackendNative)
+}
+
+// defaultBackend reads BackendEnv, ignoring values it doesn't recognise
+func defaultBackend() Backend {
+	b, err := ParseBackend(os.Getenv(BackendEnv))
+	if err != nil {
+		return BackendExec
+	}
+	return b
+}
+
+// zeroCommit is what git reports as the HEAD of an unborn branch
+const zeroCommit = "0000000000000000000000000000000000000000"
+
+// errNativeUnsupported means the repository layout needs the git binary
+var errNativeUnsupported = errors.New("repository layout not supported by the native git backend")
+
+// nativeRepo reads a repository's refs and worktree metadata from disk
+type nativeRepo struct {
+	// commonDir is the git directory shared by all worktrees
+	commonDir string
+	// bare is set when the repository has no main worktree
+	bare bool
+}
+
+// native returns a reader for the manager's repository, or nil when the
+// manager uses the exec backend or the repository needs the git binary
+func (m *Manager) native() *nativeRepo {
+	if m.backend != BackendNative {
+		return nil
+	}
+	r, err := openNativeRepo(m.repoPath)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+// openNativeRepo locates the common git directory for a repository or any
+// of its worktrees
+func openNativeRepo(repoPath string) (*nativeRepo, error) {
+	repoPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &nativeRepo{}
+	dotGit := filepath.Join(repoPath, ".git")
+	info, err := os.Stat(dotGit)
+	switch {
+	case err == nil && info.IsDir():
+		r.commonDir = dotGit
+	case err == nil:
+		// A linked worktree: .git is a file pointing at its admin directory
+		gitDir, err := readGitDirFile(dotGit)
+		if err != nil {
+			return nil, err
+		}
+		r.commonDir = gitDir
+		if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+			r.commonDir = resolveFrom(gitDir, strings.TrimSpace(string(data)))
+		}
+	case isGitDir(repoPath):
+		r.commonDir = repoPath
+		r.bare = true
+	default:
+		return nil, errNativeUnsupported
+	}
+
+	if !isGitDir(r.commonDir) {
+		return nil, errNativeUnsupported
+	}
+	if _, err := os.Stat(filepath.Join(r.commonDir, "reftable")); err == nil {
+		return nil, errNativeUnsupported
+	}
+	return r, nil
+}
+
+// readGitDirFile reads the "gitdir: <path>" line of a worktree's .git file
+func readGitDirFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(data))
+	gitDir, ok := strings.CutPrefix(line, "gitdir: ")
+	if !ok {
+		return "", errNativeUnsupported
+	}
+	return resolveFrom(filepath.Dir(path), gitDir), nil
+}
+
+// resolveFrom makes path absolute relative to dir
+func resolveFrom(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
+
+// isGitDir reports whether dir looks like a git directory
+func isGitDir(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, "refs"))
+	return err == nil && info.IsDir()
+}
+
+// branchExists reports whether refs/heads/<name> exists as a loose or
+// packed ref
+func (r *nativeRepo) branchExists(name string) (bool, error) {
+	_, ok, err := r.resolveRef("refs/heads/" + name)
+	return ok, err
+}
+
+// resolveRef returns the commit a ref points at, following symbolic refs
+func (r *nativeRepo) resolveRef(ref string) (string, bool, error) {
+	for depth := 0; depth < 5; depth++ {
+		data, err := os.ReadFile(filepath.Join(r.commonDir, filepath.FromSlash(ref)))
+		if err == nil {
+			value := strings.TrimSpace(string(data))
+			if target, ok := strings.CutPrefix(value, "ref: "); ok {
+				ref = target
+				continue
+			}
+			return value, true, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) && !isDirErr(err) {
+			return "", false, err
+		}
+		packed, err := r.packedRefs()
+		if err != nil {
+			return "", false, err
+		}
+		commit, ok := packed[ref]
+		return commit, ok, nil
+	}
+	return "", false, fmt.Errorf("too many levels of symbolic refs at %s", ref)
+}
+
+// isDirErr reports whether reading a ref failed because the path is a
+// directory of refs rather than a ref
+func isDirErr(err error) bool {
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) {
+		return false
+	}
+	info, statErr := os.Stat(pathErr.Path)
+	return statErr == nil && info.IsDir()
+}
+
+// packedRefs reads the packed-refs file into a ref -> commit map
+func (r *nativeRepo) packedRefs() (map[string]string, error) {
+	refs := make(map[string]string)
+	f, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return refs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Skip the header and the peeled values of annotated tags
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		commit, ref, ok := strings.Cut(line, " ")
+		if ok {
+			refs[ref] = commit
+		}
+	}
+	return refs, scanner.Err()
+}
+
+// branchesWithPrefix lists branch names the way
+// `git for-each-ref refs/heads/<prefix>` matches them: the prefix must end
+// at a slash or match the whole name. Results are sorted by name.
+func (r *nativeRepo) branchesWithPrefix(prefix string) ([]string, error) {
+	if strings.ContainsAny(prefix, "*?[") {
+		return nil, errNativeUnsupported
+	}
+	matches := func(name string) bool {
+		if prefix == "" || strings.HasSuffix(prefix, "/") {
+			return strings.HasPrefix(name, prefix)
+		}
+		return name == prefix || strings.HasPrefix(name, prefix+"/")
+	}
+
+	seen := make(map[string]bool)
+	packed, err := r.packedRefs()
+	if err != nil {
+		return nil, err
+	}
+	for ref := range packed {
+		if name, ok := strings.CutPrefix(ref, "refs/heads/"); ok && matches(name) {
+			seen[name] = true
+		}
+	}
+
+	headsDir := filepath.Join(r.commonDir, "refs", "heads")
+	err = filepath.WalkDir(headsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(path, ".lock") {
+			return nil
+		}
+		rel, err := filepath.Rel(headsDir, path)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); matches(name) {
+			seen[name] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make([]string, 0, len(seen))
+	for name := range seen {
+		branches = append(branches, name)
+	}
+	sort.Strings(branches)
+	return branches, nil
+}
+
+// worktrees lists the main worktree followed by the linked worktrees in the
+// order `git worktree list` prints them
+func (r *nativeRepo) worktrees() ([]WorktreeInfo, error) {
+	var list []WorktreeInfo
+
+	if r.bare {
+		list = append(list, WorktreeInfo{Path: r.commonDir})
+	} else {
+		main, err := r.worktreeHead(r.commonDir)
+		if err != nil {
+			return nil, err
+		}
+		main.Path = filepath.Dir(r.commonDir)
+		if real, err := filepath.EvalSymlinks(main.Path); err == nil {
+			main.Path = real
+		}
+		list = append(list, main)
+	}
+
+	adminRoot := filepath.Join(r.commonDir, "worktrees")
+	entries, err := os.ReadDir(adminRoot)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	// ReadDir sorts by name, which is the order git lists worktrees in
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		adminDir := filepath.Join(adminRoot, entry.Name())
+		data, err := os.ReadFile(filepath.Join(adminDir, "gitdir"))
+		if err != nil {
+			continue
+		}
+		wt, err := r.worktreeHead(adminDir)
+		if err != nil {
+			return nil, err
+		}
+		wt.Path = filepath.Dir(resolveFrom(adminDir, strings.TrimSpace(string(data))))
+		list = append(list, wt)
+	}
+	return list, nil
+}
+
+// worktreeHead reads the HEAD of the worktree whose git directory is
+// gitDir. Detached worktrees have no Branch; unborn branches report the
+// zero commit as git does.
+func (r *nativeRepo) worktreeHead(gitDir string) (WorktreeInfo, error) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return WorktreeInfo{}, err
+	}
+	head := strings.TrimSpace(string(data))
+	ref, symbolic := strings.CutPrefix(head, "ref: ")
+	if !symbolic {
+		return WorktreeInfo{Commit: head}, nil
+	}
+
+	info := WorktreeInfo{Branch: strings.TrimPrefix(ref, "refs/heads/"), Commit: zeroCommit}
+	commit, ok, err := r.resolveRef(ref)
+	if err != nil {
+		return WorktreeInfo{}, err
+	}
+	if ok {
+		info.Commit = commit
+	}
+	return info, nil
+}
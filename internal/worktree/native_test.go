@@ -0,0 +1,131 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestNativeBackendMatchesGit checks that the native backend answers the
+// same as git for loose and packed branches and for linked worktrees
+func TestNativeBackendMatchesGit(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createBranch(t, repoPath, "work/packed")
+	createBranch(t, repoPath, "work/gone")
+	createBranch(t, repoPath, "workers")
+	if _, err := runGit(repoPath, "pack-refs", "--all"); err != nil {
+		t.Fatal(err)
+	}
+	createBranch(t, repoPath, "work/loose")
+	createBranch(t, repoPath, "work/nested/deep")
+	// A deleted packed branch leaves packed-refs, and an updated one gets a
+	// loose ref that shadows its packed entry
+	if _, err := runGit(repoPath, "branch", "-D", "work/gone"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(repoPath, "update-ref", "refs/heads/workers", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+
+	parent := t.TempDir()
+	execMgr := NewManager(repoPath, WithBackend(BackendExec))
+	if err := execMgr.CreateNewBranch(filepath.Join(parent, "zz"), "work/zz", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execMgr.Create(filepath.Join(parent, "aa"), "work/loose"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execMgr.Create(filepath.Join(parent, "pp"), "work/packed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(repoPath, "worktree", "add", "--detach", filepath.Join(parent, "detached")); err != nil {
+		t.Fatal(err)
+	}
+
+	nativeMgr := NewManager(repoPath, WithBackend(BackendNative))
+	if nativeMgr.native() == nil {
+		t.Fatal("native backend should support a plain repository")
+	}
+
+	for _, prefix := range []string{"", "work/", "work", "work/nested/", "missing/"} {
+		want, err := execMgr.ListBranchesWithPrefix(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := nativeMgr.ListBranchesWithPrefix(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(want) == 0 && len(got) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ListBranchesWithPrefix(%q) = %v, git says %v", prefix, got, want)
+		}
+	}
+
+	for _, branch := range []string{"main", "work/packed", "work/loose", "work/nested", "work/zz", "work/gone", "workers", "nope"} {
+		want, _ := execMgr.BranchExists(branch)
+		got, err := nativeMgr.BranchExists(branch)
+		if err != nil || got != want {
+			t.Errorf("BranchExists(%q) = %v, %v, git says %v", branch, got, err, want)
+		}
+	}
+
+	want, err := execMgr.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := nativeMgr.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %+v\ngit says %+v", got, want)
+	}
+
+	// A manager opened on a linked worktree sees the same repository
+	fromWorktree := NewManager(filepath.Join(parent, "aa"), WithBackend(BackendNative))
+	if got, _ := fromWorktree.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() from a worktree = %+v, want %+v", got, want)
+	}
+}
+
+func TestNativeBackendFallsBack(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	if err := os.Mkdir(filepath.Join(repoPath, ".git", "reftable"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(repoPath, WithBackend(BackendNative))
+	if m.native() != nil {
+		t.Error("native backend should defer to git for reftable repositories")
+	}
+	if exists, err := m.BranchExists("main"); err != nil || !exists {
+		t.Errorf("BranchExists(main) = %v, %v, want true via git", exists, err)
+	}
+	if worktrees, err := m.List(); err != nil || len(worktrees) != 1 {
+		t.Errorf("List() = %v, %v, want the main worktree via git", worktrees, err)
+	}
+}
+
+func TestBackendSelection(t *testing.T) {
+	t.Setenv(BackendEnv, "native")
+	if got := NewManager(".").backend; got != BackendNative {
+		t.Errorf("backend with %s=native = %q", BackendEnv, got)
+	}
+	t.Setenv(BackendEnv, "libgit2")
+	if got := NewManager(".").backend; got != BackendExec {
+		t.Errorf("backend with an unknown name = %q, want exec", got)
+	}
+	if got := NewManager(".", WithBackend(BackendNative)).backend; got != BackendNative {
+		t.Errorf("WithBackend should override the environment, got %q", got)
+	}
+	if _, err := ParseBackend("libgit2"); err == nil {
+		t.Error("ParseBackend(libgit2) should fail")
+	}
+}
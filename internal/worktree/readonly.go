@@ -0,0 +1,49 @@
+package worktree
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MakeReadOnly turns a worktree into a read-only checkout for agents that
+// should review code rather than change it (e.g. reviewers). A pre-commit hook
+// blocks commits, and write permission is removed from all tracked files.
+// Directories stay writable so tooling can still create scratch files.
+func MakeReadOnly(worktreePath string) error {
+	hooksDir, err := worktreeHooksDir(worktreePath)
+	if err != nil {
+		return err
+	}
+
+	preCommit := "echo \"multiclaude: this worktree is read-only; commits are not allowed\" >&2\n" +
+		"echo \"Leave feedback as PR comments or messages instead.\" >&2\n" +
+		"exit 1\n"
+	if err := writeHook(hooksDir, "pre-commit", preCommit); err != nil {
+		return err
+	}
+
+	cmd := gitCommand("ls-files", "-z")
+	cmd.Dir = worktreePath
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to list tracked files: %w", err)
+	}
+
+	for _, name := range bytes.Split(output, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		path := filepath.Join(worktreePath, string(name))
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if err := os.Chmod(path, info.Mode().Perm()&^0222); err != nil {
+			return fmt.Errorf("failed to make %s read-only: %w", name, err)
+		}
+	}
+
+	return nil
+}
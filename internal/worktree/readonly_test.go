@@ -0,0 +1,65 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestMakeReadOnly(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-review")
+	if err := manager.CreateNewBranch(wtPath, "review/test", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	if err := MakeReadOnly(wtPath); err != nil {
+		t.Fatalf("MakeReadOnly failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(wtPath, "README.md"))
+	if err != nil {
+		t.Fatalf("Failed to stat README.md: %v", err)
+	}
+	if info.Mode().Perm()&0222 != 0 {
+		t.Errorf("README.md should not be writable, mode = %v", info.Mode().Perm())
+	}
+
+	// Commits must be blocked even for changes made despite the permissions
+	newFile := filepath.Join(wtPath, "new.txt")
+	if err := os.WriteFile(newFile, []byte("change\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	cmd := exec.Command("git", "add", "new.txt")
+	cmd.Dir = wtPath
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("git add failed: %v", err)
+	}
+	cmd = exec.Command("git", "commit", "-m", "should fail")
+	cmd.Dir = wtPath
+	if err := cmd.Run(); err == nil {
+		t.Error("commit should be blocked in read-only worktree")
+	}
+
+	// The main checkout is unaffected
+	if err := os.WriteFile(filepath.Join(repoPath, "main.txt"), []byte("ok\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	cmd = exec.Command("git", "add", "main.txt")
+	cmd.Dir = repoPath
+	cmd.Run()
+	cmd = exec.Command("git", "commit", "-m", "allowed")
+	cmd.Dir = repoPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("commit in main checkout should succeed: %v\n%s", err, output)
+	}
+
+	// Read-only worktrees can still be removed
+	if err := manager.Remove(wtPath, true); err != nil {
+		t.Errorf("Failed to remove read-only worktree: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RefreshStashPrefix starts the message of the stash a refresh saves
+// uncommitted changes in while it rebases
+const RefreshStashPrefix = "refresh-stash-"
+
+// InterruptedOp is a rebase or merge left in progress in a worktree
+type InterruptedOp struct {
+	Kind         string    // "rebase" or "merge"
+	LastActivity time.Time // When git last updated the operation's state
+}
+
+// DetectInterrupted returns the rebase or merge in progress in a worktree,
+// or nil if there is none
+func DetectInterrupted(worktreePath string) (*InterruptedOp, error) {
+	gitDir, err := worktreeAdminDir(worktreePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		if last, ok := lastModified(filepath.Join(gitDir, name)); ok {
+			return &InterruptedOp{Kind: "rebase", LastActivity: last}, nil
+		}
+	}
+	if last, ok := lastModified(filepath.Join(gitDir, "MERGE_HEAD")); ok {
+		return &InterruptedOp{Kind: "merge", LastActivity: last}, nil
+	}
+	return nil, nil
+}
+
+// lastModified returns the newest modification time of path and, for a
+// directory, the files directly in it
+func lastModified(path string) (time.Time, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	last := info.ModTime()
+	if info.IsDir() {
+		entries, _ := os.ReadDir(path)
+		for _, entry := range entries {
+			if fi, err := entry.Info(); err == nil && fi.ModTime().After(last) {
+				last = fi.ModTime()
+			}
+		}
+	}
+	return last, true
+}
+
+// RecoveryResult describes what Recover rolled back
+type RecoveryResult struct {
+	Aborted       *InterruptedOp // Operation that was aborted, if any
+	Pending       *InterruptedOp // Operation left alone because it is still recent
+	RestoredStash []string       // Messages of the refresh stashes restored
+	KeptStash     string         // Refresh stash left in place, if any (e.g. "stash@{0}")
+	KeptReason    string         // Why KeptStash was not restored
+}
+
+// Changed returns true if Recover touched the worktree
+func (r RecoveryResult) Changed() bool {
+	return r.Aborted != nil || len(r.RestoredStash) > 0
+}
+
+// Recover aborts a rebase or merge that has been idle for at least
+// olderThan and restores uncommitted changes left in refresh stashes.
+// Stashes are only restored into a clean worktree with no operation in
+// progress; otherwise they are kept so nothing is lost.
+func Recover(worktreePath string, olderThan time.Duration, now time.Time) (RecoveryResult, error) {
+	var result RecoveryResult
+
+	op, err := DetectInterrupted(worktreePath)
+	if err != nil {
+		return result, err
+	}
+	if op != nil {
+		if now.Sub(op.LastActivity) < olderThan {
+			result.Pending = op
+			return result, nil
+		}
+		cmd := gitCommand(op.Kind, "--abort")
+		cmd.Dir = worktreePath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return result, fmt.Errorf("failed to abort %s: %w\nOutput: %s", op.Kind, err, output)
+		}
+		result.Aborted = op
+	}
+
+	for {
+		ref, message, err := findRefreshStash(worktreePath)
+		if err != nil {
+			return result, err
+		}
+		if ref == "" {
+			return result, nil
+		}
+		dirty, err := HasUncommittedChanges(worktreePath)
+		if err != nil {
+			return result, err
+		}
+		if dirty {
+			result.KeptStash, result.KeptReason = ref, "the worktree has uncommitted changes"
+			return result, nil
+		}
+		cmd := gitCommand("stash", "pop", ref)
+		cmd.Dir = worktreePath
+		if output, err := cmd.CombinedOutput(); err != nil {
+			// git keeps a stash whose pop conflicted
+			result.KeptStash, result.KeptReason = ref, fmt.Sprintf("it conflicts with the branch: %s", strings.TrimSpace(string(output)))
+			return result, nil
+		}
+		result.RestoredStash = append(result.RestoredStash, message)
+	}
+}
+
+// findRefreshStash returns the newest refresh stash of the worktree's branch
+func findRefreshStash(worktreePath string) (string, string, error) {
+	output, err := runGit(worktreePath, "stash", "list", "--format=%gd%x00%s")
+	if err != nil {
+		return "", "", err
+	}
+	branch, _ := GetCurrentBranch(worktreePath)
+	for _, line := range strings.Split(output, "\n") {
+		ref, subject, ok := strings.Cut(line, "\x00")
+		if !ok {
+			continue
+		}
+		// Subjects look like "On work/fox: refresh-stash-123"
+		on, message, ok := strings.Cut(subject, ": ")
+		if !ok || !strings.HasPrefix(message, RefreshStashPrefix) {
+			continue
+		}
+		if branch != "" && on != "On "+branch {
+			continue
+		}
+		return ref, message, nil
+	}
+	return "", "", nil
+}
@@ -0,0 +1,92 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoverInterruptedRebase(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) (string, error) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		return strings.TrimSpace(string(output)), err
+	}
+	mustGit := func(dir string, args ...string) string {
+		t.Helper()
+		output, err := git(dir, args...)
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return output
+	}
+	write := func(dir, name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(repoPath, "shared.txt", "base\n")
+	mustGit(repoPath, "add", "shared.txt")
+	mustGit(repoPath, "commit", "-m", "Add shared file")
+
+	wtPath := filepath.Join(t.TempDir(), "worker")
+	if err := NewManager(repoPath).CreateNewBranch(wtPath, "work/worker", "main"); err != nil {
+		t.Fatal(err)
+	}
+	write(wtPath, "shared.txt", "worker\n")
+	mustGit(wtPath, "commit", "-am", "Worker change")
+	write(repoPath, "shared.txt", "main\n")
+	mustGit(repoPath, "commit", "-am", "Main change")
+
+	// Simulate a refresh that died mid-rebase: changes stashed, rebase stopped
+	write(wtPath, "notes.txt", "uncommitted\n")
+	mustGit(wtPath, "stash", "push", "--include-untracked", "-m", RefreshStashPrefix+"999")
+	if _, err := git(wtPath, "rebase", "main"); err == nil {
+		t.Fatal("rebase should have stopped at the conflict")
+	}
+
+	op, err := DetectInterrupted(wtPath)
+	if err != nil || op == nil || op.Kind != "rebase" {
+		t.Fatalf("DetectInterrupted = %+v, %v", op, err)
+	}
+
+	// Recent operations are left alone
+	result, err := Recover(wtPath, time.Hour, time.Now())
+	if err != nil || result.Pending == nil || result.Changed() {
+		t.Fatalf("Recover of recent rebase = %+v, %v", result, err)
+	}
+
+	result, err = Recover(wtPath, time.Hour, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if result.Aborted == nil || result.Aborted.Kind != "rebase" || len(result.RestoredStash) != 1 {
+		t.Errorf("Recover = %+v", result)
+	}
+	if op, _ := DetectInterrupted(wtPath); op != nil {
+		t.Errorf("rebase still in progress after recovery: %+v", op)
+	}
+	if data, err := os.ReadFile(filepath.Join(wtPath, "notes.txt")); err != nil || string(data) != "uncommitted\n" {
+		t.Errorf("stashed changes not restored: %q, %v", data, err)
+	}
+	if stashes := mustGit(wtPath, "stash", "list"); stashes != "" {
+		t.Errorf("refresh stash left behind: %s", stashes)
+	}
+
+	// A dirty worktree keeps the stash instead of mixing changes
+	mustGit(wtPath, "stash", "push", "--include-untracked", "-m", RefreshStashPrefix+"1000")
+	write(wtPath, "other.txt", "new work\n")
+	result, err = Recover(wtPath, time.Hour, time.Now())
+	if err != nil || result.KeptStash != "stash@{0}" || len(result.RestoredStash) != 0 {
+		t.Errorf("Recover into dirty worktree = %+v, %v", result, err)
+	}
+}
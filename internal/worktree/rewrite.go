@@ -0,0 +1,41 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// RemoteHead returns the commit remote/<branch> points at
+func (m *Manager) RemoteHead(remote, branch string) (string, error) {
+	head, err := runGit(m.repoPath, "rev-parse", "--verify", fmt.Sprintf("refs/remotes/%s/%s^{commit}", remote, branch))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s/%s: %w", remote, branch, err)
+	}
+	return head, nil
+}
+
+// IsHistoryRewrite reports whether newHead no longer contains oldHead, i.e.
+// the branch moved from oldHead to newHead by a force-push rather than by
+// adding commits. An old head the repository no longer has counts as a
+// rewrite.
+func (m *Manager) IsHistoryRewrite(oldHead, newHead string) (bool, error) {
+	if oldHead == newHead {
+		return false, nil
+	}
+	if _, err := runGit(m.repoPath, "cat-file", "-e", oldHead+"^{commit}"); err != nil {
+		return true, nil
+	}
+
+	cmd := gitCommand("merge-base", "--is-ancestor", oldHead, newHead)
+	cmd.Dir = m.repoPath
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return false, nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, fmt.Errorf("failed to compare %s with %s: %w\nOutput: %s", oldHead, newHead, err, output)
+}
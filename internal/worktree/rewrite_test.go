@@ -0,0 +1,43 @@
+package worktree
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestIsHistoryRewrite(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	m := NewManager(repoPath)
+
+	base := git("rev-parse", "HEAD")
+	git("commit", "--allow-empty", "-m", "Second")
+	second := git("rev-parse", "HEAD")
+	if rewritten, err := m.IsHistoryRewrite(base, second); err != nil || rewritten {
+		t.Errorf("IsHistoryRewrite(fast-forward) = %v, %v; want false", rewritten, err)
+	}
+
+	git("reset", "--hard", base)
+	git("commit", "--allow-empty", "-m", "Replacement")
+	replacement := git("rev-parse", "HEAD")
+	if rewritten, err := m.IsHistoryRewrite(second, replacement); err != nil || !rewritten {
+		t.Errorf("IsHistoryRewrite(force-push) = %v, %v; want true", rewritten, err)
+	}
+
+	missing := strings.Repeat("1", len(base))
+	if rewritten, err := m.IsHistoryRewrite(missing, replacement); err != nil || !rewritten {
+		t.Errorf("IsHistoryRewrite(unknown old head) = %v, %v; want true", rewritten, err)
+	}
+}
@@ -0,0 +1,37 @@
+package worktree
+
+import (
+	"fmt"
+)
+
+// CreateScratch adds a worktree at path for a spike or bisect next to the
+// worktree at from. rev is resolved in from, so "HEAD" is from's current
+// commit. With branch empty the new worktree is detached; otherwise it is
+// checked out on a new branch of that name. Returns the commit it starts at.
+func (m *Manager) CreateScratch(path, from, rev, branch string) (string, error) {
+	commit, err := runGit(from, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+
+	if branch != "" {
+		if err := m.CreateNewBranch(path, branch, commit); err != nil {
+			return "", err
+		}
+		return commit, nil
+	}
+
+	if err := RequireGitFeature(FeatureWorktreePorcelain); err != nil {
+		return "", err
+	}
+	cmd := gitCommand("worktree", "add", "--detach", path, commit)
+	cmd.Dir = m.repoPath
+	cmd.Env = m.checkoutEnv()
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to create worktree: %w\nOutput: %s%s", err, output, m.partialCloneHint())
+	}
+	if err := m.initWorktree(path); err != nil {
+		return "", err
+	}
+	return commit, nil
+}
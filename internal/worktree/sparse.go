@@ -0,0 +1,45 @@
+package worktree
+
+import (
+	"fmt"
+)
+
+// SparseCheckout limits a worktree's checkout to dirs (cone mode, so files
+// at the repository root stay present). The sparse patterns are stored per
+// worktree and do not affect the main checkout or other agents.
+func SparseCheckout(worktreePath string, dirs []string) error {
+	if err := RequireGitFeature(FeatureSparseCheckout); err != nil {
+		return err
+	}
+
+	// Per-worktree config keeps core.sparseCheckout out of the shared config
+	cmd := gitCommand("config", "extensions.worktreeConfig", "true")
+	cmd.Dir = worktreePath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to enable worktree config: %w\nOutput: %s", err, output)
+	}
+
+	cmd = gitCommand("sparse-checkout", "init", "--cone")
+	cmd.Dir = worktreePath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to enable sparse checkout: %w\nOutput: %s", err, output)
+	}
+
+	cmd = gitCommand(append([]string{"sparse-checkout", "set"}, dirs...)...)
+	cmd.Dir = worktreePath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set sparse checkout paths: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// UpstreamChangesIn reports whether commits on base that the worktree's
+// branch does not have yet touch any of dirs
+func UpstreamChangesIn(worktreePath, base string, dirs []string) (bool, error) {
+	args := append([]string{"rev-list", "--count", "HEAD.." + base, "--"}, dirs...)
+	output, err := runGit(worktreePath, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to check upstream changes: %w", err)
+	}
+	return output != "0", nil
+}
@@ -0,0 +1,65 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSparseCheckout(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	commitFile := func(dir, name string) {
+		t.Helper()
+		full := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", name}, {"commit", "-m", "Add " + name}} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v failed: %v\n%s", args, err, output)
+			}
+		}
+	}
+	commitFile(repoPath, "services/billing/main.go")
+	commitFile(repoPath, "services/search/main.go")
+
+	wtPath := filepath.Join(t.TempDir(), "wt")
+	if err := NewManager(repoPath).CreateNewBranch(wtPath, "work/scoped", "main"); err != nil {
+		t.Fatalf("CreateNewBranch failed: %v", err)
+	}
+	if err := SparseCheckout(wtPath, []string{"services/billing"}); err != nil {
+		t.Fatalf("SparseCheckout failed: %v", err)
+	}
+
+	for path, want := range map[string]bool{
+		"README.md":                true,
+		"services/billing/main.go": true,
+		"services/search/main.go":  false,
+	} {
+		_, err := os.Stat(filepath.Join(wtPath, path))
+		if got := err == nil; got != want {
+			t.Errorf("%s present = %v, want %v", path, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "services/search/main.go")); err != nil {
+		t.Errorf("sparse checkout must not affect the main checkout: %v", err)
+	}
+
+	// Upstream changes outside the scope don't count
+	commitFile(repoPath, "services/search/index.go")
+	if changed, err := UpstreamChangesIn(wtPath, "main", []string{"services/billing"}); err != nil || changed {
+		t.Errorf("UpstreamChangesIn() = %v, %v; want false", changed, err)
+	}
+	commitFile(repoPath, "services/billing/invoice.go")
+	if changed, err := UpstreamChangesIn(wtPath, "main", []string{"services/billing"}); err != nil || !changed {
+		t.Errorf("UpstreamChangesIn() = %v, %v; want true", changed, err)
+	}
+}
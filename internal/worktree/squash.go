@@ -0,0 +1,153 @@
+package worktree
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BackupRefPrefix is where SquashBranch keeps the original commits
+const BackupRefPrefix = "refs/multiclaude/backup/"
+
+// maxSquashTitle is the longest subject SynthesizeSquashMessage produces
+const maxSquashTitle = 72
+
+// maxSummaryFiles caps the file list in a synthesized squash message
+const maxSummaryFiles = 20
+
+// DiffSummary describes the changes between the merge base with base and HEAD
+type DiffSummary struct {
+	ShortStat string   // e.g. "3 files changed, 40 insertions(+), 2 deletions(-)"
+	Files     []string // Changed paths
+}
+
+// SummarizeDiff returns a DiffSummary for the worktree's branch against base
+func SummarizeDiff(worktreePath, base string) (*DiffSummary, error) {
+	mergeBase, err := runGit(worktreePath, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge base with %s: %w", base, err)
+	}
+	shortStat, err := runGit(worktreePath, "diff", "--shortstat", mergeBase, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	names, err := runGit(worktreePath, "diff", "--name-only", mergeBase, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &DiffSummary{ShortStat: strings.TrimSpace(shortStat)}
+	for _, name := range strings.Split(names, "\n") {
+		if name != "" {
+			summary.Files = append(summary.Files, name)
+		}
+	}
+	return summary, nil
+}
+
+// squashTitle derives a commit subject from the first line of a task description
+func squashTitle(task string) string {
+	title := strings.TrimSpace(strings.SplitN(strings.TrimSpace(task), "\n", 2)[0])
+	title = strings.TrimRight(title, ".")
+	if title == "" {
+		return "Squashed agent changes"
+	}
+	title = strings.ToUpper(title[:1]) + title[1:]
+	if len(title) > maxSquashTitle {
+		cut := strings.LastIndex(title[:maxSquashTitle-3], " ")
+		if cut <= 0 {
+			cut = maxSquashTitle - 3
+		}
+		title = strings.TrimSpace(title[:cut]) + "..."
+	}
+	return title
+}
+
+// SynthesizeSquashMessage builds a commit message for a squashed branch from
+// the task, the original commits, and a summary of the diff. If title is
+// empty, it is derived from the task.
+func SynthesizeSquashMessage(title, task string, commits []BranchCommit, diff *DiffSummary) string {
+	if title == "" {
+		title = squashTitle(task)
+	}
+
+	var b strings.Builder
+	b.WriteString(title)
+	b.WriteString("\n")
+
+	if task = strings.TrimSpace(task); task != "" && task != title {
+		b.WriteString("\nTask: ")
+		b.WriteString(task)
+		b.WriteString("\n")
+	}
+
+	if diff != nil && diff.ShortStat != "" {
+		b.WriteString("\n")
+		b.WriteString(diff.ShortStat)
+		b.WriteString(":\n")
+		for i, f := range diff.Files {
+			if i == maxSummaryFiles {
+				fmt.Fprintf(&b, "  ... and %d more\n", len(diff.Files)-maxSummaryFiles)
+				break
+			}
+			b.WriteString("  ")
+			b.WriteString(f)
+			b.WriteString("\n")
+		}
+	}
+
+	if len(commits) > 1 {
+		b.WriteString("\nSquashed commits:\n")
+		for _, c := range commits {
+			b.WriteString("- ")
+			b.WriteString(c.Subject)
+			b.WriteString("\n")
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// SquashBranch replaces the commits in base..HEAD with a single commit that
+// has the same tree and the given message. The original head is kept under
+// BackupRefPrefix, and the backup ref name is returned.
+func SquashBranch(worktreePath, base, message string) (string, error) {
+	oldHead, err := runGit(worktreePath, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	mergeBase, err := runGit(worktreePath, "merge-base", base, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to find merge base with %s: %w", base, err)
+	}
+	if mergeBase == oldHead {
+		return "", fmt.Errorf("no commits to squash since %s", base)
+	}
+
+	branch, err := runGit(worktreePath, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if branch == "HEAD" {
+		return "", fmt.Errorf("cannot squash a detached HEAD")
+	}
+
+	backupRef := fmt.Sprintf("%s%s/%d", BackupRefPrefix, branch, time.Now().Unix())
+	if _, err := runGit(worktreePath, "update-ref", backupRef, oldHead); err != nil {
+		return "", fmt.Errorf("failed to create backup ref: %w", err)
+	}
+
+	cmd := gitCommand("commit-tree", "HEAD^{tree}", "-p", mergeBase, "-F", "-")
+	cmd.Dir = worktreePath
+	cmd.Stdin = strings.NewReader(message)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to create squashed commit: %w", err)
+	}
+	newHead := strings.TrimSpace(string(output))
+
+	if _, err := runGit(worktreePath, "update-ref", "-m", "multiclaude: squash branch", "HEAD", newHead, oldHead); err != nil {
+		return "", fmt.Errorf("failed to update branch: %w", err)
+	}
+	return backupRef, nil
+}
@@ -0,0 +1,107 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSquashTitle(t *testing.T) {
+	tests := []struct {
+		task string
+		want string
+	}{
+		{"fix the flaky router test.", "Fix the flaky router test"},
+		{"Add retries\nwith more detail below", "Add retries"},
+		{"", "Squashed agent changes"},
+		{strings.Repeat("word ", 30), "Word word word word word word word word word word word word word..."},
+	}
+
+	for _, tt := range tests {
+		got := squashTitle(tt.task)
+		if got != tt.want {
+			t.Errorf("squashTitle(%q) = %q, want %q", tt.task, got, tt.want)
+		}
+		if len(got) > maxSquashTitle {
+			t.Errorf("title %q longer than %d characters", got, maxSquashTitle)
+		}
+	}
+}
+
+func TestSquashBranch(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-squash")
+	if err := manager.CreateNewBranch(wtPath, "work/squash", "main"); err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	for i, msg := range []string{"wip", "more wip", "fix typo"} {
+		file := filepath.Join(wtPath, "file"+string(rune('a'+i))+".txt")
+		if err := os.WriteFile(file, []byte(msg), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := runGit(wtPath, "add", "-A"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := runGit(wtPath, "commit", "-m", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldTree, _ := runGit(wtPath, "rev-parse", "HEAD^{tree}")
+
+	commits, err := ListBranchCommits(wtPath, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := SummarizeDiff(wtPath, "main")
+	if err != nil {
+		t.Fatalf("SummarizeDiff failed: %v", err)
+	}
+	if len(diff.Files) != 3 || !strings.Contains(diff.ShortStat, "3 files changed") {
+		t.Errorf("unexpected diff summary: %+v", diff)
+	}
+
+	message := SynthesizeSquashMessage("", "add three files", commits, diff)
+	for _, want := range []string{"Add three files\n", "filea.txt", "- more wip"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message missing %q:\n%s", want, message)
+		}
+	}
+
+	backupRef, err := SquashBranch(wtPath, "main", message)
+	if err != nil {
+		t.Fatalf("SquashBranch failed: %v", err)
+	}
+
+	commits, err = ListBranchCommits(wtPath, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].Subject != "Add three files" {
+		t.Errorf("expected a single squashed commit, got %+v", commits)
+	}
+
+	newTree, _ := runGit(wtPath, "rev-parse", "HEAD^{tree}")
+	if newTree != oldTree {
+		t.Error("squash must not change the tree")
+	}
+
+	if !strings.HasPrefix(backupRef, BackupRefPrefix+"work/squash/") {
+		t.Errorf("unexpected backup ref %q", backupRef)
+	}
+	backupLog, err := runGit(wtPath, "log", "--format=%s", "main.."+backupRef)
+	if err != nil {
+		t.Fatalf("backup ref not readable: %v", err)
+	}
+	if backupLog != "fix typo\nmore wip\nwip" {
+		t.Errorf("backup ref should keep original commits, got %q", backupLog)
+	}
+
+	if _, err := SquashBranch(repoPath, "main", "nothing"); err == nil {
+		t.Error("squashing a branch with no commits should fail")
+	}
+}
@@ -0,0 +1,94 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Submodule states reported by git submodule status
+const (
+	SubmoduleInSync        = "in sync"
+	SubmoduleUninitialized = "uninitialized" // never checked out in this worktree
+	SubmoduleOutOfSync     = "out of sync"   // checked out at a different commit than recorded
+	SubmoduleConflict      = "conflict"      // merge conflict on the submodule pointer
+)
+
+// Submodule is one submodule of a worktree and whether its checkout matches
+// the commit the superproject records
+type Submodule struct {
+	Path   string `json:"path"`
+	Commit string `json:"commit"`
+	State  string `json:"state"`
+}
+
+// HasSubmodules reports whether the checkout at path declares submodules
+func HasSubmodules(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".gitmodules"))
+	return err == nil
+}
+
+// UpdateSubmodules initializes the worktree's submodules and checks each one
+// out at the commit the superproject records, recursively. Worktrees share
+// the clone's submodule objects, so only commits nothing has fetched yet are
+// downloaded.
+func UpdateSubmodules(worktreePath string) error {
+	if !HasSubmodules(worktreePath) {
+		return nil
+	}
+	if _, err := runGit(worktreePath, "submodule", "update", "--init", "--recursive"); err != nil {
+		return fmt.Errorf("failed to update submodules: %w", err)
+	}
+	return nil
+}
+
+// SubmoduleStatus lists the worktree's submodules, recursively. It returns
+// nil for a worktree without submodules.
+func SubmoduleStatus(worktreePath string) ([]Submodule, error) {
+	if !HasSubmodules(worktreePath) {
+		return nil, nil
+	}
+	output, err := runGit(worktreePath, "submodule", "status", "--recursive")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submodule status: %w", err)
+	}
+	return parseSubmoduleStatus(output), nil
+}
+
+// StaleSubmodules returns the submodules in subs that are not in sync
+func StaleSubmodules(subs []Submodule) []Submodule {
+	var stale []Submodule
+	for _, sub := range subs {
+		if sub.State != SubmoduleInSync {
+			stale = append(stale, sub)
+		}
+	}
+	return stale
+}
+
+// parseSubmoduleStatus parses git submodule status lines such as
+// "+3f2a... vendor/lib (v1.2-3-g3f2a)", where the first column is the state
+func parseSubmoduleStatus(output string) []Submodule {
+	var subs []Submodule
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		fields := strings.Fields(line[1:])
+		if len(fields) < 2 {
+			continue
+		}
+		sub := Submodule{Commit: fields[0], Path: fields[1], State: SubmoduleInSync}
+		switch line[0] {
+		case '-':
+			sub.State = SubmoduleUninitialized
+		case '+':
+			sub.State = SubmoduleOutOfSync
+		case 'U':
+			sub.State = SubmoduleConflict
+		}
+		subs = append(subs, sub)
+	}
+	return subs
+}
@@ -0,0 +1,102 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSubmoduleStatus(t *testing.T) {
+	output := " 1111111111111111111111111111111111111111 libs/a (v1.0)\n" +
+		"-2222222222222222222222222222222222222222 libs/b\n" +
+		"+3333333333333333333333333333333333333333 libs/c (v1.2-3-g3333333)\n" +
+		"U4444444444444444444444444444444444444444 libs/d"
+	want := []Submodule{
+		{Path: "libs/a", Commit: "1111111111111111111111111111111111111111", State: SubmoduleInSync},
+		{Path: "libs/b", Commit: "2222222222222222222222222222222222222222", State: SubmoduleUninitialized},
+		{Path: "libs/c", Commit: "3333333333333333333333333333333333333333", State: SubmoduleOutOfSync},
+		{Path: "libs/d", Commit: "4444444444444444444444444444444444444444", State: SubmoduleConflict},
+	}
+	got := parseSubmoduleStatus(output)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseSubmoduleStatus() = %+v, want %+v", got, want)
+	}
+	if stale := StaleSubmodules(got); len(stale) != 3 || stale[0].Path != "libs/b" {
+		t.Errorf("StaleSubmodules() = %+v", stale)
+	}
+}
+
+func TestWorktreeSubmodules(t *testing.T) {
+	// Submodules in these tests are cloned from local paths
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	libPath, cleanupLib := createTestRepo(t)
+	defer cleanupLib()
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	git(repoPath, "submodule", "add", "-q", libPath, "lib")
+	git(repoPath, "commit", "-q", "-m", "Add lib submodule")
+	git(repoPath, "remote", "add", "origin", repoPath)
+	git(repoPath, "fetch", "-q", "origin")
+
+	if !HasSubmodules(repoPath) {
+		t.Fatal("HasSubmodules should find .gitmodules")
+	}
+
+	manager := NewManager(repoPath)
+	wtPath := filepath.Join(repoPath, "wt-submodules")
+	if err := manager.CreateNewBranch(wtPath, "feature/submodules", "main"); err != nil {
+		t.Fatalf("CreateNewBranch failed: %v", err)
+	}
+	defer manager.Remove(wtPath, true)
+
+	if _, err := os.Stat(filepath.Join(wtPath, "lib", "README.md")); err != nil {
+		t.Fatalf("submodule should be checked out in the new worktree: %v", err)
+	}
+	subs, err := SubmoduleStatus(wtPath)
+	if err != nil || len(subs) != 1 || subs[0].State != SubmoduleInSync {
+		t.Fatalf("SubmoduleStatus() = %+v, %v", subs, err)
+	}
+
+	// Move the submodule forward on main; the worktree's checkout is stale
+	// until a refresh follows the new pointer
+	if err := os.WriteFile(filepath.Join(libPath, "new.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(libPath, "add", "new.txt")
+	git(libPath, "commit", "-q", "-m", "Lib moves on")
+	git(filepath.Join(repoPath, "lib"), "pull", "-q", "origin", "main")
+	git(repoPath, "commit", "-q", "-am", "Bump lib")
+	git(repoPath, "fetch", "-q", "origin")
+
+	result := RefreshWorktree(wtPath, "origin", "main")
+	if result.Error != nil || !result.SubmodulesUpdated {
+		t.Fatalf("refresh should update submodules: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(wtPath, "lib", "new.txt")); err != nil {
+		t.Errorf("submodule should follow the rebased pointer: %v", err)
+	}
+
+	// Checking out a different submodule commit by hand is reported
+	git(filepath.Join(wtPath, "lib"), "checkout", "-q", "HEAD~1")
+	state, err := GetWorktreeState(wtPath, "origin", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.StaleSubmodules) != 1 || state.StaleSubmodules[0].State != SubmoduleOutOfSync {
+		t.Errorf("StaleSubmodules = %+v, want lib out of sync", state.StaleSubmodules)
+	}
+}
@@ -0,0 +1,32 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Reassign hands a pre-created worktree to a new owner: it resets the
+// worktree to startPoint, removes untracked files git does not ignore
+// (ignored files such as installed dependencies are kept), renames its
+// branch, and moves it to newPath.
+func (m *Manager) Reassign(oldPath, oldBranch, newPath, newBranch, startPoint string) error {
+	if _, err := runGit(oldPath, "reset", "--hard", "--quiet", startPoint); err != nil {
+		return fmt.Errorf("failed to reset to %s: %w", startPoint, err)
+	}
+	if _, err := runGit(oldPath, "clean", "-fdq"); err != nil {
+		return fmt.Errorf("failed to clean worktree: %w", err)
+	}
+	if err := m.RenameBranch(oldBranch, newBranch); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+	if _, err := runGit(m.repoPath, "worktree", "move", oldPath, newPath); err != nil {
+		// Leave the worktree usable where it is
+		_ = m.RenameBranch(newBranch, oldBranch)
+		return fmt.Errorf("failed to move worktree: %w", err)
+	}
+	return nil
+}
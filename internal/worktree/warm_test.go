@@ -0,0 +1,69 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReassign(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	m := NewManager(repoPath)
+
+	if err := os.WriteFile(filepath.Join(repoPath, ".gitignore"), []byte("deps/\nwarm/\nwts/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(repoPath, "add", ".gitignore")
+	git(repoPath, "commit", "-m", "Ignore deps")
+	base := git(repoPath, "rev-parse", "HEAD")
+	warmPath := filepath.Join(repoPath, "warm", "w1")
+	if err := m.CreateNewBranch(warmPath, "warm/w1", base); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bootstrap output is ignored and must survive; stray files must not
+	os.MkdirAll(filepath.Join(warmPath, "deps"), 0755)
+	os.WriteFile(filepath.Join(warmPath, "deps", "lib"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(warmPath, "stray.txt"), []byte("x"), 0644)
+
+	git(repoPath, "commit", "--allow-empty", "-m", "Main moved")
+	main := git(repoPath, "rev-parse", "HEAD")
+
+	newPath := filepath.Join(repoPath, "wts", "worker")
+	if err := m.Reassign(warmPath, "warm/w1", newPath, "work/worker", "main"); err != nil {
+		t.Fatalf("Reassign failed: %v", err)
+	}
+
+	if _, err := os.Stat(warmPath); !os.IsNotExist(err) {
+		t.Error("warm worktree should have moved")
+	}
+	if branch := git(newPath, "rev-parse", "--abbrev-ref", "HEAD"); branch != "work/worker" {
+		t.Errorf("branch = %q, want work/worker", branch)
+	}
+	if head := git(newPath, "rev-parse", "HEAD"); head != main {
+		t.Errorf("HEAD = %s, want main %s", head, main)
+	}
+	if _, err := os.Stat(filepath.Join(newPath, "stray.txt")); !os.IsNotExist(err) {
+		t.Error("untracked files should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(newPath, "deps", "lib")); err != nil {
+		t.Error("ignored bootstrap files should be kept")
+	}
+	if exists, _ := m.BranchExists("warm/w1"); exists {
+		t.Error("warm branch should be renamed")
+	}
+}
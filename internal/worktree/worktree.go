@@ -1,21 +1,59 @@
 package worktree
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/dlorenc/multiclaude/internal/tracing"
 )
 
 // Manager handles git worktree operations
 type Manager struct {
 	repoPath string
+	// skipLFSContent leaves Git LFS files as pointer files in new worktrees
+	skipLFSContent bool
+	// progress receives progress of slow steps such as LFS downloads
+	progress io.Writer
+	// backend answers read-only branch and worktree queries
+	backend Backend
+}
+
+// ManagerOption configures a Manager
+type ManagerOption func(*Manager)
+
+// WithLFSContent sets whether new worktrees download Git LFS file content
+// (the default) or keep pointer files, for agents that don't need binaries
+func WithLFSContent(fetch bool) ManagerOption {
+	return func(m *Manager) {
+		m.skipLFSContent = !fetch
+	}
+}
+
+// WithProgress reports progress of slow worktree setup steps to w
+func WithProgress(w io.Writer) ManagerOption {
+	return func(m *Manager) {
+		m.progress = w
+	}
 }
 
 // NewManager creates a new worktree manager for a repository
-func NewManager(repoPath string) *Manager {
-	return &Manager{repoPath: repoPath}
+func NewManager(repoPath string, opts ...ManagerOption) *Manager {
+	m := &Manager{repoPath: repoPath, backend: defaultBackend()}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// gitCommand returns a git command whose run is recorded as a span of the
+// current trace, if there is one
+func gitCommand(args ...string) *tracing.Cmd {
+	return tracing.Command(context.Background(), exec.Command("git", args...))
 }
 
 // resolvePathWithSymlinks resolves a path to its absolute form and evaluates symlinks.
@@ -38,20 +76,56 @@ func resolvePathWithSymlinks(path string) (string, error) {
 
 // Create creates a new git worktree
 func (m *Manager) Create(path, branch string) error {
-	cmd := exec.Command("git", "worktree", "add", path, branch)
+	if err := RequireGitFeature(FeatureWorktreePorcelain); err != nil {
+		return err
+	}
+	cmd := gitCommand("worktree", "add", path, branch)
 	cmd.Dir = m.repoPath
+	cmd.Env = m.checkoutEnv()
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to create worktree: %w\nOutput: %s", err, output)
+		return fmt.Errorf("failed to create worktree: %w\nOutput: %s%s", err, output, m.partialCloneHint())
 	}
-	return nil
+	// Best effort: a missing exclude is caught again at completion
+	_ = m.EnsureExcludes()
+	return m.initWorktree(path)
 }
 
 // CreateNewBranch creates a new worktree with a new branch
 func (m *Manager) CreateNewBranch(path, newBranch, startPoint string) error {
-	cmd := exec.Command("git", "worktree", "add", "-b", newBranch, path, startPoint)
+	if err := RequireGitFeature(FeatureWorktreePorcelain); err != nil {
+		return err
+	}
+	cmd := gitCommand("worktree", "add", "-b", newBranch, path, startPoint)
 	cmd.Dir = m.repoPath
+	cmd.Env = m.checkoutEnv()
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to create worktree with new branch: %w\nOutput: %s", err, output)
+		return fmt.Errorf("failed to create worktree with new branch: %w\nOutput: %s%s", err, output, m.partialCloneHint())
+	}
+	// Best effort: a missing exclude is caught again at completion
+	_ = m.EnsureExcludes()
+	return m.initWorktree(path)
+}
+
+// checkoutEnv returns the environment for git worktree add: Git LFS content
+// is downloaded afterwards by initWorktree rather than file by file
+func (m *Manager) checkoutEnv() []string {
+	if !UsesLFS(m.repoPath) {
+		return nil
+	}
+	return append(os.Environ(), LFSSkipSmudgeEnv)
+}
+
+// initWorktree checks out a new worktree's submodules and Git LFS content,
+// since git worktree add leaves them empty or as pointer files and builds
+// that need them fail. A worktree that can't be set up is removed again.
+func (m *Manager) initWorktree(path string) error {
+	err := UpdateSubmodules(path)
+	if err == nil {
+		err = SetupLFS(path, !m.skipLFSContent, m.progress)
+	}
+	if err != nil {
+		_ = m.Remove(path, true)
+		return fmt.Errorf("failed to create worktree: %w", err)
 	}
 	return nil
 }
@@ -63,7 +137,7 @@ func (m *Manager) Remove(path string, force bool) error {
 		args = append(args, "--force")
 	}
 
-	cmd := exec.Command("git", args...)
+	cmd := gitCommand(args...)
 	cmd.Dir = m.repoPath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to remove worktree: %w\nOutput: %s", err, output)
@@ -73,7 +147,15 @@ func (m *Manager) Remove(path string, force bool) error {
 
 // List returns a list of all worktrees
 func (m *Manager) List() ([]WorktreeInfo, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
+	if r := m.native(); r != nil {
+		if worktrees, err := r.worktrees(); err == nil {
+			return worktrees, nil
+		}
+	}
+	if err := RequireGitFeature(FeatureWorktreePorcelain); err != nil {
+		return nil, err
+	}
+	cmd := gitCommand("worktree", "list", "--porcelain")
 	cmd.Dir = m.repoPath
 	output, err := cmd.Output()
 	if err != nil {
@@ -110,7 +192,7 @@ func (m *Manager) Exists(path string) (bool, error) {
 
 // Prune removes worktree information for missing paths
 func (m *Manager) Prune() error {
-	cmd := exec.Command("git", "worktree", "prune")
+	cmd := gitCommand("worktree", "prune")
 	cmd.Dir = m.repoPath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to prune worktrees: %w\nOutput: %s", err, output)
@@ -118,9 +200,29 @@ func (m *Manager) Prune() error {
 	return nil
 }
 
+// Prunable returns the worktree entries Prune would remove, as
+// "<name>: <reason>" (e.g. "worker-1: gitdir file points to non-existent
+// location")
+func (m *Manager) Prunable() ([]string, error) {
+	cmd := gitCommand("worktree", "prune", "--dry-run", "--verbose")
+	cmd.Dir = m.repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prunable worktrees: %w\nOutput: %s", err, output)
+	}
+
+	var prunable []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if entry, ok := strings.CutPrefix(strings.TrimSpace(line), "Removing worktrees/"); ok {
+			prunable = append(prunable, entry)
+		}
+	}
+	return prunable, nil
+}
+
 // HasUncommittedChanges checks if a worktree has uncommitted changes
 func HasUncommittedChanges(path string) (bool, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
+	cmd := gitCommand("status", "--porcelain")
 	cmd.Dir = path
 	output, err := cmd.Output()
 	if err != nil {
@@ -133,14 +235,14 @@ func HasUncommittedChanges(path string) (bool, error) {
 // HasUnpushedCommits checks if a worktree has unpushed commits
 func HasUnpushedCommits(path string) (bool, error) {
 	// First verify this is a valid git repository
-	verifyCmd := exec.Command("git", "rev-parse", "--git-dir")
+	verifyCmd := gitCommand("rev-parse", "--git-dir")
 	verifyCmd.Dir = path
 	if err := verifyCmd.Run(); err != nil {
 		return false, fmt.Errorf("not a git repository: %w", err)
 	}
 
 	// Check if there's a tracking branch
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	cmd := gitCommand("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
 	cmd.Dir = path
 	if err := cmd.Run(); err != nil {
 		// No tracking branch, so no unpushed commits
@@ -149,7 +251,7 @@ func HasUnpushedCommits(path string) (bool, error) {
 	}
 
 	// Check for commits ahead of upstream
-	cmd = exec.Command("git", "rev-list", "--count", "@{u}..")
+	cmd = gitCommand("rev-list", "--count", "@{u}..")
 	cmd.Dir = path
 	output, err := cmd.Output()
 	if err != nil {
@@ -162,7 +264,7 @@ func HasUnpushedCommits(path string) (bool, error) {
 
 // GetCurrentBranch returns the current branch name for a worktree
 func GetCurrentBranch(path string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd := gitCommand("rev-parse", "--abbrev-ref", "HEAD")
 	cmd.Dir = path
 	output, err := cmd.Output()
 	if err != nil {
@@ -220,7 +322,12 @@ func parseWorktreeList(output string) []WorktreeInfo {
 
 // BranchExists checks if a branch exists in the repository
 func (m *Manager) BranchExists(branchName string) (bool, error) {
-	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
+	if r := m.native(); r != nil {
+		if exists, err := r.branchExists(branchName); err == nil {
+			return exists, nil
+		}
+	}
+	cmd := gitCommand("show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
 	cmd.Dir = m.repoPath
 	err := cmd.Run()
 	if err != nil {
@@ -235,7 +342,7 @@ func (m *Manager) BranchExists(branchName string) (bool, error) {
 
 // RenameBranch renames a branch from oldName to newName
 func (m *Manager) RenameBranch(oldName, newName string) error {
-	cmd := exec.Command("git", "branch", "-m", oldName, newName)
+	cmd := gitCommand("branch", "-m", oldName, newName)
 	cmd.Dir = m.repoPath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to rename branch: %w\nOutput: %s", err, output)
@@ -245,7 +352,7 @@ func (m *Manager) RenameBranch(oldName, newName string) error {
 
 // DeleteBranch force deletes a branch (git branch -D)
 func (m *Manager) DeleteBranch(branchName string) error {
-	cmd := exec.Command("git", "branch", "-D", branchName)
+	cmd := gitCommand("branch", "-D", branchName)
 	cmd.Dir = m.repoPath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to delete branch: %w\nOutput: %s", err, output)
@@ -255,7 +362,12 @@ func (m *Manager) DeleteBranch(branchName string) error {
 
 // ListBranchesWithPrefix lists all branches that start with the given prefix
 func (m *Manager) ListBranchesWithPrefix(prefix string) ([]string, error) {
-	cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short)", "refs/heads/"+prefix)
+	if r := m.native(); r != nil {
+		if branches, err := r.branchesWithPrefix(prefix); err == nil {
+			return branches, nil
+		}
+	}
+	cmd := gitCommand("for-each-ref", "--format=%(refname:short)", "refs/heads/"+prefix)
 	cmd.Dir = m.repoPath
 	output, err := cmd.Output()
 	if err != nil {
@@ -387,14 +499,14 @@ To fix this, you can either:
 // It prefers "upstream" if it exists, otherwise falls back to "origin"
 func (m *Manager) GetUpstreamRemote() (string, error) {
 	// Check if "upstream" remote exists
-	cmd := exec.Command("git", "remote", "get-url", "upstream")
+	cmd := gitCommand("remote", "get-url", "upstream")
 	cmd.Dir = m.repoPath
 	if err := cmd.Run(); err == nil {
 		return "upstream", nil
 	}
 
 	// Fall back to "origin"
-	cmd = exec.Command("git", "remote", "get-url", "origin")
+	cmd = gitCommand("remote", "get-url", "origin")
 	cmd.Dir = m.repoPath
 	if err := cmd.Run(); err == nil {
 		return "origin", nil
@@ -403,10 +515,55 @@ func (m *Manager) GetUpstreamRemote() (string, error) {
 	return "", fmt.Errorf("no upstream or origin remote found")
 }
 
+// RemoteURL returns the URL of a remote
+func (m *Manager) RemoteURL(remote string) (string, error) {
+	cmd := gitCommand("remote", "get-url", remote)
+	cmd.Dir = m.repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("no remote named %q", remote)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// SetPushDefault makes a plain `git push` in the clone and its worktrees go
+// to remote (remote.pushDefault). An empty remote unsets it, so pushes go to
+// each branch's own remote again.
+func (m *Manager) SetPushDefault(remote string) error {
+	args := []string{"config", "remote.pushDefault", remote}
+	if remote == "" {
+		args = []string{"config", "--unset", "remote.pushDefault"}
+	}
+	cmd := gitCommand(args...)
+	cmd.Dir = m.repoPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		// Unsetting a key that isn't set exits 5
+		if exitErr, ok := err.(*exec.ExitError); ok && remote == "" && exitErr.ExitCode() == 5 {
+			return nil
+		}
+		return fmt.Errorf("failed to set remote.pushDefault: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+// PushBranch pushes a local branch to the same name on remote and makes it
+// the branch's upstream. It pushes with --force-with-lease, so a rebased
+// branch replaces the remote one only if nobody else pushed to it since the
+// clone last fetched it.
+func (m *Manager) PushBranch(remote, branch string) error {
+	ref := "refs/heads/" + branch
+	cmd := gitCommand("push", "--force-with-lease", "--set-upstream", remote, ref+":"+ref)
+	cmd.Dir = m.repoPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to push %s to %s: %w\nOutput: %s", branch, remote, err, output)
+	}
+	return nil
+}
+
 // GetDefaultBranch returns the default branch name for a remote (e.g., "main" or "master")
 func (m *Manager) GetDefaultBranch(remote string) (string, error) {
 	// Try to get the default branch from the remote's HEAD
-	cmd := exec.Command("git", "symbolic-ref", fmt.Sprintf("refs/remotes/%s/HEAD", remote))
+	cmd := gitCommand("symbolic-ref", fmt.Sprintf("refs/remotes/%s/HEAD", remote))
 	cmd.Dir = m.repoPath
 	output, err := cmd.Output()
 	if err == nil {
@@ -420,7 +577,7 @@ func (m *Manager) GetDefaultBranch(remote string) (string, error) {
 
 	// Fallback: check for common branch names
 	for _, branch := range []string{"main", "master"} {
-		cmd := exec.Command("git", "rev-parse", "--verify", fmt.Sprintf("refs/remotes/%s/%s", remote, branch))
+		cmd := gitCommand("rev-parse", "--verify", fmt.Sprintf("refs/remotes/%s/%s", remote, branch))
 		cmd.Dir = m.repoPath
 		if err := cmd.Run(); err == nil {
 			return branch, nil
@@ -432,7 +589,7 @@ func (m *Manager) GetDefaultBranch(remote string) (string, error) {
 
 // FetchRemote fetches updates from a remote
 func (m *Manager) FetchRemote(remote string) error {
-	cmd := exec.Command("git", "fetch", remote)
+	cmd := gitCommand("fetch", remote)
 	cmd.Dir = m.repoPath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to fetch from %s: %w\nOutput: %s", remote, err, output)
@@ -464,7 +621,7 @@ func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, err
 
 	// Get branches merged into upstream's default branch
 	upstreamRef := fmt.Sprintf("%s/%s", remote, defaultBranch)
-	cmd := exec.Command("git", "branch", "--merged", upstreamRef, "--format=%(refname:short)")
+	cmd := gitCommand("branch", "--merged", upstreamRef, "--format=%(refname:short)")
 	cmd.Dir = m.repoPath
 	output, err := cmd.Output()
 	if err != nil {
@@ -494,7 +651,7 @@ func (m *Manager) FindMergedUpstreamBranches(branchPrefix string) ([]string, err
 
 // DeleteRemoteBranch deletes a branch from a remote
 func (m *Manager) DeleteRemoteBranch(remote, branchName string) error {
-	cmd := exec.Command("git", "push", remote, "--delete", branchName)
+	cmd := gitCommand("push", remote, "--delete", branchName)
 	cmd.Dir = m.repoPath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to delete remote branch: %w\nOutput: %s", err, output)
@@ -503,9 +660,11 @@ func (m *Manager) DeleteRemoteBranch(remote, branchName string) error {
 }
 
 // CleanupMergedBranches finds and deletes local branches that have been merged upstream.
-// If deleteRemote is true, it also deletes the corresponding remote branches from origin.
+// Merged status is checked against the upstream remote's default branch, but
+// branches are deleted from pushRemote, the remote workers push to (origin,
+// or the fork in a fork workflow). An empty pushRemote leaves remote branches alone.
 // Returns the list of deleted branch names.
-func (m *Manager) CleanupMergedBranches(branchPrefix string, deleteRemote bool) ([]string, error) {
+func (m *Manager) CleanupMergedBranches(branchPrefix string, pushRemote string) ([]string, error) {
 	// Find merged branches
 	mergedBranches, err := m.FindMergedUpstreamBranches(branchPrefix)
 	if err != nil {
@@ -543,10 +702,9 @@ func (m *Manager) CleanupMergedBranches(branchPrefix string, deleteRemote bool)
 		}
 		deleted = append(deleted, branch)
 
-		// Delete remote branch if requested
-		if deleteRemote {
-			// Try to delete from origin (the fork)
-			_ = m.DeleteRemoteBranch("origin", branch)
+		// Delete the branch from where it was pushed; it may never have been
+		if pushRemote != "" {
+			_ = m.DeleteRemoteBranch(pushRemote, branch)
 		}
 	}
 
@@ -576,6 +734,24 @@ func CleanupOrphanedWithDetails(wtRootDir string, manager *Manager) (*CleanupOrp
 		Errors: make(map[string]string),
 	}
 
+	orphaned, err := FindOrphaned(wtRootDir, manager)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range orphaned {
+		if err := os.RemoveAll(path); err != nil {
+			result.Errors[path] = err.Error()
+		} else {
+			result.Removed = append(result.Removed, path)
+		}
+	}
+
+	return result, nil
+}
+
+// FindOrphaned returns the directories in wtRootDir that are not git
+// worktrees of manager's repository, without removing them
+func FindOrphaned(wtRootDir string, manager *Manager) ([]string, error) {
 	// Get all worktrees from git
 	gitWorktrees, err := manager.List()
 	if err != nil {
@@ -595,11 +771,12 @@ func CleanupOrphanedWithDetails(wtRootDir string, manager *Manager) (*CleanupOrp
 	entries, err := os.ReadDir(wtRootDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return result, nil
+			return nil, nil
 		}
 		return nil, err
 	}
 
+	var orphaned []string
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -612,16 +789,11 @@ func CleanupOrphanedWithDetails(wtRootDir string, manager *Manager) (*CleanupOrp
 		}
 
 		if !gitPaths[evalPath] {
-			// This is an orphaned directory
-			if err := os.RemoveAll(path); err != nil {
-				result.Errors[path] = err.Error()
-			} else {
-				result.Removed = append(result.Removed, path)
-			}
+			orphaned = append(orphaned, path)
 		}
 	}
 
-	return result, nil
+	return orphaned, nil
 }
 
 // WorktreeState represents the current state of a worktree
@@ -636,6 +808,8 @@ type WorktreeState struct {
 	CommitsAhead   int  // Number of commits ahead of remote main
 	CanRefresh     bool // True if worktree is in a state that can be safely refreshed
 	RefreshReason  string
+	// StaleSubmodules lists submodules not checked out at the recorded commit
+	StaleSubmodules []Submodule
 }
 
 // GetWorktreeState checks the current state of a worktree and whether it can be safely refreshed
@@ -646,12 +820,12 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 	}
 
 	// Get current branch (or detect detached HEAD)
-	cmd := exec.Command("git", "symbolic-ref", "--short", "HEAD")
+	cmd := gitCommand("symbolic-ref", "--short", "HEAD")
 	cmd.Dir = worktreePath
 	output, err := cmd.Output()
 	if err != nil {
 		// Check if it's detached HEAD (different error than not being a git repo)
-		cmd2 := exec.Command("git", "rev-parse", "--verify", "HEAD")
+		cmd2 := gitCommand("rev-parse", "--verify", "HEAD")
 		cmd2.Dir = worktreePath
 		if err2 := cmd2.Run(); err2 != nil {
 			return state, fmt.Errorf("not a git repository or invalid state: %w", err)
@@ -695,6 +869,10 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 		state.HasUncommitted = hasChanges
 	}
 
+	if subs, err := SubmoduleStatus(worktreePath); err == nil {
+		state.StaleSubmodules = StaleSubmodules(subs)
+	}
+
 	// Skip commit count checks if we can't refresh anyway
 	if !state.CanRefresh {
 		return state, nil
@@ -708,7 +886,7 @@ func GetWorktreeState(worktreePath string, remote string, mainBranch string) (Wo
 	}
 
 	// Check commits behind/ahead of remote main
-	cmd = exec.Command("git", "rev-list", "--left-right", "--count", fmt.Sprintf("%s/%s...HEAD", remote, mainBranch))
+	cmd = gitCommand("rev-list", "--left-right", "--count", fmt.Sprintf("%s/%s...HEAD", remote, mainBranch))
 	cmd.Dir = worktreePath
 	output, err = cmd.Output()
 	if err != nil {
@@ -752,15 +930,33 @@ type RefreshResult struct {
 	StashRestored  bool
 	HasConflicts   bool
 	ConflictFiles  []string
-	Error          error
-	Skipped        bool
-	SkipReason     string
+	Conflicts      []ConflictFile // Per-file hunk summary of ConflictFiles
+	// SubmodulesUpdated is set when submodules were checked out at the
+	// commits the rebased branch records
+	SubmodulesUpdated bool
+	// Paused is set when a conflicting rebase was left stopped at the
+	// conflict instead of aborted. Changes stashed before it stay stashed.
+	Paused     bool
+	Error      error
+	Skipped    bool
+	SkipReason string
 }
 
 // RefreshWorktree syncs a worktree with the latest changes from the main branch.
 // It fetches from the remote, stashes any uncommitted changes, rebases onto main,
 // and restores the stash. Returns detailed results about what happened.
 func RefreshWorktree(worktreePath string, remote string, mainBranch string) RefreshResult {
+	return refreshWorktree(worktreePath, remote, mainBranch, false)
+}
+
+// RefreshWorktreeKeepingConflicts is RefreshWorktree, except that a
+// conflicting rebase is left paused at the conflict for someone to resolve
+// with `git rebase --continue` rather than aborted
+func RefreshWorktreeKeepingConflicts(worktreePath string, remote string, mainBranch string) RefreshResult {
+	return refreshWorktree(worktreePath, remote, mainBranch, true)
+}
+
+func refreshWorktree(worktreePath, remote, mainBranch string, keepConflicts bool) RefreshResult {
 	result := RefreshResult{
 		WorktreePath: worktreePath,
 	}
@@ -793,12 +989,12 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	}
 
 	// Get current branch (also detects detached HEAD)
-	cmd := exec.Command("git", "symbolic-ref", "--short", "HEAD")
+	cmd := gitCommand("symbolic-ref", "--short", "HEAD")
 	cmd.Dir = worktreePath
 	output, err := cmd.Output()
 	if err != nil {
 		// Check if it's detached HEAD vs not a git repo
-		cmd2 := exec.Command("git", "rev-parse", "--verify", "HEAD")
+		cmd2 := gitCommand("rev-parse", "--verify", "HEAD")
 		cmd2.Dir = worktreePath
 		if cmd2.Run() == nil {
 			result.Skipped = true
@@ -819,55 +1015,86 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 	}
 
 	// Fetch latest from remote
-	cmd = exec.Command("git", "fetch", remote, mainBranch)
+	cmd = gitCommand("fetch", remote, mainBranch)
 	cmd.Dir = worktreePath
 	if output, err := cmd.CombinedOutput(); err != nil {
 		result.Error = fmt.Errorf("failed to fetch from %s: %w\nOutput: %s", remote, err, output)
 		return result
 	}
 
+	rebaseWithStash(worktreePath, fmt.Sprintf("%s/%s", remote, mainBranch), keepConflicts, &result)
+	updateSubmodulesAfterRefresh(worktreePath, &result)
+	return result
+}
+
+// updateSubmodulesAfterRefresh follows submodule pointers the rebase moved,
+// so the worktree builds against what its new base records
+func updateSubmodulesAfterRefresh(worktreePath string, result *RefreshResult) {
+	if result.Error != nil || !HasSubmodules(worktreePath) {
+		return
+	}
+	if err := UpdateSubmodules(worktreePath); err != nil {
+		result.Error = err
+		return
+	}
+	result.SubmodulesUpdated = true
+}
+
+// rebaseWithStash rebases the worktree's branch onto upstream, stashing and
+// restoring uncommitted changes around the rebase. A conflicting rebase is
+// aborted so the worktree is left as it was, unless keepConflicts leaves it
+// paused at the conflict.
+func rebaseWithStash(worktreePath, upstream string, keepConflicts bool, result *RefreshResult) {
 	// Check for uncommitted changes
 	hasChanges, err := HasUncommittedChanges(worktreePath)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to check for uncommitted changes: %w", err)
-		return result
+		return
 	}
 
 	// Stash if there are uncommitted changes (including untracked files)
 	stashName := ""
 	if hasChanges {
-		stashName = fmt.Sprintf("refresh-stash-%d", os.Getpid())
-		cmd = exec.Command("git", "stash", "push", "--include-untracked", "-m", stashName)
+		stashName = fmt.Sprintf("%s%d", RefreshStashPrefix, os.Getpid())
+		cmd := gitCommand("stash", "push", "--include-untracked", "-m", stashName)
 		cmd.Dir = worktreePath
 		if output, err := cmd.CombinedOutput(); err != nil {
 			result.Error = fmt.Errorf("failed to stash changes: %w\nOutput: %s", err, output)
-			return result
+			return
 		}
 		result.WasStashed = true
 	}
 
 	// Get current commit count before rebase
-	cmd = exec.Command("git", "rev-list", "--count", fmt.Sprintf("%s/%s..HEAD", remote, mainBranch))
+	cmd := gitCommand("rev-list", "--count", upstream+"..HEAD")
 	cmd.Dir = worktreePath
 	countOutput, _ := cmd.Output()
 	commitsBefore := strings.TrimSpace(string(countOutput))
 
-	// Rebase onto main
-	cmd = exec.Command("git", "rebase", fmt.Sprintf("%s/%s", remote, mainBranch))
+	// Rebase onto upstream
+	cmd = gitCommand("rebase", upstream)
 	cmd.Dir = worktreePath
 	rebaseOutput, rebaseErr := cmd.CombinedOutput()
 
 	if rebaseErr != nil {
 		// Check if there are conflicts
-		cmd = exec.Command("git", "diff", "--name-only", "--diff-filter=U")
+		cmd = gitCommand("diff", "--name-only", "--diff-filter=U")
 		cmd.Dir = worktreePath
 		conflictOutput, _ := cmd.Output()
 		conflictFiles := strings.Split(strings.TrimSpace(string(conflictOutput)), "\n")
 		if len(conflictFiles) > 0 && conflictFiles[0] != "" {
 			result.HasConflicts = true
 			result.ConflictFiles = conflictFiles
+			result.Conflicts = SummarizeConflicts(worktreePath, conflictFiles)
+			if keepConflicts {
+				// The stash can't be popped mid-rebase; it stays until the
+				// rebase is finished or aborted
+				result.Paused = true
+				result.Error = fmt.Errorf("rebase paused at conflicts in: %s", strings.Join(conflictFiles, ", "))
+				return
+			}
 			// Abort the rebase to leave the worktree in a clean state
-			abortCmd := exec.Command("git", "rebase", "--abort")
+			abortCmd := gitCommand("rebase", "--abort")
 			abortCmd.Dir = worktreePath
 			abortCmd.Run()
 		}
@@ -875,16 +1102,16 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 
 		// Restore stash if we stashed
 		if result.WasStashed {
-			popCmd := exec.Command("git", "stash", "pop")
+			popCmd := gitCommand("stash", "pop")
 			popCmd.Dir = worktreePath
 			if popCmd.Run() == nil {
 				result.StashRestored = true
 			}
 		}
-		return result
+		return
 	}
 
-	// Calculate commits rebased (commits that were ahead of main)
+	// Calculate commits rebased (commits that were ahead of upstream)
 	// This is an approximation based on the output
 	if commitsBefore != "" && commitsBefore != "0" {
 		fmt.Sscanf(commitsBefore, "%d", &result.CommitsRebased)
@@ -892,7 +1119,7 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 
 	// Restore stash if we stashed
 	if result.WasStashed {
-		cmd = exec.Command("git", "stash", "pop")
+		cmd = gitCommand("stash", "pop")
 		cmd.Dir = worktreePath
 		if err := cmd.Run(); err != nil {
 			// Stash pop might fail if there are conflicts
@@ -902,7 +1129,6 @@ func RefreshWorktree(worktreePath string, remote string, mainBranch string) Refr
 		}
 	}
 
-	return result
 }
 
 // RefreshWorktreeWithDefaults refreshes a worktree using the repository's default remote and branch
@@ -1499,6 +1499,55 @@ func TestListBranchesWithPrefix(t *testing.T) {
 	})
 }
 
+func TestFindOrphanedAndPrunable(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	manager := NewManager(repoPath)
+	wtRootDir := t.TempDir()
+
+	kept := filepath.Join(wtRootDir, "kept")
+	gone := filepath.Join(wtRootDir, "gone")
+	for _, path := range []string{kept, gone} {
+		if err := manager.CreateNewBranch(path, filepath.Base(path), "main"); err != nil {
+			t.Fatalf("Failed to create worktree: %v", err)
+		}
+	}
+	orphan := filepath.Join(wtRootDir, "orphan")
+	if err := os.MkdirAll(orphan, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	// Deleting a worktree directory leaves git's metadata for prune
+	if err := os.RemoveAll(gone); err != nil {
+		t.Fatalf("Failed to remove worktree: %v", err)
+	}
+
+	orphaned, err := FindOrphaned(wtRootDir, manager)
+	if err != nil {
+		t.Fatalf("FindOrphaned failed: %v", err)
+	}
+	if len(orphaned) != 1 || orphaned[0] != orphan {
+		t.Errorf("FindOrphaned = %v, want [%s]", orphaned, orphan)
+	}
+	if _, err := os.Stat(orphan); err != nil {
+		t.Error("FindOrphaned must not remove anything")
+	}
+
+	prunable, err := manager.Prunable()
+	if err != nil {
+		t.Fatalf("Prunable failed: %v", err)
+	}
+	if len(prunable) != 1 || !strings.HasPrefix(prunable[0], "gone: ") {
+		t.Errorf("Prunable = %v, want the deleted worktree", prunable)
+	}
+	if err := manager.Prune(); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if prunable, _ := manager.Prunable(); len(prunable) != 0 {
+		t.Errorf("Prunable after Prune = %v", prunable)
+	}
+}
+
 func TestFindOrphanedBranches(t *testing.T) {
 	t.Run("finds branches without worktrees", func(t *testing.T) {
 		repoPath, cleanup := createTestRepo(t)
@@ -1733,7 +1782,7 @@ func TestCleanupMergedBranches(t *testing.T) {
 		cmd.Run()
 
 		// Clean up merged branches
-		deleted, err := manager.CleanupMergedBranches("work/", false)
+		deleted, err := manager.CleanupMergedBranches("work/", "")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -1777,7 +1826,7 @@ func TestCleanupMergedBranches(t *testing.T) {
 		cmd.Run()
 
 		// Clean up merged branches
-		deleted, err := manager.CleanupMergedBranches("work/", false)
+		deleted, err := manager.CleanupMergedBranches("work/", "")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -2915,7 +2964,7 @@ func TestCleanupMergedBranchesWithRemoteDeletion(t *testing.T) {
 		}
 
 		// Clean up merged branches with remote deletion
-		deleted, err := manager.CleanupMergedBranches("work/", true)
+		deleted, err := manager.CleanupMergedBranches("work/", "origin")
 		if err != nil {
 			t.Fatalf("CleanupMergedBranches failed: %v", err)
 		}
@@ -2939,3 +2988,77 @@ func TestCleanupMergedBranchesWithRemoteDeletion(t *testing.T) {
 		}
 	})
 }
+
+func TestForkWorkflow(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+	manager := NewManager(repoPath)
+
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	remotes := map[string]string{}
+	for _, name := range []string{"upstream", "origin"} {
+		dir := t.TempDir()
+		git(dir, "init", "--bare", "-q")
+		git(repoPath, "remote", "add", name, dir)
+		git(repoPath, "push", "-q", name, "main")
+		remotes[name] = dir
+	}
+	git(repoPath, "fetch", "-q", "upstream")
+
+	if url, err := manager.RemoteURL("origin"); err != nil || url != remotes["origin"] {
+		t.Errorf("RemoteURL(origin) = %q, %v", url, err)
+	}
+	if _, err := manager.RemoteURL("nope"); err == nil {
+		t.Error("RemoteURL of a missing remote should fail")
+	}
+
+	// Workers push to the fork, and can push again after rebasing
+	createBranch(t, repoPath, "work/forked")
+	if err := manager.PushBranch("origin", "work/forked"); err != nil {
+		t.Fatalf("PushBranch failed: %v", err)
+	}
+	git(repoPath, "checkout", "-q", "work/forked")
+	git(repoPath, "commit", "-q", "--allow-empty", "-m", "Rewritten")
+	git(repoPath, "commit", "-q", "--amend", "--allow-empty", "-m", "Rewritten again")
+	git(repoPath, "checkout", "-q", "main")
+	if err := manager.PushBranch("origin", "work/forked"); err != nil {
+		t.Fatalf("PushBranch after amending failed: %v", err)
+	}
+	if out := git(repoPath, "ls-remote", "--heads", "upstream", "work/forked"); out != "" {
+		t.Errorf("the branch should not be pushed upstream: %s", out)
+	}
+
+	if err := manager.SetPushDefault("origin"); err != nil {
+		t.Fatalf("SetPushDefault failed: %v", err)
+	}
+	if got := git(repoPath, "config", "remote.pushDefault"); got != "origin" {
+		t.Errorf("remote.pushDefault = %q", got)
+	}
+	for i := 0; i < 2; i++ {
+		if err := manager.SetPushDefault(""); err != nil {
+			t.Fatalf("unsetting remote.pushDefault failed: %v", err)
+		}
+	}
+
+	// Merged upstream, the branch is deleted locally and from the fork
+	git(repoPath, "push", "-q", "upstream", "work/forked:main")
+	deleted, err := manager.CleanupMergedBranches("work/", "origin")
+	if err != nil {
+		t.Fatalf("CleanupMergedBranches failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "work/forked" {
+		t.Errorf("deleted = %v, want work/forked", deleted)
+	}
+	if out := git(repoPath, "ls-remote", "--heads", "origin", "work/forked"); out != "" {
+		t.Errorf("the branch should be deleted from the fork: %s", out)
+	}
+}
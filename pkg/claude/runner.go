@@ -33,6 +33,13 @@
 //
 //	// Send a message to a running Claude instance
 //	err := runner.SendMessage(ctx, "my-session", "claude-window", "Hello, Claude!")
+//
+// # Headless Runs
+//
+//	// Run Claude on one prompt without a terminal, e.g. in CI
+//	cmd, err := runner.HeadlessCommand(ctx, claude.Config{WorkDir: "/path/to/workspace"}, "Update the CHANGELOG")
+//	cmd.Stdout = os.Stdout
+//	err = cmd.Run()
 package claude
 
 import (
@@ -40,6 +47,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"os/exec"
+	"strings"
 	"time"
 )
 
@@ -203,6 +211,17 @@ type Config struct {
 	// This is useful for showing restart instructions or other information.
 	// If empty, no MOTD is displayed.
 	MOTD string
+
+	// BinaryPath overrides the runner's BinaryPath for this instance.
+	BinaryPath string
+
+	// CommandPrefix is shell text placed before the binary, such as a
+	// wrapper command ("direnv exec . ") or a snippet ending in "&& ".
+	CommandPrefix string
+
+	// ExtraArgs are appended after the flags the runner passes.
+	// Each argument is shell-quoted.
+	ExtraArgs []string
 }
 
 // StartResult contains information about a started Claude instance.
@@ -301,7 +320,11 @@ func (r *Runner) buildCommand(sessionID string, cfg Config) string {
 	// Claude Code only reads credentials from ~/.claude/.credentials.json
 	// regardless of CLAUDE_CONFIG_DIR setting. Slash commands go in ~/.claude/commands/.
 
-	cmd += r.BinaryPath
+	binary := r.BinaryPath
+	if cfg.BinaryPath != "" {
+		binary = cfg.BinaryPath
+	}
+	cmd += cfg.CommandPrefix + binary
 
 	// Add session ID or resume
 	if cfg.Resume {
@@ -320,9 +343,60 @@ func (r *Runner) buildCommand(sessionID string, cfg Config) string {
 		cmd += fmt.Sprintf(" --append-system-prompt-file %s", cfg.SystemPromptFile)
 	}
 
+	for _, arg := range cfg.ExtraArgs {
+		cmd += " " + shellQuote(arg)
+	}
+
 	return cmd
 }
 
+// HeadlessCommand builds a command that runs Claude non-interactively in
+// print mode (-p) on prompt, without a terminal. Claude works on the prompt
+// until it is done and then exits; its output goes to the command's stdout.
+// MOTD, OutputFile, InitialMessage and CommandPrefix don't apply and are
+// ignored. The context bounds the run.
+func (r *Runner) HeadlessCommand(ctx context.Context, cfg Config, prompt string) (*exec.Cmd, error) {
+	sessionID := cfg.SessionID
+	if sessionID == "" {
+		var err error
+		sessionID, err = GenerateSessionID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate session ID: %w", err)
+		}
+	}
+
+	args := []string{"-p", prompt}
+	if cfg.Resume {
+		args = append(args, "--resume", sessionID)
+	} else {
+		args = append(args, "--session-id", sessionID)
+	}
+	if r.SkipPermissions {
+		args = append(args, "--dangerously-skip-permissions")
+	}
+	if cfg.SystemPromptFile != "" {
+		args = append(args, "--append-system-prompt-file", cfg.SystemPromptFile)
+	}
+	args = append(args, cfg.ExtraArgs...)
+
+	binary := r.BinaryPath
+	if cfg.BinaryPath != "" {
+		binary = cfg.BinaryPath
+	}
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Dir = cfg.WorkDir
+	return cmd, nil
+}
+
+// shellQuote single-quotes s unless it only contains characters that are
+// safe unquoted in a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=./:@,+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // SendMessage sends a message to a running Claude instance.
 // This properly handles multiline messages using paste-buffer and sends
 // text + Enter atomically to prevent race conditions.
@@ -584,6 +584,21 @@ func TestBuildCommandWithResume(t *testing.T) {
 	}
 }
 
+func TestBuildCommandWithLaunchOverrides(t *testing.T) {
+	runner := NewRunner(WithBinaryPath("claude"))
+
+	cmd := runner.buildCommand("sid", Config{
+		BinaryPath:    "/opt/claude/bin/claude",
+		CommandPrefix: "direnv exec . ",
+		ExtraArgs:     []string{"--model", "opus", "it's"},
+	})
+
+	want := `direnv exec . /opt/claude/bin/claude --session-id sid --dangerously-skip-permissions --model opus 'it'\''s'`
+	if cmd != want {
+		t.Errorf("buildCommand() = %q, want %q", cmd, want)
+	}
+}
+
 func TestResolveBinaryPath(t *testing.T) {
 	// This test is environment-dependent, so we just verify it doesn't panic
 	// and returns something
@@ -611,3 +626,33 @@ func TestIsBinaryAvailable(t *testing.T) {
 // were removed because CLAUDE_CONFIG_DIR is no longer used. Claude Code only reads
 // credentials from ~/.claude/.credentials.json regardless of CLAUDE_CONFIG_DIR,
 // and slash commands are now embedded directly in agent prompts.
+
+func TestHeadlessCommand(t *testing.T) {
+	runner := NewRunner(WithBinaryPath("claude"))
+
+	cmd, err := runner.HeadlessCommand(context.Background(), Config{
+		SessionID:        "sid",
+		WorkDir:          "/work",
+		SystemPromptFile: "/prompt.md",
+		BinaryPath:       "/opt/claude",
+		CommandPrefix:    "ignored ",
+		ExtraArgs:        []string{"--model", "opus"},
+	}, "update the CHANGELOG")
+	if err != nil {
+		t.Fatalf("HeadlessCommand() error = %v", err)
+	}
+
+	want := []string{"/opt/claude", "-p", "update the CHANGELOG", "--session-id", "sid", "--dangerously-skip-permissions",
+		"--append-system-prompt-file", "/prompt.md", "--model", "opus"}
+	if strings.Join(cmd.Args, "|") != strings.Join(want, "|") {
+		t.Errorf("Args = %q, want %q", cmd.Args, want)
+	}
+	if cmd.Dir != "/work" {
+		t.Errorf("Dir = %q, want /work", cmd.Dir)
+	}
+
+	cmd, _ = runner.HeadlessCommand(context.Background(), Config{}, "hi")
+	if len(cmd.Args) < 5 || cmd.Args[3] != "--session-id" || cmd.Args[4] == "" {
+		t.Errorf("Args = %q, want a generated session ID", cmd.Args)
+	}
+}
@@ -113,6 +113,17 @@ func (p *Paths) AgentLogFile(repoName, agentName string, isWorker bool) string {
 	return filepath.Join(p.RepoOutputDir(repoName), agentName+".log")
 }
 
+// HeadlessPIDFile returns the path of the PID file of a headless agent's
+// running Claude process
+func (p *Paths) HeadlessPIDFile(repoName, agentName string) string {
+	return filepath.Join(p.Root, "headless", repoName, agentName+".pid")
+}
+
+// SnapshotDir returns the path for the record of how an agent was spawned
+func (p *Paths) SnapshotDir(repoName, agentName string) string {
+	return filepath.Join(p.RepoOutputDir(repoName), "snapshots", agentName)
+}
+
 // AgentClaudeConfigDir returns the path for a specific agent's Claude config directory
 // This is used to set CLAUDE_CONFIG_DIR for per-agent slash commands
 func (p *Paths) AgentClaudeConfigDir(repoName, agentName string) string {
@@ -124,6 +135,82 @@ func (p *Paths) AgentCommandsDir(repoName, agentName string) string {
 	return filepath.Join(p.AgentClaudeConfigDir(repoName, agentName), "commands")
 }
 
+// MetricsDir returns the path for exported daily metrics snapshots
+func (p *Paths) MetricsDir() string {
+	return filepath.Join(p.Root, "metrics")
+}
+
+// FeedDir returns the path for per-repository orchestration action feeds
+func (p *Paths) FeedDir() string {
+	return filepath.Join(p.Root, "feed")
+}
+
+// AuditFile returns the path of the append-only log of state changes
+func (p *Paths) AuditFile() string {
+	return filepath.Join(p.Root, "audit.jsonl")
+}
+
+// TelemetryDir returns the path for opt-in local command timings
+func (p *Paths) TelemetryDir() string {
+	return filepath.Join(p.Root, "telemetry")
+}
+
+// MirrorsDir returns the path for shared object mirrors
+func (p *Paths) MirrorsDir() string {
+	return filepath.Join(p.Root, "mirrors")
+}
+
+// MirrorDir returns the path for a specific shared mirror
+func (p *Paths) MirrorDir(name string) string {
+	return filepath.Join(p.MirrorsDir(), name)
+}
+
+// WebhookDeadLetterFile returns the path of the JSON lines file webhook
+// events that could not be delivered are appended to
+func (p *Paths) WebhookDeadLetterFile() string {
+	return filepath.Join(p.Root, "webhook-dead-letter.jsonl")
+}
+
+// EventsFile returns the path of the JSON lines store of notification events
+// and their deliveries
+func (p *Paths) EventsFile() string {
+	return filepath.Join(p.OutputDir, "events.jsonl")
+}
+
+// ConfigFile returns the path of the config file the daemon reads settings
+// from at startup (see File)
+func (p *Paths) ConfigFile() string {
+	return filepath.Join(p.Root, "config.yaml")
+}
+
+// HostsFile returns the path of the list of other hosts' daemons that
+// fleet-wide commands, and the daemon's fleet_status, reach over SSH or TCP
+func (p *Paths) HostsFile() string {
+	return filepath.Join(p.Root, "hosts.json")
+}
+
+// WarmPoolDir returns the path for a repository's pre-created worktrees
+func (p *Paths) WarmPoolDir(repoName string) string {
+	return filepath.Join(p.Root, "warm", repoName)
+}
+
+// ScratchDir returns the path for a repository's scratch worktrees, the
+// extra worktrees agents create for spikes and bisects
+func (p *Paths) ScratchDir(repoName string) string {
+	return filepath.Join(p.Root, "scratch", repoName)
+}
+
+// ScratchWorktree returns the path of one of an agent's scratch worktrees
+func (p *Paths) ScratchWorktree(repoName, agentName, name string) string {
+	return filepath.Join(p.ScratchDir(repoName), agentName, name)
+}
+
+// MergeQueueWorktree returns the path of the worktree the daemon's merge
+// queue rebases PRs in
+func (p *Paths) MergeQueueWorktree(repoName string) string {
+	return filepath.Join(p.Root, "merge-queue", repoName)
+}
+
 // NewTestPaths creates a Paths instance for testing with all paths under tmpDir.
 // This eliminates duplicate test setup code and ensures consistent path configuration.
 func NewTestPaths(tmpDir string) *Paths {
@@ -48,6 +48,12 @@ func DirectoryDocs() []PathDoc {
 			Type:        "directory",
 			Notes:       "Full git clone of the tracked repository.",
 		},
+		{
+			Path:        "repos/<repo-name>/.git/multiclaude.lock",
+			Description: "Names the daemon that manages the clone's worktrees",
+			Type:        "file",
+			Notes:       "JSON with host, pid, root, and heartbeat. Other daemons sharing the clone treat it as read-only until the heartbeat is 10 minutes old.",
+		},
 		{
 			Path:        "wts/",
 			Description: "Git worktrees for isolated agent working directories",
@@ -90,6 +96,78 @@ func DirectoryDocs() []PathDoc {
 			Type:        "directory",
 			Notes:       "Created on-demand. Contains <agent-name>.md prompt files.",
 		},
+		{
+			Path:        "output/",
+			Description: "Captured agent output logs",
+			Type:        "directory",
+			Notes:       "tmux pipes each agent's pane into <repo-name>/<agent-name>.log (workers under <repo-name>/workers/). Logs over 10MB are rotated to <name>.log.<timestamp>, which stay here unless `multiclaude logs storage` moves them to S3 or GCS.",
+		},
+		{
+			Path:        "output/<repo-name>/snapshots/<agent-name>/",
+			Description: "Record of how an agent was spawned",
+			Type:        "directory",
+			Notes:       "snapshot.json (task, options, base commit, agent definition hash, model, launch template) and prompt.md (the exact prompt). Kept after the agent is removed so `multiclaude work rerun` can replay it.",
+		},
+		{
+			Path:        "output/events.jsonl",
+			Description: "Notification events and which adapters accepted them",
+			Type:        "file",
+			Notes:       "Appended by the daemon for every event, followed by a line per adapter that accepted it. Events older than MULTICLAUDE_EVENT_RETENTION (7 days by default) are dropped when the daemon starts and hourly after that. Events an adapter never accepted are resent to it when the daemon restarts. Query it with `multiclaude events list`.",
+		},
+		{
+			Path:        "config.yaml",
+			Description: "Optional settings for the daemon and new workers",
+			Type:        "file",
+			Notes:       "Written by the user and read when the daemon starts. Enables Slack, Telegram, and webhook notifications and the read-only HTTP API, and sets default branch prefixes and worker limits with per-repository overrides. Check it with `multiclaude config validate`.",
+		},
+		{
+			Path:        "hosts.json",
+			Description: "Other hosts' daemons in the fleet",
+			Type:        "file",
+			Notes:       "Written by `multiclaude hosts add|remove`. Each host has a name and an SSH target; `status --all-hosts` and `work --host` reach its daemon with `ssh <target> multiclaude daemon relay`. Absent unless hosts were added.",
+		},
+		{
+			Path:        "metrics/",
+			Description: "Daily per-repository metrics snapshots",
+			Type:        "directory",
+			Notes:       "Created on-demand. metrics.csv and metrics.jsonl are appended once per day by the daemon.\nmerge_queue.prom is rewritten every minute in the Prometheus text format for node_exporter's textfile collector.",
+		},
+		{
+			Path:        "feed/",
+			Description: "Per-repository feed of daemon actions",
+			Type:        "directory",
+			Notes:       "Created on-demand. <repo-name>.jsonl lists spawns, refreshes, cleanups, and merge queue outcomes in order; read it with `multiclaude agent feed`.",
+		},
+		{
+			Path:        "telemetry/",
+			Description: "Opt-in local command timings",
+			Type:        "directory",
+			Notes:       "Created by `multiclaude telemetry enable`. samples.jsonl holds command, socket, and step latencies (names and durations only) and is never uploaded.",
+		},
+		{
+			Path:        "mirrors/",
+			Description: "Bare mirrors shared between tracked repositories",
+			Type:        "directory",
+			Notes:       "Created by init --mirror. Repos cloned with --reference borrow objects from mirrors/<host>-<owner>-<repo>.git, so a mirror must not be deleted while repos use it.",
+		},
+		{
+			Path:        "warm/",
+			Description: "Pre-created worktrees waiting for new workers",
+			Type:        "directory",
+			Notes:       "Filled by the daemon when a repo sets --warm-pool. warm/<repo>/<id>/ is a bootstrapped worktree on a warm/<id> branch; spawning a worker moves it to wts/<repo>/<worker>/.",
+		},
+		{
+			Path:        "scratch/",
+			Description: "Extra worktrees agents create for spikes and bisects",
+			Type:        "directory",
+			Notes:       "Created by multiclaude agent scratch add. scratch/<repo>/<agent>/<name>/ is detached at a revision, or on a <branch>-<name> branch with --branch; removed with the agent.",
+		},
+		{
+			Path:        "webhook-dead-letter.jsonl",
+			Description: "Webhook events that could not be delivered",
+			Type:        "file",
+			Notes:       "Written only when MULTICLAUDE_WEBHOOK is set. One JSON object per line with the event, the URL, the number of attempts, and the last error.",
+		},
 	}
 }
 
@@ -105,11 +183,22 @@ func StateDocs() []StateFieldDoc {
 	return []StateFieldDoc{
 		// Top level
 		{Field: "repos", Type: "map[string]*Repository", Description: "Map of repository name to repository state"},
+		{Field: "socket_group", Type: "string", Description: "Unix group allowed to use the daemon socket (omitempty)"},
+		{Field: "log_storage", Type: "logstore.Config", Description: "Where rotated agent logs are kept: backend (local, s3, gcs), bucket, prefix, region, endpoint, retention_days (omitempty)"},
 
 		// Repository fields
 		{Field: "repos.<name>.github_url", Type: "string", Description: "GitHub URL of the repository"},
 		{Field: "repos.<name>.tmux_session", Type: "string", Description: "Name of the tmux session for this repo"},
 		{Field: "repos.<name>.agents", Type: "map[string]Agent", Description: "Map of agent name to agent state"},
+		{Field: "repos.<name>.clone_filter", Type: "string", Description: "Partial clone filter used at init, e.g. blob:none (omitempty)"},
+		{Field: "repos.<name>.mirror", Type: "string", Description: "Path of the shared mirror the clone borrows objects from (omitempty)"},
+		{Field: "repos.<name>.warm_pool", Type: "WarmPoolConfig", Description: "Warm worktree pool size and bootstrap command (omitempty)"},
+		{Field: "repos.<name>.window_reaper", Type: "WindowReaperConfig", Description: "Zombie window reaper mode (dry-run, enforce, off), grace period, and kept windows (omitempty)"},
+		{Field: "repos.<name>.recovery", Type: "RecoveryConfig", Description: "Whether interrupted rebases/merges in worker worktrees are recovered automatically, and after how many idle minutes (omitempty)"},
+		{Field: "repos.<name>.access", Type: "AccessPolicy", Description: "Users and @groups allowed to spawn, remove, merge, and administer; empty lists allow anyone (omitempty)"},
+		{Field: "repos.<name>.tmux_alerts", Type: "bool", Description: "Ring the bell in an agent's tmux window on events that need a human (omitempty)"},
+		{Field: "repos.<name>.default_base", Type: "string", Description: "Branch, tag, or commit new workers build on without --base (omitempty)"},
+		{Field: "repos.<name>.warm_worktrees", Type: "[]WarmWorktree", Description: "Bootstrapped worktrees ready to be assigned to new workers (omitempty)"},
 
 		// Agent fields
 		{Field: "repos.<name>.agents.<name>.type", Type: "string", Description: "Agent type: supervisor, worker, merge-queue, or workspace"},
@@ -121,6 +210,14 @@ func StateDocs() []StateFieldDoc {
 		{Field: "repos.<name>.agents.<name>.created_at", Type: "time.Time", Description: "When the agent was created"},
 		{Field: "repos.<name>.agents.<name>.last_nudge", Type: "time.Time", Description: "Last time agent was nudged (omitempty)"},
 		{Field: "repos.<name>.agents.<name>.ready_for_cleanup", Type: "bool", Description: "Whether worker is ready to be cleaned up (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.prompt_sha256", Type: "string", Description: "Hash of the prompt the agent was spawned with; see its spawn snapshot (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.refresh_conflict", Type: "RefreshConflict", Description: "Last rebase onto main that conflicted, its files, and the chosen resolution (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.base", Type: "string", Description: "Branch, tag, or commit the task builds on instead of the default branch (workers only, omitempty)"},
+		{Field: "repos.<name>.agents.<name>.base_branch", Type: "string", Description: "Remote branch of the base, refreshed onto and targeted by the PR; empty for tags and commits (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.model", Type: "string", Description: "Model set by the launch template at spawn (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.priority", Type: "string", Description: "Task priority P0-P3; empty means P2 (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.scratch", Type: "[]ScratchWorktree", Description: "Extra worktrees the agent created with multiclaude agent scratch, removed with the agent (omitempty)"},
+		{Field: "repos.<name>.agents.<name>.cwd_drift", Type: "string", Description: "Directory outside the worktree the agent's pane was last seen in; empty while it is inside (omitempty)"},
 	}
 }
 
@@ -0,0 +1,562 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dlorenc/multiclaude/pkg/events"
+	"github.com/dlorenc/multiclaude/pkg/mux"
+)
+
+// DefaultBranchPrefix is the prefix of the branches workers are created on
+// when the config file doesn't set one
+const DefaultBranchPrefix = "work/"
+
+// builtinBranchPrefixes are the prefixes multiclaude has always named its
+// branches with; cleanup keeps recognizing them whatever the config says
+var builtinBranchPrefixes = []string{"multiclaude/", DefaultBranchPrefix}
+
+// File is the config file, ~/.multiclaude/config.yaml. Every setting is
+// optional and a missing file is the same as an empty one. Settings made
+// with `multiclaude config <repo>` take precedence over the file, and the
+// MULTICLAUDE_* environment variables over its notification and event
+// settings.
+type File struct {
+	Notifications NotificationSettings    `yaml:"notifications"`
+	Events        EventSettings           `yaml:"events"`
+	API           APISettings             `yaml:"api"`
+	Remote        RemoteSettings          `yaml:"remote"`
+	GitHub        GitHubSettings          `yaml:"github"`
+	Worktrees     WorktreeSettings        `yaml:"worktrees"`
+	Tmux          TmuxSettings            `yaml:"tmux"`
+	Multiplexer   string                  `yaml:"multiplexer"` // mux backend agents run in; empty picks tmux if installed
+	Defaults      RepoSettings            `yaml:"defaults"`
+	Repos         map[string]RepoSettings `yaml:"repos"`
+}
+
+// NotificationSettings enables notification adapters
+type NotificationSettings struct {
+	Slack    *SlackSettings    `yaml:"slack"`
+	Telegram *TelegramSettings `yaml:"telegram"`
+	Webhook  *WebhookSettings  `yaml:"webhook"`
+	Email    *EmailSettings    `yaml:"email"`
+	Desktop  *DesktopSettings  `yaml:"desktop"`
+	Incident *IncidentSettings `yaml:"incident"`
+	// Routes limit the adapters they name to the events they match
+	Routes []RouteSettings `yaml:"routes"`
+}
+
+// SlackSettings posts events to a Slack incoming webhook
+type SlackSettings struct {
+	WebhookURL string   `yaml:"webhook_url"`
+	Events     []string `yaml:"events"` // Event types to post; empty posts all
+}
+
+// TelegramSettings sends events to a Telegram chat through a bot
+type TelegramSettings struct {
+	BotToken string   `yaml:"bot_token"`
+	ChatID   string   `yaml:"chat_id"`
+	Events   []string `yaml:"events"` // Event types to send; empty sends all
+}
+
+// WebhookSettings are the settings of MULTICLAUDE_WEBHOOK, with the secret
+// of MULTICLAUDE_WEBHOOK_SECRET
+type WebhookSettings struct {
+	URL        string            `yaml:"url"`
+	Secret     string            `yaml:"secret"`
+	Headers    map[string]string `yaml:"headers"`
+	Retries    *int              `yaml:"retries"`
+	Backoff    string            `yaml:"backoff"`
+	Timeout    string            `yaml:"timeout"`
+	DeadLetter string            `yaml:"dead_letter"`
+}
+
+// EmailSettings are the settings of MULTICLAUDE_EMAIL, with the password of
+// MULTICLAUDE_SMTP_PASSWORD
+type EmailSettings struct {
+	Host        string   `yaml:"host"`
+	Port        int      `yaml:"port"` // 587 when unset
+	User        string   `yaml:"user"` // Defaults to from when a password is set
+	Password    string   `yaml:"password"`
+	From        string   `yaml:"from"`
+	To          []string `yaml:"to"`
+	TLS         string   `yaml:"tls"`          // starttls (the default) or implicit
+	MinPriority string   `yaml:"min_priority"` // high when unset
+	Digest      string   `yaml:"digest"`       // e.g. 15m; empty mails each event
+}
+
+// DesktopSettings are the settings of MULTICLAUDE_DESKTOP
+type DesktopSettings struct {
+	Events []string `yaml:"events"` // Event types to show; empty shows agent.question and agent.error
+}
+
+// IncidentSettings are the settings of MULTICLAUDE_INCIDENTS, with the key
+// of MULTICLAUDE_INCIDENT_KEY
+type IncidentSettings struct {
+	Provider   string   `yaml:"provider"` // pagerduty or opsgenie
+	Key        string   `yaml:"key"`      // PagerDuty routing key or Opsgenie API key
+	Threshold  int      `yaml:"threshold"`
+	Window     string   `yaml:"window"`
+	AgentTypes []string `yaml:"agent_types"`
+	Severity   string   `yaml:"severity"`
+	URL        string   `yaml:"url"`
+	Timeout    string   `yaml:"timeout"`
+}
+
+// RouteSettings are one rule of MULTICLAUDE_ROUTES
+type RouteSettings struct {
+	Types       []string `yaml:"types"` // A trailing * matches a prefix, e.g. agent.*
+	Priorities  []string `yaml:"priorities"`
+	MinPriority string   `yaml:"min_priority"`
+	Repos       []string `yaml:"repos"`
+	AgentTypes  []string `yaml:"agent_types"`
+	To          []string `yaml:"to"` // Adapter names; required
+}
+
+// EventSettings configure the event store
+type EventSettings struct {
+	Retention string `yaml:"retention"` // e.g. 72h or 30d; empty keeps events a week
+}
+
+// APISettings enables the daemon's read-only HTTP API
+type APISettings struct {
+	Listen  string `yaml:"listen"`   // host:port; empty disables the API
+	Token   string `yaml:"token"`    // Bearer token clients must send
+	TLSCert string `yaml:"tls_cert"` // PEM certificate file; required unless listening on loopback
+	TLSKey  string `yaml:"tls_key"`  // PEM key file of the certificate
+}
+
+// RemoteSettings enable the daemon's TCP socket, which serves every socket
+// command to CLIs on other machines (multiclaude --host)
+type RemoteSettings struct {
+	Listen  string `yaml:"listen"`   // host:port; empty disables the TCP socket
+	Token   string `yaml:"token"`    // Token clients must send; required
+	TLSCert string `yaml:"tls_cert"` // PEM certificate file; required unless listening on loopback
+	TLSKey  string `yaml:"tls_key"`  // PEM key file of the certificate
+}
+
+// GitHubSettings limit the daemon's GitHub API use
+type GitHubSettings struct {
+	// Budgets caps the share (0-1] of the hourly rate limit each of the
+	// daemon's subsystems (merge-queue, ci-watch, pr-create,
+	// merge-queue-simulate) may spend
+	Budgets map[string]float64 `yaml:"budgets"`
+}
+
+// Actions the daemon takes when worktrees reach their disk quota
+const (
+	QuotaRefuse = "refuse" // Refuse new workers until usage drops
+	QuotaClean  = "clean"  // Clean up the oldest completed workers first
+)
+
+// DefaultRefreshInterval is how often workers' worktrees are rebased onto
+// their base when the config file doesn't say
+const DefaultRefreshInterval = 5 * time.Minute
+
+// MinRefreshInterval is the shortest refresh interval the config file accepts
+const MinRefreshInterval = time.Minute
+
+// RefreshOff turns automatic worktree refreshes off
+const RefreshOff = "off"
+
+// WorktreeSettings limit the disk space agents' worktrees may take in total
+// and set how often workers are rebased onto their base
+type WorktreeSettings struct {
+	Quota   string `yaml:"quota"`    // e.g. 50GB; empty for no quota
+	OnQuota string `yaml:"on_quota"` // QuotaRefuse (the default) or QuotaClean
+	Refresh string `yaml:"refresh"`  // e.g. 10m, or RefreshOff; empty for DefaultRefreshInterval
+}
+
+// RefreshInterval returns how often workers' worktrees are refreshed, or 0
+// if refreshing is off
+func (s WorktreeSettings) RefreshInterval() time.Duration {
+	if s.Refresh == RefreshOff {
+		return 0
+	}
+	if d, err := time.ParseDuration(s.Refresh); err == nil && d >= MinRefreshInterval {
+		return d
+	}
+	return DefaultRefreshInterval
+}
+
+// QuotaBytes returns the worktree disk quota in bytes, or 0 for none
+func (s WorktreeSettings) QuotaBytes() int64 {
+	n, _ := ParseByteSize(s.Quota)
+	return n
+}
+
+// RepoSettings are defaults for new workers, set for every repository under
+// defaults: and for one repository under repos.<name>:
+type RepoSettings struct {
+	BranchPrefix string `yaml:"branch_prefix"`
+	MaxWorkers   *int   `yaml:"max_workers"`
+}
+
+// LoadFile reads and validates the config file at path. A missing file
+// returns an empty File.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	f, err := ParseFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ParseFile parses and validates a config file's contents. Unknown keys are
+// errors, so typos don't silently leave settings unset.
+func ParseFile(data []byte) (*File, error) {
+	f := &File{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Validate checks the settings the config package understands itself.
+// Notification settings are checked again when their adapters are created.
+func (f *File) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if s := f.Notifications.Slack; s != nil {
+		if !isHTTPURL(s.WebhookURL) {
+			add("notifications.slack.webhook_url must be an http or https URL")
+		}
+		checkEventTypes("notifications.slack.events", s.Events, add)
+	}
+	if s := f.Notifications.Telegram; s != nil {
+		if s.BotToken == "" {
+			add("notifications.telegram.bot_token is required")
+		}
+		if s.ChatID == "" {
+			add("notifications.telegram.chat_id is required")
+		}
+		checkEventTypes("notifications.telegram.events", s.Events, add)
+	}
+	if s := f.Notifications.Webhook; s != nil {
+		if !isHTTPURL(s.URL) {
+			add("notifications.webhook.url must be an http or https URL")
+		}
+		if s.Secret == "" {
+			add("notifications.webhook.secret is required to sign webhook requests")
+		}
+		if s.Retries != nil && *s.Retries < 0 {
+			add("notifications.webhook.retries must not be negative")
+		}
+		for key, value := range map[string]string{"backoff": s.Backoff, "timeout": s.Timeout} {
+			if d, err := time.ParseDuration(value); value != "" && (err != nil || d <= 0) {
+				add("notifications.webhook.%s must be a positive duration like 2s", key)
+			}
+		}
+	}
+
+	if s := f.Notifications.Email; s != nil {
+		if s.Host == "" || s.From == "" || len(s.To) == 0 {
+			add("notifications.email needs host, from, and at least one to")
+		}
+		if s.Port < 0 || s.Port > 65535 {
+			add("notifications.email.port must be between 1 and 65535")
+		}
+		if s.TLS != "" && s.TLS != "starttls" && s.TLS != "implicit" {
+			add("notifications.email.tls must be starttls or implicit")
+		}
+		checkPriority("notifications.email.min_priority", s.MinPriority, add)
+		if d, err := time.ParseDuration(s.Digest); s.Digest != "" && (err != nil || d < 0) {
+			add("notifications.email.digest must be a duration like 15m")
+		}
+	}
+	if s := f.Notifications.Desktop; s != nil {
+		checkEventTypes("notifications.desktop.events", s.Events, add)
+	}
+	if s := f.Notifications.Incident; s != nil {
+		if s.Provider != "pagerduty" && s.Provider != "opsgenie" {
+			add("notifications.incident.provider must be pagerduty or opsgenie")
+		}
+		if s.Key == "" {
+			add("notifications.incident.key is required to open incidents")
+		}
+		if s.Threshold < 0 {
+			add("notifications.incident.threshold must not be negative")
+		}
+		switch s.Severity {
+		case "", "critical", "error", "warning", "info":
+		default:
+			add("notifications.incident.severity must be critical, error, warning, or info")
+		}
+		if s.URL != "" && !isHTTPURL(s.URL) {
+			add("notifications.incident.url must be an http or https URL")
+		}
+		for key, value := range map[string]string{"window": s.Window, "timeout": s.Timeout} {
+			if d, err := time.ParseDuration(value); value != "" && (err != nil || d <= 0) {
+				add("notifications.incident.%s must be a positive duration like 1h", key)
+			}
+		}
+	}
+	for i, r := range f.Notifications.Routes {
+		key := fmt.Sprintf("notifications.routes[%d]", i)
+		if len(r.To) == 0 {
+			add("%s.to must name at least one adapter", key)
+		}
+		for _, t := range r.Types {
+			if _, ok := events.LookupSchema(events.EventType(t)); !ok && !strings.HasSuffix(t, "*") {
+				add("%s.types lists unknown event type %q", key, t)
+			}
+		}
+		for _, p := range r.Priorities {
+			checkPriority(key+".priorities", p, add)
+		}
+		checkPriority(key+".min_priority", r.MinPriority, add)
+	}
+	if r := f.Events.Retention; r != "" {
+		if _, err := ParseRetention(r); err != nil {
+			add("events.retention must be a positive duration like 72h or 30d")
+		}
+	}
+
+	if f.API.Listen != "" {
+		host, _, err := net.SplitHostPort(f.API.Listen)
+		switch {
+		case err != nil:
+			add("api.listen must be host:port: %v", err)
+		case f.API.TLSCert == "" && !isLoopback(host):
+			// The bearer token would otherwise cross the network in the clear
+			add("api.tls_cert and api.tls_key are required when api.listen is not a loopback address")
+		}
+		if f.API.Token == "" {
+			// Even on loopback, every local user could otherwise read what
+			// the private daemon socket keeps from them
+			add("api.token is required when api.listen is set")
+		}
+	}
+	if (f.API.TLSCert == "") != (f.API.TLSKey == "") {
+		add("api.tls_cert and api.tls_key must be set together")
+	}
+
+	if f.Remote.Listen != "" {
+		host, _, err := net.SplitHostPort(f.Remote.Listen)
+		switch {
+		case err != nil:
+			add("remote.listen must be host:port: %v", err)
+		case f.Remote.TLSCert == "" && !isLoopback(host):
+			add("remote.tls_cert and remote.tls_key are required when remote.listen is not a loopback address")
+		}
+		if f.Remote.Token == "" {
+			add("remote.token is required when remote.listen is set")
+		}
+	}
+	if (f.Remote.TLSCert == "") != (f.Remote.TLSKey == "") {
+		add("remote.tls_cert and remote.tls_key must be set together")
+	}
+
+	for subsystem, share := range f.GitHub.Budgets {
+		if share <= 0 || share > 1 {
+			add("github.budgets.%s must be a share of the hourly rate limit between 0 and 1, like 0.25", subsystem)
+		}
+	}
+
+	if f.Worktrees.Quota != "" {
+		if n, err := ParseByteSize(f.Worktrees.Quota); err != nil || n <= 0 {
+			add("worktrees.quota must be a size like 50GB or 500MB")
+		}
+	}
+	switch f.Worktrees.OnQuota {
+	case "", QuotaRefuse, QuotaClean:
+	default:
+		add("worktrees.on_quota must be %s or %s", QuotaRefuse, QuotaClean)
+	}
+	if r := f.Worktrees.Refresh; r != "" && r != RefreshOff {
+		if d, err := time.ParseDuration(r); err != nil || d < MinRefreshInterval {
+			add("worktrees.refresh must be %s or a duration of at least %s, like 10m", RefreshOff, MinRefreshInterval)
+		}
+	}
+
+	if p := f.Tmux.SessionPrefix; p != "" && !validSessionPrefix(p) {
+		add("tmux.session_prefix %q must not start with - or contain dots, colons, spaces or slashes", p)
+	}
+	for _, p := range f.Tmux.PreviousSessionPrefixes {
+		if p == "" || !validSessionPrefix(p) {
+			add("tmux.previous_session_prefixes lists invalid prefix %q", p)
+		}
+	}
+
+	if !mux.ValidBackend(f.Multiplexer) {
+		add("multiplexer must be %s or %s", mux.BackendTmux, mux.BackendProcess)
+	}
+
+	checkRepoSettings("defaults", f.Defaults, add)
+	names := make([]string, 0, len(f.Repos))
+	for name := range f.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checkRepoSettings("repos."+name, f.Repos[name], add)
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// BranchPrefix returns the prefix of new workers' branches in a repository
+func (f *File) BranchPrefix(repoName string) string {
+	if prefix := f.Repos[repoName].BranchPrefix; prefix != "" {
+		return prefix
+	}
+	if f.Defaults.BranchPrefix != "" {
+		return f.Defaults.BranchPrefix
+	}
+	return DefaultBranchPrefix
+}
+
+// BranchPrefixes returns every prefix multiclaude names branches with in a
+// repository: the configured one and the built-in ones
+func (f *File) BranchPrefixes(repoName string) []string {
+	prefixes := append([]string(nil), builtinBranchPrefixes...)
+	if prefix := f.BranchPrefix(repoName); prefix != DefaultBranchPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// MaxWorkers returns how many workers a repository may run at once when it
+// doesn't set its own limit, or 0 for no limit
+func (f *File) MaxWorkers(repoName string) int {
+	if n := f.Repos[repoName].MaxWorkers; n != nil {
+		return *n
+	}
+	if f.Defaults.MaxWorkers != nil {
+		return *f.Defaults.MaxWorkers
+	}
+	return 0
+}
+
+// byteUnits are the suffixes ParseByteSize accepts, in powers of 1024
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size like "50GB", "1.5G", "512MiB" or "4096".
+// Units are powers of 1024 and case-insensitive. An empty string is 0.
+func ParseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	s = strings.Replace(s, "IB", "B", 1) // GiB is GB
+	unit := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+func checkRepoSettings(key string, s RepoSettings, add func(string, ...interface{})) {
+	if s.BranchPrefix != "" && !validBranchPrefix(s.BranchPrefix) {
+		add("%s.branch_prefix %q must be a branch name prefix ending in /, like work/", key, s.BranchPrefix)
+	}
+	if s.MaxWorkers != nil && *s.MaxWorkers < 0 {
+		add("%s.max_workers must not be negative (0 means no limit)", key)
+	}
+}
+
+// ParseRetention parses how long to keep events, as a Go duration or a
+// number of days: "72h" or "30d"
+func ParseRetention(s string) (time.Duration, error) {
+	var retention time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		retention = time.Duration(n) * 24 * time.Hour
+	} else {
+		retention, err = time.ParseDuration(s)
+	}
+	if err != nil || retention <= 0 {
+		return 0, fmt.Errorf("invalid retention %q: use a positive duration such as 72h or 30d", s)
+	}
+	return retention, nil
+}
+
+func checkPriority(key, priority string, add func(string, ...interface{})) {
+	switch events.Priority(priority) {
+	case "", events.PriorityLow, events.PriorityNormal, events.PriorityHigh:
+	default:
+		add("%s must be low, normal, or high", key)
+	}
+}
+
+func checkEventTypes(key string, types []string, add func(string, ...interface{})) {
+	for _, t := range types {
+		if _, ok := events.LookupSchema(events.EventType(t)); !ok {
+			add("%s lists unknown event type %q", key, t)
+		}
+	}
+}
+
+// validBranchPrefix accepts prefixes like "work/" or "alice/mc/" that give
+// valid branch names once an agent name is appended
+func validBranchPrefix(prefix string) bool {
+	if !strings.HasSuffix(prefix, "/") || strings.HasPrefix(prefix, "/") || strings.HasPrefix(prefix, "-") ||
+		strings.HasPrefix(prefix, "refs/") || strings.Contains(prefix, "//") || strings.Contains(prefix, "..") {
+		return false
+	}
+	for _, component := range strings.Split(strings.TrimSuffix(prefix, "/"), "/") {
+		if strings.HasPrefix(component, ".") || strings.HasSuffix(component, ".lock") {
+			return false
+		}
+	}
+	return !strings.ContainsAny(prefix, " ~^:?*[\\\t\n") && !strings.Contains(prefix, "@{")
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
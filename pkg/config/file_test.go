@@ -0,0 +1,253 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleConfigFile = `
+notifications:
+  slack:
+    webhook_url: https://hooks.slack.com/services/T000/B000/XXXX
+    events: [agent.question, agent.stuck]
+  telegram:
+    bot_token: "123:abc"
+    chat_id: "-1001"
+  webhook:
+    url: https://example.com/hook
+    secret: s3cret
+    retries: 2
+  email:
+    host: smtp.example.com
+    from: bot@example.com
+    to: [me@example.com]
+    digest: 15m
+  desktop:
+    events: [agent.question]
+  incident:
+    provider: pagerduty
+    key: k3y
+  routes:
+    - types: [agent.*]
+      min_priority: high
+      to: [email]
+events:
+  retention: 30d
+api:
+  listen: 127.0.0.1:7878
+  token: t0ken
+remote:
+  listen: 0.0.0.0:7879
+  token: t0ken
+  tls_cert: /etc/multiclaude/cert.pem
+  tls_key: /etc/multiclaude/key.pem
+github:
+  budgets:
+    ci-watch: 0.2
+worktrees:
+  quota: 20GB
+  on_quota: clean
+  refresh: 10m
+defaults:
+  branch_prefix: mc/
+  max_workers: 4
+repos:
+  payments:
+    branch_prefix: alice/pay/
+    max_workers: 0
+`
+
+func TestParseFile(t *testing.T) {
+	f, err := ParseFile([]byte(sampleConfigFile))
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if f.Notifications.Slack == nil || len(f.Notifications.Slack.Events) != 2 {
+		t.Errorf("slack settings = %+v, want two event types", f.Notifications.Slack)
+	}
+	if f.Notifications.Telegram == nil || f.Notifications.Telegram.ChatID != "-1001" {
+		t.Errorf("telegram settings = %+v", f.Notifications.Telegram)
+	}
+	if w := f.Notifications.Webhook; w == nil || w.Retries == nil || *w.Retries != 2 {
+		t.Errorf("webhook settings = %+v, want 2 retries", w)
+	}
+	if e := f.Notifications.Email; e == nil || len(e.To) != 1 || e.Digest != "15m" {
+		t.Errorf("email settings = %+v", e)
+	}
+	if f.Notifications.Incident == nil || f.Notifications.Incident.Key != "k3y" {
+		t.Errorf("incident settings = %+v", f.Notifications.Incident)
+	}
+	if r := f.Notifications.Routes; len(r) != 1 || r[0].To[0] != "email" || r[0].MinPriority != "high" {
+		t.Errorf("routes = %+v", r)
+	}
+	if f.Events.Retention != "30d" {
+		t.Errorf("Events.Retention = %q", f.Events.Retention)
+	}
+	if f.GitHub.Budgets["ci-watch"] != 0.2 {
+		t.Errorf("GitHub.Budgets = %v", f.GitHub.Budgets)
+	}
+	if f.Worktrees.QuotaBytes() != 20<<30 || f.Worktrees.OnQuota != QuotaClean {
+		t.Errorf("Worktrees = %+v", f.Worktrees)
+	}
+	if f.Worktrees.RefreshInterval() != 10*time.Minute {
+		t.Errorf("RefreshInterval = %s, want 10m", f.Worktrees.RefreshInterval())
+	}
+	if f.API.Listen != "127.0.0.1:7878" {
+		t.Errorf("API.Listen = %q", f.API.Listen)
+	}
+	if f.Remote.Listen != "0.0.0.0:7879" || f.Remote.TLSKey != "/etc/multiclaude/key.pem" {
+		t.Errorf("Remote = %+v", f.Remote)
+	}
+
+	tests := []struct {
+		repo       string
+		prefix     string
+		prefixes   []string
+		maxWorkers int
+	}{
+		{"payments", "alice/pay/", []string{"multiclaude/", "work/", "alice/pay/"}, 0},
+		{"web", "mc/", []string{"multiclaude/", "work/", "mc/"}, 4},
+	}
+	for _, tt := range tests {
+		if got := f.BranchPrefix(tt.repo); got != tt.prefix {
+			t.Errorf("BranchPrefix(%q) = %q, want %q", tt.repo, got, tt.prefix)
+		}
+		if got := f.BranchPrefixes(tt.repo); !reflect.DeepEqual(got, tt.prefixes) {
+			t.Errorf("BranchPrefixes(%q) = %v, want %v", tt.repo, got, tt.prefixes)
+		}
+		if got := f.MaxWorkers(tt.repo); got != tt.maxWorkers {
+			t.Errorf("MaxWorkers(%q) = %d, want %d", tt.repo, got, tt.maxWorkers)
+		}
+	}
+}
+
+func TestEmptyFileDefaults(t *testing.T) {
+	for _, data := range []string{"", "# nothing yet\n"} {
+		f, err := ParseFile([]byte(data))
+		if err != nil {
+			t.Fatalf("ParseFile(%q) error = %v", data, err)
+		}
+		if got := f.BranchPrefix("app"); got != DefaultBranchPrefix {
+			t.Errorf("BranchPrefix = %q, want %q", got, DefaultBranchPrefix)
+		}
+		if got := f.BranchPrefixes("app"); !reflect.DeepEqual(got, []string{"multiclaude/", "work/"}) {
+			t.Errorf("BranchPrefixes = %v", got)
+		}
+		if got := f.MaxWorkers("app"); got != 0 {
+			t.Errorf("MaxWorkers = %d, want 0", got)
+		}
+		if got := f.Worktrees.RefreshInterval(); got != DefaultRefreshInterval {
+			t.Errorf("RefreshInterval = %s, want %s", got, DefaultRefreshInterval)
+		}
+	}
+	off := WorktreeSettings{Refresh: RefreshOff}
+	if got := off.RefreshInterval(); got != 0 {
+		t.Errorf("RefreshInterval with refresh: off = %s, want 0", got)
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unknown key", "defaults:\n  max_worker: 3\n", "max_worker"},
+		{"bad yaml", "defaults: [\n", "yaml"},
+		{"slack url", "notifications:\n  slack:\n    webhook_url: hooks.slack.com\n", "notifications.slack.webhook_url"},
+		{"unknown event", "notifications:\n  slack:\n    webhook_url: https://x.test\n    events: [agent.sneezed]\n", `unknown event type "agent.sneezed"`},
+		{"telegram chat", "notifications:\n  telegram:\n    bot_token: t\n", "notifications.telegram.chat_id is required"},
+		{"webhook secret", "notifications:\n  webhook:\n    url: https://x.test\n", "notifications.webhook.secret"},
+		{"webhook backoff", "notifications:\n  webhook:\n    url: https://x.test\n    secret: s\n    backoff: soon\n", "notifications.webhook.backoff"},
+		{"email recipients", "notifications:\n  email:\n    host: smtp.example.com\n    from: bot@example.com\n", "notifications.email needs host, from, and at least one to"},
+		{"email tls", "notifications:\n  email:\n    host: h\n    from: f\n    to: [t]\n    tls: none\n", "notifications.email.tls"},
+		{"email priority", "notifications:\n  email:\n    host: h\n    from: f\n    to: [t]\n    min_priority: urgent\n", "notifications.email.min_priority"},
+		{"desktop event", "notifications:\n  desktop:\n    events: [agent.sneezed]\n", "notifications.desktop.events"},
+		{"incident key", "notifications:\n  incident:\n    provider: pagerduty\n", "notifications.incident.key is required"},
+		{"incident window", "notifications:\n  incident:\n    provider: opsgenie\n    key: k\n    window: soon\n", "notifications.incident.window"},
+		{"route adapter", "notifications:\n  routes:\n    - types: [agent.question]\n", "notifications.routes[0].to"},
+		{"route type", "notifications:\n  routes:\n    - types: [agent.sneezed]\n      to: [email]\n", "unknown event type"},
+		{"event retention", "events:\n  retention: forever\n", "events.retention"},
+		{"api address", "api:\n  listen: 7878\n", "api.listen must be host:port"},
+		{"api token", "api:\n  listen: 0.0.0.0:7878\n", "api.token is required"},
+		{"api token on loopback", "api:\n  listen: 127.0.0.1:7878\n", "api.token is required"},
+		{"api tls", "api:\n  listen: 0.0.0.0:7878\n  token: t\n", "api.tls_cert and api.tls_key are required"},
+		{"api tls pair", "api:\n  tls_key: key.pem\n", "must be set together"},
+		{"remote token", "remote:\n  listen: 127.0.0.1:7879\n", "remote.token is required"},
+		{"remote tls", "remote:\n  listen: 0.0.0.0:7879\n  token: t\n", "remote.tls_cert and remote.tls_key are required"},
+		{"remote tls pair", "remote:\n  tls_cert: cert.pem\n", "must be set together"},
+		{"branch prefix slash", "defaults:\n  branch_prefix: work\n", "defaults.branch_prefix"},
+		{"branch prefix chars", "repos:\n  app:\n    branch_prefix: \"a b/\"\n", "repos.app.branch_prefix"},
+		{"budget share", "github:\n  budgets:\n    merge-queue: 50\n", "github.budgets.merge-queue"},
+		{"quota size", "worktrees:\n  quota: lots\n", "worktrees.quota"},
+		{"refresh interval", "worktrees:\n  refresh: 10s\n", "worktrees.refresh"},
+		{"refresh never", "worktrees:\n  refresh: never\n", "worktrees.refresh"},
+		{"quota action", "worktrees:\n  quota: 1GB\n  on_quota: panic\n", "worktrees.on_quota"},
+		{"negative workers", "repos:\n  app:\n    max_workers: -1\n", "repos.app.max_workers must not be negative"},
+		{"session prefix chars", "tmux:\n  session_prefix: team.mc-\n", "tmux.session_prefix"},
+		{"previous session prefix", "tmux:\n  previous_session_prefixes: [\"a:b-\"]\n", "tmux.previous_session_prefixes"},
+		{"multiplexer", "multiplexer: screen\n", "multiplexer must be tmux or process"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFile([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseFile() error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"", 0},
+		{"4096", 4096},
+		{"512B", 512},
+		{"2k", 2048},
+		{"1.5G", 3 << 29},
+		{"50GB", 50 << 30},
+		{"512MiB", 512 << 20},
+		{"1 TB", 1 << 40},
+	}
+	for _, tt := range tests {
+		if got, err := ParseByteSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"GB", "-1G", "ten"} {
+		if _, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) should fail", in)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile(missing) error = %v", err)
+	}
+	if f.API.Listen != "" || f.Repos != nil {
+		t.Errorf("LoadFile(missing) = %+v, want empty", f)
+	}
+
+	if err := os.WriteFile(path, []byte("defaults:\n  max_workers: two\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("LoadFile(invalid) error = %v, want one naming the file", err)
+	}
+
+	if got := NewTestPaths(dir).ConfigFile(); got != path {
+		t.Errorf("ConfigFile() = %q, want %q", got, path)
+	}
+}
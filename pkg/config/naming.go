@@ -0,0 +1,120 @@
+package config
+
+import "strings"
+
+// DefaultSessionPrefix is prepended to a repository's name to name its tmux
+// session when the config file doesn't set tmux.session_prefix
+const DefaultSessionPrefix = "mc-"
+
+// sessionSanitizer replaces characters tmux mishandles in session names
+// (dots, colons, spaces and slashes) with hyphens
+var sessionSanitizer = strings.NewReplacer(
+	".", "-",
+	":", "-",
+	" ", "-",
+	"/", "-",
+)
+
+// TmuxSettings name the tmux sessions multiclaude creates
+type TmuxSettings struct {
+	// SessionPrefix is prepended to repository names; empty for
+	// DefaultSessionPrefix
+	SessionPrefix string `yaml:"session_prefix"`
+	// PreviousSessionPrefixes are prefixes sessions were named with
+	// before. The daemon renames those sessions to the current prefix.
+	PreviousSessionPrefixes []string `yaml:"previous_session_prefixes"`
+}
+
+// Namer names repositories' tmux sessions and recognizes the names earlier
+// prefixes gave them. The zero Namer uses DefaultSessionPrefix.
+type Namer struct {
+	prefix   string
+	previous []string
+}
+
+// NewNamer returns a Namer that names sessions with prefix (empty for
+// DefaultSessionPrefix) and recognizes sessions named with the previous
+// prefixes or DefaultSessionPrefix
+func NewNamer(prefix string, previous ...string) Namer {
+	return Namer{prefix: prefix, previous: previous}
+}
+
+// Namer returns the session Namer the file configures
+func (f *File) Namer() Namer {
+	return NewNamer(f.Tmux.SessionPrefix, f.Tmux.PreviousSessionPrefixes...)
+}
+
+// Prefix returns the prefix new sessions are named with
+func (n Namer) Prefix() string {
+	if n.prefix == "" {
+		return DefaultSessionPrefix
+	}
+	return n.prefix
+}
+
+// Session returns the tmux session name for a repository. Control
+// characters are dropped and characters tmux mishandles become hyphens.
+func (n Namer) Session(repoName string) string {
+	return n.Prefix() + sanitizeSessionPart(repoName)
+}
+
+// Prefixes returns the current prefix followed by every earlier one,
+// DefaultSessionPrefix included, without duplicates
+func (n Namer) Prefixes() []string {
+	prefixes := []string{n.Prefix()}
+	seen := map[string]bool{n.Prefix(): true}
+	for _, p := range append(append([]string(nil), n.previous...), DefaultSessionPrefix) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// OwnsSession reports whether a session name starts with any of the
+// namer's prefixes, i.e. whether multiclaude may have created it
+func (n Namer) OwnsSession(session string) bool {
+	for _, p := range n.Prefixes() {
+		if strings.HasPrefix(session, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormerSessions returns the names a repository's session had under the
+// earlier prefixes
+func (n Namer) FormerSessions(repoName string) []string {
+	var names []string
+	for _, p := range n.Prefixes()[1:] {
+		names = append(names, p+sanitizeSessionPart(repoName))
+	}
+	return names
+}
+
+// IsFormerSession reports whether session is what an earlier prefix named
+// the repository's session, so it should be renamed to Session(repoName)
+func (n Namer) IsFormerSession(repoName, session string) bool {
+	for _, name := range n.FormerSessions(repoName) {
+		if session == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeSessionPart(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, s)
+	return sessionSanitizer.Replace(s)
+}
+
+// validSessionPrefix accepts prefixes that tmux keeps as-is in session names
+func validSessionPrefix(prefix string) bool {
+	return sanitizeSessionPart(prefix) == prefix && !strings.HasPrefix(prefix, "-")
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamerSession(t *testing.T) {
+	tests := []struct {
+		repoName string
+		want     string
+	}{
+		{"my-repo", "mc-my-repo"},
+		{"demos.expanso.io", "mc-demos-expanso-io"},
+		{"repo.with.many.dots", "mc-repo-with-many-dots"},
+		{"repo:with:colons", "mc-repo-with-colons"},
+		{"repo with spaces", "mc-repo-with-spaces"},
+		{"simple", "mc-simple"},
+		{"repo/with/slashes", "mc-repo-with-slashes"},
+		{"path/to/repo.git", "mc-path-to-repo-git"},
+		{"repo\x00with\x1fnull", "mc-repowithnull"}, // control characters stripped
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repoName, func(t *testing.T) {
+			if got := (Namer{}).Session(tt.repoName); got != tt.want {
+				t.Errorf("Session(%q) = %q, want %q", tt.repoName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamerPrefixes(t *testing.T) {
+	f, err := ParseFile([]byte("tmux:\n  session_prefix: team-\n  previous_session_prefixes: [old-, team-]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := f.Namer()
+
+	if got := n.Session("api.v2"); got != "team-api-v2" {
+		t.Errorf("Session = %q, want team-api-v2", got)
+	}
+	if got := n.Prefixes(); !reflect.DeepEqual(got, []string{"team-", "old-", "mc-"}) {
+		t.Errorf("Prefixes = %v", got)
+	}
+	if got := n.FormerSessions("api"); !reflect.DeepEqual(got, []string{"old-api", "mc-api"}) {
+		t.Errorf("FormerSessions = %v", got)
+	}
+	for session, want := range map[string]bool{"mc-api": true, "old-api": true, "team-api": false, "mc-api-1234": false} {
+		if got := n.IsFormerSession("api", session); got != want {
+			t.Errorf("IsFormerSession(%q) = %v, want %v", session, got, want)
+		}
+	}
+	for session, want := range map[string]bool{"team-x": true, "mc-x": true, "old-x": true, "scratch": false} {
+		if got := n.OwnsSession(session); got != want {
+			t.Errorf("OwnsSession(%q) = %v, want %v", session, got, want)
+		}
+	}
+
+	if got := (Namer{}).FormerSessions("api"); got != nil {
+		t.Errorf("default Namer FormerSessions = %v, want none", got)
+	}
+}
@@ -0,0 +1,30 @@
+// Package events is the multiclaude notification event model: the [Event]
+// envelope the daemon sends to adapters and webhooks, the [EventType]s it
+// emits, and a typed [Payload] for each type.
+//
+// Webhook receivers and other tools outside the daemon decode events with
+// this package instead of re-declaring the structs. Unmarshaling an [Event]
+// turns its payload into the payload struct registered for its type:
+//
+//	var event events.Event
+//	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+//	    http.Error(w, err.Error(), http.StatusBadRequest)
+//	    return
+//	}
+//	switch p := event.Payload.(type) {
+//	case *events.AgentQuestionPayload:
+//	    log.Printf("%s asks: %s", event.Agent, p.Question)
+//	case *events.AgentCompletedPayload:
+//	    log.Printf("%s finished: %s", event.Agent, p.Summary)
+//	}
+//
+// # Compatibility
+//
+// Each event type has a schema version (see [LookupSchema]). Adding an
+// optional payload field keeps the version; removing a field or changing its
+// meaning bumps it. The JSON form of every version-1 event is pinned by this
+// package's tests, and [JSONSchema] describes them for non-Go consumers.
+//
+// Events from before payloads were typed carry their fields only in
+// Context; [DecodePayload] reads those too.
+package events
@@ -0,0 +1,155 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType identifies the kind of notification event
+type EventType string
+
+const (
+	// EventAgentStuck is emitted when an agent appears to be stuck
+	EventAgentStuck EventType = "agent.stuck"
+	// EventAgentCompleted is emitted when an agent signals completion
+	EventAgentCompleted EventType = "agent.completed"
+	// EventAgentError is emitted when an agent fails or crashes
+	EventAgentError EventType = "agent.error"
+	// EventAgentQuestion is emitted when an agent needs input from a human
+	EventAgentQuestion EventType = "agent.question"
+	// EventAgentTimeout is emitted when a time-boxed agent reaches its deadline
+	EventAgentTimeout EventType = "agent.timeout"
+	// EventPRCreated is emitted when the daemon opens a pull request for a
+	// completed worker's branch
+	EventPRCreated EventType = "agent.pr_created"
+	// EventPRMerged is emitted when the daemon's merge queue merges a PR
+	EventPRMerged EventType = "pr.merged"
+	// EventCIFailed is emitted when a required check fails on a PR in the
+	// daemon's merge queue
+	EventCIFailed EventType = "pr.ci_failed"
+	// EventBranchCIFailed is emitted when a check fails on the head of a
+	// worker's pushed branch
+	EventBranchCIFailed EventType = "ci.failed"
+	// EventBranchCIPassed is emitted when every check on the head of a
+	// worker's pushed branch has passed
+	EventBranchCIPassed EventType = "ci.passed"
+	// EventBranchPushed is emitted when someone else pushes to an agent's branch
+	EventBranchPushed EventType = "agent.branch_pushed"
+	// EventRefreshConflict is emitted when rebasing a worker onto main
+	// conflicts; a human picks how to resolve it
+	EventRefreshConflict EventType = "agent.refresh_conflict"
+	// EventMainRewritten is emitted when a repository's default branch is
+	// force-pushed and auto-refresh is paused
+	EventMainRewritten EventType = "repo.main_rewritten"
+	// EventMetricsDaily is emitted with each repository's daily metrics snapshot
+	EventMetricsDaily EventType = "metrics.daily"
+	// EventDaemonRestored is emitted once the daemon has restored tracked
+	// repositories after starting, with what it did to each
+	EventDaemonRestored EventType = "daemon.restored"
+)
+
+// Priority indicates how urgently an event should reach a human
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+)
+
+// Event is a single notification produced by the daemon. New events carry a
+// typed Payload (see schema.go); Context is kept for older consumers.
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      EventType              `json:"type"`
+	Version   int                    `json:"version,omitempty"` // Payload schema version
+	Priority  Priority               `json:"priority"`
+	Repo      string                 `json:"repo,omitempty"`
+	Agent     string                 `json:"agent,omitempty"`
+	AgentType string                 `json:"agent_type,omitempty"` // e.g. "worker", "review"
+	Title     string                 `json:"title"`
+	Message   string                 `json:"message,omitempty"`
+	Payload   Payload                `json:"payload,omitempty"`
+	Context   map[string]interface{} `json:"context,omitempty"`
+	Attach    *AttachTarget          `json:"attach,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// AttachTarget tells a human where the agent an event is about is running,
+// as commands they can paste to get there
+type AttachTarget struct {
+	Session string `json:"session"`
+	Window  string `json:"window"`
+	// Tmux attaches to the session and selects the agent's window
+	Tmux string `json:"tmux"`
+	// Command does the same through multiclaude
+	Command string `json:"command"`
+}
+
+// NewAttachTarget builds the attach commands for an agent's tmux window
+func NewAttachTarget(repo, agent, session, window string) *AttachTarget {
+	return &AttachTarget{
+		Session: session,
+		Window:  window,
+		Tmux:    fmt.Sprintf("tmux attach -t %s \\; select-window -t %s", shellQuote(session), shellQuote(session+":"+window)),
+		Command: fmt.Sprintf("multiclaude attach %s --repo %s", shellQuote(agent), shellQuote(repo)),
+	}
+}
+
+// shellQuote single-quotes s unless it is made only of characters the
+// shell leaves alone
+func shellQuote(s string) string {
+	safe := s != ""
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.:/@%+=", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Text renders the event for chat destinations: the title, the message, and
+// a copyable line that attaches to the agent
+func (e Event) Text() string {
+	var b strings.Builder
+	b.WriteString(e.Title)
+	if e.Message != "" {
+		b.WriteString("\n\n")
+		b.WriteString(e.Message)
+	}
+	if e.Attach != nil {
+		fmt.Fprintf(&b, "\n\nJump in: %s\nor: %s", e.Attach.Command, e.Attach.Tmux)
+	}
+	return b.String()
+}
+
+// ActionRequired reports whether the event waits on a human: a question,
+// a stuck agent, or a conflict or rewrite someone has to resolve
+func (e Event) ActionRequired() bool {
+	switch e.Type {
+	case EventAgentQuestion, EventAgentStuck, EventRefreshConflict, EventMainRewritten:
+		return true
+	}
+	return false
+}
+
+// NewEvent creates an event with a generated ID, timestamp, and normal priority
+func NewEvent(eventType EventType, repo, agent, title string) Event {
+	return Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Priority:  PriorityNormal,
+		Repo:      repo,
+		Agent:     agent,
+		Title:     title,
+		Context:   make(map[string]interface{}),
+		Timestamp: time.Now(),
+	}
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wireEvents are events as v1 consumers receive them. They must keep
+// decoding to the same payloads and encoding to the same JSON: a change
+// that breaks one of these breaks every webhook receiver, and needs a new
+// schema version instead.
+var wireEvents = map[EventType]string{
+	EventAgentStuck: `{"id":"e1","type":"agent.stuck","version":1,"priority":"high","repo":"r","agent":"w","title":"w is looping",` +
+		`"payload":{"reason":"output_loop","repeats":4,"block_lines":2,"snippet":"FAIL"},"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventAgentCompleted: `{"id":"e2","type":"agent.completed","version":1,"priority":"normal","repo":"r","agent":"w","title":"w completed",` +
+		`"payload":{"task":"Fix it","summary":"Fixed","branch":"work/w","pr_url":"https://github.com/o/r/pull/1"},"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventPRCreated: `{"id":"e11","type":"agent.pr_created","version":1,"priority":"normal","repo":"r","agent":"w","title":"w opened a PR",` +
+		`"payload":{"pr_url":"https://github.com/o/r/pull/1","pr_number":1,"title":"Fix it","branch":"work/w","base":"main","task":"Fix it"},` +
+		`"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventPRMerged: `{"id":"e12","type":"pr.merged","version":1,"priority":"normal","repo":"r","agent":"w","title":"merged #1",` +
+		`"payload":{"pr_url":"https://github.com/o/r/pull/1","pr_number":1,"branch":"work/w","base":"main","method":"squash","sha":"abc123"},` +
+		`"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventCIFailed: `{"id":"e13","type":"pr.ci_failed","version":1,"priority":"high","repo":"r","agent":"w","title":"CI failed on #1",` +
+		`"payload":{"pr_url":"https://github.com/o/r/pull/1","pr_number":1,"branch":"work/w","sha":"def456","failed":["test"]},` +
+		`"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventBranchCIFailed: `{"id":"e14","type":"ci.failed","version":1,"priority":"high","repo":"r","agent":"w","title":"CI failed on work/w",` +
+		`"payload":{"branch":"work/w","sha":"def456","pr_number":1,"failed":["test"],"pending":["lint"]},` +
+		`"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventBranchCIPassed: `{"id":"e15","type":"ci.passed","version":1,"priority":"low","repo":"r","agent":"w","title":"CI passed on work/w",` +
+		`"payload":{"branch":"work/w","sha":"def456","checks":["lint","test"]},` +
+		`"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventAgentError: `{"id":"e3","type":"agent.error","version":1,"priority":"high","repo":"r","agent":"w","title":"w crashed",` +
+		`"payload":{"error":"exit status 1"},"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventAgentQuestion: `{"id":"e4","type":"agent.question","version":1,"priority":"high","repo":"r","agent":"w","title":"w has a question",` +
+		`"payload":{"question":"Which database?","response_id":"abc","response_expires_at":"2026-05-02T12:00:00Z"},` +
+		`"attach":{"session":"mc-r","window":"w","tmux":"tmux attach -t mc-r \\; select-window -t mc-r:w","command":"multiclaude attach w --repo r"},` +
+		`"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventAgentTimeout: `{"id":"e5","type":"agent.timeout","version":1,"priority":"normal","repo":"r","agent":"w","title":"w timed out",` +
+		`"payload":{"task":"Fix it","deadline":"2026-05-01T12:00:00Z","budget_seconds":3600},"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventBranchPushed: `{"id":"e6","type":"agent.branch_pushed","version":1,"priority":"normal","repo":"r","agent":"w","title":"pushed",` +
+		`"payload":{"branch":"work/w","head":"abc123","commits":2,"authors":["alice"]},"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventRefreshConflict: `{"id":"e7","type":"agent.refresh_conflict","version":1,"priority":"high","repo":"r","agent":"w","title":"conflict",` +
+		`"payload":{"branch":"work/w","onto":"main","onto_head":"def456","files":[{"path":"a.go","hunks":1,"lines":[10]}],` +
+		`"actions":["assign","helper","skip"],"response_id":"def","response_expires_at":"2026-05-02T12:00:00Z"},"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventMainRewritten: `{"id":"e8","type":"repo.main_rewritten","version":1,"priority":"high","repo":"r","title":"main rewritten",` +
+		`"payload":{"branch":"main","old_head":"abc","new_head":"def"},"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventMetricsDaily: `{"id":"e9","type":"metrics.daily","version":1,"priority":"low","repo":"r","title":"daily metrics",` +
+		`"payload":{"snapshot":{"date":"2026-05-01","repo":"r","tasks_started":3,"tasks_completed":2,"tasks_failed":1,` +
+		`"prs_opened":2,"prs_merged":1,"mean_task_duration_seconds":1800}},"timestamp":"2026-05-01T12:00:00Z"}`,
+	EventDaemonRestored: `{"id":"e10","type":"daemon.restored","version":1,"priority":"normal","title":"restored 1 repository",` +
+		`"payload":{"started_at":"2026-05-01T12:00:00Z","finished_at":"2026-05-01T12:00:05Z","repos":[{"repo":"r","action":"session_recreated",` +
+		`"reason":"tmux session was missing","agents":[{"name":"w","type":"worker","outcome":"dropped"},` +
+		`{"name":"supervisor","type":"supervisor","outcome":"started"}]}]},"timestamp":"2026-05-01T12:00:00Z"}`,
+}
+
+func TestWireCompatibility(t *testing.T) {
+	for _, s := range Schemas() {
+		if _, ok := wireEvents[s.Type]; !ok {
+			t.Errorf("no wire sample for %s; add one so its format is pinned", s.Type)
+		}
+	}
+
+	for eventType, wire := range wireEvents {
+		t.Run(string(eventType), func(t *testing.T) {
+			var event Event
+			if err := json.Unmarshal([]byte(wire), &event); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if event.Type != eventType || event.Payload == nil || event.Payload.EventType() != eventType {
+				t.Fatalf("decoded %s with payload %#v", event.Type, event.Payload)
+			}
+			if err := event.Payload.Validate(); err != nil {
+				t.Errorf("sample payload is invalid: %v", err)
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			var got, want interface{}
+			json.Unmarshal(data, &got)
+			json.Unmarshal([]byte(wire), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip changed the event:\n got %s\nwant %s", data, wire)
+			}
+		})
+	}
+}
+
+func TestActionRequired(t *testing.T) {
+	for _, s := range Schemas() {
+		want := s.Type == EventAgentQuestion || s.Type == EventAgentStuck ||
+			s.Type == EventRefreshConflict || s.Type == EventMainRewritten
+		if got := (Event{Type: s.Type}).ActionRequired(); got != want {
+			t.Errorf("%s: ActionRequired() = %v, want %v", s.Type, got, want)
+		}
+	}
+}
+
+func TestAttachTargetText(t *testing.T) {
+	event := NewEvent(EventAgentQuestion, "my-repo", "worker-3", "worker-3 has a question")
+	event.Message = "May I add a dependency?"
+	event.Attach = NewAttachTarget("my-repo", "worker-3", "mc-my-repo", "worker-3")
+
+	if want := `tmux attach -t mc-my-repo \; select-window -t mc-my-repo:worker-3`; event.Attach.Tmux != want {
+		t.Errorf("Tmux = %q, want %q", event.Attach.Tmux, want)
+	}
+	if want := "multiclaude attach worker-3 --repo my-repo"; event.Attach.Command != want {
+		t.Errorf("Command = %q, want %q", event.Attach.Command, want)
+	}
+
+	text := event.Text()
+	for _, want := range []string{event.Title, event.Message, event.Attach.Command, event.Attach.Tmux} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() = %q, missing %q", text, want)
+		}
+	}
+
+	// Names the shell would split are quoted
+	odd := NewAttachTarget("my repo", "it's", "mc-my repo", "it's")
+	if want := `multiclaude attach 'it'\''s' --repo 'my repo'`; odd.Command != want {
+		t.Errorf("Command = %q, want %q", odd.Command, want)
+	}
+}
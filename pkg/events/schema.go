@@ -0,0 +1,673 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Payload is the typed body of an event. Each EventType has one payload struct,
+// registered with a schema version.
+type Payload interface {
+	// EventType returns the event type this payload belongs to
+	EventType() EventType
+	// Validate reports a missing or malformed field
+	Validate() error
+}
+
+// AgentStuckPayload is the payload of agent.stuck events
+type AgentStuckPayload struct {
+	Reason     string `json:"reason"` // "output_loop" or "idle"
+	Repeats    int    `json:"repeats,omitempty"`
+	BlockLines int    `json:"block_lines,omitempty"`
+	Snippet    string `json:"snippet,omitempty"`
+	// IdleSeconds is how long an idle agent has produced no output or heartbeat
+	IdleSeconds int `json:"idle_seconds,omitempty"`
+}
+
+// EventType implements Payload
+func (AgentStuckPayload) EventType() EventType { return EventAgentStuck }
+
+// Validate implements Payload
+func (p AgentStuckPayload) Validate() error {
+	if p.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	return nil
+}
+
+// AgentCompletedPayload is the payload of agent.completed events
+type AgentCompletedPayload struct {
+	Task          string `json:"task,omitempty"`
+	Summary       string `json:"summary,omitempty"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	Branch        string `json:"branch,omitempty"`
+	PRURL         string `json:"pr_url,omitempty"`
+}
+
+// EventType implements Payload
+func (AgentCompletedPayload) EventType() EventType { return EventAgentCompleted }
+
+// Validate implements Payload
+func (AgentCompletedPayload) Validate() error { return nil }
+
+// PRCreatedPayload is the payload of agent.pr_created events
+type PRCreatedPayload struct {
+	PRURL    string `json:"pr_url"`
+	PRNumber int    `json:"pr_number,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Base     string `json:"base,omitempty"`
+	Task     string `json:"task,omitempty"`
+}
+
+// EventType implements Payload
+func (PRCreatedPayload) EventType() EventType { return EventPRCreated }
+
+// Validate implements Payload
+func (p PRCreatedPayload) Validate() error {
+	if p.PRURL == "" {
+		return fmt.Errorf("pr_url is required")
+	}
+	return nil
+}
+
+// PRMergedPayload is the payload of pr.merged events
+type PRMergedPayload struct {
+	PRURL    string `json:"pr_url,omitempty"`
+	PRNumber int    `json:"pr_number"`
+	Branch   string `json:"branch,omitempty"`
+	Base     string `json:"base,omitempty"`
+	Method   string `json:"method,omitempty"`
+	SHA      string `json:"sha,omitempty"` // Merge commit
+}
+
+// EventType implements Payload
+func (PRMergedPayload) EventType() EventType { return EventPRMerged }
+
+// Validate implements Payload
+func (p PRMergedPayload) Validate() error {
+	if p.PRNumber <= 0 {
+		return fmt.Errorf("pr_number is required")
+	}
+	return nil
+}
+
+// CIFailedPayload is the payload of pr.ci_failed events
+type CIFailedPayload struct {
+	PRURL    string   `json:"pr_url,omitempty"`
+	PRNumber int      `json:"pr_number"`
+	Branch   string   `json:"branch,omitempty"`
+	SHA      string   `json:"sha,omitempty"` // Head commit the checks ran on
+	Failed   []string `json:"failed"`
+}
+
+// EventType implements Payload
+func (CIFailedPayload) EventType() EventType { return EventCIFailed }
+
+// Validate implements Payload
+func (p CIFailedPayload) Validate() error {
+	if p.PRNumber <= 0 {
+		return fmt.Errorf("pr_number is required")
+	}
+	if len(p.Failed) == 0 {
+		return fmt.Errorf("failed is required")
+	}
+	return nil
+}
+
+// BranchCIFailedPayload is the payload of ci.failed events
+type BranchCIFailedPayload struct {
+	Branch   string   `json:"branch"`
+	SHA      string   `json:"sha"` // Branch head the checks ran on
+	PRNumber int      `json:"pr_number,omitempty"`
+	Failed   []string `json:"failed"`
+	Pending  []string `json:"pending,omitempty"`
+}
+
+// EventType implements Payload
+func (BranchCIFailedPayload) EventType() EventType { return EventBranchCIFailed }
+
+// Validate implements Payload
+func (p BranchCIFailedPayload) Validate() error {
+	if p.Branch == "" || p.SHA == "" {
+		return fmt.Errorf("branch and sha are required")
+	}
+	if len(p.Failed) == 0 {
+		return fmt.Errorf("failed is required")
+	}
+	return nil
+}
+
+// BranchCIPassedPayload is the payload of ci.passed events
+type BranchCIPassedPayload struct {
+	Branch   string   `json:"branch"`
+	SHA      string   `json:"sha"`
+	PRNumber int      `json:"pr_number,omitempty"`
+	Checks   []string `json:"checks,omitempty"`
+}
+
+// EventType implements Payload
+func (BranchCIPassedPayload) EventType() EventType { return EventBranchCIPassed }
+
+// Validate implements Payload
+func (p BranchCIPassedPayload) Validate() error {
+	if p.Branch == "" || p.SHA == "" {
+		return fmt.Errorf("branch and sha are required")
+	}
+	return nil
+}
+
+// AgentErrorPayload is the payload of agent.error events
+type AgentErrorPayload struct {
+	Error string `json:"error"`
+}
+
+// EventType implements Payload
+func (AgentErrorPayload) EventType() EventType { return EventAgentError }
+
+// Validate implements Payload
+func (p AgentErrorPayload) Validate() error {
+	if p.Error == "" {
+		return fmt.Errorf("error is required")
+	}
+	return nil
+}
+
+// AgentQuestionPayload is the payload of agent.question events
+type AgentQuestionPayload struct {
+	Question string `json:"question"`
+	// ResponseID authorizes one reply via respond_agent until ResponseExpiresAt
+	ResponseID        string    `json:"response_id,omitempty"`
+	ResponseExpiresAt time.Time `json:"response_expires_at,omitempty"`
+}
+
+// EventType implements Payload
+func (AgentQuestionPayload) EventType() EventType { return EventAgentQuestion }
+
+// Validate implements Payload
+func (p AgentQuestionPayload) Validate() error {
+	if p.Question == "" {
+		return fmt.Errorf("question is required")
+	}
+	return nil
+}
+
+// AgentTimeoutPayload is the payload of agent.timeout events
+type AgentTimeoutPayload struct {
+	Task          string    `json:"task,omitempty"`
+	Deadline      time.Time `json:"deadline"`
+	BudgetSeconds float64   `json:"budget_seconds"`
+}
+
+// EventType implements Payload
+func (AgentTimeoutPayload) EventType() EventType { return EventAgentTimeout }
+
+// Validate implements Payload
+func (p AgentTimeoutPayload) Validate() error {
+	if p.Deadline.IsZero() {
+		return fmt.Errorf("deadline is required")
+	}
+	return nil
+}
+
+// BranchPushedPayload is the payload of agent.branch_pushed events
+type BranchPushedPayload struct {
+	Branch  string   `json:"branch"`
+	Head    string   `json:"head"`
+	Commits int      `json:"commits"`
+	Authors []string `json:"authors,omitempty"`
+}
+
+// EventType implements Payload
+func (BranchPushedPayload) EventType() EventType { return EventBranchPushed }
+
+// Validate implements Payload
+func (p BranchPushedPayload) Validate() error {
+	if p.Branch == "" || p.Head == "" {
+		return fmt.Errorf("branch and head are required")
+	}
+	return nil
+}
+
+// ConflictedFile summarizes the conflict hunks in one file
+type ConflictedFile struct {
+	Path  string `json:"path"`
+	Hunks int    `json:"hunks"`
+	Lines []int  `json:"lines,omitempty"` // Where each hunk starts
+}
+
+// RefreshConflictPayload is the payload of agent.refresh_conflict events.
+// Actions are the choices to offer (e.g. as buttons); the chosen one is sent
+// back with resolve_conflict and ResponseID.
+type RefreshConflictPayload struct {
+	Branch            string           `json:"branch"`
+	Onto              string           `json:"onto"`
+	OntoHead          string           `json:"onto_head"`
+	Files             []ConflictedFile `json:"files"`
+	Actions           []string         `json:"actions"`
+	ResponseID        string           `json:"response_id,omitempty"`
+	ResponseExpiresAt time.Time        `json:"response_expires_at,omitempty"`
+}
+
+// EventType implements Payload
+func (RefreshConflictPayload) EventType() EventType { return EventRefreshConflict }
+
+// Validate implements Payload
+func (p RefreshConflictPayload) Validate() error {
+	if p.Branch == "" || p.Onto == "" || len(p.Files) == 0 {
+		return fmt.Errorf("branch, onto, and files are required")
+	}
+	if len(p.Actions) == 0 {
+		return fmt.Errorf("actions are required")
+	}
+	return nil
+}
+
+// MainRewrittenPayload is the payload of repo.main_rewritten events
+type MainRewrittenPayload struct {
+	Branch  string `json:"branch"`
+	OldHead string `json:"old_head"`
+	NewHead string `json:"new_head"`
+}
+
+// EventType implements Payload
+func (MainRewrittenPayload) EventType() EventType { return EventMainRewritten }
+
+// Validate implements Payload
+func (p MainRewrittenPayload) Validate() error {
+	if p.Branch == "" || p.OldHead == "" || p.NewHead == "" {
+		return fmt.Errorf("branch, old_head, and new_head are required")
+	}
+	return nil
+}
+
+// MetricsSnapshot is one repository's metrics for one day
+type MetricsSnapshot struct {
+	Date                string  `json:"date"`
+	Repo                string  `json:"repo"`
+	TasksStarted        int     `json:"tasks_started"`
+	TasksCompleted      int     `json:"tasks_completed"`
+	TasksFailed         int     `json:"tasks_failed"`
+	PRsOpened           int     `json:"prs_opened"`
+	PRsMerged           int     `json:"prs_merged"`
+	MeanDurationSeconds float64 `json:"mean_task_duration_seconds"`
+}
+
+// MetricsDailyPayload is the payload of metrics.daily events
+type MetricsDailyPayload struct {
+	Snapshot MetricsSnapshot `json:"snapshot"`
+}
+
+// EventType implements Payload
+func (MetricsDailyPayload) EventType() EventType { return EventMetricsDaily }
+
+// Validate implements Payload
+func (p MetricsDailyPayload) Validate() error {
+	if p.Snapshot.Date == "" || p.Snapshot.Repo == "" {
+		return fmt.Errorf("snapshot date and repo are required")
+	}
+	return nil
+}
+
+// RestoredAgent is what startup restoration did with one agent
+type RestoredAgent struct {
+	Name    string `json:"name"`
+	Type    string `json:"type,omitempty"`
+	Outcome string `json:"outcome"` // e.g. "alive", "restarted", "dropped"
+	Error   string `json:"error,omitempty"`
+}
+
+// RestoredRepo is what startup restoration did with one repository
+type RestoredRepo struct {
+	Repo   string          `json:"repo"`
+	Action string          `json:"action"` // e.g. "session_kept", "session_recreated", "skipped"
+	Reason string          `json:"reason,omitempty"`
+	Agents []RestoredAgent `json:"agents,omitempty"`
+}
+
+// DaemonRestoredPayload is the payload of daemon.restored events
+type DaemonRestoredPayload struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Repos      []RestoredRepo `json:"repos"`
+}
+
+// EventType implements Payload
+func (DaemonRestoredPayload) EventType() EventType { return EventDaemonRestored }
+
+// Validate implements Payload
+func (p DaemonRestoredPayload) Validate() error {
+	if p.StartedAt.IsZero() {
+		return fmt.Errorf("started_at is required")
+	}
+	return nil
+}
+
+// Schema describes the payload of one event type. Version is bumped whenever
+// a payload field is removed or changes meaning; adding optional fields is
+// backward compatible and keeps the version.
+type Schema struct {
+	Type        EventType
+	Version     int
+	Description string
+	newPayload  func() Payload
+}
+
+// schemas is the registry of known event types
+var schemas = map[EventType]Schema{
+	EventAgentStuck: {
+		Type: EventAgentStuck, Version: 1,
+		Description: "An agent appears to be stuck",
+		newPayload:  func() Payload { return &AgentStuckPayload{} },
+	},
+	EventAgentCompleted: {
+		Type: EventAgentCompleted, Version: 1,
+		Description: "An agent signaled completion",
+		newPayload:  func() Payload { return &AgentCompletedPayload{} },
+	},
+	EventPRCreated: {
+		Type: EventPRCreated, Version: 1,
+		Description: "A pull request was opened for a completed worker's branch",
+		newPayload:  func() Payload { return &PRCreatedPayload{} },
+	},
+	EventPRMerged: {
+		Type: EventPRMerged, Version: 1,
+		Description: "The daemon's merge queue merged a PR",
+		newPayload:  func() Payload { return &PRMergedPayload{} },
+	},
+	EventCIFailed: {
+		Type: EventCIFailed, Version: 1,
+		Description: "A required check failed on a PR in the daemon's merge queue",
+		newPayload:  func() Payload { return &CIFailedPayload{} },
+	},
+	EventBranchCIFailed: {
+		Type: EventBranchCIFailed, Version: 1,
+		Description: "A check failed on a worker's pushed branch",
+		newPayload:  func() Payload { return &BranchCIFailedPayload{} },
+	},
+	EventBranchCIPassed: {
+		Type: EventBranchCIPassed, Version: 1,
+		Description: "Every check passed on a worker's pushed branch",
+		newPayload:  func() Payload { return &BranchCIPassedPayload{} },
+	},
+	EventAgentError: {
+		Type: EventAgentError, Version: 1,
+		Description: "An agent failed or crashed",
+		newPayload:  func() Payload { return &AgentErrorPayload{} },
+	},
+	EventAgentQuestion: {
+		Type: EventAgentQuestion, Version: 1,
+		Description: "An agent needs input from a human",
+		newPayload:  func() Payload { return &AgentQuestionPayload{} },
+	},
+	EventAgentTimeout: {
+		Type: EventAgentTimeout, Version: 1,
+		Description: "A time-boxed agent reached its deadline",
+		newPayload:  func() Payload { return &AgentTimeoutPayload{} },
+	},
+	EventBranchPushed: {
+		Type: EventBranchPushed, Version: 1,
+		Description: "Someone else pushed commits to an agent's branch",
+		newPayload:  func() Payload { return &BranchPushedPayload{} },
+	},
+	EventRefreshConflict: {
+		Type: EventRefreshConflict, Version: 1,
+		Description: "Rebasing a worker onto main conflicted; a human picks assign, helper, or skip",
+		newPayload:  func() Payload { return &RefreshConflictPayload{} },
+	},
+	EventMainRewritten: {
+		Type: EventMainRewritten, Version: 1,
+		Description: "A repository's default branch was force-pushed; auto-refresh is paused",
+		newPayload:  func() Payload { return &MainRewrittenPayload{} },
+	},
+	EventMetricsDaily: {
+		Type: EventMetricsDaily, Version: 1,
+		Description: "A repository's daily metrics snapshot",
+		newPayload:  func() Payload { return &MetricsDailyPayload{} },
+	},
+	EventDaemonRestored: {
+		Type: EventDaemonRestored, Version: 1,
+		Description: "The daemon restored tracked repositories after starting",
+		newPayload:  func() Payload { return &DaemonRestoredPayload{} },
+	},
+}
+
+// LookupSchema returns the registered schema for an event type
+func LookupSchema(eventType EventType) (Schema, bool) {
+	s, ok := schemas[eventType]
+	return s, ok
+}
+
+// Schemas returns every registered schema, sorted by event type
+func Schemas() []Schema {
+	result := make([]Schema, 0, len(schemas))
+	for _, s := range schemas {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// NewTypedEvent creates an event whose type and version come from the payload.
+// The payload is also flattened into Context so adapters written against the
+// untyped map keep working.
+func NewTypedEvent(repo, agent, title string, payload Payload) Event {
+	event := NewEvent(payload.EventType(), repo, agent, title)
+	event.Payload = payload
+	if s, ok := LookupSchema(payload.EventType()); ok {
+		event.Version = s.Version
+	}
+	event.Context = legacyContext(payload, event.Context)
+	return event
+}
+
+// legacyContext merges the payload's JSON fields into ctx without overwriting
+// keys the caller already set
+func legacyContext(payload Payload, ctx map[string]interface{}) map[string]interface{} {
+	if ctx == nil {
+		ctx = make(map[string]interface{})
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return ctx
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ctx
+	}
+	for k, v := range fields {
+		if _, exists := ctx[k]; !exists {
+			ctx[k] = v
+		}
+	}
+	return ctx
+}
+
+// Validate checks a typed event against the registry and fills in its
+// version and legacy context. Events without a payload are passed through
+// unchanged for compatibility.
+func (e *Event) Validate() error {
+	if e.Payload == nil {
+		return nil
+	}
+	s, ok := LookupSchema(e.Type)
+	if !ok {
+		return fmt.Errorf("no schema registered for event type %q", e.Type)
+	}
+	if e.Payload.EventType() != e.Type {
+		return fmt.Errorf("payload for %s attached to %s event", e.Payload.EventType(), e.Type)
+	}
+	if err := e.Payload.Validate(); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", e.Type, err)
+	}
+	if e.Version == 0 {
+		e.Version = s.Version
+	} else if e.Version != s.Version {
+		return fmt.Errorf("%s payload version %d does not match schema version %d", e.Type, e.Version, s.Version)
+	}
+	e.Context = legacyContext(e.Payload, e.Context)
+	return nil
+}
+
+// DecodePayload returns the event's typed payload. For events built the old
+// way it decodes the payload from Context instead.
+func DecodePayload(event Event) (Payload, error) {
+	if event.Payload != nil {
+		return event.Payload, nil
+	}
+	s, ok := LookupSchema(event.Type)
+	if !ok {
+		return nil, fmt.Errorf("no schema registered for event type %q", event.Type)
+	}
+	payload := s.newPayload()
+	data, err := json.Marshal(event.Context)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, payload); err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload from context: %w", event.Type, err)
+	}
+	return payload, nil
+}
+
+// UnmarshalJSON decodes an event, turning its payload into the registered
+// payload type for the event type
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type plain Event
+	var raw struct {
+		plain
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = Event(raw.plain)
+	e.Payload = nil
+
+	if len(raw.Payload) == 0 || string(raw.Payload) == "null" {
+		return nil
+	}
+	s, ok := LookupSchema(e.Type)
+	if !ok {
+		return fmt.Errorf("no schema registered for event type %q", e.Type)
+	}
+	payload := s.newPayload()
+	if err := json.Unmarshal(raw.Payload, payload); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", e.Type, err)
+	}
+	e.Payload = payload
+	return nil
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) document describing every
+// registered event type and its payload
+func JSONSchema() map[string]interface{} {
+	defs := make(map[string]interface{})
+	var oneOf []interface{}
+	for _, s := range Schemas() {
+		name := string(s.Type)
+		payloadType := reflect.TypeOf(s.newPayload()).Elem()
+		defs[name] = map[string]interface{}{
+			"description": s.Description,
+			"type":        "object",
+			"properties": map[string]interface{}{
+				"id":       map[string]interface{}{"type": "string"},
+				"type":     map[string]interface{}{"const": name},
+				"version":  map[string]interface{}{"const": s.Version},
+				"priority": map[string]interface{}{"enum": []string{string(PriorityLow), string(PriorityNormal), string(PriorityHigh)}},
+				"repo":     map[string]interface{}{"type": "string"},
+				"agent":    map[string]interface{}{"type": "string"},
+				"agent_type": map[string]interface{}{
+					"type":        "string",
+					"description": "Type of the agent the event is about, e.g. worker or review",
+				},
+				"title":     map[string]interface{}{"type": "string"},
+				"message":   map[string]interface{}{"type": "string"},
+				"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+				"attach": map[string]interface{}{
+					"type":        "object",
+					"description": "Commands that attach to the agent's tmux window",
+					"properties": map[string]interface{}{
+						"session": map[string]interface{}{"type": "string"},
+						"window":  map[string]interface{}{"type": "string"},
+						"tmux":    map[string]interface{}{"type": "string"},
+						"command": map[string]interface{}{"type": "string"},
+					},
+				},
+				"context": map[string]interface{}{
+					"type":        "object",
+					"description": "Deprecated: untyped copy of payload fields, kept for older consumers",
+				},
+				"payload": typeSchema(payloadType),
+			},
+			"required": []string{"id", "type", "version", "title", "timestamp", "payload"},
+		}
+		oneOf = append(oneOf, map[string]interface{}{"$ref": "#/$defs/" + name})
+	}
+
+	return map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "multiclaude notification event",
+		"oneOf":   oneOf,
+		"$defs":   defs,
+	}
+}
+
+// timeType is special-cased so timestamps are described as date-time strings
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema describes a Go type as a JSON Schema fragment, following json tags
+func typeSchema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object"}
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = typeSchema(field.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	default:
+		return map[string]interface{}{}
+	}
+}
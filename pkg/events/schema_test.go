@@ -0,0 +1,102 @@
+package events
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewTypedEvent(t *testing.T) {
+	event := NewTypedEvent("repo", "worker-1", "looping", AgentStuckPayload{Reason: "output_loop", Repeats: 4})
+
+	if event.Type != EventAgentStuck || event.Version != 1 {
+		t.Errorf("type/version = %s/%d", event.Type, event.Version)
+	}
+	// Compatibility shim: payload fields are mirrored into Context
+	if event.Context["reason"] != "output_loop" || event.Context["repeats"] != float64(4) {
+		t.Errorf("legacy context = %v", event.Context)
+	}
+}
+
+func TestEventValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   Event
+		wantErr string
+	}{
+		{"missing required field", NewTypedEvent("r", "", "x", AgentStuckPayload{}), "reason is required"},
+		{"payload for another type", Event{Type: EventAgentError, Title: "x", Payload: AgentQuestionPayload{Question: "?"}}, "attached to agent.error"},
+		{"unregistered type", Event{Type: "custom.thing", Title: "x", Payload: AgentErrorPayload{Error: "boom"}}, "no schema"},
+		{"wrong version", Event{Type: EventAgentError, Version: 9, Title: "x", Payload: AgentErrorPayload{Error: "boom"}}, "version 9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.event.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	event := Event{Type: EventAgentError, Title: "x", Payload: AgentErrorPayload{Error: "boom"}}
+	if err := event.Validate(); err != nil {
+		t.Fatalf("valid event rejected: %v", err)
+	}
+	if event.Version != 1 || event.Context["error"] != "boom" {
+		t.Errorf("Validate should fill version and legacy context, got %+v", event)
+	}
+}
+
+func TestEventJSONRoundTrip(t *testing.T) {
+	event := NewTypedEvent("repo", "", "daily", MetricsDailyPayload{
+		Snapshot: MetricsSnapshot{Date: "2026-05-01", Repo: "repo", PRsMerged: 3},
+	})
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Event
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	payload, ok := decoded.Payload.(*MetricsDailyPayload)
+	if !ok || payload.Snapshot.PRsMerged != 3 || decoded.Version != 1 {
+		t.Errorf("decoded payload = %#v (version %d)", decoded.Payload, decoded.Version)
+	}
+}
+
+func TestDecodePayloadFromLegacyContext(t *testing.T) {
+	event := NewEvent(EventAgentQuestion, "repo", "worker-1", "needs input")
+	event.Context["question"] = "Which database?"
+
+	payload, err := DecodePayload(event)
+	if err != nil {
+		t.Fatalf("DecodePayload failed: %v", err)
+	}
+	if q, ok := payload.(*AgentQuestionPayload); !ok || q.Question != "Which database?" {
+		t.Errorf("DecodePayload = %#v", payload)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	doc := JSONSchema()
+	defs := doc["$defs"].(map[string]interface{})
+	if len(defs) != len(Schemas()) {
+		t.Fatalf("expected a definition per schema, got %d", len(defs))
+	}
+
+	stuck := defs[string(EventAgentStuck)].(map[string]interface{})
+	payload := stuck["properties"].(map[string]interface{})["payload"].(map[string]interface{})
+	props := payload["properties"].(map[string]interface{})
+	if props["repeats"].(map[string]interface{})["type"] != "integer" {
+		t.Errorf("repeats should be an integer, got %v", props["repeats"])
+	}
+	if required := payload["required"].([]string); len(required) != 1 || required[0] != "reason" {
+		t.Errorf("required = %v, want [reason]", required)
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Errorf("schema must be JSON-serializable: %v", err)
+	}
+}
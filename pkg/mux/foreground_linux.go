@@ -0,0 +1,54 @@
+package mux
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// foreground returns the name and working directory of a window shell's
+// newest child, or of the shell itself when it has none, reading /proc
+func foreground(pid int, dir string) (command, path string) {
+	target := pid
+	entries, _ := os.ReadDir("/proc")
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil || child <= target {
+			continue
+		}
+		if parentPID(child) == pid {
+			target = child
+		}
+	}
+
+	comm, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(target), "comm"))
+	if err != nil {
+		return "", dir
+	}
+	path = dir
+	if cwd, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(target), "cwd")); err == nil {
+		path = cwd
+	}
+	return strings.TrimSpace(string(comm)), path
+}
+
+// parentPID reads a process's parent from /proc/<pid>/stat, or returns 0
+func parentPID(pid int) int {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0
+	}
+	// The command name is parenthesized and may contain spaces, so the
+	// fields are counted from its closing parenthesis: state, then ppid
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return 0
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	return ppid
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package mux
+
+// foreground can only find a shell's children on Linux; elsewhere the
+// command is unknown and the path is the window's start directory
+func foreground(pid int, dir string) (command, path string) {
+	return "", dir
+}
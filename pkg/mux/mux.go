@@ -0,0 +1,132 @@
+// Package mux abstracts the terminal multiplexer multiclaude runs agents in.
+//
+// A [Multiplexer] hosts named sessions made of named windows, each running a
+// shell that agents are started in and messaged through. [tmux.Client] is the
+// default implementation. [Process] runs every window as a plain background
+// process owned by the calling program, so the daemon also works on hosts
+// without tmux, Windows included.
+//
+//	m, err := mux.New(mux.BackendAuto)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := m.CreateSessionAt(ctx, "demo", "main", dir); err != nil {
+//	    log.Fatal(err)
+//	}
+//	m.SendKeys(ctx, "demo", "main", "echo hello")
+package mux
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// Backends New accepts
+const (
+	// BackendAuto picks tmux when it is installed and Process otherwise
+	BackendAuto = ""
+	// BackendTmux runs windows in tmux
+	BackendTmux = "tmux"
+	// BackendProcess runs windows as background processes
+	BackendProcess = "process"
+)
+
+// WindowInfo describes a window and the process running in it
+type WindowInfo = tmux.WindowInfo
+
+// Multiplexer is what the daemon needs from a terminal multiplexer. Windows
+// are addressed by session and window name; their panes start a shell in the
+// window's directory unless created with CreateWindowWithCommand.
+type Multiplexer interface {
+	// HasSession reports whether a session exists
+	HasSession(ctx context.Context, name string) (bool, error)
+	// CreateSessionAt creates a session whose first window is named
+	// windowName and starts in dir
+	CreateSessionAt(ctx context.Context, name, windowName, dir string) error
+	// KillSession terminates a session and every window in it
+	KillSession(ctx context.Context, name string) error
+	// RenameSession renames a session, keeping its windows running
+	RenameSession(ctx context.Context, name, newName string) error
+	// ListSessions returns every session's name
+	ListSessions(ctx context.Context) ([]string, error)
+
+	// CreateWindowAt creates a window that starts a shell in dir
+	CreateWindowAt(ctx context.Context, session, windowName, dir string) error
+	// CreateWindowWithCommand creates a window in dir that runs command
+	// instead of a shell and closes when it exits
+	CreateWindowWithCommand(ctx context.Context, session, windowName, dir, command string) error
+	// HasWindow reports whether a session has a window with exactly that name
+	HasWindow(ctx context.Context, session, windowName string) (bool, error)
+	// KillWindow terminates a window and the processes in it
+	KillWindow(ctx context.Context, session, windowName string) error
+	// ListWindowInfo describes every window in a session
+	ListWindowInfo(ctx context.Context, session string) ([]WindowInfo, error)
+	// GetWindowOption returns a user option set on a window, or "" if unset
+	GetWindowOption(ctx context.Context, session, windowName, option string) (string, error)
+	// SetWindowOption sets a user option on a window
+	SetWindowOption(ctx context.Context, session, windowName, option, value string) error
+	// RingBell alerts whoever is watching a window
+	RingBell(ctx context.Context, session, windowName string) error
+
+	// SendKeys types text into a window and presses Enter
+	SendKeys(ctx context.Context, session, windowName, text string) error
+	// SendKeysLiteral types text, newlines included, without submitting it
+	SendKeysLiteral(ctx context.Context, session, windowName, text string) error
+	// SendEnter presses Enter
+	SendEnter(ctx context.Context, session, windowName string) error
+	// SendKeysLiteralWithEnter types text and submits it in one step
+	SendKeysLiteralWithEnter(ctx context.Context, session, windowName, text string) error
+
+	// GetPanePID returns the PID of the window's shell
+	GetPanePID(ctx context.Context, session, windowName string) (int, error)
+	// StartPipePane appends everything the window prints to outputFile
+	StartPipePane(ctx context.Context, session, windowName, outputFile string) error
+	// StopPipePane stops copying the window's output
+	StopPipePane(ctx context.Context, session, windowName string) error
+	// CapturePane returns the window's screen plus up to lines lines of
+	// scrollback, without escape sequences
+	CapturePane(ctx context.Context, session, windowName string, lines int) (string, error)
+}
+
+var (
+	_ Multiplexer = (*tmux.Client)(nil)
+	_ Multiplexer = (*Process)(nil)
+)
+
+// New returns the multiplexer for a backend name
+func New(backend string) (Multiplexer, error) {
+	switch backend {
+	case BackendAuto:
+		if client := tmux.NewClient(); client.IsTmuxAvailable() {
+			return client, nil
+		}
+		return NewProcess(), nil
+	case BackendTmux:
+		return tmux.NewClient(), nil
+	case BackendProcess:
+		return NewProcess(), nil
+	}
+	return nil, fmt.Errorf("unknown multiplexer %q: use %s or %s", backend, BackendTmux, BackendProcess)
+}
+
+// Backend names the backend a multiplexer implements
+func Backend(m Multiplexer) string {
+	switch m.(type) {
+	case *tmux.Client:
+		return BackendTmux
+	case *Process:
+		return BackendProcess
+	}
+	return fmt.Sprintf("%T", m)
+}
+
+// ValidBackend reports whether New accepts a backend name
+func ValidBackend(backend string) bool {
+	switch backend {
+	case BackendAuto, BackendTmux, BackendProcess:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,495 @@
+package mux
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+const (
+	// screenLines is how many trailing lines of output CapturePane treats
+	// as a window's visible screen
+	screenLines = 50
+	// maxScrollback bounds the output kept per window for CapturePane
+	maxScrollback = 1 << 20
+	// killGrace is how long a window's processes get to exit after the
+	// hangup before they are killed
+	killGrace = 5 * time.Second
+)
+
+// escapeSequence matches the terminal escape sequences programs print, so
+// CapturePane can return plain text the way tmux does
+var escapeSequence = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// Process is a Multiplexer without a terminal: each window is a shell
+// started as a background child of the calling process, with its stdin a
+// pipe that typed keys are written to and its stdout and stderr collected
+// for CapturePane and StartPipePane. Sessions exist only in memory, so
+// they end with the process that created them; call Close before exiting.
+//
+// Programs in a window don't get a terminal. Those that need one, and
+// attaching to watch a window, still require tmux.
+type Process struct {
+	mu       sync.Mutex
+	sessions map[string]*processSession
+}
+
+type processSession struct {
+	windows []*processWindow // in creation order
+}
+
+// processWindow is one window's process and what it printed
+type processWindow struct {
+	name string
+	dir  string
+	cmd  *exec.Cmd
+	done chan struct{} // closed when the process exits
+
+	mu       sync.Mutex
+	stdin    io.WriteCloser
+	output   []byte
+	pipe     *os.File
+	activity time.Time
+	options  map[string]string
+}
+
+// NewProcess returns a Process multiplexer with no sessions
+func NewProcess() *Process {
+	return &Process{sessions: make(map[string]*processSession)}
+}
+
+// =============================================================================
+// Session Management
+// =============================================================================
+
+// HasSession reports whether a session exists
+func (p *Process) HasSession(ctx context.Context, name string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.sessions[name]
+	return ok, nil
+}
+
+// CreateSessionAt creates a session whose first window runs a shell in dir
+func (p *Process) CreateSessionAt(ctx context.Context, name, windowName, dir string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.sessions[name]; ok {
+		return &tmux.CommandError{Op: "new-session", Session: name, Err: fmt.Errorf("duplicate session")}
+	}
+	w, err := p.startWindow(windowName, dir, shellArgs())
+	if err != nil {
+		return &tmux.CommandError{Op: "new-session", Session: name, Window: windowName, Err: err}
+	}
+	p.sessions[name] = &processSession{windows: []*processWindow{w}}
+	return nil
+}
+
+// KillSession terminates every window in a session
+func (p *Process) KillSession(ctx context.Context, name string) error {
+	p.mu.Lock()
+	s, ok := p.sessions[name]
+	delete(p.sessions, name)
+	p.mu.Unlock()
+	if !ok {
+		return &tmux.SessionNotFoundError{Name: name}
+	}
+	for _, w := range s.windows {
+		w.kill()
+	}
+	return nil
+}
+
+// RenameSession renames a session; its windows keep running
+func (p *Process) RenameSession(ctx context.Context, name, newName string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.sessions[name]
+	if !ok {
+		return &tmux.SessionNotFoundError{Name: name}
+	}
+	if _, taken := p.sessions[newName]; taken {
+		return &tmux.CommandError{Op: "rename-session", Session: name, Err: fmt.Errorf("duplicate session: %s", newName)}
+	}
+	delete(p.sessions, name)
+	p.sessions[newName] = s
+	return nil
+}
+
+// ListSessions returns the sessions' names, sorted
+func (p *Process) ListSessions(ctx context.Context) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.sessions))
+	for name := range p.sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Close kills every window in every session
+func (p *Process) Close() error {
+	p.mu.Lock()
+	sessions := p.sessions
+	p.sessions = make(map[string]*processSession)
+	p.mu.Unlock()
+	for _, s := range sessions {
+		for _, w := range s.windows {
+			w.kill()
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// Window Management
+// =============================================================================
+
+// CreateWindowAt adds a window running a shell in dir
+func (p *Process) CreateWindowAt(ctx context.Context, session, windowName, dir string) error {
+	return p.addWindow(session, windowName, dir, shellArgs())
+}
+
+// CreateWindowWithCommand adds a window running a shell command in dir. The
+// window closes when the command exits.
+func (p *Process) CreateWindowWithCommand(ctx context.Context, session, windowName, dir, command string) error {
+	return p.addWindow(session, windowName, dir, commandArgs(command))
+}
+
+func (p *Process) addWindow(session, windowName, dir string, args []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.sessions[session]
+	if !ok {
+		return &tmux.SessionNotFoundError{Name: session}
+	}
+	for _, w := range s.windows {
+		if w.name == windowName {
+			return &tmux.CommandError{Op: "new-window", Session: session, Window: windowName, Err: fmt.Errorf("duplicate window")}
+		}
+	}
+	w, err := p.startWindow(windowName, dir, args)
+	if err != nil {
+		return &tmux.CommandError{Op: "new-window", Session: session, Window: windowName, Err: err}
+	}
+	s.windows = append(s.windows, w)
+	return nil
+}
+
+// startWindow starts a window's process. Callers hold p.mu.
+func (p *Process) startWindow(name, dir string, args []string) (*processWindow, error) {
+	w := &processWindow{
+		name:     name,
+		dir:      dir,
+		done:     make(chan struct{}),
+		activity: time.Now(),
+		options:  make(map[string]string),
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// Don't wait forever on output from children that outlive the shell
+	cmd.WaitDelay = time.Second
+	configureCommand(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	w.cmd = cmd
+	w.stdin = stdin
+
+	go func() {
+		cmd.Wait()
+		close(w.done)
+		w.mu.Lock()
+		if w.pipe != nil {
+			w.pipe.Close()
+			w.pipe = nil
+		}
+		w.mu.Unlock()
+		p.removeWindow(w)
+	}()
+	return w, nil
+}
+
+// removeWindow forgets a window whose process exited. Like tmux, a session
+// ends when its last window closes.
+func (p *Process) removeWindow(w *processWindow) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, s := range p.sessions {
+		for i, other := range s.windows {
+			if other != w {
+				continue
+			}
+			s.windows = append(s.windows[:i], s.windows[i+1:]...)
+			if len(s.windows) == 0 {
+				delete(p.sessions, name)
+			}
+			return
+		}
+	}
+}
+
+// window finds a window
+func (p *Process) window(session, windowName string) (*processWindow, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.sessions[session]
+	if !ok {
+		return nil, &tmux.SessionNotFoundError{Name: session}
+	}
+	for _, w := range s.windows {
+		if w.name == windowName {
+			return w, nil
+		}
+	}
+	return nil, &tmux.WindowNotFoundError{Session: session, Window: windowName}
+}
+
+// HasWindow reports whether a session has a window with that name
+func (p *Process) HasWindow(ctx context.Context, session, windowName string) (bool, error) {
+	_, err := p.window(session, windowName)
+	if tmux.IsWindowNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// KillWindow closes a window, hanging up its processes
+func (p *Process) KillWindow(ctx context.Context, session, windowName string) error {
+	w, err := p.window(session, windowName)
+	if err != nil {
+		return err
+	}
+	p.removeWindow(w)
+	w.kill()
+	return nil
+}
+
+// ListWindowInfo describes a session's windows. The pane command and path
+// are those of the shell's newest child where the platform can tell, and
+// empty and the window's start directory otherwise.
+func (p *Process) ListWindowInfo(ctx context.Context, session string) ([]WindowInfo, error) {
+	p.mu.Lock()
+	s, ok := p.sessions[session]
+	var windows []*processWindow
+	if ok {
+		windows = append(windows, s.windows...)
+	}
+	p.mu.Unlock()
+	if !ok {
+		return nil, &tmux.SessionNotFoundError{Name: session}
+	}
+
+	infos := make([]WindowInfo, 0, len(windows))
+	for _, w := range windows {
+		info := WindowInfo{Name: w.name}
+		info.PaneCommand, info.PanePath = foreground(w.cmd.Process.Pid, w.dir)
+		w.mu.Lock()
+		info.Activity = w.activity
+		w.mu.Unlock()
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// GetWindowOption returns an option set with SetWindowOption, or ""
+func (p *Process) GetWindowOption(ctx context.Context, session, windowName, option string) (string, error) {
+	w, err := p.window(session, windowName)
+	if err != nil {
+		return "", err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.options[option], nil
+}
+
+// SetWindowOption records an option on a window
+func (p *Process) SetWindowOption(ctx context.Context, session, windowName, option, value string) error {
+	w, err := p.window(session, windowName)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.options[option] = value
+	return nil
+}
+
+// RingBell does nothing: nobody can be watching a window without a terminal
+func (p *Process) RingBell(ctx context.Context, session, windowName string) error {
+	_, err := p.window(session, windowName)
+	return err
+}
+
+// =============================================================================
+// Input
+// =============================================================================
+
+// SendKeys writes text and a newline to the window's stdin
+func (p *Process) SendKeys(ctx context.Context, session, windowName, text string) error {
+	return p.send(session, windowName, text+"\n")
+}
+
+// SendKeysLiteral writes text to the window's stdin. Without a terminal to
+// paste into, every newline in text reaches the program as typed.
+func (p *Process) SendKeysLiteral(ctx context.Context, session, windowName, text string) error {
+	return p.send(session, windowName, text)
+}
+
+// SendEnter writes a newline to the window's stdin
+func (p *Process) SendEnter(ctx context.Context, session, windowName string) error {
+	return p.send(session, windowName, "\n")
+}
+
+// SendKeysLiteralWithEnter writes text and a newline in a single write
+func (p *Process) SendKeysLiteralWithEnter(ctx context.Context, session, windowName, text string) error {
+	return p.send(session, windowName, text+"\n")
+}
+
+func (p *Process) send(session, windowName, text string) error {
+	w, err := p.window(session, windowName)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := io.WriteString(w.stdin, text); err != nil {
+		return &tmux.CommandError{Op: "send-keys", Session: session, Window: windowName, Err: err}
+	}
+	return nil
+}
+
+// =============================================================================
+// Process Monitoring and Output Capture
+// =============================================================================
+
+// GetPanePID returns the PID of the window's shell
+func (p *Process) GetPanePID(ctx context.Context, session, windowName string) (int, error) {
+	w, err := p.window(session, windowName)
+	if err != nil {
+		return 0, err
+	}
+	return w.cmd.Process.Pid, nil
+}
+
+// StartPipePane appends the window's output from now on to outputFile
+func (p *Process) StartPipePane(ctx context.Context, session, windowName, outputFile string) error {
+	w, err := p.window(session, windowName)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return &tmux.CommandError{Op: "pipe-pane", Session: session, Window: windowName, Err: err}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pipe != nil {
+		// Like pipe-pane -o, an existing pipe is left alone
+		f.Close()
+		return nil
+	}
+	w.pipe = f
+	return nil
+}
+
+// StopPipePane stops copying the window's output
+func (p *Process) StopPipePane(ctx context.Context, session, windowName string) error {
+	w, err := p.window(session, windowName)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pipe != nil {
+		err = w.pipe.Close()
+		w.pipe = nil
+	}
+	return err
+}
+
+// CapturePane returns the last screenLines lines the window printed plus up
+// to lines lines before them, with escape sequences removed and carriage
+// returns applied. Trailing blank lines are dropped.
+func (p *Process) CapturePane(ctx context.Context, session, windowName string, lines int) (string, error) {
+	w, err := p.window(session, windowName)
+	if err != nil {
+		return "", err
+	}
+	w.mu.Lock()
+	output := string(w.output)
+	w.mu.Unlock()
+
+	all := strings.Split(escapeSequence.ReplaceAllString(output, ""), "\n")
+	for i, line := range all {
+		line = strings.TrimSuffix(line, "\r")
+		if j := strings.LastIndex(line, "\r"); j >= 0 {
+			line = line[j+1:]
+		}
+		all[i] = line
+	}
+	for len(all) > 0 && strings.TrimSpace(all[len(all)-1]) == "" {
+		all = all[:len(all)-1]
+	}
+	if keep := screenLines + max(lines, 0); len(all) > keep {
+		all = all[len(all)-keep:]
+	}
+	return strings.Join(all, "\n"), nil
+}
+
+// Write collects the window process's output
+func (w *processWindow) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.output = append(w.output, b...)
+	if over := len(w.output) - maxScrollback; over > 0 {
+		// Drop whole lines so the scrollback starts at a line boundary
+		if i := bytes.IndexByte(w.output[over:], '\n'); i >= 0 {
+			over += i + 1
+		}
+		w.output = append([]byte(nil), w.output[over:]...)
+	}
+	if w.pipe != nil {
+		w.pipe.Write(b)
+	}
+	w.activity = time.Now()
+	return len(b), nil
+}
+
+// kill hangs up the window's processes, as closing a terminal would, and
+// kills any still running after killGrace
+func (w *processWindow) kill() {
+	w.mu.Lock()
+	w.stdin.Close()
+	w.mu.Unlock()
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+	hangup(w.cmd.Process)
+	go func() {
+		select {
+		case <-w.done:
+		case <-time.After(killGrace):
+			kill(w.cmd.Process)
+		}
+	}()
+}
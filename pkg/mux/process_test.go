@@ -0,0 +1,210 @@
+package mux
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// waitFor polls cond until it holds or five seconds pass
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestNew(t *testing.T) {
+	m, err := New(BackendProcess)
+	if err != nil {
+		t.Fatalf("New(process) failed: %v", err)
+	}
+	if got := Backend(m); got != BackendProcess {
+		t.Errorf("Backend = %q, want %q", got, BackendProcess)
+	}
+	m, err = New(BackendTmux)
+	if err != nil {
+		t.Fatalf("New(tmux) failed: %v", err)
+	}
+	if got := Backend(m); got != BackendTmux {
+		t.Errorf("Backend = %q, want %q", got, BackendTmux)
+	}
+	if m, err := New(BackendAuto); err != nil || m == nil {
+		t.Errorf("New(auto) = %v, %v", m, err)
+	}
+	if _, err := New("screen"); err == nil || !strings.Contains(err.Error(), "unknown multiplexer") {
+		t.Errorf("New(screen) error = %v, want unknown multiplexer", err)
+	}
+	if ValidBackend("screen") || !ValidBackend(BackendAuto) {
+		t.Error("ValidBackend accepted screen or rejected the automatic choice")
+	}
+}
+
+func TestProcessSessions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	ctx := context.Background()
+	p := NewProcess()
+	defer p.Close()
+	dir := t.TempDir()
+
+	if err := p.CreateSessionAt(ctx, "mc-demo", "supervisor", dir); err != nil {
+		t.Fatalf("CreateSessionAt failed: %v", err)
+	}
+	if err := p.CreateSessionAt(ctx, "mc-demo", "supervisor", dir); err == nil {
+		t.Error("CreateSessionAt accepted a duplicate session")
+	}
+	if err := p.CreateWindowAt(ctx, "mc-demo", "worker", dir); err != nil {
+		t.Fatalf("CreateWindowAt failed: %v", err)
+	}
+	if err := p.CreateWindowAt(ctx, "mc-demo", "worker", dir); err == nil {
+		t.Error("CreateWindowAt accepted a duplicate window")
+	}
+	if err := p.CreateWindowAt(ctx, "mc-missing", "worker", dir); !tmux.IsSessionNotFound(err) {
+		t.Errorf("CreateWindowAt in a missing session = %v, want SessionNotFoundError", err)
+	}
+
+	infos, err := p.ListWindowInfo(ctx, "mc-demo")
+	if err != nil {
+		t.Fatalf("ListWindowInfo failed: %v", err)
+	}
+	if len(infos) != 2 || infos[0].Name != "supervisor" || infos[1].Name != "worker" {
+		t.Fatalf("ListWindowInfo = %+v, want supervisor then worker", infos)
+	}
+
+	if err := p.SetWindowOption(ctx, "mc-demo", "worker", "@keep", "on"); err != nil {
+		t.Fatalf("SetWindowOption failed: %v", err)
+	}
+	if value, _ := p.GetWindowOption(ctx, "mc-demo", "worker", "@keep"); value != "on" {
+		t.Errorf("GetWindowOption = %q, want on", value)
+	}
+
+	if err := p.RenameSession(ctx, "mc-demo", "team-demo"); err != nil {
+		t.Fatalf("RenameSession failed: %v", err)
+	}
+	if sessions, _ := p.ListSessions(ctx); len(sessions) != 1 || sessions[0] != "team-demo" {
+		t.Errorf("ListSessions = %v, want [team-demo]", sessions)
+	}
+
+	pid, err := p.GetPanePID(ctx, "team-demo", "worker")
+	if err != nil || pid <= 0 {
+		t.Fatalf("GetPanePID = %d, %v", pid, err)
+	}
+	if err := p.KillWindow(ctx, "team-demo", "worker"); err != nil {
+		t.Fatalf("KillWindow failed: %v", err)
+	}
+	if exists, err := p.HasWindow(ctx, "team-demo", "worker"); err != nil || exists {
+		t.Errorf("HasWindow after KillWindow = %v, %v; want false", exists, err)
+	}
+	waitFor(t, "the killed window's shell to exit", func() bool {
+		proc, err := os.FindProcess(pid)
+		return err != nil || proc.Signal(syscall.Signal(0)) != nil
+	})
+
+	if err := p.KillSession(ctx, "team-demo"); err != nil {
+		t.Fatalf("KillSession failed: %v", err)
+	}
+	if exists, _ := p.HasSession(ctx, "team-demo"); exists {
+		t.Error("session still exists after KillSession")
+	}
+}
+
+func TestProcessInputAndOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	ctx := context.Background()
+	p := NewProcess()
+	defer p.Close()
+	dir := t.TempDir()
+	logFile := filepath.Join(t.TempDir(), "window.log")
+
+	if err := p.CreateSessionAt(ctx, "mc-io", "main", dir); err != nil {
+		t.Fatalf("CreateSessionAt failed: %v", err)
+	}
+	if err := p.StartPipePane(ctx, "mc-io", "main", logFile); err != nil {
+		t.Fatalf("StartPipePane failed: %v", err)
+	}
+	if err := p.SendKeys(ctx, "mc-io", "main", "echo hello from the window"); err != nil {
+		t.Fatalf("SendKeys failed: %v", err)
+	}
+	if err := p.SendKeysLiteralWithEnter(ctx, "mc-io", "main", `printf 'progress 10%%\rprogress 100%%\n\033[1mbold\033[0m\n'`); err != nil {
+		t.Fatalf("SendKeysLiteralWithEnter failed: %v", err)
+	}
+
+	var screen string
+	waitFor(t, "the window's output", func() bool {
+		screen, _ = p.CapturePane(ctx, "mc-io", "main", 0)
+		return strings.Contains(screen, "bold")
+	})
+	want := "hello from the window\nprogress 100%\nbold"
+	if screen != want {
+		t.Errorf("CapturePane = %q, want %q", screen, want)
+	}
+
+	waitFor(t, "the pipe file", func() bool {
+		data, _ := os.ReadFile(logFile)
+		return strings.Contains(string(data), "hello from the window")
+	})
+	if err := p.StopPipePane(ctx, "mc-io", "main"); err != nil {
+		t.Fatalf("StopPipePane failed: %v", err)
+	}
+
+	if runtime.GOOS == "linux" {
+		if err := p.SendKeys(ctx, "mc-io", "main", "sleep 30"); err != nil {
+			t.Fatalf("SendKeys failed: %v", err)
+		}
+		waitFor(t, "sleep to be the window's command", func() bool {
+			infos, _ := p.ListWindowInfo(ctx, "mc-io")
+			return len(infos) == 1 && infos[0].PaneCommand == "sleep"
+		})
+	}
+}
+
+func TestProcessWindowClosesWhenCommandExits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	ctx := context.Background()
+	p := NewProcess()
+	defer p.Close()
+	dir := t.TempDir()
+
+	if err := p.CreateSessionAt(ctx, "mc-exit", "main", dir); err != nil {
+		t.Fatalf("CreateSessionAt failed: %v", err)
+	}
+	if err := p.CreateWindowWithCommand(ctx, "mc-exit", "job", dir, "pwd > out.txt"); err != nil {
+		t.Fatalf("CreateWindowWithCommand failed: %v", err)
+	}
+	waitFor(t, "the job window to close", func() bool {
+		exists, _ := p.HasWindow(ctx, "mc-exit", "job")
+		return !exists
+	})
+	if data, err := os.ReadFile(filepath.Join(dir, "out.txt")); err != nil || len(data) == 0 {
+		t.Errorf("command didn't run in %s: %v", dir, err)
+	}
+
+	// The session ends with its last window
+	if err := p.SendKeys(ctx, "mc-exit", "main", "exit"); err != nil {
+		t.Fatalf("SendKeys failed: %v", err)
+	}
+	waitFor(t, "the session to end", func() bool {
+		exists, _ := p.HasSession(ctx, "mc-exit")
+		return !exists
+	})
+	if err := p.SendKeys(ctx, "mc-exit", "main", "echo"); !tmux.IsSessionNotFound(err) {
+		t.Errorf("SendKeys to an ended session = %v, want SessionNotFoundError", err)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package mux
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// shellArgs is the command line of a window's shell
+func shellArgs() []string {
+	return []string{"sh"}
+}
+
+// commandArgs runs a shell command
+func commandArgs(command string) []string {
+	return []string{"sh", "-c", command}
+}
+
+// configureCommand puts a window's processes in their own process group so
+// hangup and kill reach everything started in the window
+func configureCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// hangup sends SIGHUP to a window's process group
+func hangup(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGHUP)
+}
+
+// kill sends SIGKILL to a window's process group
+func kill(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
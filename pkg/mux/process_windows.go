@@ -0,0 +1,33 @@
+package mux
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// shellArgs is the command line of a window's shell
+func shellArgs() []string {
+	return []string{"cmd.exe", "/Q"}
+}
+
+// commandArgs runs a shell command
+func commandArgs(command string) []string {
+	return []string{"cmd.exe", "/C", command}
+}
+
+// configureCommand starts a window's processes in a new process group
+func configureCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// hangup asks a window's process tree to exit
+func hangup(p *os.Process) error {
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(p.Pid)).Run()
+}
+
+// kill forcibly ends a window's process tree
+func kill(p *os.Process) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run()
+}
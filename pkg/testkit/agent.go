@@ -0,0 +1,70 @@
+package testkit
+
+import (
+	"github.com/dlorenc/multiclaude/internal/messages"
+)
+
+// Message is a message between agents
+type Message = messages.Message
+
+// Agent plays an agent's part from its worktree: committing, pushing,
+// messaging, and completing the way Claude would through multiclaude
+type Agent struct {
+	env  *Env
+	Repo string
+	Name string
+}
+
+// Worktree returns the agent's worktree path from the daemon's state
+func (a *Agent) Worktree() string {
+	a.env.t.Helper()
+	agent, ok := a.env.Daemon.GetState().GetAgent(a.Repo, a.Name)
+	if !ok {
+		a.env.t.Fatalf("testkit: agent %s not found in repository %s", a.Name, a.Repo)
+	}
+	return agent.WorktreePath
+}
+
+// Commit writes content to path in the worktree and commits it, returning
+// the commit's SHA
+func (a *Agent) Commit(path, content, message string) string {
+	a.env.t.Helper()
+	return commitFile(a.env.t, a.Worktree(), path, content, message)
+}
+
+// Push pushes the agent's branch to the remote
+func (a *Agent) Push() {
+	a.env.t.Helper()
+	runGit(a.env.t, a.Worktree(), "push", "--quiet", "-u", "origin", "HEAD")
+}
+
+// Send sends a message to another agent and has the daemon route it
+func (a *Agent) Send(to, body string) *Message {
+	a.env.t.Helper()
+	msg, err := messages.NewManager(a.env.Paths.MessagesDir).Send(a.Repo, a.Name, to, body)
+	if err != nil {
+		a.env.t.Fatalf("testkit: %s failed to send a message to %s: %v", a.Name, to, err)
+	}
+	a.env.Request("route_messages", nil)
+	return msg
+}
+
+// Inbox returns the messages addressed to the agent
+func (a *Agent) Inbox() []*Message {
+	a.env.t.Helper()
+	msgs, err := messages.NewManager(a.env.Paths.MessagesDir).List(a.Repo, a.Name)
+	if err != nil {
+		a.env.t.Fatalf("testkit: failed to list messages of %s: %v", a.Name, err)
+	}
+	return msgs
+}
+
+// Complete signals that the agent finished its task
+func (a *Agent) Complete(summary string) {
+	a.env.t.Helper()
+	a.env.MustRequest("complete_agent", map[string]interface{}{
+		"repo":    a.Repo,
+		"agent":   a.Name,
+		"summary": summary,
+	})
+}
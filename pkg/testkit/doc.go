@@ -0,0 +1,42 @@
+// Package testkit runs a real multiclaude daemon in a temporary directory so
+// adapters, plugins, and multiclaude's own integration tests can exercise it
+// end to end without copying setup code.
+//
+// It provides:
+//
+//   - [New]: a daemon with its own paths, socket, and state, stopped and
+//     removed when the test ends
+//   - [NewRemote]: a bare git repository standing in for GitHub, which
+//     tracked repositories are cloned from and push to
+//   - [Agent]: a scripted agent that commits, pushes, messages, and
+//     completes from its worktree the way Claude would
+//   - [EventRecorder]: a notification adapter that captures every event the
+//     daemon emits, with [EventRecorder.WaitFor] to block on one
+//
+// Claude itself is never started: the environment sets
+// MULTICLAUDE_TEST_MODE=1, so tests must not call t.Parallel.
+//
+// # Requirements
+//
+// git must be installed. Repositories and agents run in tmux windows, so
+// [Env.AddRepo] and [Env.AddWorker] also need tmux.
+//
+// # Example Usage
+//
+//	func TestMyAdapter(t *testing.T) {
+//	    env := testkit.New(t)
+//	    env.RegisterAdapter(myadapter.New(...))
+//
+//	    remote := testkit.NewRemote(t)
+//	    env.AddRepo("demo", remote)
+//	    worker := env.AddWorker("demo", "swift-fox", "Fix the flaky test")
+//
+//	    worker.Commit("fix.txt", "fixed\n", "Fix the flaky test")
+//	    worker.Push()
+//	    worker.Complete("Fixed it")
+//
+//	    env.MustRequest("export_metrics", nil)
+//	    event := env.Events.WaitFor(t, "metrics.daily", 5*time.Second)
+//	    ...
+//	}
+package testkit
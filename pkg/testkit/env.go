@@ -0,0 +1,235 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/cli"
+	"github.com/dlorenc/multiclaude/internal/daemon"
+	"github.com/dlorenc/multiclaude/internal/socket"
+	"github.com/dlorenc/multiclaude/internal/worktree"
+	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/tmux"
+)
+
+// Response is the daemon's reply to a socket request
+type Response = socket.Response
+
+// startTimeout is how long New waits for the daemon to answer on its socket
+const startTimeout = 5 * time.Second
+
+// Env is a running daemon rooted in a temporary directory
+type Env struct {
+	t testing.TB
+
+	// Paths are the daemon's files and directories, all under Paths.Root
+	Paths *config.Paths
+	// Daemon is the running daemon
+	Daemon *daemon.Daemon
+	// CLI runs multiclaude commands against the daemon
+	CLI *cli.CLI
+	// Events captures every notification event the daemon emits
+	Events *EventRecorder
+
+	tmux *tmux.Client
+	id   string // Suffix that keeps tmux session names unique to this Env
+}
+
+// New starts a daemon in a fresh temporary directory. It is stopped, its tmux
+// sessions killed, and the directory removed when the test ends.
+func New(t testing.TB) *Env {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Fatal("testkit: git is required but not installed")
+	}
+	t.Setenv("MULTICLAUDE_TEST_MODE", "1")
+
+	// Not t.TempDir: the socket path must stay under the Unix socket limit
+	// however long the test name is
+	root, err := os.MkdirTemp("", "mc-testkit-*")
+	if err != nil {
+		t.Fatalf("testkit: failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	// Resolve symlinks (macOS /tmp -> /private/tmp) so paths compare equal
+	// to what git reports
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		t.Fatalf("testkit: failed to resolve temp dir: %v", err)
+	}
+
+	paths := config.NewTestPaths(root)
+	if err := paths.EnsureDirectories(); err != nil {
+		t.Fatalf("testkit: failed to create directories: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "prompts"), 0755); err != nil {
+		t.Fatalf("testkit: failed to create prompts dir: %v", err)
+	}
+
+	d, err := daemon.New(paths)
+	if err != nil {
+		t.Fatalf("testkit: failed to create daemon: %v", err)
+	}
+	env := &Env{
+		t:      t,
+		Paths:  paths,
+		Daemon: d,
+		CLI:    cli.NewWithPaths(paths),
+		Events: NewEventRecorder(),
+		tmux:   tmux.NewClient(),
+		id:     strings.TrimPrefix(filepath.Base(root), "mc-testkit-"),
+	}
+	d.RegisterAdapter(env.Events)
+
+	if err := d.Start(); err != nil {
+		t.Fatalf("testkit: failed to start daemon: %v", err)
+	}
+	t.Cleanup(func() { d.Stop() })
+
+	client := socket.NewClient(paths.DaemonSock)
+	Eventually(t, startTimeout, "daemon to answer on its socket", func() bool {
+		resp, err := client.Send(socket.Request{Command: "ping"})
+		return err == nil && resp.Success
+	})
+	return env
+}
+
+// Request sends a command to the daemon's socket. Transport failures fail
+// the test; the daemon's own errors are returned in the response.
+func (e *Env) Request(command string, args map[string]interface{}) Response {
+	e.t.Helper()
+	resp, err := socket.NewClient(e.Paths.DaemonSock).Send(socket.Request{Command: command, Args: args})
+	if err != nil {
+		e.t.Fatalf("testkit: %s request failed: %v", command, err)
+	}
+	return *resp
+}
+
+// MustRequest sends a command to the daemon and fails the test unless it
+// succeeds, returning the response data
+func (e *Env) MustRequest(command string, args map[string]interface{}) interface{} {
+	e.t.Helper()
+	resp := e.Request(command, args)
+	if !resp.Success {
+		e.t.Fatalf("testkit: %s failed: %s", command, resp.Error)
+	}
+	return resp.Data
+}
+
+// Run executes a multiclaude command line, e.g. Run("work", "list")
+func (e *Env) Run(args ...string) error {
+	return e.CLI.Execute(args)
+}
+
+// RegisterAdapter adds a notification adapter to the daemon, so an
+// adapter under test receives the same events as the daemon log
+func (e *Env) RegisterAdapter(adapter Adapter) {
+	e.Daemon.RegisterAdapter(adapter)
+}
+
+// TmuxSession creates a detached tmux session that is killed when the test
+// ends
+func (e *Env) TmuxSession(name string) {
+	e.t.Helper()
+	if !e.tmux.IsTmuxAvailable() {
+		e.t.Fatal("testkit: tmux is required but not available")
+	}
+	if err := e.tmux.CreateSession(context.Background(), name, true); err != nil {
+		e.t.Fatalf("testkit: failed to create tmux session %s: %v", name, err)
+	}
+	e.t.Cleanup(func() { e.tmux.KillSession(context.Background(), name) })
+}
+
+// AddRepo clones remote and tracks it as repoName, the way `multiclaude init`
+// would without spawning the supervisor or merge queue. Returns the clone's
+// path.
+func (e *Env) AddRepo(repoName string, remote *Remote) string {
+	e.t.Helper()
+	repoPath := e.Paths.RepoDir(repoName)
+	runGit(e.t, "", "clone", "--quiet", remote.URL(), repoPath)
+	configureGitUser(e.t, repoPath)
+
+	// A real multiclaude may be running on the same tmux server
+	session := fmt.Sprintf("mc-%s-%s", repoName, e.id)
+	e.TmuxSession(session)
+	e.MustRequest("add_repo", map[string]interface{}{
+		"name":         repoName,
+		"github_url":   "https://github.com/testkit/" + repoName,
+		"tmux_session": session,
+	})
+	return repoPath
+}
+
+// AddWorker creates a worker's worktree on a new work/<name> branch off main
+// and registers it with the daemon, as `multiclaude work` would without
+// starting Claude
+func (e *Env) AddWorker(repoName, name, task string) *Agent {
+	e.t.Helper()
+	repoPath := e.Paths.RepoDir(repoName)
+	wtPath := e.Paths.AgentWorktree(repoName, name)
+	if err := worktree.NewManager(repoPath).CreateNewBranch(wtPath, "work/"+name, "main"); err != nil {
+		e.t.Fatalf("testkit: failed to create worktree for %s: %v", name, err)
+	}
+
+	// The daemon's health check removes agents without a tmux window
+	repo, ok := e.Daemon.GetState().GetRepo(repoName)
+	if !ok {
+		e.t.Fatalf("testkit: repository %s is not tracked", repoName)
+	}
+	if err := e.tmux.CreateWindow(context.Background(), repo.TmuxSession, name); err != nil {
+		e.t.Fatalf("testkit: failed to create tmux window for %s: %v", name, err)
+	}
+
+	e.MustRequest("add_agent", map[string]interface{}{
+		"repo":          repoName,
+		"agent":         name,
+		"type":          "worker",
+		"worktree_path": wtPath,
+		"tmux_window":   name,
+		"task":          task,
+	})
+	return e.Agent(repoName, name)
+}
+
+// Agent returns a scripted agent acting as name in repoName
+func (e *Env) Agent(repoName, name string) *Agent {
+	return &Agent{env: e, Repo: repoName, Name: name}
+}
+
+// Eventually polls cond until it returns true, failing the test with what
+// it was waiting for after timeout
+func Eventually(t testing.TB, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("testkit: timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// runGit runs git in dir and returns its trimmed output, failing the test on
+// error
+func runGit(t testing.TB, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("testkit: git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// configureGitUser sets a commit identity in a repository
+func configureGitUser(t testing.TB, dir string) {
+	t.Helper()
+	runGit(t, dir, "config", "user.email", "testkit@example.com")
+	runGit(t, dir, "config", "user.name", "testkit")
+}
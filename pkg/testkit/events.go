@@ -0,0 +1,89 @@
+package testkit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/notify"
+	"github.com/dlorenc/multiclaude/pkg/events"
+)
+
+// Event is a notification event emitted by the daemon
+type Event = events.Event
+
+// Adapter delivers notification events; chat and webhook adapters
+// implement it
+type Adapter = notify.Adapter
+
+// EventRecorder is an adapter that keeps every event it is sent
+type EventRecorder struct {
+	mu      sync.Mutex
+	events  []Event
+	changed chan struct{} // Closed and replaced whenever an event arrives
+}
+
+// NewEventRecorder creates an empty recorder
+func NewEventRecorder() *EventRecorder {
+	return &EventRecorder{changed: make(chan struct{})}
+}
+
+// Name identifies the recorder among the daemon's adapters
+func (r *EventRecorder) Name() string {
+	return "testkit"
+}
+
+// Send records an event
+func (r *EventRecorder) Send(ctx context.Context, event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	close(r.changed)
+	r.changed = make(chan struct{})
+	return nil
+}
+
+// Events returns the events recorded so far, oldest first
+func (r *EventRecorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Of returns the recorded events of one type, e.g. "agent.completed"
+func (r *EventRecorder) Of(eventType string) []Event {
+	var matched []Event
+	for _, e := range r.Events() {
+		if string(e.Type) == eventType {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// WaitFor returns the first event of eventType, waiting up to timeout for
+// one to arrive, and fails the test if none does
+func (r *EventRecorder) WaitFor(t testing.TB, eventType string, timeout time.Duration) Event {
+	t.Helper()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		r.mu.Lock()
+		changed := r.changed
+		for _, e := range r.events {
+			if string(e.Type) == eventType {
+				r.mu.Unlock()
+				return e
+			}
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			t.Fatalf("testkit: no %s event within %s (got %d other events)", eventType, timeout, len(r.Events()))
+			return Event{}
+		}
+	}
+}
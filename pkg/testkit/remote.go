@@ -0,0 +1,86 @@
+package testkit
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Remote is a bare git repository standing in for a GitHub repository. It
+// starts with one commit on main.
+type Remote struct {
+	t       testing.TB
+	dir     string
+	scratch string // Clone used to push commits as someone other than an agent
+}
+
+// NewRemote creates a remote in a temporary directory
+func NewRemote(t testing.TB) *Remote {
+	t.Helper()
+	root := t.TempDir()
+	r := &Remote{t: t, dir: filepath.Join(root, "remote.git"), scratch: filepath.Join(root, "scratch")}
+
+	runGit(t, "", "init", "--quiet", "--bare", "--initial-branch=main", r.dir)
+	runGit(t, "", "clone", "--quiet", r.dir, r.scratch)
+	configureGitUser(t, r.scratch)
+	runGit(t, r.scratch, "checkout", "--quiet", "-B", "main")
+	r.commit("README.md", "# testkit\n", "Initial commit")
+	runGit(t, r.scratch, "push", "--quiet", "origin", "main")
+	return r
+}
+
+// URL returns what to clone the remote from
+func (r *Remote) URL() string {
+	return r.dir
+}
+
+// Commit pushes a commit writing content to path on branch, as a human or
+// CI would, and returns its SHA. The branch is created from main if it
+// doesn't exist yet.
+func (r *Remote) Commit(branch, path, content, message string) string {
+	r.t.Helper()
+	runGit(r.t, r.scratch, "fetch", "--quiet", "origin")
+	start := "origin/main"
+	if r.Head(branch) != "" {
+		start = "origin/" + branch
+	}
+	runGit(r.t, r.scratch, "checkout", "--quiet", "-B", branch, start)
+	sha := r.commit(path, content, message)
+	runGit(r.t, r.scratch, "push", "--quiet", "origin", branch)
+	return sha
+}
+
+// Head returns the SHA branch points at, or "" if it doesn't exist
+func (r *Remote) Head(branch string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", "--git-dir", r.dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// commit writes and commits a file in the scratch clone
+func (r *Remote) commit(path, content, message string) string {
+	r.t.Helper()
+	return commitFile(r.t, r.scratch, path, content, message)
+}
+
+// commitFile writes content to path inside dir and commits it, returning
+// the new commit's SHA
+func commitFile(t testing.TB, dir, path, content, message string) string {
+	t.Helper()
+	full := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatalf("testkit: failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatalf("testkit: failed to write %s: %v", path, err)
+	}
+	runGit(t, dir, "add", "--", path)
+	runGit(t, dir, "commit", "--quiet", "-m", message)
+	return runGit(t, dir, "rev-parse", "HEAD")
+}
@@ -0,0 +1,63 @@
+package testkit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScriptedWorker(t *testing.T) {
+	env := New(t)
+	remote := NewRemote(t)
+	env.AddRepo("demo", remote)
+	worker := env.AddWorker("demo", "swift-fox", "Fix the flaky test")
+
+	sha := worker.Commit("fix.txt", "fixed\n", "Fix the flaky test")
+	worker.Push()
+	if head := remote.Head("work/swift-fox"); head != sha {
+		t.Errorf("remote work/swift-fox = %q, want %q", head, sha)
+	}
+
+	worker.Send("supervisor", "Done with the flaky test")
+	if inbox := env.Agent("demo", "supervisor").Inbox(); len(inbox) != 1 || !strings.Contains(inbox[0].Body, "flaky") {
+		t.Errorf("supervisor inbox = %+v, want the worker's message", inbox)
+	}
+
+	worker.Complete("Fixed it")
+	agent, _ := env.Daemon.GetState().GetAgent("demo", "swift-fox")
+	if !agent.ReadyForCleanup || agent.Summary != "Fixed it" {
+		t.Errorf("agent after Complete = %+v", agent)
+	}
+}
+
+func TestRemoteCommit(t *testing.T) {
+	remote := NewRemote(t)
+	main := remote.Head("main")
+	if main == "" {
+		t.Fatal("remote should start with a commit on main")
+	}
+	sha := remote.Commit("release", "VERSION", "2.1\n", "Cut 2.1")
+	if remote.Head("release") != sha || remote.Head("main") != main {
+		t.Error("Commit should only move the given branch")
+	}
+}
+
+func TestEventRecorder(t *testing.T) {
+	env := New(t)
+	session := "mc-events-" + env.id
+	env.TmuxSession(session)
+	env.MustRequest("add_repo", map[string]interface{}{
+		"name":         "events",
+		"github_url":   "https://github.com/testkit/events",
+		"tmux_session": session,
+	})
+
+	env.MustRequest("export_metrics", nil)
+	event := env.Events.WaitFor(t, "metrics.daily", 5*time.Second)
+	if event.Repo != "events" {
+		t.Errorf("metrics.daily event for %q, want events", event.Repo)
+	}
+	if len(env.Events.Of("metrics.daily")) != 1 {
+		t.Errorf("Of(metrics.daily) = %+v", env.Events.Of("metrics.daily"))
+	}
+}
@@ -47,8 +47,13 @@ package tmux
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/dlorenc/multiclaude/internal/tracing"
 )
 
 // Client wraps tmux operations for programmatic control of tmux sessions,
@@ -81,8 +86,9 @@ func NewClient(opts ...ClientOption) *Client {
 }
 
 // tmuxCmd creates an exec.Cmd for the configured tmux binary with context.
-func (c *Client) tmuxCmd(ctx context.Context, args ...string) *exec.Cmd {
-	return exec.CommandContext(ctx, c.tmuxPath, args...)
+// Running it records a span when ctx belongs to a trace.
+func (c *Client) tmuxCmd(ctx context.Context, args ...string) *tracing.Cmd {
+	return tracing.Command(ctx, exec.CommandContext(ctx, c.tmuxPath, args...))
 }
 
 // wrapCommandError wraps an error from a tmux command, checking for context cancellation first.
@@ -145,12 +151,26 @@ func (c *Client) CreateSession(ctx context.Context, name string, detached bool)
 	return c.wrapCommandError(ctx, cmd.Run(), "new-session", name, "")
 }
 
+// CreateSessionAt creates a detached session whose first window is named
+// windowName and starts in dir.
+func (c *Client) CreateSessionAt(ctx context.Context, name, windowName, dir string) error {
+	cmd := c.tmuxCmd(ctx, "new-session", "-d", "-s", name, "-n", windowName, "-c", dir)
+	return c.wrapCommandError(ctx, cmd.Run(), "new-session", name, windowName)
+}
+
 // KillSession terminates a tmux session.
 func (c *Client) KillSession(ctx context.Context, name string) error {
 	cmd := c.tmuxCmd(ctx, "kill-session", "-t", name)
 	return c.wrapCommandError(ctx, cmd.Run(), "kill-session", name, "")
 }
 
+// RenameSession renames a session. Its windows, panes, and any pipe-pane
+// output capture carry on under the new name.
+func (c *Client) RenameSession(ctx context.Context, name, newName string) error {
+	cmd := c.tmuxCmd(ctx, "rename-session", "-t", name, newName)
+	return c.wrapCommandError(ctx, cmd.Run(), "rename-session", name, "")
+}
+
 // ListSessions returns a list of all tmux session names.
 func (c *Client) ListSessions(ctx context.Context) ([]string, error) {
 	cmd := c.tmuxCmd(ctx, "list-sessions", "-F", "#{session_name}")
@@ -186,6 +206,23 @@ func (c *Client) CreateWindow(ctx context.Context, session, windowName string) e
 	return c.wrapCommandError(ctx, cmd.Run(), "new-window", session, windowName)
 }
 
+// CreateWindowAt creates a new window in the specified session that starts
+// in dir, without switching to it.
+func (c *Client) CreateWindowAt(ctx context.Context, session, windowName, dir string) error {
+	target := fmt.Sprintf("%s:", session)
+	cmd := c.tmuxCmd(ctx, "new-window", "-d", "-t", target, "-n", windowName, "-c", dir)
+	return c.wrapCommandError(ctx, cmd.Run(), "new-window", session, windowName)
+}
+
+// CreateWindowWithCommand creates a window in dir that runs a shell command
+// instead of the default shell, without switching to it. The window closes
+// when the command exits.
+func (c *Client) CreateWindowWithCommand(ctx context.Context, session, windowName, dir, command string) error {
+	target := fmt.Sprintf("%s:", session)
+	cmd := c.tmuxCmd(ctx, "new-window", "-d", "-t", target, "-n", windowName, "-c", dir, command)
+	return c.wrapCommandError(ctx, cmd.Run(), "new-window", session, windowName)
+}
+
 // HasWindow checks if a window with the given name exists in the session.
 // Uses exact matching via tmux format strings.
 func (c *Client) HasWindow(ctx context.Context, session, windowName string) (bool, error) {
@@ -234,6 +271,126 @@ func (c *Client) ListWindows(ctx context.Context, session string) ([]string, err
 	return windows, nil
 }
 
+// WindowInfo describes a window and the active pane in it.
+type WindowInfo struct {
+	Name string
+	// PaneDead is true when the pane's process exited and tmux kept the
+	// pane open (remain-on-exit).
+	PaneDead bool
+	// PaneCommand is the pane's foreground command, e.g. "bash" or "claude".
+	PaneCommand string
+	// PanePath is the current working directory of the pane's foreground
+	// process, when tmux can tell.
+	PanePath string
+	// Activity is when the window last had output.
+	Activity time.Time
+}
+
+// formatSep separates the fields of a -F format. tmux prints tabs and other
+// non-printable characters as "_" for a client it doesn't think can display
+// them, so the separator is printable.
+const formatSep = "|#|"
+
+// formatFields builds a -F format printing fields separated by formatSep
+func formatFields(fields ...string) string {
+	return strings.Join(fields, formatSep)
+}
+
+// splitFields splits a line printed by a formatFields format into n fields,
+// reporting false if the line has fewer
+func splitFields(line string, n int) ([]string, bool) {
+	fields := strings.SplitN(line, formatSep, n)
+	return fields, len(fields) == n
+}
+
+// ListWindowInfo returns every window in the session with the state of its
+// active pane.
+func (c *Client) ListWindowInfo(ctx context.Context, session string) ([]WindowInfo, error) {
+	format := formatFields("#{window_name}", "#{pane_dead}", "#{pane_current_command}", "#{window_activity}", "#{pane_current_path}")
+	cmd := c.tmuxCmd(ctx, "list-windows", "-t", session, "-F", format)
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &CommandError{Op: "list-windows", Session: session, Err: err}
+	}
+
+	var windows []WindowInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == "" {
+			continue
+		}
+		fields, ok := splitFields(line, 5)
+		if !ok {
+			return nil, &CommandError{Op: "list-windows", Session: session, Err: fmt.Errorf("unexpected output %q", line)}
+		}
+		info := WindowInfo{
+			Name:        fields[0],
+			PaneDead:    fields[1] == "1",
+			PaneCommand: fields[2],
+			PanePath:    fields[4],
+		}
+		if secs, err := strconv.ParseInt(fields[3], 10, 64); err == nil && secs > 0 {
+			info.Activity = time.Unix(secs, 0)
+		}
+		windows = append(windows, info)
+	}
+	return windows, nil
+}
+
+// GetWindowOption returns the value of a window option, such as a user
+// option like "@my-flag". An unset option returns an empty string.
+func (c *Client) GetWindowOption(ctx context.Context, session, windowName, option string) (string, error) {
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	cmd := c.tmuxCmd(ctx, "show-options", "-w", "-q", "-v", "-t", target, option)
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", &CommandError{Op: "show-options", Session: session, Window: windowName, Err: err}
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// SetWindowOption sets a window option, such as "monitor-bell" or a user
+// option like "@my-flag".
+func (c *Client) SetWindowOption(ctx context.Context, session, windowName, option, value string) error {
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	cmd := c.tmuxCmd(ctx, "set-option", "-w", "-t", target, option, value)
+	return c.wrapCommandError(ctx, cmd.Run(), "set-option", session, windowName)
+}
+
+// RingBell rings the terminal bell in a window, as if its program had
+// printed one. With monitor-bell on (which RingBell ensures), tmux flags the
+// window in the status line and alerts attached clients according to their
+// bell-action, without anything being typed into the pane.
+func (c *Client) RingBell(ctx context.Context, session, windowName string) error {
+	if err := c.SetWindowOption(ctx, session, windowName, "monitor-bell", "on"); err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	cmd := c.tmuxCmd(ctx, "display-message", "-t", target, "-p", "#{pane_tty}")
+	output, err := cmd.Output()
+	if err != nil {
+		return c.wrapCommandError(ctx, err, "display-message", session, windowName)
+	}
+
+	// Writing to the pane's terminal is output tmux reads like the
+	// program's own
+	tty, err := os.OpenFile(strings.TrimSpace(string(output)), os.O_WRONLY, 0)
+	if err != nil {
+		return &CommandError{Op: "ring-bell", Session: session, Window: windowName, Err: err}
+	}
+	defer tty.Close()
+	if _, err := tty.Write([]byte("\a")); err != nil {
+		return &CommandError{Op: "ring-bell", Session: session, Window: windowName, Err: err}
+	}
+	return nil
+}
+
 // =============================================================================
 // Text Input - The Key Differentiator
 // =============================================================================
@@ -385,6 +542,22 @@ func (c *Client) StartPipePane(ctx context.Context, session, windowName, outputF
 	return nil
 }
 
+// CapturePane returns what a window's pane is currently showing: the visible
+// screen plus up to lines lines of scrollback above it, without escape
+// sequences. Trailing blank lines are dropped.
+func (c *Client) CapturePane(ctx context.Context, session, windowName string, lines int) (string, error) {
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	args := []string{"capture-pane", "-p", "-t", target}
+	if lines > 0 {
+		args = append(args, "-S", fmt.Sprintf("-%d", lines))
+	}
+	output, err := c.tmuxCmd(ctx, args...).Output()
+	if err != nil {
+		return "", c.wrapCommandError(ctx, err, "capture-pane", session, windowName)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
 // StopPipePane stops the pipe-pane for a window.
 // After calling this, output is no longer captured to the file.
 func (c *Client) StopPipePane(ctx context.Context, session, windowName string) error {
@@ -393,3 +566,91 @@ func (c *Client) StopPipePane(ctx context.Context, session, windowName string) e
 	cmd := c.tmuxCmd(ctx, "pipe-pane", "-t", target)
 	return c.wrapCommandError(ctx, cmd.Run(), "pipe-pane-stop", session, windowName)
 }
+
+// =============================================================================
+// Pane Layout
+// =============================================================================
+
+// Layouts accepted by SelectLayout; tmux also takes custom layout strings.
+const (
+	LayoutTiled          = "tiled"
+	LayoutEvenHorizontal = "even-horizontal"
+	LayoutEvenVertical   = "even-vertical"
+	LayoutMainVertical   = "main-vertical"
+)
+
+// PaneInfo describes a pane in a window.
+type PaneInfo struct {
+	// ID is tmux's unique pane ID, e.g. "%3", usable as a pane target.
+	ID    string
+	Index int
+	Title string
+	// Command is the pane's foreground command.
+	Command string
+	Dead    bool
+}
+
+// ListPanes returns the panes of a window in index order.
+func (c *Client) ListPanes(ctx context.Context, session, windowName string) ([]PaneInfo, error) {
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	format := formatFields("#{pane_id}", "#{pane_index}", "#{pane_dead}", "#{pane_current_command}", "#{pane_title}")
+	cmd := c.tmuxCmd(ctx, "list-panes", "-t", target, "-F", format)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, c.wrapCommandError(ctx, err, "list-panes", session, windowName)
+	}
+
+	var panes []PaneInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields, ok := splitFields(line, 5)
+		if !ok {
+			return nil, &CommandError{Op: "list-panes", Session: session, Window: windowName, Err: fmt.Errorf("unexpected output %q", line)}
+		}
+		index, _ := strconv.Atoi(fields[1])
+		panes = append(panes, PaneInfo{
+			ID:      fields[0],
+			Index:   index,
+			Dead:    fields[2] == "1",
+			Command: fields[3],
+			Title:   fields[4],
+		})
+	}
+	return panes, nil
+}
+
+// SplitWindow adds a pane to a window running command (the default shell
+// when command is empty) and returns the new pane's ID. The window's active
+// pane doesn't change.
+func (c *Client) SplitWindow(ctx context.Context, session, windowName, command string) (string, error) {
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	args := []string{"split-window", "-d", "-t", target, "-P", "-F", "#{pane_id}"}
+	if command != "" {
+		args = append(args, command)
+	}
+	output, err := c.tmuxCmd(ctx, args...).Output()
+	if err != nil {
+		return "", c.wrapCommandError(ctx, err, "split-window", session, windowName)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RespawnPane replaces whatever runs in a pane (an ID from ListPanes or
+// SplitWindow) with command.
+func (c *Client) RespawnPane(ctx context.Context, pane, command string) error {
+	cmd := c.tmuxCmd(ctx, "respawn-pane", "-k", "-t", pane, command)
+	return c.wrapCommandError(ctx, cmd.Run(), "respawn-pane", "", pane)
+}
+
+// SetPaneTitle sets the title tmux shows for a pane in its border and in
+// the #{pane_title} format.
+func (c *Client) SetPaneTitle(ctx context.Context, pane, title string) error {
+	cmd := c.tmuxCmd(ctx, "select-pane", "-t", pane, "-T", title)
+	return c.wrapCommandError(ctx, cmd.Run(), "select-pane", "", pane)
+}
+
+// SelectLayout arranges a window's panes, e.g. with LayoutTiled.
+func (c *Client) SelectLayout(ctx context.Context, session, windowName, layout string) error {
+	target := fmt.Sprintf("%s:%s", session, windowName)
+	cmd := c.tmuxCmd(ctx, "select-layout", "-t", target, layout)
+	return c.wrapCommandError(ctx, cmd.Run(), "select-layout", session, windowName)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -242,6 +243,68 @@ func TestCreateWindow(t *testing.T) {
 	}
 }
 
+func TestCreateSessionAt(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	if !client.IsTmuxAvailable() {
+		t.Skip("tmux not available")
+	}
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionName := fmt.Sprintf("test-at-%d", time.Now().UnixNano())
+	if err := client.CreateSessionAt(ctx, sessionName, "first", dir); err != nil {
+		t.Skipf("cannot create tmux sessions in this environment: %v", err)
+	}
+	defer client.KillSession(ctx, sessionName)
+
+	if err := client.CreateWindowAt(ctx, sessionName, "second", dir); err != nil {
+		t.Fatalf("CreateWindowAt failed: %v", err)
+	}
+
+	windows, err := client.ListWindowInfo(ctx, sessionName)
+	if err != nil {
+		t.Fatalf("ListWindowInfo failed: %v", err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("got %d windows, want 2", len(windows))
+	}
+	for i, name := range []string{"first", "second"} {
+		if windows[i].Name != name || windows[i].PanePath != dir {
+			t.Errorf("window %d = %s in %s, want %s in %s", i, windows[i].Name, windows[i].PanePath, name, dir)
+		}
+	}
+}
+
+func TestCreateWindowWithCommand(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	session := createTestSessionOrSkip(t, ctx, client)
+	defer client.KillSession(ctx, session)
+
+	dir := t.TempDir()
+	if err := client.CreateWindowWithCommand(ctx, session, "job", dir, "pwd > out.txt; sleep 30"); err != nil {
+		t.Fatalf("CreateWindowWithCommand failed: %v", err)
+	}
+	if exists, err := client.HasWindow(ctx, session, "job"); err != nil || !exists {
+		t.Fatalf("HasWindow = %v, %v; want true", exists, err)
+	}
+
+	out := filepath.Join(dir, "out.txt")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, err := os.ReadFile(out); err == nil && len(data) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("command didn't run in the new window")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
 func TestHasWindow(t *testing.T) {
 	ctx := context.Background()
 	client := NewClient()
@@ -625,6 +688,95 @@ func TestListWindows(t *testing.T) {
 	}
 }
 
+func TestListWindowInfoAndWindowOption(t *testing.T) {
+	ctx := context.Background()
+	// A client outside tmux without a UTF-8 locale gets tabs in formats
+	// printed as "_"
+	for _, name := range []string{"TMUX", "LANG", "LC_ALL", "LC_CTYPE"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	client := NewClient()
+	sessionName := createTestSessionOrSkip(t, ctx, client)
+	defer client.KillSession(ctx, sessionName)
+
+	if err := client.CreateWindow(ctx, sessionName, "info-window"); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	windows, err := client.ListWindowInfo(ctx, sessionName)
+	if err != nil {
+		t.Fatalf("ListWindowInfo failed: %v", err)
+	}
+	var found *WindowInfo
+	for i := range windows {
+		if windows[i].Name == "info-window" {
+			found = &windows[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("info-window not in %+v", windows)
+	}
+	if found.PaneDead || found.PaneCommand == "" || found.PanePath == "" {
+		t.Errorf("new window should have a live pane with a command and directory, got %+v", *found)
+	}
+	if found.Activity.IsZero() || time.Since(found.Activity) > time.Minute {
+		t.Errorf("new window should report recent activity, got %v", found.Activity)
+	}
+
+	if value, err := client.GetWindowOption(ctx, sessionName, "info-window", "@test-flag"); err != nil || value != "" {
+		t.Errorf("unset option = %q, %v; want empty", value, err)
+	}
+	target := fmt.Sprintf("%s:%s", sessionName, "info-window")
+	if err := exec.Command("tmux", "set-option", "-w", "-t", target, "@test-flag", "on").Run(); err != nil {
+		t.Fatalf("Failed to set option: %v", err)
+	}
+	if value, err := client.GetWindowOption(ctx, sessionName, "info-window", "@test-flag"); err != nil || value != "on" {
+		t.Errorf("GetWindowOption = %q, %v; want on", value, err)
+	}
+}
+
+func TestRingBell(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	sessionName := createTestSessionOrSkip(t, ctx, client)
+	defer client.KillSession(ctx, sessionName)
+
+	// The bell flag is only set on windows other than the current one
+	for _, name := range []string{"quiet-window", "bell-window"} {
+		if err := client.CreateWindow(ctx, sessionName, name); err != nil {
+			t.Fatalf("Failed to create window: %v", err)
+		}
+	}
+	if err := exec.Command("tmux", "select-window", "-t", sessionName+":quiet-window").Run(); err != nil {
+		t.Fatalf("Failed to select window: %v", err)
+	}
+
+	if err := client.RingBell(ctx, sessionName, "bell-window"); err != nil {
+		t.Fatalf("RingBell failed: %v", err)
+	}
+	if value, err := client.GetWindowOption(ctx, sessionName, "bell-window", "monitor-bell"); err != nil || value != "on" {
+		t.Errorf("monitor-bell = %q, %v; want on", value, err)
+	}
+
+	flagged := false
+	for i := 0; i < 20 && !flagged; i++ {
+		output, err := exec.Command("tmux", "display-message", "-t", sessionName+":bell-window", "-p", "#{window_bell_flag}").Output()
+		if err != nil {
+			t.Fatalf("Failed to read bell flag: %v", err)
+		}
+		flagged = strings.TrimSpace(string(output)) == "1"
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !flagged {
+		t.Error("window bell flag was not set")
+	}
+
+	if err := client.RingBell(ctx, sessionName, "no-such-window"); err == nil {
+		t.Error("RingBell on a missing window should fail")
+	}
+}
+
 func TestGetPanePID(t *testing.T) {
 	ctx := context.Background()
 	client := NewClient()
@@ -846,6 +998,47 @@ func TestPipePane(t *testing.T) {
 	}
 }
 
+func TestCapturePane(t *testing.T) {
+	skipIfCannotCreateSessions(t)
+	ctx := context.Background()
+	client := NewClient()
+	session := uniqueSessionName()
+	window := "testwindow"
+
+	cmd := exec.Command("tmux", "new-session", "-d", "-s", session, "-n", window)
+	if err := cmd.Run(); err != nil {
+		t.Skipf("tmux session creation failed (intermittent CI issue): %v", err)
+	}
+	defer client.KillSession(ctx, session)
+
+	if err := client.SendKeys(ctx, session, window, "echo captured-$((6*7))"); err != nil {
+		t.Fatalf("Failed to send keys: %v", err)
+	}
+
+	// The shell may take a moment to run it
+	var screen string
+	for i := 0; i < 20; i++ {
+		time.Sleep(250 * time.Millisecond)
+		var err error
+		if screen, err = client.CapturePane(ctx, session, window, 100); err != nil {
+			t.Fatalf("CapturePane failed: %v", err)
+		}
+		if strings.Contains(screen, "captured-42") {
+			break
+		}
+	}
+	if !strings.Contains(screen, "captured-42") {
+		t.Errorf("CapturePane() = %q, want the command's output", screen)
+	}
+	if strings.HasSuffix(screen, "\n") {
+		t.Error("CapturePane should drop trailing blank lines")
+	}
+
+	if _, err := client.CapturePane(ctx, "nonexistent-session", window, 0); err == nil {
+		t.Error("CapturePane on non-existent session should fail")
+	}
+}
+
 func TestPipePaneErrorHandling(t *testing.T) {
 	ctx := context.Background()
 	client := NewClient()
@@ -1273,3 +1466,58 @@ func TestStopPipePaneContextCancellation(t *testing.T) {
 		t.Errorf("Expected context.Canceled, got %v", err)
 	}
 }
+
+func TestPaneLayout(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient()
+	session := createTestSessionOrSkip(t, ctx, client)
+	defer client.KillSession(ctx, session)
+	window := "grid"
+	if err := client.CreateWindow(ctx, session, window); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := client.SplitWindow(ctx, session, window, "sleep 60")
+		if err != nil {
+			t.Fatalf("SplitWindow failed: %v", err)
+		}
+		if !strings.HasPrefix(id, "%") {
+			t.Errorf("SplitWindow returned %q, want a pane ID", id)
+		}
+		ids = append(ids, id)
+		if err := client.SelectLayout(ctx, session, window, LayoutTiled); err != nil {
+			t.Fatalf("SelectLayout failed: %v", err)
+		}
+	}
+	if err := client.SetPaneTitle(ctx, ids[0], "worker-1"); err != nil {
+		t.Fatalf("SetPaneTitle failed: %v", err)
+	}
+	if err := client.RespawnPane(ctx, ids[1], "sleep 30"); err != nil {
+		t.Fatalf("RespawnPane failed: %v", err)
+	}
+
+	panes, err := client.ListPanes(ctx, session, window)
+	if err != nil {
+		t.Fatalf("ListPanes failed: %v", err)
+	}
+	if len(panes) != 4 {
+		t.Fatalf("ListPanes returned %d panes, want 4", len(panes))
+	}
+	for i, pane := range panes {
+		if pane.Index != i {
+			t.Errorf("pane %d has index %d", i, pane.Index)
+		}
+		if pane.ID == ids[0] && pane.Title != "worker-1" {
+			t.Errorf("pane %s title = %q, want worker-1", pane.ID, pane.Title)
+		}
+	}
+
+	if err := client.SelectLayout(ctx, session, window, "no-such-layout"); err == nil {
+		t.Error("SelectLayout with an unknown layout should fail")
+	}
+	if _, err := client.ListPanes(ctx, session, "missing"); err == nil {
+		t.Error("ListPanes on a missing window should fail")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/dlorenc/multiclaude/internal/state"
 	"github.com/dlorenc/multiclaude/internal/worktree"
 	"github.com/dlorenc/multiclaude/pkg/config"
+	"github.com/dlorenc/multiclaude/pkg/testkit"
 	"github.com/dlorenc/multiclaude/pkg/tmux"
 )
 
@@ -22,82 +23,13 @@ import (
 func setupIntegrationTest(t *testing.T, repoName string) (*cli.CLI, *daemon.Daemon, string, func()) {
 	t.Helper()
 
-	// Set test mode to skip Claude startup
-	os.Setenv("MULTICLAUDE_TEST_MODE", "1")
-
-	tmuxClient := tmux.NewClient()
-	if !tmuxClient.IsTmuxAvailable() {
-		t.Fatal("tmux is required for this test but not available")
-	}
-
-	// Create temp directory
-	tmpDir, err := os.MkdirTemp("", "integration-test-*")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
-	}
-
-	// Resolve symlinks (macOS /tmp -> /private/tmp)
-	tmpDir, err = filepath.EvalSymlinks(tmpDir)
-	if err != nil {
-		t.Fatalf("Failed to resolve symlinks: %v", err)
-	}
-
-	// Create paths
-	paths := &config.Paths{
-		Root:            tmpDir,
-		DaemonPID:       filepath.Join(tmpDir, "daemon.pid"),
-		DaemonSock:      filepath.Join(tmpDir, "daemon.sock"),
-		DaemonLog:       filepath.Join(tmpDir, "daemon.log"),
-		StateFile:       filepath.Join(tmpDir, "state.json"),
-		ReposDir:        filepath.Join(tmpDir, "repos"),
-		WorktreesDir:    filepath.Join(tmpDir, "wts"),
-		MessagesDir:     filepath.Join(tmpDir, "messages"),
-		OutputDir:       filepath.Join(tmpDir, "output"),
-		ClaudeConfigDir: filepath.Join(tmpDir, "claude-config"),
-	}
-
-	if err := paths.EnsureDirectories(); err != nil {
-		t.Fatalf("Failed to create directories: %v", err)
-	}
-
-	// Create prompts directory
-	if err := os.MkdirAll(filepath.Join(tmpDir, "prompts"), 0755); err != nil {
-		t.Fatalf("Failed to create prompts dir: %v", err)
-	}
-
-	// Create daemon
-	d, err := daemon.New(paths)
-	if err != nil {
-		t.Fatalf("Failed to create daemon: %v", err)
-	}
-
-	// Start daemon
-	if err := d.Start(); err != nil {
-		t.Fatalf("Failed to start daemon: %v", err)
-	}
-
-	// Wait for daemon to be ready
-	time.Sleep(100 * time.Millisecond)
-
-	// Create tmux session
+	env := testkit.New(t)
 	tmuxSession := "mc-" + repoName
-	if err := tmuxClient.CreateSession(context.Background(), tmuxSession, true); err != nil {
-		d.Stop()
-		os.RemoveAll(tmpDir)
-		t.Fatalf("Failed to create tmux session: %v", err)
-	}
-
-	// Create CLI with test paths
-	c := cli.NewWithPaths(paths)
+	env.TmuxSession(tmuxSession)
 
-	cleanup := func() {
-		tmuxClient.KillSession(context.Background(), tmuxSession)
-		d.Stop()
-		os.RemoveAll(tmpDir)
-		os.Unsetenv("MULTICLAUDE_TEST_MODE")
-	}
-
-	return c, d, tmuxSession, cleanup
+	// testkit stops the daemon, kills the session, and removes the
+	// directory when the test ends
+	return env.CLI, env.Daemon, tmuxSession, func() {}
 }
 
 // setupTestGitRepo creates a test git repository with initial commit
@@ -244,6 +176,82 @@ func TestWorkerCreationIntegration(t *testing.T) {
 	}
 }
 
+// TestWorkerCreationProcessMultiplexer runs `multiclaude work` against a
+// daemon using the process multiplexer, as on Windows: the daemon, not the
+// CLI, creates the worker's window
+func TestWorkerCreationProcessMultiplexer(t *testing.T) {
+	t.Setenv("MULTICLAUDE_TEST_MODE", "1")
+	tmpDir, err := os.MkdirTemp("", "mc-proc-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if tmpDir, err = filepath.EvalSymlinks(tmpDir); err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+
+	paths := config.NewTestPaths(tmpDir)
+	if err := paths.EnsureDirectories(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.WriteFile(paths.ConfigFile(), []byte("multiplexer: process\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	d, err := daemon.New(paths)
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+	if err := d.Start(); err != nil {
+		t.Fatalf("Failed to start daemon: %v", err)
+	}
+	defer d.Stop()
+
+	repoName := "proc-work"
+	repoPath := paths.RepoDir(repoName)
+	setupTestGitRepo(t, repoPath)
+	if err := d.GetState().AddRepo(repoName, &state.Repository{
+		GithubURL:        "https://github.com/test/repo",
+		TmuxSession:      "mc-proc-work",
+		Agents:           make(map[string]state.Agent),
+		MergeQueueConfig: state.DefaultMergeQueueConfig(),
+	}); err != nil {
+		t.Fatalf("Failed to add repo: %v", err)
+	}
+
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change to repo dir: %v", err)
+	}
+
+	c := cli.NewWithPaths(paths)
+	if err := c.Execute([]string{"work", "Port the installer", "--name", "proc-worker", "--repo", repoName}); err != nil {
+		t.Fatalf("Worker creation failed: %v", err)
+	}
+
+	agent, exists := d.GetState().GetAgent(repoName, "proc-worker")
+	if !exists || agent.TmuxWindow != "proc-worker" || agent.Headless {
+		t.Fatalf("worker = %+v (exists %v), want one in window proc-worker", agent, exists)
+	}
+	client := socket.NewClient(paths.DaemonSock)
+	resp, err := client.Send(socket.Request{Command: "agent_screen", Args: map[string]interface{}{"repo": repoName, "agent": "proc-worker"}})
+	if err != nil || !resp.Success {
+		t.Errorf("agent_screen = %+v, %v; want the daemon's window for the worker", resp, err)
+	}
+	if exists, _ := tmux.NewClient().HasSession(context.Background(), "mc-proc-work"); exists {
+		t.Error("the CLI created a tmux session although the daemon uses the process multiplexer")
+	}
+
+	// Removing the worker kills its window in the daemon
+	if err := c.Execute([]string{"work", "rm", "proc-worker", "--repo", repoName, "--yes"}); err != nil {
+		t.Fatalf("Worker removal failed: %v", err)
+	}
+	resp, err = client.Send(socket.Request{Command: "agent_screen", Args: map[string]interface{}{"repo": repoName, "agent": "proc-worker"}})
+	if err == nil && resp.Success {
+		t.Error("the worker's window should be gone after work rm")
+	}
+}
+
 // TestRepoInitializationIntegration tests the full repo initialization flow.
 // Uses a local git repo instead of cloning from GitHub.
 func TestRepoInitializationIntegration(t *testing.T) {